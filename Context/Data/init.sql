@@ -4,9 +4,128 @@ CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- bcrypt hash; NULL means the user cannot log in to the dashboard
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+-- Users deprovisioned through SCIM are deactivated rather than deleted
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Refresh tokens for dashboard sessions. Only a SHA-256 of the token is stored.
+-- Each refresh rotates the token; the old one is revoked.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+-- Roles are read-only < operator < admin. Users that existed before roles
+-- keep full access; new users start read-only.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'admin';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'read-only';
+
+-- API keys for machines, managed through /api/auth/keys. Only a SHA-256 of
+-- the key is stored. Revoked keys are kept for the audit trail; a name can be
+-- reused once its key is revoked.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_active_name ON api_keys(name) WHERE revoked_at IS NULL;
+
+-- ========================================
+-- Ownership
+-- ========================================
+
+-- Teams group users for alert routing and access scoping
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members(user_id);
+
+-- Named groups of sending IPs
+CREATE TABLE IF NOT EXISTS ip_pools (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ip_pool_members (
+    pool_id INTEGER NOT NULL REFERENCES ip_pools(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (pool_id, ip_address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_pool_members_ip ON ip_pool_members(ip_address);
+
+-- Ties exactly one owner (user or team) to exactly one target (IP or pool)
+CREATE TABLE IF NOT EXISTS ownership_assignments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45),
+    pool_id INTEGER REFERENCES ip_pools(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (team_id IS NULL)),
+    CHECK ((ip_address IS NULL) <> (pool_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ownership_unique ON ownership_assignments(
+    COALESCE(user_id, 0), COALESCE(team_id, 0), COALESCE(ip_address, ''), COALESCE(pool_id, 0)
+);
+CREATE INDEX IF NOT EXISTS idx_ownership_ip ON ownership_assignments(ip_address) WHERE ip_address IS NOT NULL;
+
+-- Where alerts go. A route matches an alert for an IP in its pool and/or
+-- owned by its team (NULL matches any) at its severity (NULL matches any).
+-- Routes naming a pool or team replace the catch-all routes for that alert.
+CREATE TABLE IF NOT EXISTS notification_routes (
+    id SERIAL PRIMARY KEY,
+    pool_id INTEGER REFERENCES ip_pools(id) ON DELETE CASCADE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+    severity VARCHAR(20),  -- info, warning or critical
+    notifier VARCHAR(20) NOT NULL,  -- slack, email, webhook or pagerduty
+    target TEXT NOT NULL,  -- Channel, address, URL or routing key
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_routes_unique ON notification_routes(
+    COALESCE(pool_id, 0), COALESCE(team_id, 0), COALESCE(severity, ''), notifier, target
+);
+
 -- Create index on username for faster lookups
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 
@@ -23,6 +142,19 @@ ON CONFLICT (username) DO NOTHING;
 -- IP Reputation & SMTP Failure Tracking
 -- ========================================
 
+-- Stalwart instances allowed to post webhooks, each with its own token
+CREATE TABLE IF NOT EXISTS webhook_sources (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,  -- SHA-256 of the token; the token itself is never stored
+    enabled BOOLEAN DEFAULT TRUE,
+    events_processed BIGINT DEFAULT 0,
+    events_failed BIGINT DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE
+);
+
 -- Track individual SMTP delivery failures
 CREATE TABLE IF NOT EXISTS smtp_failures (
     id SERIAL PRIMARY KEY,
@@ -35,15 +167,267 @@ CREATE TABLE IF NOT EXISTS smtp_failures (
     mx_server VARCHAR(255),
     timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     event_id VARCHAR(255) UNIQUE NOT NULL,  -- CRITICAL: UNIQUE constraint prevents duplicate webhook events
-    attempt_number INTEGER DEFAULT 1
+    attempt_number INTEGER DEFAULT 1,
+    anonymized_at TIMESTAMP WITH TIME ZONE,  -- Set once recipient PII has been scrubbed
+    source_id INTEGER REFERENCES webhook_sources(id),  -- Webhook source that reported the event
+    mta_instance VARCHAR(255),  -- MTA instance that attempted delivery, when several share an IP
+    message_id VARCHAR(255),  -- Queue ID of the message; retries of one message share it
+    event_type VARCHAR(50) DEFAULT 'smtp.delivery.failure',  -- Webhook event the failure came from (failure, bounce, DSN)
+    -- Parsed enhanced_code (RFC 3463); NULL when the code is missing or malformed
+    enhanced_class SMALLINT,
+    enhanced_subject SMALLINT,
+    enhanced_detail SMALLINT,
+    organizational_domain VARCHAR(255),  -- Registrable domain of recipient_domain (public suffix list)
+    content_hash CHAR(64),  -- SHA-256 of IP, recipient, codes, attempt and timestamp bucket; catches replays with new event IDs
+    tls_policy VARCHAR(20),  -- Destination's TLS requirement when ingested (dane, mta-sts-enforce, mta-sts-testing, none); NULL if unknown
+    reason_family TEXT,  -- reason with IPs, IDs, numbers and URLs replaced by placeholders; NULL for rows stored before families
+    block_references TEXT[],  -- Provider block list identifiers quoted in reason (Outlook S3150, Yahoo TSS04, ...)
+    retry_delivered_at TIMESTAMP WITH TIME ZONE  -- Delivery success to the recipient that followed this greylist-style deferral
 );
 
+-- Added after the initial schema; keeps existing databases in line
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS source_id INTEGER REFERENCES webhook_sources(id);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS mta_instance VARCHAR(255);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS message_id VARCHAR(255);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS event_type VARCHAR(50) DEFAULT 'smtp.delivery.failure';
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS enhanced_class SMALLINT;
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS enhanced_subject SMALLINT;
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS enhanced_detail SMALLINT;
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS organizational_domain VARCHAR(255);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS content_hash CHAR(64);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS tls_policy VARCHAR(20);
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS reason_family TEXT;
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS block_references TEXT[];
+ALTER TABLE smtp_failures ADD COLUMN IF NOT EXISTS retry_delivered_at TIMESTAMP WITH TIME ZONE;
+
+-- Domains are stored lower-cased since domain normalization was added
+UPDATE smtp_failures SET recipient_domain = LOWER(recipient_domain) WHERE recipient_domain <> LOWER(recipient_domain);
+
+-- Parse and canonicalize (5.7.001 -> 5.7.1) codes stored before the columns existed
+UPDATE smtp_failures
+SET enhanced_class = split_part(TRIM(enhanced_code), '.', 1)::SMALLINT,
+    enhanced_subject = split_part(TRIM(enhanced_code), '.', 2)::SMALLINT,
+    enhanced_detail = split_part(TRIM(enhanced_code), '.', 3)::SMALLINT,
+    enhanced_code = split_part(TRIM(enhanced_code), '.', 1)::INT || '.' ||
+                    split_part(TRIM(enhanced_code), '.', 2)::INT || '.' ||
+                    split_part(TRIM(enhanced_code), '.', 3)::INT
+WHERE enhanced_class IS NULL AND TRIM(enhanced_code) ~ '^[245]\.[0-9]{1,3}\.[0-9]{1,3}$';
+
 -- Indexes for efficient querying
 CREATE INDEX IF NOT EXISTS idx_smtp_failures_ip_timestamp ON smtp_failures(sending_ip, timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_smtp_failures_domain_timestamp ON smtp_failures(recipient_domain, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_org_domain_timestamp ON smtp_failures(organizational_domain, timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_smtp_failures_enhanced_code ON smtp_failures(enhanced_code);
-CREATE INDEX IF NOT EXISTS idx_smtp_failures_timestamp ON smtp_failures(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_reason_family_timestamp ON smtp_failures(reason_family, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_enhanced_parts ON smtp_failures(enhanced_class, enhanced_subject, enhanced_detail, timestamp DESC);
+-- Aggregation discovery reads the IPs of a time range from the index alone
+DROP INDEX IF EXISTS idx_smtp_failures_timestamp;
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_timestamp_ip ON smtp_failures(timestamp DESC) INCLUDE (sending_ip);
 CREATE INDEX IF NOT EXISTS idx_smtp_failures_event_id ON smtp_failures(event_id);  -- Fast lookup for deduplication
+CREATE UNIQUE INDEX IF NOT EXISTS idx_smtp_failures_content_hash ON smtp_failures(content_hash) WHERE content_hash IS NOT NULL;  -- Secondary content deduplication
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_source_timestamp ON smtp_failures(source_id, timestamp DESC);
+-- Only failures reported with an instance are looked up by instance
+DROP INDEX IF EXISTS idx_smtp_failures_ip_instance_timestamp;
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_ip_instance_ts ON smtp_failures(sending_ip, mta_instance, timestamp DESC) WHERE mta_instance IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_recipient_email ON smtp_failures(LOWER(recipient_email));  -- Right-to-erasure lookups
+CREATE INDEX IF NOT EXISTS idx_smtp_failures_not_anonymized ON smtp_failures(timestamp) WHERE anonymized_at IS NULL;  -- Shrinks as the retention job anonymizes
+
+-- Per-minute failure counts, maintained by the insert of each failure so
+-- aggregation need not read raw failures
+CREATE TABLE IF NOT EXISTS failure_counters (
+    sending_ip VARCHAR(45) NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,  -- Start of the one-minute bucket
+    mta_instance VARCHAR(255) NOT NULL DEFAULT '',
+    domain VARCHAR(255) NOT NULL DEFAULT '',  -- Organizational recipient domain
+    enhanced_code VARCHAR(20) NOT NULL DEFAULT '',
+    kind VARCHAR(20) NOT NULL,  -- rejection, retry, deferral, greylisted or tls_required
+    failures INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_failure_counters_bucket ON failure_counters(bucket_start);
+
+-- When counting began. Windows starting earlier are aggregated from raw
+-- failures, which the counters do not cover.
+CREATE TABLE IF NOT EXISTS failure_counter_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    counting_since TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO failure_counter_state (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+-- Messages sent per IP and period as reported by the sending systems, read
+-- by the reported volume estimator instead of estimating from failures
+CREATE TABLE IF NOT EXISTS sending_volumes (
+    sending_ip VARCHAR(45) NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    messages INTEGER NOT NULL,
+    source VARCHAR(100) NOT NULL,  -- Sending system that reported the volume
+    reported_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (sending_ip, period_start, period_end, source),
+    CHECK (period_end > period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sending_volumes_ip_period_end ON sending_volumes(sending_ip, period_end);
+
+-- Messages delivered per IP and one-minute bucket, counted from Stalwart's
+-- smtp.delivery.success events and read by the delivered volume estimator
+CREATE TABLE IF NOT EXISTS sent_volume (
+    sending_ip VARCHAR(45) NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,  -- Start of the one-minute bucket
+    mta_instance VARCHAR(255) NOT NULL DEFAULT '',
+    messages INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (sending_ip, bucket_start, mta_instance)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sent_volume_bucket ON sent_volume(bucket_start);
+
+-- Event IDs of the counted delivery successes, so redelivered events are
+-- not counted twice. Pruned with the buckets.
+CREATE TABLE IF NOT EXISTS sent_volume_events (
+    event_id VARCHAR(255) PRIMARY KEY,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sent_volume_events_received_at ON sent_volume_events(received_at);
+
+-- Outgoing SMTP authentication failures (e.g. against a smarthost)
+CREATE TABLE IF NOT EXISTS smtp_auth_failures (
+    id SERIAL PRIMARY KEY,
+    sending_ip VARCHAR(45) NOT NULL,
+    remote_host VARCHAR(255),
+    mechanism VARCHAR(50),
+    username VARCHAR(255),
+    reason TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    event_id VARCHAR(255) UNIQUE NOT NULL,
+    source_id INTEGER REFERENCES webhook_sources(id),
+    mta_instance VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_smtp_auth_failures_ip_timestamp ON smtp_auth_failures(sending_ip, timestamp DESC);
+
+-- Spamtrap hits reported by trap networks or internal traps. A hit is a
+-- critical signal: it quarantines the sending IP whatever its ratios.
+CREATE TABLE IF NOT EXISTS spamtrap_hits (
+    id SERIAL PRIMARY KEY,
+    sending_ip VARCHAR(45) NOT NULL,
+    trap_address VARCHAR(255),
+    trap_type VARCHAR(20) NOT NULL DEFAULT 'unknown',  -- pristine, recycled, typo, internal or unknown
+    source VARCHAR(100) NOT NULL,  -- Trap network or internal trap that reported the hit
+    report_id VARCHAR(255),  -- The reporter's ID of the hit, used to skip re-sent reports
+    message_id VARCHAR(255),
+    hit_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reported_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_spamtrap_hits_ip_hit_at ON spamtrap_hits(sending_ip, hit_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_spamtrap_hits_report ON spamtrap_hits(source, report_id) WHERE report_id IS NOT NULL;
+
+-- Abuse complaints parsed from ARF feedback reports (RFC 5965). Only the
+-- recipient's domain is kept; complaints feed the complaint-rate rules.
+CREATE TABLE IF NOT EXISTS abuse_complaints (
+    id SERIAL PRIMARY KEY,
+    sending_ip VARCHAR(45) NOT NULL,
+    feedback_type VARCHAR(20) NOT NULL,  -- abuse, fraud, virus, other, not-spam or auth-failure
+    reporter VARCHAR(255) NOT NULL,  -- Domain of the report's sender, e.g. the mailbox provider
+    report_id VARCHAR(255),  -- Message-ID of the report, used to skip re-sent reports
+    campaign_id VARCHAR(255),
+    original_message_id VARCHAR(255),
+    original_mail_from VARCHAR(255),
+    recipient_domain VARCHAR(255),
+    reported_domain VARCHAR(255),
+    user_agent VARCHAR(255),
+    arrived_at TIMESTAMP WITH TIME ZONE NOT NULL,  -- When the original message arrived at the reporter
+    reported_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_abuse_complaints_ip_arrived_at ON abuse_complaints(sending_ip, arrived_at DESC);
+CREATE INDEX IF NOT EXISTS idx_abuse_complaints_campaign ON abuse_complaints(campaign_id) WHERE campaign_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_abuse_complaints_report ON abuse_complaints(reporter, report_id) WHERE report_id IS NOT NULL;
+
+-- SMTP TLS reports (RFC 8460) on our outbound sessions: what each sending
+-- organization's MTAs saw negotiating TLS with each policy domain
+CREATE TABLE IF NOT EXISTS tls_reports (
+    id SERIAL PRIMARY KEY,
+    organization VARCHAR(255) NOT NULL,
+    report_id VARCHAR(255) NOT NULL,
+    contact_info VARCHAR(255),
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reported_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(organization, report_id)
+);
+
+-- Session counts per report and policy domain
+CREATE TABLE IF NOT EXISTS tls_report_policies (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES tls_reports(id) ON DELETE CASCADE,
+    policy_domain VARCHAR(255) NOT NULL,
+    policy_type VARCHAR(20) NOT NULL,  -- sts, tlsa or no-policy-found
+    successful_sessions INTEGER NOT NULL DEFAULT 0,
+    failed_sessions INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_tls_report_policies_report ON tls_report_policies(report_id);
+CREATE INDEX IF NOT EXISTS idx_tls_report_policies_domain ON tls_report_policies(policy_domain);
+
+-- Failed TLS negotiations per report, policy domain, sending IP and result
+CREATE TABLE IF NOT EXISTS tls_failures (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES tls_reports(id) ON DELETE CASCADE,
+    policy_domain VARCHAR(255) NOT NULL,
+    policy_type VARCHAR(20) NOT NULL,
+    sending_ip VARCHAR(45),  -- NULL when the report leaves it out
+    result_type VARCHAR(50) NOT NULL,  -- e.g. starttls-not-supported, certificate-expired
+    receiving_mx_hostname VARCHAR(255),
+    receiving_ip VARCHAR(45),
+    failed_sessions INTEGER NOT NULL DEFAULT 0,
+    failure_reason_code VARCHAR(255),
+    additional_information TEXT,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tls_failures_ip_end_at ON tls_failures(sending_ip, end_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tls_failures_report ON tls_failures(report_id);
+
+-- Third-party reputation feeds (threat intel, partner blocklists) allowed to
+-- post IP verdicts; weight scales their verdicts in the external score
+CREATE TABLE IF NOT EXISTS reputation_feeds (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    weight DOUBLE PRECISION NOT NULL DEFAULT 1,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,  -- SHA-256 of the token; the token itself is never stored
+    enabled BOOLEAN DEFAULT TRUE,
+    signals_received BIGINT DEFAULT 0,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE
+);
+
+-- The latest verdict of each feed on an IP
+CREATE TABLE IF NOT EXISTS external_reputation_signals (
+    id SERIAL PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES reputation_feeds(id) ON DELETE CASCADE,
+    ip VARCHAR(45) NOT NULL,
+    verdict VARCHAR(20) NOT NULL,  -- malicious, suspicious or clean
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 1,  -- 0-1, scales the verdict
+    reason TEXT,
+    reference VARCHAR(255),  -- The feed's listing ID or URL
+    reported_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (feed_id, ip)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_signals_ip_expires_at ON external_reputation_signals(ip, expires_at);
 
 -- Store aggregated IP reputation metrics
 CREATE TABLE IF NOT EXISTS ip_reputation_metrics (
@@ -67,6 +451,65 @@ CREATE INDEX IF NOT EXISTS idx_ip_reputation_ip ON ip_reputation_metrics(ip);
 CREATE INDEX IF NOT EXISTS idx_ip_reputation_status ON ip_reputation_metrics(status);
 CREATE INDEX IF NOT EXISTS idx_ip_reputation_updated ON ip_reputation_metrics(last_updated DESC);
 
+-- One row per IP and aggregation run, for heatmaps and trends over days
+CREATE TABLE IF NOT EXISTS ip_reputation_history (
+    id BIGSERIAL PRIMARY KEY,
+    ip VARCHAR(45) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    total_sent INTEGER DEFAULT 0,
+    total_rejected INTEGER DEFAULT 0,
+    rejection_ratio DECIMAL(5,4) DEFAULT 0.0000,
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_reputation_history_recorded ON ip_reputation_history(recorded_at);
+CREATE INDEX IF NOT EXISTS idx_ip_reputation_history_ip ON ip_reputation_history(ip, recorded_at);
+
+-- Periodic checks of the fleet service level objectives; their share that
+-- met an objective over its window is its compliance
+CREATE TABLE IF NOT EXISTS slo_measurements (
+    id BIGSERIAL PRIMARY KEY,
+    objective VARCHAR(100) NOT NULL,
+    measured_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    value DOUBLE PRECISION NOT NULL,  -- The fleet metric measured, e.g. the healthy share
+    threshold DOUBLE PRECISION NOT NULL,
+    met BOOLEAN NOT NULL,
+    total_ips INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_slo_measurements_objective ON slo_measurements(objective, measured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_slo_measurements_measured ON slo_measurements(measured_at);
+
+-- Runs of the scheduled self-audit of stale data and inconsistencies
+CREATE TABLE IF NOT EXISTS audit_reports (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    issues INTEGER NOT NULL DEFAULT 0,    -- Inconsistencies found by all checks
+    exceeded INTEGER NOT NULL DEFAULT 0,  -- Checks above their alert threshold
+    checks JSONB NOT NULL DEFAULT '[]'    -- [{"name", "count", "threshold", "exceeded", "samples"}]
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_reports_started ON audit_reports(started_at DESC);
+
+-- Per-window metrics for the longer evaluation windows (e.g. 1h, 24h)
+-- evaluated next to the primary window in ip_reputation_metrics
+CREATE TABLE IF NOT EXISTS ip_reputation_windows (
+    id SERIAL PRIMARY KEY,
+    ip VARCHAR(45) NOT NULL,
+    window_name VARCHAR(50) NOT NULL,
+    window_minutes INTEGER NOT NULL,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    window_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    total_sent INTEGER DEFAULT 0,
+    total_rejected INTEGER DEFAULT 0,
+    rejection_ratio DECIMAL(5,4) DEFAULT 0.0000,
+    unique_domains_rejected INTEGER DEFAULT 0,
+    status VARCHAR(20) DEFAULT 'healthy',
+    last_updated TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (ip, window_name)
+);
+
 -- Track DNSBL (DNS Blacklist) check results
 CREATE TABLE IF NOT EXISTS dnsbl_checks (
     id SERIAL PRIMARY KEY,
@@ -83,6 +526,65 @@ CREATE INDEX IF NOT EXISTS idx_dnsbl_checks_ip ON dnsbl_checks(ip);
 CREATE INDEX IF NOT EXISTS idx_dnsbl_checks_timestamp ON dnsbl_checks(checked_at DESC);
 CREATE INDEX IF NOT EXISTS idx_dnsbl_checks_listed ON dnsbl_checks(listed);
 
+-- Results of external sender reputation lookups (e.g. SenderScore), kept as
+-- history; the latest per provider is the cached result
+CREATE TABLE IF NOT EXISTS reputation_lookups (
+    id SERIAL PRIMARY KEY,
+    ip VARCHAR(45) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    score DOUBLE PRECISION,
+    listed BOOLEAN DEFAULT FALSE,
+    summary TEXT,
+    details JSONB DEFAULT '{}',
+    error TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reputation_lookups_ip_provider ON reputation_lookups(ip, provider, checked_at DESC);
+
+-- Throttling state of each IP at each destination provider, updated every
+-- aggregation run from 4.7.x rate-limit deferrals
+CREATE TABLE IF NOT EXISTS ip_throttle_states (
+    ip VARCHAR(45) NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    throttled BOOLEAN DEFAULT FALSE,
+    since TIMESTAMP WITH TIME ZONE,
+    last_throttled_at TIMESTAMP WITH TIME ZONE,
+    throttle_count INTEGER DEFAULT 0,
+    backoff_seconds INTEGER DEFAULT 0,
+    retry_after TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (ip, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_throttle_states_throttled ON ip_throttle_states(throttled) WHERE throttled;
+
+-- Recommended send rate of each IP to each destination provider, replaced
+-- every aggregation run; provider '*' is the rate for all other providers
+CREATE TABLE IF NOT EXISTS ip_send_rates (
+    ip VARCHAR(45) NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    messages_per_hour INTEGER NOT NULL,
+    warmup_stage INTEGER DEFAULT 0,
+    reasons JSONB DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (ip, provider)
+);
+
+-- Explicit outbound rate caps operators set on IPs, served with the sending
+-- policy and pushed to Stalwart's throttling hook. A cap holds until
+-- expires_at, when the IP goes back to its recommended rates.
+CREATE TABLE IF NOT EXISTS ip_rate_limits (
+    ip VARCHAR(45) PRIMARY KEY,
+    messages_per_minute INTEGER NOT NULL CHECK (messages_per_minute > 0),
+    reason TEXT NOT NULL DEFAULT '',
+    set_by VARCHAR(255) NOT NULL,
+    set_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_rate_limits_expires_at ON ip_rate_limits(expires_at);
+
 -- Track IP actions and status changes
 CREATE TABLE IF NOT EXISTS ip_actions (
     id SERIAL PRIMARY KEY,
@@ -100,6 +602,223 @@ CREATE TABLE IF NOT EXISTS ip_actions (
 CREATE INDEX IF NOT EXISTS idx_ip_actions_ip ON ip_actions(ip);
 CREATE INDEX IF NOT EXISTS idx_ip_actions_timestamp ON ip_actions(created_at DESC);
 
+-- Operator comments on IP actions (e.g. "opened ticket with Microsoft, ref 12345")
+CREATE TABLE IF NOT EXISTS ip_action_comments (
+    id SERIAL PRIMARY KEY,
+    action_id INTEGER NOT NULL REFERENCES ip_actions(id) ON DELETE CASCADE,
+    author VARCHAR(100) NOT NULL,
+    comment TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_action_comments_action ON ip_action_comments(action_id, created_at);
+
+-- Free-form operator notes on an IP
+CREATE TABLE IF NOT EXISTS ip_notes (
+    id SERIAL PRIMARY KEY,
+    ip VARCHAR(45) NOT NULL,
+    author VARCHAR(100) NOT NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_notes_ip ON ip_notes(ip, created_at DESC);
+
+-- What on-call should know about an IP at a glance, e.g. whose mail it
+-- sends, and the runbook to follow. Shown in the registry, the dashboard and
+-- alerts.
+CREATE TABLE IF NOT EXISTS ip_context (
+    ip VARCHAR(45) PRIMARY KEY,
+    notes TEXT NOT NULL DEFAULT '',
+    runbook_url TEXT NOT NULL DEFAULT '',
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Decommissioned IPs: skipped by aggregation and alerting and hidden from
+-- dashboards, with their history kept
+CREATE TABLE IF NOT EXISTS archived_ips (
+    ip VARCHAR(45) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    archived_by VARCHAR(255) NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Blocks of IPs that are not sending IPs (monitoring, corporate egress, test
+-- addresses): aggregation does not discover them and dashboards hide them
+CREATE TABLE IF NOT EXISTS aggregation_exclusions (
+    id SERIAL PRIMARY KEY,
+    cidr CIDR NOT NULL UNIQUE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Delisting and mitigation requests opened with a provider for an IP, with
+-- the block references its rejections quoted when the task was opened
+CREATE TABLE IF NOT EXISTS delisting_tasks (
+    id SERIAL PRIMARY KEY,
+    ip VARCHAR(45) NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',  -- open, submitted, resolved
+    block_references TEXT[] NOT NULL DEFAULT '{}',
+    comment TEXT NOT NULL DEFAULT '',
+    opened_by VARCHAR(255) NOT NULL,
+    opened_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_delisting_tasks_ip ON delisting_tasks(ip, opened_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delisting_tasks_unresolved ON delisting_tasks(ip, provider) WHERE status <> 'resolved';  -- One pending task per IP and provider
+
+-- Which events open a Jira or ServiceNow ticket. A rule matches an IP in its
+-- pool (NULL matches any).
+CREATE TABLE IF NOT EXISTS itsm_rules (
+    id SERIAL PRIMARY KEY,
+    event VARCHAR(32) NOT NULL,  -- ip_blacklisted or delisting_opened
+    system VARCHAR(20) NOT NULL,  -- jira or servicenow
+    project VARCHAR(255) NOT NULL,  -- Jira project key or ServiceNow assignment group
+    pool_id INTEGER REFERENCES ip_pools(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tickets opened by the rules, kept in sync with the ticket's status
+CREATE TABLE IF NOT EXISTS itsm_tickets (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER REFERENCES itsm_rules(id) ON DELETE SET NULL,
+    system VARCHAR(20) NOT NULL,
+    ticket_key VARCHAR(255) NOT NULL,  -- Jira issue key or ServiceNow number
+    external_id VARCHAR(255) NOT NULL DEFAULT '',  -- Jira issue ID or ServiceNow sys_id
+    url TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    delisting_task_id INTEGER REFERENCES delisting_tasks(id) ON DELETE SET NULL,
+    status VARCHAR(64) NOT NULL DEFAULT '',  -- As reported by the system
+    resolved BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (system, ticket_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_itsm_tickets_ip ON itsm_tickets(ip, created_at DESC);
+
+-- Recommendation catalog: the actions recommended for an IP, with
+-- per-language text overrides of the built-in texts and a runbook link
+CREATE TABLE IF NOT EXISTS recommendations (
+    action VARCHAR(64) PRIMARY KEY,
+    texts JSONB NOT NULL DEFAULT '{}',
+    link TEXT NOT NULL DEFAULT '',
+    updated_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Actions recommended per status; mappings with an issue type replace the
+-- status' other mappings for IPs with that issue type
+CREATE TABLE IF NOT EXISTS recommendation_mappings (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    issue_type VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL REFERENCES recommendations(action) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (status, issue_type, action)
+);
+
+INSERT INTO recommendations (action, updated_by) VALUES
+    ('immediate_quarantine', 'system'),
+    ('swap_to_backup_ip', 'system'),
+    ('run_dnsbl_checks', 'system'),
+    ('alert_ops_critical', 'system'),
+    ('investigate_root_cause', 'system'),
+    ('reduce_traffic_50_percent', 'system'),
+    ('alert_ops_warning', 'system'),
+    ('monitor_closely', 'system'),
+    ('reduce_send_rate', 'system'),
+    ('check_email_list_hygiene', 'system'),
+    ('continue_normal_operations', 'system')
+ON CONFLICT (action) DO NOTHING;
+
+INSERT INTO recommendation_mappings (status, action, position, created_by) VALUES
+    ('blacklisted', 'immediate_quarantine', 1, 'system'),
+    ('blacklisted', 'swap_to_backup_ip', 2, 'system'),
+    ('blacklisted', 'run_dnsbl_checks', 3, 'system'),
+    ('blacklisted', 'alert_ops_critical', 4, 'system'),
+    ('blacklisted', 'investigate_root_cause', 5, 'system'),
+    ('quarantine', 'reduce_traffic_50_percent', 1, 'system'),
+    ('quarantine', 'run_dnsbl_checks', 2, 'system'),
+    ('quarantine', 'alert_ops_warning', 3, 'system'),
+    ('quarantine', 'monitor_closely', 4, 'system'),
+    ('warning', 'monitor_closely', 1, 'system'),
+    ('warning', 'reduce_send_rate', 2, 'system'),
+    ('warning', 'check_email_list_hygiene', 3, 'system'),
+    ('healthy', 'continue_normal_operations', 1, 'system')
+ON CONFLICT (status, issue_type, action) DO NOTHING;
+
+-- Every runtime change of the reputation (and later alerting) thresholds.
+-- The newest revision of a scope is the active config; rollbacks add a new
+-- revision copying an old one.
+CREATE TABLE IF NOT EXISTS config_revisions (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(50) NOT NULL,
+    config JSONB NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',      -- field -> {"from": ..., "to": ...}
+    author VARCHAR(100) NOT NULL,
+    comment TEXT,
+    rollback_of INTEGER REFERENCES config_revisions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_revisions_scope ON config_revisions(scope, id DESC);
+
+-- Feature flags toggled at runtime; a row overrides the flag's config default
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Audit trail of feature flag toggles
+CREATE TABLE IF NOT EXISTS feature_flag_changes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    previous BOOLEAN NOT NULL,  -- Effective value before the toggle
+    author VARCHAR(100) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_flag_changes_name ON feature_flag_changes(name, id DESC);
+
+-- Background jobs started through the API (e.g. async DNSBL checks). The
+-- instance that accepted a job runs it; finished jobs are kept for a day.
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    subject VARCHAR(255),  -- What the job works on, e.g. the IP
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, running, succeeded, failed
+    result JSONB,
+    error TEXT,
+    callback_url TEXT,
+    callback_status VARCHAR(20),  -- delivered or failed once the callback was attempted
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs(finished_at);
+
+-- Done and total items of jobs that report progress, e.g. {"done": 3, "total": 10}
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS progress JSONB;
+
 -- Log initialization
 DO $$
 BEGIN
@@ -148,6 +867,16 @@ CREATE INDEX IF NOT EXISTS idx_reserved_ips_reserved_at ON reserved_ips(reserved
 CREATE INDEX IF NOT EXISTS idx_reserved_ips_uid ON reserved_ips(uid);
 CREATE INDEX IF NOT EXISTS idx_reserved_ips_block_id ON reserved_ips(reservation_block_id);
 
+-- IONOS blocks that cleanup must never delete and quota reports as protected
+CREATE TABLE IF NOT EXISTS protected_blocks (
+    id SERIAL PRIMARY KEY,
+    block_id VARCHAR(255) NOT NULL UNIQUE,  -- IONOS block ID
+    reason TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Track blacklist check history for reserved IPs
 CREATE TABLE IF NOT EXISTS reserved_ip_blacklist_history (
     id SERIAL PRIMARY KEY,
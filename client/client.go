@@ -0,0 +1,5229 @@
+// Code generated by clientgen from the OpenAPI description. DO NOT EDIT.
+
+// Package client is a typed client for the GoLang Backend Service API, version 1.0.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Client calls the API. The zero HTTPClient is http.DefaultClient.
+type Client struct {
+	// BaseURL is the service's root, e.g. https://reputation.example.com
+	BaseURL    string
+	HTTPClient *http.Client
+	// ApiKeyAuth is sent in the X-API-Key header when set
+	ApiKeyAuth string
+	// BearerAuth is sent in the Authorization header when set. Dashboard access
+	// token from /auth/login, as "Bearer <token>"
+	BearerAuth string
+}
+
+// New creates a client for the service at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Error is a response outside 2xx. Code and Message are the service's error
+// code and message when it sent them.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"error"`
+	Message    string `json:"message"`
+	Body       []byte `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        io.Reader
+	contentType string
+}
+
+// param sets a query or header parameter unless value is its zero value
+func (r *request) param(in, name string, value interface{}) {
+	if v := reflect.ValueOf(value); !v.IsValid() || v.IsZero() {
+		return
+	}
+	switch in {
+	case "query":
+		if r.query == nil {
+			r.query = url.Values{}
+		}
+		r.query.Set(name, fmt.Sprint(value))
+	case "header":
+		if r.header == nil {
+			r.header = http.Header{}
+		}
+		r.header.Set(name, fmt.Sprint(value))
+	}
+}
+
+func (r *request) jsonBody(body interface{}) error {
+	if v := reflect.ValueOf(body); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	r.body, r.contentType = bytes.NewReader(data), "application/json"
+	return nil
+}
+
+// do sends req and decodes a JSON response into out; a *[]byte out
+// receives the raw body and a nil out discards it
+func (c *Client) do(ctx context.Context, req *request, out interface{}) error {
+	target := strings.TrimSuffix(c.BaseURL, "/") + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, req.body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.ApiKeyAuth != "" {
+		httpReq.Header.Set("X-API-Key", c.ApiKeyAuth)
+	}
+	if c.BearerAuth != "" {
+		httpReq.Header.Set("Authorization", c.BearerAuth)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		apiErr.Body, _ = io.ReadAll(resp.Body)
+		json.Unmarshal(apiErr.Body, apiErr)
+		return apiErr
+	}
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out, err = io.ReadAll(resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Target is one destination an alert is sent to
+type Target struct {
+	Notifier string `json:"notifier"`
+	RouteID  int    `json:"route_id"`
+	Target   string `json:"target"`
+}
+
+// AbuseComplaintResponse is the complaint stored for an ARF report
+type AbuseComplaintResponse struct {
+	Complaint AbuseComplaint `json:"complaint"`
+	// Duplicate is true when the reporter already sent this report; nothing was
+	// stored
+	Duplicate bool `json:"duplicate"`
+}
+
+// AggregationExclusionRequest excludes a block, or a single IP, from
+// aggregation
+type AggregationExclusionRequest struct {
+	Cidr   string `json:"cidr"`
+	Reason string `json:"reason"`
+}
+
+// AggregationExclusionsResponse lists the excluded blocks
+type AggregationExclusionsResponse struct {
+	Exclusions []AggregationExclusion `json:"exclusions"`
+	Total      int                    `json:"total"`
+}
+
+// ArchiveIPRequest says why an IP is decommissioned
+type ArchiveIPRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ArchiveIPsResponse lists the IPs archived by a request and those that already
+// were
+type ArchiveIPsResponse struct {
+	AlreadyArchived []string     `json:"already_archived"`
+	Archived        []ArchivedIP `json:"archived"`
+	// Matched is set on a dry run: the IPs that would be archived
+	Matched []string `json:"matched,omitempty"`
+}
+
+// ArchivedIPsResponse lists the archived IPs
+type ArchivedIPsResponse struct {
+	Ips   []ArchivedIP `json:"ips"`
+	Total int          `json:"total"`
+}
+
+// AsyncJobRequest represents the optional request body of asynchronous
+// endpoints
+type AsyncJobRequest struct {
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// BulkActionReport is the preview of a bulk action on a dry run, and the result
+// of its job otherwise
+type BulkActionReport struct {
+	Action string `json:"action"`
+	// Changes are the status changes made, or on a dry run those that would be
+	Changes []BulkStatusChange `json:"changes"`
+	DryRun  bool               `json:"dry_run"`
+	Failed  []BulkFailedIP     `json:"failed,omitempty"`
+	Filter  BulkActionFilter   `json:"filter"`
+	Matched int                `json:"matched"`
+	Skipped []BulkSkippedIP    `json:"skipped"`
+}
+
+// BulkActionRequest selects the IPs of a bulk quarantine or restore. At least
+// one of cidr, pool and status is required.
+type BulkActionRequest struct {
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+	Cidr        string `json:"cidr,omitempty"`
+	// DryRun previews the IPs the action would change without changing them
+	DryRun bool   `json:"dry_run,omitempty"`
+	Pool   string `json:"pool,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// BulkArchiveRequest archives every known IP in a CIDR block
+type BulkArchiveRequest struct {
+	Cidr string `json:"cidr"`
+	// DryRun lists the IPs that would be archived without archiving them
+	DryRun bool   `json:"dry_run"`
+	Reason string `json:"reason"`
+}
+
+// BulkFailedIP is an IP a bulk action failed to change
+type BulkFailedIP struct {
+	Error string `json:"error"`
+	IP    string `json:"ip"`
+}
+
+// BulkSkippedIP is a matched IP a bulk action leaves alone
+type BulkSkippedIP struct {
+	IP     string `json:"ip"`
+	Reason string `json:"reason"`
+	Status string `json:"status"`
+}
+
+// BulkStatusChange is an IP's status change by a bulk action
+type BulkStatusChange struct {
+	From string `json:"from"`
+	IP   string `json:"ip"`
+	To   string `json:"to"`
+}
+
+// CapacityForecastPoint is the healthy member count of one UTC day
+type CapacityForecastPoint struct {
+	Date    time.Time `json:"date"`
+	Healthy float64   `json:"healthy"`
+}
+
+// CapacityForecastResponse projects healthy pool capacity and the reserve pool
+// from the recent trend
+type CapacityForecastResponse struct {
+	GeneratedAt  time.Time              `json:"generated_at"`
+	HorizonDays  int                    `json:"horizon_days"`
+	LookbackDays int                    `json:"lookback_days"`
+	Pools        []PoolCapacityForecast `json:"pools"`
+	Reserve      ReserveForecast        `json:"reserve"`
+}
+
+// CodeDelta is how often a code was seen in period A and B
+type CodeDelta struct {
+	A     int    `json:"a"`
+	B     int    `json:"b"`
+	Code  string `json:"code"`
+	Delta int    `json:"delta"`
+}
+
+// ComparePeriod is one of the compared time ranges, from inclusive
+type ComparePeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// CountDelta is a count in period A and B and how it changed
+type CountDelta struct {
+	A     int `json:"a"`
+	B     int `json:"b"`
+	Delta int `json:"delta"`
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Role is read-only, operator or admin
+	Role string `json:"role"`
+}
+
+// CreateAPIKeyResponse returns the new key and its secret. The secret is only
+// shown once; only its hash is stored.
+type CreateAPIKeyResponse struct {
+	APIKey APIKey `json:"api_key"`
+	Key    string `json:"key"`
+}
+
+// CreateActionCommentRequest annotates an IP action
+type CreateActionCommentRequest struct {
+	Comment string `json:"comment"`
+}
+
+// CreateAssignmentRequest represents the request body for assigning an owner
+type CreateAssignmentRequest struct {
+	// user or team ID
+	OwnerID int `json:"owner_id"`
+	// user or team
+	OwnerType string `json:"owner_type"`
+	// IP address or pool name
+	Target string `json:"target"`
+	// ip or pool
+	TargetType string `json:"target_type"`
+}
+
+// CreateIPNoteRequest adds a note to an IP
+type CreateIPNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// CreateReputationFeedRequest registers a third-party reputation feed
+type CreateReputationFeedRequest struct {
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	// Weight scales the feed's verdicts in the external score; defaults to 1
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// CreateReputationFeedResponse returns the new feed and its token. The token is
+// only shown once; only its hash is stored.
+type CreateReputationFeedResponse struct {
+	Feed  ReputationFeed `json:"feed"`
+	Token string         `json:"token"`
+}
+
+// CreateTeamRequest represents the request body for creating a team or pool
+type CreateTeamRequest struct {
+	Description string `json:"description"`
+	Name        string `json:"name"`
+}
+
+// CreateUserRequest represents the request body for creating a user
+type CreateUserRequest struct {
+	Email string `json:"email"`
+	// Password is optional; users without one cannot log in to the dashboard
+	Password string `json:"password,omitempty"`
+	Username string `json:"username"`
+}
+
+// CreateWebhookSourceRequest represents the request body for registering a
+// source
+type CreateWebhookSourceRequest struct {
+	Description string `json:"description"`
+	Name        string `json:"name"`
+}
+
+// CreateWebhookSourceResponse returns the new source and its token. The token
+// is only shown once; only its hash is stored.
+type CreateWebhookSourceResponse struct {
+	Source WebhookSource `json:"source"`
+	Token  string        `json:"token"`
+}
+
+// DeleteUserResponse confirms a deleted user
+type DeleteUserResponse struct {
+	Status string `json:"status"`
+	UserID int    `json:"user_id"`
+}
+
+// DelistingTasksResponse lists delisting tasks, newest first
+type DelistingTasksResponse struct {
+	Tasks []DelistingTask `json:"tasks"`
+	Total int             `json:"total"`
+}
+
+// DependenciesResponse reports the external dependencies, so a quiet system can
+// be told apart from one that cannot see its inputs
+type DependenciesResponse struct {
+	CheckedAt    time.Time            `json:"checked_at"`
+	Dependencies []DependenciesStatus `json:"dependencies"`
+	// Status is "ok", or "degraded" when any dependency is down or degraded
+	Status string `json:"status"`
+}
+
+// ErasureResponse reports the outcome of a right-to-erasure request
+type ErasureResponse struct {
+	Message        string `json:"message"`
+	RowsAnonymized int64  `json:"rows_anonymized"`
+	Status         string `json:"status"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// EventData represents the data payload in a webhook event
+type EventData struct {
+	// DSN action: failed, delayed, delivered, relayed, expanded
+	Action        string `json:"action,omitempty"`
+	AttemptNumber int    `json:"attempt_number"`
+	Domain        string `json:"domain"`
+	EnhancedCode  string `json:"enhanced_code"`
+	Instance      string `json:"instance"`
+	IP            string `json:"ip"`
+	Mechanism     string `json:"mechanism,omitempty"`
+	MessageID     string `json:"message_id"`
+	Mx            string `json:"mx"`
+	Reason        string `json:"reason"`
+	Recipient     string `json:"recipient"`
+	// Host an outgoing authentication was attempted against
+	RemoteHost string `json:"remote_host,omitempty"`
+	SMTPCode   int    `json:"smtp_code"`
+	Username   string `json:"username,omitempty"`
+}
+
+// ExternalSignalRequest is a feed's verdict on an IP
+type ExternalSignalRequest struct {
+	// Confidence between 0 and 1; defaults to 1
+	Confidence float64 `json:"confidence,omitempty"`
+	// Defaults to 7 days after reported_at
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	IP        string     `json:"ip"`
+	Reason    string     `json:"reason,omitempty"`
+	// The feed's ID or URL for the listing
+	Reference string `json:"reference,omitempty"`
+	// Defaults to now
+	ReportedAt *time.Time `json:"reported_at,omitempty"`
+	// malicious, suspicious or clean
+	Verdict string `json:"verdict"`
+}
+
+// ExternalSignalsByIPResponse is what third-party feeds currently say about an
+// IP
+type ExternalSignalsByIPResponse struct {
+	Feeds   map[string]float64 `json:"feeds"`
+	IP      string             `json:"ip"`
+	Score   float64            `json:"score"`
+	Signals []ExternalSignal   `json:"signals"`
+}
+
+// ExternalSignalsRequest is a batch of verdicts from one feed
+type ExternalSignalsRequest struct {
+	Signals []ExternalSignalRequest `json:"signals"`
+}
+
+// ExternalSignalsResponse counts the verdicts of a batch. A verdict is not
+// applied when the feed already reported a later one on the IP.
+type ExternalSignalsResponse struct {
+	Accepted int    `json:"accepted"`
+	Applied  int    `json:"applied"`
+	Feed     string `json:"feed"`
+}
+
+// FailureSimulation represents a simulated SMTP failure for testing
+type FailureSimulation struct {
+	Code   string `json:"code"`
+	Count  int    `json:"count"`
+	Domain string `json:"domain"`
+	Reason string `json:"reason"`
+}
+
+// FailureSimulationPayload represents the payload for simulating failures
+type FailureSimulationPayload struct {
+	Failures  []FailureSimulation `json:"failures"`
+	IP        string              `json:"ip"`
+	TotalSent int                 `json:"total_sent"`
+}
+
+// FaultsResponse lists the active injected faults
+type FaultsResponse struct {
+	Enabled bool     `json:"enabled"`
+	Faults  []Fault  `json:"faults"`
+	Targets []string `json:"targets"`
+}
+
+// FeatureFlagsResponse lists every feature flag
+type FeatureFlagsResponse struct {
+	Flags []Flag `json:"flags"`
+}
+
+// FleetComparisonResponse compares period A with period B; deltas are B minus A
+type FleetComparisonResponse struct {
+	Fleet FleetDelta `json:"fleet"`
+	// IPs holds every IP seen in either period, largest rejection ratio change
+	// first
+	Ips     []IPComparison `json:"ips"`
+	PeriodA ComparePeriod  `json:"period_a"`
+	PeriodB ComparePeriod  `json:"period_b"`
+}
+
+// FleetDelta compares the whole fleet between the periods
+type FleetDelta struct {
+	Failures CountDelta `json:"failures"`
+	Ips      CountDelta `json:"ips"`
+	// RejectionRatio is averaged over every aggregation run of the period
+	RejectionRatio RatioDelta `json:"rejection_ratio"`
+	// Statuses counts the IPs by the status of their last run in the period
+	Statuses map[string]CountDelta `json:"statuses"`
+	// TopCodes holds the most frequent codes of either period, largest change
+	// first
+	TopCodes []CodeDelta `json:"top_codes"`
+}
+
+// HealthResponse represents the health check response
+type HealthResponse struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HeatmapRow is one IP's hourly rejection ratios, averaged over the aggregation
+// runs of each hour
+type HeatmapRow struct {
+	IP string `json:"ip"`
+	// Peak is the highest hourly ratio; rows are sorted by it
+	Peak   float64   `json:"peak"`
+	Ratios []float64 `json:"ratios"`
+}
+
+// IPComparison compares one IP between the periods; the status and top code of
+// a period are empty when the IP has no record in it
+type IPComparison struct {
+	Failures       CountDelta `json:"failures"`
+	IP             string     `json:"ip"`
+	RejectionRatio RatioDelta `json:"rejection_ratio"`
+	StatusA        string     `json:"status_a"`
+	StatusB        string     `json:"status_b"`
+	TopCodeA       string     `json:"top_code_a"`
+	TopCodeB       string     `json:"top_code_b"`
+}
+
+// IPHealthDashboardResponse represents dashboard data
+type IPHealthDashboardResponse struct {
+	BlacklistedIps int `json:"blacklisted_ips"`
+	// Context is the on-call notes and runbook URL of the IPs that have one
+	Context       map[string]IPContext  `json:"context"`
+	HealthyIps    int                   `json:"healthy_ips"`
+	IPDetails     []IPReputationMetrics `json:"ip_details"`
+	QuarantineIps int                   `json:"quarantine_ips"`
+	Timestamp     time.Time             `json:"timestamp"`
+	TotalIps      int                   `json:"total_ips"`
+	WarningIps    int                   `json:"warning_ips"`
+}
+
+// IPRegistryEntry is one IP with the state derived from all subsystems
+type IPRegistryEntry struct {
+	ArchivedAt     *time.Time `json:"archived_at,omitempty"`
+	BlockID        string     `json:"block_id,omitempty"`
+	DNSBLCheckedAt *time.Time `json:"dnsbl_checked_at,omitempty"`
+	DNSBLListed    bool       `json:"dnsbl_listed,omitempty"`
+	DNSBLListings  []string   `json:"dnsbl_listings,omitempty"`
+	IP             string     `json:"ip"`
+	Location       string     `json:"location,omitempty"`
+	// Notes and RunbookURL are the IP's on-call context, see IPContext
+	Notes  string    `json:"notes,omitempty"`
+	Owners []IPOwner `json:"owners"`
+	Pools  []string  `json:"pools"`
+	// Reasons lists the findings that led to State
+	Reasons             []string   `json:"reasons"`
+	RejectionRatio      float64    `json:"rejection_ratio,omitempty"`
+	ReputationStatus    string     `json:"reputation_status,omitempty"`
+	ReputationUpdatedAt *time.Time `json:"reputation_updated_at,omitempty"`
+	ReservationStatus   string     `json:"reservation_status,omitempty"`
+	ReservedBlacklisted bool       `json:"reserved_blacklisted"`
+	ReservedIPID        int        `json:"reserved_ip_id,omitempty"`
+	RunbookURL          string     `json:"runbook_url,omitempty"`
+	State               string     `json:"state"`
+}
+
+// IPRegistryResponse is the unified view of every known IP
+type IPRegistryResponse struct {
+	Ips    []IPRegistryEntry `json:"ips"`
+	States map[string]int    `json:"states"`
+	Total  int               `json:"total"`
+}
+
+// IPReputationExplanationResponse shows which decision rules fired for an IP
+type IPReputationExplanationResponse struct {
+	Explanation Explanation `json:"explanation"`
+	// ExternalLookups are the latest results of external sender reputation sources
+	// (e.g. SenderScore), looked up when the IP was quarantined
+	ExternalLookups []ReputationLookup `json:"external_lookups"`
+	Instance        string             `json:"instance,omitempty"`
+	IP              string             `json:"ip"`
+	StoredAt        time.Time          `json:"stored_at"`
+	// StoredStatus is the status from the last aggregation run; the explanation
+	// re-evaluates the rules over the current window
+	StoredStatus string `json:"stored_status"`
+	// Windows are the longer evaluation windows from the last aggregation run; the
+	// most severe of their statuses and the explanation's applies
+	Windows []IPWindowMetrics `json:"windows"`
+}
+
+// IPReputationResponse represents the API response for IP reputation
+type IPReputationResponse struct {
+	Instances        []InstanceRejections `json:"instances"`
+	IP               string               `json:"ip"`
+	LatestDNSBLCheck DNSBLCheck           `json:"latest_dnsbl_check"`
+	Metrics          IPReputationMetrics  `json:"metrics"`
+	Notes            []IPNote             `json:"notes"`
+	RecentActions    []IPAction           `json:"recent_actions"`
+	Recommendations  []string             `json:"recommendations"`
+	// RecommendedActions are the recommendations with their texts and runbook
+	// links from the recommendation catalog
+	RecommendedActions []ReputationRecommendation `json:"recommended_actions"`
+	Status             string                     `json:"status"`
+	// in the negotiated language
+	Summary string `json:"summary"`
+	// failed TLS negotiations from recent TLS reports
+	TLSFailures []TLSFailureSummary `json:"tls_failures"`
+	// longer evaluation windows from the last run
+	Windows []IPWindowMetrics `json:"windows"`
+}
+
+// ITSMRuleRequest represents the request body for creating or replacing an ITSM
+// rule
+type ITSMRuleRequest struct {
+	// Defaults to true
+	Enabled bool `json:"enabled"`
+	// ip_blacklisted or delisting_opened
+	Event string `json:"event"`
+	// Omit to match any pool
+	PoolID int `json:"pool_id"`
+	// Jira project key or ServiceNow assignment group
+	Project string `json:"project"`
+	// jira or servicenow
+	System string `json:"system"`
+}
+
+// ITSMTicketsResponse lists an IP's tickets, newest first
+type ITSMTicketsResponse struct {
+	Tickets []ITSMTicket `json:"tickets"`
+	Total   int          `json:"total"`
+}
+
+// ITSMWebhookResponse tells the system whether its status report matched a
+// ticket
+type ITSMWebhookResponse struct {
+	// updated or ignored
+	Status string      `json:"status"`
+	Ticket *ITSMTicket `json:"ticket,omitempty"`
+}
+
+// LiveStatsResponse is an IP's failure counts over the last minutes, computed
+// on request instead of waiting for the next aggregation run
+type LiveStatsResponse struct {
+	AggregatedAt *time.Time `json:"aggregated_at,omitempty"`
+	// AggregatedStatus and AggregatedAt are the last aggregation's result, for
+	// comparison; empty before the IP was first aggregated
+	AggregatedStatus string            `json:"aggregated_status,omitempty"`
+	ComputedAt       time.Time         `json:"computed_at"`
+	IP               string            `json:"ip"`
+	Note             string            `json:"note"`
+	Provisional      bool              `json:"provisional"`
+	Windows          []LiveStatsWindow `json:"windows"`
+}
+
+// LiveStatsWindow is the failure count of one trailing window
+type LiveStatsWindow struct {
+	HardRejections      int        `json:"hard_rejections"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	Minutes             int        `json:"minutes"`
+	Rejections          int        `json:"rejections"`
+	RejectionsPerMinute float64    `json:"rejections_per_minute"`
+	UniqueDomains       int        `json:"unique_domains"`
+	Window              string     `json:"window"`
+}
+
+// LoginRequest represents the request body for logging in
+type LoginRequest struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// MigrateIPRequest optionally says why an IP is renumbered
+type MigrateIPRequest struct {
+	Comment string `json:"comment"`
+}
+
+// MitigationDataResponse is what a provider's mitigation form asks about an IP,
+// as raw values and pre-formatted answers
+type MitigationDataResponse struct {
+	// BlockReferences are the block list identifiers the rejections quote, most
+	// often quoted first
+	BlockReferences []string       `json:"block_references"`
+	ErrorCodes      map[string]int `json:"error_codes"`
+	// EstimatedDailyVolume is mail sent to the provider per day, estimated from
+	// the rejections at an assumed 5% failure rate
+	EstimatedDailyVolume int               `json:"estimated_daily_volume"`
+	Fields               []MitigationField `json:"fields"`
+	FirstOccurrence      time.Time         `json:"first_occurrence"`
+	FormURL              string            `json:"form_url"`
+	IP                   string            `json:"ip"`
+	LastOccurrence       time.Time         `json:"last_occurrence"`
+	Provider             string            `json:"provider"`
+	ProviderLabel        string            `json:"provider_label"`
+	RecipientDomains     []string          `json:"recipient_domains"`
+	// RejectedMessages are the failures to the provider in the window
+	RejectedMessages int                `json:"rejected_messages"`
+	Samples          []MitigationSample `json:"samples"`
+	// Text is every field as "label: value" lines, ready to paste
+	Text        string    `json:"text"`
+	WindowEnd   time.Time `json:"window_end"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+// MitigationField is one question of a provider's mitigation form with the
+// answer to paste
+type MitigationField struct {
+	Label string `json:"label"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// MitigationSample is a rejection to quote as an NDR sample
+type MitigationSample struct {
+	EnhancedCode string `json:"enhanced_code,omitempty"`
+	// Formatted is the sample as quoted in the form
+	Formatted       string    `json:"formatted"`
+	MxServer        string    `json:"mx_server,omitempty"`
+	Reason          string    `json:"reason"`
+	RecipientDomain string    `json:"recipient_domain"`
+	SMTPCode        int       `json:"smtp_code"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// NotificationRouteRequest represents the request body for creating or
+// replacing a notification route
+type NotificationRouteRequest struct {
+	// Defaults to true
+	Enabled bool `json:"enabled"`
+	// slack, email, webhook or pagerduty
+	Notifier string `json:"notifier"`
+	// Omit to match any pool
+	PoolID int `json:"pool_id"`
+	// info, warning or critical; empty matches any
+	Severity string `json:"severity"`
+	// Slack channel, email address, URL or PagerDuty routing key
+	Target string `json:"target"`
+	// Omit to match any team
+	TeamID int `json:"team_id"`
+}
+
+// OpenDelistingTaskRequest opens a delisting task with a provider
+type OpenDelistingTaskRequest struct {
+	Comment  string `json:"comment"`
+	Provider string `json:"provider"`
+	// Window is how far back rejections are searched for block references
+	Window string `json:"window,omitempty"`
+}
+
+// PoolCapacityForecast is the healthy member count of a pool, observed and
+// projected for the next days
+type PoolCapacityForecast struct {
+	CurrentHealthy int                     `json:"current_healthy"`
+	Forecast       []CapacityForecastPoint `json:"forecast"`
+	History        []CapacityForecastPoint `json:"history"`
+	Pool           string                  `json:"pool"`
+	PoolID         int                     `json:"pool_id"`
+	TotalIps       int                     `json:"total_ips"`
+	// TrendPerDay is the fitted change of healthy members per day
+	TrendPerDay float64 `json:"trend_per_day"`
+}
+
+// PoolIPRequest represents the request body for adding an IP to a pool
+type PoolIPRequest struct {
+	IP string `json:"ip"`
+}
+
+// RateLimitResponse is a rate cap set on an IP. Pushed tells whether Stalwart's
+// throttling hook took it; hooks polling the sending policy see it either way.
+type RateLimitResponse struct {
+	Pushed    bool        `json:"pushed"`
+	RateLimit IPRateLimit `json:"rate_limit"`
+}
+
+// RatioDelta is a ratio in period A and B and how it changed; a side is null
+// when the period has no aggregation run
+type RatioDelta struct {
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+}
+
+// ReasonFamiliesResponse lists the reason families of a window, most failures
+// first
+type ReasonFamiliesResponse struct {
+	Failures int            `json:"failures"`
+	Families []ReasonFamily `json:"families"`
+	Window   string         `json:"window"`
+}
+
+// ReasonFamily summarizes the failures whose reasons share a template
+type ReasonFamily struct {
+	// Example is the newest reason of the family, with the recipient address
+	// redacted like in the failures
+	Example   string    `json:"example"`
+	Failures  int       `json:"failures"`
+	Family    string    `json:"family"`
+	FirstSeen time.Time `json:"first_seen"`
+	// IPs holds the sending IPs that saw the family, most failures first
+	Ips      []string  `json:"ips"`
+	LastSeen time.Time `json:"last_seen"`
+	// Share is the family's fraction of all failures in the window
+	Share float64 `json:"share"`
+}
+
+// RebuildReputationRequest selects the IPs and time range to rebuild
+type RebuildReputationRequest struct {
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+	// DryRun reports what would change without storing anything
+	DryRun bool `json:"dry_run,omitempty"`
+	// From defaults to a day before to
+	From *time.Time `json:"from,omitempty"`
+	// IPs to rebuild; empty rebuilds every IP with failures in the range
+	Ips []string `json:"ips,omitempty"`
+	// Step is the spacing of the rebuilt history records, e.g. "15m"; default 1h
+	Step string `json:"step,omitempty"`
+	// To defaults to now; only a range ending now updates current metrics
+	To *time.Time `json:"to,omitempty"`
+}
+
+// RecommendationMappingRequest represents the request body for creating a
+// recommendation mapping
+type RecommendationMappingRequest struct {
+	// Catalog entry to recommend
+	Action string `json:"action"`
+	// Optional; limits the mapping to IPs with this issue type
+	IssueType string `json:"issue_type"`
+	// Order among the status' actions
+	Position int `json:"position"`
+	// healthy, warning, quarantine or blacklisted
+	Status string `json:"status"`
+}
+
+// RecommendationRequest represents the request body for saving a recommendation
+// catalog entry
+type RecommendationRequest struct {
+	// http(s) URL of the runbook; empty for none
+	Link string `json:"link"`
+	// Text per language, e.g. {"en": "...", "de": "..."}; omitted languages use
+	// the built-in text
+	Texts map[string]string `json:"texts"`
+}
+
+// RefreshRequest represents the request body for refreshing or ending a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RejectionHeatmapResponse is a matrix of hourly rejection ratios. Ratios[i] of
+// a row belongs to Hours[i]; hours without an aggregation run are null.
+type RejectionHeatmapResponse struct {
+	From  time.Time    `json:"from"`
+	Hours []time.Time  `json:"hours"`
+	Rows  []HeatmapRow `json:"rows"`
+	To    time.Time    `json:"to"`
+}
+
+// RemediationModeResponse is the mode automated actions run in and the revision
+// that set it. Revision is nil while the config default applies.
+type RemediationModeResponse struct {
+	Mode     string         `json:"mode"`
+	Revision ConfigRevision `json:"revision"`
+}
+
+// ReputationConfigResponse is the active reputation config and the revision it
+// came from. Revision is nil while the defaults are in use.
+type ReputationConfigResponse struct {
+	Config   ReputationConfig `json:"config"`
+	Revision ConfigRevision   `json:"revision"`
+}
+
+// ReputationSimulationRequest is a what-if input for the decision engine.
+// Config fields that are left out keep their active values.
+type ReputationSimulationRequest struct {
+	Config map[string]interface{} `json:"config"`
+	Health IPHealthCheck          `json:"health"`
+}
+
+// ReputationSimulationResponse compares the candidate config with the active
+// config for the same metrics
+type ReputationSimulationResponse struct {
+	BaselineRule   string      `json:"baseline_rule"`
+	BaselineStatus string      `json:"baseline_status"`
+	DecidingRule   string      `json:"deciding_rule"`
+	Explanation    Explanation `json:"explanation"`
+	Status         string      `json:"status"`
+	StatusChanged  bool        `json:"status_changed"`
+}
+
+// ReserveForecast estimates when the clean reserved IPs run out if IPs keep
+// moving into quarantine or blacklisted at the current rate
+type ReserveForecast struct {
+	Available          int        `json:"available"`
+	BurnPerDay         float64    `json:"burn_per_day"`
+	BurnedIps          int        `json:"burned_ips"`
+	DaysUntilExhausted float64    `json:"days_until_exhausted,omitempty"`
+	ExhaustedAt        *time.Time `json:"exhausted_at,omitempty"`
+}
+
+// ResolveRecommendationsResponse lists what an IP would be recommended
+type ResolveRecommendationsResponse struct {
+	IssueType       string                     `json:"issue_type,omitempty"`
+	Recommendations []ReputationRecommendation `json:"recommendations"`
+	Status          string                     `json:"status"`
+}
+
+// ResolveRoutesResponse lists where an alert would be sent
+type ResolveRoutesResponse struct {
+	IP       string   `json:"ip"`
+	Severity string   `json:"severity"`
+	Targets  []Target `json:"targets"`
+}
+
+// RestoreResponse counts the rows restored per table
+type RestoreResponse struct {
+	BackupAt   time.Time      `json:"backup_at"`
+	Restored   map[string]int `json:"restored"`
+	RestoredAt time.Time      `json:"restored_at"`
+}
+
+// RollbackRequest optionally explains a rollback
+type RollbackRequest struct {
+	Comment string `json:"comment"`
+}
+
+// SCIMEmail is an entry of a SCIM user's emails
+type SCIMEmail struct {
+	Primary bool   `json:"primary,omitempty"`
+	Value   string `json:"value"`
+}
+
+// SCIMError is the SCIM error response
+type SCIMError struct {
+	Detail   string   `json:"detail"`
+	Schemas  []string `json:"schemas"`
+	ScimType string   `json:"scimType,omitempty"`
+	Status   string   `json:"status"`
+}
+
+// SCIMGroup is a SCIM group resource mapped onto a team
+type SCIMGroup struct {
+	DisplayName string       `json:"displayName"`
+	ID          string       `json:"id,omitempty"`
+	Members     []SCIMMember `json:"members"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+	Schemas     []string     `json:"schemas"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Resources    interface{} `json:"Resources"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Schemas      []string    `json:"schemas"`
+	StartIndex   int         `json:"startIndex"`
+	TotalResults int         `json:"totalResults"`
+}
+
+// SCIMMember references a user from a group
+type SCIMMember struct {
+	Value string `json:"value"`
+}
+
+// SCIMMeta is the resource metadata
+type SCIMMeta struct {
+	Created      time.Time  `json:"created"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+	ResourceType string     `json:"resourceType"`
+}
+
+// SCIMPatchOp is one PATCH operation
+type SCIMPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH body
+type SCIMPatchRequest struct {
+	Operations []SCIMPatchOp `json:"Operations"`
+	Schemas    []string      `json:"schemas"`
+}
+
+// SCIMUser is a SCIM user resource mapped onto a database user
+type SCIMUser struct {
+	Active bool        `json:"active,omitempty"`
+	Emails []SCIMEmail `json:"emails,omitempty"`
+	ID     string      `json:"id,omitempty"`
+	Meta   *SCIMMeta   `json:"meta,omitempty"`
+	// Password is write-only and never returned
+	Password string   `json:"password,omitempty"`
+	Schemas  []string `json:"schemas"`
+	UserName string   `json:"userName"`
+}
+
+// SLOMeasurementsResponse is the measurements of one objective
+type SLOMeasurementsResponse struct {
+	Measurements []SLOMeasurement `json:"measurements"`
+	Objective    Objective        `json:"objective"`
+}
+
+// SLOStatusResponse is the status of every fleet objective
+type SLOStatusResponse struct {
+	// Breached counts the objectives whose error budget is spent
+	Breached    int         `json:"breached"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Objectives  []SLOStatus `json:"objectives"`
+}
+
+// SendingPolicyResponse tells an MTA how to send from an IP: its reputation
+// status and, per destination provider, whether the provider is throttling it,
+// how long to back off and how many messages an hour to send. A provider
+// missing from Throttles has not rate-limited the IP lately, and one missing
+// from Rates gets the "*" rate. RateLimit, when set, caps the IP's total
+// outbound rate whatever its rates are.
+type SendingPolicyResponse struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	IP          string          `json:"ip"`
+	RateLimit   *IPRateLimit    `json:"rate_limit,omitempty"`
+	Rates       []SendRate      `json:"rates"`
+	Status      string          `json:"status"`
+	Throttles   []ThrottleState `json:"throttles"`
+}
+
+// SendingVolumeRequest is the number of messages an IP sent over a period
+type SendingVolumeRequest struct {
+	Messages    int       `json:"messages"`
+	PeriodEnd   time.Time `json:"period_end"`
+	PeriodStart time.Time `json:"period_start"`
+	SendingIP   string    `json:"sending_ip"`
+}
+
+// SendingVolumesRequest is a batch of volumes from one sending system
+type SendingVolumesRequest struct {
+	// Sending system reporting the volumes
+	Source  string                 `json:"source"`
+	Volumes []SendingVolumeRequest `json:"volumes"`
+}
+
+// SendingVolumesResponse counts the volumes stored
+type SendingVolumesResponse struct {
+	Accepted int    `json:"accepted"`
+	Source   string `json:"source"`
+}
+
+// SetFaultRequest injects latency and errors into calls to a dependency
+type SetFaultRequest struct {
+	// Duration is how long the fault lasts, e.g. "15m"; 10m by default and at most
+	// 1h
+	Duration  string  `json:"duration,omitempty"`
+	ErrorRate float64 `json:"error_rate"`
+	LatencyMs int     `json:"latency_ms"`
+	// StatusCode is the response of failed HTTP calls; 500 by default
+	StatusCode int `json:"status_code,omitempty"`
+}
+
+// SetIPContextRequest sets what on-call should know about an IP. Both fields
+// replace the stored ones; at least one is required.
+type SetIPContextRequest struct {
+	Notes      string `json:"notes"`
+	RunbookURL string `json:"runbook_url"`
+}
+
+// SetRateLimitRequest caps an IP's outbound rate for a while
+type SetRateLimitRequest struct {
+	MessagesPerMinute int    `json:"messages_per_minute"`
+	Reason            string `json:"reason,omitempty"`
+	// TTL is how long the cap holds, e.g. 30m or 6h; at most 168h
+	TTL string `json:"ttl"`
+}
+
+// ShadowConfigResponse is the registered shadow config, how it differs from the
+// live one and how it classified the fleet on the last aggregation run
+type ShadowConfigResponse struct {
+	Config   ReputationConfig        `json:"config"`
+	Diff     map[string]ConfigChange `json:"diff"`
+	Report   ShadowReport            `json:"report"`
+	Revision ConfigRevision          `json:"revision"`
+}
+
+// SpamtrapHitRequest represents a spamtrap hit reported by a trap network or an
+// internal trap
+type SpamtrapHitRequest struct {
+	// Defaults to now
+	HitAt     *time.Time `json:"hit_at,omitempty"`
+	MessageID string     `json:"message_id,omitempty"`
+	// The reporter's ID; re-sent reports are stored once
+	ReportID  string `json:"report_id,omitempty"`
+	SendingIP string `json:"sending_ip"`
+	// Trap network or internal trap
+	Source      string `json:"source"`
+	TrapAddress string `json:"trap_address,omitempty"`
+	// pristine, recycled, typo or internal; defaults to unknown
+	TrapType string `json:"trap_type,omitempty"`
+}
+
+// SpamtrapHitResponse is the stored hit and what it did to the IP
+type SpamtrapHitResponse struct {
+	// Duplicate is true when the source already reported this report ID; nothing
+	// was stored or changed
+	Duplicate bool             `json:"duplicate"`
+	Hit       SpamtrapHit      `json:"hit"`
+	Outcome   *SpamtrapOutcome `json:"outcome,omitempty"`
+}
+
+// SwapIPRequest swaps an IP in use for a clean reserved IP
+type SwapIPRequest struct {
+	DryRun bool   `json:"dry_run"`
+	Reason string `json:"reason"`
+}
+
+// TLSPolicyResponse is the MTA-STS and DANE policy of a destination domain
+type TLSPolicyResponse struct {
+	// DANEHosts are the MX hosts with DNSSEC-validated TLSA records
+	DaneHosts []string `json:"dane_hosts,omitempty"`
+	Domain    string   `json:"domain"`
+	// Error is the first lookup that failed; the policy may be partial
+	Error     string    `json:"error,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Requirement is what failures to the domain are annotated with: dane,
+	// mta-sts-enforce, mta-sts-testing, none, or empty if unknown
+	Requirement string `json:"requirement"`
+	RequiresTLS bool   `json:"requires_tls"`
+	StsID       string `json:"sts_id,omitempty"`
+	StsMaxAge   int    `json:"sts_max_age,omitempty"`
+	// STSMode is the mode of the domain's MTA-STS policy, empty without one
+	StsMode string   `json:"sts_mode,omitempty"`
+	StsMx   []string `json:"sts_mx,omitempty"`
+}
+
+// TLSReportResponse is the stored TLS report
+type TLSReportResponse struct {
+	// Duplicate is true when the organization already sent this report; nothing
+	// was stored
+	Duplicate bool      `json:"duplicate"`
+	Report    TLSReport `json:"report"`
+}
+
+// TeamMemberRequest represents the request body for adding a team member
+type TeamMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// TestCase represents a single test scenario
+type TestCase struct {
+	Category       string              `json:"category"`
+	Description    string              `json:"description"`
+	ExpectedStatus string              `json:"expected_status"`
+	Failures       []FailureSimulation `json:"failures"`
+	ID             string              `json:"id"`
+	IP             string              `json:"ip"`
+	Name           string              `json:"name"`
+	TotalSent      int                 `json:"total_sent"`
+}
+
+// TestResult represents the result of running a test case
+type TestResult struct {
+	ActualStatus    string    `json:"actual_status"`
+	ErrorMessage    string    `json:"error_message,omitempty"`
+	ExecutionTimeMs float64   `json:"execution_time_ms"`
+	ExpectedStatus  string    `json:"expected_status"`
+	FailureCount    int       `json:"failure_count"`
+	Passed          bool      `json:"passed"`
+	RejectionRatio  float64   `json:"rejection_ratio"`
+	TestID          string    `json:"test_id"`
+	TestName        string    `json:"test_name"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// TestSuiteResult represents the results of running all tests
+type TestSuiteResult struct {
+	ExecutionTimeMs float64      `json:"execution_time_ms"`
+	FailedTests     int          `json:"failed_tests"`
+	PassedTests     int          `json:"passed_tests"`
+	Results         []TestResult `json:"results"`
+	Timestamp       time.Time    `json:"timestamp"`
+	TotalTests      int          `json:"total_tests"`
+}
+
+// ThrottledIPsResponse lists every IP a provider is currently throttling
+type ThrottledIPsResponse struct {
+	Count       int             `json:"count"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Throttles   []ThrottleState `json:"throttles"`
+}
+
+// ToggleFeatureFlagRequest turns a flag on or off
+type ToggleFeatureFlagRequest struct {
+	Comment string `json:"comment"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ToggleFeatureFlagResponse is the flag after the toggle and its audit record
+type ToggleFeatureFlagResponse struct {
+	Change FeatureFlagChange `json:"change"`
+	Flag   Flag              `json:"flag"`
+}
+
+// TokenResponse carries a new access token and its refresh token
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	User         User   `json:"user"`
+}
+
+// UpdateDelistingTaskRequest moves a delisting task to a new status
+type UpdateDelistingTaskRequest struct {
+	Comment string `json:"comment"`
+	Status  string `json:"status"`
+}
+
+// UpdateRemediationModeRequest switches the remediation mode
+type UpdateRemediationModeRequest struct {
+	Comment string `json:"comment"`
+	Mode    string `json:"mode"`
+}
+
+// UpdateReputationConfigRequest changes thresholds. Fields left out of config
+// keep their active values.
+type UpdateReputationConfigRequest struct {
+	Comment string                 `json:"comment"`
+	Config  map[string]interface{} `json:"config"`
+}
+
+// UpdateReputationFeedRequest changes the fields that are set
+type UpdateReputationFeedRequest struct {
+	Description string  `json:"description,omitempty"`
+	Enabled     bool    `json:"enabled,omitempty"`
+	Weight      float64 `json:"weight,omitempty"`
+}
+
+// UpdateUserRequest represents the request body for updating a user. Omitted
+// fields are left unchanged.
+type UpdateUserRequest struct {
+	Email string `json:"email,omitempty"`
+	// Password replaces the dashboard password and ends existing sessions
+	Password string `json:"password,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// UpdateUserRoleRequest represents the request body for changing a user's role
+type UpdateUserRoleRequest struct {
+	// Role is read-only, operator or admin
+	Role string `json:"role"`
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Total  int    `json:"total"`
+	Users  []User `json:"users"`
+}
+
+// WebhookEvent represents a Stalwart webhook event
+type WebhookEvent struct {
+	CreatedAt string    `json:"createdAt"`
+	Data      EventData `json:"data"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+}
+
+// WebhookEventResult is the outcome of one event of a webhook payload
+type WebhookEventResult struct {
+	Error   string `json:"error,omitempty"`
+	EventID string `json:"event_id"`
+	Status  string `json:"status"`
+}
+
+// WebhookIngestResponse summarizes a processed webhook payload. Results are
+// only included when the request asked for ?detailed=true.
+type WebhookIngestResponse struct {
+	Failed    int                  `json:"failed"`
+	Processed int                  `json:"processed"`
+	Results   []WebhookEventResult `json:"results,omitempty"`
+	Status    string               `json:"status"`
+	Total     int                  `json:"total"`
+}
+
+// WebhookPayload represents the complete webhook payload from Stalwart. Version
+// selects the schema of the events; events may override it with their own
+// version, and unversioned events are detected by their keys.
+type WebhookPayload struct {
+	Events  []WebhookEvent `json:"events"`
+	Version string         `json:"version,omitempty"`
+}
+
+// Principal is the authenticated caller of a request: an operator with a
+// dashboard session or a machine using an API key
+type Principal struct {
+	KeyName  string `json:"key_name,omitempty"`
+	Kind     string `json:"kind"`
+	Role     string `json:"role"`
+	UserID   int    `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// APIKey is a machine key managed through the API. Only the SHA-256 of the key
+// is stored; Prefix is its first characters so operators can tell keys apart.
+type APIKey struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by"`
+	ID         int        `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Role       string     `json:"role"`
+}
+
+// AbuseComplaint is a recipient's complaint about a message, parsed from an ARF
+// feedback report
+type AbuseComplaint struct {
+	ArrivedAt         time.Time `json:"arrived_at"`
+	CampaignID        string    `json:"campaign_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	FeedbackType      string    `json:"feedback_type"`
+	ID                int       `json:"id"`
+	OriginalMailFrom  string    `json:"original_mail_from,omitempty"`
+	OriginalMessageID string    `json:"original_message_id,omitempty"`
+	RecipientDomain   string    `json:"recipient_domain,omitempty"`
+	ReportID          string    `json:"report_id,omitempty"`
+	ReportedBy        string    `json:"reported_by,omitempty"`
+	ReportedDomain    string    `json:"reported_domain,omitempty"`
+	Reporter          string    `json:"reporter"`
+	SendingIP         string    `json:"sending_ip"`
+	UserAgent         string    `json:"user_agent,omitempty"`
+}
+
+// AggregationExclusion is a CIDR block whose IPs are not sending IPs, e.g.
+// monitoring probes, corporate egress or test addresses. Aggregation does not
+// discover its IPs and dashboards hide them unless asked; their failures are
+// still stored.
+type AggregationExclusion struct {
+	Cidr      string    `json:"cidr"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	ID        int       `json:"id"`
+	Reason    string    `json:"reason"`
+}
+
+// ArchivedIP is a decommissioned IP. Its history is kept, but aggregation and
+// alerting skip it and dashboards hide it unless asked.
+type ArchivedIP struct {
+	ArchivedAt time.Time `json:"archived_at"`
+	ArchivedBy string    `json:"archived_by"`
+	IP         string    `json:"ip"`
+	Reason     string    `json:"reason"`
+}
+
+// AuditCheck is the outcome of one self-audit check
+type AuditCheck struct {
+	// Count is the number of inconsistencies found
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+	// Exceeded is set when Count is above Threshold, which alerts
+	Exceeded bool   `json:"exceeded"`
+	Name     string `json:"name"`
+	// Samples are up to the configured number of affected IPs
+	Samples []string `json:"samples"`
+	// Skipped is set for checks that cannot run, e.g. without IONOS
+	Skipped   bool `json:"skipped,omitempty"`
+	Threshold int  `json:"threshold"`
+}
+
+// AuditReport is one run of the self-audit
+type AuditReport struct {
+	Checks []AuditCheck `json:"checks"`
+	// Exceeded is the number of checks above their threshold
+	Exceeded   int       `json:"exceeded"`
+	FinishedAt time.Time `json:"finished_at"`
+	ID         int       `json:"id"`
+	// Issues is the sum of the inconsistencies of all checks
+	Issues    int       `json:"issues"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Backup is a consistent snapshot of the reputation state: the rows of every
+// backup table as JSON objects, column by column
+type Backup struct {
+	CreatedAt time.Time              `json:"created_at"`
+	Format    string                 `json:"format"`
+	Tables    map[string]interface{} `json:"tables"`
+	Version   int                    `json:"version"`
+}
+
+// BulkActionFilter selects the IPs of a bulk action. Empty fields match any IP;
+// set fields must all match.
+type BulkActionFilter struct {
+	Cidr   string `json:"cidr,omitempty"`
+	Pool   string `json:"pool,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// ConfigRevision is one version of a runtime-editable config
+type ConfigRevision struct {
+	Author    string                 `json:"author"`
+	Comment   string                 `json:"comment,omitempty"`
+	Config    map[string]interface{} `json:"config"`
+	CreatedAt time.Time              `json:"created_at"`
+	// Diff maps changed fields to {"from": old, "to": new}
+	Diff       map[string]interface{} `json:"diff"`
+	ID         int                    `json:"id"`
+	RollbackOf int                    `json:"rollback_of,omitempty"`
+	Scope      string                 `json:"scope"`
+}
+
+// DNSBLCheck represents a DNSBL check result
+type DNSBLCheck struct {
+	CheckDurationMs int                    `json:"check_duration_ms"`
+	CheckedAt       time.Time              `json:"checked_at"`
+	ID              int                    `json:"id"`
+	IP              string                 `json:"ip"`
+	Listed          bool                   `json:"listed"`
+	Listings        []string               `json:"listings"`
+	Metadata        map[string]interface{} `json:"metadata"`
+}
+
+// DelistingTask is a delisting or mitigation request opened with a provider for
+// an IP
+type DelistingTask struct {
+	// BlockReferences are the block list identifiers the IP's rejections by the
+	// provider quoted when the task was opened, to cite in the request
+	BlockReferences []string   `json:"block_references"`
+	Comment         string     `json:"comment"`
+	ID              int        `json:"id"`
+	IP              string     `json:"ip"`
+	OpenedAt        time.Time  `json:"opened_at"`
+	OpenedBy        string     `json:"opened_by"`
+	Provider        string     `json:"provider"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	Status          string     `json:"status"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ExternalSignal is the latest verdict of a feed on an IP
+type ExternalSignal struct {
+	Confidence float64   `json:"confidence"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	FeedID     int       `json:"feed_id"`
+	FeedName   string    `json:"feed_name,omitempty"`
+	FeedWeight float64   `json:"feed_weight"`
+	ID         int       `json:"id"`
+	IP         string    `json:"ip"`
+	Reason     string    `json:"reason,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Verdict    string    `json:"verdict"`
+}
+
+// FeatureFlagChange is one audited toggle of a flag
+type FeatureFlagChange struct {
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Enabled   bool      `json:"enabled"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	// Previous is the effective value before the toggle
+	Previous bool `json:"previous"`
+}
+
+// IPAction represents an action taken on an IP
+type IPAction struct {
+	Action         string                 `json:"action"`
+	Comments       []IPActionComment      `json:"comments,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	ID             int                    `json:"id"`
+	IP             string                 `json:"ip"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	NewStatus      string                 `json:"new_status"`
+	PreviousStatus string                 `json:"previous_status"`
+	Reason         string                 `json:"reason"`
+	TriggeredBy    string                 `json:"triggered_by"`
+}
+
+// IPActionComment is an operator's annotation of an IP action
+type IPActionComment struct {
+	ActionID  int       `json:"action_id"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+}
+
+// IPContext is what on-call engineers should know about an IP: free-form notes,
+// e.g. "serves customer X's transactional mail", and the runbook to follow when
+// it alerts
+type IPContext struct {
+	IP         string    `json:"ip"`
+	Notes      string    `json:"notes"`
+	RunbookURL string    `json:"runbook_url"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UpdatedBy  string    `json:"updated_by"`
+}
+
+// IPHistoryRecord is the outcome of one aggregation run for an IP
+type IPHistoryRecord struct {
+	RecordedAt     time.Time `json:"recorded_at"`
+	RejectionRatio float64   `json:"rejection_ratio"`
+	Status         string    `json:"status"`
+	TotalRejected  int       `json:"total_rejected"`
+	TotalSent      int       `json:"total_sent"`
+}
+
+// IPMigration is the outcome of moving an IP's history to a new address
+type IPMigration struct {
+	AuditActionID int       `json:"audit_action_id"`
+	Comment       string    `json:"comment,omitempty"`
+	From          string    `json:"from"`
+	MigratedAt    time.Time `json:"migrated_at"`
+	MigratedBy    string    `json:"migrated_by"`
+	// Moved counts the rows re-keyed to the new address per table
+	Moved map[string]int64 `json:"moved"`
+	// Superseded counts derived rows of the old address dropped because the new
+	// one already has its own, e.g. current metrics
+	Superseded map[string]int64 `json:"superseded,omitempty"`
+	To         string           `json:"to"`
+}
+
+// IPNote is a free-form operator note on an IP
+type IPNote struct {
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+	IP        string    `json:"ip"`
+	Note      string    `json:"note"`
+}
+
+// IPOwner is a user or team owning an IP, directly or through a pool
+type IPOwner struct {
+	OwnerID   int    `json:"owner_id"`
+	OwnerName string `json:"owner_name"`
+	OwnerType string `json:"owner_type"`
+	// Via is the pool name when ownership comes through a pool
+	Via string `json:"via,omitempty"`
+}
+
+// IPPool is a named group of sending IPs
+type IPPool struct {
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	ID          int       `json:"id"`
+	Ips         []string  `json:"ips"`
+	Name        string    `json:"name"`
+}
+
+// IPRateLimit is an explicit outbound rate cap on an IP. It holds until
+// ExpiresAt, when the IP goes back to its recommended send rates.
+type IPRateLimit struct {
+	ExpiresAt         time.Time `json:"expires_at"`
+	IP                string    `json:"ip"`
+	MessagesPerMinute int       `json:"messages_per_minute"`
+	Reason            string    `json:"reason,omitempty"`
+	SetAt             time.Time `json:"set_at"`
+	SetBy             string    `json:"set_by"`
+}
+
+// IPReputationMetrics represents aggregated reputation metrics for an IP
+type IPReputationMetrics struct {
+	DistinctRejectionReasons map[string]int         `json:"distinct_rejection_reasons"`
+	ID                       int                    `json:"id"`
+	IP                       string                 `json:"ip"`
+	LastUpdated              time.Time              `json:"last_updated"`
+	MajorProvidersRejecting  []string               `json:"major_providers_rejecting"`
+	Metadata                 map[string]interface{} `json:"metadata"`
+	RejectionRatio           float64                `json:"rejection_ratio"`
+	Status                   string                 `json:"status"`
+	TotalRejected            int                    `json:"total_rejected"`
+	TotalSent                int                    `json:"total_sent"`
+	UniqueDomainsRejected    int                    `json:"unique_domains_rejected"`
+	WindowEnd                time.Time              `json:"window_end"`
+	WindowStart              time.Time              `json:"window_start"`
+}
+
+// IPWindowMetrics are the metrics of an IP over one evaluation window. Status
+// is what the window supports on its own, before it is combined with the other
+// windows.
+type IPWindowMetrics struct {
+	IP                    string    `json:"ip"`
+	LastUpdated           time.Time `json:"last_updated"`
+	RejectionRatio        float64   `json:"rejection_ratio"`
+	Status                string    `json:"status"`
+	TotalRejected         int       `json:"total_rejected"`
+	TotalSent             int       `json:"total_sent"`
+	UniqueDomainsRejected int       `json:"unique_domains_rejected"`
+	WindowEnd             time.Time `json:"window_end"`
+	WindowMinutes         int       `json:"window_minutes"`
+	WindowName            string    `json:"window_name"`
+	WindowStart           time.Time `json:"window_start"`
+}
+
+// ITSMRule opens a ticket in a Jira project or ServiceNow assignment group when
+// an event happens to an IP. A nil PoolID matches any IP.
+type ITSMRule struct {
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Enabled   bool      `json:"enabled"`
+	Event     string    `json:"event"`
+	ID        int       `json:"id"`
+	PoolID    int       `json:"pool_id,omitempty"`
+	PoolName  string    `json:"pool_name,omitempty"`
+	Project   string    `json:"project"`
+	System    string    `json:"system"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ITSMTicket is a ticket a rule opened, with the status last reported by its
+// system
+type ITSMTicket struct {
+	CreatedAt       time.Time `json:"created_at"`
+	DelistingTaskID int       `json:"delisting_task_id,omitempty"`
+	Event           string    `json:"event"`
+	ExternalID      string    `json:"external_id"`
+	ID              int       `json:"id"`
+	IP              string    `json:"ip"`
+	// Key is the Jira issue key or ServiceNow number shown to people; ExternalID
+	// the issue ID or sys_id the API addresses it by
+	Key       string    `json:"key"`
+	Resolved  bool      `json:"resolved"`
+	RuleID    int       `json:"rule_id,omitempty"`
+	Status    string    `json:"status"`
+	System    string    `json:"system"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+}
+
+// InstanceRejections summarizes the failures one MTA instance reported for an
+// IP
+type InstanceRejections struct {
+	Instance      string    `json:"instance"`
+	LastSeen      time.Time `json:"last_seen"`
+	TotalRejected int       `json:"total_rejected"`
+	UniqueDomains int       `json:"unique_domains"`
+}
+
+// Job is a background task started through the API
+type Job struct {
+	CallbackStatus string     `json:"callback_status,omitempty"`
+	CallbackURL    string     `json:"callback_url,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	ID             string     `json:"id"`
+	// Progress is reported by jobs that work through many items
+	Progress *JobProgress `json:"progress,omitempty"`
+	// Result is the job's output once it succeeded
+	Result    map[string]interface{} `json:"result,omitempty"`
+	StartedAt *time.Time             `json:"started_at,omitempty"`
+	Status    string                 `json:"status"`
+	Subject   string                 `json:"subject,omitempty"`
+	Type      string                 `json:"type"`
+}
+
+// JobProgress is how far a running job has got, e.g. 3 of 10 IPs
+type JobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// NotificationRoute sends alerts for a pool and/or team at a severity to one
+// notifier target. Nil PoolID, TeamID and an empty Severity match any.
+type NotificationRoute struct {
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Enabled   bool      `json:"enabled"`
+	ID        int       `json:"id"`
+	Notifier  string    `json:"notifier"`
+	PoolID    int       `json:"pool_id,omitempty"`
+	PoolName  string    `json:"pool_name,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Target    string    `json:"target"`
+	TeamID    int       `json:"team_id,omitempty"`
+	TeamName  string    `json:"team_name,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OwnershipAssignment ties a user or team to an IP or pool
+type OwnershipAssignment struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"owner_id"`
+	OwnerName string    `json:"owner_name"`
+	OwnerType string    `json:"owner_type"`
+	// IP address or pool name
+	Target     string `json:"target"`
+	TargetType string `json:"target_type"`
+}
+
+// DatabaseRecommendation is an entry of the recommendation catalog. Texts
+// overrides the built-in text of the action per language; Link points at the
+// runbook.
+type DatabaseRecommendation struct {
+	Action    string            `json:"action"`
+	CreatedAt time.Time         `json:"created_at"`
+	Link      string            `json:"link,omitempty"`
+	Texts     map[string]string `json:"texts"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	UpdatedBy string            `json:"updated_by"`
+}
+
+// RecommendationMapping recommends an action for a status, and optionally only
+// for one issue type. Position orders the actions of a status.
+type RecommendationMapping struct {
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	ID        int       `json:"id"`
+	IssueType string    `json:"issue_type,omitempty"`
+	Position  int       `json:"position"`
+	Status    string    `json:"status"`
+}
+
+// ReputationFeed is a third-party source of IP verdicts, e.g. internal threat
+// intel or a partner blocklist
+type ReputationFeed struct {
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       string     `json:"created_by,omitempty"`
+	Description     string     `json:"description"`
+	Enabled         bool       `json:"enabled"`
+	ID              int        `json:"id"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+	Name            string     `json:"name"`
+	SignalsReceived int64      `json:"signals_received"`
+	// Weight scales the feed's verdicts in the external score
+	Weight float64 `json:"weight"`
+}
+
+// ReputationLookup is the answer of an external sender reputation source, e.g.
+// SenderScore, about an IP
+type ReputationLookup struct {
+	CheckedAt time.Time         `json:"checked_at"`
+	Details   map[string]string `json:"details,omitempty"`
+	// Error is set when the lookup failed; the result is then kept for a shorter
+	// time
+	Error    string `json:"error,omitempty"`
+	ID       int    `json:"id"`
+	IP       string `json:"ip"`
+	Listed   bool   `json:"listed"`
+	Provider string `json:"provider"`
+	// Score is the provider's own scale, nil when it has no score for the IP
+	Score   float64 `json:"score,omitempty"`
+	Summary string  `json:"summary,omitempty"`
+}
+
+// ReservedIP represents a reserved IP in the database
+type ReservedIP struct {
+	AssignedTo         string                 `json:"assigned_to,omitempty"`
+	BlacklistDetails   []string               `json:"blacklist_details"`
+	CreatedAt          time.Time              `json:"created_at"`
+	ID                 int                    `json:"id"`
+	IPAddress          string                 `json:"ip_address"`
+	IsBlacklisted      bool                   `json:"is_blacklisted"`
+	LastCheckedAt      *time.Time             `json:"last_checked_at,omitempty"`
+	Location           string                 `json:"location"`
+	Metadata           map[string]interface{} `json:"metadata,omitempty"`
+	Notes              string                 `json:"notes,omitempty"`
+	ReleasedAt         *time.Time             `json:"released_at,omitempty"`
+	ReservationBlockID string                 `json:"reservation_block_id"`
+	ReservedAt         time.Time              `json:"reserved_at"`
+	Status             string                 `json:"status"`
+	Uid                string                 `json:"uid"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+	UsageCount         int                    `json:"usage_count"`
+}
+
+// SLOMeasurement is one check of a fleet objective: the value measured and
+// whether it met the objective's threshold
+type SLOMeasurement struct {
+	MeasuredAt time.Time `json:"measured_at"`
+	Met        bool      `json:"met"`
+	Objective  string    `json:"objective"`
+	Threshold  float64   `json:"threshold"`
+	// TotalIPs is the size of the fleet measured
+	TotalIps int     `json:"total_ips"`
+	Value    float64 `json:"value"`
+}
+
+// SMTPAuthFailure is a failed outgoing SMTP authentication, e.g. against a
+// smarthost
+type SMTPAuthFailure struct {
+	EventID     string    `json:"event_id"`
+	ID          int       `json:"id"`
+	Mechanism   string    `json:"mechanism,omitempty"`
+	MtaInstance string    `json:"mta_instance,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	RemoteHost  string    `json:"remote_host,omitempty"`
+	SendingIP   string    `json:"sending_ip"`
+	SourceID    int       `json:"source_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Username    string    `json:"username,omitempty"`
+}
+
+// SMTPFailure represents an individual SMTP delivery failure
+type SMTPFailure struct {
+	AttemptNumber int `json:"attempt_number"`
+	// BlockReferences are the provider block list identifiers the reason quotes
+	// (validation.BlockReferences), e.g. Outlook's S3150
+	BlockReferences []string `json:"block_references,omitempty"`
+	EnhancedCode    string   `json:"enhanced_code"`
+	EventID         string   `json:"event_id"`
+	EventType       string   `json:"event_type,omitempty"`
+	ID              int      `json:"id"`
+	MessageID       string   `json:"message_id,omitempty"`
+	MtaInstance     string   `json:"mta_instance,omitempty"`
+	MxServer        string   `json:"mx_server"`
+	// OrganizationalDomain is the registrable domain of RecipientDomain, so
+	// mx1.mail.yahoo.co.jp and yahoo.co.jp are one entity
+	OrganizationalDomain string `json:"organizational_domain,omitempty"`
+	Reason               string `json:"reason"`
+	// ReasonFamily is Reason reduced to its template (validation.ReasonFamily), so
+	// reasons quoting different IPs or block IDs group together
+	ReasonFamily    string `json:"reason_family,omitempty"`
+	RecipientDomain string `json:"recipient_domain"`
+	RecipientEmail  string `json:"recipient_email"`
+	// RetryDeliveredAt is when a delivery from the IP to the recipient followed
+	// this greylist-style deferral (MarkRetriesDelivered), which makes it
+	// greylisting rather than a rejection
+	RetryDeliveredAt *time.Time `json:"retry_delivered_at,omitempty"`
+	SendingIP        string     `json:"sending_ip"`
+	SMTPCode         int        `json:"smtp_code"`
+	SourceID         int        `json:"source_id,omitempty"`
+	Timestamp        time.Time  `json:"timestamp"`
+	// TLSPolicy is the TLS requirement (tlspolicy.Requirement*) the destination
+	// published when the failure was ingested, empty if unknown
+	TLSPolicy string `json:"tls_policy,omitempty"`
+}
+
+// SendRate is the recommended send rate of an IP to a destination provider
+type SendRate struct {
+	IP              string `json:"ip"`
+	MessagesPerHour int    `json:"messages_per_hour"`
+	// Provider is "*" for every provider without a rate of its own
+	Provider string `json:"provider"`
+	// Reasons explain each reduction from the full rate
+	Reasons   []string  `json:"reasons"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// WarmupStage is the 1-based warm-up stage capping the rate, 0 once the IP is
+	// warmed up
+	WarmupStage int `json:"warmup_stage"`
+}
+
+// SpamtrapHit is mail from a sending IP that reached a spamtrap address
+type SpamtrapHit struct {
+	CreatedAt   time.Time `json:"created_at"`
+	HitAt       time.Time `json:"hit_at"`
+	ID          int       `json:"id"`
+	MessageID   string    `json:"message_id,omitempty"`
+	ReportID    string    `json:"report_id,omitempty"`
+	ReportedBy  string    `json:"reported_by,omitempty"`
+	SendingIP   string    `json:"sending_ip"`
+	Source      string    `json:"source"`
+	TrapAddress string    `json:"trap_address,omitempty"`
+	TrapType    string    `json:"trap_type"`
+}
+
+// TLSDestinationSummary totals the reported sessions to a policy domain
+type TLSDestinationSummary struct {
+	FailedSessions     int     `json:"failed_sessions"`
+	FailureRatio       float64 `json:"failure_ratio"`
+	PolicyDomain       string  `json:"policy_domain"`
+	PolicyType         string  `json:"policy_type"`
+	SuccessfulSessions int     `json:"successful_sessions"`
+}
+
+// TLSFailure is one kind of failed TLS negotiation from a sending IP to a
+// policy domain over the report's date range
+type TLSFailure struct {
+	AdditionalInformation string `json:"additional_information,omitempty"`
+	FailedSessions        int    `json:"failed_sessions"`
+	FailureReasonCode     string `json:"failure_reason_code,omitempty"`
+	PolicyDomain          string `json:"policy_domain"`
+	PolicyType            string `json:"policy_type"`
+	ReceivingIP           string `json:"receiving_ip,omitempty"`
+	ReceivingMxHostname   string `json:"receiving_mx_hostname,omitempty"`
+	ResultType            string `json:"result_type"`
+	SendingIP             string `json:"sending_ip,omitempty"`
+}
+
+// TLSFailureSummary totals an IP's failed sessions to a policy domain by result
+// type
+type TLSFailureSummary struct {
+	FailedSessions int `json:"failed_sessions"`
+	// End of the newest report's date range
+	LastSeen     time.Time `json:"last_seen"`
+	PolicyDomain string    `json:"policy_domain"`
+	PolicyType   string    `json:"policy_type"`
+	Reports      int       `json:"reports"`
+	ResultType   string    `json:"result_type"`
+}
+
+// TLSReport is an SMTP TLS report (RFC 8460) on our outbound sessions
+type TLSReport struct {
+	ContactInfo  string            `json:"contact_info,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	EndAt        time.Time         `json:"end_at"`
+	Failures     []TLSFailure      `json:"failures"`
+	ID           int               `json:"id"`
+	Organization string            `json:"organization"`
+	Policies     []TLSReportPolicy `json:"policies"`
+	ReportID     string            `json:"report_id"`
+	ReportedBy   string            `json:"reported_by,omitempty"`
+	StartAt      time.Time         `json:"start_at"`
+}
+
+// TLSReportPolicy counts a report's sessions to one policy domain
+type TLSReportPolicy struct {
+	FailedSessions     int    `json:"failed_sessions"`
+	PolicyDomain       string `json:"policy_domain"`
+	PolicyType         string `json:"policy_type"`
+	SuccessfulSessions int    `json:"successful_sessions"`
+}
+
+// Team groups users
+type Team struct {
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	ID          int       `json:"id"`
+	MemberIds   []int     `json:"member_ids"`
+	Name        string    `json:"name"`
+}
+
+// ThrottleState is whether a destination provider is rate-limiting an IP
+type ThrottleState struct {
+	// BackoffSeconds is how long MTAs should hold mail to the provider after the
+	// last rate-limit response; RetryAfter is when that ends
+	BackoffSeconds  int        `json:"backoff_seconds"`
+	IP              string     `json:"ip"`
+	LastThrottledAt *time.Time `json:"last_throttled_at,omitempty"`
+	Provider        string     `json:"provider"`
+	RetryAfter      *time.Time `json:"retry_after,omitempty"`
+	Since           *time.Time `json:"since,omitempty"`
+	// ThrottleCount is the rate-limit responses within the throttle window
+	ThrottleCount int `json:"throttle_count"`
+	// Throttled is set while the provider keeps deferring the IP's mail with
+	// rate-limit responses; Since is when that started
+	Throttled bool      `json:"throttled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// User represents a user in the database
+type User struct {
+	// false once deprovisioned; cannot log in
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	Email     string    `json:"email"`
+	ID        int       `json:"id"`
+	// read-only, operator or admin
+	Role      string    `json:"role"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Username  string    `json:"username"`
+}
+
+// WebhookSource is a Stalwart instance allowed to post webhook events
+type WebhookSource struct {
+	CreatedAt       time.Time  `json:"created_at"`
+	Description     string     `json:"description"`
+	Enabled         bool       `json:"enabled"`
+	EventsFailed    int64      `json:"events_failed"`
+	EventsProcessed int64      `json:"events_processed"`
+	ID              int        `json:"id"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+	Name            string     `json:"name"`
+}
+
+// WebhookSourceStats summarizes what a source has ingested
+type WebhookSourceStats struct {
+	EnhancedCodes  map[string]int `json:"enhanced_codes"`
+	FailuresStored int            `json:"failures_stored"`
+	LastEventAt    *time.Time     `json:"last_event_at,omitempty"`
+	Since          time.Time      `json:"since"`
+	Source         WebhookSource  `json:"source"`
+	UniqueDomains  int            `json:"unique_domains"`
+	UniqueIps      int            `json:"unique_ips"`
+}
+
+// DependenciesStatus is the reachability and recent error rate of one
+// dependency
+type DependenciesStatus struct {
+	Calls       int        `json:"calls"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	ErrorRate   float64    `json:"error_rate"`
+	Errors      int        `json:"errors"`
+	Kind        string     `json:"kind"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	LatencyMs   int64      `json:"latency_ms,omitempty"`
+	Name        string     `json:"name"`
+	ProbeError  string     `json:"probe_error,omitempty"`
+	// Reachable is the result of the last probe; nil without a probe
+	Reachable     bool   `json:"reachable,omitempty"`
+	Status        string `json:"status"`
+	WindowMinutes int    `json:"window_minutes"`
+}
+
+// Fault delays calls to a target and fails a share of them until it expires
+type Fault struct {
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	// ErrorRate is the share of calls failed, between 0 and 1
+	ErrorRate float64   `json:"error_rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// LatencyMS is added to every call
+	LatencyMs int `json:"latency_ms"`
+	// StatusCode is the response of failed HTTP calls, 500 by default; DNS calls
+	// time out and database calls return an InjectedError
+	StatusCode int    `json:"status_code,omitempty"`
+	Target     string `json:"target"`
+}
+
+// Flag is the effective value of a flag and where it came from
+type Flag struct {
+	Default     bool       `json:"default"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Name        string     `json:"name"`
+	Source      string     `json:"source"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+}
+
+// SwapResult describes a swap of an IP for a clean reserved IP
+type SwapResult struct {
+	DryRun      bool       `json:"dry_run"`
+	Replaced    ReservedIP `json:"replaced"`
+	Replacement ReservedIP `json:"replacement"`
+}
+
+// BurstRule detects an extreme burst of matching failures at ingestion time,
+// before the next aggregation run sees it
+type BurstRule struct {
+	Action string `json:"action"`
+	// Domain is the organizational recipient domain that must reject (e.g.
+	// gmail.com); empty matches any domain
+	Domain string `json:"domain"`
+	// EnhancedCode is an exact code (5.7.1) or a class.subject prefix (5.7); empty
+	// matches any code
+	EnhancedCode  string `json:"enhanced_code"`
+	Name          string `json:"name"`
+	Threshold     int    `json:"threshold"`
+	WindowSeconds int    `json:"window_seconds"`
+}
+
+// CodeCount is an enhanced code counted by hasReputationRelatedCodes
+type CodeCount struct {
+	Category  string `json:"category"`
+	Code      string `json:"code"`
+	Count     int    `json:"count"`
+	Threshold int    `json:"threshold"`
+	Tripped   bool   `json:"tripped"`
+}
+
+// Condition is one comparison inside a rule, with the configured threshold next
+// to the observed value
+type Condition struct {
+	Met       bool    `json:"met"`
+	Name      string  `json:"name"`
+	Observed  float64 `json:"observed"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+}
+
+// ConfigChange is the old and new value of a changed config field
+type ConfigChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DNSBLResult represents the result of a DNSBL check
+type DNSBLResult struct {
+	CheckDurationMS int       `json:"CheckDurationMS"`
+	CheckedAt       time.Time `json:"CheckedAt"`
+	Error           string    `json:"Error"`
+	IP              string    `json:"IP"`
+	Listed          bool      `json:"Listed"`
+	Listings        []string  `json:"Listings"`
+}
+
+// EvaluationWindow is a longer window evaluated next to WindowMinutes so that
+// slow-burn degradation is caught even when the primary window is clean. A
+// window can escalate an IP to warning or quarantine, never to blacklisted.
+type EvaluationWindow struct {
+	MinVolumeForAssessment int    `json:"min_volume_for_assessment"`
+	Minutes                int    `json:"minutes"`
+	Name                   string `json:"name"`
+	// QuarantineRejectionRatio is exceeded, with a major provider rejecting, for
+	// quarantine; 0 disables it
+	QuarantineRejectionRatio float64 `json:"quarantine_rejection_ratio"`
+	// WarningRejectionRatio is reached for warning; 0 disables it
+	WarningRejectionRatio float64 `json:"warning_rejection_ratio"`
+}
+
+// Explanation shows why DetermineIPStatus picks a status
+type Explanation struct {
+	Config ReputationConfig `json:"config"`
+	// DecidingRule is the first rule that fired, in evaluation order
+	DecidingRule string        `json:"deciding_rule"`
+	Observed     IPHealthCheck `json:"observed"`
+	// ProviderCounts are rejections per major provider
+	ProviderCounts map[string]int `json:"provider_counts"`
+	// ReputationCodes lists the observed codes that count towards the blacklist
+	// rule
+	ReputationCodes []CodeCount  `json:"reputation_codes"`
+	Rules           []RuleResult `json:"rules"`
+	// Status is the status the rules produce for the observed metrics
+	Status string `json:"status"`
+}
+
+// IPHealthCheck contains calculated metrics for IP health assessment
+type IPHealthCheck struct {
+	// AuthFailures counts failed outgoing SMTP authentications of the IP
+	AuthFailures  int     `json:"auth_failures"`
+	ComplaintRate float64 `json:"complaint_rate"`
+	// Complaints counts ARF complaints about the IP's mail within the config's
+	// ComplaintWindowHours; ComplaintRate divides them by the mail sent in that
+	// window
+	Complaints    int                `json:"complaints"`
+	DomainCounts  map[string]int     `json:"domain_counts"`
+	ExternalFeeds map[string]float64 `json:"external_feeds,omitempty"`
+	// ExternalScore is the weighted verdicts of third-party reputation feeds;
+	// ExternalFeeds is each feed's share of it
+	ExternalScore float64 `json:"external_score"`
+	// Greylisted counts deferrals recognised as greylisting; they are left out of
+	// every other count and the rejection ratio
+	Greylisted        int            `json:"greylisted"`
+	GreylistedDomains map[string]int `json:"greylisted_domains"`
+	Instance          string         `json:"instance,omitempty"`
+	InstanceCounts    map[string]int `json:"instance_counts"`
+	IP                string         `json:"ip"`
+	MajorProviders    []string       `json:"major_providers"`
+	RejectionRatio    float64        `json:"rejection_ratio"`
+	ReputationCodes   map[string]int `json:"reputation_codes"`
+	// RetriesDeduplicated counts retry attempts not counted as rejections
+	RetriesDeduplicated int `json:"retries_deduplicated"`
+	// SpamtrapHits counts spamtrap hits of the IP within the config's
+	// SpamtrapWindowHours rather than WindowMinutes
+	SpamtrapHits  int `json:"spamtrap_hits"`
+	ThrottleCount int `json:"throttle_count"`
+	// TLSFailedSessions counts the IP's failed TLS negotiations in TLS reports of
+	// the last two days. They are diagnostic only: forced-TLS failures look like
+	// deliverability problems but say nothing about reputation.
+	TLSFailedSessions int `json:"tls_failed_sessions"`
+	// TLSRequired counts TLS failures to destinations whose MTA-STS or DANE policy
+	// requires TLS; like greylisting they are left out of every other count and
+	// the rejection ratio
+	TLSRequired           int            `json:"tls_required"`
+	TLSRequiredDomains    map[string]int `json:"tls_required_domains"`
+	TotalRejected         int            `json:"total_rejected"`
+	TotalSent             int            `json:"total_sent"`
+	UniqueDomainsRejected int            `json:"unique_domains_rejected"`
+	VolumeEstimated       bool           `json:"volume_estimated"`
+	// VolumeSource names the estimator TotalSent came from; VolumeEstimated is set
+	// when it is the failure heuristic rather than a counted volume
+	VolumeSource string `json:"volume_source,omitempty"`
+	// TotalRejected with age decay, used for the ratio
+	WeightedRejected float64 `json:"weighted_rejected"`
+	WindowMinutes    int     `json:"window_minutes"`
+}
+
+// ReputationRecommendation is a recommended action with its text in the
+// response language and, from the catalog, its runbook link
+type ReputationRecommendation struct {
+	Action string `json:"action"`
+	Link   string `json:"link,omitempty"`
+	Text   string `json:"text"`
+}
+
+// ReputationConfig: Configuration for reputation thresholds
+type ReputationConfig struct {
+	BlacklistMinDomains        int     `json:"blacklist_min_domains"`
+	BlacklistMinMajorProviders int     `json:"blacklist_min_major_providers"`
+	BlacklistRejectionRatio    float64 `json:"blacklist_rejection_ratio"`
+	// BurstRules are checked as failures are ingested and act without waiting for
+	// the next aggregation run
+	BurstRules           []BurstRule `json:"burst_rules"`
+	ComplaintWindowHours int         `json:"complaint_window_hours"`
+	// DecayHalfLifeMinutes weights failures in the primary window by age, so an
+	// old burst counts less as it ages; 0 weighs all failures equally
+	DecayHalfLifeMinutes int `json:"decay_half_life_minutes"`
+	// MaxMessagesPerHour is the send rate recommended to each provider for a
+	// healthy, warmed-up IP, capped by WarmupStages while the IP is young; 0
+	// disables send rate recommendations
+	MaxMessagesPerHour     int `json:"max_messages_per_hour"`
+	MinVolumeForAssessment int `json:"min_volume_for_assessment"`
+	// Complaints from ARF reports within ComplaintWindowHours over the mail sent
+	// in that window give the complaint rate; a rate above QuarantineComplaintRate
+	// quarantines and one above WarningComplaintRate warns. A threshold of 0
+	// disables its rule.
+	QuarantineComplaintRate float64 `json:"quarantine_complaint_rate"`
+	// The external score sums the unexpired verdicts of third-party feeds, each
+	// scaled by the feed's weight and the verdict's confidence. A score of at
+	// least QuarantineExternalScore quarantines and one of at least
+	// WarningExternalScore warns, whatever the volume. 0 disables a rule.
+	QuarantineExternalScore  float64 `json:"quarantine_external_score"`
+	QuarantineMinDomains     int     `json:"quarantine_min_domains"`
+	QuarantineRejectionRatio float64 `json:"quarantine_rejection_ratio"`
+	// SpamtrapQuarantineHits spamtrap hits within SpamtrapWindowHours quarantine
+	// an IP whatever its volume and ratios; 0 disables it
+	SpamtrapQuarantineHits int `json:"spamtrap_quarantine_hits"`
+	SpamtrapWindowHours    int `json:"spamtrap_window_hours"`
+	// ThrottleMinResponses rate-limit deferrals from one provider within
+	// ThrottleWindowMinutes mark the IP throttled there, so MTAs back off before
+	// the provider blocks it; 0 disables throttle tracking
+	ThrottleMinResponses           int           `json:"throttle_min_responses"`
+	ThrottleWindowMinutes          int           `json:"throttle_window_minutes"`
+	WarmupStages                   []WarmupStage `json:"warmup_stages"`
+	WarningComplaintRate           float64       `json:"warning_complaint_rate"`
+	WarningExternalScore           float64       `json:"warning_external_score"`
+	WarningRejectionRatio          float64       `json:"warning_rejection_ratio"`
+	WarningReputationCodeThreshold int           `json:"warning_reputation_code_threshold"`
+	WindowMinutes                  int           `json:"window_minutes"`
+	// Windows are evaluated next to WindowMinutes and may escalate the status
+	Windows []EvaluationWindow `json:"windows"`
+}
+
+// RuleResult is the outcome of one decision rule. A rule fires when all of its
+// conditions are met.
+type RuleResult struct {
+	Conditions  []Condition `json:"conditions"`
+	Description string      `json:"description"`
+	Fired       bool        `json:"fired"`
+	Rule        string      `json:"rule"`
+	Status      string      `json:"status"`
+}
+
+// ShadowDivergence is an IP the shadow config classifies differently from the
+// live one
+type ShadowDivergence struct {
+	IP           string `json:"ip"`
+	LiveStatus   string `json:"live_status"`
+	ShadowRule   string `json:"shadow_rule"`
+	ShadowStatus string `json:"shadow_status"`
+}
+
+// ShadowReport compares the live and shadow classifications of one aggregation
+// run. Statuses are compared before the state machine clamps transitions, so
+// they show what each rule set decided.
+type ShadowReport struct {
+	Agreed      int                `json:"agreed"`
+	Diverged    int                `json:"diverged"`
+	Divergences []ShadowDivergence `json:"divergences"`
+	Evaluated   int                `json:"evaluated"`
+	RunAt       time.Time          `json:"run_at"`
+	// Transitions counts diverging IPs by "live->shadow" status pair
+	Transitions map[string]int `json:"transitions"`
+	// Truncated is set when more IPs diverged than Divergences lists
+	Truncated bool `json:"truncated"`
+}
+
+// SpamtrapOutcome is what recording a spamtrap hit did to its IP
+type SpamtrapOutcome struct {
+	// Hits counts the IP's hits within the config's SpamtrapWindowHours, including
+	// this one
+	Hits           int    `json:"hits"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Quarantined    bool   `json:"quarantined"`
+	// Simulated is set when the quarantine was only recorded, in dry-run
+	// remediation mode
+	Simulated bool   `json:"simulated,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+// WarmupStage caps the send rate of an IP younger than Days days, counted from
+// its first aggregation run
+type WarmupStage struct {
+	Days            int `json:"days"`
+	MessagesPerHour int `json:"messages_per_hour"`
+}
+
+// Objective bounds a fleet metric from below with Min, above with Max, or both.
+// The fleet is compliant at a measurement that is within the bounds.
+type Objective struct {
+	Max    float64 `json:"max,omitempty"`
+	Metric string  `json:"metric"`
+	Min    float64 `json:"min,omitempty"`
+	Name   string  `json:"name"`
+}
+
+// SLOStatus is an objective's compliance and error budget over the window
+type SLOStatus struct {
+	// Breached is set when the error budget is spent
+	Breached bool `json:"breached"`
+	// BurnRate is how fast the budget is spent over the burn window: 1 spends it
+	// exactly over the window, above 1 exhausts it early
+	BurnRate float64 `json:"burn_rate"`
+	// Compliance is the share of measurements in the window that met the
+	// objective; 1 without measurements
+	Compliance float64 `json:"compliance"`
+	// Compliant is whether the latest measurement met the objective
+	Compliant bool `json:"compliant"`
+	// Current is the latest measurement; nil before the first
+	Current float64 `json:"current,omitempty"`
+	// ErrorBudgetRemaining is the share of the error budget, 1 - Target, not yet
+	// spent; negative once the objective is breached
+	ErrorBudgetRemaining float64    `json:"error_budget_remaining"`
+	Max                  float64    `json:"max,omitempty"`
+	MeasuredAt           *time.Time `json:"measured_at,omitempty"`
+	Measurements         int        `json:"measurements"`
+	Metric               string     `json:"metric"`
+	Min                  float64    `json:"min,omitempty"`
+	Name                 string     `json:"name"`
+	Target               float64    `json:"target"`
+	WindowHours          float64    `json:"window_hours"`
+}
+
+// DateRange is the period a report covers
+type DateRange struct {
+	EndDatetime   time.Time `json:"end-datetime"`
+	StartDatetime time.Time `json:"start-datetime"`
+}
+
+// Failure is one kind of failed TLS negotiation
+type Failure struct {
+	AdditionalInformation string `json:"additional-information,omitempty"`
+	FailedSessionCount    int    `json:"failed-session-count"`
+	FailureReasonCode     string `json:"failure-reason-code,omitempty"`
+	ReceivingIP           string `json:"receiving-ip,omitempty"`
+	ReceivingMxHelo       string `json:"receiving-mx-helo,omitempty"`
+	ReceivingMxHostname   string `json:"receiving-mx-hostname,omitempty"`
+	ResultType            string `json:"result-type"`
+	SendingMtaIP          string `json:"sending-mta-ip,omitempty"`
+}
+
+// Policy is the sessions to one policy domain under one policy
+type Policy struct {
+	FailureDetails []Failure     `json:"failure-details"`
+	Policy         PolicyDetails `json:"policy"`
+	Summary        Summary       `json:"summary"`
+}
+
+// PolicyDetails names the policy that was applied
+type PolicyDetails struct {
+	MxHost       []string `json:"mx-host,omitempty"`
+	PolicyDomain string   `json:"policy-domain"`
+	PolicyString []string `json:"policy-string,omitempty"`
+	PolicyType   string   `json:"policy-type"`
+}
+
+// Report is an SMTP TLS report (RFC 8460): the TLS sessions one sending
+// organization attempted to each policy domain over a date range
+type Report struct {
+	ContactInfo      string    `json:"contact-info"`
+	DateRange        DateRange `json:"date-range"`
+	OrganizationName string    `json:"organization-name"`
+	Policies         []Policy  `json:"policies"`
+	ReportID         string    `json:"report-id"`
+}
+
+// Summary counts the sessions under a policy
+type Summary struct {
+	TotalFailureSessionCount    int `json:"total-failure-session-count"`
+	TotalSuccessfulSessionCount int `json:"total-successful-session-count"`
+}
+
+// AddANoteToAnIP calls POST /api/ips/{ip}/notes.
+//
+// Add a note to an IP. Store a free-form operator note, shown in the IP's
+// reputation response
+func (c *Client) AddANoteToAnIP(ctx context.Context, ip string, body CreateIPNoteRequest) (*IPNote, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/notes"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(IPNote)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddIPToPool calls POST /api/pools/{id}/ips.
+//
+// Add IP to pool.
+func (c *Client) AddIPToPool(ctx context.Context, id int, body PoolIPRequest) (map[string]interface{}, error) {
+	req := &request{method: "POST", path: "/api/pools/" + url.PathEscape(fmt.Sprint(id)) + "/ips"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// AddTeamMember calls POST /api/teams/{id}/members.
+//
+// Add team member.
+func (c *Client) AddTeamMember(ctx context.Context, id int, body TeamMemberRequest) (map[string]interface{}, error) {
+	req := &request{method: "POST", path: "/api/teams/" + url.PathEscape(fmt.Sprint(id)) + "/members"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ArchiveAnIP calls POST /api/ips/{ip}/archive.
+//
+// Archive an IP. Decommission an IP: its history is kept and stays queryable,
+// but aggregation and alerting skip it and dashboards hide it unless called
+// with include_archived=true. Archiving an archived IP changes nothing.
+func (c *Client) ArchiveAnIP(ctx context.Context, ip string, body *ArchiveIPRequest) (*ArchiveIPsResponse, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/archive"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ArchiveIPsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ArchiveTheIPsOfACIDRBlock calls POST /api/ips/archive.
+//
+// Archive the IPs of a CIDR block. Archive every IP in a block known to the
+// reserved IPs, reputation metrics, pools, ownership assignments or failures.
+// With dry_run the matching IPs are listed and nothing is archived.
+func (c *Client) ArchiveTheIPsOfACIDRBlock(ctx context.Context, body BulkArchiveRequest) (*ArchiveIPsResponse, error) {
+	req := &request{method: "POST", path: "/api/ips/archive"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ArchiveIPsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CapacityForecastParams are the query and header parameters of
+// CapacityForecast. Zero values are not sent.
+type CapacityForecastParams struct {
+	// Days of history to fit (1-90)
+	Days int
+}
+
+// CapacityForecast calls GET /api/dashboard/capacity-forecast.
+//
+// Capacity forecast. Projects the healthy IPs of each pool over the next 7 days
+// from the daily trend, and when the clean reserved IPs run out at the current
+// rate of IPs moving into quarantine or blacklisted
+func (c *Client) CapacityForecast(ctx context.Context, params *CapacityForecastParams) (*CapacityForecastResponse, error) {
+	req := &request{method: "GET", path: "/api/dashboard/capacity-forecast"}
+	if params != nil {
+		req.param("query", "days", params.Days)
+	}
+	out := new(CapacityForecastResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckDNSBLStatusForIP calls POST /api/ips/{ip}/dnsbl-check.
+//
+// Check DNSBL status for IP. Run DNSBL checks for a specific IP
+func (c *Client) CheckDNSBLStatusForIP(ctx context.Context, ip string) (*DNSBLResult, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/dnsbl-check"}
+	out := new(DNSBLResult)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckDNSBLStatusForIPAsynchronously calls POST
+// /api/ips/{ip}/dnsbl-check/async.
+//
+// Check DNSBL status for IP asynchronously. Start the DNSBL checks for an IP in
+// the background and return the job at once. Poll GET /api/jobs/{id} for the
+// reputation.DNSBLResult, or give a callback_url to receive the finished job as
+// a POST.
+func (c *Client) CheckDNSBLStatusForIPAsynchronously(ctx context.Context, ip string, body *AsyncJobRequest) (*Job, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/dnsbl-check/async"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(Job)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClearAllInjectedFaults calls DELETE /api/system/faults.
+//
+// Clear all injected faults. Stop injecting failures into every target
+func (c *Client) ClearAllInjectedFaults(ctx context.Context) error {
+	req := &request{method: "DELETE", path: "/api/system/faults"}
+	return c.do(ctx, req, nil)
+}
+
+// ClearAnInjectedFault calls DELETE /api/system/faults/{target}.
+//
+// Clear an injected fault. Stop injecting failures into calls to a target
+func (c *Client) ClearAnInjectedFault(ctx context.Context, target string) error {
+	req := &request{method: "DELETE", path: "/api/system/faults/" + url.PathEscape(target)}
+	return c.do(ctx, req, nil)
+}
+
+// ClearTheOnCallContextOfAnIP calls DELETE /api/ips/{ip}/context.
+//
+// Clear the on-call context of an IP.
+func (c *Client) ClearTheOnCallContextOfAnIP(ctx context.Context, ip string) error {
+	req := &request{method: "DELETE", path: "/api/ips/" + url.PathEscape(ip) + "/context"}
+	return c.do(ctx, req, nil)
+}
+
+// CommentOnAnIPAction calls POST /api/ips/{ip}/actions/{id}/comments.
+//
+// Comment on an IP action. Annotate an automated or manual action, e.g. with
+// the ticket opened with a provider
+func (c *Client) CommentOnAnIPAction(ctx context.Context, ip string, id int, body CreateActionCommentRequest) (*IPActionComment, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/actions/" + url.PathEscape(fmt.Sprint(id)) + "/comments"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(IPActionComment)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CompareTheFleetBetweenTwoPeriodsParams are the query and header parameters of
+// CompareTheFleetBetweenTwoPeriods. Zero values are not sent.
+type CompareTheFleetBetweenTwoPeriodsParams struct {
+	// Start of period A (RFC 3339)
+	FromA string
+	// End of period A (RFC 3339, exclusive)
+	ToA string
+	// Start of period B (RFC 3339)
+	FromB string
+	// End of period B (RFC 3339, exclusive)
+	ToB string
+	// Include archived IPs
+	IncludeArchived bool
+	// Include IPs in aggregation exclusions
+	IncludeExcluded bool
+}
+
+// CompareTheFleetBetweenTwoPeriods calls GET /api/analytics/compare.
+//
+// Compare the fleet between two periods. Per-IP and fleet-level changes in
+// rejection ratio, status distribution and top codes from period A to period B,
+// e.g. before and after an infrastructure or content change. Ratios and
+// statuses come from the aggregation history, codes from the stored failures.
+func (c *Client) CompareTheFleetBetweenTwoPeriods(ctx context.Context, params *CompareTheFleetBetweenTwoPeriodsParams) (*FleetComparisonResponse, error) {
+	req := &request{method: "GET", path: "/api/analytics/compare"}
+	if params != nil {
+		req.param("query", "from_a", params.FromA)
+		req.param("query", "to_a", params.ToA)
+		req.param("query", "from_b", params.FromB)
+		req.param("query", "to_b", params.ToB)
+		req.param("query", "include_archived", params.IncludeArchived)
+		req.param("query", "include_excluded", params.IncludeExcluded)
+	}
+	out := new(FleetComparisonResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateANewUser calls POST /users.
+//
+// Create a new user. Create a new user with username and email
+func (c *Client) CreateANewUser(ctx context.Context, body CreateUserRequest) (*User, error) {
+	req := &request{method: "POST", path: "/users"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(User)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAPIKey calls POST /api/auth/keys.
+//
+// Create API key. Create an API key with a role and return it. The key is only
+// returned once. Requires the admin role.
+func (c *Client) CreateAPIKey(ctx context.Context, body CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	req := &request{method: "POST", path: "/api/auth/keys"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(CreateAPIKeyResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateIPPool calls POST /api/pools.
+//
+// Create IP pool.
+func (c *Client) CreateIPPool(ctx context.Context, body CreateTeamRequest) (*IPPool, error) {
+	req := &request{method: "POST", path: "/api/pools"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(IPPool)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateITSMRule calls POST /api/itsm/rules.
+//
+// Create ITSM rule. Open a ticket in a Jira project or ServiceNow assignment
+// group when an IP, optionally of one pool, is blacklisted or a delisting task
+// is opened for it. Rules naming a pool replace the catch-all rules for its
+// IPs. While a rule's ticket for the event is open no second one is opened.
+func (c *Client) CreateITSMRule(ctx context.Context, body ITSMRuleRequest) (*ITSMRule, error) {
+	req := &request{method: "POST", path: "/api/itsm/rules"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ITSMRule)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateNotificationRoute calls POST /api/notification-routes.
+//
+// Create notification route. Send alerts matching a pool and/or team and a
+// severity to a notifier target. Omitted fields match any alert; routes naming
+// a pool or team replace the catch-all routes for alerts they match.
+func (c *Client) CreateNotificationRoute(ctx context.Context, body NotificationRouteRequest) (*NotificationRoute, error) {
+	req := &request{method: "POST", path: "/api/notification-routes"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(NotificationRoute)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateOwnershipAssignment calls POST /api/assignments.
+//
+// Create ownership assignment. Make a user or team responsible for an IP or
+// pool
+func (c *Client) CreateOwnershipAssignment(ctx context.Context, body CreateAssignmentRequest) (*OwnershipAssignment, error) {
+	req := &request{method: "POST", path: "/api/assignments"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(OwnershipAssignment)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateRecommendationMapping calls POST /api/recommendation-mappings.
+//
+// Create recommendation mapping. Recommend a catalog action for a status.
+// Mappings with an issue type replace the status' mappings without one for IPs
+// with that issue type.
+func (c *Client) CreateRecommendationMapping(ctx context.Context, body RecommendationMappingRequest) (*RecommendationMapping, error) {
+	req := &request{method: "POST", path: "/api/recommendation-mappings"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(RecommendationMapping)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTeam calls POST /api/teams.
+//
+// Create team.
+func (c *Client) CreateTeam(ctx context.Context, body CreateTeamRequest) (*Team, error) {
+	req := &request{method: "POST", path: "/api/teams"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(Team)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CurrentPrincipal calls GET /auth/me.
+//
+// Current principal. Return the user or API key the request is authenticated as
+func (c *Client) CurrentPrincipal(ctx context.Context) (*Principal, error) {
+	req := &request{method: "GET", path: "/auth/me"}
+	out := new(Principal)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteANoteOnAnIP calls DELETE /api/ips/{ip}/notes/{id}.
+//
+// Delete a note on an IP.
+func (c *Client) DeleteANoteOnAnIP(ctx context.Context, ip string, id int) error {
+	req := &request{method: "DELETE", path: "/api/ips/" + url.PathEscape(ip) + "/notes/" + url.PathEscape(fmt.Sprint(id))}
+	return c.do(ctx, req, nil)
+}
+
+// DeleteAPIV1IpsProtectedBlocksByBlockID calls DELETE
+// /api/v1/ips/protected-blocks/{block_id}.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) DeleteAPIV1IpsProtectedBlocksByBlockID(ctx context.Context, blockID string) (json.RawMessage, error) {
+	req := &request{method: "DELETE", path: "/api/v1/ips/protected-blocks/" + url.PathEscape(blockID)}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteAPIV1IpsReservedByID calls DELETE /api/v1/ips/reserved/{id}.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) DeleteAPIV1IpsReservedByID(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "DELETE", path: "/api/v1/ips/reserved/" + url.PathEscape(id)}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteAggregationExclusion calls DELETE /api/reputation/exclusions/{id}.
+//
+// Delete aggregation exclusion. Put a block's IPs back into aggregation and the
+// dashboards
+func (c *Client) DeleteAggregationExclusion(ctx context.Context, id int) error {
+	req := &request{method: "DELETE", path: "/api/reputation/exclusions/" + url.PathEscape(fmt.Sprint(id))}
+	return c.do(ctx, req, nil)
+}
+
+// DeleteIPPool calls DELETE /api/pools/{id}.
+//
+// Delete IP pool. Delete a pool with its members and assignments
+func (c *Client) DeleteIPPool(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/pools/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteITSMRule calls DELETE /api/itsm/rules/{id}.
+//
+// Delete ITSM rule. Stop opening tickets for the rule; the tickets it opened
+// keep syncing
+func (c *Client) DeleteITSMRule(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/itsm/rules/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteNotificationRoute calls DELETE /api/notification-routes/{id}.
+//
+// Delete notification route.
+func (c *Client) DeleteNotificationRoute(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/notification-routes/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteOwnershipAssignment calls DELETE /api/assignments/{id}.
+//
+// Delete ownership assignment.
+func (c *Client) DeleteOwnershipAssignment(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/assignments/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteRecommendation calls DELETE /api/recommendations/{action}.
+//
+// Delete recommendation. Remove a catalog entry; it is no longer recommended
+// for any status
+func (c *Client) DeleteRecommendation(ctx context.Context, action string) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/recommendations/" + url.PathEscape(action)}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteRecommendationMapping calls DELETE /api/recommendation-mappings/{id}.
+//
+// Delete recommendation mapping.
+func (c *Client) DeleteRecommendationMapping(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/recommendation-mappings/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteSCIMGroup calls DELETE /scim/v2/Groups/{id}.
+//
+// Delete SCIM group.
+func (c *Client) DeleteSCIMGroup(ctx context.Context, id int) error {
+	req := &request{method: "DELETE", path: "/scim/v2/Groups/" + url.PathEscape(fmt.Sprint(id))}
+	return c.do(ctx, req, nil)
+}
+
+// DeleteSCIMUser calls DELETE /scim/v2/Users/{id}.
+//
+// Delete SCIM user.
+func (c *Client) DeleteSCIMUser(ctx context.Context, id int) error {
+	req := &request{method: "DELETE", path: "/scim/v2/Users/" + url.PathEscape(fmt.Sprint(id))}
+	return c.do(ctx, req, nil)
+}
+
+// DeleteTeam calls DELETE /api/teams/{id}.
+//
+// Delete team. Delete a team with its memberships and assignments
+func (c *Client) DeleteTeam(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/teams/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// DeleteUser calls DELETE /users/{id}.
+//
+// Delete user. Delete a user by ID
+func (c *Client) DeleteUser(ctx context.Context, id int) (*DeleteUserResponse, error) {
+	req := &request{method: "DELETE", path: "/users/" + url.PathEscape(fmt.Sprint(id))}
+	out := new(DeleteUserResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DisableWebhookSource calls DELETE /api/webhooks/sources/{id}.
+//
+// Disable webhook source. Revoke a webhook source's token. Failures it reported
+// are kept.
+func (c *Client) DisableWebhookSource(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/webhooks/sources/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// EraseRecipientData calls DELETE /api/privacy/recipients/{email}.
+//
+// Erase recipient data. Anonymize all stored SMTP failures for a recipient
+// address. Rows are kept so aggregate counts stay intact.
+func (c *Client) EraseRecipientData(ctx context.Context, email string) (*ErasureResponse, error) {
+	req := &request{method: "DELETE", path: "/api/privacy/recipients/" + url.PathEscape(email)}
+	out := new(ErasureResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExcludeABlockFromAggregation calls POST /api/reputation/exclusions.
+//
+// Exclude a block from aggregation. Stop aggregating the IPs of a CIDR block,
+// or of a single IP, and hide them from the IP health dashboard, heatmap, fleet
+// comparison and reason families. Their failures are still stored, and metrics
+// already computed are kept but no longer updated.
+func (c *Client) ExcludeABlockFromAggregation(ctx context.Context, body AggregationExclusionRequest) (*AggregationExclusion, error) {
+	req := &request{method: "POST", path: "/api/reputation/exclusions"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(AggregationExclusion)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExplainIPStatusParams are the query and header parameters of ExplainIPStatus.
+// Zero values are not sent.
+type ExplainIPStatusParams struct {
+	// Evaluate only the failures of one MTA instance
+	Instance string
+}
+
+// ExplainIPStatus calls GET /api/ips/{ip}/reputation/explain.
+//
+// Explain IP status. Show which decision rules fired for an IP: thresholds
+// against observed values, the enhanced codes that count as reputation related
+// and rejections per major provider, with the latest results of external
+// reputation sources such as SenderScore
+func (c *Client) ExplainIPStatus(ctx context.Context, ip string, params *ExplainIPStatusParams) (*IPReputationExplanationResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/reputation/explain"}
+	if params != nil {
+		req.param("query", "instance", params.Instance)
+	}
+	out := new(IPReputationExplanationResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportReputationState calls GET /api/system/backup.
+//
+// Export reputation state. A consistent snapshot of the reputation metrics,
+// config revisions, feature flag overrides and their history, reserved IPs,
+// protected blocks and archived IPs, as a gzipped JSON archive for disaster
+// recovery or cloning an environment
+func (c *Client) ExportReputationState(ctx context.Context) ([]byte, error) {
+	req := &request{method: "GET", path: "/api/system/backup"}
+	var out []byte
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ExternalDependencyHealth calls GET /api/system/dependencies.
+//
+// External dependency health. Reachability (probed at most every 30 seconds)
+// and error rates over the last 15 minutes for Postgres, the IONOS API, the DNS
+// resolver used for DNSBL checks and configured alert destinations
+func (c *Client) ExternalDependencyHealth(ctx context.Context) (*DependenciesResponse, error) {
+	req := &request{method: "GET", path: "/api/system/dependencies"}
+	out := new(DependenciesResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FeatureFlagHistoryParams are the query and header parameters of
+// FeatureFlagHistory. Zero values are not sent.
+type FeatureFlagHistoryParams struct {
+	// Number of changes (1-500)
+	Limit int
+}
+
+// FeatureFlagHistory calls GET /api/system/features/{name}/history.
+//
+// Feature flag history. Audited toggles of a flag, newest first
+func (c *Client) FeatureFlagHistory(ctx context.Context, name string, params *FeatureFlagHistoryParams) ([]FeatureFlagChange, error) {
+	req := &request{method: "GET", path: "/api/system/features/" + url.PathEscape(name) + "/history"}
+	if params != nil {
+		req.param("query", "limit", params.Limit)
+	}
+	var out []FeatureFlagChange
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// FleetRejectionHeatmapParams are the query and header parameters of
+// FleetRejectionHeatmap. Zero values are not sent.
+type FleetRejectionHeatmapParams struct {
+	// Days to cover (1-30)
+	Days int
+	// Only IPs owned by me, user:<id> or team:<id>
+	Owner string
+	// Include archived IPs
+	IncludeArchived bool
+	// Include IPs in aggregation exclusions
+	IncludeExcluded bool
+}
+
+// FleetRejectionHeatmap calls GET /api/dashboard/heatmap.
+//
+// Fleet rejection heatmap. Hourly rejection ratios per IP for the last N days,
+// bucketed server-side from the aggregation history
+func (c *Client) FleetRejectionHeatmap(ctx context.Context, params *FleetRejectionHeatmapParams) (*RejectionHeatmapResponse, error) {
+	req := &request{method: "GET", path: "/api/dashboard/heatmap"}
+	if params != nil {
+		req.param("query", "days", params.Days)
+		req.param("query", "owner", params.Owner)
+		req.param("query", "include_archived", params.IncludeArchived)
+		req.param("query", "include_excluded", params.IncludeExcluded)
+	}
+	out := new(RejectionHeatmapResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetADelistingTask calls GET /api/delisting-tasks/{id}.
+//
+// Get a delisting task.
+func (c *Client) GetADelistingTask(ctx context.Context, id int) (*DelistingTask, error) {
+	req := &request{method: "GET", path: "/api/delisting-tasks/" + url.PathEscape(fmt.Sprint(id))}
+	out := new(DelistingTask)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAPIV1IpsLocationsStats calls GET /api/v1/ips/locations/stats.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsLocationsStats(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/locations/stats"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsProtectedBlocks calls GET /api/v1/ips/protected-blocks.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsProtectedBlocks(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/protected-blocks"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsQuota calls GET /api/v1/ips/quota.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsQuota(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/quota"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsReserved calls GET /api/v1/ips/reserved.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsReserved(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/reserved"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsReservedByID calls GET /api/v1/ips/reserved/{id}.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsReservedByID(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/reserved/" + url.PathEscape(id)}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsStale calls GET /api/v1/ips/stale.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsStale(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/stale"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAPIV1IpsStatistics calls GET /api/v1/ips/statistics.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) GetAPIV1IpsStatistics(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "GET", path: "/api/v1/ips/statistics"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAbuseComplaintsForIPParams are the query and header parameters of
+// GetAbuseComplaintsForIP. Zero values are not sent.
+type GetAbuseComplaintsForIPParams struct {
+	// Time window (e.g., 24h, 168h)
+	Window string
+}
+
+// GetAbuseComplaintsForIP calls GET /api/ips/{ip}/complaints.
+//
+// Get abuse complaints for IP. Complaints about an IP's mail that arrived
+// within a time window, newest first
+func (c *Client) GetAbuseComplaintsForIP(ctx context.Context, ip string, params *GetAbuseComplaintsForIPParams) ([]AbuseComplaint, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/complaints"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []AbuseComplaint
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetAllTestCases calls GET /api/testing/test-cases.
+//
+// Get all test cases. Retrieve all predefined IP reputation test scenarios
+func (c *Client) GetAllTestCases(ctx context.Context) ([]TestCase, error) {
+	req := &request{method: "GET", path: "/api/testing/test-cases"}
+	var out []TestCase
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetDestinationTLSPolicy calls GET /api/destinations/{domain}/tls-policy.
+//
+// Get destination TLS policy. MTA-STS and DANE policy of a destination domain,
+// resolved unless a fresh one is cached. Failures ingested for the domain are
+// annotated with its requirement; failures about TLS to a domain that requires
+// it are left out of reputation like greylisting.
+func (c *Client) GetDestinationTLSPolicy(ctx context.Context, domain string) (*TLSPolicyResponse, error) {
+	req := &request{method: "GET", path: "/api/destinations/" + url.PathEscape(domain) + "/tls-policy"}
+	out := new(TLSPolicyResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetExternalSignalsForIP calls GET /api/ips/{ip}/external-signals.
+//
+// Get external signals for IP. The unexpired verdicts of enabled third-party
+// feeds on an IP, heaviest first, with the external score they add up to and
+// each feed's share
+func (c *Client) GetExternalSignalsForIP(ctx context.Context, ip string) (*ExternalSignalsByIPResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/external-signals"}
+	out := new(ExternalSignalsByIPResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIPHealthDashboardParams are the query and header parameters of
+// GetIPHealthDashboard. Zero values are not sent.
+type GetIPHealthDashboardParams struct {
+	// Filter by status (healthy, warning, quarantine, blacklisted)
+	Status string
+	// Only IPs owned by me, user:<id> or team:<id>
+	Owner string
+	// Include archived IPs
+	IncludeArchived bool
+	// Include IPs in aggregation exclusions
+	IncludeExcluded bool
+	// ETag from a previous response
+	IfNoneMatch string
+	// Last-Modified from a previous response
+	IfModifiedSince string
+}
+
+// GetIPHealthDashboard calls GET /api/dashboard/ip-health.
+//
+// Get IP health dashboard. Retrieve aggregated IP health metrics for dashboard
+func (c *Client) GetIPHealthDashboard(ctx context.Context, params *GetIPHealthDashboardParams) (*IPHealthDashboardResponse, error) {
+	req := &request{method: "GET", path: "/api/dashboard/ip-health"}
+	if params != nil {
+		req.param("query", "status", params.Status)
+		req.param("query", "owner", params.Owner)
+		req.param("query", "include_archived", params.IncludeArchived)
+		req.param("query", "include_excluded", params.IncludeExcluded)
+		req.param("header", "If-None-Match", params.IfNoneMatch)
+		req.param("header", "If-Modified-Since", params.IfModifiedSince)
+	}
+	out := new(IPHealthDashboardResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIPOwners calls GET /api/ips/{ip}/owners.
+//
+// Get IP owners. Users and teams responsible for an IP, directly or through a
+// pool
+func (c *Client) GetIPOwners(ctx context.Context, ip string) ([]IPOwner, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/owners"}
+	var out []IPOwner
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetIPReputationParams are the query and header parameters of GetIPReputation.
+// Zero values are not sent.
+type GetIPReputationParams struct {
+	// Scope the health summary to one MTA instance
+	Instance string
+	// ETag from a previous response
+	IfNoneMatch string
+	// Last-Modified from a previous response
+	IfModifiedSince string
+	// Language of the summary and recommendation texts, e.g. de-CH, fr;q=0.8
+	AcceptLanguage string
+}
+
+// GetIPReputation calls GET /api/ips/{ip}/reputation.
+//
+// Get IP reputation. Retrieve reputation metrics and status for a specific IP
+func (c *Client) GetIPReputation(ctx context.Context, ip string, params *GetIPReputationParams) (*IPReputationResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/reputation"}
+	if params != nil {
+		req.param("query", "instance", params.Instance)
+		req.param("header", "If-None-Match", params.IfNoneMatch)
+		req.param("header", "If-Modified-Since", params.IfModifiedSince)
+		req.param("header", "Accept-Language", params.AcceptLanguage)
+	}
+	out := new(IPReputationResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIPsOwnedByAUser calls GET /users/{id}/ips.
+//
+// Get IPs owned by a user. IPs a user is responsible for directly, through a
+// pool or through a team
+func (c *Client) GetIPsOwnedByAUser(ctx context.Context, id int) ([]string, error) {
+	req := &request{method: "GET", path: "/users/" + url.PathEscape(fmt.Sprint(id)) + "/ips"}
+	var out []string
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetJob calls GET /api/jobs/{id}.
+//
+// Get job. The status of a background job, with its progress while jobs working
+// through many items run, its result once it succeeded or its error once it
+// failed. Finished jobs are kept for the configured retention.
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	req := &request{method: "GET", path: "/api/jobs/" + url.PathEscape(id)}
+	out := new(Job)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLatestAuditReport calls GET /api/system/audit.
+//
+// Get latest audit report. The newest self-audit report. Each check counts one
+// kind of inconsistency: stale_metrics (reputation metrics not updated within
+// AUDIT_STALE_METRICS_AFTER, 24 hours by default, although failures arrived),
+// missing_ionos_blocks (unreleased reserved IPs whose IONOS block is gone),
+// stale_dnsbl_checks (active IPs whose last DNSBL check is older than the
+// policy) and orphaned_actions (IP actions of IPs the service no longer knows).
+// A check above its threshold is exceeded.
+func (c *Client) GetLatestAuditReport(ctx context.Context) (*AuditReport, error) {
+	req := &request{method: "GET", path: "/api/system/audit"}
+	out := new(AuditReport)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMitigationFormDataForIPParams are the query and header parameters of
+// GetMitigationFormDataForIP. Zero values are not sent.
+type GetMitigationFormDataForIPParams struct {
+	// outlook, gmail or yahoo
+	Provider string
+	// Time window (e.g., 24h, 168h), at most 720h
+	Window string
+}
+
+// GetMitigationFormDataForIP calls GET /api/ips/{ip}/mitigation-data.
+//
+// Get mitigation form data for IP. The answers a mailbox provider's sender
+// mitigation form asks for about a blocked IP: sample NDRs (one per distinct
+// error, permanent ones first), the first and last rejection, the error codes
+// and the daily volume to the provider, estimated from the rejections at an
+// assumed 5% failure rate. Fields follow the form's order and are
+// pre-formatted; text joins them for pasting. Recipient addresses in NDR text
+// are redacted.
+func (c *Client) GetMitigationFormDataForIP(ctx context.Context, ip string, params *GetMitigationFormDataForIPParams) (*MitigationDataResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/mitigation-data"}
+	if params != nil {
+		req.param("query", "provider", params.Provider)
+		req.param("query", "window", params.Window)
+	}
+	out := new(MitigationDataResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOutgoingSMTPAuthFailuresForIPParams are the query and header parameters of
+// GetOutgoingSMTPAuthFailuresForIP. Zero values are not sent.
+type GetOutgoingSMTPAuthFailuresForIPParams struct {
+	// Time window (e.g., 15m, 1h, 24h)
+	Window string
+	// Only failures reported by this MTA instance
+	Instance string
+}
+
+// GetOutgoingSMTPAuthFailuresForIP calls GET /api/ips/{ip}/auth-failures.
+//
+// Get outgoing SMTP auth failures for IP. Retrieve failed outgoing SMTP
+// authentications for a specific IP within a time window
+func (c *Client) GetOutgoingSMTPAuthFailuresForIP(ctx context.Context, ip string, params *GetOutgoingSMTPAuthFailuresForIPParams) ([]SMTPAuthFailure, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/auth-failures"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+		req.param("query", "instance", params.Instance)
+	}
+	var out []SMTPAuthFailure
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetRejectionsPerMTAInstanceParams are the query and header parameters of
+// GetRejectionsPerMTAInstance. Zero values are not sent.
+type GetRejectionsPerMTAInstanceParams struct {
+	// Time window (e.g., 15m, 1h, 24h)
+	Window string
+}
+
+// GetRejectionsPerMTAInstance calls GET /api/ips/{ip}/instances.
+//
+// Get rejections per MTA instance. Break an IP's SMTP failures down by the MTA
+// instance that reported them
+func (c *Client) GetRejectionsPerMTAInstance(ctx context.Context, ip string, params *GetRejectionsPerMTAInstanceParams) ([]InstanceRejections, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/instances"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []InstanceRejections
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetRemediationMode calls GET /api/system/remediation.
+//
+// Get remediation mode. The mode automated actions (burst and spamtrap
+// quarantines, reserved IP quarantines, stale reservation releases and send
+// rate cuts) run in: off, dry-run or enforce. In dry-run mode the actions are
+// recorded in the IP actions with metadata simulated=true instead of being
+// taken.
+func (c *Client) GetRemediationMode(ctx context.Context) (*RemediationModeResponse, error) {
+	req := &request{method: "GET", path: "/api/system/remediation"}
+	out := new(RemediationModeResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReputationConfig calls GET /api/reputation/config.
+//
+// Get reputation config. The thresholds the decision engine applies and the
+// revision they came from
+func (c *Client) GetReputationConfig(ctx context.Context) (*ReputationConfigResponse, error) {
+	req := &request{method: "GET", path: "/api/reputation/config"}
+	out := new(ReputationConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReputationHistoryForIPParams are the query and header parameters of
+// GetReputationHistoryForIP. Zero values are not sent.
+type GetReputationHistoryForIPParams struct {
+	// Time window (e.g., 1h, 24h, 720h)
+	Window string
+}
+
+// GetReputationHistoryForIP calls GET /api/ips/{ip}/history.
+//
+// Get reputation history for IP. Retrieve the outcome of every aggregation run
+// for an IP within a time window, newest first
+func (c *Client) GetReputationHistoryForIP(ctx context.Context, ip string, params *GetReputationHistoryForIPParams) ([]IPHistoryRecord, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/history"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []IPHistoryRecord
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetSCIMGroup calls GET /scim/v2/Groups/{id}.
+//
+// Get SCIM group.
+func (c *Client) GetSCIMGroup(ctx context.Context, id int) (*SCIMGroup, error) {
+	req := &request{method: "GET", path: "/scim/v2/Groups/" + url.PathEscape(fmt.Sprint(id))}
+	out := new(SCIMGroup)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSCIMUser calls GET /scim/v2/Users/{id}.
+//
+// Get SCIM user.
+func (c *Client) GetSCIMUser(ctx context.Context, id int) (*SCIMUser, error) {
+	req := &request{method: "GET", path: "/scim/v2/Users/" + url.PathEscape(fmt.Sprint(id))}
+	out := new(SCIMUser)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSLOMeasurementsParams are the query and header parameters of
+// GetSLOMeasurements. Zero values are not sent.
+type GetSLOMeasurementsParams struct {
+	// Time window (e.g., 24h, 720h)
+	Window string
+}
+
+// GetSLOMeasurements calls GET /api/slo/{objective}/measurements.
+//
+// Get SLO measurements. Measurements of one fleet objective within a time
+// window, newest first, for reporting compliance over time
+func (c *Client) GetSLOMeasurements(ctx context.Context, objective string, params *GetSLOMeasurementsParams) (*SLOMeasurementsResponse, error) {
+	req := &request{method: "GET", path: "/api/slo/" + url.PathEscape(objective) + "/measurements"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	out := new(SLOMeasurementsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSLOStatus calls GET /api/slo.
+//
+// Get SLO status. Compliance of every fleet service level objective over the
+// SLO window (30 days by default): the latest measured value, the share of
+// measurements that met the objective, the error budget remaining and the burn
+// rate over the burn window. A burn rate of 1 spends the budget exactly over
+// the window.
+func (c *Client) GetSLOStatus(ctx context.Context) (*SLOStatusResponse, error) {
+	req := &request{method: "GET", path: "/api/slo"}
+	out := new(SLOStatusResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSMTPFailuresForIPParams are the query and header parameters of
+// GetSMTPFailuresForIP. Zero values are not sent.
+type GetSMTPFailuresForIPParams struct {
+	// Time window (e.g., 15m, 1h, 24h)
+	Window string
+	// Only failures reported by this MTA instance
+	Instance string
+}
+
+// GetSMTPFailuresForIP calls GET /api/ips/{ip}/failures.
+//
+// Get SMTP failures for IP. Retrieve SMTP failures for a specific IP within a
+// time window
+func (c *Client) GetSMTPFailuresForIP(ctx context.Context, ip string, params *GetSMTPFailuresForIPParams) ([]SMTPFailure, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/failures"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+		req.param("query", "instance", params.Instance)
+	}
+	var out []SMTPFailure
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetSMTPReasonFamiliesParams are the query and header parameters of
+// GetSMTPReasonFamilies. Zero values are not sent.
+type GetSMTPReasonFamiliesParams struct {
+	// Time window (e.g., 24h, 168h; at most 720h)
+	Window string
+	// Only failures of this sending IP
+	IP string
+	// Only failures to this organizational domain
+	Domain string
+	// Maximum families (1-500)
+	Limit int
+	// Include archived IPs
+	IncludeArchived bool
+	// Include IPs in aggregation exclusions
+	IncludeExcluded bool
+}
+
+// GetSMTPReasonFamilies calls GET /api/analytics/reason-families.
+//
+// Get SMTP reason families. Failures grouped by reason family: the reason with
+// IPs, recipients, block and queue IDs, numbers and URLs replaced by
+// placeholders, so one rejection reported with different block IDs is counted
+// once
+func (c *Client) GetSMTPReasonFamilies(ctx context.Context, params *GetSMTPReasonFamiliesParams) (*ReasonFamiliesResponse, error) {
+	req := &request{method: "GET", path: "/api/analytics/reason-families"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+		req.param("query", "ip", params.IP)
+		req.param("query", "domain", params.Domain)
+		req.param("query", "limit", params.Limit)
+		req.param("query", "include_archived", params.IncludeArchived)
+		req.param("query", "include_excluded", params.IncludeExcluded)
+	}
+	out := new(ReasonFamiliesResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSendingPolicyForIP calls GET /api/ips/{ip}/sending-policy.
+//
+// Get sending policy for IP. The IP's reputation status, its throttling state
+// at each destination provider that rate-limited it within the throttle window
+// (whether it is throttled, since when, and the backoff MTAs should apply until
+// retry_after), and the recommended messages/hour per provider with the reasons
+// for each reduction. Updated every aggregation run. rate_limit is the explicit
+// messages/minute cap set with PUT /api/ips/{ip}/rate-limit, until it expires.
+func (c *Client) GetSendingPolicyForIP(ctx context.Context, ip string) (*SendingPolicyResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/sending-policy"}
+	out := new(SendingPolicyResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetShadowReputationConfig calls GET /api/reputation/shadow.
+//
+// Get shadow reputation config. The shadow thresholds, their diff against the
+// live config and the comparison from the last aggregation run
+func (c *Client) GetShadowReputationConfig(ctx context.Context) (*ShadowConfigResponse, error) {
+	req := &request{method: "GET", path: "/api/reputation/shadow"}
+	out := new(ShadowConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSpamtrapHitsForIPParams are the query and header parameters of
+// GetSpamtrapHitsForIP. Zero values are not sent.
+type GetSpamtrapHitsForIPParams struct {
+	// Time window (e.g., 24h, 168h)
+	Window string
+}
+
+// GetSpamtrapHitsForIP calls GET /api/ips/{ip}/spamtrap-hits.
+//
+// Get spamtrap hits for IP. Spamtrap hits of a specific IP within a time
+// window, newest first
+func (c *Client) GetSpamtrapHitsForIP(ctx context.Context, ip string, params *GetSpamtrapHitsForIPParams) ([]SpamtrapHit, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/spamtrap-hits"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []SpamtrapHit
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetTLSFailuresForIPParams are the query and header parameters of
+// GetTLSFailuresForIP. Zero values are not sent.
+type GetTLSFailuresForIPParams struct {
+	// Time window (e.g., 48h, 168h)
+	Window string
+}
+
+// GetTLSFailuresForIP calls GET /api/ips/{ip}/tls-failures.
+//
+// Get TLS failures for IP. Failed TLS sessions of an IP in TLS reports ending
+// within a time window, by policy domain and result type, most failures first
+func (c *Client) GetTLSFailuresForIP(ctx context.Context, ip string, params *GetTLSFailuresForIPParams) ([]TLSFailureSummary, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/tls-failures"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []TLSFailureSummary
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetTLSResultsPerDestinationParams are the query and header parameters of
+// GetTLSResultsPerDestination. Zero values are not sent.
+type GetTLSResultsPerDestinationParams struct {
+	// Time window (e.g., 48h, 168h)
+	Window string
+}
+
+// GetTLSResultsPerDestination calls GET /api/reports/tls/destinations.
+//
+// Get TLS results per destination. Successful and failed TLS sessions per
+// policy domain in TLS reports ending within a time window, highest failure
+// ratio first
+func (c *Client) GetTLSResultsPerDestination(ctx context.Context, params *GetTLSResultsPerDestinationParams) ([]TLSDestinationSummary, error) {
+	req := &request{method: "GET", path: "/api/reports/tls/destinations"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	var out []TLSDestinationSummary
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// GetTheOnCallContextOfAnIP calls GET /api/ips/{ip}/context.
+//
+// Get the on-call context of an IP. Notes and runbook URL shown in the
+// registry, the IP health dashboard and alerts
+func (c *Client) GetTheOnCallContextOfAnIP(ctx context.Context, ip string) (*IPContext, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/context"}
+	out := new(IPContext)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUserByID calls GET /users/{id}.
+//
+// Get user by ID. Retrieve a specific user by their ID
+func (c *Client) GetUserByID(ctx context.Context, id int) (*User, error) {
+	req := &request{method: "GET", path: "/users/" + url.PathEscape(fmt.Sprint(id))}
+	out := new(User)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWebhookSourceStatisticsParams are the query and header parameters of
+// GetWebhookSourceStatistics. Zero values are not sent.
+type GetWebhookSourceStatisticsParams struct {
+	// Time window (e.g., 1h, 24h)
+	Window string
+}
+
+// GetWebhookSourceStatistics calls GET /api/webhooks/sources/{id}/stats.
+//
+// Get webhook source statistics. Ingestion counters and a breakdown of failures
+// stored by a source
+func (c *Client) GetWebhookSourceStatistics(ctx context.Context, id int, params *GetWebhookSourceStatisticsParams) (*WebhookSourceStats, error) {
+	req := &request{method: "GET", path: "/api/webhooks/sources/" + url.PathEscape(fmt.Sprint(id)) + "/stats"}
+	if params != nil {
+		req.param("query", "window", params.Window)
+	}
+	out := new(WebhookSourceStats)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HealthCheck calls GET /health.
+//
+// Health check. Check if the service is healthy. Responds 503 "draining" once
+// shutdown has begun, so load balancers stop routing to the instance.
+func (c *Client) HealthCheck(ctx context.Context) (*HealthResponse, error) {
+	req := &request{method: "GET", path: "/health"}
+	out := new(HealthResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IngestARFAbuseReport calls POST /api/reports/arf.
+//
+// Ingest ARF abuse report. Parse a raw ARF feedback report (RFC 5965
+// multipart/report email) sent as the body, or as the "report" file of a
+// multipart/form-data upload. The sending IP is the report's Source-IP, or the
+// first public IP in the original message's Received headers; the campaign
+// comes from its X-Campaign-ID, Feedback-ID or List-ID header. Only the
+// recipient's domain is stored. Complaints feed the complaint-rate rules of the
+// next aggregation run; not-spam and auth-failure reports are stored but not
+// counted.
+func (c *Client) IngestARFAbuseReport(ctx context.Context, body io.Reader) (*AbuseComplaintResponse, error) {
+	req := &request{method: "POST", path: "/api/reports/arf"}
+	req.body, req.contentType = body, "message/rfc822"
+	out := new(AbuseComplaintResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IngestSMTPTLSReport calls POST /api/reports/tls.
+//
+// Ingest SMTP TLS report. Store an SMTP TLS report (RFC 8460 JSON, optionally
+// gzipped as application/tlsrpt+gzip) on our outbound sessions. Failed
+// negotiations are kept per sending IP, policy domain and result type. They are
+// diagnostic only: they never change an IP's status, but they show up in the
+// IP's reputation and explanation and set the issue type to
+// tls_negotiation_failure when they rival its rejections.
+func (c *Client) IngestSMTPTLSReport(ctx context.Context, body Report) (*TLSReportResponse, error) {
+	req := &request{method: "POST", path: "/api/reports/tls"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(TLSReportResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InjectAFault calls PUT /api/system/faults/{target}.
+//
+// Inject a fault. Delay every call to a dependency (postgres, ionos or dns) by
+// latency_ms and fail error_rate of them until the fault expires. Failed IONOS
+// calls get status_code, DNS lookups time out and database calls return an
+// error. Replaces the target's previous fault.
+func (c *Client) InjectAFault(ctx context.Context, target string, body SetFaultRequest) (*Fault, error) {
+	req := &request{method: "PUT", path: "/api/system/faults/" + url.PathEscape(target)}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(Fault)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LiftIPRateLimit calls DELETE /api/ips/{ip}/rate-limit.
+//
+// Lift IP rate limit. Lift the IP's rate cap before it expires and push the
+// lifting to Stalwart's throttling hook
+func (c *Client) LiftIPRateLimit(ctx context.Context, ip string) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/ips/" + url.PathEscape(ip) + "/rate-limit"}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListAPIKeys calls GET /api/auth/keys.
+//
+// List API keys. List the API keys created through the API, revoked ones
+// included, newest first. Static keys from AUTH_API_KEYS are not listed.
+// Requires the admin role.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	req := &request{method: "GET", path: "/api/auth/keys"}
+	var out []APIKey
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListAggregationExclusions calls GET /api/reputation/exclusions.
+//
+// List aggregation exclusions. Blocks whose IPs aggregation does not discover
+// and dashboards hide unless called with include_excluded=true, ordered by
+// block
+func (c *Client) ListAggregationExclusions(ctx context.Context) (*AggregationExclusionsResponse, error) {
+	req := &request{method: "GET", path: "/api/reputation/exclusions"}
+	out := new(AggregationExclusionsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAnIPsDelistingTasksParams are the query and header parameters of
+// ListAnIPsDelistingTasks. Zero values are not sent.
+type ListAnIPsDelistingTasksParams struct {
+	// Only tasks with this status (open, submitted, resolved)
+	Status string
+}
+
+// ListAnIPsDelistingTasks calls GET /api/ips/{ip}/delisting-tasks.
+//
+// List an IP's delisting tasks. Delisting and mitigation requests opened for an
+// IP with their block references, newest first
+func (c *Client) ListAnIPsDelistingTasks(ctx context.Context, ip string, params *ListAnIPsDelistingTasksParams) (*DelistingTasksResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/delisting-tasks"}
+	if params != nil {
+		req.param("query", "status", params.Status)
+	}
+	out := new(DelistingTasksResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAnIPsITSMTickets calls GET /api/ips/{ip}/itsm-tickets.
+//
+// List an IP's ITSM tickets. Jira and ServiceNow tickets the rules opened for
+// an IP, with their keys, links and last reported status, newest first. Ticket
+// changes also appear in the IP's actions.
+func (c *Client) ListAnIPsITSMTickets(ctx context.Context, ip string) (*ITSMTicketsResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/itsm-tickets"}
+	out := new(ITSMTicketsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListArchivedIPs calls GET /api/ips/archived.
+//
+// List archived IPs. Decommissioned IPs with who archived them and why, most
+// recently archived first
+func (c *Client) ListArchivedIPs(ctx context.Context) (*ArchivedIPsResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/archived"}
+	out := new(ArchivedIPsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAuditReportsParams are the query and header parameters of
+// ListAuditReports. Zero values are not sent.
+type ListAuditReportsParams struct {
+	// Number of reports (1-500)
+	Limit int
+}
+
+// ListAuditReports calls GET /api/system/audit/reports.
+//
+// List audit reports. Self-audit reports, newest first
+func (c *Client) ListAuditReports(ctx context.Context, params *ListAuditReportsParams) ([]AuditReport, error) {
+	req := &request{method: "GET", path: "/api/system/audit/reports"}
+	if params != nil {
+		req.param("query", "limit", params.Limit)
+	}
+	var out []AuditReport
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListCachedDestinationTLSPolicies calls GET /api/destinations/tls-policies.
+//
+// List cached destination TLS policies. The MTA-STS and DANE policies cached
+// for destination domains, by domain, expired ones included
+func (c *Client) ListCachedDestinationTLSPolicies(ctx context.Context) ([]TLSPolicyResponse, error) {
+	req := &request{method: "GET", path: "/api/destinations/tls-policies"}
+	var out []TLSPolicyResponse
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListCommentsOnAnIPAction calls GET /api/ips/{ip}/actions/{id}/comments.
+//
+// List comments on an IP action.
+func (c *Client) ListCommentsOnAnIPAction(ctx context.Context, ip string, id int) ([]IPActionComment, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/actions/" + url.PathEscape(fmt.Sprint(id)) + "/comments"}
+	var out []IPActionComment
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListDelistingTasksParams are the query and header parameters of
+// ListDelistingTasks. Zero values are not sent.
+type ListDelistingTasksParams struct {
+	// Only tasks with this status (open, submitted, resolved)
+	Status string
+}
+
+// ListDelistingTasks calls GET /api/delisting-tasks.
+//
+// List delisting tasks. Delisting and mitigation requests of every IP, newest
+// first, e.g. the pending ones with status=open
+func (c *Client) ListDelistingTasks(ctx context.Context, params *ListDelistingTasksParams) (*DelistingTasksResponse, error) {
+	req := &request{method: "GET", path: "/api/delisting-tasks"}
+	if params != nil {
+		req.param("query", "status", params.Status)
+	}
+	out := new(DelistingTasksResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListFeatureFlags calls GET /api/system/features.
+//
+// List feature flags. Every flag with its effective value and source: the
+// built-in default, the config, or a runtime toggle stored in the database
+func (c *Client) ListFeatureFlags(ctx context.Context) (*FeatureFlagsResponse, error) {
+	req := &request{method: "GET", path: "/api/system/features"}
+	out := new(FeatureFlagsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListIPPools calls GET /api/pools.
+//
+// List IP pools.
+func (c *Client) ListIPPools(ctx context.Context) ([]IPPool, error) {
+	req := &request{method: "GET", path: "/api/pools"}
+	var out []IPPool
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListITSMRules calls GET /api/itsm/rules.
+//
+// List ITSM rules. The rules deciding which events open a Jira or ServiceNow
+// ticket for an IP
+func (c *Client) ListITSMRules(ctx context.Context) ([]ITSMRule, error) {
+	req := &request{method: "GET", path: "/api/itsm/rules"}
+	var out []ITSMRule
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListInjectedFaults calls GET /api/system/faults.
+//
+// List injected faults. Whether failure injection is enabled (chaos.enabled,
+// never in production), the targets it covers and the active faults
+func (c *Client) ListInjectedFaults(ctx context.Context) (*FaultsResponse, error) {
+	req := &request{method: "GET", path: "/api/system/faults"}
+	out := new(FaultsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNotesOnAnIP calls GET /api/ips/{ip}/notes.
+//
+// List notes on an IP.
+func (c *Client) ListNotesOnAnIP(ctx context.Context, ip string) ([]IPNote, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/notes"}
+	var out []IPNote
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListNotificationRoutes calls GET /api/notification-routes.
+//
+// List notification routes. The routing table deciding which notifier targets
+// receive alerts for each pool, team and severity. Targets are masked for
+// callers below admin.
+func (c *Client) ListNotificationRoutes(ctx context.Context) ([]NotificationRoute, error) {
+	req := &request{method: "GET", path: "/api/notification-routes"}
+	var out []NotificationRoute
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListOwnershipAssignmentsParams are the query and header parameters of
+// ListOwnershipAssignments. Zero values are not sent.
+type ListOwnershipAssignmentsParams struct {
+	// Only assignments of this owner (me, user:<id> or team:<id>)
+	Owner string
+	// Only assignments covering this IP, directly or through a pool
+	IP string
+}
+
+// ListOwnershipAssignments calls GET /api/assignments.
+//
+// List ownership assignments.
+func (c *Client) ListOwnershipAssignments(ctx context.Context, params *ListOwnershipAssignmentsParams) ([]OwnershipAssignment, error) {
+	req := &request{method: "GET", path: "/api/assignments"}
+	if params != nil {
+		req.param("query", "owner", params.Owner)
+		req.param("query", "ip", params.IP)
+	}
+	var out []OwnershipAssignment
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListRecommendationMappings calls GET /api/recommendation-mappings.
+//
+// List recommendation mappings. Which actions are recommended for each status
+// and, optionally, issue type
+func (c *Client) ListRecommendationMappings(ctx context.Context) ([]RecommendationMapping, error) {
+	req := &request{method: "GET", path: "/api/recommendation-mappings"}
+	var out []RecommendationMapping
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListRecommendations calls GET /api/recommendations.
+//
+// List recommendations. The recommendation catalog: each action's text
+// overrides per language and its runbook link
+func (c *Client) ListRecommendations(ctx context.Context) ([]DatabaseRecommendation, error) {
+	req := &request{method: "GET", path: "/api/recommendations"}
+	var out []DatabaseRecommendation
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListReputationFeeds calls GET /api/reputation/feeds.
+//
+// List reputation feeds. Registered third-party reputation feeds with their
+// weights and counters
+func (c *Client) ListReputationFeeds(ctx context.Context) ([]ReputationFeed, error) {
+	req := &request{method: "GET", path: "/api/reputation/feeds"}
+	var out []ReputationFeed
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListSCIMGroupsParams are the query and header parameters of ListSCIMGroups.
+// Zero values are not sent.
+type ListSCIMGroupsParams struct {
+	// displayName eq \"ops\"
+	Filter string
+}
+
+// ListSCIMGroups calls GET /scim/v2/Groups.
+//
+// List SCIM groups.
+func (c *Client) ListSCIMGroups(ctx context.Context, params *ListSCIMGroupsParams) (*SCIMListResponse, error) {
+	req := &request{method: "GET", path: "/scim/v2/Groups"}
+	if params != nil {
+		req.param("query", "filter", params.Filter)
+	}
+	out := new(SCIMListResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSCIMUsersParams are the query and header parameters of ListSCIMUsers.
+// Zero values are not sent.
+type ListSCIMUsersParams struct {
+	// userName eq \"alice\"
+	Filter string
+	// 1-based index of the first result
+	StartIndex int
+	// Page size
+	Count int
+}
+
+// ListSCIMUsers calls GET /scim/v2/Users.
+//
+// List SCIM users.
+func (c *Client) ListSCIMUsers(ctx context.Context, params *ListSCIMUsersParams) (*SCIMListResponse, error) {
+	req := &request{method: "GET", path: "/scim/v2/Users"}
+	if params != nil {
+		req.param("query", "filter", params.Filter)
+		req.param("query", "startIndex", params.StartIndex)
+		req.param("query", "count", params.Count)
+	}
+	out := new(SCIMListResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTeams calls GET /api/teams.
+//
+// List teams.
+func (c *Client) ListTeams(ctx context.Context) ([]Team, error) {
+	req := &request{method: "GET", path: "/api/teams"}
+	var out []Team
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ListThrottledIPs calls GET /api/sending-policy/throttles.
+//
+// List throttled IPs. Every IP and destination provider currently throttled,
+// longest throttled first, for MTAs that poll one list for all their IPs
+func (c *Client) ListThrottledIPs(ctx context.Context) (*ThrottledIPsResponse, error) {
+	req := &request{method: "GET", path: "/api/sending-policy/throttles"}
+	out := new(ThrottledIPsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListUsersParams are the query and header parameters of ListUsers. Zero values
+// are not sent.
+type ListUsersParams struct {
+	// Page size (1-500)
+	Limit int
+	// Number of users to skip
+	Offset int
+}
+
+// ListUsers calls GET /users.
+//
+// List users. Retrieve a page of users ordered by ID
+func (c *Client) ListUsers(ctx context.Context, params *ListUsersParams) (*UserListResponse, error) {
+	req := &request{method: "GET", path: "/users"}
+	if params != nil {
+		req.param("query", "limit", params.Limit)
+		req.param("query", "offset", params.Offset)
+	}
+	out := new(UserListResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListWebhookSources calls GET /api/webhooks/sources.
+//
+// List webhook sources. List registered webhook sources with their ingestion
+// counters
+func (c *Client) ListWebhookSources(ctx context.Context) ([]WebhookSource, error) {
+	req := &request{method: "GET", path: "/api/webhooks/sources"}
+	var out []WebhookSource
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// LiveFailureCountsForIP calls GET /api/ips/{ip}/live-stats.
+//
+// Live failure counts for IP. Provisional rejection counts for the last 1, 5
+// and 15 minutes, read directly from the failure log without waiting for
+// aggregation
+func (c *Client) LiveFailureCountsForIP(ctx context.Context, ip string) (*LiveStatsResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/" + url.PathEscape(ip) + "/live-stats"}
+	out := new(LiveStatsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogIn calls POST /auth/login.
+//
+// Log in. Exchange a username and password for a short-lived access token and a
+// refresh token
+func (c *Client) LogIn(ctx context.Context, body LoginRequest) (*TokenResponse, error) {
+	req := &request{method: "POST", path: "/auth/login"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(TokenResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogOut calls POST /auth/logout.
+//
+// Log out. Revoke a refresh token. Access tokens stay valid until they expire.
+func (c *Client) LogOut(ctx context.Context, body RefreshRequest) (map[string]interface{}, error) {
+	req := &request{method: "POST", path: "/auth/logout"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ManuallyQuarantineIP calls POST /api/ips/{ip}/quarantine.
+//
+// Manually quarantine IP. Manually set an IP to quarantine status
+func (c *Client) ManuallyQuarantineIP(ctx context.Context, ip string) (map[string]interface{}, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/quarantine"}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// MigrateAnIPsHistoryToANewAddress calls POST
+// /api/admin/ips/{old}/migrate-to/{new}.
+//
+// Migrate an IP's history to a new address. Re-key the failures, metrics,
+// reputation history, DNSBL and reputation lookups, actions and notes of an IP
+// that was renumbered to its new address, in one transaction, and record the
+// migration as an action on both. Where the new address already has current
+// metrics, windows, throttle states or send rates, it keeps its own. Pool
+// membership, ownership, reservations and feed verdicts stay with the old
+// address. Migrating again moves rows stored for the old address since.
+func (c *Client) MigrateAnIPsHistoryToANewAddress(ctx context.Context, old string, newParam string, body *MigrateIPRequest) (*IPMigration, error) {
+	req := &request{method: "POST", path: "/api/admin/ips/" + url.PathEscape(old) + "/migrate-to/" + url.PathEscape(newParam)}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(IPMigration)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OpenADelistingTask calls POST /api/ips/{ip}/delisting-tasks.
+//
+// Open a delisting task. Record a delisting or mitigation request opened with a
+// provider for an IP. The block references (e.g. Outlook's S3150) the IP's
+// rejections by the provider quoted within the window are linked to the task,
+// and the task is recorded in the IP's actions. One task per IP and provider
+// can be pending at a time.
+func (c *Client) OpenADelistingTask(ctx context.Context, ip string, body OpenDelistingTaskRequest) (*DelistingTask, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/delisting-tasks"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(DelistingTask)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OpenAPIDescription calls GET /openapi.json.
+//
+// OpenAPI description. The OpenAPI 3.1 description of the API, generated from
+// the registered routes at build time. cmd/clientgen turns it into a typed Go
+// client.
+func (c *Client) OpenAPIDescription(ctx context.Context) (map[string]interface{}, error) {
+	req := &request{method: "GET", path: "/openapi.json"}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PatchSCIMGroup calls PATCH /scim/v2/Groups/{id}.
+//
+// Patch SCIM group. Supports displayName and adding, removing or replacing
+// members
+func (c *Client) PatchSCIMGroup(ctx context.Context, id int, body SCIMPatchRequest) (*SCIMGroup, error) {
+	req := &request{method: "PATCH", path: "/scim/v2/Groups/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMGroup)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchSCIMUser calls PATCH /scim/v2/Users/{id}.
+//
+// Patch SCIM user. Supports userName, emails, password and active; deactivating
+// revokes sessions
+func (c *Client) PatchSCIMUser(ctx context.Context, id int, body SCIMPatchRequest) (*SCIMUser, error) {
+	req := &request{method: "PATCH", path: "/scim/v2/Users/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMUser)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostAPIV1IpsCleanup calls POST /api/v1/ips/cleanup.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsCleanup(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/cleanup"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsQuarantineReconcile calls POST /api/v1/ips/quarantine/reconcile.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsQuarantineReconcile(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/quarantine/reconcile"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsReserve calls POST /api/v1/ips/reserve.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsReserve(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/reserve"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsReservedByIDAttach calls POST /api/v1/ips/reserved/{id}/attach.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsReservedByIDAttach(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/reserved/" + url.PathEscape(id) + "/attach"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsReservedByIDRecheck calls POST /api/v1/ips/reserved/{id}/recheck.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsReservedByIDRecheck(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/reserved/" + url.PathEscape(id) + "/recheck"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsReservedByIDRelease calls POST /api/v1/ips/reserved/{id}/release.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsReservedByIDRelease(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/reserved/" + url.PathEscape(id) + "/release"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostAPIV1IpsStaleEvaluate calls POST /api/v1/ips/stale/evaluate.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PostAPIV1IpsStaleEvaluate(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "POST", path: "/api/v1/ips/stale/evaluate"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PostReputationFeedVerdicts calls POST /api/webhooks/reputation-feeds/signals.
+//
+// Post reputation feed verdicts. Post a feed's verdicts on IPs, authenticated
+// with the feed token (X-Webhook-Token, Bearer or the Basic password). Each
+// verdict replaces the feed's earlier one on the IP unless that was reported
+// later. Unexpired verdicts of enabled feeds add up to an IP's external score:
+// malicious counts 1, suspicious 0.5 and clean 0, each times the feed weight
+// and the confidence. A score of quarantine_external_score quarantines the IP
+// and one of warning_external_score warns (runtime config, 2 and 1 by default).
+// The whole batch is refused if one verdict is invalid.
+func (c *Client) PostReputationFeedVerdicts(ctx context.Context, body ExternalSignalsRequest) (*ExternalSignalsResponse, error) {
+	req := &request{method: "POST", path: "/api/webhooks/reputation-feeds/signals"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ExternalSignalsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProcessStalwartWebhookEventsParams are the query and header parameters of
+// ProcessStalwartWebhookEvents. Zero values are not sent.
+type ProcessStalwartWebhookEventsParams struct {
+	// Bearer or Basic credentials carrying the webhook source token
+	Authorization string
+	// Unix time the payload was signed at; required with a signing secret
+	XSignatureTimestamp int
+	// sha256=<hex HMAC-SHA256 of <timestamp>.<body>>; required with a signing
+	// secret
+	XSignature256 string
+	// Include a result per event; responds 207 when any event failed
+	Detailed bool
+}
+
+// ProcessStalwartWebhookEvents calls POST
+// /api/webhooks/stalwart/delivery-failure.
+//
+// Process Stalwart webhook events. Receive SMTP delivery failure, bounce, DSN
+// and outgoing auth-failure events from Stalwart, and delivery successes, which
+// count the IP's sent volume and turn the greylist-style deferrals they follow
+// into greylisting. Other event types are skipped. Responds 429 or 503 with
+// Retry-After while ingestion is overloaded, and 503 when an event could not be
+// stored, so Stalwart retries the payload.
+func (c *Client) ProcessStalwartWebhookEvents(ctx context.Context, params *ProcessStalwartWebhookEventsParams, body WebhookPayload) (*WebhookIngestResponse, error) {
+	req := &request{method: "POST", path: "/api/webhooks/stalwart/delivery-failure"}
+	if params != nil {
+		req.param("header", "Authorization", params.Authorization)
+		req.param("header", "X-Signature-Timestamp", params.XSignatureTimestamp)
+		req.param("header", "X-Signature-256", params.XSignature256)
+		req.param("query", "detailed", params.Detailed)
+	}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(WebhookIngestResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProcessStalwartWebhookEvents2Params are the query and header parameters of
+// ProcessStalwartWebhookEvents2. Zero values are not sent.
+type ProcessStalwartWebhookEvents2Params struct {
+	// Bearer or Basic credentials carrying the webhook source token
+	Authorization string
+	// Unix time the payload was signed at; required with a signing secret
+	XSignatureTimestamp int
+	// sha256=<hex HMAC-SHA256 of <timestamp>.<body>>; required with a signing
+	// secret
+	XSignature256 string
+	// Include a result per event; responds 207 when any event failed
+	Detailed bool
+}
+
+// ProcessStalwartWebhookEvents2 calls POST /api/webhooks/stalwart/events.
+//
+// Process Stalwart webhook events. Receive SMTP delivery failure, bounce, DSN
+// and outgoing auth-failure events from Stalwart, and delivery successes, which
+// count the IP's sent volume and turn the greylist-style deferrals they follow
+// into greylisting. Other event types are skipped. Responds 429 or 503 with
+// Retry-After while ingestion is overloaded, and 503 when an event could not be
+// stored, so Stalwart retries the payload.
+func (c *Client) ProcessStalwartWebhookEvents2(ctx context.Context, params *ProcessStalwartWebhookEvents2Params, body WebhookPayload) (*WebhookIngestResponse, error) {
+	req := &request{method: "POST", path: "/api/webhooks/stalwart/events"}
+	if params != nil {
+		req.param("header", "Authorization", params.Authorization)
+		req.param("header", "X-Signature-Timestamp", params.XSignatureTimestamp)
+		req.param("header", "X-Signature-256", params.XSignature256)
+		req.param("query", "detailed", params.Detailed)
+	}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(WebhookIngestResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PromoteShadowReputationConfig calls POST /api/reputation/shadow/promote.
+//
+// Promote shadow reputation config. Make the shadow thresholds live and remove
+// the shadow. The change is recorded in the config history.
+func (c *Client) PromoteShadowReputationConfig(ctx context.Context) (*ReputationConfigResponse, error) {
+	req := &request{method: "POST", path: "/api/reputation/shadow/promote"}
+	out := new(ReputationConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProvisionSCIMGroup calls POST /scim/v2/Groups.
+//
+// Provision SCIM group.
+func (c *Client) ProvisionSCIMGroup(ctx context.Context, body SCIMGroup) (*SCIMGroup, error) {
+	req := &request{method: "POST", path: "/scim/v2/Groups"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMGroup)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProvisionSCIMUser calls POST /scim/v2/Users.
+//
+// Provision SCIM user.
+func (c *Client) ProvisionSCIMUser(ctx context.Context, body SCIMUser) (*SCIMUser, error) {
+	req := &request{method: "POST", path: "/scim/v2/Users"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMUser)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PutAPIV1IpsPoolSpec calls PUT /api/v1/ips/pool-spec.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PutAPIV1IpsPoolSpec(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: "PUT", path: "/api/v1/ips/pool-spec"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PutAPIV1IpsProtectedBlocksByBlockID calls PUT
+// /api/v1/ips/protected-blocks/{block_id}.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PutAPIV1IpsProtectedBlocksByBlockID(ctx context.Context, blockID string) (json.RawMessage, error) {
+	req := &request{method: "PUT", path: "/api/v1/ips/protected-blocks/" + url.PathEscape(blockID)}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// PutAPIV1IpsReservedByIDStatus calls PUT /api/v1/ips/reserved/{id}/status.
+//
+// The operation is undocumented; its response is returned as raw JSON.
+func (c *Client) PutAPIV1IpsReservedByIDStatus(ctx context.Context, id string) (json.RawMessage, error) {
+	req := &request{method: "PUT", path: "/api/v1/ips/reserved/" + url.PathEscape(id) + "/status"}
+	var out json.RawMessage
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// QuarantineIPsInBulk calls POST /api/ips/bulk/quarantine.
+//
+// Quarantine IPs in bulk. Quarantine every IP with reputation metrics matching
+// the filter: a CIDR block, a pool and a status, all matching when several are
+// given. Archived IPs and IPs already quarantined or blacklisted are skipped.
+// With dry_run the BulkActionReport of what would change is returned at once;
+// otherwise the IPs are quarantined in the background and GET /api/jobs/{id}
+// reports the progress and the BulkActionReport. Every change is recorded in
+// the IP's action history as bulk_quarantine.
+func (c *Client) QuarantineIPsInBulk(ctx context.Context, body BulkActionRequest) (*BulkActionReport, error) {
+	req := &request{method: "POST", path: "/api/ips/bulk/quarantine"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(BulkActionReport)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RebuildReputationFromRawFailures calls POST /api/admin/reputation/rebuild.
+//
+// Rebuild reputation from raw failures. Recompute the reputation history, and
+// the current metrics when the range ends now, of the selected IPs from their
+// stored SMTP failures under the active config, in the background. History
+// records are replayed every step through the status state machine, starting
+// from the last status recorded before the range, and replace the records of
+// the range. Status changes are recorded as actions, without alerts or
+// automated measures. Poll GET /api/jobs/{id} for progress (IPs done of total)
+// and the reputation.RebuildResult.
+func (c *Client) RebuildReputationFromRawFailures(ctx context.Context, body *RebuildReputationRequest) (*Job, error) {
+	req := &request{method: "POST", path: "/api/admin/reputation/rebuild"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(Job)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReceiveAnITSMStatusWebhook calls POST /api/webhooks/itsm/{system}.
+//
+// Receive an ITSM status webhook. Jira issue webhooks (issue key and status;
+// the done category resolves) and ServiceNow business rule posts ({"number",
+// "state", "state_label"}) update the status of the tickets the rules opened.
+// Resolving a delisting task's ticket resolves the task. Reports about other
+// tickets are ignored. Authenticated with the shared ITSM webhook secret as
+// X-Webhook-Token, Bearer token or basic auth password.
+func (c *Client) ReceiveAnITSMStatusWebhook(ctx context.Context, system string) (*ITSMWebhookResponse, error) {
+	req := &request{method: "POST", path: "/api/webhooks/itsm/" + url.PathEscape(system)}
+	out := new(ITSMWebhookResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RecordSpamtrapHit calls POST /api/spamtrap-hits.
+//
+// Record spamtrap hit. Record mail from a sending IP that reached a spamtrap.
+// Hits are a critical signal: once an IP has spamtrap_quarantine_hits within
+// spamtrap_window_hours (runtime config, 1 within 24h by default) it is
+// quarantined at once, whatever its volume and rejection ratios, and stays
+// quarantined while the hits are in the window.
+func (c *Client) RecordSpamtrapHit(ctx context.Context, body SpamtrapHitRequest) (*SpamtrapHitResponse, error) {
+	req := &request{method: "POST", path: "/api/spamtrap-hits"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SpamtrapHitResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RefreshSession calls POST /auth/refresh.
+//
+// Refresh session. Exchange a refresh token for a new access token. The refresh
+// token is rotated and can only be used once.
+func (c *Client) RefreshSession(ctx context.Context, body RefreshRequest) (*TokenResponse, error) {
+	req := &request{method: "POST", path: "/auth/refresh"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(TokenResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterReputationFeed calls POST /api/reputation/feeds.
+//
+// Register reputation feed. Register a third-party reputation feed (threat
+// intel, partner blocklist) and return its token, which it posts verdicts with.
+// The token is only returned once.
+func (c *Client) RegisterReputationFeed(ctx context.Context, body CreateReputationFeedRequest) (*CreateReputationFeedResponse, error) {
+	req := &request{method: "POST", path: "/api/reputation/feeds"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(CreateReputationFeedResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterShadowReputationConfig calls PUT /api/reputation/shadow.
+//
+// Register shadow reputation config. Evaluate a second set of thresholds on
+// every aggregation run without acting on it. Fields left out of config keep
+// their live values.
+func (c *Client) RegisterShadowReputationConfig(ctx context.Context, body UpdateReputationConfigRequest) (*ShadowConfigResponse, error) {
+	req := &request{method: "PUT", path: "/api/reputation/shadow"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ShadowConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterWebhookSource calls POST /api/webhooks/sources.
+//
+// Register webhook source. Create a webhook source and return its token. The
+// token is only returned once.
+func (c *Client) RegisterWebhookSource(ctx context.Context, body CreateWebhookSourceRequest) (*CreateWebhookSourceResponse, error) {
+	req := &request{method: "POST", path: "/api/webhooks/sources"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(CreateWebhookSourceResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemediationModeHistoryParams are the query and header parameters of
+// RemediationModeHistory. Zero values are not sent.
+type RemediationModeHistoryParams struct {
+	// Number of changes (1-500)
+	Limit int
+}
+
+// RemediationModeHistory calls GET /api/system/remediation/history.
+//
+// Remediation mode history. Remediation mode changes, newest first
+func (c *Client) RemediationModeHistory(ctx context.Context, params *RemediationModeHistoryParams) ([]ConfigRevision, error) {
+	req := &request{method: "GET", path: "/api/system/remediation/history"}
+	if params != nil {
+		req.param("query", "limit", params.Limit)
+	}
+	var out []ConfigRevision
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// RemoveIPFromPool calls DELETE /api/pools/{id}/ips/{ip}.
+//
+// Remove IP from pool.
+func (c *Client) RemoveIPFromPool(ctx context.Context, id int, ip string) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/pools/" + url.PathEscape(fmt.Sprint(id)) + "/ips/" + url.PathEscape(ip)}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// RemoveShadowReputationConfig calls DELETE /api/reputation/shadow.
+//
+// Remove shadow reputation config. Stop evaluating the shadow thresholds
+func (c *Client) RemoveShadowReputationConfig(ctx context.Context) error {
+	req := &request{method: "DELETE", path: "/api/reputation/shadow"}
+	return c.do(ctx, req, nil)
+}
+
+// RemoveTeamMember calls DELETE /api/teams/{id}/members/{user_id}.
+//
+// Remove team member.
+func (c *Client) RemoveTeamMember(ctx context.Context, id int, userID int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/teams/" + url.PathEscape(fmt.Sprint(id)) + "/members/" + url.PathEscape(fmt.Sprint(userID))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ReplaceITSMRule calls PUT /api/itsm/rules/{id}.
+//
+// Replace ITSM rule.
+func (c *Client) ReplaceITSMRule(ctx context.Context, id int, body ITSMRuleRequest) (*ITSMRule, error) {
+	req := &request{method: "PUT", path: "/api/itsm/rules/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ITSMRule)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplaceNotificationRoute calls PUT /api/notification-routes/{id}.
+//
+// Replace notification route.
+func (c *Client) ReplaceNotificationRoute(ctx context.Context, id int, body NotificationRouteRequest) (*NotificationRoute, error) {
+	req := &request{method: "PUT", path: "/api/notification-routes/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(NotificationRoute)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplaceSCIMGroup calls PUT /scim/v2/Groups/{id}.
+//
+// Replace SCIM group.
+func (c *Client) ReplaceSCIMGroup(ctx context.Context, id int, body SCIMGroup) (*SCIMGroup, error) {
+	req := &request{method: "PUT", path: "/scim/v2/Groups/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMGroup)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplaceSCIMUser calls PUT /scim/v2/Users/{id}.
+//
+// Replace SCIM user.
+func (c *Client) ReplaceSCIMUser(ctx context.Context, id int, body SCIMUser) (*SCIMUser, error) {
+	req := &request{method: "PUT", path: "/scim/v2/Users/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SCIMUser)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReportSendingVolumes calls POST /api/volumes.
+//
+// Report sending volumes. Record how many messages IPs sent over periods, as
+// counted by a sending system. With the reported volume estimator
+// (volume.estimator: reported) these volumes are the total_sent rejection and
+// complaint ratios are divided by; periods only partly in a window count in
+// proportion to their overlap. Reporting the same IP, period and source again
+// replaces the earlier report.
+func (c *Client) ReportSendingVolumes(ctx context.Context, body SendingVolumesRequest) (*SendingVolumesResponse, error) {
+	req := &request{method: "POST", path: "/api/volumes"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SendingVolumesResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReputationConfigHistoryParams are the query and header parameters of
+// ReputationConfigHistory. Zero values are not sent.
+type ReputationConfigHistoryParams struct {
+	// Config scope
+	Scope string
+	// Number of revisions (1-500)
+	Limit int
+}
+
+// ReputationConfigHistory calls GET /api/reputation/config/history.
+//
+// Reputation config history. Config revisions, newest first
+func (c *Client) ReputationConfigHistory(ctx context.Context, params *ReputationConfigHistoryParams) ([]ConfigRevision, error) {
+	req := &request{method: "GET", path: "/api/reputation/config/history"}
+	if params != nil {
+		req.param("query", "scope", params.Scope)
+		req.param("query", "limit", params.Limit)
+	}
+	var out []ConfigRevision
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// ResolveNotificationTargetsParams are the query and header parameters of
+// ResolveNotificationTargets. Zero values are not sent.
+type ResolveNotificationTargetsParams struct {
+	// IP address
+	IP string
+	// info, warning or critical
+	Severity string
+}
+
+// ResolveNotificationTargets calls GET /api/notification-routes/resolve.
+//
+// Resolve notification targets. Preview where an alert about an IP at a
+// severity would be sent, using the IP's pools and owning teams. Targets are
+// masked for callers below admin.
+func (c *Client) ResolveNotificationTargets(ctx context.Context, params *ResolveNotificationTargetsParams) (*ResolveRoutesResponse, error) {
+	req := &request{method: "GET", path: "/api/notification-routes/resolve"}
+	if params != nil {
+		req.param("query", "ip", params.IP)
+		req.param("query", "severity", params.Severity)
+	}
+	out := new(ResolveRoutesResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResolveRecommendationsParams are the query and header parameters of
+// ResolveRecommendations. Zero values are not sent.
+type ResolveRecommendationsParams struct {
+	// healthy, warning, quarantine or blacklisted
+	Status string
+	// Issue type, e.g. spamtrap_hit
+	IssueType string
+	// Language of the texts
+	AcceptLanguage string
+}
+
+// ResolveRecommendations calls GET /api/recommendation-mappings/resolve.
+//
+// Resolve recommendations. Preview the recommendations of an IP with a status
+// and issue type, in the Accept-Language language
+func (c *Client) ResolveRecommendations(ctx context.Context, params *ResolveRecommendationsParams) (*ResolveRecommendationsResponse, error) {
+	req := &request{method: "GET", path: "/api/recommendation-mappings/resolve"}
+	if params != nil {
+		req.param("query", "status", params.Status)
+		req.param("query", "issue_type", params.IssueType)
+		req.param("header", "Accept-Language", params.AcceptLanguage)
+	}
+	out := new(ResolveRecommendationsResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreQuarantinedIPsInBulk calls POST /api/ips/bulk/restore.
+//
+// Restore quarantined IPs in bulk. Move every quarantined IP matching the
+// filter back to healthy, for the next aggregation to reassess. The filter, dry
+// run and job work as for POST /api/ips/bulk/quarantine; IPs not in quarantine
+// are skipped. Every change is recorded in the IP's action history as
+// bulk_restore.
+func (c *Client) RestoreQuarantinedIPsInBulk(ctx context.Context, body BulkActionRequest) (*BulkActionReport, error) {
+	req := &request{method: "POST", path: "/api/ips/bulk/restore"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(BulkActionReport)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreReputationState calls POST /api/system/restore.
+//
+// Restore reputation state. Restore an archive from GET /api/system/backup,
+// gzipped or plain JSON, into a deployment without reputation state. Rows keep
+// their IDs; the restored config and flags apply to this instance immediately
+// and to others on their next reload. Responds 409 when any of the tables
+// already has rows.
+func (c *Client) RestoreReputationState(ctx context.Context, body io.Reader) (*RestoreResponse, error) {
+	req := &request{method: "POST", path: "/api/system/restore"}
+	req.body, req.contentType = body, "application/gzip"
+	out := new(RestoreResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeAPIKey calls DELETE /api/auth/keys/{id}.
+//
+// Revoke API key. Revoke an API key. Requests with it are rejected from then
+// on; the key stays listed. Requires the admin role.
+func (c *Client) RevokeAPIKey(ctx context.Context, id int) (map[string]interface{}, error) {
+	req := &request{method: "DELETE", path: "/api/auth/keys/" + url.PathEscape(fmt.Sprint(id))}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// RollBackReputationConfig calls POST
+// /api/reputation/config/revisions/{id}/rollback.
+//
+// Roll back reputation config. Make an earlier revision active again. The
+// rollback is recorded as a new revision.
+func (c *Client) RollBackReputationConfig(ctx context.Context, id int, body *RollbackRequest) (*ReputationConfigResponse, error) {
+	req := &request{method: "POST", path: "/api/reputation/config/revisions/" + url.PathEscape(fmt.Sprint(id)) + "/rollback"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ReputationConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunASingleTestCase calls POST /api/testing/test-cases/{id}/run.
+//
+// Run a single test case. Execute a single test case by ID and return the
+// result
+func (c *Client) RunASingleTestCase(ctx context.Context, id string) (*TestResult, error) {
+	req := &request{method: "POST", path: "/api/testing/test-cases/" + url.PathEscape(id) + "/run"}
+	out := new(TestResult)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunAllTestCases calls POST /api/testing/test-suite/run.
+//
+// Run all test cases. Execute all predefined test cases and return
+// comprehensive results
+func (c *Client) RunAllTestCases(ctx context.Context) (*TestSuiteResult, error) {
+	req := &request{method: "POST", path: "/api/testing/test-suite/run"}
+	out := new(TestSuiteResult)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunAudit calls POST /api/system/audit/run.
+//
+// Run audit. Run the self-audit now instead of waiting for the schedule, and
+// return its report. Checks above their threshold alert as on a scheduled run.
+func (c *Client) RunAudit(ctx context.Context) (*AuditReport, error) {
+	req := &request{method: "POST", path: "/api/system/audit/run"}
+	out := new(AuditReport)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveRecommendation calls PUT /api/recommendations/{action}.
+//
+// Save recommendation. Create or replace a catalog entry. Texts override the
+// built-in text of the action per language; languages without one keep the
+// built-in translation, or the English text for actions without a built-in
+// text.
+func (c *Client) SaveRecommendation(ctx context.Context, action string, body RecommendationRequest) (*DatabaseRecommendation, error) {
+	req := &request{method: "PUT", path: "/api/recommendations/" + url.PathEscape(action)}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(DatabaseRecommendation)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SCIMServiceProviderConfiguration calls GET /scim/v2/ServiceProviderConfig.
+//
+// SCIM service provider configuration.
+func (c *Client) SCIMServiceProviderConfiguration(ctx context.Context) (map[string]interface{}, error) {
+	req := &request{method: "GET", path: "/scim/v2/ServiceProviderConfig"}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// SetIPRateLimit calls PUT /api/ips/{ip}/rate-limit.
+//
+// Set IP rate limit. Cap the IP's outbound rate at messages_per_minute for ttl,
+// replacing an earlier cap. The cap is served in the sending policy and pushed
+// to Stalwart's throttling hook; when it expires the IP goes back to its
+// recommended rates and the lifting is pushed too. A failed push does not fail
+// the request; pushed is false then.
+func (c *Client) SetIPRateLimit(ctx context.Context, ip string, body SetRateLimitRequest) (*RateLimitResponse, error) {
+	req := &request{method: "PUT", path: "/api/ips/" + url.PathEscape(ip) + "/rate-limit"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(RateLimitResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetRemediationMode calls PUT /api/system/remediation.
+//
+// Set remediation mode. Switch automated remediation to off, dry-run or
+// enforce. The change is stored as a config revision with its author, applies
+// right away on this instance and reaches every other instance within 30
+// seconds.
+func (c *Client) SetRemediationMode(ctx context.Context, body UpdateRemediationModeRequest) (*RemediationModeResponse, error) {
+	req := &request{method: "PUT", path: "/api/system/remediation"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(RemediationModeResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetTheOnCallContextOfAnIP calls PUT /api/ips/{ip}/context.
+//
+// Set the on-call context of an IP. Replace the notes and runbook URL of an IP,
+// e.g. whose mail it sends and what to do when it alerts. They are shown in the
+// registry, the IP health dashboard and alerts.
+func (c *Client) SetTheOnCallContextOfAnIP(ctx context.Context, ip string, body SetIPContextRequest) (*IPContext, error) {
+	req := &request{method: "PUT", path: "/api/ips/" + url.PathEscape(ip) + "/context"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(IPContext)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetUserRole calls PUT /users/{id}/role.
+//
+// Set user role. Change what a user may do: read-only, operator or admin.
+// Requires the admin role. The new role applies from the user's next login or
+// session refresh.
+func (c *Client) SetUserRole(ctx context.Context, id int, body UpdateUserRoleRequest) (*User, error) {
+	req := &request{method: "PUT", path: "/users/" + url.PathEscape(fmt.Sprint(id)) + "/role"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(User)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SimulateAReputationDecision calls POST /api/reputation/simulate.
+//
+// Simulate a reputation decision. Run the decision engine on a hand-written
+// health check and candidate thresholds. Nothing is read from or written to the
+// database.
+func (c *Client) SimulateAReputationDecision(ctx context.Context, body ReputationSimulationRequest) (*ReputationSimulationResponse, error) {
+	req := &request{method: "POST", path: "/api/reputation/simulate"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ReputationSimulationResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SimulateSMTPFailuresForTesting calls POST /api/testing/simulate-failures.
+//
+// Simulate SMTP failures for testing. Simulate SMTP failures for testing the
+// reputation system
+func (c *Client) SimulateSMTPFailuresForTesting(ctx context.Context, body FailureSimulationPayload) (map[string]interface{}, error) {
+	req := &request{method: "POST", path: "/api/testing/simulate-failures"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	err := c.do(ctx, req, &out)
+	return out, err
+}
+
+// SwapIPForACleanReservedIP calls POST /api/ips/{ip}/swap.
+//
+// Swap IP for a clean reserved IP. Put the least used clean reserved IP of the
+// same location in use for the IP's assignment and quarantine the IP, which
+// returns to the reserve once its reputation recovers. Both sides are recorded
+// in the IP actions. With dry_run the replacement is picked but nothing
+// changes. The replacement still has to be attached to the server's NIC.
+func (c *Client) SwapIPForACleanReservedIP(ctx context.Context, ip string, body *SwapIPRequest) (*SwapResult, error) {
+	req := &request{method: "POST", path: "/api/ips/" + url.PathEscape(ip) + "/swap"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(SwapResult)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ToggleAFeatureFlag calls PUT /api/system/features/{name}.
+//
+// Toggle a feature flag. Turn a flag on or off at runtime. The toggle overrides
+// the config default on every instance and is recorded with its author.
+func (c *Client) ToggleAFeatureFlag(ctx context.Context, name string, body ToggleFeatureFlagRequest) (*ToggleFeatureFlagResponse, error) {
+	req := &request{method: "PUT", path: "/api/system/features/" + url.PathEscape(name)}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ToggleFeatureFlagResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnarchiveAnIP calls DELETE /api/ips/{ip}/archive.
+//
+// Unarchive an IP. Put an archived IP back into aggregation, alerting and the
+// dashboards
+func (c *Client) UnarchiveAnIP(ctx context.Context, ip string) error {
+	req := &request{method: "DELETE", path: "/api/ips/" + url.PathEscape(ip) + "/archive"}
+	return c.do(ctx, req, nil)
+}
+
+// UnifiedIPRegistryParams are the query and header parameters of
+// UnifiedIPRegistry. Zero values are not sent.
+type UnifiedIPRegistryParams struct {
+	// Filter by composite state (archived, released, blacklisted, quarantined,
+	// warning, healthy, unassessed)
+	State string
+	// Only IPs in this pool
+	Pool string
+	// Include archived IPs; implied by state=archived
+	IncludeArchived bool
+}
+
+// UnifiedIPRegistry calls GET /api/ips/registry.
+//
+// Unified IP registry. One row per IP joining its reservation, reputation
+// metrics, latest DNSBL check, pools, owners and on-call context (notes and
+// runbook URL), with a composite state
+func (c *Client) UnifiedIPRegistry(ctx context.Context, params *UnifiedIPRegistryParams) (*IPRegistryResponse, error) {
+	req := &request{method: "GET", path: "/api/ips/registry"}
+	if params != nil {
+		req.param("query", "state", params.State)
+		req.param("query", "pool", params.Pool)
+		req.param("query", "include_archived", params.IncludeArchived)
+	}
+	out := new(IPRegistryResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateADelistingTask calls PATCH /api/delisting-tasks/{id}.
+//
+// Update a delisting task. Move a task to submitted once the request is sent to
+// the provider, or to resolved once the IP is delisted; the change is recorded
+// in the IP's actions. Resolved tasks cannot be changed.
+func (c *Client) UpdateADelistingTask(ctx context.Context, id int, body UpdateDelistingTaskRequest) (*DelistingTask, error) {
+	req := &request{method: "PATCH", path: "/api/delisting-tasks/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(DelistingTask)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateReputationConfig calls PUT /api/reputation/config.
+//
+// Update reputation config. Change thresholds at runtime. The change is stored
+// as a new revision with its author and diff.
+func (c *Client) UpdateReputationConfig(ctx context.Context, body UpdateReputationConfigRequest) (*ReputationConfigResponse, error) {
+	req := &request{method: "PUT", path: "/api/reputation/config"}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ReputationConfigResponse)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateReputationFeed calls PATCH /api/reputation/feeds/{id}.
+//
+// Update reputation feed. Change a feed's description, weight or enabled flag.
+// A disabled feed's token is refused and its verdicts stop counting; they are
+// kept.
+func (c *Client) UpdateReputationFeed(ctx context.Context, id int, body UpdateReputationFeedRequest) (*ReputationFeed, error) {
+	req := &request{method: "PATCH", path: "/api/reputation/feeds/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(ReputationFeed)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateUser calls PUT /users/{id}.
+//
+// Update user. Change a user's username, email and/or password. Omitted fields
+// are left unchanged. Changing the password ends the user's sessions.
+func (c *Client) UpdateUser(ctx context.Context, id int, body UpdateUserRequest) (*User, error) {
+	req := &request{method: "PUT", path: "/users/" + url.PathEscape(fmt.Sprint(id))}
+	if err := req.jsonBody(body); err != nil {
+		return nil, err
+	}
+	out := new(User)
+	if err := c.do(ctx, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,44 @@
+// Command openapigen writes the OpenAPI 3.1 description of the API. Paths
+// and methods come from the registered routes; summaries, parameters and
+// types from the handlers' swag comments.
+//
+//	go run ./cmd/openapigen -o internal/api/openapi.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"golang-backend-service/internal/api"
+	"golang-backend-service/internal/openapi"
+)
+
+func main() {
+	root := flag.String("root", ".", "Module root")
+	out := flag.String("o", "internal/api/openapi.json", "Output file")
+	strict := flag.Bool("strict", false, "Fail when a route has no @Router comment or a comment matches no route")
+	flag.Parse()
+
+	opts := api.OpenAPISources
+	opts.Root = *root
+	doc, problems, err := openapi.Generate(api.DocumentedRoutes(), opts)
+	if err != nil {
+		log.Fatalf("openapigen: %v", err)
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "openapigen: %s\n", problem)
+	}
+
+	data, err := doc.MarshalIndent()
+	if err != nil {
+		log.Fatalf("openapigen: %v", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("openapigen: %v", err)
+	}
+	if *strict && len(problems) > 0 {
+		log.Fatalf("openapigen: %d documentation problems", len(problems))
+	}
+}
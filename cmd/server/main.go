@@ -2,19 +2,40 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"golang-backend-service/internal/alerting"
 	"golang-backend-service/internal/api"
+	"golang-backend-service/internal/audit"
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/bus"
 	"golang-backend-service/internal/config"
 	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/dependencies"
+	"golang-backend-service/internal/faults"
+	"golang-backend-service/internal/features"
+	"golang-backend-service/internal/i18n"
 	"golang-backend-service/internal/ionos"
+	"golang-backend-service/internal/itsm"
+	"golang-backend-service/internal/jobs"
 	"golang-backend-service/internal/logger"
+	"golang-backend-service/internal/privacy"
+	"golang-backend-service/internal/redact"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reporting"
 	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/reputation/state"
+	"golang-backend-service/internal/resolver"
+	"golang-backend-service/internal/slo"
+	"golang-backend-service/internal/stalwart"
+	"golang-backend-service/internal/tlspolicy"
 
 	_ "golang-backend-service/docs"
 
@@ -28,6 +49,13 @@ import (
 // @contact.email support@example.com
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Dashboard access token from /auth/login, as "Bearer <token>"
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -36,11 +64,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Set up redaction of recipient data before anything is logged
+	redactionMode, err := redact.ParseMode(cfg.Privacy.RedactionMode)
+	if err != nil {
+		fmt.Printf("Invalid redaction mode: %v\n", err)
+		os.Exit(1)
+	}
+	redactor, err := redact.New(redactionMode, cfg.Privacy.RedactionKey)
+	if err != nil {
+		fmt.Printf("Failed to initialize redaction: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Set up error reporting so the logger can forward errors to it
+	errorReporter := reporting.Nop
+	if cfg.Sentry.DSN != "" {
+		environment := cfg.Sentry.Environment
+		if environment == "" {
+			environment = cfg.Environment
+		}
+		sentry, err := reporting.NewSentry(reporting.SentryOptions{
+			DSN:         cfg.Sentry.DSN,
+			Environment: environment,
+			Release:     cfg.Sentry.Release,
+		})
+		if err != nil {
+			fmt.Printf("Failed to initialize Sentry: %v\n", err)
+			os.Exit(1)
+		}
+		defer sentry.Close()
+		errorReporter = sentry
+	}
+
 	// Initialize logger
-	if err := logger.Init(cfg.Logger.Level); err != nil {
+	moduleLevels, err := logger.ParseModuleLevels(cfg.Logger.Modules)
+	if err != nil {
+		fmt.Printf("Failed to parse module log levels: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logger.Setup(logger.Options{
+		Level:        cfg.Logger.Level,
+		Format:       cfg.Logger.Format,
+		Modules:      moduleLevels,
+		OTLPEndpoint: cfg.Logger.OTLPEndpoint,
+		Redact:       redactor.LogAttr,
+		Reporter:     errorReporter,
+	}); err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
+	defer logger.Close()
 
 	logger.Info("Starting GoLang Backend Service")
 	logger.WithFields(logrus.Fields{
@@ -48,26 +121,143 @@ func main() {
 		"port":    cfg.Server.Port,
 	}).Info("Service configuration loaded")
 
+	// Failure injection for staging; a nil resolver is the system DNS
+	var dnsResolver resolver.Resolver
+	if cfg.Chaos.Enabled {
+		if faults.AllowedIn(cfg.Environment) {
+			faults.Default.SetEnabled(true)
+			database.SetFaultHook(func(ctx context.Context) error {
+				return faults.Default.Inject(ctx, faults.Postgres)
+			})
+			dnsResolver = faults.Default.Resolver(faults.DNS, &net.Resolver{PreferGo: true})
+			logger.WithFields(logrus.Fields{
+				"targets": faults.Targets(),
+			}).Warn("Failure injection is enabled; faults set through /api/system/faults affect real traffic")
+		} else {
+			logger.WithFields(logrus.Fields{
+				"environment": cfg.Environment,
+			}).Error("CHAOS_ENABLED is ignored in production")
+		}
+	}
+
 	// Connect to database
+	database.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
 	dsn := cfg.GetDatabaseDSN()
-	if err := database.Connect(dsn, logger.Log); err != nil {
+	if err := database.Connect(dsn, logger.Module("database")); err != nil {
 		logger.WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Fatal("Failed to connect to database")
 	}
 	defer database.Close()
+	database.SetSubdomainFolding(cfg.Webhooks.FoldSubdomains)
+	database.SetContentDedupWindow(cfg.Webhooks.ContentDedupWindow)
+	database.SetFailureClassifier(reputation.FailureKind)
+	dependencies.Default.Register(dependencies.Postgres, dependencies.KindDatabase, database.Ping)
+
+	// Feature flags: config defaults, overridden by toggles stored in the database
+	if err := features.Default.Configure(map[string]bool{
+		features.AutoRotation:      cfg.Features.AutoRotation,
+		features.StrictWebhookAuth: cfg.Webhooks.RequireSourceAuth,
+		features.ShadowRules:       cfg.Features.ShadowRules,
+		features.FailureCounters:   cfg.Features.FailureCounters,
+	}); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid feature flag config")
+	}
+	if err := features.Default.Start(30*time.Second, logger.Module("features")); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Failed to start feature flag refresh")
+	}
+	defer features.Default.Stop()
+
+	// Remediation mode: config default, overridden by the newest stored mode,
+	// which every instance reloads
+	remediationMode, err := remediation.ParseMode(cfg.Remediation.Mode)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid remediation config")
+	}
+	if err := remediation.Start(30*time.Second, remediationMode, logger.Module("remediation")); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Failed to start remediation mode refresh")
+	}
+	defer remediation.Stop()
+	logger.WithFields(logrus.Fields{
+		"mode": string(remediation.Current()),
+	}).Info("Automated remediation mode")
 
 	// Initialize IONOS service if token is configured
 	var ionosService *ionos.Service
+	var ionosBlocks audit.BlockLister
 	if cfg.Ionos.Token != "" {
 		logger.Info("Initializing IONOS IP Reservation service")
-		ionosClient := ionos.NewClient(cfg.Ionos.APIURL, cfg.Ionos.Token, logger.Log)
+		ionosClient := ionos.NewClient(cfg.Ionos.APIURL, cfg.Ionos.Token, logger.Module("ionos"))
+		ionosBlocks = ionosClient
 		ionosService = ionos.NewService(
 			ionosClient,
-			logger.Log,
+			logger.Module("ionos"),
 			cfg.Ionos.DefaultLocation,
 			cfg.Ionos.MaxQuota,
 		)
+		dependencies.Default.Register(dependencies.IONOS, dependencies.KindAPI, func(ctx context.Context) error {
+			_, err := ionosClient.ListIPBlocks(ctx)
+			return err
+		})
+
+		webhookURLs, err := ionos.ParseWebhookURLs(cfg.Ionos.ReservationWebhooks)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("Invalid IONOS_RESERVATION_WEBHOOKS")
+		}
+		if len(webhookURLs) > 0 {
+			publisher := ionos.NewWebhookPublisher(webhookURLs, cfg.Ionos.ReservationWebhookSecret, logger.Module("ionos"))
+			defer publisher.Close()
+			ionosService.SetPublisher(publisher)
+			dependencies.Default.Register(dependencies.ReservationWebhooks, dependencies.KindAPI, nil)
+			logger.WithFields(logrus.Fields{
+				"webhooks": len(webhookURLs),
+			}).Info("Reservation webhooks enabled")
+		}
+		ionosService.SetVerification(ionos.VerificationConfig{
+			PTR:        cfg.Ionos.VerifyPTR,
+			SMTPTarget: cfg.Ionos.VerifySMTPTarget,
+			MinScore:   cfg.Ionos.VerifyMinScore,
+		})
+		if cfg.Ionos.StaleAfter > 0 {
+			ionosService.SetStalePolicy(ionos.StalePolicy{
+				MaxIdle:     cfg.Ionos.StaleAfter,
+				GracePeriod: cfg.Ionos.StaleGracePeriod,
+				AutoRelease: cfg.Ionos.StaleAutoRelease,
+			})
+			staleService := ionos.NewStaleReservationService(ionosService, logger.Module("ionos"))
+			interval := cfg.Ionos.StaleCheckInterval
+			if interval <= 0 {
+				interval = time.Hour
+			}
+			if err := staleService.Start(interval); err != nil {
+				logger.WithFields(logrus.Fields{
+					"error": err.Error(),
+				}).Warn("Failed to start stale reservation service")
+			}
+			defer staleService.Stop()
+		}
+		state.Default.AddHook(ionosService.ReputationHook())
+		// After ReputationHook, so the swap sees the IP already quarantined
+		state.Default.AddHook(ionosService.SwapHook())
+		if cfg.Ionos.QuarantineReconcileInterval > 0 {
+			reconciler := ionos.NewQuarantineReconciliationService(ionosService, logger.Module("ionos"))
+			if err := reconciler.Start(cfg.Ionos.QuarantineReconcileInterval); err != nil {
+				logger.WithFields(logrus.Fields{
+					"error": err.Error(),
+				}).Warn("Failed to start quarantine reconciliation service")
+			}
+			defer reconciler.Stop()
+		}
 		logger.WithFields(logrus.Fields{
 			"api_url":          cfg.Ionos.APIURL,
 			"default_location": cfg.Ionos.DefaultLocation,
@@ -77,12 +267,122 @@ func main() {
 		logger.Warn("IONOS_TOKEN not configured. IP reservation endpoints will not be available.")
 	}
 
-	// Set up routes
-	router := api.SetupRoutesWithDependencies(ionosService)
+	// Open Jira and ServiceNow tickets for the ITSM rules
+	var itsmService *itsm.Service
+	itsmClients := map[string]itsm.Client{}
+	if cfg.ITSM.Jira.BaseURL != "" {
+		itsmClients[itsm.SystemJira] = itsm.NewJiraClient(itsm.JiraConfig{
+			BaseURL:           cfg.ITSM.Jira.BaseURL,
+			User:              cfg.ITSM.Jira.User,
+			Token:             cfg.ITSM.Jira.Token,
+			IssueType:         cfg.ITSM.Jira.IssueType,
+			ResolveTransition: cfg.ITSM.Jira.ResolveTransition,
+		})
+		dependencies.Default.Register(dependencies.Jira, dependencies.KindAPI, nil)
+	}
+	if cfg.ITSM.ServiceNow.InstanceURL != "" {
+		itsmClients[itsm.SystemServiceNow] = itsm.NewServiceNowClient(itsm.ServiceNowConfig{
+			InstanceURL:   cfg.ITSM.ServiceNow.InstanceURL,
+			User:          cfg.ITSM.ServiceNow.User,
+			Password:      cfg.ITSM.ServiceNow.Password,
+			Table:         cfg.ITSM.ServiceNow.Table,
+			ResolvedState: cfg.ITSM.ServiceNow.ResolvedState,
+			CloseCode:     cfg.ITSM.ServiceNow.CloseCode,
+		})
+		dependencies.Default.Register(dependencies.ServiceNow, dependencies.KindAPI, nil)
+	}
+	if len(itsmClients) > 0 {
+		itsmService = itsm.NewService(itsmClients, nil, logger.Module("itsm"))
+		defer itsmService.Close()
+		state.Default.AddHook(itsmService.ReputationHook())
+		if cfg.ITSM.WebhookSecret == "" {
+			logger.Warn("ITSM_WEBHOOK_SECRET not set. Ticket status webhooks will be refused.")
+		}
+		logger.WithFields(logrus.Fields{
+			"systems": itsmService.Systems(),
+		}).Info("ITSM integration enabled")
+	}
 
 	// Start IP reputation aggregation service
-	reputationConfig := reputation.DefaultReputationConfig()
-	aggregationService := reputation.NewAggregationService(reputationConfig)
+	reputationConfig, revision, err := reputation.LoadActiveConfig()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Failed to load reputation config revision, using defaults")
+	} else if revision != nil {
+		logger.WithFields(logrus.Fields{
+			"revision_id": revision.ID,
+			"author":      revision.Author,
+		}).Info("Loaded reputation config revision")
+	}
+	aggregationService := reputation.NewAggregationService(reputationConfig, logger.Module("reputation"))
+	if shadow, revision, err := reputation.LoadShadowConfig(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Failed to load shadow reputation config")
+	} else if shadow != nil {
+		aggregationService.SetShadowConfig(shadow)
+		logger.WithFields(logrus.Fields{
+			"revision_id": revision.ID,
+		}).Info("Evaluating shadow reputation config")
+	}
+	if cfg.Lookups.Enabled {
+		providers, err := reputation.NewLookupProviders(cfg.Lookups.Providers, dnsResolver)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("Invalid external reputation lookup configuration")
+		}
+		aggregationService.SetLookupService(reputation.NewLookupService(providers, cfg.Lookups.CacheTTL, cfg.Lookups.Timeout, logger.Module("reputation")))
+	}
+	volumeEstimators, err := reputation.NewVolumeEstimators(cfg.Volume.Estimator, reputation.VolumeOptions{
+		PrometheusURL:   cfg.Volume.Prometheus.URL,
+		PrometheusQuery: cfg.Volume.Prometheus.Query,
+		Timeout:         cfg.Volume.Prometheus.Timeout,
+	})
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid volume estimator configuration")
+	}
+	for _, estimator := range volumeEstimators {
+		if estimator.Name() == reputation.VolumeFromPrometheus {
+			dependencies.Default.Register(dependencies.Prometheus, dependencies.KindAPI, nil)
+		}
+	}
+	aggregationService.SetVolumeEstimator(volumeEstimators...)
+	alertSeverities, err := alerting.ParseSeverities(cfg.Alerting.Severities)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid alert severities")
+	}
+	alertNotifiers := map[string]alerting.Notifier{
+		alerting.NotifierSlack:     alerting.NewSlackNotifier(cfg.Alerting.Slack.WebhookURL, cfg.Alerting.Timeout),
+		alerting.NotifierWebhook:   alerting.NewWebhookNotifier(cfg.Alerting.Webhook.Secret, cfg.Alerting.Timeout),
+		alerting.NotifierPagerDuty: alerting.NewPagerDutyNotifier(cfg.Alerting.PagerDuty.EventsURL, cfg.Alerting.Timeout),
+	}
+	dependencies.Default.Register(dependencies.Slack, dependencies.KindAlert, nil)
+	dependencies.Default.Register(dependencies.AlertWebhooks, dependencies.KindAlert, nil)
+	dependencies.Default.Register(dependencies.PagerDuty, dependencies.KindAlert, nil)
+	if cfg.Alerting.Email.Host != "" {
+		alertNotifiers[alerting.NotifierEmail] = alerting.NewEmailNotifier(alerting.EmailConfig{
+			Host:     cfg.Alerting.Email.Host,
+			Port:     cfg.Alerting.Email.Port,
+			Username: cfg.Alerting.Email.Username,
+			Password: cfg.Alerting.Email.Password,
+			From:     cfg.Alerting.Email.From,
+		})
+		dependencies.Default.Register(dependencies.SMTP, dependencies.KindAlert, nil)
+	}
+	alertRouter := alerting.NewRouter(nil)
+	alertDispatcher := alerting.NewDispatcher(alertRouter, alertNotifiers, alerting.Config{
+		Severities: alertSeverities,
+		Attempts:   cfg.Alerting.Attempts,
+		RetryDelay: cfg.Alerting.RetryDelay,
+		Timeout:    cfg.Alerting.Timeout,
+	}, logger.Module("alerting"))
+	aggregationService.SetAlertDispatcher(alertDispatcher)
 	if err := aggregationService.Start(5); err != nil {
 		logger.WithFields(logrus.Fields{
 			"error": err.Error(),
@@ -92,14 +392,294 @@ func main() {
 	}
 	defer aggregationService.Stop()
 
+	// Start PII retention service
+	if cfg.Privacy.AnonymizeAfter > 0 {
+		retentionService := privacy.NewRetentionService(cfg.Privacy.AnonymizeAfter, logger.Module("privacy"))
+		interval := cfg.Privacy.AnonymizeInterval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		if err := retentionService.Start(interval); err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Failed to start PII retention service")
+		}
+		defer retentionService.Stop()
+	} else {
+		logger.Warn("PII_ANONYMIZE_AFTER is 0. Recipient data will be kept until deleted.")
+	}
+
+	// Track the fleet service level objectives
+	sloConfig := slo.Config{
+		Target:     cfg.SLO.Target,
+		Window:     cfg.SLO.Window,
+		BurnWindow: cfg.SLO.BurnWindow,
+		Retention:  cfg.SLO.Retention,
+	}
+	for _, o := range cfg.SLO.Objectives {
+		sloConfig.Objectives = append(sloConfig.Objectives, slo.Objective{Name: o.Name, Metric: o.Metric, Min: o.Min, Max: o.Max})
+	}
+	if len(sloConfig.Objectives) == 0 {
+		sloConfig.Objectives = slo.DefaultConfig().Objectives
+	}
+	if err := sloConfig.Validate(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid SLO configuration")
+	}
+	sloTracker := slo.NewTracker(sloConfig, logger.Module("slo"))
+	if cfg.SLO.Enabled {
+		if err := sloTracker.Start(cfg.SLO.Interval); err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Failed to start SLO tracker")
+		}
+		defer sloTracker.Stop()
+	}
+
+	// Audit stale data and inconsistencies on a schedule
+	auditConfig := audit.Config{
+		StaleMetricsAfter: cfg.Audit.StaleMetricsAfter,
+		DNSBLMaxAge:       cfg.Audit.DNSBLMaxAge,
+		SampleSize:        cfg.Audit.SampleSize,
+		Retention:         cfg.Audit.Retention,
+		Thresholds: map[string]int{
+			audit.CheckStaleMetrics:    cfg.Audit.Thresholds.StaleMetrics,
+			audit.CheckMissingBlocks:   cfg.Audit.Thresholds.MissingIONOSBlocks,
+			audit.CheckStaleDNSBL:      cfg.Audit.Thresholds.StaleDNSBLChecks,
+			audit.CheckOrphanedActions: cfg.Audit.Thresholds.OrphanedActions,
+		},
+	}
+	if err := auditConfig.Validate(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid audit configuration")
+	}
+	auditor := audit.NewAuditor(auditConfig, ionosBlocks, logger.Module("audit"))
+	if cfg.Audit.Enabled {
+		if err := auditor.Start(cfg.Audit.Interval); err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Failed to start self-audit")
+		}
+		defer auditor.Stop()
+	}
+
+	// Push IP rate caps to Stalwart and lift them once expired
+	var stalwartPusher stalwart.Pusher
+	if cfg.Stalwart.ThrottleHookURL != "" {
+		stalwartPusher = stalwart.NewClient(cfg.Stalwart.ThrottleHookURL, cfg.Stalwart.ThrottleHookToken, cfg.Stalwart.Timeout)
+		dependencies.Default.Register(dependencies.Stalwart, dependencies.KindAPI, nil)
+	}
+	rateLimitExpirer := stalwart.NewExpirer(stalwartPusher, logger.Module("stalwart"))
+	if err := rateLimitExpirer.Start(cfg.Stalwart.ExpiryInterval); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Failed to start rate limit expirer")
+	}
+	defer rateLimitExpirer.Stop()
+
+	// Cache destination TLS policies for annotating failures
+	var tlsPolicies *tlspolicy.Cache
+	if cfg.TLSPolicy.Enabled {
+		var tlsa tlspolicy.TLSAChecker
+		if cfg.TLSPolicy.DNSSECResolver != "" {
+			tlsa = tlspolicy.NewDNSSECTLSA(cfg.TLSPolicy.DNSSECResolver, cfg.TLSPolicy.Timeout)
+		} else {
+			logger.Warn("TLS_POLICY_DNSSEC_RESOLVER not set. DANE policies will not be detected.")
+		}
+		policyResolver := tlspolicy.NewResolver(nil, tlsa, tlspolicy.NewHTTPFetcher(cfg.TLSPolicy.Timeout))
+		tlsPolicies = tlspolicy.NewCache(policyResolver, cfg.TLSPolicy.CacheTTL, cfg.TLSPolicy.Timeout, logger.Module("tlspolicy"))
+	}
+
+	// Start the message bus consumer
+	stopBus := func() {}
+	switch cfg.Bus.Driver {
+	case "":
+	case bus.DriverNATS:
+		consumer, err := bus.NewNATSConsumer(bus.NATSConfig{
+			URL:      cfg.Bus.URL,
+			Stream:   cfg.Bus.Stream,
+			Consumer: cfg.Bus.Consumer,
+			Name:     "serviceapp",
+		})
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("Invalid message bus configuration")
+		}
+		ingester := api.NewReputationHandler(aggregationService, nil, tlsPolicies, redactor, logger.Module("bus"))
+		busRunner := bus.NewRunner(cfg.Bus.Stream, consumer, func(data []byte) error {
+			return ingester.IngestMessage(cfg.Bus.Driver+":"+cfg.Bus.Stream, data)
+		}, cfg.Bus.BatchSize, cfg.Bus.FetchWait, logger.Module("bus"))
+		if err := busRunner.Start(); err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Failed to start message bus consumer")
+		}
+		defer busRunner.Stop()
+		stopBus = busRunner.Stop
+	default:
+		logger.WithFields(logrus.Fields{
+			"driver": cfg.Bus.Driver,
+		}).Fatal("Unsupported BUS_DRIVER")
+	}
+
+	// Set up routes
+	routeSampleRates, err := api.ParseRouteSampleRates(cfg.Logger.AccessLog.Routes)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid access log route sample rates")
+	}
+
+	routeTimeouts, err := api.ParseRouteTimeouts(cfg.Server.RouteTimeouts)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid route timeouts")
+	}
+
+	cors := api.DefaultCORSConfig(cfg.Environment)
+	if origins := api.ParseCORSList(cfg.Server.CORS.AllowedOrigins); len(origins) > 0 {
+		cors.AllowedOrigins = origins
+	}
+	if methods := api.ParseCORSList(cfg.Server.CORS.AllowedMethods); len(methods) > 0 {
+		cors.AllowedMethods = methods
+	}
+	if headers := api.ParseCORSList(cfg.Server.CORS.AllowedHeaders); len(headers) > 0 {
+		cors.AllowedHeaders = headers
+	}
+	if exposed := api.ParseCORSList(cfg.Server.CORS.ExposedHeaders); len(exposed) > 0 {
+		cors.ExposedHeaders = exposed
+	}
+	if cfg.Server.CORS.MaxAge > 0 {
+		cors.MaxAge = cfg.Server.CORS.MaxAge
+	}
+	cors.AllowCredentials = cfg.Server.CORS.AllowCredentials
+	if err := cors.Validate(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"environment": cfg.Environment,
+			"error":       err.Error(),
+		}).Fatal("Invalid CORS configuration")
+	}
+	if len(cors.AllowedOrigins) == 0 {
+		logger.WithFields(logrus.Fields{
+			"environment": cfg.Environment,
+		}).Info("CORS_ALLOWED_ORIGINS is empty. Cross-origin requests will be refused.")
+	}
+
+	languages, err := i18n.NewBundle(i18n.ParseLanguages(cfg.I18n.Languages), cfg.I18n.DefaultLanguage)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid I18N_LANGUAGES")
+	}
+
+	webhookSecrets, err := api.ParseWebhookSecrets(cfg.Webhooks.Signatures.SourceSecrets)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid WEBHOOK_SOURCE_SIGNING_SECRETS")
+	}
+
+	apiKeys, err := auth.ParseAPIKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid AUTH_API_KEYS")
+	}
+	jwtSecret := []byte(cfg.Auth.JWTSecret)
+	if len(jwtSecret) == 0 {
+		logger.Warn("AUTH_JWT_SECRET not set. Using a random key; dashboard sessions end on restart.")
+		jwtSecret = make([]byte, 32)
+		if _, err := rand.Read(jwtSecret); err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("Failed to generate JWT secret")
+		}
+	}
+	tokenIssuer, err := auth.NewTokenIssuer(jwtSecret, cfg.Auth.AccessTokenTTL)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("Invalid auth configuration")
+	}
+	if !cfg.Auth.Required {
+		logger.Warn("AUTH_REQUIRED is false. API endpoints accept unauthenticated requests.")
+	}
+
+	dnsblService := reputation.NewDNSBLServiceWithResolver(logger.Module("reputation"), dnsResolver)
+	dependencies.Default.Register(dependencies.DNS, dependencies.KindDNS, dnsblService.Probe)
+
+	jobRunner := jobs.NewRunner(nil, jobs.Config{
+		Workers:        cfg.Jobs.Workers,
+		Timeout:        cfg.Jobs.Timeout,
+		Retention:      cfg.Jobs.Retention,
+		CallbackSecret: cfg.Jobs.CallbackSecret,
+	}, logger.Module("jobs"))
+
+	drainer := api.NewDrainer(0)
+	router := api.SetupRoutesWithDependencies(api.Dependencies{
+		Logger:       logger.Module("api"),
+		IonosService: ionosService,
+		Aggregator:   aggregationService,
+		DNSBL:        dnsblService,
+		Redactor:     redactor,
+		CORS:         &cors,
+		Auth: &api.AuthConfig{
+			Required:    cfg.Auth.Required,
+			Issuer:      tokenIssuer,
+			APIKeys:     apiKeys,
+			RefreshTTL:  cfg.Auth.RefreshTokenTTL,
+			SCIMEnabled: cfg.Auth.SCIMEnabled,
+		},
+		AccessLog: &api.AccessLogConfig{
+			SlowThreshold: cfg.Logger.AccessLog.SlowThreshold,
+			SampleRate:    cfg.Logger.AccessLog.SampleRate,
+			Routes:        routeSampleRates,
+		},
+		Compression: &api.CompressionConfig{
+			Enabled: cfg.Server.Compression.Enabled,
+			MinSize: cfg.Server.Compression.MinSize,
+			Level:   cfg.Server.Compression.Level,
+		},
+		Timeouts: &api.TimeoutConfig{
+			Default: cfg.Server.HandlerTimeout,
+			Routes:  routeTimeouts,
+		},
+		Backpressure: &api.BackpressureConfig{
+			MaxInFlight:      cfg.Webhooks.Backpressure.MaxInFlight,
+			PoolThreshold:    cfg.Webhooks.Backpressure.PoolThreshold,
+			FailureThreshold: cfg.Webhooks.Backpressure.FailureThreshold,
+			RetryAfter:       cfg.Webhooks.Backpressure.RetryAfter,
+		},
+		WebhookSignatures: &api.WebhookSignatureConfig{
+			Secret:        cfg.Webhooks.Signatures.Secret,
+			SourceSecrets: webhookSecrets,
+			MaxAge:        cfg.Webhooks.Signatures.MaxAge,
+		},
+		Drainer:           drainer,
+		ErrorReporter:     errorReporter,
+		Jobs:              jobRunner,
+		TLSPolicies:       tlsPolicies,
+		ITSM:              itsmService,
+		ITSMWebhookSecret: cfg.ITSM.WebhookSecret,
+		Languages:         languages,
+		SLO:               sloTracker,
+		Auditor:           auditor,
+		Stalwart:          stalwartPusher,
+		AlertRouter:       alertRouter,
+	})
+
 	// Create HTTP server
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// Start server in a goroutine
@@ -132,12 +712,35 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Drain ingestion first: new webhooks get 503 + Retry-After while the
+	// in-flight ones finish, then the bus consumer finishes its batch
+	if err := drainer.Drain(ctx); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Webhook requests still in flight at the shutdown deadline")
+	} else {
+		logger.Info("Webhook ingestion drained")
+	}
+	stopBus()
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Server forced to shutdown")
 	}
 
+	if err := jobRunner.Close(ctx); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Background jobs still running at the shutdown deadline")
+	}
+
+	if err := alertDispatcher.Close(ctx); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("Alerts still being delivered at the shutdown deadline")
+	}
+
 	logger.Info("Server exited gracefully")
 }
 
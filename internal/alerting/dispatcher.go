@@ -0,0 +1,200 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAttempts   = 3
+	defaultRetryDelay = time.Second
+	defaultTimeout    = 10 * time.Second
+)
+
+// DefaultSeverities alerts blacklisted, quarantined and warning IPs
+const DefaultSeverities = "blacklisted:critical,quarantine:warning,warning:info"
+
+var alertsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "alerts_total",
+		Help: "Total number of alerts sent to notifier targets, by notifier and status (delivered, failed, unconfigured)",
+	},
+	[]string{"notifier", "status"},
+)
+
+// ParseSeverities parses status:severity pairs such as DefaultSeverities
+func ParseSeverities(s string) (map[string]string, error) {
+	severities := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		status, severity, ok := strings.Cut(pair, ":")
+		status, severity = strings.TrimSpace(status), strings.ToLower(strings.TrimSpace(severity))
+		if !ok || status == "" || !ValidSeverity(severity) {
+			return nil, fmt.Errorf("invalid alert severity %q: want status:severity with severity info, warning or critical", pair)
+		}
+		severities[status] = severity
+	}
+	return severities, nil
+}
+
+// Config tunes the dispatcher; zero values use the defaults
+type Config struct {
+	// Severities maps the statuses alerted about to their severity; a status
+	// missing is not alerted. Nil uses DefaultSeverities.
+	Severities map[string]string
+	// Attempts is how often a target is tried (default 3)
+	Attempts int
+	// RetryDelay doubles after every failed attempt (default 1s)
+	RetryDelay time.Duration
+	// Timeout bounds each attempt (default 10s)
+	Timeout time.Duration
+}
+
+// Dispatcher sends alerts to the targets the router picks for their
+// severity, in the background, retrying failed deliveries
+type Dispatcher struct {
+	router     *Router
+	notifiers  map[string]Notifier
+	severities map[string]string
+	attempts   int
+	retryDelay time.Duration
+	timeout    time.Duration
+	logger     *logrus.Logger
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher sending through notifiers, keyed by
+// notifier name. Targets of a notifier missing from notifiers are skipped.
+func NewDispatcher(router *Router, notifiers map[string]Notifier, config Config, logger *logrus.Logger) *Dispatcher {
+	if config.Severities == nil {
+		config.Severities, _ = ParseSeverities(DefaultSeverities)
+	}
+	if config.Attempts <= 0 {
+		config.Attempts = defaultAttempts
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultRetryDelay
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	return &Dispatcher{
+		router:     router,
+		notifiers:  notifiers,
+		severities: config.Severities,
+		attempts:   config.Attempts,
+		retryDelay: config.RetryDelay,
+		timeout:    config.Timeout,
+		logger:     logger,
+	}
+}
+
+// Severity returns the severity of alerts about IPs moving to status, empty
+// when the status is not alerted
+func (d *Dispatcher) Severity(status string) string {
+	return d.severities[status]
+}
+
+// Dispatch sends alert to the targets routed for the severity of its status
+// and returns how many it is sent to. Delivery runs in the background.
+func (d *Dispatcher) Dispatch(alert Alert) (int, error) {
+	alert.Severity = d.Severity(alert.Status)
+	if alert.Severity == "" {
+		return 0, nil
+	}
+	targets, err := d.router.Targets(alert.IP, alert.Severity)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return 0, fmt.Errorf("alert dispatcher is closed")
+	}
+	for _, target := range targets {
+		d.wg.Add(1)
+		go d.deliver(target, alert)
+	}
+	return len(targets), nil
+}
+
+// Close stops accepting alerts and waits for the deliveries in progress, or
+// returns ctx's error when its deadline passes first
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver sends alert to target, retrying until it succeeds, fails
+// permanently or runs out of attempts
+func (d *Dispatcher) deliver(target Target, alert Alert) {
+	defer d.wg.Done()
+	fields := logrus.Fields{
+		"ip":       alert.IP,
+		"status":   alert.Status,
+		"severity": alert.Severity,
+		"notifier": target.Notifier,
+		"route_id": target.RouteID,
+	}
+
+	notifier, ok := d.notifiers[target.Notifier]
+	if !ok {
+		alertsTotal.WithLabelValues(target.Notifier, "unconfigured").Inc()
+		fields["action"] = "alert_notifier_unconfigured"
+		d.logger.WithFields(fields).Warn("Alert routed to a notifier that is not configured")
+		return
+	}
+
+	var err error
+	delay := d.retryDelay
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		err = notifier.Notify(ctx, target.Target, alert)
+		cancel()
+		if err == nil || IsPermanent(err) {
+			break
+		}
+		if attempt < d.attempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	if err != nil {
+		alertsTotal.WithLabelValues(target.Notifier, "failed").Inc()
+		fields["action"] = "alert_delivery_failed"
+		fields["error"] = err.Error()
+		d.logger.WithFields(fields).Error("Failed to deliver alert")
+		return
+	}
+	alertsTotal.WithLabelValues(target.Notifier, "delivered").Inc()
+	fields["action"] = "alert_delivered"
+	d.logger.WithFields(fields).Info("Alert delivered")
+}
@@ -0,0 +1,175 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type sentAlert struct {
+	target string
+	alert  Alert
+}
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	failures []error
+	attempts int
+	sent     []sentAlert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, target string, alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.attempts++
+	if len(n.failures) > 0 {
+		err := n.failures[0]
+		n.failures = n.failures[1:]
+		return err
+	}
+	n.sent = append(n.sent, sentAlert{target: target, alert: alert})
+	return nil
+}
+
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Alerts were not delivered: %v", err)
+	}
+}
+
+func TestParseSeverities(t *testing.T) {
+	severities, err := ParseSeverities(" blacklisted:Critical, quarantine:warning,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"blacklisted": "critical", "quarantine": "warning"}; !reflect.DeepEqual(severities, want) {
+		t.Errorf("Expected %v, got %v", want, severities)
+	}
+	if severities, err := ParseSeverities(""); err != nil || len(severities) != 0 {
+		t.Errorf("Expected no statuses alerted, got %v %v", severities, err)
+	}
+
+	for _, invalid := range []string{"blacklisted", ":critical", "blacklisted:urgent"} {
+		if _, err := ParseSeverities(invalid); err == nil {
+			t.Errorf("Expected %q rejected", invalid)
+		}
+	}
+}
+
+func TestDispatchRoutesBySeverity(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	slack, pagerDuty := &recordingNotifier{}, &recordingNotifier{}
+	dispatcher := NewDispatcher(NewRouter(&memoryStore{routes: testRoutes}), map[string]Notifier{
+		NotifierSlack:     slack,
+		NotifierPagerDuty: pagerDuty,
+	}, Config{}, logger)
+
+	n, err := dispatcher.Dispatch(Alert{IP: "192.0.2.1", PreviousStatus: "warning", Status: "blacklisted"})
+	if err != nil || n != 2 {
+		t.Fatalf("Expected the catch-all routes, got %d %v", n, err)
+	}
+	if n, _ := dispatcher.Dispatch(Alert{IP: "192.0.2.1", PreviousStatus: "blacklisted", Status: "healthy"}); n != 0 {
+		t.Errorf("Expected healthy not alerted by default, got %d targets", n)
+	}
+	closeDispatcher(t, dispatcher)
+
+	if len(slack.sent) != 1 || slack.sent[0].target != "#deliverability" || slack.sent[0].alert.Severity != SeverityCritical {
+		t.Errorf("Unexpected Slack alerts: %+v", slack.sent)
+	}
+	if len(pagerDuty.sent) != 1 || pagerDuty.sent[0].target != "ops-key" {
+		t.Errorf("Unexpected PagerDuty alerts: %+v", pagerDuty.sent)
+	}
+	if _, err := dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "blacklisted"}); err == nil {
+		t.Error("Expected a closed dispatcher to refuse alerts")
+	}
+
+	var actions []string
+	for _, entry := range hook.AllEntries() {
+		actions = append(actions, entry.Data["action"].(string))
+	}
+	sort.Strings(actions)
+	if !reflect.DeepEqual(actions, []string{"alert_delivered", "alert_delivered"}) {
+		t.Errorf("Unexpected log actions: %v", actions)
+	}
+}
+
+func TestDispatchCustomSeverities(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	slack := &recordingNotifier{}
+	dispatcher := NewDispatcher(NewRouter(&memoryStore{routes: testRoutes, poolIDs: []int{10}}), map[string]Notifier{
+		NotifierSlack: slack,
+	}, Config{Severities: map[string]string{"healthy": SeverityCritical}}, logger)
+
+	if n, _ := dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "blacklisted"}); n != 0 {
+		t.Errorf("Expected blacklisted not alerted, got %d targets", n)
+	}
+	// The pool's routes replace the catch-all ones; email is not configured
+	if n, _ := dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "healthy"}); n != 2 {
+		t.Errorf("Expected the pool's critical routes, got %d targets", n)
+	}
+	closeDispatcher(t, dispatcher)
+
+	if len(slack.sent) != 1 || slack.sent[0].target != "#marketing" {
+		t.Errorf("Unexpected Slack alerts: %+v", slack.sent)
+	}
+	entries := hook.AllEntries()
+	unconfigured := false
+	for _, entry := range entries {
+		if entry.Data["action"] == "alert_notifier_unconfigured" && entry.Data["notifier"] == NotifierEmail {
+			unconfigured = true
+		}
+	}
+	if !unconfigured {
+		t.Error("Expected the email route logged as unconfigured")
+	}
+}
+
+func TestDispatchRetries(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	routes := &memoryStore{routes: testRoutes[:1]}
+	config := Config{Attempts: 3, RetryDelay: time.Millisecond}
+
+	flaky := &recordingNotifier{failures: []error{errors.New("connection reset"), errors.New("503")}}
+	dispatcher := NewDispatcher(NewRouter(routes), map[string]Notifier{NotifierSlack: flaky}, config, logger)
+	dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "quarantine"})
+	closeDispatcher(t, dispatcher)
+	if flaky.attempts != 3 || len(flaky.sent) != 1 {
+		t.Errorf("Expected delivery on the third attempt, got %d attempts", flaky.attempts)
+	}
+
+	down := &recordingNotifier{failures: []error{errors.New("503"), errors.New("503"), errors.New("503"), errors.New("503")}}
+	dispatcher = NewDispatcher(NewRouter(routes), map[string]Notifier{NotifierSlack: down}, config, logger)
+	dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "quarantine"})
+	closeDispatcher(t, dispatcher)
+	if down.attempts != 3 || hook.LastEntry().Data["action"] != "alert_delivery_failed" {
+		t.Errorf("Expected 3 attempts then a failure, got %d attempts", down.attempts)
+	}
+
+	rejected := &recordingNotifier{failures: []error{&permanentError{errors.New("404")}}}
+	dispatcher = NewDispatcher(NewRouter(routes), map[string]Notifier{NotifierSlack: rejected}, config, logger)
+	dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "quarantine"})
+	closeDispatcher(t, dispatcher)
+	if rejected.attempts != 1 {
+		t.Errorf("Expected a permanent failure not retried, got %d attempts", rejected.attempts)
+	}
+}
+
+func TestDispatchSkipsArchivedIPs(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	slack := &recordingNotifier{}
+	dispatcher := NewDispatcher(NewRouter(&memoryStore{routes: testRoutes, archived: true}), map[string]Notifier{NotifierSlack: slack}, Config{}, logger)
+
+	if n, err := dispatcher.Dispatch(Alert{IP: "192.0.2.1", Status: "blacklisted"}); n != 0 || err != nil {
+		t.Errorf("Expected no alert about an archived IP, got %d %v", n, err)
+	}
+	closeDispatcher(t, dispatcher)
+}
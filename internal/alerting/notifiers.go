@@ -0,0 +1,337 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/dependencies"
+)
+
+// DefaultPagerDutyEventsURL is PagerDuty's Events API v2 endpoint
+const DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Alert is a status change of an IP sent to on-call
+type Alert struct {
+	IP             string `json:"ip"`
+	Severity       string `json:"severity"`
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+	// Summary says why the IP has its status
+	Summary         string   `json:"summary"`
+	RejectionRatio  float64  `json:"rejection_ratio"`
+	MajorProviders  []string `json:"major_providers,omitempty"`
+	Recommendations []string `json:"recommendations,omitempty"`
+	// Notes and RunbookURL are the IP's on-call context
+	Notes      string    `json:"notes,omitempty"`
+	RunbookURL string    `json:"runbook_url,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// Title is the one-line description of the alert
+func (a Alert) Title() string {
+	return fmt.Sprintf("[%s] %s is now %s (was %s)", strings.ToUpper(a.Severity), a.IP, a.Status, a.PreviousStatus)
+}
+
+// Text is the plain text body of the alert
+func (a Alert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", a.Title(), a.Summary)
+	fmt.Fprintf(&b, "Rejection ratio: %.1f%%\n", a.RejectionRatio*100)
+	if len(a.MajorProviders) > 0 {
+		fmt.Fprintf(&b, "Rejecting providers: %s\n", strings.Join(a.MajorProviders, ", "))
+	}
+	if len(a.Recommendations) > 0 {
+		fmt.Fprintf(&b, "Recommended actions: %s\n", strings.Join(a.Recommendations, ", "))
+	}
+	if a.Notes != "" {
+		fmt.Fprintf(&b, "Notes: %s\n", a.Notes)
+	}
+	if a.RunbookURL != "" {
+		fmt.Fprintf(&b, "Runbook: %s\n", a.RunbookURL)
+	}
+	return b.String()
+}
+
+// Notifier sends an alert to one target of its kind: a Slack channel, a URL,
+// an email address or a PagerDuty routing key
+type Notifier interface {
+	Notify(ctx context.Context, target string, alert Alert) error
+}
+
+// permanentError is a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// IsPermanent reports whether a notifier failed in a way retrying cannot fix
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// postJSON posts body to url. Transport errors, 429 and 5xx can be retried;
+// other statuses above 2xx are permanent.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("failed to create request: %w", err)}
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-backend-service/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		err := fmt.Errorf("%s responded %d: %s", url, resp.StatusCode, respBody)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return &permanentError{err}
+		}
+		return err
+	}
+	return nil
+}
+
+// SlackNotifier posts alerts to the channel named by the target through an
+// incoming webhook. A target that is itself a webhook URL is posted to.
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier posting to webhookURL
+func NewSlackNotifier(webhookURL string, timeout time.Duration) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: timeout, Transport: dependencies.Default.Transport(dependencies.Slack, nil)},
+	}
+}
+
+// Notify implements Notifier
+func (n *SlackNotifier) Notify(ctx context.Context, target string, alert Alert) error {
+	url := n.webhookURL
+	message := map[string]string{"text": alert.Text()}
+	if strings.HasPrefix(target, "https://") {
+		url = target
+	} else {
+		message["channel"] = target
+	}
+	if url == "" {
+		return &permanentError{errors.New("no Slack webhook URL configured")}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return &permanentError{err}
+	}
+	return postJSON(ctx, n.client, url, body, nil)
+}
+
+// WebhookNotifier posts alerts as JSON to the target URL, signed with
+// X-Signature-256: sha256=<hex HMAC-SHA256 of the body> when it has a secret
+type WebhookNotifier struct {
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier; an empty secret sends
+// unsigned alerts
+func NewWebhookNotifier(secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout, Transport: dependencies.Default.Transport(dependencies.AlertWebhooks, nil)},
+	}
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(ctx context.Context, target string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return &permanentError{err}
+	}
+	header := http.Header{}
+	if len(n.secret) > 0 {
+		mac := hmac.New(sha256.New, n.secret)
+		mac.Write(body)
+		header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return postJSON(ctx, n.client, target, body, header)
+}
+
+// PagerDutyNotifier triggers PagerDuty incidents with the target as routing
+// key. Incidents are deduplicated per IP, and an IP back to healthy
+// resolves its incident.
+type PagerDutyNotifier struct {
+	eventsURL string
+	client    *http.Client
+}
+
+// NewPagerDutyNotifier creates a PagerDuty notifier sending to eventsURL;
+// empty uses DefaultPagerDutyEventsURL
+func NewPagerDutyNotifier(eventsURL string, timeout time.Duration) *PagerDutyNotifier {
+	if eventsURL == "" {
+		eventsURL = DefaultPagerDutyEventsURL
+	}
+	return &PagerDutyNotifier{
+		eventsURL: eventsURL,
+		client:    &http.Client{Timeout: timeout, Transport: dependencies.Default.Transport(dependencies.PagerDuty, nil)},
+	}
+}
+
+// pagerDutyEvent is an Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string `json:"summary"`
+	Source        string `json:"source"`
+	Severity      string `json:"severity"`
+	Component     string `json:"component"`
+	CustomDetails Alert  `json:"custom_details"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notify implements Notifier
+func (n *PagerDutyNotifier) Notify(ctx context.Context, target string, alert Alert) error {
+	event := pagerDutyEvent{RoutingKey: target, EventAction: "trigger", DedupKey: "ip-status-" + alert.IP}
+	if alert.Status == "healthy" {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:       alert.Title(),
+			Source:        alert.IP,
+			Severity:      alert.Severity,
+			Component:     "sending-ip",
+			CustomDetails: alert,
+		}
+		if alert.RunbookURL != "" {
+			event.Links = []pagerDutyLink{{Href: alert.RunbookURL, Text: "Runbook"}}
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return &permanentError{err}
+	}
+	return postJSON(ctx, n.client, n.eventsURL, body, nil)
+}
+
+// EmailConfig holds the SMTP relay alerts are mailed through
+type EmailConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth; empty skips auth
+	Username string
+	Password string
+	From     string
+}
+
+// EmailNotifier mails alerts to the target address, with STARTTLS when the
+// relay offers it
+type EmailNotifier struct {
+	config EmailConfig
+}
+
+// NewEmailNotifier creates an email notifier; a zero port uses 587
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &EmailNotifier{config: config}
+}
+
+// Notify implements Notifier
+func (n *EmailNotifier) Notify(ctx context.Context, target string, alert Alert) error {
+	err := n.send(ctx, target, alert)
+	dependencies.Record(dependencies.SMTP, err)
+	return err
+}
+
+func (n *EmailNotifier) send(ctx context.Context, target string, alert Alert) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP relay: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet SMTP relay: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return &permanentError{fmt.Errorf("SMTP authentication failed: %w", err)}
+		}
+	}
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("SMTP relay refused sender: %w", err)
+	}
+	if err := client.Rcpt(target); err != nil {
+		return fmt.Errorf("SMTP relay refused recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(target, alert)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP relay refused message: %w", err)
+	}
+	return client.Quit()
+}
+
+// message is the RFC 5322 message of an alert
+func (n *EmailNotifier) message(target string, alert Alert) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", target)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", alert.Title()))
+	fmt.Fprintf(&b, "Date: %s\r\n", alert.At.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(alert.Text(), "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,257 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAlert = Alert{
+	IP:              "192.0.2.1",
+	Severity:        SeverityCritical,
+	PreviousStatus:  "warning",
+	Status:          "blacklisted",
+	Summary:         "Listed on zen.spamhaus.org",
+	RejectionRatio:  0.25,
+	MajorProviders:  []string{"gmail"},
+	Recommendations: []string{"request_delisting"},
+	RunbookURL:      "https://wiki.example.com/runbooks/192.0.2.1",
+	At:              time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+}
+
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var requests []capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, capturedRequest{path: r.URL.Path, header: r.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestAlertText(t *testing.T) {
+	text := testAlert.Text()
+	for _, want := range []string{
+		"[CRITICAL] 192.0.2.1 is now blacklisted (was warning)",
+		"Rejection ratio: 25.0%",
+		"Rejecting providers: gmail",
+		"Recommended actions: request_delisting",
+		"Runbook: https://wiki.example.com/runbooks/192.0.2.1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %q", want, text)
+		}
+	}
+	if strings.Contains(text, "Notes:") {
+		t.Errorf("Expected no notes line without notes, got %q", text)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var requests []capturedRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, capturedRequest{path: r.URL.Path, body: body})
+	}))
+	defer server.Close()
+	notifier := NewSlackNotifier(server.URL+"/default", time.Second)
+	notifier.client = server.Client()
+
+	if err := notifier.Notify(context.Background(), "#deliverability", testAlert); err != nil {
+		t.Fatal(err)
+	}
+	// A URL target posts to its own webhook
+	if err := notifier.Notify(context.Background(), server.URL+"/services/T/B/X", testAlert); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].path != "/default" || requests[1].path != "/services/T/B/X" {
+		t.Fatalf("Unexpected requests: %+v", requests)
+	}
+	var message map[string]string
+	json.Unmarshal(requests[0].body, &message)
+	if message["channel"] != "#deliverability" || message["text"] != testAlert.Text() {
+		t.Errorf("Unexpected message: %v", message)
+	}
+	message = nil
+	json.Unmarshal(requests[1].body, &message)
+	if _, ok := message["channel"]; ok {
+		t.Errorf("Expected no channel for a webhook target, got %v", message)
+	}
+
+	if err := NewSlackNotifier("", time.Second).Notify(context.Background(), "#deliverability", testAlert); !IsPermanent(err) {
+		t.Errorf("Expected a permanent error without a webhook URL, got %v", err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusNoContent)
+
+	if err := NewWebhookNotifier("s3cret", time.Second).Notify(context.Background(), server.URL+"/hook", testAlert); err != nil {
+		t.Fatal(err)
+	}
+	request := (*requests)[0]
+	var delivered Alert
+	if err := json.Unmarshal(request.body, &delivered); err != nil {
+		t.Fatal(err)
+	}
+	if delivered.IP != testAlert.IP || delivered.Status != "blacklisted" || delivered.RunbookURL != testAlert.RunbookURL {
+		t.Errorf("Unexpected alert: %+v", delivered)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(request.body)
+	if signature := request.header.Get("X-Signature-256"); signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("Unexpected signature %q", signature)
+	}
+
+	NewWebhookNotifier("", time.Second).Notify(context.Background(), server.URL, testAlert)
+	if signature := (*requests)[1].header.Get("X-Signature-256"); signature != "" {
+		t.Errorf("Expected no signature without a secret, got %q", signature)
+	}
+}
+
+func TestNotifierErrorsRetryable(t *testing.T) {
+	for status, permanent := range map[int]bool{
+		http.StatusBadRequest:          true,
+		http.StatusNotFound:            true,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+		http.StatusBadGateway:          false,
+	} {
+		server, _ := newCaptureServer(t, status)
+		err := NewWebhookNotifier("", time.Second).Notify(context.Background(), server.URL, testAlert)
+		if err == nil || IsPermanent(err) != permanent {
+			t.Errorf("Expected %d permanent=%v, got %v", status, permanent, err)
+		}
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusAccepted)
+	notifier := NewPagerDutyNotifier(server.URL+"/v2/enqueue", time.Second)
+
+	if err := notifier.Notify(context.Background(), "routing-key", testAlert); err != nil {
+		t.Fatal(err)
+	}
+	resolved := testAlert
+	resolved.PreviousStatus, resolved.Status = "blacklisted", "healthy"
+	if err := notifier.Notify(context.Background(), "routing-key", resolved); err != nil {
+		t.Fatal(err)
+	}
+
+	var trigger, resolve pagerDutyEvent
+	json.Unmarshal((*requests)[0].body, &trigger)
+	json.Unmarshal((*requests)[1].body, &resolve)
+	if trigger.RoutingKey != "routing-key" || trigger.EventAction != "trigger" || trigger.DedupKey != "ip-status-192.0.2.1" {
+		t.Errorf("Unexpected trigger: %+v", trigger)
+	}
+	if trigger.Payload == nil || trigger.Payload.Severity != SeverityCritical || trigger.Payload.Source != "192.0.2.1" {
+		t.Errorf("Unexpected payload: %+v", trigger.Payload)
+	}
+	if len(trigger.Links) != 1 || trigger.Links[0].Href != testAlert.RunbookURL {
+		t.Errorf("Expected the runbook linked, got %+v", trigger.Links)
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Errorf("Expected the incident resolved, got %+v", resolve)
+	}
+
+	if NewPagerDutyNotifier("", time.Second).eventsURL != DefaultPagerDutyEventsURL {
+		t.Error("Expected the default events URL")
+	}
+}
+
+// serveSMTP answers one SMTP session without extensions and returns the
+// commands and message it received
+func serveSMTP(t *testing.T, listener net.Listener) <-chan []string {
+	t.Helper()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		reader := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+		received <- lines
+	}()
+	return received
+}
+
+func TestEmailNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := serveSMTP(t, listener)
+
+	notifier := NewEmailNotifier(EmailConfig{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port, From: "alerts@example.com"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, "ops@example.com", testAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	session := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<alerts@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"Subject: [CRITICAL] 192.0.2.1 is now blacklisted (was warning)",
+		"Rejection ratio: 25.0%",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("Expected %q in the session:\n%s", want, session)
+		}
+	}
+
+	if NewEmailNotifier(EmailConfig{Host: "relay"}).config.Port != 587 {
+		t.Error("Expected the submission port by default")
+	}
+}
@@ -0,0 +1,164 @@
+package alerting
+
+import (
+	"fmt"
+
+	"golang-backend-service/internal/database"
+)
+
+// Alert severities, from lowest to highest
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notifiers alerts can be sent through
+const (
+	NotifierSlack     = "slack"
+	NotifierEmail     = "email"
+	NotifierWebhook   = "webhook"
+	NotifierPagerDuty = "pagerduty"
+)
+
+// ValidSeverity reports whether s is a known severity
+func ValidSeverity(s string) bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
+// ValidNotifier reports whether n is a known notifier
+func ValidNotifier(n string) bool {
+	return n == NotifierSlack || n == NotifierEmail || n == NotifierWebhook || n == NotifierPagerDuty
+}
+
+// Target is one destination an alert is sent to
+type Target struct {
+	RouteID  int    `json:"route_id"`
+	Notifier string `json:"notifier"`
+	Target   string `json:"target"`
+}
+
+// Store reads notification routes and what an IP belongs to
+type Store interface {
+	ListNotificationRoutes() ([]database.NotificationRoute, error)
+	GetIPPoolIDs(ip string) ([]int, error)
+	GetIPOwners(ip string) ([]database.IPOwner, error)
+	IsIPArchived(ip string) (bool, error)
+}
+
+type dbStore struct{}
+
+func (dbStore) ListNotificationRoutes() ([]database.NotificationRoute, error) {
+	return database.ListNotificationRoutes()
+}
+
+func (dbStore) GetIPPoolIDs(ip string) ([]int, error) {
+	return database.GetIPPoolIDs(ip)
+}
+
+func (dbStore) GetIPOwners(ip string) ([]database.IPOwner, error) {
+	return database.GetIPOwners(ip)
+}
+
+func (dbStore) IsIPArchived(ip string) (bool, error) {
+	return database.IsIPArchived(ip)
+}
+
+// Router picks the destinations of an alert from the routing table. The
+// alert dispatcher asks it where each alert goes.
+type Router struct {
+	store Store
+}
+
+// NewRouter creates a router backed by store; a nil store uses the database
+func NewRouter(store Store) *Router {
+	if store == nil {
+		store = dbStore{}
+	}
+	return &Router{store: store}
+}
+
+// Targets returns where an alert about ip at severity is sent. Alerts about
+// archived IPs are not sent anywhere.
+func (r *Router) Targets(ip, severity string) ([]Target, error) {
+	archived, err := r.store.IsIPArchived(ip)
+	if err != nil {
+		return nil, err
+	}
+	if archived {
+		return []Target{}, nil
+	}
+	routes, err := r.store.ListNotificationRoutes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification routes: %w", err)
+	}
+	poolIDs, err := r.store.GetIPPoolIDs(ip)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := r.store.GetIPOwners(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	var teamIDs []int
+	for _, o := range owners {
+		if o.OwnerType == database.OwnerTeam {
+			teamIDs = append(teamIDs, o.OwnerID)
+		}
+	}
+	return SelectRoutes(routes, poolIDs, teamIDs, severity), nil
+}
+
+// SelectRoutes returns the targets of the enabled routes matching an alert at
+// severity for an IP in poolIDs owned by teamIDs. A route matches when each
+// of its pool, team and severity is unset or equal. Routes naming a pool or
+// team are more specific than catch-all routes and replace them, so a pool
+// with its own channel does not also page the default one.
+func SelectRoutes(routes []database.NotificationRoute, poolIDs, teamIDs []int, severity string) []Target {
+	var specific, fallback []Target
+	for _, route := range routes {
+		if !route.Enabled || (route.Severity != "" && route.Severity != severity) {
+			continue
+		}
+		if !matchesID(route.PoolID, poolIDs) || !matchesID(route.TeamID, teamIDs) {
+			continue
+		}
+
+		target := Target{RouteID: route.ID, Notifier: route.Notifier, Target: route.Target}
+		if route.PoolID != nil || route.TeamID != nil {
+			specific = append(specific, target)
+		} else {
+			fallback = append(fallback, target)
+		}
+	}
+
+	selected := fallback
+	if len(specific) > 0 {
+		selected = specific
+	}
+
+	// Two routes may point at the same destination; send once
+	targets := []Target{}
+	seen := map[string]bool{}
+	for _, t := range selected {
+		key := t.Notifier + "\x00" + t.Target
+		if !seen[key] {
+			seen[key] = true
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+func matchesID(id *int, ids []int) bool {
+	if id == nil {
+		return true
+	}
+	for _, candidate := range ids {
+		if candidate == *id {
+			return true
+		}
+	}
+	return false
+}
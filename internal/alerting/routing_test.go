@@ -0,0 +1,113 @@
+package alerting
+
+import (
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func intPtr(i int) *int { return &i }
+
+type memoryStore struct {
+	routes   []database.NotificationRoute
+	poolIDs  []int
+	owners   []database.IPOwner
+	archived bool
+	err      error
+}
+
+func (m *memoryStore) ListNotificationRoutes() ([]database.NotificationRoute, error) {
+	return m.routes, m.err
+}
+
+func (m *memoryStore) GetIPPoolIDs(ip string) ([]int, error) {
+	return m.poolIDs, nil
+}
+
+func (m *memoryStore) GetIPOwners(ip string) ([]database.IPOwner, error) {
+	return m.owners, nil
+}
+
+func (m *memoryStore) IsIPArchived(ip string) (bool, error) {
+	return m.archived, nil
+}
+
+var testRoutes = []database.NotificationRoute{
+	{ID: 1, Notifier: NotifierSlack, Target: "#deliverability", Enabled: true},
+	{ID: 2, Severity: SeverityCritical, Notifier: NotifierPagerDuty, Target: "ops-key", Enabled: true},
+	{ID: 3, PoolID: intPtr(10), Notifier: NotifierSlack, Target: "#marketing", Enabled: true},
+	{ID: 4, PoolID: intPtr(10), Severity: SeverityCritical, Notifier: NotifierEmail, Target: "marketing@example.com", Enabled: true},
+	{ID: 5, TeamID: intPtr(7), Notifier: NotifierSlack, Target: "#marketing", Enabled: true},
+	{ID: 6, PoolID: intPtr(20), Notifier: NotifierSlack, Target: "#transactional", Enabled: false},
+}
+
+func routeIDs(targets []Target) []int {
+	ids := []int{}
+	for _, t := range targets {
+		ids = append(ids, t.RouteID)
+	}
+	return ids
+}
+
+func TestSelectRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		poolIDs  []int
+		teamIDs  []int
+		severity string
+		want     []int
+	}{
+		{"unowned IP uses the catch-all routes", nil, nil, SeverityWarning, []int{1}},
+		{"catch-all severity routes", nil, nil, SeverityCritical, []int{1, 2}},
+		{"pool routes replace the catch-all", []int{10}, nil, SeverityWarning, []int{3}},
+		{"pool severity routes", []int{10}, nil, SeverityCritical, []int{3, 4}},
+		{"same destination is sent once", []int{10}, []int{7}, SeverityWarning, []int{3}},
+		{"team routes", nil, []int{7}, SeverityInfo, []int{5}},
+		{"disabled routes fall back to the catch-all", []int{20}, nil, SeverityInfo, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := routeIDs(SelectRoutes(testRoutes, tt.poolIDs, tt.teamIDs, tt.severity))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected routes %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected routes %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterTargets(t *testing.T) {
+	store := &memoryStore{
+		routes: testRoutes,
+		owners: []database.IPOwner{
+			{OwnerType: database.OwnerUser, OwnerID: 10, OwnerName: "alice"},
+			{OwnerType: database.OwnerTeam, OwnerID: 7, OwnerName: "marketing"},
+		},
+	}
+
+	targets, err := NewRouter(store).Targets("192.0.2.1", SeverityInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// User 10 must not be mistaken for pool 10
+	if len(targets) != 1 || targets[0].RouteID != 5 || targets[0].Target != "#marketing" {
+		t.Errorf("Expected the team route, got %+v", targets)
+	}
+
+	store.archived = true
+	if targets, err := NewRouter(store).Targets("192.0.2.1", SeverityCritical); err != nil || len(targets) != 0 {
+		t.Errorf("Expected no targets for an archived IP, got %+v %v", targets, err)
+	}
+	store.archived = false
+
+	store.err = errors.New("connection refused")
+	if _, err := NewRouter(store).Targets("192.0.2.1", SeverityInfo); err == nil {
+		t.Error("Expected the store error")
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogConfig controls which requests loggingMiddleware writes.
+// Error responses and slow requests are always logged; other requests are
+// sampled at the route's rate.
+type AccessLogConfig struct {
+	// SlowThreshold marks requests at or above this duration as slow.
+	// Zero disables slow request detection.
+	SlowThreshold time.Duration
+	// SampleRate is the fraction (0-1) of successful requests logged for
+	// routes without an override
+	SampleRate float64
+	// Routes overrides SampleRate per route path template, e.g.
+	// "/api/webhooks/stalwart/delivery-failure": 0.01
+	Routes map[string]float64
+}
+
+// DefaultAccessLogConfig logs every request and flags requests over one second
+func DefaultAccessLogConfig() AccessLogConfig {
+	return AccessLogConfig{
+		SlowThreshold: time.Second,
+		SampleRate:    1,
+	}
+}
+
+// ParseRouteSampleRates parses per-route overrides written as
+// "/metrics=0,/api/webhooks/stalwart/delivery-failure=0.01"
+func ParseRouteSampleRates(s string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, raw, ok := strings.Cut(pair, "=")
+		route = strings.TrimSpace(route)
+		if !ok || route == "" {
+			return nil, fmt.Errorf("invalid route sample rate %q, expected route=rate", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid sample rate for route %s: must be between 0 and 1", route)
+		}
+		rates[route] = rate
+	}
+	return rates, nil
+}
+
+// accessLogSampler decides per request whether a successful request is logged.
+// Sampling is counter based so a rate of 0.1 logs exactly one in ten requests.
+type accessLogSampler struct {
+	config   AccessLogConfig
+	mu       sync.Mutex
+	counters map[string]*atomic.Uint64
+}
+
+func newAccessLogSampler(config AccessLogConfig) *accessLogSampler {
+	return &accessLogSampler{
+		config:   config,
+		counters: make(map[string]*atomic.Uint64),
+	}
+}
+
+func (s *accessLogSampler) rate(route string) float64 {
+	if rate, ok := s.config.Routes[route]; ok {
+		return rate
+	}
+	return s.config.SampleRate
+}
+
+func (s *accessLogSampler) sample(route string) bool {
+	rate := s.rate(route)
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	counter, ok := s.counters[route]
+	if !ok {
+		counter = &atomic.Uint64{}
+		s.counters[route] = counter
+	}
+	s.mu.Unlock()
+
+	n := float64(counter.Add(1))
+	return int64(n*rate) != int64((n-1)*rate)
+}
+
+// routeTemplate returns the matched mux path template, falling back to the
+// redacted path for unmatched requests
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return redactedPath(r)
+}
+
+// personalPathPrefixes are followed by personal data, e.g. the address of an
+// erasure request, which must not reach the logs or the metric labels
+var personalPathPrefixes = []string{"/api/privacy/recipients/"}
+
+// redactedPath returns the request path to log or label metrics with: the
+// path, with whatever follows a personal path prefix masked. Unmatched
+// requests, e.g. with a method the route does not allow, are masked too.
+func redactedPath(r *http.Request) string {
+	path := r.URL.Path
+	for _, prefix := range personalPathPrefixes {
+		if strings.HasPrefix(path, prefix) && len(path) > len(prefix) {
+			return prefix + "{redacted}"
+		}
+	}
+	return path
+}
+
+// loggingMiddleware logs HTTP requests according to the access log config
+func loggingMiddleware(log *logrus.Logger, config AccessLogConfig) mux.MiddlewareFunc {
+	sampler := newAccessLogSampler(config)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			duration := time.Since(start)
+			route := routeTemplate(r)
+			slow := config.SlowThreshold > 0 && duration >= config.SlowThreshold
+			failed := wrapped.statusCode >= http.StatusBadRequest
+
+			if !failed && !slow && !sampler.sample(route) {
+				return
+			}
+
+			entry := log.WithFields(logrus.Fields{
+				"method":      r.Method,
+				"path":        redactedPath(r),
+				"route":       route,
+				"status":      wrapped.statusCode,
+				"duration_ms": duration.Milliseconds(),
+				"remote_addr": r.RemoteAddr,
+			})
+
+			switch {
+			case wrapped.statusCode >= http.StatusInternalServerError:
+				entry.Error("HTTP request processed")
+			case failed:
+				entry.Warn("HTTP request processed")
+			case slow:
+				entry.WithField("slow", true).Warn("HTTP request processed")
+			default:
+				entry.WithField("sample_rate", sampler.rate(route)).Info("HTTP request processed")
+			}
+		})
+	}
+}
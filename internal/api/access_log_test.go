@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func newAccessLogRouter(config AccessLogConfig) (*mux.Router, *test.Hook) {
+	log, hook := test.NewNullLogger()
+
+	router := mux.NewRouter()
+	router.Use(loggingMiddleware(log, config))
+	router.HandleFunc("/ok/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	return router, hook
+}
+
+func serve(router http.Handler, path string, times int) {
+	for i := 0; i < times; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+}
+
+func TestAccessLogSamplesSuccessfulRequests(t *testing.T) {
+	router, hook := newAccessLogRouter(AccessLogConfig{
+		SampleRate: 1,
+		Routes:     map[string]float64{"/ok/{id}": 0.1},
+	})
+
+	serve(router, "/ok/1", 100)
+
+	entries := hook.AllEntries()
+	if len(entries) != 10 {
+		t.Fatalf("Expected 10 sampled entries, got %d", len(entries))
+	}
+	if entries[0].Data["route"] != "/ok/{id}" {
+		t.Errorf("Expected route template, got %v", entries[0].Data["route"])
+	}
+	if entries[0].Data["sample_rate"] != 0.1 {
+		t.Errorf("Expected sample_rate 0.1, got %v", entries[0].Data["sample_rate"])
+	}
+}
+
+func TestAccessLogAlwaysLogsErrorsAndSlowRequests(t *testing.T) {
+	router, hook := newAccessLogRouter(AccessLogConfig{
+		SlowThreshold: 10 * time.Millisecond,
+		SampleRate:    0,
+	})
+
+	serve(router, "/ok/1", 5)
+	serve(router, "/fail", 3)
+	serve(router, "/slow", 1)
+	serve(router, "/missing", 1)
+
+	var errorCount, slowCount, notFoundCount int
+	for _, entry := range hook.AllEntries() {
+		switch {
+		case entry.Data["status"] == http.StatusInternalServerError && entry.Level == logrus.ErrorLevel:
+			errorCount++
+		case entry.Data["slow"] == true && entry.Level == logrus.WarnLevel:
+			slowCount++
+		case entry.Data["status"] == http.StatusNotFound:
+			notFoundCount++
+		default:
+			t.Errorf("Unexpected entry: %v", entry.Data)
+		}
+	}
+
+	if errorCount != 3 {
+		t.Errorf("Expected 3 error entries, got %d", errorCount)
+	}
+	if slowCount != 1 {
+		t.Errorf("Expected 1 slow entry, got %d", slowCount)
+	}
+	// Unmatched routes never reach router middleware
+	if notFoundCount != 0 {
+		t.Errorf("Expected no entries for unmatched routes, got %d", notFoundCount)
+	}
+}
+
+func TestParseRouteSampleRates(t *testing.T) {
+	rates, err := ParseRouteSampleRates("/health=0, /api/webhooks/stalwart/delivery-failure=0.01")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rates["/health"] != 0 || rates["/api/webhooks/stalwart/delivery-failure"] != 0.01 {
+		t.Errorf("Unexpected rates: %v", rates)
+	}
+
+	for _, bad := range []string{"/health", "/health=2", "=0.5", "/health=abc"} {
+		if _, err := ParseRouteSampleRates(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AggregationExclusionRequest excludes a block, or a single IP, from
+// aggregation
+type AggregationExclusionRequest struct {
+	CIDR   string `json:"cidr" example:"203.0.113.0/28"`
+	Reason string `json:"reason" example:"Uptime monitoring probes"`
+}
+
+// AggregationExclusionsResponse lists the excluded blocks
+type AggregationExclusionsResponse struct {
+	Total      int                             `json:"total"`
+	Exclusions []database.AggregationExclusion `json:"exclusions"`
+}
+
+// exclusionStore persists the aggregation exclusions
+type exclusionStore interface {
+	CreateAggregationExclusion(exclusion *database.AggregationExclusion) (*database.AggregationExclusion, error)
+	ListAggregationExclusions() ([]database.AggregationExclusion, error)
+	DeleteAggregationExclusion(id int) error
+}
+
+type dbExclusionStore struct{}
+
+func (dbExclusionStore) CreateAggregationExclusion(exclusion *database.AggregationExclusion) (*database.AggregationExclusion, error) {
+	return database.CreateAggregationExclusion(exclusion)
+}
+
+func (dbExclusionStore) ListAggregationExclusions() ([]database.AggregationExclusion, error) {
+	return database.ListAggregationExclusions()
+}
+
+func (dbExclusionStore) DeleteAggregationExclusion(id int) error {
+	return database.DeleteAggregationExclusion(id)
+}
+
+// AggregationExclusionHandler manages the blocks of IPs that are not sending
+// IPs, such as monitoring or corporate egress addresses, so that their
+// failures do not pollute the fleet view
+type AggregationExclusionHandler struct {
+	store  exclusionStore
+	logger *logrus.Logger
+}
+
+// NewAggregationExclusionHandler creates a new aggregation exclusion handler
+func NewAggregationExclusionHandler(logger *logrus.Logger) *AggregationExclusionHandler {
+	return &AggregationExclusionHandler{store: dbExclusionStore{}, logger: logger}
+}
+
+// exclusionBlock parses a CIDR block, or a single IP as its host block, and
+// returns it in canonical form
+func exclusionBlock(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if ip := net.ParseIP(raw); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return v4.String() + "/32", true
+		}
+		return ip.String() + "/128", true
+	}
+	_, block, err := net.ParseCIDR(raw)
+	if err != nil {
+		return "", false
+	}
+	return block.String(), true
+}
+
+// @Summary List aggregation exclusions
+// @Description Blocks whose IPs aggregation does not discover and dashboards hide unless called with include_excluded=true, ordered by block
+// @Tags ip-reputation
+// @Produce json
+// @Success 200 {object} AggregationExclusionsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/exclusions [get]
+func (h *AggregationExclusionHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	exclusions, err := h.store.ListAggregationExclusions()
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_aggregation_exclusions",
+			"error":  err.Error(),
+		}).Error("Failed to list aggregation exclusions")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list aggregation exclusions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AggregationExclusionsResponse{Total: len(exclusions), Exclusions: exclusions})
+}
+
+// @Summary Exclude a block from aggregation
+// @Description Stop aggregating the IPs of a CIDR block, or of a single IP, and hide them from the IP health dashboard, heatmap, fleet comparison and reason families. Their failures are still stored, and metrics already computed are kept but no longer updated.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body AggregationExclusionRequest true "Block to exclude"
+// @Success 201 {object} database.AggregationExclusion
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/exclusions [post]
+func (h *AggregationExclusionHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var req AggregationExclusionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	cidr, ok := exclusionBlock(req.CIDR)
+	if !ok {
+		writeUserError(w, http.StatusBadRequest, "invalid_cidr", "cidr must be a CIDR block such as 203.0.113.0/28 or an IP address")
+		return
+	}
+	reason, ok := archiveReason(w, req.Reason)
+	if !ok {
+		return
+	}
+
+	created, err := h.store.CreateAggregationExclusion(&database.AggregationExclusion{CIDR: cidr, Reason: reason, CreatedBy: principalName(r)})
+	if errors.Is(err, database.ErrAggregationExclusionExists) {
+		writeUserError(w, http.StatusConflict, "already_excluded", cidr+" is already excluded")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_aggregation_exclusion",
+			"cidr":   cidr,
+			"error":  err.Error(),
+		}).Error("Failed to create aggregation exclusion")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to create aggregation exclusion")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":       "aggregation_exclusion_created",
+		"exclusion_id": created.ID,
+		"cidr":         created.CIDR,
+		"author":       created.CreatedBy,
+	}).Warn("Block excluded from aggregation")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary Delete aggregation exclusion
+// @Description Put a block's IPs back into aggregation and the dashboards
+// @Tags ip-reputation
+// @Param id path int true "Exclusion ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/exclusions/{id} [delete]
+func (h *AggregationExclusionHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteAggregationExclusion(id)
+	if errors.Is(err, database.ErrAggregationExclusionNotFound) {
+		writeUserError(w, http.StatusNotFound, "not_found", "Aggregation exclusion not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":       "delete_aggregation_exclusion",
+			"exclusion_id": id,
+			"error":        err.Error(),
+		}).Error("Failed to delete aggregation exclusion")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to delete aggregation exclusion")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":       "aggregation_exclusion_deleted",
+		"exclusion_id": id,
+		"author":       principalName(r),
+	}).Info("Aggregation exclusion deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
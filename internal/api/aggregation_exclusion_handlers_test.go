@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryExclusionStore struct {
+	nextID     int
+	exclusions []database.AggregationExclusion
+}
+
+func (s *memoryExclusionStore) CreateAggregationExclusion(exclusion *database.AggregationExclusion) (*database.AggregationExclusion, error) {
+	for _, e := range s.exclusions {
+		if e.CIDR == exclusion.CIDR {
+			return nil, database.ErrAggregationExclusionExists
+		}
+	}
+	s.nextID++
+	stored := *exclusion
+	stored.ID = s.nextID
+	stored.CreatedAt = time.Now()
+	s.exclusions = append(s.exclusions, stored)
+	return &stored, nil
+}
+
+func (s *memoryExclusionStore) ListAggregationExclusions() ([]database.AggregationExclusion, error) {
+	return append([]database.AggregationExclusion{}, s.exclusions...), nil
+}
+
+func (s *memoryExclusionStore) DeleteAggregationExclusion(id int) error {
+	for i, e := range s.exclusions {
+		if e.ID == id {
+			s.exclusions = append(s.exclusions[:i], s.exclusions[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrAggregationExclusionNotFound
+}
+
+func newTestExclusionRouter() (*mux.Router, *memoryExclusionStore) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryExclusionStore{}
+	handler := &AggregationExclusionHandler{store: store, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reputation/exclusions", handler.HandleList).Methods("GET")
+	router.HandleFunc("/api/reputation/exclusions", handler.HandleCreate).Methods("POST")
+	router.HandleFunc("/api/reputation/exclusions/{id}", handler.HandleDelete).Methods("DELETE")
+	return router, store
+}
+
+func serveExclusions(router *mux.Router, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateAggregationExclusion(t *testing.T) {
+	router, store := newTestExclusionRouter()
+
+	tests := []struct {
+		cidr string
+		want string
+	}{
+		{"203.0.113.7/28", "203.0.113.0/28"},
+		{" 198.51.100.10 ", "198.51.100.10/32"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8:1::/48", "2001:db8:1::/48"},
+	}
+	for _, tt := range tests {
+		rec := serveExclusions(router, "POST", "/api/reputation/exclusions", `{"cidr":"`+tt.cidr+`","reason":" monitoring "}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 for %s, got %d: %s", tt.cidr, rec.Code, rec.Body.String())
+		}
+		var created database.AggregationExclusion
+		json.NewDecoder(rec.Body).Decode(&created)
+		if created.CIDR != tt.want || created.Reason != "monitoring" || created.ID == 0 {
+			t.Errorf("Expected %s stored as %s, got %+v", tt.cidr, tt.want, created)
+		}
+	}
+
+	rec := serveExclusions(router, "POST", "/api/reputation/exclusions", `{"cidr":"203.0.113.0/28"}`)
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusConflict || resp.Error != "already_excluded" {
+		t.Errorf("Expected 409 already_excluded, got %d %s", rec.Code, resp.Error)
+	}
+	if len(store.exclusions) != len(tests) {
+		t.Errorf("Expected %d exclusions, got %+v", len(tests), store.exclusions)
+	}
+}
+
+func TestCreateAggregationExclusionValidation(t *testing.T) {
+	router, _ := newTestExclusionRouter()
+
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{`, "invalid_request"},
+		{`{}`, "invalid_cidr"},
+		{`{"cidr":"203.0.113.0/33"}`, "invalid_cidr"},
+		{`{"cidr":"monitoring"}`, "invalid_cidr"},
+		{`{"cidr":"203.0.113.0/28","reason":"` + strings.Repeat("x", maxAnnotationLength+1) + `"}`, "invalid_reason"},
+	}
+	for _, tt := range tests {
+		rec := serveExclusions(router, "POST", "/api/reputation/exclusions", tt.body)
+		var resp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusBadRequest || resp.Error != tt.code {
+			t.Errorf("Expected 400 %s for %.40s, got %d %s", tt.code, tt.body, rec.Code, resp.Error)
+		}
+	}
+}
+
+func TestListAndDeleteAggregationExclusions(t *testing.T) {
+	router, store := newTestExclusionRouter()
+	store.CreateAggregationExclusion(&database.AggregationExclusion{CIDR: "203.0.113.0/28", CreatedBy: "alice"})
+	store.CreateAggregationExclusion(&database.AggregationExclusion{CIDR: "198.51.100.10/32", CreatedBy: "alice"})
+
+	rec := serveExclusions(router, "GET", "/api/reputation/exclusions", "")
+	var list AggregationExclusionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 || len(list.Exclusions) != 2 {
+		t.Errorf("Expected 2 exclusions, got %+v", list)
+	}
+
+	if rec := serveExclusions(router, "DELETE", "/api/reputation/exclusions/1", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveExclusions(router, "DELETE", "/api/reputation/exclusions/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", rec.Code)
+	}
+	if rec := serveExclusions(router, "DELETE", "/api/reputation/exclusions/first", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-numeric ID, got %d", rec.Code)
+	}
+	if len(store.exclusions) != 1 || store.exclusions[0].CIDR != "198.51.100.10/32" {
+		t.Errorf("Unexpected exclusions left: %+v", store.exclusions)
+	}
+}
+
+func TestHiddenIPs(t *testing.T) {
+	_, block, _ := net.ParseCIDR("203.0.113.0/28")
+	_, host, _ := net.ParseCIDR("2001:db8::1/128")
+	hidden := hiddenIPs{archived: map[string]bool{"192.0.2.9": true}, excluded: []*net.IPNet{block, host}}
+
+	for ip, want := range map[string]bool{
+		"192.0.2.9":    true,
+		"203.0.113.15": true,
+		"203.0.113.16": false,
+		"2001:db8::1":  true,
+		"2001:db8::2":  false,
+		"192.0.2.1":    false,
+		"not-an-ip":    false,
+	} {
+		if got := hidden.hides(ip); got != want {
+			t.Errorf("Expected hides(%s) = %v, got %v", ip, want, got)
+		}
+	}
+	if hidden.empty() || !(hiddenIPs{}).empty() || (hiddenIPs{}).hides("192.0.2.9") {
+		t.Error("Expected only the zero value to hide nothing")
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxAPIKeyNameLength matches api_keys.name
+const maxAPIKeyNameLength = 100
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Role is read-only, operator or admin
+	Role string `json:"role"`
+}
+
+// CreateAPIKeyResponse returns the new key and its secret. The secret is only
+// shown once; only its hash is stored.
+type CreateAPIKeyResponse struct {
+	APIKey database.APIKey `json:"api_key"`
+	Key    string          `json:"key"`
+}
+
+// APIKeyStore persists the API keys managed through the API. The default
+// implementation delegates to the database package; tests substitute an
+// in-memory store.
+type APIKeyStore interface {
+	CreateAPIKey(name, role, prefix, keyHash, createdBy string) (*database.APIKey, error)
+	GetActiveAPIKeyByHash(keyHash string) (*database.APIKey, error)
+	TouchAPIKey(id int) error
+	ListAPIKeys() ([]database.APIKey, error)
+	RevokeAPIKey(id int) (bool, error)
+}
+
+// dbAPIKeyStore is the APIKeyStore backed by the global database connection
+type dbAPIKeyStore struct{}
+
+func (dbAPIKeyStore) CreateAPIKey(name, role, prefix, keyHash, createdBy string) (*database.APIKey, error) {
+	return database.CreateAPIKey(name, role, prefix, keyHash, createdBy)
+}
+
+func (dbAPIKeyStore) GetActiveAPIKeyByHash(keyHash string) (*database.APIKey, error) {
+	return database.GetActiveAPIKeyByHash(keyHash)
+}
+
+func (dbAPIKeyStore) TouchAPIKey(id int) error {
+	return database.TouchAPIKey(id)
+}
+
+func (dbAPIKeyStore) ListAPIKeys() ([]database.APIKey, error) {
+	return database.ListAPIKeys()
+}
+
+func (dbAPIKeyStore) RevokeAPIKey(id int) (bool, error) {
+	return database.RevokeAPIKey(id)
+}
+
+// APIKeyHandler manages the API keys stored in the database
+type APIKeyHandler struct {
+	store  APIKeyStore
+	static *auth.APIKeys
+	logger *logrus.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler. Names of the static keys
+// in static cannot be reused.
+func NewAPIKeyHandler(store APIKeyStore, static *auth.APIKeys, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{store: store, static: static, logger: logger}
+}
+
+// @Summary Create API key
+// @Description Create an API key with a role and return it. The key is only returned once. Requires the admin role.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param key body CreateAPIKeyRequest true "Key name and role"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/keys [post]
+func (h *APIKeyHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		writeUserError(w, http.StatusBadRequest, "validation_error", "Key name is required and must be at most 100 characters")
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if h.static.Role(name) != "" {
+		writeUserError(w, http.StatusConflict, "conflict", "A static API key with this name is configured")
+		return
+	}
+
+	key, hash, err := auth.NewAPIKey()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to generate API key")
+		writeUserError(w, http.StatusInternalServerError, "token_generation_failed", "Failed to generate API key")
+		return
+	}
+
+	created, err := h.store.CreateAPIKey(name, string(role), key[:len(auth.APIKeyPrefix)+8], hash, principalName(r))
+	if errors.Is(err, database.ErrAPIKeyExists) {
+		writeUserError(w, http.StatusConflict, "conflict", "An active API key with this name already exists")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_api_key_failed",
+			"name":   name,
+			"error":  err.Error(),
+		}).Error("Failed to create API key")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to create API key")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "api_key_created",
+		"key_id": created.ID,
+		"name":   created.Name,
+		"role":   created.Role,
+		"author": created.CreatedBy,
+	}).Info("API key created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{APIKey: *created, Key: key})
+}
+
+// @Summary List API keys
+// @Description List the API keys created through the API, revoked ones included, newest first. Static keys from AUTH_API_KEYS are not listed. Requires the admin role.
+// @Tags auth
+// @Produce json
+// @Success 200 {array} database.APIKey
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/keys [get]
+func (h *APIKeyHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.ListAPIKeys()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list API keys")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list API keys")
+		return
+	}
+	if keys == nil {
+		keys = []database.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// @Summary Revoke API key
+// @Description Revoke an API key. Requests with it are rejected from then on; the key stays listed. Requires the admin role.
+// @Tags auth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/keys/{id} [delete]
+func (h *APIKeyHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "API key ID must be a number")
+		return
+	}
+
+	revoked, err := h.store.RevokeAPIKey(id)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "revoke_api_key_failed",
+			"key_id": id,
+			"error":  err.Error(),
+		}).Error("Failed to revoke API key")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to revoke API key")
+		return
+	}
+	if !revoked {
+		writeUserError(w, http.StatusNotFound, "not_found", "Active API key not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "api_key_revoked",
+		"key_id": id,
+		"author": principalName(r),
+	}).Info("API key revoked")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"key_id": id,
+	})
+}
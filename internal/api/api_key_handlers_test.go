@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryAPIKeyStore is an in-memory APIKeyStore with unique active names
+type memoryAPIKeyStore struct {
+	keys    []database.APIKey
+	hashes  map[int]string
+	touched int
+}
+
+func (s *memoryAPIKeyStore) CreateAPIKey(name, role, prefix, keyHash, createdBy string) (*database.APIKey, error) {
+	for _, k := range s.keys {
+		if k.Name == name && k.RevokedAt == nil {
+			return nil, database.ErrAPIKeyExists
+		}
+	}
+	key := database.APIKey{ID: len(s.keys) + 1, Name: name, Role: role, Prefix: prefix, CreatedBy: createdBy, CreatedAt: time.Now()}
+	s.keys = append(s.keys, key)
+	if s.hashes == nil {
+		s.hashes = make(map[int]string)
+	}
+	s.hashes[key.ID] = keyHash
+	return &key, nil
+}
+
+func (s *memoryAPIKeyStore) GetActiveAPIKeyByHash(keyHash string) (*database.APIKey, error) {
+	for _, k := range s.keys {
+		if s.hashes[k.ID] == keyHash && k.RevokedAt == nil {
+			key := k
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryAPIKeyStore) TouchAPIKey(id int) error {
+	now := time.Now()
+	s.keys[id-1].LastUsedAt = &now
+	s.touched++
+	return nil
+}
+
+func (s *memoryAPIKeyStore) ListAPIKeys() ([]database.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *memoryAPIKeyStore) RevokeAPIKey(id int) (bool, error) {
+	if id < 1 || id > len(s.keys) || s.keys[id-1].RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	s.keys[id-1].RevokedAt = &now
+	return true, nil
+}
+
+func TestAPIKeyHandlers(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	store := &memoryAPIKeyStore{}
+	static, _ := auth.ParseAPIKeys("ci:0123456789abcdef")
+	h := NewAPIKeyHandler(store, static, log)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/keys", h.HandleCreate).Methods("POST")
+	router.HandleFunc("/api/auth/keys", h.HandleList).Methods("GET")
+	router.HandleFunc("/api/auth/keys/{id}", h.HandleRevoke).Methods("DELETE")
+
+	rec := doUserRequest(router, "POST", "/api/auth/keys", `{"name":"grafana","role":"read-only"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateAPIKeyResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, auth.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.APIKey.Prefix) || created.APIKey.Role != "read-only" {
+		t.Errorf("Unexpected key: %+v", created)
+	}
+	if store.hashes[1] != auth.HashToken(created.Key) {
+		t.Error("Expected only the hash of the key stored")
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"duplicate name", `{"name":"grafana","role":"operator"}`, http.StatusConflict},
+		{"static key name", `{"name":"ci","role":"operator"}`, http.StatusConflict},
+		{"unknown role", `{"name":"deploy","role":"root"}`, http.StatusBadRequest},
+		{"no name", `{"name":" ","role":"operator"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := doUserRequest(router, "POST", "/api/auth/keys", tt.body); rec.Code != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expected, rec.Code)
+		}
+	}
+
+	if rec := doUserRequest(router, "DELETE", "/api/auth/keys/1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 on revoke, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "DELETE", "/api/auth/keys/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 on second revoke, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "POST", "/api/auth/keys", `{"name":"grafana","role":"read-only"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected the name of a revoked key to be reusable, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/auth/keys", nil))
+	var keys []database.APIKey
+	json.NewDecoder(rec.Body).Decode(&keys)
+	if len(keys) != 2 || keys[0].RevokedAt == nil {
+		t.Errorf("Expected the revoked and the new key listed, got %+v", keys)
+	}
+}
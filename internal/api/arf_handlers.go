@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/arf"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxARFReportSize caps an uploaded report, original message included
+const maxARFReportSize = 10 << 20
+
+// AbuseComplaintResponse is the complaint stored for an ARF report
+type AbuseComplaintResponse struct {
+	Complaint database.AbuseComplaint `json:"complaint"`
+	// Duplicate is true when the reporter already sent this report; nothing
+	// was stored
+	Duplicate bool `json:"duplicate"`
+}
+
+// complaintStore persists abuse complaints
+type complaintStore interface {
+	InsertAbuseComplaint(c *database.AbuseComplaint) (bool, error)
+	GetAbuseComplaintsByIP(ip string, since time.Time) ([]database.AbuseComplaint, error)
+}
+
+type dbComplaintStore struct{}
+
+func (dbComplaintStore) InsertAbuseComplaint(c *database.AbuseComplaint) (bool, error) {
+	return database.InsertAbuseComplaint(c)
+}
+
+func (dbComplaintStore) GetAbuseComplaintsByIP(ip string, since time.Time) ([]database.AbuseComplaint, error) {
+	return database.GetAbuseComplaintsByIP(ip, since)
+}
+
+// ARFHandler turns ARF abuse reports into stored complaints
+type ARFHandler struct {
+	store  complaintStore
+	logger *logrus.Logger
+}
+
+// NewARFHandler creates a new ARF report handler
+func NewARFHandler(logger *logrus.Logger) *ARFHandler {
+	return &ARFHandler{store: dbComplaintStore{}, logger: logger}
+}
+
+// reportBody returns the raw report: the request body, or the "report" file
+// of a multipart/form-data upload
+func reportBody(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	if err := r.ParseMultipartForm(maxARFReportSize); err != nil {
+		return nil, err
+	}
+	file, _, err := r.FormFile("report")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// @Summary Ingest ARF abuse report
+// @Description Parse a raw ARF feedback report (RFC 5965 multipart/report email) sent as the body, or as the "report" file of a multipart/form-data upload. The sending IP is the report's Source-IP, or the first public IP in the original message's Received headers; the campaign comes from its X-Campaign-ID, Feedback-ID or List-ID header. Only the recipient's domain is stored. Complaints feed the complaint-rate rules of the next aggregation run; not-spam and auth-failure reports are stored but not counted.
+// @Tags ip-reputation
+// @Accept message/rfc822
+// @Accept multipart/form-data
+// @Produce json
+// @Param report body string true "Raw ARF report"
+// @Success 201 {object} AbuseComplaintResponse
+// @Success 200 {object} AbuseComplaintResponse "Already reported"
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reports/arf [post]
+func (h *ARFHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxARFReportSize)
+
+	var report *arf.Report
+	body, err := reportBody(r)
+	if err == nil {
+		report, err = arf.Parse(body)
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeUserError(w, http.StatusRequestEntityTooLarge, "report_too_large", "Report exceeds 10MB")
+		return
+	case errors.Is(err, http.ErrMissingFile):
+		writeUserError(w, http.StatusBadRequest, "missing_report", "Upload the report as the \"report\" file")
+		return
+	case errors.Is(err, arf.ErrNotARF):
+		writeUserError(w, http.StatusBadRequest, "not_arf", "Body must be a multipart/report feedback report")
+		return
+	case err != nil:
+		writeUserError(w, http.StatusBadRequest, "invalid_report", err.Error())
+		return
+	}
+
+	ip, err := validation.NormalizeIP(report.SendingIP)
+	if err != nil {
+		writeUserError(w, http.StatusUnprocessableEntity, "unknown_sending_ip", "No sending IP in the report's Source-IP or the original message's Received headers")
+		return
+	}
+
+	now := time.Now()
+	arrivedAt := report.ArrivalDate
+	if arrivedAt.IsZero() || arrivedAt.After(now) {
+		arrivedAt = now
+	}
+	reporter := report.Reporter
+	if reporter == "" {
+		reporter = "unknown"
+	}
+
+	complaint := &database.AbuseComplaint{
+		SendingIP:         ip,
+		FeedbackType:      report.FeedbackType,
+		Reporter:          reporter,
+		ReportID:          report.ReportID,
+		CampaignID:        report.CampaignID,
+		OriginalMessageID: report.OriginalMessageID,
+		OriginalMailFrom:  report.OriginalMailFrom,
+		RecipientDomain:   arf.Domain(report.OriginalRcptTo),
+		ReportedDomain:    report.ReportedDomain,
+		UserAgent:         report.UserAgent,
+		ArrivedAt:         arrivedAt,
+		ReportedBy:        principalName(r),
+	}
+	stored, err := h.store.InsertAbuseComplaint(complaint)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "record_abuse_complaint_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to store abuse complaint")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to store abuse complaint")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !stored {
+		json.NewEncoder(w).Encode(AbuseComplaintResponse{Complaint: *complaint, Duplicate: true})
+		return
+	}
+
+	reputation.RecordAbuseComplaint(ip, reporter, complaint.FeedbackType)
+	h.logger.WithFields(logrus.Fields{
+		"action":        "abuse_complaint_recorded",
+		"ip":            ip,
+		"reporter":      reporter,
+		"feedback_type": complaint.FeedbackType,
+		"campaign_id":   complaint.CampaignID,
+	}).Info("Abuse complaint recorded")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(AbuseComplaintResponse{Complaint: *complaint})
+}
+
+// @Summary Get abuse complaints for IP
+// @Description Complaints about an IP's mail that arrived within a time window, newest first
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 24h, 168h)" default(24h)
+// @Success 200 {array} database.AbuseComplaint
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/complaints [get]
+func (h *ARFHandler) HandleListComplaints(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "24h"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil || duration <= 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.)")
+		return
+	}
+
+	complaints, err := h.store.GetAbuseComplaintsByIP(ip, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_abuse_complaints_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get abuse complaints")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve abuse complaints")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(complaints)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryComplaintStore is an in-memory complaintStore
+type memoryComplaintStore struct {
+	complaints []database.AbuseComplaint
+}
+
+func (s *memoryComplaintStore) InsertAbuseComplaint(c *database.AbuseComplaint) (bool, error) {
+	for _, existing := range s.complaints {
+		if c.ReportID != "" && existing.Reporter == c.Reporter && existing.ReportID == c.ReportID {
+			return false, nil
+		}
+	}
+	c.ID = len(s.complaints) + 1
+	c.CreatedAt = time.Now()
+	s.complaints = append(s.complaints, *c)
+	return true, nil
+}
+
+func (s *memoryComplaintStore) GetAbuseComplaintsByIP(ip string, since time.Time) ([]database.AbuseComplaint, error) {
+	complaints := []database.AbuseComplaint{}
+	for i := len(s.complaints) - 1; i >= 0; i-- {
+		if c := s.complaints[i]; c.SendingIP == ip && !c.ArrivedAt.Before(since) {
+			complaints = append(complaints, c)
+		}
+	}
+	return complaints, nil
+}
+
+func newTestARFRouter() (*mux.Router, *memoryComplaintStore) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryComplaintStore{}
+	handler := &ARFHandler{store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reports/arf", handler.HandleReport).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/complaints", handler.HandleListComplaints).Methods("GET")
+	return router, store
+}
+
+// arfReport is a minimal feedback report with the given report ID and
+// feedback report fields
+func arfReport(reportID, feedback string) string {
+	return strings.ReplaceAll(`From: "Abuse Desk" <fbl@Mail-Provider.example>
+Message-ID: <`+reportID+`>
+Content-Type: multipart/report; report-type=feedback-report; boundary="b"
+
+--b
+Content-Type: text/plain
+
+This is an abuse report.
+--b
+Content-Type: message/feedback-report
+
+Feedback-Type: abuse
+User-Agent: FBL/1.0
+Version: 1
+Original-Rcpt-To: <someone@mail-provider.example>
+`+feedback+`
+--b
+Content-Type: text/rfc822-headers
+
+Received: from out.sender.example (out.sender.example [192.0.2.10]) by mx.mail-provider.example
+Message-ID: <m-1@sender.example>
+X-Campaign-ID: spring-sale
+--b--
+`, "\n", "\r\n")
+}
+
+func TestIngestARFReport(t *testing.T) {
+	router, store := newTestARFRouter()
+
+	body := arfReport("r-1@mail-provider.example", "Arrival-Date: Thu, 8 Mar 2005 14:00:00 -0400")
+	rec := serveConfig(router, "POST", "/api/reports/arf", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AbuseComplaintResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Complaint
+	if c.SendingIP != "192.0.2.10" || c.CampaignID != "spring-sale" || c.Reporter != "mail-provider.example" || c.FeedbackType != "abuse" {
+		t.Errorf("Unexpected complaint: %+v", c)
+	}
+	// Only the recipient's domain is kept
+	if c.RecipientDomain != "mail-provider.example" || strings.Contains(rec.Body.String(), "someone@") {
+		t.Errorf("Expected the recipient reduced to its domain: %s", rec.Body.String())
+	}
+	if c.ArrivedAt.Year() != 2005 {
+		t.Errorf("Expected the report's arrival date, got %v", c.ArrivedAt)
+	}
+
+	// The same report again is stored once
+	rec = serveConfig(router, "POST", "/api/reports/arf", body)
+	resp = AbuseComplaintResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || !resp.Duplicate || len(store.complaints) != 1 {
+		t.Errorf("Expected a re-sent report to be skipped, got %d %+v", rec.Code, resp)
+	}
+
+	// A multipart/form-data upload, with Source-IP overriding Received
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	file, _ := writer.CreateFormFile("report", "report.eml")
+	file.Write([]byte(arfReport("r-2@mail-provider.example", "Source-IP: 198.51.100.20")))
+	writer.Close()
+	req := httptest.NewRequest("POST", "/api/reports/arf", &form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || len(store.complaints) != 2 || store.complaints[1].SendingIP != "198.51.100.20" {
+		t.Errorf("Expected the uploaded report stored, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if arrived := store.complaints[1].ArrivedAt; time.Since(arrived) > time.Minute {
+		t.Errorf("Expected a report without Arrival-Date to arrive now, got %v", arrived)
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/198.51.100.20/complaints", "")
+	var complaints []database.AbuseComplaint
+	if err := json.Unmarshal(rec.Body.Bytes(), &complaints); err != nil || len(complaints) != 1 {
+		t.Errorf("Unexpected complaints: %s", rec.Body.String())
+	}
+}
+
+func TestIngestARFReportValidation(t *testing.T) {
+	router, store := newTestARFRouter()
+
+	noIP := strings.Replace(arfReport("r-3@mail-provider.example", ""), "[192.0.2.10]", "[10.0.0.1]", 1)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"not a report", "From: a@example.com\r\n\r\nhello\r\n", http.StatusBadRequest, "not_arf"},
+		{"unreadable", "\x00", http.StatusBadRequest, "invalid_report"},
+		{"no sending ip", noIP, http.StatusUnprocessableEntity, "unknown_sending_ip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveConfig(router, "POST", "/api/reports/arf", tt.body)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.code) {
+				t.Errorf("Expected %d %s, got %d: %s", tt.status, tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("comment", "no file")
+	writer.Close()
+	req := httptest.NewRequest("POST", "/api/reports/arf", &form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "missing_report") {
+		t.Errorf("Expected 400 missing_report, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(store.complaints) != 0 {
+		t.Errorf("Rejected reports were stored: %+v", store.complaints)
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-backend-service/internal/audit"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler reports and triggers the self-audit of stale data and
+// inconsistencies
+type AuditHandler struct {
+	auditor *audit.Auditor
+	logger  *logrus.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditor *audit.Auditor, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{auditor: auditor, logger: logger}
+}
+
+// @Summary Get latest audit report
+// @Description The newest self-audit report. Each check counts one kind of inconsistency: stale_metrics (reputation metrics not updated within AUDIT_STALE_METRICS_AFTER, 24 hours by default, although failures arrived), missing_ionos_blocks (unreleased reserved IPs whose IONOS block is gone), stale_dnsbl_checks (active IPs whose last DNSBL check is older than the policy) and orphaned_actions (IP actions of IPs the service no longer knows). A check above its threshold is exceeded.
+// @Tags system
+// @Produce json
+// @Success 200 {object} database.AuditReport
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/audit [get]
+func (h *AuditHandler) HandleLatest(w http.ResponseWriter, r *http.Request) {
+	report, err := h.auditor.Latest()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get latest audit report")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve the audit report")
+		return
+	}
+	if report == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "The self-audit has not run yet")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// @Summary Run audit
+// @Description Run the self-audit now instead of waiting for the schedule, and return its report. Checks above their threshold alert as on a scheduled run.
+// @Tags system
+// @Produce json
+// @Success 200 {object} database.AuditReport
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/audit/run [post]
+func (h *AuditHandler) HandleRun(w http.ResponseWriter, r *http.Request) {
+	report, err := h.auditor.Run(r.Context(), time.Now())
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "audit_failed",
+			"error":  err.Error(),
+		}).Error("Failed to run self-audit")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to run the self-audit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// @Summary List audit reports
+// @Description Self-audit reports, newest first
+// @Tags system
+// @Produce json
+// @Param limit query int false "Number of reports (1-500)" default(30)
+// @Success 200 {array} database.AuditReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/audit/reports [get]
+func (h *AuditHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	limit := 30
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > 500 {
+			writeUserError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	reports, err := h.auditor.Reports(limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list audit reports")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list audit reports")
+		return
+	}
+	if reports == nil {
+		reports = []database.AuditReport{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/audit"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryAuditStore is an in-memory audit.Store finding orphaned actions only
+type memoryAuditStore struct {
+	orphans database.AuditFinding
+	reports []database.AuditReport
+}
+
+func (s *memoryAuditStore) AuditStaleMetrics(time.Time, int) (database.AuditFinding, error) {
+	return database.AuditFinding{}, nil
+}
+
+func (s *memoryAuditStore) AuditStaleDNSBLChecks(time.Time, int) (database.AuditFinding, error) {
+	return database.AuditFinding{}, nil
+}
+
+func (s *memoryAuditStore) AuditOrphanedActions(int) (database.AuditFinding, error) {
+	return s.orphans, nil
+}
+
+func (s *memoryAuditStore) ListActiveReservedIPBlocks() ([]database.ReservedIPBlock, error) {
+	return nil, nil
+}
+
+func (s *memoryAuditStore) InsertAuditReport(report *database.AuditReport) error {
+	report.ID = len(s.reports) + 1
+	s.reports = append([]database.AuditReport{*report}, s.reports...)
+	return nil
+}
+
+func (s *memoryAuditStore) GetLatestAuditReport() (*database.AuditReport, error) {
+	if len(s.reports) == 0 {
+		return nil, nil
+	}
+	return &s.reports[0], nil
+}
+
+func (s *memoryAuditStore) ListAuditReports(limit int) ([]database.AuditReport, error) {
+	if limit < len(s.reports) {
+		return s.reports[:limit], nil
+	}
+	return s.reports, nil
+}
+
+func (s *memoryAuditStore) DeleteAuditReportsBefore(time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestAuditHandlers(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryAuditStore{orphans: database.AuditFinding{Count: 2, Samples: []string{"203.0.113.9"}}}
+	handler := NewAuditHandler(audit.NewAuditorWithStore(audit.DefaultConfig(), store, nil, logger), logger)
+
+	rec := httptest.NewRecorder()
+	handler.HandleLatest(rec, httptest.NewRequest(http.MethodGet, "/api/system/audit", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before the first run, got %d", rec.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		handler.HandleRun(rec, httptest.NewRequest(http.MethodPost, "/api/system/audit/run", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	var report database.AuditReport
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.ID != 2 || report.Issues != 2 || report.Exceeded != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleLatest(rec, httptest.NewRequest(http.MethodGet, "/api/system/audit", nil))
+	var latest database.AuditReport
+	json.NewDecoder(rec.Body).Decode(&latest)
+	if rec.Code != http.StatusOK || latest.ID != 2 {
+		t.Errorf("Expected the second report, got %d %+v", rec.Code, latest)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleList(rec, httptest.NewRequest(http.MethodGet, "/api/system/audit/reports?limit=1", nil))
+	var reports []database.AuditReport
+	json.NewDecoder(rec.Body).Decode(&reports)
+	if len(reports) != 1 || reports[0].ID != 2 {
+		t.Errorf("Expected the newest report only, got %+v", reports)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleList(rec, httptest.NewRequest(http.MethodGet, "/api/system/audit/reports?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", rec.Code)
+	}
+}
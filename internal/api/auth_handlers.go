@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoginRequest represents the request body for logging in
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest represents the request body for refreshing or ending a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse carries a new access token and its refresh token
+type TokenResponse struct {
+	AccessToken  string        `json:"access_token"`
+	TokenType    string        `json:"token_type"`
+	ExpiresIn    int           `json:"expires_in"`
+	RefreshToken string        `json:"refresh_token"`
+	User         database.User `json:"user"`
+}
+
+// authStore persists credentials and refresh tokens
+type authStore interface {
+	GetUserCredentials(username string) (*database.User, string, error)
+	GetUserByID(id int) (*database.User, error)
+	CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error
+	ConsumeRefreshToken(tokenHash string) (*database.RefreshToken, error)
+}
+
+// dbAuthStore is the authStore backed by the global database connection
+type dbAuthStore struct{}
+
+func (dbAuthStore) GetUserCredentials(username string) (*database.User, string, error) {
+	return database.GetUserCredentials(username)
+}
+
+func (dbAuthStore) GetUserByID(id int) (*database.User, error) {
+	return database.GetUserByID(id)
+}
+
+func (dbAuthStore) CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	return database.CreateRefreshToken(userID, tokenHash, expiresAt)
+}
+
+func (dbAuthStore) ConsumeRefreshToken(tokenHash string) (*database.RefreshToken, error) {
+	return database.ConsumeRefreshToken(tokenHash)
+}
+
+// AuthHandler handles dashboard login sessions
+type AuthHandler struct {
+	issuer     *auth.TokenIssuer
+	refreshTTL time.Duration
+	store      authStore
+	logger     *logrus.Logger
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(issuer *auth.TokenIssuer, refreshTTL time.Duration, logger *logrus.Logger) *AuthHandler {
+	return &AuthHandler{
+		issuer:     issuer,
+		refreshTTL: refreshTTL,
+		store:      dbAuthStore{},
+		logger:     logger,
+	}
+}
+
+// issueSession writes a new access and refresh token pair for a user
+func (h *AuthHandler) issueSession(w http.ResponseWriter, user *database.User) {
+	accessToken, _, err := h.issuer.Issue(user.ID, user.Username, auth.Role(user.Role))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to issue access token")
+		writeUserError(w, http.StatusInternalServerError, "token_generation_failed", "Failed to issue access token")
+		return
+	}
+
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err == nil {
+		err = h.store.CreateRefreshToken(user.ID, refreshHash, time.Now().Add(h.refreshTTL))
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to store refresh token")
+		writeUserError(w, http.StatusInternalServerError, "token_generation_failed", "Failed to issue refresh token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.issuer.TTL().Seconds()),
+		RefreshToken: refreshToken,
+		User:         *user,
+	})
+}
+
+// @Summary Log in
+// @Description Exchange a username and password for a short-lived access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Username and password"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" || req.Password == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Username and password are required")
+		return
+	}
+
+	user, hash, err := h.store.GetUserCredentials(req.Username)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to look up user credentials")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to log in")
+		return
+	}
+
+	if !auth.CheckPassword(hash, req.Password) || user == nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":      "login_failed",
+			"username":    req.Username,
+			"remote_addr": r.RemoteAddr,
+		}).Warn("Failed login attempt")
+
+		writeUserError(w, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "login",
+		"user_id": user.ID,
+	}).Info("User logged in")
+
+	h.issueSession(w, user)
+}
+
+// @Summary Refresh session
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated and can only be used once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	token, err := h.store.ConsumeRefreshToken(auth.HashToken(req.RefreshToken))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to consume refresh token")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to refresh session")
+		return
+	}
+	if token == nil {
+		writeUserError(w, http.StatusUnauthorized, "invalid_refresh_token", "Refresh token is invalid, expired or already used")
+		return
+	}
+
+	user, err := h.store.GetUserByID(token.UserID)
+	if err != nil {
+		// The user was deleted since the token was issued
+		writeUserError(w, http.StatusUnauthorized, "invalid_refresh_token", "Refresh token is invalid, expired or already used")
+		return
+	}
+
+	h.issueSession(w, user)
+}
+
+// @Summary Log out
+// @Description Revoke a refresh token. Access tokens stay valid until they expire.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	if _, err := h.store.ConsumeRefreshToken(auth.HashToken(req.RefreshToken)); err != nil {
+		h.logger.WithError(err).Error("Failed to revoke refresh token")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to log out")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+	})
+}
+
+// @Summary Current principal
+// @Description Return the user or API key the request is authenticated as
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.Principal
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/me [get]
+func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal == nil {
+		writeUserError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(principal)
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/auth"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// unauthenticatedPaths are reachable without credentials even when
+// authentication is required. Webhook ingestion has its own source tokens
+// and reputation feeds their feed tokens, ITSM status webhooks the shared
+// ITSM secret.
+var unauthenticatedPaths = []string{
+	"/health",
+	"/metrics",
+	"/swagger/",
+	"/openapi.json",
+	"/auth/login",
+	"/auth/refresh",
+	"/api/webhooks/stalwart/delivery-failure",
+	"/api/webhooks/stalwart/events",
+	"/api/webhooks/reputation-feeds/signals",
+	"/api/webhooks/itsm/",
+}
+
+// adminPaths need the admin role for every method: they expose API keys,
+// directory provisioning or the whole database. A path matches its
+// subpaths.
+var adminPaths = []string{
+	"/api/auth/keys",
+	"/api/system/backup",
+	"/api/system/restore",
+	"/scim/v2",
+}
+
+// adminWritePaths can be read with the read-only role but need the admin
+// role to change: users, access, system settings, the reputation model and
+// where alerts are sent
+var adminWritePaths = []string{
+	"/users",
+	"/api/teams",
+	"/api/system",
+	"/api/admin",
+	"/api/reputation/config",
+	"/api/reputation/shadow",
+	"/api/reputation/feeds",
+	"/api/reputation/exclusions",
+	"/api/webhooks/sources",
+	"/api/privacy",
+	"/api/notification-routes",
+}
+
+// readOnlyPosts are POSTs that change nothing
+var readOnlyPosts = []string{
+	"/auth/logout",
+	"/api/reputation/simulate",
+}
+
+// AuthConfig configures request authentication
+type AuthConfig struct {
+	// Required rejects requests without valid credentials. When false,
+	// credentials are still verified if present.
+	Required bool
+	// Issuer signs dashboard access tokens
+	Issuer *auth.TokenIssuer
+	// APIKeys are the static machine keys
+	APIKeys *auth.APIKeys
+	// RefreshTTL is how long a refresh token stays valid
+	RefreshTTL time.Duration
+	// SCIMEnabled serves /scim/v2 so a corporate directory can provision
+	// users and teams with an API key
+	SCIMEnabled bool
+	// KeyStore holds the API keys created through /api/auth/keys. Nil only
+	// accepts the static keys.
+	KeyStore APIKeyStore
+}
+
+// authMiddleware authenticates requests with either an API key (X-API-Key or
+// Bearer) or a dashboard JWT (Bearer), stores the principal on the context
+// and rejects principals whose role does not allow the request. Anonymous
+// requests, accepted while authentication is optional, are not restricted.
+func authMiddleware(config AuthConfig, log *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || isUnauthenticatedPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			credential, fromHeader := requestCredential(r)
+			if credential == "" {
+				if config.Required {
+					rejectUnauthenticated(w, r, log, "missing_credentials")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := authenticate(config, credential, fromHeader)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"action": "api_key_lookup_failed",
+					"error":  err.Error(),
+				}).Error("Failed to look up API key")
+				writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to authenticate API key")
+				return
+			}
+			if principal == nil {
+				rejectUnauthenticated(w, r, log, "invalid_credentials")
+				return
+			}
+			if required := requiredRole(r); !principal.Allows(required) {
+				rejectForbidden(w, r, log, principal, required)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// requestCredential returns the credential and the header it came from
+func requestCredential(r *http.Request) (string, string) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, "X-API-Key"
+	}
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")), "Authorization"
+	}
+	return "", ""
+}
+
+// authenticate resolves a credential to a principal, nil if it is invalid.
+// Errors are failures to look up a stored API key.
+func authenticate(config AuthConfig, credential, fromHeader string) (*auth.Principal, error) {
+	if name, ok := config.APIKeys.Lookup(credential); ok {
+		return &auth.Principal{Kind: auth.KindAPIKey, KeyName: name, Role: config.APIKeys.Role(name)}, nil
+	}
+	if strings.HasPrefix(credential, auth.APIKeyPrefix) {
+		return authenticateStoredKey(config.KeyStore, credential)
+	}
+	// JWTs only come as Bearer tokens
+	if fromHeader != "Authorization" || config.Issuer == nil || strings.Count(credential, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := config.Issuer.Verify(credential)
+	if err != nil {
+		return nil, nil
+	}
+	userID, _ := claims.UserID()
+	// Tokens without a valid role get the least privileged one
+	role, err := auth.ParseRole(string(claims.Role))
+	if err != nil {
+		role = auth.RoleReadOnly
+	}
+	return &auth.Principal{Kind: auth.KindUser, UserID: userID, Username: claims.Username, Role: role}, nil
+}
+
+// authenticateStoredKey looks up a key created through the API and records
+// its use about once a minute
+func authenticateStoredKey(store APIKeyStore, credential string) (*auth.Principal, error) {
+	if store == nil {
+		return nil, nil
+	}
+	key, err := store.GetActiveAPIKeyByHash(auth.HashToken(credential))
+	if err != nil || key == nil {
+		return nil, err
+	}
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > time.Minute {
+		if err := store.TouchAPIKey(key.ID); err != nil {
+			return nil, err
+		}
+	}
+	return &auth.Principal{Kind: auth.KindAPIKey, KeyName: key.Name, Role: auth.Role(key.Role)}, nil
+}
+
+// requiredRole is the least role allowed to make a request: read-only for
+// reads, operator for changes and admin for adminPaths and changes under
+// adminWritePaths
+func requiredRole(r *http.Request) auth.Role {
+	if hasPathPrefix(r.URL.Path, adminPaths) {
+		return auth.RoleAdmin
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return auth.RoleReadOnly
+	}
+	if r.Method == http.MethodPost && hasPathPrefix(r.URL.Path, readOnlyPosts) {
+		return auth.RoleReadOnly
+	}
+	if hasPathPrefix(r.URL.Path, adminWritePaths) {
+		return auth.RoleAdmin
+	}
+	return auth.RoleOperator
+}
+
+// hasPathPrefix reports whether path is one of prefixes or below one
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnauthenticatedPath(path string) bool {
+	for _, p := range unauthenticatedPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func rejectUnauthenticated(w http.ResponseWriter, r *http.Request, log *logrus.Logger, reason string) {
+	log.WithFields(logrus.Fields{
+		"action":      "auth_failed",
+		"reason":      reason,
+		"path":        redactedPath(r),
+		"remote_addr": r.RemoteAddr,
+	}).Warn("Rejected unauthenticated request")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   "unauthorized",
+		Message: "A valid API key or access token is required",
+	})
+}
+
+func rejectForbidden(w http.ResponseWriter, r *http.Request, log *logrus.Logger, principal *auth.Principal, required auth.Role) {
+	log.WithFields(logrus.Fields{
+		"action":    "auth_forbidden",
+		"principal": principal.Name(),
+		"role":      principal.Role,
+		"required":  required,
+		"method":    r.Method,
+		"path":      redactedPath(r),
+	}).Warn("Rejected request above the principal's role")
+
+	writeUserError(w, http.StatusForbidden, "forbidden", "This request requires the "+string(required)+" role")
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryAuthStore is an in-memory authStore with a single user
+type memoryAuthStore struct {
+	user     database.User
+	hash     string
+	sessions map[string]int
+}
+
+func (s *memoryAuthStore) GetUserCredentials(username string) (*database.User, string, error) {
+	if username != s.user.Username {
+		return nil, "", nil
+	}
+	user := s.user
+	return &user, s.hash, nil
+}
+
+func (s *memoryAuthStore) GetUserByID(id int) (*database.User, error) {
+	user := s.user
+	return &user, nil
+}
+
+func (s *memoryAuthStore) CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	s.sessions[tokenHash] = userID
+	return nil
+}
+
+func (s *memoryAuthStore) ConsumeRefreshToken(tokenHash string) (*database.RefreshToken, error) {
+	userID, ok := s.sessions[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.sessions, tokenHash)
+	return &database.RefreshToken{UserID: userID}, nil
+}
+
+func newTestAuthHandler(t *testing.T) (*AuthHandler, *auth.TokenIssuer) {
+	t.Helper()
+	log, _ := test.NewNullLogger()
+	issuer, err := auth.NewTokenIssuer([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+	hash, _ := auth.HashPassword("correct horse battery")
+
+	h := NewAuthHandler(issuer, time.Hour, log)
+	h.store = &memoryAuthStore{
+		user:     database.User{ID: 7, Username: "operator"},
+		hash:     hash,
+		sessions: make(map[string]int),
+	}
+	return h, issuer
+}
+
+func postJSON(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/auth", strings.NewReader(body)))
+	return rec
+}
+
+func TestLoginAndRefreshRotation(t *testing.T) {
+	h, issuer := newTestAuthHandler(t)
+
+	if rec := postJSON(h.HandleLogin, `{"username":"operator","password":"wrong password!"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong password, got %d", rec.Code)
+	}
+	if rec := postJSON(h.HandleLogin, `{"username":"nobody","password":"correct horse battery"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for unknown user, got %d", rec.Code)
+	}
+
+	rec := postJSON(h.HandleLogin, `{"username":"operator","password":"correct horse battery"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var session TokenResponse
+	json.NewDecoder(rec.Body).Decode(&session)
+	if claims, err := issuer.Verify(session.AccessToken); err != nil || claims.Username != "operator" {
+		t.Fatalf("Expected valid access token, got %v", err)
+	}
+	if session.TokenType != "Bearer" || session.ExpiresIn != 60 || session.RefreshToken == "" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+
+	body := `{"refresh_token":"` + session.RefreshToken + `"}`
+	rec = postJSON(h.HandleRefresh, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected refresh to succeed, got %d", rec.Code)
+	}
+	var refreshed TokenResponse
+	json.NewDecoder(rec.Body).Decode(&refreshed)
+	if refreshed.RefreshToken == session.RefreshToken {
+		t.Error("Expected refresh token to be rotated")
+	}
+
+	if rec := postJSON(h.HandleRefresh, body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected reused refresh token to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	issuer, _ := auth.NewTokenIssuer([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	keys, _ := auth.ParseAPIKeys("ci:0123456789abcdef")
+	token, _, _ := issuer.Issue(7, "operator", auth.RoleOperator)
+
+	var seen *auth.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = auth.PrincipalFromContext(r.Context())
+	})
+
+	tests := []struct {
+		name      string
+		required  bool
+		path      string
+		headers   map[string]string
+		status    int
+		principal string
+	}{
+		{"required without credentials", true, "/api/dashboard/ip-health", nil, http.StatusUnauthorized, ""},
+		{"optional without credentials", false, "/api/dashboard/ip-health", nil, http.StatusOK, ""},
+		{"exempt path", true, "/health", nil, http.StatusOK, ""},
+		{"api key header", true, "/users", map[string]string{"X-API-Key": "0123456789abcdef"}, http.StatusOK, "api_key:ci"},
+		{"api key bearer", true, "/users", map[string]string{"Authorization": "Bearer 0123456789abcdef"}, http.StatusOK, "api_key:ci"},
+		{"jwt bearer", true, "/users", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK, "user:operator"},
+		{"jwt in api key header", true, "/users", map[string]string{"X-API-Key": token}, http.StatusUnauthorized, ""},
+		{"invalid even when optional", false, "/users", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		seen = nil
+		handler := authMiddleware(AuthConfig{Required: tt.required, Issuer: issuer, APIKeys: keys}, log)(next)
+		req := httptest.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, rec.Code)
+		}
+		got := ""
+		if seen != nil {
+			got = seen.Name()
+		}
+		if got != tt.principal {
+			t.Errorf("%s: expected principal %q, got %q", tt.name, tt.principal, got)
+		}
+	}
+}
+
+func TestAuthMiddlewareRoles(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	issuer, _ := auth.NewTokenIssuer([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	keys, _ := auth.ParseAPIKeys("ci:0123456789abcdef,grafana:fedcba9876543210:read-only")
+	store := &memoryAPIKeyStore{}
+	stored, hash, _ := auth.NewAPIKey()
+	store.CreateAPIKey("deploy", "operator", stored[:11], hash, "user:admin")
+	revoked, hash, _ := auth.NewAPIKey()
+	store.CreateAPIKey("old", "admin", revoked[:11], hash, "user:admin")
+	store.RevokeAPIKey(2)
+
+	viewer, _, _ := issuer.Issue(1, "viewer", auth.RoleReadOnly)
+	operator, _, _ := issuer.Issue(2, "operator", auth.RoleOperator)
+	legacy, _, _ := issuer.Issue(3, "legacy", "")
+
+	handler := authMiddleware(AuthConfig{Required: true, Issuer: issuer, APIKeys: keys, KeyStore: store}, log)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		credential string
+		status     int
+	}{
+		{"read-only reads", "GET", "/api/ips/registry", viewer, http.StatusOK},
+		{"read-only cannot quarantine", "POST", "/api/ips/192.0.2.1/quarantine", viewer, http.StatusForbidden},
+		{"read-only cannot reserve", "POST", "/api/v1/ips/reserve", "fedcba9876543210", http.StatusForbidden},
+		{"read-only simulates", "POST", "/api/reputation/simulate", viewer, http.StatusOK},
+		{"read-only logs out", "POST", "/auth/logout", viewer, http.StatusOK},
+		{"read-only cannot read backups", "GET", "/api/system/backup", viewer, http.StatusForbidden},
+		{"operator quarantines", "POST", "/api/ips/192.0.2.1/quarantine", operator, http.StatusOK},
+		{"operator reserves", "POST", "/api/v1/ips/reserve", operator, http.StatusOK},
+		{"operator cannot change users", "PUT", "/users/1/role", operator, http.StatusForbidden},
+		{"operator cannot toggle features", "PUT", "/api/system/features/auto_rotation", operator, http.StatusForbidden},
+		{"operator cannot list keys", "GET", "/api/auth/keys", operator, http.StatusForbidden},
+		{"operator reads users", "GET", "/users", operator, http.StatusOK},
+		{"operator cannot route alerts", "POST", "/api/notification-routes", operator, http.StatusForbidden},
+		{"operator reads alert routes", "GET", "/api/notification-routes", operator, http.StatusOK},
+		{"token without role is read-only", "POST", "/api/ips/192.0.2.1/quarantine", legacy, http.StatusForbidden},
+		{"static key defaults to admin", "POST", "/api/auth/keys", "0123456789abcdef", http.StatusOK},
+		{"stored operator key", "POST", "/api/v1/ips/reserve", stored, http.StatusOK},
+		{"stored operator key below admin", "DELETE", "/api/auth/keys/1", stored, http.StatusForbidden},
+		{"revoked key", "GET", "/api/ips/registry", revoked, http.StatusUnauthorized},
+		{"unknown stored key", "GET", "/api/ips/registry", auth.APIKeyPrefix + "unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.credential)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, rec.Code)
+		}
+	}
+	if store.touched != 1 {
+		t.Errorf("Expected the stored key's use recorded once, got %d", store.touched)
+	}
+}
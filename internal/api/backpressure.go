@@ -0,0 +1,144 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+)
+
+// Backpressure rejection reasons, as reported by the metric
+const (
+	backpressureInFlight = "in_flight"
+	backpressurePool     = "database_pool"
+	backpressureFailures = "storage_failures"
+)
+
+// BackpressureConfig sets when the webhook refuses payloads so Stalwart keeps
+// them queued and retries, instead of them being stored partially
+type BackpressureConfig struct {
+	// MaxInFlight is the number of payloads ingested at once; further ones
+	// get 429. 0 disables the limit.
+	MaxInFlight int
+	// PoolThreshold is the share (0-1] of the database connections in use at
+	// which new payloads get 503. 0 disables the check.
+	PoolThreshold float64
+	// FailureThreshold consecutive payloads with storage errors make the
+	// webhook answer 503 for RetryAfter. 0 disables it.
+	FailureThreshold int
+	// RetryAfter is sent with every refusal
+	RetryAfter time.Duration
+}
+
+// DefaultBackpressureConfig refuses payloads beyond 64 in flight, at 90%
+// pool use, or for 30 seconds after three payloads failed to store
+func DefaultBackpressureConfig() BackpressureConfig {
+	return BackpressureConfig{
+		MaxInFlight:      64,
+		PoolThreshold:    0.9,
+		FailureThreshold: 3,
+		RetryAfter:       30 * time.Second,
+	}
+}
+
+// Backpressure applies a BackpressureConfig to the webhook. A payload counts
+// as failed when the handler answers 503, which it does when any event could
+// not be stored.
+type Backpressure struct {
+	config BackpressureConfig
+	pool   func() (sql.DBStats, bool)
+	now    func() time.Time
+
+	mu        sync.Mutex
+	inFlight  int
+	failures  int
+	openUntil time.Time
+}
+
+// NewBackpressure creates a backpressure policy reading the database pool;
+// a RetryAfter <= 0 uses 30 seconds
+func NewBackpressure(config BackpressureConfig) *Backpressure {
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = 30 * time.Second
+	}
+	return &Backpressure{config: config, pool: database.PoolStats, now: time.Now}
+}
+
+// admit registers a payload, or returns the status and reason to refuse it
+func (b *Backpressure) admit() (int, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.now().Before(b.openUntil) {
+		return http.StatusServiceUnavailable, backpressureFailures
+	}
+	if b.config.PoolThreshold > 0 {
+		if stats, ok := b.pool(); ok && stats.MaxOpenConnections > 0 &&
+			float64(stats.InUse)/float64(stats.MaxOpenConnections) >= b.config.PoolThreshold {
+			return http.StatusServiceUnavailable, backpressurePool
+		}
+	}
+	if b.config.MaxInFlight > 0 && b.inFlight >= b.config.MaxInFlight {
+		return http.StatusTooManyRequests, backpressureInFlight
+	}
+	b.inFlight++
+	return 0, ""
+}
+
+// done releases a payload and counts consecutive storage failures
+func (b *Backpressure) done(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.config.FailureThreshold > 0 && b.failures >= b.config.FailureThreshold {
+		b.openUntil = b.now().Add(b.config.RetryAfter)
+		b.failures = 0
+	}
+}
+
+// backpressureWriter adds Retry-After to the handler's own 503s
+type backpressureWriter struct {
+	http.ResponseWriter
+	retryAfter string
+	statusCode int
+}
+
+func (w *backpressureWriter) WriteHeader(code int) {
+	w.statusCode = code
+	if code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+		w.Header().Set("Retry-After", w.retryAfter)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Middleware refuses payloads with Retry-After while the policy says the
+// service is overloaded
+func (b *Backpressure) Middleware(next http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(b.config.RetryAfter / time.Second))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, reason := b.admit(); status != 0 {
+			reputation.RecordWebhookBackpressure(reason)
+			w.Header().Set("Retry-After", retryAfter)
+			if status == http.StatusTooManyRequests {
+				writeUserError(w, status, "too_many_requests", "Too many webhook payloads in flight, retry later")
+			} else {
+				writeUserError(w, status, "backpressure", "Event storage is degraded, retry later")
+			}
+			return
+		}
+
+		bw := &backpressureWriter{ResponseWriter: w, retryAfter: retryAfter, statusCode: http.StatusOK}
+		defer func() { b.done(bw.statusCode == http.StatusServiceUnavailable) }()
+		next.ServeHTTP(bw, r)
+	})
+}
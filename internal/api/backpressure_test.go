@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBackpressure(config BackpressureConfig, stats sql.DBStats) *Backpressure {
+	b := NewBackpressure(config)
+	b.pool = func() (sql.DBStats, bool) { return stats, true }
+	return b
+}
+
+func TestBackpressureInFlightLimit(t *testing.T) {
+	b := newTestBackpressure(BackpressureConfig{MaxInFlight: 1, RetryAfter: 5 * time.Second}, sql.DBStats{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+		close(done)
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "5" {
+		t.Errorf("Expected 429 with Retry-After 5 at the limit, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	<-done
+	if status, _ := b.admit(); status != 0 {
+		t.Errorf("Expected a payload admitted once the first finished, got %d", status)
+	}
+}
+
+func TestBackpressurePoolThreshold(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	config := BackpressureConfig{PoolThreshold: 0.9}
+
+	rec := httptest.NewRecorder()
+	newTestBackpressure(config, sql.DBStats{MaxOpenConnections: 10, InUse: 9}).Middleware(handler).ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected 503 with the default Retry-After at 90%% pool use, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rec = httptest.NewRecorder()
+	newTestBackpressure(config, sql.DBStats{MaxOpenConnections: 10, InUse: 5}).Middleware(handler).ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 below the threshold, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	newTestBackpressure(config, sql.DBStats{InUse: 50}).Middleware(handler).ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected an unlimited pool never to trip, got %d", rec.Code)
+	}
+}
+
+func TestBackpressureStorageFailures(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBackpressure(BackpressureConfig{FailureThreshold: 2, RetryAfter: time.Minute}, sql.DBStats{})
+	b.now = func() time.Time { return now }
+
+	failing := true
+	handler := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+		return rec
+	}
+
+	if rec := serve(); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Expected the handler's 503 to carry Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	serve()
+
+	failing = false
+	if rec := serve(); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "backpressure") {
+		t.Fatalf("Expected payloads refused after consecutive failures, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	now = now.Add(time.Minute)
+	if rec := serve(); rec.Code != http.StatusOK {
+		t.Errorf("Expected storage retried after Retry-After, got %d", rec.Code)
+	}
+}
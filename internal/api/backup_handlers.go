@@ -0,0 +1,163 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRestoreBytes bounds an uploaded archive, compressed and expanded
+const maxRestoreBytes = 256 << 20
+
+// RestoreResponse counts the rows restored per table
+type RestoreResponse struct {
+	Restored   map[string]int `json:"restored"`
+	BackupAt   time.Time      `json:"backup_at"`
+	RestoredAt time.Time      `json:"restored_at"`
+}
+
+// backupStore exports and restores the reputation state
+type backupStore interface {
+	ExportBackup() (*database.Backup, error)
+	RestoreBackup(backup *database.Backup) (map[string]int, error)
+}
+
+type dbBackupStore struct{}
+
+func (dbBackupStore) ExportBackup() (*database.Backup, error) {
+	return database.ExportBackup()
+}
+
+func (dbBackupStore) RestoreBackup(backup *database.Backup) (map[string]int, error) {
+	return database.RestoreBackup(backup)
+}
+
+// BackupHandler exports the reputation state as an archive and restores it
+// into a fresh deployment
+type BackupHandler struct {
+	store backupStore
+	// reload applies the restored config and feature flags to this instance
+	reload func() error
+	logger *logrus.Logger
+}
+
+// NewBackupHandler creates a backup handler that reloads the aggregator's
+// config and the flags after a restore
+func NewBackupHandler(aggregator *reputation.AggregationService, flags *features.Set, logger *logrus.Logger) *BackupHandler {
+	reload := func() error {
+		config, _, err := reputation.LoadActiveConfig()
+		if err != nil {
+			return err
+		}
+		shadow, _, err := reputation.LoadShadowConfig()
+		if err != nil {
+			return err
+		}
+		aggregator.SetConfig(config)
+		aggregator.SetShadowConfig(shadow)
+		return flags.Refresh()
+	}
+	return &BackupHandler{store: dbBackupStore{}, reload: reload, logger: logger}
+}
+
+// @Summary Export reputation state
+// @Description A consistent snapshot of the reputation metrics, config revisions, feature flag overrides and their history, reserved IPs, protected blocks and archived IPs, as a gzipped JSON archive for disaster recovery or cloning an environment
+// @Tags system
+// @Produce application/gzip
+// @Success 200 {file} file "Backup archive (database.Backup, gzipped)"
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/backup [get]
+func (h *BackupHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	backup, err := h.store.ExportBackup()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to export backup")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to export backup")
+		return
+	}
+
+	counts := make(map[string]int, len(backup.Tables))
+	for table, rows := range backup.Tables {
+		counts[table] = len(rows)
+	}
+	h.logger.WithFields(logrus.Fields{
+		"action": "backup_exported",
+		"tables": counts,
+		"author": principalName(r),
+	}).Info("Reputation state exported")
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="reputation-state-%s.json.gz"`, backup.CreatedAt.Format("20060102T150405Z")))
+	gz := gzip.NewWriter(w)
+	json.NewEncoder(gz).Encode(backup)
+	gz.Close()
+}
+
+// @Summary Restore reputation state
+// @Description Restore an archive from GET /api/system/backup, gzipped or plain JSON, into a deployment without reputation state. Rows keep their IDs; the restored config and flags apply to this instance immediately and to others on their next reload. Responds 409 when any of the tables already has rows.
+// @Tags system
+// @Accept application/gzip
+// @Produce json
+// @Param archive body database.Backup true "Backup archive"
+// @Success 200 {object} RestoreResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/restore [post]
+func (h *BackupHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	var archive io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_archive", "Invalid gzip archive")
+			return
+		}
+		defer gz.Close()
+		archive = io.LimitReader(gz, maxRestoreBytes)
+	}
+
+	var backup database.Backup
+	if err := json.NewDecoder(archive).Decode(&backup); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_archive", "Invalid backup archive")
+		return
+	}
+	if err := backup.Validate(); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_archive", err.Error())
+		return
+	}
+
+	restored, err := h.store.RestoreBackup(&backup)
+	if errors.Is(err, database.ErrRestoreNotEmpty) {
+		writeUserError(w, http.StatusConflict, "not_empty", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to restore backup")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to restore backup")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":    "backup_restored",
+		"tables":    restored,
+		"backup_at": backup.CreatedAt,
+		"author":    principalName(r),
+	}).Warn("Reputation state restored")
+	if err := h.reload(); err != nil {
+		h.logger.WithError(err).Error("Failed to reload restored config; it applies after a restart")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RestoreResponse{Restored: restored, BackupAt: backup.CreatedAt, RestoredAt: time.Now()})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryBackupStore struct {
+	tables map[string][]json.RawMessage
+}
+
+func (m *memoryBackupStore) ExportBackup() (*database.Backup, error) {
+	return &database.Backup{Format: database.BackupFormat, Version: database.BackupVersion, CreatedAt: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), Tables: m.tables}, nil
+}
+
+func (m *memoryBackupStore) RestoreBackup(backup *database.Backup) (map[string]int, error) {
+	for _, rows := range m.tables {
+		if len(rows) > 0 {
+			return nil, fmt.Errorf("%w: table is not empty", database.ErrRestoreNotEmpty)
+		}
+	}
+	restored := make(map[string]int)
+	for table, rows := range backup.Tables {
+		restored[table] = len(rows)
+	}
+	m.tables = backup.Tables
+	return restored, nil
+}
+
+func newTestBackupRouter(store *memoryBackupStore, reloads *int) *mux.Router {
+	logger, _ := test.NewNullLogger()
+	handler := &BackupHandler{store: store, reload: func() error { *reloads++; return nil }, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/system/backup", handler.HandleExport).Methods("GET")
+	router.HandleFunc("/api/system/restore", handler.HandleRestore).Methods("POST")
+	return router
+}
+
+func TestBackupExportAndRestore(t *testing.T) {
+	source := &memoryBackupStore{tables: map[string][]json.RawMessage{
+		"ip_reputation_metrics": {json.RawMessage(`{"id":1,"ip":"192.0.2.1","status":"warning"}`)},
+		"feature_flags":         {json.RawMessage(`{"name":"auto_rotation","enabled":true}`)},
+	}}
+	var reloads int
+	rec := serveConfig(newTestBackupRouter(source, &reloads), "GET", "/api/system/backup", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("Unexpected export %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="reputation-state-20261014T120000Z.json.gz"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	archive := rec.Body.Bytes()
+
+	target := &memoryBackupStore{}
+	router := newTestBackupRouter(target, &reloads)
+	rec = serveConfig(router, "POST", "/api/system/restore", string(archive))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RestoreResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Restored["ip_reputation_metrics"] != 1 || resp.Restored["feature_flags"] != 1 || reloads != 1 {
+		t.Errorf("Unexpected restore: %+v after %d reloads", resp, reloads)
+	}
+	if string(target.tables["ip_reputation_metrics"][0]) != `{"id":1,"ip":"192.0.2.1","status":"warning"}` {
+		t.Errorf("Unexpected restored rows: %s", target.tables["ip_reputation_metrics"])
+	}
+
+	// Restoring over existing state is refused
+	if rec := serveConfig(router, "POST", "/api/system/restore", string(archive)); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 restoring twice, got %d", rec.Code)
+	}
+	if reloads != 1 {
+		t.Errorf("Expected no reload after a refused restore, got %d", reloads)
+	}
+}
+
+func TestRestoreRejectsInvalidArchives(t *testing.T) {
+	var reloads int
+	router := newTestBackupRouter(&memoryBackupStore{}, &reloads)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(`{"format":"ip-reputation-state","version":2,"tables":{}}`))
+	w.Close()
+
+	for name, body := range map[string]string{
+		"not json":          `not json`,
+		"other format":      `{"format":"pg_dump","version":1}`,
+		"newer version":     gz.String(),
+		"unknown table":     `{"format":"ip-reputation-state","version":1,"tables":{"users":[{"id":1}]}}`,
+		"row not an object": `{"format":"ip-reputation-state","version":1,"tables":{"feature_flags":[[1]]}}`,
+		"truncated gzip":    gz.String()[:4],
+	} {
+		if rec := serveConfig(router, "POST", "/api/system/restore", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	// Plain JSON archives are accepted too
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/system/restore", bytes.NewReader([]byte(`{"format":"ip-reputation-state","version":1,"tables":{"feature_flags":[]}}`))))
+	if rec.Code != http.StatusOK || reloads != 1 {
+		t.Errorf("Expected a plain JSON archive to restore, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
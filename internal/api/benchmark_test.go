@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var benchmarkEvents = map[string]string{
+	webhookSchemaV1: `{"id":"e1","createdAt":"2026-03-02T10:15:00Z","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","ip":"192.0.2.10","smtp_code":550,"enhanced_code":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attempt_number":2,"instance":"mta-1","message_id":"q-123"}}`,
+	webhookSchemaV2: `{"id":"e1","createdAt":"2026-03-02T10:15:00Z","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","remoteIp":"192.0.2.10","smtpCode":550,"enhancedCode":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attemptNumber":2,"instance":"mta-1","queueId":"q-123"}}`,
+}
+
+func BenchmarkDecodeWebhookEvent(b *testing.B) {
+	for _, version := range []string{webhookSchemaV1, webhookSchemaV2} {
+		raw := json.RawMessage(benchmarkEvents[version])
+		b.Run("v"+version, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				decodeWebhookEvent(raw, "")
+			}
+		})
+	}
+}
+
+// BenchmarkIngestEvents measures a 100-event payload from decoding to the
+// store, with an in-memory store
+func BenchmarkIngestEvents(b *testing.B) {
+	events := make([]string, 100)
+	for i := range events {
+		events[i] = strings.Replace(benchmarkEvents[webhookSchemaV1], `"e1"`, fmt.Sprintf(`"e%d"`, i), 1)
+	}
+	var payload rawWebhookPayload
+	if err := json.Unmarshal([]byte(`{"events":[`+strings.Join(events, ",")+`]}`), &payload); err != nil {
+		b.Fatal(err)
+	}
+
+	logger, _ := test.NewNullLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		handler := &ReputationHandler{store: &memoryIngestStore{}, logger: logger}
+		handler.ingestEvents(payload, nil, "benchmark")
+	}
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/jobs"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Bulk actions, and the job types and IP actions they are recorded as
+const (
+	bulkActionQuarantine = "quarantine"
+	bulkActionRestore    = "restore"
+
+	jobTypeBulkQuarantine = "bulk_quarantine"
+	jobTypeBulkRestore    = "bulk_restore"
+)
+
+// maxBulkTargets bounds the IPs one bulk action changes; a larger selection
+// has to be narrowed or split
+const maxBulkTargets = 4096
+
+// bulkJobTimeout allows bulk actions over thousands of IPs to finish
+const bulkJobTimeout = 30 * time.Minute
+
+// BulkActionRequest selects the IPs of a bulk quarantine or restore. At
+// least one of cidr, pool and status is required.
+type BulkActionRequest struct {
+	database.BulkActionFilter
+	Reason string `json:"reason,omitempty" example:"Block listed by Spamhaus"`
+	// DryRun previews the IPs the action would change without changing them
+	DryRun bool `json:"dry_run,omitempty"`
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// BulkStatusChange is an IP's status change by a bulk action
+type BulkStatusChange struct {
+	IP   string `json:"ip"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// BulkSkippedIP is a matched IP a bulk action leaves alone
+type BulkSkippedIP struct {
+	IP     string `json:"ip"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// BulkFailedIP is an IP a bulk action failed to change
+type BulkFailedIP struct {
+	IP    string `json:"ip"`
+	Error string `json:"error"`
+}
+
+// BulkActionReport is the preview of a bulk action on a dry run, and the
+// result of its job otherwise
+type BulkActionReport struct {
+	Action  string                    `json:"action" example:"quarantine"`
+	Filter  database.BulkActionFilter `json:"filter"`
+	DryRun  bool                      `json:"dry_run"`
+	Matched int                       `json:"matched"`
+	// Changes are the status changes made, or on a dry run those that would be
+	Changes []BulkStatusChange `json:"changes"`
+	Skipped []BulkSkippedIP    `json:"skipped"`
+	Failed  []BulkFailedIP     `json:"failed,omitempty"`
+}
+
+// bulkActionStore reads and changes the statuses of the selected IPs
+type bulkActionStore interface {
+	ListBulkActionTargets(filter database.BulkActionFilter) ([]database.BulkActionTarget, error)
+	GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error)
+	UpsertIPReputationMetrics(metrics *database.IPReputationMetrics) error
+	InsertIPAction(action *database.IPAction) error
+}
+
+type dbBulkActionStore struct{}
+
+func (dbBulkActionStore) ListBulkActionTargets(filter database.BulkActionFilter) ([]database.BulkActionTarget, error) {
+	return database.ListBulkActionTargets(filter)
+}
+
+func (dbBulkActionStore) GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error) {
+	return database.GetIPReputationMetrics(ip)
+}
+
+func (dbBulkActionStore) UpsertIPReputationMetrics(metrics *database.IPReputationMetrics) error {
+	return database.UpsertIPReputationMetrics(metrics)
+}
+
+func (dbBulkActionStore) InsertIPAction(action *database.IPAction) error {
+	return database.InsertIPAction(action)
+}
+
+// BulkActionHandler quarantines and restores every IP matching a filter, as
+// a background job
+type BulkActionHandler struct {
+	store   bulkActionStore
+	machine *state.Machine
+	jobs    *JobHandler
+	logger  *logrus.Logger
+}
+
+// NewBulkActionHandler creates a bulk action handler starting its jobs with
+// jobHandler
+func NewBulkActionHandler(jobHandler *JobHandler, logger *logrus.Logger) *BulkActionHandler {
+	return &BulkActionHandler{store: dbBulkActionStore{}, machine: state.Default, jobs: jobHandler, logger: logger}
+}
+
+// bulkTarget returns the status action moves an IP in status to, or why
+// the IP is skipped
+func bulkTarget(action string, status state.Status) (state.Status, string) {
+	switch action {
+	case bulkActionQuarantine:
+		if status.Severity() >= state.Quarantine.Severity() {
+			return status, "already quarantined or blacklisted"
+		}
+		return state.Quarantine, ""
+	default:
+		if status != state.Quarantine {
+			return status, "not quarantined"
+		}
+		return state.Healthy, ""
+	}
+}
+
+// planBulkAction splits the targets into the changes action makes and the IPs it skips
+func planBulkAction(action string, targets []database.BulkActionTarget) ([]BulkStatusChange, []BulkSkippedIP) {
+	changes, skipped := []BulkStatusChange{}, []BulkSkippedIP{}
+	for _, target := range targets {
+		to, reason := bulkTarget(action, state.Status(target.Status))
+		if reason != "" {
+			skipped = append(skipped, BulkSkippedIP{IP: target.IP, Status: target.Status, Reason: reason})
+			continue
+		}
+		changes = append(changes, BulkStatusChange{IP: target.IP, From: target.Status, To: to.String()})
+	}
+	return changes, skipped
+}
+
+// @Summary Quarantine IPs in bulk
+// @Description Quarantine every IP with reputation metrics matching the filter: a CIDR block, a pool and a status, all matching when several are given. Archived IPs and IPs already quarantined or blacklisted are skipped. With dry_run the BulkActionReport of what would change is returned at once; otherwise the IPs are quarantined in the background and GET /api/jobs/{id} reports the progress and the BulkActionReport. Every change is recorded in the IP's action history as bulk_quarantine.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body BulkActionRequest true "IPs to quarantine"
+// @Success 200 {object} BulkActionReport "Dry run"
+// @Success 202 {object} database.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/ips/bulk/quarantine [post]
+func (h *BulkActionHandler) HandleBulkQuarantine(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, bulkActionQuarantine, jobTypeBulkQuarantine)
+}
+
+// @Summary Restore quarantined IPs in bulk
+// @Description Move every quarantined IP matching the filter back to healthy, for the next aggregation to reassess. The filter, dry run and job work as for POST /api/ips/bulk/quarantine; IPs not in quarantine are skipped. Every change is recorded in the IP's action history as bulk_restore.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body BulkActionRequest true "IPs to restore"
+// @Success 200 {object} BulkActionReport "Dry run"
+// @Success 202 {object} database.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/ips/bulk/restore [post]
+func (h *BulkActionHandler) HandleBulkRestore(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, bulkActionRestore, jobTypeBulkRestore)
+}
+
+func (h *BulkActionHandler) handle(w http.ResponseWriter, r *http.Request, action, jobType string) {
+	var req BulkActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	filter, ok := bulkFilter(w, req.BulkActionFilter)
+	if !ok {
+		return
+	}
+	reason, ok := archiveReason(w, req.Reason)
+	if !ok {
+		return
+	}
+
+	targets, err := h.store.ListBulkActionTargets(filter)
+	if errors.Is(err, database.ErrIPPoolNotFound) {
+		writeUserError(w, http.StatusBadRequest, "unknown_pool", "No pool is named "+filter.Pool)
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_bulk_action_targets_failed",
+			"error":  err.Error(),
+		}).Error("Failed to list bulk action targets")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list the matching IPs")
+		return
+	}
+
+	changes, skipped := planBulkAction(action, targets)
+	report := BulkActionReport{Action: action, Filter: filter, DryRun: req.DryRun, Matched: len(targets), Changes: changes, Skipped: skipped}
+	if req.DryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	if len(changes) > maxBulkTargets {
+		writeUserError(w, http.StatusBadRequest, "too_many_ips", fmt.Sprintf("The filter would change %d IPs, at most %d can be changed at once", len(changes), maxBulkTargets))
+		return
+	}
+
+	author := principalName(r)
+	if reason == "" {
+		reason = fmt.Sprintf("Bulk %s via API", action)
+	}
+	h.jobs.start(w, r, jobs.Options{
+		Type:        jobType,
+		CallbackURL: req.CallbackURL,
+		Timeout:     bulkJobTimeout,
+	}, func(ctx context.Context) (interface{}, error) {
+		return h.run(ctx, report, reason, author)
+	})
+}
+
+// bulkFilter checks a filter and normalizes its CIDR block
+func bulkFilter(w http.ResponseWriter, filter database.BulkActionFilter) (database.BulkActionFilter, bool) {
+	filter.CIDR = strings.TrimSpace(filter.CIDR)
+	filter.Pool = strings.TrimSpace(filter.Pool)
+	filter.Status = strings.TrimSpace(filter.Status)
+	if filter.CIDR == "" && filter.Pool == "" && filter.Status == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_filter", "At least one of cidr, pool and status is required")
+		return filter, false
+	}
+	if filter.CIDR != "" {
+		_, block, err := net.ParseCIDR(filter.CIDR)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_cidr", "cidr must be a CIDR block such as 192.0.2.0/24")
+			return filter, false
+		}
+		filter.CIDR = block.String()
+	}
+	if filter.Status != "" {
+		if _, err := state.Parse(filter.Status); err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_status", "status must be healthy, warning, quarantine or blacklisted")
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+// run applies the planned changes of report one IP at a time. Each IP's
+// status is read again, so IPs whose status changed since the plan are
+// skipped or changed from their new status.
+func (h *BulkActionHandler) run(ctx context.Context, report BulkActionReport, reason, author string) (*BulkActionReport, error) {
+	planned := report.Changes
+	report.Changes = []BulkStatusChange{}
+	jobID := jobs.ID(ctx)
+
+	for i, change := range planned {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		jobs.ReportProgress(ctx, i, len(planned))
+
+		metrics, err := h.store.GetIPReputationMetrics(change.IP)
+		if err != nil {
+			report.Failed = append(report.Failed, BulkFailedIP{IP: change.IP, Error: err.Error()})
+			continue
+		}
+		from := state.Status(metrics.Status)
+		to, skip := bulkTarget(report.Action, from)
+		if skip != "" {
+			report.Skipped = append(report.Skipped, BulkSkippedIP{IP: change.IP, Status: metrics.Status, Reason: skip})
+			continue
+		}
+
+		transition := state.Transition{IP: change.IP, From: from, To: to, TriggeredBy: author}
+		if err := h.machine.Apply(transition, func() error {
+			metrics.Status = to.String()
+			return h.store.UpsertIPReputationMetrics(metrics)
+		}); err != nil {
+			report.Failed = append(report.Failed, BulkFailedIP{IP: change.IP, Error: err.Error()})
+			continue
+		}
+		reputation.RecordStatusChange(change.IP, from.String(), to.String())
+		report.Changes = append(report.Changes, BulkStatusChange{IP: change.IP, From: from.String(), To: to.String()})
+
+		action := &database.IPAction{
+			IP:             change.IP,
+			Action:         "bulk_" + report.Action,
+			PreviousStatus: from.String(),
+			NewStatus:      to.String(),
+			Reason:         reason,
+			TriggeredBy:    author,
+			Metadata: map[string]interface{}{
+				"job_id": jobID,
+				"filter": report.Filter,
+			},
+			CreatedAt: time.Now(),
+		}
+		if err := h.store.InsertIPAction(action); err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "record_bulk_action_failed",
+				"ip":     change.IP,
+				"error":  err.Error(),
+			}).Warn("Failed to record bulk action")
+		}
+	}
+	jobs.ReportProgress(ctx, len(planned), len(planned))
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "bulk_" + report.Action,
+		"job_id":  jobID,
+		"cidr":    report.Filter.CIDR,
+		"pool":    report.Filter.Pool,
+		"status":  report.Filter.Status,
+		"changed": len(report.Changes),
+		"skipped": len(report.Skipped),
+		"failed":  len(report.Failed),
+		"author":  author,
+	}).Warn("Bulk action finished")
+	return &report, nil
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/jobs"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryBulkActionStore struct {
+	mu      sync.Mutex
+	metrics map[string]*database.IPReputationMetrics
+	pools   map[string][]string
+	actions []database.IPAction
+}
+
+func (s *memoryBulkActionStore) ListBulkActionTargets(filter database.BulkActionFilter) ([]database.BulkActionTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members, ok := s.pools[filter.Pool]
+	if filter.Pool != "" && !ok {
+		return nil, database.ErrIPPoolNotFound
+	}
+	targets := []database.BulkActionTarget{}
+	for ip, metrics := range s.metrics {
+		if filter.CIDR != "" {
+			_, block, _ := net.ParseCIDR(filter.CIDR)
+			if !block.Contains(net.ParseIP(ip)) {
+				continue
+			}
+		}
+		if filter.Pool != "" && !containsString(members, ip) {
+			continue
+		}
+		if filter.Status != "" && metrics.Status != filter.Status {
+			continue
+		}
+		targets = append(targets, database.BulkActionTarget{IP: ip, Status: metrics.Status})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].IP < targets[j].IP })
+	return targets, nil
+}
+
+func (s *memoryBulkActionStore) GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics := *s.metrics[ip]
+	return &metrics, nil
+}
+
+func (s *memoryBulkActionStore) UpsertIPReputationMetrics(metrics *database.IPReputationMetrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *metrics
+	s.metrics[metrics.IP] = &stored
+	return nil
+}
+
+func (s *memoryBulkActionStore) InsertIPAction(action *database.IPAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, *action)
+	return nil
+}
+
+func newTestBulkActionRouter() (*mux.Router, *memoryBulkActionStore, *jobs.Runner) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryBulkActionStore{
+		metrics: map[string]*database.IPReputationMetrics{
+			"192.0.2.1":    {IP: "192.0.2.1", Status: "healthy"},
+			"192.0.2.2":    {IP: "192.0.2.2", Status: "warning"},
+			"192.0.2.3":    {IP: "192.0.2.3", Status: "quarantine"},
+			"192.0.2.4":    {IP: "192.0.2.4", Status: "blacklisted"},
+			"198.51.100.1": {IP: "198.51.100.1", Status: "warning"},
+		},
+		pools: map[string][]string{"transactional": {"192.0.2.2", "198.51.100.1"}},
+	}
+	runner := jobs.NewRunner(&memoryJobStore{jobs: map[string]database.Job{}}, jobs.Config{}, logger)
+	handler := &BulkActionHandler{store: store, machine: state.NewMachine(), jobs: NewJobHandler(runner, nil, nil, logger), logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/bulk/quarantine", handler.HandleBulkQuarantine).Methods("POST")
+	router.HandleFunc("/api/ips/bulk/restore", handler.HandleBulkRestore).Methods("POST")
+	router.HandleFunc("/api/jobs/{id}", handler.jobs.HandleGetJob).Methods("GET")
+	return router, store, runner
+}
+
+func postBulkAction(t *testing.T, router *mux.Router, action, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/ips/bulk/"+action, strings.NewReader(body)))
+	return rec
+}
+
+func TestBulkQuarantinePreview(t *testing.T) {
+	router, store, _ := newTestBulkActionRouter()
+
+	rec := postBulkAction(t, router, "quarantine", `{"cidr":"192.0.2.1/24","dry_run":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report BulkActionReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Filter.CIDR != "192.0.2.0/24" || report.Matched != 4 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Changes) != 2 || report.Changes[0] != (BulkStatusChange{IP: "192.0.2.1", From: "healthy", To: "quarantine"}) {
+		t.Errorf("Expected the healthy and warning IPs to change, got %+v", report.Changes)
+	}
+	if len(report.Skipped) != 2 || report.Skipped[1].IP != "192.0.2.4" {
+		t.Errorf("Expected the quarantined and blacklisted IPs skipped, got %+v", report.Skipped)
+	}
+	if store.metrics["192.0.2.1"].Status != "healthy" || len(store.actions) != 0 {
+		t.Error("Expected a dry run to change nothing")
+	}
+
+	// Filters combine
+	rec = postBulkAction(t, router, "quarantine", `{"pool":"transactional","status":"warning","cidr":"198.51.100.0/24","dry_run":true}`)
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Matched != 1 || len(report.Changes) != 1 || report.Changes[0].IP != "198.51.100.1" {
+		t.Errorf("Expected only the pool's warning IP in the block, got %+v", report)
+	}
+}
+
+func TestBulkQuarantineAndRestore(t *testing.T) {
+	router, store, runner := newTestBulkActionRouter()
+
+	rec := postBulkAction(t, router, "quarantine", `{"pool":"transactional","reason":"Listed by Spamhaus"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var job database.Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Type != "bulk_quarantine" || rec.Header().Get("Location") != "/api/jobs/"+job.ID {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	if err := runner.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil))
+	json.NewDecoder(rec.Body).Decode(&job)
+	var report BulkActionReport
+	if job.Status != database.JobSucceeded || json.Unmarshal(job.Result, &report) != nil {
+		t.Fatalf("Expected the job to succeed, got %+v", job)
+	}
+	if len(report.Changes) != 2 || report.DryRun || job.Progress == nil || job.Progress.Done != 2 {
+		t.Errorf("Unexpected result %+v with progress %+v", report, job.Progress)
+	}
+	if store.metrics["192.0.2.2"].Status != "quarantine" || store.metrics["198.51.100.1"].Status != "quarantine" {
+		t.Error("Expected the pool quarantined")
+	}
+	if len(store.actions) != 2 {
+		t.Fatalf("Expected an action per IP, got %+v", store.actions)
+	}
+	action := store.actions[0]
+	if action.Action != "bulk_quarantine" || action.PreviousStatus != "warning" || action.Reason != "Listed by Spamhaus" || action.Metadata["job_id"] != job.ID {
+		t.Errorf("Unexpected action: %+v", action)
+	}
+
+	// Restoring the block brings back its quarantined IPs only
+	router, store, runner = newTestBulkActionRouter()
+	postBulkAction(t, router, "restore", `{"cidr":"192.0.2.0/24"}`)
+	runner.Close(context.Background())
+	if store.metrics["192.0.2.3"].Status != "healthy" || store.metrics["192.0.2.4"].Status != "blacklisted" {
+		t.Errorf("Expected only the quarantined IP restored, got %+v", store.metrics)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != "bulk_restore" || store.actions[0].Reason != "Bulk restore via API" {
+		t.Errorf("Unexpected actions: %+v", store.actions)
+	}
+}
+
+func TestBulkActionRechecksStatuses(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryBulkActionStore{metrics: map[string]*database.IPReputationMetrics{
+		"192.0.2.1": {IP: "192.0.2.1", Status: "blacklisted"},
+		"192.0.2.2": {IP: "192.0.2.2", Status: "healthy"},
+	}}
+	machine := state.NewMachine()
+	machine.AddGuard(func(t state.Transition) error {
+		if t.IP == "192.0.2.2" {
+			return errors.New("maintenance window")
+		}
+		return nil
+	})
+	handler := &BulkActionHandler{store: store, machine: machine, logger: logger}
+
+	// Both were healthy when planned; one has been blacklisted since
+	planned := BulkActionReport{Action: bulkActionQuarantine, Matched: 2, Changes: []BulkStatusChange{
+		{IP: "192.0.2.1", From: "healthy", To: "quarantine"},
+		{IP: "192.0.2.2", From: "healthy", To: "quarantine"},
+	}, Skipped: []BulkSkippedIP{}}
+	report, err := handler.run(context.Background(), planned, "test", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 0 || len(report.Skipped) != 1 || report.Skipped[0].Status != "blacklisted" {
+		t.Errorf("Expected the blacklisted IP skipped, got %+v", report)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].IP != "192.0.2.2" || store.metrics["192.0.2.2"].Status != "healthy" {
+		t.Errorf("Expected the guarded transition to fail, got %+v", report.Failed)
+	}
+	if len(store.actions) != 0 {
+		t.Errorf("Expected no action recorded, got %+v", store.actions)
+	}
+}
+
+func TestBulkActionValidation(t *testing.T) {
+	router, _, _ := newTestBulkActionRouter()
+
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{`, "invalid_request"},
+		{`{}`, "invalid_filter"},
+		{`{"cidr":"  "}`, "invalid_filter"},
+		{`{"cidr":"192.0.2.1"}`, "invalid_cidr"},
+		{`{"status":"suspended"}`, "invalid_status"},
+		{`{"pool":"marketing"}`, "unknown_pool"},
+		{`{"status":"warning","reason":"` + strings.Repeat("x", maxAnnotationLength+1) + `"}`, "invalid_reason"},
+		{`{"status":"warning","callback_url":"ftp://example.com"}`, "invalid_callback_url"},
+	}
+	for _, tt := range tests {
+		rec := postBulkAction(t, router, "quarantine", tt.body)
+		var resp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusBadRequest || resp.Error != tt.code {
+			t.Errorf("Expected 400 %s for %.40s, got %d %s", tt.code, tt.body, rec.Code, resp.Error)
+		}
+	}
+}
+
+func TestBulkActionRoutesBeforeIPRoutes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	// Answered by the bulk handler, not by the quarantine of an IP "bulk"
+	rec := postBulkAction(t, router, "quarantine", `{}`)
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusBadRequest || resp.Error != "invalid_filter" {
+		t.Errorf("Expected 400 invalid_filter, got %d %s", rec.Code, resp.Error)
+	}
+}
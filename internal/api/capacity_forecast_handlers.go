@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultForecastLookbackDays = 14
+	maxForecastLookbackDays     = 90
+	forecastHorizonDays         = 7
+)
+
+// CapacityForecastResponse projects healthy pool capacity and the reserve
+// pool from the recent trend
+type CapacityForecastResponse struct {
+	GeneratedAt  time.Time              `json:"generated_at"`
+	LookbackDays int                    `json:"lookback_days"`
+	HorizonDays  int                    `json:"horizon_days"`
+	Pools        []PoolCapacityForecast `json:"pools"`
+	Reserve      ReserveForecast        `json:"reserve"`
+}
+
+// PoolCapacityForecast is the healthy member count of a pool, observed and
+// projected for the next days
+type PoolCapacityForecast struct {
+	PoolID         int    `json:"pool_id"`
+	Pool           string `json:"pool"`
+	TotalIPs       int    `json:"total_ips"`
+	CurrentHealthy int    `json:"current_healthy"`
+	// TrendPerDay is the fitted change of healthy members per day
+	TrendPerDay float64                 `json:"trend_per_day"`
+	History     []CapacityForecastPoint `json:"history"`
+	Forecast    []CapacityForecastPoint `json:"forecast"`
+}
+
+// CapacityForecastPoint is the healthy member count of one UTC day
+type CapacityForecastPoint struct {
+	Date    time.Time `json:"date"`
+	Healthy float64   `json:"healthy"`
+}
+
+// ReserveForecast estimates when the clean reserved IPs run out if IPs keep
+// moving into quarantine or blacklisted at the current rate
+type ReserveForecast struct {
+	Available          int        `json:"available"`
+	BurnedIPs          int        `json:"burned_ips"`
+	BurnPerDay         float64    `json:"burn_per_day"`
+	DaysUntilExhausted *float64   `json:"days_until_exhausted,omitempty"`
+	ExhaustedAt        *time.Time `json:"exhausted_at,omitempty"`
+}
+
+// buildCapacityForecast fits a linear trend to the daily healthy counts of
+// each pool starting at from (a UTC midnight)
+func buildCapacityForecast(pools []database.IPPool, days []database.PoolHealthDay, from time.Time, lookbackDays, reserve, burned int, now time.Time) CapacityForecastResponse {
+	response := CapacityForecastResponse{
+		GeneratedAt:  now,
+		LookbackDays: lookbackDays,
+		HorizonDays:  forecastHorizonDays,
+		Pools:        []PoolCapacityForecast{},
+	}
+
+	byPool := make(map[int][]database.PoolHealthDay)
+	for _, d := range days {
+		byPool[d.PoolID] = append(byPool[d.PoolID], d)
+	}
+
+	lastDay := lookbackDays - 1
+	for _, pool := range pools {
+		forecast := PoolCapacityForecast{
+			PoolID:   pool.ID,
+			Pool:     pool.Name,
+			TotalIPs: len(pool.IPs),
+			History:  []CapacityForecastPoint{},
+			Forecast: []CapacityForecastPoint{},
+		}
+
+		points := []reputation.TrendPoint{}
+		for _, d := range byPool[pool.ID] {
+			day := int(d.Day.Sub(from) / (24 * time.Hour))
+			points = append(points, reputation.TrendPoint{Day: day, Value: float64(d.Healthy)})
+			forecast.History = append(forecast.History, CapacityForecastPoint{Date: d.Day, Healthy: float64(d.Healthy)})
+			forecast.CurrentHealthy = d.Healthy
+		}
+
+		if len(points) > 0 {
+			forecast.TrendPerDay, _ = reputation.LinearTrend(points)
+			projected := reputation.ForecastLinear(points, lastDay, forecastHorizonDays, float64(forecast.TotalIPs))
+			for i, healthy := range projected {
+				forecast.Forecast = append(forecast.Forecast, CapacityForecastPoint{
+					Date:    from.AddDate(0, 0, lastDay+i+1),
+					Healthy: healthy,
+				})
+			}
+		}
+		response.Pools = append(response.Pools, forecast)
+	}
+
+	response.Reserve = ReserveForecast{
+		Available:  reserve,
+		BurnedIPs:  burned,
+		BurnPerDay: float64(burned) / float64(lookbackDays),
+	}
+	if remaining, ok := reputation.DaysUntilExhausted(reserve, response.Reserve.BurnPerDay); ok {
+		exhaustedAt := now.Add(time.Duration(remaining * float64(24*time.Hour)))
+		response.Reserve.DaysUntilExhausted = &remaining
+		response.Reserve.ExhaustedAt = &exhaustedAt
+	}
+	return response
+}
+
+// @Summary Capacity forecast
+// @Description Projects the healthy IPs of each pool over the next 7 days from the daily trend, and when the clean reserved IPs run out at the current rate of IPs moving into quarantine or blacklisted
+// @Tags ip-reputation
+// @Produce json
+// @Param days query int false "Days of history to fit (1-90)" default(14)
+// @Success 200 {object} CapacityForecastResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dashboard/capacity-forecast [get]
+func (h *ReputationHandler) getCapacityForecastHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultForecastLookbackDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxForecastLookbackDays {
+			writeUserError(w, http.StatusBadRequest, "invalid_days", "days must be between 1 and "+strconv.Itoa(maxForecastLookbackDays))
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(days - 1))
+
+	fail := func(err error, message string) {
+		h.logger.WithFields(logrus.Fields{
+			"action": "capacity_forecast_failed",
+			"error":  err.Error(),
+		}).Error(message)
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to build capacity forecast")
+	}
+
+	pools, err := database.ListIPPools()
+	if err != nil {
+		fail(err, "Failed to list IP pools")
+		return
+	}
+	health, err := database.GetDailyPoolHealth(from)
+	if err != nil {
+		fail(err, "Failed to get daily pool health")
+		return
+	}
+	burned, err := database.CountIPsEnteringStatus([]string{state.Quarantine.String(), state.Blacklisted.String()}, from)
+	if err != nil {
+		fail(err, "Failed to count quarantined IPs")
+		return
+	}
+	reservedStatus, blacklisted := "reserved", false
+	reserve, err := database.ListReservedIPs(&reservedStatus, &blacklisted, nil)
+	if err != nil {
+		fail(err, "Failed to list reserved IPs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildCapacityForecast(pools, health, from, days, len(reserve), burned, now))
+}
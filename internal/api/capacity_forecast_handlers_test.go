@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBuildCapacityForecast(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	now := from.AddDate(0, 0, 3).Add(12 * time.Hour)
+	pools := []database.IPPool{
+		{ID: 1, Name: "transactional", IPs: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"}},
+		{ID: 2, Name: "new", IPs: []string{"192.0.2.9"}},
+	}
+	days := []database.PoolHealthDay{
+		{PoolID: 1, Day: from, Healthy: 4, Tracked: 4},
+		{PoolID: 1, Day: from.AddDate(0, 0, 1), Healthy: 3, Tracked: 4},
+		{PoolID: 1, Day: from.AddDate(0, 0, 3), Healthy: 1, Tracked: 4},
+	}
+
+	forecast := buildCapacityForecast(pools, days, from, 4, 6, 8, now)
+
+	pool := forecast.Pools[0]
+	if pool.CurrentHealthy != 1 || pool.TrendPerDay != -1 || len(pool.History) != 3 {
+		t.Errorf("Unexpected pool forecast: %+v", pool)
+	}
+	if len(pool.Forecast) != forecastHorizonDays || pool.Forecast[0].Healthy != 0 || !pool.Forecast[0].Date.Equal(from.AddDate(0, 0, 4)) {
+		t.Errorf("Unexpected projection: %+v", pool.Forecast)
+	}
+	if empty := forecast.Pools[1]; len(empty.Forecast) != 0 || empty.TotalIPs != 1 {
+		t.Errorf("Expected no projection for a pool without history, got %+v", empty)
+	}
+
+	reserve := forecast.Reserve
+	if reserve.BurnPerDay != 2 || reserve.DaysUntilExhausted == nil || *reserve.DaysUntilExhausted != 3 {
+		t.Fatalf("Unexpected reserve forecast: %+v", reserve)
+	}
+	if !reserve.ExhaustedAt.Equal(now.AddDate(0, 0, 3)) {
+		t.Errorf("ExhaustedAt = %v", reserve.ExhaustedAt)
+	}
+
+	if idle := buildCapacityForecast(nil, nil, from, 4, 6, 0, now).Reserve; idle.DaysUntilExhausted != nil {
+		t.Errorf("Expected no exhaustion without burn, got %+v", idle)
+	}
+}
+
+func TestCapacityForecastValidatesDays(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	for _, days := range []string{"0", "91", "x"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/dashboard/capacity-forecast?days="+days, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: status = %d, want 400", days, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CompressionConfig controls response compression
+type CompressionConfig struct {
+	Enabled bool
+	// MinSize is the smallest response body, in bytes, that is compressed.
+	// Smaller bodies are sent as is since the encoding overhead outweighs
+	// the savings.
+	MinSize int
+	// Level is the gzip/deflate level (1-9, or -1 for the library default)
+	Level int
+}
+
+// DefaultCompressionConfig compresses responses of 1 KiB and more
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		Enabled: true,
+		MinSize: 1024,
+		Level:   gzip.DefaultCompression,
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q-values. gzip wins ties. An empty result means identity.
+func negotiateEncoding(header string) string {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range []string{"gzip", "deflate"} {
+		q, ok := accepted[name]
+		if !ok {
+			// A wildcard only covers encodings that are not listed explicitly
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressionMiddleware compresses response bodies of at least MinSize bytes
+// with the encoding the client prefers
+func compressionMiddleware(config CompressionConfig) mux.MiddlewareFunc {
+	if config.Level < flate.HuffmanOnly || config.Level > flate.BestCompression {
+		config.Level = gzip.DefaultCompression
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !config.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, config: config, encoding: encoding, statusCode: http.StatusOK}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body reaches the size threshold, then either compresses or passes it through
+type compressWriter struct {
+	http.ResponseWriter
+	config     CompressionConfig
+	encoding   string
+	statusCode int
+	buf        []byte
+	encoder    io.WriteCloser
+	decided    bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		return
+	}
+	cw.statusCode = code
+	// Bodiless responses go out straight away
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < http.StatusOK {
+		cw.passthrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.config.MinSize {
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush commits to compression so streamed responses are not held back
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.start(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes any buffered body and finishes the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		cw.passthrough()
+		return nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+func (cw *compressWriter) passthrough() {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	if len(cw.buf) > 0 {
+		cw.ResponseWriter.Write(cw.buf)
+		cw.buf = nil
+	}
+}
+
+func (cw *compressWriter) start() error {
+	// Leave responses alone that a handler already encoded (e.g. promhttp)
+	if cw.Header().Get("Content-Encoding") != "" {
+		cw.passthrough()
+		return nil
+	}
+
+	cw.decided = true
+	h := cw.Header()
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	var err error
+	if cw.encoding == "gzip" {
+		cw.encoder, err = gzip.NewWriterLevel(cw.ResponseWriter, cw.config.Level)
+	} else {
+		cw.encoder, err = zlib.NewWriterLevel(cw.ResponseWriter, cw.config.Level)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = cw.encoder.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0", ""},
+		{"*", "gzip"},
+		{"gzip;q=0, *", "deflate"},
+		{"GZIP;q=0.8", "gzip"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.expected {
+			t.Errorf("negotiateEncoding(%q) = %q, expected %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func serveCompressed(t *testing.T, acceptEncoding string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	config := DefaultCompressionConfig()
+	config.MinSize = 100
+	handler := compressionMiddleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/dashboard/ip-health", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"ip":"192.0.2.1"},`, 50)
+
+	rec := serveCompressed(t, "gzip", large)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status to be preserved, got %d", rec.Code)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	if decoded, _ := io.ReadAll(zr); string(decoded) != large {
+		t.Error("Decompressed body does not match")
+	}
+
+	rec = serveCompressed(t, "deflate", large)
+	if rec.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("Expected deflate encoding, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	fr, err := zlib.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("Invalid deflate body: %v", err)
+	}
+	if decoded, _ := io.ReadAll(fr); string(decoded) != large {
+		t.Error("Decompressed body does not match")
+	}
+}
+
+func TestCompressionSkipsSmallAndUnacceptedResponses(t *testing.T) {
+	large := strings.Repeat("x", 500)
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"below threshold": serveCompressed(t, "gzip", `{"status":"ok"}`),
+		"not accepted":    serveCompressed(t, "", large),
+	} {
+		if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+			t.Errorf("%s: expected no encoding, got %q", name, enc)
+		}
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("%s: expected status to be preserved, got %d", name, rec.Code)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: expected Vary header", name)
+		}
+	}
+}
+
+func TestCompressionLeavesEncodedResponsesAlone(t *testing.T) {
+	handler := compressionMiddleware(CompressionConfig{Enabled: true, MinSize: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, "already encoded")
+	}))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "br" || rec.Body.String() != "already encoded" {
+		t.Errorf("Expected response to pass through, got %q %q", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestComputeETagIsStable(t *testing.T) {
+	a, err := computeETag(map[string]int{"b": 2, "a": 1})
+	if err != nil {
+		t.Fatalf("computeETag failed: %v", err)
+	}
+	b, _ := computeETag(map[string]int{"a": 1, "b": 2})
+	c, _ := computeETag(map[string]int{"a": 1, "b": 3})
+
+	if a != b {
+		t.Errorf("Expected same ETag for equal values, got %s and %s", a, b)
+	}
+	if a == c {
+		t.Error("Expected different ETags for different values")
+	}
+}
+
+func TestWriteNotModified(t *testing.T) {
+	etag := `W/"abc"`
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		expected bool
+	}{
+		{"no conditional headers", "GET", nil, false},
+		{"matching etag", "GET", map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"strong form of weak etag", "GET", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag in list", "GET", map[string]string{"If-None-Match": `"old", W/"abc"`}, true},
+		{"wildcard", "GET", map[string]string{"If-None-Match": "*"}, true},
+		{"stale etag", "GET", map[string]string{"If-None-Match": `W/"old"`}, false},
+		{"stale etag wins over fresh date", "GET", map[string]string{
+			"If-None-Match":     `W/"old"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, false},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": modified.Add(-time.Minute).Format(http.TimeFormat)}, false},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"not a GET", "POST", map[string]string{"If-None-Match": `W/"abc"`}, false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/dashboard/ip-health", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+
+		got := writeNotModified(rec, req, etag, modified)
+		if got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+		if got && rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304, got %d", tt.name, rec.Code)
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("%s: expected ETag header to be set", tt.name)
+		}
+		if rec.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+			t.Errorf("%s: unexpected Last-Modified %q", tt.name, rec.Header().Get("Last-Modified"))
+		}
+	}
+}
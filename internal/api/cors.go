@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSConfig controls which browser origins may call the API
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as https://dashboard.example.com.
+	// "*" allows any origin and "https://*.example.com" any subdomain.
+	// An empty list disables cross-origin access.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders lists request headers a browser may send; "*" allows
+	// whatever the preflight asks for
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers browser scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers
+	// from the listed origins, which are echoed as the spec requires. It
+	// cannot be combined with "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig returns the CORS defaults for an environment. Development
+// allows any origin; every other environment, including an unset one, allows
+// none until origins are configured.
+func DefaultCORSConfig(environment string) CORSConfig {
+	config := CORSConfig{
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key"},
+		ExposedHeaders: []string{"ETag", "Last-Modified"},
+		MaxAge:         time.Hour,
+	}
+	switch strings.ToLower(environment) {
+	case "development", "dev", "local":
+		config.AllowedOrigins = []string{"*"}
+		config.MaxAge = 10 * time.Minute
+	}
+	return config
+}
+
+// ErrCORSCredentialsAnyOrigin is returned by Validate for a config that would
+// let any website make credentialed requests
+var ErrCORSCredentialsAnyOrigin = errors.New(`CORS_ALLOW_CREDENTIALS cannot be combined with CORS_ALLOWED_ORIGINS "*": list the origins allowed to send credentials`)
+
+// Validate refuses "*" together with AllowCredentials
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && c.allowAnyOrigin() {
+		return ErrCORSCredentialsAnyOrigin
+	}
+	return nil
+}
+
+// ParseCORSList splits a comma separated config value, dropping empty entries
+func ParseCORSList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// allowOrigin reports whether origin matches the allow-list
+func (c CORSConfig) allowOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) &&
+			len(origin) > len(prefix)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CORSConfig) allowAnyOrigin() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// setOriginHeaders writes the headers shared by preflight and actual
+// responses. It returns false when the origin is not allowed.
+func (c CORSConfig) setOriginHeaders(w http.ResponseWriter, origin string) bool {
+	if !c.allowOrigin(origin) {
+		return false
+	}
+
+	h := w.Header()
+	if c.allowAnyOrigin() {
+		// Never with credentials, whatever the config: any website could
+		// then act as the signed-in user
+		h.Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Add("Vary", "Origin")
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// preflight answers an OPTIONS request
+func (c CORSConfig) preflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin != "" && r.Header.Get("Access-Control-Request-Method") != "" && c.setOriginHeaders(w, origin) {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+
+		allowedHeaders := strings.Join(c.AllowedHeaders, ", ")
+		if allowedHeaders == "*" {
+			allowedHeaders = r.Header.Get("Access-Control-Request-Headers")
+		}
+		if allowedHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if c.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// corsMiddleware adds CORS headers for allowed origins and answers preflight
+// requests
+func corsMiddleware(config CORSConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				config.preflight(w, r)
+				return
+			}
+
+			if origin := r.Header.Get("Origin"); origin != "" && config.setOriginHeaders(w, origin) && len(config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// methodNotAllowedHandler answers preflight requests for routes registered
+// without OPTIONS, which mux would otherwise reject before any middleware
+// runs
+func methodNotAllowedHandler(config CORSConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			config.preflight(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+}
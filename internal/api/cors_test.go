@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func corsRouter(t *testing.T, cors CORSConfig) http.Handler {
+	t.Helper()
+	log, _ := test.NewNullLogger()
+	log.SetLevel(logrus.PanicLevel)
+	return SetupRoutesWithDependencies(Dependencies{Logger: log, CORS: &cors})
+}
+
+func TestDefaultCORSConfigPerEnvironment(t *testing.T) {
+	if dev := DefaultCORSConfig("development"); len(dev.AllowedOrigins) != 1 || dev.AllowedOrigins[0] != "*" {
+		t.Errorf("Expected development to allow any origin, got %v", dev.AllowedOrigins)
+	}
+	for _, env := range []string{"production", ""} {
+		if config := DefaultCORSConfig(env); len(config.AllowedOrigins) != 0 {
+			t.Errorf("Expected %q to allow no origins by default, got %v", env, config.AllowedOrigins)
+		}
+	}
+}
+
+func TestCORSValidateRefusesCredentialsWithAnyOrigin(t *testing.T) {
+	cors := DefaultCORSConfig("development")
+	if err := cors.Validate(); err != nil {
+		t.Errorf("Expected any origin without credentials accepted, got %v", err)
+	}
+	cors.AllowCredentials = true
+	if err := cors.Validate(); !errors.Is(err, ErrCORSCredentialsAnyOrigin) {
+		t.Errorf("Expected any origin with credentials refused, got %v", err)
+	}
+
+	router := corsRouter(t, cors)
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected any origin served without credentials, got %q %q", got, rec.Header().Get("Access-Control-Allow-Credentials"))
+	}
+}
+
+func TestCORSPreflightOnGetOnlyRoute(t *testing.T) {
+	cors := DefaultCORSConfig("production")
+	cors.AllowedOrigins = []string{"https://dashboard.example.com"}
+	cors.MaxAge = 30 * time.Minute
+	router := corsRouter(t, cors)
+
+	req := httptest.NewRequest("OPTIONS", "/health", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dashboard.example.com" {
+		t.Errorf("Expected origin to be echoed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "1800" {
+		t.Errorf("Expected max age 1800, got %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Error("Expected allowed methods")
+	}
+}
+
+func TestCORSRejectsUnlistedOrigin(t *testing.T) {
+	cors := DefaultCORSConfig("production")
+	cors.AllowedOrigins = []string{"https://*.example.com"}
+	router := corsRouter(t, cors)
+
+	for origin, allowed := range map[string]bool{
+		"https://ops.example.com": true,
+		"https://example.com":     false,
+		"https://evil.com":        false,
+		"http://ops.example.com":  false,
+	} {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		got := rec.Header().Get("Access-Control-Allow-Origin")
+		if allowed && got != origin {
+			t.Errorf("%s: expected origin to be allowed, got %q", origin, got)
+		}
+		if !allowed && got != "" {
+			t.Errorf("%s: expected no CORS headers, got %q", origin, got)
+		}
+	}
+}
+
+func TestCORSCredentialsEchoOrigin(t *testing.T) {
+	cors := DefaultCORSConfig("development")
+	cors.AllowedOrigins = []string{"http://localhost:3000"}
+	cors.AllowCredentials = true
+	router := corsRouter(t, cors)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("Expected origin instead of * with credentials, got %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Expected credentials header")
+	}
+	if rec.Header().Get("Access-Control-Expose-Headers") != "ETag, Last-Modified" {
+		t.Errorf("Expected exposed headers, got %q", rec.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
+
+func TestMethodNotAllowedStillRejected(t *testing.T) {
+	router := corsRouter(t, DefaultCORSConfig("development"))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PATCH", "/health", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/itsm"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// delistingProviderPattern is a provider name: a mailbox provider such as
+// outlook, a blocklist such as spamhaus or a recipient domain such as
+// comcast.net
+var delistingProviderPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{0,63}$`)
+
+// OpenDelistingTaskRequest opens a delisting task with a provider
+type OpenDelistingTaskRequest struct {
+	Provider string `json:"provider" example:"outlook"`
+	// Window is how far back rejections are searched for block references
+	Window  string `json:"window,omitempty" example:"168h"`
+	Comment string `json:"comment" example:"Submitted via olcsupport.office.com"`
+}
+
+// UpdateDelistingTaskRequest moves a delisting task to a new status
+type UpdateDelistingTaskRequest struct {
+	Status  string `json:"status" example:"submitted"`
+	Comment string `json:"comment" example:"Microsoft ticket SRX1234567890"`
+}
+
+// DelistingTasksResponse lists delisting tasks, newest first
+type DelistingTasksResponse struct {
+	Total int                      `json:"total"`
+	Tasks []database.DelistingTask `json:"tasks"`
+}
+
+// delistingStore persists delisting tasks and reads the failures their
+// block references come from
+type delistingStore interface {
+	OpenDelistingTask(ip, provider string, refs []string, comment, author string) (*database.DelistingTask, error)
+	GetDelistingTask(id int) (*database.DelistingTask, error)
+	ListDelistingTasks(ip, status string) ([]database.DelistingTask, error)
+	UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error)
+	GetSMTPFailures(ctx context.Context, ip string, since time.Time) ([]database.SMTPFailure, error)
+}
+
+type dbDelistingStore struct{}
+
+func (dbDelistingStore) OpenDelistingTask(ip, provider string, refs []string, comment, author string) (*database.DelistingTask, error) {
+	return database.OpenDelistingTask(ip, provider, refs, comment, author)
+}
+
+func (dbDelistingStore) GetDelistingTask(id int) (*database.DelistingTask, error) {
+	return database.GetDelistingTask(id)
+}
+
+func (dbDelistingStore) ListDelistingTasks(ip, status string) ([]database.DelistingTask, error) {
+	return database.ListDelistingTasks(ip, status)
+}
+
+func (dbDelistingStore) UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error) {
+	return database.UpdateDelistingTaskStatus(id, status, comment, author)
+}
+
+func (dbDelistingStore) GetSMTPFailures(ctx context.Context, ip string, since time.Time) ([]database.SMTPFailure, error) {
+	return database.GetSMTPFailuresByIPAndInstanceContext(ctx, ip, "", since)
+}
+
+// DelistingTaskHandler tracks the delisting and mitigation requests opened
+// with providers, each linked to the block references the provider quoted
+type DelistingTaskHandler struct {
+	store delistingStore
+	// tickets opens and updates the ITSM tickets of tasks; nil disables them
+	tickets *itsm.Service
+	logger  *logrus.Logger
+}
+
+// NewDelistingTaskHandler creates a new delisting task handler
+func NewDelistingTaskHandler(tickets *itsm.Service, logger *logrus.Logger) *DelistingTaskHandler {
+	return &DelistingTaskHandler{store: dbDelistingStore{}, tickets: tickets, logger: logger}
+}
+
+// blockReferencesOf returns the block references quoted by the failures from
+// a provider, most often quoted first
+func blockReferencesOf(failures []database.SMTPFailure, provider string) []string {
+	counts := map[string]int{}
+	for _, f := range failures {
+		if reputation.ProviderOf(f) != provider {
+			continue
+		}
+		for _, ref := range f.BlockRefs() {
+			counts[ref]++
+		}
+	}
+	return topKeys(counts, len(counts))
+}
+
+// delistingProvider resolves a requested provider name to the name
+// reputation.ProviderOf gives its failures
+func delistingProvider(raw string) (string, bool) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if alias, ok := mitigationProviderAliases[name]; ok {
+		name = alias
+	}
+	if provider, ok := mitigationProviders[name]; ok {
+		return provider.name, true
+	}
+	return name, delistingProviderPattern.MatchString(name)
+}
+
+// delistingComment trims an optional comment and checks its length
+func delistingComment(w http.ResponseWriter, comment string) (string, bool) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "invalid_comment", "comment must be at most "+strconv.Itoa(maxAnnotationLength)+" characters")
+		return "", false
+	}
+	return comment, true
+}
+
+// @Summary Open a delisting task
+// @Description Record a delisting or mitigation request opened with a provider for an IP. The block references (e.g. Outlook's S3150) the IP's rejections by the provider quoted within the window are linked to the task, and the task is recorded in the IP's actions. One task per IP and provider can be pending at a time.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param request body OpenDelistingTaskRequest true "Provider"
+// @Success 201 {object} database.DelistingTask
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/delisting-tasks [post]
+func (h *DelistingTaskHandler) HandleOpen(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+	var req OpenDelistingTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	provider, ok := delistingProvider(req.Provider)
+	if !ok {
+		writeUserError(w, http.StatusBadRequest, "invalid_provider", "provider must be one of "+mitigationProviderNames()+", a blocklist or a recipient domain")
+		return
+	}
+	window := defaultMitigationWindow
+	if req.Window != "" {
+		window, err = time.ParseDuration(req.Window)
+		if err != nil || window <= 0 || window > maxMitigationWindow {
+			writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.; at most 720h)")
+			return
+		}
+	}
+	comment, ok := delistingComment(w, req.Comment)
+	if !ok {
+		return
+	}
+
+	failures, err := h.store.GetSMTPFailures(r.Context(), ip, time.Now().Add(-window))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "open_delisting_task",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get SMTP failures")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve SMTP failures")
+		return
+	}
+	refs := blockReferencesOf(failures, provider)
+
+	author := principalName(r)
+	task, err := h.store.OpenDelistingTask(ip, provider, refs, comment, author)
+	if errors.Is(err, database.ErrDelistingTaskPending) {
+		writeUserError(w, http.StatusConflict, "task_pending", "A delisting task with "+provider+" is already pending for this IP")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "open_delisting_task",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to open delisting task")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to open delisting task")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":           "delisting_task_opened",
+		"task_id":          task.ID,
+		"ip":               ip,
+		"provider":         provider,
+		"block_references": refs,
+		"author":           author,
+	}).Info("Delisting task opened")
+	if h.tickets != nil {
+		h.tickets.DelistingOpened(*task)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(task)
+}
+
+// listTasks writes the tasks of an IP, or of all IPs when ip is empty
+func (h *DelistingTaskHandler) listTasks(w http.ResponseWriter, r *http.Request, ip string) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", database.DelistingOpen, database.DelistingSubmitted, database.DelistingResolved:
+	default:
+		writeUserError(w, http.StatusBadRequest, "invalid_status", "status must be open, submitted or resolved")
+		return
+	}
+
+	tasks, err := h.store.ListDelistingTasks(ip, status)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_delisting_tasks",
+			"error":  err.Error(),
+		}).Error("Failed to list delisting tasks")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list delisting tasks")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DelistingTasksResponse{Total: len(tasks), Tasks: tasks})
+}
+
+// @Summary List an IP's delisting tasks
+// @Description Delisting and mitigation requests opened for an IP with their block references, newest first
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param status query string false "Only tasks with this status (open, submitted, resolved)"
+// @Success 200 {object} DelistingTasksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/delisting-tasks [get]
+func (h *DelistingTaskHandler) HandleListIPTasks(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+	h.listTasks(w, r, ip)
+}
+
+// @Summary List delisting tasks
+// @Description Delisting and mitigation requests of every IP, newest first, e.g. the pending ones with status=open
+// @Tags ip-reputation
+// @Produce json
+// @Param status query string false "Only tasks with this status (open, submitted, resolved)"
+// @Success 200 {object} DelistingTasksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/delisting-tasks [get]
+func (h *DelistingTaskHandler) HandleListTasks(w http.ResponseWriter, r *http.Request) {
+	h.listTasks(w, r, "")
+}
+
+// delistingTaskID reads the id path variable, writing 400 when it is not a
+// number
+func delistingTaskID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Invalid task ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// @Summary Get a delisting task
+// @Tags ip-reputation
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} database.DelistingTask
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/delisting-tasks/{id} [get]
+func (h *DelistingTaskHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
+	id, ok := delistingTaskID(w, r)
+	if !ok {
+		return
+	}
+	task, err := h.store.GetDelistingTask(id)
+	if errors.Is(err, database.ErrDelistingTaskNotFound) {
+		writeUserError(w, http.StatusNotFound, "not_found", "Delisting task not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "get_delisting_task",
+			"task_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to get delisting task")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to get delisting task")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// @Summary Update a delisting task
+// @Description Move a task to submitted once the request is sent to the provider, or to resolved once the IP is delisted; the change is recorded in the IP's actions. Resolved tasks cannot be changed.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param request body UpdateDelistingTaskRequest true "New status"
+// @Success 200 {object} database.DelistingTask
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/delisting-tasks/{id} [patch]
+func (h *DelistingTaskHandler) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, ok := delistingTaskID(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateDelistingTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	switch req.Status {
+	case database.DelistingOpen, database.DelistingSubmitted, database.DelistingResolved:
+	default:
+		writeUserError(w, http.StatusBadRequest, "invalid_status", "status must be open, submitted or resolved")
+		return
+	}
+	comment, ok := delistingComment(w, req.Comment)
+	if !ok {
+		return
+	}
+
+	// Setting the current status changes nothing, so its tickets get no comment
+	previous := ""
+	if h.tickets != nil {
+		if current, err := h.store.GetDelistingTask(id); err == nil {
+			previous = current.Status
+		}
+	}
+
+	author := principalName(r)
+	task, err := h.store.UpdateDelistingTaskStatus(id, req.Status, comment, author)
+	switch {
+	case errors.Is(err, database.ErrDelistingTaskNotFound):
+		writeUserError(w, http.StatusNotFound, "not_found", "Delisting task not found")
+		return
+	case errors.Is(err, database.ErrDelistingTaskResolved):
+		writeUserError(w, http.StatusConflict, "task_resolved", "Resolved delisting tasks cannot be changed; open a new one")
+		return
+	case err != nil:
+		h.logger.WithFields(logrus.Fields{
+			"action":  "update_delisting_task",
+			"task_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to update delisting task")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to update delisting task")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "delisting_task_updated",
+		"task_id": task.ID,
+		"ip":      task.IP,
+		"status":  task.Status,
+		"author":  author,
+	}).Info("Delisting task updated")
+	if h.tickets != nil && task.Status != previous {
+		h.tickets.DelistingUpdated(*task, comment)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryDelistingStore is an in-memory delistingStore over a fixed list of
+// failures
+type memoryDelistingStore struct {
+	failures []database.SMTPFailure
+	tasks    []*database.DelistingTask
+}
+
+func (s *memoryDelistingStore) OpenDelistingTask(ip, provider string, refs []string, comment, author string) (*database.DelistingTask, error) {
+	for _, t := range s.tasks {
+		if t.IP == ip && t.Provider == provider && t.Status != database.DelistingResolved {
+			return nil, database.ErrDelistingTaskPending
+		}
+	}
+	task := &database.DelistingTask{ID: len(s.tasks) + 1, IP: ip, Provider: provider, Status: database.DelistingOpen,
+		BlockReferences: refs, Comment: comment, OpenedBy: author, OpenedAt: time.Now(), UpdatedAt: time.Now()}
+	s.tasks = append(s.tasks, task)
+	return task, nil
+}
+
+func (s *memoryDelistingStore) GetDelistingTask(id int) (*database.DelistingTask, error) {
+	if id < 1 || id > len(s.tasks) {
+		return nil, database.ErrDelistingTaskNotFound
+	}
+	return s.tasks[id-1], nil
+}
+
+func (s *memoryDelistingStore) ListDelistingTasks(ip, status string) ([]database.DelistingTask, error) {
+	tasks := []database.DelistingTask{}
+	for i := len(s.tasks) - 1; i >= 0; i-- {
+		if t := s.tasks[i]; (ip == "" || t.IP == ip) && (status == "" || t.Status == status) {
+			tasks = append(tasks, *t)
+		}
+	}
+	return tasks, nil
+}
+
+func (s *memoryDelistingStore) UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error) {
+	task, err := s.GetDelistingTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status == status {
+		return task, nil
+	}
+	if task.Status == database.DelistingResolved {
+		return nil, database.ErrDelistingTaskResolved
+	}
+	task.Status = status
+	return task, nil
+}
+
+func (s *memoryDelistingStore) GetSMTPFailures(ctx context.Context, ip string, since time.Time) ([]database.SMTPFailure, error) {
+	var failures []database.SMTPFailure
+	for _, f := range s.failures {
+		if f.SendingIP == ip && !f.Timestamp.Before(since) {
+			failures = append(failures, f)
+		}
+	}
+	return failures, nil
+}
+
+func newTestDelistingRouter(store *memoryDelistingStore) *mux.Router {
+	logger, _ := test.NewNullLogger()
+	handler := &DelistingTaskHandler{store: store, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/{ip}/delisting-tasks", handler.HandleOpen).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/delisting-tasks", handler.HandleListIPTasks).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks", handler.HandleListTasks).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks/{id}", handler.HandleGetTask).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks/{id}", handler.HandleUpdateTask).Methods("PATCH")
+	return router
+}
+
+func TestOpenDelistingTaskLinksBlockReferences(t *testing.T) {
+	now := time.Now()
+	outlook := func(reason string, age time.Duration) database.SMTPFailure {
+		return database.SMTPFailure{SendingIP: "192.0.2.1", RecipientDomain: "hotmail.com", SMTPCode: 550, Reason: reason, Timestamp: now.Add(-age)}
+	}
+	store := &memoryDelistingStore{failures: []database.SMTPFailure{
+		outlook("550 5.7.1 Unfortunately, messages from [192.0.2.1] weren't sent. (S3150)", time.Hour),
+		outlook("550 5.7.1 Unfortunately, messages from [192.0.2.1] weren't sent. (S3150)", 2*time.Hour),
+		outlook("550 5.7.606 Access denied, banned sending IP [192.0.2.1]. (AS3140)", 3*time.Hour),
+		// Outside the window
+		outlook("550 5.7.1 Unfortunately, messages from [192.0.2.1] weren't sent. (S3140)", 10*24*time.Hour),
+		// Another provider's reference
+		{SendingIP: "192.0.2.1", RecipientDomain: "yahoo.com", SMTPCode: 421, Reason: "421 4.7.0 [TSS04] Messages temporarily deferred", Timestamp: now},
+	}}
+	router := newTestDelistingRouter(store)
+
+	rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"Microsoft","comment":" Submitted via olcsupport "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var task database.DelistingTask
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Provider != "outlook" || task.Comment != "Submitted via olcsupport" || task.OpenedBy != "anonymous" {
+		t.Errorf("Unexpected task: %+v", task)
+	}
+	if len(task.BlockReferences) != 2 || task.BlockReferences[0] != "S3150" || task.BlockReferences[1] != "AS3140" {
+		t.Errorf("Expected the window's Outlook references, most quoted first, got %v", task.BlockReferences)
+	}
+
+	if rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"outlook"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a second pending task, got %d", rec.Code)
+	}
+
+	rec = serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"spamhaus"}`)
+	task = database.DelistingTask{}
+	json.NewDecoder(rec.Body).Decode(&task)
+	if rec.Code != http.StatusCreated || len(task.BlockReferences) != 0 {
+		t.Errorf("Expected a blocklist task without references, got %d %+v", rec.Code, task)
+	}
+
+	for _, body := range []string{`{"provider":""}`, `{"provider":"bad provider!"}`, `{"provider":"gmail","window":"9999h"}`, `not json`} {
+		if rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestUpdateDelistingTask(t *testing.T) {
+	store := &memoryDelistingStore{}
+	router := newTestDelistingRouter(store)
+	serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"yahoo"}`)
+
+	rec := serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"submitted","comment":"Yahoo case 123"}`)
+	if rec.Code != http.StatusOK || store.tasks[0].Status != database.DelistingSubmitted {
+		t.Fatalf("Expected the task submitted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"resolved"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 resolving, got %d", rec.Code)
+	}
+	if rec := serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"open"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 reopening a resolved task, got %d", rec.Code)
+	}
+	if rec := serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"done"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown status, got %d", rec.Code)
+	}
+	if rec := serveConfig(router, "PATCH", "/api/delisting-tasks/7", `{"status":"resolved"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	// Resolved tasks no longer block a new one
+	if rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"aol"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected 201 after resolving, got %d", rec.Code)
+	}
+	rec = serveConfig(router, "GET", "/api/delisting-tasks?status=open", "")
+	var list DelistingTasksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || list.Total != 1 || list.Tasks[0].ID != 2 {
+		t.Errorf("Expected the new task pending, got %+v %v", list, err)
+	}
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/delisting-tasks", "")
+	list = DelistingTasksResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || list.Total != 2 {
+		t.Errorf("Expected both tasks of the IP, got %+v %v", list, err)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultDrainRetryAfter is the Retry-After sent while draining, long enough
+// for a restarted or replacement instance to come up
+const defaultDrainRetryAfter = 30 * time.Second
+
+// Drainer lets webhook ingestion finish cleanly on shutdown. Once draining,
+// new requests get 503 with Retry-After so Stalwart queues and retries them
+// instead of losing them to a connection reset, while requests already in
+// flight run to completion.
+type Drainer struct {
+	retryAfter time.Duration
+
+	mu       sync.Mutex
+	draining bool
+	inFlight sync.WaitGroup
+}
+
+// NewDrainer creates a drainer; retryAfter <= 0 uses 30 seconds
+func NewDrainer(retryAfter time.Duration) *Drainer {
+	if retryAfter <= 0 {
+		retryAfter = defaultDrainRetryAfter
+	}
+	return &Drainer{retryAfter: retryAfter}
+}
+
+// Draining reports whether a drain has started
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// enter registers an in-flight request unless the drainer is draining
+func (d *Drainer) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight.Add(1)
+	return true
+}
+
+// Middleware tracks requests and rejects new ones while draining
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.enter() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(d.retryAfter/time.Second)))
+			writeUserError(w, http.StatusServiceUnavailable, "draining", "Service is shutting down, retry later")
+			return
+		}
+		defer d.inFlight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Drain stops accepting requests and waits for the in-flight ones, or
+// returns ctx's error when its deadline passes first
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
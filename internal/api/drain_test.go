@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestDrainerRejectsNewRequests(t *testing.T) {
+	drainer := NewDrainer(10 * time.Second)
+	handler := drainer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 before draining, got %d", rec.Code)
+	}
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while draining, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Expected Retry-After 10, got %q", got)
+	}
+}
+
+func TestDrainerWaitsForInFlightRequests(t *testing.T) {
+	drainer := NewDrainer(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := drainer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rec := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+		close(served)
+	}()
+	<-started
+
+	drained := make(chan error, 1)
+	go func() { drained <- drainer.Drain(context.Background()) }()
+
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v with a request in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	<-served
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected the in-flight request to complete with 202, got %d", rec.Code)
+	}
+}
+
+func TestDrainerStopsAtDeadline(t *testing.T) {
+	drainer := NewDrainer(0)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := drainer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := drainer.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestHealthReportsDraining(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	drainer := NewDrainer(0)
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Drainer: drainer})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 before draining, got %d", rec.Code)
+	}
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	var resp HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || resp.Status != "draining" {
+		t.Errorf("Expected 503 draining, got %d %+v", rec.Code, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected webhooks to be rejected while draining, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/faults"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// FaultsResponse lists the active injected faults
+type FaultsResponse struct {
+	Enabled bool           `json:"enabled"`
+	Targets []string       `json:"targets"`
+	Faults  []faults.Fault `json:"faults"`
+}
+
+// SetFaultRequest injects latency and errors into calls to a dependency
+type SetFaultRequest struct {
+	LatencyMS int     `json:"latency_ms" example:"2000"`
+	ErrorRate float64 `json:"error_rate" example:"0.5"`
+	// StatusCode is the response of failed HTTP calls; 500 by default
+	StatusCode int `json:"status_code,omitempty" example:"503"`
+	// Duration is how long the fault lasts, e.g. "15m"; 10m by default and
+	// at most 1h
+	Duration string `json:"duration,omitempty" example:"15m"`
+}
+
+// FaultHandler sets and clears injected dependency failures
+type FaultHandler struct {
+	faults *faults.Injector
+	logger *logrus.Logger
+}
+
+// NewFaultHandler creates a fault injection handler
+func NewFaultHandler(injector *faults.Injector, logger *logrus.Logger) *FaultHandler {
+	return &FaultHandler{faults: injector, logger: logger}
+}
+
+// @Summary List injected faults
+// @Description Whether failure injection is enabled (chaos.enabled, never in production), the targets it covers and the active faults
+// @Tags system
+// @Produce json
+// @Success 200 {object} FaultsResponse
+// @Router /api/system/faults [get]
+func (h *FaultHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FaultsResponse{Enabled: h.faults.Enabled(), Targets: faults.Targets(), Faults: h.faults.List()})
+}
+
+// @Summary Inject a fault
+// @Description Delay every call to a dependency (postgres, ionos or dns) by latency_ms and fail error_rate of them until the fault expires. Failed IONOS calls get status_code, DNS lookups time out and database calls return an error. Replaces the target's previous fault.
+// @Tags system
+// @Accept json
+// @Produce json
+// @Param target path string true "Target: postgres, ionos or dns"
+// @Param fault body SetFaultRequest true "Fault"
+// @Success 200 {object} faults.Fault
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/system/faults/{target} [put]
+func (h *FaultHandler) HandleSet(w http.ResponseWriter, r *http.Request) {
+	if !h.faults.Enabled() {
+		writeUserError(w, http.StatusForbidden, "faults_disabled", "Failure injection is disabled; set CHAOS_ENABLED outside production")
+		return
+	}
+
+	var req SetFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_duration", "duration must be a duration such as 15m")
+			return
+		}
+	}
+
+	fault, err := h.faults.Set(faults.Fault{
+		Target:     mux.Vars(r)["target"],
+		LatencyMS:  req.LatencyMS,
+		ErrorRate:  req.ErrorRate,
+		StatusCode: req.StatusCode,
+		CreatedBy:  principalName(r),
+	}, duration)
+	switch {
+	case errors.Is(err, faults.ErrUnknownTarget):
+		writeUserError(w, http.StatusNotFound, "not_found", "Unknown fault target")
+		return
+	case errors.Is(err, faults.ErrDisabled):
+		writeUserError(w, http.StatusForbidden, "faults_disabled", "Failure injection is disabled; set CHAOS_ENABLED outside production")
+		return
+	case err != nil:
+		writeUserError(w, http.StatusBadRequest, "invalid_fault", err.Error())
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":      "fault_injected",
+		"target":      fault.Target,
+		"latency_ms":  fault.LatencyMS,
+		"error_rate":  fault.ErrorRate,
+		"status_code": fault.StatusCode,
+		"expires_at":  fault.ExpiresAt,
+		"author":      fault.CreatedBy,
+	}).Warn("Fault injected")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fault)
+}
+
+// @Summary Clear an injected fault
+// @Description Stop injecting failures into calls to a target
+// @Tags system
+// @Param target path string true "Target: postgres, ionos or dns"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Router /api/system/faults/{target} [delete]
+func (h *FaultHandler) HandleClear(w http.ResponseWriter, r *http.Request) {
+	target := mux.Vars(r)["target"]
+	if !h.faults.Clear(target) {
+		writeUserError(w, http.StatusNotFound, "not_found", "No active fault for this target")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "fault_cleared",
+		"target": target,
+		"author": principalName(r),
+	}).Info("Fault cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Clear all injected faults
+// @Description Stop injecting failures into every target
+// @Tags system
+// @Success 204
+// @Router /api/system/faults [delete]
+func (h *FaultHandler) HandleClearAll(w http.ResponseWriter, r *http.Request) {
+	h.faults.ClearAll()
+	h.logger.WithFields(logrus.Fields{
+		"action": "faults_cleared",
+		"author": principalName(r),
+	}).Info("All faults cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
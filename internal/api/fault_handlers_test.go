@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"golang-backend-service/internal/faults"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestFaultEndpoints(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	injector := faults.NewInjector()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Faults: injector})
+
+	if rec := serveConfig(router, "PUT", "/api/system/faults/ionos", `{"error_rate":1}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 while injection is disabled, got %d", rec.Code)
+	}
+
+	injector.SetEnabled(true)
+	rec := serveConfig(router, "PUT", "/api/system/faults/ionos", `{"error_rate":0.5,"status_code":503,"duration":"15m"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var fault faults.Fault
+	if err := json.NewDecoder(rec.Body).Decode(&fault); err != nil {
+		t.Fatal(err)
+	}
+	if fault.StatusCode != 503 || fault.CreatedBy != "anonymous" || fault.ExpiresAt.Sub(fault.CreatedAt).Minutes() != 15 {
+		t.Errorf("Unexpected fault: %+v", fault)
+	}
+	audited := false
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["action"] == "fault_injected" && entry.Data["target"] == faults.IONOS {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Error("Expected a log entry for the injected fault")
+	}
+
+	rec = serveConfig(router, "GET", "/api/system/faults", "")
+	var list FaultsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if !list.Enabled || len(list.Targets) != 3 || len(list.Faults) != 1 || list.Faults[0].Target != faults.IONOS {
+		t.Errorf("Unexpected faults: %+v", list)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"PUT", "/api/system/faults/redis", `{"error_rate":1}`, http.StatusNotFound},
+		{"PUT", "/api/system/faults/dns", `{"error_rate":2}`, http.StatusBadRequest},
+		{"PUT", "/api/system/faults/dns", `{"error_rate":1,"duration":"soon"}`, http.StatusBadRequest},
+		{"PUT", "/api/system/faults/postgres", `not json`, http.StatusBadRequest},
+		{"DELETE", "/api/system/faults/dns", "", http.StatusNotFound},
+		{"DELETE", "/api/system/faults/ionos", "", http.StatusNoContent},
+		{"PUT", "/api/system/faults/postgres", `{"latency_ms":250}`, http.StatusOK},
+		{"DELETE", "/api/system/faults", "", http.StatusNoContent},
+	} {
+		rec := serveConfig(router, tc.method, tc.path, tc.body)
+		if rec.Code != tc.code {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, rec.Code)
+		}
+	}
+	if faults := injector.List(); len(faults) != 0 {
+		t.Errorf("Expected every fault cleared, got %+v", faults)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// FeatureFlagsResponse lists every feature flag
+type FeatureFlagsResponse struct {
+	Flags []features.Flag `json:"flags"`
+}
+
+// ToggleFeatureFlagRequest turns a flag on or off
+type ToggleFeatureFlagRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Comment string `json:"comment"`
+}
+
+// ToggleFeatureFlagResponse is the flag after the toggle and its audit record
+type ToggleFeatureFlagResponse struct {
+	Flag   features.Flag              `json:"flag"`
+	Change database.FeatureFlagChange `json:"change"`
+}
+
+// FeatureHandler lists and toggles feature flags at runtime
+type FeatureHandler struct {
+	flags  *features.Set
+	logger *logrus.Logger
+}
+
+// NewFeatureHandler creates a feature flag handler
+func NewFeatureHandler(flags *features.Set, logger *logrus.Logger) *FeatureHandler {
+	return &FeatureHandler{flags: flags, logger: logger}
+}
+
+// @Summary List feature flags
+// @Description Every flag with its effective value and source: the built-in default, the config, or a runtime toggle stored in the database
+// @Tags features
+// @Produce json
+// @Success 200 {object} FeatureFlagsResponse
+// @Router /api/system/features [get]
+func (h *FeatureHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FeatureFlagsResponse{Flags: h.flags.List()})
+}
+
+// @Summary Toggle a feature flag
+// @Description Turn a flag on or off at runtime. The toggle overrides the config default on every instance and is recorded with its author.
+// @Tags features
+// @Accept json
+// @Produce json
+// @Param name path string true "Flag name"
+// @Param toggle body ToggleFeatureFlagRequest true "New value"
+// @Success 200 {object} ToggleFeatureFlagResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/features/{name} [put]
+func (h *FeatureHandler) HandleToggle(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req ToggleFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "enabled is required")
+		return
+	}
+
+	flag, change, err := h.flags.Toggle(name, *req.Enabled, principalName(r), req.Comment)
+	if errors.Is(err, features.ErrUnknownFlag) {
+		writeUserError(w, http.StatusNotFound, "not_found", "Feature flag not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "toggle_feature_flag",
+			"flag":   name,
+			"error":  err.Error(),
+		}).Error("Failed to toggle feature flag")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to toggle feature flag")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "feature_flag_changed",
+		"flag":    name,
+		"from":    change.Previous,
+		"to":      change.Enabled,
+		"author":  change.Author,
+		"comment": change.Comment,
+	}).Info("Feature flag changed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToggleFeatureFlagResponse{Flag: flag, Change: *change})
+}
+
+// @Summary Feature flag history
+// @Description Audited toggles of a flag, newest first
+// @Tags features
+// @Produce json
+// @Param name path string true "Flag name"
+// @Param limit query int false "Number of changes (1-500)" default(50)
+// @Success 200 {array} database.FeatureFlagChange
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/features/{name}/history [get]
+func (h *FeatureHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > 500 {
+			writeUserError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	changes, err := h.flags.History(mux.Vars(r)["name"], limit)
+	if errors.Is(err, features.ErrUnknownFlag) {
+		writeUserError(w, http.StatusNotFound, "not_found", "Feature flag not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list feature flag changes")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list feature flag history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
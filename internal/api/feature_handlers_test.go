@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/features"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestFeatureFlagEndpoints(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	flags := features.New(nil)
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Features: flags})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/system/features", nil))
+	var list FeatureFlagsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Flags) != 4 || list.Flags[0].Name != features.AutoRotation {
+		t.Fatalf("Unexpected flags: %+v", list.Flags)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/system/features/strict_webhook_auth", strings.NewReader(`{"enabled":true,"comment":"rollout"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var toggled ToggleFeatureFlagResponse
+	if err := json.NewDecoder(rec.Body).Decode(&toggled); err != nil {
+		t.Fatal(err)
+	}
+	if !toggled.Flag.Enabled || toggled.Change.Previous || toggled.Change.Author != "anonymous" {
+		t.Errorf("Unexpected toggle response: %+v", toggled)
+	}
+	audited := false
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["action"] == "feature_flag_changed" && entry.Data["flag"] == features.StrictWebhookAuth && entry.Data["to"] == true {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Error("Expected an audit log entry for the toggle")
+	}
+
+	// strict_webhook_auth now rejects webhooks without a source token
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 once strict_webhook_auth is on, got %d", rec.Code)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"PUT", "/api/system/features/unknown", `{"enabled":true}`, http.StatusNotFound},
+		{"PUT", "/api/system/features/auto_rotation", `{"comment":"missing value"}`, http.StatusBadRequest},
+		{"GET", "/api/system/features/unknown/history", "", http.StatusNotFound},
+		{"GET", "/api/system/features/auto_rotation/history?limit=0", "", http.StatusBadRequest},
+		{"GET", "/api/system/features/auto_rotation/history", "", http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if rec.Code != tc.code {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxComparePeriodDays bounds each compared period
+	maxComparePeriodDays = 90
+	// compareTopCodes is how many of each period's most frequent codes are
+	// compared
+	compareTopCodes = 10
+)
+
+// ComparePeriod is one of the compared time ranges, from inclusive
+type ComparePeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// RatioDelta is a ratio in period A and B and how it changed; a side is null
+// when the period has no aggregation run
+type RatioDelta struct {
+	A     *float64 `json:"a"`
+	B     *float64 `json:"b"`
+	Delta *float64 `json:"delta"`
+}
+
+// CountDelta is a count in period A and B and how it changed
+type CountDelta struct {
+	A     int `json:"a"`
+	B     int `json:"b"`
+	Delta int `json:"delta"`
+}
+
+// CodeDelta is how often a code was seen in period A and B
+type CodeDelta struct {
+	Code string `json:"code"`
+	CountDelta
+}
+
+// FleetDelta compares the whole fleet between the periods
+type FleetDelta struct {
+	IPs CountDelta `json:"ips"`
+	// RejectionRatio is averaged over every aggregation run of the period
+	RejectionRatio RatioDelta `json:"rejection_ratio"`
+	Failures       CountDelta `json:"failures"`
+	// Statuses counts the IPs by the status of their last run in the period
+	Statuses map[string]CountDelta `json:"statuses"`
+	// TopCodes holds the most frequent codes of either period, largest
+	// change first
+	TopCodes []CodeDelta `json:"top_codes"`
+}
+
+// IPComparison compares one IP between the periods; the status and top code
+// of a period are empty when the IP has no record in it
+type IPComparison struct {
+	IP             string     `json:"ip"`
+	RejectionRatio RatioDelta `json:"rejection_ratio"`
+	StatusA        string     `json:"status_a"`
+	StatusB        string     `json:"status_b"`
+	Failures       CountDelta `json:"failures"`
+	TopCodeA       string     `json:"top_code_a"`
+	TopCodeB       string     `json:"top_code_b"`
+}
+
+// FleetComparisonResponse compares period A with period B; deltas are B
+// minus A
+type FleetComparisonResponse struct {
+	PeriodA ComparePeriod `json:"period_a"`
+	PeriodB ComparePeriod `json:"period_b"`
+	Fleet   FleetDelta    `json:"fleet"`
+	// IPs holds every IP seen in either period, largest rejection ratio
+	// change first
+	IPs []IPComparison `json:"ips"`
+}
+
+// periodData is what one period contributes to a comparison
+type periodData struct {
+	Stats []database.PeriodIPStats
+	Codes []database.PeriodCodeCount
+}
+
+func newRatioDelta(a, b *float64) RatioDelta {
+	delta := RatioDelta{A: a, B: b}
+	if a != nil && b != nil {
+		d := *b - *a
+		delta.Delta = &d
+	}
+	return delta
+}
+
+func newCountDelta(a, b int) CountDelta {
+	return CountDelta{A: a, B: b, Delta: b - a}
+}
+
+// topKeys returns the n keys of counts with the largest counts
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// buildFleetComparison compares period a with period b, leaving out the IPs
+// in hidden
+func buildFleetComparison(periodA, periodB ComparePeriod, a, b periodData, hidden hiddenIPs) FleetComparisonResponse {
+	type side struct {
+		stats    *database.PeriodIPStats
+		failures int
+		codes    map[string]int
+	}
+	type sides [2]side
+
+	ips := make(map[string]*sides)
+	get := func(ip string) *sides {
+		s, ok := ips[ip]
+		if !ok {
+			s = &sides{}
+			ips[ip] = s
+		}
+		return s
+	}
+	fleetCodes := [2]map[string]int{{}, {}}
+	var runs, failures [2]int
+	var ratioSum [2]float64
+	statuses := [2]map[string]int{{}, {}}
+
+	for i, period := range []periodData{a, b} {
+		for j := range period.Stats {
+			stat := period.Stats[j]
+			if hidden.hides(stat.IP) {
+				continue
+			}
+			get(stat.IP)[i].stats = &stat
+			runs[i] += stat.Runs
+			ratioSum[i] += stat.RejectionRatio * float64(stat.Runs)
+			statuses[i][stat.Status]++
+		}
+		for _, c := range period.Codes {
+			if hidden.hides(c.IP) {
+				continue
+			}
+			s := &get(c.IP)[i]
+			if s.codes == nil {
+				s.codes = map[string]int{}
+			}
+			s.codes[c.Code] += c.Count
+			s.failures += c.Count
+			fleetCodes[i][c.Code] += c.Count
+			failures[i] += c.Count
+		}
+	}
+
+	response := FleetComparisonResponse{PeriodA: periodA, PeriodB: periodB, IPs: []IPComparison{}}
+	for ip, s := range ips {
+		var ratios [2]*float64
+		var status, code [2]string
+		for i := range s {
+			if s[i].stats != nil {
+				ratio := s[i].stats.RejectionRatio
+				ratios[i] = &ratio
+				status[i] = s[i].stats.Status
+			}
+			if top := topKeys(s[i].codes, 1); len(top) > 0 {
+				code[i] = top[0]
+			}
+		}
+		response.IPs = append(response.IPs, IPComparison{
+			IP:             ip,
+			RejectionRatio: newRatioDelta(ratios[0], ratios[1]),
+			StatusA:        status[0],
+			StatusB:        status[1],
+			Failures:       newCountDelta(s[0].failures, s[1].failures),
+			TopCodeA:       code[0],
+			TopCodeB:       code[1],
+		})
+	}
+	change := func(d RatioDelta) float64 {
+		if d.Delta == nil {
+			return 0
+		}
+		return math.Abs(*d.Delta)
+	}
+	sort.Slice(response.IPs, func(i, j int) bool {
+		ci, cj := change(response.IPs[i].RejectionRatio), change(response.IPs[j].RejectionRatio)
+		if ci != cj {
+			return ci > cj
+		}
+		return response.IPs[i].IP < response.IPs[j].IP
+	})
+
+	var fleetRatios [2]*float64
+	tracked := [2]int{}
+	for i := range runs {
+		for _, n := range statuses[i] {
+			tracked[i] += n
+		}
+		if runs[i] > 0 {
+			ratio := ratioSum[i] / float64(runs[i])
+			fleetRatios[i] = &ratio
+		}
+	}
+	response.Fleet = FleetDelta{
+		IPs:            newCountDelta(tracked[0], tracked[1]),
+		RejectionRatio: newRatioDelta(fleetRatios[0], fleetRatios[1]),
+		Failures:       newCountDelta(failures[0], failures[1]),
+		Statuses:       map[string]CountDelta{},
+		TopCodes:       []CodeDelta{},
+	}
+	for _, counts := range statuses {
+		for status := range counts {
+			response.Fleet.Statuses[status] = newCountDelta(statuses[0][status], statuses[1][status])
+		}
+	}
+	seen := map[string]bool{}
+	for _, counts := range fleetCodes {
+		for _, code := range topKeys(counts, compareTopCodes) {
+			if !seen[code] {
+				seen[code] = true
+				response.Fleet.TopCodes = append(response.Fleet.TopCodes, CodeDelta{Code: code, CountDelta: newCountDelta(fleetCodes[0][code], fleetCodes[1][code])})
+			}
+		}
+	}
+	sort.Slice(response.Fleet.TopCodes, func(i, j int) bool {
+		di, dj := response.Fleet.TopCodes[i].Delta, response.Fleet.TopCodes[j].Delta
+		if di < 0 {
+			di = -di
+		}
+		if dj < 0 {
+			dj = -dj
+		}
+		if di != dj {
+			return di > dj
+		}
+		return response.Fleet.TopCodes[i].Code < response.Fleet.TopCodes[j].Code
+	})
+	return response
+}
+
+// parseComparePeriod reads the period from the from_<name> and to_<name>
+// RFC 3339 query parameters
+func parseComparePeriod(w http.ResponseWriter, r *http.Request, name string) (ComparePeriod, bool) {
+	var period ComparePeriod
+	bounds := []*time.Time{&period.From, &period.To}
+	for i, param := range []string{"from_" + name, "to_" + name} {
+		parsed, err := time.Parse(time.RFC3339, r.URL.Query().Get(param))
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_period", param+" must be an RFC 3339 time")
+			return period, false
+		}
+		*bounds[i] = parsed.UTC()
+	}
+	if !period.From.Before(period.To) {
+		writeUserError(w, http.StatusBadRequest, "invalid_period", "from_"+name+" must be before to_"+name)
+		return period, false
+	}
+	if period.To.Sub(period.From) > maxComparePeriodDays*24*time.Hour {
+		writeUserError(w, http.StatusBadRequest, "invalid_period", "period "+name+" must be at most "+strconv.Itoa(maxComparePeriodDays)+" days")
+		return period, false
+	}
+	return period, true
+}
+
+// @Summary Compare the fleet between two periods
+// @Description Per-IP and fleet-level changes in rejection ratio, status distribution and top codes from period A to period B, e.g. before and after an infrastructure or content change. Ratios and statuses come from the aggregation history, codes from the stored failures.
+// @Tags analytics
+// @Produce json
+// @Param from_a query string true "Start of period A (RFC 3339)"
+// @Param to_a query string true "End of period A (RFC 3339, exclusive)"
+// @Param from_b query string true "Start of period B (RFC 3339)"
+// @Param to_b query string true "End of period B (RFC 3339, exclusive)"
+// @Param include_archived query bool false "Include archived IPs"
+// @Param include_excluded query bool false "Include IPs in aggregation exclusions"
+// @Success 200 {object} FleetComparisonResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/analytics/compare [get]
+func (h *ReputationHandler) getFleetComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	periodA, ok := parseComparePeriod(w, r, "a")
+	if !ok {
+		return
+	}
+	periodB, ok := parseComparePeriod(w, r, "b")
+	if !ok {
+		return
+	}
+
+	var data [2]periodData
+	for i, period := range []ComparePeriod{periodA, periodB} {
+		stats, err := database.GetPeriodIPStats(period.From, period.To)
+		if err == nil {
+			data[i].Stats = stats
+			data[i].Codes, err = database.GetPeriodFailureCodes(period.From, period.To)
+		}
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "get_fleet_comparison_failed",
+				"error":  err.Error(),
+			}).Error("Failed to get fleet comparison")
+			writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve comparison data")
+			return
+		}
+	}
+	hidden, err := dashboardHiddenIPs(r)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_fleet_comparison_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get hidden IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve comparison data")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildFleetComparison(periodA, periodB, data[0], data[1], hidden))
+}
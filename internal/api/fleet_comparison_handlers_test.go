@@ -0,0 +1,94 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBuildFleetComparison(t *testing.T) {
+	a := periodData{
+		Stats: []database.PeriodIPStats{
+			{IP: "192.0.2.1", Runs: 3, RejectionRatio: 0.02, Status: "healthy"},
+			{IP: "192.0.2.2", Runs: 1, RejectionRatio: 0.10, Status: "warning"},
+			{IP: "192.0.2.9", Runs: 5, RejectionRatio: 0.90, Status: "blacklisted"},
+		},
+		Codes: []database.PeriodCodeCount{
+			{IP: "192.0.2.1", Code: "5.7.1", Count: 4},
+			{IP: "192.0.2.2", Code: "4.2.1", Count: 10},
+		},
+	}
+	b := periodData{
+		Stats: []database.PeriodIPStats{
+			{IP: "192.0.2.1", Runs: 4, RejectionRatio: 0.12, Status: "warning"},
+			{IP: "192.0.2.3", Runs: 4, RejectionRatio: 0.04, Status: "healthy"},
+		},
+		Codes: []database.PeriodCodeCount{
+			{IP: "192.0.2.1", Code: "5.7.1", Count: 20},
+			{IP: "192.0.2.1", Code: "550", Count: 2},
+			{IP: "192.0.2.3", Code: "4.2.1", Count: 8},
+		},
+	}
+
+	comparison := buildFleetComparison(ComparePeriod{}, ComparePeriod{}, a, b, hiddenIPs{archived: map[string]bool{"192.0.2.9": true}})
+
+	if len(comparison.IPs) != 3 {
+		t.Fatalf("Expected the archived IP left out, got %+v", comparison.IPs)
+	}
+	first := comparison.IPs[0]
+	if first.IP != "192.0.2.1" || first.RejectionRatio.Delta == nil || math.Abs(*first.RejectionRatio.Delta-0.10) > 1e-9 {
+		t.Errorf("Expected the largest ratio change first, got %+v", first)
+	}
+	if first.StatusA != "healthy" || first.StatusB != "warning" || first.Failures.Delta != 18 || first.TopCodeB != "5.7.1" {
+		t.Errorf("Unexpected comparison of %s: %+v", first.IP, first)
+	}
+	for _, c := range comparison.IPs[1:] {
+		if c.RejectionRatio.Delta != nil {
+			t.Errorf("Expected no ratio delta for %s seen in one period, got %v", c.IP, *c.RejectionRatio.Delta)
+		}
+	}
+
+	fleet := comparison.Fleet
+	if fleet.IPs.A != 2 || fleet.IPs.B != 2 || fleet.Failures.A != 14 || fleet.Failures.B != 30 {
+		t.Errorf("Unexpected fleet counts: %+v", fleet)
+	}
+	// Run-weighted: (3*0.02 + 0.10) / 4 and (4*0.12 + 4*0.04) / 8
+	if math.Abs(*fleet.RejectionRatio.A-0.04) > 1e-9 || math.Abs(*fleet.RejectionRatio.B-0.08) > 1e-9 {
+		t.Errorf("Unexpected fleet ratios: %v %v", *fleet.RejectionRatio.A, *fleet.RejectionRatio.B)
+	}
+	if fleet.Statuses["warning"] != (CountDelta{A: 1, B: 1, Delta: 0}) || fleet.Statuses["healthy"] != (CountDelta{A: 1, B: 1, Delta: 0}) {
+		t.Errorf("Unexpected status distribution: %+v", fleet.Statuses)
+	}
+	if _, ok := fleet.Statuses["blacklisted"]; ok {
+		t.Errorf("Expected the archived IP's status left out, got %+v", fleet.Statuses)
+	}
+	if len(fleet.TopCodes) != 3 || fleet.TopCodes[0].Code != "5.7.1" || fleet.TopCodes[0].Delta != 16 {
+		t.Errorf("Expected codes by largest change, got %+v", fleet.TopCodes)
+	}
+}
+
+func TestFleetComparisonRejectsInvalidPeriods(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	handler := &ReputationHandler{logger: logger}
+	now := time.Now().UTC().Truncate(time.Second)
+	at := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	for name, query := range map[string]string{
+		"missing period b": "from_a=" + at(-48*time.Hour) + "&to_a=" + at(-24*time.Hour),
+		"not rfc 3339":     "from_a=yesterday&to_a=" + at(0) + "&from_b=" + at(-time.Hour) + "&to_b=" + at(0),
+		"reversed":         "from_a=" + at(0) + "&to_a=" + at(-time.Hour) + "&from_b=" + at(-time.Hour) + "&to_b=" + at(0),
+		"too long":         "from_a=" + at(-100*24*time.Hour) + "&to_a=" + at(0) + "&from_b=" + at(-time.Hour) + "&to_b=" + at(0),
+	} {
+		rec := httptest.NewRecorder()
+		handler.getFleetComparisonHandler(rec, httptest.NewRequest("GET", "/api/analytics/compare?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultHeatmapDays = 7
+	maxHeatmapDays     = 30
+)
+
+// RejectionHeatmapResponse is a matrix of hourly rejection ratios. Ratios[i]
+// of a row belongs to Hours[i]; hours without an aggregation run are null.
+type RejectionHeatmapResponse struct {
+	From  time.Time    `json:"from"`
+	To    time.Time    `json:"to"`
+	Hours []time.Time  `json:"hours"`
+	Rows  []HeatmapRow `json:"rows"`
+}
+
+// HeatmapRow is one IP's hourly rejection ratios, averaged over the
+// aggregation runs of each hour
+type HeatmapRow struct {
+	IP     string     `json:"ip"`
+	Ratios []*float64 `json:"ratios"`
+	// Peak is the highest hourly ratio; rows are sorted by it
+	Peak float64 `json:"peak"`
+}
+
+// buildHeatmap lays buckets out on the hours from..to (inclusive, UTC)
+func buildHeatmap(buckets []database.HeatmapBucket, from, to time.Time) RejectionHeatmapResponse {
+	response := RejectionHeatmapResponse{From: from, To: to, Hours: []time.Time{}, Rows: []HeatmapRow{}}
+	for hour := from; !hour.After(to); hour = hour.Add(time.Hour) {
+		response.Hours = append(response.Hours, hour)
+	}
+
+	rows := make(map[string]*HeatmapRow)
+	for _, b := range buckets {
+		index := int(b.Hour.Sub(from) / time.Hour)
+		if index < 0 || index >= len(response.Hours) {
+			continue
+		}
+		row, ok := rows[b.IP]
+		if !ok {
+			row = &HeatmapRow{IP: b.IP, Ratios: make([]*float64, len(response.Hours))}
+			rows[b.IP] = row
+		}
+		ratio := b.Ratio
+		row.Ratios[index] = &ratio
+		if ratio > row.Peak {
+			row.Peak = ratio
+		}
+	}
+
+	for _, row := range rows {
+		response.Rows = append(response.Rows, *row)
+	}
+	sort.Slice(response.Rows, func(i, j int) bool {
+		if response.Rows[i].Peak != response.Rows[j].Peak {
+			return response.Rows[i].Peak > response.Rows[j].Peak
+		}
+		return response.Rows[i].IP < response.Rows[j].IP
+	})
+	return response
+}
+
+// @Summary Fleet rejection heatmap
+// @Description Hourly rejection ratios per IP for the last N days, bucketed server-side from the aggregation history
+// @Tags ip-reputation
+// @Produce json
+// @Param days query int false "Days to cover (1-30)" default(7)
+// @Param owner query string false "Only IPs owned by me, user:<id> or team:<id>"
+// @Param include_archived query bool false "Include archived IPs"
+// @Param include_excluded query bool false "Include IPs in aggregation exclusions"
+// @Success 200 {object} RejectionHeatmapResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dashboard/heatmap [get]
+func (h *ReputationHandler) getRejectionHeatmapHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultHeatmapDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxHeatmapDays {
+			writeUserError(w, http.StatusBadRequest, "invalid_days", "days must be between 1 and "+strconv.Itoa(maxHeatmapDays))
+			return
+		}
+		days = parsed
+	}
+
+	to := time.Now().UTC().Truncate(time.Hour)
+	from := to.Add(-time.Duration(days*24-1) * time.Hour)
+
+	buckets, err := database.GetRejectionHeatmap(from)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_heatmap_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get rejection heatmap")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve heatmap data")
+		return
+	}
+
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		ownerType, ownerID, err := parseOwnerRef(r, owner)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_owner", err.Error())
+			return
+		}
+		ownedIPs, err := database.GetOwnedIPs(ownerType, ownerID)
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "get_heatmap_failed",
+				"error":  err.Error(),
+			}).Error("Failed to get owned IPs")
+			writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve heatmap data")
+			return
+		}
+		owned := make(map[string]bool, len(ownedIPs))
+		for _, ip := range ownedIPs {
+			owned[ip] = true
+		}
+		kept := buckets[:0]
+		for _, b := range buckets {
+			if owned[b.IP] {
+				kept = append(kept, b)
+			}
+		}
+		buckets = kept
+	}
+
+	hidden, err := dashboardHiddenIPs(r)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_heatmap_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get hidden IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve heatmap data")
+		return
+	}
+	if !hidden.empty() {
+		kept := buckets[:0]
+		for _, b := range buckets {
+			if !hidden.hides(b.IP) {
+				kept = append(kept, b)
+			}
+		}
+		buckets = kept
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildHeatmap(buckets, from, to))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	from := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	to := from.Add(3 * time.Hour)
+	buckets := []database.HeatmapBucket{
+		{IP: "192.0.2.1", Hour: from, Ratio: 0.01},
+		{IP: "192.0.2.2", Hour: from.Add(time.Hour), Ratio: 0.08},
+		{IP: "192.0.2.1", Hour: to, Ratio: 0.03},
+		{IP: "192.0.2.3", Hour: from.Add(-time.Hour), Ratio: 0.5},
+	}
+
+	heatmap := buildHeatmap(buckets, from, to)
+	if len(heatmap.Hours) != 4 || !heatmap.Hours[3].Equal(to) {
+		t.Fatalf("Unexpected hours: %v", heatmap.Hours)
+	}
+	if len(heatmap.Rows) != 2 {
+		t.Fatalf("Expected buckets outside the range to be dropped, got %+v", heatmap.Rows)
+	}
+
+	first, second := heatmap.Rows[0], heatmap.Rows[1]
+	if first.IP != "192.0.2.2" || first.Peak != 0.08 {
+		t.Errorf("Expected rows sorted by peak, got %+v", heatmap.Rows)
+	}
+	if second.Ratios[0] == nil || *second.Ratios[0] != 0.01 || second.Ratios[1] != nil || *second.Ratios[3] != 0.03 {
+		t.Errorf("Unexpected ratios for %s: %v", second.IP, second.Ratios)
+	}
+}
+
+func TestRejectionHeatmapValidatesDays(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	for _, days := range []string{"0", "31", "week"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/dashboard/heatmap?days="+days, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: status = %d, want 400", days, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxAnnotationLength bounds comments and notes, in characters
+const maxAnnotationLength = 4000
+
+// CreateActionCommentRequest annotates an IP action
+type CreateActionCommentRequest struct {
+	Comment string `json:"comment" example:"Opened ticket with Microsoft, ref 12345"`
+}
+
+// CreateIPNoteRequest adds a note to an IP
+type CreateIPNoteRequest struct {
+	Note string `json:"note" example:"Serves customer X's transactional mail"`
+}
+
+// SetIPContextRequest sets what on-call should know about an IP. Both fields
+// replace the stored ones; at least one is required.
+type SetIPContextRequest struct {
+	Notes      string `json:"notes" example:"Serves customer X's transactional mail"`
+	RunbookURL string `json:"runbook_url" example:"https://wiki.example.com/runbooks/customer-x"`
+}
+
+// annotationStore persists action comments and IP notes
+type annotationStore interface {
+	GetIPAction(id int) (*database.IPAction, error)
+	CreateIPActionComment(comment *database.IPActionComment) (*database.IPActionComment, error)
+	ListIPActionComments(actionIDs []int) (map[int][]database.IPActionComment, error)
+	CreateIPNote(note *database.IPNote) (*database.IPNote, error)
+	ListIPNotes(ip string) ([]database.IPNote, error)
+	DeleteIPNote(ip string, id int) (bool, error)
+	GetIPContext(ip string) (*database.IPContext, error)
+	SetIPContext(c *database.IPContext) (*database.IPContext, error)
+	DeleteIPContext(ip string) (bool, error)
+}
+
+type dbAnnotationStore struct{}
+
+func (dbAnnotationStore) GetIPAction(id int) (*database.IPAction, error) {
+	return database.GetIPAction(id)
+}
+
+func (dbAnnotationStore) CreateIPActionComment(comment *database.IPActionComment) (*database.IPActionComment, error) {
+	return database.CreateIPActionComment(comment)
+}
+
+func (dbAnnotationStore) ListIPActionComments(actionIDs []int) (map[int][]database.IPActionComment, error) {
+	return database.ListIPActionComments(actionIDs)
+}
+
+func (dbAnnotationStore) CreateIPNote(note *database.IPNote) (*database.IPNote, error) {
+	return database.CreateIPNote(note)
+}
+
+func (dbAnnotationStore) ListIPNotes(ip string) ([]database.IPNote, error) {
+	return database.ListIPNotes(ip)
+}
+
+func (dbAnnotationStore) DeleteIPNote(ip string, id int) (bool, error) {
+	return database.DeleteIPNote(ip, id)
+}
+
+func (dbAnnotationStore) GetIPContext(ip string) (*database.IPContext, error) {
+	return database.GetIPContext(ip)
+}
+
+func (dbAnnotationStore) SetIPContext(c *database.IPContext) (*database.IPContext, error) {
+	return database.SetIPContext(c)
+}
+
+func (dbAnnotationStore) DeleteIPContext(ip string) (bool, error) {
+	return database.DeleteIPContext(ip)
+}
+
+// IPAnnotationHandler lets operators comment on IP actions and keep notes on
+// IPs
+type IPAnnotationHandler struct {
+	store  annotationStore
+	logger *logrus.Logger
+}
+
+// NewIPAnnotationHandler creates a new annotation handler
+func NewIPAnnotationHandler(logger *logrus.Logger) *IPAnnotationHandler {
+	return &IPAnnotationHandler{store: dbAnnotationStore{}, logger: logger}
+}
+
+// annotationText trims text and checks it is neither empty nor too long
+func annotationText(w http.ResponseWriter, field, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		writeUserError(w, http.StatusBadRequest, "missing_"+field, field+" is required")
+		return "", false
+	}
+	if len([]rune(text)) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "invalid_"+field, field+" must be at most "+strconv.Itoa(maxAnnotationLength)+" characters")
+		return "", false
+	}
+	return text, true
+}
+
+// ipAction loads the action in the path and checks it belongs to the IP
+func (h *IPAnnotationHandler) ipAction(w http.ResponseWriter, r *http.Request) (*database.IPAction, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil || id < 1 {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Action ID must be a positive integer")
+		return nil, false
+	}
+	action, err := h.store.GetIPAction(id)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "get_ip_action",
+			"action_id": id,
+			"error":     err.Error(),
+		}).Error("Failed to get IP action")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve IP action")
+		return nil, false
+	}
+	if action == nil || action.IP != vars["ip"] {
+		writeUserError(w, http.StatusNotFound, "not_found", "No such action for this IP")
+		return nil, false
+	}
+	return action, true
+}
+
+// @Summary Comment on an IP action
+// @Description Annotate an automated or manual action, e.g. with the ticket opened with a provider
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param id path int true "Action ID"
+// @Param comment body CreateActionCommentRequest true "Comment"
+// @Success 201 {object} database.IPActionComment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/actions/{id}/comments [post]
+func (h *IPAnnotationHandler) HandleCreateActionComment(w http.ResponseWriter, r *http.Request) {
+	var req CreateActionCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object with a comment")
+		return
+	}
+	text, ok := annotationText(w, "comment", req.Comment)
+	if !ok {
+		return
+	}
+	action, ok := h.ipAction(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.store.CreateIPActionComment(&database.IPActionComment{
+		ActionID: action.ID,
+		Author:   principalName(r),
+		Comment:  text,
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "create_action_comment",
+			"action_id": action.ID,
+			"error":     err.Error(),
+		}).Error("Failed to create action comment")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save comment")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":    "action_comment_created",
+		"ip":        action.IP,
+		"action_id": action.ID,
+		"author":    comment.Author,
+	}).Info("IP action annotated")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// @Summary List comments on an IP action
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param id path int true "Action ID"
+// @Success 200 {array} database.IPActionComment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/actions/{id}/comments [get]
+func (h *IPAnnotationHandler) HandleListActionComments(w http.ResponseWriter, r *http.Request) {
+	action, ok := h.ipAction(w, r)
+	if !ok {
+		return
+	}
+	comments, err := h.store.ListIPActionComments([]int{action.ID})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "list_action_comments",
+			"action_id": action.ID,
+			"error":     err.Error(),
+		}).Error("Failed to list action comments")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve comments")
+		return
+	}
+
+	list := comments[action.ID]
+	if list == nil {
+		list = []database.IPActionComment{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// @Summary Add a note to an IP
+// @Description Store a free-form operator note, shown in the IP's reputation response
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param note body CreateIPNoteRequest true "Note"
+// @Success 201 {object} database.IPNote
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/notes [post]
+func (h *IPAnnotationHandler) HandleCreateNote(w http.ResponseWriter, r *http.Request) {
+	var req CreateIPNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object with a note")
+		return
+	}
+	text, ok := annotationText(w, "note", req.Note)
+	if !ok {
+		return
+	}
+
+	ip := mux.Vars(r)["ip"]
+	note, err := h.store.CreateIPNote(&database.IPNote{IP: ip, Author: principalName(r), Note: text})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_ip_note",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to create IP note")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save note")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "ip_note_created",
+		"ip":      ip,
+		"note_id": note.ID,
+		"author":  note.Author,
+	}).Info("IP note added")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// @Summary List notes on an IP
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {array} database.IPNote
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/notes [get]
+func (h *IPAnnotationHandler) HandleListNotes(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	notes, err := h.store.ListIPNotes(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_ip_notes",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to list IP notes")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve notes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// @Summary Delete a note on an IP
+// @Tags ip-reputation
+// @Param ip path string true "IP Address"
+// @Param id path int true "Note ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/notes/{id} [delete]
+func (h *IPAnnotationHandler) HandleDeleteNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil || id < 1 {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Note ID must be a positive integer")
+		return
+	}
+
+	deleted, err := h.store.DeleteIPNote(vars["ip"], id)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "delete_ip_note",
+			"ip":      vars["ip"],
+			"note_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to delete IP note")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to delete note")
+		return
+	}
+	if !deleted {
+		writeUserError(w, http.StatusNotFound, "not_found", "No such note for this IP")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "ip_note_deleted",
+		"ip":      vars["ip"],
+		"note_id": id,
+		"author":  principalName(r),
+	}).Info("IP note deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Get the on-call context of an IP
+// @Description Notes and runbook URL shown in the registry, the IP health dashboard and alerts
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} database.IPContext
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/context [get]
+func (h *IPAnnotationHandler) HandleGetContext(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	c, err := h.store.GetIPContext(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_ip_context",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get IP context")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve IP context")
+		return
+	}
+	if c == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "No context set for this IP")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// @Summary Set the on-call context of an IP
+// @Description Replace the notes and runbook URL of an IP, e.g. whose mail it sends and what to do when it alerts. They are shown in the registry, the IP health dashboard and alerts.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param context body SetIPContextRequest true "Notes and runbook URL"
+// @Success 200 {object} database.IPContext
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/context [put]
+func (h *IPAnnotationHandler) HandleSetContext(w http.ResponseWriter, r *http.Request) {
+	var req SetIPContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object with notes and/or a runbook_url")
+		return
+	}
+	notes := strings.TrimSpace(req.Notes)
+	runbook := strings.TrimSpace(req.RunbookURL)
+	if notes == "" && runbook == "" {
+		writeUserError(w, http.StatusBadRequest, "missing_context", "notes or runbook_url is required; delete the context to clear it")
+		return
+	}
+	if len([]rune(notes)) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "invalid_notes", "notes must be at most "+strconv.Itoa(maxAnnotationLength)+" characters")
+		return
+	}
+	if runbook != "" {
+		u, err := url.Parse(runbook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeUserError(w, http.StatusBadRequest, "invalid_runbook_url", "runbook_url must be an http or https URL")
+			return
+		}
+	}
+
+	ip := mux.Vars(r)["ip"]
+	c, err := h.store.SetIPContext(&database.IPContext{IP: ip, Notes: notes, RunbookURL: runbook, UpdatedBy: principalName(r)})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "set_ip_context",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to set IP context")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save IP context")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "ip_context_set",
+		"ip":     ip,
+		"author": c.UpdatedBy,
+	}).Info("IP context updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// @Summary Clear the on-call context of an IP
+// @Tags ip-reputation
+// @Param ip path string true "IP Address"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/context [delete]
+func (h *IPAnnotationHandler) HandleDeleteContext(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	deleted, err := h.store.DeleteIPContext(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "delete_ip_context",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to delete IP context")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to delete IP context")
+		return
+	}
+	if !deleted {
+		writeUserError(w, http.StatusNotFound, "not_found", "No context set for this IP")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "ip_context_deleted",
+		"ip":     ip,
+		"author": principalName(r),
+	}).Info("IP context cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
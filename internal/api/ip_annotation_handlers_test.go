@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryAnnotationStore is an in-memory annotationStore
+type memoryAnnotationStore struct {
+	actions  map[int]database.IPAction
+	comments []database.IPActionComment
+	notes    []database.IPNote
+	contexts map[string]database.IPContext
+}
+
+func (s *memoryAnnotationStore) GetIPAction(id int) (*database.IPAction, error) {
+	action, ok := s.actions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &action, nil
+}
+
+func (s *memoryAnnotationStore) CreateIPActionComment(comment *database.IPActionComment) (*database.IPActionComment, error) {
+	stored := *comment
+	stored.ID = len(s.comments) + 1
+	stored.CreatedAt = time.Now()
+	s.comments = append(s.comments, stored)
+	return &stored, nil
+}
+
+func (s *memoryAnnotationStore) ListIPActionComments(actionIDs []int) (map[int][]database.IPActionComment, error) {
+	comments := make(map[int][]database.IPActionComment)
+	for _, c := range s.comments {
+		for _, id := range actionIDs {
+			if c.ActionID == id {
+				comments[id] = append(comments[id], c)
+			}
+		}
+	}
+	return comments, nil
+}
+
+func (s *memoryAnnotationStore) CreateIPNote(note *database.IPNote) (*database.IPNote, error) {
+	stored := *note
+	stored.ID = len(s.notes) + 1
+	stored.CreatedAt = time.Now()
+	s.notes = append(s.notes, stored)
+	return &stored, nil
+}
+
+func (s *memoryAnnotationStore) ListIPNotes(ip string) ([]database.IPNote, error) {
+	notes := []database.IPNote{}
+	for i := len(s.notes) - 1; i >= 0; i-- {
+		if s.notes[i].IP == ip {
+			notes = append(notes, s.notes[i])
+		}
+	}
+	return notes, nil
+}
+
+func (s *memoryAnnotationStore) DeleteIPNote(ip string, id int) (bool, error) {
+	for i, n := range s.notes {
+		if n.ID == id && n.IP == ip {
+			s.notes = append(s.notes[:i], s.notes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryAnnotationStore) GetIPContext(ip string) (*database.IPContext, error) {
+	c, ok := s.contexts[ip]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (s *memoryAnnotationStore) SetIPContext(c *database.IPContext) (*database.IPContext, error) {
+	if s.contexts == nil {
+		s.contexts = make(map[string]database.IPContext)
+	}
+	stored := *c
+	stored.UpdatedAt = time.Now()
+	s.contexts[c.IP] = stored
+	return &stored, nil
+}
+
+func (s *memoryAnnotationStore) DeleteIPContext(ip string) (bool, error) {
+	_, ok := s.contexts[ip]
+	delete(s.contexts, ip)
+	return ok, nil
+}
+
+func newTestAnnotationRouter() (*mux.Router, *memoryAnnotationStore) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryAnnotationStore{actions: map[int]database.IPAction{
+		1: {ID: 1, IP: "192.0.2.1", Action: "status_change"},
+		2: {ID: 2, IP: "192.0.2.2", Action: "status_change"},
+	}}
+	handler := &IPAnnotationHandler{store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/{ip}/actions/{id}/comments", handler.HandleCreateActionComment).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/actions/{id}/comments", handler.HandleListActionComments).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/notes", handler.HandleCreateNote).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/notes", handler.HandleListNotes).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/notes/{id}", handler.HandleDeleteNote).Methods("DELETE")
+	router.HandleFunc("/api/ips/{ip}/context", handler.HandleGetContext).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/context", handler.HandleSetContext).Methods("PUT")
+	router.HandleFunc("/api/ips/{ip}/context", handler.HandleDeleteContext).Methods("DELETE")
+	return router, store
+}
+
+func TestActionComments(t *testing.T) {
+	router, store := newTestAnnotationRouter()
+
+	rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/actions/1/comments", `{"comment":"  Opened ticket with Microsoft, ref 12345 "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if c := store.comments[0]; c.ActionID != 1 || c.Author != "anonymous" || c.Comment != "Opened ticket with Microsoft, ref 12345" {
+		t.Errorf("Unexpected comment: %+v", c)
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/actions/1/comments", "")
+	var comments []database.IPActionComment
+	if err := json.Unmarshal(rec.Body.Bytes(), &comments); err != nil || len(comments) != 1 {
+		t.Errorf("Unexpected comments: %s", rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+	}{
+		{"action of another IP", "/api/ips/192.0.2.1/actions/2/comments", `{"comment":"x"}`, http.StatusNotFound},
+		{"unknown action", "/api/ips/192.0.2.1/actions/9/comments", `{"comment":"x"}`, http.StatusNotFound},
+		{"bad id", "/api/ips/192.0.2.1/actions/abc/comments", `{"comment":"x"}`, http.StatusBadRequest},
+		{"empty comment", "/api/ips/192.0.2.1/actions/1/comments", `{"comment":"  "}`, http.StatusBadRequest},
+		{"too long", "/api/ips/192.0.2.1/actions/1/comments", `{"comment":"` + strings.Repeat("a", maxAnnotationLength+1) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveConfig(router, "POST", tt.target, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+	if len(store.comments) != 1 {
+		t.Errorf("Rejected comments were stored: %+v", store.comments)
+	}
+}
+
+func TestIPNotes(t *testing.T) {
+	router, store := newTestAnnotationRouter()
+
+	for _, note := range []string{"first", "second"} {
+		if rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/notes", `{"note":"`+note+`"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	serveConfig(router, "POST", "/api/ips/192.0.2.2/notes", `{"note":"other IP"}`)
+
+	rec := serveConfig(router, "GET", "/api/ips/192.0.2.1/notes", "")
+	var notes []database.IPNote
+	json.Unmarshal(rec.Body.Bytes(), &notes)
+	if len(notes) != 2 || notes[0].Note != "second" {
+		t.Errorf("Expected the IP's notes newest first, got %+v", notes)
+	}
+
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/notes/3", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete of another IP's note status = %d, want 404", rec.Code)
+	}
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/notes/1", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", rec.Code)
+	}
+	if len(store.notes) != 2 {
+		t.Errorf("Expected 2 notes left, got %+v", store.notes)
+	}
+}
+
+func TestIPContext(t *testing.T) {
+	router, store := newTestAnnotationRouter()
+
+	if rec := serveConfig(router, "GET", "/api/ips/192.0.2.1/context", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get status before set = %d, want 404", rec.Code)
+	}
+
+	rec := serveConfig(router, "PUT", "/api/ips/192.0.2.1/context", `{"notes":" Serves customer X's transactional mail ","runbook_url":"https://wiki.example.com/runbooks/x"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if c := store.contexts["192.0.2.1"]; c.Notes != "Serves customer X's transactional mail" || c.RunbookURL != "https://wiki.example.com/runbooks/x" || c.UpdatedBy != "anonymous" {
+		t.Errorf("Unexpected context: %+v", c)
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/context", "")
+	var c database.IPContext
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil || c.RunbookURL != "https://wiki.example.com/runbooks/x" {
+		t.Errorf("Unexpected context: %s", rec.Body.String())
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `{"notes":" ","runbook_url":""}`},
+		{"not a URL", `{"runbook_url":"wiki/runbooks/x"}`},
+		{"other scheme", `{"runbook_url":"javascript:alert(1)"}`},
+		{"too long", `{"notes":"` + strings.Repeat("a", maxAnnotationLength+1) + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveConfig(router, "PUT", "/api/ips/192.0.2.1/context", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/context", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", rec.Code)
+	}
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/context", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ArchiveIPRequest says why an IP is decommissioned
+type ArchiveIPRequest struct {
+	Reason string `json:"reason" example:"Returned to IONOS"`
+}
+
+// BulkArchiveRequest archives every known IP in a CIDR block
+type BulkArchiveRequest struct {
+	CIDR   string `json:"cidr" example:"192.0.2.0/28"`
+	Reason string `json:"reason" example:"Block decommissioned"`
+	// DryRun lists the IPs that would be archived without archiving them
+	DryRun bool `json:"dry_run"`
+}
+
+// ArchiveIPsResponse lists the IPs archived by a request and those that
+// already were
+type ArchiveIPsResponse struct {
+	Archived        []database.ArchivedIP `json:"archived"`
+	AlreadyArchived []string              `json:"already_archived"`
+	// Matched is set on a dry run: the IPs that would be archived
+	Matched []string `json:"matched,omitempty"`
+}
+
+// ArchivedIPsResponse lists the archived IPs
+type ArchivedIPsResponse struct {
+	Total int                   `json:"total"`
+	IPs   []database.ArchivedIP `json:"ips"`
+}
+
+// archiveStore persists the archived IPs
+type archiveStore interface {
+	ArchiveIPs(ips []string, reason, author string) ([]database.ArchivedIP, error)
+	UnarchiveIP(ip, author string) error
+	ListArchivedIPs() ([]database.ArchivedIP, error)
+	ListKnownIPsInCIDR(cidr string) ([]string, error)
+}
+
+type dbArchiveStore struct{}
+
+func (dbArchiveStore) ArchiveIPs(ips []string, reason, author string) ([]database.ArchivedIP, error) {
+	return database.ArchiveIPs(ips, reason, author)
+}
+
+func (dbArchiveStore) UnarchiveIP(ip, author string) error {
+	return database.UnarchiveIP(ip, author)
+}
+
+func (dbArchiveStore) ListArchivedIPs() ([]database.ArchivedIP, error) {
+	return database.ListArchivedIPs()
+}
+
+func (dbArchiveStore) ListKnownIPsInCIDR(cidr string) ([]string, error) {
+	return database.ListKnownIPsInCIDR(cidr)
+}
+
+// IPArchiveHandler decommissions IPs: archived IPs keep their history but
+// are skipped by aggregation and alerting and hidden from dashboards
+type IPArchiveHandler struct {
+	store  archiveStore
+	logger *logrus.Logger
+}
+
+// NewIPArchiveHandler creates a new archive handler
+func NewIPArchiveHandler(logger *logrus.Logger) *IPArchiveHandler {
+	return &IPArchiveHandler{store: dbArchiveStore{}, logger: logger}
+}
+
+// includeArchived reports whether a listing was asked to show archived IPs
+func includeArchived(r *http.Request) bool {
+	return r.URL.Query().Get("include_archived") == "true"
+}
+
+// hiddenIPs are the IPs a dashboard leaves out: the archived IPs and those
+// in an aggregation exclusion
+type hiddenIPs struct {
+	archived map[string]bool
+	excluded []*net.IPNet
+}
+
+// hides reports whether a dashboard leaves ip out
+func (h hiddenIPs) hides(ip string) bool {
+	if h.archived[ip] {
+		return true
+	}
+	if len(h.excluded) == 0 {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	for _, block := range h.excluded {
+		if block.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// empty reports whether nothing is hidden
+func (h hiddenIPs) empty() bool {
+	return len(h.archived) == 0 && len(h.excluded) == 0
+}
+
+// dashboardHiddenIPs returns the IPs a dashboard hides: archived IPs unless
+// the request has include_archived=true, and excluded IPs unless it has
+// include_excluded=true
+func dashboardHiddenIPs(r *http.Request) (hiddenIPs, error) {
+	var hidden hiddenIPs
+	var err error
+	if !includeArchived(r) {
+		if hidden.archived, err = database.GetArchivedIPSet(); err != nil {
+			return hiddenIPs{}, err
+		}
+	}
+	if r.URL.Query().Get("include_excluded") != "true" {
+		if hidden.excluded, err = database.GetExcludedBlocks(); err != nil {
+			return hiddenIPs{}, err
+		}
+	}
+	return hidden, nil
+}
+
+// archiveReason trims an optional reason and checks its length
+func archiveReason(w http.ResponseWriter, reason string) (string, bool) {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "invalid_reason", "reason must be at most "+strconv.Itoa(maxAnnotationLength)+" characters")
+		return "", false
+	}
+	return reason, true
+}
+
+// archive archives ips and writes which were archived and which already were
+func (h *IPArchiveHandler) archive(w http.ResponseWriter, r *http.Request, ips []string, reason string, fields logrus.Fields) {
+	author := principalName(r)
+	archived, err := h.store.ArchiveIPs(ips, reason, author)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "archive_ips",
+			"error":  err.Error(),
+		}).Error("Failed to archive IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to archive IPs")
+		return
+	}
+
+	response := ArchiveIPsResponse{Archived: archived, AlreadyArchived: []string{}}
+	done := make(map[string]bool, len(archived))
+	for _, a := range archived {
+		done[a.IP] = true
+	}
+	for _, ip := range ips {
+		if !done[ip] {
+			response.AlreadyArchived = append(response.AlreadyArchived, ip)
+		}
+	}
+
+	if len(archived) > 0 {
+		fields["action"] = "ips_archived"
+		fields["count"] = len(archived)
+		fields["author"] = author
+		h.logger.WithFields(fields).Warn("IPs archived")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Archive an IP
+// @Description Decommission an IP: its history is kept and stays queryable, but aggregation and alerting skip it and dashboards hide it unless called with include_archived=true. Archiving an archived IP changes nothing.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param request body ArchiveIPRequest false "Reason"
+// @Success 200 {object} ArchiveIPsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/archive [post]
+func (h *IPArchiveHandler) HandleArchive(w http.ResponseWriter, r *http.Request) {
+	var req ArchiveIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	reason, ok := archiveReason(w, req.Reason)
+	if !ok {
+		return
+	}
+	ip := mux.Vars(r)["ip"]
+	h.archive(w, r, []string{ip}, reason, logrus.Fields{"ip": ip})
+}
+
+// @Summary Archive the IPs of a CIDR block
+// @Description Archive every IP in a block known to the reserved IPs, reputation metrics, pools, ownership assignments or failures. With dry_run the matching IPs are listed and nothing is archived.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body BulkArchiveRequest true "Block to archive"
+// @Success 200 {object} ArchiveIPsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/archive [post]
+func (h *IPArchiveHandler) HandleBulkArchive(w http.ResponseWriter, r *http.Request) {
+	var req BulkArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	_, block, err := net.ParseCIDR(strings.TrimSpace(req.CIDR))
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_cidr", "cidr must be a CIDR block such as 192.0.2.0/28")
+		return
+	}
+	reason, ok := archiveReason(w, req.Reason)
+	if !ok {
+		return
+	}
+
+	cidr := block.String()
+	ips, err := h.store.ListKnownIPsInCIDR(cidr)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_ips_in_cidr",
+			"cidr":   cidr,
+			"error":  err.Error(),
+		}).Error("Failed to list IPs in block")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list IPs in block")
+		return
+	}
+
+	if req.DryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ArchiveIPsResponse{Archived: []database.ArchivedIP{}, AlreadyArchived: []string{}, Matched: ips})
+		return
+	}
+	h.archive(w, r, ips, reason, logrus.Fields{"cidr": cidr})
+}
+
+// @Summary Unarchive an IP
+// @Description Put an archived IP back into aggregation, alerting and the dashboards
+// @Tags ip-reputation
+// @Param ip path string true "IP Address"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/archive [delete]
+func (h *IPArchiveHandler) HandleUnarchive(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	author := principalName(r)
+	err := h.store.UnarchiveIP(ip, author)
+	if errors.Is(err, database.ErrIPNotArchived) {
+		writeUserError(w, http.StatusNotFound, "not_found", "IP is not archived")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "unarchive_ip",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to unarchive IP")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to unarchive IP")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "ip_unarchived",
+		"ip":     ip,
+		"author": author,
+	}).Info("IP unarchived")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List archived IPs
+// @Description Decommissioned IPs with who archived them and why, most recently archived first
+// @Tags ip-reputation
+// @Produce json
+// @Success 200 {object} ArchivedIPsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/archived [get]
+func (h *IPArchiveHandler) HandleListArchived(w http.ResponseWriter, r *http.Request) {
+	archived, err := h.store.ListArchivedIPs()
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_archived_ips",
+			"error":  err.Error(),
+		}).Error("Failed to list archived IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list archived IPs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ArchivedIPsResponse{Total: len(archived), IPs: archived})
+}
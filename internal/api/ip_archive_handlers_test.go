@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryArchiveStore is an in-memory archiveStore over a list of known IPs
+type memoryArchiveStore struct {
+	known    []string
+	archived map[string]database.ArchivedIP
+}
+
+func (s *memoryArchiveStore) ArchiveIPs(ips []string, reason, author string) ([]database.ArchivedIP, error) {
+	archived := []database.ArchivedIP{}
+	for _, ip := range ips {
+		if _, ok := s.archived[ip]; ok {
+			continue
+		}
+		a := database.ArchivedIP{IP: ip, Reason: reason, ArchivedBy: author, ArchivedAt: time.Now()}
+		s.archived[ip] = a
+		archived = append(archived, a)
+	}
+	return archived, nil
+}
+
+func (s *memoryArchiveStore) UnarchiveIP(ip, author string) error {
+	if _, ok := s.archived[ip]; !ok {
+		return database.ErrIPNotArchived
+	}
+	delete(s.archived, ip)
+	return nil
+}
+
+func (s *memoryArchiveStore) ListArchivedIPs() ([]database.ArchivedIP, error) {
+	archived := []database.ArchivedIP{}
+	for _, a := range s.archived {
+		archived = append(archived, a)
+	}
+	return archived, nil
+}
+
+func (s *memoryArchiveStore) ListKnownIPsInCIDR(cidr string) ([]string, error) {
+	_, block, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ips := []string{}
+	for _, ip := range s.known {
+		if block.Contains(net.ParseIP(ip)) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+func newTestArchiveRouter(store *memoryArchiveStore) *mux.Router {
+	logger, _ := test.NewNullLogger()
+	handler := &IPArchiveHandler{store: store, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/archived", handler.HandleListArchived).Methods("GET")
+	router.HandleFunc("/api/ips/archive", handler.HandleBulkArchive).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/archive", handler.HandleArchive).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/archive", handler.HandleUnarchive).Methods("DELETE")
+	return router
+}
+
+func TestArchiveAndUnarchiveIP(t *testing.T) {
+	store := &memoryArchiveStore{archived: map[string]database.ArchivedIP{}}
+	router := newTestArchiveRouter(store)
+
+	rec := serveConfig(router, "POST", "/api/ips/192.0.2.1/archive", `{"reason":" returned to IONOS "}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ArchiveIPsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Archived) != 1 || resp.Archived[0].Reason != "returned to IONOS" || resp.Archived[0].ArchivedBy != "anonymous" {
+		t.Errorf("Unexpected archival: %+v", resp)
+	}
+
+	// Archiving again changes nothing
+	rec = serveConfig(router, "POST", "/api/ips/192.0.2.1/archive", "")
+	resp = ArchiveIPsResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || len(resp.Archived) != 0 || len(resp.AlreadyArchived) != 1 {
+		t.Errorf("Expected the IP reported as already archived, got %d %+v", rec.Code, resp)
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/archived", "")
+	var list ArchivedIPsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || list.Total != 1 || list.IPs[0].IP != "192.0.2.1" {
+		t.Errorf("Unexpected archived IPs: %+v %v", list, err)
+	}
+
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/archive", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/archive", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 unarchiving twice, got %d", rec.Code)
+	}
+}
+
+func TestBulkArchiveByCIDR(t *testing.T) {
+	store := &memoryArchiveStore{
+		known:    []string{"192.0.2.1", "192.0.2.14", "192.0.2.17", "2001:db8::1"},
+		archived: map[string]database.ArchivedIP{"192.0.2.14": {IP: "192.0.2.14"}},
+	}
+	router := newTestArchiveRouter(store)
+
+	rec := serveConfig(router, "POST", "/api/ips/archive", `{"cidr":"192.0.2.5/28","dry_run":true}`)
+	var resp ArchiveIPsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(resp.Matched) != 2 || len(store.archived) != 1 {
+		t.Fatalf("Expected a dry run matching 2 IPs, got %d %+v", rec.Code, resp)
+	}
+
+	rec = serveConfig(router, "POST", "/api/ips/archive", `{"cidr":"192.0.2.0/28","reason":"block decommissioned"}`)
+	resp = ArchiveIPsResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Archived) != 1 || resp.Archived[0].IP != "192.0.2.1" || len(resp.AlreadyArchived) != 1 || resp.AlreadyArchived[0] != "192.0.2.14" {
+		t.Errorf("Unexpected bulk archival: %+v", resp)
+	}
+	if _, ok := store.archived["192.0.2.17"]; ok {
+		t.Error("Expected the IP outside the block to stay active")
+	}
+
+	for _, body := range []string{`{"cidr":"192.0.2.1"}`, `{"cidr":""}`, `not json`} {
+		if rec := serveConfig(router, "POST", "/api/ips/archive", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// MigrateIPRequest optionally says why an IP is renumbered
+type MigrateIPRequest struct {
+	Comment string `json:"comment" example:"Moved mta-3 to the new /28"`
+}
+
+// ipMigrationStore moves an IP's history to a new address
+type ipMigrationStore interface {
+	MigrateIPHistory(from, to, author, comment string) (*database.IPMigration, error)
+}
+
+type dbIPMigrationStore struct{}
+
+func (dbIPMigrationStore) MigrateIPHistory(from, to, author, comment string) (*database.IPMigration, error) {
+	return database.MigrateIPHistory(from, to, author, comment)
+}
+
+// IPMigrationHandler carries reputation history over when sending
+// infrastructure is renumbered
+type IPMigrationHandler struct {
+	store  ipMigrationStore
+	logger *logrus.Logger
+}
+
+// NewIPMigrationHandler creates a new IP migration handler
+func NewIPMigrationHandler(logger *logrus.Logger) *IPMigrationHandler {
+	return &IPMigrationHandler{store: dbIPMigrationStore{}, logger: logger}
+}
+
+// @Summary Migrate an IP's history to a new address
+// @Description Re-key the failures, metrics, reputation history, DNSBL and reputation lookups, actions and notes of an IP that was renumbered to its new address, in one transaction, and record the migration as an action on both. Where the new address already has current metrics, windows, throttle states or send rates, it keeps its own. Pool membership, ownership, reservations and feed verdicts stay with the old address. Migrating again moves rows stored for the old address since.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param old path string true "IP address being retired"
+// @Param new path string true "IP address taking over"
+// @Param request body MigrateIPRequest false "Reason for the migration"
+// @Success 200 {object} database.IPMigration
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/ips/{old}/migrate-to/{new} [post]
+func (h *IPMigrationHandler) HandleMigrate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	from, err := validation.NormalizeIP(vars["old"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Old IP address is invalid")
+		return
+	}
+	to, err := validation.NormalizeIP(vars["new"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "New IP address is invalid")
+		return
+	}
+	if from == to {
+		writeUserError(w, http.StatusBadRequest, "same_ip", "Old and new IP addresses must differ")
+		return
+	}
+
+	var req MigrateIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if len([]rune(req.Comment)) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "invalid_comment", "comment is too long")
+		return
+	}
+
+	author := principalName(r)
+	migration, err := h.store.MigrateIPHistory(from, to, author, req.Comment)
+	if errors.Is(err, database.ErrNoIPHistory) {
+		writeUserError(w, http.StatusNotFound, "not_found", "No history stored for "+from)
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "migrate_ip_history",
+			"from":   from,
+			"to":     to,
+			"error":  err.Error(),
+		}).Error("Failed to migrate IP history")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to migrate IP history")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":     "ip_history_migrated",
+		"from":       from,
+		"to":         to,
+		"moved":      migration.Moved,
+		"superseded": migration.Superseded,
+		"author":     author,
+	}).Warn("IP history migrated to new address")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(migration)
+}
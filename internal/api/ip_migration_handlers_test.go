@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryIPMigrationStore is an in-memory ipMigrationStore over a count of
+// failures per IP
+type memoryIPMigrationStore struct {
+	failures map[string]int64
+	calls    []string
+}
+
+func (s *memoryIPMigrationStore) MigrateIPHistory(from, to, author, comment string) (*database.IPMigration, error) {
+	s.calls = append(s.calls, from+">"+to+":"+author+":"+comment)
+	n := s.failures[from]
+	if n == 0 {
+		return nil, database.ErrNoIPHistory
+	}
+	s.failures[to] += n
+	delete(s.failures, from)
+	return &database.IPMigration{From: from, To: to, Moved: map[string]int64{"smtp_failures": n}, MigratedBy: author, Comment: comment, MigratedAt: time.Now(), AuditAction: 7}, nil
+}
+
+func newTestIPMigrationRouter(store *memoryIPMigrationStore) *mux.Router {
+	logger, _ := test.NewNullLogger()
+	handler := &IPMigrationHandler{store: store, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/ips/{old}/migrate-to/{new}", handler.HandleMigrate).Methods("POST")
+	return router
+}
+
+func TestMigrateIPHistory(t *testing.T) {
+	store := &memoryIPMigrationStore{failures: map[string]int64{"2001:db8::1": 12}}
+	router := newTestIPMigrationRouter(store)
+
+	rec := serveConfig(router, "POST", "/api/admin/ips/2001:DB8:0::1/migrate-to/192.0.2.20", `{"comment":"renumbered mta-3"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var migration database.IPMigration
+	if err := json.NewDecoder(rec.Body).Decode(&migration); err != nil {
+		t.Fatal(err)
+	}
+	if migration.From != "2001:db8::1" || migration.To != "192.0.2.20" || migration.Moved["smtp_failures"] != 12 || migration.AuditAction != 7 {
+		t.Errorf("Unexpected migration: %+v", migration)
+	}
+	if store.failures["192.0.2.20"] != 12 || store.calls[0] != "2001:db8::1>192.0.2.20:anonymous:renumbered mta-3" {
+		t.Errorf("Unexpected store state %v after %v", store.failures, store.calls)
+	}
+
+	// The old address has nothing left to move; a body is optional
+	if rec := serveConfig(router, "POST", "/api/admin/ips/2001:db8::1/migrate-to/192.0.2.20", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 migrating an IP without history, got %d", rec.Code)
+	}
+}
+
+func TestMigrateIPHistoryRejectsInvalidAddresses(t *testing.T) {
+	store := &memoryIPMigrationStore{failures: map[string]int64{"192.0.2.10": 1}}
+	router := newTestIPMigrationRouter(store)
+
+	for name, target := range map[string]string{
+		"invalid old": "/api/admin/ips/mta-3/migrate-to/192.0.2.20",
+		"invalid new": "/api/admin/ips/192.0.2.10/migrate-to/192.0.2.300",
+		"same ip":     "/api/admin/ips/192.0.2.10/migrate-to/192.0.2.10",
+	} {
+		if rec := serveConfig(router, "POST", target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := serveConfig(router, "POST", "/api/admin/ips/192.0.2.10/migrate-to/192.0.2.20", `{"comment":`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed body, got %d", rec.Code)
+	}
+	if len(store.calls) != 0 {
+		t.Errorf("Expected no migration, got %v", store.calls)
+	}
+}
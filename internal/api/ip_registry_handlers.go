@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Composite registry states, most severe first
+const (
+	RegistryArchived    = "archived"
+	RegistryReleased    = "released"
+	RegistryBlacklisted = "blacklisted"
+	RegistryQuarantined = "quarantined"
+	RegistryWarning     = "warning"
+	RegistryHealthy     = "healthy"
+	RegistryUnassessed  = "unassessed"
+)
+
+// IPRegistryEntry is one IP with the state derived from all subsystems
+type IPRegistryEntry struct {
+	database.IPRegistryRow
+	State string `json:"state"`
+	// Reasons lists the findings that led to State
+	Reasons []string `json:"reasons"`
+}
+
+// IPRegistryResponse is the unified view of every known IP
+type IPRegistryResponse struct {
+	Total  int               `json:"total"`
+	States map[string]int    `json:"states"`
+	IPs    []IPRegistryEntry `json:"ips"`
+}
+
+// registryEntry derives the composite state of an IP. An archived IP was
+// decommissioned and a released IP is no longer ours; they are reported so
+// whatever their history. Otherwise the most severe finding of the
+// reservation, reputation and DNSBL records wins.
+func registryEntry(row database.IPRegistryRow) IPRegistryEntry {
+	entry := IPRegistryEntry{IPRegistryRow: row, Reasons: []string{}}
+	reservation := ""
+	if row.ReservationStatus != nil {
+		reservation = *row.ReservationStatus
+	}
+	reputationStatus := ""
+	if row.ReputationStatus != nil {
+		reputationStatus = *row.ReputationStatus
+	}
+
+	if row.ArchivedAt != nil {
+		entry.State = RegistryArchived
+		entry.Reasons = append(entry.Reasons, "archived")
+		return entry
+	}
+	if reservation == "released" {
+		entry.State = RegistryReleased
+		entry.Reasons = append(entry.Reasons, "reservation released")
+		return entry
+	}
+
+	if reputationStatus == state.Blacklisted.String() {
+		entry.Reasons = append(entry.Reasons, "reputation blacklisted")
+	}
+	if row.DNSBLListed != nil && *row.DNSBLListed {
+		entry.Reasons = append(entry.Reasons, fmt.Sprintf("listed on %s", strings.Join(row.DNSBLListings, ", ")))
+	}
+	if row.ReservedBlacklist {
+		entry.Reasons = append(entry.Reasons, "blacklisted when reserved")
+	}
+	if len(entry.Reasons) > 0 {
+		entry.State = RegistryBlacklisted
+		return entry
+	}
+
+	if reputationStatus == state.Quarantine.String() {
+		entry.Reasons = append(entry.Reasons, "reputation quarantine")
+	}
+	if reservation == "quarantined" {
+		entry.Reasons = append(entry.Reasons, "reservation quarantined")
+	}
+	switch {
+	case len(entry.Reasons) > 0:
+		entry.State = RegistryQuarantined
+	case reputationStatus == state.Warning.String():
+		entry.State = RegistryWarning
+		entry.Reasons = append(entry.Reasons, "reputation warning")
+	case reputationStatus == state.Healthy.String():
+		entry.State = RegistryHealthy
+	default:
+		entry.State = RegistryUnassessed
+		entry.Reasons = append(entry.Reasons, "no reputation metrics yet")
+	}
+	return entry
+}
+
+// matchRegistryEntry derives the entry of a row and reports whether it
+// matches the state and pool filters, which are ignored when empty
+func matchRegistryEntry(row database.IPRegistryRow, stateFilter, pool string) (IPRegistryEntry, bool) {
+	entry := registryEntry(row)
+	if stateFilter != "" && entry.State != stateFilter {
+		return entry, false
+	}
+	if pool != "" && !containsString(row.Pools, pool) {
+		return entry, false
+	}
+	return entry, true
+}
+
+// buildIPRegistry derives the entries and keeps those matching the state
+// and pool filters; empty filters match all
+func buildIPRegistry(rows []database.IPRegistryRow, stateFilter, pool string) IPRegistryResponse {
+	response := IPRegistryResponse{States: map[string]int{}, IPs: []IPRegistryEntry{}}
+	for _, row := range rows {
+		entry, ok := matchRegistryEntry(row, stateFilter, pool)
+		if !ok {
+			continue
+		}
+		response.States[entry.State]++
+		response.IPs = append(response.IPs, entry)
+	}
+	response.Total = len(response.IPs)
+	return response
+}
+
+// withoutArchivedRows drops the rows of archived IPs
+func withoutArchivedRows(rows []database.IPRegistryRow) []database.IPRegistryRow {
+	kept := rows[:0]
+	for _, row := range rows {
+		if row.ArchivedAt == nil {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// @Summary Unified IP registry
+// @Description One row per IP joining its reservation, reputation metrics, latest DNSBL check, pools, owners and on-call context (notes and runbook URL), with a composite state
+// @Tags ip-reputation
+// @Produce json
+// @Param state query string false "Filter by composite state (archived, released, blacklisted, quarantined, warning, healthy, unassessed)"
+// @Param pool query string false "Only IPs in this pool"
+// @Param include_archived query bool false "Include archived IPs; implied by state=archived"
+// @Success 200 {object} IPRegistryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/registry [get]
+func (h *ReputationHandler) getIPRegistryHandler(w http.ResponseWriter, r *http.Request) {
+	stateFilter := r.URL.Query().Get("state")
+	switch stateFilter {
+	case "", RegistryArchived, RegistryReleased, RegistryBlacklisted, RegistryQuarantined, RegistryWarning, RegistryHealthy, RegistryUnassessed:
+	default:
+		writeUserError(w, http.StatusBadRequest, "invalid_state", "state must be archived, released, blacklisted, quarantined, warning, healthy or unassessed")
+		return
+	}
+
+	// Entries are streamed as they are read; the counts follow them
+	hideArchived := !includeArchived(r) && stateFilter != RegistryArchived
+	pool := r.URL.Query().Get("pool")
+	states := map[string]int{}
+	total := 0
+	stream := newJSONArrayStream(w, `{"ips":`)
+	w.Header().Set("Content-Type", "application/json")
+	err := database.EachIPRegistryRow(r.Context(), func(row database.IPRegistryRow) error {
+		if hideArchived && row.ArchivedAt != nil {
+			return nil
+		}
+		entry, ok := matchRegistryEntry(row, stateFilter, pool)
+		if !ok {
+			return nil
+		}
+		states[entry.State]++
+		total++
+		return stream.Write(entry)
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_ip_registry_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get IP registry")
+		writeStreamError(w, stream, "Failed to retrieve IP registry")
+		return
+	}
+
+	stream.Close()
+	counts, _ := json.Marshal(states)
+	fmt.Fprintf(w, `,"total":%d,"states":%s}`, total, counts)
+}
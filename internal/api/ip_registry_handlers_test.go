@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestRegistryEntryCompositeState(t *testing.T) {
+	str := func(s string) *string { return &s }
+	listed := true
+	archivedAt := time.Now()
+
+	tests := []struct {
+		name string
+		row  database.IPRegistryRow
+		want string
+	}{
+		{"archived wins", database.IPRegistryRow{ArchivedAt: &archivedAt, ReservationStatus: str("released"), ReputationStatus: str("blacklisted")}, RegistryArchived},
+		{"released wins", database.IPRegistryRow{ReservationStatus: str("released"), ReputationStatus: str("blacklisted")}, RegistryReleased},
+		{"dnsbl listing", database.IPRegistryRow{ReputationStatus: str("healthy"), DNSBLListed: &listed, DNSBLListings: []string{"zen.spamhaus.org"}}, RegistryBlacklisted},
+		{"reservation quarantined", database.IPRegistryRow{ReservationStatus: str("quarantined"), ReputationStatus: str("healthy")}, RegistryQuarantined},
+		{"reputation warning", database.IPRegistryRow{ReservationStatus: str("in_use"), ReputationStatus: str("warning")}, RegistryWarning},
+		{"healthy", database.IPRegistryRow{ReputationStatus: str("healthy")}, RegistryHealthy},
+		{"reserved only", database.IPRegistryRow{ReservationStatus: str("reserved")}, RegistryUnassessed},
+	}
+	for _, tt := range tests {
+		if got := registryEntry(tt.row); got.State != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.name, tt.want, got.State, got.Reasons)
+		}
+	}
+
+	entry := registryEntry(tests[2].row)
+	if len(entry.Reasons) != 1 || entry.Reasons[0] != "listed on zen.spamhaus.org" {
+		t.Errorf("Expected the listing as reason, got %v", entry.Reasons)
+	}
+}
+
+func TestBuildIPRegistryFilters(t *testing.T) {
+	healthy := "healthy"
+	rows := []database.IPRegistryRow{
+		{IP: "192.0.2.1", ReputationStatus: &healthy, Pools: []string{"marketing"}},
+		{IP: "192.0.2.2", ReputationStatus: &healthy},
+		{IP: "192.0.2.3", Pools: []string{"marketing"}},
+	}
+
+	all := buildIPRegistry(rows, "", "")
+	if all.Total != 3 || all.States[RegistryHealthy] != 2 || all.States[RegistryUnassessed] != 1 {
+		t.Errorf("Unexpected registry: %+v", all)
+	}
+
+	filtered := buildIPRegistry(rows, RegistryHealthy, "marketing")
+	if filtered.Total != 1 || filtered.IPs[0].IP != "192.0.2.1" {
+		t.Errorf("Expected only the healthy marketing IP, got %+v", filtered)
+	}
+}
+
+func TestWithoutArchivedRows(t *testing.T) {
+	archivedAt := time.Now()
+	rows := withoutArchivedRows([]database.IPRegistryRow{
+		{IP: "192.0.2.1"},
+		{IP: "192.0.2.2", ArchivedAt: &archivedAt},
+		{IP: "192.0.2.3"},
+	})
+	if len(rows) != 2 || rows[0].IP != "192.0.2.1" || rows[1].IP != "192.0.2.3" {
+		t.Errorf("Expected the archived IP dropped, got %+v", rows)
+	}
+}
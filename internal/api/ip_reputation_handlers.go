@@ -2,14 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"golang-backend-service/internal/database"
-	"golang-backend-service/internal/logger"
+	"golang-backend-service/internal/redact"
 	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/reputation/state"
+	"golang-backend-service/internal/tlspolicy"
+	"golang-backend-service/internal/validation"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -33,11 +37,20 @@ type EventData struct {
 	Reason        string `json:"reason"`
 	MX            string `json:"mx"`
 	AttemptNumber int    `json:"attempt_number"`
+	Instance      string `json:"instance"`
+	MessageID     string `json:"message_id"`
+	Action        string `json:"action,omitempty"`      // DSN action: failed, delayed, delivered, relayed, expanded
+	RemoteHost    string `json:"remote_host,omitempty"` // Host an outgoing authentication was attempted against
+	Mechanism     string `json:"mechanism,omitempty"`
+	Username      string `json:"username,omitempty"`
 }
 
-// WebhookPayload represents the complete webhook payload from Stalwart
+// WebhookPayload represents the complete webhook payload from Stalwart.
+// Version selects the schema of the events; events may override it with
+// their own version, and unversioned events are detected by their keys.
 type WebhookPayload struct {
-	Events []WebhookEvent `json:"events"`
+	Version string         `json:"version,omitempty" example:"1"`
+	Events  []WebhookEvent `json:"events"`
 }
 
 // IPReputationResponse represents the API response for IP reputation
@@ -47,8 +60,60 @@ type IPReputationResponse struct {
 	Metrics         *database.IPReputationMetrics `json:"metrics"`
 	LatestDNSBL     *database.DNSBLCheck          `json:"latest_dnsbl_check"`
 	RecentActions   []database.IPAction           `json:"recent_actions"`
-	Summary         string                        `json:"summary"`
+	Instances       []database.InstanceRejections `json:"instances"`
+	Windows         []database.IPWindowMetrics    `json:"windows"` // longer evaluation windows from the last run
+	Notes           []database.IPNote             `json:"notes"`
+	TLSFailures     []database.TLSFailureSummary  `json:"tls_failures"` // failed TLS negotiations from recent TLS reports
+	Summary         string                        `json:"summary"`      // in the negotiated language
 	Recommendations []string                      `json:"recommendations"`
+	// RecommendedActions are the recommendations with their texts and runbook
+	// links from the recommendation catalog
+	RecommendedActions []reputation.Recommendation `json:"recommended_actions"`
+}
+
+// WebhookIngestResponse summarizes a processed webhook payload. Results are
+// only included when the request asked for ?detailed=true.
+type WebhookIngestResponse struct {
+	Status    string               `json:"status"`
+	Processed int                  `json:"processed"`
+	Failed    int                  `json:"failed"`
+	Total     int                  `json:"total"`
+	Results   []WebhookEventResult `json:"results,omitempty"`
+}
+
+// IPReputationExplanationResponse shows which decision rules fired for an IP
+type IPReputationExplanationResponse struct {
+	IP string `json:"ip"`
+	// StoredStatus is the status from the last aggregation run; the
+	// explanation re-evaluates the rules over the current window
+	StoredStatus string                 `json:"stored_status"`
+	StoredAt     time.Time              `json:"stored_at"`
+	Instance     string                 `json:"instance,omitempty"`
+	Explanation  reputation.Explanation `json:"explanation"`
+	// Windows are the longer evaluation windows from the last aggregation
+	// run; the most severe of their statuses and the explanation's applies
+	Windows []database.IPWindowMetrics `json:"windows"`
+	// ExternalLookups are the latest results of external sender reputation
+	// sources (e.g. SenderScore), looked up when the IP was quarantined
+	ExternalLookups []database.ReputationLookup `json:"external_lookups"`
+}
+
+// ReputationSimulationRequest is a what-if input for the decision engine.
+// Config fields that are left out keep their active values.
+type ReputationSimulationRequest struct {
+	Health reputation.IPHealthCheck `json:"health"`
+	Config json.RawMessage          `json:"config" swaggertype:"object"`
+}
+
+// ReputationSimulationResponse compares the candidate config with the
+// active config for the same metrics
+type ReputationSimulationResponse struct {
+	Status         string                 `json:"status"`
+	DecidingRule   string                 `json:"deciding_rule"`
+	BaselineStatus string                 `json:"baseline_status"`
+	BaselineRule   string                 `json:"baseline_rule"`
+	StatusChanged  bool                   `json:"status_changed"`
+	Explanation    reputation.Explanation `json:"explanation"`
 }
 
 // IPHealthDashboardResponse represents dashboard data
@@ -60,6 +125,8 @@ type IPHealthDashboardResponse struct {
 	QuarantineIPs  int                            `json:"quarantine_ips"`
 	BlacklistedIPs int                            `json:"blacklisted_ips"`
 	IPDetails      []database.IPReputationMetrics `json:"ip_details"`
+	// Context is the on-call notes and runbook URL of the IPs that have one
+	Context map[string]database.IPContext `json:"context"`
 }
 
 // FailureSimulation represents a simulated SMTP failure for testing
@@ -77,20 +144,80 @@ type FailureSimulationPayload struct {
 	Failures  []FailureSimulation `json:"failures"`
 }
 
-// @Summary Process Stalwart delivery failure webhook
-// @Description Receive and process SMTP delivery failure events from Stalwart
+// ingestStore stores the failures and deliveries decoded from webhook events
+type ingestStore interface {
+	StoreSMTPFailure(failure *database.SMTPFailure) (string, error)
+	InsertSMTPAuthFailure(failure *database.SMTPAuthFailure) error
+	RecordDeliverySuccess(success *database.DeliverySuccess) (bool, error)
+	ResolveGreylisting(success *database.DeliverySuccess) (int, error)
+}
+
+type dbIngestStore struct{}
+
+func (dbIngestStore) StoreSMTPFailure(failure *database.SMTPFailure) (string, error) {
+	return database.StoreSMTPFailure(failure)
+}
+
+func (dbIngestStore) InsertSMTPAuthFailure(failure *database.SMTPAuthFailure) error {
+	return database.InsertSMTPAuthFailure(failure)
+}
+
+func (dbIngestStore) RecordDeliverySuccess(success *database.DeliverySuccess) (bool, error) {
+	return database.RecordDeliverySuccess(success)
+}
+
+func (dbIngestStore) ResolveGreylisting(success *database.DeliverySuccess) (int, error) {
+	return reputation.ResolveGreylisting(*success)
+}
+
+// ReputationHandler handles IP reputation, DNSBL and testing endpoints
+type ReputationHandler struct {
+	aggregator  *reputation.AggregationService
+	dnsbl       *reputation.DNSBLService
+	tlsPolicies *tlspolicy.Cache
+	redactor    *redact.Redactor
+	store       ingestStore
+	recommender *reputation.RecommendationCatalog
+	logger      *logrus.Logger
+}
+
+// NewReputationHandler creates a new IP reputation handler. A nil policy
+// cache leaves failures without the destination's TLS policy.
+func NewReputationHandler(aggregator *reputation.AggregationService, dnsbl *reputation.DNSBLService, tlsPolicies *tlspolicy.Cache, redactor *redact.Redactor, logger *logrus.Logger) *ReputationHandler {
+	return &ReputationHandler{
+		aggregator:  aggregator,
+		dnsbl:       dnsbl,
+		tlsPolicies: tlsPolicies,
+		redactor:    redactor,
+		store:       dbIngestStore{},
+		recommender: reputation.NewRecommendationCatalog(nil),
+		logger:      logger,
+	}
+}
+
+// @Summary Process Stalwart webhook events
+// @Description Receive SMTP delivery failure, bounce, DSN and outgoing auth-failure events from Stalwart, and delivery successes, which count the IP's sent volume and turn the greylist-style deferrals they follow into greylisting. Other event types are skipped. Responds 429 or 503 with Retry-After while ingestion is overloaded, and 503 when an event could not be stored, so Stalwart retries the payload.
 // @Tags webhooks
 // @Accept json
 // @Produce json
 // @Param payload body WebhookPayload true "Webhook payload"
-// @Success 200 {object} map[string]interface{}
+// @Param Authorization header string false "Bearer or Basic credentials carrying the webhook source token"
+// @Param X-Signature-Timestamp header int false "Unix time the payload was signed at; required with a signing secret"
+// @Param X-Signature-256 header string false "sha256=<hex HMAC-SHA256 of <timestamp>.<body>>; required with a signing secret"
+// @Param detailed query bool false "Include a result per event; responds 207 when any event failed"
+// @Success 200 {object} WebhookIngestResponse
+// @Success 207 {object} WebhookIngestResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} WebhookIngestResponse
 // @Router /api/webhooks/stalwart/delivery-failure [post]
-func processDeliveryFailureHandler(w http.ResponseWriter, r *http.Request) {
-	var payload WebhookPayload
+// @Router /api/webhooks/stalwart/events [post]
+func (h *ReputationHandler) processDeliveryFailureHandler(w http.ResponseWriter, r *http.Request) {
+	var payload rawWebhookPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "webhook_decode_failed",
 			"error":  err.Error(),
 		}).Error("Failed to decode webhook payload")
@@ -103,76 +230,48 @@ func processDeliveryFailureHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	processedCount := 0
-	failedCount := 0
+	source := webhookSourceFromContext(r.Context())
+	sourceName := "unauthenticated"
+	if source != nil {
+		sourceName = source.Name
+	}
 
-	for _, event := range payload.Events {
-		// Only process delivery failure events
-		if event.Type != "smtp.delivery.failure" {
-			continue
-		}
+	result := h.ingestEvents(payload, source, sourceName)
 
-		// Extract domain from recipient email
-		domain := database.ExtractDomain(event.Data.Recipient)
-
-		// Create SMTP failure record
-		failure := &database.SMTPFailure{
-			SendingIP:       event.Data.IP,
-			RecipientEmail:  event.Data.Recipient,
-			RecipientDomain: domain,
-			SMTPCode:        event.Data.SMTPCode,
-			EnhancedCode:    event.Data.EnhancedCode,
-			Reason:          event.Data.Reason,
-			MXServer:        event.Data.MX,
-			Timestamp:       time.Now(),
-			EventID:         event.ID,
-			AttemptNumber:   event.Data.AttemptNumber,
-		}
-
-		// Parse timestamp if provided
-		if event.CreatedAt != "" {
-			if parsed, err := time.Parse(time.RFC3339, event.CreatedAt); err == nil {
-				failure.Timestamp = parsed
-			}
+	if source != nil {
+		if err := database.RecordWebhookSourceIngestion(source.ID, result.Processed, result.Failed); err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action":    "record_source_ingestion_failed",
+				"source_id": source.ID,
+				"error":     err.Error(),
+			}).Warn("Failed to update webhook source counters")
 		}
+	}
 
-		// Insert failure record
-		if err := database.InsertSMTPFailure(failure); err != nil {
-			logger.WithFields(logrus.Fields{
-				"action":   "insert_failure_failed",
-				"event_id": event.ID,
-				"ip":       event.Data.IP,
-				"error":    err.Error(),
-			}).Error("Failed to insert SMTP failure")
-			reputation.RecordWebhookEvent(event.Type, "failed")
-			failedCount++
-			continue
+	response := WebhookIngestResponse{
+		Status:    "success",
+		Processed: result.Processed,
+		Failed:    result.Failed,
+		Total:     len(payload.Events),
+	}
+	status := http.StatusOK
+	if r.URL.Query().Get("detailed") == "true" {
+		response.Results = result.Events
+		if result.Failed > 0 {
+			response.Status = "partial"
+			status = http.StatusMultiStatus
 		}
-
-		// Record metrics
-		reputation.RecordSMTPFailure(event.Data.IP, event.Data.EnhancedCode, domain)
-		reputation.RecordWebhookEvent(event.Type, "success")
-
-		processedCount++
-
-		logger.WithFields(logrus.Fields{
-			"action":        "smtp_failure_recorded",
-			"event_id":      event.ID,
-			"ip":            event.Data.IP,
-			"recipient":     event.Data.Recipient,
-			"smtp_code":     event.Data.SMTPCode,
-			"enhanced_code": event.Data.EnhancedCode,
-		}).Info("SMTP failure recorded")
+	}
+	if result.Retry {
+		// Ask for the whole payload again; stored events are skipped by
+		// their event ID on redelivery
+		response.Status = "retry"
+		status = http.StatusServiceUnavailable
 	}
 
-	// Return success response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "success",
-		"processed": processedCount,
-		"failed":    failedCount,
-		"total":     len(payload.Events),
-	})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
 
 // @Summary Get IP reputation
@@ -180,11 +279,17 @@ func processDeliveryFailureHandler(w http.ResponseWriter, r *http.Request) {
 // @Tags ip-reputation
 // @Produce json
 // @Param ip path string true "IP Address"
+// @Param instance query string false "Scope the health summary to one MTA instance"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
+// @Param Accept-Language header string false "Language of the summary and recommendation texts, e.g. de-CH, fr;q=0.8"
 // @Success 200 {object} IPReputationResponse
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/ips/{ip}/reputation [get]
-func getIPReputationHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) getIPReputationHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ip := vars["ip"]
 
@@ -200,7 +305,7 @@ func getIPReputationHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "get_reputation_failed",
 			"ip":     ip,
 			"error":  err.Error(),
@@ -217,38 +322,245 @@ func getIPReputationHandler(w http.ResponseWriter, r *http.Request) {
 	// Get latest DNSBL check (optional)
 	latestDNSBL, _ := database.GetLatestDNSBLCheck(ip)
 
-	// Get recent actions
+	// Get recent actions with their operator comments
 	recentActions, _ := database.GetIPActions(ip, 10)
+	actionIDs := make([]int, len(recentActions))
+	for i, action := range recentActions {
+		actionIDs[i] = action.ID
+	}
+	if comments, err := database.ListIPActionComments(actionIDs); err == nil {
+		for i := range recentActions {
+			recentActions[i].Comments = comments[recentActions[i].ID]
+		}
+	}
 
-	// Calculate health for summary
-	config := reputation.DefaultReputationConfig()
-	health, _ := reputation.CalculateIPHealthCheck(ip, config.WindowMinutes, metrics.TotalSent)
+	notes, err := database.ListIPNotes(ip)
+	if err != nil {
+		notes = []database.IPNote{}
+	}
+
+	// Calculate health for summary, optionally scoped to one MTA instance
+	config := h.aggregator.Config()
+	instance := r.URL.Query().Get("instance")
+	health, _ := reputation.CalculateIPHealthCheckForInstance(ip, instance, config.WindowMinutes, metrics.TotalSent, config.DecayHalfLifeMinutes)
 
+	// Break the window down by MTA instance
+	windowStart := time.Now().Add(-time.Duration(config.WindowMinutes) * time.Minute)
+	instances, _ := database.GetInstanceRejections(ip, windowStart)
+
+	windows, err := database.GetIPWindowMetrics(ip)
+	if err != nil {
+		windows = []database.IPWindowMetrics{}
+	}
+
+	tlsFailures, err := database.GetTLSFailureSummary(ip, time.Now().Add(-reputation.TLSReportWindow))
+	if err != nil {
+		tlsFailures = []database.TLSFailureSummary{}
+	}
+
+	language := setContentLanguage(w, r)
+	recommended := h.recommend(language, metrics.Status, *health)
 	response := IPReputationResponse{
-		IP:              ip,
-		Status:          metrics.Status,
-		Metrics:         metrics,
-		LatestDNSBL:     latestDNSBL,
-		RecentActions:   recentActions,
-		Summary:         reputation.GetStatusSummary(metrics.Status, *health),
-		Recommendations: reputation.GetRecommendedActions(metrics.Status),
+		IP:                 ip,
+		Status:             metrics.Status,
+		Metrics:            metrics,
+		LatestDNSBL:        latestDNSBL,
+		RecentActions:      recentActions,
+		Instances:          instances,
+		Windows:            windows,
+		Notes:              notes,
+		TLSFailures:        tlsFailures,
+		Summary:            reputation.GetLocalizedStatusSummary(language, metrics.Status, *health),
+		Recommendations:    reputation.RecommendedActionCodes(recommended),
+		RecommendedActions: recommended,
+	}
+
+	// Let polling clients revalidate instead of re-downloading
+	lastModified := metrics.LastUpdated
+	if latestDNSBL != nil {
+		lastModified = latestUpdate(lastModified, latestDNSBL.CheckedAt)
+	}
+	for _, action := range recentActions {
+		lastModified = latestUpdate(lastModified, action.CreatedAt)
+		for _, comment := range action.Comments {
+			lastModified = latestUpdate(lastModified, comment.CreatedAt)
+		}
+	}
+	for _, note := range notes {
+		lastModified = latestUpdate(lastModified, note.CreatedAt)
+	}
+	etag, err := computeETag(response)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to compute reputation ETag")
+	}
+	if writeNotModified(w, r, etag, lastModified) {
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// @Summary Explain IP status
+// @Description Show which decision rules fired for an IP: thresholds against observed values, the enhanced codes that count as reputation related and rejections per major provider, with the latest results of external reputation sources such as SenderScore
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param instance query string false "Evaluate only the failures of one MTA instance"
+// @Success 200 {object} IPReputationExplanationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/reputation/explain [get]
+func (h *ReputationHandler) explainIPReputationHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	metrics, err := database.GetIPReputationMetrics(ip)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "not_found",
+				Message: "No reputation data found for this IP",
+			})
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"action": "explain_reputation_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get IP reputation")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to retrieve IP reputation",
+		})
+		return
+	}
+
+	config := h.aggregator.Config()
+	instance := r.URL.Query().Get("instance")
+	health, err := reputation.CalculateIPHealthCheckForInstance(ip, instance, config.WindowMinutes, metrics.TotalSent, config.DecayHalfLifeMinutes)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "explain_reputation_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to calculate IP health")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to evaluate IP reputation",
+		})
+		return
+	}
+	if err := h.aggregator.AddReportedSignals(health, config, time.Now()); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "explain_reputation_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to count reported signals")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to evaluate IP reputation",
+		})
+		return
+	}
+
+	windows, err := database.GetIPWindowMetrics(ip)
+	if err != nil {
+		windows = []database.IPWindowMetrics{}
+	}
+	lookups, err := database.GetLatestReputationLookups(ip)
+	if err != nil {
+		lookups = []database.ReputationLookup{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(IPReputationExplanationResponse{
+		IP:              ip,
+		StoredStatus:    metrics.Status,
+		StoredAt:        metrics.LastUpdated,
+		Instance:        instance,
+		Explanation:     reputation.ExplainIPStatus(*health, config),
+		Windows:         windows,
+		ExternalLookups: lookups,
+	})
+}
+
+// @Summary Simulate a reputation decision
+// @Description Run the decision engine on a hand-written health check and candidate thresholds. Nothing is read from or written to the database.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param simulation body ReputationSimulationRequest true "Health check and candidate config"
+// @Success 200 {object} ReputationSimulationResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/reputation/simulate [post]
+func (h *ReputationHandler) simulateReputationHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReputationSimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid simulation payload",
+		})
+		return
+	}
+
+	baseline := h.aggregator.Config()
+	config := baseline
+	if len(req.Config) > 0 {
+		if err := json.Unmarshal(req.Config, &config); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "invalid_config",
+				Message: "Invalid candidate config",
+			})
+			return
+		}
+	}
+	if err := config.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_config",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	health := req.Health.WithDerivedFields()
+	explanation := reputation.ExplainIPStatus(health, config)
+	base := reputation.ExplainIPStatus(health, baseline)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReputationSimulationResponse{
+		Status:         explanation.Status,
+		DecidingRule:   explanation.DecidingRule,
+		BaselineStatus: base.Status,
+		BaselineRule:   base.DecidingRule,
+		StatusChanged:  explanation.Status != base.Status,
+		Explanation:    explanation,
+	})
+}
+
 // @Summary Get SMTP failures for IP
 // @Description Retrieve SMTP failures for a specific IP within a time window
 // @Tags ip-reputation
 // @Produce json
 // @Param ip path string true "IP Address"
 // @Param window query string false "Time window (e.g., 15m, 1h, 24h)" default(15m)
+// @Param instance query string false "Only failures reported by this MTA instance"
 // @Success 200 {array} database.SMTPFailure
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/ips/{ip}/failures [get]
-func getIPFailuresHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) getIPFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ip := vars["ip"]
 
@@ -270,25 +582,160 @@ func getIPFailuresHandler(w http.ResponseWriter, r *http.Request) {
 
 	since := time.Now().Add(-duration)
 
-	// Get failures
-	failures, err := database.GetSMTPFailuresByIP(ip, since)
+	// Stream failures, optionally for a single MTA instance, as they are read
+	instance := r.URL.Query().Get("instance")
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONArrayStream(w, "")
+	err = database.EachSMTPFailureByIPAndInstance(r.Context(), ip, instance, since, func(f database.SMTPFailure) error {
+		return stream.Write(h.redactor.SMTPFailure(f))
+	})
 	if err != nil {
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "get_failures_failed",
 			"ip":     ip,
 			"error":  err.Error(),
 		}).Error("Failed to get SMTP failures")
+		writeStreamError(w, stream, "Failed to retrieve SMTP failures")
+		return
+	}
+	stream.Close()
+}
+
+// @Summary Get reputation history for IP
+// @Description Retrieve the outcome of every aggregation run for an IP within a time window, newest first
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 1h, 24h, 720h)" default(24h)
+// @Success 200 {array} database.IPHistoryRecord
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/history [get]
+func (h *ReputationHandler) getIPHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "24h"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 1h, 24h, 720h, etc.)")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONArrayStream(w, "")
+	err = database.EachIPHistoryRecord(r.Context(), ip, time.Now().Add(-duration), func(record database.IPHistoryRecord) error {
+		return stream.Write(record)
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_ip_history_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get IP reputation history")
+		writeStreamError(w, stream, "Failed to retrieve IP reputation history")
+		return
+	}
+	stream.Close()
+}
+
+// @Summary Get outgoing SMTP auth failures for IP
+// @Description Retrieve failed outgoing SMTP authentications for a specific IP within a time window
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 15m, 1h, 24h)" default(15m)
+// @Param instance query string false "Only failures reported by this MTA instance"
+// @Success 200 {array} database.SMTPAuthFailure
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/auth-failures [get]
+func (h *ReputationHandler) getIPAuthFailuresHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "15m"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_window",
+			Message: "Invalid time window format (use 15m, 1h, 24h, etc.)",
+		})
+		return
+	}
+
+	instance := r.URL.Query().Get("instance")
+	failures, err := database.GetSMTPAuthFailuresByIP(ip, instance, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_auth_failures_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get SMTP auth failures")
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{
 			Error:   "database_error",
-			Message: "Failed to retrieve SMTP failures",
+			Message: "Failed to retrieve SMTP auth failures",
 		})
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(failures)
+	json.NewEncoder(w).Encode(h.redactor.SMTPAuthFailures(failures))
+}
+
+// @Summary Get rejections per MTA instance
+// @Description Break an IP's SMTP failures down by the MTA instance that reported them
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 15m, 1h, 24h)" default(1h)
+// @Success 200 {array} database.InstanceRejections
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/instances [get]
+func (h *ReputationHandler) getIPInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "1h"
+	}
+
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_window",
+			Message: "Invalid time window format (use 15m, 1h, 24h, etc.)",
+		})
+		return
+	}
+
+	instances, err := database.GetInstanceRejections(ip, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_instances_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get instance rejections")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to retrieve instance rejections",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instances)
 }
 
 // @Summary Manually quarantine IP
@@ -297,17 +744,18 @@ func getIPFailuresHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param ip path string true "IP Address"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/ips/{ip}/quarantine [post]
-func quarantineIPHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) quarantineIPHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ip := vars["ip"]
 
 	// Trigger on-demand aggregation with quarantine override
-	config := reputation.DefaultReputationConfig()
-	metrics, err := reputation.AggregateIPOnDemand(ip, config)
+	metrics, err := h.aggregator.AggregateIPOnDemand(ip)
 	if err != nil {
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "quarantine_failed",
 			"ip":     ip,
 			"error":  err.Error(),
@@ -322,8 +770,26 @@ func quarantineIPHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Force quarantine status
-	metrics.Status = "quarantine"
-	if err := database.UpsertIPReputationMetrics(metrics); err != nil {
+	previousStatus := metrics.Status
+	transition := state.Transition{
+		IP:          ip,
+		From:        state.Status(previousStatus),
+		To:          state.Quarantine,
+		TriggeredBy: "manual",
+	}
+	if err := state.Default.Apply(transition, func() error {
+		metrics.Status = state.Quarantine.String()
+		return database.UpsertIPReputationMetrics(metrics)
+	}); err != nil {
+		if errors.Is(err, state.ErrInvalidTransition) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "invalid_transition",
+				Message: err.Error(),
+			})
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{
 			Error:   "database_error",
@@ -334,18 +800,19 @@ func quarantineIPHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Record manual action
 	action := &database.IPAction{
-		IP:          ip,
-		Action:      "manual_quarantine",
-		NewStatus:   "quarantine",
-		Reason:      "Manually quarantined via API",
-		TriggeredBy: "manual",
-		Metadata:    make(map[string]interface{}),
-		CreatedAt:   time.Now(),
+		IP:             ip,
+		Action:         "manual_quarantine",
+		PreviousStatus: previousStatus,
+		NewStatus:      state.Quarantine.String(),
+		Reason:         "Manually quarantined via API",
+		TriggeredBy:    "manual",
+		Metadata:       make(map[string]interface{}),
+		CreatedAt:      time.Now(),
 	}
 	database.InsertIPAction(action)
 
 	// Trigger DNSBL check
-	go reputation.CheckDNSBL(ip, 5)
+	go h.dnsbl.CheckDNSBL(ip, 5)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -361,16 +828,17 @@ func quarantineIPHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param ip path string true "IP Address"
 // @Success 200 {object} reputation.DNSBLResult
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/ips/{ip}/dnsbl-check [post]
-func checkDNSBLHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) checkDNSBLHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ip := vars["ip"]
 
 	// Run DNSBL check
-	result, err := reputation.CheckDNSBL(ip, 5)
+	result, err := h.dnsbl.CheckDNSBLContext(r.Context(), ip, 5)
 	if err != nil {
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "dnsbl_check_failed",
 			"ip":     ip,
 			"error":  err.Error(),
@@ -393,16 +861,24 @@ func checkDNSBLHandler(w http.ResponseWriter, r *http.Request) {
 // @Tags ip-reputation
 // @Produce json
 // @Param status query string false "Filter by status (healthy, warning, quarantine, blacklisted)"
+// @Param owner query string false "Only IPs owned by me, user:<id> or team:<id>"
+// @Param include_archived query bool false "Include archived IPs"
+// @Param include_excluded query bool false "Include IPs in aggregation exclusions"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
 // @Success 200 {object} IPHealthDashboardResponse
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/dashboard/ip-health [get]
-func getIPHealthDashboardHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) getIPHealthDashboardHandler(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
+	owner := r.URL.Query().Get("owner")
 
 	// Get all IP metrics (optionally filtered by status)
 	allMetrics, err := database.GetAllIPReputationMetrics(status)
 	if err != nil {
-		logger.WithFields(logrus.Fields{
+		h.logger.WithFields(logrus.Fields{
 			"action": "get_dashboard_failed",
 			"error":  err.Error(),
 		}).Error("Failed to get dashboard data")
@@ -415,12 +891,94 @@ func getIPHealthDashboardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if owner != "" {
+		ownerType, ownerID, err := parseOwnerRef(r, owner)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "invalid_owner",
+				Message: err.Error(),
+			})
+			return
+		}
+		ownedIPs, err := database.GetOwnedIPs(ownerType, ownerID)
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "get_dashboard_failed",
+				"error":  err.Error(),
+			}).Error("Failed to get owned IPs")
+
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "database_error",
+				Message: "Failed to retrieve dashboard data",
+			})
+			return
+		}
+		allMetrics = filterMetricsByIP(allMetrics, ownedIPs)
+	}
+
+	hidden, err := dashboardHiddenIPs(r)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_dashboard_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get hidden IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve dashboard data")
+		return
+	}
+	if !hidden.empty() {
+		kept := allMetrics[:0]
+		for _, m := range allMetrics {
+			if !hidden.hides(m.IP) {
+				kept = append(kept, m)
+			}
+		}
+		allMetrics = kept
+	}
+
+	allContexts, err := database.ListIPContexts()
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_dashboard_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get IP contexts")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve dashboard data")
+		return
+	}
+	contexts := make(map[string]database.IPContext)
+	for _, m := range allMetrics {
+		if c, ok := allContexts[m.IP]; ok {
+			contexts[m.IP] = c
+		}
+	}
+
+	// The counts and timestamp are derived from the metrics and contexts, so
+	// they alone identify the representation
+	var lastModified time.Time
+	for _, m := range allMetrics {
+		lastModified = latestUpdate(lastModified, m.LastUpdated)
+	}
+	for _, c := range contexts {
+		lastModified = latestUpdate(lastModified, c.UpdatedAt)
+	}
+	etag, err := computeETag(struct {
+		Status   string
+		Owner    string
+		Metrics  []database.IPReputationMetrics
+		Contexts map[string]database.IPContext
+	}{status, owner, allMetrics, contexts})
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to compute dashboard ETag")
+	}
+	if writeNotModified(w, r, etag, lastModified) {
+		return
+	}
+
 	// Count by status
-	statusCounts := map[string]int{
-		"healthy":     0,
-		"warning":     0,
-		"quarantine":  0,
-		"blacklisted": 0,
+	statusCounts := make(map[string]int)
+	for _, s := range state.All() {
+		statusCounts[s.String()] = 0
 	}
 
 	for _, m := range allMetrics {
@@ -430,17 +988,33 @@ func getIPHealthDashboardHandler(w http.ResponseWriter, r *http.Request) {
 	response := IPHealthDashboardResponse{
 		Timestamp:      time.Now(),
 		TotalIPs:       len(allMetrics),
-		HealthyIPs:     statusCounts["healthy"],
-		WarningIPs:     statusCounts["warning"],
-		QuarantineIPs:  statusCounts["quarantine"],
-		BlacklistedIPs: statusCounts["blacklisted"],
+		HealthyIPs:     statusCounts[state.Healthy.String()],
+		WarningIPs:     statusCounts[state.Warning.String()],
+		QuarantineIPs:  statusCounts[state.Quarantine.String()],
+		BlacklistedIPs: statusCounts[state.Blacklisted.String()],
 		IPDetails:      allMetrics,
+		Context:        contexts,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// filterMetricsByIP keeps the metrics of the given IPs
+func filterMetricsByIP(metrics []database.IPReputationMetrics, ips []string) []database.IPReputationMetrics {
+	keep := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		keep[ip] = true
+	}
+	filtered := make([]database.IPReputationMetrics, 0, len(metrics))
+	for _, m := range metrics {
+		if keep[m.IP] {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
 // @Summary Simulate SMTP failures for testing
 // @Description Simulate SMTP failures for testing the reputation system
 // @Tags testing
@@ -451,7 +1025,7 @@ func getIPHealthDashboardHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/testing/simulate-failures [post]
-func simulateFailuresHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) simulateFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	var testData FailureSimulationPayload
 	if err := json.NewDecoder(r.Body).Decode(&testData); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -472,6 +1046,17 @@ func simulateFailuresHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	normalizedIP, err := validation.NormalizeIP(testData.IP)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_ip",
+			Message: err.Error(),
+		})
+		return
+	}
+	testData.IP = normalizedIP
+
 	// Insert test failures
 	insertedCount := 0
 	for _, failure := range testData.Failures {
@@ -497,8 +1082,8 @@ func simulateFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Trigger aggregation
-	config := reputation.DefaultReputationConfig()
-	metrics, err := reputation.AggregateIPOnDemand(testData.IP, config)
+	config := h.aggregator.Config()
+	metrics, err := h.aggregator.AggregateIPOnDemand(testData.IP)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{
@@ -509,15 +1094,28 @@ func simulateFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get health summary
-	health, _ := reputation.CalculateIPHealthCheck(testData.IP, config.WindowMinutes, testData.TotalSent)
+	health, _ := reputation.CalculateIPHealthCheck(testData.IP, config.WindowMinutes, testData.TotalSent, config.DecayHalfLifeMinutes)
 
+	language := setContentLanguage(w, r)
+	recommended := h.recommend(language, metrics.Status, *health)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":           "success",
-		"failures_created": insertedCount,
-		"ip_status":        metrics.Status,
-		"metrics":          metrics,
-		"summary":          reputation.GetStatusSummary(metrics.Status, *health),
-		"recommendations":  reputation.GetRecommendedActions(metrics.Status),
+		"status":              "success",
+		"failures_created":    insertedCount,
+		"ip_status":           metrics.Status,
+		"metrics":             metrics,
+		"summary":             reputation.GetLocalizedStatusSummary(language, metrics.Status, *health),
+		"recommendations":     reputation.RecommendedActionCodes(recommended),
+		"recommended_actions": recommended,
 	})
 }
+
+// recommend returns the catalog's recommendations for an IP, or the built-in
+// ones while the catalog cannot be read
+func (h *ReputationHandler) recommend(language, status string, health reputation.IPHealthCheck) []reputation.Recommendation {
+	recommended, err := h.recommender.RecommendOrDefault(language, status, reputation.GetIssueType(health))
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to read the recommendation catalog, using the built-in recommendations")
+	}
+	return recommended
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestSimulateReputation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	// 4% rejections at gmail: quarantine by default, warning once the
+	// quarantine ratio is raised to 5%
+	health := `{"total_sent":1000,"total_rejected":40,"domain_counts":{"gmail.com":30,"example.org":10},"reputation_codes":{"5.7.1":1}}`
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int
+		wantStatus  string
+		wantChanged bool
+	}{
+		{"defaults", `{"health":` + health + `}`, http.StatusOK, "quarantine", false},
+		{"candidate config", `{"health":` + health + `,"config":{"quarantine_rejection_ratio":0.05}}`, http.StatusOK, "warning", true},
+		{"invalid ratio", `{"health":` + health + `,"config":{"warning_rejection_ratio":2}}`, http.StatusBadRequest, "", false},
+		{"invalid body", `{"health":`, http.StatusBadRequest, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/reputation/simulate", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp ReputationSimulationResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantStatus || resp.BaselineStatus != "quarantine" || resp.StatusChanged != tt.wantChanged {
+				t.Errorf("Unexpected simulation: %+v", resp)
+			}
+			if resp.Explanation.Observed.RejectionRatio != 0.04 || resp.Explanation.Observed.UniqueDomainsRejected != 2 {
+				t.Errorf("Expected derived fields to be filled, got %+v", resp.Explanation.Observed)
+			}
+		})
+	}
+}
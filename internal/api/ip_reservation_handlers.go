@@ -2,8 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -25,10 +30,14 @@ func NewIPReservationHandler(service *ionos.Service, logger *logrus.Logger) *IPR
 	}
 }
 
-// ReserveIPsRequest represents the request to reserve IPs
+// ReserveIPsRequest represents the request to reserve IPs. With a BlockSize
+// above 1, Count is the number of blocks and MaxBlacklisted the number of
+// listed members a block may have and still be kept.
 type ReserveIPsRequest struct {
-	Count    int    `json:"count"`
-	Location string `json:"location,omitempty"`
+	Count          int    `json:"count"`
+	Location       string `json:"location,omitempty"`
+	BlockSize      int    `json:"block_size,omitempty"`
+	MaxBlacklisted int    `json:"max_blacklisted,omitempty"`
 }
 
 // HandleReserveIPs handles POST /api/v1/ips/reserve
@@ -40,18 +49,30 @@ func (h *IPReservationHandler) HandleReserveIPs(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if req.Count <= 0 || req.Count > 50 {
-		http.Error(w, "Count must be between 1 and 50", http.StatusBadRequest)
+	if req.BlockSize == 0 {
+		req.BlockSize = 1
+	}
+	if req.BlockSize < 1 || req.BlockSize > ionos.MaxBlockSize {
+		http.Error(w, fmt.Sprintf("Block size must be between 1 and %d", ionos.MaxBlockSize), http.StatusBadRequest)
+		return
+	}
+	if req.MaxBlacklisted < 0 || req.MaxBlacklisted >= req.BlockSize {
+		http.Error(w, "Max blacklisted must be below the block size", http.StatusBadRequest)
+		return
+	}
+	if req.Count <= 0 || req.Count*req.BlockSize > 50 {
+		http.Error(w, "Count must be between 1 and 50 IPs in total", http.StatusBadRequest)
 		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
-		"action":   "reserve_ips",
-		"count":    req.Count,
-		"location": req.Location,
+		"action":     "reserve_ips",
+		"count":      req.Count,
+		"block_size": req.BlockSize,
+		"location":   req.Location,
 	}).Info("Received IP reservation request")
 
-	response, err := h.service.ReserveCleanIPs(r.Context(), req.Count, req.Location)
+	response, err := h.service.ReserveCleanBlocks(r.Context(), req.Count, req.BlockSize, req.Location, req.MaxBlacklisted)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to reserve IPs")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -63,6 +84,48 @@ func (h *IPReservationHandler) HandleReserveIPs(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleApplyPoolSpec handles PUT /api/v1/ips/pool-spec
+//
+// The body is the desired state of a tagged pool, e.g. {"count": 5,
+// "location": "de/fra", "tag": "marketing"}. IPs are reserved or released
+// until it holds, and the diff is returned; ?dry_run=true only plans it.
+func (h *IPReservationHandler) HandleApplyPoolSpec(w http.ResponseWriter, r *http.Request) {
+	var spec ionos.PoolSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	spec.Tag = strings.TrimSpace(spec.Tag)
+	spec.Location = strings.TrimSpace(spec.Location)
+	if spec.Tag == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "tag is required")
+		return
+	}
+	if spec.Count < 0 || spec.Count > 500 {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "count must be between 0 and 500")
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	diff, err := h.service.ApplyPoolSpec(r.Context(), spec, dryRun)
+	if errors.Is(err, ionos.ErrInsufficientQuota) {
+		writeUserError(w, http.StatusConflict, "insufficient_quota", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "apply_pool_spec",
+			"tag":    spec.Tag,
+			"error":  err.Error(),
+		}).Error("Failed to apply IP pool spec")
+		writeUserError(w, http.StatusBadGateway, "reservation_failed", "Failed to apply pool spec; applying it again resumes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
 // HandleListReservedIPs handles GET /api/v1/ips/reserved
 func (h *IPReservationHandler) HandleListReservedIPs(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -188,6 +251,161 @@ func (h *IPReservationHandler) HandleUpdateIPStatus(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(ip)
 }
 
+// HandleAttachIP handles POST /api/v1/ips/reserved/{id}/attach
+//
+// The body names the server NIC: {"datacenter_id": "...", "server_id":
+// "...", "nic_id": "..."}, or "lan_id" instead of "nic_id".
+func (h *IPReservationHandler) HandleAttachIP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Invalid IP ID")
+		return
+	}
+
+	var req ionos.AttachIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	req.DatacenterID = strings.TrimSpace(req.DatacenterID)
+	req.ServerID = strings.TrimSpace(req.ServerID)
+	if req.DatacenterID == "" || req.ServerID == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "datacenter_id and server_id are required")
+		return
+	}
+
+	ip, err := h.service.AttachIP(r.Context(), id, req)
+	switch {
+	case err == nil:
+	case errors.Is(err, ionos.ErrNotAttachable):
+		writeUserError(w, http.StatusConflict, "not_attachable", err.Error())
+		return
+	case errors.Is(err, ionos.ErrNICNotFound):
+		writeUserError(w, http.StatusUnprocessableEntity, "nic_not_found", err.Error())
+		return
+	case errors.Is(err, database.ErrReservedIPNotFound):
+		writeUserError(w, http.StatusNotFound, "not_found", "Reserved IP not found")
+		return
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action":    "attach_ip",
+			"ip_id":     id,
+			"server_id": req.ServerID,
+			"error":     err.Error(),
+		}).Error("Failed to attach IP")
+		writeUserError(w, http.StatusBadGateway, "attach_failed", "Failed to attach IP to the server")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ip)
+}
+
+// HandleReleaseIP handles POST /api/v1/ips/reserved/{id}/release
+//
+// The IP is marked released and its IONOS block deleted once no other
+// member of the block is held. In-use IPs must be detached first.
+func (h *IPReservationHandler) HandleReleaseIP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Invalid IP ID")
+		return
+	}
+
+	blockDeleted, err := h.service.ReleaseIP(r.Context(), id)
+	switch {
+	case err == nil:
+	case errors.Is(err, ionos.ErrIPInUse):
+		writeUserError(w, http.StatusConflict, "ip_in_use", err.Error())
+		return
+	case errors.Is(err, database.ErrReservedIPNotFound):
+		writeUserError(w, http.StatusNotFound, "not_found", "Reserved IP not found")
+		return
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action": "release_ip",
+			"ip_id":  id,
+			"error":  err.Error(),
+		}).Error("Failed to release IP")
+		writeUserError(w, http.StatusBadGateway, "release_failed", "Failed to release IP")
+		return
+	}
+
+	ip, err := database.GetReservedIPByID(id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get released IP")
+		http.Error(w, "Failed to retrieve released IP", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ip":            ip,
+		"block_deleted": blockDeleted,
+	})
+}
+
+// SwapIPRequest swaps an IP in use for a clean reserved IP
+type SwapIPRequest struct {
+	Reason string `json:"reason" example:"Listed on zen.spamhaus.org"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// @Summary Swap IP for a clean reserved IP
+// @Description Put the least used clean reserved IP of the same location in use for the IP's assignment and quarantine the IP, which returns to the reserve once its reputation recovers. Both sides are recorded in the IP actions. With dry_run the replacement is picked but nothing changes. The replacement still has to be attached to the server's NIC.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param request body SwapIPRequest false "Reason and dry run"
+// @Success 200 {object} ionos.SwapResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/swap [post]
+func (h *IPReservationHandler) HandleSwapIP(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	var req SwapIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	reason, ok := archiveReason(w, req.Reason)
+	if !ok {
+		return
+	}
+	if reason == "" {
+		reason = "manual swap"
+	}
+
+	result, err := h.service.SwapIP(ip, reason, principalName(r), req.DryRun)
+	switch {
+	case err == nil:
+	case errors.Is(err, database.ErrReservedIPNotFound):
+		writeUserError(w, http.StatusNotFound, "not_found", ip+" is not a reserved IP")
+		return
+	case errors.Is(err, ionos.ErrNotSwappable):
+		writeUserError(w, http.StatusConflict, "not_swappable", ip+" is not in use")
+		return
+	case errors.Is(err, ionos.ErrNoCleanReserve):
+		writeUserError(w, http.StatusConflict, "no_clean_reserve", err.Error())
+		return
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action": "swap_ip",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to swap IP")
+		writeUserError(w, http.StatusInternalServerError, "swap_failed", "Failed to swap IP")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // HandleRecheckBlacklist handles POST /api/v1/ips/reserved/{id}/recheck
 func (h *IPReservationHandler) HandleRecheckBlacklist(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -240,10 +458,13 @@ func (h *IPReservationHandler) HandleDeleteReservedIP(w http.ResponseWriter, r *
 		return
 	}
 
-	// Delete from IONOS if block still exists
+	// Delete from IONOS unless other members of the block are still held
 	if ip.ReservationBlockID != "" {
-		h.logger.WithField("block_id", ip.ReservationBlockID).Info("Deleting IONOS block")
-		if _, err := h.service.CleanupSingleIPBlocks(r.Context()); err != nil {
+		h.logger.WithField("block_id", ip.ReservationBlockID).Info("Releasing IONOS block")
+		if _, err := h.service.ReleaseIP(r.Context(), id); errors.Is(err, ionos.ErrIPInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
 			h.logger.WithError(err).Warn("Failed to delete IONOS block, continuing with database deletion")
 		}
 	}
@@ -258,6 +479,136 @@ func (h *IPReservationHandler) HandleDeleteReservedIP(w http.ResponseWriter, r *
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ProtectBlockRequest is the body of a protect block request
+type ProtectBlockRequest struct {
+	Reason string `json:"reason"`
+}
+
+// HandleListProtectedBlocks handles GET /api/v1/ips/protected-blocks
+func (h *IPReservationHandler) HandleListProtectedBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks, err := h.service.ListProtectedBlocks(r.Context())
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_protected_blocks",
+			"error":  err.Error(),
+		}).Error("Failed to list protected blocks")
+		writeUserError(w, http.StatusInternalServerError, "internal_error", "Failed to list protected blocks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"protected_blocks": blocks,
+		"count":            len(blocks),
+	})
+}
+
+// HandleProtectBlock handles PUT /api/v1/ips/protected-blocks/{block_id}
+//
+// Protected blocks are never deleted by cleanup or release and are reported
+// separately by the quota check. The body is optional: {"reason": "..."}.
+func (h *IPReservationHandler) HandleProtectBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["block_id"]
+
+	var req ProtectBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	protected, err := h.service.ProtectBlock(r.Context(), blockID, strings.TrimSpace(req.Reason), principalName(r))
+	switch {
+	case err == nil:
+	case errors.Is(err, ionos.ErrBlockNotFound):
+		writeUserError(w, http.StatusNotFound, "not_found", "IP block not found in IONOS")
+		return
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action":   "protect_block",
+			"block_id": blockID,
+			"error":    err.Error(),
+		}).Error("Failed to protect block")
+		writeUserError(w, http.StatusInternalServerError, "internal_error", "Failed to protect block")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protected)
+}
+
+// HandleUnprotectBlock handles DELETE /api/v1/ips/protected-blocks/{block_id}
+func (h *IPReservationHandler) HandleUnprotectBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["block_id"]
+
+	err := h.service.UnprotectBlock(blockID)
+	if err != nil && !errors.Is(err, database.ErrProtectedBlockNotFound) {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "unprotect_block",
+			"block_id": blockID,
+			"error":    err.Error(),
+		}).Error("Failed to unprotect block")
+		writeUserError(w, http.StatusInternalServerError, "internal_error", "Failed to unprotect block")
+		return
+	}
+	writeDeleted(w, err == nil, "Protected block")
+}
+
+// HandleListStaleReservations handles GET /api/v1/ips/stale
+//
+// Lists the reserved IPs the stale policy would flag or release now,
+// without changing anything.
+func (h *IPReservationHandler) HandleListStaleReservations(w http.ResponseWriter, r *http.Request) {
+	h.evaluateStale(w, r, true)
+}
+
+// HandleEvaluateStaleReservations handles POST /api/v1/ips/stale/evaluate
+//
+// Applies the stale policy immediately instead of waiting for the next
+// scheduled run.
+func (h *IPReservationHandler) HandleEvaluateStaleReservations(w http.ResponseWriter, r *http.Request) {
+	h.evaluateStale(w, r, false)
+}
+
+func (h *IPReservationHandler) evaluateStale(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	report, err := h.service.EvaluateStaleReservations(r.Context(), dryRun)
+	switch {
+	case err == nil:
+	case errors.Is(err, ionos.ErrStalePolicyDisabled):
+		writeUserError(w, http.StatusConflict, "policy_disabled", "The stale reservation policy is disabled; set IONOS_STALE_AFTER")
+		return
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action":  "evaluate_stale_reservations",
+			"dry_run": dryRun,
+			"error":   err.Error(),
+		}).Error("Failed to evaluate stale reservations")
+		writeUserError(w, http.StatusInternalServerError, "internal_error", "Failed to evaluate stale reservations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleReconcileQuarantine handles POST /api/v1/ips/quarantine/reconcile
+//
+// Reconciles the reserved IP statuses with the reputation statuses
+// immediately instead of waiting for the next scheduled run.
+func (h *IPReservationHandler) HandleReconcileQuarantine(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.ReconcileQuarantine()
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "reconcile_quarantine",
+			"error":  err.Error(),
+		}).Error("Failed to reconcile reserved IP quarantine")
+		writeUserError(w, http.StatusInternalServerError, "internal_error", "Failed to reconcile reserved IP quarantine")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // HandleCheckQuota handles GET /api/v1/ips/quota
 func (h *IPReservationHandler) HandleCheckQuota(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Checking IONOS quota")
@@ -306,3 +657,104 @@ func (h *IPReservationHandler) HandleGetStatistics(w http.ResponseWriter, r *htt
 	json.NewEncoder(w).Encode(stats)
 }
 
+
+// locationStatsMinAttempts is how many attempts a location needs in a window
+// before it can be recommended
+const locationStatsMinAttempts = 5
+
+// LocationStatsWindow holds the per-location reservation stats of one window
+type LocationStatsWindow struct {
+	Window    string                              `json:"window"`
+	Since     time.Time                           `json:"since"`
+	Locations []database.LocationReservationStats `json:"locations"`
+	// BestLocation needs the fewest attempts per clean IP among locations
+	// with enough attempts
+	BestLocation string `json:"best_location,omitempty"`
+}
+
+// HandleGetLocationStats handles GET /api/v1/ips/locations/stats
+//
+// ?windows=24h,168h,720h (the default) selects the windows; each reports the
+// blacklist hit rate, attempts per clean IP and average latency of every
+// location, to guide where to reserve.
+func (h *IPReservationHandler) HandleGetLocationStats(w http.ResponseWriter, r *http.Request) {
+	windowsParam := r.URL.Query().Get("windows")
+	if windowsParam == "" {
+		windowsParam = "24h,168h,720h"
+	}
+
+	windows, err := parseStatsWindows(windowsParam)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", err.Error())
+		return
+	}
+
+	now := time.Now()
+	result := make([]LocationStatsWindow, 0, len(windows))
+	for _, window := range windows {
+		since := now.Add(-window.duration)
+		stats, err := database.GetLocationReservationStats(since)
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "get_location_stats",
+				"window": window.label,
+				"error":  err.Error(),
+			}).Error("Failed to get location reservation stats")
+			writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve location statistics")
+			return
+		}
+		result = append(result, LocationStatsWindow{
+			Window:       window.label,
+			Since:        since,
+			Locations:    stats,
+			BestLocation: bestLocation(stats, locationStatsMinAttempts),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"windows": result,
+	})
+}
+
+// statsWindow is one requested window, labelled as the client wrote it
+type statsWindow struct {
+	label    string
+	duration time.Duration
+}
+
+// parseStatsWindows parses a comma-separated list of positive durations
+func parseStatsWindows(param string) ([]statsWindow, error) {
+	parts := strings.Split(param, ",")
+	if len(parts) > 10 {
+		return nil, fmt.Errorf("at most 10 windows can be requested")
+	}
+
+	windows := make([]statsWindow, 0, len(parts))
+	for _, part := range parts {
+		label := strings.TrimSpace(part)
+		duration, err := time.ParseDuration(label)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid window %q (use 24h, 168h, etc.)", label)
+		}
+		windows = append(windows, statsWindow{label: label, duration: duration})
+	}
+	return windows, nil
+}
+
+// bestLocation returns the location with the fewest attempts per clean IP,
+// ignoring locations with fewer than minAttempts attempts
+func bestLocation(stats []database.LocationReservationStats, minAttempts int) string {
+	best := ""
+	bestRatio := 0.0
+	for _, l := range stats {
+		if l.Location == "" || l.Attempts < minAttempts || l.AttemptsPerCleanIP == nil {
+			continue
+		}
+		if best == "" || *l.AttemptsPerCleanIP < bestRatio {
+			best = l.Location
+			bestRatio = *l.AttemptsPerCleanIP
+		}
+	}
+	return best
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestParseStatsWindows(t *testing.T) {
+	windows, err := parseStatsWindows("24h, 168h")
+	if err != nil {
+		t.Fatalf("parseStatsWindows failed: %v", err)
+	}
+	if len(windows) != 2 || windows[1].label != "168h" || windows[1].duration.Hours() != 168 {
+		t.Errorf("Unexpected windows: %+v", windows)
+	}
+
+	for _, param := range []string{"7d", "24h,-1h", "0s"} {
+		if _, err := parseStatsWindows(param); err == nil {
+			t.Errorf("Expected %q to be rejected", param)
+		}
+	}
+}
+
+func TestBestLocation(t *testing.T) {
+	ratio := func(v float64) *float64 { return &v }
+	stats := []database.LocationReservationStats{
+		{Location: "de/fra", Attempts: 20, CleanIPs: 10, AttemptsPerCleanIP: ratio(2)},
+		{Location: "us/ewr", Attempts: 12, CleanIPs: 10, AttemptsPerCleanIP: ratio(1.2)},
+		// Too few attempts to be trusted
+		{Location: "es/vit", Attempts: 2, CleanIPs: 2, AttemptsPerCleanIP: ratio(1)},
+		// Never produced a clean IP
+		{Location: "gb/lhr", Attempts: 30},
+	}
+
+	if best := bestLocation(stats, 5); best != "us/ewr" {
+		t.Errorf("Expected us/ewr, got %q", best)
+	}
+	if best := bestLocation(stats[3:], 5); best != "" {
+		t.Errorf("Expected no recommendation, got %q", best)
+	}
+}
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/itsm"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const maxITSMWebhookSize = 1 << 20
+
+// jiraProjectPattern is a Jira project key such as OPS or DELIV2
+var jiraProjectPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,254}$`)
+
+// ITSMRuleRequest represents the request body for creating or replacing an
+// ITSM rule
+type ITSMRuleRequest struct {
+	Event   string `json:"event"`   // ip_blacklisted or delisting_opened
+	System  string `json:"system"`  // jira or servicenow
+	Project string `json:"project"` // Jira project key or ServiceNow assignment group
+	PoolID  *int   `json:"pool_id"` // Omit to match any pool
+	Enabled *bool  `json:"enabled"` // Defaults to true
+}
+
+// ITSMTicketsResponse lists an IP's tickets, newest first
+type ITSMTicketsResponse struct {
+	Total   int                   `json:"total"`
+	Tickets []database.ITSMTicket `json:"tickets"`
+}
+
+// ITSMWebhookResponse tells the system whether its status report matched a
+// ticket
+type ITSMWebhookResponse struct {
+	Status string               `json:"status"` // updated or ignored
+	Ticket *database.ITSMTicket `json:"ticket,omitempty"`
+}
+
+// ITSMHandler manages the rules opening Jira and ServiceNow tickets and
+// receives the systems' status webhooks
+type ITSMHandler struct {
+	service   *itsm.Service
+	secret    string
+	ownership *OwnershipHandler
+	logger    *logrus.Logger
+}
+
+// NewITSMHandler creates a new ITSM handler. A nil service refuses status
+// webhooks; an empty secret refuses them too.
+func NewITSMHandler(service *itsm.Service, secret string, logger *logrus.Logger) *ITSMHandler {
+	return &ITSMHandler{service: service, secret: secret, ownership: NewOwnershipHandler(logger), logger: logger}
+}
+
+// decodeITSMRule reads and validates a rule request
+func decodeITSMRule(w http.ResponseWriter, r *http.Request) (*database.ITSMRule, bool) {
+	var req ITSMRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return nil, false
+	}
+
+	req.Event = strings.ToLower(strings.TrimSpace(req.Event))
+	req.System = strings.ToLower(strings.TrimSpace(req.System))
+	req.Project = strings.TrimSpace(req.Project)
+	if !itsm.ValidEvent(req.Event) {
+		writeUserError(w, http.StatusBadRequest, "invalid_event", "event must be ip_blacklisted or delisting_opened")
+		return nil, false
+	}
+	if !itsm.ValidSystem(req.System) {
+		writeUserError(w, http.StatusBadRequest, "invalid_system", "system must be jira or servicenow")
+		return nil, false
+	}
+	switch {
+	case req.Project == "" || len(req.Project) > 255:
+		writeUserError(w, http.StatusBadRequest, "invalid_project", "project is required and must be at most 255 characters")
+		return nil, false
+	case req.System == itsm.SystemJira && !jiraProjectPattern.MatchString(req.Project):
+		writeUserError(w, http.StatusBadRequest, "invalid_project", "project must be a Jira project key such as OPS")
+		return nil, false
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &database.ITSMRule{
+		Event:     req.Event,
+		System:    req.System,
+		Project:   req.Project,
+		PoolID:    req.PoolID,
+		Enabled:   enabled,
+		CreatedBy: principalName(r),
+	}, true
+}
+
+// @Summary List ITSM rules
+// @Description The rules deciding which events open a Jira or ServiceNow ticket for an IP
+// @Tags itsm
+// @Produce json
+// @Success 200 {array} database.ITSMRule
+// @Failure 500 {object} ErrorResponse
+// @Router /api/itsm/rules [get]
+func (h *ITSMHandler) HandleListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := database.ListITSMRules()
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "list_itsm_rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// @Summary Create ITSM rule
+// @Description Open a ticket in a Jira project or ServiceNow assignment group when an IP, optionally of one pool, is blacklisted or a delisting task is opened for it. Rules naming a pool replace the catch-all rules for its IPs. While a rule's ticket for the event is open no second one is opened.
+// @Tags itsm
+// @Accept json
+// @Produce json
+// @Param rule body ITSMRuleRequest true "Rule"
+// @Success 201 {object} database.ITSMRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/itsm/rules [post]
+func (h *ITSMHandler) HandleCreateRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeITSMRule(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := database.CreateITSMRule(rule)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "create_itsm_rule")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "itsm_rule_created",
+		"rule_id": created.ID,
+		"event":   created.Event,
+		"system":  created.System,
+		"author":  created.CreatedBy,
+	}).Info("ITSM rule created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary Replace ITSM rule
+// @Tags itsm
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rule body ITSMRuleRequest true "Rule"
+// @Success 200 {object} database.ITSMRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/itsm/rules/{id} [put]
+func (h *ITSMHandler) HandleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	rule, ok := decodeITSMRule(w, r)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	updated, err := database.UpdateITSMRule(rule)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "update_itsm_rule")
+		return
+	}
+	if updated == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "ITSM rule not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "itsm_rule_updated",
+		"rule_id": id,
+		"author":  rule.CreatedBy,
+	}).Info("ITSM rule updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// @Summary Delete ITSM rule
+// @Description Stop opening tickets for the rule; the tickets it opened keep syncing
+// @Tags itsm
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/itsm/rules/{id} [delete]
+func (h *ITSMHandler) HandleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteITSMRule(id)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "delete_itsm_rule")
+		return
+	}
+	if found {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "itsm_rule_deleted",
+			"rule_id": id,
+			"author":  principalName(r),
+		}).Info("ITSM rule deleted")
+	}
+	writeDeleted(w, found, "ITSM rule")
+}
+
+// @Summary List an IP's ITSM tickets
+// @Description Jira and ServiceNow tickets the rules opened for an IP, with their keys, links and last reported status, newest first. Ticket changes also appear in the IP's actions.
+// @Tags itsm
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} ITSMTicketsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/itsm-tickets [get]
+func (h *ITSMHandler) HandleListIPTickets(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+
+	tickets, err := database.ListITSMTickets(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "list_itsm_tickets",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to list ITSM tickets")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list ITSM tickets")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ITSMTicketsResponse{Total: len(tickets), Tickets: tickets})
+}
+
+// @Summary Receive an ITSM status webhook
+// @Description Jira issue webhooks (issue key and status; the done category resolves) and ServiceNow business rule posts ({"number", "state", "state_label"}) update the status of the tickets the rules opened. Resolving a delisting task's ticket resolves the task. Reports about other tickets are ignored. Authenticated with the shared ITSM webhook secret as X-Webhook-Token, Bearer token or basic auth password.
+// @Tags itsm
+// @Accept json
+// @Produce json
+// @Param system path string true "jira or servicenow"
+// @Success 200 {object} ITSMWebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/itsm/{system} [post]
+func (h *ITSMHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	system := mux.Vars(r)["system"]
+	var client itsm.Client
+	if h.service != nil {
+		client = h.service.Client(system)
+	}
+	if client == nil {
+		writeUserError(w, http.StatusNotFound, "not_configured", "No ITSM integration is configured for "+system)
+		return
+	}
+	token := webhookToken(r)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		h.logger.WithFields(logrus.Fields{
+			"action":      "itsm_webhook_auth_failed",
+			"system":      system,
+			"remote_addr": r.RemoteAddr,
+		}).Warn("Rejected ITSM webhook")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="itsm"`)
+		writeUserError(w, http.StatusUnauthorized, "unauthorized", "A valid ITSM webhook secret is required")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxITSMWebhookSize))
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Request body is unreadable or too large")
+		return
+	}
+	report, err := client.ParseWebhook(body)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_payload", err.Error())
+		return
+	}
+
+	ticket, err := h.service.ApplyStatusReport(system, report)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, database.ErrITSMTicketNotFound):
+		json.NewEncoder(w).Encode(ITSMWebhookResponse{Status: "ignored"})
+		return
+	case err != nil:
+		h.logger.WithFields(logrus.Fields{
+			"action":     "itsm_webhook_failed",
+			"system":     system,
+			"ticket_key": report.Key,
+			"error":      err.Error(),
+		}).Error("Failed to apply ITSM status")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to update ticket status")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":     "itsm_ticket_status",
+		"system":     system,
+		"ticket_key": ticket.Key,
+		"status":     ticket.Status,
+		"resolved":   ticket.Resolved,
+	}).Info("ITSM ticket status received")
+	json.NewEncoder(w).Encode(ITSMWebhookResponse{Status: "updated", Ticket: ticket})
+}
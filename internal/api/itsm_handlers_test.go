@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/itsm"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryITSMStore is an in-memory itsm.Store with one catch-all rule per
+// event in OPS
+type memoryITSMStore struct {
+	mu      sync.Mutex
+	tickets []database.ITSMTicket
+	tasks   map[int]string
+}
+
+func (s *memoryITSMStore) ListITSMRules() ([]database.ITSMRule, error) {
+	return []database.ITSMRule{
+		{ID: 1, Event: itsm.EventBlacklisted, System: itsm.SystemJira, Project: "OPS", Enabled: true},
+		{ID: 2, Event: itsm.EventDelistingOpened, System: itsm.SystemJira, Project: "OPS", Enabled: true},
+	}, nil
+}
+
+func (s *memoryITSMStore) GetIPPoolIDs(ip string) ([]int, error) { return nil, nil }
+
+func (s *memoryITSMStore) IsIPArchived(ip string) (bool, error) { return false, nil }
+
+func (s *memoryITSMStore) HasOpenITSMTicket(ruleID int, ip, event string, taskID *int) (bool, error) {
+	return false, nil
+}
+
+func (s *memoryITSMStore) RecordITSMTicket(ticket *database.ITSMTicket, author string) (*database.ITSMTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *ticket
+	stored.ID = len(s.tickets) + 1
+	s.tickets = append(s.tickets, stored)
+	return &stored, nil
+}
+
+func (s *memoryITSMStore) ListITSMTickets(ip string) ([]database.ITSMTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]database.ITSMTicket{}, s.tickets...), nil
+}
+
+func (s *memoryITSMStore) GetITSMTicketByKey(system, key string) (*database.ITSMTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tickets {
+		if t.System == system && t.Key == key {
+			return &t, nil
+		}
+	}
+	return nil, database.ErrITSMTicketNotFound
+}
+
+func (s *memoryITSMStore) UpdateITSMTicketStatus(id int, status string, resolved bool, author string) (*database.ITSMTicket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &s.tickets[id-1]
+	changed := t.Status != status || t.Resolved != resolved
+	t.Status, t.Resolved = status, resolved
+	updated := *t
+	return &updated, changed, nil
+}
+
+func (s *memoryITSMStore) UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[id] = status
+	return &database.DelistingTask{ID: id, Status: status}, nil
+}
+
+// stubJira opens OPS-1, OPS-2, ... and records the updates it receives
+type stubJira struct {
+	*itsm.JiraClient
+	mu      sync.Mutex
+	created []itsm.Ticket
+	updates []itsm.Update
+}
+
+func (c *stubJira) CreateTicket(ctx context.Context, ticket itsm.Ticket) (itsm.Ref, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, ticket)
+	key := "OPS-" + strconv.Itoa(len(c.created))
+	return itsm.Ref{Key: key, ExternalID: key}, nil
+}
+
+func (c *stubJira) UpdateTicket(ctx context.Context, ref itsm.Ref, update itsm.Update) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = append(c.updates, update)
+	return false, nil
+}
+
+func newTestITSMService(store *memoryITSMStore) (*itsm.Service, *stubJira) {
+	logger, _ := test.NewNullLogger()
+	client := &stubJira{JiraClient: itsm.NewJiraClient(itsm.JiraConfig{})}
+	return itsm.NewService(map[string]itsm.Client{itsm.SystemJira: client}, store, logger), client
+}
+
+func TestITSMRuleValidation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               string
+	}{
+		{"POST", "/api/itsm/rules", `not json`, "invalid_request"},
+		{"POST", "/api/itsm/rules", `{"event":"ip_quarantined","system":"jira","project":"OPS"}`, "invalid_event"},
+		{"POST", "/api/itsm/rules", `{"event":"ip_blacklisted","system":"zendesk","project":"OPS"}`, "invalid_system"},
+		{"POST", "/api/itsm/rules", `{"event":"ip_blacklisted","system":"servicenow","project":" "}`, "invalid_project"},
+		{"POST", "/api/itsm/rules", `{"event":"delisting_opened","system":"jira","project":"ops team"}`, "invalid_project"},
+		{"PUT", "/api/itsm/rules/abc", `{"event":"ip_blacklisted","system":"jira","project":"OPS"}`, "invalid_id"},
+		{"DELETE", "/api/itsm/rules/abc", "", "invalid_id"},
+	} {
+		rec := serveConfig(router, tc.method, tc.path, tc.body)
+		var resp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusBadRequest || resp.Error != tc.code {
+			t.Errorf("%s %s %s: expected 400 %s, got %d %s", tc.method, tc.path, tc.body, tc.code, rec.Code, resp.Error)
+		}
+	}
+}
+
+func TestITSMWebhook(t *testing.T) {
+	taskID := 4
+	store := &memoryITSMStore{tasks: map[int]string{}, tickets: []database.ITSMTicket{
+		{ID: 1, System: itsm.SystemJira, Key: "OPS-1", IP: "192.0.2.1", Event: itsm.EventDelistingOpened, DelistingTaskID: &taskID, Status: "open"},
+	}}
+	service, _ := newTestITSMService(store)
+	defer service.Close()
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, ITSM: service, ITSMWebhookSecret: "s3cret"})
+
+	post := func(system, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/webhooks/itsm/"+system, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Webhook-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	done := `{"issue":{"key":"OPS-1","fields":{"status":{"name":"Done","statusCategory":{"key":"done"}}}}}`
+
+	if rec := post("jira", "", done); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the secret, got %d", rec.Code)
+	}
+	if rec := post("jira", "wrong", done); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong secret, got %d", rec.Code)
+	}
+	if rec := post("servicenow", "s3cret", `{"number":"INC1","state":"6"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unconfigured system, got %d", rec.Code)
+	}
+	if rec := post("jira", "s3cret", `{"issue":{}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid payload, got %d", rec.Code)
+	}
+
+	rec := post("jira", "s3cret", done)
+	var resp ITSMWebhookResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || resp.Status != "updated" {
+		t.Fatalf("Expected the ticket updated, got %d %+v %v", rec.Code, resp, err)
+	}
+	if !resp.Ticket.Resolved || resp.Ticket.Status != "Done" || store.tasks[4] != database.DelistingResolved {
+		t.Errorf("Expected the ticket and its task resolved, got %+v, task %q", resp.Ticket, store.tasks[4])
+	}
+
+	rec = post("jira", "s3cret", strings.Replace(done, "OPS-1", "OPS-99", 1))
+	resp = ITSMWebhookResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Status != "ignored" {
+		t.Errorf("Expected another issue to be ignored, got %d %+v", rec.Code, resp)
+	}
+}
+
+func TestITSMWebhookWithoutService(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, ITSMWebhookSecret: "s3cret"})
+	if rec := serveConfig(router, "POST", "/api/webhooks/itsm/jira", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without an ITSM integration, got %d", rec.Code)
+	}
+}
+
+func TestDelistingTaskTickets(t *testing.T) {
+	store := &memoryITSMStore{}
+	service, client := newTestITSMService(store)
+	logger, _ := test.NewNullLogger()
+	handler := &DelistingTaskHandler{store: &memoryDelistingStore{}, tickets: service, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/{ip}/delisting-tasks", handler.HandleOpen).Methods("POST")
+	router.HandleFunc("/api/delisting-tasks/{id}", handler.HandleUpdateTask).Methods("PATCH")
+
+	serveConfig(router, "POST", "/api/ips/192.0.2.1/delisting-tasks", `{"provider":"outlook"}`)
+	serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"submitted","comment":"SRX123"}`)
+	// Setting the current status again is not sent
+	serveConfig(router, "PATCH", "/api/delisting-tasks/1", `{"status":"submitted"}`)
+	service.Close()
+
+	if len(client.created) != 1 || client.created[0].Summary != "Delist 192.0.2.1 with outlook" {
+		t.Fatalf("Expected one ticket for the task, got %+v", client.created)
+	}
+	if len(store.tickets) != 1 || *store.tickets[0].DelistingTaskID != 1 {
+		t.Errorf("Expected the ticket linked to the task, got %+v", store.tickets)
+	}
+	if len(client.updates) != 1 || !strings.Contains(client.updates[0].Comment, "SRX123") {
+		t.Errorf("Expected one comment with the submission, got %+v", client.updates)
+	}
+}
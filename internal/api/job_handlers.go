@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/jobs"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Job types
+const (
+	jobTypeDNSBLCheck        = "dnsbl_check"
+	jobTypeReputationRebuild = "reputation_rebuild"
+)
+
+// rebuildJobTimeout allows rebuilds of many IPs over long ranges to finish
+const rebuildJobTimeout = time.Hour
+
+// AsyncJobRequest represents the optional request body of asynchronous
+// endpoints
+type AsyncJobRequest struct {
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// JobHandler starts background jobs and reports their progress
+type JobHandler struct {
+	runner     *jobs.Runner
+	dnsbl      *reputation.DNSBLService
+	aggregator *reputation.AggregationService
+	logger     *logrus.Logger
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(runner *jobs.Runner, dnsbl *reputation.DNSBLService, aggregator *reputation.AggregationService, logger *logrus.Logger) *JobHandler {
+	return &JobHandler{runner: runner, dnsbl: dnsbl, aggregator: aggregator, logger: logger}
+}
+
+// submit starts fn as a job of the given type and answers 202 with the job
+// and its Location
+func (h *JobHandler) submit(w http.ResponseWriter, r *http.Request, jobType, subject string, fn jobs.Func) {
+	var req AsyncJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	h.start(w, r, jobs.Options{Type: jobType, Subject: subject, CallbackURL: req.CallbackURL}, fn)
+}
+
+// start submits fn with the options, checking the callback URL and
+// recording the caller as the job's creator
+func (h *JobHandler) start(w http.ResponseWriter, r *http.Request, opts jobs.Options, fn jobs.Func) {
+	if opts.CallbackURL != "" {
+		if err := jobs.ValidateCallbackURL(opts.CallbackURL); err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_callback_url", "callback_url must be an http or https URL of a public host")
+			return
+		}
+	}
+
+	opts.CreatedBy = principalName(r)
+	job, err := h.runner.Submit(opts, fn)
+	if errors.Is(err, jobs.ErrClosed) {
+		writeUserError(w, http.StatusServiceUnavailable, "shutting_down", "The service is shutting down, retry later")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "submit_job_failed",
+			"type":   opts.Type,
+			"error":  err.Error(),
+		}).Error("Failed to submit job")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to start job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// @Summary Check DNSBL status for IP asynchronously
+// @Description Start the DNSBL checks for an IP in the background and return the job at once. Poll GET /api/jobs/{id} for the reputation.DNSBLResult, or give a callback_url to receive the finished job as a POST.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param request body AsyncJobRequest false "Optional callback"
+// @Success 202 {object} database.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/ips/{ip}/dnsbl-check/async [post]
+func (h *JobHandler) HandleDNSBLCheck(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	h.submit(w, r, jobTypeDNSBLCheck, ip, func(ctx context.Context) (interface{}, error) {
+		return h.dnsbl.CheckDNSBLContext(ctx, ip, 5)
+	})
+}
+
+// RebuildReputationRequest selects the IPs and time range to rebuild
+type RebuildReputationRequest struct {
+	// IPs to rebuild; empty rebuilds every IP with failures in the range
+	IPs []string `json:"ips,omitempty"`
+	// From defaults to a day before to
+	From time.Time `json:"from,omitempty"`
+	// To defaults to now; only a range ending now updates current metrics
+	To time.Time `json:"to,omitempty"`
+	// Step is the spacing of the rebuilt history records, e.g. "15m"; default 1h
+	Step string `json:"step,omitempty"`
+	// DryRun reports what would change without storing anything
+	DryRun bool `json:"dry_run,omitempty"`
+	// CallbackURL receives the finished job as a JSON POST
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// @Summary Rebuild reputation from raw failures
+// @Description Recompute the reputation history, and the current metrics when the range ends now, of the selected IPs from their stored SMTP failures under the active config, in the background. History records are replayed every step through the status state machine, starting from the last status recorded before the range, and replace the records of the range. Status changes are recorded as actions, without alerts or automated measures. Poll GET /api/jobs/{id} for progress (IPs done of total) and the reputation.RebuildResult.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body RebuildReputationRequest false "IPs and range to rebuild"
+// @Success 202 {object} database.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/admin/reputation/rebuild [post]
+func (h *JobHandler) HandleRebuildReputation(w http.ResponseWriter, r *http.Request) {
+	var req RebuildReputationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	opts := reputation.RebuildOptions{IPs: req.IPs, From: req.From, To: req.To, DryRun: req.DryRun}
+	if req.Step != "" {
+		step, err := time.ParseDuration(req.Step)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_step", "step must be a duration such as 15m or 1h")
+			return
+		}
+		opts.Step = step
+	}
+	if err := opts.Validate(time.Now()); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_rebuild", err.Error())
+		return
+	}
+
+	subject := ""
+	if len(opts.IPs) == 1 {
+		subject = opts.IPs[0]
+	}
+	h.start(w, r, jobs.Options{
+		Type:        jobTypeReputationRebuild,
+		Subject:     subject,
+		CallbackURL: req.CallbackURL,
+		Timeout:     rebuildJobTimeout,
+	}, func(ctx context.Context) (interface{}, error) {
+		return h.aggregator.Rebuild(ctx, opts, func(done, total int) {
+			jobs.ReportProgress(ctx, done, total)
+		})
+	})
+}
+
+// @Summary Get job
+// @Description The status of a background job, with its progress while jobs working through many items run, its result once it succeeded or its error once it failed. Finished jobs are kept for the configured retention.
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} database.Job
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/jobs/{id} [get]
+func (h *JobHandler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	job, err := h.runner.Get(id)
+	if err == database.ErrJobNotFound {
+		writeUserError(w, http.StatusNotFound, "not_found", "Job not found")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_job_failed",
+			"job_id": id,
+			"error":  err.Error(),
+		}).Error("Failed to get job")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/jobs"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]database.Job
+}
+
+func (s *memoryJobStore) CreateJob(job *database.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryJobStore) UpdateJob(job *database.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryJobStore) GetJob(id string) (*database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, database.ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *memoryJobStore) DeleteJobsFinishedBefore(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestAsyncDNSBLCheck(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	// Jobs time out at once, so the check fails before touching the database
+	runner := jobs.NewRunner(&memoryJobStore{jobs: map[string]database.Job{}}, jobs.Config{Timeout: time.Nanosecond}, logger)
+	router := SetupRoutesWithDependencies(Dependencies{
+		Logger: logger,
+		DNSBL:  reputation.NewDNSBLService(logger),
+		Jobs:   runner,
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/ips/192.0.2.1/dnsbl-check/async", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var job database.Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Type != "dnsbl_check" || job.Subject != "192.0.2.1" || job.Status != database.JobPending {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+	if location := rec.Header().Get("Location"); location != "/api/jobs/"+job.ID {
+		t.Errorf("Expected Location of the job, got %q", location)
+	}
+
+	if err := runner.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil))
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || job.Status != database.JobFailed || !strings.Contains(job.Error, "cancelled") {
+		t.Errorf("Expected the timed out check reported as failed, got %d %+v", rec.Code, job)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/ips/192.0.2.1/dnsbl-check/async", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 once the runner closed, got %d", rec.Code)
+	}
+}
+
+func TestAsyncDNSBLCheckValidation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	runner := jobs.NewRunner(&memoryJobStore{jobs: map[string]database.Job{}}, jobs.Config{}, logger)
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Jobs: runner})
+
+	for _, body := range []string{`{"callback_url":"ftp://example.com"}`, `{`} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/ips/192.0.2.1/dnsbl-check/async", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/jobs/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", rec.Code)
+	}
+}
+
+func TestRebuildReputation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	runner := jobs.NewRunner(&memoryJobStore{jobs: map[string]database.Job{}}, jobs.Config{}, logger)
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Jobs: runner})
+
+	rec := httptest.NewRecorder()
+	body := `{"ips":["192.0.2.1"],"from":"2024-03-01T00:00:00Z","to":"2024-03-02T00:00:00Z","step":"15m","dry_run":true}`
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/admin/reputation/rebuild", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var job database.Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Type != "reputation_rebuild" || job.Subject != "192.0.2.1" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+	if location := rec.Header().Get("Location"); location != "/api/jobs/"+job.ID {
+		t.Errorf("Expected Location of the job, got %q", location)
+	}
+
+	// Without a database the rebuild fails, and is reported as failed
+	if err := runner.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil))
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != database.JobFailed {
+		t.Errorf("Expected the rebuild reported as failed, got %+v", job)
+	}
+}
+
+func TestRebuildReputationValidation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	runner := jobs.NewRunner(&memoryJobStore{jobs: map[string]database.Job{}}, jobs.Config{}, logger)
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Jobs: runner})
+
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{`, "invalid_request"},
+		{`{"from":"yesterday"}`, "invalid_request"},
+		{`{"step":"often"}`, "invalid_step"},
+		{`{"step":"1s"}`, "invalid_rebuild"},
+		{`{"ips":["not-an-ip"]}`, "invalid_rebuild"},
+		{`{"from":"2024-03-02T00:00:00Z","to":"2024-03-01T00:00:00Z"}`, "invalid_rebuild"},
+		{`{"from":"2024-01-01T00:00:00Z","to":"2024-12-31T00:00:00Z"}`, "invalid_rebuild"},
+		{`{"callback_url":"ftp://example.com"}`, "invalid_callback_url"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/admin/reputation/rebuild", strings.NewReader(tt.body)))
+		var resp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusBadRequest || resp.Error != tt.code {
+			t.Errorf("Expected 400 %s for %s, got %d %s", tt.code, tt.body, rec.Code, resp.Error)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// jsonArrayStream writes a JSON array one element at a time, so lists are
+// encoded as their rows are read instead of being built in memory first
+type jsonArrayStream struct {
+	w       io.Writer
+	enc     *json.Encoder
+	prefix  string
+	started bool
+}
+
+// newJSONArrayStream streams an array to w. prefix is written before it,
+// e.g. the opening of the object holding the array.
+func newJSONArrayStream(w io.Writer, prefix string) *jsonArrayStream {
+	return &jsonArrayStream{w: w, enc: json.NewEncoder(w), prefix: prefix}
+}
+
+// Write appends v to the array, opening it with the first element
+func (s *jsonArrayStream) Write(v interface{}) error {
+	sep := ","
+	if !s.started {
+		sep = s.prefix + "["
+		s.started = true
+	}
+	if _, err := io.WriteString(s.w, sep); err != nil {
+		return err
+	}
+	return s.enc.Encode(v)
+}
+
+// Close ends the array, writing an empty one when nothing was written
+func (s *jsonArrayStream) Close() error {
+	if !s.started {
+		s.started = true
+		_, err := io.WriteString(s.w, s.prefix+"[]")
+		return err
+	}
+	_, err := io.WriteString(s.w, "]")
+	return err
+}
+
+// writeStreamError reports a database error that cut a stream short. Before
+// the first element the client gets the error as usual. After it the status
+// has been sent, so the connection is aborted instead, and a truncated list
+// cannot be taken for a complete one.
+func writeStreamError(w http.ResponseWriter, stream *jsonArrayStream, message string) {
+	if stream.started {
+		panic(http.ErrAbortHandler)
+	}
+	writeUserError(w, http.StatusInternalServerError, "database_error", message)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONArrayStream(t *testing.T) {
+	var b strings.Builder
+	stream := newJSONArrayStream(&b, `{"ips":`)
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		if err := stream.Write(map[string]string{"ip": ip}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b.WriteString(`,"total":2}`)
+
+	var decoded struct {
+		IPs   []map[string]string `json:"ips"`
+		Total int                 `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", b.String(), err)
+	}
+	if len(decoded.IPs) != 2 || decoded.IPs[1]["ip"] != "192.0.2.2" || decoded.Total != 2 {
+		t.Errorf("Unexpected stream: %q", b.String())
+	}
+
+	b.Reset()
+	empty := newJSONArrayStream(&b, "")
+	empty.Close()
+	if b.String() != "[]" {
+		t.Errorf("Expected an empty array, got %q", b.String())
+	}
+}
+
+func TestWriteStreamError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeStreamError(rec, newJSONArrayStream(rec, ""), "Failed to retrieve SMTP failures")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "database_error") {
+		t.Errorf("Expected a 500 before the first element, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	stream := newJSONArrayStream(rec, "")
+	stream.Write(map[string]string{"ip": "192.0.2.1"})
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("Expected the connection aborted after the first element, got %v", recovered)
+		}
+	}()
+	writeStreamError(rec, stream, "Failed to retrieve SMTP failures")
+}
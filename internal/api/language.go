@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"golang-backend-service/internal/i18n"
+
+	"github.com/gorilla/mux"
+)
+
+// languageMiddleware negotiates the language of localized responses from
+// Accept-Language and stores it in the request context
+func languageMiddleware(languages *i18n.Bundle) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			language := languages.Negotiate(r.Header.Get("Accept-Language"))
+			next.ServeHTTP(w, r.WithContext(i18n.WithLanguage(r.Context(), language)))
+		})
+	}
+}
+
+// setContentLanguage marks a response as rendered in the request's language
+// and returns that language
+func setContentLanguage(w http.ResponseWriter, r *http.Request) string {
+	language := i18n.FromContext(r.Context())
+	w.Header().Set("Content-Language", language)
+	w.Header().Add("Vary", "Accept-Language")
+	return language
+}
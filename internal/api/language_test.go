@@ -0,0 +1,30 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-backend-service/internal/i18n"
+)
+
+func TestLanguageMiddleware(t *testing.T) {
+	languages, err := i18n.NewBundle([]string{"en", "de"}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := languageMiddleware(languages)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, setContentLanguage(w, r))
+	}))
+
+	for header, expected := range map[string]string{"": "en", "de-AT,de;q=0.9": "de", "fr": "en"} {
+		req := httptest.NewRequest("GET", "/api/ips/192.0.2.1/reputation", nil)
+		req.Header.Set("Accept-Language", header)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Body.String() != expected || rec.Header().Get("Content-Language") != expected || rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("%q: expected %s, got %q with headers %v", header, expected, rec.Body.String(), rec.Header())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// liveStatsMinutes are the trailing windows reported by the live stats
+var liveStatsMinutes = []int{1, 5, 15}
+
+const liveStatsNote = "Provisional counts read directly from the failure log; they are not deduplicated by the aggregation and do not change the IP's status"
+
+// LiveStatsResponse is an IP's failure counts over the last minutes,
+// computed on request instead of waiting for the next aggregation run
+type LiveStatsResponse struct {
+	IP          string    `json:"ip"`
+	Provisional bool      `json:"provisional"`
+	Note        string    `json:"note"`
+	ComputedAt  time.Time `json:"computed_at"`
+	// AggregatedStatus and AggregatedAt are the last aggregation's result, for
+	// comparison; empty before the IP was first aggregated
+	AggregatedStatus string            `json:"aggregated_status,omitempty"`
+	AggregatedAt     *time.Time        `json:"aggregated_at,omitempty"`
+	Windows          []LiveStatsWindow `json:"windows"`
+}
+
+// LiveStatsWindow is the failure count of one trailing window
+type LiveStatsWindow struct {
+	Window string `json:"window"`
+	database.LiveRejectionCount
+	RejectionsPerMinute float64 `json:"rejections_per_minute"`
+}
+
+// buildLiveStats turns the raw counts into the response
+func buildLiveStats(ip string, counts []database.LiveRejectionCount, metrics *database.IPReputationMetrics, now time.Time) LiveStatsResponse {
+	response := LiveStatsResponse{
+		IP:          ip,
+		Provisional: true,
+		Note:        liveStatsNote,
+		ComputedAt:  now,
+		Windows:     make([]LiveStatsWindow, 0, len(counts)),
+	}
+	if metrics != nil {
+		response.AggregatedStatus = metrics.Status
+		response.AggregatedAt = &metrics.LastUpdated
+	}
+	for _, c := range counts {
+		response.Windows = append(response.Windows, LiveStatsWindow{
+			Window:              fmt.Sprintf("%dm", c.Minutes),
+			LiveRejectionCount:  c,
+			RejectionsPerMinute: float64(c.Rejections) / float64(c.Minutes),
+		})
+	}
+	return response
+}
+
+// @Summary Live failure counts for IP
+// @Description Provisional rejection counts for the last 1, 5 and 15 minutes, read directly from the failure log without waiting for aggregation
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} LiveStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/live-stats [get]
+func (h *ReputationHandler) getIPLiveStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	now := time.Now()
+
+	counts, err := database.GetLiveRejectionCountsContext(r.Context(), ip, liveStatsMinutes, now)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_live_stats_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get live rejection counts")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve live stats")
+		return
+	}
+
+	metrics, err := database.GetIPReputationMetrics(ip)
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			h.logger.WithFields(logrus.Fields{
+				"action": "get_live_stats_metrics_failed",
+				"ip":     ip,
+				"error":  err.Error(),
+			}).Warn("Failed to get aggregated metrics for live stats")
+		}
+		metrics = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(buildLiveStats(ip, counts, metrics, now))
+}
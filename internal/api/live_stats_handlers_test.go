@@ -0,0 +1,29 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestBuildLiveStats(t *testing.T) {
+	now := time.Now()
+	counts := []database.LiveRejectionCount{
+		{Minutes: 1, Rejections: 3, HardRejections: 2, UniqueDomains: 1},
+		{Minutes: 5, Rejections: 10, HardRejections: 4, UniqueDomains: 3},
+	}
+
+	live := buildLiveStats("192.0.2.1", counts, nil, now)
+	if !live.Provisional || live.AggregatedAt != nil || len(live.Windows) != 2 {
+		t.Fatalf("Unexpected live stats: %+v", live)
+	}
+	if w := live.Windows[1]; w.Window != "5m" || w.RejectionsPerMinute != 2 || w.Rejections != 10 {
+		t.Errorf("Unexpected 5m window: %+v", w)
+	}
+
+	metrics := &database.IPReputationMetrics{Status: "warning", LastUpdated: now.Add(-4 * time.Minute)}
+	if live := buildLiveStats("192.0.2.1", counts, metrics, now); live.AggregatedStatus != "warning" || live.AggregatedAt == nil {
+		t.Errorf("Expected the last aggregation included, got %+v", live)
+	}
+}
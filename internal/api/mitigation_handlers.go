@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMitigationWindow = 7 * 24 * time.Hour
+	maxMitigationWindow     = 30 * 24 * time.Hour
+	// maxMitigationSamples caps the NDR samples; forms take a few
+	maxMitigationSamples = 5
+	mitigationTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// mitigationProvider is a mailbox provider with a sender mitigation form
+type mitigationProvider struct {
+	// name is the provider as reputation.ProviderOf returns it
+	name    string
+	label   string
+	formURL string
+	// fields are the form's questions, in form order
+	fields []mitigationFieldSpec
+}
+
+type mitigationFieldSpec struct {
+	name  string
+	label string
+}
+
+var mitigationProviders = map[string]mitigationProvider{
+	"outlook": {
+		name:    reputation.ProviderOutlook,
+		label:   "Microsoft (Outlook.com, Hotmail, Live, MSN)",
+		formURL: "https://olcsupport.office.com/",
+		fields: []mitigationFieldSpec{
+			{"sending_ip", "IP address(es) affected"},
+			{"error_samples", "Error message / NDR you received (complete text)"},
+			{"first_occurrence", "When did the issue start?"},
+			{"last_occurrence", "When did you last see the issue?"},
+			{"estimated_daily_volume", "Approximately how many messages per day do you send to Outlook.com users?"},
+			{"recipient_domains", "Recipient domains affected"},
+		},
+	},
+	"gmail": {
+		name:    reputation.ProviderGmail,
+		label:   "Google (Gmail)",
+		formURL: "https://support.google.com/mail/contact/bulk_send_new",
+		fields: []mitigationFieldSpec{
+			{"sending_ip", "Sending IP address"},
+			{"recipient_domains", "Recipient domains"},
+			{"error_samples", "SMTP error message and bounce text"},
+			{"timeframe", "Date and time range of the delivery problem (UTC)"},
+			{"estimated_daily_volume", "Average daily volume sent to Gmail"},
+			{"rejected_messages", "Messages rejected in the period"},
+		},
+	},
+	"yahoo": {
+		name:    reputation.ProviderYahoo,
+		label:   "Yahoo (Yahoo Mail, AOL)",
+		formURL: "https://senders.yahooinc.com/contact",
+		fields: []mitigationFieldSpec{
+			{"sending_ip", "Sending IP(s)"},
+			{"error_codes", "Error code(s) received"},
+			{"error_samples", "Full SMTP error text"},
+			{"first_occurrence", "First occurrence (UTC)"},
+			{"last_occurrence", "Last occurrence (UTC)"},
+			{"estimated_daily_volume", "Daily volume to Yahoo/AOL"},
+		},
+	},
+}
+
+// mitigationProviderAliases are the other names a provider is asked for by
+var mitigationProviderAliases = map[string]string{
+	"microsoft": "outlook",
+	"hotmail":   "outlook",
+	"google":    "gmail",
+	"aol":       "yahoo",
+}
+
+func mitigationProviderNames() string {
+	names := make([]string, 0, len(mitigationProviders))
+	for name := range mitigationProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// MitigationField is one question of a provider's mitigation form with the
+// answer to paste
+type MitigationField struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// MitigationSample is a rejection to quote as an NDR sample
+type MitigationSample struct {
+	Timestamp       time.Time `json:"timestamp"`
+	RecipientDomain string    `json:"recipient_domain"`
+	MXServer        string    `json:"mx_server,omitempty"`
+	SMTPCode        int       `json:"smtp_code"`
+	EnhancedCode    string    `json:"enhanced_code,omitempty"`
+	Reason          string    `json:"reason"`
+	// Formatted is the sample as quoted in the form
+	Formatted string `json:"formatted"`
+}
+
+// MitigationDataResponse is what a provider's mitigation form asks about an
+// IP, as raw values and pre-formatted answers
+type MitigationDataResponse struct {
+	IP            string    `json:"ip"`
+	Provider      string    `json:"provider"`
+	ProviderLabel string    `json:"provider_label"`
+	FormURL       string    `json:"form_url"`
+	WindowStart   time.Time `json:"window_start"`
+	WindowEnd     time.Time `json:"window_end"`
+	// RejectedMessages are the failures to the provider in the window
+	RejectedMessages int            `json:"rejected_messages"`
+	ErrorCodes       map[string]int `json:"error_codes"`
+	RecipientDomains []string       `json:"recipient_domains"`
+	FirstOccurrence  time.Time      `json:"first_occurrence"`
+	LastOccurrence   time.Time      `json:"last_occurrence"`
+	// EstimatedDailyVolume is mail sent to the provider per day, estimated
+	// from the rejections at an assumed 5% failure rate
+	EstimatedDailyVolume int `json:"estimated_daily_volume"`
+	// BlockReferences are the block list identifiers the rejections quote,
+	// most often quoted first
+	BlockReferences []string           `json:"block_references"`
+	Samples         []MitigationSample `json:"samples"`
+	Fields          []MitigationField  `json:"fields"`
+	// Text is every field as "label: value" lines, ready to paste
+	Text string `json:"text"`
+}
+
+// formatCount writes n with thousands separators
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func formatMitigationSample(s MitigationSample) string {
+	text := fmt.Sprintf("%s: %d", s.Timestamp.UTC().Format(mitigationTimeFormat), s.SMTPCode)
+	if s.EnhancedCode != "" {
+		text += " " + s.EnhancedCode
+	}
+	text += " " + s.Reason
+	if s.MXServer != "" {
+		text += fmt.Sprintf(" (remote server %s, recipient domain %s)", s.MXServer, s.RecipientDomain)
+	} else {
+		text += fmt.Sprintf(" (recipient domain %s)", s.RecipientDomain)
+	}
+	return text
+}
+
+// buildMitigationData picks the failures to the provider, newest first, and
+// fills its form. ok is false when there are none.
+func buildMitigationData(ip string, provider mitigationProvider, failures []database.SMTPFailure, since, now time.Time) (MitigationDataResponse, bool) {
+	resp := MitigationDataResponse{
+		IP:            ip,
+		Provider:      provider.name,
+		ProviderLabel: provider.label,
+		FormURL:       provider.formURL,
+		WindowStart:   since,
+		WindowEnd:     now,
+		ErrorCodes:    map[string]int{},
+		Samples:       []MitigationSample{},
+	}
+
+	var matched []database.SMTPFailure
+	domains := map[string]bool{}
+	for _, f := range failures {
+		if reputation.ProviderOf(f) != provider.name {
+			continue
+		}
+		matched = append(matched, f)
+		code := f.EnhancedCode
+		if code == "" {
+			code = strconv.Itoa(f.SMTPCode)
+		}
+		resp.ErrorCodes[code]++
+		domains[f.RecipientDomain] = true
+		if resp.FirstOccurrence.IsZero() || f.Timestamp.Before(resp.FirstOccurrence) {
+			resp.FirstOccurrence = f.Timestamp
+		}
+		if f.Timestamp.After(resp.LastOccurrence) {
+			resp.LastOccurrence = f.Timestamp
+		}
+	}
+	if len(matched) == 0 {
+		return resp, false
+	}
+	resp.RejectedMessages = len(matched)
+	resp.BlockReferences = blockReferencesOf(matched, provider.name)
+	for d := range domains {
+		resp.RecipientDomains = append(resp.RecipientDomains, d)
+	}
+	sort.Strings(resp.RecipientDomains)
+
+	days := now.Sub(since).Hours() / 24
+	resp.EstimatedDailyVolume = int(float64(reputation.EstimateSentFromFailures(len(matched))) / days)
+
+	// Permanent rejections make the case; one sample per distinct code and
+	// reason family, newest first
+	sort.SliceStable(matched, func(i, j int) bool {
+		pi, pj := matched[i].SMTPCode >= 500, matched[j].SMTPCode >= 500
+		if pi != pj {
+			return pi
+		}
+		return matched[i].Timestamp.After(matched[j].Timestamp)
+	})
+	seen := map[string]bool{}
+	for _, f := range matched {
+		key := fmt.Sprintf("%d %s %s", f.SMTPCode, f.EnhancedCode, f.Family())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sample := MitigationSample{
+			Timestamp:       f.Timestamp,
+			RecipientDomain: f.RecipientDomain,
+			MXServer:        f.MXServer,
+			SMTPCode:        f.SMTPCode,
+			EnhancedCode:    f.EnhancedCode,
+			Reason:          f.Reason,
+		}
+		sample.Formatted = formatMitigationSample(sample)
+		resp.Samples = append(resp.Samples, sample)
+		if len(resp.Samples) == maxMitigationSamples {
+			break
+		}
+	}
+
+	codes := make([]string, 0, len(resp.ErrorCodes))
+	for code := range resp.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if resp.ErrorCodes[codes[i]] != resp.ErrorCodes[codes[j]] {
+			return resp.ErrorCodes[codes[i]] > resp.ErrorCodes[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	for i, code := range codes {
+		codes[i] = fmt.Sprintf("%s (%s)", code, formatCount(resp.ErrorCodes[code]))
+	}
+	samples := make([]string, len(resp.Samples))
+	for i, s := range resp.Samples {
+		samples[i] = s.Formatted
+	}
+	first := resp.FirstOccurrence.UTC().Format(mitigationTimeFormat)
+	last := resp.LastOccurrence.UTC().Format(mitigationTimeFormat)
+	values := map[string]string{
+		"sending_ip":             ip,
+		"recipient_domains":      strings.Join(resp.RecipientDomains, ", "),
+		"error_codes":            strings.Join(codes, ", "),
+		"error_samples":          strings.Join(samples, "\n"),
+		"first_occurrence":       first,
+		"last_occurrence":        last,
+		"timeframe":              first + " to " + last,
+		"rejected_messages":      formatCount(resp.RejectedMessages),
+		"estimated_daily_volume": "approximately " + formatCount(resp.EstimatedDailyVolume),
+	}
+
+	var text strings.Builder
+	for _, spec := range provider.fields {
+		field := MitigationField{Name: spec.name, Label: spec.label, Value: values[spec.name]}
+		resp.Fields = append(resp.Fields, field)
+		if strings.Contains(field.Value, "\n") {
+			fmt.Fprintf(&text, "%s:\n%s\n", field.Label, field.Value)
+		} else {
+			fmt.Fprintf(&text, "%s: %s\n", field.Label, field.Value)
+		}
+	}
+	resp.Text = text.String()
+	return resp, true
+}
+
+// @Summary Get mitigation form data for IP
+// @Description The answers a mailbox provider's sender mitigation form asks for about a blocked IP: sample NDRs (one per distinct error, permanent ones first), the first and last rejection, the error codes and the daily volume to the provider, estimated from the rejections at an assumed 5% failure rate. Fields follow the form's order and are pre-formatted; text joins them for pasting. Recipient addresses in NDR text are redacted.
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param provider query string true "outlook, gmail or yahoo"
+// @Param window query string false "Time window (e.g., 24h, 168h), at most 720h" default(168h)
+// @Success 200 {object} MitigationDataResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/mitigation-data [get]
+func (h *ReputationHandler) getIPMitigationDataHandler(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("provider")))
+	if alias, ok := mitigationProviderAliases[name]; ok {
+		name = alias
+	}
+	provider, ok := mitigationProviders[name]
+	if !ok {
+		writeUserError(w, http.StatusBadRequest, "invalid_provider", "provider must be one of "+mitigationProviderNames())
+		return
+	}
+	window := defaultMitigationWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		window, err = time.ParseDuration(raw)
+		if err != nil || window <= 0 || window > maxMitigationWindow {
+			writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.; at most 720h)")
+			return
+		}
+	}
+
+	now := time.Now()
+	since := now.Add(-window)
+	failures, err := database.GetSMTPFailuresByIPAndInstanceContext(r.Context(), ip, "", since)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_mitigation_data_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get SMTP failures")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve SMTP failures")
+		return
+	}
+	data, found := buildMitigationData(ip, provider, h.redactor.SMTPFailures(failures), since, now)
+	if !found {
+		writeUserError(w, http.StatusNotFound, "no_rejections", fmt.Sprintf("No rejections by %s in the last %s", provider.name, window))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
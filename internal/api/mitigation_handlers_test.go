@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func mitigationFailure(domain, mx string, smtpCode int, enhanced, reason string, ts time.Time) database.SMTPFailure {
+	return database.SMTPFailure{
+		SendingIP:       "192.0.2.1",
+		RecipientEmail:  "user@" + domain,
+		RecipientDomain: domain,
+		MXServer:        mx,
+		SMTPCode:        smtpCode,
+		EnhancedCode:    enhanced,
+		Reason:          reason,
+		Timestamp:       ts,
+	}
+}
+
+func TestBuildMitigationData(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-7 * 24 * time.Hour)
+	blocked := "Access denied, banned sending IP [192.0.2.1] (S3150)"
+	failures := []database.SMTPFailure{
+		mitigationFailure("hotmail.com", "hotmail-com.olc.protection.outlook.com", 550, "5.7.1", blocked, now.Add(-time.Hour)),
+		mitigationFailure("outlook.com", "outlook-com.olc.protection.outlook.com", 550, "5.7.1", blocked, now.Add(-2*time.Hour)),
+		mitigationFailure("live.co.uk", "", 451, "4.7.650", "Temporarily rate limited due to IP reputation", now.Add(-3*time.Hour)),
+		// A company hosted at Microsoft
+		mitigationFailure("contoso.example", "contoso-example.mail.protection.outlook.com", 550, "5.7.606", "Access denied, banned sending IP", now.Add(-3*24*time.Hour)),
+		mitigationFailure("gmail.com", "gmail-smtp-in.l.google.com", 421, "4.7.28", "Unusual rate of unsolicited mail", now.Add(-time.Hour)),
+	}
+
+	data, ok := buildMitigationData("192.0.2.1", mitigationProviders["outlook"], failures, since, now)
+	if !ok {
+		t.Fatal("Expected rejections by outlook")
+	}
+	if data.RejectedMessages != 4 || data.ErrorCodes["5.7.1"] != 2 || len(data.RecipientDomains) != 4 {
+		t.Errorf("Unexpected counts: %+v", data)
+	}
+	if !data.FirstOccurrence.Equal(now.Add(-3*24*time.Hour)) || !data.LastOccurrence.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Unexpected occurrences %s - %s", data.FirstOccurrence, data.LastOccurrence)
+	}
+	if len(data.BlockReferences) != 1 || data.BlockReferences[0] != "S3150" {
+		t.Errorf("Expected the quoted block reference, got %v", data.BlockReferences)
+	}
+	// 4 rejections at 5% is 80 messages over 7 days
+	if data.EstimatedDailyVolume != 11 {
+		t.Errorf("EstimatedDailyVolume = %d, want 11", data.EstimatedDailyVolume)
+	}
+
+	// One sample per distinct error, permanent ones first
+	if len(data.Samples) != 3 || data.Samples[0].RecipientDomain != "hotmail.com" || data.Samples[2].SMTPCode != 451 {
+		t.Fatalf("Unexpected samples: %+v", data.Samples)
+	}
+	if want := "2024-05-08 11:00 UTC: 550 5.7.1 " + blocked + " (remote server hotmail-com.olc.protection.outlook.com, recipient domain hotmail.com)"; data.Samples[0].Formatted != want {
+		t.Errorf("Formatted = %q, want %q", data.Samples[0].Formatted, want)
+	}
+
+	if len(data.Fields) != len(mitigationProviders["outlook"].fields) || data.Fields[0].Value != "192.0.2.1" {
+		t.Errorf("Unexpected fields: %+v", data.Fields)
+	}
+	if !strings.Contains(data.Text, "When did the issue start?: 2024-05-05 12:00 UTC\n") {
+		t.Errorf("Unexpected text:\n%s", data.Text)
+	}
+
+	if _, ok := buildMitigationData("192.0.2.1", mitigationProviders["yahoo"], failures, since, now); ok {
+		t.Error("Expected no rejections by yahoo")
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestMitigationDataValidation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	handler := &ReputationHandler{logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/{ip}/mitigation-data", handler.getIPMitigationDataHandler).Methods("GET")
+
+	for _, target := range []string{
+		"/api/ips/192.0.2.1/mitigation-data",
+		"/api/ips/192.0.2.1/mitigation-data?provider=example",
+		"/api/ips/192.0.2.1/mitigation-data?provider=outlook&window=1000h",
+		"/api/ips/not-an-ip/mitigation-data?provider=outlook",
+	} {
+		if rec := serveConfig(router, "GET", target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"golang-backend-service/internal/alerting"
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationRouteRequest represents the request body for creating or
+// replacing a notification route
+type NotificationRouteRequest struct {
+	PoolID   *int   `json:"pool_id"`  // Omit to match any pool
+	TeamID   *int   `json:"team_id"`  // Omit to match any team
+	Severity string `json:"severity"` // info, warning or critical; empty matches any
+	Notifier string `json:"notifier"` // slack, email, webhook or pagerduty
+	Target   string `json:"target"`   // Slack channel, email address, URL or PagerDuty routing key
+	Enabled  *bool  `json:"enabled"`  // Defaults to true
+}
+
+// ResolveRoutesResponse lists where an alert would be sent
+type ResolveRoutesResponse struct {
+	IP       string            `json:"ip"`
+	Severity string            `json:"severity"`
+	Targets  []alerting.Target `json:"targets"`
+}
+
+// NotificationRouteHandler manages the alert routing table
+type NotificationRouteHandler struct {
+	router    *alerting.Router
+	ownership *OwnershipHandler
+	logger    *logrus.Logger
+}
+
+// NewNotificationRouteHandler creates a new notification route handler
+func NewNotificationRouteHandler(router *alerting.Router, logger *logrus.Logger) *NotificationRouteHandler {
+	return &NotificationRouteHandler{router: router, ownership: NewOwnershipHandler(logger), logger: logger}
+}
+
+// decodeRoute reads and validates a route request
+func decodeRoute(w http.ResponseWriter, r *http.Request) (*database.NotificationRoute, bool) {
+	var req NotificationRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return nil, false
+	}
+
+	req.Notifier = strings.ToLower(strings.TrimSpace(req.Notifier))
+	req.Severity = strings.ToLower(strings.TrimSpace(req.Severity))
+	req.Target = strings.TrimSpace(req.Target)
+	if !alerting.ValidNotifier(req.Notifier) {
+		writeUserError(w, http.StatusBadRequest, "invalid_notifier", "notifier must be slack, email, webhook or pagerduty")
+		return nil, false
+	}
+	if req.Severity != "" && !alerting.ValidSeverity(req.Severity) {
+		writeUserError(w, http.StatusBadRequest, "invalid_severity", "severity must be info, warning or critical")
+		return nil, false
+	}
+	if msg := validateRouteTarget(req.Notifier, req.Target); msg != "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_target", msg)
+		return nil, false
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &database.NotificationRoute{
+		PoolID:    req.PoolID,
+		TeamID:    req.TeamID,
+		Severity:  req.Severity,
+		Notifier:  req.Notifier,
+		Target:    req.Target,
+		Enabled:   enabled,
+		CreatedBy: principalName(r),
+	}, true
+}
+
+// validateRouteTarget returns why target cannot be sent to with notifier, or
+// an empty string
+func validateRouteTarget(notifier, target string) string {
+	if target == "" {
+		return "target is required"
+	}
+	switch notifier {
+	case alerting.NotifierEmail:
+		if addr, err := mail.ParseAddress(target); err != nil || addr.Address != target {
+			return "target must be an email address"
+		}
+	case alerting.NotifierWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "target must be an http or https URL"
+		}
+	}
+	return ""
+}
+
+// maskedTargetPrefix is how much of a route target callers below admin see,
+// like the prefix shown of an API key
+const maskedTargetPrefix = 4
+
+// maskRouteTarget hides all but the start of a target: webhook URLs and
+// PagerDuty routing keys are secrets
+func maskRouteTarget(target string) string {
+	runes := []rune(target)
+	if len(runes) <= maskedTargetPrefix {
+		return "****"
+	}
+	return string(runes[:maskedTargetPrefix]) + "****"
+}
+
+// targetsVisible reports whether the caller may see route targets: admins,
+// and callers of an API without authentication configured
+func targetsVisible(r *http.Request) bool {
+	principal := auth.PrincipalFromContext(r.Context())
+	return principal == nil || principal.Allows(auth.RoleAdmin)
+}
+
+// @Summary List notification routes
+// @Description The routing table deciding which notifier targets receive alerts for each pool, team and severity. Targets are masked for callers below admin.
+// @Tags alerting
+// @Produce json
+// @Success 200 {array} database.NotificationRoute
+// @Failure 500 {object} ErrorResponse
+// @Router /api/notification-routes [get]
+func (h *NotificationRouteHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	routes, err := database.ListNotificationRoutes()
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "list_notification_routes")
+		return
+	}
+	if !targetsVisible(r) {
+		for i := range routes {
+			routes[i].Target = maskRouteTarget(routes[i].Target)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routes)
+}
+
+// @Summary Create notification route
+// @Description Send alerts matching a pool and/or team and a severity to a notifier target. Omitted fields match any alert; routes naming a pool or team replace the catch-all routes for alerts they match.
+// @Tags alerting
+// @Accept json
+// @Produce json
+// @Param route body NotificationRouteRequest true "Route"
+// @Success 201 {object} database.NotificationRoute
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/notification-routes [post]
+func (h *NotificationRouteHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	route, ok := decodeRoute(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := database.CreateNotificationRoute(route)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "create_notification_route")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "notification_route_created",
+		"route_id": created.ID,
+		"notifier": created.Notifier,
+		"author":   created.CreatedBy,
+	}).Info("Notification route created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary Replace notification route
+// @Tags alerting
+// @Accept json
+// @Produce json
+// @Param id path int true "Route ID"
+// @Param route body NotificationRouteRequest true "Route"
+// @Success 200 {object} database.NotificationRoute
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/notification-routes/{id} [put]
+func (h *NotificationRouteHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	route, ok := decodeRoute(w, r)
+	if !ok {
+		return
+	}
+	route.ID = id
+
+	updated, err := database.UpdateNotificationRoute(route)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "update_notification_route")
+		return
+	}
+	if updated == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "Notification route not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "notification_route_updated",
+		"route_id": id,
+		"author":   route.CreatedBy,
+	}).Info("Notification route updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// @Summary Delete notification route
+// @Tags alerting
+// @Produce json
+// @Param id path int true "Route ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/notification-routes/{id} [delete]
+func (h *NotificationRouteHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteNotificationRoute(id)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "delete_notification_route")
+		return
+	}
+	if found {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "notification_route_deleted",
+			"route_id": id,
+			"author":   principalName(r),
+		}).Info("Notification route deleted")
+	}
+	writeDeleted(w, found, "Notification route")
+}
+
+// @Summary Resolve notification targets
+// @Description Preview where an alert about an IP at a severity would be sent, using the IP's pools and owning teams. Targets are masked for callers below admin.
+// @Tags alerting
+// @Produce json
+// @Param ip query string true "IP address"
+// @Param severity query string true "info, warning or critical"
+// @Success 200 {object} ResolveRoutesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/notification-routes/resolve [get]
+func (h *NotificationRouteHandler) HandleResolve(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(r.URL.Query().Get("ip"))
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", err.Error())
+		return
+	}
+	severity := strings.ToLower(r.URL.Query().Get("severity"))
+	if !alerting.ValidSeverity(severity) {
+		writeUserError(w, http.StatusBadRequest, "invalid_severity", "severity must be info, warning or critical")
+		return
+	}
+
+	targets, err := h.router.Targets(ip, severity)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "resolve_notification_routes",
+			"error":  err.Error(),
+		}).Error("Failed to resolve notification routes")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to resolve notification routes")
+		return
+	}
+	if !targetsVisible(r) {
+		for i := range targets {
+			targets[i].Target = maskRouteTarget(targets[i].Target)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ResolveRoutesResponse{IP: ip, Severity: severity, Targets: targets})
+}
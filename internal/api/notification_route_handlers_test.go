@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/alerting"
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type routeStore struct{}
+
+func (routeStore) ListNotificationRoutes() ([]database.NotificationRoute, error) {
+	pool := 3
+	return []database.NotificationRoute{
+		{ID: 1, Notifier: alerting.NotifierSlack, Target: "#deliverability", Enabled: true},
+		{ID: 2, PoolID: &pool, Notifier: alerting.NotifierSlack, Target: "#marketing", Enabled: true},
+	}, nil
+}
+
+func (routeStore) GetIPPoolIDs(ip string) ([]int, error) {
+	if ip == "192.0.2.10" {
+		return []int{3}, nil
+	}
+	return nil, nil
+}
+
+func (routeStore) GetIPOwners(ip string) ([]database.IPOwner, error) {
+	return nil, nil
+}
+
+func (routeStore) IsIPArchived(ip string) (bool, error) {
+	return false, nil
+}
+
+func TestResolveNotificationRoutes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, AlertRouter: alerting.NewRouter(routeStore{})})
+
+	for ip, want := range map[string]string{"192.0.2.10": "#marketing", "192.0.2.20": "#deliverability"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/notification-routes/resolve?severity=warning&ip="+ip, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp ResolveRoutesResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Targets) != 1 || resp.Targets[0].Target != want {
+			t.Errorf("%s: expected %s, got %+v", ip, want, resp.Targets)
+		}
+	}
+}
+
+func TestResolveNotificationRoutesMasksTargets(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, AlertRouter: alerting.NewRouter(routeStore{})})
+
+	for role, want := range map[auth.Role]string{auth.RoleReadOnly: "#mar****", auth.RoleOperator: "#mar****", auth.RoleAdmin: "#marketing"} {
+		req := httptest.NewRequest("GET", "/api/notification-routes/resolve?severity=warning&ip=192.0.2.10", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Kind: auth.KindUser, Username: "u", Role: role}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp ResolveRoutesResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Targets) != 1 || resp.Targets[0].Target != want {
+			t.Errorf("%s: expected %s, got %+v", role, want, resp.Targets)
+		}
+	}
+	if got := maskRouteTarget("#ops"); got != "****" {
+		t.Errorf("Expected a short target masked whole, got %s", got)
+	}
+}
+
+func TestNotificationRouteValidation(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               string
+	}{
+		{"POST", "/api/notification-routes", `not json`, "invalid_request"},
+		{"POST", "/api/notification-routes", `{"notifier":"sms","target":"+15550100"}`, "invalid_notifier"},
+		{"POST", "/api/notification-routes", `{"notifier":"slack","target":"#ops","severity":"urgent"}`, "invalid_severity"},
+		{"POST", "/api/notification-routes", `{"notifier":"slack","target":" "}`, "invalid_target"},
+		{"POST", "/api/notification-routes", `{"notifier":"email","target":"ops"}`, "invalid_target"},
+		{"POST", "/api/notification-routes", `{"notifier":"webhook","target":"ftp://example.com/hook"}`, "invalid_target"},
+		{"PUT", "/api/notification-routes/abc", `{"notifier":"slack","target":"#ops"}`, "invalid_id"},
+		{"DELETE", "/api/notification-routes/abc", "", "invalid_id"},
+		{"GET", "/api/notification-routes/resolve?ip=nope&severity=info", "", "invalid_ip"},
+		{"GET", "/api/notification-routes/resolve?ip=192.0.2.1", "", "invalid_severity"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		var resp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusBadRequest || resp.Error != tc.code {
+			t.Errorf("%s %s %s: expected 400 %s, got %d %s", tc.method, tc.path, tc.body, tc.code, rec.Code, resp.Error)
+		}
+	}
+}
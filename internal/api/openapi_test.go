@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"golang-backend-service/client"
+	"golang-backend-service/internal/openapi"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestOpenAPISpecIsCurrent(t *testing.T) {
+	opts := OpenAPISources
+	opts.Root = "../.."
+	doc, _, err := openapi.Generate(DocumentedRoutes(), opts)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	spec, err := doc.MarshalIndent()
+	if err != nil {
+		t.Fatalf("MarshalIndent failed: %v", err)
+	}
+	if !bytes.Equal(spec, openAPISpec) {
+		t.Error("openapi.json is stale; run go generate ./internal/api")
+	}
+
+	src, err := openapi.GenerateClient(doc, "client")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	committed, err := os.ReadFile("../../client/client.go")
+	if err != nil {
+		t.Fatalf("Failed to read the client: %v", err)
+	}
+	if !bytes.Equal(src, committed) {
+		t.Error("client/client.go is stale; run go generate ./internal/api")
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, Auth: &AuthConfig{Required: true}})
+
+	rec := serveConfig(router, "GET", "/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the description without credentials, got %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version || doc.Paths["/api/ips/{ip}/reputation"]["get"] == nil {
+		t.Errorf("Unexpected description: %s with %d paths", doc.OpenAPI, len(doc.Paths))
+	}
+}
+
+func TestGeneratedClient(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	server := httptest.NewServer(SetupRoutesWithDependencies(Dependencies{Logger: logger}))
+	defer server.Close()
+	c := client.New(server.URL)
+
+	health, err := c.HealthCheck(context.Background())
+	if err != nil || health.Status != "healthy" {
+		t.Fatalf("Expected healthy, got %+v %v", health, err)
+	}
+
+	_, err = c.ResolveRecommendations(context.Background(), &client.ResolveRecommendationsParams{Status: "degraded"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "invalid_status" {
+		t.Errorf("Expected a 400 invalid_status error, got %v", err)
+	}
+}
@@ -0,0 +1,539 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateTeamRequest represents the request body for creating a team or pool
+type CreateTeamRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TeamMemberRequest represents the request body for adding a team member
+type TeamMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// PoolIPRequest represents the request body for adding an IP to a pool
+type PoolIPRequest struct {
+	IP string `json:"ip"`
+}
+
+// CreateAssignmentRequest represents the request body for assigning an owner
+type CreateAssignmentRequest struct {
+	OwnerType  string `json:"owner_type"`  // user or team
+	OwnerID    int    `json:"owner_id"`    // user or team ID
+	TargetType string `json:"target_type"` // ip or pool
+	Target     string `json:"target"`      // IP address or pool name
+}
+
+// OwnershipHandler manages teams, IP pools and ownership assignments
+type OwnershipHandler struct {
+	logger *logrus.Logger
+}
+
+// NewOwnershipHandler creates a new ownership handler
+func NewOwnershipHandler(logger *logrus.Logger) *OwnershipHandler {
+	return &OwnershipHandler{logger: logger}
+}
+
+// parseOwnerRef parses an owner reference written as "user:12", "team:3" or
+// "me" (the authenticated dashboard user)
+func parseOwnerRef(r *http.Request, ref string) (string, int, error) {
+	if ref == "me" {
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil || principal.Kind != auth.KindUser {
+			return "", 0, errors.New("owner=me requires a dashboard session")
+		}
+		return database.OwnerUser, principal.UserID, nil
+	}
+
+	ownerType, rawID, ok := strings.Cut(ref, ":")
+	id, err := strconv.Atoi(rawID)
+	if !ok || err != nil || (ownerType != database.OwnerUser && ownerType != database.OwnerTeam) {
+		return "", 0, fmt.Errorf("owner must be me, user:<id> or team:<id>")
+	}
+	return ownerType, id, nil
+}
+
+// writeOwnershipError maps database ownership errors to responses
+func (h *OwnershipHandler) writeOwnershipError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, database.ErrOwnershipConflict):
+		writeUserError(w, http.StatusConflict, "conflict", "It already exists")
+	case errors.Is(err, database.ErrOwnershipReference):
+		writeUserError(w, http.StatusUnprocessableEntity, "invalid_reference", err.Error())
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action": action,
+			"error":  err.Error(),
+		}).Error("Ownership operation failed")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to "+strings.ReplaceAll(action, "_", " "))
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "ID must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeDeleted(w http.ResponseWriter, found bool, what string) {
+	if !found {
+		writeUserError(w, http.StatusNotFound, "not_found", what+" not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+	})
+}
+
+func decodeNamed(w http.ResponseWriter, r *http.Request) (CreateTeamRequest, bool) {
+	var req CreateTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Name is required")
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	return req, true
+}
+
+// @Summary Create team
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param team body CreateTeamRequest true "Team details"
+// @Success 201 {object} database.Team
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/teams [post]
+func (h *OwnershipHandler) HandleCreateTeam(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeNamed(w, r)
+	if !ok {
+		return
+	}
+
+	team, err := database.CreateTeam(req.Name, req.Description)
+	if err != nil {
+		h.writeOwnershipError(w, err, "create_team")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(team)
+}
+
+// @Summary List teams
+// @Tags ownership
+// @Produce json
+// @Success 200 {array} database.Team
+// @Failure 500 {object} ErrorResponse
+// @Router /api/teams [get]
+func (h *OwnershipHandler) HandleListTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := database.ListTeams()
+	if err != nil {
+		h.writeOwnershipError(w, err, "list_teams")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(teams)
+}
+
+// @Summary Delete team
+// @Description Delete a team with its memberships and assignments
+// @Tags ownership
+// @Produce json
+// @Param id path int true "Team ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/teams/{id} [delete]
+func (h *OwnershipHandler) HandleDeleteTeam(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteTeam(id)
+	if err != nil {
+		h.writeOwnershipError(w, err, "delete_team")
+		return
+	}
+	writeDeleted(w, found, "Team")
+}
+
+// @Summary Add team member
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param id path int true "Team ID"
+// @Param member body TeamMemberRequest true "User to add"
+// @Success 201 {object} map[string]interface{}
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/teams/{id}/members [post]
+func (h *OwnershipHandler) HandleAddTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req TeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "user_id is required")
+		return
+	}
+
+	if err := database.AddTeamMember(teamID, req.UserID); err != nil {
+		h.writeOwnershipError(w, err, "add_team_member")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"team_id": teamID,
+		"user_id": req.UserID,
+	})
+}
+
+// @Summary Remove team member
+// @Tags ownership
+// @Produce json
+// @Param id path int true "Team ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/teams/{id}/members/{user_id} [delete]
+func (h *OwnershipHandler) HandleRemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, ok := pathID(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	found, err := database.RemoveTeamMember(teamID, userID)
+	if err != nil {
+		h.writeOwnershipError(w, err, "remove_team_member")
+		return
+	}
+	writeDeleted(w, found, "Team member")
+}
+
+// @Summary Create IP pool
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param pool body CreateTeamRequest true "Pool details"
+// @Success 201 {object} database.IPPool
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/pools [post]
+func (h *OwnershipHandler) HandleCreatePool(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeNamed(w, r)
+	if !ok {
+		return
+	}
+
+	pool, err := database.CreateIPPool(req.Name, req.Description)
+	if err != nil {
+		h.writeOwnershipError(w, err, "create_ip_pool")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pool)
+}
+
+// @Summary List IP pools
+// @Tags ownership
+// @Produce json
+// @Success 200 {array} database.IPPool
+// @Failure 500 {object} ErrorResponse
+// @Router /api/pools [get]
+func (h *OwnershipHandler) HandleListPools(w http.ResponseWriter, r *http.Request) {
+	pools, err := database.ListIPPools()
+	if err != nil {
+		h.writeOwnershipError(w, err, "list_ip_pools")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pools)
+}
+
+// @Summary Delete IP pool
+// @Description Delete a pool with its members and assignments
+// @Tags ownership
+// @Produce json
+// @Param id path int true "Pool ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/pools/{id} [delete]
+func (h *OwnershipHandler) HandleDeletePool(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteIPPool(id)
+	if err != nil {
+		h.writeOwnershipError(w, err, "delete_ip_pool")
+		return
+	}
+	writeDeleted(w, found, "IP pool")
+}
+
+// @Summary Add IP to pool
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param id path int true "Pool ID"
+// @Param ip body PoolIPRequest true "IP to add"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/pools/{id}/ips [post]
+func (h *OwnershipHandler) HandleAddPoolIP(w http.ResponseWriter, r *http.Request) {
+	poolID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req PoolIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "ip is required")
+		return
+	}
+	ip, err := validation.NormalizeIP(req.IP)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", err.Error())
+		return
+	}
+
+	if err := database.AddIPToPool(poolID, ip); err != nil {
+		h.writeOwnershipError(w, err, "add_ip_to_pool")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"pool_id": poolID,
+		"ip":      ip,
+	})
+}
+
+// @Summary Remove IP from pool
+// @Tags ownership
+// @Produce json
+// @Param id path int true "Pool ID"
+// @Param ip path string true "IP Address"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/pools/{id}/ips/{ip} [delete]
+func (h *OwnershipHandler) HandleRemovePoolIP(w http.ResponseWriter, r *http.Request) {
+	poolID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.RemoveIPFromPool(poolID, mux.Vars(r)["ip"])
+	if err != nil {
+		h.writeOwnershipError(w, err, "remove_ip_from_pool")
+		return
+	}
+	writeDeleted(w, found, "Pool member")
+}
+
+// @Summary Create ownership assignment
+// @Description Make a user or team responsible for an IP or pool
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param assignment body CreateAssignmentRequest true "Owner and target"
+// @Success 201 {object} database.OwnershipAssignment
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/assignments [post]
+func (h *OwnershipHandler) HandleCreateAssignment(w http.ResponseWriter, r *http.Request) {
+	var req CreateAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if err := validateAssignment(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	assignment, err := database.CreateOwnershipAssignment(req.OwnerType, req.OwnerID, req.TargetType, req.Target)
+	if err != nil {
+		h.writeOwnershipError(w, err, "create_ownership_assignment")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":        "ownership_assigned",
+		"assignment_id": assignment.ID,
+		"owner":         req.OwnerType + ":" + strconv.Itoa(req.OwnerID),
+		"target":        req.TargetType + ":" + req.Target,
+	}).Info("Ownership assigned")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// validateAssignment checks the owner and target types and normalizes IPs
+func validateAssignment(req *CreateAssignmentRequest) error {
+	if req.OwnerType != database.OwnerUser && req.OwnerType != database.OwnerTeam {
+		return errors.New("owner_type must be user or team")
+	}
+	if req.OwnerID <= 0 {
+		return errors.New("owner_id is required")
+	}
+
+	switch req.TargetType {
+	case database.TargetIP:
+		ip, err := validation.NormalizeIP(req.Target)
+		if err != nil {
+			return err
+		}
+		req.Target = ip
+	case database.TargetPool:
+		if strings.TrimSpace(req.Target) == "" {
+			return errors.New("target pool name is required")
+		}
+	default:
+		return errors.New("target_type must be ip or pool")
+	}
+	return nil
+}
+
+// @Summary List ownership assignments
+// @Tags ownership
+// @Produce json
+// @Param owner query string false "Only assignments of this owner (me, user:<id> or team:<id>)"
+// @Param ip query string false "Only assignments covering this IP, directly or through a pool"
+// @Success 200 {array} database.OwnershipAssignment
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/assignments [get]
+func (h *OwnershipHandler) HandleListAssignments(w http.ResponseWriter, r *http.Request) {
+	var filter database.AssignmentFilter
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		ownerType, ownerID, err := parseOwnerRef(r, owner)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_owner", err.Error())
+			return
+		}
+		filter.OwnerType, filter.OwnerID = ownerType, ownerID
+	}
+	if raw := r.URL.Query().Get("ip"); raw != "" {
+		ip, err := validation.NormalizeIP(raw)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_ip", err.Error())
+			return
+		}
+		filter.IP = ip
+	}
+
+	assignments, err := database.ListOwnershipAssignments(filter)
+	if err != nil {
+		h.writeOwnershipError(w, err, "list_ownership_assignments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// @Summary Delete ownership assignment
+// @Tags ownership
+// @Produce json
+// @Param id path int true "Assignment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/assignments/{id} [delete]
+func (h *OwnershipHandler) HandleDeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteOwnershipAssignment(id)
+	if err != nil {
+		h.writeOwnershipError(w, err, "delete_ownership_assignment")
+		return
+	}
+	writeDeleted(w, found, "Assignment")
+}
+
+// @Summary Get IP owners
+// @Description Users and teams responsible for an IP, directly or through a pool
+// @Tags ownership
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {array} database.IPOwner
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/owners [get]
+func (h *OwnershipHandler) HandleGetIPOwners(w http.ResponseWriter, r *http.Request) {
+	owners, err := database.GetIPOwners(mux.Vars(r)["ip"])
+	if err != nil {
+		h.writeOwnershipError(w, err, "get_ip_owners")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owners)
+}
+
+// @Summary Get IPs owned by a user
+// @Description IPs a user is responsible for directly, through a pool or through a team
+// @Tags ownership
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/{id}/ips [get]
+func (h *OwnershipHandler) HandleGetUserIPs(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	ips, err := database.GetOwnedIPs(database.OwnerUser, id)
+	if err != nil {
+		h.writeOwnershipError(w, err, "get_owned_ips")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ips)
+}
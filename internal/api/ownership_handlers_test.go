@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestParseOwnerRef(t *testing.T) {
+	userReq := httptest.NewRequest("GET", "/", nil)
+	userReq = userReq.WithContext(auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, UserID: 7}))
+	keyReq := httptest.NewRequest("GET", "/", nil)
+	keyReq = keyReq.WithContext(auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindAPIKey, KeyName: "ci"}))
+	anonReq := httptest.NewRequest("GET", "/", nil)
+
+	tests := []struct {
+		name      string
+		req       *http.Request
+		ref       string
+		wantType  string
+		wantID    int
+		wantError bool
+	}{
+		{"user", anonReq, "user:12", database.OwnerUser, 12, false},
+		{"team", anonReq, "team:3", database.OwnerTeam, 3, false},
+		{"me with session", userReq, "me", database.OwnerUser, 7, false},
+		{"me with api key", keyReq, "me", "", 0, true},
+		{"me anonymous", anonReq, "me", "", 0, true},
+		{"unknown type", anonReq, "group:1", "", 0, true},
+		{"missing id", anonReq, "user:", "", 0, true},
+		{"no separator", anonReq, "12", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ownerType, id, err := parseOwnerRef(tt.req, tt.ref)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseOwnerRef(%q) error = %v, wantError %v", tt.ref, err, tt.wantError)
+			}
+			if ownerType != tt.wantType || id != tt.wantID {
+				t.Errorf("parseOwnerRef(%q) = %s:%d, want %s:%d", tt.ref, ownerType, id, tt.wantType, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateAssignment(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        CreateAssignmentRequest
+		wantTarget string
+		wantError  bool
+	}{
+		{"user owns ip", CreateAssignmentRequest{"user", 1, "ip", "192.0.2.010"}, "192.0.2.10", false},
+		{"team owns pool", CreateAssignmentRequest{"team", 2, "pool", "outbound"}, "outbound", false},
+		{"bad owner type", CreateAssignmentRequest{"group", 1, "ip", "192.0.2.1"}, "", true},
+		{"missing owner id", CreateAssignmentRequest{"user", 0, "ip", "192.0.2.1"}, "", true},
+		{"bad ip", CreateAssignmentRequest{"user", 1, "ip", "not-an-ip"}, "", true},
+		{"empty pool", CreateAssignmentRequest{"user", 1, "pool", " "}, "", true},
+		{"bad target type", CreateAssignmentRequest{"user", 1, "domain", "example.com"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := validateAssignment(&req)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("validateAssignment() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && req.Target != tt.wantTarget {
+				t.Errorf("target = %q, want %q", req.Target, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestFilterMetricsByIP(t *testing.T) {
+	metrics := []database.IPReputationMetrics{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}, {IP: "192.0.2.3"}}
+
+	filtered := filterMetricsByIP(metrics, []string{"192.0.2.3", "192.0.2.1", "198.51.100.1"})
+	if len(filtered) != 2 || filtered[0].IP != "192.0.2.1" || filtered[1].IP != "192.0.2.3" {
+		t.Errorf("filterMetricsByIP() = %+v", filtered)
+	}
+	if filtered := filterMetricsByIP(metrics, nil); filtered == nil || len(filtered) != 0 {
+		t.Errorf("filterMetricsByIP(nil) = %#v, want empty slice", filtered)
+	}
+}
+
+func TestOwnershipHandlersRejectInvalidInput(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"team without name", "POST", "/api/teams", `{"description":"x"}`, http.StatusBadRequest},
+		{"member without user", "POST", "/api/teams/1/members", `{}`, http.StatusBadRequest},
+		{"non-numeric team", "DELETE", "/api/teams/abc", "", http.StatusBadRequest},
+		{"pool with bad ip", "POST", "/api/pools/1/ips", `{"ip":"999.1.1.1"}`, http.StatusBadRequest},
+		{"assignment bad target", "POST", "/api/assignments", `{"owner_type":"user","owner_id":1,"target_type":"host","target":"x"}`, http.StatusBadRequest},
+		{"assignments bad owner", "GET", "/api/assignments?owner=me", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ErasureResponse reports the outcome of a right-to-erasure request
+type ErasureResponse struct {
+	Status         string `json:"status"`
+	RowsAnonymized int64  `json:"rows_anonymized"`
+	Message        string `json:"message"`
+}
+
+// PrivacyHandler handles data-protection endpoints
+type PrivacyHandler struct {
+	logger *logrus.Logger
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(logger *logrus.Logger) *PrivacyHandler {
+	return &PrivacyHandler{logger: logger}
+}
+
+// @Summary Erase recipient data
+// @Description Anonymize all stored SMTP failures for a recipient address. Rows are kept so aggregate counts stay intact.
+// @Tags privacy
+// @Produce json
+// @Param email path string true "Recipient email address"
+// @Success 200 {object} ErasureResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/privacy/recipients/{email} [delete]
+func (h *PrivacyHandler) HandleEraseRecipient(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(mux.Vars(r)["email"])
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_email",
+			Message: "Path parameter must be a plain email address",
+		})
+		return
+	}
+
+	rows, err := database.EraseRecipient(email)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":           "recipient_erasure_failed",
+			"recipient_domain": recipientDomain(email),
+			"error":            err.Error(),
+		}).Error("Failed to erase recipient data")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to erase recipient data",
+		})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":           "recipient_erased",
+		"recipient_domain": recipientDomain(email),
+		"rows_anonymized":  rows,
+	}).Info("Recipient data erased")
+
+	json.NewEncoder(w).Encode(ErasureResponse{
+		Status:         "success",
+		RowsAnonymized: rows,
+		Message:        "Recipient data has been anonymized",
+	})
+}
+
+// recipientDomain returns the domain of a recipient address, which erasure
+// keeps, so that erasures can be logged without the address itself
+func recipientDomain(email string) string {
+	return email[strings.LastIndex(email, "@")+1:]
+}
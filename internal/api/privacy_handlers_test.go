@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestEraseRecipientRejectsInvalidEmail(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: log})
+
+	for _, email := range []string{"not-an-email", "Alice%20%3Calice@example.com%3E"} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/privacy/recipients/"+email, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", email, rec.Code)
+		}
+	}
+}
+
+func TestEraseRecipientKeepsAddressOutOfLogsAndMetrics(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: log})
+
+	// Rejected, so always access logged, but the path holds the address
+	req := httptest.NewRequest(http.MethodDelete, "/api/privacy/recipients/Alice%20%3Calice@example.com%3E", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+
+	logged := false
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "HTTP request processed" {
+			logged = true
+		}
+		for key, value := range entry.Data {
+			if strings.Contains(fmt.Sprint(value), "alice@") {
+				t.Errorf("Expected the address masked, got %s=%v", key, value)
+			}
+		}
+	}
+	if !logged {
+		t.Error("Expected the request access logged")
+	}
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counted := false
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if strings.Contains(label.GetValue(), "alice@") {
+					t.Errorf("Expected the address masked, got %s{%s=%q}", family.GetName(), label.GetName(), label.GetValue())
+				}
+				if family.GetName() == "http_requests_total" && label.GetValue() == "/api/privacy/recipients/{redacted}" {
+					counted = true
+				}
+			}
+		}
+	}
+	if !counted {
+		t.Error("Expected the request counted under the masked path")
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReasonFamilyWindow = 24 * time.Hour
+	maxReasonFamilyWindow     = 30 * 24 * time.Hour
+	defaultReasonFamilyLimit  = 50
+)
+
+// ReasonFamily summarizes the failures whose reasons share a template
+type ReasonFamily struct {
+	Family   string `json:"family"`
+	Failures int    `json:"failures"`
+	// Share is the family's fraction of all failures in the window
+	Share float64 `json:"share"`
+	// IPs holds the sending IPs that saw the family, most failures first
+	IPs []string `json:"ips"`
+	// Example is the newest reason of the family, with the recipient
+	// address redacted like in the failures
+	Example   string    `json:"example"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ReasonFamiliesResponse lists the reason families of a window, most
+// failures first
+type ReasonFamiliesResponse struct {
+	Window   string         `json:"window"`
+	Failures int            `json:"failures"`
+	Families []ReasonFamily `json:"families"`
+}
+
+// buildReasonFamilies merges the per-IP counts into families, leaving out
+// the IPs in hidden, and keeps the limit largest
+func buildReasonFamilies(counts []database.ReasonFamilyCount, hidden hiddenIPs, limit int) ReasonFamiliesResponse {
+	byFamily := map[string]*ReasonFamily{}
+	ipCounts := map[string]map[string]int{}
+	response := ReasonFamiliesResponse{Families: []ReasonFamily{}}
+	for _, c := range counts {
+		if hidden.hides(c.IP) {
+			continue
+		}
+		family, ok := byFamily[c.Family]
+		if !ok {
+			family = &ReasonFamily{Family: c.Family, Example: c.Example, FirstSeen: c.FirstSeen, LastSeen: c.LastSeen}
+			byFamily[c.Family] = family
+			ipCounts[c.Family] = map[string]int{}
+		}
+		family.Failures += c.Count
+		ipCounts[c.Family][c.IP] += c.Count
+		response.Failures += c.Count
+		if c.FirstSeen.Before(family.FirstSeen) {
+			family.FirstSeen = c.FirstSeen
+		}
+		if c.LastSeen.After(family.LastSeen) {
+			family.LastSeen = c.LastSeen
+			family.Example = c.Example
+		}
+	}
+
+	for name, family := range byFamily {
+		family.IPs = topKeys(ipCounts[name], len(ipCounts[name]))
+		family.Share = float64(family.Failures) / float64(response.Failures)
+		response.Families = append(response.Families, *family)
+	}
+	sort.Slice(response.Families, func(i, j int) bool {
+		if response.Families[i].Failures != response.Families[j].Failures {
+			return response.Families[i].Failures > response.Families[j].Failures
+		}
+		return response.Families[i].Family < response.Families[j].Family
+	})
+	if len(response.Families) > limit {
+		response.Families = response.Families[:limit]
+	}
+	return response
+}
+
+// @Summary Get SMTP reason families
+// @Description Failures grouped by reason family: the reason with IPs, recipients, block and queue IDs, numbers and URLs replaced by placeholders, so one rejection reported with different block IDs is counted once
+// @Tags analytics
+// @Produce json
+// @Param window query string false "Time window (e.g., 24h, 168h; at most 720h)" default(24h)
+// @Param ip query string false "Only failures of this sending IP"
+// @Param domain query string false "Only failures to this organizational domain"
+// @Param limit query int false "Maximum families (1-500)" default(50)
+// @Param include_archived query bool false "Include archived IPs"
+// @Param include_excluded query bool false "Include IPs in aggregation exclusions"
+// @Success 200 {object} ReasonFamiliesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/analytics/reason-families [get]
+func (h *ReputationHandler) getReasonFamiliesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := defaultReasonFamilyWindow
+	if raw := query.Get("window"); raw != "" {
+		var err error
+		window, err = time.ParseDuration(raw)
+		if err != nil || window <= 0 || window > maxReasonFamilyWindow {
+			writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.; at most 720h)")
+			return
+		}
+	}
+	ip := ""
+	if raw := query.Get("ip"); raw != "" {
+		var err error
+		if ip, err = validation.NormalizeIP(raw); err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+			return
+		}
+	}
+	domain := ""
+	if raw := query.Get("domain"); raw != "" {
+		normalized, err := validation.NormalizeDomain(raw)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_domain", "Invalid domain")
+			return
+		}
+		domain = validation.RegistrableDomain(normalized)
+	}
+	limit := defaultReasonFamilyLimit
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > 500 {
+			writeUserError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	counts, err := database.GetReasonFamilyCounts(time.Now().Add(-window), ip, domain)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_reason_families_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get reason families")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve reason families")
+		return
+	}
+	hidden, err := dashboardHiddenIPs(r)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_reason_families_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get hidden IPs")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve reason families")
+		return
+	}
+
+	response := buildReasonFamilies(h.redactor.ReasonFamilyCounts(counts), hidden, limit)
+	response.Window = window.String()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
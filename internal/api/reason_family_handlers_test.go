@@ -0,0 +1,63 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBuildReasonFamilies(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	spamhaus := "550 5.7.1 client host [<ip>] blocked using zen.spamhaus.org; <url>"
+	quota := "552 mailbox <<email>> is over quota"
+	counts := []database.ReasonFamilyCount{
+		{Family: spamhaus, IP: "192.0.2.1", Count: 3, Example: "550 5.7.1 Client host [192.0.2.1] blocked", FirstSeen: now.Add(-3 * time.Hour), LastSeen: now.Add(-time.Hour)},
+		{Family: spamhaus, IP: "192.0.2.2", Count: 5, Example: "550 5.7.1 Client host [192.0.2.2] blocked", FirstSeen: now.Add(-2 * time.Hour), LastSeen: now},
+		// A legacy row of the same IP and family
+		{Family: spamhaus, IP: "192.0.2.1", Count: 1, Example: "550 5.7.1 Client host [192.0.2.1] blocked", FirstSeen: now.Add(-5 * time.Hour), LastSeen: now.Add(-5 * time.Hour)},
+		{Family: quota, IP: "192.0.2.1", Count: 1, FirstSeen: now, LastSeen: now},
+		{Family: quota, IP: "192.0.2.9", Count: 20, FirstSeen: now, LastSeen: now},
+	}
+
+	resp := buildReasonFamilies(counts, hiddenIPs{archived: map[string]bool{"192.0.2.9": true}}, 50)
+
+	if resp.Failures != 10 || len(resp.Families) != 2 {
+		t.Fatalf("Expected the archived IP left out, got %+v", resp)
+	}
+	top := resp.Families[0]
+	if top.Family != spamhaus || top.Failures != 9 || math.Abs(top.Share-0.9) > 1e-9 {
+		t.Errorf("Unexpected top family: %+v", top)
+	}
+	if len(top.IPs) != 2 || top.IPs[0] != "192.0.2.2" {
+		t.Errorf("Expected IPs by failures, got %v", top.IPs)
+	}
+	if !top.FirstSeen.Equal(now.Add(-5*time.Hour)) || !top.LastSeen.Equal(now) || top.Example != "550 5.7.1 Client host [192.0.2.2] blocked" {
+		t.Errorf("Expected the range and newest example over every row, got %+v", top)
+	}
+
+	if limited := buildReasonFamilies(counts, hiddenIPs{}, 1); len(limited.Families) != 1 || limited.Families[0].Family != quota {
+		t.Errorf("Expected only the largest family, got %+v", limited.Families)
+	}
+	if empty := buildReasonFamilies(nil, hiddenIPs{}, 50); empty.Families == nil || empty.Failures != 0 {
+		t.Errorf("Expected an empty list, got %+v", empty)
+	}
+}
+
+func TestReasonFamiliesRejectsInvalidParameters(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	handler := &ReputationHandler{logger: logger}
+
+	for _, query := range []string{"window=forever", "window=2000h", "ip=not-an-ip", "domain=-bad-.example..", "limit=0"} {
+		rec := httptest.NewRecorder()
+		handler.getReasonFamiliesHandler(rec, httptest.NewRequest("GET", "/api/analytics/reason-families?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRecommendationText bounds an operator's recommendation text
+const maxRecommendationText = 500
+
+var recommendationActionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,63}$`)
+
+// RecommendationRequest represents the request body for saving a
+// recommendation catalog entry
+type RecommendationRequest struct {
+	Texts map[string]string `json:"texts"` // Text per language, e.g. {"en": "...", "de": "..."}; omitted languages use the built-in text
+	Link  string            `json:"link"`  // http(s) URL of the runbook; empty for none
+}
+
+// RecommendationMappingRequest represents the request body for creating a
+// recommendation mapping
+type RecommendationMappingRequest struct {
+	Status    string `json:"status"`     // healthy, warning, quarantine or blacklisted
+	IssueType string `json:"issue_type"` // Optional; limits the mapping to IPs with this issue type
+	Action    string `json:"action"`     // Catalog entry to recommend
+	Position  int    `json:"position"`   // Order among the status' actions
+}
+
+// ResolveRecommendationsResponse lists what an IP would be recommended
+type ResolveRecommendationsResponse struct {
+	Status          string                      `json:"status"`
+	IssueType       string                      `json:"issue_type,omitempty"`
+	Recommendations []reputation.Recommendation `json:"recommendations"`
+}
+
+// RecommendationHandler manages the recommendation catalog
+type RecommendationHandler struct {
+	recommender *reputation.RecommendationCatalog
+	ownership   *OwnershipHandler
+	logger      *logrus.Logger
+}
+
+// NewRecommendationHandler creates a new recommendation catalog handler
+func NewRecommendationHandler(recommender *reputation.RecommendationCatalog, logger *logrus.Logger) *RecommendationHandler {
+	return &RecommendationHandler{recommender: recommender, ownership: NewOwnershipHandler(logger), logger: logger}
+}
+
+// validRecommendationStatus reports whether status can be mapped to actions
+func validRecommendationStatus(status string) bool {
+	switch state.Status(status) {
+	case state.Healthy, state.Warning, state.Quarantine, state.Blacklisted:
+		return true
+	}
+	return false
+}
+
+// @Summary List recommendations
+// @Description The recommendation catalog: each action's text overrides per language and its runbook link
+// @Tags recommendations
+// @Produce json
+// @Success 200 {array} database.Recommendation
+// @Failure 500 {object} ErrorResponse
+// @Router /api/recommendations [get]
+func (h *RecommendationHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	recs, err := database.ListRecommendations()
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "list_recommendations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
+
+// @Summary Save recommendation
+// @Description Create or replace a catalog entry. Texts override the built-in text of the action per language; languages without one keep the built-in translation, or the English text for actions without a built-in text.
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Param action path string true "Action, e.g. run_dnsbl_checks"
+// @Param recommendation body RecommendationRequest true "Recommendation"
+// @Success 200 {object} database.Recommendation
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/recommendations/{action} [put]
+func (h *RecommendationHandler) HandleSave(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	if !recommendationActionPattern.MatchString(action) {
+		writeUserError(w, http.StatusBadRequest, "invalid_action", "action must be lower-case letters, digits and underscores")
+		return
+	}
+	var req RecommendationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	texts := make(map[string]string, len(req.Texts))
+	for language, text := range req.Texts {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if !i18n.IsAvailable(language) {
+			writeUserError(w, http.StatusBadRequest, "invalid_language", "texts must be keyed by one of "+strings.Join(i18n.Available(), ", "))
+			return
+		}
+		if text = strings.TrimSpace(text); text == "" || len(text) > maxRecommendationText {
+			writeUserError(w, http.StatusBadRequest, "invalid_text", "texts must be 1 to 500 characters")
+			return
+		}
+		texts[language] = text
+	}
+	link := strings.TrimSpace(req.Link)
+	if link != "" {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeUserError(w, http.StatusBadRequest, "invalid_link", "link must be an http or https URL")
+			return
+		}
+	}
+
+	saved, err := database.SaveRecommendation(&database.Recommendation{Action: action, Texts: texts, Link: link, UpdatedBy: principalName(r)})
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "save_recommendation")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":         "recommendation_saved",
+		"recommendation": action,
+		"author":         saved.UpdatedBy,
+	}).Info("Recommendation saved")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// @Summary Delete recommendation
+// @Description Remove a catalog entry; it is no longer recommended for any status
+// @Tags recommendations
+// @Produce json
+// @Param action path string true "Action"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /api/recommendations/{action} [delete]
+func (h *RecommendationHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	found, err := database.DeleteRecommendation(action)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "delete_recommendation")
+		return
+	}
+	if found {
+		h.logger.WithFields(logrus.Fields{
+			"action":         "recommendation_deleted",
+			"recommendation": action,
+			"author":         principalName(r),
+		}).Info("Recommendation deleted")
+	}
+	writeDeleted(w, found, "Recommendation")
+}
+
+// @Summary List recommendation mappings
+// @Description Which actions are recommended for each status and, optionally, issue type
+// @Tags recommendations
+// @Produce json
+// @Success 200 {array} database.RecommendationMapping
+// @Failure 500 {object} ErrorResponse
+// @Router /api/recommendation-mappings [get]
+func (h *RecommendationHandler) HandleListMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := database.ListRecommendationMappings()
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "list_recommendation_mappings")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mappings)
+}
+
+// @Summary Create recommendation mapping
+// @Description Recommend a catalog action for a status. Mappings with an issue type replace the status' mappings without one for IPs with that issue type.
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Param mapping body RecommendationMappingRequest true "Mapping"
+// @Success 201 {object} database.RecommendationMapping
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/recommendation-mappings [post]
+func (h *RecommendationHandler) HandleCreateMapping(w http.ResponseWriter, r *http.Request) {
+	var req RecommendationMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	req.Status = strings.ToLower(strings.TrimSpace(req.Status))
+	req.IssueType = strings.ToLower(strings.TrimSpace(req.IssueType))
+	if !validRecommendationStatus(req.Status) {
+		writeUserError(w, http.StatusBadRequest, "invalid_status", "status must be healthy, warning, quarantine or blacklisted")
+		return
+	}
+	if req.IssueType != "" && !reputation.ValidIssueType(req.IssueType) {
+		writeUserError(w, http.StatusBadRequest, "invalid_issue_type", "issue_type must be one of "+strings.Join(reputation.IssueTypes, ", "))
+		return
+	}
+	if !recommendationActionPattern.MatchString(req.Action) {
+		writeUserError(w, http.StatusBadRequest, "invalid_action", "action must be lower-case letters, digits and underscores")
+		return
+	}
+
+	created, err := database.CreateRecommendationMapping(&database.RecommendationMapping{
+		Status:    req.Status,
+		IssueType: req.IssueType,
+		Action:    req.Action,
+		Position:  req.Position,
+		CreatedBy: principalName(r),
+	})
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "create_recommendation_mapping")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":         "recommendation_mapping_created",
+		"mapping_id":     created.ID,
+		"recommendation": created.Action,
+		"author":         created.CreatedBy,
+	}).Info("Recommendation mapping created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary Delete recommendation mapping
+// @Tags recommendations
+// @Produce json
+// @Param id path int true "Mapping ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/recommendation-mappings/{id} [delete]
+func (h *RecommendationHandler) HandleDeleteMapping(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := database.DeleteRecommendationMapping(id)
+	if err != nil {
+		h.ownership.writeOwnershipError(w, err, "delete_recommendation_mapping")
+		return
+	}
+	if found {
+		h.logger.WithFields(logrus.Fields{
+			"action":     "recommendation_mapping_deleted",
+			"mapping_id": id,
+			"author":     principalName(r),
+		}).Info("Recommendation mapping deleted")
+	}
+	writeDeleted(w, found, "Recommendation mapping")
+}
+
+// @Summary Resolve recommendations
+// @Description Preview the recommendations of an IP with a status and issue type, in the Accept-Language language
+// @Tags recommendations
+// @Produce json
+// @Param status query string true "healthy, warning, quarantine or blacklisted"
+// @Param issue_type query string false "Issue type, e.g. spamtrap_hit"
+// @Param Accept-Language header string false "Language of the texts"
+// @Success 200 {object} ResolveRecommendationsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/recommendation-mappings/resolve [get]
+func (h *RecommendationHandler) HandleResolve(w http.ResponseWriter, r *http.Request) {
+	status := strings.ToLower(r.URL.Query().Get("status"))
+	issueType := strings.ToLower(r.URL.Query().Get("issue_type"))
+	if !validRecommendationStatus(status) {
+		writeUserError(w, http.StatusBadRequest, "invalid_status", "status must be healthy, warning, quarantine or blacklisted")
+		return
+	}
+	if issueType != "" && !reputation.ValidIssueType(issueType) {
+		writeUserError(w, http.StatusBadRequest, "invalid_issue_type", "issue_type must be one of "+strings.Join(reputation.IssueTypes, ", "))
+		return
+	}
+
+	language := setContentLanguage(w, r)
+	recommended, err := h.recommender.Recommend(language, status, issueType)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "resolve_recommendations",
+			"error":  err.Error(),
+		}).Error("Failed to resolve recommendations")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to resolve recommendations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ResolveRecommendationsResponse{Status: status, IssueType: issueType, Recommendations: recommended})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryRecommendationStore struct{}
+
+func (memoryRecommendationStore) ListRecommendations() ([]database.Recommendation, error) {
+	return []database.Recommendation{
+		{Action: "monitor_closely", Link: "https://wiki.example.com/runbooks/monitoring"},
+	}, nil
+}
+
+func (memoryRecommendationStore) ListRecommendationMappings() ([]database.RecommendationMapping, error) {
+	return []database.RecommendationMapping{
+		{ID: 1, Status: "warning", Action: "monitor_closely", Position: 1},
+	}, nil
+}
+
+func newTestRecommendationRouter() http.Handler {
+	logger, _ := test.NewNullLogger()
+	h := NewRecommendationHandler(reputation.NewRecommendationCatalog(memoryRecommendationStore{}), logger)
+
+	router := mux.NewRouter()
+	router.Use(languageMiddleware(i18n.Default))
+	router.HandleFunc("/api/recommendations/{action}", h.HandleSave).Methods("PUT")
+	router.HandleFunc("/api/recommendation-mappings", h.HandleCreateMapping).Methods("POST")
+	router.HandleFunc("/api/recommendation-mappings/resolve", h.HandleResolve).Methods("GET")
+	return router
+}
+
+func TestRecommendationValidation(t *testing.T) {
+	router := newTestRecommendationRouter()
+
+	cases := []struct {
+		method, target, body, code string
+	}{
+		{"PUT", "/api/recommendations/Run-Checks", `{}`, "invalid_action"},
+		{"PUT", "/api/recommendations/run_checks", `{`, "invalid_request"},
+		{"PUT", "/api/recommendations/run_checks", `{"texts":{"xx":"Run the checks"}}`, "invalid_language"},
+		{"PUT", "/api/recommendations/run_checks", `{"texts":{"en":"  "}}`, "invalid_text"},
+		{"PUT", "/api/recommendations/run_checks", `{"link":"ftp://wiki.example.com/runbook"}`, "invalid_link"},
+		{"POST", "/api/recommendation-mappings", `{"status":"degraded","action":"run_checks"}`, "invalid_status"},
+		{"POST", "/api/recommendation-mappings", `{"status":"warning","issue_type":"bad_luck","action":"run_checks"}`, "invalid_issue_type"},
+		{"POST", "/api/recommendation-mappings", `{"status":"warning","action":""}`, "invalid_action"},
+		{"GET", "/api/recommendation-mappings/resolve?status=degraded", "", "invalid_status"},
+	}
+	for _, tc := range cases {
+		rec := serveConfig(router, tc.method, tc.target, tc.body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s %s: status = %d, want 400", tc.method, tc.target, tc.body, rec.Code)
+			continue
+		}
+		var resp ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error != tc.code {
+			t.Errorf("%s %s %s: code = %q, want %q", tc.method, tc.target, tc.body, resp.Error, tc.code)
+		}
+	}
+}
+
+func TestResolveRecommendations(t *testing.T) {
+	router := newTestRecommendationRouter()
+
+	req := httptest.NewRequest("GET", "/api/recommendation-mappings/resolve?status=warning", nil)
+	req.Header.Set("Accept-Language", "fr-CA, en;q=0.5")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Language"); got != "fr" {
+		t.Errorf("Content-Language = %q, want fr", got)
+	}
+
+	var resp ResolveRecommendationsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Recommendations) != 1 {
+		t.Fatalf("recommendations = %+v, want one", resp.Recommendations)
+	}
+	got := resp.Recommendations[0]
+	if got.Text != i18n.Translate("fr", "recommendation.monitor_closely") || got.Link != "https://wiki.example.com/runbooks/monitoring" {
+		t.Errorf("recommendation = %+v, want the French text with the runbook link", got)
+	}
+}
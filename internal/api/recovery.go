@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"golang-backend-service/internal/reporting"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var httpPanicsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_panics_total",
+		Help: "Total number of panics recovered in HTTP handlers",
+	},
+	[]string{"method", "route"},
+)
+
+// recoveryWriter records whether the response has started, after which a
+// 500 can no longer be sent
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// recoveryMiddleware turns a handler panic into a 500, logs it with its
+// stack, counts it and hands it to the error reporter. http.ErrAbortHandler
+// is re-raised so the server still aborts the connection.
+func recoveryMiddleware(reporter reporting.ErrorReporter, log *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &recoveryWriter{ResponseWriter: w}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				route := routeTemplate(r)
+				err, ok := recovered.(error)
+				if !ok {
+					err = errors.New(fmt.Sprint(recovered))
+				}
+
+				httpPanicsTotal.WithLabelValues(r.Method, route).Inc()
+				log.WithFields(logrus.Fields{
+					"action": "http_panic",
+					"method": r.Method,
+					"route":  route,
+					"path":   redactedPath(r),
+					"error":  err.Error(),
+					"stack":  string(stack),
+				}).Error("Recovered panic in HTTP handler")
+
+				tags := map[string]string{
+					"method": r.Method,
+					"route":  route,
+				}
+				if ip, ok := mux.Vars(r)["ip"]; ok {
+					tags["ip"] = ip
+				}
+				reporter.Report(reporting.Event{
+					Message:   err.Error(),
+					Err:       err,
+					Panic:     true,
+					Stack:     stack,
+					Tags:      tags,
+					Timestamp: time.Now(),
+				})
+
+				if !wrapped.wroteHeader {
+					writeUserError(wrapped, http.StatusInternalServerError, "internal_error", "Internal server error")
+				}
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
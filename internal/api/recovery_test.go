@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/reporting"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type recordingReporter struct {
+	mu     sync.Mutex
+	events []reporting.Event
+}
+
+func (r *recordingReporter) Report(event reporting.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestRecoveryMiddlewareReportsPanics(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	reporter := &recordingReporter{}
+
+	router := mux.NewRouter()
+	router.Use(recoveryMiddleware(reporter, log))
+	router.HandleFunc("/api/ips/{ip}/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map write")
+	})
+
+	before := testutil.ToFloat64(httpPanicsTotal.WithLabelValues("GET", "/api/ips/{ip}/boom"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/ips/192.0.2.1/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "internal_error") {
+		t.Fatalf("Expected a 500 internal_error, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got := testutil.ToFloat64(httpPanicsTotal.WithLabelValues("GET", "/api/ips/{ip}/boom")); got != before+1 {
+		t.Errorf("Expected the panic counter to increase by 1, got %v -> %v", before, got)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.ErrorLevel || entry.Data["action"] != "http_panic" {
+		t.Fatalf("Expected an http_panic error log, got %+v", entry)
+	}
+	if stack, _ := entry.Data["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("Expected the logged stack to include the panicking handler, got %q", stack)
+	}
+
+	if len(reporter.events) != 1 {
+		t.Fatalf("Expected one reported event, got %d", len(reporter.events))
+	}
+	event := reporter.events[0]
+	if !event.Panic || event.Message != "nil map write" || len(event.Stack) == 0 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Tags["route"] != "/api/ips/{ip}/boom" || event.Tags["ip"] != "192.0.2.1" {
+		t.Errorf("Unexpected tags: %v", event.Tags)
+	}
+}
+
+func TestRecoveryMiddlewareKeepsStartedResponse(t *testing.T) {
+	log, _ := test.NewNullLogger()
+
+	router := mux.NewRouter()
+	router.Use(recoveryMiddleware(reporting.Nop, log))
+	router.HandleFunc("/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after write")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/partial", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("Expected the started response to be left alone, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecoveryMiddlewareReraisesAbort(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	handler := recoveryMiddleware(reporting.Nop, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to propagate, got %v", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RemediationModeResponse is the mode automated actions run in and the
+// revision that set it. Revision is nil while the config default applies.
+type RemediationModeResponse struct {
+	Mode     remediation.Mode         `json:"mode"`
+	Revision *database.ConfigRevision `json:"revision"`
+}
+
+// UpdateRemediationModeRequest switches the remediation mode
+type UpdateRemediationModeRequest struct {
+	Mode    string `json:"mode"`
+	Comment string `json:"comment"`
+}
+
+// RemediationHandler switches automated remediation between off, dry-run
+// and enforce at runtime
+type RemediationHandler struct {
+	store  configRevisionStore
+	logger *logrus.Logger
+}
+
+// NewRemediationHandler creates a remediation mode handler
+func NewRemediationHandler(logger *logrus.Logger) *RemediationHandler {
+	return &RemediationHandler{store: dbConfigRevisionStore{}, logger: logger}
+}
+
+// @Summary Get remediation mode
+// @Description The mode automated actions (burst and spamtrap quarantines, reserved IP quarantines, stale reservation releases and send rate cuts) run in: off, dry-run or enforce. In dry-run mode the actions are recorded in the IP actions with metadata simulated=true instead of being taken.
+// @Tags features
+// @Produce json
+// @Success 200 {object} RemediationModeResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/remediation [get]
+func (h *RemediationHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	rev, err := h.store.GetLatestConfigRevision(database.ConfigScopeRemediation)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load remediation mode revision")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load remediation mode")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RemediationModeResponse{Mode: remediation.Current(), Revision: rev})
+}
+
+// @Summary Set remediation mode
+// @Description Switch automated remediation to off, dry-run or enforce. The change is stored as a config revision with its author, applies right away on this instance and reaches every other instance within 30 seconds.
+// @Tags features
+// @Accept json
+// @Produce json
+// @Param mode body UpdateRemediationModeRequest true "New mode"
+// @Success 200 {object} RemediationModeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/remediation [put]
+func (h *RemediationHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRemediationModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Mode == "" {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "mode is required")
+		return
+	}
+	mode, err := remediation.ParseMode(req.Mode)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_mode", err.Error())
+		return
+	}
+	previous := remediation.Current()
+	if mode == previous {
+		writeUserError(w, http.StatusBadRequest, "no_changes", "The remediation mode is unchanged")
+		return
+	}
+
+	config, _ := json.Marshal(remediation.Settings{Mode: mode})
+	diff, _ := json.Marshal(map[string]reputation.ConfigChange{"mode": {From: previous, To: mode}})
+	rev, err := h.store.CreateConfigRevision(&database.ConfigRevision{
+		Scope:   database.ConfigScopeRemediation,
+		Config:  config,
+		Diff:    diff,
+		Author:  principalName(r),
+		Comment: req.Comment,
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "save_remediation_mode",
+			"error":  err.Error(),
+		}).Error("Failed to save remediation mode")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save remediation mode")
+		return
+	}
+	remediation.SetMode(mode)
+
+	h.logger.WithFields(logrus.Fields{
+		"action":      "remediation_mode_changed",
+		"from":        string(previous),
+		"to":          string(mode),
+		"revision_id": rev.ID,
+		"author":      rev.Author,
+	}).Warn("Remediation mode changed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RemediationModeResponse{Mode: mode, Revision: rev})
+}
+
+// @Summary Remediation mode history
+// @Description Remediation mode changes, newest first
+// @Tags features
+// @Produce json
+// @Param limit query int false "Number of changes (1-500)" default(50)
+// @Success 200 {array} database.ConfigRevision
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/system/remediation/history [get]
+func (h *RemediationHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > 500 {
+			writeUserError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	revisions, err := h.store.ListConfigRevisions(database.ConfigScopeRemediation, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list remediation mode revisions")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list remediation mode history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
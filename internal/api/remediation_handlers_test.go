@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRemediationMode(t *testing.T) {
+	defer remediation.SetMode(remediation.Enforce)
+	remediation.SetMode(remediation.Enforce)
+	logger, _ := test.NewNullLogger()
+	store := &memoryRevisionStore{}
+	handler := &RemediationHandler{store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/system/remediation", handler.HandleGet).Methods("GET")
+	router.HandleFunc("/api/system/remediation", handler.HandleUpdate).Methods("PUT")
+	router.HandleFunc("/api/system/remediation/history", handler.HandleHistory).Methods("GET")
+
+	rec := serveConfig(router, "GET", "/api/system/remediation", "")
+	var resp RemediationModeResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Mode != remediation.Enforce || resp.Revision != nil {
+		t.Fatalf("Expected enforce from the config, got %+v", resp)
+	}
+
+	rec = serveConfig(router, "PUT", "/api/system/remediation", `{"mode":"dry-run","comment":"trial week"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Mode != remediation.DryRun || remediation.Current() != remediation.DryRun {
+		t.Errorf("Expected dry-run applied, got %+v", resp)
+	}
+	if resp.Revision == nil || resp.Revision.Scope != database.ConfigScopeRemediation || !strings.Contains(string(resp.Revision.Diff), `"from":"enforce"`) {
+		t.Errorf("Expected the change stored as a remediation revision, got %+v", resp.Revision)
+	}
+	if mode, err := remediation.DecodeSettings(resp.Revision); err != nil || mode != remediation.DryRun {
+		t.Errorf("Expected the revision to decode to dry-run, got %q %v", mode, err)
+	}
+
+	for body, code := range map[string]string{
+		`{"mode":"dry-run"}`: "no_changes",
+		`{"mode":"later"}`:   "invalid_mode",
+		`{}`:                 "invalid_request",
+	} {
+		rec := serveConfig(router, "PUT", "/api/system/remediation", body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), code) {
+			t.Errorf("Expected 400 %s for %s, got %d: %s", code, body, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = serveConfig(router, "GET", "/api/system/remediation/history", "")
+	var history []database.ConfigRevision
+	json.NewDecoder(rec.Body).Decode(&history)
+	if len(history) != 1 || history[0].Comment != "trial week" {
+		t.Errorf("Expected the change in the history, got %+v", history)
+	}
+}
@@ -0,0 +1,454 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ReputationConfigResponse is the active reputation config and the revision
+// it came from. Revision is nil while the defaults are in use.
+type ReputationConfigResponse struct {
+	Config   reputation.ReputationConfig `json:"config"`
+	Revision *database.ConfigRevision    `json:"revision"`
+}
+
+// UpdateReputationConfigRequest changes thresholds. Fields left out of
+// config keep their active values.
+type UpdateReputationConfigRequest struct {
+	Config  json.RawMessage `json:"config" swaggertype:"object"`
+	Comment string          `json:"comment"`
+}
+
+// RollbackRequest optionally explains a rollback
+type RollbackRequest struct {
+	Comment string `json:"comment"`
+}
+
+// configRevisionStore persists config revisions
+type configRevisionStore interface {
+	CreateConfigRevision(rev *database.ConfigRevision) (*database.ConfigRevision, error)
+	GetLatestConfigRevision(scope string) (*database.ConfigRevision, error)
+	GetConfigRevision(id int) (*database.ConfigRevision, error)
+	ListConfigRevisions(scope string, limit int) ([]database.ConfigRevision, error)
+}
+
+type dbConfigRevisionStore struct{}
+
+func (dbConfigRevisionStore) CreateConfigRevision(rev *database.ConfigRevision) (*database.ConfigRevision, error) {
+	return database.CreateConfigRevision(rev)
+}
+
+func (dbConfigRevisionStore) GetLatestConfigRevision(scope string) (*database.ConfigRevision, error) {
+	return database.GetLatestConfigRevision(scope)
+}
+
+func (dbConfigRevisionStore) GetConfigRevision(id int) (*database.ConfigRevision, error) {
+	return database.GetConfigRevision(id)
+}
+
+func (dbConfigRevisionStore) ListConfigRevisions(scope string, limit int) ([]database.ConfigRevision, error) {
+	return database.ListConfigRevisions(scope, limit)
+}
+
+// ReputationConfigHandler edits the reputation thresholds at runtime and
+// keeps every version
+type ReputationConfigHandler struct {
+	aggregator *reputation.AggregationService
+	store      configRevisionStore
+	logger     *logrus.Logger
+}
+
+// NewReputationConfigHandler creates a handler that applies config changes to
+// the aggregator
+func NewReputationConfigHandler(aggregator *reputation.AggregationService, logger *logrus.Logger) *ReputationConfigHandler {
+	return &ReputationConfigHandler{aggregator: aggregator, store: dbConfigRevisionStore{}, logger: logger}
+}
+
+// active returns the newest stored config, or the defaults
+func (h *ReputationConfigHandler) active() (reputation.ReputationConfig, *database.ConfigRevision, error) {
+	config := reputation.DefaultReputationConfig()
+	rev, err := h.store.GetLatestConfigRevision(database.ConfigScopeReputation)
+	if err != nil || rev == nil {
+		return config, nil, err
+	}
+	if err := json.Unmarshal(rev.Config, &config); err != nil {
+		return reputation.DefaultReputationConfig(), nil, err
+	}
+	return config, rev, nil
+}
+
+func principalName(r *http.Request) string {
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		return principal.Name()
+	}
+	return "anonymous"
+}
+
+// activeShadow returns the newest stored shadow config, or nil
+func (h *ReputationConfigHandler) activeShadow() (*reputation.ReputationConfig, *database.ConfigRevision, error) {
+	rev, err := h.store.GetLatestConfigRevision(database.ConfigScopeReputationShadow)
+	if err != nil || rev == nil {
+		return nil, nil, err
+	}
+	config, err := reputation.DecodeShadowConfig(rev)
+	if err != nil || config == nil {
+		return nil, nil, err
+	}
+	return config, rev, nil
+}
+
+// record stores a revision of scope, logging failures
+func (h *ReputationConfigHandler) record(r *http.Request, scope string, config interface{}, diff map[string]reputation.ConfigChange, comment string, rollbackOf *int) (*database.ConfigRevision, error) {
+	configJSON, _ := json.Marshal(config)
+	diffJSON, _ := json.Marshal(diff)
+	rev, err := h.store.CreateConfigRevision(&database.ConfigRevision{
+		Scope:      scope,
+		Config:     configJSON,
+		Diff:       diffJSON,
+		Author:     principalName(r),
+		Comment:    comment,
+		RollbackOf: rollbackOf,
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "save_reputation_config",
+			"scope":  scope,
+			"error":  err.Error(),
+		}).Error("Failed to save config revision")
+		return nil, err
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":      "reputation_config_changed",
+		"scope":       scope,
+		"revision_id": rev.ID,
+		"author":      rev.Author,
+		"diff":        string(diffJSON),
+		"rollback_of": rollbackOf,
+	}).Info("Reputation config changed")
+	return rev, nil
+}
+
+// save stores config as a new revision and applies it
+func (h *ReputationConfigHandler) save(w http.ResponseWriter, r *http.Request, current, config reputation.ReputationConfig, comment string, rollbackOf *int) {
+	diff := reputation.DiffConfigs(current, config)
+	if len(diff) == 0 {
+		writeUserError(w, http.StatusBadRequest, "no_changes", "The config is unchanged")
+		return
+	}
+
+	rev, err := h.record(r, database.ConfigScopeReputation, config, diff, comment, rollbackOf)
+	if err != nil {
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save config")
+		return
+	}
+	h.aggregator.SetConfig(config)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReputationConfigResponse{Config: config, Revision: rev})
+}
+
+// @Summary Get reputation config
+// @Description The thresholds the decision engine applies and the revision they came from
+// @Tags reputation-config
+// @Produce json
+// @Success 200 {object} ReputationConfigResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/config [get]
+func (h *ReputationConfigHandler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	config, rev, err := h.active()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load reputation config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load config")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReputationConfigResponse{Config: config, Revision: rev})
+}
+
+// @Summary Update reputation config
+// @Description Change thresholds at runtime. The change is stored as a new revision with its author and diff.
+// @Tags reputation-config
+// @Accept json
+// @Produce json
+// @Param config body UpdateReputationConfigRequest true "Changed thresholds"
+// @Success 200 {object} ReputationConfigResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/config [put]
+func (h *ReputationConfigHandler) HandleUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var req UpdateReputationConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Config) == 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "config is required")
+		return
+	}
+
+	current, _, err := h.active()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load reputation config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load config")
+		return
+	}
+
+	config := current
+	if err := json.Unmarshal(req.Config, &config); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_config", "Invalid config")
+		return
+	}
+	if err := config.Validate(); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_config", err.Error())
+		return
+	}
+
+	h.save(w, r, current, config, req.Comment, nil)
+}
+
+// @Summary Reputation config history
+// @Description Config revisions, newest first
+// @Tags reputation-config
+// @Produce json
+// @Param scope query string false "Config scope" Enums(reputation, reputation_shadow) default(reputation)
+// @Param limit query int false "Number of revisions (1-500)" default(50)
+// @Success 200 {array} database.ConfigRevision
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/config/history [get]
+func (h *ReputationConfigHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > 500 {
+			writeUserError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	scope := database.ConfigScopeReputation
+	switch raw := r.URL.Query().Get("scope"); raw {
+	case "", database.ConfigScopeReputation:
+	case database.ConfigScopeReputationShadow:
+		scope = raw
+	default:
+		writeUserError(w, http.StatusBadRequest, "invalid_scope", "scope must be reputation or reputation_shadow")
+		return
+	}
+
+	revisions, err := h.store.ListConfigRevisions(scope, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list config revisions")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list config history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// @Summary Roll back reputation config
+// @Description Make an earlier revision active again. The rollback is recorded as a new revision.
+// @Tags reputation-config
+// @Accept json
+// @Produce json
+// @Param id path int true "Revision ID"
+// @Param rollback body RollbackRequest false "Reason"
+// @Success 200 {object} ReputationConfigResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/config/revisions/{id}/rollback [post]
+func (h *ReputationConfigHandler) HandleRollback(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Revision ID must be a number")
+		return
+	}
+	var req RollbackRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	target, err := h.store.GetConfigRevision(id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load config revision")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load revision")
+		return
+	}
+	if target == nil || target.Scope != database.ConfigScopeReputation {
+		writeUserError(w, http.StatusNotFound, "not_found", "Revision not found")
+		return
+	}
+
+	current, _, err := h.active()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load reputation config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load config")
+		return
+	}
+
+	config := reputation.DefaultReputationConfig()
+	if err := json.Unmarshal(target.Config, &config); err != nil || config.Validate() != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_config", "Revision does not hold a valid config")
+		return
+	}
+
+	comment := req.Comment
+	if comment == "" {
+		comment = "Rollback to revision " + strconv.Itoa(id)
+	}
+	h.save(w, r, current, config, comment, &id)
+}
+
+// ShadowConfigResponse is the registered shadow config, how it differs from
+// the live one and how it classified the fleet on the last aggregation run
+type ShadowConfigResponse struct {
+	Config   *reputation.ReputationConfig       `json:"config"`
+	Revision *database.ConfigRevision           `json:"revision"`
+	Diff     map[string]reputation.ConfigChange `json:"diff"`
+	Report   *reputation.ShadowReport           `json:"report"`
+}
+
+// @Summary Get shadow reputation config
+// @Description The shadow thresholds, their diff against the live config and the comparison from the last aggregation run
+// @Tags reputation-config
+// @Produce json
+// @Success 200 {object} ShadowConfigResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/shadow [get]
+func (h *ReputationConfigHandler) HandleGetShadow(w http.ResponseWriter, r *http.Request) {
+	shadow, rev, err := h.activeShadow()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load shadow config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load shadow config")
+		return
+	}
+
+	response := ShadowConfigResponse{Config: shadow, Revision: rev, Diff: map[string]reputation.ConfigChange{}}
+	if shadow != nil {
+		response.Diff = reputation.DiffConfigs(h.aggregator.Config(), *shadow)
+		response.Report = h.aggregator.ShadowReport()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Register shadow reputation config
+// @Description Evaluate a second set of thresholds on every aggregation run without acting on it. Fields left out of config keep their live values.
+// @Tags reputation-config
+// @Accept json
+// @Produce json
+// @Param config body UpdateReputationConfigRequest true "Shadow thresholds"
+// @Success 200 {object} ShadowConfigResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/shadow [put]
+func (h *ReputationConfigHandler) HandleSetShadow(w http.ResponseWriter, r *http.Request) {
+	var req UpdateReputationConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Config) == 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "config is required")
+		return
+	}
+
+	live := h.aggregator.Config()
+	shadow := live
+	if err := json.Unmarshal(req.Config, &shadow); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_config", "Invalid config")
+		return
+	}
+	if err := shadow.Validate(); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_config", err.Error())
+		return
+	}
+
+	diff := reputation.DiffConfigs(live, shadow)
+	if len(diff) == 0 {
+		writeUserError(w, http.StatusBadRequest, "no_changes", "The shadow config matches the live config")
+		return
+	}
+
+	rev, err := h.record(r, database.ConfigScopeReputationShadow, shadow, diff, req.Comment, nil)
+	if err != nil {
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to save shadow config")
+		return
+	}
+	h.aggregator.SetShadowConfig(&shadow)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShadowConfigResponse{Config: &shadow, Revision: rev, Diff: diff})
+}
+
+// @Summary Remove shadow reputation config
+// @Description Stop evaluating the shadow thresholds
+// @Tags reputation-config
+// @Produce json
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/shadow [delete]
+func (h *ReputationConfigHandler) HandleDeleteShadow(w http.ResponseWriter, r *http.Request) {
+	shadow, _, err := h.activeShadow()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load shadow config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load shadow config")
+		return
+	}
+	if shadow == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "No shadow config is registered")
+		return
+	}
+
+	if _, err := h.record(r, database.ConfigScopeReputationShadow, nil, nil, "Shadow config removed", nil); err != nil {
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to remove shadow config")
+		return
+	}
+	h.aggregator.SetShadowConfig(nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Promote shadow reputation config
+// @Description Make the shadow thresholds live and remove the shadow. The change is recorded in the config history.
+// @Tags reputation-config
+// @Produce json
+// @Success 200 {object} ReputationConfigResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/shadow/promote [post]
+func (h *ReputationConfigHandler) HandlePromoteShadow(w http.ResponseWriter, r *http.Request) {
+	shadow, shadowRev, err := h.activeShadow()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load shadow config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load shadow config")
+		return
+	}
+	if shadow == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "No shadow config is registered")
+		return
+	}
+
+	current, _, err := h.active()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load reputation config")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to load config")
+		return
+	}
+	if len(reputation.DiffConfigs(current, *shadow)) == 0 {
+		writeUserError(w, http.StatusBadRequest, "no_changes", "The shadow config matches the live config")
+		return
+	}
+
+	if _, err := h.record(r, database.ConfigScopeReputationShadow, nil, nil, "Shadow config promoted", nil); err != nil {
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to remove shadow config")
+		return
+	}
+	h.aggregator.SetShadowConfig(nil)
+
+	h.save(w, r, current, *shadow, "Promote shadow revision "+strconv.Itoa(shadowRev.ID), nil)
+}
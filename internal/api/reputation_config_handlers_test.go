@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryRevisionStore is an in-memory configRevisionStore
+type memoryRevisionStore struct {
+	revisions []database.ConfigRevision
+}
+
+func (s *memoryRevisionStore) CreateConfigRevision(rev *database.ConfigRevision) (*database.ConfigRevision, error) {
+	stored := *rev
+	stored.ID = len(s.revisions) + 1
+	stored.CreatedAt = time.Now()
+	s.revisions = append(s.revisions, stored)
+	return &stored, nil
+}
+
+func (s *memoryRevisionStore) GetLatestConfigRevision(scope string) (*database.ConfigRevision, error) {
+	for i := len(s.revisions) - 1; i >= 0; i-- {
+		if s.revisions[i].Scope == scope {
+			rev := s.revisions[i]
+			return &rev, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryRevisionStore) GetConfigRevision(id int) (*database.ConfigRevision, error) {
+	if id < 1 || id > len(s.revisions) {
+		return nil, nil
+	}
+	rev := s.revisions[id-1]
+	return &rev, nil
+}
+
+func (s *memoryRevisionStore) ListConfigRevisions(scope string, limit int) ([]database.ConfigRevision, error) {
+	list := []database.ConfigRevision{}
+	for i := len(s.revisions) - 1; i >= 0 && len(list) < limit; i-- {
+		if s.revisions[i].Scope == scope {
+			list = append(list, s.revisions[i])
+		}
+	}
+	return list, nil
+}
+
+func newTestConfigRouter(t *testing.T) (*mux.Router, *reputation.AggregationService, *memoryRevisionStore) {
+	t.Helper()
+	logger, _ := test.NewNullLogger()
+	aggregator := reputation.NewAggregationService(reputation.DefaultReputationConfig(), logger)
+	store := &memoryRevisionStore{}
+	handler := &ReputationConfigHandler{aggregator: aggregator, store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reputation/config", handler.HandleGetConfig).Methods("GET")
+	router.HandleFunc("/api/reputation/config", handler.HandleUpdateConfig).Methods("PUT")
+	router.HandleFunc("/api/reputation/config/history", handler.HandleHistory).Methods("GET")
+	router.HandleFunc("/api/reputation/config/revisions/{id}/rollback", handler.HandleRollback).Methods("POST")
+	router.HandleFunc("/api/reputation/shadow", handler.HandleGetShadow).Methods("GET")
+	router.HandleFunc("/api/reputation/shadow", handler.HandleSetShadow).Methods("PUT")
+	router.HandleFunc("/api/reputation/shadow", handler.HandleDeleteShadow).Methods("DELETE")
+	router.HandleFunc("/api/reputation/shadow/promote", handler.HandlePromoteShadow).Methods("POST")
+	return router, aggregator, store
+}
+
+func serveConfig(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestReputationConfigUpdateAndRollback(t *testing.T) {
+	router, aggregator, store := newTestConfigRouter(t)
+
+	rec := serveConfig(router, "PUT", "/api/reputation/config", `{"config":{"warning_rejection_ratio":0.1},"comment":"tighten"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := aggregator.Config().WarningRejectionRatio; got != 0.1 {
+		t.Errorf("aggregator warning ratio = %v, want 0.1", got)
+	}
+	if got := aggregator.Config().BlacklistRejectionRatio; got != reputation.DefaultReputationConfig().BlacklistRejectionRatio {
+		t.Errorf("Fields left out of the update changed: blacklist ratio = %v", got)
+	}
+
+	first := store.revisions[0]
+	if first.Author != "anonymous" || first.Comment != "tighten" || !strings.Contains(string(first.Diff), "warning_rejection_ratio") {
+		t.Errorf("Unexpected revision: %+v, diff %s", first, first.Diff)
+	}
+
+	if rec := serveConfig(router, "PUT", "/api/reputation/config", `{"config":{"warning_rejection_ratio":0.1}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unchanged update status = %d, want 400", rec.Code)
+	}
+
+	serveConfig(router, "PUT", "/api/reputation/config", `{"config":{"warning_rejection_ratio":0.2}}`)
+	rec = serveConfig(router, "POST", "/api/reputation/config/revisions/1/rollback", `{}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rollback status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := aggregator.Config().WarningRejectionRatio; got != 0.1 {
+		t.Errorf("warning ratio after rollback = %v, want 0.1", got)
+	}
+	latest := store.revisions[len(store.revisions)-1]
+	if latest.RollbackOf == nil || *latest.RollbackOf != 1 || latest.Comment != "Rollback to revision 1" {
+		t.Errorf("Unexpected rollback revision: %+v", latest)
+	}
+
+	rec = serveConfig(router, "GET", "/api/reputation/config/history?limit=2", "")
+	var history []database.ConfigRevision
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("invalid history: %v", err)
+	}
+	if len(history) != 2 || history[0].ID != 3 {
+		t.Errorf("Expected the two newest revisions, got %+v", history)
+	}
+}
+
+func TestReputationConfigRejectsInvalidRequests(t *testing.T) {
+	router, aggregator, _ := newTestConfigRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"missing config", "PUT", "/api/reputation/config", `{}`, http.StatusBadRequest},
+		{"ratio out of range", "PUT", "/api/reputation/config", `{"config":{"warning_rejection_ratio":1.5}}`, http.StatusBadRequest},
+		{"wrong type", "PUT", "/api/reputation/config", `{"config":{"window_minutes":"hour"}}`, http.StatusBadRequest},
+		{"bad limit", "GET", "/api/reputation/config/history?limit=0", "", http.StatusBadRequest},
+		{"bad id", "POST", "/api/reputation/config/revisions/abc/rollback", "", http.StatusBadRequest},
+		{"unknown revision", "POST", "/api/reputation/config/revisions/9/rollback", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveConfig(router, tt.method, tt.target, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(aggregator.Config(), reputation.DefaultReputationConfig()) {
+		t.Error("Rejected requests changed the active config")
+	}
+}
+
+func TestShadowConfigLifecycle(t *testing.T) {
+	router, aggregator, store := newTestConfigRouter(t)
+
+	if rec := serveConfig(router, "DELETE", "/api/reputation/shadow", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete without shadow status = %d, want 404", rec.Code)
+	}
+	if rec := serveConfig(router, "PUT", "/api/reputation/shadow", `{"config":{}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("shadow matching live status = %d, want 400", rec.Code)
+	}
+
+	rec := serveConfig(router, "PUT", "/api/reputation/shadow", `{"config":{"warning_rejection_ratio":0.01}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set shadow status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if shadow := aggregator.ShadowConfig(); shadow == nil || shadow.WarningRejectionRatio != 0.01 {
+		t.Errorf("Unexpected shadow config: %+v", shadow)
+	}
+	if !reflect.DeepEqual(aggregator.Config(), reputation.DefaultReputationConfig()) {
+		t.Error("Registering a shadow config changed the live config")
+	}
+
+	rec = serveConfig(router, "GET", "/api/reputation/shadow", "")
+	var got ShadowConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if got.Config == nil || len(got.Diff) != 1 || got.Diff["warning_rejection_ratio"].To != 0.01 {
+		t.Errorf("Unexpected shadow response: %+v", got)
+	}
+
+	rec = serveConfig(router, "POST", "/api/reputation/shadow/promote", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("promote status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if aggregator.ShadowConfig() != nil || aggregator.Config().WarningRejectionRatio != 0.01 {
+		t.Errorf("Expected promoted live config and no shadow, got %+v, %+v", aggregator.Config(), aggregator.ShadowConfig())
+	}
+	if latest, _ := store.GetLatestConfigRevision(database.ConfigScopeReputationShadow); string(latest.Config) != "null" {
+		t.Errorf("Expected the shadow to be recorded as removed, got %s", latest.Config)
+	}
+
+	rec = serveConfig(router, "GET", "/api/reputation/config/history?scope=reputation_shadow", "")
+	var history []database.ConfigRevision
+	json.Unmarshal(rec.Body.Bytes(), &history)
+	if len(history) != 2 {
+		t.Errorf("Expected 2 shadow revisions, got %d", len(history))
+	}
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// feedTokenPrefix makes leaked feed tokens easy to recognise in secret
+// scanners
+const feedTokenPrefix = "rfeed_"
+
+const (
+	// maxFeedSignals caps the verdicts of one request
+	maxFeedSignals = 1000
+	// maxFeedBodySize caps a signals request body
+	maxFeedBodySize = 1 << 20
+	// defaultSignalTTL is how long a verdict counts without expires_at
+	defaultSignalTTL = 7 * 24 * time.Hour
+	// maxSignalClockSkew is how far in the future reported_at may be
+	maxSignalClockSkew = 5 * time.Minute
+)
+
+// CreateReputationFeedRequest registers a third-party reputation feed
+type CreateReputationFeedRequest struct {
+	Name        string `json:"name" example:"partner-blocklist"`
+	Description string `json:"description"`
+	// Weight scales the feed's verdicts in the external score; defaults to 1
+	Weight *float64 `json:"weight,omitempty" example:"1"`
+}
+
+// CreateReputationFeedResponse returns the new feed and its token. The token
+// is only shown once; only its hash is stored.
+type CreateReputationFeedResponse struct {
+	Feed  database.ReputationFeed `json:"feed"`
+	Token string                  `json:"token"`
+}
+
+// UpdateReputationFeedRequest changes the fields that are set
+type UpdateReputationFeedRequest struct {
+	Description *string  `json:"description,omitempty"`
+	Weight      *float64 `json:"weight,omitempty"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+// ExternalSignalRequest is a feed's verdict on an IP
+type ExternalSignalRequest struct {
+	IP      string `json:"ip" example:"192.0.2.1"`
+	Verdict string `json:"verdict" example:"malicious"` // malicious, suspicious or clean
+	// Confidence between 0 and 1; defaults to 1
+	Confidence *float64   `json:"confidence,omitempty" example:"0.8"`
+	Reason     string     `json:"reason,omitempty"`
+	Reference  string     `json:"reference,omitempty"`   // The feed's ID or URL for the listing
+	ReportedAt *time.Time `json:"reported_at,omitempty"` // Defaults to now
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`  // Defaults to 7 days after reported_at
+}
+
+// ExternalSignalsRequest is a batch of verdicts from one feed
+type ExternalSignalsRequest struct {
+	Signals []ExternalSignalRequest `json:"signals"`
+}
+
+// ExternalSignalsResponse counts the verdicts of a batch. A verdict is not
+// applied when the feed already reported a later one on the IP.
+type ExternalSignalsResponse struct {
+	Feed     string `json:"feed"`
+	Accepted int    `json:"accepted"`
+	Applied  int    `json:"applied"`
+}
+
+// ExternalSignalsByIPResponse is what third-party feeds currently say about
+// an IP
+type ExternalSignalsByIPResponse struct {
+	IP      string                    `json:"ip"`
+	Score   float64                   `json:"score"`
+	Feeds   map[string]float64        `json:"feeds"`
+	Signals []database.ExternalSignal `json:"signals"`
+}
+
+// feedStore persists reputation feeds and their verdicts
+type feedStore interface {
+	CreateReputationFeed(name, description string, weight float64, tokenHash, createdBy string) (*database.ReputationFeed, error)
+	GetReputationFeedByTokenHash(tokenHash string) (*database.ReputationFeed, error)
+	ListReputationFeeds() ([]database.ReputationFeed, error)
+	UpdateReputationFeed(id int, description *string, weight *float64, enabled *bool) (*database.ReputationFeed, error)
+	UpsertExternalSignals(feedID int, signals []database.ExternalSignal) (int, error)
+	GetExternalSignalsByIP(ip string, now time.Time) ([]database.ExternalSignal, error)
+}
+
+type dbFeedStore struct{}
+
+func (dbFeedStore) CreateReputationFeed(name, description string, weight float64, tokenHash, createdBy string) (*database.ReputationFeed, error) {
+	return database.CreateReputationFeed(name, description, weight, tokenHash, createdBy)
+}
+
+func (dbFeedStore) GetReputationFeedByTokenHash(tokenHash string) (*database.ReputationFeed, error) {
+	return database.GetReputationFeedByTokenHash(tokenHash)
+}
+
+func (dbFeedStore) ListReputationFeeds() ([]database.ReputationFeed, error) {
+	return database.ListReputationFeeds()
+}
+
+func (dbFeedStore) UpdateReputationFeed(id int, description *string, weight *float64, enabled *bool) (*database.ReputationFeed, error) {
+	return database.UpdateReputationFeed(id, description, weight, enabled)
+}
+
+func (dbFeedStore) UpsertExternalSignals(feedID int, signals []database.ExternalSignal) (int, error) {
+	return database.UpsertExternalSignals(feedID, signals)
+}
+
+func (dbFeedStore) GetExternalSignalsByIP(ip string, now time.Time) ([]database.ExternalSignal, error) {
+	return database.GetExternalSignalsByIP(ip, now)
+}
+
+// ReputationFeedHandler registers third-party reputation feeds and ingests
+// their verdicts
+type ReputationFeedHandler struct {
+	store  feedStore
+	logger *logrus.Logger
+}
+
+// NewReputationFeedHandler creates a new reputation feed handler
+func NewReputationFeedHandler(logger *logrus.Logger) *ReputationFeedHandler {
+	return &ReputationFeedHandler{store: dbFeedStore{}, logger: logger}
+}
+
+func generateFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	return feedTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// @Summary Register reputation feed
+// @Description Register a third-party reputation feed (threat intel, partner blocklist) and return its token, which it posts verdicts with. The token is only returned once.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param feed body CreateReputationFeedRequest true "Feed details"
+// @Success 201 {object} CreateReputationFeedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/feeds [post]
+func (h *ReputationFeedHandler) HandleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	var req CreateReputationFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeUserError(w, http.StatusBadRequest, "missing_name", "Feed name is required")
+		return
+	}
+	weight := 1.0
+	if req.Weight != nil {
+		weight = *req.Weight
+	}
+	if weight < 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_weight", "weight must not be negative")
+		return
+	}
+
+	token, err := generateFeedToken()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to generate feed token")
+		writeUserError(w, http.StatusInternalServerError, "token_generation_failed", "Failed to generate feed token")
+		return
+	}
+
+	feed, err := h.store.CreateReputationFeed(req.Name, strings.TrimSpace(req.Description), weight, hashWebhookToken(token), principalName(r))
+	if errors.Is(err, database.ErrFeedExists) {
+		writeUserError(w, http.StatusConflict, "feed_exists", "A reputation feed with this name already exists")
+		return
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_reputation_feed_failed",
+			"name":   req.Name,
+			"error":  err.Error(),
+		}).Error("Failed to create reputation feed")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to create reputation feed")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "reputation_feed_created",
+		"feed_id": feed.ID,
+		"name":    feed.Name,
+		"weight":  feed.Weight,
+	}).Info("Reputation feed created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateReputationFeedResponse{Feed: *feed, Token: token})
+}
+
+// @Summary List reputation feeds
+// @Description Registered third-party reputation feeds with their weights and counters
+// @Tags ip-reputation
+// @Produce json
+// @Success 200 {array} database.ReputationFeed
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/feeds [get]
+func (h *ReputationFeedHandler) HandleListFeeds(w http.ResponseWriter, r *http.Request) {
+	feeds, err := h.store.ListReputationFeeds()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list reputation feeds")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list reputation feeds")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feeds)
+}
+
+// @Summary Update reputation feed
+// @Description Change a feed's description, weight or enabled flag. A disabled feed's token is refused and its verdicts stop counting; they are kept.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param id path int true "Feed ID"
+// @Param feed body UpdateReputationFeedRequest true "Fields to change"
+// @Success 200 {object} database.ReputationFeed
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reputation/feeds/{id} [patch]
+func (h *ReputationFeedHandler) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "Invalid feed ID")
+		return
+	}
+	var req UpdateReputationFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if req.Weight != nil && *req.Weight < 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_weight", "weight must not be negative")
+		return
+	}
+
+	feed, err := h.store.UpdateReputationFeed(id, req.Description, req.Weight, req.Enabled)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "update_reputation_feed_failed",
+			"feed_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to update reputation feed")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to update reputation feed")
+		return
+	}
+	if feed == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "Reputation feed not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":     "reputation_feed_updated",
+		"feed_id":    feed.ID,
+		"weight":     feed.Weight,
+		"enabled":    feed.Enabled,
+		"updated_by": principalName(r),
+	}).Info("Reputation feed updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// decodeExternalSignal validates a verdict and fills its defaults
+func decodeExternalSignal(req ExternalSignalRequest, now time.Time) (database.ExternalSignal, error) {
+	ip, err := validation.NormalizeIP(req.IP)
+	if err != nil {
+		return database.ExternalSignal{}, fmt.Errorf("ip must be a valid IP address")
+	}
+	verdict := strings.ToLower(strings.TrimSpace(req.Verdict))
+	switch verdict {
+	case database.VerdictMalicious, database.VerdictSuspicious, database.VerdictClean:
+	default:
+		return database.ExternalSignal{}, fmt.Errorf("verdict must be malicious, suspicious or clean")
+	}
+	confidence := 1.0
+	if req.Confidence != nil {
+		confidence = *req.Confidence
+	}
+	if confidence < 0 || confidence > 1 {
+		return database.ExternalSignal{}, fmt.Errorf("confidence must be between 0 and 1")
+	}
+
+	reportedAt := now
+	if req.ReportedAt != nil {
+		if req.ReportedAt.After(now.Add(maxSignalClockSkew)) {
+			return database.ExternalSignal{}, fmt.Errorf("reported_at must not be in the future")
+		}
+		reportedAt = *req.ReportedAt
+	}
+	expiresAt := reportedAt.Add(defaultSignalTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(reportedAt) {
+			return database.ExternalSignal{}, fmt.Errorf("expires_at must be after reported_at")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	return database.ExternalSignal{
+		IP:         ip,
+		Verdict:    verdict,
+		Confidence: confidence,
+		Reason:     strings.TrimSpace(req.Reason),
+		Reference:  strings.TrimSpace(req.Reference),
+		ReportedAt: reportedAt,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// @Summary Post reputation feed verdicts
+// @Description Post a feed's verdicts on IPs, authenticated with the feed token (X-Webhook-Token, Bearer or the Basic password). Each verdict replaces the feed's earlier one on the IP unless that was reported later. Unexpired verdicts of enabled feeds add up to an IP's external score: malicious counts 1, suspicious 0.5 and clean 0, each times the feed weight and the confidence. A score of quarantine_external_score quarantines the IP and one of warning_external_score warns (runtime config, 2 and 1 by default). The whole batch is refused if one verdict is invalid.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body ExternalSignalsRequest true "Verdicts, at most 1000"
+// @Success 200 {object} ExternalSignalsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/reputation-feeds/signals [post]
+func (h *ReputationFeedHandler) HandlePostSignals(w http.ResponseWriter, r *http.Request) {
+	token := webhookToken(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="reputation-feeds"`)
+		writeUserError(w, http.StatusUnauthorized, "unauthorized", "A valid reputation feed token is required")
+		return
+	}
+	feed, err := h.store.GetReputationFeedByTokenHash(hashWebhookToken(token))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "reputation_feed_lookup_failed",
+			"error":  err.Error(),
+		}).Error("Failed to look up reputation feed")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to authenticate reputation feed")
+		return
+	}
+	if feed == nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":      "reputation_feed_auth_failed",
+			"remote_addr": r.RemoteAddr,
+		}).Warn("Rejected reputation feed token")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="reputation-feeds"`)
+		writeUserError(w, http.StatusUnauthorized, "unauthorized", "A valid reputation feed token is required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFeedBodySize)
+	var req ExternalSignalsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeUserError(w, http.StatusRequestEntityTooLarge, "request_too_large", "Request body exceeds 1MB")
+			return
+		}
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if len(req.Signals) == 0 {
+		writeUserError(w, http.StatusBadRequest, "missing_signals", "signals must not be empty")
+		return
+	}
+	if len(req.Signals) > maxFeedSignals {
+		writeUserError(w, http.StatusBadRequest, "too_many_signals", fmt.Sprintf("At most %d signals per request", maxFeedSignals))
+		return
+	}
+
+	now := time.Now()
+	signals := make([]database.ExternalSignal, 0, len(req.Signals))
+	for i, s := range req.Signals {
+		signal, err := decodeExternalSignal(s, now)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_signal", fmt.Sprintf("signals[%d]: %s", i, err.Error()))
+			return
+		}
+		signals = append(signals, signal)
+	}
+
+	applied, err := h.store.UpsertExternalSignals(feed.ID, signals)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "store_external_signals_failed",
+			"feed_id": feed.ID,
+			"error":   err.Error(),
+		}).Error("Failed to store external signals")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to store signals")
+		return
+	}
+	for _, s := range signals {
+		reputation.RecordExternalSignal(feed.Name, s.Verdict)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "external_signals_received",
+		"feed":     feed.Name,
+		"accepted": len(signals),
+		"applied":  applied,
+	}).Info("Reputation feed signals received")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExternalSignalsResponse{Feed: feed.Name, Accepted: len(signals), Applied: applied})
+}
+
+// @Summary Get external signals for IP
+// @Description The unexpired verdicts of enabled third-party feeds on an IP, heaviest first, with the external score they add up to and each feed's share
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} ExternalSignalsByIPResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/external-signals [get]
+func (h *ReputationFeedHandler) HandleListIPSignals(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+
+	signals, err := h.store.GetExternalSignalsByIP(ip, time.Now())
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_external_signals_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get external signals")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve external signals")
+		return
+	}
+
+	score, feeds := reputation.ExternalScore(signals)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExternalSignalsByIPResponse{IP: ip, Score: score, Feeds: feeds, Signals: signals})
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryFeedStore is an in-memory feedStore
+type memoryFeedStore struct {
+	feeds   []database.ReputationFeed
+	hashes  map[int]string
+	signals []database.ExternalSignal
+}
+
+func (s *memoryFeedStore) CreateReputationFeed(name, description string, weight float64, tokenHash, createdBy string) (*database.ReputationFeed, error) {
+	for _, f := range s.feeds {
+		if f.Name == name {
+			return nil, database.ErrFeedExists
+		}
+	}
+	feed := database.ReputationFeed{ID: len(s.feeds) + 1, Name: name, Description: description, Weight: weight, Enabled: true, CreatedBy: createdBy, CreatedAt: time.Now()}
+	s.feeds = append(s.feeds, feed)
+	s.hashes[feed.ID] = tokenHash
+	return &feed, nil
+}
+
+func (s *memoryFeedStore) GetReputationFeedByTokenHash(tokenHash string) (*database.ReputationFeed, error) {
+	for _, f := range s.feeds {
+		if s.hashes[f.ID] == tokenHash && f.Enabled {
+			return &f, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryFeedStore) ListReputationFeeds() ([]database.ReputationFeed, error) {
+	return append([]database.ReputationFeed{}, s.feeds...), nil
+}
+
+func (s *memoryFeedStore) UpdateReputationFeed(id int, description *string, weight *float64, enabled *bool) (*database.ReputationFeed, error) {
+	for i := range s.feeds {
+		f := &s.feeds[i]
+		if f.ID != id {
+			continue
+		}
+		if description != nil {
+			f.Description = *description
+		}
+		if weight != nil {
+			f.Weight = *weight
+		}
+		if enabled != nil {
+			f.Enabled = *enabled
+		}
+		updated := *f
+		return &updated, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryFeedStore) UpsertExternalSignals(feedID int, signals []database.ExternalSignal) (int, error) {
+	applied := 0
+next:
+	for _, signal := range signals {
+		signal.FeedID = feedID
+		for i, existing := range s.signals {
+			if existing.FeedID == feedID && existing.IP == signal.IP {
+				if !signal.ReportedAt.Before(existing.ReportedAt) {
+					s.signals[i] = signal
+					applied++
+				}
+				continue next
+			}
+		}
+		s.signals = append(s.signals, signal)
+		applied++
+	}
+	return applied, nil
+}
+
+func (s *memoryFeedStore) GetExternalSignalsByIP(ip string, now time.Time) ([]database.ExternalSignal, error) {
+	signals := []database.ExternalSignal{}
+	for _, signal := range s.signals {
+		feed := s.feeds[signal.FeedID-1]
+		if signal.IP == ip && signal.ExpiresAt.After(now) && feed.Enabled {
+			signal.FeedName, signal.FeedWeight = feed.Name, feed.Weight
+			signals = append(signals, signal)
+		}
+	}
+	return signals, nil
+}
+
+func newTestFeedRouter() (*mux.Router, *memoryFeedStore) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryFeedStore{hashes: map[int]string{}}
+	handler := &ReputationFeedHandler{store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reputation/feeds", handler.HandleCreateFeed).Methods("POST")
+	router.HandleFunc("/api/reputation/feeds", handler.HandleListFeeds).Methods("GET")
+	router.HandleFunc("/api/reputation/feeds/{id}", handler.HandleUpdateFeed).Methods("PATCH")
+	router.HandleFunc("/api/webhooks/reputation-feeds/signals", handler.HandlePostSignals).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/external-signals", handler.HandleListIPSignals).Methods("GET")
+	return router, store
+}
+
+func createTestFeed(t *testing.T, router http.Handler, body string) CreateReputationFeedResponse {
+	t.Helper()
+	rec := serveConfig(router, "POST", "/api/reputation/feeds", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create feed status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp CreateReputationFeedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func postSignals(router http.Handler, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/webhooks/reputation-feeds/signals", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReputationFeedSignals(t *testing.T) {
+	router, _ := newTestFeedRouter()
+
+	intel := createTestFeed(t, router, `{"name":"threat-intel","weight":2}`)
+	if !strings.HasPrefix(intel.Token, feedTokenPrefix) || intel.Feed.Weight != 2 {
+		t.Fatalf("Unexpected feed %+v", intel)
+	}
+	partner := createTestFeed(t, router, `{"name":"partner-blocklist"}`)
+	if partner.Feed.Weight != 1 {
+		t.Errorf("Expected default weight 1, got %v", partner.Feed.Weight)
+	}
+	if rec := serveConfig(router, "POST", "/api/reputation/feeds", `{"name":"partner-blocklist"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate name, got %d", rec.Code)
+	}
+
+	rec := postSignals(router, intel.Token, `{"signals":[{"ip":"192.0.2.1","verdict":"Malicious","confidence":0.75},{"ip":"192.0.2.2","verdict":"clean"}]}`)
+	var resp ExternalSignalsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || resp.Accepted != 2 || resp.Applied != 2 {
+		t.Fatalf("Unexpected signals response %d: %s", rec.Code, rec.Body.String())
+	}
+	// An older verdict does not replace a newer one
+	old := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	rec = postSignals(router, intel.Token, `{"signals":[{"ip":"192.0.2.1","verdict":"clean","reported_at":"`+old+`"}]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Applied != 0 {
+		t.Errorf("Expected the older verdict not applied: %s", rec.Body.String())
+	}
+	postSignals(router, partner.Token, `{"signals":[{"ip":"192.0.2.1","verdict":"suspicious"}]}`)
+
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/external-signals", "")
+	var signals ExternalSignalsByIPResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &signals); err != nil {
+		t.Fatal(err)
+	}
+	if signals.Score != 2 || len(signals.Signals) != 2 || signals.Feeds["threat-intel"] != 1.5 || signals.Feeds["partner-blocklist"] != 0.5 {
+		t.Errorf("Unexpected external signals %s", rec.Body.String())
+	}
+
+	// A disabled feed's token is refused and its verdicts stop counting
+	rec = serveConfig(router, "PATCH", fmt.Sprintf("/api/reputation/feeds/%d", intel.Feed.ID), `{"enabled":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update feed status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postSignals(router, intel.Token, `{"signals":[{"ip":"192.0.2.3","verdict":"malicious"}]}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a disabled feed, got %d", rec.Code)
+	}
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/external-signals", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &signals); err != nil || signals.Score != 0.5 {
+		t.Errorf("Expected only the partner verdict to count: %s", rec.Body.String())
+	}
+}
+
+func TestReputationFeedSignalsValidation(t *testing.T) {
+	router, store := newTestFeedRouter()
+	feed := createTestFeed(t, router, `{"name":"threat-intel"}`)
+
+	if rec := postSignals(router, "", `{"signals":[{"ip":"192.0.2.1","verdict":"malicious"}]}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := postSignals(router, "rfeed_unknown", `{"signals":[{"ip":"192.0.2.1","verdict":"malicious"}]}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown token, got %d", rec.Code)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no signals", `{"signals":[]}`},
+		{"invalid ip", `{"signals":[{"ip":"192.0.2.1","verdict":"malicious"},{"ip":"not-an-ip","verdict":"malicious"}]}`},
+		{"unknown verdict", `{"signals":[{"ip":"192.0.2.1","verdict":"bad"}]}`},
+		{"confidence above 1", `{"signals":[{"ip":"192.0.2.1","verdict":"malicious","confidence":1.5}]}`},
+		{"reported in the future", `{"signals":[{"ip":"192.0.2.1","verdict":"malicious","reported_at":"` + future + `"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postSignals(router, feed.Token, tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if len(store.signals) != 0 {
+		t.Errorf("Expected invalid batches not stored, got %d signals", len(store.signals))
+	}
+
+	if rec := serveConfig(router, "PATCH", "/api/reputation/feeds/99", `{"weight":2}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown feed, got %d", rec.Code)
+	}
+	if rec := serveConfig(router, "PATCH", fmt.Sprintf("/api/reputation/feeds/%d", feed.Feed.ID), `{"weight":-1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative weight, got %d", rec.Code)
+	}
+}
@@ -1,14 +1,29 @@
 package api
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
 
-	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/alerting"
+	"golang-backend-service/internal/audit"
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/dependencies"
+	"golang-backend-service/internal/faults"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/features"
 	"golang-backend-service/internal/ionos"
-	"golang-backend-service/internal/logger"
+	"golang-backend-service/internal/itsm"
+	"golang-backend-service/internal/jobs"
+	"golang-backend-service/internal/redact"
+	"golang-backend-service/internal/reporting"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/slo"
+	"golang-backend-service/internal/stalwart"
+	"golang-backend-service/internal/tlspolicy"
+	"golang-backend-service/internal/validation"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,34 +53,76 @@ var (
 	)
 )
 
-// CreateUserRequest represents the request body for creating a user
-type CreateUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-}
-
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 }
 
-// corsMiddleware adds CORS headers to all responses
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from any origin (for development)
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		next.ServeHTTP(w, r)
-	})
+// Dependencies holds the services and logger the API routes are built from.
+// Nil fields fall back to defaults; a nil IonosService disables the IP
+// reservation endpoints.
+type Dependencies struct {
+	Logger       *logrus.Logger
+	IonosService *ionos.Service
+	Aggregator   *reputation.AggregationService
+	DNSBL        *reputation.DNSBLService
+	AccessLog    *AccessLogConfig
+	Compression  *CompressionConfig
+	// Timeouts sets the context deadline of requests; nil uses
+	// DefaultTimeoutConfig
+	Timeouts *TimeoutConfig
+	// Backpressure refuses webhooks while ingestion is overloaded; nil uses
+	// DefaultBackpressureConfig
+	Backpressure *BackpressureConfig
+	// WebhookSignatures are the secrets ingestion payloads are signed with;
+	// nil accepts unsigned payloads
+	WebhookSignatures *WebhookSignatureConfig
+	CORS         *CORSConfig
+	// Auth configures API keys and dashboard sessions. Nil accepts
+	// unauthenticated requests and signs sessions with a random key.
+	Auth *AuthConfig
+	Redactor     *redact.Redactor
+	// Features holds the feature flags, e.g. strict_webhook_auth to reject
+	// webhook deliveries without a source token; nil uses features.Default
+	Features *features.Set
+	// DependencyRegistry backs /api/system/dependencies; nil uses
+	// dependencies.Default
+	DependencyRegistry *dependencies.Registry
+	// Drainer rejects webhooks and fails /health once shutdown begins; nil
+	// never drains
+	Drainer *Drainer
+	// ErrorReporter receives recovered handler panics; nil only logs them
+	ErrorReporter reporting.ErrorReporter
+	// AlertRouter resolves alert destinations from the notification routes;
+	// nil reads them from the database
+	AlertRouter *alerting.Router
+	// Jobs runs asynchronous requests such as DNSBL checks; nil stores jobs
+	// in the database with the default config
+	Jobs *jobs.Runner
+	// TLSPolicies caches destinations' MTA-STS and DANE policies to annotate
+	// failures with; nil disables the annotation and the policy endpoints
+	TLSPolicies *tlspolicy.Cache
+	// Faults backs /api/system/faults; nil uses faults.Default
+	Faults *faults.Injector
+	// Languages are the languages summaries and recommendations are served
+	// in; nil uses i18n.Default
+	Languages *i18n.Bundle
+	// ITSM opens and syncs Jira and ServiceNow tickets for blacklisted IPs
+	// and delisting tasks; nil only manages the rules
+	ITSM *itsm.Service
+	// ITSMWebhookSecret authenticates the Jira and ServiceNow status
+	// webhooks; empty refuses them
+	ITSMWebhookSecret string
+	// SLO reports the fleet objectives; nil reads the measurements of
+	// slo.DefaultConfig
+	SLO *slo.Tracker
+	// Auditor runs the self-audit of stale data and inconsistencies; nil
+	// audits with audit.DefaultConfig and without the IONOS block check
+	Auditor *audit.Auditor
+	// Stalwart receives the rate caps set on IPs; nil only serves them in the
+	// sending policy
+	Stalwart stalwart.Pusher
 }
 
 // HealthResponse represents the health check response
@@ -76,55 +133,389 @@ type HealthResponse struct {
 
 // SetupRoutes configures all API routes
 func SetupRoutes() *mux.Router {
-	return SetupRoutesWithDependencies(nil)
+	return SetupRoutesWithDependencies(Dependencies{})
 }
 
-// SetupRoutesWithDependencies configures all API routes with optional dependencies
-func SetupRoutesWithDependencies(ionosService *ionos.Service) *mux.Router {
+// SetupRoutesWithDependencies configures all API routes with the given dependencies
+func SetupRoutesWithDependencies(deps Dependencies) *mux.Router {
+	log := deps.Logger
+	if log == nil {
+		log = logrus.StandardLogger()
+	}
+	aggregator := deps.Aggregator
+	if aggregator == nil {
+		aggregator = reputation.NewAggregationService(reputation.DefaultReputationConfig(), log)
+	}
+	dnsbl := deps.DNSBL
+	if dnsbl == nil {
+		dnsbl = reputation.NewDNSBLService(log)
+	}
+
+	redactor := deps.Redactor
+	if redactor == nil {
+		// Pseudonymize by default; New only fails if the system RNG does
+		redactor, _ = redact.New(redact.ModeHash, "")
+	}
+	accessLog := DefaultAccessLogConfig()
+	if deps.AccessLog != nil {
+		accessLog = *deps.AccessLog
+	}
+	cors := DefaultCORSConfig("development")
+	if deps.CORS != nil {
+		cors = *deps.CORS
+	}
+	authConfig := AuthConfig{RefreshTTL: 30 * 24 * time.Hour}
+	if deps.Auth != nil {
+		authConfig = *deps.Auth
+	}
+	if authConfig.Issuer == nil {
+		authConfig.Issuer = randomTokenIssuer(log)
+	}
+	if authConfig.KeyStore == nil {
+		authConfig.KeyStore = dbAPIKeyStore{}
+	}
+	compression := DefaultCompressionConfig()
+	if deps.Compression != nil {
+		compression = *deps.Compression
+	}
+	timeouts := DefaultTimeoutConfig()
+	if deps.Timeouts != nil {
+		timeouts = *deps.Timeouts
+	}
+	backpressure := DefaultBackpressureConfig()
+	if deps.Backpressure != nil {
+		backpressure = *deps.Backpressure
+	}
+	var webhookSignatures WebhookSignatureConfig
+	if deps.WebhookSignatures != nil {
+		webhookSignatures = *deps.WebhookSignatures
+	}
+	drainer := deps.Drainer
+	if drainer == nil {
+		drainer = NewDrainer(0)
+	}
+	reporter := deps.ErrorReporter
+	if reporter == nil {
+		reporter = reporting.Nop
+	}
+	injector := deps.Faults
+	if injector == nil {
+		injector = faults.Default
+	}
+	flags := deps.Features
+	if flags == nil {
+		flags = features.Default
+	}
+	alertRouter := deps.AlertRouter
+	if alertRouter == nil {
+		alertRouter = alerting.NewRouter(nil)
+	}
+	sloTracker := deps.SLO
+	if sloTracker == nil {
+		sloTracker = slo.NewTracker(slo.DefaultConfig(), log)
+	}
+	auditor := deps.Auditor
+	if auditor == nil {
+		auditor = audit.NewAuditor(audit.DefaultConfig(), nil, log)
+	}
+	jobRunner := deps.Jobs
+	if jobRunner == nil {
+		jobRunner = jobs.NewRunner(nil, jobs.Config{}, log)
+	}
+	languages := deps.Languages
+	if languages == nil {
+		languages = i18n.Default
+	}
+
+	userHandler := NewUserHandler(log)
+	reputationHandler := NewReputationHandler(aggregator, dnsbl, deps.TLSPolicies, redactor, log)
+	privacyHandler := NewPrivacyHandler(log)
+	webhookSourceHandler := NewWebhookSourceHandler(func() bool { return flags.Enabled(features.StrictWebhookAuth) }, log)
+	authHandler := NewAuthHandler(authConfig.Issuer, authConfig.RefreshTTL, log)
+	apiKeyHandler := NewAPIKeyHandler(authConfig.KeyStore, authConfig.APIKeys, log)
+	ownershipHandler := NewOwnershipHandler(log)
+	reputationConfigHandler := NewReputationConfigHandler(aggregator, log)
+	annotationHandler := NewIPAnnotationHandler(log)
+	jobHandler := NewJobHandler(jobRunner, dnsbl, aggregator, log)
+	spamtrapHandler := NewSpamtrapHandler(aggregator, log)
+	arfHandler := NewARFHandler(log)
+	tlsReportHandler := NewTLSReportHandler(log)
+	volumeHandler := NewVolumeHandler(log)
+	sloHandler := NewSLOHandler(sloTracker, log)
+	auditHandler := NewAuditHandler(auditor, log)
+	tlsPolicyHandler := NewTLSPolicyHandler(deps.TLSPolicies, log)
+	feedHandler := NewReputationFeedHandler(log)
+	sendingPolicyHandler := NewSendingPolicyHandler(deps.Stalwart, log)
+	ipMigrationHandler := NewIPMigrationHandler(log)
+	archiveHandler := NewIPArchiveHandler(log)
+	delistingHandler := NewDelistingTaskHandler(deps.ITSM, log)
+	itsmHandler := NewITSMHandler(deps.ITSM, deps.ITSMWebhookSecret, log)
+	recommendationHandler := NewRecommendationHandler(reputation.NewRecommendationCatalog(nil), log)
+	bulkActionHandler := NewBulkActionHandler(jobHandler, log)
+	exclusionHandler := NewAggregationExclusionHandler(log)
+
 	router := mux.NewRouter()
 
 	// Add CORS middleware (must be first to handle preflight requests)
-	router.Use(corsMiddleware)
+	router.Use(corsMiddleware(cors))
+	router.MethodNotAllowedHandler = methodNotAllowedHandler(cors)
 	
 	// Add metrics middleware
 	router.Use(metricsMiddleware)
-	router.Use(loggingMiddleware)
+	router.Use(loggingMiddleware(log, accessLog))
+
+	// Compress large responses; inside logging and metrics so they see the
+	// real status code
+	router.Use(compressionMiddleware(compression))
+
+	// Recover handler panics inside compression, logging and metrics so they
+	// record the 500; outside compression, closing the compressed response
+	// while a panic unwinds would send a 200 first
+	router.Use(recoveryMiddleware(reporter, log))
+
+	// Give each request a context deadline; its 504 is logged and compressed
+	// like any other response
+	router.Use(timeoutMiddleware(timeouts))
+
+	// Authenticate API keys and dashboard sessions
+	router.Use(authMiddleware(authConfig, log))
+
+	// Validate and normalize {ip} path parameters before any handler sees them
+	router.Use(ipParamMiddleware(log))
+
+	// Negotiate the language of localized summaries and recommendations
+	router.Use(languageMiddleware(languages))
 
 	// Health check endpoint
-	router.HandleFunc("/health", healthHandler).Methods("GET")
+	router.HandleFunc("/health", healthHandler(drainer)).Methods("GET")
+	router.HandleFunc("/api/system/dependencies", NewSystemHandler(deps.DependencyRegistry).getDependenciesHandler).Methods("GET")
+	featureHandler := NewFeatureHandler(flags, log)
+	router.HandleFunc("/api/system/features", featureHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/system/features/{name}", featureHandler.HandleToggle).Methods("PUT")
+	router.HandleFunc("/api/system/features/{name}/history", featureHandler.HandleHistory).Methods("GET")
+	remediationHandler := NewRemediationHandler(log)
+	router.HandleFunc("/api/system/remediation", remediationHandler.HandleGet).Methods("GET")
+	router.HandleFunc("/api/system/remediation", remediationHandler.HandleUpdate).Methods("PUT")
+	router.HandleFunc("/api/system/remediation/history", remediationHandler.HandleHistory).Methods("GET")
+	backupHandler := NewBackupHandler(aggregator, flags, log)
+	router.HandleFunc("/api/system/backup", backupHandler.HandleExport).Methods("GET")
+	router.HandleFunc("/api/system/restore", backupHandler.HandleRestore).Methods("POST")
+	faultHandler := NewFaultHandler(injector, log)
+	router.HandleFunc("/api/system/faults", faultHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/system/faults", faultHandler.HandleClearAll).Methods("DELETE")
+	router.HandleFunc("/api/system/faults/{target}", faultHandler.HandleSet).Methods("PUT")
+	router.HandleFunc("/api/system/faults/{target}", faultHandler.HandleClear).Methods("DELETE")
+	router.HandleFunc("/api/system/audit", auditHandler.HandleLatest).Methods("GET")
+	router.HandleFunc("/api/system/audit/run", auditHandler.HandleRun).Methods("POST")
+	router.HandleFunc("/api/system/audit/reports", auditHandler.HandleList).Methods("GET")
+
+	router.HandleFunc("/api/slo", sloHandler.HandleStatus).Methods("GET")
+	router.HandleFunc("/api/slo/{objective}/measurements", sloHandler.HandleMeasurements).Methods("GET")
+
+	// Auth endpoints
+	router.HandleFunc("/auth/login", authHandler.HandleLogin).Methods("POST")
+	router.HandleFunc("/auth/refresh", authHandler.HandleRefresh).Methods("POST")
+	router.HandleFunc("/auth/logout", authHandler.HandleLogout).Methods("POST")
+	router.HandleFunc("/auth/me", authHandler.HandleMe).Methods("GET")
+	router.HandleFunc("/api/auth/keys", apiKeyHandler.HandleCreate).Methods("POST")
+	router.HandleFunc("/api/auth/keys", apiKeyHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/auth/keys/{id}", apiKeyHandler.HandleRevoke).Methods("DELETE")
 
 	// User endpoints
-	router.HandleFunc("/users", getUsersHandler).Methods("GET")
-	router.HandleFunc("/users", createUserHandler).Methods("POST")
-	router.HandleFunc("/users/{id}", getUserByIDHandler).Methods("GET")
+	router.HandleFunc("/users", userHandler.getUsersHandler).Methods("GET")
+	router.HandleFunc("/users", userHandler.createUserHandler).Methods("POST")
+	router.HandleFunc("/users/{id}", userHandler.getUserByIDHandler).Methods("GET")
+	router.HandleFunc("/users/{id}", userHandler.updateUserHandler).Methods("PUT")
+	router.HandleFunc("/users/{id}", userHandler.deleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/users/{id}/role", userHandler.updateUserRoleHandler).Methods("PUT")
+	router.HandleFunc("/users/{id}/ips", ownershipHandler.HandleGetUserIPs).Methods("GET")
+
+	// Ownership endpoints (teams, IP pools and assignments)
+	router.HandleFunc("/api/teams", ownershipHandler.HandleCreateTeam).Methods("POST")
+	router.HandleFunc("/api/teams", ownershipHandler.HandleListTeams).Methods("GET")
+	router.HandleFunc("/api/teams/{id}", ownershipHandler.HandleDeleteTeam).Methods("DELETE")
+	router.HandleFunc("/api/teams/{id}/members", ownershipHandler.HandleAddTeamMember).Methods("POST")
+	router.HandleFunc("/api/teams/{id}/members/{user_id}", ownershipHandler.HandleRemoveTeamMember).Methods("DELETE")
+	router.HandleFunc("/api/pools", ownershipHandler.HandleCreatePool).Methods("POST")
+	router.HandleFunc("/api/pools", ownershipHandler.HandleListPools).Methods("GET")
+	router.HandleFunc("/api/pools/{id}", ownershipHandler.HandleDeletePool).Methods("DELETE")
+	router.HandleFunc("/api/pools/{id}/ips", ownershipHandler.HandleAddPoolIP).Methods("POST")
+	router.HandleFunc("/api/pools/{id}/ips/{ip}", ownershipHandler.HandleRemovePoolIP).Methods("DELETE")
+	router.HandleFunc("/api/assignments", ownershipHandler.HandleCreateAssignment).Methods("POST")
+	router.HandleFunc("/api/assignments", ownershipHandler.HandleListAssignments).Methods("GET")
+	router.HandleFunc("/api/assignments/{id}", ownershipHandler.HandleDeleteAssignment).Methods("DELETE")
+	router.HandleFunc("/api/ips/{ip}/owners", ownershipHandler.HandleGetIPOwners).Methods("GET")
+
+	// Alert routing per pool, team and severity
+	notificationRouteHandler := NewNotificationRouteHandler(alertRouter, log)
+	router.HandleFunc("/api/notification-routes", notificationRouteHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/notification-routes", notificationRouteHandler.HandleCreate).Methods("POST")
+	router.HandleFunc("/api/notification-routes/resolve", notificationRouteHandler.HandleResolve).Methods("GET")
+	router.HandleFunc("/api/notification-routes/{id}", notificationRouteHandler.HandleUpdate).Methods("PUT")
+	router.HandleFunc("/api/notification-routes/{id}", notificationRouteHandler.HandleDelete).Methods("DELETE")
+
+	// Jira and ServiceNow tickets for blacklistings and delisting tasks
+	router.HandleFunc("/api/itsm/rules", itsmHandler.HandleListRules).Methods("GET")
+	router.HandleFunc("/api/itsm/rules", itsmHandler.HandleCreateRule).Methods("POST")
+	router.HandleFunc("/api/itsm/rules/{id}", itsmHandler.HandleUpdateRule).Methods("PUT")
+	router.HandleFunc("/api/itsm/rules/{id}", itsmHandler.HandleDeleteRule).Methods("DELETE")
+	router.HandleFunc("/api/webhooks/itsm/{system}", itsmHandler.HandleWebhook).Methods("POST")
+
+	// Recommendation catalog: texts, runbook links and the actions per status
+	router.HandleFunc("/api/recommendations", recommendationHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/recommendations/{action}", recommendationHandler.HandleSave).Methods("PUT")
+	router.HandleFunc("/api/recommendations/{action}", recommendationHandler.HandleDelete).Methods("DELETE")
+	router.HandleFunc("/api/recommendation-mappings", recommendationHandler.HandleListMappings).Methods("GET")
+	router.HandleFunc("/api/recommendation-mappings", recommendationHandler.HandleCreateMapping).Methods("POST")
+	router.HandleFunc("/api/recommendation-mappings/resolve", recommendationHandler.HandleResolve).Methods("GET")
+	router.HandleFunc("/api/recommendation-mappings/{id}", recommendationHandler.HandleDeleteMapping).Methods("DELETE")
+
+	// SCIM provisioning endpoints
+	if authConfig.SCIMEnabled {
+		scimHandler := NewSCIMHandler(log)
+		scim := router.PathPrefix("/scim/v2").Subrouter()
+		scim.Use(scimHandler.Authenticate)
+		scim.HandleFunc("/ServiceProviderConfig", scimHandler.HandleServiceProviderConfig).Methods("GET")
+		scim.HandleFunc("/Users", scimHandler.HandleListUsers).Methods("GET")
+		scim.HandleFunc("/Users", scimHandler.HandleCreateUser).Methods("POST")
+		scim.HandleFunc("/Users/{id}", scimHandler.HandleGetUser).Methods("GET")
+		scim.HandleFunc("/Users/{id}", scimHandler.HandleReplaceUser).Methods("PUT")
+		scim.HandleFunc("/Users/{id}", scimHandler.HandlePatchUser).Methods("PATCH")
+		scim.HandleFunc("/Users/{id}", scimHandler.HandleDeleteUser).Methods("DELETE")
+		scim.HandleFunc("/Groups", scimHandler.HandleListGroups).Methods("GET")
+		scim.HandleFunc("/Groups", scimHandler.HandleCreateGroup).Methods("POST")
+		scim.HandleFunc("/Groups/{id}", scimHandler.HandleGetGroup).Methods("GET")
+		scim.HandleFunc("/Groups/{id}", scimHandler.HandleReplaceGroup).Methods("PUT")
+		scim.HandleFunc("/Groups/{id}", scimHandler.HandlePatchGroup).Methods("PATCH")
+		scim.HandleFunc("/Groups/{id}", scimHandler.HandleDeleteGroup).Methods("DELETE")
+	}
 
 	// IP Reputation endpoints
-	router.HandleFunc("/api/webhooks/stalwart/delivery-failure", processDeliveryFailureHandler).Methods("POST")
-	router.HandleFunc("/api/ips/{ip}/reputation", getIPReputationHandler).Methods("GET")
-	router.HandleFunc("/api/ips/{ip}/failures", getIPFailuresHandler).Methods("GET")
-	router.HandleFunc("/api/ips/{ip}/quarantine", quarantineIPHandler).Methods("POST")
-	router.HandleFunc("/api/ips/{ip}/dnsbl-check", checkDNSBLHandler).Methods("POST")
-	router.HandleFunc("/api/dashboard/ip-health", getIPHealthDashboardHandler).Methods("GET")
+	webhook := drainer.Middleware(NewBackpressure(backpressure).Middleware(webhookSourceHandler.Authenticate(NewWebhookSignatures(webhookSignatures, log).Middleware(http.HandlerFunc(reputationHandler.processDeliveryFailureHandler)))))
+	router.Handle("/api/webhooks/stalwart/delivery-failure", webhook).Methods("POST")
+	router.Handle("/api/webhooks/stalwart/events", webhook).Methods("POST")
+	router.HandleFunc("/api/ips/registry", reputationHandler.getIPRegistryHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/reputation", reputationHandler.getIPReputationHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/reputation/explain", reputationHandler.explainIPReputationHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/failures", reputationHandler.getIPFailuresHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/history", reputationHandler.getIPHistoryHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/live-stats", reputationHandler.getIPLiveStatsHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/auth-failures", reputationHandler.getIPAuthFailuresHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/spamtrap-hits", spamtrapHandler.HandleListHits).Methods("GET")
+	router.HandleFunc("/api/spamtrap-hits", spamtrapHandler.HandleRecordHit).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/complaints", arfHandler.HandleListComplaints).Methods("GET")
+	router.HandleFunc("/api/reports/arf", arfHandler.HandleReport).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/tls-failures", tlsReportHandler.HandleListIPFailures).Methods("GET")
+	router.HandleFunc("/api/reports/tls", tlsReportHandler.HandleReport).Methods("POST")
+	router.HandleFunc("/api/volumes", volumeHandler.HandleReport).Methods("POST")
+	router.HandleFunc("/api/reports/tls/destinations", tlsReportHandler.HandleListDestinations).Methods("GET")
+	router.HandleFunc("/api/destinations/tls-policies", tlsPolicyHandler.HandleListPolicies).Methods("GET")
+	router.HandleFunc("/api/destinations/{domain}/tls-policy", tlsPolicyHandler.HandleGetPolicy).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/external-signals", feedHandler.HandleListIPSignals).Methods("GET")
+	router.HandleFunc("/api/webhooks/reputation-feeds/signals", feedHandler.HandlePostSignals).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/mitigation-data", reputationHandler.getIPMitigationDataHandler).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/delisting-tasks", delistingHandler.HandleOpen).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/delisting-tasks", delistingHandler.HandleListIPTasks).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks", delistingHandler.HandleListTasks).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks/{id}", delistingHandler.HandleGetTask).Methods("GET")
+	router.HandleFunc("/api/delisting-tasks/{id}", delistingHandler.HandleUpdateTask).Methods("PATCH")
+	router.HandleFunc("/api/ips/{ip}/itsm-tickets", itsmHandler.HandleListIPTickets).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/sending-policy", sendingPolicyHandler.HandleGetPolicy).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/rate-limit", sendingPolicyHandler.HandleSetRateLimit).Methods("PUT")
+	router.HandleFunc("/api/ips/{ip}/rate-limit", sendingPolicyHandler.HandleDeleteRateLimit).Methods("DELETE")
+	router.HandleFunc("/api/sending-policy/throttles", sendingPolicyHandler.HandleListThrottles).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/instances", reputationHandler.getIPInstancesHandler).Methods("GET")
+	// Registered before /api/ips/{ip}/quarantine, which would take "bulk" for an IP
+	router.HandleFunc("/api/ips/bulk/quarantine", bulkActionHandler.HandleBulkQuarantine).Methods("POST")
+	router.HandleFunc("/api/ips/bulk/restore", bulkActionHandler.HandleBulkRestore).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/quarantine", reputationHandler.quarantineIPHandler).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/actions/{id}/comments", annotationHandler.HandleCreateActionComment).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/actions/{id}/comments", annotationHandler.HandleListActionComments).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/notes", annotationHandler.HandleCreateNote).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/notes", annotationHandler.HandleListNotes).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/notes/{id}", annotationHandler.HandleDeleteNote).Methods("DELETE")
+	router.HandleFunc("/api/ips/{ip}/context", annotationHandler.HandleGetContext).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/context", annotationHandler.HandleSetContext).Methods("PUT")
+	router.HandleFunc("/api/ips/{ip}/context", annotationHandler.HandleDeleteContext).Methods("DELETE")
+	router.HandleFunc("/api/ips/archived", archiveHandler.HandleListArchived).Methods("GET")
+	router.HandleFunc("/api/ips/archive", archiveHandler.HandleBulkArchive).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/archive", archiveHandler.HandleArchive).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/archive", archiveHandler.HandleUnarchive).Methods("DELETE")
+	router.HandleFunc("/api/ips/{ip}/dnsbl-check", reputationHandler.checkDNSBLHandler).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/dnsbl-check/async", jobHandler.HandleDNSBLCheck).Methods("POST")
+	router.HandleFunc("/api/jobs/{id}", jobHandler.HandleGetJob).Methods("GET")
+	router.HandleFunc("/api/dashboard/ip-health", reputationHandler.getIPHealthDashboardHandler).Methods("GET")
+	router.HandleFunc("/api/dashboard/heatmap", reputationHandler.getRejectionHeatmapHandler).Methods("GET")
+	router.HandleFunc("/api/dashboard/capacity-forecast", reputationHandler.getCapacityForecastHandler).Methods("GET")
+	router.HandleFunc("/api/analytics/compare", reputationHandler.getFleetComparisonHandler).Methods("GET")
+	router.HandleFunc("/api/analytics/reason-families", reputationHandler.getReasonFamiliesHandler).Methods("GET")
+	router.HandleFunc("/api/reputation/simulate", reputationHandler.simulateReputationHandler).Methods("POST")
+
+	// Moving history to a new address when sending IPs are renumbered
+	router.HandleFunc("/api/admin/ips/{old}/migrate-to/{new}", ipMigrationHandler.HandleMigrate).Methods("POST")
+
+	// Recomputing metrics and history after the decision rules changed
+	router.HandleFunc("/api/admin/reputation/rebuild", jobHandler.HandleRebuildReputation).Methods("POST")
+
+	// Runtime-editable reputation thresholds with history and rollback
+	router.HandleFunc("/api/reputation/config", reputationConfigHandler.HandleGetConfig).Methods("GET")
+	router.HandleFunc("/api/reputation/config", reputationConfigHandler.HandleUpdateConfig).Methods("PUT")
+	router.HandleFunc("/api/reputation/config/history", reputationConfigHandler.HandleHistory).Methods("GET")
+	router.HandleFunc("/api/reputation/config/revisions/{id}/rollback", reputationConfigHandler.HandleRollback).Methods("POST")
+	router.HandleFunc("/api/reputation/shadow", reputationConfigHandler.HandleGetShadow).Methods("GET")
+	router.HandleFunc("/api/reputation/shadow", reputationConfigHandler.HandleSetShadow).Methods("PUT")
+	router.HandleFunc("/api/reputation/shadow", reputationConfigHandler.HandleDeleteShadow).Methods("DELETE")
+	router.HandleFunc("/api/reputation/shadow/promote", reputationConfigHandler.HandlePromoteShadow).Methods("POST")
+
+	// Blocks of non-sending IPs kept out of aggregation and the dashboards
+	router.HandleFunc("/api/reputation/exclusions", exclusionHandler.HandleList).Methods("GET")
+	router.HandleFunc("/api/reputation/exclusions", exclusionHandler.HandleCreate).Methods("POST")
+	router.HandleFunc("/api/reputation/exclusions/{id}", exclusionHandler.HandleDelete).Methods("DELETE")
+
+	// Third-party reputation feeds posting IP verdicts
+	router.HandleFunc("/api/reputation/feeds", feedHandler.HandleCreateFeed).Methods("POST")
+	router.HandleFunc("/api/reputation/feeds", feedHandler.HandleListFeeds).Methods("GET")
+	router.HandleFunc("/api/reputation/feeds/{id}", feedHandler.HandleUpdateFeed).Methods("PATCH")
 	
+	// Webhook source endpoints
+	router.HandleFunc("/api/webhooks/sources", webhookSourceHandler.HandleCreateSource).Methods("POST")
+	router.HandleFunc("/api/webhooks/sources", webhookSourceHandler.HandleListSources).Methods("GET")
+	router.HandleFunc("/api/webhooks/sources/{id}", webhookSourceHandler.HandleDisableSource).Methods("DELETE")
+	router.HandleFunc("/api/webhooks/sources/{id}/stats", webhookSourceHandler.HandleSourceStats).Methods("GET")
+
+	// Privacy endpoints
+	router.HandleFunc("/api/privacy/recipients/{email}", privacyHandler.HandleEraseRecipient).Methods("DELETE")
+
 	// IP Reservation endpoints (IONOS)
-	if ionosService != nil {
-		ipHandler := NewIPReservationHandler(ionosService, logger.Log)
+	if deps.IonosService != nil {
+		ipHandler := NewIPReservationHandler(deps.IonosService, log)
 		router.HandleFunc("/api/v1/ips/reserve", ipHandler.HandleReserveIPs).Methods("POST")
+		router.HandleFunc("/api/v1/ips/pool-spec", ipHandler.HandleApplyPoolSpec).Methods("PUT")
 		router.HandleFunc("/api/v1/ips/reserved", ipHandler.HandleListReservedIPs).Methods("GET")
 		router.HandleFunc("/api/v1/ips/reserved/{id}", ipHandler.HandleGetReservedIP).Methods("GET")
 		router.HandleFunc("/api/v1/ips/reserved/{id}/status", ipHandler.HandleUpdateIPStatus).Methods("PUT")
 		router.HandleFunc("/api/v1/ips/reserved/{id}/recheck", ipHandler.HandleRecheckBlacklist).Methods("POST")
+		router.HandleFunc("/api/v1/ips/reserved/{id}/attach", ipHandler.HandleAttachIP).Methods("POST")
+		router.HandleFunc("/api/v1/ips/reserved/{id}/release", ipHandler.HandleReleaseIP).Methods("POST")
 		router.HandleFunc("/api/v1/ips/reserved/{id}", ipHandler.HandleDeleteReservedIP).Methods("DELETE")
+		router.HandleFunc("/api/v1/ips/stale", ipHandler.HandleListStaleReservations).Methods("GET")
+		router.HandleFunc("/api/v1/ips/stale/evaluate", ipHandler.HandleEvaluateStaleReservations).Methods("POST")
+		router.HandleFunc("/api/v1/ips/quarantine/reconcile", ipHandler.HandleReconcileQuarantine).Methods("POST")
+		router.HandleFunc("/api/v1/ips/protected-blocks", ipHandler.HandleListProtectedBlocks).Methods("GET")
+		router.HandleFunc("/api/v1/ips/protected-blocks/{block_id}", ipHandler.HandleProtectBlock).Methods("PUT")
+		router.HandleFunc("/api/v1/ips/protected-blocks/{block_id}", ipHandler.HandleUnprotectBlock).Methods("DELETE")
 		router.HandleFunc("/api/v1/ips/quota", ipHandler.HandleCheckQuota).Methods("GET")
 		router.HandleFunc("/api/v1/ips/cleanup", ipHandler.HandleCleanupBlocks).Methods("POST")
 		router.HandleFunc("/api/v1/ips/statistics", ipHandler.HandleGetStatistics).Methods("GET")
+		router.HandleFunc("/api/v1/ips/locations/stats", ipHandler.HandleGetLocationStats).Methods("GET")
+		router.HandleFunc("/api/ips/{ip}/swap", ipHandler.HandleSwapIP).Methods("POST")
 	}
 	
 	// Testing endpoints
-	router.HandleFunc("/api/testing/simulate-failures", simulateFailuresHandler).Methods("POST")
-	router.HandleFunc("/api/testing/test-cases", getTestCasesHandler).Methods("GET")
-	router.HandleFunc("/api/testing/test-cases/{id}/run", runTestCaseHandler).Methods("POST")
-	router.HandleFunc("/api/testing/test-suite/run", runTestSuiteHandler).Methods("POST")
+	router.HandleFunc("/api/testing/simulate-failures", reputationHandler.simulateFailuresHandler).Methods("POST")
+	router.HandleFunc("/api/testing/test-cases", reputationHandler.getTestCasesHandler).Methods("GET")
+	router.HandleFunc("/api/testing/test-cases/{id}/run", reputationHandler.runTestCaseHandler).Methods("POST")
+	router.HandleFunc("/api/testing/test-suite/run", reputationHandler.runTestSuiteHandler).Methods("POST")
 
 	// Metrics endpoint for Prometheus
 	router.Handle("/metrics", promhttp.Handler())
@@ -132,9 +523,23 @@ func SetupRoutesWithDependencies(ionosService *ionos.Service) *mux.Router {
 	// Swagger documentation
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
+	// OpenAPI 3.1 description generated from these routes
+	router.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+
 	return router
 }
 
+// randomTokenIssuer signs sessions with a per-process key, so sessions end on
+// restart
+func randomTokenIssuer(log *logrus.Logger) *auth.TokenIssuer {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.WithError(err).Fatal("Failed to generate session signing key")
+	}
+	issuer, _ := auth.NewTokenIssuer(secret, 15*time.Minute)
+	return issuer
+}
+
 // metricsMiddleware tracks HTTP request metrics
 func metricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -150,33 +555,53 @@ func metricsMiddleware(next http.Handler) http.Handler {
 		// Record metrics
 		httpRequestsTotal.WithLabelValues(
 			r.Method,
-			r.URL.Path,
+			redactedPath(r),
 			strconv.Itoa(wrapped.statusCode),
 		).Inc()
 
 		httpRequestDuration.WithLabelValues(
 			r.Method,
-			r.URL.Path,
+			redactedPath(r),
 		).Observe(duration)
 	})
 }
 
-// loggingMiddleware logs all HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		next.ServeHTTP(wrapped, r)
-
-		logger.WithFields(logrus.Fields{
-			"method":      r.Method,
-			"path":        r.URL.Path,
-			"status":      wrapped.statusCode,
-			"duration_ms": time.Since(start).Milliseconds(),
-			"remote_addr": r.RemoteAddr,
-		}).Info("HTTP request processed")
-	})
+// ipParamMiddleware rejects requests whose {ip} path parameter is not a valid
+// IP address and rewrites the parameter to its canonical form
+func ipParamMiddleware(log *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			raw, ok := vars["ip"]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			normalized, err := validation.NormalizeIP(raw)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"action": "invalid_ip_param",
+					"path":   r.URL.Path,
+				}).Warn("Rejected request with invalid IP address")
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ErrorResponse{
+					Error:   "invalid_ip",
+					Message: "Path parameter must be a valid IPv4 or IPv6 address",
+				})
+				return
+			}
+
+			if normalized != raw {
+				vars["ip"] = normalized
+				r = mux.SetURLVars(r, vars)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -191,167 +616,24 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 // @Summary Health check
-// @Description Check if the service is healthy
+// @Description Check if the service is healthy. Responds 503 "draining" once shutdown has begun, so load balancers stop routing to the instance.
 // @Tags health
 // @Produce json
 // @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
 // @Router /health [get]
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now(),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// @Summary Get all users
-// @Description Retrieve all users from the database
-// @Tags users
-// @Produce json
-// @Success 200 {array} database.User
-// @Failure 500 {object} ErrorResponse
-// @Router /users [get]
-func getUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := database.GetAllUsers()
-	if err != nil {
-		logger.WithFields(logrus.Fields{
-			"action": "get_users",
-			"error":  err.Error(),
-		}).Error("Failed to retrieve users")
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "database_error",
-			Message: "Failed to retrieve users",
-		})
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
-}
-
-// @Summary Create a new user
-// @Description Create a new user with username and email
-// @Tags users
-// @Accept json
-// @Produce json
-// @Param user body CreateUserRequest true "User information"
-// @Success 201 {object} database.User
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /users [post]
-func createUserHandler(w http.ResponseWriter, r *http.Request) {
-	var req CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.WithFields(logrus.Fields{
-			"action": "create_user",
-			"error":  err.Error(),
-		}).Warn("Invalid request body")
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Invalid request body",
-		})
-		return
-	}
-
-	if req.Username == "" || req.Email == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "validation_error",
-			Message: "Username and email are required",
-		})
-		return
-	}
-
-	user, err := database.CreateUser(req.Username, req.Email)
-	if err != nil {
-		logger.WithFields(logrus.Fields{
-			"action":   "create_user",
-			"username": req.Username,
-			"error":    err.Error(),
-		}).Error("Failed to create user")
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "database_error",
-			Message: "Failed to create user",
-		})
-		return
-	}
-
-	logger.WithFields(logrus.Fields{
-		"action":   "create_user",
-		"user_id":  user.ID,
-		"username": user.Username,
-	}).Info("User created successfully")
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
-}
-
-// @Summary Get user by ID
-// @Description Retrieve a specific user by their ID
-// @Tags users
-// @Produce json
-// @Param id path int true "User ID"
-// @Success 200 {object} database.User
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /users/{id} [get]
-func getUserByIDHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "invalid_id",
-			Message: "User ID must be a number",
-		})
-		return
-	}
-
-	user, err := database.GetUserByID(id)
-	if err != nil {
-		if err.Error() == "user not found" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(ErrorResponse{
-				Error:   "not_found",
-				Message: "User not found",
-			})
-			return
+func healthHandler(drainer *Drainer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := HealthResponse{
+			Status:    "healthy",
+			Timestamp: time.Now(),
 		}
 
-		logger.WithFields(logrus.Fields{
-			"action":  "get_user_by_id",
-			"user_id": id,
-			"error":   err.Error(),
-		}).Error("Failed to retrieve user")
-
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   "database_error",
-			Message: "Failed to retrieve user",
-		})
-		return
+		if drainer.Draining() {
+			response.Status = "draining"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(response)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
 }
-
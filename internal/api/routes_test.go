@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestInvalidIPParamRejectedWithInjectedLogger(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: log})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ips/not-an-ip/reputation", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Error != "invalid_ip" {
+		t.Errorf("Expected error invalid_ip, got %q", body.Error)
+	}
+
+	var sawRejection, sawRequest bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["action"] == "invalid_ip_param" && entry.Level == logrus.WarnLevel {
+			sawRejection = true
+		}
+		if entry.Message == "HTTP request processed" {
+			sawRequest = true
+		}
+	}
+	if !sawRejection {
+		t.Error("Expected invalid IP rejection to be logged on the injected logger")
+	}
+	if !sawRequest {
+		t.Error("Expected request to be logged on the injected logger")
+	}
+}
+
+func TestHealthWithoutLogger(t *testing.T) {
+	router := SetupRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestPanicWithCompressionReturns500(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: log})
+	router.HandleFunc("/api/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map write")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/boom", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d %q", rec.Code, rec.Body.String())
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error != "internal_error" {
+		t.Errorf("Expected an internal_error body, got %v %+v", err, body)
+	}
+}
@@ -0,0 +1,994 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// SCIM 2.0 (RFC 7643/7644) schema URNs
+const (
+	scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema  = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	scimContentType = "application/scim+json"
+	scimMaxResults  = 500
+)
+
+// SCIMUser is a SCIM user resource mapped onto a database user
+type SCIMUser struct {
+	Schemas  []string    `json:"schemas"`
+	ID       string      `json:"id,omitempty"`
+	UserName string      `json:"userName"`
+	Emails   []SCIMEmail `json:"emails,omitempty"`
+	Active   *bool       `json:"active,omitempty"`
+	// Password is write-only and never returned
+	Password string    `json:"password,omitempty"`
+	Meta     *SCIMMeta `json:"meta,omitempty"`
+}
+
+// SCIMEmail is an entry of a SCIM user's emails
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMGroup is a SCIM group resource mapped onto a team
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMMember references a user from a group
+type SCIMMember struct {
+	Value string `json:"value"`
+}
+
+// SCIMMeta is the resource metadata
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      time.Time  `json:"created"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH body
+type SCIMPatchRequest struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []SCIMPatchOp `json:"Operations"`
+}
+
+// SCIMPatchOp is one PATCH operation
+type SCIMPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SCIMError is the SCIM error response
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimError is returned by the patch helpers to pick the response
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string { return e.detail }
+
+func badSCIMRequest(scimType, detail string) *scimError {
+	return &scimError{status: http.StatusBadRequest, scimType: scimType, detail: detail}
+}
+
+// SCIMHandler provisions users and teams from a corporate directory (Okta,
+// Entra ID, ...) over SCIM 2.0. Users map to users and groups to teams.
+type SCIMHandler struct {
+	logger *logrus.Logger
+}
+
+// NewSCIMHandler creates a new SCIM handler
+func NewSCIMHandler(logger *logrus.Logger) *SCIMHandler {
+	return &SCIMHandler{logger: logger}
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeSCIM(w, status, SCIMError{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func (h *SCIMHandler) writeFailure(w http.ResponseWriter, err error, action string) {
+	var se *scimError
+	switch {
+	case errors.As(err, &se):
+		writeSCIMError(w, se.status, se.scimType, se.detail)
+	case errors.Is(err, database.ErrUserExists), errors.Is(err, database.ErrOwnershipConflict):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", err.Error())
+	case errors.Is(err, database.ErrOwnershipReference):
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "A member does not exist")
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"action": action,
+			"error":  err.Error(),
+		}).Error("SCIM request failed")
+		writeSCIMError(w, http.StatusInternalServerError, "", "Internal error")
+	}
+}
+
+// Authenticate only admits API key principals. Directory connectors use a
+// dedicated key rather than a dashboard session.
+func (h *SCIMHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil || principal.Kind != auth.KindAPIKey {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", "An API key is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// scimLocation returns the absolute URL of a resource
+func scimLocation(r *http.Request, resource, id string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/scim/v2/" + resource + "/" + id
+}
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*(\w+)\s+eq\s+"([^"]*)"\s*$`)
+
+// parseSCIMFilter parses the only filter directory clients rely on,
+// `<attr> eq "<value>"`. An empty filter returns ok false.
+func parseSCIMFilter(filter, attr string) (value string, ok bool, err error) {
+	if filter == "" {
+		return "", false, nil
+	}
+	m := scimFilterPattern.FindStringSubmatch(filter)
+	if m == nil || !strings.EqualFold(m[1], attr) {
+		return "", false, badSCIMRequest("invalidFilter", "Only `"+attr+` eq "value"`+"` filters are supported")
+	}
+	return m[2], true, nil
+}
+
+// parseSCIMPage reads the 1-based startIndex and count parameters
+func parseSCIMPage(r *http.Request) (startIndex, count int) {
+	startIndex, count = 1, 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && v > 1 {
+		startIndex = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && v >= 0 {
+		count = v
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+	return startIndex, count
+}
+
+func scimUser(r *http.Request, u *database.User) SCIMUser {
+	active := u.Active
+	id := strconv.Itoa(u.ID)
+	updated := u.UpdatedAt
+	return SCIMUser{
+		Schemas:  []string{scimUserSchema},
+		ID:       id,
+		UserName: u.Username,
+		Emails:   []SCIMEmail{{Value: u.Email, Primary: true}},
+		Active:   &active,
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: &updated,
+			Location:     scimLocation(r, "Users", id),
+		},
+	}
+}
+
+func scimGroup(r *http.Request, t *database.Team) SCIMGroup {
+	id := strconv.Itoa(t.ID)
+	members := make([]SCIMMember, len(t.MemberIDs))
+	for i, m := range t.MemberIDs {
+		members[i] = SCIMMember{Value: strconv.Itoa(m)}
+	}
+	return SCIMGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          id,
+		DisplayName: t.Name,
+		Members:     members,
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Created:      t.CreatedAt,
+			Location:     scimLocation(r, "Groups", id),
+		},
+	}
+}
+
+// primaryEmail picks the primary email, or the first one
+func primaryEmail(emails []SCIMEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// scimUserChanges holds the user attributes a request sets. Nil fields are
+// left unchanged.
+type scimUserChanges struct {
+	UserName *string
+	Email    *string
+	Active   *bool
+	Password *string
+}
+
+var scimEmailValuePath = regexp.MustCompile(`(?i)^emails(\[.*\])?\.value$`)
+
+// applyUserPatch turns PATCH operations into user changes. Attributes this
+// service does not store (name, title, ...) are ignored so directory
+// connectors can send their full mapping.
+func applyUserPatch(ops []SCIMPatchOp) (scimUserChanges, error) {
+	var changes scimUserChanges
+	for _, op := range ops {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			return changes, badSCIMRequest("invalidSyntax", "Unsupported user operation: "+op.Op)
+		}
+
+		values := map[string]json.RawMessage{op.Path: op.Value}
+		if op.Path == "" {
+			values = nil
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return changes, badSCIMRequest("invalidValue", "Operation value must be an object when path is omitted")
+			}
+		}
+
+		for path, raw := range values {
+			var err error
+			switch {
+			case strings.EqualFold(path, "userName"):
+				err = json.Unmarshal(raw, &changes.UserName)
+			case strings.EqualFold(path, "active"):
+				err = json.Unmarshal(raw, &changes.Active)
+				if err != nil {
+					// Some connectors send "False"/"True" as strings
+					var s string
+					if json.Unmarshal(raw, &s) == nil {
+						if b, perr := strconv.ParseBool(s); perr == nil {
+							changes.Active, err = &b, nil
+						}
+					}
+				}
+			case strings.EqualFold(path, "password"):
+				err = json.Unmarshal(raw, &changes.Password)
+			case strings.EqualFold(path, "emails"):
+				var emails []SCIMEmail
+				if err = json.Unmarshal(raw, &emails); err == nil && len(emails) > 0 {
+					email := primaryEmail(emails)
+					changes.Email = &email
+				}
+			case scimEmailValuePath.MatchString(path):
+				err = json.Unmarshal(raw, &changes.Email)
+			}
+			if err != nil {
+				return changes, badSCIMRequest("invalidValue", "Invalid value for "+path)
+			}
+		}
+	}
+	return changes, nil
+}
+
+var scimMemberFilterPath = regexp.MustCompile(`(?i)^members\[value eq "([^"]+)"\]$`)
+
+// parseSCIMMembers reads a list of member references
+func parseSCIMMembers(raw json.RawMessage) ([]int, error) {
+	var members []SCIMMember
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, badSCIMRequest("invalidValue", "members must be a list of {value}")
+	}
+	ids := make([]int, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.Atoi(m.Value)
+		if err != nil {
+			return nil, badSCIMRequest("invalidValue", "Unknown member: "+m.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// applyGroupPatch applies PATCH operations to a team's name and members and
+// returns the result
+func applyGroupPatch(name string, members []int, ops []SCIMPatchOp) (string, []int, error) {
+	set := make(map[int]bool, len(members))
+	for _, id := range members {
+		set[id] = true
+	}
+
+	for _, op := range ops {
+		kind := strings.ToLower(op.Op)
+		values := map[string]json.RawMessage{op.Path: op.Value}
+		if op.Path == "" {
+			values = nil
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return "", nil, badSCIMRequest("invalidValue", "Operation value must be an object when path is omitted")
+			}
+		}
+
+		for path, raw := range values {
+			if m := scimMemberFilterPath.FindStringSubmatch(path); m != nil && kind == "remove" {
+				id, err := strconv.Atoi(m[1])
+				if err != nil {
+					return "", nil, badSCIMRequest("invalidValue", "Unknown member: "+m[1])
+				}
+				delete(set, id)
+				continue
+			}
+
+			switch {
+			case strings.EqualFold(path, "displayName") && kind != "remove":
+				if err := json.Unmarshal(raw, &name); err != nil || name == "" {
+					return "", nil, badSCIMRequest("invalidValue", "displayName must be a non-empty string")
+				}
+			case strings.EqualFold(path, "members"):
+				var ids []int
+				if len(raw) > 0 {
+					var err error
+					if ids, err = parseSCIMMembers(raw); err != nil {
+						return "", nil, err
+					}
+				}
+				switch kind {
+				case "add":
+					for _, id := range ids {
+						set[id] = true
+					}
+				case "replace":
+					set = make(map[int]bool, len(ids))
+					for _, id := range ids {
+						set[id] = true
+					}
+				case "remove":
+					if len(raw) == 0 {
+						set = map[int]bool{}
+					}
+					for _, id := range ids {
+						delete(set, id)
+					}
+				default:
+					return "", nil, badSCIMRequest("invalidSyntax", "Unsupported group operation: "+op.Op)
+				}
+			default:
+				return "", nil, badSCIMRequest("invalidPath", "Unsupported group path: "+path)
+			}
+		}
+	}
+
+	result := make([]int, 0, len(set))
+	for id := range set {
+		result = append(result, id)
+	}
+	sort.Ints(result)
+	return name, result, nil
+}
+
+func scimPathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, &scimError{status: http.StatusNotFound, detail: "Resource not found"}
+	}
+	return id, nil
+}
+
+// @Summary SCIM service provider configuration
+// @Tags scim
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) HandleServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(b bool) map[string]bool { return map[string]bool{"supported": b} }
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "API key",
+			"description": "An API key from AUTH_API_KEYS as a Bearer token",
+		}},
+	})
+}
+
+// @Summary List SCIM users
+// @Tags scim
+// @Produce json
+// @Security ApiKeyAuth
+// @Param filter query string false "userName eq \"alice\""
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Page size"
+// @Success 200 {object} SCIMListResponse
+// @Failure 400 {object} SCIMError
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := parseSCIMPage(r)
+	userName, filtered, err := parseSCIMFilter(r.URL.Query().Get("filter"), "userName")
+	if err != nil {
+		h.writeFailure(w, err, "scim_list_users")
+		return
+	}
+
+	var users []database.User
+	var total int
+	if filtered {
+		var user *database.User
+		if user, err = database.GetUserByUsername(userName); user != nil {
+			users, total = []database.User{*user}, 1
+		}
+	} else if count > 0 {
+		users, total, err = database.ListUsers(count, startIndex-1)
+	} else {
+		_, total, err = database.ListUsers(1, 0)
+	}
+	if err != nil {
+		h.writeFailure(w, err, "scim_list_users")
+		return
+	}
+
+	resources := make([]SCIMUser, len(users))
+	for i := range users {
+		resources[i] = scimUser(r, &users[i])
+	}
+	writeSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// saveUserChanges writes changes to an existing user. Deactivation also
+// revokes the user's dashboard sessions.
+func (h *SCIMHandler) saveUserChanges(id int, changes scimUserChanges) error {
+	if changes.UserName != nil {
+		if err := validation.ValidateUsername(*changes.UserName); err != nil {
+			return badSCIMRequest("invalidValue", err.Error())
+		}
+	}
+	if changes.Email != nil {
+		email, err := validation.NormalizeEmail(*changes.Email)
+		if err != nil {
+			return badSCIMRequest("invalidValue", "Email must be a valid address")
+		}
+		changes.Email = &email
+	}
+	var passwordHash string
+	if changes.Password != nil {
+		var err error
+		if passwordHash, err = auth.HashPassword(*changes.Password); err != nil {
+			return badSCIMRequest("invalidValue", err.Error())
+		}
+	}
+
+	if changes.UserName != nil || changes.Email != nil {
+		user, err := database.UpdateUser(id, changes.UserName, changes.Email)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return &scimError{status: http.StatusNotFound, detail: "User not found"}
+		}
+	}
+	if passwordHash != "" {
+		if _, err := database.SetUserPassword(id, passwordHash); err != nil {
+			return err
+		}
+	}
+	if changes.Active != nil {
+		if _, err := database.SetUserActive(id, *changes.Active); err != nil {
+			return err
+		}
+	}
+	if passwordHash != "" || (changes.Active != nil && !*changes.Active) {
+		if err := database.RevokeUserRefreshTokens(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Summary Provision SCIM user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user body SCIMUser true "SCIM user"
+// @Success 201 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	if err := validation.ValidateUsername(req.UserName); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	email, err := validation.NormalizeEmail(primaryEmail(req.Emails))
+	if err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "A valid email is required")
+		return
+	}
+
+	user, err := database.CreateUser(req.UserName, email)
+	if err != nil {
+		h.writeFailure(w, err, "scim_create_user")
+		return
+	}
+
+	changes := scimUserChanges{Active: req.Active}
+	if req.Password != "" {
+		changes.Password = &req.Password
+	}
+	if err := h.saveUserChanges(user.ID, changes); err != nil {
+		h.writeFailure(w, err, "scim_create_user")
+		return
+	}
+	if user, err = database.GetUserByID(user.ID); err != nil {
+		h.writeFailure(w, err, "scim_create_user")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "scim_user_provisioned",
+		"user_id":  user.ID,
+		"username": user.Username,
+	}).Info("User provisioned from directory")
+
+	resource := scimUser(r, user)
+	w.Header().Set("Location", resource.Meta.Location)
+	writeSCIM(w, http.StatusCreated, resource)
+}
+
+func (h *SCIMHandler) writeUser(w http.ResponseWriter, r *http.Request, id int, action string) {
+	user, err := database.GetUserByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			writeSCIMError(w, http.StatusNotFound, "", "User not found")
+			return
+		}
+		h.writeFailure(w, err, action)
+		return
+	}
+	writeSCIM(w, http.StatusOK, scimUser(r, user))
+}
+
+// @Summary Get SCIM user
+// @Tags scim
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} SCIMUser
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_get_user")
+		return
+	}
+	h.writeUser(w, r, id, "scim_get_user")
+}
+
+// @Summary Replace SCIM user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param user body SCIMUser true "SCIM user"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) HandleReplaceUser(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_replace_user")
+		return
+	}
+	var req SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	email := primaryEmail(req.Emails)
+	changes := scimUserChanges{UserName: &req.UserName, Email: &email, Active: req.Active}
+	if req.Password != "" {
+		changes.Password = &req.Password
+	}
+	if err := h.saveUserChanges(id, changes); err != nil {
+		h.writeFailure(w, err, "scim_replace_user")
+		return
+	}
+	h.writeUser(w, r, id, "scim_replace_user")
+}
+
+// @Summary Patch SCIM user
+// @Description Supports userName, emails, password and active; deactivating revokes sessions
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param patch body SCIMPatchRequest true "PATCH operations"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) HandlePatchUser(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_patch_user")
+		return
+	}
+	var req SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	changes, err := applyUserPatch(req.Operations)
+	if err == nil {
+		err = h.saveUserChanges(id, changes)
+	}
+	if err != nil {
+		h.writeFailure(w, err, "scim_patch_user")
+		return
+	}
+
+	if changes.Active != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "scim_user_active",
+			"user_id": id,
+			"active":  *changes.Active,
+		}).Info("User activation changed by directory")
+	}
+	h.writeUser(w, r, id, "scim_patch_user")
+}
+
+// @Summary Delete SCIM user
+// @Tags scim
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_delete_user")
+		return
+	}
+	found, err := database.DeleteUser(id)
+	if err != nil {
+		h.writeFailure(w, err, "scim_delete_user")
+		return
+	}
+	if !found {
+		writeSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "scim_user_deleted",
+		"user_id": id,
+	}).Info("User deleted by directory")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List SCIM groups
+// @Tags scim
+// @Produce json
+// @Security ApiKeyAuth
+// @Param filter query string false "displayName eq \"ops\""
+// @Success 200 {object} SCIMListResponse
+// @Failure 400 {object} SCIMError
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) HandleListGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := parseSCIMPage(r)
+	displayName, filtered, err := parseSCIMFilter(r.URL.Query().Get("filter"), "displayName")
+	if err != nil {
+		h.writeFailure(w, err, "scim_list_groups")
+		return
+	}
+
+	teams, err := database.ListTeams()
+	if err != nil {
+		h.writeFailure(w, err, "scim_list_groups")
+		return
+	}
+
+	var matched []database.Team
+	for _, t := range teams {
+		if !filtered || t.Name == displayName {
+			matched = append(matched, t)
+		}
+	}
+
+	resources := []SCIMGroup{}
+	for i := startIndex - 1; i < len(matched) && len(resources) < count; i++ {
+		resources = append(resources, scimGroup(r, &matched[i]))
+	}
+	writeSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: len(matched),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (h *SCIMHandler) writeGroup(w http.ResponseWriter, r *http.Request, status, id int, action string) {
+	team, err := database.GetTeam(id)
+	if err != nil {
+		h.writeFailure(w, err, action)
+		return
+	}
+	if team == nil {
+		writeSCIMError(w, http.StatusNotFound, "", "Group not found")
+		return
+	}
+
+	resource := scimGroup(r, team)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", resource.Meta.Location)
+	}
+	writeSCIM(w, status, resource)
+}
+
+// @Summary Provision SCIM group
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param group body SCIMGroup true "SCIM group"
+// @Success 201 {object} SCIMGroup
+// @Failure 400 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) HandleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DisplayName string          `json:"displayName"`
+		Members     json.RawMessage `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.DisplayName) == "" {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	var members []int
+	if len(req.Members) > 0 {
+		var err error
+		if members, err = parseSCIMMembers(req.Members); err != nil {
+			h.writeFailure(w, err, "scim_create_group")
+			return
+		}
+	}
+
+	team, err := database.CreateTeam(strings.TrimSpace(req.DisplayName), "Provisioned by SCIM")
+	if err == nil && len(members) > 0 {
+		err = database.SetTeamMembers(team.ID, members)
+	}
+	if err != nil {
+		h.writeFailure(w, err, "scim_create_group")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "scim_group_provisioned",
+		"team_id": team.ID,
+		"name":    team.Name,
+	}).Info("Team provisioned from directory")
+	h.writeGroup(w, r, http.StatusCreated, team.ID, "scim_create_group")
+}
+
+// @Summary Get SCIM group
+// @Tags scim
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Team ID"
+// @Success 200 {object} SCIMGroup
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) HandleGetGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_get_group")
+		return
+	}
+	h.writeGroup(w, r, http.StatusOK, id, "scim_get_group")
+}
+
+// updateGroup stores a team's new name and members
+func (h *SCIMHandler) updateGroup(team *database.Team, name string, members []int) error {
+	if name != team.Name {
+		if _, err := database.RenameTeam(team.ID, name); err != nil {
+			return err
+		}
+	}
+	return database.SetTeamMembers(team.ID, members)
+}
+
+func (h *SCIMHandler) loadGroup(r *http.Request) (*database.Team, error) {
+	id, err := scimPathID(r)
+	if err != nil {
+		return nil, err
+	}
+	team, err := database.GetTeam(id)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, &scimError{status: http.StatusNotFound, detail: "Group not found"}
+	}
+	return team, nil
+}
+
+// @Summary Replace SCIM group
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Team ID"
+// @Param group body SCIMGroup true "SCIM group"
+// @Success 200 {object} SCIMGroup
+// @Failure 400 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) HandleReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	team, err := h.loadGroup(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_replace_group")
+		return
+	}
+	var req struct {
+		DisplayName string          `json:"displayName"`
+		Members     json.RawMessage `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.DisplayName) == "" {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+
+	members := []int{}
+	if len(req.Members) > 0 {
+		if members, err = parseSCIMMembers(req.Members); err != nil {
+			h.writeFailure(w, err, "scim_replace_group")
+			return
+		}
+	}
+	if err := h.updateGroup(team, strings.TrimSpace(req.DisplayName), members); err != nil {
+		h.writeFailure(w, err, "scim_replace_group")
+		return
+	}
+	h.writeGroup(w, r, http.StatusOK, team.ID, "scim_replace_group")
+}
+
+// @Summary Patch SCIM group
+// @Description Supports displayName and adding, removing or replacing members
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Team ID"
+// @Param patch body SCIMPatchRequest true "PATCH operations"
+// @Success 200 {object} SCIMGroup
+// @Failure 400 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) HandlePatchGroup(w http.ResponseWriter, r *http.Request) {
+	team, err := h.loadGroup(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_patch_group")
+		return
+	}
+	var req SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	name, members, err := applyGroupPatch(team.Name, team.MemberIDs, req.Operations)
+	if err == nil {
+		err = h.updateGroup(team, name, members)
+	}
+	if err != nil {
+		h.writeFailure(w, err, "scim_patch_group")
+		return
+	}
+	h.writeGroup(w, r, http.StatusOK, team.ID, "scim_patch_group")
+}
+
+// @Summary Delete SCIM group
+// @Tags scim
+// @Security ApiKeyAuth
+// @Param id path int true "Team ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) HandleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := scimPathID(r)
+	if err != nil {
+		h.writeFailure(w, err, "scim_delete_group")
+		return
+	}
+	found, err := database.DeleteTeam(id)
+	if err != nil {
+		h.writeFailure(w, err, "scim_delete_group")
+		return
+	}
+	if !found {
+		writeSCIMError(w, http.StatusNotFound, "", "Group not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang-backend-service/internal/auth"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		filter    string
+		wantValue string
+		wantOK    bool
+		wantError bool
+	}{
+		{"", "", false, false},
+		{`userName eq "alice"`, "alice", true, false},
+		{`USERNAME EQ "Alice@Example.com"`, "Alice@Example.com", true, false},
+		{`displayName eq "ops"`, "", false, true},
+		{`userName sw "al"`, "", false, true},
+		{`userName eq alice`, "", false, true},
+	}
+
+	for _, tt := range tests {
+		value, ok, err := parseSCIMFilter(tt.filter, "userName")
+		if (err != nil) != tt.wantError || ok != tt.wantOK || value != tt.wantValue {
+			t.Errorf("parseSCIMFilter(%q) = %q, %v, %v", tt.filter, value, ok, err)
+		}
+	}
+}
+
+func patchOps(t *testing.T, body string) []SCIMPatchOp {
+	t.Helper()
+	var req SCIMPatchRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("invalid patch body: %v", err)
+	}
+	return req.Operations
+}
+
+func TestApplyUserPatch(t *testing.T) {
+	// Okta sends a path, Entra ID an object value with string booleans
+	changes, err := applyUserPatch(patchOps(t, `{"Operations":[{"op":"replace","path":"active","value":false}]}`))
+	if err != nil || changes.Active == nil || *changes.Active {
+		t.Errorf("Expected active=false, got %+v, %v", changes, err)
+	}
+
+	changes, err = applyUserPatch(patchOps(t, `{"Operations":[
+		{"op":"Replace","value":{"active":"False","userName":"bob","name.givenName":"Bob"}},
+		{"op":"replace","path":"emails[type eq \"work\"].value","value":"bob@example.com"}
+	]}`))
+	if err != nil {
+		t.Fatalf("applyUserPatch failed: %v", err)
+	}
+	if changes.Active == nil || *changes.Active || changes.UserName == nil || *changes.UserName != "bob" ||
+		changes.Email == nil || *changes.Email != "bob@example.com" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+
+	if _, err := applyUserPatch(patchOps(t, `{"Operations":[{"op":"remove","path":"active"}]}`)); err == nil {
+		t.Error("Expected remove to be rejected")
+	}
+	if _, err := applyUserPatch(patchOps(t, `{"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`)); err == nil {
+		t.Error("Expected invalid active value to be rejected")
+	}
+}
+
+func TestApplyGroupPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantName    string
+		wantMembers []int
+		wantError   bool
+	}{
+		{"add members", `{"Operations":[{"op":"add","path":"members","value":[{"value":"5"},{"value":"1"}]}]}`, "ops", []int{1, 2, 5}, false},
+		{"remove by filter", `{"Operations":[{"op":"remove","path":"members[value eq \"2\"]"}]}`, "ops", []int{1}, false},
+		{"remove listed", `{"Operations":[{"op":"remove","path":"members","value":[{"value":"1"}]}]}`, "ops", []int{2}, false},
+		{"remove all", `{"Operations":[{"op":"remove","path":"members"}]}`, "ops", []int{}, false},
+		{"replace without path", `{"Operations":[{"op":"replace","value":{"displayName":"sre","members":[{"value":"9"}]}}]}`, "sre", []int{9}, false},
+		{"bad member", `{"Operations":[{"op":"add","path":"members","value":[{"value":"bob"}]}]}`, "", nil, true},
+		{"unknown path", `{"Operations":[{"op":"replace","path":"owner","value":"x"}]}`, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, members, err := applyGroupPatch("ops", []int{1, 2}, patchOps(t, tt.body))
+			if (err != nil) != tt.wantError {
+				t.Fatalf("applyGroupPatch() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if name != tt.wantName || !reflect.DeepEqual(members, tt.wantMembers) {
+				t.Errorf("applyGroupPatch() = %q, %v, want %q, %v", name, members, tt.wantName, tt.wantMembers)
+			}
+		})
+	}
+}
+
+func TestSCIMRequiresAPIKey(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	keys, err := auth.ParseAPIKeys("directory:0123456789abcdef0123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := randomTokenIssuer(logger)
+	session, _, err := issuer.Issue(1, "operator", auth.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := SetupRoutesWithDependencies(Dependencies{
+		Logger: logger,
+		Auth:   &AuthConfig{Issuer: issuer, APIKeys: keys, SCIMEnabled: true},
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"dashboard session", "Bearer " + session, http.StatusUnauthorized},
+		{"api key", "Bearer 0123456789abcdef0123", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/scim/v2/ServiceProviderConfig", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != scimContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, scimContentType)
+			}
+		})
+	}
+}
+
+func TestSCIMDisabledByDefault(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/scim/v2/Users", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 when SCIM is disabled", rec.Code)
+	}
+}
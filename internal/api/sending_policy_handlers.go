@@ -0,0 +1,384 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+	"golang-backend-service/internal/stalwart"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// SendingPolicyResponse tells an MTA how to send from an IP: its reputation
+// status and, per destination provider, whether the provider is throttling
+// it, how long to back off and how many messages an hour to send. A
+// provider missing from Throttles has not rate-limited the IP lately, and
+// one missing from Rates gets the "*" rate. RateLimit, when set, caps the
+// IP's total outbound rate whatever its rates are.
+type SendingPolicyResponse struct {
+	IP          string                   `json:"ip"`
+	Status      string                   `json:"status"`
+	Throttles   []database.ThrottleState `json:"throttles"`
+	Rates       []database.SendRate      `json:"rates"`
+	RateLimit   *database.IPRateLimit    `json:"rate_limit,omitempty"`
+	GeneratedAt time.Time                `json:"generated_at"`
+}
+
+// ThrottledIPsResponse lists every IP a provider is currently throttling
+type ThrottledIPsResponse struct {
+	Throttles   []database.ThrottleState `json:"throttles"`
+	Count       int                      `json:"count"`
+	GeneratedAt time.Time                `json:"generated_at"`
+}
+
+// Limits of a rate cap
+const (
+	maxRateLimit    = 100000
+	minRateLimitTTL = time.Minute
+	maxRateLimitTTL = 7 * 24 * time.Hour
+)
+
+// IP actions recorded for rate caps set and lifted through the API
+const (
+	actionRateLimitSet    = "rate_limit_set"
+	actionRateLimitLifted = "rate_limit_lifted"
+)
+
+// SetRateLimitRequest caps an IP's outbound rate for a while
+type SetRateLimitRequest struct {
+	MessagesPerMinute int `json:"messages_per_minute" example:"30"`
+	// TTL is how long the cap holds, e.g. 30m or 6h; at most 168h
+	TTL    string `json:"ttl" example:"2h"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RateLimitResponse is a rate cap set on an IP. Pushed tells whether
+// Stalwart's throttling hook took it; hooks polling the sending policy see
+// it either way.
+type RateLimitResponse struct {
+	RateLimit database.IPRateLimit `json:"rate_limit"`
+	Pushed    bool                 `json:"pushed"`
+}
+
+// sendingPolicyStore reads what the sending policy is made of and stores
+// the rate caps
+type sendingPolicyStore interface {
+	GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error)
+	GetThrottleStates(ip string) ([]database.ThrottleState, error)
+	ListThrottledStates() ([]database.ThrottleState, error)
+	GetSendRates(ip string) ([]database.SendRate, error)
+	GetIPRateLimit(ip string) (*database.IPRateLimit, error)
+	SetIPRateLimit(limit *database.IPRateLimit) (*database.IPRateLimit, error)
+	DeleteIPRateLimit(ip string) (bool, error)
+	InsertIPAction(action *database.IPAction) error
+}
+
+type dbSendingPolicyStore struct{}
+
+func (dbSendingPolicyStore) GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error) {
+	return database.GetIPReputationMetrics(ip)
+}
+
+func (dbSendingPolicyStore) GetThrottleStates(ip string) ([]database.ThrottleState, error) {
+	return database.GetThrottleStates(ip)
+}
+
+func (dbSendingPolicyStore) ListThrottledStates() ([]database.ThrottleState, error) {
+	return database.ListThrottledStates()
+}
+
+func (dbSendingPolicyStore) GetSendRates(ip string) ([]database.SendRate, error) {
+	return database.GetSendRates(ip)
+}
+
+func (dbSendingPolicyStore) GetIPRateLimit(ip string) (*database.IPRateLimit, error) {
+	return database.GetIPRateLimit(ip)
+}
+
+func (dbSendingPolicyStore) SetIPRateLimit(limit *database.IPRateLimit) (*database.IPRateLimit, error) {
+	return database.SetIPRateLimit(limit)
+}
+
+func (dbSendingPolicyStore) DeleteIPRateLimit(ip string) (bool, error) {
+	return database.DeleteIPRateLimit(ip)
+}
+
+func (dbSendingPolicyStore) InsertIPAction(action *database.IPAction) error {
+	return database.InsertIPAction(action)
+}
+
+// SendingPolicyHandler serves the sending policy MTAs poll to slow down
+// before providers escalate throttling to blocks, and the rate caps pushed
+// to Stalwart
+type SendingPolicyHandler struct {
+	store  sendingPolicyStore
+	pusher stalwart.Pusher
+	logger *logrus.Logger
+}
+
+// NewSendingPolicyHandler creates a new sending policy handler. A nil
+// pusher only serves rate caps in the sending policy.
+func NewSendingPolicyHandler(pusher stalwart.Pusher, logger *logrus.Logger) *SendingPolicyHandler {
+	return &SendingPolicyHandler{store: dbSendingPolicyStore{}, pusher: pusher, logger: logger}
+}
+
+// @Summary Get sending policy for IP
+// @Description The IP's reputation status, its throttling state at each destination provider that rate-limited it within the throttle window (whether it is throttled, since when, and the backoff MTAs should apply until retry_after), and the recommended messages/hour per provider with the reasons for each reduction. Updated every aggregation run. rate_limit is the explicit messages/minute cap set with PUT /api/ips/{ip}/rate-limit, until it expires.
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} SendingPolicyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/sending-policy [get]
+func (h *SendingPolicyHandler) HandleGetPolicy(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+
+	status := state.Unknown.String()
+	metrics, err := h.store.GetIPReputationMetrics(ip)
+	switch {
+	case err == nil:
+		status = metrics.Status
+	case !strings.Contains(err.Error(), "not found"):
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_sending_policy_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get IP reputation for sending policy")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve sending policy")
+		return
+	}
+
+	throttles, err := h.store.GetThrottleStates(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_sending_policy_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get throttle states for sending policy")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve sending policy")
+		return
+	}
+
+	rates, err := h.store.GetSendRates(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_sending_policy_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get send rates for sending policy")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve sending policy")
+		return
+	}
+
+	limit, err := h.store.GetIPRateLimit(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_sending_policy_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get rate limit for sending policy")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve sending policy")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SendingPolicyResponse{IP: ip, Status: status, Throttles: throttles, Rates: rates, RateLimit: limit, GeneratedAt: time.Now()})
+}
+
+// @Summary Set IP rate limit
+// @Description Cap the IP's outbound rate at messages_per_minute for ttl, replacing an earlier cap. The cap is served in the sending policy and pushed to Stalwart's throttling hook; when it expires the IP goes back to its recommended rates and the lifting is pushed too. A failed push does not fail the request; pushed is false then.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param limit body SetRateLimitRequest true "Messages per minute and how long the cap holds"
+// @Success 200 {object} RateLimitResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/rate-limit [put]
+func (h *SendingPolicyHandler) HandleSetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+	var req SetRateLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if req.MessagesPerMinute <= 0 || req.MessagesPerMinute > maxRateLimit {
+		writeUserError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("messages_per_minute must be between 1 and %d", maxRateLimit))
+		return
+	}
+	ttl, err := time.ParseDuration(strings.TrimSpace(req.TTL))
+	if err != nil || ttl < minRateLimitTTL || ttl > maxRateLimitTTL {
+		writeUserError(w, http.StatusBadRequest, "invalid_ttl", "ttl must be a duration between 1m and 168h, e.g. 30m or 6h")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > maxAnnotationLength {
+		writeUserError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("reason must be at most %d characters", maxAnnotationLength))
+		return
+	}
+
+	author := principalName(r)
+	limit, err := h.store.SetIPRateLimit(&database.IPRateLimit{
+		IP:                ip,
+		MessagesPerMinute: req.MessagesPerMinute,
+		Reason:            reason,
+		SetBy:             author,
+		ExpiresAt:         time.Now().Add(ttl),
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "set_rate_limit_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to set IP rate limit")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to set rate limit")
+		return
+	}
+
+	summary := fmt.Sprintf("Rate limited to %d messages/minute", limit.MessagesPerMinute)
+	if reason != "" {
+		summary += ": " + reason
+	}
+	h.recordRateLimitAction(ip, actionRateLimitSet, summary, author, map[string]interface{}{
+		"messages_per_minute": limit.MessagesPerMinute,
+		"expires_at":          limit.ExpiresAt,
+	})
+	pushed := h.push(r.Context(), stalwart.Capped(*limit))
+
+	h.logger.WithFields(logrus.Fields{
+		"action":              "rate_limit_set",
+		"ip":                  ip,
+		"messages_per_minute": limit.MessagesPerMinute,
+		"expires_at":          limit.ExpiresAt,
+		"author":              author,
+		"pushed":              pushed,
+	}).Info("IP rate limit set")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RateLimitResponse{RateLimit: *limit, Pushed: pushed})
+}
+
+// @Summary Lift IP rate limit
+// @Description Lift the IP's rate cap before it expires and push the lifting to Stalwart's throttling hook
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/rate-limit [delete]
+func (h *SendingPolicyHandler) HandleDeleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	ip, err := validation.NormalizeIP(mux.Vars(r)["ip"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return
+	}
+
+	lifted, err := h.store.DeleteIPRateLimit(ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "lift_rate_limit_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to lift IP rate limit")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to lift rate limit")
+		return
+	}
+	if !lifted {
+		writeUserError(w, http.StatusNotFound, "not_found", "IP has no rate limit")
+		return
+	}
+
+	author := principalName(r)
+	h.recordRateLimitAction(ip, actionRateLimitLifted, "Rate limit lifted", author, map[string]interface{}{})
+	pushed := h.push(r.Context(), stalwart.Lifted(ip))
+
+	h.logger.WithFields(logrus.Fields{
+		"action": "rate_limit_lifted",
+		"ip":     ip,
+		"author": author,
+		"pushed": pushed,
+	}).Info("IP rate limit lifted")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"ip":     ip,
+		"pushed": pushed,
+	})
+}
+
+// recordRateLimitAction adds a rate cap change to the IP's action history,
+// logging failures
+func (h *SendingPolicyHandler) recordRateLimitAction(ip, action, reason, author string, metadata map[string]interface{}) {
+	err := h.store.InsertIPAction(&database.IPAction{
+		IP:          ip,
+		Action:      action,
+		Reason:      reason,
+		TriggeredBy: author,
+		Metadata:    metadata,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "record_rate_limit_action_failed",
+			"ip":        ip,
+			"ip_action": action,
+			"error":     err.Error(),
+		}).Warn("Failed to record rate limit action")
+	}
+}
+
+// push sends a rate limit to Stalwart and reports whether it took it
+func (h *SendingPolicyHandler) push(ctx context.Context, limit stalwart.RateLimit) bool {
+	if h.pusher == nil {
+		return false
+	}
+	if err := h.pusher.PushRateLimit(ctx, limit); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "rate_limit_push_failed",
+			"ip":     limit.IP,
+			"error":  err.Error(),
+		}).Warn("Failed to push rate limit to Stalwart")
+		return false
+	}
+	return true
+}
+
+// @Summary List throttled IPs
+// @Description Every IP and destination provider currently throttled, longest throttled first, for MTAs that poll one list for all their IPs
+// @Tags ip-reputation
+// @Produce json
+// @Success 200 {object} ThrottledIPsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sending-policy/throttles [get]
+func (h *SendingPolicyHandler) HandleListThrottles(w http.ResponseWriter, r *http.Request) {
+	throttles, err := h.store.ListThrottledStates()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list throttle states")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to list throttled IPs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ThrottledIPsResponse{Throttles: throttles, Count: len(throttles), GeneratedAt: time.Now()})
+}
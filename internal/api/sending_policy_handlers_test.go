@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/stalwart"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memorySendingPolicyStore is an in-memory sendingPolicyStore
+type memorySendingPolicyStore struct {
+	metrics   map[string]*database.IPReputationMetrics
+	throttles []database.ThrottleState
+	rates     []database.SendRate
+	limits    map[string]*database.IPRateLimit
+	actions   []database.IPAction
+}
+
+func (s *memorySendingPolicyStore) GetIPReputationMetrics(ip string) (*database.IPReputationMetrics, error) {
+	if m, ok := s.metrics[ip]; ok {
+		return m, nil
+	}
+	return nil, errors.New("IP reputation metrics not found")
+}
+
+func (s *memorySendingPolicyStore) GetThrottleStates(ip string) ([]database.ThrottleState, error) {
+	states := []database.ThrottleState{}
+	for _, t := range s.throttles {
+		if t.IP == ip {
+			states = append(states, t)
+		}
+	}
+	return states, nil
+}
+
+func (s *memorySendingPolicyStore) ListThrottledStates() ([]database.ThrottleState, error) {
+	states := []database.ThrottleState{}
+	for _, t := range s.throttles {
+		if t.Throttled {
+			states = append(states, t)
+		}
+	}
+	return states, nil
+}
+
+func (s *memorySendingPolicyStore) GetSendRates(ip string) ([]database.SendRate, error) {
+	rates := []database.SendRate{}
+	for _, r := range s.rates {
+		if r.IP == ip {
+			rates = append(rates, r)
+		}
+	}
+	return rates, nil
+}
+
+func (s *memorySendingPolicyStore) GetIPRateLimit(ip string) (*database.IPRateLimit, error) {
+	if limit, ok := s.limits[ip]; ok && limit.ExpiresAt.After(time.Now()) {
+		return limit, nil
+	}
+	return nil, nil
+}
+
+func (s *memorySendingPolicyStore) SetIPRateLimit(limit *database.IPRateLimit) (*database.IPRateLimit, error) {
+	stored := *limit
+	stored.SetAt = time.Now()
+	s.limits[limit.IP] = &stored
+	return &stored, nil
+}
+
+func (s *memorySendingPolicyStore) DeleteIPRateLimit(ip string) (bool, error) {
+	limit, ok := s.limits[ip]
+	delete(s.limits, ip)
+	return ok && limit.ExpiresAt.After(time.Now()), nil
+}
+
+func (s *memorySendingPolicyStore) InsertIPAction(action *database.IPAction) error {
+	s.actions = append(s.actions, *action)
+	return nil
+}
+
+// recordingPusher records the rate limits pushed, failing with err
+type recordingPusher struct {
+	pushed []stalwart.RateLimit
+	err    error
+}
+
+func (p *recordingPusher) PushRateLimit(ctx context.Context, limit stalwart.RateLimit) error {
+	p.pushed = append(p.pushed, limit)
+	return p.err
+}
+
+func newTestSendingPolicyRouter() *mux.Router {
+	router, _, _ := newTestSendingPolicyRouterWithPusher()
+	return router
+}
+
+func newTestSendingPolicyRouterWithPusher() (*mux.Router, *memorySendingPolicyStore, *recordingPusher) {
+	logger, _ := test.NewNullLogger()
+	since := time.Now().Add(-time.Hour)
+	store := &memorySendingPolicyStore{
+		metrics: map[string]*database.IPReputationMetrics{"192.0.2.1": {IP: "192.0.2.1", Status: "warning"}},
+		throttles: []database.ThrottleState{
+			{IP: "192.0.2.1", Provider: "gmail", Throttled: true, Since: &since, ThrottleCount: 4, BackoffSeconds: 600},
+			{IP: "192.0.2.1", Provider: "outlook", ThrottleCount: 1},
+			{IP: "192.0.2.2", Provider: "yahoo", Throttled: true, Since: &since, ThrottleCount: 3, BackoffSeconds: 600},
+		},
+		rates: []database.SendRate{
+			{IP: "192.0.2.1", Provider: "*", MessagesPerHour: 1000, Reasons: []string{"IP status is warning"}},
+			{IP: "192.0.2.1", Provider: "gmail", MessagesPerHour: 250, Reasons: []string{"IP status is warning", "throttled, 4 rate-limit responses"}},
+		},
+		limits: map[string]*database.IPRateLimit{},
+	}
+	pusher := &recordingPusher{}
+	handler := &SendingPolicyHandler{store: store, pusher: pusher, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ips/{ip}/sending-policy", handler.HandleGetPolicy).Methods("GET")
+	router.HandleFunc("/api/sending-policy/throttles", handler.HandleListThrottles).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/rate-limit", handler.HandleSetRateLimit).Methods("PUT")
+	router.HandleFunc("/api/ips/{ip}/rate-limit", handler.HandleDeleteRateLimit).Methods("DELETE")
+	return router, store, pusher
+}
+
+func TestSendingPolicy(t *testing.T) {
+	router := newTestSendingPolicyRouter()
+
+	rec := serveConfig(router, "GET", "/api/ips/192.0.2.1/sending-policy", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var policy SendingPolicyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Status != "warning" || len(policy.Throttles) != 2 || !policy.Throttles[0].Throttled || policy.Throttles[0].BackoffSeconds != 600 {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+	if len(policy.Rates) != 2 || policy.Rates[1].Provider != "gmail" || policy.Rates[1].MessagesPerHour != 250 {
+		t.Errorf("Unexpected rates: %+v", policy.Rates)
+	}
+
+	// An IP without metrics or throttles has an unknown status and no throttles
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.9/sending-policy", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if policy.Status != "unknown" || policy.Throttles == nil || len(policy.Throttles) != 0 || len(policy.Rates) != 0 {
+		t.Errorf("Unexpected policy for an unknown IP: %+v", policy)
+	}
+
+	if rec := serveConfig(router, "GET", "/api/ips/not-an-ip/sending-policy", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid IP, got %d", rec.Code)
+	}
+}
+
+func TestListThrottles(t *testing.T) {
+	router := newTestSendingPolicyRouter()
+
+	rec := serveConfig(router, "GET", "/api/sending-policy/throttles", "")
+	var list ThrottledIPsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if list.Count != 2 || len(list.Throttles) != 2 {
+		t.Errorf("Expected the two throttled IPs, got %+v", list)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	router, store, pusher := newTestSendingPolicyRouterWithPusher()
+
+	for _, body := range []string{
+		`{"messages_per_minute":0,"ttl":"1h"}`,
+		`{"messages_per_minute":100001,"ttl":"1h"}`,
+		`{"messages_per_minute":30}`,
+		`{"messages_per_minute":30,"ttl":"30s"}`,
+		`{"messages_per_minute":30,"ttl":"169h"}`,
+		`not json`,
+	} {
+		if rec := serveConfig(router, "PUT", "/api/ips/192.0.2.1/rate-limit", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if rec := serveConfig(router, "PUT", "/api/ips/not-an-ip/rate-limit", `{"messages_per_minute":30,"ttl":"1h"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid IP, got %d", rec.Code)
+	}
+
+	rec := serveConfig(router, "PUT", "/api/ips/192.0.2.1/rate-limit", `{"messages_per_minute":30,"ttl":"2h","reason":"Gmail deferrals"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var set RateLimitResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	if !set.Pushed || set.RateLimit.MessagesPerMinute != 30 || time.Until(set.RateLimit.ExpiresAt) < 119*time.Minute {
+		t.Errorf("Unexpected rate limit: %+v", set)
+	}
+	if len(pusher.pushed) != 1 || pusher.pushed[0].MessagesPerMinute != 30 || pusher.pushed[0].ExpiresAt == nil || pusher.pushed[0].Reason != "Gmail deferrals" {
+		t.Errorf("Expected the cap pushed, got %+v", pusher.pushed)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != actionRateLimitSet || store.actions[0].Reason != "Rate limited to 30 messages/minute: Gmail deferrals" {
+		t.Errorf("Expected the cap recorded, got %+v", store.actions)
+	}
+
+	var policy SendingPolicyResponse
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/sending-policy", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.RateLimit == nil || policy.RateLimit.MessagesPerMinute != 30 {
+		t.Errorf("Expected the cap in the sending policy, got %+v", policy.RateLimit)
+	}
+
+	// A failed push keeps the cap
+	pusher.err = errors.New("connection refused")
+	rec = serveConfig(router, "PUT", "/api/ips/192.0.2.1/rate-limit", `{"messages_per_minute":10,"ttl":"1h"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil || rec.Code != http.StatusOK || set.Pushed || set.RateLimit.MessagesPerMinute != 10 {
+		t.Errorf("Expected the cap set without a push, got %d %+v", rec.Code, set)
+	}
+	pusher.err = nil
+
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/rate-limit", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if last := pusher.pushed[len(pusher.pushed)-1]; last.MessagesPerMinute != 0 || last.IP != "192.0.2.1" {
+		t.Errorf("Expected the lifting pushed, got %+v", last)
+	}
+	if rec := serveConfig(router, "DELETE", "/api/ips/192.0.2.1/rate-limit", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a cap, got %d", rec.Code)
+	}
+
+	// An expired cap is not served
+	store.limits["192.0.2.1"] = &database.IPRateLimit{IP: "192.0.2.1", MessagesPerMinute: 5, ExpiresAt: time.Now().Add(-time.Minute)}
+	policy = SendingPolicyResponse{}
+	rec = serveConfig(router, "GET", "/api/ips/192.0.2.1/sending-policy", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil || policy.RateLimit != nil {
+		t.Errorf("Expected no cap once expired, got %+v %v", policy.RateLimit, err)
+	}
+}
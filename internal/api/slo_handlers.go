@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/slo"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// SLOStatusResponse is the status of every fleet objective
+type SLOStatusResponse struct {
+	Objectives []slo.Status `json:"objectives"`
+	// Breached counts the objectives whose error budget is spent
+	Breached    int       `json:"breached"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SLOMeasurementsResponse is the measurements of one objective
+type SLOMeasurementsResponse struct {
+	Objective    slo.Objective             `json:"objective"`
+	Measurements []database.SLOMeasurement `json:"measurements"`
+}
+
+// SLOHandler reports the fleet's service level objectives
+type SLOHandler struct {
+	tracker *slo.Tracker
+	logger  *logrus.Logger
+}
+
+// NewSLOHandler creates a new SLO handler
+func NewSLOHandler(tracker *slo.Tracker, logger *logrus.Logger) *SLOHandler {
+	return &SLOHandler{tracker: tracker, logger: logger}
+}
+
+// @Summary Get SLO status
+// @Description Compliance of every fleet service level objective over the SLO window (30 days by default): the latest measured value, the share of measurements that met the objective, the error budget remaining and the burn rate over the burn window. A burn rate of 1 spends the budget exactly over the window.
+// @Tags system
+// @Produce json
+// @Success 200 {object} SLOStatusResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/slo [get]
+func (h *SLOHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	statuses, err := h.tracker.Status(now)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_slo_status_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get SLO status")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve SLO status")
+		return
+	}
+
+	resp := SLOStatusResponse{Objectives: statuses, GeneratedAt: now}
+	for _, s := range statuses {
+		if s.Breached {
+			resp.Breached++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary Get SLO measurements
+// @Description Measurements of one fleet objective within a time window, newest first, for reporting compliance over time
+// @Tags system
+// @Produce json
+// @Param objective path string true "Objective name"
+// @Param window query string false "Time window (e.g., 24h, 720h)" default(24h)
+// @Success 200 {object} SLOMeasurementsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/slo/{objective}/measurements [get]
+func (h *SLOHandler) HandleMeasurements(w http.ResponseWriter, r *http.Request) {
+	objective, ok := h.tracker.Objective(mux.Vars(r)["objective"])
+	if !ok {
+		writeUserError(w, http.StatusNotFound, "not_found", "Unknown SLO objective")
+		return
+	}
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "24h"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil || duration <= 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 720h, etc.)")
+		return
+	}
+
+	measurements, err := h.tracker.Measurements(objective.Name, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "get_slo_measurements_failed",
+			"objective": objective.Name,
+			"error":     err.Error(),
+		}).Error("Failed to get SLO measurements")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve SLO measurements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SLOMeasurementsResponse{Objective: objective, Measurements: measurements})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/slo"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memorySLOStore is an in-memory slo.Store
+type memorySLOStore struct {
+	counts       map[string]int
+	measurements []database.SLOMeasurement
+}
+
+func (s *memorySLOStore) CountIPsByStatus() (map[string]int, error) {
+	return s.counts, nil
+}
+
+func (s *memorySLOStore) InsertSLOMeasurements(measurements []database.SLOMeasurement) error {
+	s.measurements = append(measurements, s.measurements...)
+	return nil
+}
+
+func (s *memorySLOStore) CountSLOMeasurements(objective string, since time.Time) (int, int, error) {
+	total, met := 0, 0
+	for _, m := range s.measurements {
+		if m.Objective == objective && !m.MeasuredAt.Before(since) {
+			total++
+			if m.Met {
+				met++
+			}
+		}
+	}
+	return total, met, nil
+}
+
+func (s *memorySLOStore) GetLatestSLOMeasurement(objective string) (*database.SLOMeasurement, error) {
+	for i := range s.measurements {
+		if s.measurements[i].Objective == objective {
+			return &s.measurements[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memorySLOStore) GetSLOMeasurements(objective string, since time.Time) ([]database.SLOMeasurement, error) {
+	measurements := []database.SLOMeasurement{}
+	for _, m := range s.measurements {
+		if m.Objective == objective && !m.MeasuredAt.Before(since) {
+			measurements = append(measurements, m)
+		}
+	}
+	return measurements, nil
+}
+
+func (s *memorySLOStore) DeleteSLOMeasurementsBefore(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestGetSLOStatus(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memorySLOStore{counts: map[string]int{"healthy": 9, "blacklisted": 1}}
+	tracker := slo.NewTrackerWithStore(slo.DefaultConfig(), store, logger)
+	if err := tracker.Measure(time.Now()); err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	handler := NewSLOHandler(tracker, logger)
+
+	rec := httptest.NewRecorder()
+	handler.HandleStatus(rec, httptest.NewRequest(http.MethodGet, "/api/slo", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SLOStatusResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Objectives) != 2 || resp.Breached != 2 {
+		t.Fatalf("Expected both objectives breached, got %+v", resp)
+	}
+	if o := resp.Objectives[0]; o.Name != "fleet_healthy" || o.Current == nil || *o.Current != 0.9 || o.Compliant {
+		t.Errorf("Unexpected fleet_healthy status: %+v", o)
+	}
+}
+
+func TestGetSLOMeasurements(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	now := time.Now()
+	store := &memorySLOStore{measurements: []database.SLOMeasurement{
+		{Objective: "fleet_healthy", MeasuredAt: now.Add(-time.Hour), Value: 0.97, Threshold: 0.95, Met: true, TotalIPs: 100},
+		{Objective: "fleet_healthy", MeasuredAt: now.Add(-48 * time.Hour), Value: 0.9, Threshold: 0.95, Met: false, TotalIPs: 100},
+	}}
+	handler := NewSLOHandler(slo.NewTrackerWithStore(slo.DefaultConfig(), store, logger), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/slo/{objective}/measurements", handler.HandleMeasurements).Methods("GET")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/slo/fleet_healthy/measurements", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SLOMeasurementsResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Objective.Name != "fleet_healthy" || len(resp.Measurements) != 1 {
+		t.Errorf("Expected the last day's measurement, got %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/slo/fleet_healthy/measurements?window=72h", nil))
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Measurements) != 2 {
+		t.Errorf("Expected both measurements within 72h, got %+v", resp.Measurements)
+	}
+
+	for path, code := range map[string]int{
+		"/api/slo/uptime/measurements":                   http.StatusNotFound,
+		"/api/slo/fleet_healthy/measurements?window=bad": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("Expected %d for %s, got %d", code, path, rec.Code)
+		}
+	}
+}
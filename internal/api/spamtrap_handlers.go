@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSpamtrapClockSkew is how far in the future a reported hit may be
+const maxSpamtrapClockSkew = 5 * time.Minute
+
+// SpamtrapHitRequest represents a spamtrap hit reported by a trap network or
+// an internal trap
+type SpamtrapHitRequest struct {
+	SendingIP   string     `json:"sending_ip" example:"192.0.2.1"`
+	TrapAddress string     `json:"trap_address,omitempty"`
+	TrapType    string     `json:"trap_type,omitempty" example:"pristine"` // pristine, recycled, typo or internal; defaults to unknown
+	Source      string     `json:"source" example:"spamhaus"`              // Trap network or internal trap
+	ReportID    string     `json:"report_id,omitempty"`                    // The reporter's ID; re-sent reports are stored once
+	MessageID   string     `json:"message_id,omitempty"`
+	HitAt       *time.Time `json:"hit_at,omitempty"` // Defaults to now
+}
+
+// SpamtrapHitResponse is the stored hit and what it did to the IP
+type SpamtrapHitResponse struct {
+	Hit database.SpamtrapHit `json:"hit"`
+	// Duplicate is true when the source already reported this report ID;
+	// nothing was stored or changed
+	Duplicate bool                        `json:"duplicate"`
+	Outcome   *reputation.SpamtrapOutcome `json:"outcome,omitempty"`
+}
+
+// spamtrapStore persists spamtrap hits
+type spamtrapStore interface {
+	InsertSpamtrapHit(hit *database.SpamtrapHit) (bool, error)
+	GetSpamtrapHitsByIP(ip string, since time.Time) ([]database.SpamtrapHit, error)
+}
+
+type dbSpamtrapStore struct{}
+
+func (dbSpamtrapStore) InsertSpamtrapHit(hit *database.SpamtrapHit) (bool, error) {
+	return database.InsertSpamtrapHit(hit)
+}
+
+func (dbSpamtrapStore) GetSpamtrapHitsByIP(ip string, since time.Time) ([]database.SpamtrapHit, error) {
+	return database.GetSpamtrapHitsByIP(ip, since)
+}
+
+// SpamtrapHandler records spamtrap hits and quarantines the IPs that hit them
+type SpamtrapHandler struct {
+	store spamtrapStore
+	// onHit acts on a stored hit; the aggregator's HandleSpamtrapHit
+	onHit  func(hit *database.SpamtrapHit) (*reputation.SpamtrapOutcome, error)
+	logger *logrus.Logger
+}
+
+// NewSpamtrapHandler creates a new spamtrap handler
+func NewSpamtrapHandler(aggregator *reputation.AggregationService, logger *logrus.Logger) *SpamtrapHandler {
+	return &SpamtrapHandler{store: dbSpamtrapStore{}, onHit: aggregator.HandleSpamtrapHit, logger: logger}
+}
+
+// decodeSpamtrapHit reads and validates a hit report
+func decodeSpamtrapHit(w http.ResponseWriter, r *http.Request) (*database.SpamtrapHit, bool) {
+	var req SpamtrapHitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return nil, false
+	}
+
+	ip, err := validation.NormalizeIP(req.SendingIP)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_ip", "sending_ip must be a valid IP address")
+		return nil, false
+	}
+	req.Source = strings.TrimSpace(req.Source)
+	if req.Source == "" {
+		writeUserError(w, http.StatusBadRequest, "missing_source", "source is required")
+		return nil, false
+	}
+	req.TrapType = strings.ToLower(strings.TrimSpace(req.TrapType))
+	switch req.TrapType {
+	case "":
+		req.TrapType = database.TrapUnknown
+	case database.TrapPristine, database.TrapRecycled, database.TrapTypo, database.TrapInternal, database.TrapUnknown:
+	default:
+		writeUserError(w, http.StatusBadRequest, "invalid_trap_type", "trap_type must be pristine, recycled, typo, internal or unknown")
+		return nil, false
+	}
+
+	now := time.Now()
+	hitAt := now
+	if req.HitAt != nil {
+		if req.HitAt.After(now.Add(maxSpamtrapClockSkew)) {
+			writeUserError(w, http.StatusBadRequest, "invalid_hit_at", "hit_at must not be in the future")
+			return nil, false
+		}
+		hitAt = *req.HitAt
+	}
+
+	return &database.SpamtrapHit{
+		SendingIP:   ip,
+		TrapAddress: strings.TrimSpace(req.TrapAddress),
+		TrapType:    req.TrapType,
+		Source:      req.Source,
+		ReportID:    strings.TrimSpace(req.ReportID),
+		MessageID:   strings.TrimSpace(req.MessageID),
+		HitAt:       hitAt,
+		ReportedBy:  principalName(r),
+	}, true
+}
+
+// @Summary Record spamtrap hit
+// @Description Record mail from a sending IP that reached a spamtrap. Hits are a critical signal: once an IP has spamtrap_quarantine_hits within spamtrap_window_hours (runtime config, 1 within 24h by default) it is quarantined at once, whatever its volume and rejection ratios, and stays quarantined while the hits are in the window.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body SpamtrapHitRequest true "Spamtrap hit"
+// @Success 201 {object} SpamtrapHitResponse
+// @Success 200 {object} SpamtrapHitResponse "Already reported"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/spamtrap-hits [post]
+func (h *SpamtrapHandler) HandleRecordHit(w http.ResponseWriter, r *http.Request) {
+	hit, ok := decodeSpamtrapHit(w, r)
+	if !ok {
+		return
+	}
+
+	stored, err := h.store.InsertSpamtrapHit(hit)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "record_spamtrap_hit_failed",
+			"ip":     hit.SendingIP,
+			"error":  err.Error(),
+		}).Error("Failed to store spamtrap hit")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to store spamtrap hit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !stored {
+		json.NewEncoder(w).Encode(SpamtrapHitResponse{Hit: *hit, Duplicate: true})
+		return
+	}
+
+	outcome, err := h.onHit(hit)
+	if err != nil {
+		// The hit is stored; the next aggregation run still quarantines
+		h.logger.WithFields(logrus.Fields{
+			"action": "spamtrap_quarantine_failed",
+			"ip":     hit.SendingIP,
+			"error":  err.Error(),
+		}).Error("Failed to act on spamtrap hit")
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SpamtrapHitResponse{Hit: *hit, Outcome: outcome})
+}
+
+// @Summary Get spamtrap hits for IP
+// @Description Spamtrap hits of a specific IP within a time window, newest first
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 24h, 168h)" default(24h)
+// @Success 200 {array} database.SpamtrapHit
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/spamtrap-hits [get]
+func (h *SpamtrapHandler) HandleListHits(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "24h"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil || duration <= 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.)")
+		return
+	}
+
+	hits, err := h.store.GetSpamtrapHitsByIP(ip, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_spamtrap_hits_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get spamtrap hits")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve spamtrap hits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hits)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memorySpamtrapStore is an in-memory spamtrapStore
+type memorySpamtrapStore struct {
+	hits []database.SpamtrapHit
+}
+
+func (s *memorySpamtrapStore) InsertSpamtrapHit(hit *database.SpamtrapHit) (bool, error) {
+	for _, h := range s.hits {
+		if hit.ReportID != "" && h.Source == hit.Source && h.ReportID == hit.ReportID {
+			return false, nil
+		}
+	}
+	hit.ID = len(s.hits) + 1
+	hit.CreatedAt = time.Now()
+	s.hits = append(s.hits, *hit)
+	return true, nil
+}
+
+func (s *memorySpamtrapStore) GetSpamtrapHitsByIP(ip string, since time.Time) ([]database.SpamtrapHit, error) {
+	hits := []database.SpamtrapHit{}
+	for i := len(s.hits) - 1; i >= 0; i-- {
+		if h := s.hits[i]; h.SendingIP == ip && !h.HitAt.Before(since) {
+			hits = append(hits, h)
+		}
+	}
+	return hits, nil
+}
+
+func newTestSpamtrapRouter() (*mux.Router, *memorySpamtrapStore, *[]database.SpamtrapHit) {
+	logger, _ := test.NewNullLogger()
+	store := &memorySpamtrapStore{}
+	acted := &[]database.SpamtrapHit{}
+	handler := &SpamtrapHandler{
+		store: store,
+		onHit: func(hit *database.SpamtrapHit) (*reputation.SpamtrapOutcome, error) {
+			*acted = append(*acted, *hit)
+			return &reputation.SpamtrapOutcome{Hits: len(*acted), PreviousStatus: "healthy", Status: "quarantine", Quarantined: true}, nil
+		},
+		logger: logger,
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/spamtrap-hits", handler.HandleRecordHit).Methods("POST")
+	router.HandleFunc("/api/ips/{ip}/spamtrap-hits", handler.HandleListHits).Methods("GET")
+	return router, store, acted
+}
+
+func TestRecordSpamtrapHit(t *testing.T) {
+	router, store, acted := newTestSpamtrapRouter()
+
+	body := `{"sending_ip":"2001:DB8::1","trap_type":"Pristine","source":" spamhaus ","report_id":"r-1"}`
+	rec := serveConfig(router, "POST", "/api/spamtrap-hits", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SpamtrapHitResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Hit.SendingIP != "2001:db8::1" || resp.Hit.TrapType != database.TrapPristine || resp.Hit.Source != "spamhaus" || resp.Hit.HitAt.IsZero() {
+		t.Errorf("Unexpected hit: %+v", resp.Hit)
+	}
+	if resp.Duplicate || resp.Outcome == nil || !resp.Outcome.Quarantined || len(*acted) != 1 {
+		t.Errorf("Expected the hit acted on, got %+v", resp)
+	}
+
+	// The same report again is stored once and not acted on
+	rec = serveConfig(router, "POST", "/api/spamtrap-hits", body)
+	resp = SpamtrapHitResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || !resp.Duplicate || resp.Outcome != nil || len(store.hits) != 1 || len(*acted) != 1 {
+		t.Errorf("Expected a re-sent report to be skipped, got %d %+v", rec.Code, resp)
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/2001:db8::1/spamtrap-hits", "")
+	var hits []database.SpamtrapHit
+	if err := json.Unmarshal(rec.Body.Bytes(), &hits); err != nil || len(hits) != 1 {
+		t.Errorf("Unexpected hits: %s", rec.Body.String())
+	}
+}
+
+func TestRecordSpamtrapHitValidation(t *testing.T) {
+	router, store, _ := newTestSpamtrapRouter()
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid body", `{`},
+		{"invalid ip", `{"sending_ip":"mail.example.com","source":"spamhaus"}`},
+		{"missing source", `{"sending_ip":"192.0.2.1"}`},
+		{"invalid trap type", `{"sending_ip":"192.0.2.1","source":"spamhaus","trap_type":"honey"}`},
+		{"future hit", `{"sending_ip":"192.0.2.1","source":"spamhaus","hit_at":"` + future + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveConfig(router, "POST", "/api/spamtrap-hits", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if len(store.hits) != 0 {
+		t.Errorf("Rejected hits were stored: %+v", store.hits)
+	}
+
+	if rec := serveConfig(router, "GET", "/api/ips/192.0.2.1/spamtrap-hits?window=-1h", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative window, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/dependencies"
+)
+
+// DependenciesResponse reports the external dependencies, so a quiet system
+// can be told apart from one that cannot see its inputs
+type DependenciesResponse struct {
+	// Status is "ok", or "degraded" when any dependency is down or degraded
+	Status       string                `json:"status"`
+	CheckedAt    time.Time             `json:"checked_at"`
+	Dependencies []dependencies.Status `json:"dependencies"`
+}
+
+// SystemHandler serves operational status endpoints
+type SystemHandler struct {
+	registry *dependencies.Registry
+}
+
+// NewSystemHandler creates a system handler; a nil registry uses
+// dependencies.Default
+func NewSystemHandler(registry *dependencies.Registry) *SystemHandler {
+	if registry == nil {
+		registry = dependencies.Default
+	}
+	return &SystemHandler{registry: registry}
+}
+
+// @Summary External dependency health
+// @Description Reachability (probed at most every 30 seconds) and error rates over the last 15 minutes for Postgres, the IONOS API, the DNS resolver used for DNSBL checks and configured alert destinations
+// @Tags health
+// @Produce json
+// @Success 200 {object} DependenciesResponse
+// @Router /api/system/dependencies [get]
+func (h *SystemHandler) getDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	response := DependenciesResponse{
+		Status:       "ok",
+		CheckedAt:    time.Now(),
+		Dependencies: h.registry.Check(r.Context()),
+	}
+	for _, dep := range response.Dependencies {
+		if dep.Status == dependencies.StatusDown || dep.Status == dependencies.StatusDegraded {
+			response.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"golang-backend-service/internal/dependencies"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestDependenciesEndpoint(t *testing.T) {
+	registry := dependencies.NewRegistry()
+	registry.Register(dependencies.Postgres, dependencies.KindDatabase, func(ctx context.Context) error { return nil })
+	registry.Register(dependencies.DNS, dependencies.KindDNS, func(ctx context.Context) error { return errors.New("i/o timeout") })
+
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger, DependencyRegistry: registry})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/system/dependencies", nil))
+
+	var resp DependenciesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "degraded" || len(resp.Dependencies) != 2 {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if db := resp.Dependencies[0]; db.Name != "postgres" || db.Status != dependencies.StatusUp || !*db.Reachable {
+		t.Errorf("Unexpected postgres status: %+v", db)
+	}
+	if dns := resp.Dependencies[1]; dns.Status != dependencies.StatusDown || dns.ProbeError != "i/o timeout" {
+		t.Errorf("Unexpected DNS status: %+v", dns)
+	}
+}
@@ -7,8 +7,6 @@ import (
 	"time"
 
 	"golang-backend-service/internal/database"
-	"golang-backend-service/internal/logger"
-	"golang-backend-service/internal/reputation"
 
 	"github.com/sirupsen/logrus"
 )
@@ -265,10 +263,10 @@ func getTestCases() []TestCase {
 // @Produce json
 // @Success 200 {array} TestCase
 // @Router /api/testing/test-cases [get]
-func getTestCasesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) getTestCasesHandler(w http.ResponseWriter, r *http.Request) {
 	testCases := getTestCases()
 	
-	logger.WithFields(logrus.Fields{
+	h.logger.WithFields(logrus.Fields{
 		"action":     "get_test_cases",
 		"test_count": len(testCases),
 	}).Info("Retrieved test cases")
@@ -286,7 +284,7 @@ func getTestCasesHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/testing/test-cases/{id}/run [post]
-func runTestCaseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ReputationHandler) runTestCaseHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract test ID from URL
 	testID := r.URL.Query().Get("id")
 	if testID == "" {
@@ -294,7 +292,7 @@ func runTestCaseHandler(w http.ResponseWriter, r *http.Request) {
 		testID = r.PathValue("id")
 	}
 
-	logger.WithFields(logrus.Fields{
+	h.logger.WithFields(logrus.Fields{
 		"action":  "run_test_case",
 		"test_id": testID,
 	}).Info("Running test case")
@@ -320,10 +318,10 @@ func runTestCaseHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Run the test
 	startTime := time.Now()
-	result := executeTestCase(testCase)
+	result := h.executeTestCase(testCase)
 	result.ExecutionTime = float64(time.Since(startTime).Microseconds()) / 1000.0
 
-	logger.WithFields(logrus.Fields{
+	h.logger.WithFields(logrus.Fields{
 		"action":        "test_case_completed",
 		"test_id":       testID,
 		"passed":        result.Passed,
@@ -343,8 +341,8 @@ func runTestCaseHandler(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} TestSuiteResult
 // @Failure 500 {object} ErrorResponse
 // @Router /api/testing/test-suite/run [post]
-func runTestSuiteHandler(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Starting test suite execution")
+func (h *ReputationHandler) runTestSuiteHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Starting test suite execution")
 	
 	startTime := time.Now()
 	testCases := getTestCases()
@@ -354,7 +352,7 @@ func runTestSuiteHandler(w http.ResponseWriter, r *http.Request) {
 
 	for _, testCase := range testCases {
 		tcStartTime := time.Now()
-		result := executeTestCase(&testCase)
+		result := h.executeTestCase(&testCase)
 		result.ExecutionTime = float64(time.Since(tcStartTime).Microseconds()) / 1000.0
 		
 		results = append(results, result)
@@ -374,7 +372,7 @@ func runTestSuiteHandler(w http.ResponseWriter, r *http.Request) {
 		Results:       results,
 	}
 
-	logger.WithFields(logrus.Fields{
+	h.logger.WithFields(logrus.Fields{
 		"action":       "test_suite_completed",
 		"total":        suite.TotalTests,
 		"passed":       suite.PassedTests,
@@ -387,7 +385,7 @@ func runTestSuiteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // executeTestCase runs a single test case and returns the result
-func executeTestCase(testCase *TestCase) TestResult {
+func (h *ReputationHandler) executeTestCase(testCase *TestCase) TestResult {
 	result := TestResult{
 		TestID:         testCase.ID,
 		TestName:       testCase.Name,
@@ -436,8 +434,7 @@ func executeTestCase(testCase *TestCase) TestResult {
 	}
 
 	// Trigger aggregation
-	config := reputation.DefaultReputationConfig()
-	metrics, err := reputation.AggregateIPOnDemand(payload.IP, config)
+	metrics, err := h.aggregator.AggregateIPOnDemand(payload.IP)
 	if err != nil {
 		result.ErrorMessage = "Failed to aggregate: " + err.Error()
 		result.Passed = false
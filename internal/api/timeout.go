@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TimeoutConfig sets the deadline of each request's context. Handlers pass
+// the context to their database queries and DNS lookups, which fail once it
+// expires; the resulting error response is then replaced by a 504.
+type TimeoutConfig struct {
+	// Default applies to routes without an override; 0 disables it
+	Default time.Duration
+	// Routes overrides Default per route path template, e.g.
+	// "/api/ips/{ip}/dnsbl-check": 20s; 0 disables the deadline
+	Routes map[string]time.Duration
+}
+
+// DefaultTimeoutConfig gives every request ten seconds, below the server's
+// write timeout so the 504 still reaches the client
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{Default: 10 * time.Second}
+}
+
+// ParseRouteTimeouts parses per-route overrides written as
+// "/api/ips/{ip}/dnsbl-check=20s,/api/ips/registry=30s"
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, raw, ok := strings.Cut(pair, "=")
+		route = strings.TrimSpace(route)
+		if !ok || route == "" {
+			return nil, fmt.Errorf("invalid route timeout %q, expected route=duration", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid timeout for route %s: must be a non-negative duration", route)
+		}
+		timeouts[route] = timeout
+	}
+	return timeouts, nil
+}
+
+func (c TimeoutConfig) timeout(route string) time.Duration {
+	if timeout, ok := c.Routes[route]; ok {
+		return timeout
+	}
+	return c.Default
+}
+
+// timeoutWriter turns the 500 a handler writes after its deadline passed
+// into a 504, dropping the handler's body
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code == http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		writeUserError(w.ResponseWriter, http.StatusGatewayTimeout, "timeout", "The request took too long and was cancelled")
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// timeoutMiddleware gives each request a context deadline from the config
+func timeoutMiddleware(config TimeoutConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := config.timeout(routeTemplate(r))
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+		})
+	}
+}
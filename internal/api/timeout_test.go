@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestParseRouteTimeouts(t *testing.T) {
+	timeouts, err := ParseRouteTimeouts(" /api/ips/{ip}/dnsbl-check=20s, /api/ips/registry=0 ,")
+	if err != nil {
+		t.Fatalf("ParseRouteTimeouts failed: %v", err)
+	}
+	if len(timeouts) != 2 || timeouts["/api/ips/{ip}/dnsbl-check"] != 20*time.Second || timeouts["/api/ips/registry"] != 0 {
+		t.Errorf("Unexpected timeouts: %v", timeouts)
+	}
+
+	for _, invalid := range []string{"/api/ips", "=5s", "/api/ips=soon", "/api/ips=-1s"} {
+		if _, err := ParseRouteTimeouts(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(timeoutMiddleware(TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Routes:  map[string]time.Duration{"/unlimited": 0},
+	}))
+	// Fails the way a handler does when its query is cancelled
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		writeUserError(w, http.StatusInternalServerError, "database_error", "query failed")
+	}
+	router.HandleFunc("/slow", slow)
+	router.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		writeUserError(w, http.StatusInternalServerError, "database_error", "query failed")
+	})
+	router.HandleFunc("/unlimited", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusGatewayTimeout || resp.Error != "timeout" {
+		t.Errorf("Expected a structured 504 past the deadline, got %d %+v", rec.Code, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/broken", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected errors within the deadline kept, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/unlimited", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected no deadline on a route overridden with 0, got %d", rec.Code)
+	}
+}
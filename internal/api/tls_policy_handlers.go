@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"golang-backend-service/internal/tlspolicy"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// TLSPolicyResponse is the MTA-STS and DANE policy of a destination domain
+type TLSPolicyResponse struct {
+	tlspolicy.Policy
+	// Requirement is what failures to the domain are annotated with:
+	// dane, mta-sts-enforce, mta-sts-testing, none, or empty if unknown
+	Requirement string `json:"requirement"`
+	RequiresTLS bool   `json:"requires_tls"`
+}
+
+func newTLSPolicyResponse(policy tlspolicy.Policy) TLSPolicyResponse {
+	requirement := policy.Requirement()
+	return TLSPolicyResponse{Policy: policy, Requirement: requirement, RequiresTLS: tlspolicy.RequiresTLS(requirement)}
+}
+
+// TLSPolicyHandler serves the cached TLS policies of destination domains
+type TLSPolicyHandler struct {
+	cache  *tlspolicy.Cache
+	logger *logrus.Logger
+}
+
+// NewTLSPolicyHandler creates a new TLS policy handler. A nil cache answers
+// 503.
+func NewTLSPolicyHandler(cache *tlspolicy.Cache, logger *logrus.Logger) *TLSPolicyHandler {
+	return &TLSPolicyHandler{cache: cache, logger: logger}
+}
+
+// @Summary Get destination TLS policy
+// @Description MTA-STS and DANE policy of a destination domain, resolved unless a fresh one is cached. Failures ingested for the domain are annotated with its requirement; failures about TLS to a domain that requires it are left out of reputation like greylisting.
+// @Tags ip-reputation
+// @Produce json
+// @Param domain path string true "Destination domain"
+// @Success 200 {object} TLSPolicyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Failure 504 {object} ErrorResponse
+// @Router /api/destinations/{domain}/tls-policy [get]
+func (h *TLSPolicyHandler) HandleGetPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		writeUserError(w, http.StatusServiceUnavailable, "tls_policy_disabled", "TLS policy lookups are disabled")
+		return
+	}
+	domain, err := validation.NormalizeDomain(mux.Vars(r)["domain"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_domain", err.Error())
+		return
+	}
+
+	policy, err := h.cache.Lookup(r.Context(), domain)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_tls_policy_failed",
+			"domain": domain,
+			"error":  err.Error(),
+		}).Error("Failed to resolve TLS policy")
+		writeUserError(w, http.StatusInternalServerError, "lookup_failed", "Failed to resolve TLS policy")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTLSPolicyResponse(policy))
+}
+
+// @Summary List cached destination TLS policies
+// @Description The MTA-STS and DANE policies cached for destination domains, by domain, expired ones included
+// @Tags ip-reputation
+// @Produce json
+// @Success 200 {array} TLSPolicyResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/destinations/tls-policies [get]
+func (h *TLSPolicyHandler) HandleListPolicies(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		writeUserError(w, http.StatusServiceUnavailable, "tls_policy_disabled", "TLS policy lookups are disabled")
+		return
+	}
+
+	policies := []TLSPolicyResponse{}
+	for _, policy := range h.cache.Policies() {
+		policies = append(policies, newTLSPolicyResponse(policy))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/tlspolicy"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// staticPolicyDNS publishes an MTA-STS record for gmail.com only
+type staticPolicyDNS struct{}
+
+func (staticPolicyDNS) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if name == "_mta-sts.gmail.com" {
+		return []string{"v=STSv1; id=1"}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (staticPolicyDNS) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+type staticPolicyFetcher struct{}
+
+func (staticPolicyFetcher) FetchPolicy(ctx context.Context, domain string) (string, error) {
+	return "version: STSv1\nmode: enforce\nmx: gmail-smtp-in.l.google.com\nmax_age: 86400\n", nil
+}
+
+func newTestTLSPolicyRouter(cache *tlspolicy.Cache) *mux.Router {
+	logger, _ := test.NewNullLogger()
+	handler := NewTLSPolicyHandler(cache, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/destinations/tls-policies", handler.HandleListPolicies).Methods("GET")
+	router.HandleFunc("/api/destinations/{domain}/tls-policy", handler.HandleGetPolicy).Methods("GET")
+	return router
+}
+
+func TestGetTLSPolicy(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	resolver := tlspolicy.NewResolver(staticPolicyDNS{}, nil, staticPolicyFetcher{})
+	router := newTestTLSPolicyRouter(tlspolicy.NewCache(resolver, time.Hour, time.Second, logger))
+
+	rec := serveConfig(router, "GET", "/api/destinations/Gmail.com/tls-policy", "")
+	var resp TLSPolicyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || resp.Domain != "gmail.com" || resp.Requirement != tlspolicy.RequirementSTSEnforce || !resp.RequiresTLS {
+		t.Errorf("Unexpected policy %d: %s", rec.Code, rec.Body.String())
+	}
+
+	serveConfig(router, "GET", "/api/destinations/example.com/tls-policy", "")
+	rec = serveConfig(router, "GET", "/api/destinations/tls-policies", "")
+	var policies []TLSPolicyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &policies); err != nil || len(policies) != 2 || policies[0].Requirement != tlspolicy.RequirementNone {
+		t.Errorf("Unexpected cached policies: %s", rec.Body.String())
+	}
+
+	if rec := serveConfig(router, "GET", "/api/destinations/not_a_domain!/tls-policy", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid domain, got %d", rec.Code)
+	}
+}
+
+func TestTLSPolicyDisabled(t *testing.T) {
+	router := newTestTLSPolicyRouter(nil)
+	for _, target := range []string{"/api/destinations/gmail.com/tls-policy", "/api/destinations/tls-policies"} {
+		if rec := serveConfig(router, "GET", target, ""); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 for %s, got %d", target, rec.Code)
+		}
+	}
+}
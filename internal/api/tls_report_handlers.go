@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/tlsrpt"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxTLSReportSize caps an uploaded TLS report before decompression
+const maxTLSReportSize = 5 << 20
+
+// TLSReportResponse is the stored TLS report
+type TLSReportResponse struct {
+	Report database.TLSReport `json:"report"`
+	// Duplicate is true when the organization already sent this report;
+	// nothing was stored
+	Duplicate bool `json:"duplicate"`
+}
+
+// tlsReportStore persists TLS reports
+type tlsReportStore interface {
+	InsertTLSReport(report *database.TLSReport) (bool, error)
+	GetTLSFailureSummary(ip string, since time.Time) ([]database.TLSFailureSummary, error)
+	GetTLSDestinationSummary(since time.Time) ([]database.TLSDestinationSummary, error)
+}
+
+type dbTLSReportStore struct{}
+
+func (dbTLSReportStore) InsertTLSReport(report *database.TLSReport) (bool, error) {
+	return database.InsertTLSReport(report)
+}
+
+func (dbTLSReportStore) GetTLSFailureSummary(ip string, since time.Time) ([]database.TLSFailureSummary, error) {
+	return database.GetTLSFailureSummary(ip, since)
+}
+
+func (dbTLSReportStore) GetTLSDestinationSummary(since time.Time) ([]database.TLSDestinationSummary, error) {
+	return database.GetTLSDestinationSummary(since)
+}
+
+// TLSReportHandler ingests SMTP TLS reports and summarizes their failures
+type TLSReportHandler struct {
+	store  tlsReportStore
+	logger *logrus.Logger
+}
+
+// NewTLSReportHandler creates a new TLS report handler
+func NewTLSReportHandler(logger *logrus.Logger) *TLSReportHandler {
+	return &TLSReportHandler{store: dbTLSReportStore{}, logger: logger}
+}
+
+// storedTLSReport flattens a parsed report into its stored form. A failure
+// with an unparsable sending-mta-ip is kept without an IP.
+func storedTLSReport(report *tlsrpt.Report, reportedBy string) *database.TLSReport {
+	stored := &database.TLSReport{
+		Organization: report.OrganizationName,
+		ReportID:     report.ReportID,
+		ContactInfo:  strings.TrimSpace(report.ContactInfo),
+		StartAt:      report.DateRange.StartDatetime,
+		EndAt:        report.DateRange.EndDatetime,
+		Policies:     []database.TLSReportPolicy{},
+		Failures:     []database.TLSFailure{},
+		ReportedBy:   reportedBy,
+	}
+	for _, p := range report.Policies {
+		stored.Policies = append(stored.Policies, database.TLSReportPolicy{
+			PolicyDomain:       p.Policy.PolicyDomain,
+			PolicyType:         p.Policy.PolicyType,
+			SuccessfulSessions: p.Summary.TotalSuccessfulSessionCount,
+			FailedSessions:     p.Summary.TotalFailureSessionCount,
+		})
+		for _, f := range p.FailureDetails {
+			ip, _ := validation.NormalizeIP(f.SendingMTAIP)
+			stored.Failures = append(stored.Failures, database.TLSFailure{
+				PolicyDomain:          p.Policy.PolicyDomain,
+				PolicyType:            p.Policy.PolicyType,
+				SendingIP:             ip,
+				ResultType:            f.ResultType,
+				ReceivingMXHostname:   strings.ToLower(strings.TrimSpace(f.ReceivingMXHostname)),
+				ReceivingIP:           strings.TrimSpace(f.ReceivingIP),
+				FailedSessions:        f.FailedSessionCount,
+				FailureReasonCode:     strings.TrimSpace(f.FailureReasonCode),
+				AdditionalInformation: strings.TrimSpace(f.AdditionalInformation),
+			})
+		}
+	}
+	return stored
+}
+
+// @Summary Ingest SMTP TLS report
+// @Description Store an SMTP TLS report (RFC 8460 JSON, optionally gzipped as application/tlsrpt+gzip) on our outbound sessions. Failed negotiations are kept per sending IP, policy domain and result type. They are diagnostic only: they never change an IP's status, but they show up in the IP's reputation and explanation and set the issue type to tls_negotiation_failure when they rival its rejections.
+// @Tags ip-reputation
+// @Accept json
+// @Param report body tlsrpt.Report true "TLS report"
+// @Produce json
+// @Success 201 {object} TLSReportResponse
+// @Success 200 {object} TLSReportResponse "Already reported"
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reports/tls [post]
+func (h *TLSReportHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxTLSReportSize)
+
+	report, err := tlsrpt.Parse(r.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeUserError(w, http.StatusRequestEntityTooLarge, "report_too_large", "Report exceeds 5MB")
+		return
+	case err != nil:
+		writeUserError(w, http.StatusBadRequest, "invalid_report", err.Error())
+		return
+	}
+
+	stored := storedTLSReport(report, principalName(r))
+	inserted, err := h.store.InsertTLSReport(stored)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":    "record_tls_report_failed",
+			"report_id": stored.ReportID,
+			"error":     err.Error(),
+		}).Error("Failed to store TLS report")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to store TLS report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !inserted {
+		json.NewEncoder(w).Encode(TLSReportResponse{Report: *stored, Duplicate: true})
+		return
+	}
+
+	failed := 0
+	for _, f := range stored.Failures {
+		reputation.RecordTLSFailedSessions(f.SendingIP, f.PolicyType, f.ResultType, f.FailedSessions)
+		failed += f.FailedSessions
+	}
+	h.logger.WithFields(logrus.Fields{
+		"action":          "tls_report_recorded",
+		"organization":    stored.Organization,
+		"report_id":       stored.ReportID,
+		"policies":        len(stored.Policies),
+		"failed_sessions": failed,
+	}).Info("TLS report recorded")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(TLSReportResponse{Report: *stored})
+}
+
+// parseReportWindow reads the window query parameter, 168h by default
+func parseReportWindow(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "168h"
+	}
+	duration, err := time.ParseDuration(windowStr)
+	if err != nil || duration <= 0 {
+		writeUserError(w, http.StatusBadRequest, "invalid_window", "Invalid time window format (use 24h, 168h, etc.)")
+		return 0, false
+	}
+	return duration, true
+}
+
+// @Summary Get TLS failures for IP
+// @Description Failed TLS sessions of an IP in TLS reports ending within a time window, by policy domain and result type, most failures first
+// @Tags ip-reputation
+// @Produce json
+// @Param ip path string true "IP Address"
+// @Param window query string false "Time window (e.g., 48h, 168h)" default(168h)
+// @Success 200 {array} database.TLSFailureSummary
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ips/{ip}/tls-failures [get]
+func (h *TLSReportHandler) HandleListIPFailures(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	duration, ok := parseReportWindow(w, r)
+	if !ok {
+		return
+	}
+
+	summaries, err := h.store.GetTLSFailureSummary(ip, time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_tls_failures_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to get TLS failures")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve TLS failures")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// @Summary Get TLS results per destination
+// @Description Successful and failed TLS sessions per policy domain in TLS reports ending within a time window, highest failure ratio first
+// @Tags ip-reputation
+// @Produce json
+// @Param window query string false "Time window (e.g., 48h, 168h)" default(168h)
+// @Success 200 {array} database.TLSDestinationSummary
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/reports/tls/destinations [get]
+func (h *TLSReportHandler) HandleListDestinations(w http.ResponseWriter, r *http.Request) {
+	duration, ok := parseReportWindow(w, r)
+	if !ok {
+		return
+	}
+
+	summaries, err := h.store.GetTLSDestinationSummary(time.Now().Add(-duration))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_tls_destinations_failed",
+			"error":  err.Error(),
+		}).Error("Failed to get TLS destinations")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve TLS destinations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
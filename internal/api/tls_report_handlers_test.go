@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryTLSReportStore is an in-memory tlsReportStore that keeps reports
+// and answers summaries for every stored failure
+type memoryTLSReportStore struct {
+	reports []database.TLSReport
+}
+
+func (s *memoryTLSReportStore) InsertTLSReport(report *database.TLSReport) (bool, error) {
+	for _, r := range s.reports {
+		if r.Organization == report.Organization && r.ReportID == report.ReportID {
+			return false, nil
+		}
+	}
+	report.ID = len(s.reports) + 1
+	report.CreatedAt = time.Now()
+	s.reports = append(s.reports, *report)
+	return true, nil
+}
+
+func (s *memoryTLSReportStore) GetTLSFailureSummary(ip string, since time.Time) ([]database.TLSFailureSummary, error) {
+	summaries := []database.TLSFailureSummary{}
+	for _, r := range s.reports {
+		for _, f := range r.Failures {
+			if f.SendingIP == ip && !r.EndAt.Before(since) {
+				summaries = append(summaries, database.TLSFailureSummary{
+					PolicyDomain: f.PolicyDomain, PolicyType: f.PolicyType, ResultType: f.ResultType,
+					FailedSessions: f.FailedSessions, Reports: 1, LastSeen: r.EndAt,
+				})
+			}
+		}
+	}
+	return summaries, nil
+}
+
+func (s *memoryTLSReportStore) GetTLSDestinationSummary(since time.Time) ([]database.TLSDestinationSummary, error) {
+	summaries := []database.TLSDestinationSummary{}
+	for _, r := range s.reports {
+		for _, p := range r.Policies {
+			if !r.EndAt.Before(since) {
+				summaries = append(summaries, database.TLSDestinationSummary{
+					PolicyDomain: p.PolicyDomain, PolicyType: p.PolicyType,
+					SuccessfulSessions: p.SuccessfulSessions, FailedSessions: p.FailedSessions,
+				})
+			}
+		}
+	}
+	return summaries, nil
+}
+
+func newTestTLSReportRouter() (*mux.Router, *memoryTLSReportStore) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryTLSReportStore{}
+	handler := &TLSReportHandler{store: store, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/reports/tls", handler.HandleReport).Methods("POST")
+	router.HandleFunc("/api/reports/tls/destinations", handler.HandleListDestinations).Methods("GET")
+	router.HandleFunc("/api/ips/{ip}/tls-failures", handler.HandleListIPFailures).Methods("GET")
+	return router, store
+}
+
+// tlsReportBody is a report from our MTA ending an hour ago
+func tlsReportBody(reportID string) string {
+	end := time.Now().Add(-time.Hour).UTC()
+	return `{
+		"organization-name": "Our MTA",
+		"date-range": {"start-datetime": "` + end.Add(-24*time.Hour).Format(time.RFC3339) + `", "end-datetime": "` + end.Format(time.RFC3339) + `"},
+		"contact-info": "tlsrpt@sender.example",
+		"report-id": "` + reportID + `",
+		"policies": [{
+			"policy": {"policy-type": "sts", "policy-domain": "Gmail.com"},
+			"summary": {"total-successful-session-count": 900, "total-failure-session-count": 100},
+			"failure-details": [
+				{"result-type": "certificate-expired", "sending-mta-ip": "2001:DB8::1", "receiving-mx-hostname": "MX1.gmail.com", "failed-session-count": 70},
+				{"result-type": "validation-failure", "sending-mta-ip": "not-an-ip", "failed-session-count": 30}
+			]
+		}]
+	}`
+}
+
+func TestIngestTLSReport(t *testing.T) {
+	router, store := newTestTLSReportRouter()
+
+	rec := serveConfig(router, "POST", "/api/reports/tls", tlsReportBody("r-1"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp TLSReportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	report := resp.Report
+	if len(report.Policies) != 1 || report.Policies[0].PolicyDomain != "gmail.com" || report.Policies[0].FailedSessions != 100 {
+		t.Errorf("Unexpected policies: %+v", report.Policies)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].SendingIP != "2001:db8::1" || report.Failures[0].ReceivingMXHostname != "mx1.gmail.com" {
+		t.Errorf("Unexpected failures: %+v", report.Failures)
+	}
+	// An unparsable sending IP is kept unattributed
+	if report.Failures[1].SendingIP != "" || report.Failures[1].FailedSessions != 30 {
+		t.Errorf("Expected the second failure kept without an IP, got %+v", report.Failures[1])
+	}
+
+	// The same report again is stored once
+	rec = serveConfig(router, "POST", "/api/reports/tls", tlsReportBody("r-1"))
+	resp = TLSReportResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || !resp.Duplicate || len(store.reports) != 1 {
+		t.Errorf("Expected a re-sent report to be skipped, got %d %+v", rec.Code, resp)
+	}
+
+	// Gzipped reports, as application/tlsrpt+gzip
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(tlsReportBody("r-2")))
+	gz.Close()
+	req := httptest.NewRequest("POST", "/api/reports/tls", &compressed)
+	req.Header.Set("Content-Type", "application/tlsrpt+gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || len(store.reports) != 2 {
+		t.Errorf("Expected the gzipped report stored, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveConfig(router, "GET", "/api/ips/2001:db8::1/tls-failures?window=48h", "")
+	var failures []database.TLSFailureSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &failures); err != nil || len(failures) != 2 || failures[0].ResultType != "certificate-expired" {
+		t.Errorf("Unexpected TLS failures: %s", rec.Body.String())
+	}
+	rec = serveConfig(router, "GET", "/api/reports/tls/destinations", "")
+	var destinations []database.TLSDestinationSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &destinations); err != nil || len(destinations) != 2 {
+		t.Errorf("Unexpected TLS destinations: %s", rec.Body.String())
+	}
+}
+
+func TestIngestTLSReportValidation(t *testing.T) {
+	router, store := newTestTLSReportRouter()
+
+	for _, body := range []string{`{`, `{"organization-name":"Our MTA"}`} {
+		if rec := serveConfig(router, "POST", "/api/reports/tls", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if len(store.reports) != 0 {
+		t.Errorf("Rejected reports were stored: %+v", store.reports)
+	}
+
+	for _, target := range []string{"/api/ips/192.0.2.1/tls-failures?window=-1h", "/api/reports/tls/destinations?window=week"} {
+		if rec := serveConfig(router, "GET", target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-backend-service/internal/auth"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 500
+)
+
+// CreateUserRequest represents the request body for creating a user
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// Password is optional; users without one cannot log in to the dashboard
+	Password string `json:"password,omitempty"`
+}
+
+// UpdateUserRequest represents the request body for updating a user.
+// Omitted fields are left unchanged.
+type UpdateUserRequest struct {
+	Username *string `json:"username,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	// Password replaces the dashboard password and ends existing sessions
+	Password *string `json:"password,omitempty"`
+}
+
+// UpdateUserRoleRequest represents the request body for changing a user's role
+type UpdateUserRoleRequest struct {
+	// Role is read-only, operator or admin
+	Role string `json:"role"`
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Users  []database.User `json:"users"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// DeleteUserResponse confirms a deleted user
+type DeleteUserResponse struct {
+	Status string `json:"status"`
+	UserID int    `json:"user_id"`
+}
+
+// userStore persists users. The default implementation delegates to the
+// database package; tests substitute an in-memory store.
+type userStore interface {
+	ListUsers(limit, offset int) ([]database.User, int, error)
+	CreateUser(username, email string) (*database.User, error)
+	GetUserByID(id int) (*database.User, error)
+	UpdateUser(id int, username, email *string) (*database.User, error)
+	DeleteUser(id int) (bool, error)
+	SetUserPassword(id int, passwordHash string) (bool, error)
+	SetUserRole(id int, role string) (*database.User, error)
+	RevokeUserRefreshTokens(id int) error
+}
+
+// dbUserStore is the userStore backed by the global database connection
+type dbUserStore struct{}
+
+func (dbUserStore) ListUsers(limit, offset int) ([]database.User, int, error) {
+	return database.ListUsers(limit, offset)
+}
+
+func (dbUserStore) CreateUser(username, email string) (*database.User, error) {
+	return database.CreateUser(username, email)
+}
+
+func (dbUserStore) GetUserByID(id int) (*database.User, error) {
+	return database.GetUserByID(id)
+}
+
+func (dbUserStore) UpdateUser(id int, username, email *string) (*database.User, error) {
+	return database.UpdateUser(id, username, email)
+}
+
+func (dbUserStore) DeleteUser(id int) (bool, error) {
+	return database.DeleteUser(id)
+}
+
+func (dbUserStore) SetUserPassword(id int, passwordHash string) (bool, error) {
+	return database.SetUserPassword(id, passwordHash)
+}
+
+func (dbUserStore) SetUserRole(id int, role string) (*database.User, error) {
+	return database.SetUserRole(id, role)
+}
+
+func (dbUserStore) RevokeUserRefreshTokens(id int) error {
+	return database.RevokeUserRefreshTokens(id)
+}
+
+// UserHandler handles user endpoints
+type UserHandler struct {
+	store  userStore
+	logger *logrus.Logger
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(logger *logrus.Logger) *UserHandler {
+	return &UserHandler{store: dbUserStore{}, logger: logger}
+}
+
+func writeUserError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   code,
+		Message: message,
+	})
+}
+
+// writeUserConflict maps ErrUserExists to a 409 and reports whether it did
+func writeUserConflict(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, database.ErrUserExists) {
+		return false
+	}
+	message := "A user with this username or email already exists"
+	var conflict *database.UserConflictError
+	if errors.As(err, &conflict) {
+		message = "A user with this " + conflict.Field + " already exists"
+	}
+	writeUserError(w, http.StatusConflict, "conflict", message)
+	return true
+}
+
+func parseUserPage(r *http.Request) (limit, offset int, err error) {
+	limit = defaultUserPageSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxUserPageSize {
+			return 0, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxUserPageSize))
+		}
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative number")
+		}
+	}
+	return limit, offset, nil
+}
+
+// @Summary List users
+// @Description Retrieve a page of users ordered by ID
+// @Tags users
+// @Produce json
+// @Param limit query int false "Page size (1-500)" default(50)
+// @Param offset query int false "Number of users to skip" default(0)
+// @Success 200 {object} UserListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users [get]
+func (h *UserHandler) getUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parseUserPage(r)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
+		return
+	}
+
+	users, total, err := h.store.ListUsers(limit, offset)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "get_users",
+			"error":  err.Error(),
+		}).Error("Failed to retrieve users")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserListResponse{
+		Users:  users,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// @Summary Create a new user
+// @Description Create a new user with username and email
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body CreateUserRequest true "User information"
+// @Success 201 {object} database.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users [post]
+func (h *UserHandler) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_user",
+			"error":  err.Error(),
+		}).Warn("Invalid request body")
+
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	if req.Username == "" || req.Email == "" {
+		writeUserError(w, http.StatusBadRequest, "validation_error", "Username and email are required")
+		return
+	}
+	if err := validation.ValidateUsername(req.Username); err != nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	email, err := validation.NormalizeEmail(req.Email)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", "Email must be a valid address")
+		return
+	}
+	var passwordHash string
+	if req.Password != "" {
+		if passwordHash, err = auth.HashPassword(req.Password); err != nil {
+			writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+	}
+
+	user, err := h.store.CreateUser(req.Username, email)
+	if err != nil {
+		if writeUserConflict(w, err) {
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"action":   "create_user",
+			"username": req.Username,
+			"error":    err.Error(),
+		}).Error("Failed to create user")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to create user")
+		return
+	}
+	if passwordHash != "" {
+		if _, err := h.store.SetUserPassword(user.ID, passwordHash); err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action":  "create_user",
+				"user_id": user.ID,
+				"error":   err.Error(),
+			}).Error("Failed to set user password")
+
+			writeUserError(w, http.StatusInternalServerError, "database_error", "User created but password could not be set")
+			return
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "create_user",
+		"user_id":  user.ID,
+		"username": user.Username,
+	}).Info("User created successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// @Summary Get user by ID
+// @Description Retrieve a specific user by their ID
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} database.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/{id} [get]
+func (h *UserHandler) getUserByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "User ID must be a number")
+		return
+	}
+
+	user, err := h.store.GetUserByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			writeUserError(w, http.StatusNotFound, "not_found", "User not found")
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"action":  "get_user_by_id",
+			"user_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to retrieve user")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// @Summary Update user
+// @Description Change a user's username, email and/or password. Omitted fields are left unchanged. Changing the password ends the user's sessions.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body UpdateUserRequest true "Fields to change"
+// @Success 200 {object} database.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/{id} [put]
+func (h *UserHandler) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "User ID must be a number")
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if req.Username == nil && req.Email == nil && req.Password == nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", "Provide a username, email or password to update")
+		return
+	}
+	if req.Username != nil {
+		if err := validation.ValidateUsername(*req.Username); err != nil {
+			writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+	}
+	if req.Email != nil {
+		email, err := validation.NormalizeEmail(*req.Email)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "validation_error", "Email must be a valid address")
+			return
+		}
+		req.Email = &email
+	}
+	var passwordHash string
+	if req.Password != nil {
+		if passwordHash, err = auth.HashPassword(*req.Password); err != nil {
+			writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+	}
+
+	user, err := h.store.UpdateUser(id, req.Username, req.Email)
+	if err != nil {
+		if writeUserConflict(w, err) {
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"action":  "update_user",
+			"user_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to update user")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to update user")
+		return
+	}
+	if user == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if passwordHash != "" {
+		_, err := h.store.SetUserPassword(id, passwordHash)
+		if err == nil {
+			err = h.store.RevokeUserRefreshTokens(id)
+		}
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action":  "update_user",
+				"user_id": id,
+				"error":   err.Error(),
+			}).Error("Failed to change user password")
+
+			writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to change password")
+			return
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "update_user",
+		"user_id": user.ID,
+	}).Info("User updated successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// @Summary Set user role
+// @Description Change what a user may do: read-only, operator or admin. Requires the admin role. The new role applies from the user's next login or session refresh.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body UpdateUserRoleRequest true "New role"
+// @Success 200 {object} database.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/{id}/role [put]
+func (h *UserHandler) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "User ID must be a number")
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	user, err := h.store.SetUserRole(id, string(role))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "update_user_role",
+			"user_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to set user role")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to set user role")
+		return
+	}
+	if user == nil {
+		writeUserError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "update_user_role",
+		"user_id": user.ID,
+		"role":    user.Role,
+		"author":  principalName(r),
+	}).Info("User role changed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// @Summary Delete user
+// @Description Delete a user by ID
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} DeleteUserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/{id} [delete]
+func (h *UserHandler) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "invalid_id", "User ID must be a number")
+		return
+	}
+
+	found, err := h.store.DeleteUser(id)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":  "delete_user",
+			"user_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to delete user")
+
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to delete user")
+		return
+	}
+	if !found {
+		writeUserError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":  "delete_user",
+		"user_id": id,
+	}).Info("User deleted successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeleteUserResponse{
+		Status: "success",
+		UserID: id,
+	})
+}
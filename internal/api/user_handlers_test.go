@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryUserStore is an in-memory userStore enforcing unique names and emails
+type memoryUserStore struct {
+	users     []database.User
+	nextID    int
+	passwords map[int]string
+	revoked   map[int]bool
+}
+
+func (s *memoryUserStore) conflict(id int, username, email string) error {
+	for _, u := range s.users {
+		if u.ID == id {
+			continue
+		}
+		if u.Username == username {
+			return &database.UserConflictError{Field: "username"}
+		}
+		if u.Email == email {
+			return &database.UserConflictError{Field: "email"}
+		}
+	}
+	return nil
+}
+
+func (s *memoryUserStore) ListUsers(limit, offset int) ([]database.User, int, error) {
+	page := []database.User{}
+	for i := offset; i < len(s.users) && (limit == 0 || len(page) < limit); i++ {
+		page = append(page, s.users[i])
+	}
+	return page, len(s.users), nil
+}
+
+func (s *memoryUserStore) CreateUser(username, email string) (*database.User, error) {
+	if err := s.conflict(0, username, email); err != nil {
+		return nil, err
+	}
+	s.nextID++
+	user := database.User{ID: s.nextID, Username: username, Email: email, Active: true, Role: "read-only", CreatedAt: time.Now()}
+	s.users = append(s.users, user)
+	return &user, nil
+}
+
+func (s *memoryUserStore) GetUserByID(id int) (*database.User, error) {
+	for _, u := range s.users {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (s *memoryUserStore) UpdateUser(id int, username, email *string) (*database.User, error) {
+	for i, u := range s.users {
+		if u.ID != id {
+			continue
+		}
+		if username != nil {
+			u.Username = *username
+		}
+		if email != nil {
+			u.Email = *email
+		}
+		if err := s.conflict(id, u.Username, u.Email); err != nil {
+			return nil, err
+		}
+		s.users[i] = u
+		return &u, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryUserStore) DeleteUser(id int) (bool, error) {
+	for i, u := range s.users {
+		if u.ID == id {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryUserStore) SetUserPassword(id int, passwordHash string) (bool, error) {
+	if s.passwords == nil {
+		s.passwords = make(map[int]string)
+	}
+	s.passwords[id] = passwordHash
+	return true, nil
+}
+
+func (s *memoryUserStore) SetUserRole(id int, role string) (*database.User, error) {
+	for i := range s.users {
+		if s.users[i].ID == id {
+			s.users[i].Role = role
+			user := s.users[i]
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryUserStore) RevokeUserRefreshTokens(id int) error {
+	if s.revoked == nil {
+		s.revoked = make(map[int]bool)
+	}
+	s.revoked[id] = true
+	return nil
+}
+
+func newTestUserRouter(t *testing.T) (*mux.Router, *memoryUserStore) {
+	t.Helper()
+	log, _ := test.NewNullLogger()
+	store := &memoryUserStore{}
+	h := &UserHandler{store: store, logger: log}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users", h.getUsersHandler).Methods("GET")
+	router.HandleFunc("/users", h.createUserHandler).Methods("POST")
+	router.HandleFunc("/users/{id}", h.getUserByIDHandler).Methods("GET")
+	router.HandleFunc("/users/{id}", h.updateUserHandler).Methods("PUT")
+	router.HandleFunc("/users/{id}", h.deleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/users/{id}/role", h.updateUserRoleHandler).Methods("PUT")
+	return router, store
+}
+
+func doUserRequest(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateUserValidationAndConflicts(t *testing.T) {
+	router, _ := newTestUserRouter(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"valid", `{"username":"john_doe","email":"john@Example.com"}`, http.StatusCreated},
+		{"invalid email", `{"username":"jane","email":"jane-at-example.com"}`, http.StatusBadRequest},
+		{"invalid username", `{"username":"j d","email":"jd@example.com"}`, http.StatusBadRequest},
+		{"duplicate username", `{"username":"john_doe","email":"other@example.com"}`, http.StatusConflict},
+		{"duplicate email", `{"username":"johnny","email":"john@example.com"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		rec := doUserRequest(router, "POST", "/users", tt.body)
+		if rec.Code != tt.expected {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := doUserRequest(router, "POST", "/users", `{"username":"johnny","email":"john@example.com"}`)
+	var body ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Error != "conflict" || !strings.Contains(body.Message, "email") {
+		t.Errorf("Expected email conflict, got %+v", body)
+	}
+}
+
+func TestListUsersPagination(t *testing.T) {
+	router, store := newTestUserRouter(t)
+	for i := 0; i < 5; i++ {
+		store.CreateUser(fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+	}
+
+	rec := doUserRequest(router, "GET", "/users?limit=2&offset=3", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var page UserListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if page.Total != 5 || page.Limit != 2 || page.Offset != 3 || len(page.Users) != 2 || page.Users[0].Username != "user3" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	for _, query := range []string{"limit=0", "limit=501", "offset=-1", "limit=abc"} {
+		if rec := doUserRequest(router, "GET", "/users?"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestUpdateAndDeleteUser(t *testing.T) {
+	router, store := newTestUserRouter(t)
+	store.CreateUser("john_doe", "john@example.com")
+	store.CreateUser("jane", "jane@example.com")
+
+	rec := doUserRequest(router, "PUT", "/users/1", `{"email":"johnd@example.com"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var user database.User
+	json.NewDecoder(rec.Body).Decode(&user)
+	if user.Email != "johnd@example.com" || user.Username != "john_doe" {
+		t.Errorf("Unexpected updated user: %+v", user)
+	}
+
+	if rec := doUserRequest(router, "PUT", "/users/1", `{"username":"jane"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for taken username, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "PUT", "/users/1", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty update, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "PUT", "/users/99", `{"username":"ghost"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing user, got %d", rec.Code)
+	}
+
+	if rec := doUserRequest(router, "PUT", "/users/1", `{"password":"short"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for short password, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "PUT", "/users/1", `{"password":"correct horse battery"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for password change, got %d", rec.Code)
+	}
+	if store.passwords[1] == "" || store.passwords[1] == "correct horse battery" || !store.revoked[1] {
+		t.Error("Expected hashed password to be stored and sessions revoked")
+	}
+
+	if rec := doUserRequest(router, "DELETE", "/users/1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 on delete, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "DELETE", "/users/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 on second delete, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "GET", "/users/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	router, store := newTestUserRouter(t)
+	store.CreateUser("john_doe", "john@example.com")
+
+	rec := doUserRequest(router, "PUT", "/users/1/role", `{"role":"operator"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var user database.User
+	json.NewDecoder(rec.Body).Decode(&user)
+	if user.Role != "operator" || store.users[0].Role != "operator" {
+		t.Errorf("Expected the operator role stored, got %+v", user)
+	}
+
+	if rec := doUserRequest(router, "PUT", "/users/1/role", `{"role":"root"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown role, got %d", rec.Code)
+	}
+	if rec := doUserRequest(router, "PUT", "/users/99/role", `{"role":"admin"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing user, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxVolumeReports caps the volumes of one request
+	maxVolumeReports = 5000
+	// maxVolumeBodySize caps a volumes request body
+	maxVolumeBodySize = 1 << 20
+	// maxVolumeClockSkew is how far in the future a period may end
+	maxVolumeClockSkew = 5 * time.Minute
+)
+
+// SendingVolumeRequest is the number of messages an IP sent over a period
+type SendingVolumeRequest struct {
+	SendingIP   string    `json:"sending_ip" example:"192.0.2.1"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Messages    int       `json:"messages" example:"12000"`
+}
+
+// SendingVolumesRequest is a batch of volumes from one sending system
+type SendingVolumesRequest struct {
+	Source  string                 `json:"source" example:"mta-fleet-eu"` // Sending system reporting the volumes
+	Volumes []SendingVolumeRequest `json:"volumes"`
+}
+
+// SendingVolumesResponse counts the volumes stored
+type SendingVolumesResponse struct {
+	Source   string `json:"source"`
+	Accepted int    `json:"accepted"`
+}
+
+// volumeStore persists reported volumes
+type volumeStore interface {
+	UpsertSendingVolumes(volumes []database.SendingVolume) error
+}
+
+type dbVolumeStore struct{}
+
+func (dbVolumeStore) UpsertSendingVolumes(volumes []database.SendingVolume) error {
+	return database.UpsertSendingVolumes(volumes)
+}
+
+// VolumeHandler records the volumes the sending systems report, read by the
+// reported volume estimator
+type VolumeHandler struct {
+	store  volumeStore
+	logger *logrus.Logger
+}
+
+// NewVolumeHandler creates a new volume handler
+func NewVolumeHandler(logger *logrus.Logger) *VolumeHandler {
+	return &VolumeHandler{store: dbVolumeStore{}, logger: logger}
+}
+
+// decodeSendingVolume validates one reported volume
+func decodeSendingVolume(req SendingVolumeRequest, source string, now time.Time) (database.SendingVolume, error) {
+	ip, err := validation.NormalizeIP(req.SendingIP)
+	if err != nil {
+		return database.SendingVolume{}, fmt.Errorf("sending_ip must be a valid IP address")
+	}
+	if req.PeriodStart.IsZero() || !req.PeriodEnd.After(req.PeriodStart) {
+		return database.SendingVolume{}, fmt.Errorf("period_end must be after period_start")
+	}
+	if req.PeriodEnd.After(now.Add(maxVolumeClockSkew)) {
+		return database.SendingVolume{}, fmt.Errorf("period_end must not be in the future")
+	}
+	if req.Messages < 0 {
+		return database.SendingVolume{}, fmt.Errorf("messages must not be negative")
+	}
+	return database.SendingVolume{
+		SendingIP:   ip,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		Messages:    req.Messages,
+		Source:      source,
+	}, nil
+}
+
+// @Summary Report sending volumes
+// @Description Record how many messages IPs sent over periods, as counted by a sending system. With the reported volume estimator (volume.estimator: reported) these volumes are the total_sent rejection and complaint ratios are divided by; periods only partly in a window count in proportion to their overlap. Reporting the same IP, period and source again replaces the earlier report.
+// @Tags ip-reputation
+// @Accept json
+// @Produce json
+// @Param request body SendingVolumesRequest true "Sending volumes"
+// @Success 200 {object} SendingVolumesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/volumes [post]
+func (h *VolumeHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxVolumeBodySize)
+	var req SendingVolumesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeUserError(w, http.StatusRequestEntityTooLarge, "request_too_large", "Request body exceeds 1MB")
+			return
+		}
+		writeUserError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	req.Source = strings.TrimSpace(req.Source)
+	if req.Source == "" {
+		writeUserError(w, http.StatusBadRequest, "missing_source", "source is required")
+		return
+	}
+	if len(req.Volumes) == 0 {
+		writeUserError(w, http.StatusBadRequest, "missing_volumes", "volumes must not be empty")
+		return
+	}
+	if len(req.Volumes) > maxVolumeReports {
+		writeUserError(w, http.StatusBadRequest, "too_many_volumes", fmt.Sprintf("At most %d volumes per request", maxVolumeReports))
+		return
+	}
+
+	now := time.Now()
+	volumes := make([]database.SendingVolume, 0, len(req.Volumes))
+	for i, v := range req.Volumes {
+		volume, err := decodeSendingVolume(v, req.Source, now)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_volume", fmt.Sprintf("volumes[%d]: %s", i, err.Error()))
+			return
+		}
+		volumes = append(volumes, volume)
+	}
+
+	if err := h.store.UpsertSendingVolumes(volumes); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "store_sending_volumes_failed",
+			"source": req.Source,
+			"error":  err.Error(),
+		}).Error("Failed to store sending volumes")
+		writeUserError(w, http.StatusInternalServerError, "database_error", "Failed to store sending volumes")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":   "sending_volumes_received",
+		"source":   req.Source,
+		"accepted": len(volumes),
+	}).Info("Sending volumes received")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SendingVolumesResponse{Source: req.Source, Accepted: len(volumes)})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryVolumeStore is an in-memory volumeStore
+type memoryVolumeStore struct {
+	volumes []database.SendingVolume
+}
+
+func (s *memoryVolumeStore) UpsertSendingVolumes(volumes []database.SendingVolume) error {
+	s.volumes = append(s.volumes, volumes...)
+	return nil
+}
+
+func TestReportSendingVolumes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryVolumeStore{}
+	handler := &VolumeHandler{store: store, logger: logger}
+
+	end := time.Now().Truncate(time.Hour)
+	body := `{"source":"mta-fleet-eu","volumes":[
+		{"sending_ip":"192.0.2.1","period_start":"` + end.Add(-time.Hour).Format(time.RFC3339) + `","period_end":"` + end.Format(time.RFC3339) + `","messages":12000},
+		{"sending_ip":"2001:DB8::1","period_start":"` + end.Add(-time.Hour).Format(time.RFC3339) + `","period_end":"` + end.Format(time.RFC3339) + `","messages":0}
+	]}`
+	rec := httptest.NewRecorder()
+	handler.HandleReport(rec, httptest.NewRequest(http.MethodPost, "/api/volumes", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SendingVolumesResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Accepted != 2 || len(store.volumes) != 2 {
+		t.Fatalf("Expected 2 volumes stored, got %+v and %+v", resp, store.volumes)
+	}
+	if v := store.volumes[1]; v.SendingIP != "2001:db8::1" || v.Source != "mta-fleet-eu" {
+		t.Errorf("Expected the IP normalized and the source set, got %+v", v)
+	}
+}
+
+func TestReportSendingVolumesRejectsInvalid(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	now := time.Now()
+	period := func(start, end time.Time) string {
+		return `"period_start":"` + start.Format(time.RFC3339) + `","period_end":"` + end.Format(time.RFC3339) + `"`
+	}
+
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{"volumes":[{"sending_ip":"192.0.2.1",` + period(now.Add(-time.Hour), now) + `,"messages":1}]}`, "missing_source"},
+		{`{"source":"mta","volumes":[]}`, "missing_volumes"},
+		{`{"source":"mta","volumes":[{"sending_ip":"not-an-ip",` + period(now.Add(-time.Hour), now) + `,"messages":1}]}`, "invalid_volume"},
+		{`{"source":"mta","volumes":[{"sending_ip":"192.0.2.1",` + period(now, now.Add(-time.Hour)) + `,"messages":1}]}`, "invalid_volume"},
+		{`{"source":"mta","volumes":[{"sending_ip":"192.0.2.1",` + period(now, now.Add(time.Hour)) + `,"messages":1}]}`, "invalid_volume"},
+		{`{"source":"mta","volumes":[{"sending_ip":"192.0.2.1",` + period(now.Add(-time.Hour), now) + `,"messages":-1}]}`, "invalid_volume"},
+	}
+	for _, tt := range tests {
+		store := &memoryVolumeStore{}
+		handler := &VolumeHandler{store: store, logger: logger}
+		rec := httptest.NewRecorder()
+		handler.HandleReport(rec, httptest.NewRequest(http.MethodPost, "/api/volumes", strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.code) {
+			t.Errorf("Expected 400 %s for %s, got %d: %s", tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+		if len(store.volumes) != 0 {
+			t.Errorf("Expected nothing stored for %s, got %+v", tt.body, store.volumes)
+		}
+	}
+}
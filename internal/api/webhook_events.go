@@ -0,0 +1,455 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/bus"
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Stalwart event types the webhook ingests
+const (
+	eventTypeDeliveryFailure = "smtp.delivery.failure"
+	eventTypeBounce          = "smtp.bounce"
+	eventTypeDSN             = "smtp.dsn"
+	eventTypeAuthFailure     = "smtp.auth.failure"
+	eventTypeDeliverySuccess = "smtp.delivery.success"
+)
+
+// unsupportedEventType labels skipped events of other types, keeping the
+// metric's cardinality bounded
+const unsupportedEventType = "unsupported"
+
+// Per-event outcomes reported by the detailed webhook response
+const (
+	eventStatusProcessed     = "processed"
+	eventStatusSkipped       = "skipped"
+	eventStatusInvalidSchema = "invalid_schema"
+	eventStatusMissingIP     = "missing_ip"
+	eventStatusInvalidIP     = "invalid_ip"
+	eventStatusInvalidCode   = "invalid_code"
+	eventStatusStorageError  = "storage_error"
+)
+
+// errInvalidEvent marks events that can never be stored, as opposed to
+// storage errors that may succeed on a retry
+var errInvalidEvent = errors.New("invalid event")
+
+// invalidEventError is an errInvalidEvent carrying the per-event status
+type invalidEventError struct {
+	status string
+	err    error
+}
+
+func (e *invalidEventError) Error() string        { return e.err.Error() }
+func (e *invalidEventError) Is(target error) bool { return target == errInvalidEvent }
+
+func invalidEvent(status string, err error) error {
+	return &invalidEventError{status: status, err: err}
+}
+
+// WebhookEventResult is the outcome of one event of a webhook payload
+type WebhookEventResult struct {
+	EventID string `json:"event_id"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ingestResult counts the outcome of ingesting one payload. Retry is set
+// when an event failed for a reason a redelivery could fix.
+type ingestResult struct {
+	Processed int
+	Failed    int
+	Retry     bool
+	Events    []WebhookEventResult
+}
+
+// add records the outcome of one event, counting it as failed when err is set
+func (r *ingestResult) add(eventID, status string, err error) {
+	result := WebhookEventResult{EventID: eventID, Status: status}
+	if err != nil {
+		result.Error = err.Error()
+		r.Failed++
+	} else if status == eventStatusProcessed {
+		r.Processed++
+	}
+	r.Events = append(r.Events, result)
+}
+
+// ingestEvents decodes and stores the events of a payload, whether it came
+// from the webhook or the message bus
+func (h *ReputationHandler) ingestEvents(payload rawWebhookPayload, source *database.WebhookSource, sourceName string) ingestResult {
+	var result ingestResult
+	payloadVersion := parseSchemaVersion(payload.Version)
+
+	for _, raw := range payload.Events {
+		decoded := decodeWebhookEvent(raw, payloadVersion)
+		recordSchemaMetrics(decoded)
+		event := decoded.Event
+		if len(decoded.Unknown) > 0 {
+			h.logger.WithFields(logrus.Fields{
+				"action":         "webhook_unknown_fields",
+				"event_id":       event.ID,
+				"schema_version": decoded.Version,
+				"fields":         decoded.Unknown,
+			}).Warn("Webhook event has fields the schema does not know")
+		}
+		if decoded.Err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action":         "webhook_event_decode_failed",
+				"event_id":       event.ID,
+				"schema_version": decoded.Version,
+				"error":          decoded.Err.Error(),
+			}).Warn("Skipping webhook event that does not match its schema")
+			reputation.RecordWebhookEvent(event.Type, "failed")
+			reputation.RecordWebhookSourceEvent(sourceName, "failed")
+			result.add(event.ID, eventStatusInvalidSchema, decoded.Err)
+			continue
+		}
+
+		if !isSupportedEvent(event.Type) {
+			reputation.RecordWebhookEvent(unsupportedEventType, "skipped")
+			h.logger.WithFields(logrus.Fields{
+				"action":     "webhook_event_unsupported",
+				"event_id":   event.ID,
+				"event_type": event.Type,
+			}).Debug("Skipping unsupported webhook event type")
+			result.add(event.ID, eventStatusSkipped, nil)
+			continue
+		}
+		if event.Type == eventTypeDSN && !dsnReportsFailure(event.Data.Action) {
+			// Successful DSNs carry no reputation signal
+			reputation.RecordWebhookEvent(event.Type, "skipped")
+			result.add(event.ID, eventStatusSkipped, nil)
+			continue
+		}
+
+		var err error
+		switch event.Type {
+		case eventTypeAuthFailure:
+			err = h.ingestAuthFailure(event, source)
+		case eventTypeDeliverySuccess:
+			err = h.ingestDeliverySuccess(event, source)
+		default:
+			err = h.ingestFailure(event, source)
+		}
+		if err != nil {
+			reputation.RecordWebhookEvent(event.Type, "failed")
+			reputation.RecordWebhookSourceEvent(sourceName, "failed")
+			var invalid *invalidEventError
+			if errors.As(err, &invalid) {
+				result.add(event.ID, invalid.status, err)
+			} else {
+				// Storage errors are logged; they are not the sender's concern
+				result.add(event.ID, eventStatusStorageError, errors.New("failed to store event"))
+				result.Retry = true
+			}
+			continue
+		}
+
+		reputation.RecordWebhookEvent(event.Type, "success")
+		reputation.RecordWebhookSourceEvent(sourceName, "success")
+		result.add(event.ID, eventStatusProcessed, nil)
+	}
+	return result
+}
+
+func isSupportedEvent(eventType string) bool {
+	switch eventType {
+	case eventTypeDeliveryFailure, eventTypeBounce, eventTypeDSN, eventTypeAuthFailure, eventTypeDeliverySuccess:
+		return true
+	}
+	return false
+}
+
+// dsnReportsFailure reports whether a DSN action (RFC 3464) means the
+// message was not delivered. A DSN without an action is treated as a failure.
+func dsnReportsFailure(action string) bool {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "delivered", "relayed", "expanded":
+		return false
+	}
+	return true
+}
+
+// eventTimestamp returns the event's creation time, or now if it is missing
+// or malformed
+func eventTimestamp(event WebhookEvent) time.Time {
+	if event.CreatedAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, event.CreatedAt); err == nil {
+			return parsed
+		}
+	}
+	return time.Now()
+}
+
+// eventSendingIP validates and normalizes the sending IP of an event
+func (h *ReputationHandler) eventSendingIP(event WebhookEvent) (string, error) {
+	if strings.TrimSpace(event.Data.IP) == "" {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "missing_event_ip",
+			"event_id": event.ID,
+		}).Warn("Skipping webhook event without an IP")
+		return "", invalidEvent(eventStatusMissingIP, errors.New("sending IP is required"))
+	}
+	sendingIP, err := validation.NormalizeIP(event.Data.IP)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "invalid_event_ip",
+			"event_id": event.ID,
+			"error":    err.Error(),
+		}).Warn("Skipping webhook event with invalid IP")
+		return "", invalidEvent(eventStatusInvalidIP, err)
+	}
+	return sendingIP, nil
+}
+
+// normalizeEventCodes checks the SMTP reply code (RFC 5321) of an event and
+// rewrites its enhanced status code (RFC 3463) to the canonical form. Both
+// are optional.
+func normalizeEventCodes(event *WebhookEvent) error {
+	if code := event.Data.SMTPCode; code != 0 && (code < 200 || code > 599) {
+		return invalidEvent(eventStatusInvalidCode, fmt.Errorf("invalid SMTP code: %d", code))
+	}
+	if strings.TrimSpace(event.Data.EnhancedCode) == "" {
+		event.Data.EnhancedCode = ""
+		return nil
+	}
+	code, err := validation.NormalizeEnhancedCode(event.Data.EnhancedCode)
+	if err != nil {
+		return invalidEvent(eventStatusInvalidCode, err)
+	}
+	event.Data.EnhancedCode = code
+	return nil
+}
+
+// eventInstance attributes an event to its MTA instance, falling back to the
+// webhook source
+func eventInstance(event WebhookEvent, source *database.WebhookSource) string {
+	instance := strings.TrimSpace(event.Data.Instance)
+	if instance == "" && source != nil {
+		instance = source.Name
+	}
+	return instance
+}
+
+func sourceIDOf(source *database.WebhookSource) *int {
+	if source == nil {
+		return nil
+	}
+	return &source.ID
+}
+
+// ingestFailure stores a delivery failure, bounce or failed DSN as an SMTP
+// failure
+func (h *ReputationHandler) ingestFailure(event WebhookEvent, source *database.WebhookSource) error {
+	sendingIP, err := h.eventSendingIP(event)
+	if err != nil {
+		return err
+	}
+	if err := normalizeEventCodes(&event); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "invalid_event_code",
+			"event_id": event.ID,
+			"error":    err.Error(),
+		}).Warn("Skipping webhook event with malformed status codes")
+		return err
+	}
+	instance := eventInstance(event, source)
+
+	// Extract domain from recipient email
+	domain := database.ExtractDomain(event.Data.Recipient)
+
+	failure := &database.SMTPFailure{
+		SendingIP:       sendingIP,
+		RecipientEmail:  event.Data.Recipient,
+		RecipientDomain: domain,
+		SMTPCode:        event.Data.SMTPCode,
+		EnhancedCode:    event.Data.EnhancedCode,
+		Reason:          event.Data.Reason,
+		MXServer:        event.Data.MX,
+		Timestamp:       eventTimestamp(event),
+		EventID:         event.ID,
+		AttemptNumber:   event.Data.AttemptNumber,
+		SourceID:        sourceIDOf(source),
+		MTAInstance:     instance,
+		MessageID:       strings.TrimSpace(event.Data.MessageID),
+		EventType:       event.Type,
+	}
+	// The destination's TLS policy at the time, from the cache only
+	if h.tlsPolicies != nil {
+		failure.TLSPolicy = h.tlsPolicies.Requirement(domain)
+	}
+
+	outcome, err := h.store.StoreSMTPFailure(failure)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "insert_failure_failed",
+			"event_id": event.ID,
+			"ip":       sendingIP,
+			"error":    err.Error(),
+		}).Error("Failed to insert SMTP failure")
+		return err
+	}
+	if outcome != database.FailureStored {
+		reputation.RecordSMTPFailureDuplicate(outcome)
+		h.logger.WithFields(logrus.Fields{
+			"action":    "smtp_failure_duplicate",
+			"event_id":  event.ID,
+			"ip":        sendingIP,
+			"duplicate": outcome,
+		}).Debug("Skipping duplicate SMTP failure")
+		return nil
+	}
+
+	reputation.RecordSMTPFailure(sendingIP, event.Data.EnhancedCode, domain)
+	if instance != "" {
+		reputation.RecordSMTPFailureInstance(sendingIP, instance)
+	}
+	if h.aggregator != nil {
+		h.aggregator.ObserveFailure(sendingIP, failure.OrgDomain(), event.Data.EnhancedCode, failure.Timestamp)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":        "smtp_failure_recorded",
+		"event_id":      event.ID,
+		"event_type":    event.Type,
+		"instance":      instance,
+		"ip":            sendingIP,
+		"recipient":     event.Data.Recipient,
+		"smtp_code":     event.Data.SMTPCode,
+		"enhanced_code": event.Data.EnhancedCode,
+	}).Info("SMTP failure recorded")
+	return nil
+}
+
+// ingestAuthFailure stores a failed outgoing SMTP authentication
+func (h *ReputationHandler) ingestAuthFailure(event WebhookEvent, source *database.WebhookSource) error {
+	sendingIP, err := h.eventSendingIP(event)
+	if err != nil {
+		return err
+	}
+	instance := eventInstance(event, source)
+
+	failure := &database.SMTPAuthFailure{
+		SendingIP:   sendingIP,
+		RemoteHost:  strings.TrimSpace(event.Data.RemoteHost),
+		Mechanism:   strings.ToUpper(strings.TrimSpace(event.Data.Mechanism)),
+		Username:    event.Data.Username,
+		Reason:      event.Data.Reason,
+		Timestamp:   eventTimestamp(event),
+		EventID:     event.ID,
+		SourceID:    sourceIDOf(source),
+		MTAInstance: instance,
+	}
+
+	if err := h.store.InsertSMTPAuthFailure(failure); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "insert_auth_failure_failed",
+			"event_id": event.ID,
+			"ip":       sendingIP,
+			"error":    err.Error(),
+		}).Error("Failed to insert SMTP auth failure")
+		return err
+	}
+
+	reputation.RecordSMTPAuthFailure(sendingIP, failure.Mechanism)
+
+	h.logger.WithFields(logrus.Fields{
+		"action":      "smtp_auth_failure_recorded",
+		"event_id":    event.ID,
+		"instance":    instance,
+		"ip":          sendingIP,
+		"remote_host": failure.RemoteHost,
+		"mechanism":   failure.Mechanism,
+	}).Info("SMTP auth failure recorded")
+	return nil
+}
+
+// ingestDeliverySuccess counts a delivered message in the sent volume of its
+// IP and turns the greylist-style deferrals the delivery got past into
+// greylisting. Deliveries are logged at debug level only; there is one per
+// message.
+func (h *ReputationHandler) ingestDeliverySuccess(event WebhookEvent, source *database.WebhookSource) error {
+	sendingIP, err := h.eventSendingIP(event)
+	if err != nil {
+		return err
+	}
+	instance := eventInstance(event, source)
+
+	success := &database.DeliverySuccess{
+		SendingIP:      sendingIP,
+		EventID:        event.ID,
+		MTAInstance:    instance,
+		RecipientEmail: strings.TrimSpace(event.Data.Recipient),
+		MessageID:      strings.TrimSpace(event.Data.MessageID),
+		Timestamp:      eventTimestamp(event),
+	}
+	stored, err := h.store.RecordDeliverySuccess(success)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "record_delivery_success_failed",
+			"event_id": event.ID,
+			"ip":       sendingIP,
+			"error":    err.Error(),
+		}).Error("Failed to record delivery success")
+		return err
+	}
+
+	// Replays resolve again, so a delivery whose resolving failed before
+	// does not leave its deferrals counted as rejections
+	resolved, err := h.store.ResolveGreylisting(success)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action":   "resolve_greylisting_failed",
+			"event_id": event.ID,
+			"ip":       sendingIP,
+			"error":    err.Error(),
+		}).Error("Failed to resolve greylisting deferrals")
+		return err
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":    "delivery_success_recorded",
+		"event_id":  event.ID,
+		"instance":  instance,
+		"ip":        sendingIP,
+		"duplicate": !stored,
+		"resolved":  resolved,
+	}).Debug("Delivery success recorded")
+	return nil
+}
+
+// IngestMessage stores the events of a message-bus message, which holds the
+// same JSON as the webhook: either a whole payload or a single event. Events
+// are deduplicated by event ID, so redelivered messages are harmless. An
+// error asks for redelivery unless bus.IsPermanent reports otherwise.
+func (h *ReputationHandler) IngestMessage(sourceName string, data []byte) error {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return bus.Permanent(fmt.Errorf("message is not a JSON object: %w", err))
+	}
+
+	var payload rawWebhookPayload
+	if _, ok := envelope["events"]; ok {
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return bus.Permanent(fmt.Errorf("failed to decode webhook payload: %w", err))
+		}
+	} else {
+		payload.Events = []json.RawMessage{data}
+	}
+
+	result := h.ingestEvents(payload, nil, sourceName)
+	if result.Retry {
+		return fmt.Errorf("%d of %d events could not be stored", result.Failed, len(payload.Events))
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/bus"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestDSNReportsFailure(t *testing.T) {
+	tests := map[string]bool{
+		"failed":    true,
+		"Delayed":   true,
+		"":          true,
+		"delivered": false,
+		" relayed ": false,
+		"expanded":  false,
+	}
+	for action, want := range tests {
+		if got := dsnReportsFailure(action); got != want {
+			t.Errorf("dsnReportsFailure(%q) = %v, want %v", action, got, want)
+		}
+	}
+}
+
+func TestIsSupportedEvent(t *testing.T) {
+	for _, eventType := range []string{"smtp.delivery.failure", "smtp.bounce", "smtp.dsn", "smtp.auth.failure", "smtp.delivery.success"} {
+		if !isSupportedEvent(eventType) {
+			t.Errorf("Expected %s to be supported", eventType)
+		}
+	}
+	if isSupportedEvent("smtp.delivery.delayed") {
+		t.Error("Expected smtp.delivery.delayed to be unsupported")
+	}
+}
+
+func TestDecodeAuthFailureEvent(t *testing.T) {
+	events := map[string]string{
+		"v1": `{"id":"a1","type":"smtp.auth.failure","data":{"ip":"192.0.2.10","remote_host":"smtp.relay.example","mechanism":"plain","username":"relay@example.com","reason":"535 5.7.8 Authentication failed"}}`,
+		"v2": `{"id":"a1","type":"smtp.auth.failure","data":{"remoteIp":"192.0.2.10","remoteHost":"smtp.relay.example","mechanism":"plain","username":"relay@example.com","reason":"535 5.7.8 Authentication failed"}}`,
+	}
+	for name, raw := range events {
+		t.Run(name, func(t *testing.T) {
+			decoded := decodeWebhookEvent(json.RawMessage(raw), "")
+			if decoded.Err != nil || len(decoded.Unknown) > 0 {
+				t.Fatalf("decodeWebhookEvent() error = %v, unknown = %v", decoded.Err, decoded.Unknown)
+			}
+			data := decoded.Event.Data
+			if data.IP != "192.0.2.10" || data.RemoteHost != "smtp.relay.example" || data.Mechanism != "plain" || data.Username != "relay@example.com" {
+				t.Errorf("Unexpected data: %+v", data)
+			}
+		})
+	}
+}
+
+func TestIngestMessageClassifiesErrors(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	h := NewReputationHandler(nil, nil, nil, nil, logger)
+
+	if err := h.IngestMessage("nats:test", []byte(`not json`)); !bus.IsPermanent(err) {
+		t.Errorf("Expected a permanent error for invalid JSON, got %v", err)
+	}
+	if err := h.IngestMessage("nats:test", []byte(`{"events":"nope"}`)); !bus.IsPermanent(err) {
+		t.Errorf("Expected a permanent error for a malformed payload, got %v", err)
+	}
+
+	// Invalid events are dropped without a redelivery, payload or single event
+	for _, message := range []string{
+		`{"events":[{"id":"e1","type":"smtp.delivery.failure","data":{"ip":"not-an-ip"}}]}`,
+		`{"id":"e1","type":"smtp.delivery.failure","data":{"ip":"not-an-ip"}}`,
+		`{"id":"e2","type":"smtp.delivery.delayed","data":{"ip":"192.0.2.1"}}`,
+	} {
+		if err := h.IngestMessage("nats:test", []byte(message)); err != nil {
+			t.Errorf("IngestMessage(%s) error = %v", message, err)
+		}
+	}
+}
+
+func TestIngestDeliverySuccess(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryIngestStore{}
+	handler := &ReputationHandler{store: store, logger: logger}
+	body := `{"events":[
+		{"id":"s1","createdAt":"2026-03-02T14:00:00Z","type":"smtp.delivery.success","data":{"ip":"192.0.2.1","instance":"mta-1"}},
+		{"id":"s2","type":"smtp.delivery.success","data":{"ip":"::ffff:192.0.2.1"}},
+		{"id":"s1","createdAt":"2026-03-02T14:00:00Z","type":"smtp.delivery.success","data":{"ip":"192.0.2.1","instance":"mta-1"}},
+		{"id":"s3","type":"smtp.delivery.success","data":{}}
+	]}`
+
+	rec := httptest.NewRecorder()
+	handler.processDeliveryFailureHandler(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events?detailed=true", strings.NewReader(body)))
+	var resp WebhookIngestResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Processed != 3 || resp.Failed != 1 || resp.Results[3].Status != eventStatusMissingIP {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if len(store.deliveries) != 2 || store.deliveries[1].SendingIP != "192.0.2.1" || store.deliveries[0].MTAInstance != "mta-1" {
+		t.Errorf("Expected the redelivered event counted once, got %+v", store.deliveries)
+	}
+	if len(store.failures) != 0 {
+		t.Errorf("Expected no failures stored, got %+v", store.failures)
+	}
+}
+
+func TestNormalizeEventCodes(t *testing.T) {
+	tests := []struct {
+		smtpCode int
+		enhanced string
+		want     string
+		valid    bool
+	}{
+		{550, "5.7.1", "5.7.1", true},
+		{0, "", "", true},
+		{421, "4.7.001", "4.7.1", true},
+		{550, " ", "", true},
+		{999, "5.7.1", "", false},
+		{550, "5.7", "", false},
+		{550, "3.1.1", "", false},
+	}
+	for _, tt := range tests {
+		event := WebhookEvent{Data: EventData{SMTPCode: tt.smtpCode, EnhancedCode: tt.enhanced}}
+		err := normalizeEventCodes(&event)
+		if (err == nil) != tt.valid {
+			t.Errorf("normalizeEventCodes(%d, %q) = %v, want valid=%v", tt.smtpCode, tt.enhanced, err, tt.valid)
+		}
+		if tt.valid && event.Data.EnhancedCode != tt.want {
+			t.Errorf("normalizeEventCodes(%q) stored %q, want %q", tt.enhanced, event.Data.EnhancedCode, tt.want)
+		}
+	}
+}
+
+func TestWebhookDetailedResults(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := SetupRoutesWithDependencies(Dependencies{Logger: logger})
+	body := `{"events":[
+		{"id":"e1","type":"smtp.delivery.failure","data":{"recipient":"a@gmail.com"}},
+		{"id":"e2","type":"smtp.delivery.failure","data":{"ip":"not-an-ip"}},
+		{"id":"e3","type":"smtp.delivery.failure","data":{"ip":"192.0.2.1","smtp_code":550,"enhanced_code":"5.7"}},
+		{"id":"e4","type":"smtp.delivery.failure","data":{"ip":"192.0.2.1","smtp_code":"550"}},
+		{"id":"e5","type":"smtp.delivery.delayed","data":{"ip":"192.0.2.1"}}
+	]}`
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events?detailed=true", strings.NewReader(body)))
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want 207: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp WebhookIngestResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "partial" || resp.Failed != 4 || resp.Total != 5 || len(resp.Results) != 5 {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	want := []string{eventStatusMissingIP, eventStatusInvalidIP, eventStatusInvalidCode, eventStatusInvalidSchema, eventStatusSkipped}
+	for i, result := range resp.Results {
+		if result.Status != want[i] || (result.Error == "") == (i < 4) {
+			t.Errorf("result %d = %+v, want status %s", i, result, want[i])
+		}
+	}
+
+	// Without the flag the response keeps its summary shape
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events", strings.NewReader(body)))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "results") {
+		t.Errorf("Unexpected summary response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// FuzzWebhookIngestion tests that any webhook body gets a response and that
+// every failure stored from it has a normalized IP and status codes
+func FuzzWebhookIngestion(f *testing.F) {
+	f.Add(`{"version":"1","events":[{"id":"e1","type":"smtp.delivery.failure","data":{"ip":"192.0.2.10","smtp_code":550,"enhanced_code":"5.7.001"}}]}`)
+	f.Add(`{"events":[{"id":"e1","type":"smtp.bounce","data":{"remoteIp":"2001:DB8::1","smtpCode":421,"enhancedCode":"4.7.28"}}]}`)
+	f.Add(`{"events":[{"id":"a1","type":"smtp.auth.failure","data":{"ip":" 192.0.2.1 ","mechanism":"plain"}}]}`)
+	f.Add(`{"events":[{"id":"e1","type":"smtp.dsn","data":{"ip":"::ffff:192.0.2.1","action":"delivered"}}]}`)
+	f.Add(`{"events":[{"id":"s1","type":"smtp.delivery.success","data":{"remoteIp":"2001:DB8::1"}}]}`)
+	f.Add(`{"version":2,"events":null}`)
+	f.Add(`{"events":[1,"x",null]}`)
+	f.Fuzz(func(t *testing.T, body string) {
+		logger, _ := test.NewNullLogger()
+		store := &memoryIngestStore{}
+		handler := &ReputationHandler{store: store, logger: logger}
+		rec := httptest.NewRecorder()
+		handler.processDeliveryFailureHandler(rec, httptest.NewRequest("POST", "/api/webhooks/stalwart/events?detailed=true", strings.NewReader(body)))
+		switch rec.Code {
+		case http.StatusOK, http.StatusMultiStatus, http.StatusBadRequest:
+		default:
+			t.Fatalf("status = %d for %s", rec.Code, body)
+		}
+
+		for _, failure := range store.failures {
+			if ip, err := validation.NormalizeIP(failure.SendingIP); err != nil || ip != failure.SendingIP {
+				t.Errorf("Stored unnormalized IP %q from %s", failure.SendingIP, body)
+			}
+			if code := failure.EnhancedCode; code != "" {
+				if normalized, err := validation.NormalizeEnhancedCode(code); err != nil || normalized != code {
+					t.Errorf("Stored unnormalized enhanced code %q from %s", code, body)
+				}
+			}
+			if failure.SMTPCode != 0 && (failure.SMTPCode < 200 || failure.SMTPCode > 599) {
+				t.Errorf("Stored SMTP code %d from %s", failure.SMTPCode, body)
+			}
+		}
+		for _, failure := range store.authFailures {
+			if ip, err := validation.NormalizeIP(failure.SendingIP); err != nil || ip != failure.SendingIP {
+				t.Errorf("Stored unnormalized IP %q from %s", failure.SendingIP, body)
+			}
+		}
+		for _, success := range store.deliveries {
+			if ip, err := validation.NormalizeIP(success.SendingIP); err != nil || ip != success.SendingIP {
+				t.Errorf("Counted unnormalized IP %q from %s", success.SendingIP, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/reputation"
+)
+
+// Stalwart webhook schema versions. v1 is the original snake_case shape;
+// v2 uses camelCase keys and names the message ID queueId.
+const (
+	webhookSchemaV1      = "1"
+	webhookSchemaV2      = "2"
+	webhookSchemaUnknown = "unknown"
+)
+
+// maxFieldNameLength bounds unknown field names used as metric labels
+const maxFieldNameLength = 64
+
+// webhookEventFields are the event envelope keys every version shares
+var webhookEventFields = map[string]bool{
+	"id": true, "createdAt": true, "type": true, "data": true, "version": true,
+}
+
+// webhookSchema maps one version's data keys onto EventData
+type webhookSchema struct {
+	fields map[string]func(*EventData, json.RawMessage) error
+}
+
+func stringField(set func(*EventData, string)) func(*EventData, json.RawMessage) error {
+	return func(d *EventData, raw json.RawMessage) error {
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		set(d, v)
+		return nil
+	}
+}
+
+func intField(set func(*EventData, int)) func(*EventData, json.RawMessage) error {
+	return func(d *EventData, raw json.RawMessage) error {
+		var v int
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		set(d, v)
+		return nil
+	}
+}
+
+var (
+	setDomain    = stringField(func(d *EventData, v string) { d.Domain = v })
+	setRecipient = stringField(func(d *EventData, v string) { d.Recipient = v })
+	setIP        = stringField(func(d *EventData, v string) { d.IP = v })
+	setSMTPCode  = intField(func(d *EventData, v int) { d.SMTPCode = v })
+	setEnhanced  = stringField(func(d *EventData, v string) { d.EnhancedCode = v })
+	setReason    = stringField(func(d *EventData, v string) { d.Reason = v })
+	setMX        = stringField(func(d *EventData, v string) { d.MX = v })
+	setAttempt   = intField(func(d *EventData, v int) { d.AttemptNumber = v })
+	setInstance  = stringField(func(d *EventData, v string) { d.Instance = v })
+	setMessageID = stringField(func(d *EventData, v string) { d.MessageID = v })
+	setAction    = stringField(func(d *EventData, v string) { d.Action = v })
+	setHost      = stringField(func(d *EventData, v string) { d.RemoteHost = v })
+	setMechanism = stringField(func(d *EventData, v string) { d.Mechanism = v })
+	setUsername  = stringField(func(d *EventData, v string) { d.Username = v })
+)
+
+var webhookSchemas = map[string]webhookSchema{
+	webhookSchemaV1: {fields: map[string]func(*EventData, json.RawMessage) error{
+		"domain":         setDomain,
+		"recipient":      setRecipient,
+		"ip":             setIP,
+		"smtp_code":      setSMTPCode,
+		"enhanced_code":  setEnhanced,
+		"reason":         setReason,
+		"mx":             setMX,
+		"attempt_number": setAttempt,
+		"instance":       setInstance,
+		"message_id":     setMessageID,
+		"action":         setAction,
+		"remote_host":    setHost,
+		"mechanism":      setMechanism,
+		"username":       setUsername,
+	}},
+	webhookSchemaV2: {fields: map[string]func(*EventData, json.RawMessage) error{
+		"domain":        setDomain,
+		"recipient":     setRecipient,
+		"remoteIp":      setIP,
+		"smtpCode":      setSMTPCode,
+		"enhancedCode":  setEnhanced,
+		"reason":        setReason,
+		"mx":            setMX,
+		"attemptNumber": setAttempt,
+		"instance":      setInstance,
+		"queueId":       setMessageID,
+		"action":        setAction,
+		"remoteHost":    setHost,
+		"mechanism":     setMechanism,
+		"username":      setUsername,
+	}},
+}
+
+// rawWebhookPayload is a webhook body before its events are decoded
+type rawWebhookPayload struct {
+	Version json.RawMessage   `json:"version"`
+	Events  []json.RawMessage `json:"events"`
+}
+
+// decodedWebhookEvent is an event translated to the canonical shape, or the
+// reason it could not be
+type decodedWebhookEvent struct {
+	Event   WebhookEvent
+	Version string
+	Unknown []string
+	Err     error
+}
+
+// parseSchemaVersion reads a version given as a JSON string or number
+func parseSchemaVersion(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(s), "v")
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		if i, err := strconv.Atoi(n.String()); err == nil {
+			return strconv.Itoa(i)
+		}
+	}
+	return webhookSchemaUnknown
+}
+
+// detectSchemaVersion guesses the version of an unversioned event from its
+// data keys
+func detectSchemaVersion(data map[string]json.RawMessage) string {
+	for _, key := range []string{"remoteIp", "smtpCode", "enhancedCode", "attemptNumber", "queueId", "remoteHost"} {
+		if _, ok := data[key]; ok {
+			return webhookSchemaV2
+		}
+	}
+	return webhookSchemaV1
+}
+
+// decodeWebhookEvent decodes one event. The event's own version wins over
+// the payload's; without either the version is detected. Known fields must
+// have the right JSON type; unknown fields are reported, not rejected.
+func decodeWebhookEvent(raw json.RawMessage, payloadVersion string) decodedWebhookEvent {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return decodedWebhookEvent{Version: webhookSchemaUnknown, Err: fmt.Errorf("event is not an object")}
+	}
+
+	var decoded decodedWebhookEvent
+	for key := range envelope {
+		if !webhookEventFields[key] {
+			decoded.Unknown = append(decoded.Unknown, key)
+		}
+	}
+
+	for key, target := range map[string]*string{"id": &decoded.Event.ID, "createdAt": &decoded.Event.CreatedAt, "type": &decoded.Event.Type} {
+		if value, ok := envelope[key]; ok {
+			if err := json.Unmarshal(value, target); err != nil {
+				decoded.Version = webhookSchemaUnknown
+				decoded.Err = fmt.Errorf("%s must be a string", key)
+				return decoded
+			}
+		}
+	}
+
+	var data map[string]json.RawMessage
+	if value, ok := envelope["data"]; ok && string(bytes.TrimSpace(value)) != "null" {
+		if err := json.Unmarshal(value, &data); err != nil {
+			decoded.Version = webhookSchemaUnknown
+			decoded.Err = fmt.Errorf("data must be an object")
+			return decoded
+		}
+	}
+
+	decoded.Version = parseSchemaVersion(envelope["version"])
+	if decoded.Version == "" {
+		decoded.Version = payloadVersion
+	}
+	if decoded.Version == "" {
+		decoded.Version = detectSchemaVersion(data)
+	}
+	schema, ok := webhookSchemas[decoded.Version]
+	if !ok {
+		decoded.Version = webhookSchemaUnknown
+		decoded.Err = fmt.Errorf("unsupported schema version")
+		return decoded
+	}
+
+	for key, value := range data {
+		set, known := schema.fields[key]
+		if !known {
+			decoded.Unknown = append(decoded.Unknown, "data."+key)
+			continue
+		}
+		if string(bytes.TrimSpace(value)) == "null" {
+			continue
+		}
+		if err := set(&decoded.Event.Data, value); err != nil {
+			decoded.Err = fmt.Errorf("data.%s has the wrong type", key)
+			return decoded
+		}
+	}
+	sort.Strings(decoded.Unknown)
+	return decoded
+}
+
+// recordSchemaMetrics counts an event by schema version and its unknown fields
+func recordSchemaMetrics(decoded decodedWebhookEvent) {
+	reputation.RecordWebhookSchemaVersion(decoded.Version)
+	for _, field := range decoded.Unknown {
+		if len(field) > maxFieldNameLength {
+			field = field[:maxFieldNameLength]
+		}
+		reputation.RecordWebhookUnknownField(decoded.Version, field)
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDecodeWebhookEventVersions(t *testing.T) {
+	want := EventData{
+		Domain:        "gmail.com",
+		Recipient:     "user@gmail.com",
+		IP:            "192.0.2.10",
+		SMTPCode:      550,
+		EnhancedCode:  "5.7.1",
+		Reason:        "Blocked",
+		MX:            "gmail-smtp-in.l.google.com",
+		AttemptNumber: 2,
+		Instance:      "mta-1",
+		MessageID:     "q-123",
+	}
+
+	tests := []struct {
+		name           string
+		payloadVersion string
+		event          string
+		wantVersion    string
+	}{
+		{"v1 detected", "", `{"id":"e1","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","ip":"192.0.2.10","smtp_code":550,"enhanced_code":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attempt_number":2,"instance":"mta-1","message_id":"q-123"}}`, "1"},
+		{"v2 detected", "", `{"id":"e1","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","remoteIp":"192.0.2.10","smtpCode":550,"enhancedCode":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attemptNumber":2,"instance":"mta-1","queueId":"q-123"}}`, "2"},
+		{"v2 from payload", "2", `{"id":"e1","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","remoteIp":"192.0.2.10","smtpCode":550,"enhancedCode":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attemptNumber":2,"instance":"mta-1","queueId":"q-123"}}`, "2"},
+		{"event version wins", "2", `{"id":"e1","version":"v1","type":"smtp.delivery.failure","data":{"domain":"gmail.com","recipient":"user@gmail.com","ip":"192.0.2.10","smtp_code":550,"enhanced_code":"5.7.1","reason":"Blocked","mx":"gmail-smtp-in.l.google.com","attempt_number":2,"instance":"mta-1","message_id":"q-123"}}`, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := decodeWebhookEvent(json.RawMessage(tt.event), tt.payloadVersion)
+			if decoded.Err != nil {
+				t.Fatalf("decodeWebhookEvent() error = %v", decoded.Err)
+			}
+			if decoded.Version != tt.wantVersion || len(decoded.Unknown) != 0 {
+				t.Errorf("version = %q, unknown = %v, want %q and none", decoded.Version, decoded.Unknown, tt.wantVersion)
+			}
+			if !reflect.DeepEqual(decoded.Event.Data, want) || decoded.Event.ID != "e1" || decoded.Event.Type != "smtp.delivery.failure" {
+				t.Errorf("decoded = %+v, want data %+v", decoded.Event, want)
+			}
+		})
+	}
+}
+
+func TestDecodeWebhookEventStrictness(t *testing.T) {
+	decoded := decodeWebhookEvent(json.RawMessage(`{"id":"e1","type":"smtp.delivery.failure","trace":"x","data":{"ip":"192.0.2.10","smtp_code":550,"dsn":{"action":"failed"}}}`), "")
+	if decoded.Err != nil {
+		t.Fatalf("Unknown fields must not reject the event: %v", decoded.Err)
+	}
+	if !reflect.DeepEqual(decoded.Unknown, []string{"data.dsn", "trace"}) {
+		t.Errorf("unknown = %v", decoded.Unknown)
+	}
+
+	rejected := []struct {
+		name    string
+		payload string
+		event   string
+	}{
+		{"wrong field type", "", `{"id":"e1","data":{"smtp_code":"550"}}`},
+		{"data not an object", "", `{"id":"e1","data":[1]}`},
+		{"id not a string", "", `{"id":7,"data":{}}`},
+		{"unsupported event version", "", `{"id":"e1","version":3,"data":{}}`},
+		{"unsupported payload version", "9", `{"id":"e1","data":{}}`},
+		{"not an object", "", `"e1"`},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			if decoded := decodeWebhookEvent(json.RawMessage(tt.event), tt.payload); decoded.Err == nil {
+				t.Errorf("Expected %s to be rejected, got %+v", tt.event, decoded)
+			}
+		})
+	}
+}
+
+func TestParseSchemaVersion(t *testing.T) {
+	tests := map[string]string{
+		``:        "",
+		`null`:    "",
+		`1`:       "1",
+		`"2"`:     "2",
+		`"v2"`:    "2",
+		`1.5`:     webhookSchemaUnknown,
+		`{"a":1}`: webhookSchemaUnknown,
+	}
+	for raw, want := range tests {
+		if got := parseSchemaVersion(json.RawMessage(raw)); got != want {
+			t.Errorf("parseSchemaVersion(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+// FuzzDecodeWebhookEvent tests that malformed MTA output is rejected or
+// reported, never accepted under an unknown schema or a panic
+func FuzzDecodeWebhookEvent(f *testing.F) {
+	f.Add(`{"id":"e1","type":"smtp.delivery.failure","data":{"ip":"192.0.2.10","smtp_code":550,"enhanced_code":"5.7.1"}}`, "")
+	f.Add(`{"id":"e1","type":"smtp.delivery.failure","data":{"remoteIp":"192.0.2.10","smtpCode":550,"queueId":"q-1"}}`, "2")
+	f.Add(`{"id":"e1","version":"v1","data":{"smtp_code":"550"}}`, "2")
+	f.Add(`{"id":7,"data":[1],"trace":null}`, "9")
+	f.Add(`{"data":null}`, "")
+	f.Add(`"e1"`, "")
+	f.Fuzz(func(t *testing.T, raw string, payloadVersion string) {
+		decoded := decodeWebhookEvent(json.RawMessage(raw), payloadVersion)
+		if _, ok := webhookSchemas[decoded.Version]; !ok && decoded.Err == nil {
+			t.Errorf("Accepted %s under unknown schema version %q", raw, decoded.Version)
+		}
+		if decoded.Err == nil && !sort.StringsAreSorted(decoded.Unknown) {
+			t.Errorf("Unknown fields of %s are not sorted: %v", raw, decoded.Unknown)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/reputation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Signed webhook headers. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>", so a captured payload cannot be replayed with a
+// fresh timestamp.
+const (
+	webhookSignatureHeader = "X-Signature-256"
+	webhookTimestampHeader = "X-Signature-Timestamp"
+	webhookSignaturePrefix = "sha256="
+)
+
+// maxSignedWebhookSize bounds the body buffered to verify its signature
+const maxSignedWebhookSize = 32 << 20
+
+// WebhookSignatureConfig sets the shared secrets ingestion payloads are
+// signed with. Payloads of a source without a secret are not checked.
+// Payloads without a source token are rejected when only source secrets are
+// set, since nothing could verify them.
+type WebhookSignatureConfig struct {
+	// Secret applies to sources without their own secret and to payloads
+	// without a source token
+	Secret string
+	// SourceSecrets are secrets by webhook source name
+	SourceSecrets map[string]string
+	// MaxAge is how far the signature timestamp may be from now; 0 accepts
+	// any timestamp
+	MaxAge time.Duration
+}
+
+// ParseWebhookSecrets parses per source secrets written as
+// "stalwart-eu:secret1,stalwart-us:secret2"
+func ParseWebhookSecrets(s string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, secret, ok := strings.Cut(pair, ":")
+		name, secret = strings.TrimSpace(name), strings.TrimSpace(secret)
+		if !ok || name == "" || secret == "" {
+			return nil, fmt.Errorf("invalid webhook secret %q, expected source:secret", name)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// SignWebhook returns the X-Signature-256 value of a payload sent at timestamp
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookSignatures verifies the HMAC signature of ingestion payloads. It
+// runs after WebhookSourceHandler.Authenticate to pick the source's secret.
+type WebhookSignatures struct {
+	config WebhookSignatureConfig
+	now    func() time.Time
+	logger *logrus.Logger
+}
+
+// NewWebhookSignatures creates the signature verification middleware
+func NewWebhookSignatures(config WebhookSignatureConfig, logger *logrus.Logger) *WebhookSignatures {
+	return &WebhookSignatures{config: config, now: time.Now, logger: logger}
+}
+
+// secret returns the secret payloads of a source are signed with; sourceName
+// is empty for payloads without a source token
+func (s *WebhookSignatures) secret(sourceName string) string {
+	if secret, ok := s.config.SourceSecrets[sourceName]; ok && sourceName != "" {
+		return secret
+	}
+	return s.config.Secret
+}
+
+// Middleware rejects unsigned, wrongly signed or stale payloads with 401
+func (s *WebhookSignatures) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sourceName := ""
+		if source := webhookSourceFromContext(r.Context()); source != nil {
+			sourceName = source.Name
+		}
+		secret := s.secret(sourceName)
+		if secret == "" && sourceName == "" && len(s.config.SourceSecrets) > 0 {
+			s.reject(w, r, sourceName, "missing_source_token")
+			return
+		}
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		signature := r.Header.Get(webhookSignatureHeader)
+		if signature == "" {
+			s.reject(w, r, sourceName, "missing_signature")
+			return
+		}
+		timestamp, err := strconv.ParseInt(r.Header.Get(webhookTimestampHeader), 10, 64)
+		if err != nil {
+			s.reject(w, r, sourceName, "invalid_timestamp")
+			return
+		}
+		if s.config.MaxAge > 0 {
+			age := s.now().Sub(time.Unix(timestamp, 0))
+			if age > s.config.MaxAge || age < -s.config.MaxAge {
+				s.reject(w, r, sourceName, "stale_timestamp")
+				return
+			}
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedWebhookSize))
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "invalid_request", "Request body is unreadable or too large")
+			return
+		}
+		if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+			s.reject(w, r, sourceName, "invalid_signature")
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *WebhookSignatures) reject(w http.ResponseWriter, r *http.Request, sourceName, reason string) {
+	label := sourceName
+	if label == "" {
+		label = "unauthenticated"
+	}
+	reputation.RecordWebhookSignatureRejection(reason, label)
+	s.logger.WithFields(logrus.Fields{
+		"action":      "webhook_signature_rejected",
+		"reason":      reason,
+		"source":      label,
+		"remote_addr": r.RemoteAddr,
+	}).Warn("Rejected webhook with an invalid signature")
+
+	if reason == "missing_source_token" {
+		writeUserError(w, http.StatusUnauthorized, "invalid_signature", "A source token is required once webhook secrets are configured")
+		return
+	}
+	writeUserError(w, http.StatusUnauthorized, "invalid_signature", "A valid "+webhookSignatureHeader+" signature is required")
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/reputation"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestParseWebhookSecrets(t *testing.T) {
+	secrets, err := ParseWebhookSecrets(" mta-1:one, mta-2:two ,")
+	if err != nil || len(secrets) != 2 || secrets["mta-1"] != "one" || secrets["mta-2"] != "two" {
+		t.Fatalf("Unexpected secrets: %v %v", secrets, err)
+	}
+	for _, invalid := range []string{"mta-1", "mta-1:", ":secret"} {
+		if _, err := ParseWebhookSecrets(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestWebhookSignatures(t *testing.T) {
+	const body = `{"events":[]}`
+	now := time.Unix(1760000000, 0)
+	signed := func(secret string, at time.Time) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set(webhookTimestampHeader, strconv.FormatInt(at.Unix(), 10))
+			r.Header.Set(webhookSignatureHeader, SignWebhook(secret, at.Unix(), []byte(body)))
+		}
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		setup        func(r *http.Request)
+		expectedCode int
+		reason       string
+	}{
+		{"source secret", "whsrc_secret", signed("source-secret", now), http.StatusOK, ""},
+		{"default secret without token", "", signed("default-secret", now.Add(-time.Minute)), http.StatusOK, ""},
+		{"default secret for a source with its own", "whsrc_secret", signed("default-secret", now), http.StatusUnauthorized, "invalid_signature"},
+		{"unsigned", "whsrc_secret", func(r *http.Request) {}, http.StatusUnauthorized, "missing_signature"},
+		{"no timestamp", "", func(r *http.Request) {
+			r.Header.Set(webhookSignatureHeader, SignWebhook("default-secret", now.Unix(), []byte(body)))
+		}, http.StatusUnauthorized, "invalid_timestamp"},
+		{"stale", "", signed("default-secret", now.Add(-10*time.Minute)), http.StatusUnauthorized, "stale_timestamp"},
+		{"future", "", signed("default-secret", now.Add(10*time.Minute)), http.StatusUnauthorized, "stale_timestamp"},
+		{"timestamp not signed", "", func(r *http.Request) {
+			signed("default-secret", now)(r)
+			r.Header.Set(webhookTimestampHeader, strconv.FormatInt(now.Unix()+1, 10))
+		}, http.StatusUnauthorized, "invalid_signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, hook := test.NewNullLogger()
+			signatures := NewWebhookSignatures(WebhookSignatureConfig{
+				Secret:        "default-secret",
+				SourceSecrets: map[string]string{"mta-1": "source-secret"},
+				MaxAge:        5 * time.Minute,
+			}, log)
+			signatures.now = func() time.Time { return now }
+
+			var gotBody string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks/stalwart/delivery-failure", strings.NewReader(body))
+			if tt.token != "" {
+				req.Header.Set("X-Webhook-Token", tt.token)
+			}
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+
+			source := "mta-1"
+			if tt.token == "" {
+				source = "unauthenticated"
+			}
+			var before float64
+			if tt.reason != "" {
+				before = testutil.ToFloat64(reputation.WebhookSignatureRejectionsTotal.WithLabelValues(tt.reason, source))
+			}
+
+			newTestSourceHandler(false, "whsrc_secret").Authenticate(signatures.Middleware(next)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+			if tt.reason == "" {
+				if gotBody != body {
+					t.Errorf("Expected the verified body to reach the handler, got %q", gotBody)
+				}
+				return
+			}
+			if gotBody != "" {
+				t.Error("Expected the rejected payload not to reach the handler")
+			}
+			if after := testutil.ToFloat64(reputation.WebhookSignatureRejectionsTotal.WithLabelValues(tt.reason, source)); after != before+1 {
+				t.Errorf("Expected a %s rejection for %s to be counted", tt.reason, source)
+			}
+			if entry := hook.LastEntry(); entry == nil || entry.Data["reason"] != tt.reason {
+				t.Errorf("Expected the rejection to be logged, got %+v", entry)
+			}
+		})
+	}
+}
+
+func TestWebhookSignaturesWithoutSecret(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	signatures := NewWebhookSignatures(WebhookSignatureConfig{SourceSecrets: map[string]string{"mta-2": "other"}}, log)
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/stalwart/delivery-failure", strings.NewReader("{}"))
+	req.Header.Set("X-Webhook-Token", "whsrc_secret")
+	rec := httptest.NewRecorder()
+
+	newTestSourceHandler(false, "whsrc_secret").Authenticate(signatures.Middleware(next)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !called {
+		t.Errorf("Expected unsigned payloads of a source without a secret to pass, got %d", rec.Code)
+	}
+}
+
+func TestWebhookSignaturesRejectTokenlessWithSourceSecrets(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	signatures := NewWebhookSignatures(WebhookSignatureConfig{SourceSecrets: map[string]string{"mta-1": "source-secret"}}, log)
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/stalwart/delivery-failure", strings.NewReader("{}"))
+	rec := httptest.NewRecorder()
+	before := testutil.ToFloat64(reputation.WebhookSignatureRejectionsTotal.WithLabelValues("missing_source_token", "unauthenticated"))
+
+	newTestSourceHandler(false, "whsrc_secret").Authenticate(signatures.Middleware(next)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized || called {
+		t.Errorf("Expected a payload with neither token nor signature rejected, got %d", rec.Code)
+	}
+	if after := testutil.ToFloat64(reputation.WebhookSignatureRejectionsTotal.WithLabelValues("missing_source_token", "unauthenticated")); after != before+1 {
+		t.Error("Expected the rejection to be counted")
+	}
+}
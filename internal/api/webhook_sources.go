@@ -0,0 +1,343 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookTokenPrefix makes leaked tokens easy to recognise in secret scanners
+const webhookTokenPrefix = "whsrc_"
+
+type webhookSourceContextKey struct{}
+
+// CreateWebhookSourceRequest represents the request body for registering a source
+type CreateWebhookSourceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateWebhookSourceResponse returns the new source and its token. The token
+// is only shown once; only its hash is stored.
+type CreateWebhookSourceResponse struct {
+	Source database.WebhookSource `json:"source"`
+	Token  string                 `json:"token"`
+}
+
+// WebhookSourceHandler manages webhook sources and authenticates ingestion
+type WebhookSourceHandler struct {
+	requireAuth  func() bool
+	lookupSource func(tokenHash string) (*database.WebhookSource, error)
+	logger       *logrus.Logger
+}
+
+// NewWebhookSourceHandler creates a new webhook source handler. While
+// requireAuth returns false, requests without a token are accepted and stored
+// without a source; requests with an unknown token are always rejected.
+func NewWebhookSourceHandler(requireAuth func() bool, logger *logrus.Logger) *WebhookSourceHandler {
+	return &WebhookSourceHandler{
+		requireAuth:  requireAuth,
+		lookupSource: database.GetWebhookSourceByTokenHash,
+		logger:       logger,
+	}
+}
+
+// hashWebhookToken returns the hex SHA-256 of a token as stored in webhook_sources
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateWebhookToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook token: %w", err)
+	}
+	return webhookTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// webhookToken extracts the source token from a Bearer or Basic Authorization
+// header (Stalwart's webhook auth uses Basic; the password is the token) or
+// from X-Webhook-Token
+func webhookToken(r *http.Request) string {
+	if token := r.Header.Get("X-Webhook-Token"); token != "" {
+		return token
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// webhookSourceFromContext returns the authenticated source, if any
+func webhookSourceFromContext(ctx context.Context) *database.WebhookSource {
+	source, _ := ctx.Value(webhookSourceContextKey{}).(*database.WebhookSource)
+	return source
+}
+
+// Authenticate resolves the request's token to a webhook source and stores it
+// on the request context
+func (h *WebhookSourceHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := webhookToken(r)
+		if token == "" {
+			if h.requireAuth() {
+				h.reject(w, r, "missing_token")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		source, err := h.lookupSource(hashWebhookToken(token))
+		if err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"action": "webhook_source_lookup_failed",
+				"error":  err.Error(),
+			}).Error("Failed to look up webhook source")
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "database_error",
+				Message: "Failed to authenticate webhook source",
+			})
+			return
+		}
+		if source == nil {
+			h.reject(w, r, "unknown_token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webhookSourceContextKey{}, source)))
+	})
+}
+
+func (h *WebhookSourceHandler) reject(w http.ResponseWriter, r *http.Request, reason string) {
+	h.logger.WithFields(logrus.Fields{
+		"action":      "webhook_auth_failed",
+		"reason":      reason,
+		"remote_addr": r.RemoteAddr,
+	}).Warn("Rejected unauthenticated webhook")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="webhooks"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   "unauthorized",
+		Message: "A valid webhook source token is required",
+	})
+}
+
+// @Summary Register webhook source
+// @Description Create a webhook source and return its token. The token is only returned once.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param source body CreateWebhookSourceRequest true "Source details"
+// @Success 201 {object} CreateWebhookSourceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/sources [post]
+func (h *WebhookSourceHandler) HandleCreateSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CreateWebhookSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Source name is required",
+		})
+		return
+	}
+
+	token, err := generateWebhookToken()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to generate webhook token")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "token_generation_failed",
+			Message: "Failed to generate webhook token",
+		})
+		return
+	}
+
+	source, err := database.CreateWebhookSource(strings.TrimSpace(req.Name), req.Description, hashWebhookToken(token))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"action": "create_webhook_source_failed",
+			"name":   req.Name,
+			"error":  err.Error(),
+		}).Error("Failed to create webhook source")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to create webhook source",
+		})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"action":    "webhook_source_created",
+		"source_id": source.ID,
+		"name":      source.Name,
+	}).Info("Webhook source created")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateWebhookSourceResponse{
+		Source: *source,
+		Token:  token,
+	})
+}
+
+// @Summary List webhook sources
+// @Description List registered webhook sources with their ingestion counters
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} database.WebhookSource
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/sources [get]
+func (h *WebhookSourceHandler) HandleListSources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sources, err := database.ListWebhookSources()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list webhook sources")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to list webhook sources",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(sources)
+}
+
+// @Summary Disable webhook source
+// @Description Revoke a webhook source's token. Failures it reported are kept.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Source ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/sources/{id} [delete]
+func (h *WebhookSourceHandler) HandleDisableSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Source ID must be a number",
+		})
+		return
+	}
+
+	found, err := database.DisableWebhookSource(id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to disable webhook source")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to disable webhook source",
+		})
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "not_found",
+			Message: "Webhook source not found",
+		})
+		return
+	}
+
+	h.logger.WithField("source_id", id).Info("Webhook source disabled")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"source_id": id,
+		"message":   "Webhook source disabled",
+	})
+}
+
+// @Summary Get webhook source statistics
+// @Description Ingestion counters and a breakdown of failures stored by a source
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Source ID"
+// @Param window query string false "Time window (e.g., 1h, 24h)" default(24h)
+// @Success 200 {object} database.WebhookSourceStats
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/webhooks/sources/{id}/stats [get]
+func (h *WebhookSourceHandler) HandleSourceStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Source ID must be a number",
+		})
+		return
+	}
+
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		windowStr = "24h"
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "invalid_window",
+			Message: "Invalid time window format (use 15m, 1h, 24h, etc.)",
+		})
+		return
+	}
+
+	stats, err := database.GetWebhookSourceStats(id, time.Now().Add(-window))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhook source stats")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to get webhook source statistics",
+		})
+		return
+	}
+	if stats == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error:   "not_found",
+			Message: "Webhook source not found",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
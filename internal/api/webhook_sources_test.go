@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func newTestSourceHandler(requireAuth bool, token string) *WebhookSourceHandler {
+	log, _ := test.NewNullLogger()
+	handler := NewWebhookSourceHandler(func() bool { return requireAuth }, log)
+	handler.lookupSource = func(tokenHash string) (*database.WebhookSource, error) {
+		if tokenHash == hashWebhookToken(token) {
+			return &database.WebhookSource{ID: 7, Name: "mta-1", Enabled: true}, nil
+		}
+		return nil, nil
+	}
+	return handler
+}
+
+func TestWebhookAuthenticate(t *testing.T) {
+	const token = "whsrc_secret"
+
+	tests := []struct {
+		name         string
+		requireAuth  bool
+		setup        func(r *http.Request)
+		expectedCode int
+		expectSource bool
+	}{
+		{"bearer token", true, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }, http.StatusOK, true},
+		{"basic auth password", true, func(r *http.Request) { r.SetBasicAuth("stalwart", token) }, http.StatusOK, true},
+		{"header token", true, func(r *http.Request) { r.Header.Set("X-Webhook-Token", token) }, http.StatusOK, true},
+		{"missing token required", true, func(r *http.Request) {}, http.StatusUnauthorized, false},
+		{"missing token optional", false, func(r *http.Request) {}, http.StatusOK, false},
+		{"unknown token optional", false, func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSource *database.WebhookSource
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotSource = webhookSourceFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks/stalwart/delivery-failure", strings.NewReader("{}"))
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+
+			newTestSourceHandler(tt.requireAuth, token).Authenticate(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if tt.expectSource && (gotSource == nil || gotSource.ID != 7) {
+				t.Errorf("Expected source 7 on context, got %+v", gotSource)
+			}
+			if !tt.expectSource && gotSource != nil {
+				t.Errorf("Expected no source on context, got %+v", gotSource)
+			}
+		})
+	}
+}
+
+func TestGenerateWebhookToken(t *testing.T) {
+	a, err := generateWebhookToken()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, _ := generateWebhookToken()
+
+	if !strings.HasPrefix(a, webhookTokenPrefix) || a == b {
+		t.Errorf("Expected distinct prefixed tokens, got %q and %q", a, b)
+	}
+	if len(hashWebhookToken(a)) != 64 {
+		t.Errorf("Expected 64 character hash")
+	}
+}
@@ -0,0 +1,236 @@
+package arf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/netip"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/validation"
+)
+
+// Feedback types of RFC 5965
+const (
+	FeedbackAbuse       = "abuse"
+	FeedbackFraud       = "fraud"
+	FeedbackVirus       = "virus"
+	FeedbackOther       = "other"
+	FeedbackNotSpam     = "not-spam"
+	FeedbackAuthFailure = "auth-failure"
+)
+
+// ErrNotARF is returned for messages that are not a multipart/report with a
+// feedback-report part
+var ErrNotARF = errors.New("not an ARF feedback report")
+
+// maxPartSize caps each decoded part; reports carry headers and at most a
+// copy of one message
+const maxPartSize = 10 << 20
+
+// Report is what an ARF feedback report says about the original message
+type Report struct {
+	// ReportID is the Message-ID of the report itself
+	ReportID string
+	// Reporter is the domain of the report's From address
+	Reporter         string
+	FeedbackType     string
+	UserAgent        string
+	SourceIP         string
+	OriginalMailFrom string
+	OriginalRcptTo   string
+	ReportedDomain   string
+	ArrivalDate      time.Time
+	// OriginalMessageID, SendingIP and CampaignID come from the headers of
+	// the original message
+	OriginalMessageID string
+	// SendingIP is Source-IP, or the first public IP in the original's
+	// Received headers when the reporter left Source-IP out
+	SendingIP  string
+	CampaignID string
+}
+
+// campaignHeaders are checked in order for the original message's campaign
+var campaignHeaders = []string{"X-Campaign-ID", "X-Campaign", "Feedback-ID", "List-ID"}
+
+// Parse reads a raw ARF report (RFC 5965): a multipart/report with a
+// message/feedback-report part and the original message or its headers
+func Parse(r io.Reader) (*Report, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || params["boundary"] == "" {
+		return nil, ErrNotARF
+	}
+
+	report := &Report{
+		ReportID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-ID")), "<>"),
+		Reporter: addressDomain(msg.Header.Get("From")),
+	}
+	var feedback, original textproto.MIMEHeader
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report part: %w", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/feedback-report":
+			if feedback, err = readHeaderPart(part); err != nil {
+				return nil, fmt.Errorf("failed to read feedback report: %w", err)
+			}
+		case "message/rfc822", "text/rfc822-headers", "message/rfc822-headers":
+			if original, err = readHeaderPart(part); err != nil {
+				return nil, fmt.Errorf("failed to read original message: %w", err)
+			}
+		}
+	}
+	if feedback == nil {
+		return nil, ErrNotARF
+	}
+
+	report.FeedbackType = strings.ToLower(strings.TrimSpace(feedback.Get("Feedback-Type")))
+	if report.FeedbackType == "" {
+		return nil, fmt.Errorf("feedback report has no Feedback-Type")
+	}
+	report.UserAgent = strings.TrimSpace(feedback.Get("User-Agent"))
+	report.OriginalMailFrom = strings.Trim(strings.TrimSpace(feedback.Get("Original-Mail-From")), "<>")
+	report.OriginalRcptTo = strings.Trim(strings.TrimSpace(feedback.Get("Original-Rcpt-To")), "<>")
+	report.ReportedDomain = strings.ToLower(strings.TrimSpace(feedback.Get("Reported-Domain")))
+	if addr, err := netip.ParseAddr(strings.TrimSpace(feedback.Get("Source-IP"))); err == nil {
+		report.SourceIP = addr.Unmap().String()
+	}
+	for _, name := range []string{"Arrival-Date", "Received-Date"} {
+		if date, err := mail.ParseDate(feedback.Get(name)); err == nil {
+			report.ArrivalDate = date
+			break
+		}
+	}
+
+	report.SendingIP = report.SourceIP
+	if original != nil {
+		report.OriginalMessageID = strings.Trim(strings.TrimSpace(original.Get("Message-ID")), "<>")
+		report.CampaignID = campaignID(original)
+		if report.SendingIP == "" {
+			report.SendingIP = receivedFromIP(original.Values("Received"))
+		}
+		if report.OriginalRcptTo == "" {
+			report.OriginalRcptTo = strings.Trim(strings.TrimSpace(original.Get("To")), "<>")
+		}
+		if report.OriginalMailFrom == "" {
+			report.OriginalMailFrom = strings.Trim(strings.TrimSpace(original.Get("Return-Path")), "<>")
+		}
+	}
+	return report, nil
+}
+
+// readHeaderPart decodes a part and reads the header block at its start
+func readHeaderPart(part *multipart.Part) (textproto.MIMEHeader, error) {
+	var body io.Reader = io.LimitReader(part, maxPartSize)
+	switch strings.ToLower(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	// Headers-only parts may lack the blank line that ends a header block
+	raw = append(bytes.TrimRight(raw, "\r\n"), "\r\n\r\n"...)
+
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil, err
+	}
+	return header, nil
+}
+
+// campaignID picks the campaign from the first campaign header present. A
+// Feedback-ID (campaign:customer:type:sender) gives its first field.
+func campaignID(header textproto.MIMEHeader) string {
+	for _, name := range campaignHeaders {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		switch name {
+		case "Feedback-ID":
+			if fields := strings.Split(value, ":"); len(fields) >= 2 && fields[0] != "" {
+				return fields[0]
+			}
+			continue
+		case "List-ID":
+			// "Newsletter <news.example.com>" names the list in angle brackets
+			if start, end := strings.LastIndex(value, "<"), strings.LastIndex(value, ">"); start >= 0 && end > start {
+				value = value[start+1 : end]
+			}
+		}
+		return value
+	}
+	return ""
+}
+
+// bracketedIP matches address literals like [192.0.2.1] or [IPv6:2001:db8::1]
+var bracketedIP = regexp.MustCompile(`\[(?i:IPv6:)?([0-9A-Fa-f:.]+)\]`)
+
+// receivedFromIP returns the first public IP in the from clauses of the
+// Received headers, newest first. The reporter's own hops use private
+// addresses; the first public one is the host that handed the message over.
+func receivedFromIP(received []string) string {
+	for _, header := range received {
+		from := header
+		if i := strings.Index(strings.ToLower(from), " by "); i >= 0 {
+			from = from[:i]
+		}
+		for _, match := range bracketedIP.FindAllStringSubmatch(from, -1) {
+			addr, err := netip.ParseAddr(match[1])
+			if err != nil {
+				continue
+			}
+			if !validation.IsPublicIP(addr) {
+				continue
+			}
+			return addr.Unmap().String()
+		}
+	}
+	return ""
+}
+
+// addressDomain is the lowercased domain of the first address in a header
+func addressDomain(header string) string {
+	addr, err := mail.ParseAddress(header)
+	if err != nil {
+		return ""
+	}
+	return Domain(addr.Address)
+}
+
+// Domain is the lowercased domain part of an address, or "" without one
+func Domain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 && i < len(address)-1 {
+		return strings.ToLower(address[i+1:])
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package arf
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sampleReport builds a report in the shape of RFC 5965's example
+func sampleReport(feedback, original string) string {
+	return strings.ReplaceAll(`From: <abusedesk@mail-provider.example>
+To: <abuse@sender.example>
+Subject: FW: Special offer
+Message-ID: <report-1@mail-provider.example>
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=feedback-report;
+     boundary="part1_13d.2e68ed54_boundary"
+
+--part1_13d.2e68ed54_boundary
+Content-Type: text/plain; charset="US-ASCII"
+Content-Transfer-Encoding: 7bit
+
+This is an email abuse report for an email message received from IP
+192.0.2.1 on Thu, 8 Mar 2005 14:00:00 EDT.
+
+--part1_13d.2e68ed54_boundary
+Content-Type: message/feedback-report
+
+`+feedback+`
+--part1_13d.2e68ed54_boundary
+Content-Type: message/rfc822
+Content-Disposition: inline
+
+`+original+`
+--part1_13d.2e68ed54_boundary--
+`, "\n", "\r\n")
+}
+
+const sampleFeedback = `Feedback-Type: Abuse
+User-Agent: SomeGenerator/1.0
+Version: 1
+Original-Mail-From: <bounces@sender.example>
+Original-Rcpt-To: <user@mail-provider.example>
+Arrival-Date: Thu, 8 Mar 2005 14:00:00 -0400
+Source-IP: 192.0.2.1
+Reported-Domain: Sender.example
+`
+
+const sampleOriginal = `Received: from mailserver.sender.example (mailserver.sender.example [192.0.2.1])
+     by mx.mail-provider.example; Thu, 8 Mar 2005 14:00:00 -0400
+From: <newsletter@sender.example>
+To: <user@mail-provider.example>
+Subject: Special offer
+Message-ID: <8787KJKJ3K4J3K4J3K4J3.mail@sender.example>
+X-Campaign-ID: spring-sale
+Date: Thu, 8 Mar 2005 13:59:00 -0400
+
+Buy now!
+`
+
+func TestParse(t *testing.T) {
+	report, err := Parse(strings.NewReader(sampleReport(sampleFeedback, sampleOriginal)))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if report.ReportID != "report-1@mail-provider.example" || report.Reporter != "mail-provider.example" {
+		t.Errorf("Unexpected report identity: %q from %q", report.ReportID, report.Reporter)
+	}
+	if report.FeedbackType != FeedbackAbuse || report.UserAgent != "SomeGenerator/1.0" {
+		t.Errorf("Unexpected feedback type %q / user agent %q", report.FeedbackType, report.UserAgent)
+	}
+	if report.SourceIP != "192.0.2.1" || report.SendingIP != "192.0.2.1" {
+		t.Errorf("Expected sending IP 192.0.2.1, got source %q sending %q", report.SourceIP, report.SendingIP)
+	}
+	if report.OriginalMailFrom != "bounces@sender.example" || report.OriginalRcptTo != "user@mail-provider.example" {
+		t.Errorf("Unexpected envelope: %q -> %q", report.OriginalMailFrom, report.OriginalRcptTo)
+	}
+	if report.ReportedDomain != "sender.example" || report.ArrivalDate.IsZero() {
+		t.Errorf("Unexpected reported domain %q / arrival %v", report.ReportedDomain, report.ArrivalDate)
+	}
+	if report.OriginalMessageID != "8787KJKJ3K4J3K4J3K4J3.mail@sender.example" || report.CampaignID != "spring-sale" {
+		t.Errorf("Unexpected original message %q / campaign %q", report.OriginalMessageID, report.CampaignID)
+	}
+}
+
+func TestParseSendingIPFromReceived(t *testing.T) {
+	feedback := "Feedback-Type: abuse\nUser-Agent: SomeGenerator/1.0\nVersion: 1\n"
+	original := `Received: from mx-in.mail-provider.example ([10.1.2.3]) by store.mail-provider.example; Thu, 8 Mar 2005 14:00:01 -0400
+Received: from out.sender.example (out.sender.example [IPv6:2001:DB8::25]) by mx-in.mail-provider.example ([10.1.2.3]); Thu, 8 Mar 2005 14:00:00 -0400
+Message-ID: <m-2@sender.example>
+Feedback-ID: winter-promo:customer-7:newsletter:esp
+`
+
+	report, err := Parse(strings.NewReader(sampleReport(feedback, original)))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// The reporter's internal hop is skipped
+	if report.SourceIP != "" || report.SendingIP != "2001:db8::25" {
+		t.Errorf("Expected the sending IP from Received, got source %q sending %q", report.SourceIP, report.SendingIP)
+	}
+	if report.CampaignID != "winter-promo" {
+		t.Errorf("Expected the campaign from Feedback-ID, got %q", report.CampaignID)
+	}
+}
+
+func TestParseEncodedHeadersPart(t *testing.T) {
+	headers := "Received: from out.sender.example ([198.51.100.7]) by mx.mail-provider.example\r\nList-ID: Weekly news <weekly.sender.example>\r\n"
+	report := strings.ReplaceAll(`From: abuse-reports@mail-provider.example
+Content-Type: multipart/report; report-type=feedback-report; boundary="b"
+
+--b
+Content-Type: message/feedback-report
+
+Feedback-Type: fraud
+--b
+Content-Type: text/rfc822-headers
+Content-Transfer-Encoding: base64
+
+`, "\n", "\r\n") + base64.StdEncoding.EncodeToString([]byte(headers)) + "\r\n--b--\r\n"
+
+	parsed, err := Parse(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.FeedbackType != FeedbackFraud || parsed.SendingIP != "198.51.100.7" || parsed.CampaignID != "weekly.sender.example" {
+		t.Errorf("Unexpected report: %+v", parsed)
+	}
+}
+
+func TestParseRejectsNonARF(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"plain message", "From: a@example.com\r\nSubject: hi\r\n\r\nhello\r\n"},
+		{"other report", "Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n\r\n--b\r\nContent-Type: text/plain\r\n\r\nbounced\r\n--b--\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.message)); !errors.Is(err, ErrNotARF) {
+				t.Errorf("Expected ErrNotARF, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Parse(strings.NewReader(sampleReport("User-Agent: x\n", sampleOriginal))); err == nil || errors.Is(err, ErrNotARF) {
+		t.Errorf("Expected a missing Feedback-Type error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// Checks the audit runs
+const (
+	// CheckStaleMetrics finds reputation metrics not refreshed although
+	// failures of the IP arrived since
+	CheckStaleMetrics = "stale_metrics"
+	// CheckMissingBlocks finds unreleased reserved IPs whose IONOS block is
+	// gone or was never stored
+	CheckMissingBlocks = "missing_ionos_blocks"
+	// CheckStaleDNSBL finds IPs whose last DNSBL check is older than the
+	// DNSBL policy allows
+	CheckStaleDNSBL = "stale_dnsbl_checks"
+	// CheckOrphanedActions finds IP actions of IPs the service no longer
+	// knows
+	CheckOrphanedActions = "orphaned_actions"
+)
+
+// Checks lists every check in report order
+var Checks = []string{CheckStaleMetrics, CheckMissingBlocks, CheckStaleDNSBL, CheckOrphanedActions}
+
+// Config sets what the audit counts as stale and when it alerts
+type Config struct {
+	// StaleMetricsAfter is how long metrics may go without an update while
+	// failures arrive
+	StaleMetricsAfter time.Duration
+	// DNSBLMaxAge is the oldest DNSBL check an active IP may have
+	DNSBLMaxAge time.Duration
+	// Thresholds are the issue counts per check above which the audit
+	// alerts; checks without one alert on any issue
+	Thresholds map[string]int
+	// SampleSize is the number of affected IPs reported per check
+	SampleSize int
+	// Retention is how long reports are kept
+	Retention time.Duration
+}
+
+// DefaultConfig flags metrics a day behind, DNSBL checks older than three
+// days, and alerts on any issue
+func DefaultConfig() Config {
+	return Config{
+		StaleMetricsAfter: 24 * time.Hour,
+		DNSBLMaxAge:       72 * time.Hour,
+		Thresholds:        map[string]int{},
+		SampleSize:        20,
+		Retention:         30 * 24 * time.Hour,
+	}
+}
+
+// Validate checks the ages are positive and the thresholds name known checks
+func (c Config) Validate() error {
+	if c.StaleMetricsAfter <= 0 || c.DNSBLMaxAge <= 0 {
+		return fmt.Errorf("stale_metrics_after and dnsbl_max_age must be positive")
+	}
+	if c.SampleSize < 1 {
+		return fmt.Errorf("sample_size must be at least 1")
+	}
+	if c.Retention <= 0 {
+		return fmt.Errorf("retention must be positive")
+	}
+	for name, threshold := range c.Thresholds {
+		if !knownCheck(name) {
+			return fmt.Errorf("threshold for unknown check %q", name)
+		}
+		if threshold < 0 {
+			return fmt.Errorf("threshold of %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func knownCheck(name string) bool {
+	for _, check := range Checks {
+		if check == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newCheck builds the outcome of a check from its finding
+func newCheck(name string, finding database.AuditFinding, threshold int) database.AuditCheck {
+	samples := finding.Samples
+	if samples == nil {
+		samples = []string{}
+	}
+	return database.AuditCheck{
+		Name:      name,
+		Count:     finding.Count,
+		Threshold: threshold,
+		Exceeded:  finding.Count > threshold,
+		Samples:   samples,
+	}
+}
+
+// missingBlocks finds the reserved IPs whose block is not among the blocks
+// IONOS reports
+func missingBlocks(reserved []database.ReservedIPBlock, blockIDs map[string]bool, sampleSize int) database.AuditFinding {
+	finding := database.AuditFinding{Samples: []string{}}
+	for _, r := range reserved {
+		if r.BlockID != "" && blockIDs[r.BlockID] {
+			continue
+		}
+		finding.Count++
+		if len(finding.Samples) < sampleSize {
+			finding.Samples = append(finding.Samples, r.IP)
+		}
+	}
+	return finding
+}
+
+// summarize totals the issues and checks above threshold of a report
+func summarize(report *database.AuditReport) {
+	report.Issues, report.Exceeded = 0, 0
+	for _, check := range report.Checks {
+		report.Issues += check.Count
+		if check.Exceeded {
+			report.Exceeded++
+		}
+	}
+}
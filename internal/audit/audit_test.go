@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/ionos"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryStore is an in-memory Store returning fixed findings
+type memoryStore struct {
+	staleMetrics database.AuditFinding
+	staleDNSBL   database.AuditFinding
+	orphans      database.AuditFinding
+	orphansErr   error
+	reserved     []database.ReservedIPBlock
+	reports      []database.AuditReport
+	cutoffs      map[string]time.Time
+}
+
+func (s *memoryStore) AuditStaleMetrics(cutoff time.Time, sampleSize int) (database.AuditFinding, error) {
+	s.cutoffs[CheckStaleMetrics] = cutoff
+	return s.staleMetrics, nil
+}
+
+func (s *memoryStore) AuditStaleDNSBLChecks(cutoff time.Time, sampleSize int) (database.AuditFinding, error) {
+	s.cutoffs[CheckStaleDNSBL] = cutoff
+	return s.staleDNSBL, nil
+}
+
+func (s *memoryStore) AuditOrphanedActions(sampleSize int) (database.AuditFinding, error) {
+	return s.orphans, s.orphansErr
+}
+
+func (s *memoryStore) ListActiveReservedIPBlocks() ([]database.ReservedIPBlock, error) {
+	return s.reserved, nil
+}
+
+func (s *memoryStore) InsertAuditReport(report *database.AuditReport) error {
+	report.ID = len(s.reports) + 1
+	s.reports = append([]database.AuditReport{*report}, s.reports...)
+	return nil
+}
+
+func (s *memoryStore) GetLatestAuditReport() (*database.AuditReport, error) {
+	if len(s.reports) == 0 {
+		return nil, nil
+	}
+	return &s.reports[0], nil
+}
+
+func (s *memoryStore) ListAuditReports(limit int) ([]database.AuditReport, error) {
+	if limit < len(s.reports) {
+		return s.reports[:limit], nil
+	}
+	return s.reports, nil
+}
+
+func (s *memoryStore) DeleteAuditReportsBefore(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeBlocks lists fixed IONOS block IDs
+type fakeBlocks []string
+
+func (b fakeBlocks) ListIPBlocks(ctx context.Context) (*ionos.IPBlocksResponse, error) {
+	resp := &ionos.IPBlocksResponse{}
+	for _, id := range b {
+		resp.Items = append(resp.Items, ionos.IPBlock{ID: id})
+	}
+	return resp, nil
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"no stale metrics age", func(c *Config) { c.StaleMetricsAfter = 0 }},
+		{"no DNSBL age", func(c *Config) { c.DNSBLMaxAge = 0 }},
+		{"no samples", func(c *Config) { c.SampleSize = 0 }},
+		{"no retention", func(c *Config) { c.Retention = 0 }},
+		{"unknown check", func(c *Config) { c.Thresholds["uptime"] = 1 }},
+		{"negative threshold", func(c *Config) { c.Thresholds[CheckOrphanedActions] = -1 }},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.modify(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", tt.name)
+		}
+	}
+}
+
+func TestMissingBlocks(t *testing.T) {
+	reserved := []database.ReservedIPBlock{
+		{IP: "198.51.100.1", BlockID: "block-1"},
+		{IP: "198.51.100.2", BlockID: "block-2"},
+		{IP: "198.51.100.3"},
+	}
+	finding := missingBlocks(reserved, map[string]bool{"block-1": true}, 1)
+	if finding.Count != 2 || len(finding.Samples) != 1 || finding.Samples[0] != "198.51.100.2" {
+		t.Errorf("Expected the deleted and the unstored block missing, got %+v", finding)
+	}
+}
+
+func TestAuditorRun(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	store := &memoryStore{
+		staleMetrics: database.AuditFinding{Count: 1, Samples: []string{"192.0.2.1"}},
+		staleDNSBL:   database.AuditFinding{Count: 3, Samples: []string{"192.0.2.2", "192.0.2.3", "192.0.2.4"}},
+		orphansErr:   errors.New("connection refused"),
+		reserved:     []database.ReservedIPBlock{{IP: "198.51.100.1", BlockID: "block-1"}, {IP: "198.51.100.2", BlockID: "gone"}},
+		cutoffs:      map[string]time.Time{},
+	}
+	config := DefaultConfig()
+	config.Thresholds[CheckStaleDNSBL] = 5
+	auditor := NewAuditorWithStore(config, store, fakeBlocks{"block-1"}, logger)
+
+	now := time.Now()
+	report, err := auditor.Run(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.ID != 1 || report.Issues != 5 || report.Exceeded != 2 || len(report.Checks) != len(Checks) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	byName := map[string]database.AuditCheck{}
+	for _, check := range report.Checks {
+		byName[check.Name] = check
+	}
+	if c := byName[CheckStaleMetrics]; !c.Exceeded || c.Threshold != 0 {
+		t.Errorf("Expected any stale metrics to exceed the default threshold, got %+v", c)
+	}
+	if c := byName[CheckStaleDNSBL]; c.Exceeded || c.Count != 3 {
+		t.Errorf("Expected 3 stale DNSBL checks within the threshold of 5, got %+v", c)
+	}
+	if c := byName[CheckMissingBlocks]; !c.Exceeded || c.Count != 1 || c.Samples[0] != "198.51.100.2" {
+		t.Errorf("Expected the reservation of the deleted block, got %+v", c)
+	}
+	if c := byName[CheckOrphanedActions]; c.Error == "" || c.Exceeded || c.Samples == nil {
+		t.Errorf("Expected the failed check reported with its error, got %+v", c)
+	}
+	if !store.cutoffs[CheckStaleMetrics].Equal(now.Add(-24*time.Hour)) || !store.cutoffs[CheckStaleDNSBL].Equal(now.Add(-72*time.Hour)) {
+		t.Errorf("Unexpected cutoffs: %v", store.cutoffs)
+	}
+
+	alerted := map[string]bool{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["action"] == "audit_threshold_exceeded" {
+			alerted[entry.Data["check"].(string)] = true
+		}
+	}
+	if len(alerted) != 2 || !alerted[CheckStaleMetrics] || !alerted[CheckMissingBlocks] {
+		t.Errorf("Expected alerts for the checks above threshold, got %v", alerted)
+	}
+
+	if latest, _ := auditor.Latest(); latest == nil || latest.ID != report.ID {
+		t.Errorf("Expected the report stored, got %+v", latest)
+	}
+}
+
+func TestAuditorRunWithoutIONOS(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := &memoryStore{reserved: []database.ReservedIPBlock{{IP: "198.51.100.1"}}, cutoffs: map[string]time.Time{}}
+	report, err := NewAuditorWithStore(DefaultConfig(), store, nil, logger).Run(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if c := report.Checks[1]; c.Name != CheckMissingBlocks || !c.Skipped || c.Count != 0 {
+		t.Errorf("Expected the IONOS block check skipped, got %+v", c)
+	}
+	if report.Issues != 0 || report.Exceeded != 0 {
+		t.Errorf("Expected a clean report, got %+v", report)
+	}
+}
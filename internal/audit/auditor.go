@@ -0,0 +1,303 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/ionos"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// runTimeout bounds one audit, including listing the IONOS blocks
+const runTimeout = 5 * time.Minute
+
+// Prometheus gauges of every check, updated at each run
+var (
+	auditIssues = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "audit_issues",
+			Help: "Inconsistencies found by the latest self-audit, by check",
+		},
+		[]string{"check"},
+	)
+	auditThresholdExceeded = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "audit_threshold_exceeded",
+			Help: "1 while the latest self-audit found more issues than the check's threshold",
+		},
+		[]string{"check"},
+	)
+	auditLastRun = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "audit_last_run_timestamp_seconds",
+			Help: "Unix time the latest self-audit finished",
+		},
+	)
+)
+
+// Store runs the audit queries and persists reports. The default
+// implementation delegates to the database package; tests substitute an
+// in-memory store.
+type Store interface {
+	AuditStaleMetrics(cutoff time.Time, sampleSize int) (database.AuditFinding, error)
+	AuditStaleDNSBLChecks(cutoff time.Time, sampleSize int) (database.AuditFinding, error)
+	AuditOrphanedActions(sampleSize int) (database.AuditFinding, error)
+	ListActiveReservedIPBlocks() ([]database.ReservedIPBlock, error)
+	InsertAuditReport(report *database.AuditReport) error
+	GetLatestAuditReport() (*database.AuditReport, error)
+	ListAuditReports(limit int) ([]database.AuditReport, error)
+	DeleteAuditReportsBefore(cutoff time.Time) (int64, error)
+}
+
+// dbStore is the Store backed by the global database connection
+type dbStore struct{}
+
+func (dbStore) AuditStaleMetrics(cutoff time.Time, sampleSize int) (database.AuditFinding, error) {
+	return database.AuditStaleMetrics(cutoff, sampleSize)
+}
+
+func (dbStore) AuditStaleDNSBLChecks(cutoff time.Time, sampleSize int) (database.AuditFinding, error) {
+	return database.AuditStaleDNSBLChecks(cutoff, sampleSize)
+}
+
+func (dbStore) AuditOrphanedActions(sampleSize int) (database.AuditFinding, error) {
+	return database.AuditOrphanedActions(sampleSize)
+}
+
+func (dbStore) ListActiveReservedIPBlocks() ([]database.ReservedIPBlock, error) {
+	return database.ListActiveReservedIPBlocks()
+}
+
+func (dbStore) InsertAuditReport(report *database.AuditReport) error {
+	return database.InsertAuditReport(report)
+}
+
+func (dbStore) GetLatestAuditReport() (*database.AuditReport, error) {
+	return database.GetLatestAuditReport()
+}
+
+func (dbStore) ListAuditReports(limit int) ([]database.AuditReport, error) {
+	return database.ListAuditReports(limit)
+}
+
+func (dbStore) DeleteAuditReportsBefore(cutoff time.Time) (int64, error) {
+	return database.DeleteAuditReportsBefore(cutoff)
+}
+
+// BlockLister lists the IP blocks reserved in IONOS; *ionos.Client
+// implements it
+type BlockLister interface {
+	ListIPBlocks(ctx context.Context) (*ionos.IPBlocksResponse, error)
+}
+
+// Auditor periodically looks for stale data and inconsistencies and alerts
+// when a check finds more than its threshold
+type Auditor struct {
+	config   Config
+	store    Store
+	blocks   BlockLister
+	logger   *logrus.Logger
+	runMu    sync.Mutex
+	ticker   *time.Ticker
+	stopChan chan bool
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewAuditor creates an auditor storing reports in the database. A nil
+// blocks skips the IONOS block check.
+func NewAuditor(config Config, blocks BlockLister, logger *logrus.Logger) *Auditor {
+	return NewAuditorWithStore(config, dbStore{}, blocks, logger)
+}
+
+// NewAuditorWithStore creates an auditor backed by store
+func NewAuditorWithStore(config Config, store Store, blocks BlockLister, logger *logrus.Logger) *Auditor {
+	return &Auditor{config: config, store: store, blocks: blocks, logger: logger, stopChan: make(chan bool)}
+}
+
+// Start audits every interval. The first run is one interval after start,
+// so restarts do not audit again.
+func (a *Auditor) Start(interval time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running {
+		return fmt.Errorf("auditor is already running")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("auditor requires a positive interval")
+	}
+
+	a.ticker = time.NewTicker(interval)
+	a.running = true
+
+	a.logger.WithFields(logrus.Fields{
+		"action":   "audit_start",
+		"interval": interval.String(),
+	}).Info("Starting scheduled self-audit")
+
+	go func() {
+		for {
+			select {
+			case <-a.ticker.C:
+				a.RunOnce()
+			case <-a.stopChan:
+				a.logger.Info("Scheduled self-audit stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the auditor
+func (a *Auditor) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.running {
+		return
+	}
+
+	if a.ticker != nil {
+		a.ticker.Stop()
+	}
+
+	a.stopChan <- true
+	a.running = false
+}
+
+// RunOnce audits now, logging failures
+func (a *Auditor) RunOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+	if _, err := a.Run(ctx, time.Now()); err != nil {
+		a.logger.WithFields(logrus.Fields{
+			"action": "audit_failed",
+			"error":  err.Error(),
+		}).Error("Failed to run self-audit")
+	}
+}
+
+// Run runs every check at now, stores the report and alerts on the checks
+// above their threshold. A failing check is reported with its error and
+// does not stop the others. Runs do not overlap.
+func (a *Auditor) Run(ctx context.Context, now time.Time) (*database.AuditReport, error) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	report := &database.AuditReport{StartedAt: now}
+	for _, name := range Checks {
+		check := a.runCheck(ctx, name, now)
+		if check.Error != "" {
+			a.logger.WithFields(logrus.Fields{
+				"action": "audit_check_failed",
+				"check":  name,
+				"error":  check.Error,
+			}).Error("Self-audit check failed")
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	summarize(report)
+	report.FinishedAt = time.Now()
+
+	if err := a.store.InsertAuditReport(report); err != nil {
+		return nil, err
+	}
+
+	for _, check := range report.Checks {
+		auditIssues.WithLabelValues(check.Name).Set(float64(check.Count))
+		exceeded := 0.0
+		if check.Exceeded {
+			exceeded = 1
+			a.logger.WithFields(logrus.Fields{
+				"action":    "audit_threshold_exceeded",
+				"check":     check.Name,
+				"count":     check.Count,
+				"threshold": check.Threshold,
+				"samples":   check.Samples,
+				"report_id": report.ID,
+			}).Warn("Self-audit found more issues than the threshold")
+		}
+		auditThresholdExceeded.WithLabelValues(check.Name).Set(exceeded)
+	}
+	auditLastRun.Set(float64(report.FinishedAt.Unix()))
+
+	a.logger.WithFields(logrus.Fields{
+		"action":    "audit_completed",
+		"report_id": report.ID,
+		"issues":    report.Issues,
+		"exceeded":  report.Exceeded,
+	}).Info("Self-audit completed")
+
+	if _, err := a.store.DeleteAuditReportsBefore(now.Add(-a.config.Retention)); err != nil {
+		a.logger.WithFields(logrus.Fields{
+			"action": "audit_prune_failed",
+			"error":  err.Error(),
+		}).Warn("Failed to delete old audit reports")
+	}
+	return report, nil
+}
+
+func (a *Auditor) runCheck(ctx context.Context, name string, now time.Time) database.AuditCheck {
+	threshold := a.config.Thresholds[name]
+	var finding database.AuditFinding
+	var err error
+	switch name {
+	case CheckStaleMetrics:
+		finding, err = a.store.AuditStaleMetrics(now.Add(-a.config.StaleMetricsAfter), a.config.SampleSize)
+	case CheckStaleDNSBL:
+		finding, err = a.store.AuditStaleDNSBLChecks(now.Add(-a.config.DNSBLMaxAge), a.config.SampleSize)
+	case CheckOrphanedActions:
+		finding, err = a.store.AuditOrphanedActions(a.config.SampleSize)
+	case CheckMissingBlocks:
+		if a.blocks == nil {
+			check := newCheck(name, finding, threshold)
+			check.Skipped = true
+			return check
+		}
+		finding, err = a.findMissingBlocks(ctx)
+	}
+	if err != nil {
+		check := newCheck(name, database.AuditFinding{}, threshold)
+		check.Error = err.Error()
+		return check
+	}
+	return newCheck(name, finding, threshold)
+}
+
+func (a *Auditor) findMissingBlocks(ctx context.Context) (database.AuditFinding, error) {
+	reserved, err := a.store.ListActiveReservedIPBlocks()
+	if err != nil {
+		return database.AuditFinding{}, err
+	}
+	if len(reserved) == 0 {
+		return database.AuditFinding{}, nil
+	}
+	blocks, err := a.blocks.ListIPBlocks(ctx)
+	if err != nil {
+		return database.AuditFinding{}, fmt.Errorf("failed to list IONOS blocks: %w", err)
+	}
+	blockIDs := make(map[string]bool, len(blocks.Items))
+	for _, block := range blocks.Items {
+		blockIDs[block.ID] = true
+	}
+	return missingBlocks(reserved, blockIDs, a.config.SampleSize), nil
+}
+
+// Latest returns the newest report, nil if the audit never ran
+func (a *Auditor) Latest() (*database.AuditReport, error) {
+	return a.store.GetLatestAuditReport()
+}
+
+// Reports returns the newest reports first
+func (a *Auditor) Reports(limit int) ([]database.AuditReport, error) {
+	return a.store.ListAuditReports(limit)
+}
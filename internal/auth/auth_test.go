@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestIssueAndVerify(t *testing.T) {
+	issuer, err := NewTokenIssuer(testSecret, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+
+	token, expiresAt, err := issuer.Issue(42, "alice", RoleOperator)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if time.Until(expiresAt) > 15*time.Minute {
+		t.Errorf("Unexpected expiry %v", expiresAt)
+	}
+
+	claims, err := issuer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if id, _ := claims.UserID(); id != 42 || claims.Username != "alice" || claims.Role != RoleOperator {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	issuer, _ := NewTokenIssuer(testSecret, time.Minute)
+	other, _ := NewTokenIssuer([]byte("ffffffffffffffffffffffffffffffff"), time.Minute)
+	token, _, _ := issuer.Issue(1, "alice", RoleOperator)
+	foreign, _, _ := other.Issue(1, "alice", RoleOperator)
+
+	expiredIssuer, _ := NewTokenIssuer(testSecret, time.Minute)
+	expiredIssuer.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expired, _, _ := expiredIssuer.Issue(1, "alice", RoleOperator)
+
+	for name, bad := range map[string]string{
+		"garbage":      "not.a.jwt",
+		"wrong key":    foreign,
+		"expired":      expired,
+		"tampered":     token[:len(token)-2] + "xx",
+		"alg none":     "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiIxIn0.",
+		"empty string": "",
+	} {
+		if _, err := issuer.Verify(bad); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestNewTokenIssuerValidates(t *testing.T) {
+	if _, err := NewTokenIssuer([]byte("short"), time.Minute); err == nil {
+		t.Error("Expected error for short secret")
+	}
+	if _, err := NewTokenIssuer(testSecret, 0); err == nil {
+		t.Error("Expected error for zero TTL")
+	}
+}
+
+func TestPasswords(t *testing.T) {
+	if _, err := HashPassword("short"); err == nil {
+		t.Error("Expected error for short password")
+	}
+	if _, err := HashPassword(strings.Repeat("a", 73)); err == nil {
+		t.Error("Expected error for password over 72 bytes")
+	}
+
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !CheckPassword(hash, "correct horse battery") {
+		t.Error("Expected password to match")
+	}
+	if CheckPassword(hash, "wrong horse battery") {
+		t.Error("Expected wrong password to fail")
+	}
+	if CheckPassword("", "correct horse battery") {
+		t.Error("Expected empty hash to never match")
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("ci:0123456789abcdef, monitoring:fedcba9876543210")
+	if err != nil {
+		t.Fatalf("ParseAPIKeys failed: %v", err)
+	}
+	if keys.Len() != 2 {
+		t.Errorf("Expected 2 keys, got %d", keys.Len())
+	}
+	if name, ok := keys.Lookup("fedcba9876543210"); !ok || name != "monitoring" {
+		t.Errorf("Expected monitoring key, got %q %v", name, ok)
+	}
+	if _, ok := keys.Lookup("unknown-key-00000"); ok {
+		t.Error("Expected unknown key to fail")
+	}
+
+	for _, bad := range []string{"nokey", ":abc", "ci:short"} {
+		if _, err := ParseAPIKeys(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	keys, err := ParseAPIKeys("ci:0123456789abcdef,grafana:fedcba9876543210:read-only,odd:0123456789:abcdef")
+	if err != nil {
+		t.Fatalf("ParseAPIKeys failed: %v", err)
+	}
+	if keys.Role("ci") != RoleAdmin {
+		t.Errorf("Expected keys without a role to be admin, got %q", keys.Role("ci"))
+	}
+	if name, ok := keys.Lookup("fedcba9876543210"); !ok || keys.Role(name) != RoleReadOnly {
+		t.Errorf("Expected the read-only grafana key, got %q %v", name, ok)
+	}
+	if _, ok := keys.Lookup("0123456789:abcdef"); !ok {
+		t.Error("Expected a colon not followed by a role to be part of the key")
+	}
+}
+
+func TestRoles(t *testing.T) {
+	if !RoleAdmin.Allows(RoleOperator) || !RoleOperator.Allows(RoleOperator) || RoleReadOnly.Allows(RoleOperator) {
+		t.Error("Expected roles to include the ones below them only")
+	}
+	if Role("").Allows(RoleReadOnly) {
+		t.Error("Expected an unknown role to allow nothing")
+	}
+	if _, err := ParseRole("superuser"); err == nil {
+		t.Error("Expected unknown role to be rejected")
+	}
+	var nobody *Principal
+	if nobody.Allows(RoleReadOnly) {
+		t.Error("Expected a nil principal to allow nothing")
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// Principal kinds
+const (
+	KindUser   = "user"
+	KindAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request: an operator with a
+// dashboard session or a machine using an API key
+type Principal struct {
+	Kind     string `json:"kind"`
+	UserID   int    `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	KeyName  string `json:"key_name,omitempty"`
+	Role     Role   `json:"role"`
+}
+
+// Name identifies the principal in logs and audit trails
+func (p *Principal) Name() string {
+	if p.Kind == KindAPIKey {
+		return "api_key:" + p.KeyName
+	}
+	return "user:" + p.Username
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the request's principal, if any
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+// Allows reports whether the principal has at least the required role
+func (p *Principal) Allows(required Role) bool {
+	return p != nil && p.Role.Allows(required)
+}
+
+// APIKeys is a static set of named machine keys. Only key hashes are kept in
+// memory after parsing.
+type APIKeys struct {
+	hashes map[string]string // hash -> name
+	roles  map[string]Role   // name -> role
+}
+
+// ParseAPIKeys parses keys written as "ci:secret1,monitoring:secret2". A key
+// may end in ":<role>" to restrict it, e.g. "grafana:secret3:read-only";
+// other keys are admin.
+func ParseAPIKeys(s string) (*APIKeys, error) {
+	keys := &APIKeys{hashes: make(map[string]string), roles: make(map[string]Role)}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("invalid API key %q, expected name:key", name)
+		}
+		role := RoleAdmin
+		if i := strings.LastIndex(key, ":"); i >= 0 {
+			if parsed, err := ParseRole(key[i+1:]); err == nil {
+				key, role = key[:i], parsed
+			}
+		}
+		if len(key) < 16 {
+			return nil, fmt.Errorf("API key %s must be at least 16 characters", name)
+		}
+		keys.hashes[HashToken(key)] = name
+		keys.roles[name] = role
+	}
+	return keys, nil
+}
+
+// Lookup returns the name of a key, comparing hashes in constant time
+func (k *APIKeys) Lookup(key string) (string, bool) {
+	if k == nil || key == "" {
+		return "", false
+	}
+	hash := HashToken(key)
+	for stored, name := range k.hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Role returns the role of a named key
+func (k *APIKeys) Role(name string) Role {
+	if k == nil {
+		return ""
+	}
+	return k.roles[name]
+}
+
+// Len returns the number of configured keys
+func (k *APIKeys) Len() int {
+	if k == nil {
+		return 0
+	}
+	return len(k.hashes)
+}
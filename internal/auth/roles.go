@@ -0,0 +1,42 @@
+package auth
+
+import "fmt"
+
+// Role is what a principal may do. Each role includes the ones below it.
+type Role string
+
+// Roles, from least to most privileged
+const (
+	// RoleReadOnly may read everything except secrets and backups
+	RoleReadOnly Role = "read-only"
+	// RoleOperator may also act on IPs: reserve, quarantine, archive,
+	// annotate and report
+	RoleOperator Role = "operator"
+	// RoleAdmin may also manage users, API keys and system settings
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Roles lists the valid roles from least to most privileged
+var Roles = []Role{RoleReadOnly, RoleOperator, RoleAdmin}
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, expected read-only, operator or admin", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role includes required. Unknown roles allow
+// nothing.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
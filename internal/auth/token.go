@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// refreshTokenPrefix makes leaked refresh tokens easy to recognise
+const refreshTokenPrefix = "rt_"
+
+// APIKeyPrefix starts every API key created through the API, so leaked keys
+// are easy to recognise and only such keys are looked up in the database
+const APIKeyPrefix = "ak_"
+
+// ErrInvalidToken is returned for access tokens that are malformed, expired
+// or signed with another key
+var ErrInvalidToken = errors.New("invalid access token")
+
+// Claims are the claims carried by dashboard access tokens
+type Claims struct {
+	Username string `json:"username"`
+	Role     Role   `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user ID from the subject claim
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// TokenIssuer signs and verifies short-lived HS256 access tokens
+type TokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	issuer string
+	now    func() time.Time
+}
+
+// NewTokenIssuer creates an issuer for access tokens valid for ttl
+func NewTokenIssuer(secret []byte, ttl time.Duration) (*TokenIssuer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("JWT secret must be at least 32 bytes")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("access token TTL must be positive")
+	}
+	return &TokenIssuer{
+		secret: secret,
+		ttl:    ttl,
+		issuer: "golang-backend-service",
+		now:    time.Now,
+	}, nil
+}
+
+// TTL returns how long issued access tokens are valid
+func (i *TokenIssuer) TTL() time.Duration {
+	return i.ttl
+}
+
+// Issue returns a signed access token for a user and its expiry. The role is
+// fixed for the token's lifetime; a changed role applies from the next
+// refresh.
+func (i *TokenIssuer) Issue(userID int, username string, role Role) (string, time.Time, error) {
+	now := i.now()
+	expiresAt := now.Add(i.ttl)
+
+	claims := Claims{
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Verify parses an access token and returns its claims
+func (i *TokenIssuer) Verify(token string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return i.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(i.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(i.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// NewRefreshToken returns a random refresh token and the hash to store
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = refreshTokenPrefix + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// NewAPIKey returns a random API key and the hash to store
+func NewAPIKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, HashToken(key), nil
+}
+
+// HashToken returns the hex SHA-256 of an opaque token as stored in the
+// database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,305 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Driver names accepted in the bus configuration
+const (
+	DriverNATS = "nats"
+)
+
+const (
+	defaultBatchSize = 100
+	maxFetchBackoff  = 30 * time.Second
+)
+
+var (
+	messagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bus_messages_total",
+			Help: "Total number of message bus messages by outcome (acked, redelivered, rejected)",
+		},
+		[]string{"consumer", "result"},
+	)
+
+	consumerLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bus_consumer_lag",
+			Help: "Messages waiting in the stream for the consumer, as of the last fetched message",
+		},
+		[]string{"consumer"},
+	)
+
+	fetchErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bus_fetch_errors_total",
+			Help: "Total number of failed message bus fetches",
+		},
+		[]string{"consumer"},
+	)
+)
+
+// Message is one message fetched from the bus. It must be acknowledged with
+// Ack once handled, or Nak to have it redelivered.
+type Message struct {
+	Data []byte
+	// Pending is how many messages the stream still holds for the consumer
+	// after this one
+	Pending uint64
+	ack     func(ack bool) error
+}
+
+// NewMessage builds a message whose Ack and Nak call ack with true and false
+func NewMessage(data []byte, pending uint64, ack func(ack bool) error) Message {
+	return Message{Data: data, Pending: pending, ack: ack}
+}
+
+// Ack marks the message as handled
+func (m Message) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack(true)
+}
+
+// Nak asks for the message to be redelivered
+func (m Message) Nak() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack(false)
+}
+
+// Consumer pulls messages from a durable subscription. Messages that are
+// neither acked nor naked are redelivered by the broker, so delivery is at
+// least once and handlers must be idempotent.
+type Consumer interface {
+	// Fetch waits up to the context deadline for at most batch messages. It
+	// returns no messages and no error when none arrived in time.
+	Fetch(ctx context.Context, batch int) ([]Message, error)
+	Close() error
+}
+
+// permanentError marks a handler error a redelivery cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the runner acks the message instead of having it
+// redelivered
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Handler processes the body of one message
+type Handler func(data []byte) error
+
+// Runner feeds messages of a Consumer to a Handler and acknowledges them
+type Runner struct {
+	name      string
+	consumer  Consumer
+	handle    Handler
+	batch     int
+	fetchWait time.Duration
+	logger    *logrus.Logger
+
+	mu       sync.Mutex
+	running  bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	acked    int64
+	naked    int64
+	rejected int64
+	errors   int
+	lastLag  uint64
+}
+
+// NewRunner creates a runner. name labels the metrics; batch is the fetch
+// size and fetchWait how long one fetch waits for messages.
+func NewRunner(name string, consumer Consumer, handle Handler, batch int, fetchWait time.Duration, logger *logrus.Logger) *Runner {
+	if batch <= 0 {
+		batch = defaultBatchSize
+	}
+	if fetchWait <= 0 {
+		fetchWait = 5 * time.Second
+	}
+	return &Runner{
+		name:      name,
+		consumer:  consumer,
+		handle:    handle,
+		batch:     batch,
+		fetchWait: fetchWait,
+		logger:    logger,
+	}
+}
+
+// Start begins consuming in the background
+func (r *Runner) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return fmt.Errorf("bus consumer %s is already running", r.name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.running = true
+
+	r.logger.WithFields(logrus.Fields{
+		"action":   "bus_consumer_start",
+		"consumer": r.name,
+		"batch":    r.batch,
+	}).Info("Starting message bus consumer")
+
+	go r.run(ctx)
+	return nil
+}
+
+// Stop finishes the current batch, stops consuming and closes the consumer
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.cancel()
+	done := r.done
+	r.running = false
+	r.mu.Unlock()
+
+	<-done
+	if err := r.consumer.Close(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"action":   "bus_consumer_close_failed",
+			"consumer": r.name,
+			"error":    err.Error(),
+		}).Warn("Failed to close message bus consumer")
+	}
+	r.logger.WithField("consumer", r.name).Info("Message bus consumer stopped")
+}
+
+func (r *Runner) run(ctx context.Context) {
+	defer close(r.done)
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, r.fetchWait)
+		messages, err := r.consumer.Fetch(fetchCtx, r.batch)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fetchErrorsTotal.WithLabelValues(r.name).Inc()
+			r.mu.Lock()
+			r.errors++
+			r.mu.Unlock()
+			r.logger.WithFields(logrus.Fields{
+				"action":   "bus_fetch_failed",
+				"consumer": r.name,
+				"retry_in": backoff.String(),
+				"error":    err.Error(),
+			}).Warn("Failed to fetch from message bus")
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > maxFetchBackoff {
+				backoff = maxFetchBackoff
+			}
+			continue
+		}
+		backoff = time.Second
+
+		r.Process(messages)
+	}
+}
+
+// Process handles and acknowledges fetched messages. Failed messages are
+// naked for redelivery unless the handler marked the error Permanent.
+func (r *Runner) Process(messages []Message) {
+	for _, msg := range messages {
+		result := "acked"
+		err := r.handle(msg.Data)
+		switch {
+		case err == nil:
+			err = msg.Ack()
+		case IsPermanent(err):
+			result = "rejected"
+			r.logger.WithFields(logrus.Fields{
+				"action":   "bus_message_rejected",
+				"consumer": r.name,
+				"error":    err.Error(),
+			}).Warn("Dropping message bus message that cannot be processed")
+			err = msg.Ack()
+		default:
+			result = "redelivered"
+			r.logger.WithFields(logrus.Fields{
+				"action":   "bus_message_failed",
+				"consumer": r.name,
+				"error":    err.Error(),
+			}).Warn("Message bus message failed, requesting redelivery")
+			err = msg.Nak()
+		}
+		if err != nil {
+			r.logger.WithFields(logrus.Fields{
+				"action":   "bus_ack_failed",
+				"consumer": r.name,
+				"error":    err.Error(),
+			}).Warn("Failed to acknowledge message bus message; the broker will redeliver it")
+		}
+
+		messagesTotal.WithLabelValues(r.name, result).Inc()
+		consumerLag.WithLabelValues(r.name).Set(float64(msg.Pending))
+
+		r.mu.Lock()
+		switch result {
+		case "acked":
+			r.acked++
+		case "rejected":
+			r.rejected++
+		default:
+			r.naked++
+		}
+		r.lastLag = msg.Pending
+		r.mu.Unlock()
+	}
+}
+
+// GetStats returns consumer statistics
+func (r *Runner) GetStats() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return map[string]interface{}{
+		"consumer":     r.name,
+		"running":      r.running,
+		"acked":        r.acked,
+		"redelivered":  r.naked,
+		"rejected":     r.rejected,
+		"fetch_errors": r.errors,
+		"lag":          r.lastLag,
+	}
+}
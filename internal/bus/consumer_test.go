@@ -0,0 +1,62 @@
+package bus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRunnerProcessAcknowledges(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+
+	var outcomes []string
+	message := func(body string, pending uint64) Message {
+		return NewMessage([]byte(body), pending, func(ack bool) error {
+			if ack {
+				outcomes = append(outcomes, body+":ack")
+			} else {
+				outcomes = append(outcomes, body+":nak")
+			}
+			return nil
+		})
+	}
+
+	runner := NewRunner("test", nil, func(data []byte) error {
+		switch string(data) {
+		case "bad":
+			return Permanent(errors.New("not JSON"))
+		case "retry":
+			return errors.New("database unavailable")
+		}
+		return nil
+	}, 0, 0, logger)
+
+	runner.Process([]Message{message("ok", 3), message("bad", 2), message("retry", 1)})
+
+	want := []string{"ok:ack", "bad:ack", "retry:nak"}
+	if len(outcomes) != len(want) {
+		t.Fatalf("outcomes = %v, want %v", outcomes, want)
+	}
+	for i := range want {
+		if outcomes[i] != want[i] {
+			t.Errorf("outcomes = %v, want %v", outcomes, want)
+			break
+		}
+	}
+
+	stats := runner.GetStats()
+	if stats["acked"] != int64(1) || stats["rejected"] != int64(1) || stats["redelivered"] != int64(1) || stats["lag"] != uint64(1) {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := Permanent(errors.New("bad"))
+	if !IsPermanent(err) || !IsPermanent(errors.Join(errors.New("wrapped"), err)) {
+		t.Error("Expected permanent errors to be recognised")
+	}
+	if IsPermanent(errors.New("transient")) {
+		t.Error("Expected plain errors to be transient")
+	}
+}
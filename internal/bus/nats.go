@@ -0,0 +1,435 @@
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDefaultPort = "4222"
+	natsDialTimeout = 5 * time.Second
+	// natsExpiryMargin is how much earlier than the fetch deadline the server
+	// is asked to give up, so its 408 arrives before the read times out
+	natsExpiryMargin = 100 * time.Millisecond
+	natsMaxLine      = 4096
+)
+
+// NATSConfig configures a NATS JetStream pull consumer
+type NATSConfig struct {
+	// URL is nats://[user:pass@]host[:port], or tls:// for TLS. A user
+	// without a password is sent as an auth token.
+	URL string
+	// Stream and Consumer name a durable pull consumer with explicit acks
+	Stream   string
+	Consumer string
+	// Name identifies this client in server monitoring
+	Name string
+}
+
+// NATSConsumer fetches messages from a JetStream durable pull consumer. It
+// speaks the NATS text protocol directly and reconnects on the next fetch
+// after a connection error.
+type NATSConsumer struct {
+	config NATSConfig
+	addr   string
+	tls    bool
+	user   string
+	pass   string
+	token  string
+	inbox  string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+// NewNATSConsumer validates the configuration. The connection is opened on
+// the first fetch.
+func NewNATSConsumer(config NATSConfig) (*NATSConsumer, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	c := &NATSConsumer{config: config}
+	switch u.Scheme {
+	case "nats":
+	case "tls":
+		c.tls = true
+	default:
+		return nil, fmt.Errorf("invalid NATS URL: scheme must be nats or tls")
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL: missing host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	c.addr = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			c.user, c.pass = u.User.Username(), pass
+		} else {
+			c.token = u.User.Username()
+		}
+	}
+
+	for name, value := range map[string]string{"stream": config.Stream, "consumer": config.Consumer} {
+		if value == "" || strings.ContainsAny(value, " \t\r\n.*>") {
+			return nil, fmt.Errorf("invalid NATS %s name %q", name, value)
+		}
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate NATS inbox: %w", err)
+	}
+	c.inbox = "_INBOX." + hex.EncodeToString(id[:])
+	return c, nil
+}
+
+// Fetch requests a batch from the pull consumer and waits for it until the
+// server reports no more messages or the context deadline passes
+func (c *NATSConsumer) Fetch(ctx context.Context, batch int) ([]Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connectLocked(); err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	expires := time.Until(deadline) - natsExpiryMargin
+	if expires < time.Millisecond {
+		expires = time.Millisecond
+	}
+
+	c.seq++
+	reply := c.inbox + "." + strconv.Itoa(c.seq)
+	request, _ := json.Marshal(map[string]int64{"batch": int64(batch), "expires": expires.Nanoseconds()})
+	subject := "$JS.API.CONSUMER.MSG.NEXT." + c.config.Stream + "." + c.config.Consumer
+	if err := c.publishLocked(subject, reply, request); err != nil {
+		c.resetLocked()
+		return nil, err
+	}
+
+	conn := c.conn
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	messages := []Message{}
+	for len(messages) < batch {
+		msg, err := c.readMessageLocked()
+		if err != nil {
+			// Anything but a clean timeout leaves the stream unusable, and a
+			// timeout may have cut a frame in half
+			c.resetLocked()
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return messages, nil
+			}
+			if len(messages) > 0 {
+				return messages, nil
+			}
+			return nil, err
+		}
+		if msg.subject != reply {
+			// A late reply to an earlier fetch; the server redelivers it
+			continue
+		}
+		if msg.status != "" {
+			switch msg.status[:3] {
+			case "100":
+				continue
+			case "404", "408":
+				return messages, nil
+			default:
+				if len(messages) > 0 {
+					return messages, nil
+				}
+				return nil, fmt.Errorf("jetstream fetch failed: %s", msg.status)
+			}
+		}
+		if !strings.HasPrefix(msg.reply, "$JS.ACK.") {
+			continue
+		}
+		messages = append(messages, NewMessage(msg.data, ackPending(msg.reply), c.acker(msg.reply)))
+	}
+	return messages, nil
+}
+
+// Close closes the connection
+func (c *NATSConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.reader = nil, nil
+	return err
+}
+
+func (c *NATSConsumer) acker(subject string) func(bool) error {
+	return func(ack bool) error {
+		body := []byte("+ACK")
+		if !ack {
+			body = []byte("-NAK")
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err := c.connectLocked(); err != nil {
+			return err
+		}
+		if err := c.publishLocked(subject, "", body); err != nil {
+			c.resetLocked()
+			return err
+		}
+		return nil
+	}
+}
+
+// ackPending reads the pending count from a JetStream ack subject, in either
+// the $JS.ACK.<stream>.<consumer>.<delivered>.<sseq>.<cseq>.<ts>.<pending>
+// form or the newer one prefixed with domain and account hash
+func ackPending(subject string) uint64 {
+	tokens := strings.Split(subject, ".")
+	index := -1
+	switch {
+	case len(tokens) == 9:
+		index = 8
+	case len(tokens) >= 11:
+		index = 10
+	}
+	if index < 0 {
+		return 0
+	}
+	pending, _ := strconv.ParseUint(tokens[index], 10, 64)
+	return pending
+}
+
+func (c *NATSConsumer) connectLocked() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: natsDialTimeout}
+	conn, err := dialer.Dial("tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(natsDialTimeout))
+	reader := bufio.NewReaderSize(conn, 32*1024)
+
+	line, err := readLine(reader)
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("failed to read NATS server info: %v", err)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	if c.tls {
+		host, _, _ := net.SplitHostPort(c.addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return fmt.Errorf("NATS TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReaderSize(conn, 32*1024)
+	} else if info.TLSRequired {
+		conn.Close()
+		return fmt.Errorf("NATS server requires TLS; use a tls:// URL")
+	}
+
+	options := map[string]interface{}{
+		"verbose":       false,
+		"pedantic":      false,
+		"lang":          "go",
+		"version":       "1.0.0",
+		"protocol":      1,
+		"headers":       true,
+		"no_responders": true,
+		"name":          c.config.Name,
+	}
+	if c.user != "" {
+		options["user"], options["pass"] = c.user, c.pass
+	}
+	if c.token != "" {
+		options["auth_token"] = c.token
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS CONNECT: %w", err)
+	}
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to complete NATS handshake: %w", err)
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return fmt.Errorf("NATS server refused connection: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		if line == "PONG" {
+			break
+		}
+	}
+	if _, err := fmt.Fprintf(conn, "SUB %s.* 1\r\n", c.inbox); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to subscribe to NATS inbox: %w", err)
+	}
+
+	conn.SetDeadline(time.Time{})
+	c.conn, c.reader = conn, reader
+	return nil
+}
+
+func (c *NATSConsumer) resetLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn, c.reader = nil, nil
+}
+
+func (c *NATSConsumer) publishLocked(subject, reply string, body []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString("PUB ")
+	buf.WriteString(subject)
+	if reply != "" {
+		buf.WriteString(" ")
+		buf.WriteString(reply)
+	}
+	fmt.Fprintf(&buf, " %d\r\n", len(body))
+	buf.Write(body)
+	buf.WriteString("\r\n")
+
+	c.conn.SetWriteDeadline(time.Now().Add(natsDialTimeout))
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	return nil
+}
+
+// natsMessage is a delivered MSG or HMSG. status is the status line of a
+// JetStream control message, such as "404 No Messages".
+type natsMessage struct {
+	subject string
+	reply   string
+	status  string
+	data    []byte
+}
+
+// readMessageLocked reads the next delivered message, answering pings and
+// skipping other protocol lines
+func (c *NATSConsumer) readMessageLocked() (natsMessage, error) {
+	for {
+		line, err := readLine(c.reader)
+		if err != nil {
+			return natsMessage{}, err
+		}
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(c.conn, "PONG\r\n"); err != nil {
+				return natsMessage{}, err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return natsMessage{}, fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			return c.readPayloadLocked(line)
+		}
+	}
+}
+
+func (c *NATSConsumer) readPayloadLocked(line string) (natsMessage, error) {
+	fields := strings.Fields(line)
+	headers := fields[0] == "HMSG"
+
+	// MSG <subject> <sid> [reply] <size>
+	// HMSG <subject> <sid> [reply] <header size> <total size>
+	sizes := 1
+	if headers {
+		sizes = 2
+	}
+	if len(fields) != 3+sizes && len(fields) != 4+sizes {
+		return natsMessage{}, fmt.Errorf("malformed NATS message line %q", line)
+	}
+	msg := natsMessage{subject: fields[1]}
+	if len(fields) == 4+sizes {
+		msg.reply = fields[3]
+	}
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return natsMessage{}, fmt.Errorf("malformed NATS message size in %q", line)
+	}
+	headerSize := 0
+	if headers {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err != nil || headerSize < 0 || headerSize > total {
+			return natsMessage{}, fmt.Errorf("malformed NATS header size in %q", line)
+		}
+	}
+
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return natsMessage{}, err
+	}
+	payload = payload[:total]
+	if headers {
+		msg.status = headerStatus(payload[:headerSize])
+	}
+	msg.data = payload[headerSize:]
+	return msg, nil
+}
+
+// headerStatus returns the status of a "NATS/1.0 404 No Messages" header
+// block, or "" for plain headers
+func headerStatus(header []byte) string {
+	first, _, _ := bytes.Cut(header, []byte("\r\n"))
+	status := strings.TrimSpace(strings.TrimPrefix(string(first), "NATS/1.0"))
+	if len(status) < 3 {
+		return ""
+	}
+	return status
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > natsMaxLine {
+			return "", fmt.Errorf("NATS protocol line too long")
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJetStream is a minimal NATS server that answers pull requests with
+// queued messages followed by a 404 status
+type fakeJetStream struct {
+	listener net.Listener
+	messages []string
+
+	mu   sync.Mutex
+	acks []string
+}
+
+func newFakeJetStream(t *testing.T, messages ...string) *fakeJetStream {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on localhost: %v", err)
+	}
+	s := &fakeJetStream{listener: listener, messages: messages}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeJetStream) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeJetStream) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	io.WriteString(conn, "INFO {\"server_id\":\"fake\",\"headers\":true}\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			body := make([]byte, size+2)
+			io.ReadFull(reader, body)
+			body = body[:size]
+
+			subject := fields[1]
+			if strings.HasPrefix(subject, "$JS.ACK.") {
+				s.mu.Lock()
+				s.acks = append(s.acks, string(body)+" "+subject)
+				s.mu.Unlock()
+				continue
+			}
+			reply := fields[2]
+			for i, msg := range s.messages {
+				pending := len(s.messages) - i - 1
+				ack := fmt.Sprintf("$JS.ACK.EVENTS.app.1.%d.%d.1700000000000000000.%d", i+1, i+1, pending)
+				if i%2 == 0 {
+					fmt.Fprintf(conn, "MSG %s 1 %s %d\r\n%s\r\n", reply, ack, len(msg), msg)
+				} else {
+					header := "NATS/1.0\r\nNats-Msg-Id: x\r\n\r\n"
+					fmt.Fprintf(conn, "HMSG %s 1 %s %d %d\r\n%s%s\r\n", reply, ack, len(header), len(header)+len(msg), header, msg)
+				}
+			}
+			s.messages = nil
+			status := "NATS/1.0 404 No Messages\r\n\r\n"
+			fmt.Fprintf(conn, "HMSG %s 1 %d %d\r\n%s\r\n", reply, len(status), len(status), status)
+		}
+	}
+}
+
+func (s *fakeJetStream) ackList() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.acks...)
+}
+
+func TestNATSConsumerFetchAndAck(t *testing.T) {
+	server := newFakeJetStream(t, `{"id":"e1"}`, `{"id":"e2"}`)
+	consumer, err := NewNATSConsumer(NATSConfig{URL: "nats://" + server.listener.Addr().String(), Stream: "EVENTS", Consumer: "app"})
+	if err != nil {
+		t.Fatalf("NewNATSConsumer() error = %v", err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	messages, err := consumer.Fetch(ctx, 10)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(messages) != 2 || string(messages[0].Data) != `{"id":"e1"}` || string(messages[1].Data) != `{"id":"e2"}` {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+	if messages[0].Pending != 1 || messages[1].Pending != 0 {
+		t.Errorf("Pending = %d, %d; want 1, 0", messages[0].Pending, messages[1].Pending)
+	}
+
+	messages[0].Ack()
+	messages[1].Nak()
+	deadline := time.Now().Add(time.Second)
+	for len(server.ackList()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	acks := server.ackList()
+	if len(acks) != 2 || !strings.HasPrefix(acks[0], "+ACK $JS.ACK.EVENTS.app.1.1.") || !strings.HasPrefix(acks[1], "-NAK $JS.ACK.EVENTS.app.1.2.") {
+		t.Errorf("Unexpected acks: %v", acks)
+	}
+
+	// The stream is drained now; the 404 ends the fetch without messages
+	messages, err = consumer.Fetch(ctx, 10)
+	if err != nil || len(messages) != 0 {
+		t.Errorf("Fetch() on empty stream = %d messages, %v", len(messages), err)
+	}
+}
+
+func TestNewNATSConsumerValidates(t *testing.T) {
+	tests := []NATSConfig{
+		{URL: "http://localhost:4222", Stream: "EVENTS", Consumer: "app"},
+		{URL: "nats://", Stream: "EVENTS", Consumer: "app"},
+		{URL: "nats://localhost", Stream: "EVENTS.>", Consumer: "app"},
+		{URL: "nats://localhost", Stream: "EVENTS", Consumer: ""},
+	}
+	for _, config := range tests {
+		if _, err := NewNATSConsumer(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+
+	consumer, err := NewNATSConsumer(NATSConfig{URL: "nats://s3cret@localhost", Stream: "EVENTS", Consumer: "app"})
+	if err != nil || consumer.addr != "localhost:4222" || consumer.token != "s3cret" {
+		t.Errorf("Unexpected consumer: %+v, %v", consumer, err)
+	}
+}
+
+func TestAckPending(t *testing.T) {
+	tests := map[string]uint64{
+		"$JS.ACK.EVENTS.app.1.10.8.1700000000000000000.42":               42,
+		"$JS.ACK.hub.ACCHASH.EVENTS.app.1.10.8.1700000000000000000.7.r1": 7,
+		"$JS.ACK.short": 0,
+	}
+	for subject, want := range tests {
+		if got := ackPending(subject); got != want {
+			t.Errorf("ackPending(%q) = %d, want %d", subject, got, want)
+		}
+	}
+}
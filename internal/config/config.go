@@ -17,12 +17,30 @@ const (
 
 // Config holds the application configuration
 type Config struct {
-	Environment string           `mapstructure:"environment"`
-	Server      ServerConfig     `mapstructure:"server"`
-	Database    DatabaseConfig   `mapstructure:"database"`
-	Logger      LoggerConfig     `mapstructure:"logging"`
-	Monitoring  MonitoringConfig `mapstructure:"monitoring"`
-	Ionos       IonosConfig      `mapstructure:"ionos"`
+	Environment string            `mapstructure:"environment"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Logger      LoggerConfig      `mapstructure:"logging"`
+	Monitoring  MonitoringConfig  `mapstructure:"monitoring"`
+	Ionos       IonosConfig       `mapstructure:"ionos"`
+	Privacy     PrivacyConfig     `mapstructure:"privacy"`
+	Webhooks    WebhooksConfig    `mapstructure:"webhooks"`
+	Bus         BusConfig         `mapstructure:"bus"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Sentry      SentryConfig      `mapstructure:"sentry"`
+	Features    FeaturesConfig    `mapstructure:"features"`
+	Remediation RemediationConfig `mapstructure:"remediation"`
+	Jobs        JobsConfig        `mapstructure:"jobs"`
+	TLSPolicy   TLSPolicyConfig   `mapstructure:"tls_policy"`
+	Lookups     LookupsConfig     `mapstructure:"external_lookups"`
+	Volume      VolumeConfig      `mapstructure:"volume"`
+	SLO         SLOConfig         `mapstructure:"slo"`
+	Audit       AuditConfig       `mapstructure:"audit"`
+	Chaos       ChaosConfig       `mapstructure:"chaos"`
+	ITSM        ITSMConfig        `mapstructure:"itsm"`
+	Stalwart    StalwartConfig    `mapstructure:"stalwart"`
+	Alerting    AlertingConfig    `mapstructure:"alerting"`
+	I18n        I18nConfig        `mapstructure:"i18n"`
 }
 
 // ServerConfig holds server configuration
@@ -31,6 +49,30 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	// HandlerTimeout is the context deadline of a request; RouteTimeouts
+	// overrides it per route as "/route=20s,..."
+	HandlerTimeout time.Duration     `mapstructure:"handler_timeout"`
+	RouteTimeouts  string            `mapstructure:"route_timeouts"`
+	Compression    CompressionConfig `mapstructure:"compression"`
+	CORS           CORSConfig        `mapstructure:"cors"`
+}
+
+// CORSConfig holds the cross-origin allow-list. Empty lists fall back to the
+// defaults for the environment.
+type CORSConfig struct {
+	AllowedOrigins   string        `mapstructure:"allowed_origins"`
+	AllowedMethods   string        `mapstructure:"allowed_methods"`
+	AllowedHeaders   string        `mapstructure:"allowed_headers"`
+	ExposedHeaders   string        `mapstructure:"exposed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
+}
+
+// CompressionConfig holds HTTP response compression configuration
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	MinSize int  `mapstructure:"min_size"`
+	Level   int  `mapstructure:"level"`
 }
 
 // DatabaseConfig holds database configuration
@@ -44,12 +86,25 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// SlowQueryThreshold logs statements taking at least this long; zero
+	// logs none
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
 }
 
 // LoggerConfig holds logging configuration
 type LoggerConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
+	Level        string          `mapstructure:"level"`
+	Format       string          `mapstructure:"format"`
+	Modules      string          `mapstructure:"modules"`
+	OTLPEndpoint string          `mapstructure:"otlp_endpoint"`
+	AccessLog    AccessLogConfig `mapstructure:"access_log"`
+}
+
+// AccessLogConfig holds HTTP access log sampling configuration
+type AccessLogConfig struct {
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"`
+	SampleRate    float64       `mapstructure:"sample_rate"`
+	Routes        string        `mapstructure:"routes"`
 }
 
 // MonitoringConfig holds monitoring configuration
@@ -72,6 +127,297 @@ type IonosConfig struct {
 	DefaultReservationSize int           `mapstructure:"default_reservation_size"`
 	MaxQuota               int           `mapstructure:"max_quota"`
 	ReservationTimeout     time.Duration `mapstructure:"reservation_timeout"`
+	// ReservationWebhooks are comma-separated URLs notified when a
+	// reservation job ends; empty disables them
+	ReservationWebhooks      string `mapstructure:"reservation_webhooks"`
+	ReservationWebhookSecret string `mapstructure:"reservation_webhook_secret"`
+	// StaleAfter is how long a reserved IP may stay unassigned before it is
+	// flagged stale; 0 disables the policy
+	StaleAfter         time.Duration `mapstructure:"stale_after"`
+	StaleGracePeriod   time.Duration `mapstructure:"stale_grace_period"`
+	StaleAutoRelease   bool          `mapstructure:"stale_auto_release"`
+	StaleCheckInterval time.Duration `mapstructure:"stale_check_interval"`
+	// QuarantineReconcileInterval is how often reserved IP statuses are
+	// reconciled with the reputation statuses; 0 disables the job
+	QuarantineReconcileInterval time.Duration `mapstructure:"quarantine_reconcile_interval"`
+	// Checks a newly reserved IP must pass besides the DNSBL check
+	VerifyPTR        bool    `mapstructure:"verify_ptr"`
+	VerifySMTPTarget string  `mapstructure:"verify_smtp_target"`
+	VerifyMinScore   float64 `mapstructure:"verify_min_score"`
+}
+
+// PrivacyConfig holds redaction and retention settings for recipient data
+type PrivacyConfig struct {
+	RedactionMode string `mapstructure:"redaction_mode"`
+	RedactionKey  string `mapstructure:"redaction_key"`
+	// AnonymizeAfter is the age after which failure rows lose recipient PII; 0 disables
+	AnonymizeAfter    time.Duration `mapstructure:"anonymize_after"`
+	AnonymizeInterval time.Duration `mapstructure:"anonymize_interval"`
+}
+
+// WebhooksConfig holds webhook ingestion configuration
+type WebhooksConfig struct {
+	RequireSourceAuth bool `mapstructure:"require_source_auth"`
+	// FoldSubdomains stores recipient domains as their registrable domain
+	FoldSubdomains bool `mapstructure:"fold_subdomains"`
+	// ContentDedupWindow is the timestamp bucket within which failures with
+	// the same IP, recipient, codes and attempt are duplicates; 0 disables
+	ContentDedupWindow time.Duration      `mapstructure:"content_dedup_window"`
+	Backpressure       BackpressureConfig `mapstructure:"backpressure"`
+	Signatures         SignaturesConfig   `mapstructure:"signatures"`
+}
+
+// SignaturesConfig holds the HMAC secrets ingestion payloads are signed with.
+// SourceSecrets are source:secret pairs; an empty secret disables the check.
+type SignaturesConfig struct {
+	Secret        string        `mapstructure:"secret"`
+	SourceSecrets string        `mapstructure:"source_secrets"`
+	MaxAge        time.Duration `mapstructure:"max_age"`
+}
+
+// BackpressureConfig sets when webhook payloads are refused with 429/503
+// and Retry-After. Zero values disable the respective check.
+type BackpressureConfig struct {
+	MaxInFlight      int           `mapstructure:"max_in_flight"`
+	PoolThreshold    float64       `mapstructure:"pool_threshold"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	RetryAfter       time.Duration `mapstructure:"retry_after"`
+}
+
+// BusConfig holds the optional message bus consumer that ingests the same
+// events as the webhook. An empty driver disables it.
+type BusConfig struct {
+	Driver    string        `mapstructure:"driver"`
+	URL       string        `mapstructure:"url"`
+	Stream    string        `mapstructure:"stream"`
+	Consumer  string        `mapstructure:"consumer"`
+	BatchSize int           `mapstructure:"batch_size"`
+	FetchWait time.Duration `mapstructure:"fetch_wait"`
+}
+
+// AuthConfig holds API key and dashboard session configuration
+type AuthConfig struct {
+	Required        bool          `mapstructure:"required"`
+	JWTSecret       string        `mapstructure:"jwt_secret"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+	APIKeys         string        `mapstructure:"api_keys"`
+	SCIMEnabled     bool          `mapstructure:"scim_enabled"`
+}
+
+// FeaturesConfig holds feature flag defaults. strict_webhook_auth defaults
+// to webhooks.require_source_auth. Toggles made at runtime win.
+type FeaturesConfig struct {
+	AutoRotation    bool `mapstructure:"auto_rotation"`
+	ShadowRules     bool `mapstructure:"shadow_rules"`
+	FailureCounters bool `mapstructure:"failure_counters"`
+}
+
+// RemediationConfig holds the default mode of automated remediation: off,
+// dry-run or enforce. A mode set through the API wins.
+type RemediationConfig struct {
+	Mode string `mapstructure:"mode"`
+}
+
+// JobsConfig holds the background job runner behind the asynchronous
+// endpoints
+type JobsConfig struct {
+	Workers   int           `mapstructure:"workers"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	Retention time.Duration `mapstructure:"retention"`
+	// CallbackSecret signs job callbacks with X-Signature-256; empty sends
+	// them unsigned
+	CallbackSecret string `mapstructure:"callback_secret"`
+}
+
+// TLSPolicyConfig holds the cache of destinations' MTA-STS and DANE
+// policies that failures are annotated with
+type TLSPolicyConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// DNSSECResolver is a validating resolver (host:port) asked for TLSA
+	// records; empty skips DANE
+	DNSSECResolver string `mapstructure:"dnssec_resolver"`
+}
+
+// LookupsConfig holds the external sender reputation lookups run when an IP
+// is quarantined
+type LookupsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Providers is a comma-separated list of providers, e.g. senderscore
+	Providers string        `mapstructure:"providers"`
+	CacheTTL  time.Duration `mapstructure:"cache_ttl"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// VolumeConfig selects where the sent volume rejection ratios are divided
+// by comes from
+type VolumeConfig struct {
+	// Estimator is a comma separated list of delivered, reported,
+	// prometheus and failures, asked in order
+	Estimator  string                 `mapstructure:"estimator"`
+	Prometheus PrometheusVolumeConfig `mapstructure:"prometheus"`
+}
+
+// PrometheusVolumeConfig holds the Prometheus server and query the
+// prometheus volume estimator reads
+type PrometheusVolumeConfig struct {
+	URL string `mapstructure:"url"`
+	// Query is an instant query; {{ip}} and {{window}} are replaced with
+	// the IP and the window as a Prometheus duration. Empty uses the
+	// estimator's default.
+	Query   string        `mapstructure:"query"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SLOConfig holds the fleet service level objectives and the error budget
+// they are held to
+type SLOConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// Target is the share of measurements within Window that must meet each
+	// objective
+	Target     float64              `mapstructure:"target"`
+	Window     time.Duration        `mapstructure:"window"`
+	BurnWindow time.Duration        `mapstructure:"burn_window"`
+	Retention  time.Duration        `mapstructure:"retention"`
+	Objectives []SLOObjectiveConfig `mapstructure:"objectives"`
+}
+
+// AuditConfig holds the scheduled self-audit of stale data and
+// inconsistencies
+type AuditConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Interval          time.Duration `mapstructure:"interval"`
+	StaleMetricsAfter time.Duration `mapstructure:"stale_metrics_after"`
+	DNSBLMaxAge       time.Duration `mapstructure:"dnsbl_max_age"`
+	SampleSize        int           `mapstructure:"sample_size"`
+	Retention         time.Duration `mapstructure:"retention"`
+	// Thresholds are the issue counts per check above which the audit alerts
+	Thresholds AuditThresholdsConfig `mapstructure:"thresholds"`
+}
+
+// AuditThresholdsConfig holds the alert threshold of each audit check
+type AuditThresholdsConfig struct {
+	StaleMetrics       int `mapstructure:"stale_metrics"`
+	MissingIONOSBlocks int `mapstructure:"missing_ionos_blocks"`
+	StaleDNSBLChecks   int `mapstructure:"stale_dnsbl_checks"`
+	OrphanedActions    int `mapstructure:"orphaned_actions"`
+}
+
+// SLOObjectiveConfig bounds a fleet metric (healthy_ratio,
+// blacklisted_ratio, warning, quarantined or blacklisted) with min, max or
+// both
+type SLOObjectiveConfig struct {
+	Name   string   `mapstructure:"name"`
+	Metric string   `mapstructure:"metric"`
+	Min    *float64 `mapstructure:"min"`
+	Max    *float64 `mapstructure:"max"`
+}
+
+// StalwartConfig holds Stalwart's throttling hook, which receives the rate
+// caps set on IPs. Without a URL caps are only served in the sending policy.
+type StalwartConfig struct {
+	ThrottleHookURL   string        `mapstructure:"throttle_hook_url"`
+	ThrottleHookToken string        `mapstructure:"throttle_hook_token"`
+	Timeout           time.Duration `mapstructure:"timeout"`
+	// ExpiryInterval is how often expired caps are deleted and their lifting
+	// pushed
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"`
+}
+
+// AlertingConfig holds how status change alerts are sent to the targets of
+// the notification routes. Severities are status:severity pairs; statuses
+// left out are not alerted.
+type AlertingConfig struct {
+	Severities string `mapstructure:"severities"`
+	// Attempts is how often a target is tried; RetryDelay doubles after
+	// every failed attempt
+	Attempts   int           `mapstructure:"attempts"`
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	Slack      struct {
+		// WebhookURL is the incoming webhook posting to the routes' channels;
+		// empty only sends to routes whose target is a webhook URL
+		WebhookURL string `mapstructure:"webhook_url"`
+	} `mapstructure:"slack"`
+	Webhook struct {
+		// Secret signs the alerts posted to webhook routes; empty sends
+		// them unsigned
+		Secret string `mapstructure:"secret"`
+	} `mapstructure:"webhook"`
+	Email     AlertEmailConfig `mapstructure:"email"`
+	PagerDuty struct {
+		EventsURL string `mapstructure:"events_url"`
+	} `mapstructure:"pagerduty"`
+}
+
+// AlertEmailConfig holds the SMTP relay alerts are mailed through; without a
+// host email routes are not sent
+type AlertEmailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// ITSMConfig holds the Jira and ServiceNow sites the ITSM rules open tickets
+// in. A system without a URL is disabled.
+type ITSMConfig struct {
+	Jira       JiraConfig       `mapstructure:"jira"`
+	ServiceNow ServiceNowConfig `mapstructure:"servicenow"`
+	// WebhookSecret authenticates the systems' status webhooks; empty
+	// refuses them
+	WebhookSecret string `mapstructure:"webhook_secret"`
+}
+
+// JiraConfig holds the Jira site and account tickets are opened with
+type JiraConfig struct {
+	BaseURL   string `mapstructure:"base_url"`
+	User      string `mapstructure:"user"`
+	Token     string `mapstructure:"token"`
+	IssueType string `mapstructure:"issue_type"`
+	// ResolveTransition is the workflow transition ID that resolves an
+	// issue; empty leaves resolving to people
+	ResolveTransition string `mapstructure:"resolve_transition"`
+}
+
+// ServiceNowConfig holds the ServiceNow instance and account tickets are
+// opened with
+type ServiceNowConfig struct {
+	InstanceURL   string `mapstructure:"instance_url"`
+	User          string `mapstructure:"user"`
+	Password      string `mapstructure:"password"`
+	Table         string `mapstructure:"table"`
+	ResolvedState string `mapstructure:"resolved_state"`
+	CloseCode     string `mapstructure:"close_code"`
+}
+
+// I18nConfig holds the languages status summaries and recommendations are
+// rendered in
+type I18nConfig struct {
+	// Languages are comma separated language tags negotiated with
+	// Accept-Language; empty serves every bundled catalog
+	Languages string `mapstructure:"languages"`
+	// DefaultLanguage is served when a client accepts none of them
+	DefaultLanguage string `mapstructure:"default_language"`
+}
+
+// ChaosConfig holds the failure injection used to exercise resilience in
+// staging. It is refused in production.
+type ChaosConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// SentryConfig holds the optional error aggregation integration. An empty
+// DSN disables it.
+type SentryConfig struct {
+	DSN string `mapstructure:"dsn"`
+	// Environment defaults to the service environment
+	Environment string `mapstructure:"environment"`
+	Release     string `mapstructure:"release"`
 }
 
 // Load reads and parses the configuration file
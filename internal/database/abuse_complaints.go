@@ -0,0 +1,103 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AbuseComplaint is a recipient's complaint about a message, parsed from an
+// ARF feedback report
+type AbuseComplaint struct {
+	ID                int       `json:"id"`
+	SendingIP         string    `json:"sending_ip"`
+	FeedbackType      string    `json:"feedback_type"`
+	Reporter          string    `json:"reporter"`
+	ReportID          string    `json:"report_id,omitempty"`
+	CampaignID        string    `json:"campaign_id,omitempty"`
+	OriginalMessageID string    `json:"original_message_id,omitempty"`
+	OriginalMailFrom  string    `json:"original_mail_from,omitempty"`
+	RecipientDomain   string    `json:"recipient_domain,omitempty"`
+	ReportedDomain    string    `json:"reported_domain,omitempty"`
+	UserAgent         string    `json:"user_agent,omitempty"`
+	ArrivedAt         time.Time `json:"arrived_at"`
+	ReportedBy        string    `json:"reported_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// InsertAbuseComplaint stores a complaint and sets its ID. It returns false
+// when the reporter already sent a report with the same report ID.
+func InsertAbuseComplaint(c *AbuseComplaint) (bool, error) {
+	query := `
+		INSERT INTO abuse_complaints (
+			sending_ip, feedback_type, reporter, report_id, campaign_id, original_message_id,
+			original_mail_from, recipient_domain, reported_domain, user_agent, arrived_at, reported_by
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''),
+			NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, NULLIF($12, ''))
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := DB.QueryRow(query,
+		c.SendingIP, c.FeedbackType, c.Reporter, c.ReportID, c.CampaignID, c.OriginalMessageID,
+		c.OriginalMailFrom, c.RecipientDomain, c.ReportedDomain, c.UserAgent, c.ArrivedAt, c.ReportedBy,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert abuse complaint: %w", err)
+	}
+	return true, nil
+}
+
+// GetAbuseComplaintsByIP returns the complaints about an IP's mail that
+// arrived since a time, newest first
+func GetAbuseComplaintsByIP(ip string, since time.Time) ([]AbuseComplaint, error) {
+	query := `
+		SELECT id, sending_ip, feedback_type, reporter, COALESCE(report_id, ''),
+		       COALESCE(campaign_id, ''), COALESCE(original_message_id, ''),
+		       COALESCE(original_mail_from, ''), COALESCE(recipient_domain, ''),
+		       COALESCE(reported_domain, ''), COALESCE(user_agent, ''), arrived_at,
+		       COALESCE(reported_by, ''), created_at
+		FROM abuse_complaints
+		WHERE sending_ip = $1 AND arrived_at >= $2
+		ORDER BY arrived_at DESC
+	`
+
+	rows, err := DB.Query(query, ip, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query abuse complaints: %w", err)
+	}
+	defer rows.Close()
+
+	complaints := []AbuseComplaint{}
+	for rows.Next() {
+		var c AbuseComplaint
+		if err := rows.Scan(
+			&c.ID, &c.SendingIP, &c.FeedbackType, &c.Reporter, &c.ReportID,
+			&c.CampaignID, &c.OriginalMessageID, &c.OriginalMailFrom, &c.RecipientDomain,
+			&c.ReportedDomain, &c.UserAgent, &c.ArrivedAt, &c.ReportedBy, &c.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan abuse complaint: %w", err)
+		}
+		complaints = append(complaints, c)
+	}
+	return complaints, rows.Err()
+}
+
+// CountAbuseComplaints counts the complaints about an IP's mail that arrived
+// since a time. not-spam and auth-failure reports are not complaints and are
+// left out.
+func CountAbuseComplaints(ip string, since time.Time) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM abuse_complaints
+		WHERE sending_ip = $1 AND arrived_at >= $2
+		  AND feedback_type NOT IN ('not-spam', 'auth-failure')
+	`, ip, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count abuse complaints: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Aggregation exclusion errors
+var (
+	ErrAggregationExclusionExists   = errors.New("block is already excluded")
+	ErrAggregationExclusionNotFound = errors.New("aggregation exclusion not found")
+)
+
+// AggregationExclusion is a CIDR block whose IPs are not sending IPs, e.g.
+// monitoring probes, corporate egress or test addresses. Aggregation does
+// not discover its IPs and dashboards hide them unless asked; their
+// failures are still stored.
+type AggregationExclusion struct {
+	ID        int       `json:"id"`
+	CIDR      string    `json:"cidr" example:"203.0.113.0/28"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAggregationExclusion excludes a block from aggregation
+func CreateAggregationExclusion(exclusion *AggregationExclusion) (*AggregationExclusion, error) {
+	stored := *exclusion
+	err := DB.QueryRow(`
+		INSERT INTO aggregation_exclusions (cidr, reason, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, cidr::text, created_at
+	`, exclusion.CIDR, exclusion.Reason, exclusion.CreatedBy).Scan(&stored.ID, &stored.CIDR, &stored.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrAggregationExclusionExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aggregation exclusion: %w", err)
+	}
+	return &stored, nil
+}
+
+// ListAggregationExclusions returns every excluded block, ordered by block
+func ListAggregationExclusions() ([]AggregationExclusion, error) {
+	rows, err := DB.Query(`
+		SELECT id, cidr::text, reason, created_by, created_at
+		FROM aggregation_exclusions
+		ORDER BY cidr
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregation exclusions: %w", err)
+	}
+	defer rows.Close()
+
+	exclusions := []AggregationExclusion{}
+	for rows.Next() {
+		var e AggregationExclusion
+		if err := rows.Scan(&e.ID, &e.CIDR, &e.Reason, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregation exclusion: %w", err)
+		}
+		exclusions = append(exclusions, e)
+	}
+	return exclusions, rows.Err()
+}
+
+// DeleteAggregationExclusion puts a block's IPs back into aggregation and
+// the dashboards
+func DeleteAggregationExclusion(id int) error {
+	result, err := DB.Exec(`DELETE FROM aggregation_exclusions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete aggregation exclusion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAggregationExclusionNotFound
+	}
+	return nil
+}
+
+// GetExcludedBlocks returns the excluded blocks, parsed
+func GetExcludedBlocks() ([]*net.IPNet, error) {
+	exclusions, err := ListAggregationExclusions()
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*net.IPNet, 0, len(exclusions))
+	for _, e := range exclusions {
+		_, block, err := net.ParseCIDR(e.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse excluded block %q: %w", e.CIDR, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
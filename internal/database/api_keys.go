@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrAPIKeyExists is returned when an active API key already has the name
+var ErrAPIKeyExists = errors.New("API key already exists")
+
+// APIKey is a machine key managed through the API. Only the SHA-256 of the
+// key is stored; Prefix is its first characters so operators can tell keys
+// apart.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Prefix     string     `json:"prefix"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const apiKeyColumns = `id, name, role, prefix, created_by, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Role, &key.Prefix, &key.CreatedBy, &key.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new key with the hash of its secret
+func CreateAPIKey(name, role, prefix, keyHash, createdBy string) (*APIKey, error) {
+	query := `
+		INSERT INTO api_keys (name, role, prefix, key_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(DB.QueryRow(query, name, role, prefix, keyHash, createdBy))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrAPIKeyExists
+		}
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, nil
+}
+
+// GetActiveAPIKeyByHash looks up an unrevoked key by the hash of its secret.
+// It returns nil, nil for unknown and revoked keys.
+func GetActiveAPIKeyByHash(keyHash string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	key, err := scanAPIKey(DB.QueryRow(query, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+// TouchAPIKey records that a key was used. It writes at most once a minute
+// per key so busy clients do not update the row on every request.
+func TouchAPIKey(id int) error {
+	_, err := DB.Exec(`
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch API key: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeys returns all keys, revoked ones included, newest first
+func ListAPIKeys() ([]APIKey, error) {
+	rows, err := DB.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes a key. It reports whether an active key was revoked.
+func RevokeAPIKey(id int) (bool, error) {
+	result, err := DB.Exec(`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrIPNotArchived is returned when unarchiving an IP that is not archived
+var ErrIPNotArchived = errors.New("IP is not archived")
+
+// Actions recording archival on an IP
+const (
+	IPArchivedAction   = "archived"
+	IPUnarchivedAction = "unarchived"
+)
+
+// ArchivedIP is a decommissioned IP. Its history is kept, but aggregation
+// and alerting skip it and dashboards hide it unless asked.
+type ArchivedIP struct {
+	IP         string    `json:"ip"`
+	Reason     string    `json:"reason"`
+	ArchivedBy string    `json:"archived_by"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// ArchiveIPs archives the IPs that are not archived yet and records an
+// action on each, in one transaction. It returns the IPs it archived.
+func ArchiveIPs(ips []string, reason, author string) ([]ArchivedIP, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		INSERT INTO archived_ips (ip, reason, archived_by)
+		SELECT DISTINCT unnest($1::text[]), $2, $3
+		ON CONFLICT (ip) DO NOTHING
+		RETURNING ip, reason, archived_by, archived_at
+	`, pq.Array(ips), reason, author)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive IPs: %w", err)
+	}
+	archived := []ArchivedIP{}
+	for rows.Next() {
+		var a ArchivedIP
+		if err := rows.Scan(&a.IP, &a.Reason, &a.ArchivedBy, &a.ArchivedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan archived IP: %w", err)
+		}
+		archived = append(archived, a)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive IPs: %w", err)
+	}
+
+	for _, a := range archived {
+		if _, err := tx.Exec(`
+			INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by)
+			VALUES ($1, $2, '', '', $3, $4)
+		`, a.IP, IPArchivedAction, a.Reason, author); err != nil {
+			return nil, fmt.Errorf("failed to record archival: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit archival: %w", err)
+	}
+	return archived, nil
+}
+
+// UnarchiveIP puts an archived IP back into aggregation, alerting and the
+// dashboards
+func UnarchiveIP(ip, author string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM archived_ips WHERE ip = $1`, ip)
+	if err != nil {
+		return fmt.Errorf("failed to unarchive IP: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrIPNotArchived
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by)
+		VALUES ($1, $2, '', '', '', $3)
+	`, ip, IPUnarchivedAction, author); err != nil {
+		return fmt.Errorf("failed to record unarchival: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit unarchival: %w", err)
+	}
+	return nil
+}
+
+// ListArchivedIPs returns every archived IP, most recently archived first
+func ListArchivedIPs() ([]ArchivedIP, error) {
+	rows, err := DB.Query(`
+		SELECT ip, reason, archived_by, archived_at
+		FROM archived_ips
+		ORDER BY archived_at DESC, ip
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived IPs: %w", err)
+	}
+	defer rows.Close()
+
+	archived := []ArchivedIP{}
+	for rows.Next() {
+		var a ArchivedIP
+		if err := rows.Scan(&a.IP, &a.Reason, &a.ArchivedBy, &a.ArchivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archived IP: %w", err)
+		}
+		archived = append(archived, a)
+	}
+	return archived, rows.Err()
+}
+
+// GetArchivedIPSet returns the archived IPs as a set
+func GetArchivedIPSet() (map[string]bool, error) {
+	archived, err := ListArchivedIPs()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(archived))
+	for _, a := range archived {
+		set[a.IP] = true
+	}
+	return set, nil
+}
+
+// IsIPArchived reports whether an IP is archived
+func IsIPArchived(ip string) (bool, error) {
+	var archived bool
+	if err := DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM archived_ips WHERE ip = $1)`, ip).Scan(&archived); err != nil {
+		return false, fmt.Errorf("failed to check archived IP: %w", err)
+	}
+	return archived, nil
+}
+
+// ListKnownIPsInCIDR returns the IPs in a CIDR block known to the reserved
+// IPs, the reputation metrics, the pools, the ownership assignments or the
+// failures, ordered by IP
+func ListKnownIPsInCIDR(cidr string) ([]string, error) {
+	rows, err := DB.Query(`
+		WITH ips AS (
+			SELECT host(ip_address) AS ip FROM reserved_ips
+			UNION SELECT ip FROM ip_reputation_metrics
+			UNION SELECT ip_address FROM ip_pool_members
+			UNION SELECT ip_address FROM ownership_assignments WHERE ip_address IS NOT NULL
+			UNION SELECT DISTINCT sending_ip FROM smtp_failures
+		)
+		SELECT ip FROM ips
+		WHERE ip::inet <<= $1::cidr
+		ORDER BY ip::inet
+	`, cidr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IPs in %s: %w", cidr, err)
+	}
+	defer rows.Close()
+
+	ips := []string{}
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("failed to scan IP: %w", err)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, rows.Err()
+}
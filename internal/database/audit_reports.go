@@ -0,0 +1,231 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AuditCheck is the outcome of one self-audit check
+type AuditCheck struct {
+	Name string `json:"name"`
+	// Count is the number of inconsistencies found
+	Count     int `json:"count"`
+	Threshold int `json:"threshold"`
+	// Exceeded is set when Count is above Threshold, which alerts
+	Exceeded bool `json:"exceeded"`
+	// Samples are up to the configured number of affected IPs
+	Samples []string `json:"samples"`
+	// Skipped is set for checks that cannot run, e.g. without IONOS
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AuditReport is one run of the self-audit
+type AuditReport struct {
+	ID         int       `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Issues is the sum of the inconsistencies of all checks
+	Issues int `json:"issues"`
+	// Exceeded is the number of checks above their threshold
+	Exceeded int          `json:"exceeded"`
+	Checks   []AuditCheck `json:"checks"`
+}
+
+// AuditFinding is what one audit query found: the number of rows and the
+// IPs of the first ones
+type AuditFinding struct {
+	Count   int
+	Samples []string
+}
+
+// ReservedIPBlock is the IONOS block a reserved IP was reserved in
+type ReservedIPBlock struct {
+	IP      string
+	BlockID string
+}
+
+// scanAuditFinding reads rows of (ip, total count) into a finding
+func scanAuditFinding(rows *sql.Rows) (AuditFinding, error) {
+	defer rows.Close()
+	finding := AuditFinding{Samples: []string{}}
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip, &finding.Count); err != nil {
+			return AuditFinding{}, fmt.Errorf("failed to scan audit finding: %w", err)
+		}
+		finding.Samples = append(finding.Samples, ip)
+	}
+	return finding, rows.Err()
+}
+
+// AuditStaleMetrics finds non-archived IPs whose reputation metrics were last
+// updated before cutoff although failures arrived since, so the aggregation
+// should have refreshed them
+func AuditStaleMetrics(cutoff time.Time, sampleSize int) (AuditFinding, error) {
+	rows, err := DB.Query(`
+		SELECT m.ip, COUNT(*) OVER ()
+		FROM ip_reputation_metrics m
+		WHERE m.last_updated < $1
+		  AND NOT EXISTS (SELECT 1 FROM archived_ips a WHERE a.ip = m.ip)
+		  AND EXISTS (SELECT 1 FROM smtp_failures f WHERE f.sending_ip = m.ip AND f.timestamp > m.last_updated)
+		ORDER BY m.last_updated
+		LIMIT $2
+	`, cutoff, sampleSize)
+	if err != nil {
+		return AuditFinding{}, fmt.Errorf("failed to audit stale metrics: %w", err)
+	}
+	return scanAuditFinding(rows)
+}
+
+// AuditStaleDNSBLChecks finds non-archived IPs with reputation metrics or an
+// unreleased reservation whose last DNSBL check is older than cutoff, or
+// which were never checked
+func AuditStaleDNSBLChecks(cutoff time.Time, sampleSize int) (AuditFinding, error) {
+	rows, err := DB.Query(`
+		WITH ips AS (
+			SELECT ip FROM ip_reputation_metrics
+			UNION SELECT host(ip_address) FROM reserved_ips WHERE status <> 'released'
+		), checked AS (
+			SELECT i.ip, GREATEST(
+				(SELECT MAX(d.checked_at) FROM dnsbl_checks d WHERE d.ip = i.ip),
+				(SELECT r.last_checked_at FROM reserved_ips r WHERE host(r.ip_address) = i.ip)
+			) AS checked_at
+			FROM ips i
+			WHERE NOT EXISTS (SELECT 1 FROM archived_ips a WHERE a.ip = i.ip)
+		)
+		SELECT ip, COUNT(*) OVER ()
+		FROM checked
+		WHERE checked_at IS NULL OR checked_at < $1
+		ORDER BY checked_at NULLS FIRST, ip
+		LIMIT $2
+	`, cutoff, sampleSize)
+	if err != nil {
+		return AuditFinding{}, fmt.Errorf("failed to audit DNSBL checks: %w", err)
+	}
+	return scanAuditFinding(rows)
+}
+
+// AuditOrphanedActions finds IP actions of IPs the service no longer knows:
+// without reputation metrics, reservation, pool, owner or archive entry.
+// Count is the number of actions, Samples their distinct IPs.
+func AuditOrphanedActions(sampleSize int) (AuditFinding, error) {
+	var finding AuditFinding
+	err := DB.QueryRow(`
+		WITH orphans AS (
+			SELECT a.ip FROM ip_actions a
+			WHERE NOT EXISTS (SELECT 1 FROM ip_reputation_metrics m WHERE m.ip = a.ip)
+			  AND NOT EXISTS (SELECT 1 FROM reserved_ips r WHERE host(r.ip_address) = a.ip)
+			  AND NOT EXISTS (SELECT 1 FROM ip_pool_members p WHERE p.ip_address = a.ip)
+			  AND NOT EXISTS (SELECT 1 FROM ownership_assignments o WHERE o.ip_address = a.ip)
+			  AND NOT EXISTS (SELECT 1 FROM archived_ips ar WHERE ar.ip = a.ip)
+		)
+		SELECT
+			(SELECT COUNT(*) FROM orphans),
+			COALESCE((SELECT array_agg(ip) FROM (SELECT DISTINCT ip FROM orphans ORDER BY ip LIMIT $1) s), '{}')
+	`, sampleSize).Scan(&finding.Count, pq.Array(&finding.Samples))
+	if err != nil {
+		return AuditFinding{}, fmt.Errorf("failed to audit orphaned actions: %w", err)
+	}
+	return finding, nil
+}
+
+// ListActiveReservedIPBlocks returns the block of every reserved IP not
+// released; BlockID is empty for IPs stored without one
+func ListActiveReservedIPBlocks() ([]ReservedIPBlock, error) {
+	rows, err := DB.Query(`
+		SELECT host(ip_address), COALESCE(reservation_block_id, '')
+		FROM reserved_ips
+		WHERE status <> 'released'
+		ORDER BY ip_address
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved IP blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []ReservedIPBlock{}
+	for rows.Next() {
+		var b ReservedIPBlock
+		if err := rows.Scan(&b.IP, &b.BlockID); err != nil {
+			return nil, fmt.Errorf("failed to scan reserved IP block: %w", err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+const auditReportColumns = `id, started_at, finished_at, issues, exceeded, checks`
+
+func scanAuditReport(row interface{ Scan(...interface{}) error }) (*AuditReport, error) {
+	var report AuditReport
+	var checks []byte
+	if err := row.Scan(&report.ID, &report.StartedAt, &report.FinishedAt, &report.Issues, &report.Exceeded, &checks); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(checks, &report.Checks); err != nil {
+		return nil, fmt.Errorf("failed to decode audit report %d: %w", report.ID, err)
+	}
+	return &report, nil
+}
+
+// InsertAuditReport stores a report and sets its ID
+func InsertAuditReport(report *AuditReport) error {
+	checks, err := json.Marshal(report.Checks)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit checks: %w", err)
+	}
+	err = DB.QueryRow(`
+		INSERT INTO audit_reports (started_at, finished_at, issues, exceeded, checks)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, report.StartedAt, report.FinishedAt, report.Issues, report.Exceeded, checks).Scan(&report.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit report: %w", err)
+	}
+	return nil
+}
+
+// GetLatestAuditReport returns the newest report, nil if the audit never ran
+func GetLatestAuditReport() (*AuditReport, error) {
+	report, err := scanAuditReport(DB.QueryRow(`SELECT ` + auditReportColumns + ` FROM audit_reports ORDER BY started_at DESC, id DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest audit report: %w", err)
+	}
+	return report, nil
+}
+
+// ListAuditReports returns the newest reports first
+func ListAuditReports(limit int) ([]AuditReport, error) {
+	rows, err := DB.Query(`SELECT `+auditReportColumns+` FROM audit_reports ORDER BY started_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []AuditReport{}
+	for rows.Next() {
+		report, err := scanAuditReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit report: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+// DeleteAuditReportsBefore deletes the reports of runs started before cutoff
+func DeleteAuditReportsBefore(cutoff time.Time) (int64, error) {
+	result, err := DB.Exec(`DELETE FROM audit_reports WHERE started_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old audit reports: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RefreshToken is a stored dashboard session refresh token
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// SetUserPassword stores a password hash for a user. It reports whether the
+// user exists.
+func SetUserPassword(id int, passwordHash string) (bool, error) {
+	result, err := DB.Exec(`UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`, id, passwordHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to set user password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetUserCredentials returns a user and their password hash by username.
+// It returns nil, "", nil when the user does not exist or is inactive; the
+// hash is empty when no password is set.
+func GetUserCredentials(username string) (*User, string, error) {
+	query := "SELECT " + userColumns + ", COALESCE(password_hash, '') FROM users WHERE username = $1 AND active"
+
+	var user User
+	var hash string
+	err := DB.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.Active, &user.Role, &user.CreatedAt, &user.UpdatedAt, &hash)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user credentials: %w", err)
+	}
+
+	return &user, hash, nil
+}
+
+// CreateRefreshToken stores the hash of a new refresh token
+func CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := DB.Exec(`
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRefreshToken revokes an active refresh token and returns it. It
+// returns nil, nil if the token is unknown, expired or already revoked, so a
+// token can only be exchanged once.
+func ConsumeRefreshToken(tokenHash string) (*RefreshToken, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, expires_at, revoked_at, created_at
+	`
+
+	var token RefreshToken
+	var revokedAt sql.NullTime
+	err := DB.QueryRow(query, tokenHash).Scan(&token.ID, &token.UserID, &token.ExpiresAt, &revokedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return &token, nil
+}
+
+// RevokeUserRefreshTokens ends all dashboard sessions of a user
+func RevokeUserRefreshTokens(userID int) error {
+	_, err := DB.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
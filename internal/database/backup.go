@@ -0,0 +1,169 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Backups are identified by BackupFormat; BackupVersion changes when the
+// archive layout does
+const (
+	BackupFormat  = "ip-reputation-state"
+	BackupVersion = 1
+)
+
+// backupTable is a table a backup holds, ordered by key
+type backupTable struct {
+	name   string
+	key    string
+	serial bool // key is a SERIAL whose sequence a restore advances
+}
+
+// backupTables are the tables of the reputation state, in restore order:
+// config revisions reference earlier revisions by ID
+var backupTables = []backupTable{
+	{name: "ip_reputation_metrics", key: "id", serial: true},
+	{name: "config_revisions", key: "id", serial: true},
+	{name: "feature_flags", key: "name"},
+	{name: "feature_flag_changes", key: "id", serial: true},
+	{name: "reserved_ips", key: "id", serial: true},
+	{name: "protected_blocks", key: "id", serial: true},
+	{name: "archived_ips", key: "ip"},
+	{name: "aggregation_exclusions", key: "id", serial: true},
+	{name: "ip_rate_limits", key: "ip"},
+	{name: "ip_context", key: "ip"},
+}
+
+// ErrRestoreNotEmpty is returned when restoring into a deployment that
+// already has reputation state
+var ErrRestoreNotEmpty = errors.New("deployment already has reputation state")
+
+// Backup is a consistent snapshot of the reputation state: the rows of every
+// backup table as JSON objects, column by column
+type Backup struct {
+	Format    string                       `json:"format"`
+	Version   int                          `json:"version"`
+	CreatedAt time.Time                    `json:"created_at"`
+	Tables    map[string][]json.RawMessage `json:"tables" swaggertype:"object"`
+}
+
+// Validate checks a backup was made by a compatible version and holds
+// nothing but rows of backup tables
+func (b *Backup) Validate() error {
+	if b.Format != BackupFormat {
+		return fmt.Errorf("not a reputation state backup")
+	}
+	if b.Version != BackupVersion {
+		return fmt.Errorf("unsupported backup version %d, want %d", b.Version, BackupVersion)
+	}
+	known := make(map[string]bool, len(backupTables))
+	for _, t := range backupTables {
+		known[t.name] = true
+	}
+	for name, rows := range b.Tables {
+		if !known[name] {
+			return fmt.Errorf("unknown table %q", name)
+		}
+		for i, row := range rows {
+			if trimmed := bytes.TrimSpace(row); len(trimmed) == 0 || trimmed[0] != '{' || !json.Valid(trimmed) {
+				return fmt.Errorf("%s row %d is not a JSON object", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// ExportBackup reads every backup table in one repeatable-read transaction,
+// so the snapshot is consistent while aggregation keeps writing
+func ExportBackup() (*Backup, error) {
+	tx, err := DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin backup: %w", err)
+	}
+	defer tx.Rollback()
+
+	backup := &Backup{Format: BackupFormat, Version: BackupVersion, CreatedAt: time.Now().UTC(), Tables: make(map[string][]json.RawMessage)}
+	for _, t := range backupTables {
+		rows, err := tx.Query(fmt.Sprintf(`SELECT row_to_json(t) FROM %s t ORDER BY t.%s`, t.name, t.key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+		table := []json.RawMessage{}
+		for rows.Next() {
+			var row []byte
+			if err := rows.Scan(&row); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s row: %w", t.name, err)
+			}
+			table = append(table, row)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+		backup.Tables[t.name] = table
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+	return backup, nil
+}
+
+// RestoreBackup inserts a backup's rows with their original IDs and advances
+// the ID sequences past them. It restores only into an empty deployment:
+// when any backup table has rows it returns ErrRestoreNotEmpty and changes
+// nothing. Columns the backup lacks are left NULL.
+func RestoreBackup(backup *Backup) (map[string]int, error) {
+	if err := backup.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin restore: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Keep aggregation runs from writing until the restore is committed
+	for _, t := range backupTables {
+		if _, err := tx.Exec(fmt.Sprintf(`LOCK TABLE %s IN EXCLUSIVE MODE`, t.name)); err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", t.name, err)
+		}
+		var exists bool
+		if err := tx.QueryRow(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, t.name)).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", t.name, err)
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: %s is not empty", ErrRestoreNotEmpty, t.name)
+		}
+	}
+
+	restored := make(map[string]int, len(backupTables))
+	for _, t := range backupTables {
+		insert := fmt.Sprintf(`INSERT INTO %s SELECT * FROM json_populate_record(NULL::%s, $1::json)`, t.name, t.name)
+		for i, row := range backup.Tables[t.name] {
+			if _, err := tx.Exec(insert, string(row)); err != nil {
+				return nil, fmt.Errorf("failed to restore %s row %d: %w", t.name, i, err)
+			}
+		}
+		if t.serial {
+			sequence := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 1), MAX(%s) IS NOT NULL) FROM %s`, t.name, t.key, t.key, t.key, t.name)
+			if _, err := tx.Exec(sequence); err != nil {
+				return nil, fmt.Errorf("failed to advance %s IDs: %w", t.name, err)
+			}
+		}
+		restored[t.name] = len(backup.Tables[t.name])
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return restored, nil
+}
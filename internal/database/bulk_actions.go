@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrIPPoolNotFound is returned when a filter names a pool that does not exist
+var ErrIPPoolNotFound = errors.New("IP pool not found")
+
+// BulkActionFilter selects the IPs of a bulk action. Empty fields match any
+// IP; set fields must all match.
+type BulkActionFilter struct {
+	CIDR   string `json:"cidr,omitempty" example:"192.0.2.0/24"`
+	Pool   string `json:"pool,omitempty" example:"transactional"`
+	Status string `json:"status,omitempty" example:"warning"`
+}
+
+// BulkActionTarget is an IP a bulk action filter matched, with its status
+type BulkActionTarget struct {
+	IP     string `json:"ip"`
+	Status string `json:"status"`
+}
+
+// ListBulkActionTargets returns the IPs with reputation metrics that match
+// filter, skipping archived IPs, ordered by IP
+func ListBulkActionTargets(filter BulkActionFilter) ([]BulkActionTarget, error) {
+	if filter.Pool != "" {
+		var exists bool
+		if err := DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM ip_pools WHERE name = $1)`, filter.Pool).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to look up IP pool: %w", err)
+		}
+		if !exists {
+			return nil, ErrIPPoolNotFound
+		}
+	}
+
+	rows, err := DB.Query(`
+		SELECT m.ip, m.status
+		FROM ip_reputation_metrics m
+		WHERE ($1::cidr IS NULL OR m.ip::inet <<= $1::cidr)
+		  AND ($2::text IS NULL OR m.ip IN (
+		      SELECT pm.ip_address FROM ip_pool_members pm
+		      JOIN ip_pools p ON p.id = pm.pool_id
+		      WHERE p.name = $2))
+		  AND ($3::text IS NULL OR m.status = $3)
+		  AND NOT EXISTS (SELECT 1 FROM archived_ips a WHERE a.ip = m.ip)
+		ORDER BY m.ip::inet
+	`, nullableString(filter.CIDR), nullableString(filter.Pool), nullableString(filter.Status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bulk action targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []BulkActionTarget{}
+	for rows.Next() {
+		var target BulkActionTarget
+		if err := rows.Scan(&target.IP, &target.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan bulk action target: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
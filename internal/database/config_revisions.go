@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Config revision scopes
+const (
+	// ConfigScopeReputation holds the reputation decision thresholds
+	ConfigScopeReputation = "reputation"
+	// ConfigScopeReputationShadow holds thresholds evaluated alongside the
+	// live ones without acting; a JSON null config removes the shadow
+	ConfigScopeReputationShadow = "reputation_shadow"
+	// ConfigScopeRemediation holds the mode automated remediation runs in
+	ConfigScopeRemediation = "remediation"
+)
+
+// ConfigRevision is one version of a runtime-editable config
+type ConfigRevision struct {
+	ID     int             `json:"id"`
+	Scope  string          `json:"scope"`
+	Config json.RawMessage `json:"config" swaggertype:"object"`
+	// Diff maps changed fields to {"from": old, "to": new}
+	Diff       json.RawMessage `json:"diff" swaggertype:"object"`
+	Author     string          `json:"author"`
+	Comment    string          `json:"comment,omitempty"`
+	RollbackOf *int            `json:"rollback_of,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+const configRevisionColumns = `id, scope, config, diff, author, COALESCE(comment, ''), rollback_of, created_at`
+
+func scanConfigRevision(row interface{ Scan(...interface{}) error }) (*ConfigRevision, error) {
+	var rev ConfigRevision
+	var config, diff []byte
+	var rollbackOf sql.NullInt64
+	if err := row.Scan(&rev.ID, &rev.Scope, &config, &diff, &rev.Author, &rev.Comment, &rollbackOf, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	rev.Config, rev.Diff = config, diff
+	if rollbackOf.Valid {
+		id := int(rollbackOf.Int64)
+		rev.RollbackOf = &id
+	}
+	return &rev, nil
+}
+
+// CreateConfigRevision stores a new revision, which becomes the active config
+// of its scope
+func CreateConfigRevision(rev *ConfigRevision) (*ConfigRevision, error) {
+	diff := rev.Diff
+	if len(diff) == 0 {
+		diff = json.RawMessage(`{}`)
+	}
+
+	query := `
+		INSERT INTO config_revisions (scope, config, diff, author, comment, rollback_of)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING ` + configRevisionColumns
+
+	created, err := scanConfigRevision(DB.QueryRow(query,
+		rev.Scope, []byte(rev.Config), []byte(diff), rev.Author, rev.Comment, rev.RollbackOf,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config revision: %w", err)
+	}
+	return created, nil
+}
+
+// GetLatestConfigRevision returns the active revision of a scope, or nil if
+// the config was never changed
+func GetLatestConfigRevision(scope string) (*ConfigRevision, error) {
+	query := "SELECT " + configRevisionColumns + " FROM config_revisions WHERE scope = $1 ORDER BY id DESC LIMIT 1"
+
+	rev, err := scanConfigRevision(DB.QueryRow(query, scope))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest config revision: %w", err)
+	}
+	return rev, nil
+}
+
+// GetConfigRevision returns a revision by ID, or nil if it does not exist
+func GetConfigRevision(id int) (*ConfigRevision, error) {
+	query := "SELECT " + configRevisionColumns + " FROM config_revisions WHERE id = $1"
+
+	rev, err := scanConfigRevision(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config revision: %w", err)
+	}
+	return rev, nil
+}
+
+// ListConfigRevisions returns the newest revisions of a scope first
+func ListConfigRevisions(scope string, limit int) ([]ConfigRevision, error) {
+	query := "SELECT " + configRevisionColumns + " FROM config_revisions WHERE scope = $1 ORDER BY id DESC LIMIT $2"
+
+	rows, err := DB.Query(query, scope, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query config revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []ConfigRevision{}
+	for rows.Next() {
+		rev, err := scanConfigRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan config revision: %w", err)
+		}
+		revisions = append(revisions, *rev)
+	}
+
+	return revisions, rows.Err()
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDelistingTaskNotFound is returned for an unknown task ID
+	ErrDelistingTaskNotFound = errors.New("delisting task not found")
+	// ErrDelistingTaskPending is returned when opening a task for an IP and
+	// provider that already have an unresolved one
+	ErrDelistingTaskPending = errors.New("delisting task already pending")
+	// ErrDelistingTaskResolved is returned when changing a resolved task
+	ErrDelistingTaskResolved = errors.New("delisting task is resolved")
+)
+
+// Delisting task statuses
+const (
+	DelistingOpen      = "open"
+	DelistingSubmitted = "submitted"
+	DelistingResolved  = "resolved"
+)
+
+// Actions recording delisting tasks on an IP
+const (
+	DelistingOpenedAction  = "delisting_opened"
+	DelistingUpdatedAction = "delisting_updated"
+)
+
+// DelistingTask is a delisting or mitigation request opened with a provider
+// for an IP
+type DelistingTask struct {
+	ID       int    `json:"id"`
+	IP       string `json:"ip"`
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	// BlockReferences are the block list identifiers the IP's rejections by
+	// the provider quoted when the task was opened, to cite in the request
+	BlockReferences []string   `json:"block_references"`
+	Comment         string     `json:"comment"`
+	OpenedBy        string     `json:"opened_by"`
+	OpenedAt        time.Time  `json:"opened_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+const delistingTaskColumns = `id, ip, provider, status, block_references, comment, opened_by, opened_at, updated_at, resolved_at`
+
+func scanDelistingTask(row interface{ Scan(...interface{}) error }) (*DelistingTask, error) {
+	var task DelistingTask
+	var resolvedAt sql.NullTime
+	if err := row.Scan(&task.ID, &task.IP, &task.Provider, &task.Status, pq.Array(&task.BlockReferences),
+		&task.Comment, &task.OpenedBy, &task.OpenedAt, &task.UpdatedAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	if task.BlockReferences == nil {
+		task.BlockReferences = []string{}
+	}
+	if resolvedAt.Valid {
+		task.ResolvedAt = &resolvedAt.Time
+	}
+	return &task, nil
+}
+
+// recordDelistingAction adds a delisting task change to the IP's actions
+func recordDelistingAction(tx *sql.Tx, task *DelistingTask, action, previousStatus, reason, author string) error {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"task_id":          task.ID,
+		"provider":         task.Provider,
+		"block_references": task.BlockReferences,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delisting metadata: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, task.IP, action, previousStatus, task.Status, reason, author, metadata); err != nil {
+		return fmt.Errorf("failed to record delisting task: %w", err)
+	}
+	return nil
+}
+
+// OpenDelistingTask stores a new open task and records it as an action on
+// the IP, in one transaction. It returns ErrDelistingTaskPending when the IP
+// and provider already have an unresolved task.
+func OpenDelistingTask(ip, provider string, refs []string, comment, author string) (*DelistingTask, error) {
+	if refs == nil {
+		refs = []string{}
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	task, err := scanDelistingTask(tx.QueryRow(`
+		INSERT INTO delisting_tasks (ip, provider, status, block_references, comment, opened_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+delistingTaskColumns,
+		ip, provider, DelistingOpen, pq.Array(refs), comment, author))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrDelistingTaskPending
+		}
+		return nil, fmt.Errorf("failed to open delisting task: %w", err)
+	}
+	if err := recordDelistingAction(tx, task, DelistingOpenedAction, "", comment, author); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit delisting task: %w", err)
+	}
+	return task, nil
+}
+
+// GetDelistingTask returns a task by ID
+func GetDelistingTask(id int) (*DelistingTask, error) {
+	task, err := scanDelistingTask(DB.QueryRow(`SELECT `+delistingTaskColumns+` FROM delisting_tasks WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDelistingTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delisting task: %w", err)
+	}
+	return task, nil
+}
+
+// ListDelistingTasks returns the tasks of an IP, newest first. An empty ip
+// matches all IPs and an empty status all statuses.
+func ListDelistingTasks(ip, status string) ([]DelistingTask, error) {
+	rows, err := DB.Query(`
+		SELECT `+delistingTaskColumns+`
+		FROM delisting_tasks
+		WHERE ($1 = '' OR ip = $1) AND ($2 = '' OR status = $2)
+		ORDER BY opened_at DESC, id DESC
+	`, ip, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delisting tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []DelistingTask{}
+	for rows.Next() {
+		task, err := scanDelistingTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delisting task: %w", err)
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// UpdateDelistingTaskStatus moves a task to a new status and records the
+// change as an action on the IP. Setting the current status changes nothing;
+// resolved tasks cannot be changed.
+func UpdateDelistingTaskStatus(id int, status, comment, author string) (*DelistingTask, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	task, err := scanDelistingTask(tx.QueryRow(`SELECT `+delistingTaskColumns+` FROM delisting_tasks WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDelistingTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delisting task: %w", err)
+	}
+	if task.Status == status {
+		return task, nil
+	}
+	if task.Status == DelistingResolved {
+		return nil, ErrDelistingTaskResolved
+	}
+
+	previous := task.Status
+	task, err = scanDelistingTask(tx.QueryRow(`
+		UPDATE delisting_tasks
+		SET status = $2, updated_at = NOW(),
+		    resolved_at = CASE WHEN $2 = '`+DelistingResolved+`' THEN NOW() END
+		WHERE id = $1
+		RETURNING `+delistingTaskColumns,
+		id, status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update delisting task: %w", err)
+	}
+	if err := recordDelistingAction(tx, task, DelistingUpdatedAction, previous, comment, author); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit delisting task: %w", err)
+	}
+	return task, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// FailureCounterBucket is the width of the buckets failures are counted in
+const FailureCounterBucket = time.Minute
+
+// FailureClassifier names the counter kind a failure is counted under
+type FailureClassifier func(SMTPFailure) string
+
+var failureClassifier FailureClassifier
+
+// SetFailureClassifier makes StoreSMTPFailure count every failure it stores
+// in failure_counters, under the kind classify returns. Every process that
+// ingests failures must set it, or the counters miss its failures; nil
+// keeps no counters.
+func SetFailureClassifier(classify FailureClassifier) {
+	failureClassifier = classify
+}
+
+// FailureCounter is the number of failures of one kind an IP had in one
+// bucket, per MTA instance, organizational domain and enhanced code
+type FailureCounter struct {
+	SendingIP    string    `json:"sending_ip"`
+	BucketStart  time.Time `json:"bucket_start"`
+	MTAInstance  string    `json:"mta_instance,omitempty"`
+	Domain       string    `json:"domain"`
+	EnhancedCode string    `json:"enhanced_code,omitempty"`
+	Kind         string    `json:"kind"`
+	Failures     int       `json:"failures"`
+}
+
+// countFailureQuery adds a stored failure to its counter in the statement
+// that stores it, so the two cannot disagree. It only counts when the
+// insert returned a row; duplicates are not counted.
+const countFailureQuery = `
+		, counted AS (
+			INSERT INTO failure_counters (sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind, failures)
+			SELECT $1, $24, $12, $18, $5, $23, 1 FROM stored
+			ON CONFLICT (sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind)
+			DO UPDATE SET failures = failure_counters.failures + 1
+		)
+		SELECT id FROM stored
+	`
+
+// GetFailureCounters returns the counters of an IP from the bucket holding
+// since onwards, for one MTA instance or, when empty, all of them
+func GetFailureCounters(ip, instance string, since time.Time) ([]FailureCounter, error) {
+	query := `
+		SELECT sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind, failures
+		FROM failure_counters
+		WHERE sending_ip = $1 AND bucket_start >= $2
+	`
+	args := []interface{}{ip, since.Truncate(FailureCounterBucket)}
+	if instance != "" {
+		query += ` AND mta_instance = $3`
+		args = append(args, instance)
+	}
+	query += ` ORDER BY bucket_start DESC`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failure counters: %w", err)
+	}
+	defer rows.Close()
+
+	var counters []FailureCounter
+	for rows.Next() {
+		var c FailureCounter
+		if err := rows.Scan(&c.SendingIP, &c.BucketStart, &c.MTAInstance, &c.Domain, &c.EnhancedCode, &c.Kind, &c.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan failure counter: %w", err)
+		}
+		counters = append(counters, c)
+	}
+	return counters, rows.Err()
+}
+
+// SumFailureCounters counts the failures of an IP from the bucket holding
+// since onwards, whatever their kind
+func SumFailureCounters(ip string, since time.Time) (int, error) {
+	var total int
+	query := `SELECT COALESCE(SUM(failures), 0) FROM failure_counters WHERE sending_ip = $1 AND bucket_start >= $2`
+	if err := DB.QueryRow(query, ip, since.Truncate(FailureCounterBucket)).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to sum failure counters: %w", err)
+	}
+	return total, nil
+}
+
+// GetFailureCountingSince returns the time from which the counters hold
+// every stored failure. Earlier buckets are missing or incomplete.
+func GetFailureCountingSince() (time.Time, error) {
+	var since time.Time
+	if err := DB.QueryRow(`SELECT counting_since FROM failure_counter_state`).Scan(&since); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get failure counting start: %w", err)
+	}
+	return since, nil
+}
+
+// PruneFailureCounters deletes the counters of buckets before olderThan and
+// moves the counting start up to it, so windows reaching further back are
+// read from raw failures. Returns the rows deleted and the counting start.
+func PruneFailureCounters(olderThan time.Time) (int64, time.Time, error) {
+	olderThan = olderThan.Truncate(FailureCounterBucket)
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM failure_counters WHERE bucket_start < $1`, olderThan)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to prune failure counters: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	var since time.Time
+	query := `UPDATE failure_counter_state SET counting_since = GREATEST(counting_since, $1) RETURNING counting_since`
+	if err := tx.QueryRow(query, olderThan).Scan(&since); err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to move failure counting start: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to commit failure counter pruning: %w", err)
+	}
+	return rowsAffected, since, nil
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// FaultHook is called before every connection, statement, transaction and
+// ping; an error fails the call. It exists for failure injection in staging.
+type FaultHook func(ctx context.Context) error
+
+var faultHook FaultHook
+
+// SetFaultHook makes Connect route every call through hook. It must be set
+// before Connect; nil connects without one.
+func SetFaultHook(hook FaultHook) {
+	faultHook = hook
+}
+
+// faultConnector wraps the connections of a connector in faultConns
+type faultConnector struct {
+	connector driver.Connector
+	hook      FaultHook
+}
+
+func (c *faultConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.hook(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{conn: conn, hook: c.hook}, nil
+}
+
+func (c *faultConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// faultConn calls the hook before delegating to the driver's connection
+type faultConn struct {
+	conn driver.Conn
+	hook FaultHook
+}
+
+func (c *faultConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *faultConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.hook(ctx); err != nil {
+		return nil, err
+	}
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.hook(ctx); err != nil {
+		return nil, err
+	}
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.conn.Begin()
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.hook(ctx); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.hook(ctx); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) Ping(ctx context.Context) error {
+	if err := c.hook(ctx); err != nil {
+		return err
+	}
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// FeatureFlagOverride is a flag value set at runtime, which wins over the
+// flag's config default
+type FeatureFlagOverride struct {
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureFlagChange is one audited toggle of a flag
+type FeatureFlagChange struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Previous is the effective value before the toggle
+	Previous  bool      `json:"previous"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListFeatureFlagOverrides returns every flag set at runtime
+func ListFeatureFlagOverrides() ([]FeatureFlagOverride, error) {
+	rows, err := DB.Query(`SELECT name, enabled, updated_by, updated_at FROM feature_flags ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []FeatureFlagOverride{}
+	for rows.Next() {
+		var o FeatureFlagOverride
+		if err := rows.Scan(&o.Name, &o.Enabled, &o.UpdatedBy, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetFeatureFlag stores the flag's new value and its audit record in one
+// transaction
+func SetFeatureFlag(change *FeatureFlagChange) (*FeatureFlagChange, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO feature_flags (name, enabled, updated_by, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, change.Name, change.Enabled, change.Author)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set feature flag: %w", err)
+	}
+
+	created := *change
+	err = tx.QueryRow(`
+		INSERT INTO feature_flag_changes (name, enabled, previous, author, comment)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at
+	`, change.Name, change.Enabled, change.Previous, change.Author, change.Comment).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record feature flag change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit feature flag change: %w", err)
+	}
+	return &created, nil
+}
+
+// ListFeatureFlagChanges returns the newest toggles of a flag first
+func ListFeatureFlagChanges(name string, limit int) ([]FeatureFlagChange, error) {
+	rows, err := DB.Query(`
+		SELECT id, name, enabled, previous, author, COALESCE(comment, ''), created_at
+		FROM feature_flag_changes
+		WHERE name = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flag changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []FeatureFlagChange{}
+	for rows.Next() {
+		var c FeatureFlagChange
+		if err := rows.Scan(&c.ID, &c.Name, &c.Enabled, &c.Previous, &c.Author, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
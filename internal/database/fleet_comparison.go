@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// PeriodIPStats summarizes an IP's aggregation runs in a period
+type PeriodIPStats struct {
+	IP   string
+	Runs int
+	// RejectionRatio is averaged over the runs
+	RejectionRatio float64
+	// Status is the status of the last run
+	Status string
+}
+
+// PeriodCodeCount is how many failures of an IP had a code in a period.
+// Failures without an enhanced code are counted under their SMTP code.
+type PeriodCodeCount struct {
+	IP    string
+	Code  string
+	Count int
+}
+
+// GetPeriodIPStats summarizes the aggregation history of every IP with a
+// run in [from, to)
+func GetPeriodIPStats(from, to time.Time) ([]PeriodIPStats, error) {
+	rows, err := DB.Query(`
+		SELECT h.ip, COUNT(*), AVG(h.rejection_ratio)::float8,
+		       (array_agg(h.status ORDER BY h.recorded_at DESC))[1]
+		FROM ip_reputation_history h
+		WHERE h.recorded_at >= $1 AND h.recorded_at < $2
+		GROUP BY h.ip
+		ORDER BY h.ip
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query period IP stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []PeriodIPStats{}
+	for rows.Next() {
+		var s PeriodIPStats
+		if err := rows.Scan(&s.IP, &s.Runs, &s.RejectionRatio, &s.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan period IP stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// GetPeriodFailureCodes counts the failures of every IP per code in
+// [from, to)
+func GetPeriodFailureCodes(from, to time.Time) ([]PeriodCodeCount, error) {
+	rows, err := DB.Query(`
+		SELECT sending_ip, COALESCE(NULLIF(enhanced_code, ''), smtp_code::text, 'unknown') AS code, COUNT(*)
+		FROM smtp_failures
+		WHERE timestamp >= $1 AND timestamp < $2
+		GROUP BY sending_ip, code
+		ORDER BY sending_ip, code
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query period failure codes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []PeriodCodeCount{}
+	for rows.Next() {
+		var c PeriodCodeCount
+		if err := rows.Scan(&c.IP, &c.Code, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan period failure code: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
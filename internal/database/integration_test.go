@@ -0,0 +1,3054 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// schemaPath is the init script used by docker-compose, reused as the test migration
+var schemaPath = filepath.Join("..", "..", "Context", "Data", "init.sql")
+
+// testDSN is the test database, for tests opening connections of their own
+var testDSN string
+
+// TestMain starts a disposable Postgres container, applies the schema and
+// points the package-level DB at it for the duration of the test run.
+// Run with: go test -tags=integration ./internal/database/...
+func TestMain(m *testing.M) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		fmt.Printf("Could not construct docker pool: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := pool.Client.Ping(); err != nil {
+		fmt.Printf("Could not connect to docker: %v\n", err)
+		os.Exit(1)
+	}
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "13",
+		Env: []string{
+			"POSTGRES_USER=postgres",
+			"POSTGRES_PASSWORD=postgres",
+			"POSTGRES_DB=integration",
+		},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		fmt.Printf("Could not start postgres container: %v\n", err)
+		os.Exit(1)
+	}
+	resource.Expire(300)
+
+	dsn := fmt.Sprintf(
+		"host=localhost port=%s user=postgres password=postgres dbname=integration sslmode=disable",
+		resource.GetPort("5432/tcp"),
+	)
+	testDSN = dsn
+
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	pool.MaxWait = 60 * time.Second
+	if err := pool.Retry(func() error {
+		return Connect(dsn, log)
+	}); err != nil {
+		pool.Purge(resource)
+		fmt.Printf("Could not connect to postgres: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := applySchema(DB); err != nil {
+		pool.Purge(resource)
+		fmt.Printf("Could not apply schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	Close()
+	if err := pool.Purge(resource); err != nil {
+		fmt.Printf("Could not purge postgres container: %v\n", err)
+	}
+
+	os.Exit(code)
+}
+
+// applySchema runs the init script against the test database
+func applySchema(db *sql.DB) error {
+	schema, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	if _, err := db.Exec(string(schema)); err != nil {
+		return fmt.Errorf("failed to execute schema: %w", err)
+	}
+
+	return nil
+}
+
+// resetTables truncates tables touched by the tests so each test starts clean
+func resetTables(t *testing.T) {
+	t.Helper()
+
+	_, err := DB.Exec(`TRUNCATE smtp_failures, failure_counters, sending_volumes, sent_volume, sent_volume_events, slo_measurements, ip_reputation_metrics, ip_actions, ip_action_comments, ip_reputation_history, dnsbl_checks RESTART IDENTITY`)
+	if err != nil {
+		t.Fatalf("Failed to reset tables: %v", err)
+	}
+}
+
+func newFailure(ip, eventID string, ts time.Time) *SMTPFailure {
+	return &SMTPFailure{
+		SendingIP:       ip,
+		RecipientEmail:  "user@gmail.com",
+		RecipientDomain: "gmail.com",
+		SMTPCode:        550,
+		EnhancedCode:    "5.7.1",
+		Reason:          "IP reputation",
+		MXServer:        "mx.gmail.com",
+		Timestamp:       ts,
+		EventID:         eventID,
+		AttemptNumber:   1,
+	}
+}
+
+// TestInsertSMTPFailureDeduplicates tests that duplicate event IDs are ignored
+func TestInsertSMTPFailureDeduplicates(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	if err := InsertSMTPFailure(newFailure("192.0.2.1", "evt-1", now)); err != nil {
+		t.Fatalf("First insert failed: %v", err)
+	}
+
+	if err := InsertSMTPFailure(newFailure("192.0.2.1", "evt-1", now)); err != nil {
+		t.Fatalf("Duplicate insert should be silently ignored, got: %v", err)
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.1", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+
+	if len(failures) != 1 {
+		t.Errorf("Expected 1 failure after duplicate insert, got %d", len(failures))
+	}
+}
+
+// TestStoreSMTPFailureContentDedup tests that replays under new event IDs are caught by the content hash
+func TestStoreSMTPFailureContentDedup(t *testing.T) {
+	resetTables(t)
+	SetContentDedupWindow(5 * time.Second)
+	defer SetContentDedupWindow(0)
+
+	now := time.Now().UTC().Truncate(5 * time.Second)
+	retry := newFailure("192.0.2.1", "evt-5", now)
+	retry.AttemptNumber = 2
+	tests := []struct {
+		name    string
+		failure *SMTPFailure
+		want    string
+	}{
+		{"first", newFailure("192.0.2.1", "evt-1", now), FailureStored},
+		{"same event", newFailure("192.0.2.1", "evt-1", now), FailureDuplicateEvent},
+		{"new event id", newFailure("192.0.2.1", "evt-2", now.Add(2*time.Second)), FailureDuplicateContent},
+		{"next bucket", newFailure("192.0.2.1", "evt-3", now.Add(5*time.Second)), FailureStored},
+		{"other ip", newFailure("192.0.2.2", "evt-4", now), FailureStored},
+		{"retry", retry, FailureStored},
+	}
+
+	for _, tt := range tests {
+		got, err := StoreSMTPFailure(tt.failure)
+		if err != nil {
+			t.Fatalf("%s: StoreSMTPFailure failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: StoreSMTPFailure() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	SetContentDedupWindow(0)
+	if got, err := StoreSMTPFailure(newFailure("192.0.2.1", "evt-6", now)); err != nil || got != FailureStored {
+		t.Errorf("Expected content dedup off with a zero window, got %s %v", got, err)
+	}
+}
+
+// TestInsertSMTPFailureParsesEnhancedCode tests that codes are canonicalized
+// and malformed ones are kept without parsed columns
+func TestInsertSMTPFailureParsesEnhancedCode(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	padded := newFailure("192.0.2.1", "evt-padded", now)
+	padded.EnhancedCode = "5.7.001"
+	garbage := newFailure("192.0.2.1", "evt-garbage", now)
+	garbage.EnhancedCode = "5.7"
+	for _, f := range []*SMTPFailure{padded, garbage} {
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+
+	var code string
+	var class, subject, detail sql.NullInt16
+	query := `SELECT enhanced_code, enhanced_class, enhanced_subject, enhanced_detail FROM smtp_failures WHERE event_id = $1`
+	if err := DB.QueryRow(query, "evt-padded").Scan(&code, &class, &subject, &detail); err != nil {
+		t.Fatal(err)
+	}
+	if code != "5.7.1" || class.Int16 != 5 || subject.Int16 != 7 || detail.Int16 != 1 {
+		t.Errorf("Unexpected parsed code: %s %v.%v.%v", code, class, subject, detail)
+	}
+	if err := DB.QueryRow(query, "evt-garbage").Scan(&code, &class, &subject, &detail); err != nil {
+		t.Fatal(err)
+	}
+	if code != "5.7" || class.Valid || subject.Valid || detail.Valid {
+		t.Errorf("Expected a malformed code to be kept unparsed, got %s %v", code, class)
+	}
+}
+
+// TestAggregationWindows tests that window queries only return recent failures
+func TestAggregationWindows(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	inserts := []*SMTPFailure{
+		newFailure("192.0.2.10", "evt-recent-1", now.Add(-5*time.Minute)),
+		newFailure("192.0.2.10", "evt-recent-2", now.Add(-10*time.Minute)),
+		newFailure("192.0.2.10", "evt-old", now.Add(-2*time.Hour)),
+		newFailure("192.0.2.11", "evt-other-old", now.Add(-3*time.Hour)),
+	}
+	for _, f := range inserts {
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	since := now.Add(-15 * time.Minute)
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.10", since)
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	if len(failures) != 2 {
+		t.Errorf("Expected 2 failures in 15m window, got %d", len(failures))
+	}
+
+	ips, err := GetIPsNeedingAggregation(since)
+	if err != nil {
+		t.Fatalf("GetIPsNeedingAggregation failed: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "192.0.2.10" {
+		t.Errorf("Expected only 192.0.2.10 to need aggregation, got %v", ips)
+	}
+}
+
+// TestUpsertIPReputationMetrics tests insert and update of the metrics row
+func TestUpsertIPReputationMetrics(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	metrics := &IPReputationMetrics{
+		IP:                       "192.0.2.20",
+		WindowStart:              now.Add(-15 * time.Minute),
+		WindowEnd:                now,
+		TotalSent:                100,
+		TotalRejected:            2,
+		RejectionRatio:           0.02,
+		UniqueDomainsRejected:    1,
+		DistinctRejectionReasons: map[string]int{"5.7.1": 2},
+		MajorProvidersRejecting:  []string{"gmail.com"},
+		Status:                   "warning",
+		LastUpdated:              now,
+		Metadata:                 map[string]interface{}{"issue_type": "mixed_issues"},
+	}
+
+	if err := UpsertIPReputationMetrics(metrics); err != nil {
+		t.Fatalf("Initial upsert failed: %v", err)
+	}
+	firstID := metrics.ID
+
+	metrics.Status = "quarantine"
+	metrics.TotalRejected = 5
+	if err := UpsertIPReputationMetrics(metrics); err != nil {
+		t.Fatalf("Second upsert failed: %v", err)
+	}
+
+	if metrics.ID != firstID {
+		t.Errorf("Upsert should keep the same row, got IDs %d and %d", firstID, metrics.ID)
+	}
+
+	stored, err := GetIPReputationMetrics("192.0.2.20")
+	if err != nil {
+		t.Fatalf("GetIPReputationMetrics failed: %v", err)
+	}
+
+	if stored.Status != "quarantine" || stored.TotalRejected != 5 {
+		t.Errorf("Expected updated row, got status=%s total_rejected=%d", stored.Status, stored.TotalRejected)
+	}
+
+	if stored.DistinctRejectionReasons["5.7.1"] != 2 {
+		t.Errorf("Expected JSONB reasons to round-trip, got %v", stored.DistinctRejectionReasons)
+	}
+
+	if _, err := GetIPReputationMetrics("192.0.2.99"); err == nil {
+		t.Error("Expected not found error for unknown IP")
+	}
+}
+
+// TestCleanOldSMTPFailures tests the retention cleanup
+func TestCleanOldSMTPFailures(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	if err := InsertSMTPFailure(newFailure("192.0.2.30", "evt-keep", now.Add(-time.Hour))); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := InsertSMTPFailure(newFailure("192.0.2.30", "evt-drop", now.Add(-48*time.Hour))); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	deleted, err := CleanOldSMTPFailures(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("CleanOldSMTPFailures failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 row deleted, got %d", deleted)
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.30", now.Add(-72*time.Hour))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].EventID != "evt-keep" {
+		t.Errorf("Expected only evt-keep to remain, got %v", failures)
+	}
+}
+
+// TestEraseRecipientKeepsAggregates tests that erasure scrubs PII but keeps rows
+func TestEraseRecipientKeepsAggregates(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	target := newFailure("192.0.2.4", "evt-erase-1", now)
+	target.RecipientEmail = "Alice@gmail.com"
+	target.Reason = "550 5.7.1 <alice@gmail.com> rejected"
+	if err := InsertSMTPFailure(target); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := InsertSMTPFailure(newFailure("192.0.2.4", "evt-erase-2", now)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	rows, err := EraseRecipient("alice@gmail.com")
+	if err != nil {
+		t.Fatalf("EraseRecipient failed: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("Expected 1 row anonymized, got %d", rows)
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.4", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("Expected both rows to remain, got %d", len(failures))
+	}
+
+	for _, f := range failures {
+		if f.EventID != "evt-erase-1" {
+			if f.RecipientEmail != "user@gmail.com" {
+				t.Errorf("Expected other recipient untouched, got %q", f.RecipientEmail)
+			}
+			continue
+		}
+		if f.RecipientEmail != ErasedMarker+"@gmail.com" {
+			t.Errorf("Expected erased address, got %q", f.RecipientEmail)
+		}
+		if f.Reason != "550 5.7.1 <"+ErasedMarker+"> rejected" {
+			t.Errorf("Expected address scrubbed from reason, got %q", f.Reason)
+		}
+		if f.RecipientDomain != "gmail.com" {
+			t.Errorf("Expected domain to be kept, got %q", f.RecipientDomain)
+		}
+	}
+}
+
+// TestAnonymizeSMTPFailuresBefore tests the age-based anonymization pass
+func TestAnonymizeSMTPFailuresBefore(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	old := newFailure("192.0.2.5", "evt-old", now.Add(-48*time.Hour))
+	old.Reason = "rejected user@gmail.com"
+	if err := InsertSMTPFailure(old); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := InsertSMTPFailure(newFailure("192.0.2.5", "evt-new", now)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	rows, err := AnonymizeSMTPFailuresBefore(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("AnonymizeSMTPFailuresBefore failed: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("Expected 1 row anonymized, got %d", rows)
+	}
+
+	// Already anonymized rows are skipped on the next pass
+	rows, err = AnonymizeSMTPFailuresBefore(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Second pass failed: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("Expected second pass to skip anonymized rows, got %d", rows)
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.5", now.Add(-72*time.Hour))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	for _, f := range failures {
+		switch f.EventID {
+		case "evt-old":
+			if f.RecipientEmail != ErasedMarker+"@gmail.com" || f.Reason != "rejected "+ErasedMarker {
+				t.Errorf("Expected old row anonymized, got %q / %q", f.RecipientEmail, f.Reason)
+			}
+		case "evt-new":
+			if f.RecipientEmail != "user@gmail.com" {
+				t.Errorf("Expected recent row untouched, got %q", f.RecipientEmail)
+			}
+		}
+	}
+}
+
+// TestFailuresByInstance tests per-instance filtering and breakdown
+func TestFailuresByInstance(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	for i, instance := range []string{"mta-1", "mta-1", "mta-2", ""} {
+		f := newFailure("192.0.2.6", fmt.Sprintf("evt-inst-%d", i), now)
+		f.MTAInstance = instance
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	failures, err := GetSMTPFailuresByIPAndInstance("192.0.2.6", "mta-1", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIPAndInstance failed: %v", err)
+	}
+	if len(failures) != 2 {
+		t.Errorf("Expected 2 failures for mta-1, got %d", len(failures))
+	}
+
+	all, err := GetSMTPFailuresByIP("192.0.2.6", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 failures across instances, got %d", len(all))
+	}
+
+	instances, err := GetInstanceRejections("192.0.2.6", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetInstanceRejections failed: %v", err)
+	}
+	if len(instances) != 3 {
+		t.Fatalf("Expected 3 instance groups, got %d", len(instances))
+	}
+	if instances[0].Instance != "mta-1" || instances[0].TotalRejected != 2 {
+		t.Errorf("Expected mta-1 first with 2 rejections, got %+v", instances[0])
+	}
+}
+
+// TestUserCRUD tests user update, delete, pagination and conflict mapping
+func TestUserCRUD(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE users RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset users: %v", err)
+	}
+
+	first, err := CreateUser("alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := CreateUser("bob", "bob@example.com"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	var conflict *UserConflictError
+	if _, err := CreateUser("alice", "other@example.com"); !errors.As(err, &conflict) || conflict.Field != "username" {
+		t.Errorf("Expected username conflict, got %v", err)
+	}
+	if _, err := CreateUser("carol", "bob@example.com"); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists for duplicate email, got %v", err)
+	}
+
+	users, total, err := ListUsers(1, 1)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if total != 2 || len(users) != 1 || users[0].Username != "bob" {
+		t.Errorf("Unexpected page: total=%d users=%+v", total, users)
+	}
+
+	email := "alice@example.org"
+	updated, err := UpdateUser(first.ID, nil, &email)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.Email != email || updated.Username != "alice" {
+		t.Errorf("Unexpected updated user: %+v", updated)
+	}
+	if missing, err := UpdateUser(9999, &email, nil); err != nil || missing != nil {
+		t.Errorf("Expected nil, nil for missing user, got %+v, %v", missing, err)
+	}
+
+	if found, err := DeleteUser(first.ID); err != nil || !found {
+		t.Errorf("Expected user to be deleted, got %v, %v", found, err)
+	}
+	if found, _ := DeleteUser(first.ID); found {
+		t.Error("Expected second delete to report not found")
+	}
+}
+
+// TestRefreshTokensAreSingleUse tests refresh token rotation and revocation
+func TestRefreshTokensAreSingleUse(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE users RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset users: %v", err)
+	}
+
+	user, err := CreateUser("operator", "operator@example.com")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if found, err := SetUserPassword(user.ID, "$2a$10$hash"); err != nil || !found {
+		t.Fatalf("SetUserPassword failed: %v", err)
+	}
+	if _, hash, err := GetUserCredentials("operator"); err != nil || hash != "$2a$10$hash" {
+		t.Errorf("Expected stored hash, got %q, %v", hash, err)
+	}
+	if missing, _, err := GetUserCredentials("nobody"); err != nil || missing != nil {
+		t.Errorf("Expected nil user for unknown username, got %+v, %v", missing, err)
+	}
+
+	if err := CreateRefreshToken(user.ID, "hash-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateRefreshToken failed: %v", err)
+	}
+	if err := CreateRefreshToken(user.ID, "hash-expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateRefreshToken failed: %v", err)
+	}
+
+	token, err := ConsumeRefreshToken("hash-1")
+	if err != nil || token == nil || token.UserID != user.ID {
+		t.Fatalf("Expected token to be consumed, got %+v, %v", token, err)
+	}
+	if again, _ := ConsumeRefreshToken("hash-1"); again != nil {
+		t.Error("Expected consumed token to be rejected")
+	}
+	if expired, _ := ConsumeRefreshToken("hash-expired"); expired != nil {
+		t.Error("Expected expired token to be rejected")
+	}
+
+	CreateRefreshToken(user.ID, "hash-2", time.Now().Add(time.Hour))
+	if err := RevokeUserRefreshTokens(user.ID); err != nil {
+		t.Fatalf("RevokeUserRefreshTokens failed: %v", err)
+	}
+	if revoked, _ := ConsumeRefreshToken("hash-2"); revoked != nil {
+		t.Error("Expected revoked token to be rejected")
+	}
+}
+
+// TestOwnershipAssignments tests direct, pool and team ownership lookups
+func TestOwnershipAssignments(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE users, teams, ip_pools RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset ownership tables: %v", err)
+	}
+
+	alice, _ := CreateUser("alice", "alice@example.com")
+	bob, _ := CreateUser("bob", "bob@example.com")
+	team, err := CreateTeam("deliverability", "")
+	if err != nil {
+		t.Fatalf("CreateTeam failed: %v", err)
+	}
+	if err := AddTeamMember(team.ID, bob.ID); err != nil {
+		t.Fatalf("AddTeamMember failed: %v", err)
+	}
+	if err := AddTeamMember(team.ID, bob.ID); !errors.Is(err, ErrOwnershipConflict) {
+		t.Errorf("Expected ErrOwnershipConflict for duplicate member, got %v", err)
+	}
+	if err := AddTeamMember(team.ID, 9999); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected ErrOwnershipReference for unknown user, got %v", err)
+	}
+
+	pool, err := CreateIPPool("outbound", "")
+	if err != nil {
+		t.Fatalf("CreateIPPool failed: %v", err)
+	}
+	AddIPToPool(pool.ID, "192.0.2.10")
+	AddIPToPool(pool.ID, "192.0.2.11")
+
+	if _, err := CreateOwnershipAssignment(OwnerUser, alice.ID, TargetIP, "192.0.2.1"); err != nil {
+		t.Fatalf("CreateOwnershipAssignment failed: %v", err)
+	}
+	if _, err := CreateOwnershipAssignment(OwnerUser, alice.ID, TargetIP, "192.0.2.1"); !errors.Is(err, ErrOwnershipConflict) {
+		t.Errorf("Expected ErrOwnershipConflict for duplicate assignment, got %v", err)
+	}
+	if _, err := CreateOwnershipAssignment(OwnerTeam, team.ID, TargetPool, "outbound"); err != nil {
+		t.Fatalf("CreateOwnershipAssignment failed: %v", err)
+	}
+	if _, err := CreateOwnershipAssignment(OwnerTeam, team.ID, TargetPool, "missing"); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected ErrOwnershipReference for unknown pool, got %v", err)
+	}
+
+	owners, err := GetIPOwners("192.0.2.11")
+	if err != nil {
+		t.Fatalf("GetIPOwners failed: %v", err)
+	}
+	if len(owners) != 1 || owners[0].OwnerName != "deliverability" || owners[0].Via != "outbound" {
+		t.Errorf("Unexpected owners: %+v", owners)
+	}
+
+	ips, err := GetOwnedIPs(OwnerUser, bob.ID)
+	if err != nil {
+		t.Fatalf("GetOwnedIPs failed: %v", err)
+	}
+	if len(ips) != 2 || ips[0] != "192.0.2.10" || ips[1] != "192.0.2.11" {
+		t.Errorf("Expected bob to own the pool through his team, got %v", ips)
+	}
+
+	assignments, err := ListOwnershipAssignments(AssignmentFilter{IP: "192.0.2.10"})
+	if err != nil || len(assignments) != 1 || assignments[0].Target != "outbound" {
+		t.Errorf("Unexpected assignments for pool IP: %+v, %v", assignments, err)
+	}
+
+	// Deleting the team drops its assignments
+	if found, err := DeleteTeam(team.ID); err != nil || !found {
+		t.Fatalf("DeleteTeam failed: %v, %v", found, err)
+	}
+	if owners, _ := GetIPOwners("192.0.2.11"); len(owners) != 0 {
+		t.Errorf("Expected no owners after team deletion, got %+v", owners)
+	}
+}
+
+// TestDirectoryProvisioning tests deactivation and team member sync
+func TestDirectoryProvisioning(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE users, teams RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset users: %v", err)
+	}
+
+	alice, _ := CreateUser("alice", "alice@example.com")
+	bob, _ := CreateUser("bob", "bob@example.com")
+	if !alice.Active {
+		t.Error("Expected new users to be active")
+	}
+
+	if found, err := SetUserActive(alice.ID, false); err != nil || !found {
+		t.Fatalf("SetUserActive failed: %v, %v", found, err)
+	}
+	if user, _, err := GetUserCredentials("alice"); err != nil || user != nil {
+		t.Errorf("Expected inactive user to have no credentials, got %+v, %v", user, err)
+	}
+	if user, err := GetUserByUsername("alice"); err != nil || user == nil || user.Active {
+		t.Errorf("Expected inactive alice, got %+v, %v", user, err)
+	}
+
+	team, _ := CreateTeam("ops", "")
+	if err := SetTeamMembers(team.ID, []int{alice.ID, bob.ID}); err != nil {
+		t.Fatalf("SetTeamMembers failed: %v", err)
+	}
+	if err := SetTeamMembers(team.ID, []int{bob.ID}); err != nil {
+		t.Fatalf("SetTeamMembers failed: %v", err)
+	}
+	if err := SetTeamMembers(team.ID, []int{9999}); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected ErrOwnershipReference for unknown member, got %v", err)
+	}
+	if found, err := RenameTeam(team.ID, "sre"); err != nil || !found {
+		t.Fatalf("RenameTeam failed: %v, %v", found, err)
+	}
+
+	got, err := GetTeam(team.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetTeam failed: %+v, %v", got, err)
+	}
+	if got.Name != "sre" || len(got.MemberIDs) != 1 || got.MemberIDs[0] != bob.ID {
+		t.Errorf("Unexpected team after sync: %+v", got)
+	}
+}
+
+func TestConfigRevisions(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE config_revisions RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset config revisions: %v", err)
+	}
+
+	if latest, err := GetLatestConfigRevision(ConfigScopeReputation); err != nil || latest != nil {
+		t.Fatalf("Expected no revisions, got %+v, %v", latest, err)
+	}
+
+	first, err := CreateConfigRevision(&ConfigRevision{
+		Scope:  ConfigScopeReputation,
+		Config: []byte(`{"warning_rejection_ratio":0.1}`),
+		Author: "alice",
+	})
+	if err != nil {
+		t.Fatalf("CreateConfigRevision failed: %v", err)
+	}
+	if string(first.Diff) != "{}" {
+		t.Errorf("Expected empty diff by default, got %s", first.Diff)
+	}
+
+	rollback, err := CreateConfigRevision(&ConfigRevision{
+		Scope:      ConfigScopeReputation,
+		Config:     []byte(`{"warning_rejection_ratio":0.1}`),
+		Diff:       []byte(`{"warning_rejection_ratio":{"from":0.2,"to":0.1}}`),
+		Author:     "bob",
+		Comment:    "revert",
+		RollbackOf: &first.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateConfigRevision failed: %v", err)
+	}
+
+	latest, err := GetLatestConfigRevision(ConfigScopeReputation)
+	if err != nil || latest == nil || latest.ID != rollback.ID || latest.RollbackOf == nil || *latest.RollbackOf != first.ID {
+		t.Errorf("Unexpected latest revision: %+v, %v", latest, err)
+	}
+	if got, err := GetConfigRevision(9999); err != nil || got != nil {
+		t.Errorf("Expected nil for unknown revision, got %+v, %v", got, err)
+	}
+
+	history, err := ListConfigRevisions(ConfigScopeReputation, 10)
+	if err != nil || len(history) != 2 || history[0].ID != rollback.ID {
+		t.Errorf("Expected newest first, got %+v, %v", history, err)
+	}
+}
+
+func TestIPWindowMetrics(t *testing.T) {
+	ip := "192.0.2.77"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, w := range []IPWindowMetrics{
+		{IP: ip, WindowName: "24h", WindowMinutes: 1440, WindowStart: now.Add(-24 * time.Hour), WindowEnd: now, TotalSent: 5000, Status: "warning", LastUpdated: now},
+		{IP: ip, WindowName: "1h", WindowMinutes: 60, WindowStart: now.Add(-time.Hour), WindowEnd: now, TotalSent: 300, Status: "healthy", LastUpdated: now},
+		{IP: ip, WindowName: "1h", WindowMinutes: 60, WindowStart: now.Add(-time.Hour), WindowEnd: now, TotalSent: 400, Status: "healthy", LastUpdated: now},
+	} {
+		w := w
+		if err := UpsertIPWindowMetrics(&w); err != nil {
+			t.Fatalf("UpsertIPWindowMetrics failed: %v", err)
+		}
+	}
+
+	windows, err := GetIPWindowMetrics(ip)
+	if err != nil {
+		t.Fatalf("GetIPWindowMetrics failed: %v", err)
+	}
+	if len(windows) != 2 || windows[0].WindowName != "1h" || windows[0].TotalSent != 400 || windows[1].Status != "warning" {
+		t.Errorf("Unexpected windows: %+v", windows)
+	}
+
+	if err := DeleteStaleIPWindowMetrics(ip, []string{"24h"}); err != nil {
+		t.Fatalf("DeleteStaleIPWindowMetrics failed: %v", err)
+	}
+	if windows, _ := GetIPWindowMetrics(ip); len(windows) != 1 || windows[0].WindowName != "24h" {
+		t.Errorf("Expected only the 24h window to remain, got %+v", windows)
+	}
+}
+
+func TestSMTPAuthFailures(t *testing.T) {
+	ip := "192.0.2.78"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, f := range []SMTPAuthFailure{
+		{SendingIP: ip, RemoteHost: "smtp.relay.example", Mechanism: "PLAIN", Timestamp: now.Add(-2 * time.Hour), EventID: "auth-1", MTAInstance: "mta-1"},
+		{SendingIP: ip, RemoteHost: "smtp.relay.example", Mechanism: "PLAIN", Timestamp: now.Add(-time.Minute), EventID: "auth-2", MTAInstance: "mta-1"},
+		{SendingIP: ip, RemoteHost: "smtp.relay.example", Mechanism: "LOGIN", Timestamp: now, EventID: "auth-3", MTAInstance: "mta-2"},
+		{SendingIP: ip, RemoteHost: "smtp.relay.example", Mechanism: "LOGIN", Timestamp: now, EventID: "auth-3", MTAInstance: "mta-2"},
+	} {
+		f := f
+		if err := InsertSMTPAuthFailure(&f); err != nil {
+			t.Fatalf("InsertSMTPAuthFailure failed: %v", err)
+		}
+	}
+
+	since := now.Add(-time.Hour)
+	if count, err := CountSMTPAuthFailures(ip, "", since); err != nil || count != 2 {
+		t.Errorf("CountSMTPAuthFailures() = %d, %v; want 2", count, err)
+	}
+	if count, _ := CountSMTPAuthFailures(ip, "mta-1", since); count != 1 {
+		t.Errorf("Expected 1 failure for mta-1, got %d", count)
+	}
+
+	failures, err := GetSMTPAuthFailuresByIP(ip, "", since)
+	if err != nil {
+		t.Fatalf("GetSMTPAuthFailuresByIP failed: %v", err)
+	}
+	if len(failures) != 2 || failures[0].EventID != "auth-3" || failures[0].Mechanism != "LOGIN" {
+		t.Errorf("Unexpected failures: %+v", failures)
+	}
+}
+
+func TestSMTPFailureEventType(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	bounce := newFailure("192.0.2.79", "bounce-1", now)
+	bounce.EventType = "smtp.bounce"
+	for _, f := range []*SMTPFailure{newFailure("192.0.2.79", "failure-1", now), bounce} {
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.79", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetSMTPFailuresByIP failed: %v", err)
+	}
+	types := map[string]string{}
+	for _, f := range failures {
+		types[f.EventID] = f.EventType
+	}
+	if types["failure-1"] != "smtp.delivery.failure" || types["bounce-1"] != "smtp.bounce" {
+		t.Errorf("Unexpected event types: %v", types)
+	}
+}
+
+func TestIPAnnotations(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE ip_notes RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset notes: %v", err)
+	}
+
+	action := &IPAction{IP: "192.0.2.80", Action: "status_change", NewStatus: "quarantine", TriggeredBy: "automated", Metadata: map[string]interface{}{}, CreatedAt: time.Now()}
+	if err := InsertIPAction(action); err != nil {
+		t.Fatalf("InsertIPAction failed: %v", err)
+	}
+	if got, err := GetIPAction(action.ID); err != nil || got == nil || got.IP != "192.0.2.80" {
+		t.Fatalf("GetIPAction() = %+v, %v", got, err)
+	}
+	if got, _ := GetIPAction(action.ID + 1); got != nil {
+		t.Errorf("Expected nil for a missing action, got %+v", got)
+	}
+
+	for _, text := range []string{"ticket opened", "ticket closed"} {
+		if _, err := CreateIPActionComment(&IPActionComment{ActionID: action.ID, Author: "ops", Comment: text}); err != nil {
+			t.Fatalf("CreateIPActionComment failed: %v", err)
+		}
+	}
+	comments, err := ListIPActionComments([]int{action.ID})
+	if err != nil || len(comments[action.ID]) != 2 || comments[action.ID][0].Comment != "ticket opened" {
+		t.Errorf("ListIPActionComments() = %+v, %v", comments, err)
+	}
+
+	note, err := CreateIPNote(&IPNote{IP: "192.0.2.80", Author: "ops", Note: "transactional mail for customer X"})
+	if err != nil {
+		t.Fatalf("CreateIPNote failed: %v", err)
+	}
+	if notes, _ := ListIPNotes("192.0.2.80"); len(notes) != 1 || notes[0].Note != note.Note {
+		t.Errorf("Unexpected notes: %+v", notes)
+	}
+	if deleted, _ := DeleteIPNote("192.0.2.81", note.ID); deleted {
+		t.Error("Deleted a note through another IP")
+	}
+	if deleted, err := DeleteIPNote("192.0.2.80", note.ID); err != nil || !deleted {
+		t.Errorf("DeleteIPNote() = %v, %v", deleted, err)
+	}
+}
+
+func TestRejectionHeatmap(t *testing.T) {
+	resetTables(t)
+
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	for i, ratio := range []float64{0.02, 0.04} {
+		metrics := &IPReputationMetrics{IP: "192.0.2.90", Status: "warning", TotalSent: 500, RejectionRatio: ratio,
+			LastUpdated: hour.Add(time.Duration(10+i*20) * time.Minute)}
+		if err := InsertIPReputationHistory(metrics); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+	old := &IPReputationMetrics{IP: "192.0.2.90", Status: "healthy", LastUpdated: hour.Add(-48 * time.Hour)}
+	if err := InsertIPReputationHistory(old); err != nil {
+		t.Fatalf("InsertIPReputationHistory failed: %v", err)
+	}
+
+	buckets, err := GetRejectionHeatmap(hour.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetRejectionHeatmap failed: %v", err)
+	}
+	if len(buckets) != 1 || !buckets[0].Hour.Equal(hour) || math.Abs(buckets[0].Ratio-0.03) > 1e-9 {
+		t.Errorf("GetRejectionHeatmap() = %+v", buckets)
+	}
+}
+
+func TestCapacityForecastQueries(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE ip_pools RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset pools: %v", err)
+	}
+
+	pool, err := CreateIPPool("forecast", "")
+	if err != nil {
+		t.Fatalf("CreateIPPool failed: %v", err)
+	}
+	for _, ip := range []string{"192.0.2.91", "192.0.2.92"} {
+		if err := AddIPToPool(pool.ID, ip); err != nil {
+			t.Fatalf("AddIPToPool failed: %v", err)
+		}
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	history := []IPReputationMetrics{
+		{IP: "192.0.2.91", Status: "healthy", LastUpdated: day.Add(time.Hour)},
+		{IP: "192.0.2.91", Status: "quarantine", LastUpdated: day.Add(2 * time.Hour)},
+		{IP: "192.0.2.92", Status: "healthy", LastUpdated: day.Add(time.Hour)},
+		{IP: "192.0.2.99", Status: "healthy", LastUpdated: day.Add(time.Hour)},
+	}
+	for i := range history {
+		if err := InsertIPReputationHistory(&history[i]); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+
+	days, err := GetDailyPoolHealth(day)
+	if err != nil {
+		t.Fatalf("GetDailyPoolHealth failed: %v", err)
+	}
+	if len(days) != 1 || days[0].PoolID != pool.ID || days[0].Healthy != 1 || days[0].Tracked != 2 || !days[0].Day.Equal(day) {
+		t.Errorf("GetDailyPoolHealth() = %+v", days)
+	}
+
+	for _, ip := range []string{"192.0.2.91", "192.0.2.91", "192.0.2.93"} {
+		action := &IPAction{IP: ip, Action: "status_change", NewStatus: "quarantine", TriggeredBy: "automated", Metadata: map[string]interface{}{}, CreatedAt: time.Now()}
+		if err := InsertIPAction(action); err != nil {
+			t.Fatalf("InsertIPAction failed: %v", err)
+		}
+	}
+	if count, err := CountIPsEnteringStatus([]string{"quarantine", "blacklisted"}, day); err != nil || count != 2 {
+		t.Errorf("CountIPsEnteringStatus() = %d, %v; want 2", count, err)
+	}
+}
+
+func TestDomainNormalization(t *testing.T) {
+	if got := ExtractDomain("User@Mail.Gmail.COM."); got != "mail.gmail.com" {
+		t.Errorf("ExtractDomain() = %q", got)
+	}
+	if got := ExtractDomain("kunde@Bücher.example"); got != "xn--bcher-kva.example" {
+		t.Errorf("ExtractDomain() = %q", got)
+	}
+
+	SetSubdomainFolding(true)
+	defer SetSubdomainFolding(false)
+	if got := ExtractDomain("user@mx1.mail.yahoo.co.jp"); got != "yahoo.co.jp" {
+		t.Errorf("ExtractDomain() with folding = %q", got)
+	}
+
+	for _, domain := range []string{"Gmail.com", "gmail.com.", "eu.mail.yahoo.com"} {
+		if !IsMajorProvider(domain) {
+			t.Errorf("Expected %s to be a major provider", domain)
+		}
+	}
+	if IsMajorProvider("gmail.com.example.org") {
+		t.Error("Expected a lookalike domain not to be a major provider")
+	}
+}
+
+func TestOrganizationalDomainRollup(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	for i, domain := range []string{"mx1.mail.yahoo.co.jp", "yahoo.co.jp"} {
+		f := newFailure("192.0.2.95", fmt.Sprintf("evt-org-%d", i), now)
+		f.RecipientEmail, f.RecipientDomain = "user@"+domain, domain
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.95", now.Add(-time.Minute))
+	if err != nil || len(failures) != 2 {
+		t.Fatalf("GetSMTPFailuresByIP() = %d failures, %v", len(failures), err)
+	}
+	for _, f := range failures {
+		if f.OrganizationalDomain != "yahoo.co.jp" {
+			t.Errorf("OrganizationalDomain of %s = %q", f.RecipientDomain, f.OrganizationalDomain)
+		}
+	}
+
+	instances, err := GetInstanceRejections("192.0.2.95", now.Add(-time.Minute))
+	if err != nil || len(instances) != 1 || instances[0].UniqueDomains != 1 {
+		t.Errorf("GetInstanceRejections() = %+v, %v", instances, err)
+	}
+}
+
+// TestFailureTLSPolicy tests that a failure keeps the destination's TLS
+// policy and reads back without one when it was unknown
+func TestFailureTLSPolicy(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	for i, policy := range []string{"mta-sts-enforce", ""} {
+		f := newFailure("192.0.2.96", fmt.Sprintf("evt-tls-%d", i), now.Add(-time.Duration(i)*time.Second))
+		f.TLSPolicy = policy
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.96", now.Add(-time.Minute))
+	if err != nil || len(failures) != 2 {
+		t.Fatalf("GetSMTPFailuresByIP() = %d failures, %v", len(failures), err)
+	}
+	if failures[0].TLSPolicy != "mta-sts-enforce" || failures[1].TLSPolicy != "" {
+		t.Errorf("Unexpected TLS policies %q and %q", failures[0].TLSPolicy, failures[1].TLSPolicy)
+	}
+}
+
+// TestFeatureFlags tests that toggles upsert the override and keep an audit trail
+func TestFeatureFlags(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE feature_flags, feature_flag_changes RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset feature flags: %v", err)
+	}
+
+	if _, err := SetFeatureFlag(&FeatureFlagChange{Name: "auto_rotation", Enabled: true, Previous: false, Author: "user:alice", Comment: "pilot"}); err != nil {
+		t.Fatalf("SetFeatureFlag failed: %v", err)
+	}
+	second, err := SetFeatureFlag(&FeatureFlagChange{Name: "auto_rotation", Enabled: false, Previous: true, Author: "user:bob"})
+	if err != nil {
+		t.Fatalf("SetFeatureFlag failed: %v", err)
+	}
+	if second.ID != 2 || second.CreatedAt.IsZero() {
+		t.Errorf("Expected the second change to be stored, got %+v", second)
+	}
+
+	overrides, err := ListFeatureFlagOverrides()
+	if err != nil {
+		t.Fatalf("ListFeatureFlagOverrides failed: %v", err)
+	}
+	if len(overrides) != 1 || overrides[0].Enabled || overrides[0].UpdatedBy != "user:bob" {
+		t.Errorf("Expected one override set by bob, got %+v", overrides)
+	}
+
+	changes, err := ListFeatureFlagChanges("auto_rotation", 10)
+	if err != nil {
+		t.Fatalf("ListFeatureFlagChanges failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Author != "user:bob" || changes[1].Comment != "pilot" || !changes[1].Enabled {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
+
+func TestNotificationRoutes(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE teams, ip_pools, notification_routes RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset notification routes: %v", err)
+	}
+
+	pool, err := CreateIPPool("marketing", "")
+	if err != nil {
+		t.Fatalf("CreateIPPool failed: %v", err)
+	}
+	if err := AddIPToPool(pool.ID, "192.0.2.10"); err != nil {
+		t.Fatalf("AddIPToPool failed: %v", err)
+	}
+
+	route, err := CreateNotificationRoute(&NotificationRoute{PoolID: &pool.ID, Notifier: "slack", Target: "#marketing", Enabled: true, CreatedBy: "user:alice"})
+	if err != nil {
+		t.Fatalf("CreateNotificationRoute failed: %v", err)
+	}
+	if route.PoolName != "marketing" || route.TeamID != nil || route.Severity != "" {
+		t.Errorf("Unexpected route: %+v", route)
+	}
+	if _, err := CreateNotificationRoute(&NotificationRoute{PoolID: &pool.ID, Notifier: "slack", Target: "#marketing", Enabled: true, CreatedBy: "user:bob"}); !errors.Is(err, ErrOwnershipConflict) {
+		t.Errorf("Expected a conflict for a duplicate route, got %v", err)
+	}
+	missing := 999
+	if _, err := CreateNotificationRoute(&NotificationRoute{TeamID: &missing, Notifier: "email", Target: "ops@example.com", CreatedBy: "user:bob"}); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected a reference error for a missing team, got %v", err)
+	}
+
+	route.Severity = "critical"
+	route.Enabled = false
+	updated, err := UpdateNotificationRoute(route)
+	if err != nil || updated == nil || updated.Severity != "critical" || updated.Enabled {
+		t.Errorf("UpdateNotificationRoute = %+v, %v", updated, err)
+	}
+	if updated, err := UpdateNotificationRoute(&NotificationRoute{ID: 999, Notifier: "slack", Target: "#x"}); err != nil || updated != nil {
+		t.Errorf("Expected nil for a missing route, got %+v, %v", updated, err)
+	}
+
+	poolIDs, err := GetIPPoolIDs("192.0.2.10")
+	if err != nil || len(poolIDs) != 1 || poolIDs[0] != pool.ID {
+		t.Errorf("GetIPPoolIDs = %v, %v", poolIDs, err)
+	}
+
+	if found, err := DeleteNotificationRoute(route.ID); err != nil || !found {
+		t.Errorf("DeleteNotificationRoute = %v, %v", found, err)
+	}
+	routes, err := ListNotificationRoutes()
+	if err != nil || len(routes) != 0 {
+		t.Errorf("Expected no routes, got %+v, %v", routes, err)
+	}
+}
+
+func TestProtectedBlocks(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE protected_blocks RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset protected blocks: %v", err)
+	}
+
+	if _, err := ProtectBlock(&ProtectedBlock{BlockID: "block-mx", Reason: "mail servers", CreatedBy: "user:alice"}); err != nil {
+		t.Fatalf("ProtectBlock failed: %v", err)
+	}
+	updated, err := ProtectBlock(&ProtectedBlock{BlockID: "block-mx", Reason: "production MX", CreatedBy: "user:bob"})
+	if err != nil {
+		t.Fatalf("ProtectBlock failed: %v", err)
+	}
+	if updated.ID != 1 || updated.CreatedBy != "user:alice" || updated.Reason != "production MX" {
+		t.Errorf("Expected protecting again to update the reason only, got %+v", updated)
+	}
+
+	blocks, err := ListProtectedBlocks()
+	if err != nil || len(blocks) != 1 {
+		t.Fatalf("Expected one protected block, got %+v (%v)", blocks, err)
+	}
+
+	if err := UnprotectBlock("block-mx"); err != nil {
+		t.Fatalf("UnprotectBlock failed: %v", err)
+	}
+	if err := UnprotectBlock("block-mx"); !errors.Is(err, ErrProtectedBlockNotFound) {
+		t.Errorf("Expected ErrProtectedBlockNotFound, got %v", err)
+	}
+}
+
+func TestLocationReservationStats(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE ip_reservation_attempts RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset reservation attempts: %v", err)
+	}
+
+	ip := func(s string) *string { return &s }
+	ms := func(v int) *int { return &v }
+	attempts := []ReservationAttempt{
+		{AttemptUID: "fra-1", Location: "de/fra", IPAddress: ip("192.0.2.1"), Success: true, DurationMs: ms(1000)},
+		{AttemptUID: "fra-2", Location: "de/fra", IPAddress: ip("192.0.2.2"), WasBlacklisted: true, DurationMs: ms(2000)},
+		{AttemptUID: "fra-3", Location: "de/fra", DurationMs: ms(3000)},
+		{AttemptUID: "ewr-1", Location: "us/ewr", IPAddress: ip("192.0.2.3"), WasBlacklisted: true, DurationMs: ms(500)},
+		{AttemptUID: "ewr-old", Location: "us/ewr", IPAddress: ip("192.0.2.4"), Success: true, AttemptedAt: time.Now().Add(-48 * time.Hour)},
+	}
+	for i := range attempts {
+		if attempts[i].AttemptedAt.IsZero() {
+			attempts[i].AttemptedAt = time.Now()
+		}
+		attempts[i].BlacklistsFound = []string{}
+		attempts[i].Metadata = map[string]interface{}{}
+		if err := CreateReservationAttempt(&attempts[i]); err != nil {
+			t.Fatalf("CreateReservationAttempt failed: %v", err)
+		}
+	}
+
+	stats, err := GetLocationReservationStats(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("GetLocationReservationStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected two locations, got %+v", stats)
+	}
+	fra, ewr := stats[0], stats[1]
+	if fra.Attempts != 3 || fra.Checked != 2 || fra.BlacklistHitRate != 0.5 || fra.AttemptsPerCleanIP == nil || *fra.AttemptsPerCleanIP != 3 || fra.AvgLatencyMs != 2000 {
+		t.Errorf("Unexpected de/fra stats: %+v", fra)
+	}
+	if ewr.Attempts != 1 || ewr.BlacklistHitRate != 1 || ewr.AttemptsPerCleanIP != nil {
+		t.Errorf("Expected the old us/ewr attempt outside the window, got %+v", ewr)
+	}
+}
+
+func TestReservedIPReputationQuarantine(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE reserved_ips RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset reserved IPs: %v", err)
+	}
+
+	ip := &ReservedIP{
+		IPAddress:          "192.0.2.10",
+		ReservationBlockID: "block-1",
+		UID:                "uid-1",
+		Location:           "de/fra",
+		Status:             "in_use",
+		BlacklistDetails:   []string{},
+		ReservedAt:         time.Now(),
+		Metadata:           map[string]interface{}{"attachment": map[string]interface{}{"nic_id": "nic-1"}},
+	}
+	if err := CreateReservedIP(ip); err != nil {
+		t.Fatalf("CreateReservedIP failed: %v", err)
+	}
+
+	if err := QuarantineReservedIP(ip.ID, "in_use"); err != nil {
+		t.Fatalf("QuarantineReservedIP failed: %v", err)
+	}
+	quarantined, err := GetReservedIPByAddress("192.0.2.10")
+	if err != nil {
+		t.Fatalf("GetReservedIPByAddress failed: %v", err)
+	}
+	if quarantined.Status != "quarantined" || quarantined.Metadata["reputation_previous_status"] != "in_use" || quarantined.Metadata["attachment"] == nil {
+		t.Errorf("Expected the IP quarantined with its metadata kept, got %s %+v", quarantined.Status, quarantined.Metadata)
+	}
+
+	if err := RestoreReservedIPStatus(ip.ID, "in_use"); err != nil {
+		t.Fatalf("RestoreReservedIPStatus failed: %v", err)
+	}
+	restored, _ := GetReservedIPByID(ip.ID)
+	if _, ok := restored.Metadata["reputation_previous_status"]; ok || restored.Status != "in_use" {
+		t.Errorf("Expected the status restored and the key dropped, got %s %+v", restored.Status, restored.Metadata)
+	}
+
+	if _, err := GetReservedIPByAddress("192.0.2.99"); !errors.Is(err, ErrReservedIPNotFound) {
+		t.Errorf("Expected ErrReservedIPNotFound, got %v", err)
+	}
+	if err := QuarantineReservedIP(999, "reserved"); !errors.Is(err, ErrReservedIPNotFound) {
+		t.Errorf("Expected ErrReservedIPNotFound, got %v", err)
+	}
+}
+
+func TestSwapReservedIPs(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE reserved_ips RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset reserved IPs: %v", err)
+	}
+
+	assignedTo := "mta-1"
+	replaced := &ReservedIP{IPAddress: "192.0.2.10", ReservationBlockID: "block-2", UID: "uid-1", Location: "de/fra", Status: "in_use", AssignedTo: &assignedTo, BlacklistDetails: []string{}, ReservedAt: time.Now(), Metadata: map[string]interface{}{}}
+	spare := &ReservedIP{IPAddress: "192.0.2.20", ReservationBlockID: "block-2", UID: "uid-2", Location: "de/fra", Status: "reserved", BlacklistDetails: []string{}, ReservedAt: time.Now(), Metadata: map[string]interface{}{}}
+	for _, ip := range []*ReservedIP{replaced, spare} {
+		if err := CreateReservedIP(ip); err != nil {
+			t.Fatalf("CreateReservedIP failed: %v", err)
+		}
+	}
+	actions := func() []*IPAction {
+		return []*IPAction{
+			{IP: "192.0.2.10", Action: "ip_swapped_out", PreviousStatus: "in_use", NewStatus: "quarantined", Metadata: map[string]interface{}{}, CreatedAt: time.Now()},
+			{IP: "192.0.2.20", Action: "ip_swapped_in", PreviousStatus: "reserved", NewStatus: "in_use", Metadata: map[string]interface{}{}, CreatedAt: time.Now()},
+		}
+	}
+
+	// A replaced IP that is gone rolls back the claim
+	if err := SwapReservedIPs(999, spare.ID, &assignedTo, "192.0.2.10", actions()); !errors.Is(err, ErrReservedIPNotFound) {
+		t.Errorf("Expected ErrReservedIPNotFound, got %v", err)
+	}
+	if ip, _ := GetReservedIPByID(spare.ID); ip.Status != "reserved" || ip.UsageCount != 0 {
+		t.Errorf("Expected the failed swap rolled back, got %+v", ip)
+	}
+	if recorded, _ := GetIPActions("192.0.2.20", 10); len(recorded) != 0 {
+		t.Errorf("Expected no actions from the failed swap, got %+v", recorded)
+	}
+
+	swapped := actions()
+	if err := SwapReservedIPs(replaced.ID, spare.ID, &assignedTo, "192.0.2.10", swapped); err != nil {
+		t.Fatalf("SwapReservedIPs failed: %v", err)
+	}
+	claimed, _ := GetReservedIPByID(spare.ID)
+	if claimed.Status != "in_use" || claimed.AssignedTo == nil || *claimed.AssignedTo != "mta-1" || claimed.UsageCount != 1 || claimed.Metadata["swapped_for"] != "192.0.2.10" {
+		t.Errorf("Expected the IP in use for mta-1 in place of 192.0.2.10, got %+v", claimed)
+	}
+	quarantined, _ := GetReservedIPByID(replaced.ID)
+	if quarantined.Status != "quarantined" || quarantined.Metadata["reputation_previous_status"] != "reserved" {
+		t.Errorf("Expected the replaced IP quarantined for the reserve, got %+v", quarantined)
+	}
+	if swapped[0].ID == 0 || swapped[1].ID == 0 {
+		t.Errorf("Expected the swap actions stored, got %+v", swapped)
+	}
+
+	if err := SwapReservedIPs(replaced.ID, spare.ID, &assignedTo, "192.0.2.11", actions()); !errors.Is(err, ErrReservedIPUnavailable) {
+		t.Errorf("Expected an IP in use not claimed twice, got %v", err)
+	}
+	if recorded, _ := GetIPActions("192.0.2.10", 10); len(recorded) != 1 {
+		t.Errorf("Expected only the successful swap recorded, got %+v", recorded)
+	}
+}
+
+func TestIPRegistry(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE reserved_ips, ip_reputation_metrics, dnsbl_checks, ip_pools, ownership_assignments, teams RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset registry tables: %v", err)
+	}
+
+	reserved := &ReservedIP{IPAddress: "192.0.2.1", ReservationBlockID: "block-1", UID: "uid-1", Location: "de/fra", Status: "in_use", BlacklistDetails: []string{}, ReservedAt: time.Now(), Metadata: map[string]interface{}{}}
+	if err := CreateReservedIP(reserved); err != nil {
+		t.Fatalf("CreateReservedIP failed: %v", err)
+	}
+	if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: "192.0.2.1", Status: "warning", DistinctRejectionReasons: map[string]int{}, MajorProvidersRejecting: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+	}
+	if err := InsertDNSBLCheck(&DNSBLCheck{IP: "192.0.2.1", CheckedAt: time.Now().Add(-time.Hour), Listed: true, Listings: []string{"old"}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("InsertDNSBLCheck failed: %v", err)
+	}
+	if err := InsertDNSBLCheck(&DNSBLCheck{IP: "192.0.2.1", CheckedAt: time.Now(), Listings: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("InsertDNSBLCheck failed: %v", err)
+	}
+	pool, _ := CreateIPPool("marketing", "")
+	AddIPToPool(pool.ID, "192.0.2.1")
+	AddIPToPool(pool.ID, "192.0.2.2")
+	team, _ := CreateTeam("deliverability", "")
+	if _, err := CreateOwnershipAssignment(OwnerTeam, team.ID, TargetPool, "marketing"); err != nil {
+		t.Fatalf("CreateOwnershipAssignment failed: %v", err)
+	}
+
+	registry, err := ListIPRegistry()
+	if err != nil {
+		t.Fatalf("ListIPRegistry failed: %v", err)
+	}
+	if len(registry) != 2 {
+		t.Fatalf("Expected the reserved and the pool-only IP, got %+v", registry)
+	}
+	row := registry[0]
+	if row.IP != "192.0.2.1" || row.ReservedIPID == nil || *row.ReservationStatus != "in_use" || *row.ReputationStatus != "warning" {
+		t.Errorf("Unexpected joined row: %+v", row)
+	}
+	if row.DNSBLListed == nil || *row.DNSBLListed || len(row.Pools) != 1 || len(row.Owners) != 1 || row.Owners[0].Via != "marketing" {
+		t.Errorf("Expected the latest DNSBL check, the pool and its owner, got %+v", row)
+	}
+	if poolOnly := registry[1]; poolOnly.ReservedIPID != nil || poolOnly.ReputationStatus != nil || len(poolOnly.Owners) != 1 {
+		t.Errorf("Expected the pool-only IP without reservation or reputation, got %+v", poolOnly)
+	}
+}
+
+func TestLiveRejectionCounts(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	ages := []time.Duration{30 * time.Second, 3 * time.Minute, 10 * time.Minute, 20 * time.Minute}
+	for i, age := range ages {
+		failure := newFailure("192.0.2.1", fmt.Sprintf("live-%d", i), now.Add(-age))
+		failure.RecipientDomain = fmt.Sprintf("example%d.com", i)
+		if i == 1 {
+			failure.SMTPCode = 421
+		}
+		if err := InsertSMTPFailure(failure); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+
+	counts, err := GetLiveRejectionCounts("192.0.2.1", []int{1, 5, 15}, now)
+	if err != nil {
+		t.Fatalf("GetLiveRejectionCounts failed: %v", err)
+	}
+	if len(counts) != 3 || counts[0].Rejections != 1 || counts[1].Rejections != 2 || counts[2].Rejections != 3 {
+		t.Fatalf("Unexpected live counts: %+v", counts)
+	}
+	if counts[2].HardRejections != 2 || counts[2].UniqueDomains != 3 || counts[2].LastFailureAt == nil {
+		t.Errorf("Unexpected 15m window: %+v", counts[2])
+	}
+
+	empty, _ := GetLiveRejectionCounts("192.0.2.9", []int{1}, now)
+	if len(empty) != 1 || empty[0].Rejections != 0 || empty[0].LastFailureAt != nil {
+		t.Errorf("Expected an empty window for an unknown IP, got %+v", empty)
+	}
+}
+
+// TestJobs tests that job progress is stored and finished jobs expire
+func TestJobs(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE jobs`); err != nil {
+		t.Fatalf("Failed to reset jobs: %v", err)
+	}
+
+	created := time.Now().Add(-48 * time.Hour)
+	job := &Job{ID: "11111111-1111-1111-1111-111111111111", Type: "dnsbl_check", Subject: "192.0.2.1", Status: JobPending, CreatedBy: "alice", CreatedAt: created}
+	if err := CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	finished := created.Add(time.Second)
+	job.Status = JobSucceeded
+	job.Progress = &JobProgress{Done: 3, Total: 3}
+	job.Result = []byte(`{"listed":false}`)
+	job.StartedAt = &created
+	job.FinishedAt = &finished
+	job.CallbackStatus = CallbackDelivered
+	if err := UpdateJob(job); err != nil {
+		t.Fatalf("UpdateJob failed: %v", err)
+	}
+
+	stored, err := GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if stored.Status != JobSucceeded || string(stored.Result) != `{"listed": false}` || stored.CallbackStatus != CallbackDelivered || stored.FinishedAt == nil {
+		t.Errorf("Unexpected stored job: %+v", stored)
+	}
+	if stored.Progress == nil || *stored.Progress != (JobProgress{Done: 3, Total: 3}) {
+		t.Errorf("Expected the progress stored, got %+v", stored.Progress)
+	}
+	if _, err := GetJob("missing"); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if err := UpdateJob(&Job{ID: "missing", Status: JobFailed}); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound updating an unknown job, got %v", err)
+	}
+
+	deleted, err := DeleteJobsFinishedBefore(time.Now().Add(-24 * time.Hour))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected the finished job deleted, got %d %v", deleted, err)
+	}
+}
+
+// TestSpamtrapHits tests that re-sent reports are stored once and hits are counted per IP
+func TestSpamtrapHits(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE spamtrap_hits RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset spamtrap hits: %v", err)
+	}
+
+	now := time.Now()
+	hits := []*SpamtrapHit{
+		{SendingIP: "192.0.2.1", TrapType: TrapPristine, Source: "spamhaus", ReportID: "r-1", HitAt: now.Add(-time.Hour)},
+		{SendingIP: "192.0.2.1", TrapType: TrapRecycled, Source: "spamhaus", HitAt: now.Add(-30 * time.Hour)},
+		{SendingIP: "192.0.2.1", TrapType: TrapInternal, Source: "internal", HitAt: now.Add(-time.Minute)},
+		{SendingIP: "192.0.2.2", TrapType: TrapUnknown, Source: "internal", HitAt: now},
+	}
+	for _, hit := range hits {
+		stored, err := InsertSpamtrapHit(hit)
+		if err != nil || !stored || hit.ID == 0 {
+			t.Fatalf("InsertSpamtrapHit failed: stored=%v err=%v", stored, err)
+		}
+	}
+
+	resent := &SpamtrapHit{SendingIP: "192.0.2.1", TrapType: TrapPristine, Source: "spamhaus", ReportID: "r-1", HitAt: now}
+	if stored, err := InsertSpamtrapHit(resent); err != nil || stored {
+		t.Errorf("Expected a re-sent report to be skipped, got stored=%v err=%v", stored, err)
+	}
+	other := &SpamtrapHit{SendingIP: "192.0.2.1", TrapType: TrapPristine, Source: "abusix", ReportID: "r-1", HitAt: now}
+	if stored, err := InsertSpamtrapHit(other); err != nil || !stored {
+		t.Errorf("Expected the same report ID from another source to be stored, got stored=%v err=%v", stored, err)
+	}
+
+	count, err := CountSpamtrapHits("192.0.2.1", now.Add(-24*time.Hour))
+	if err != nil || count != 3 {
+		t.Errorf("Expected 3 hits within 24h, got %d %v", count, err)
+	}
+	listed, err := GetSpamtrapHitsByIP("192.0.2.1", now.Add(-24*time.Hour))
+	if err != nil || len(listed) != 3 || listed[0].Source != "abusix" || listed[2].ReportID != "r-1" {
+		t.Errorf("Unexpected hits: %+v %v", listed, err)
+	}
+}
+
+// TestAbuseComplaints tests that complaints are stored once per report and
+// that only abuse-type feedback counts
+func TestAbuseComplaints(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE abuse_complaints RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset abuse complaints: %v", err)
+	}
+
+	now := time.Now()
+	complaints := []*AbuseComplaint{
+		{SendingIP: "192.0.2.1", FeedbackType: "abuse", Reporter: "mail-provider.example", ReportID: "r-1", CampaignID: "spring-sale", ArrivedAt: now.Add(-time.Hour)},
+		{SendingIP: "192.0.2.1", FeedbackType: "fraud", Reporter: "mail-provider.example", ArrivedAt: now.Add(-2 * time.Hour)},
+		{SendingIP: "192.0.2.1", FeedbackType: "not-spam", Reporter: "mail-provider.example", ArrivedAt: now.Add(-time.Minute)},
+		{SendingIP: "192.0.2.1", FeedbackType: "abuse", Reporter: "mail-provider.example", ArrivedAt: now.Add(-30 * time.Hour)},
+		{SendingIP: "192.0.2.2", FeedbackType: "abuse", Reporter: "other.example", ArrivedAt: now},
+	}
+	for _, c := range complaints {
+		stored, err := InsertAbuseComplaint(c)
+		if err != nil || !stored || c.ID == 0 {
+			t.Fatalf("InsertAbuseComplaint failed: stored=%v err=%v", stored, err)
+		}
+	}
+
+	resent := &AbuseComplaint{SendingIP: "192.0.2.1", FeedbackType: "abuse", Reporter: "mail-provider.example", ReportID: "r-1", ArrivedAt: now}
+	if stored, err := InsertAbuseComplaint(resent); err != nil || stored {
+		t.Errorf("Expected a re-sent report to be skipped, got stored=%v err=%v", stored, err)
+	}
+
+	count, err := CountAbuseComplaints("192.0.2.1", now.Add(-24*time.Hour))
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 complaints within 24h, got %d %v", count, err)
+	}
+	listed, err := GetAbuseComplaintsByIP("192.0.2.1", now.Add(-24*time.Hour))
+	if err != nil || len(listed) != 3 || listed[0].FeedbackType != "not-spam" || listed[1].CampaignID != "spring-sale" {
+		t.Errorf("Unexpected complaints: %+v %v", listed, err)
+	}
+}
+
+// TestTLSReports tests that TLS reports are stored once and summarized per
+// IP and per destination
+func TestTLSReports(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE tls_reports, tls_report_policies, tls_failures RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset TLS reports: %v", err)
+	}
+
+	end := time.Now().Add(-time.Hour)
+	report := func(id string, end time.Time) *TLSReport {
+		return &TLSReport{
+			Organization: "Our MTA", ReportID: id, StartAt: end.Add(-24 * time.Hour), EndAt: end,
+			Policies: []TLSReportPolicy{
+				{PolicyDomain: "gmail.com", PolicyType: "sts", SuccessfulSessions: 90, FailedSessions: 10},
+				{PolicyDomain: "example.org", PolicyType: "tlsa", SuccessfulSessions: 50, FailedSessions: 50},
+			},
+			Failures: []TLSFailure{
+				{PolicyDomain: "gmail.com", PolicyType: "sts", SendingIP: "192.0.2.1", ResultType: "certificate-expired", FailedSessions: 10},
+				{PolicyDomain: "example.org", PolicyType: "tlsa", SendingIP: "192.0.2.1", ResultType: "dane-required", FailedSessions: 45},
+				{PolicyDomain: "example.org", PolicyType: "tlsa", ResultType: "dane-required", FailedSessions: 5},
+			},
+		}
+	}
+
+	for _, r := range []*TLSReport{report("r-1", end), report("r-2", end.Add(-24*time.Hour)), report("old", end.Add(-10*24*time.Hour))} {
+		stored, err := InsertTLSReport(r)
+		if err != nil || !stored || r.ID == 0 {
+			t.Fatalf("InsertTLSReport failed: stored=%v err=%v", stored, err)
+		}
+	}
+	if stored, err := InsertTLSReport(report("r-1", end)); err != nil || stored {
+		t.Errorf("Expected a re-sent report to be skipped, got stored=%v err=%v", stored, err)
+	}
+
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	count, err := CountTLSFailedSessions("192.0.2.1", since)
+	if err != nil || count != 110 {
+		t.Errorf("Expected 110 failed sessions, got %d %v", count, err)
+	}
+
+	failures, err := GetTLSFailureSummary("192.0.2.1", since)
+	if err != nil || len(failures) != 2 {
+		t.Fatalf("Unexpected failure summary: %+v %v", failures, err)
+	}
+	if failures[0].PolicyDomain != "example.org" || failures[0].FailedSessions != 90 || failures[0].Reports != 2 {
+		t.Errorf("Expected example.org's DANE failures first, got %+v", failures[0])
+	}
+
+	destinations, err := GetTLSDestinationSummary(since)
+	if err != nil || len(destinations) != 2 {
+		t.Fatalf("Unexpected destination summary: %+v %v", destinations, err)
+	}
+	if destinations[0].PolicyDomain != "example.org" || destinations[0].FailureRatio != 0.5 || destinations[1].SuccessfulSessions != 180 {
+		t.Errorf("Unexpected destinations: %+v", destinations)
+	}
+}
+
+// TestReputationFeeds tests that feed verdicts replace older ones per IP and
+// only unexpired verdicts of enabled feeds are returned
+func TestReputationFeeds(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE reputation_feeds, external_reputation_signals RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset reputation feeds: %v", err)
+	}
+
+	intel, err := CreateReputationFeed("threat-intel", "", 2, "hash-intel", "admin")
+	if err != nil {
+		t.Fatalf("CreateReputationFeed failed: %v", err)
+	}
+	partner, err := CreateReputationFeed("partner", "Partner blocklist", 1, "hash-partner", "")
+	if err != nil {
+		t.Fatalf("CreateReputationFeed failed: %v", err)
+	}
+	if _, err := CreateReputationFeed("partner", "", 1, "hash-other", ""); err != ErrFeedExists {
+		t.Errorf("Expected ErrFeedExists, got %v", err)
+	}
+
+	now := time.Now()
+	applied, err := UpsertExternalSignals(intel.ID, []ExternalSignal{
+		{IP: "192.0.2.1", Verdict: VerdictMalicious, Confidence: 0.5, ReportedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{IP: "192.0.2.2", Verdict: VerdictMalicious, Confidence: 1, ReportedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+	})
+	if err != nil || applied != 2 {
+		t.Fatalf("UpsertExternalSignals failed: applied=%d err=%v", applied, err)
+	}
+	// An older verdict does not replace the stored one
+	applied, err = UpsertExternalSignals(intel.ID, []ExternalSignal{
+		{IP: "192.0.2.1", Verdict: VerdictClean, Confidence: 1, ReportedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+	})
+	if err != nil || applied != 0 {
+		t.Errorf("Expected the older verdict skipped, got applied=%d err=%v", applied, err)
+	}
+	if _, err := UpsertExternalSignals(partner.ID, []ExternalSignal{
+		{IP: "192.0.2.1", Verdict: VerdictSuspicious, Confidence: 1, Reference: "PB-1", ReportedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}); err != nil {
+		t.Fatalf("UpsertExternalSignals failed: %v", err)
+	}
+
+	signals, err := GetExternalSignalsByIP("192.0.2.1", now)
+	if err != nil || len(signals) != 2 {
+		t.Fatalf("Unexpected signals: %+v %v", signals, err)
+	}
+	if signals[0].FeedName != "partner" || signals[0].Reference != "PB-1" || signals[1].Verdict != VerdictMalicious || signals[1].FeedWeight != 2 {
+		t.Errorf("Unexpected signals: %+v", signals)
+	}
+	if expired, err := GetExternalSignalsByIP("192.0.2.2", now); err != nil || len(expired) != 0 {
+		t.Errorf("Expected expired signals left out, got %+v %v", expired, err)
+	}
+
+	disabled := false
+	if feed, err := UpdateReputationFeed(intel.ID, nil, nil, &disabled); err != nil || feed == nil || feed.Enabled || feed.SignalsReceived != 3 {
+		t.Fatalf("UpdateReputationFeed failed: %+v %v", feed, err)
+	}
+	if feed, err := GetReputationFeedByTokenHash("hash-intel"); err != nil || feed != nil {
+		t.Errorf("Expected a disabled feed's token refused, got %+v %v", feed, err)
+	}
+	if signals, err := GetExternalSignalsByIP("192.0.2.1", now); err != nil || len(signals) != 1 {
+		t.Errorf("Expected only the enabled feed's signal, got %+v %v", signals, err)
+	}
+}
+
+// TestReputationLookups tests that the latest lookup of each provider is
+// returned with its score and details
+func TestReputationLookups(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE reputation_lookups RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset reputation lookups: %v", err)
+	}
+
+	now := time.Now()
+	score := 62.0
+	for _, l := range []*ReputationLookup{
+		{IP: "192.0.2.1", Provider: "senderscore", Error: "timeout", CheckedAt: now.Add(-time.Hour)},
+		{IP: "192.0.2.1", Provider: "senderscore", Score: &score, Listed: true, Summary: "SenderScore 62/100", Details: map[string]string{"answer": "127.0.4.62"}, CheckedAt: now},
+		{IP: "192.0.2.1", Provider: "other", CheckedAt: now},
+		{IP: "192.0.2.2", Provider: "senderscore", CheckedAt: now},
+	} {
+		if err := InsertReputationLookup(l); err != nil || l.ID == 0 {
+			t.Fatalf("InsertReputationLookup failed: %v", err)
+		}
+	}
+
+	lookups, err := GetLatestReputationLookups("192.0.2.1")
+	if err != nil || len(lookups) != 2 {
+		t.Fatalf("Unexpected lookups: %+v %v", lookups, err)
+	}
+	if lookups[0].Provider != "other" || lookups[0].Score != nil {
+		t.Errorf("Expected the other provider without a score first, got %+v", lookups[0])
+	}
+	latest := lookups[1]
+	if latest.Score == nil || *latest.Score != 62 || !latest.Listed || latest.Error != "" || latest.Details["answer"] != "127.0.4.62" {
+		t.Errorf("Expected the latest senderscore lookup, got %+v", latest)
+	}
+}
+
+// TestThrottleStates tests that throttle states are upserted per IP and
+// provider and that only throttled ones are listed
+func TestThrottleStates(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE ip_throttle_states`); err != nil {
+		t.Fatalf("Failed to reset throttle states: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	since := now.Add(-time.Hour)
+	retryAfter := now.Add(10 * time.Minute)
+	for _, s := range []*ThrottleState{
+		{IP: "192.0.2.1", Provider: "gmail", Throttled: true, Since: &since, LastThrottledAt: &now, ThrottleCount: 5, BackoffSeconds: 600, RetryAfter: &retryAfter, UpdatedAt: now},
+		{IP: "192.0.2.1", Provider: "outlook", LastThrottledAt: &now, ThrottleCount: 1, UpdatedAt: now},
+		{IP: "192.0.2.2", Provider: "yahoo", Throttled: true, Since: &now, ThrottleCount: 3, BackoffSeconds: 300, UpdatedAt: now},
+	} {
+		if err := UpsertThrottleState(s); err != nil {
+			t.Fatalf("UpsertThrottleState failed: %v", err)
+		}
+	}
+	// Clearing replaces the state
+	if err := UpsertThrottleState(&ThrottleState{IP: "192.0.2.2", Provider: "yahoo", LastThrottledAt: &now, UpdatedAt: now}); err != nil {
+		t.Fatalf("UpsertThrottleState failed: %v", err)
+	}
+
+	states, err := GetThrottleStates("192.0.2.1")
+	if err != nil || len(states) != 2 {
+		t.Fatalf("Unexpected throttle states: %+v %v", states, err)
+	}
+	gmail := states[0]
+	if gmail.Provider != "gmail" || !gmail.Throttled || gmail.Since == nil || !gmail.Since.Equal(since) || gmail.RetryAfter == nil || gmail.BackoffSeconds != 600 {
+		t.Errorf("Unexpected gmail state: %+v", gmail)
+	}
+	if states[1].Since != nil || states[1].RetryAfter != nil {
+		t.Errorf("Expected no since or retry_after when not throttled, got %+v", states[1])
+	}
+
+	throttled, err := ListThrottledStates()
+	if err != nil || len(throttled) != 1 || throttled[0].Provider != "gmail" {
+		t.Errorf("Expected only gmail throttled, got %+v %v", throttled, err)
+	}
+}
+
+// TestSendRates tests that an IP's send rates are replaced as a whole and
+// that its first aggregation run is found
+func TestSendRates(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE ip_send_rates, ip_reputation_history RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset send rates: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	if err := ReplaceSendRates("192.0.2.1", []SendRate{
+		{IP: "192.0.2.1", Provider: "*", MessagesPerHour: 2000, Reasons: []string{}, UpdatedAt: now},
+		{IP: "192.0.2.1", Provider: "gmail", MessagesPerHour: 500, Reasons: []string{"throttled, 4 rate-limit responses"}, UpdatedAt: now},
+	}); err != nil {
+		t.Fatalf("ReplaceSendRates failed: %v", err)
+	}
+	if err := ReplaceSendRates("192.0.2.1", []SendRate{
+		{IP: "192.0.2.1", Provider: "*", MessagesPerHour: 100, WarmupStage: 3, Reasons: []string{"warm-up stage 3 of 5 (day 4)"}, UpdatedAt: now},
+	}); err != nil {
+		t.Fatalf("ReplaceSendRates failed: %v", err)
+	}
+
+	rates, err := GetSendRates("192.0.2.1")
+	if err != nil || len(rates) != 1 {
+		t.Fatalf("Expected the rates replaced, got %+v %v", rates, err)
+	}
+	if r := rates[0]; r.MessagesPerHour != 100 || r.WarmupStage != 3 || len(r.Reasons) != 1 {
+		t.Errorf("Unexpected rate: %+v", r)
+	}
+
+	if _, ok, err := GetIPFirstSeen("192.0.2.1"); err != nil || ok {
+		t.Errorf("Expected no first seen without history, got %v %v", ok, err)
+	}
+	for _, ago := range []time.Duration{48 * time.Hour, time.Hour} {
+		if err := InsertIPReputationHistory(&IPReputationMetrics{IP: "192.0.2.1", Status: "healthy", LastUpdated: now.Add(-ago)}); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+	if first, ok, err := GetIPFirstSeen("192.0.2.1"); err != nil || !ok || !first.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Expected first seen 48h ago, got %s %v %v", first, ok, err)
+	}
+}
+
+// TestBackupRestore tests that a backup restores into an empty deployment
+// with its IDs, that the ID sequences continue after them and that restoring
+// over existing state is refused
+func TestBackupRestore(t *testing.T) {
+	reset := func() {
+		if _, err := DB.Exec(`TRUNCATE ip_reputation_metrics, config_revisions, feature_flags, feature_flag_changes, reserved_ips, protected_blocks, archived_ips, aggregation_exclusions, ip_rate_limits, ip_context RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("Failed to reset reputation state: %v", err)
+		}
+	}
+	reset()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: "192.0.2.1", WindowStart: now.Add(-time.Hour), WindowEnd: now, TotalSent: 100, TotalRejected: 7, RejectionRatio: 0.07, Status: "quarantine", MajorProvidersRejecting: []string{"gmail.com"}, DistinctRejectionReasons: map[string]int{"5.7.1": 7}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+	}
+	first, err := CreateConfigRevision(&ConfigRevision{Scope: ConfigScopeReputation, Config: []byte(`{"warning_rejection_ratio":0.1}`), Diff: []byte(`{}`), Author: "alice"})
+	if err != nil {
+		t.Fatalf("CreateConfigRevision failed: %v", err)
+	}
+	if _, err := CreateConfigRevision(&ConfigRevision{Scope: ConfigScopeReputation, Config: []byte(`{}`), Diff: []byte(`{}`), Author: "bob", RollbackOf: &first.ID}); err != nil {
+		t.Fatalf("CreateConfigRevision failed: %v", err)
+	}
+	if _, err := SetFeatureFlag(&FeatureFlagChange{Name: "auto_rotation", Enabled: true, Author: "alice"}); err != nil {
+		t.Fatalf("SetFeatureFlag failed: %v", err)
+	}
+	if err := CreateReservedIP(&ReservedIP{IPAddress: "198.51.100.7", UID: "ip-1", Location: "us/ewr", Status: "reserved", BlacklistDetails: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("CreateReservedIP failed: %v", err)
+	}
+	if _, err := ProtectBlock(&ProtectedBlock{BlockID: "block-1", Reason: "production pool"}); err != nil {
+		t.Fatalf("ProtectBlock failed: %v", err)
+	}
+	if _, err := SetIPRateLimit(&IPRateLimit{IP: "192.0.2.1", MessagesPerMinute: 30, Reason: "deferrals", SetBy: "alice", ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("SetIPRateLimit failed: %v", err)
+	}
+	if _, err := SetIPContext(&IPContext{IP: "192.0.2.1", Notes: "Customer X", RunbookURL: "https://wiki.example.com/x", UpdatedBy: "alice"}); err != nil {
+		t.Fatalf("SetIPContext failed: %v", err)
+	}
+
+	backup, err := ExportBackup()
+	if err != nil {
+		t.Fatalf("ExportBackup failed: %v", err)
+	}
+	if len(backup.Tables["config_revisions"]) != 2 || len(backup.Tables["reserved_ips"]) != 1 || len(backup.Tables["protected_blocks"]) != 1 || len(backup.Tables["ip_rate_limits"]) != 1 || len(backup.Tables["ip_context"]) != 1 {
+		t.Fatalf("Unexpected backup: %+v", backup.Tables)
+	}
+	archive, err := json.Marshal(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RestoreBackup(backup); !errors.Is(err, ErrRestoreNotEmpty) {
+		t.Errorf("Expected ErrRestoreNotEmpty over existing state, got %v", err)
+	}
+
+	reset()
+	var portable Backup
+	if err := json.Unmarshal(archive, &portable); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreBackup(&portable)
+	if err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if restored["config_revisions"] != 2 || restored["feature_flags"] != 1 || restored["ip_reputation_metrics"] != 1 {
+		t.Errorf("Unexpected restore counts: %v", restored)
+	}
+
+	metrics, err := GetIPReputationMetrics("192.0.2.1")
+	if err != nil || metrics.Status != "quarantine" || metrics.TotalRejected != 7 || metrics.DistinctRejectionReasons["5.7.1"] != 7 {
+		t.Errorf("Unexpected restored metrics: %+v %v", metrics, err)
+	}
+	latest, err := GetLatestConfigRevision(ConfigScopeReputation)
+	if err != nil || latest.ID != 2 || latest.RollbackOf == nil || *latest.RollbackOf != first.ID {
+		t.Errorf("Unexpected restored revision: %+v %v", latest, err)
+	}
+	next, err := CreateConfigRevision(&ConfigRevision{Scope: ConfigScopeReputation, Config: []byte(`{}`), Diff: []byte(`{}`), Author: "carol"})
+	if err != nil || next.ID != 3 {
+		t.Errorf("Expected the revision sequence to continue at 3, got %+v %v", next, err)
+	}
+	if reserved, err := GetReservedIPByAddress("198.51.100.7"); err != nil || reserved.UID != "ip-1" {
+		t.Errorf("Unexpected restored reserved IP: %+v %v", reserved, err)
+	}
+	if limit, err := GetIPRateLimit("192.0.2.1"); err != nil || limit == nil || limit.MessagesPerMinute != 30 || limit.SetBy != "alice" || !limit.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Unexpected restored rate limit: %+v %v", limit, err)
+	}
+	if c, err := GetIPContext("192.0.2.1"); err != nil || c == nil || c.Notes != "Customer X" || c.RunbookURL != "https://wiki.example.com/x" {
+		t.Errorf("Unexpected restored IP context: %+v %v", c, err)
+	}
+}
+
+// TestMigrateIPHistory tests that a renumbered IP's history moves to the new
+// address, the new address keeps its own current state and both record it
+func TestMigrateIPHistory(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE ip_throttle_states, ip_notes RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset tables: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for i, ip := range []string{"192.0.2.1", "192.0.2.1", "198.51.100.1"} {
+		if _, err := StoreSMTPFailure(newFailure(ip, fmt.Sprintf("evt-%d", i), now.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+	for _, m := range []*IPReputationMetrics{
+		{IP: "192.0.2.1", WindowStart: now.Add(-time.Hour), WindowEnd: now, TotalSent: 2, TotalRejected: 2, RejectionRatio: 1, Status: "blocked", MajorProvidersRejecting: []string{}, DistinctRejectionReasons: map[string]int{}, Metadata: map[string]interface{}{}},
+		{IP: "198.51.100.1", WindowStart: now.Add(-time.Hour), WindowEnd: now, TotalSent: 1, TotalRejected: 1, RejectionRatio: 1, Status: "warning", MajorProvidersRejecting: []string{}, DistinctRejectionReasons: map[string]int{}, Metadata: map[string]interface{}{}},
+	} {
+		if err := UpsertIPReputationMetrics(m); err != nil {
+			t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+		}
+	}
+	if err := UpsertThrottleState(&ThrottleState{IP: "192.0.2.1", Provider: "gmail", LastThrottledAt: &now, ThrottleCount: 2, UpdatedAt: now}); err != nil {
+		t.Fatalf("UpsertThrottleState failed: %v", err)
+	}
+	if _, err := CreateIPNote(&IPNote{IP: "192.0.2.1", Note: "transactional pool", Author: "alice"}); err != nil {
+		t.Fatalf("CreateIPNote failed: %v", err)
+	}
+
+	migration, err := MigrateIPHistory("192.0.2.1", "198.51.100.1", "alice", " renumbered ")
+	if err != nil {
+		t.Fatalf("MigrateIPHistory failed: %v", err)
+	}
+	if migration.Moved["smtp_failures"] != 2 || migration.Moved["ip_throttle_states"] != 1 || migration.Moved["ip_notes"] != 1 || migration.Superseded["ip_reputation_metrics"] != 1 || migration.Comment != "renumbered" {
+		t.Errorf("Unexpected migration: %+v", migration)
+	}
+
+	var failures int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM smtp_failures WHERE sending_ip = '198.51.100.1'`).Scan(&failures); err != nil || failures != 3 {
+		t.Errorf("Expected 3 failures on the new address, got %d %v", failures, err)
+	}
+	if metrics, err := GetIPReputationMetrics("198.51.100.1"); err != nil || metrics.Status != "warning" {
+		t.Errorf("Expected the new address to keep its metrics, got %+v %v", metrics, err)
+	}
+	if metrics, err := GetIPReputationMetrics("192.0.2.1"); err == nil {
+		t.Errorf("Expected no metrics left on the old address, got %+v", metrics)
+	}
+	for ip, reason := range map[string]string{"198.51.100.1": "History migrated from 192.0.2.1: renumbered", "192.0.2.1": "History migrated to 198.51.100.1"} {
+		actions, err := GetIPActions(ip, 10)
+		if err != nil || len(actions) != 1 || actions[0].Action != IPMigrationAction || actions[0].Reason != reason || actions[0].TriggeredBy != "alice" {
+			t.Errorf("Unexpected actions on %s: %+v %v", ip, actions, err)
+		}
+	}
+
+	// The record of the migration stays on the old address
+	if _, err := MigrateIPHistory("192.0.2.1", "198.51.100.1", "alice", ""); !errors.Is(err, ErrNoIPHistory) {
+		t.Errorf("Expected ErrNoIPHistory, got %v", err)
+	}
+}
+
+// TestBulkActionTargets tests that bulk action filters combine, skip
+// archived IPs and reject unknown pools
+func TestBulkActionTargets(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE ip_pools, archived_ips RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset pools: %v", err)
+	}
+	defer DB.Exec(`TRUNCATE archived_ips`)
+
+	statuses := map[string]string{"192.0.2.1": "healthy", "192.0.2.2": "warning", "192.0.2.10": "warning", "198.51.100.1": "warning"}
+	for ip, status := range statuses {
+		if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: ip, Status: status, DistinctRejectionReasons: map[string]int{}, MajorProvidersRejecting: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+			t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+		}
+	}
+	pool, err := CreateIPPool("transactional", "")
+	if err != nil {
+		t.Fatalf("CreateIPPool failed: %v", err)
+	}
+	AddIPToPool(pool.ID, "192.0.2.2")
+	AddIPToPool(pool.ID, "198.51.100.1")
+	if _, err := ArchiveIPs([]string{"192.0.2.10"}, "", "alice"); err != nil {
+		t.Fatalf("ArchiveIPs failed: %v", err)
+	}
+
+	targets, err := ListBulkActionTargets(BulkActionFilter{CIDR: "192.0.2.0/24"})
+	if err != nil || len(targets) != 2 || targets[0] != (BulkActionTarget{IP: "192.0.2.1", Status: "healthy"}) || targets[1].IP != "192.0.2.2" {
+		t.Errorf("Expected the block's unarchived IPs, got %+v %v", targets, err)
+	}
+	targets, err = ListBulkActionTargets(BulkActionFilter{Pool: "transactional", Status: "warning"})
+	if err != nil || len(targets) != 2 {
+		t.Errorf("Expected the pool's warning IPs, got %+v %v", targets, err)
+	}
+	targets, err = ListBulkActionTargets(BulkActionFilter{CIDR: "198.51.100.0/24", Pool: "transactional", Status: "healthy"})
+	if err != nil || len(targets) != 0 {
+		t.Errorf("Expected no IP matching every filter, got %+v %v", targets, err)
+	}
+	if _, err := ListBulkActionTargets(BulkActionFilter{Pool: "marketing"}); !errors.Is(err, ErrIPPoolNotFound) {
+		t.Errorf("Expected ErrIPPoolNotFound, got %v", err)
+	}
+}
+
+// TestArchivedIPs tests that archived IPs are skipped by aggregation, found
+// by CIDR and recorded as actions
+func TestArchivedIPs(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE archived_ips`); err != nil {
+		t.Fatalf("Failed to reset archived IPs: %v", err)
+	}
+	defer DB.Exec(`TRUNCATE archived_ips`)
+
+	now := time.Now()
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2", "198.51.100.1"} {
+		if _, err := StoreSMTPFailure(newFailure(ip, fmt.Sprintf("evt-%d", i), now)); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+
+	ips, err := ListKnownIPsInCIDR("192.0.2.0/30")
+	if err != nil || len(ips) != 2 || ips[0] != "192.0.2.1" || ips[1] != "192.0.2.2" {
+		t.Fatalf("Unexpected IPs in block: %v %v", ips, err)
+	}
+	archived, err := ArchiveIPs(ips, "decommissioned", "alice")
+	if err != nil || len(archived) != 2 {
+		t.Fatalf("ArchiveIPs failed: %+v %v", archived, err)
+	}
+	if again, err := ArchiveIPs([]string{"192.0.2.1"}, "again", "bob"); err != nil || len(again) != 0 {
+		t.Errorf("Expected archiving twice to change nothing, got %+v %v", again, err)
+	}
+
+	pending, err := GetIPsNeedingAggregation(now.Add(-time.Hour))
+	if err != nil || len(pending) != 1 || pending[0] != "198.51.100.1" {
+		t.Errorf("Expected only the active IP aggregated, got %v %v", pending, err)
+	}
+	if ok, err := IsIPArchived("192.0.2.1"); err != nil || !ok {
+		t.Errorf("Expected 192.0.2.1 archived, got %v %v", ok, err)
+	}
+	actions, err := GetIPActions("192.0.2.1", 10)
+	if err != nil || len(actions) != 1 || actions[0].Action != IPArchivedAction || actions[0].Reason != "decommissioned" {
+		t.Errorf("Unexpected actions: %+v %v", actions, err)
+	}
+
+	if err := UnarchiveIP("192.0.2.1", "alice"); err != nil {
+		t.Fatalf("UnarchiveIP failed: %v", err)
+	}
+	if err := UnarchiveIP("192.0.2.1", "alice"); !errors.Is(err, ErrIPNotArchived) {
+		t.Errorf("Expected ErrIPNotArchived, got %v", err)
+	}
+	if list, err := ListArchivedIPs(); err != nil || len(list) != 1 || list[0].IP != "192.0.2.2" || list[0].ArchivedBy != "alice" {
+		t.Errorf("Unexpected archived IPs: %+v %v", list, err)
+	}
+}
+
+// TestAggregationExclusions tests that IPs in excluded blocks are skipped by
+// aggregation discovery until the exclusion is deleted
+func TestAggregationExclusions(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE aggregation_exclusions RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset aggregation exclusions: %v", err)
+	}
+	defer DB.Exec(`TRUNCATE aggregation_exclusions`)
+
+	now := time.Now()
+	for i, ip := range []string{"192.0.2.1", "203.0.113.5", "203.0.113.200", "2001:db8::1"} {
+		if _, err := StoreSMTPFailure(newFailure(ip, fmt.Sprintf("evt-%d", i), now)); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+
+	exclusion, err := CreateAggregationExclusion(&AggregationExclusion{CIDR: "203.0.113.0/25", Reason: "monitoring probes", CreatedBy: "alice"})
+	if err != nil || exclusion.ID == 0 || exclusion.CIDR != "203.0.113.0/25" {
+		t.Fatalf("CreateAggregationExclusion failed: %+v %v", exclusion, err)
+	}
+	if _, err := CreateAggregationExclusion(&AggregationExclusion{CIDR: "2001:db8::1/128", CreatedBy: "alice"}); err != nil {
+		t.Fatalf("CreateAggregationExclusion failed: %v", err)
+	}
+	if _, err := CreateAggregationExclusion(&AggregationExclusion{CIDR: "203.0.113.0/25", CreatedBy: "bob"}); !errors.Is(err, ErrAggregationExclusionExists) {
+		t.Errorf("Expected ErrAggregationExclusionExists, got %v", err)
+	}
+
+	pending, err := GetIPsNeedingAggregation(now.Add(-time.Hour))
+	sort.Strings(pending)
+	if err != nil || len(pending) != 2 || pending[0] != "192.0.2.1" || pending[1] != "203.0.113.200" {
+		t.Errorf("Expected the excluded IPs skipped, got %v %v", pending, err)
+	}
+	if ips, err := GetIPsWithFailuresBetween(now.Add(-time.Hour), now.Add(time.Hour)); err != nil || len(ips) != 2 {
+		t.Errorf("Expected the excluded IPs skipped when rebuilding, got %v %v", ips, err)
+	}
+	blocks, err := GetExcludedBlocks()
+	if err != nil || len(blocks) != 2 || !blocks[0].Contains(net.ParseIP("203.0.113.5")) || !blocks[1].Contains(net.ParseIP("2001:db8::1")) {
+		t.Errorf("Unexpected excluded blocks: %v %v", blocks, err)
+	}
+
+	if err := DeleteAggregationExclusion(exclusion.ID); err != nil {
+		t.Fatalf("DeleteAggregationExclusion failed: %v", err)
+	}
+	if err := DeleteAggregationExclusion(exclusion.ID); !errors.Is(err, ErrAggregationExclusionNotFound) {
+		t.Errorf("Expected ErrAggregationExclusionNotFound, got %v", err)
+	}
+	if pending, err := GetIPsNeedingAggregation(now.Add(-time.Hour)); err != nil || len(pending) != 3 {
+		t.Errorf("Expected the block aggregated again, got %v %v", pending, err)
+	}
+	if list, err := ListAggregationExclusions(); err != nil || len(list) != 1 || list[0].CIDR != "2001:db8::1/128" || list[0].CreatedBy != "alice" {
+		t.Errorf("Unexpected exclusions: %+v %v", list, err)
+	}
+}
+
+// TestPeriodStats tests that a period's history and failure codes are
+// summarized per IP, excluding the end of the period
+func TestPeriodStats(t *testing.T) {
+	resetTables(t)
+
+	from := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Hour)
+	to := from.Add(time.Hour)
+	for _, m := range []*IPReputationMetrics{
+		{IP: "192.0.2.1", Status: "healthy", RejectionRatio: 0.02, LastUpdated: from},
+		{IP: "192.0.2.1", Status: "warning", RejectionRatio: 0.08, LastUpdated: from.Add(30 * time.Minute)},
+		{IP: "192.0.2.1", Status: "quarantine", RejectionRatio: 0.5, LastUpdated: to},
+	} {
+		if err := InsertIPReputationHistory(m); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+	stats, err := GetPeriodIPStats(from, to)
+	if err != nil || len(stats) != 1 || stats[0].Runs != 2 || stats[0].Status != "warning" || math.Abs(stats[0].RejectionRatio-0.05) > 1e-9 {
+		t.Errorf("Unexpected period stats: %+v %v", stats, err)
+	}
+
+	coded := newFailure("192.0.2.1", "evt-1", from.Add(time.Minute))
+	bare := newFailure("192.0.2.1", "evt-2", from.Add(2*time.Minute))
+	bare.EnhancedCode = ""
+	late := newFailure("192.0.2.1", "evt-3", to)
+	for _, f := range []*SMTPFailure{coded, bare, late} {
+		if _, err := StoreSMTPFailure(f); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+	codes, err := GetPeriodFailureCodes(from, to)
+	if err != nil || len(codes) != 2 || codes[0].Code != "5.7.1" || codes[0].Count != 1 || codes[1].Code != "550" {
+		t.Errorf("Unexpected period codes: %+v %v", codes, err)
+	}
+}
+
+// TestReasonFamilyCounts tests that failures are stored with their reason
+// family and counted per family, with legacy rows grouped by their reason
+func TestReasonFamilyCounts(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now().UTC()
+	first := newFailure("192.0.2.1", "evt-1", now.Add(-time.Hour))
+	first.Reason = "550 5.7.1 Message rejected, block ID 8f14e45fce"
+	second := newFailure("192.0.2.1", "evt-2", now.Add(-time.Minute))
+	second.Reason = "550 5.7.1 Message rejected, block ID c9f0f895fb2"
+	other := newFailure("192.0.2.2", "evt-3", now.Add(-time.Minute))
+	other.RecipientDomain = "yahoo.com"
+	for _, f := range []*SMTPFailure{first, second, other} {
+		if _, err := StoreSMTPFailure(f); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+	legacy := newFailure("192.0.2.1", "evt-4", now.Add(-2*time.Hour))
+	legacy.Reason = "550 5.7.1 Message rejected, block ID 45c48cce2e"
+	if _, err := StoreSMTPFailure(legacy); err != nil {
+		t.Fatalf("StoreSMTPFailure failed: %v", err)
+	}
+	if _, err := DB.Exec(`UPDATE smtp_failures SET reason_family = NULL WHERE event_id = 'evt-4'`); err != nil {
+		t.Fatalf("Failed to clear reason family: %v", err)
+	}
+
+	failures, err := GetSMTPFailuresByIP("192.0.2.1", now.Add(-3*time.Hour))
+	if err != nil || len(failures) != 3 {
+		t.Fatalf("Expected 3 failures, got %d: %v", len(failures), err)
+	}
+	family := "550 5.7.1 message rejected, block id <id>"
+	for _, f := range failures {
+		if f.ReasonFamily != family {
+			t.Errorf("Expected family %q for %s, got %q", family, f.EventID, f.ReasonFamily)
+		}
+	}
+
+	counts, err := GetReasonFamilyCounts(now.Add(-3*time.Hour), "192.0.2.1", "")
+	if err != nil {
+		t.Fatalf("GetReasonFamilyCounts failed: %v", err)
+	}
+	total := 0
+	for _, c := range counts {
+		if c.Family != family || c.IP != "192.0.2.1" || c.ExampleRecipient != "user@gmail.com" {
+			t.Errorf("Unexpected count: %+v", c)
+		}
+		total += c.Count
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 failures in the family, got %d", total)
+	}
+
+	counts, err = GetReasonFamilyCounts(now.Add(-3*time.Hour), "", "yahoo.com")
+	if err != nil || len(counts) != 1 || counts[0].IP != "192.0.2.2" || counts[0].Family != "ip reputation" {
+		t.Errorf("Expected only the yahoo.com failure, got %+v %v", counts, err)
+	}
+}
+
+// TestDelistingTasks tests that failures are stored with their block
+// references and that delisting tasks are opened, updated and recorded in
+// the IP's actions
+func TestDelistingTasks(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE delisting_tasks RESTART IDENTITY`); err != nil {
+		t.Fatalf("Failed to reset delisting tasks: %v", err)
+	}
+
+	f := newFailure("192.0.2.1", "evt-1", time.Now())
+	f.Reason = "550 5.7.1 Unfortunately, messages from [192.0.2.1] weren't sent. (S3150)"
+	if _, err := StoreSMTPFailure(f); err != nil {
+		t.Fatalf("StoreSMTPFailure failed: %v", err)
+	}
+	failures, err := GetSMTPFailuresByIP("192.0.2.1", time.Now().Add(-time.Hour))
+	if err != nil || len(failures) != 1 || len(failures[0].BlockReferences) != 1 || failures[0].BlockReferences[0] != "S3150" {
+		t.Fatalf("Expected the stored block reference, got %+v %v", failures, err)
+	}
+
+	task, err := OpenDelistingTask("192.0.2.1", "outlook", []string{"S3150"}, "submitted via form", "alice")
+	if err != nil {
+		t.Fatalf("OpenDelistingTask failed: %v", err)
+	}
+	if task.Status != DelistingOpen || len(task.BlockReferences) != 1 || task.ResolvedAt != nil {
+		t.Errorf("Unexpected task: %+v", task)
+	}
+	if _, err := OpenDelistingTask("192.0.2.1", "outlook", nil, "", "bob"); !errors.Is(err, ErrDelistingTaskPending) {
+		t.Errorf("Expected ErrDelistingTaskPending, got %v", err)
+	}
+
+	resolved, err := UpdateDelistingTaskStatus(task.ID, DelistingResolved, "delisted", "alice")
+	if err != nil || resolved.Status != DelistingResolved || resolved.ResolvedAt == nil {
+		t.Fatalf("Expected the task resolved, got %+v %v", resolved, err)
+	}
+	if _, err := UpdateDelistingTaskStatus(task.ID, DelistingOpen, "", "alice"); !errors.Is(err, ErrDelistingTaskResolved) {
+		t.Errorf("Expected ErrDelistingTaskResolved, got %v", err)
+	}
+	if _, err := UpdateDelistingTaskStatus(999, DelistingResolved, "", "alice"); !errors.Is(err, ErrDelistingTaskNotFound) {
+		t.Errorf("Expected ErrDelistingTaskNotFound, got %v", err)
+	}
+	if _, err := OpenDelistingTask("192.0.2.1", "outlook", nil, "", "bob"); err != nil {
+		t.Errorf("Expected a new task once the old one is resolved, got %v", err)
+	}
+
+	if tasks, err := ListDelistingTasks("192.0.2.1", DelistingOpen); err != nil || len(tasks) != 1 || tasks[0].OpenedBy != "bob" || tasks[0].BlockReferences == nil {
+		t.Errorf("Unexpected open tasks: %+v %v", tasks, err)
+	}
+	actions, err := GetIPActions("192.0.2.1", 10)
+	if err != nil {
+		t.Fatalf("GetIPActions failed: %v", err)
+	}
+	var opened, updated int
+	for _, a := range actions {
+		switch a.Action {
+		case DelistingOpenedAction:
+			opened++
+		case DelistingUpdatedAction:
+			updated++
+			if a.PreviousStatus != DelistingOpen || a.NewStatus != DelistingResolved {
+				t.Errorf("Unexpected status change: %+v", a)
+			}
+		}
+	}
+	if opened != 2 || updated != 1 {
+		t.Errorf("Expected 2 openings and 1 update, got %d and %d", opened, updated)
+	}
+}
+
+// TestITSMRulesAndTickets tests ITSM rules and the tickets recorded on an IP's timeline
+func TestITSMRulesAndTickets(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE ip_pools, itsm_rules, itsm_tickets RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset ITSM tables: %v", err)
+	}
+
+	pool, err := CreateIPPool("marketing", "")
+	if err != nil {
+		t.Fatalf("CreateIPPool failed: %v", err)
+	}
+	missing := 999
+	rule, err := CreateITSMRule(&ITSMRule{Event: "ip_blacklisted", System: "jira", Project: "OPS", PoolID: &pool.ID, Enabled: true, CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateITSMRule failed: %v", err)
+	}
+	if rule.PoolName != "marketing" || !rule.Enabled {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+	if _, err := CreateITSMRule(&ITSMRule{Event: "ip_blacklisted", System: "jira", Project: "OPS", PoolID: &missing, CreatedBy: "alice"}); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected ErrOwnershipReference for a missing pool, got %v", err)
+	}
+	rule.Enabled = false
+	if updated, err := UpdateITSMRule(rule); err != nil || updated.Enabled {
+		t.Errorf("Expected the rule disabled, got %+v %v", updated, err)
+	}
+	if updated, err := UpdateITSMRule(&ITSMRule{ID: 999, Event: "ip_blacklisted", System: "jira", Project: "OPS"}); err != nil || updated != nil {
+		t.Errorf("Expected nil for a missing rule, got %+v %v", updated, err)
+	}
+
+	ticket, err := RecordITSMTicket(&ITSMTicket{RuleID: &rule.ID, System: "jira", Key: "OPS-1", URL: "https://jira.example.com/browse/OPS-1", IP: "192.0.2.1", Event: "ip_blacklisted", Status: "open"}, "automated_itsm")
+	if err != nil {
+		t.Fatalf("RecordITSMTicket failed: %v", err)
+	}
+	if open, err := HasOpenITSMTicket(rule.ID, "192.0.2.1", "ip_blacklisted", nil); err != nil || !open {
+		t.Errorf("Expected an open ticket, got %v %v", open, err)
+	}
+	if _, err := GetITSMTicketByKey("servicenow", "OPS-1"); !errors.Is(err, ErrITSMTicketNotFound) {
+		t.Errorf("Expected ErrITSMTicketNotFound, got %v", err)
+	}
+
+	resolved, changed, err := UpdateITSMTicketStatus(ticket.ID, "Done", true, "itsm:jira")
+	if err != nil || !changed || !resolved.Resolved {
+		t.Fatalf("Expected the ticket resolved, got %+v %v %v", resolved, changed, err)
+	}
+	if _, changed, err := UpdateITSMTicketStatus(ticket.ID, "Done", true, "itsm:jira"); err != nil || changed {
+		t.Errorf("Expected a repeated status to change nothing, got %v %v", changed, err)
+	}
+	if open, err := HasOpenITSMTicket(rule.ID, "192.0.2.1", "ip_blacklisted", nil); err != nil || open {
+		t.Errorf("Expected no open ticket, got %v %v", open, err)
+	}
+
+	// Deleting the rule keeps its tickets
+	if deleted, err := DeleteITSMRule(rule.ID); err != nil || !deleted {
+		t.Fatalf("DeleteITSMRule failed: %v %v", deleted, err)
+	}
+	tickets, err := ListITSMTickets("192.0.2.1")
+	if err != nil || len(tickets) != 1 || tickets[0].RuleID != nil || tickets[0].Status != "Done" {
+		t.Errorf("Unexpected tickets: %+v %v", tickets, err)
+	}
+
+	actions, err := GetIPActions("192.0.2.1", 10)
+	if err != nil {
+		t.Fatalf("GetIPActions failed: %v", err)
+	}
+	var created, updated int
+	for _, a := range actions {
+		switch a.Action {
+		case ITSMTicketCreatedAction:
+			created++
+		case ITSMTicketUpdatedAction:
+			updated++
+		}
+	}
+	if created != 1 || updated != 1 {
+		t.Errorf("Expected 1 creation and 1 update, got %d and %d", created, updated)
+	}
+}
+
+// TestRecommendationCatalog tests catalog entries and the mappings that
+// recommend them per status and issue type
+func TestRecommendationCatalog(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE recommendations, recommendation_mappings RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("Failed to reset recommendation tables: %v", err)
+	}
+
+	rec, err := SaveRecommendation(&Recommendation{Action: "run_dnsbl_checks", Texts: map[string]string{"en": "Run the DNSBL checks"}, UpdatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+	if rec.Texts["en"] != "Run the DNSBL checks" || rec.Link != "" {
+		t.Errorf("Unexpected recommendation: %+v", rec)
+	}
+	rec, err = SaveRecommendation(&Recommendation{Action: "run_dnsbl_checks", Link: "https://wiki.example.com/runbooks/dnsbl", UpdatedBy: "bob"})
+	if err != nil {
+		t.Fatalf("SaveRecommendation failed: %v", err)
+	}
+	if len(rec.Texts) != 0 || rec.Link != "https://wiki.example.com/runbooks/dnsbl" || rec.UpdatedBy != "bob" {
+		t.Errorf("Expected the entry replaced, got %+v", rec)
+	}
+	if missing, err := GetRecommendation("unknown"); err != nil || missing != nil {
+		t.Errorf("Expected nil for a missing entry, got %+v %v", missing, err)
+	}
+
+	mapping, err := CreateRecommendationMapping(&RecommendationMapping{Status: "quarantine", Action: "run_dnsbl_checks", Position: 1, CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateRecommendationMapping failed: %v", err)
+	}
+	if mapping.ID == 0 {
+		t.Errorf("Expected an ID, got %+v", mapping)
+	}
+	if _, err := CreateRecommendationMapping(&RecommendationMapping{Status: "quarantine", Action: "run_dnsbl_checks", Position: 2}); !errors.Is(err, ErrOwnershipConflict) {
+		t.Errorf("Expected ErrOwnershipConflict for a repeated mapping, got %v", err)
+	}
+	if _, err := CreateRecommendationMapping(&RecommendationMapping{Status: "quarantine", IssueType: "spamtrap_hit", Action: "run_dnsbl_checks"}); err != nil {
+		t.Errorf("Expected an issue type mapping of the same action, got %v", err)
+	}
+	if _, err := CreateRecommendationMapping(&RecommendationMapping{Status: "quarantine", Action: "unknown"}); !errors.Is(err, ErrOwnershipReference) {
+		t.Errorf("Expected ErrOwnershipReference for a missing action, got %v", err)
+	}
+
+	mappings, err := ListRecommendationMappings()
+	if err != nil || len(mappings) != 2 || mappings[0].IssueType != "" || mappings[1].IssueType != "spamtrap_hit" {
+		t.Errorf("Unexpected mappings: %+v %v", mappings, err)
+	}
+
+	// Deleting an entry removes its mappings
+	if deleted, err := DeleteRecommendation("run_dnsbl_checks"); err != nil || !deleted {
+		t.Fatalf("DeleteRecommendation failed: %v %v", deleted, err)
+	}
+	if mappings, err := ListRecommendationMappings(); err != nil || len(mappings) != 0 {
+		t.Errorf("Expected no mappings, got %+v %v", mappings, err)
+	}
+	if deleted, err := DeleteRecommendationMapping(mapping.ID); err != nil || deleted {
+		t.Errorf("Expected the mapping gone, got %v %v", deleted, err)
+	}
+}
+
+// TestReputationRebuildQueries tests the reads and the history replacement
+// of reputation rebuilds
+func TestReputationRebuildQueries(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE archived_ips`); err != nil {
+		t.Fatalf("Failed to reset archived IPs: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, f := range []*SMTPFailure{
+		newFailure("192.0.2.1", "evt-1", now.Add(-3*time.Hour)),
+		newFailure("192.0.2.1", "evt-2", now.Add(-time.Hour)),
+		newFailure("192.0.2.2", "evt-3", now.Add(-30*time.Minute)),
+		newFailure("192.0.2.3", "evt-4", now.Add(-30*time.Minute)),
+	} {
+		if err := InsertSMTPFailure(f); err != nil {
+			t.Fatalf("InsertSMTPFailure %d failed: %v", i, err)
+		}
+	}
+	if _, err := ArchiveIPs([]string{"192.0.2.3"}, "decommissioned", "alice"); err != nil {
+		t.Fatalf("ArchiveIPs failed: %v", err)
+	}
+
+	ips, err := GetIPsWithFailuresBetween(now.Add(-2*time.Hour), now)
+	if err != nil || len(ips) != 2 || ips[0] != "192.0.2.1" || ips[1] != "192.0.2.2" {
+		t.Errorf("Expected the unarchived IPs with failures in the range, got %v %v", ips, err)
+	}
+	failures, err := GetSMTPFailuresByIPBetween(context.Background(), "192.0.2.1", now.Add(-4*time.Hour), now.Add(-2*time.Hour))
+	if err != nil || len(failures) != 1 || failures[0].EventID != "evt-1" {
+		t.Errorf("Expected the failure in the range, got %+v %v", failures, err)
+	}
+
+	record := func(status string, at time.Time) *IPReputationMetrics {
+		return &IPReputationMetrics{IP: "192.0.2.1", Status: status, TotalSent: 100, LastUpdated: at}
+	}
+	for _, m := range []*IPReputationMetrics{record("quarantine", now.Add(-5*time.Hour)), record("healthy", now.Add(-time.Hour))} {
+		if err := InsertIPReputationHistory(m); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+
+	if status, ok, err := GetIPStatusBefore("192.0.2.1", now.Add(-2*time.Hour)); err != nil || !ok || status != "quarantine" {
+		t.Errorf("Expected quarantine before the range, got %q %v %v", status, ok, err)
+	}
+	if _, ok, err := GetIPStatusBefore("192.0.2.2", now); err != nil || ok {
+		t.Errorf("Expected no status for an IP without history, got %v %v", ok, err)
+	}
+
+	rebuilt := []*IPReputationMetrics{record("warning", now.Add(-2*time.Hour)), record("warning", now.Add(-time.Hour)), record("healthy", now)}
+	if err := ReplaceIPReputationHistory("192.0.2.1", now.Add(-2*time.Hour), now, rebuilt); err != nil {
+		t.Fatalf("ReplaceIPReputationHistory failed: %v", err)
+	}
+	var count, inRange int
+	if err := DB.QueryRow(`SELECT COUNT(*), COUNT(*) FILTER (WHERE recorded_at >= $2) FROM ip_reputation_history WHERE ip = $1`, "192.0.2.1", now.Add(-2*time.Hour)).Scan(&count, &inRange); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 || inRange != 3 {
+		t.Errorf("Expected the range replaced and the earlier record kept, got %d records, %d in range", count, inRange)
+	}
+}
+
+// explain returns the plan of a query. Sequential scans are disabled, so
+// that the planner shows the index it would use on a large table rather
+// than reading the few rows of the test table.
+func explain(t *testing.T, query string, args ...interface{}) string {
+	t.Helper()
+
+	tx, err := DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SET LOCAL enable_seqscan = off`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := tx.Query("EXPLAIN "+query, args...)
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, line)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(plan, "\n")
+}
+
+// TestFailureQueryPlans tests that the failure queries run on every
+// aggregation pass are served by their indexes
+func TestFailureQueryPlans(t *testing.T) {
+	resetTables(t)
+
+	// Two weeks of failures, a minute apart, over 20 IPs; half of them
+	// with an instance and the first week anonymized
+	if _, err := DB.Exec(`
+		INSERT INTO smtp_failures (sending_ip, recipient_email, recipient_domain, smtp_code, enhanced_code, reason, timestamp, event_id, mta_instance)
+		SELECT '192.0.2.' || (i % 20), 'user' || i || '@gmail.com', 'gmail.com', 550, '5.7.1', 'blocked',
+		       NOW() - i * INTERVAL '1 minute', 'plan-' || i, CASE WHEN i % 2 = 0 THEN 'mta-' || (i % 4) END
+		FROM generate_series(1, 20160) AS i
+	`); err != nil {
+		t.Fatalf("Failed to insert failures: %v", err)
+	}
+	if _, err := DB.Exec(`UPDATE smtp_failures SET anonymized_at = NOW() WHERE timestamp < NOW() - INTERVAL '7 days'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DB.Exec(`ANALYZE smtp_failures`); err != nil {
+		t.Fatal(err)
+	}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	byIP, byIPArgs := smtpFailuresByIPQuery("192.0.2.2", "", hourAgo)
+	byInstance, byInstanceArgs := smtpFailuresByIPQuery("192.0.2.2", "mta-2", hourAgo)
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		index string
+	}{
+		{"failures by IP", byIP, byIPArgs, "idx_smtp_failures_ip_timestamp"},
+		{"failures by IP and instance", byInstance, byInstanceArgs, "idx_smtp_failures_ip_instance_ts"},
+		{"aggregation discovery", ipsNeedingAggregationQuery, []interface{}{hourAgo}, "idx_smtp_failures_timestamp_ip"},
+		{"anonymization", anonymizeSMTPFailuresQuery, []interface{}{time.Now().Add(-72 * time.Hour), ErasedMarker, emailPattern}, "idx_smtp_failures_not_anonymized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plan := explain(t, tt.query, tt.args...); !strings.Contains(plan, tt.index) {
+				t.Errorf("Expected the plan to use %s, got:\n%s", tt.index, plan)
+			}
+		})
+	}
+}
+
+// TestSlowQueryLog tests that statements reaching the threshold are logged
+// without their arguments
+func TestSlowQueryLog(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	SetSlowQueryThreshold(50 * time.Millisecond)
+	defer SetSlowQueryThreshold(0)
+
+	db, err := open(testDSN, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.Entries) != 0 {
+		t.Fatalf("Expected fast statements not logged, got %d entries", len(hook.Entries))
+	}
+
+	if _, err := db.Exec(`SELECT pg_sleep(0.1), $1::text`, "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Data["action"] != "slow_query" || entry.Level != logrus.WarnLevel {
+		t.Fatalf("Expected a slow_query warning, got %+v", entry)
+	}
+	if query := entry.Data["query"]; query != "SELECT pg_sleep(0.1), $1::text" {
+		t.Errorf("Expected the statement without its arguments, got %v", query)
+	}
+	if ms, _ := entry.Data["duration_ms"].(int64); ms < 100 {
+		t.Errorf("Expected the duration logged, got %v", entry.Data["duration_ms"])
+	}
+}
+
+// TestFailureCounters tests that stored failures are counted once, merged
+// into the new address by a migration and pruned with the counting start
+func TestFailureCounters(t *testing.T) {
+	resetTables(t)
+	SetFailureClassifier(func(f SMTPFailure) string {
+		if f.AttemptNumber > 1 {
+			return "retry"
+		}
+		return "rejection"
+	})
+	defer SetFailureClassifier(nil)
+
+	bucket := time.Now().Truncate(FailureCounterBucket)
+	retry := newFailure("192.0.2.1", "evt-retry", bucket.Add(20*time.Second))
+	retry.AttemptNumber = 2
+	for _, f := range []*SMTPFailure{
+		newFailure("192.0.2.1", "evt-1", bucket.Add(10*time.Second)),
+		newFailure("192.0.2.1", "evt-2", bucket.Add(15*time.Second)),
+		newFailure("192.0.2.1", "evt-1", bucket.Add(10*time.Second)),
+		retry,
+		newFailure("192.0.2.1", "evt-old", bucket.Add(-2*time.Hour)),
+		newFailure("198.51.100.1", "evt-new", bucket.Add(30*time.Second)),
+	} {
+		if _, err := StoreSMTPFailure(f); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+
+	counters, err := GetFailureCounters("192.0.2.1", "", bucket.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetFailureCounters failed: %v", err)
+	}
+	kinds := make(map[string]int)
+	for _, c := range counters {
+		if !c.BucketStart.Equal(bucket) || c.Domain != "gmail.com" || c.EnhancedCode != "5.7.1" {
+			t.Errorf("Unexpected counter: %+v", c)
+		}
+		kinds[c.Kind] += c.Failures
+	}
+	if kinds["rejection"] != 2 || kinds["retry"] != 1 {
+		t.Errorf("Expected the duplicate left uncounted, got %v", kinds)
+	}
+	if total, err := SumFailureCounters("192.0.2.1", bucket.Add(-3*time.Hour)); err != nil || total != 4 {
+		t.Errorf("Expected 4 failures counted, got %d %v", total, err)
+	}
+
+	migration, err := MigrateIPHistory("192.0.2.1", "198.51.100.1", "alice", "")
+	if err != nil {
+		t.Fatalf("MigrateIPHistory failed: %v", err)
+	}
+	if migration.Moved["failure_counters"] != 3 {
+		t.Errorf("Expected 3 counters moved, got %+v", migration.Moved)
+	}
+	counters, err = GetFailureCounters("198.51.100.1", "", bucket)
+	if err != nil {
+		t.Fatalf("GetFailureCounters failed: %v", err)
+	}
+	for _, c := range counters {
+		if c.Kind == "rejection" && c.Failures != 3 {
+			t.Errorf("Expected the counters of the bucket summed, got %+v", c)
+		}
+	}
+
+	// Counting started when the schema was applied, which stays later
+	pruned, since, err := PruneFailureCounters(bucket.Add(-time.Hour))
+	if err != nil || pruned != 1 || since.Before(bucket.Add(-time.Hour)) {
+		t.Errorf("Expected the old counter pruned, got %d since %v %v", pruned, since, err)
+	}
+	if stored, err := GetFailureCountingSince(); err != nil || !stored.Equal(since) {
+		t.Errorf("Expected counting to start at %v, got %v %v", since, stored, err)
+	}
+}
+
+// TestMarkRetriesDelivered tests that deferrals followed by a delivery are
+// marked once and move to the counter of their new kind
+func TestMarkRetriesDelivered(t *testing.T) {
+	resetTables(t)
+	SetFailureClassifier(func(f SMTPFailure) string {
+		if f.RetryDeliveredAt != nil {
+			return "greylisted"
+		}
+		return "deferral"
+	})
+	defer SetFailureClassifier(nil)
+
+	bucket := time.Now().Truncate(FailureCounterBucket)
+	deferral := newFailure("192.0.2.1", "evt-1", bucket.Add(10*time.Second))
+	deferral.RecipientEmail, deferral.SMTPCode, deferral.EnhancedCode = "User@gmail.com", 451, "4.7.0"
+	other := newFailure("192.0.2.1", "evt-2", bucket.Add(20*time.Second))
+	other.RecipientEmail = "other@gmail.com"
+	for _, f := range []*SMTPFailure{deferral, other} {
+		if _, err := StoreSMTPFailure(f); err != nil {
+			t.Fatalf("StoreSMTPFailure failed: %v", err)
+		}
+	}
+
+	failures, err := GetSMTPFailuresToRecipient("192.0.2.1", "user@gmail.com", bucket.Add(-time.Hour), bucket.Add(time.Minute))
+	if err != nil || len(failures) != 1 || failures[0].ID != deferral.ID || failures[0].RetryDeliveredAt != nil {
+		t.Fatalf("Expected the deferral to the recipient, got %+v %v", failures, err)
+	}
+
+	deliveredAt := bucket.Add(time.Minute)
+	for i, expected := range []int{1, 0} {
+		if marked, err := MarkRetriesDelivered([]int{deferral.ID}, deliveredAt); err != nil || marked != expected {
+			t.Errorf("Expected %d marked on call %d, got %d %v", expected, i+1, marked, err)
+		}
+	}
+	failures, err = GetSMTPFailuresToRecipient("192.0.2.1", "user@gmail.com", bucket.Add(-time.Hour), bucket.Add(time.Minute))
+	if err != nil || len(failures) != 1 || failures[0].RetryDeliveredAt == nil || !failures[0].RetryDeliveredAt.Equal(deliveredAt) {
+		t.Errorf("Expected the deferral marked delivered, got %+v %v", failures, err)
+	}
+
+	counters, err := GetFailureCounters("192.0.2.1", "", bucket)
+	if err != nil {
+		t.Fatalf("GetFailureCounters failed: %v", err)
+	}
+	kinds := make(map[string]int)
+	for _, c := range counters {
+		kinds[c.EnhancedCode+" "+c.Kind] += c.Failures
+	}
+	if kinds["4.7.0 deferral"] != 0 || kinds["4.7.0 greylisted"] != 1 || kinds["5.7.1 deferral"] != 1 {
+		t.Errorf("Expected the delivered deferral moved to greylisted, got %v", kinds)
+	}
+}
+
+// TestStreamingReads tests that the row-by-row readers return what the
+// list functions do and stop at the first error of their callback
+func TestStreamingReads(t *testing.T) {
+	resetTables(t)
+
+	now := time.Now()
+	for i, eventID := range []string{"evt-1", "evt-2", "evt-3"} {
+		if err := InsertSMTPFailure(newFailure("192.0.2.1", eventID, now.Add(-time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+	var streamed []SMTPFailure
+	err := EachSMTPFailureByIPAndInstance(context.Background(), "192.0.2.1", "", now.Add(-time.Hour), func(f SMTPFailure) error {
+		streamed = append(streamed, f)
+		return nil
+	})
+	if err != nil || len(streamed) != 3 || streamed[0].EventID != "evt-1" || streamed[0].OrganizationalDomain != "gmail.com" {
+		t.Errorf("Expected the failures newest first, got %+v %v", streamed, err)
+	}
+
+	stop := errors.New("client gone")
+	calls := 0
+	err = EachSMTPFailureByIPAndInstance(context.Background(), "192.0.2.1", "", now.Add(-time.Hour), func(SMTPFailure) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the callback error after one call, got %v after %d", err, calls)
+	}
+
+	for _, m := range []*IPReputationMetrics{
+		{IP: "192.0.2.1", Status: "healthy", TotalSent: 100, TotalRejected: 1, RejectionRatio: 0.01, LastUpdated: now.Add(-2 * time.Hour)},
+		{IP: "192.0.2.1", Status: "warning", TotalSent: 100, TotalRejected: 8, RejectionRatio: 0.08, LastUpdated: now.Add(-time.Hour)},
+		{IP: "192.0.2.1", Status: "healthy", TotalSent: 100, TotalRejected: 0, RejectionRatio: 0, LastUpdated: now.Add(-48 * time.Hour)},
+	} {
+		if err := InsertIPReputationHistory(m); err != nil {
+			t.Fatalf("InsertIPReputationHistory failed: %v", err)
+		}
+	}
+	var history []IPHistoryRecord
+	err = EachIPHistoryRecord(context.Background(), "192.0.2.1", now.Add(-24*time.Hour), func(record IPHistoryRecord) error {
+		history = append(history, record)
+		return nil
+	})
+	if err != nil || len(history) != 2 || history[0].Status != "warning" || history[0].RejectionRatio != 0.08 || history[1].TotalRejected != 1 {
+		t.Errorf("Expected the last day's history newest first, got %+v %v", history, err)
+	}
+}
+
+// TestSendingVolumes tests that reported volumes replace earlier reports of
+// the same period, are summed over a window in proportion to their overlap,
+// and move with a migration
+func TestSendingVolumes(t *testing.T) {
+	resetTables(t)
+
+	hour := time.Now().Truncate(time.Hour)
+	err := UpsertSendingVolumes([]SendingVolume{
+		{SendingIP: "192.0.2.1", PeriodStart: hour.Add(-2 * time.Hour), PeriodEnd: hour.Add(-time.Hour), Messages: 1000, Source: "mta-eu"},
+		{SendingIP: "192.0.2.1", PeriodStart: hour.Add(-time.Hour), PeriodEnd: hour, Messages: 500, Source: "mta-eu"},
+		{SendingIP: "192.0.2.1", PeriodStart: hour.Add(-time.Hour), PeriodEnd: hour, Messages: 200, Source: "mta-us"},
+	})
+	if err != nil {
+		t.Fatalf("UpsertSendingVolumes failed: %v", err)
+	}
+	err = UpsertSendingVolumes([]SendingVolume{
+		{SendingIP: "192.0.2.1", PeriodStart: hour.Add(-time.Hour), PeriodEnd: hour, Messages: 800, Source: "mta-eu"},
+	})
+	if err != nil {
+		t.Fatalf("UpsertSendingVolumes failed: %v", err)
+	}
+
+	// Half of the earlier hour and all of the last, from both sources
+	messages, found, err := SumSendingVolume("192.0.2.1", hour.Add(-90*time.Minute), hour)
+	if err != nil || !found || messages != 1500 {
+		t.Errorf("Expected 1500 messages, got %d %v %v", messages, found, err)
+	}
+	if _, found, err := SumSendingVolume("198.51.100.1", hour.Add(-time.Hour), hour); err != nil || found {
+		t.Errorf("Expected no volume for an unreported IP, got %v %v", found, err)
+	}
+
+	migration, err := MigrateIPHistory("192.0.2.1", "198.51.100.1", "alice", "")
+	if err != nil {
+		t.Fatalf("MigrateIPHistory failed: %v", err)
+	}
+	if migration.Moved["sending_volumes"] != 3 {
+		t.Errorf("Expected 3 volumes moved, got %+v", migration.Moved)
+	}
+	if messages, _, err := SumSendingVolume("198.51.100.1", hour.Add(-time.Hour), hour); err != nil || messages != 1000 {
+		t.Errorf("Expected the last hour's 1000 messages moved, got %d %v", messages, err)
+	}
+}
+
+// TestSentVolume tests that delivery successes are counted per bucket once
+// per event ID, summed over a window, pruned and moved with a migration
+func TestSentVolume(t *testing.T) {
+	resetTables(t)
+
+	minute := time.Now().Truncate(time.Minute)
+	successes := []DeliverySuccess{
+		{SendingIP: "192.0.2.1", EventID: "s1", MTAInstance: "mta-1", Timestamp: minute.Add(-90 * time.Minute)},
+		{SendingIP: "192.0.2.1", EventID: "s2", MTAInstance: "mta-1", Timestamp: minute.Add(-10 * time.Minute)},
+		{SendingIP: "192.0.2.1", EventID: "s3", MTAInstance: "mta-2", Timestamp: minute.Add(-10 * time.Minute)},
+		{SendingIP: "192.0.2.1", MTAInstance: "mta-1", Timestamp: minute.Add(-10 * time.Minute)},
+	}
+	for i := range successes {
+		if stored, err := RecordDeliverySuccess(&successes[i]); err != nil || !stored {
+			t.Fatalf("RecordDeliverySuccess failed: %v %v", stored, err)
+		}
+	}
+	if stored, err := RecordDeliverySuccess(&successes[1]); err != nil || stored {
+		t.Errorf("Expected a redelivered event not to count, got %v %v", stored, err)
+	}
+
+	messages, found, err := SumSentVolume("192.0.2.1", minute.Add(-time.Hour), minute)
+	if err != nil || !found || messages != 3 {
+		t.Errorf("Expected 3 messages in the last hour, got %d %v %v", messages, found, err)
+	}
+	if _, found, err := SumSentVolume("198.51.100.1", minute.Add(-time.Hour), minute); err != nil || found {
+		t.Errorf("Expected no volume for an IP without deliveries, got %v %v", found, err)
+	}
+
+	pruned, err := PruneSentVolume(minute.Add(-time.Hour))
+	if err != nil || pruned != 1 {
+		t.Errorf("Expected the oldest bucket pruned, got %d %v", pruned, err)
+	}
+
+	migration, err := MigrateIPHistory("192.0.2.1", "198.51.100.1", "alice", "")
+	if err != nil {
+		t.Fatalf("MigrateIPHistory failed: %v", err)
+	}
+	if migration.Moved["sent_volume"] != 2 {
+		t.Errorf("Expected 2 buckets moved, got %+v", migration.Moved)
+	}
+	if messages, _, err := SumSentVolume("198.51.100.1", minute.Add(-time.Hour), minute); err != nil || messages != 3 {
+		t.Errorf("Expected the 3 messages moved, got %d %v", messages, err)
+	}
+}
+
+// TestIPRateLimits tests that a rate cap replaces the earlier one, is only
+// served until it expires, and that expired caps are deleted and returned
+func TestIPRateLimits(t *testing.T) {
+	if _, err := DB.Exec(`TRUNCATE ip_rate_limits`); err != nil {
+		t.Fatalf("Failed to reset rate limits: %v", err)
+	}
+	defer DB.Exec(`TRUNCATE ip_rate_limits`)
+
+	if _, err := SetIPRateLimit(&IPRateLimit{IP: "192.0.2.1", MessagesPerMinute: 30, SetBy: "alice", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("SetIPRateLimit failed: %v", err)
+	}
+	limit, err := SetIPRateLimit(&IPRateLimit{IP: "192.0.2.1", MessagesPerMinute: 10, Reason: "deferrals", SetBy: "bob", ExpiresAt: time.Now().Add(2 * time.Hour)})
+	if err != nil || limit.MessagesPerMinute != 10 || limit.SetBy != "bob" {
+		t.Fatalf("Expected the cap replaced, got %+v %v", limit, err)
+	}
+	if got, err := GetIPRateLimit("192.0.2.1"); err != nil || got == nil || got.Reason != "deferrals" {
+		t.Errorf("Expected the cap served, got %+v %v", got, err)
+	}
+
+	if _, err := SetIPRateLimit(&IPRateLimit{IP: "192.0.2.2", MessagesPerMinute: 5, SetBy: "alice", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("SetIPRateLimit failed: %v", err)
+	}
+	if got, err := GetIPRateLimit("192.0.2.2"); err != nil || got != nil {
+		t.Errorf("Expected no expired cap served, got %+v %v", got, err)
+	}
+	expired, err := DeleteExpiredIPRateLimits(time.Now())
+	if err != nil || len(expired) != 1 || expired[0].IP != "192.0.2.2" {
+		t.Errorf("Expected the expired cap deleted, got %+v %v", expired, err)
+	}
+
+	if lifted, err := DeleteIPRateLimit("192.0.2.1"); err != nil || !lifted {
+		t.Errorf("Expected the cap lifted, got %v %v", lifted, err)
+	}
+	if lifted, err := DeleteIPRateLimit("192.0.2.1"); err != nil || lifted {
+		t.Errorf("Expected nothing left to lift, got %v %v", lifted, err)
+	}
+}
+
+// TestSLOMeasurements tests that the fleet is counted per status without
+// archived IPs, and that measurements are counted, listed newest first and
+// pruned
+func TestSLOMeasurements(t *testing.T) {
+	resetTables(t)
+	if _, err := DB.Exec(`TRUNCATE archived_ips`); err != nil {
+		t.Fatalf("Failed to reset archived IPs: %v", err)
+	}
+	defer DB.Exec(`TRUNCATE archived_ips`)
+
+	for ip, status := range map[string]string{"192.0.2.1": "healthy", "192.0.2.2": "healthy", "192.0.2.3": "blacklisted", "192.0.2.4": "warning"} {
+		if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: ip, Status: status, DistinctRejectionReasons: map[string]int{}, MajorProvidersRejecting: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+			t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+		}
+	}
+	if _, err := ArchiveIPs([]string{"192.0.2.4"}, "decommissioned", "alice"); err != nil {
+		t.Fatalf("ArchiveIPs failed: %v", err)
+	}
+	counts, err := CountIPsByStatus()
+	if err != nil || counts["healthy"] != 2 || counts["blacklisted"] != 1 || counts["warning"] != 0 {
+		t.Errorf("Unexpected status counts: %v %v", counts, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	var measurements []SLOMeasurement
+	for i, met := range []bool{true, false, true} {
+		measurements = append(measurements, SLOMeasurement{Objective: "fleet_healthy", MeasuredAt: now.Add(-time.Duration(i) * time.Hour), Value: 0.96, Threshold: 0.95, Met: met, TotalIPs: 3})
+	}
+	measurements = append(measurements, SLOMeasurement{Objective: "no_blacklisted", MeasuredAt: now, Value: 1, Threshold: 0, Met: false, TotalIPs: 3})
+	if err := InsertSLOMeasurements(measurements); err != nil {
+		t.Fatalf("InsertSLOMeasurements failed: %v", err)
+	}
+
+	if total, met, err := CountSLOMeasurements("fleet_healthy", now.Add(-90*time.Minute)); err != nil || total != 2 || met != 1 {
+		t.Errorf("Expected 1 of 2 measurements met, got %d of %d %v", met, total, err)
+	}
+	latest, err := GetLatestSLOMeasurement("fleet_healthy")
+	if err != nil || latest == nil || !latest.MeasuredAt.Equal(now) || !latest.Met {
+		t.Errorf("Unexpected latest measurement: %+v %v", latest, err)
+	}
+	if latest, err := GetLatestSLOMeasurement("unmeasured"); err != nil || latest != nil {
+		t.Errorf("Expected no measurement, got %+v %v", latest, err)
+	}
+	listed, err := GetSLOMeasurements("fleet_healthy", now.Add(-3*time.Hour))
+	if err != nil || len(listed) != 3 || !listed[0].MeasuredAt.Equal(now) {
+		t.Errorf("Expected 3 measurements newest first, got %+v %v", listed, err)
+	}
+
+	deleted, err := DeleteSLOMeasurementsBefore(now.Add(-90 * time.Minute))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 measurement pruned, got %d %v", deleted, err)
+	}
+}
+
+// TestAuditQueries tests that the self-audit finds metrics left behind by
+// newer failures, unreleased reservations and their blocks, DNSBL checks
+// older than the cutoff and actions of unknown IPs, and that reports are
+// stored, listed newest first and pruned
+func TestAuditQueries(t *testing.T) {
+	resetTables(t)
+	reset := func() {
+		if _, err := DB.Exec(`TRUNCATE reserved_ips, archived_ips, audit_reports RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("Failed to reset audit state: %v", err)
+		}
+	}
+	reset()
+	defer reset()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: ip, Status: "healthy", DistinctRejectionReasons: map[string]int{}, MajorProvidersRejecting: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+			t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+		}
+	}
+	if _, err := DB.Exec(`UPDATE ip_reputation_metrics SET last_updated = $1`, now.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("Failed to age metrics: %v", err)
+	}
+	// 192.0.2.1 and the archived 192.0.2.3 received failures since; 192.0.2.2 is quiet
+	for i, ip := range []string{"192.0.2.1", "192.0.2.3"} {
+		if err := InsertSMTPFailure(newFailure(ip, fmt.Sprintf("audit-%d", i), now.Add(-time.Hour))); err != nil {
+			t.Fatalf("InsertSMTPFailure failed: %v", err)
+		}
+	}
+	if _, err := ArchiveIPs([]string{"192.0.2.3"}, "decommissioned", "alice"); err != nil {
+		t.Fatalf("ArchiveIPs failed: %v", err)
+	}
+
+	stale, err := AuditStaleMetrics(now.Add(-24*time.Hour), 10)
+	if err != nil || stale.Count != 1 || len(stale.Samples) != 1 || stale.Samples[0] != "192.0.2.1" {
+		t.Errorf("Expected only 192.0.2.1 stale, got %+v %v", stale, err)
+	}
+
+	for _, r := range []ReservedIP{
+		{IPAddress: "198.51.100.1", ReservationBlockID: "block-1", UID: "ip-1", Location: "us/ewr", Status: "reserved"},
+		{IPAddress: "198.51.100.2", UID: "ip-2", Location: "us/ewr", Status: "in_use"},
+		{IPAddress: "198.51.100.3", ReservationBlockID: "block-3", UID: "ip-3", Location: "us/ewr", Status: "released"},
+	} {
+		r.BlacklistDetails, r.Metadata = []string{}, map[string]interface{}{}
+		if err := CreateReservedIP(&r); err != nil {
+			t.Fatalf("CreateReservedIP failed: %v", err)
+		}
+	}
+	blocks, err := ListActiveReservedIPBlocks()
+	if err != nil || len(blocks) != 2 || blocks[0] != (ReservedIPBlock{IP: "198.51.100.1", BlockID: "block-1"}) || blocks[1].BlockID != "" {
+		t.Errorf("Expected the two unreleased reservations, got %+v %v", blocks, err)
+	}
+
+	for ip, age := range map[string]time.Duration{"192.0.2.1": time.Hour, "192.0.2.2": 96 * time.Hour} {
+		if err := InsertDNSBLCheck(&DNSBLCheck{IP: ip, CheckedAt: now.Add(-age), Listings: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+			t.Fatalf("InsertDNSBLCheck failed: %v", err)
+		}
+	}
+	if _, err := DB.Exec(`UPDATE reserved_ips SET last_checked_at = $1 WHERE uid = 'ip-1'`, now); err != nil {
+		t.Fatalf("Failed to mark reservation checked: %v", err)
+	}
+	dnsbl, err := AuditStaleDNSBLChecks(now.Add(-72*time.Hour), 10)
+	if err != nil || dnsbl.Count != 2 || len(dnsbl.Samples) != 2 || dnsbl.Samples[0] != "198.51.100.2" || dnsbl.Samples[1] != "192.0.2.2" {
+		t.Errorf("Expected the never checked reservation, then 192.0.2.2, got %+v %v", dnsbl, err)
+	}
+	if limited, err := AuditStaleDNSBLChecks(now.Add(-72*time.Hour), 1); err != nil || limited.Count != 2 || len(limited.Samples) != 1 {
+		t.Errorf("Expected the count beyond the sample size, got %+v %v", limited, err)
+	}
+
+	for _, ip := range []string{"192.0.2.1", "203.0.113.9", "203.0.113.9", "192.0.2.3"} {
+		if err := InsertIPAction(&IPAction{IP: ip, Action: "quarantined", TriggeredBy: "automated", Metadata: map[string]interface{}{}, CreatedAt: now}); err != nil {
+			t.Fatalf("InsertIPAction failed: %v", err)
+		}
+	}
+	orphans, err := AuditOrphanedActions(10)
+	if err != nil || orphans.Count != 2 || len(orphans.Samples) != 1 || orphans.Samples[0] != "203.0.113.9" {
+		t.Errorf("Expected both actions of 203.0.113.9 orphaned, got %+v %v", orphans, err)
+	}
+
+	if latest, err := GetLatestAuditReport(); err != nil || latest != nil {
+		t.Errorf("Expected no report yet, got %+v %v", latest, err)
+	}
+	for _, started := range []time.Time{now.Add(-48 * time.Hour), now} {
+		report := &AuditReport{StartedAt: started, FinishedAt: started.Add(time.Second), Issues: 3, Exceeded: 1, Checks: []AuditCheck{
+			{Name: "orphaned_actions", Count: 3, Exceeded: true, Samples: []string{"203.0.113.9"}},
+		}}
+		if err := InsertAuditReport(report); err != nil || report.ID == 0 {
+			t.Fatalf("InsertAuditReport failed: %v", err)
+		}
+	}
+	latest, err := GetLatestAuditReport()
+	if err != nil || latest == nil || !latest.StartedAt.Equal(now) || len(latest.Checks) != 1 || latest.Checks[0].Samples[0] != "203.0.113.9" {
+		t.Errorf("Unexpected latest report: %+v %v", latest, err)
+	}
+	if reports, err := ListAuditReports(10); err != nil || len(reports) != 2 || reports[0].ID != latest.ID {
+		t.Errorf("Expected 2 reports newest first, got %+v %v", reports, err)
+	}
+	if deleted, err := DeleteAuditReportsBefore(now.Add(-24 * time.Hour)); err != nil || deleted != 1 {
+		t.Errorf("Expected 1 report pruned, got %d %v", deleted, err)
+	}
+}
+
+// TestAPIKeys tests that revoked keys stop authenticating, that a revoked
+// key's name can be reused and that new users start read-only
+func TestAPIKeys(t *testing.T) {
+	resetTables(t)
+	reset := func() {
+		if _, err := DB.Exec(`TRUNCATE api_keys RESTART IDENTITY`); err != nil {
+			t.Fatalf("Failed to reset API keys: %v", err)
+		}
+		if _, err := DB.Exec(`DELETE FROM users WHERE username = 'viewer'`); err != nil {
+			t.Fatalf("Failed to delete test user: %v", err)
+		}
+	}
+	reset()
+	defer reset()
+
+	key, err := CreateAPIKey("deploy", "operator", "ak_01234567", "hash-1", "user:admin")
+	if err != nil || key.Role != "operator" || key.LastUsedAt != nil {
+		t.Fatalf("CreateAPIKey failed: %+v %v", key, err)
+	}
+	if _, err := CreateAPIKey("deploy", "admin", "ak_89abcdef", "hash-2", "user:admin"); err != ErrAPIKeyExists {
+		t.Errorf("Expected ErrAPIKeyExists for an active name, got %v", err)
+	}
+
+	if err := TouchAPIKey(key.ID); err != nil {
+		t.Fatalf("TouchAPIKey failed: %v", err)
+	}
+	found, err := GetActiveAPIKeyByHash("hash-1")
+	if err != nil || found == nil || found.LastUsedAt == nil {
+		t.Fatalf("Expected the used key, got %+v %v", found, err)
+	}
+
+	if revoked, err := RevokeAPIKey(key.ID); err != nil || !revoked {
+		t.Fatalf("RevokeAPIKey failed: %v %v", revoked, err)
+	}
+	if revoked, _ := RevokeAPIKey(key.ID); revoked {
+		t.Error("Expected a revoked key not to be revoked again")
+	}
+	if found, err := GetActiveAPIKeyByHash("hash-1"); err != nil || found != nil {
+		t.Errorf("Expected the revoked key not found, got %+v %v", found, err)
+	}
+	if _, err := CreateAPIKey("deploy", "admin", "ak_89abcdef", "hash-2", "user:admin"); err != nil {
+		t.Errorf("Expected the name of a revoked key to be reusable, got %v", err)
+	}
+	if keys, err := ListAPIKeys(); err != nil || len(keys) != 2 || keys[0].RevokedAt != nil {
+		t.Errorf("Expected both keys newest first, got %+v %v", keys, err)
+	}
+
+	user, err := CreateUser("viewer", "viewer@example.com")
+	if err != nil || user.Role != "read-only" {
+		t.Fatalf("Expected new users to start read-only, got %+v %v", user, err)
+	}
+	if user, err := SetUserRole(user.ID, "operator"); err != nil || user.Role != "operator" {
+		t.Errorf("SetUserRole failed: %+v %v", user, err)
+	}
+}
+
+// TestIPContext tests that the context of an IP is replaced on update and
+// shown in its registry row
+func TestIPContext(t *testing.T) {
+	resetTables(t)
+	reset := func() {
+		if _, err := DB.Exec(`TRUNCATE ip_context`); err != nil {
+			t.Fatalf("Failed to reset IP context: %v", err)
+		}
+	}
+	reset()
+	defer reset()
+
+	if c, err := GetIPContext("192.0.2.1"); err != nil || c != nil {
+		t.Fatalf("Expected no context, got %+v %v", c, err)
+	}
+	if _, err := SetIPContext(&IPContext{IP: "192.0.2.1", Notes: "Customer X", RunbookURL: "https://wiki.example.com/x", UpdatedBy: "alice"}); err != nil {
+		t.Fatalf("SetIPContext failed: %v", err)
+	}
+	c, err := SetIPContext(&IPContext{IP: "192.0.2.1", Notes: "Customer Y", UpdatedBy: "bob"})
+	if err != nil || c.Notes != "Customer Y" || c.RunbookURL != "" || c.UpdatedBy != "bob" {
+		t.Fatalf("Expected the context replaced, got %+v %v", c, err)
+	}
+
+	if err := UpsertIPReputationMetrics(&IPReputationMetrics{IP: "192.0.2.1", Status: "healthy", DistinctRejectionReasons: map[string]int{}, MajorProvidersRejecting: []string{}, Metadata: map[string]interface{}{}}); err != nil {
+		t.Fatalf("UpsertIPReputationMetrics failed: %v", err)
+	}
+	registry, err := ListIPRegistry()
+	if err != nil {
+		t.Fatalf("ListIPRegistry failed: %v", err)
+	}
+	var row *IPRegistryRow
+	for i := range registry {
+		if registry[i].IP == "192.0.2.1" {
+			row = &registry[i]
+		}
+	}
+	if row == nil || row.Notes == nil || *row.Notes != "Customer Y" || row.RunbookURL != nil {
+		t.Errorf("Expected the notes and no runbook in the registry, got %+v", row)
+	}
+	if contexts, err := ListIPContexts(); err != nil || contexts["192.0.2.1"].Notes != "Customer Y" {
+		t.Errorf("ListIPContexts failed: %+v %v", contexts, err)
+	}
+
+	if deleted, err := DeleteIPContext("192.0.2.1"); err != nil || !deleted {
+		t.Errorf("DeleteIPContext failed: %v %v", deleted, err)
+	}
+}
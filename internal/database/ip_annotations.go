@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IPActionComment is an operator's annotation of an IP action
+type IPActionComment struct {
+	ID        int       `json:"id"`
+	ActionID  int       `json:"action_id"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IPNote is a free-form operator note on an IP
+type IPNote struct {
+	ID        int       `json:"id"`
+	IP        string    `json:"ip"`
+	Author    string    `json:"author"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetIPAction returns an action by ID, or nil if it does not exist
+func GetIPAction(id int) (*IPAction, error) {
+	query := `
+		SELECT id, ip, action, previous_status, new_status, reason, triggered_by, metadata, created_at
+		FROM ip_actions
+		WHERE id = $1
+	`
+
+	var action IPAction
+	var metadataJSON []byte
+	err := DB.QueryRow(query, id).Scan(
+		&action.ID, &action.IP, &action.Action, &action.PreviousStatus, &action.NewStatus,
+		&action.Reason, &action.TriggeredBy, &metadataJSON, &action.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP action: %w", err)
+	}
+	if err := json.Unmarshal(metadataJSON, &action.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return &action, nil
+}
+
+// CreateIPActionComment stores a comment on an action
+func CreateIPActionComment(comment *IPActionComment) (*IPActionComment, error) {
+	created := *comment
+	err := DB.QueryRow(`
+		INSERT INTO ip_action_comments (action_id, author, comment)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, comment.ActionID, comment.Author, comment.Comment).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IP action comment: %w", err)
+	}
+	return &created, nil
+}
+
+// ListIPActionComments returns the comments of the given actions, oldest
+// first, keyed by action ID
+func ListIPActionComments(actionIDs []int) (map[int][]IPActionComment, error) {
+	comments := make(map[int][]IPActionComment)
+	if len(actionIDs) == 0 {
+		return comments, nil
+	}
+
+	ids := make([]int64, len(actionIDs))
+	for i, id := range actionIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := DB.Query(`
+		SELECT id, action_id, author, comment, created_at
+		FROM ip_action_comments
+		WHERE action_id = ANY($1)
+		ORDER BY created_at, id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IP action comments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c IPActionComment
+		if err := rows.Scan(&c.ID, &c.ActionID, &c.Author, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan IP action comment: %w", err)
+		}
+		comments[c.ActionID] = append(comments[c.ActionID], c)
+	}
+	return comments, rows.Err()
+}
+
+// CreateIPNote stores a note on an IP
+func CreateIPNote(note *IPNote) (*IPNote, error) {
+	created := *note
+	err := DB.QueryRow(`
+		INSERT INTO ip_notes (ip, author, note)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, note.IP, note.Author, note.Note).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IP note: %w", err)
+	}
+	return &created, nil
+}
+
+// ListIPNotes returns the notes on an IP, newest first
+func ListIPNotes(ip string) ([]IPNote, error) {
+	rows, err := DB.Query(`
+		SELECT id, ip, author, note, created_at
+		FROM ip_notes
+		WHERE ip = $1
+		ORDER BY created_at DESC, id DESC
+	`, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IP notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []IPNote{}
+	for rows.Next() {
+		var n IPNote
+		if err := rows.Scan(&n.ID, &n.IP, &n.Author, &n.Note, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan IP note: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// DeleteIPNote removes a note from an IP and reports whether it existed
+func DeleteIPNote(ip string, id int) (bool, error) {
+	result, err := DB.Exec(`DELETE FROM ip_notes WHERE id = $1 AND ip = $2`, id, ip)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete IP note: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
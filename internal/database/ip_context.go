@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IPContext is what on-call engineers should know about an IP: free-form
+// notes, e.g. "serves customer X's transactional mail", and the runbook to
+// follow when it alerts
+type IPContext struct {
+	IP         string    `json:"ip"`
+	Notes      string    `json:"notes"`
+	RunbookURL string    `json:"runbook_url"`
+	UpdatedBy  string    `json:"updated_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const ipContextColumns = `ip, notes, runbook_url, updated_by, updated_at`
+
+func scanIPContext(row interface{ Scan(...interface{}) error }) (*IPContext, error) {
+	var c IPContext
+	if err := row.Scan(&c.IP, &c.Notes, &c.RunbookURL, &c.UpdatedBy, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetIPContext returns the context of an IP, nil if none was set
+func GetIPContext(ip string) (*IPContext, error) {
+	c, err := scanIPContext(DB.QueryRow(`SELECT `+ipContextColumns+` FROM ip_context WHERE ip = $1`, ip))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP context: %w", err)
+	}
+	return c, nil
+}
+
+// SetIPContext creates or replaces the context of an IP
+func SetIPContext(c *IPContext) (*IPContext, error) {
+	saved, err := scanIPContext(DB.QueryRow(`
+		INSERT INTO ip_context (ip, notes, runbook_url, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (ip) DO UPDATE SET
+			notes = EXCLUDED.notes,
+			runbook_url = EXCLUDED.runbook_url,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING `+ipContextColumns,
+		c.IP, c.Notes, c.RunbookURL, c.UpdatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IP context: %w", err)
+	}
+	return saved, nil
+}
+
+// DeleteIPContext clears the context of an IP. It reports whether one was set.
+func DeleteIPContext(ip string) (bool, error) {
+	result, err := DB.Exec(`DELETE FROM ip_context WHERE ip = $1`, ip)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete IP context: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ListIPContexts returns the context of every IP that has one, by IP
+func ListIPContexts() (map[string]IPContext, error) {
+	rows, err := DB.Query(`SELECT ` + ipContextColumns + ` FROM ip_context`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP contexts: %w", err)
+	}
+	defer rows.Close()
+
+	contexts := make(map[string]IPContext)
+	for rows.Next() {
+		c, err := scanIPContext(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan IP context: %w", err)
+		}
+		contexts[c.IP] = *c
+	}
+	return contexts, rows.Err()
+}
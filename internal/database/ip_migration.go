@@ -0,0 +1,159 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoIPHistory is returned when migrating an IP nothing is stored about
+var ErrNoIPHistory = errors.New("no history stored for IP")
+
+// IPMigrationAction is the ip_actions action recording a migration on both
+// addresses
+const IPMigrationAction = "history_migrated"
+
+// migrationTable holds rows about an IP that a migration re-keys
+type migrationTable struct {
+	name   string
+	column string
+	// unique is set when the IP is part of a unique key, key the rest of it
+	unique bool
+	key    []string
+	// exclude filters out rows that stay with the old address
+	exclude string
+	// merge is the count column summed into the new address's row where
+	// both have one, instead of keeping the new address's own
+	merge string
+}
+
+// migrationTables are the reputation history of an IP: its failures, the
+// metrics and counters derived from them, the volumes it reported, its
+// actions and notes, and its DNSBL and external lookups. Pool membership, ownership, reservations and feed
+// verdicts belong to the address and stay, as do earlier migration records.
+var migrationTables = []migrationTable{
+	{name: "smtp_failures", column: "sending_ip"},
+	{name: "smtp_auth_failures", column: "sending_ip"},
+	{name: "spamtrap_hits", column: "sending_ip"},
+	{name: "abuse_complaints", column: "sending_ip"},
+	{name: "tls_failures", column: "sending_ip"},
+	{name: "failure_counters", column: "sending_ip", unique: true, key: []string{"bucket_start", "mta_instance", "domain", "enhanced_code", "kind"}, merge: "failures"},
+	{name: "sending_volumes", column: "sending_ip", unique: true, key: []string{"period_start", "period_end", "source"}, merge: "messages"},
+	{name: "sent_volume", column: "sending_ip", unique: true, key: []string{"bucket_start", "mta_instance"}, merge: "messages"},
+	{name: "ip_reputation_metrics", column: "ip", unique: true},
+	{name: "ip_reputation_history", column: "ip"},
+	{name: "ip_reputation_windows", column: "ip", unique: true, key: []string{"window_name"}},
+	{name: "ip_throttle_states", column: "ip", unique: true, key: []string{"provider"}},
+	{name: "ip_send_rates", column: "ip", unique: true, key: []string{"provider"}},
+	{name: "dnsbl_checks", column: "ip"},
+	{name: "reputation_lookups", column: "ip"},
+	{name: "ip_actions", column: "ip", exclude: "action = '" + IPMigrationAction + "'"},
+	{name: "ip_notes", column: "ip"},
+}
+
+// IPMigration is the outcome of moving an IP's history to a new address
+type IPMigration struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Moved counts the rows re-keyed to the new address per table
+	Moved map[string]int64 `json:"moved"`
+	// Superseded counts derived rows of the old address dropped because the
+	// new one already has its own, e.g. current metrics
+	Superseded  map[string]int64 `json:"superseded,omitempty"`
+	MigratedBy  string           `json:"migrated_by"`
+	Comment     string           `json:"comment,omitempty"`
+	MigratedAt  time.Time        `json:"migrated_at"`
+	AuditAction int              `json:"audit_action_id"`
+}
+
+// MigrateIPHistory re-keys the history of from to to in one transaction and
+// records the migration as an action on both addresses. Where both have a
+// row for the same unique key (metrics, windows, throttle states, send
+// rates) the new address keeps its own; those are recomputed every
+// aggregation run. Failure counters and sent volume of the same bucket are summed. Rows stored for from while it keeps sending can be moved
+// by migrating again. Returns ErrNoIPHistory when from has no history.
+func MigrateIPHistory(from, to, author, comment string) (*IPMigration, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	comment = strings.TrimSpace(comment)
+	migration := &IPMigration{From: from, To: to, Moved: make(map[string]int64), Superseded: make(map[string]int64), MigratedBy: author, Comment: comment, MigratedAt: time.Now()}
+	var total int64
+	for _, t := range migrationTables {
+		if t.unique {
+			match := fmt.Sprintf("n.%s = $2", t.column)
+			for _, k := range t.key {
+				match += fmt.Sprintf(" AND n.%s = o.%s", k, k)
+			}
+			if t.merge != "" {
+				result, err := tx.Exec(fmt.Sprintf(`UPDATE %s n SET %s = n.%s + o.%s FROM %s o WHERE o.%s = $1 AND %s`, t.name, t.merge, t.merge, t.merge, t.name, t.column, match), from, to)
+				if err != nil {
+					return nil, fmt.Errorf("failed to merge %s: %w", t.name, err)
+				}
+				if n, _ := result.RowsAffected(); n > 0 {
+					migration.Moved[t.name] = n
+					total += n
+				}
+			}
+			result, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s o WHERE o.%s = $1 AND EXISTS (SELECT 1 FROM %s n WHERE %s)`, t.name, t.column, t.name, match), from, to)
+			if err != nil {
+				return nil, fmt.Errorf("failed to supersede %s: %w", t.name, err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 && t.merge == "" {
+				migration.Superseded[t.name] = n
+				total += n
+			}
+		}
+		update := fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE %s = $1`, t.name, t.column, t.column)
+		if t.exclude != "" {
+			update += " AND NOT (" + t.exclude + ")"
+		}
+		result, err := tx.Exec(update, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate %s: %w", t.name, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			migration.Moved[t.name] += n
+			total += n
+		}
+	}
+	if total == 0 {
+		return nil, ErrNoIPHistory
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"from":       from,
+		"to":         to,
+		"moved":      migration.Moved,
+		"superseded": migration.Superseded,
+		"comment":    comment,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migration metadata: %w", err)
+	}
+	insert := `
+		INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by, metadata)
+		VALUES ($1, $2, '', '', $3, $4, $5)
+		RETURNING id
+	`
+	reason := fmt.Sprintf("History migrated from %s", from)
+	if comment != "" {
+		reason += ": " + comment
+	}
+	if err := tx.QueryRow(insert, to, IPMigrationAction, reason, author, metadata).Scan(&migration.AuditAction); err != nil {
+		return nil, fmt.Errorf("failed to record migration: %w", err)
+	}
+	if _, err := tx.Exec(insert, from, IPMigrationAction, fmt.Sprintf("History migrated to %s", to), author, metadata); err != nil {
+		return nil, fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return migration, nil
+}
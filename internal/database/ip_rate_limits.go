@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IPRateLimit is an explicit outbound rate cap on an IP. It holds until
+// ExpiresAt, when the IP goes back to its recommended send rates.
+type IPRateLimit struct {
+	IP                string    `json:"ip"`
+	MessagesPerMinute int       `json:"messages_per_minute"`
+	Reason            string    `json:"reason,omitempty"`
+	SetBy             string    `json:"set_by"`
+	SetAt             time.Time `json:"set_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+const ipRateLimitColumns = `ip, messages_per_minute, reason, set_by, set_at, expires_at`
+
+func scanIPRateLimit(row interface{ Scan(...interface{}) error }) (*IPRateLimit, error) {
+	var limit IPRateLimit
+	if err := row.Scan(&limit.IP, &limit.MessagesPerMinute, &limit.Reason, &limit.SetBy, &limit.SetAt, &limit.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// SetIPRateLimit sets the rate cap of an IP, replacing an earlier one
+func SetIPRateLimit(limit *IPRateLimit) (*IPRateLimit, error) {
+	query := `
+		INSERT INTO ip_rate_limits (ip, messages_per_minute, reason, set_by, set_at, expires_at)
+		VALUES ($1, $2, $3, $4, NOW(), $5)
+		ON CONFLICT (ip) DO UPDATE SET
+			messages_per_minute = EXCLUDED.messages_per_minute,
+			reason = EXCLUDED.reason,
+			set_by = EXCLUDED.set_by,
+			set_at = EXCLUDED.set_at,
+			expires_at = EXCLUDED.expires_at
+		RETURNING ` + ipRateLimitColumns
+
+	stored, err := scanIPRateLimit(DB.QueryRow(query, limit.IP, limit.MessagesPerMinute, limit.Reason, limit.SetBy, limit.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IP rate limit: %w", err)
+	}
+	return stored, nil
+}
+
+// GetIPRateLimit returns the rate cap of an IP, nil when it has none or it
+// expired
+func GetIPRateLimit(ip string) (*IPRateLimit, error) {
+	query := `SELECT ` + ipRateLimitColumns + ` FROM ip_rate_limits WHERE ip = $1 AND expires_at > NOW()`
+
+	limit, err := scanIPRateLimit(DB.QueryRow(query, ip))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP rate limit: %w", err)
+	}
+	return limit, nil
+}
+
+// DeleteIPRateLimit lifts the rate cap of an IP. It reports whether the IP
+// had one that had not expired.
+func DeleteIPRateLimit(ip string) (bool, error) {
+	var active bool
+	err := DB.QueryRow(`DELETE FROM ip_rate_limits WHERE ip = $1 RETURNING expires_at > NOW()`, ip).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete IP rate limit: %w", err)
+	}
+	return active, nil
+}
+
+// DeleteExpiredIPRateLimits deletes the rate caps expired at now and
+// returns them
+func DeleteExpiredIPRateLimits(now time.Time) ([]IPRateLimit, error) {
+	rows, err := DB.Query(`DELETE FROM ip_rate_limits WHERE expires_at <= $1 RETURNING `+ipRateLimitColumns, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired IP rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	limits := []IPRateLimit{}
+	for rows.Next() {
+		limit, err := scanIPRateLimit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan IP rate limit: %w", err)
+		}
+		limits = append(limits, *limit)
+	}
+	return limits, rows.Err()
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IPRegistryRow is everything known about one IP across the reservation,
+// reputation, DNSBL and ownership tables. Fields of a subsystem that has no
+// record of the IP are nil.
+type IPRegistryRow struct {
+	IP string `json:"ip"`
+
+	ReservedIPID      *int    `json:"reserved_ip_id,omitempty"`
+	ReservationStatus *string `json:"reservation_status,omitempty"`
+	Location          *string `json:"location,omitempty"`
+	BlockID           *string `json:"block_id,omitempty"`
+	ReservedBlacklist bool    `json:"reserved_blacklisted"`
+
+	ReputationStatus *string    `json:"reputation_status,omitempty"`
+	RejectionRatio   *float64   `json:"rejection_ratio,omitempty"`
+	ReputationAt     *time.Time `json:"reputation_updated_at,omitempty"`
+
+	DNSBLListed    *bool      `json:"dnsbl_listed,omitempty"`
+	DNSBLListings  []string   `json:"dnsbl_listings,omitempty"`
+	DNSBLCheckedAt *time.Time `json:"dnsbl_checked_at,omitempty"`
+
+	Pools  []string  `json:"pools"`
+	Owners []IPOwner `json:"owners"`
+
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Notes and RunbookURL are the IP's on-call context, see IPContext
+	Notes      *string `json:"notes,omitempty"`
+	RunbookURL *string `json:"runbook_url,omitempty"`
+}
+
+// ListIPRegistry returns one row per IP known to any of the reserved IPs,
+// the reputation metrics, the pools or the ownership assignments, ordered
+// by IP
+func ListIPRegistry() ([]IPRegistryRow, error) {
+	return ListIPRegistryContext(context.Background())
+}
+
+// ListIPRegistryContext is ListIPRegistry, cancelled with ctx
+func ListIPRegistryContext(ctx context.Context) ([]IPRegistryRow, error) {
+	registry := []IPRegistryRow{}
+	err := EachIPRegistryRow(ctx, func(row IPRegistryRow) error {
+		registry = append(registry, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// EachIPRegistryRow calls fn with the rows of ListIPRegistry one at a time,
+// without holding them in memory. It stops at the first error fn returns.
+func EachIPRegistryRow(ctx context.Context, fn func(IPRegistryRow) error) error {
+	rows, err := DB.QueryContext(ctx, `
+		WITH ips AS (
+			SELECT host(ip_address) AS ip FROM reserved_ips
+			UNION SELECT ip FROM ip_reputation_metrics
+			UNION SELECT ip_address FROM ip_pool_members
+			UNION SELECT ip_address FROM ownership_assignments WHERE ip_address IS NOT NULL
+		)
+		SELECT i.ip,
+		       r.id, r.status, r.location, r.reservation_block_id, COALESCE(r.is_blacklisted, FALSE),
+		       m.status, m.rejection_ratio, m.last_updated,
+		       d.listed, d.listings, d.checked_at,
+		       COALESCE(pools.names, '{}'),
+		       COALESCE(owners.list, '[]'),
+		       ar.archived_at,
+		       NULLIF(c.notes, ''), NULLIF(c.runbook_url, '')
+		FROM ips i
+		LEFT JOIN reserved_ips r ON host(r.ip_address) = i.ip
+		LEFT JOIN ip_reputation_metrics m ON m.ip = i.ip
+		LEFT JOIN archived_ips ar ON ar.ip = i.ip
+		LEFT JOIN ip_context c ON c.ip = i.ip
+		LEFT JOIN LATERAL (
+			SELECT listed, listings, checked_at FROM dnsbl_checks
+			WHERE ip = i.ip ORDER BY checked_at DESC LIMIT 1
+		) d ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT array_agg(p.name ORDER BY p.name) AS names
+			FROM ip_pool_members pm JOIN ip_pools p ON p.id = pm.pool_id
+			WHERE pm.ip_address = i.ip
+		) pools ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT json_agg(json_build_object(
+			           'owner_type', CASE WHEN a.user_id IS NOT NULL THEN 'user' ELSE 'team' END,
+			           'owner_id', COALESCE(a.user_id, a.team_id),
+			           'owner_name', COALESCE(u.username, t.name),
+			           'via', COALESCE(p.name, '')
+			       ) ORDER BY COALESCE(u.username, t.name)) AS list
+			FROM ownership_assignments a
+			LEFT JOIN users u ON u.id = a.user_id
+			LEFT JOIN teams t ON t.id = a.team_id
+			LEFT JOIN ip_pools p ON p.id = a.pool_id
+			WHERE a.ip_address = i.ip
+			   OR a.pool_id IN (SELECT pool_id FROM ip_pool_members WHERE ip_address = i.ip)
+		) owners ON TRUE
+		ORDER BY i.ip
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query IP registry: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row IPRegistryRow
+		var listingsJSON, ownersJSON []byte
+		var pools pq.StringArray
+		err := rows.Scan(
+			&row.IP,
+			&row.ReservedIPID,
+			&row.ReservationStatus,
+			&row.Location,
+			&row.BlockID,
+			&row.ReservedBlacklist,
+			&row.ReputationStatus,
+			&row.RejectionRatio,
+			&row.ReputationAt,
+			&row.DNSBLListed,
+			&listingsJSON,
+			&row.DNSBLCheckedAt,
+			&pools,
+			&ownersJSON,
+			&row.ArchivedAt,
+			&row.Notes,
+			&row.RunbookURL,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan IP registry row: %w", err)
+		}
+
+		if listingsJSON != nil {
+			if err := json.Unmarshal(listingsJSON, &row.DNSBLListings); err != nil {
+				return fmt.Errorf("failed to unmarshal listings: %w", err)
+			}
+		}
+		if err := json.Unmarshal(ownersJSON, &row.Owners); err != nil {
+			return fmt.Errorf("failed to unmarshal owners: %w", err)
+		}
+		row.Pools = []string(pools)
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -1,10 +1,19 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang-backend-service/internal/validation"
+
+	"github.com/lib/pq"
 )
 
 // SMTPFailure represents an individual SMTP delivery failure
@@ -20,6 +29,72 @@ type SMTPFailure struct {
 	Timestamp       time.Time `json:"timestamp"`
 	EventID         string    `json:"event_id"`
 	AttemptNumber   int       `json:"attempt_number"`
+	SourceID        *int      `json:"source_id,omitempty"`
+	MTAInstance     string    `json:"mta_instance,omitempty"`
+	MessageID       string    `json:"message_id,omitempty"`
+	EventType       string    `json:"event_type,omitempty"`
+	// OrganizationalDomain is the registrable domain of RecipientDomain, so
+	// mx1.mail.yahoo.co.jp and yahoo.co.jp are one entity
+	OrganizationalDomain string `json:"organizational_domain,omitempty"`
+	// TLSPolicy is the TLS requirement (tlspolicy.Requirement*) the
+	// destination published when the failure was ingested, empty if unknown
+	TLSPolicy string `json:"tls_policy,omitempty"`
+	// ReasonFamily is Reason reduced to its template
+	// (validation.ReasonFamily), so reasons quoting different IPs or block
+	// IDs group together
+	ReasonFamily string `json:"reason_family,omitempty"`
+	// BlockReferences are the provider block list identifiers the reason
+	// quotes (validation.BlockReferences), e.g. Outlook's S3150
+	BlockReferences []string `json:"block_references,omitempty"`
+	// RetryDeliveredAt is when a delivery from the IP to the recipient
+	// followed this greylist-style deferral (MarkRetriesDelivered), which
+	// makes it greylisting rather than a rejection
+	RetryDeliveredAt *time.Time `json:"retry_delivered_at,omitempty"`
+}
+
+// OrgDomain returns the organizational domain, deriving it for failures that
+// were built in memory rather than read back from the database
+func (f SMTPFailure) OrgDomain() string {
+	if f.OrganizationalDomain != "" {
+		return f.OrganizationalDomain
+	}
+	return validation.RegistrableDomain(f.RecipientDomain)
+}
+
+// Family returns the reason family, deriving it for failures that were built
+// in memory rather than read back from the database
+func (f SMTPFailure) Family() string {
+	if f.ReasonFamily != "" {
+		return f.ReasonFamily
+	}
+	return validation.ReasonFamily(f.Reason)
+}
+
+// BlockRefs returns the block references, deriving them for failures that
+// were built in memory or stored before references were extracted
+func (f SMTPFailure) BlockRefs() []string {
+	if len(f.BlockReferences) > 0 {
+		return f.BlockReferences
+	}
+	return validation.BlockReferences(f.Reason)
+}
+
+// InstanceRejections summarizes the failures one MTA instance reported for an IP
+type InstanceRejections struct {
+	Instance      string    `json:"instance"`
+	TotalRejected int       `json:"total_rejected"`
+	UniqueDomains int       `json:"unique_domains"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// LiveRejectionCount counts an IP's failures over the last minutes, read
+// straight from smtp_failures instead of the aggregated metrics
+type LiveRejectionCount struct {
+	Minutes        int        `json:"minutes"`
+	Rejections     int        `json:"rejections"`
+	HardRejections int        `json:"hard_rejections"`
+	UniqueDomains  int        `json:"unique_domains"`
+	LastFailureAt  *time.Time `json:"last_failure_at,omitempty"`
 }
 
 // IPReputationMetrics represents aggregated reputation metrics for an IP
@@ -61,22 +136,83 @@ type IPAction struct {
 	TriggeredBy    string                 `json:"triggered_by"`
 	Metadata       map[string]interface{} `json:"metadata"`
 	CreatedAt      time.Time              `json:"created_at"`
+	Comments       []IPActionComment      `json:"comments,omitempty"`
+}
+
+// Outcomes of StoreSMTPFailure
+const (
+	FailureStored           = "stored"
+	FailureDuplicateEvent   = "duplicate_event"
+	FailureDuplicateContent = "duplicate_content"
+)
+
+// contentDedupWindow is the timestamp bucket of the content hash; 0 disables
+// content deduplication
+var contentDedupWindow time.Duration
+
+// SetContentDedupWindow makes failures with the same IP, recipient, codes
+// and attempt number within one window of this size count as duplicates,
+// whatever their event IDs
+func SetContentDedupWindow(window time.Duration) {
+	contentDedupWindow = window
+}
+
+// FailureContentHash identifies a failure by its content: sending IP,
+// recipient, SMTP and enhanced code, attempt number and timestamp truncated
+// to the window. Replays with new event IDs hash the same.
+func FailureContentHash(failure *SMTPFailure, window time.Duration) string {
+	bucket := failure.Timestamp.UTC().Truncate(window).Unix()
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		failure.SendingIP,
+		strings.ToLower(strings.TrimSpace(failure.RecipientEmail)),
+		strconv.Itoa(failure.SMTPCode),
+		failure.EnhancedCode,
+		strconv.Itoa(failure.AttemptNumber),
+		strconv.FormatInt(bucket, 10),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
 }
 
 // InsertSMTPFailure inserts a new SMTP failure record
 // Uses ON CONFLICT DO NOTHING to handle duplicate webhook events gracefully
 func InsertSMTPFailure(failure *SMTPFailure) error {
+	_, err := StoreSMTPFailure(failure)
+	return err
+}
+
+// StoreSMTPFailure inserts a failure unless its event ID, or with content
+// deduplication enabled its content hash, was stored before, and reports
+// which happened. With a failure classifier set, the failure is counted in
+// failure_counters by the same statement.
+func StoreSMTPFailure(failure *SMTPFailure) (string, error) {
 	query := `
 		INSERT INTO smtp_failures (
 			sending_ip, recipient_email, recipient_domain, smtp_code, 
-			enhanced_code, reason, mx_server, timestamp, event_id, attempt_number
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		ON CONFLICT (event_id) DO NOTHING
+			enhanced_code, reason, mx_server, timestamp, event_id, attempt_number,
+			source_id, mta_instance, message_id, event_type,
+			enhanced_class, enhanced_subject, enhanced_detail, organizational_domain,
+			content_hash, tls_policy, reason_family, block_references
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''),
+			COALESCE(NULLIF($14, ''), 'smtp.delivery.failure'), $15, $16, $17, $18, NULLIF($19, ''), NULLIF($20, ''), $21, $22)
+		ON CONFLICT DO NOTHING
 		RETURNING id
 	`
 
-	err := DB.QueryRow(
-		query,
+	// Malformed codes are kept as reported, with NULL parsed columns
+	var class, subject, detail sql.NullInt16
+	if code, err := validation.ParseEnhancedCode(failure.EnhancedCode); err == nil {
+		failure.EnhancedCode = code.String()
+		class = sql.NullInt16{Int16: int16(code.Class), Valid: true}
+		subject = sql.NullInt16{Int16: int16(code.Subject), Valid: true}
+		detail = sql.NullInt16{Int16: int16(code.Detail), Valid: true}
+	}
+
+	contentHash := ""
+	if contentDedupWindow > 0 {
+		contentHash = FailureContentHash(failure, contentDedupWindow)
+	}
+
+	args := []interface{}{
 		failure.SendingIP,
 		failure.RecipientEmail,
 		failure.RecipientDomain,
@@ -87,46 +223,141 @@ func InsertSMTPFailure(failure *SMTPFailure) error {
 		failure.Timestamp,
 		failure.EventID,
 		failure.AttemptNumber,
-	).Scan(&failure.ID)
+		failure.SourceID,
+		failure.MTAInstance,
+		failure.MessageID,
+		failure.EventType,
+		class,
+		subject,
+		detail,
+		failure.OrgDomain(),
+		contentHash,
+		failure.TLSPolicy,
+		failure.Family(),
+		pq.Array(failure.BlockRefs()),
+	}
+	if failureClassifier != nil {
+		query = "WITH stored AS (" + query + ")" + countFailureQuery
+		args = append(args, failureClassifier(*failure), failure.Timestamp.UTC().Truncate(FailureCounterBucket))
+	}
 
-	// If ON CONFLICT triggered, no rows returned - this is OK (duplicate event)
-	if err != nil {
-		// Check if it's a "no rows" error (duplicate), which is expected
-		if err.Error() == "sql: no rows in result set" {
-			return nil // Duplicate event, silently ignore
-		}
-		return fmt.Errorf("failed to insert SMTP failure: %w", err)
+	err := DB.QueryRow(query, args...).Scan(&failure.ID)
+	if err == nil {
+		return FailureStored, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to insert SMTP failure: %w", err)
 	}
 
-	return nil
+	// A conflict returns no row; tell an event replay from a content duplicate
+	var eventExists bool
+	if err := DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM smtp_failures WHERE event_id = $1)`, failure.EventID).Scan(&eventExists); err != nil {
+		return "", fmt.Errorf("failed to check duplicate SMTP failure: %w", err)
+	}
+	if eventExists {
+		return FailureDuplicateEvent, nil
+	}
+	return FailureDuplicateContent, nil
 }
 
 // GetSMTPFailuresByIP retrieves SMTP failures for a specific IP within a time window
 func GetSMTPFailuresByIP(ip string, since time.Time) ([]SMTPFailure, error) {
-	query := `
-		SELECT id, sending_ip, recipient_email, recipient_domain, smtp_code,
-		       enhanced_code, reason, mx_server, timestamp, event_id, attempt_number
+	return GetSMTPFailuresByIPAndInstance(ip, "", since)
+}
+
+// GetSMTPFailuresByIPAndInstance retrieves SMTP failures for an IP reported by
+// one MTA instance. An empty instance matches all instances.
+func GetSMTPFailuresByIPAndInstance(ip, instance string, since time.Time) ([]SMTPFailure, error) {
+	return GetSMTPFailuresByIPAndInstanceContext(context.Background(), ip, instance, since)
+}
+
+// smtpFailureColumns are the columns scanSMTPFailures reads
+const smtpFailureColumns = `id, sending_ip, recipient_email, recipient_domain, smtp_code,
+		       enhanced_code, reason, mx_server, timestamp, event_id, attempt_number,
+		       source_id, COALESCE(mta_instance, ''), COALESCE(message_id, ''),
+		       COALESCE(event_type, 'smtp.delivery.failure'), COALESCE(organizational_domain, ''),
+		       COALESCE(tls_policy, ''), COALESCE(reason_family, ''), block_references,
+		       retry_delivered_at`
+
+// GetSMTPFailuresByIPAndInstanceContext is GetSMTPFailuresByIPAndInstance,
+// cancelled with ctx
+func GetSMTPFailuresByIPAndInstanceContext(ctx context.Context, ip, instance string, since time.Time) ([]SMTPFailure, error) {
+	query, args := smtpFailuresByIPQuery(ip, instance, since)
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SMTP failures: %w", err)
+	}
+	return scanSMTPFailures(rows)
+}
+
+// smtpFailuresByIPQuery is the query of GetSMTPFailuresByIPAndInstance. The
+// instance condition is left out rather than made optional in SQL, so that
+// the planner serves each form from its own index.
+func smtpFailuresByIPQuery(ip, instance string, since time.Time) (string, []interface{}) {
+	if instance == "" {
+		return `
+		SELECT ` + smtpFailureColumns + `
 		FROM smtp_failures
 		WHERE sending_ip = $1 AND timestamp >= $2
 		ORDER BY timestamp DESC
+	`, []interface{}{ip, since}
+	}
+	return `
+		SELECT ` + smtpFailureColumns + `
+		FROM smtp_failures
+		WHERE sending_ip = $1 AND timestamp >= $2 AND mta_instance = $3
+		ORDER BY timestamp DESC
+	`, []interface{}{ip, since, instance}
+}
+
+// GetSMTPFailuresByIPBetween retrieves the SMTP failures of an IP from since
+// up to and including until, newest first like GetSMTPFailuresByIP
+func GetSMTPFailuresByIPBetween(ctx context.Context, ip string, since, until time.Time) ([]SMTPFailure, error) {
+	query := `
+		SELECT ` + smtpFailureColumns + `
+		FROM smtp_failures
+		WHERE sending_ip = $1 AND timestamp >= $2 AND timestamp <= $3
+		ORDER BY timestamp DESC
 	`
 
-	rows, err := DB.Query(query, ip, since)
+	rows, err := DB.QueryContext(ctx, query, ip, since, until)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query SMTP failures: %w", err)
 	}
+	return scanSMTPFailures(rows)
+}
+
+// EachSMTPFailureByIPAndInstance calls fn with the failures of
+// GetSMTPFailuresByIPAndInstanceContext one row at a time, without holding
+// them in memory. It stops at the first error fn returns.
+func EachSMTPFailureByIPAndInstance(ctx context.Context, ip, instance string, since time.Time, fn func(SMTPFailure) error) error {
+	query, args := smtpFailuresByIPQuery(ip, instance, since)
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query SMTP failures: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f, err := scanSMTPFailure(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanSMTPFailures(rows *sql.Rows) ([]SMTPFailure, error) {
 	defer rows.Close()
 
 	var failures []SMTPFailure
 	for rows.Next() {
-		var f SMTPFailure
-		err := rows.Scan(
-			&f.ID, &f.SendingIP, &f.RecipientEmail, &f.RecipientDomain,
-			&f.SMTPCode, &f.EnhancedCode, &f.Reason, &f.MXServer,
-			&f.Timestamp, &f.EventID, &f.AttemptNumber,
-		)
+		f, err := scanSMTPFailure(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan SMTP failure: %w", err)
+			return nil, err
 		}
 		failures = append(failures, f)
 	}
@@ -134,6 +365,91 @@ func GetSMTPFailuresByIP(ip string, since time.Time) ([]SMTPFailure, error) {
 	return failures, rows.Err()
 }
 
+func scanSMTPFailure(rows *sql.Rows) (SMTPFailure, error) {
+	var f SMTPFailure
+	err := rows.Scan(
+		&f.ID, &f.SendingIP, &f.RecipientEmail, &f.RecipientDomain,
+		&f.SMTPCode, &f.EnhancedCode, &f.Reason, &f.MXServer,
+		&f.Timestamp, &f.EventID, &f.AttemptNumber, &f.SourceID, &f.MTAInstance, &f.MessageID,
+		&f.EventType, &f.OrganizationalDomain, &f.TLSPolicy, &f.ReasonFamily, pq.Array(&f.BlockReferences),
+		&f.RetryDeliveredAt,
+	)
+	if err != nil {
+		return f, fmt.Errorf("failed to scan SMTP failure: %w", err)
+	}
+	// Rows stored before the column existed
+	f.OrganizationalDomain = f.OrgDomain()
+	f.ReasonFamily = f.Family()
+	f.BlockReferences = f.BlockRefs()
+	return f, nil
+}
+
+// GetInstanceRejections breaks an IP's failures down by MTA instance.
+// Failures without an instance are grouped under an empty name.
+func GetInstanceRejections(ip string, since time.Time) ([]InstanceRejections, error) {
+	query := `
+		SELECT COALESCE(mta_instance, ''), COUNT(*), COUNT(DISTINCT COALESCE(organizational_domain, recipient_domain)), MAX(timestamp)
+		FROM smtp_failures
+		WHERE sending_ip = $1 AND timestamp >= $2
+		GROUP BY COALESCE(mta_instance, '')
+		ORDER BY COUNT(*) DESC
+	`
+
+	rows, err := DB.Query(query, ip, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query instance rejections: %w", err)
+	}
+	defer rows.Close()
+
+	instances := []InstanceRejections{}
+	for rows.Next() {
+		var ir InstanceRejections
+		if err := rows.Scan(&ir.Instance, &ir.TotalRejected, &ir.UniqueDomains, &ir.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan instance rejections: %w", err)
+		}
+		instances = append(instances, ir)
+	}
+
+	return instances, rows.Err()
+}
+
+// GetLiveRejectionCounts counts an IP's failures in each of the trailing
+// windows ending at now. Each window is a range scan of the
+// (sending_ip, timestamp) index, so it stays cheap enough to poll.
+func GetLiveRejectionCounts(ip string, minutes []int, now time.Time) ([]LiveRejectionCount, error) {
+	return GetLiveRejectionCountsContext(context.Background(), ip, minutes, now)
+}
+
+// GetLiveRejectionCountsContext is GetLiveRejectionCounts, cancelled with ctx
+func GetLiveRejectionCountsContext(ctx context.Context, ip string, minutes []int, now time.Time) ([]LiveRejectionCount, error) {
+	query := `
+		SELECT w.minutes, COUNT(f.id), COUNT(f.id) FILTER (WHERE f.smtp_code >= 500),
+		       COUNT(DISTINCT COALESCE(f.organizational_domain, f.recipient_domain)), MAX(f.timestamp)
+		FROM unnest($2::int[]) AS w(minutes)
+		LEFT JOIN smtp_failures f
+		  ON f.sending_ip = $1 AND f.timestamp > $3::timestamptz - w.minutes * INTERVAL '1 minute' AND f.timestamp <= $3
+		GROUP BY w.minutes
+		ORDER BY w.minutes
+	`
+
+	rows, err := DB.QueryContext(ctx, query, ip, pq.Array(minutes), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query live rejection counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []LiveRejectionCount{}
+	for rows.Next() {
+		var c LiveRejectionCount
+		if err := rows.Scan(&c.Minutes, &c.Rejections, &c.HardRejections, &c.UniqueDomains, &c.LastFailureAt); err != nil {
+			return nil, fmt.Errorf("failed to scan live rejection count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
 // UpsertIPReputationMetrics inserts or updates IP reputation metrics
 func UpsertIPReputationMetrics(metrics *IPReputationMetrics) error {
 	// Marshal JSON fields
@@ -480,15 +796,20 @@ func GetIPActions(ip string, limit int) ([]IPAction, error) {
 	return actions, rows.Err()
 }
 
-// GetIPsNeedingAggregation returns IPs that have recent failures but need metrics update
-func GetIPsNeedingAggregation(since time.Time) ([]string, error) {
-	query := `
+// ipsNeedingAggregationQuery finds the IPs of GetIPsNeedingAggregation in
+// idx_smtp_failures_timestamp_ip alone
+const ipsNeedingAggregationQuery = `
 		SELECT DISTINCT sending_ip
 		FROM smtp_failures
 		WHERE timestamp >= $1
+		  AND sending_ip NOT IN (SELECT ip FROM archived_ips)
+		  AND NOT EXISTS (SELECT 1 FROM aggregation_exclusions x WHERE sending_ip::inet <<= x.cidr)
 	`
 
-	rows, err := DB.Query(query, since)
+// GetIPsNeedingAggregation returns IPs that have recent failures but need
+// metrics update; archived and excluded IPs are skipped
+func GetIPsNeedingAggregation(since time.Time) ([]string, error) {
+	rows, err := DB.Query(ipsNeedingAggregationQuery, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query IPs needing aggregation: %w", err)
 	}
@@ -506,6 +827,33 @@ func GetIPsNeedingAggregation(since time.Time) ([]string, error) {
 	return ips, rows.Err()
 }
 
+// GetIPsWithFailuresBetween returns the IPs with failures from since up to
+// and including until, in order; archived and excluded IPs are skipped
+func GetIPsWithFailuresBetween(since, until time.Time) ([]string, error) {
+	rows, err := DB.Query(`
+		SELECT DISTINCT sending_ip
+		FROM smtp_failures
+		WHERE timestamp >= $1 AND timestamp <= $2
+		  AND sending_ip NOT IN (SELECT ip FROM archived_ips)
+		  AND NOT EXISTS (SELECT 1 FROM aggregation_exclusions x WHERE sending_ip::inet <<= x.cidr)
+		ORDER BY sending_ip
+	`, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IPs with failures: %w", err)
+	}
+	defer rows.Close()
+
+	ips := []string{}
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("failed to scan IP: %w", err)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, rows.Err()
+}
+
 // CleanOldSMTPFailures deletes SMTP failures older than specified duration
 func CleanOldSMTPFailures(olderThan time.Time) (int64, error) {
 	query := `DELETE FROM smtp_failures WHERE timestamp < $1`
@@ -522,18 +870,45 @@ func CleanOldSMTPFailures(olderThan time.Time) (int64, error) {
 	return rowsAffected, nil
 }
 
-// ExtractDomain extracts the domain from an email address
+// foldSubdomains makes ExtractDomain return registrable domains
+var foldSubdomains bool
+
+// SetSubdomainFolding folds recipient domains to their registrable domain
+// (mail.example.co.uk to example.co.uk) at ingestion when enabled
+func SetSubdomainFolding(enabled bool) {
+	foldSubdomains = enabled
+}
+
+// ExtractDomain extracts the normalized domain from an email address.
+// Domains that are not valid IDNs are only lower-cased.
 func ExtractDomain(email string) string {
+	domain := email
 	for i := len(email) - 1; i >= 0; i-- {
 		if email[i] == '@' {
-			return email[i+1:]
+			domain = email[i+1:]
+			break
 		}
 	}
-	return email
+
+	normalized, err := validation.NormalizeDomain(domain)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(domain))
+	}
+	if foldSubdomains {
+		return validation.RegistrableDomain(normalized)
+	}
+	return normalized
 }
 
-// IsMajorProvider checks if a domain is a major email provider
+// IsMajorProvider checks if a domain, or the registrable domain it belongs
+// to, is a major email provider
 func IsMajorProvider(domain string) bool {
+	normalized, err := validation.NormalizeDomain(domain)
+	if err != nil {
+		return false
+	}
+	registrable := validation.RegistrableDomain(normalized)
+
 	majorProviders := []string{
 		"gmail.com",
 		"googlemail.com",
@@ -550,7 +925,7 @@ func IsMajorProvider(domain string) bool {
 	}
 
 	for _, provider := range majorProviders {
-		if domain == provider {
+		if normalized == provider || registrable == provider {
 			return true
 		}
 	}
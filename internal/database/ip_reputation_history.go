@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// HeatmapBucket is the rejection ratio of one IP over one hour, averaged
+// over the aggregation runs in that hour
+type HeatmapBucket struct {
+	IP    string
+	Hour  time.Time
+	Ratio float64
+}
+
+// IPHistoryRecord is the outcome of one aggregation run for an IP
+type IPHistoryRecord struct {
+	Status         string    `json:"status"`
+	TotalSent      int       `json:"total_sent"`
+	TotalRejected  int       `json:"total_rejected"`
+	RejectionRatio float64   `json:"rejection_ratio"`
+	RecordedAt     time.Time `json:"recorded_at"`
+}
+
+// InsertIPReputationHistory records the outcome of an aggregation run
+func InsertIPReputationHistory(metrics *IPReputationMetrics) error {
+	_, err := DB.Exec(`
+		INSERT INTO ip_reputation_history (ip, status, total_sent, total_rejected, rejection_ratio, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, metrics.IP, metrics.Status, metrics.TotalSent, metrics.TotalRejected, metrics.RejectionRatio, metrics.LastUpdated)
+	if err != nil {
+		return fmt.Errorf("failed to insert IP reputation history: %w", err)
+	}
+	return nil
+}
+
+// ReplaceIPReputationHistory replaces the history of an IP recorded from
+// since up to and including until with the given records, in one transaction
+func ReplaceIPReputationHistory(ip string, since, until time.Time, history []*IPReputationMetrics) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM ip_reputation_history WHERE ip = $1 AND recorded_at >= $2 AND recorded_at <= $3
+	`, ip, since, until); err != nil {
+		return fmt.Errorf("failed to delete IP reputation history: %w", err)
+	}
+	for _, metrics := range history {
+		if _, err := tx.Exec(`
+			INSERT INTO ip_reputation_history (ip, status, total_sent, total_rejected, rejection_ratio, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, ip, metrics.Status, metrics.TotalSent, metrics.TotalRejected, metrics.RejectionRatio, metrics.LastUpdated); err != nil {
+			return fmt.Errorf("failed to insert IP reputation history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit IP reputation history: %w", err)
+	}
+	return nil
+}
+
+// EachIPHistoryRecord calls fn with the history of an IP recorded since a
+// time, newest first, one row at a time without holding them in memory. It
+// stops at the first error fn returns.
+func EachIPHistoryRecord(ctx context.Context, ip string, since time.Time, fn func(IPHistoryRecord) error) error {
+	rows, err := DB.QueryContext(ctx, `
+		SELECT status, COALESCE(total_sent, 0), COALESCE(total_rejected, 0), COALESCE(rejection_ratio, 0)::float8, recorded_at
+		FROM ip_reputation_history
+		WHERE ip = $1 AND recorded_at >= $2
+		ORDER BY recorded_at DESC
+	`, ip, since)
+	if err != nil {
+		return fmt.Errorf("failed to query IP reputation history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record IPHistoryRecord
+		if err := rows.Scan(&record.Status, &record.TotalSent, &record.TotalRejected, &record.RejectionRatio, &record.RecordedAt); err != nil {
+			return fmt.Errorf("failed to scan IP reputation history: %w", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetIPStatusBefore returns the last status recorded for an IP before a
+// time; ok is false when none was
+func GetIPStatusBefore(ip string, before time.Time) (string, bool, error) {
+	var status string
+	err := DB.QueryRow(`
+		SELECT status FROM ip_reputation_history
+		WHERE ip = $1 AND recorded_at < $2
+		ORDER BY recorded_at DESC
+		LIMIT 1
+	`, ip, before).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to query IP status: %w", err)
+	}
+	return status, true, nil
+}
+
+// GetIPFirstSeen returns when an IP's first aggregation run was recorded;
+// ok is false when it has none
+func GetIPFirstSeen(ip string) (time.Time, bool, error) {
+	var first sql.NullTime
+	if err := DB.QueryRow(`SELECT MIN(recorded_at) FROM ip_reputation_history WHERE ip = $1`, ip).Scan(&first); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to query IP first seen: %w", err)
+	}
+	return first.Time, first.Valid, nil
+}
+
+// GetRejectionHeatmap buckets the history since a time by IP and UTC hour
+func GetRejectionHeatmap(since time.Time) ([]HeatmapBucket, error) {
+	rows, err := DB.Query(`
+		SELECT ip, date_trunc('hour', recorded_at AT TIME ZONE 'UTC') AS hour,
+		       AVG(rejection_ratio)::float8
+		FROM ip_reputation_history
+		WHERE recorded_at >= $1
+		GROUP BY ip, hour
+		ORDER BY ip, hour
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejection heatmap: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []HeatmapBucket{}
+	for rows.Next() {
+		var b HeatmapBucket
+		if err := rows.Scan(&b.IP, &b.Hour, &b.Ratio); err != nil {
+			return nil, fmt.Errorf("failed to scan heatmap bucket: %w", err)
+		}
+		b.Hour = time.Date(b.Hour.Year(), b.Hour.Month(), b.Hour.Day(), b.Hour.Hour(), 0, 0, 0, time.UTC)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// PoolHealthDay is how many members of a pool ended a UTC day healthy, out of
+// the members that had an aggregation run that day
+type PoolHealthDay struct {
+	PoolID  int
+	Day     time.Time
+	Healthy int
+	Tracked int
+}
+
+// GetDailyPoolHealth counts healthy pool members per day since a time, using
+// each IP's last recorded status of the day
+func GetDailyPoolHealth(since time.Time) ([]PoolHealthDay, error) {
+	rows, err := DB.Query(`
+		WITH daily AS (
+			SELECT DISTINCT ON (ip, date_trunc('day', recorded_at AT TIME ZONE 'UTC'))
+			       ip, date_trunc('day', recorded_at AT TIME ZONE 'UTC') AS day, status
+			FROM ip_reputation_history
+			WHERE recorded_at >= $1
+			ORDER BY ip, date_trunc('day', recorded_at AT TIME ZONE 'UTC'), recorded_at DESC
+		)
+		SELECT m.pool_id, d.day, COUNT(*) FILTER (WHERE d.status = 'healthy'), COUNT(*)
+		FROM daily d
+		JOIN ip_pool_members m ON m.ip_address = d.ip
+		GROUP BY m.pool_id, d.day
+		ORDER BY m.pool_id, d.day
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily pool health: %w", err)
+	}
+	defer rows.Close()
+
+	days := []PoolHealthDay{}
+	for rows.Next() {
+		var d PoolHealthDay
+		if err := rows.Scan(&d.PoolID, &d.Day, &d.Healthy, &d.Tracked); err != nil {
+			return nil, fmt.Errorf("failed to scan pool health: %w", err)
+		}
+		d.Day = time.Date(d.Day.Year(), d.Day.Month(), d.Day.Day(), 0, 0, 0, 0, time.UTC)
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// CountIPsEnteringStatus counts the distinct IPs that changed into one of the
+// statuses since a time
+func CountIPsEnteringStatus(statuses []string, since time.Time) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(DISTINCT ip)
+		FROM ip_actions
+		WHERE action = 'status_change' AND new_status = ANY($1) AND created_at >= $2
+	`, pq.Array(statuses), since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count status changes: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzExtractDomain tests that any recipient yields a trimmed domain without
+// an @ that extracts to itself
+func FuzzExtractDomain(f *testing.F) {
+	for _, seed := range []string{"user@Example.COM", "user@mail.example.co.uk", "a@b@c.org", "no-at-sign", "user@", "@", " user@bücher.de ", "user@xn--bcher-kva.de", "user@[192.0.2.1]", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, email string) {
+		domain := ExtractDomain(email)
+		if strings.Contains(domain, "@") {
+			t.Errorf("ExtractDomain(%q) = %q, which has an @", email, domain)
+		}
+		if domain != strings.TrimSpace(domain) {
+			t.Errorf("ExtractDomain(%q) = %q, not trimmed", email, domain)
+		}
+		if again := ExtractDomain(domain); again != domain {
+			t.Errorf("ExtractDomain(%q) = %q, but ExtractDomain(%q) = %q", email, domain, domain, again)
+		}
+	})
+}
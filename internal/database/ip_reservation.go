@@ -3,12 +3,20 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+// ErrReservedIPNotFound is returned when no reserved IP has the ID
+var ErrReservedIPNotFound = errors.New("reserved IP not found")
+
+// ErrReservedIPUnavailable is returned when a reserved IP can no longer be
+// swapped in
+var ErrReservedIPUnavailable = errors.New("reserved IP is no longer available")
+
 // ReservedIP represents a reserved IP in the database
 type ReservedIP struct {
 	ID                  int       `json:"id"`
@@ -152,7 +160,7 @@ func GetReservedIPByID(id int) (*ReservedIP, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("reserved IP not found")
+		return nil, ErrReservedIPNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reserved IP: %w", err)
@@ -205,7 +213,7 @@ func GetReservedIPByAddress(ipAddress string) (*ReservedIP, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("reserved IP not found")
+		return nil, ErrReservedIPNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reserved IP: %w", err)
@@ -261,6 +269,32 @@ func ListReservedIPs(status *string, isBlacklisted *bool, location *string) ([]R
 	}
 	defer rows.Close()
 
+	return scanReservedIPs(rows)
+}
+
+// ListReservedIPsByBlock retrieves the member IPs of one IONOS block
+func ListReservedIPsByBlock(blockID string) ([]ReservedIP, error) {
+	query := `
+		SELECT id, ip_address, reservation_block_id, uid, location, status, 
+		       is_blacklisted, blacklist_details, reserved_at, last_checked_at, 
+		       released_at, assigned_to, usage_count, metadata, notes, 
+		       created_at, updated_at
+		FROM reserved_ips
+		WHERE reservation_block_id = $1
+		ORDER BY ip_address
+	`
+
+	rows, err := DB.Query(query, blockID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query block members: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReservedIPs(rows)
+}
+
+// scanReservedIPs reads reserved IP rows selected with the full column list
+func scanReservedIPs(rows *sql.Rows) ([]ReservedIP, error) {
 	var ips []ReservedIP
 	for rows.Next() {
 		var ip ReservedIP
@@ -381,6 +415,218 @@ func DeleteReservedIP(id int) error {
 	return nil
 }
 
+// UpdateReservedIPAttachment records the server NIC an IP is bound to under
+// metadata.attachment and marks the IP in use
+func UpdateReservedIPAttachment(id int, attachment map[string]interface{}, assignedTo string) error {
+	attachmentJSON, err := json.Marshal(attachment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attachment: %w", err)
+	}
+
+	query := `
+		UPDATE reserved_ips
+		SET status = 'in_use', assigned_to = $1, usage_count = usage_count + 1,
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('attachment', $2::jsonb),
+		    updated_at = NOW()
+		WHERE id = $3
+	`
+
+	result, err := DB.Exec(query, assignedTo, attachmentJSON, id)
+	if err != nil {
+		return fmt.Errorf("failed to update reserved IP attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("reserved IP not found")
+	}
+
+	return nil
+}
+
+// ReleaseReservedIP marks an IP as released once its IONOS block is gone
+func ReleaseReservedIP(id int) error {
+	query := `
+		UPDATE reserved_ips
+		SET status = 'released', released_at = NOW(), assigned_to = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := DB.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to release reserved IP: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("reserved IP not found")
+	}
+
+	return nil
+}
+
+// FlagReservedIPStale records in the metadata when an idle IP was flagged
+// by the stale reservation policy
+func FlagReservedIPStale(id int, flaggedAt time.Time) error {
+	query := `
+		UPDATE reserved_ips
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('stale_flagged_at', $1::text),
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+
+	result, err := DB.Exec(query, flaggedAt.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("failed to flag reserved IP stale: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrReservedIPNotFound
+	}
+
+	return nil
+}
+
+// QuarantineReservedIP marks an IP quarantined because of its reputation,
+// keeping the status it had under metadata.reputation_previous_status so
+// it can be restored when the IP recovers
+func QuarantineReservedIP(id int, previousStatus string) error {
+	query := `
+		UPDATE reserved_ips
+		SET status = 'quarantined',
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('reputation_previous_status', $1::text),
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+
+	result, err := DB.Exec(query, previousStatus, id)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine reserved IP: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrReservedIPNotFound
+	}
+
+	return nil
+}
+
+// RestoreReservedIPStatus sets an IP quarantined by QuarantineReservedIP
+// back to status and drops the remembered previous status
+func RestoreReservedIPStatus(id int, status string) error {
+	query := `
+		UPDATE reserved_ips
+		SET status = $1, metadata = COALESCE(metadata, '{}'::jsonb) - 'reputation_previous_status',
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+
+	result, err := DB.Exec(query, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore reserved IP status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrReservedIPNotFound
+	}
+
+	return nil
+}
+
+// SwapReservedIPs puts the clean reserved IP replacementID in use in place
+// of replacedID, for the same assignment and keeping the replaced address
+// under metadata.swapped_for, and quarantines replacedID to go back to the
+// reserve. Both changes and the actions recording them are made in one
+// transaction. It returns ErrReservedIPUnavailable when the replacement is
+// no longer reserved or has been blacklisted since it was picked, e.g. by a
+// concurrent swap.
+func SwapReservedIPs(replacedID, replacementID int, assignedTo *string, swappedFor string, actions []*IPAction) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE reserved_ips
+		SET status = 'in_use', assigned_to = $1, usage_count = usage_count + 1,
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('swapped_for', $2::text),
+		    updated_at = NOW()
+		WHERE id = $3 AND status = 'reserved' AND NOT is_blacklisted
+	`, assignedTo, swappedFor, replacementID)
+	if err != nil {
+		return fmt.Errorf("failed to claim reserved IP: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrReservedIPUnavailable
+	}
+
+	result, err = tx.Exec(`
+		UPDATE reserved_ips
+		SET status = 'quarantined',
+		    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('reputation_previous_status', 'reserved'),
+		    updated_at = NOW()
+		WHERE id = $1
+	`, replacedID)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine reserved IP: %w", err)
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrReservedIPNotFound
+	}
+
+	for _, action := range actions {
+		metadataJSON, err := json.Marshal(action.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		err = tx.QueryRow(`
+			INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by, metadata, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, action.IP, action.Action, action.PreviousStatus, action.NewStatus, action.Reason, action.TriggeredBy, metadataJSON, action.CreatedAt).Scan(&action.ID)
+		if err != nil {
+			return fmt.Errorf("failed to record IP swap: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit IP swap: %w", err)
+	}
+	return nil
+}
+
 // CreateReservationAttempt records an IP reservation attempt
 func CreateReservationAttempt(attempt *ReservationAttempt) error {
 	blacklistJSON, err := json.Marshal(attempt.BlacklistsFound)
@@ -502,6 +748,62 @@ func CreateQuotaSnapshot(snapshot *QuotaSnapshot) error {
 	return nil
 }
 
+// LocationReservationStats summarizes the reservation attempts made in one
+// IONOS location over a window
+type LocationReservationStats struct {
+	Location    string `json:"location"`
+	Attempts    int    `json:"attempts"`
+	CleanIPs    int    `json:"clean_ips"`
+	Blacklisted int    `json:"blacklisted"`
+	// Checked counts attempts that got an IP to check
+	Checked int `json:"checked"`
+	// BlacklistHitRate is the share of checked IPs that were listed
+	BlacklistHitRate float64 `json:"blacklist_hit_rate"`
+	// AttemptsPerCleanIP is nil when no clean IP was kept
+	AttemptsPerCleanIP *float64 `json:"attempts_per_clean_ip"`
+	AvgLatencyMs       float64  `json:"avg_latency_ms"`
+}
+
+// GetLocationReservationStats aggregates the reservation attempts made since
+// the given time per location
+func GetLocationReservationStats(since time.Time) ([]LocationReservationStats, error) {
+	query := `
+		SELECT COALESCE(location, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE success),
+		       COUNT(*) FILTER (WHERE was_blacklisted),
+		       COUNT(*) FILTER (WHERE ip_address IS NOT NULL),
+		       COALESCE(AVG(duration_ms), 0)
+		FROM ip_reservation_attempts
+		WHERE attempted_at >= $1
+		GROUP BY COALESCE(location, '')
+		ORDER BY 1
+	`
+
+	rows, err := DB.Query(query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location reservation stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []LocationReservationStats{}
+	for rows.Next() {
+		var l LocationReservationStats
+		if err := rows.Scan(&l.Location, &l.Attempts, &l.CleanIPs, &l.Blacklisted, &l.Checked, &l.AvgLatencyMs); err != nil {
+			return nil, fmt.Errorf("failed to scan location reservation stats: %w", err)
+		}
+		if l.Checked > 0 {
+			l.BlacklistHitRate = float64(l.Blacklisted) / float64(l.Checked)
+		}
+		if l.CleanIPs > 0 {
+			perClean := float64(l.Attempts) / float64(l.CleanIPs)
+			l.AttemptsPerCleanIP = &perClean
+		}
+		stats = append(stats, l)
+	}
+	return stats, rows.Err()
+}
+
 // GetReservationStatistics returns statistics about IP reservations
 func GetReservationStatistics() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
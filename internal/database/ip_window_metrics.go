@@ -0,0 +1,96 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IPWindowMetrics are the metrics of an IP over one evaluation window. Status
+// is what the window supports on its own, before it is combined with the
+// other windows.
+type IPWindowMetrics struct {
+	IP                    string    `json:"ip"`
+	WindowName            string    `json:"window_name"`
+	WindowMinutes         int       `json:"window_minutes"`
+	WindowStart           time.Time `json:"window_start"`
+	WindowEnd             time.Time `json:"window_end"`
+	TotalSent             int       `json:"total_sent"`
+	TotalRejected         int       `json:"total_rejected"`
+	RejectionRatio        float64   `json:"rejection_ratio"`
+	UniqueDomainsRejected int       `json:"unique_domains_rejected"`
+	Status                string    `json:"status"`
+	LastUpdated           time.Time `json:"last_updated"`
+}
+
+// UpsertIPWindowMetrics stores the metrics of one IP and window
+func UpsertIPWindowMetrics(metrics *IPWindowMetrics) error {
+	query := `
+		INSERT INTO ip_reputation_windows (
+			ip, window_name, window_minutes, window_start, window_end, total_sent,
+			total_rejected, rejection_ratio, unique_domains_rejected, status, last_updated
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (ip, window_name) DO UPDATE SET
+			window_minutes = EXCLUDED.window_minutes,
+			window_start = EXCLUDED.window_start,
+			window_end = EXCLUDED.window_end,
+			total_sent = EXCLUDED.total_sent,
+			total_rejected = EXCLUDED.total_rejected,
+			rejection_ratio = EXCLUDED.rejection_ratio,
+			unique_domains_rejected = EXCLUDED.unique_domains_rejected,
+			status = EXCLUDED.status,
+			last_updated = EXCLUDED.last_updated
+	`
+
+	_, err := DB.Exec(query,
+		metrics.IP, metrics.WindowName, metrics.WindowMinutes, metrics.WindowStart, metrics.WindowEnd,
+		metrics.TotalSent, metrics.TotalRejected, metrics.RejectionRatio, metrics.UniqueDomainsRejected,
+		metrics.Status, metrics.LastUpdated,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert IP window metrics: %w", err)
+	}
+	return nil
+}
+
+// GetIPWindowMetrics returns the stored window metrics of an IP, shortest
+// window first
+func GetIPWindowMetrics(ip string) ([]IPWindowMetrics, error) {
+	query := `
+		SELECT ip, window_name, window_minutes, window_start, window_end, total_sent,
+		       total_rejected, rejection_ratio, unique_domains_rejected, status, last_updated
+		FROM ip_reputation_windows
+		WHERE ip = $1
+		ORDER BY window_minutes, window_name
+	`
+
+	rows, err := DB.Query(query, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP window metrics: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []IPWindowMetrics{}
+	for rows.Next() {
+		var m IPWindowMetrics
+		if err := rows.Scan(
+			&m.IP, &m.WindowName, &m.WindowMinutes, &m.WindowStart, &m.WindowEnd, &m.TotalSent,
+			&m.TotalRejected, &m.RejectionRatio, &m.UniqueDomainsRejected, &m.Status, &m.LastUpdated,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan IP window metrics: %w", err)
+		}
+		windows = append(windows, m)
+	}
+	return windows, rows.Err()
+}
+
+// DeleteStaleIPWindowMetrics removes the windows of an IP that are no longer
+// configured
+func DeleteStaleIPWindowMetrics(ip string, keep []string) error {
+	_, err := DB.Exec(`DELETE FROM ip_reputation_windows WHERE ip = $1 AND NOT (window_name = ANY($2))`, ip, pq.Array(keep))
+	if err != nil {
+		return fmt.Errorf("failed to delete stale IP window metrics: %w", err)
+	}
+	return nil
+}
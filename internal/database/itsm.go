@@ -0,0 +1,306 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrITSMTicketNotFound is returned for an unknown ticket
+var ErrITSMTicketNotFound = errors.New("ITSM ticket not found")
+
+// Actions recording ITSM tickets on an IP
+const (
+	ITSMTicketCreatedAction = "itsm_ticket_created"
+	ITSMTicketUpdatedAction = "itsm_ticket_updated"
+)
+
+// ITSMRule opens a ticket in a Jira project or ServiceNow assignment group
+// when an event happens to an IP. A nil PoolID matches any IP.
+type ITSMRule struct {
+	ID        int       `json:"id"`
+	Event     string    `json:"event"`
+	System    string    `json:"system"`
+	Project   string    `json:"project"`
+	PoolID    *int      `json:"pool_id,omitempty"`
+	PoolName  string    `json:"pool_name,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ITSMTicket is a ticket a rule opened, with the status last reported by its
+// system
+type ITSMTicket struct {
+	ID     int    `json:"id"`
+	RuleID *int   `json:"rule_id,omitempty"`
+	System string `json:"system"`
+	// Key is the Jira issue key or ServiceNow number shown to people;
+	// ExternalID the issue ID or sys_id the API addresses it by
+	Key             string    `json:"key"`
+	ExternalID      string    `json:"external_id"`
+	URL             string    `json:"url"`
+	IP              string    `json:"ip"`
+	Event           string    `json:"event"`
+	DelistingTaskID *int      `json:"delisting_task_id,omitempty"`
+	Status          string    `json:"status"`
+	Resolved        bool      `json:"resolved"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CreateITSMRule stores a new rule
+func CreateITSMRule(rule *ITSMRule) (*ITSMRule, error) {
+	var id int
+	err := DB.QueryRow(`
+		INSERT INTO itsm_rules (event, system, project, pool_id, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, rule.Event, rule.System, rule.Project, nullableID(rule.PoolID), rule.Enabled, rule.CreatedBy).Scan(&id)
+	if err != nil {
+		return nil, ownershipError(err, "create ITSM rule")
+	}
+	return GetITSMRule(id)
+}
+
+// UpdateITSMRule replaces a rule, returning nil if it does not exist
+func UpdateITSMRule(rule *ITSMRule) (*ITSMRule, error) {
+	result, err := DB.Exec(`
+		UPDATE itsm_rules
+		SET event = $2, system = $3, project = $4, pool_id = $5, enabled = $6, updated_at = NOW()
+		WHERE id = $1
+	`, rule.ID, rule.Event, rule.System, rule.Project, nullableID(rule.PoolID), rule.Enabled)
+	if err != nil {
+		return nil, ownershipError(err, "update ITSM rule")
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, err
+	}
+	return GetITSMRule(rule.ID)
+}
+
+// DeleteITSMRule removes a rule; its tickets are kept
+func DeleteITSMRule(id int) (bool, error) {
+	return deleteByID(`DELETE FROM itsm_rules WHERE id = $1`, "delete ITSM rule", id)
+}
+
+// GetITSMRule returns a rule by ID, or nil if it does not exist
+func GetITSMRule(id int) (*ITSMRule, error) {
+	rules, err := listITSMRules(`WHERE r.id = $1`, id)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return &rules[0], nil
+}
+
+// ListITSMRules returns every rule
+func ListITSMRules() ([]ITSMRule, error) {
+	return listITSMRules("")
+}
+
+func listITSMRules(where string, args ...interface{}) ([]ITSMRule, error) {
+	rows, err := DB.Query(`
+		SELECT r.id, r.event, r.system, r.project, r.pool_id, COALESCE(p.name, ''),
+		       r.enabled, r.created_by, r.created_at, r.updated_at
+		FROM itsm_rules r
+		LEFT JOIN ip_pools p ON p.id = r.pool_id
+		`+where+`
+		ORDER BY r.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ITSM rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []ITSMRule{}
+	for rows.Next() {
+		var rule ITSMRule
+		var poolID sql.NullInt64
+		if err := rows.Scan(&rule.ID, &rule.Event, &rule.System, &rule.Project, &poolID, &rule.PoolName,
+			&rule.Enabled, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ITSM rule: %w", err)
+		}
+		if poolID.Valid {
+			id := int(poolID.Int64)
+			rule.PoolID = &id
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+const itsmTicketColumns = `id, rule_id, system, ticket_key, external_id, url, ip, event, delisting_task_id, status, resolved, created_at, updated_at`
+
+func scanITSMTicket(row interface{ Scan(...interface{}) error }) (*ITSMTicket, error) {
+	var ticket ITSMTicket
+	var ruleID, taskID sql.NullInt64
+	if err := row.Scan(&ticket.ID, &ruleID, &ticket.System, &ticket.Key, &ticket.ExternalID, &ticket.URL,
+		&ticket.IP, &ticket.Event, &taskID, &ticket.Status, &ticket.Resolved, &ticket.CreatedAt, &ticket.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if ruleID.Valid {
+		id := int(ruleID.Int64)
+		ticket.RuleID = &id
+	}
+	if taskID.Valid {
+		id := int(taskID.Int64)
+		ticket.DelistingTaskID = &id
+	}
+	return &ticket, nil
+}
+
+// recordITSMAction adds a ticket change to the IP's actions, linking the
+// ticket key and URL
+func recordITSMAction(tx *sql.Tx, ticket *ITSMTicket, action, reason, author string) error {
+	metadata := map[string]interface{}{
+		"ticket_id":  ticket.ID,
+		"system":     ticket.System,
+		"ticket_key": ticket.Key,
+		"url":        ticket.URL,
+		"event":      ticket.Event,
+		"status":     ticket.Status,
+		"resolved":   ticket.Resolved,
+	}
+	if ticket.RuleID != nil {
+		metadata["rule_id"] = *ticket.RuleID
+	}
+	if ticket.DelistingTaskID != nil {
+		metadata["delisting_task_id"] = *ticket.DelistingTaskID
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ITSM metadata: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO ip_actions (ip, action, previous_status, new_status, reason, triggered_by, metadata)
+		VALUES ($1, $2, '', '', $3, $4, $5)
+	`, ticket.IP, action, reason, author, encoded); err != nil {
+		return fmt.Errorf("failed to record ITSM ticket: %w", err)
+	}
+	return nil
+}
+
+// RecordITSMTicket stores a ticket a rule opened and records it as an action
+// on the IP, in one transaction
+func RecordITSMTicket(ticket *ITSMTicket, author string) (*ITSMTicket, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := scanITSMTicket(tx.QueryRow(`
+		INSERT INTO itsm_tickets (rule_id, system, ticket_key, external_id, url, ip, event, delisting_task_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+itsmTicketColumns,
+		nullableID(ticket.RuleID), ticket.System, ticket.Key, ticket.ExternalID, ticket.URL,
+		ticket.IP, ticket.Event, nullableID(ticket.DelistingTaskID), ticket.Status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store ITSM ticket: %w", err)
+	}
+	reason := fmt.Sprintf("Opened %s ticket %s", stored.System, stored.Key)
+	if err := recordITSMAction(tx, stored, ITSMTicketCreatedAction, reason, author); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ITSM ticket: %w", err)
+	}
+	return stored, nil
+}
+
+// HasOpenITSMTicket reports whether a rule already has an unresolved ticket
+// for an event on an IP and delisting task (nil for events without one)
+func HasOpenITSMTicket(ruleID int, ip, event string, taskID *int) (bool, error) {
+	var open bool
+	err := DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM itsm_tickets
+			WHERE rule_id = $1 AND ip = $2 AND event = $3
+			  AND delisting_task_id IS NOT DISTINCT FROM $4 AND NOT resolved
+		)
+	`, ruleID, ip, event, nullableID(taskID)).Scan(&open)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ITSM tickets: %w", err)
+	}
+	return open, nil
+}
+
+// ListITSMTickets returns the tickets of an IP, newest first
+func ListITSMTickets(ip string) ([]ITSMTicket, error) {
+	rows, err := DB.Query(`
+		SELECT `+itsmTicketColumns+`
+		FROM itsm_tickets
+		WHERE ip = $1
+		ORDER BY created_at DESC, id DESC
+	`, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ITSM tickets: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []ITSMTicket{}
+	for rows.Next() {
+		ticket, err := scanITSMTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ITSM ticket: %w", err)
+		}
+		tickets = append(tickets, *ticket)
+	}
+	return tickets, rows.Err()
+}
+
+// GetITSMTicketByKey returns the ticket a system knows by key
+func GetITSMTicketByKey(system, key string) (*ITSMTicket, error) {
+	ticket, err := scanITSMTicket(DB.QueryRow(`SELECT `+itsmTicketColumns+` FROM itsm_tickets WHERE system = $1 AND ticket_key = $2`, system, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrITSMTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ITSM ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// UpdateITSMTicketStatus stores the status a ticket's system reports and
+// records the change as an action on the IP. It reports whether anything
+// changed; reporting the stored status again changes nothing.
+func UpdateITSMTicketStatus(id int, status string, resolved bool, author string) (*ITSMTicket, bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ticket, err := scanITSMTicket(tx.QueryRow(`SELECT `+itsmTicketColumns+` FROM itsm_tickets WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrITSMTicketNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get ITSM ticket: %w", err)
+	}
+	if ticket.Status == status && ticket.Resolved == resolved {
+		return ticket, false, nil
+	}
+
+	ticket, err = scanITSMTicket(tx.QueryRow(`
+		UPDATE itsm_tickets
+		SET status = $2, resolved = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+itsmTicketColumns,
+		id, status, resolved))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update ITSM ticket: %w", err)
+	}
+	reason := fmt.Sprintf("%s ticket %s is %s", ticket.System, ticket.Key, status)
+	if err := recordITSMAction(tx, ticket, ITSMTicketUpdatedAction, reason, author); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit ITSM ticket: %w", err)
+	}
+	return ticket, true, nil
+}
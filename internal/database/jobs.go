@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job callback statuses
+const (
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// ErrJobNotFound is returned when no job has the ID
+var ErrJobNotFound = errors.New("job not found")
+
+// JobProgress is how far a running job has got, e.g. 3 of 10 IPs
+type JobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Job is a background task started through the API
+type Job struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Subject string `json:"subject,omitempty"`
+	Status  string `json:"status"`
+	// Progress is reported by jobs that work through many items
+	Progress *JobProgress `json:"progress,omitempty"`
+	// Result is the job's output once it succeeded
+	Result         json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error          string          `json:"error,omitempty"`
+	CallbackURL    string          `json:"callback_url,omitempty"`
+	CallbackStatus string          `json:"callback_status,omitempty"`
+	CreatedBy      string          `json:"created_by,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	StartedAt      *time.Time      `json:"started_at,omitempty"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty"`
+}
+
+const jobColumns = `id, type, COALESCE(subject, ''), status, progress, result, COALESCE(error, ''),
+	COALESCE(callback_url, ''), COALESCE(callback_status, ''), COALESCE(created_by, ''),
+	created_at, started_at, finished_at`
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var progress, result []byte
+	if err := row.Scan(&job.ID, &job.Type, &job.Subject, &job.Status, &progress, &result, &job.Error,
+		&job.CallbackURL, &job.CallbackStatus, &job.CreatedBy,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		job.Progress = &JobProgress{}
+		if err := json.Unmarshal(progress, job.Progress); err != nil {
+			return nil, fmt.Errorf("failed to decode job progress: %w", err)
+		}
+	}
+	if result != nil {
+		job.Result = result
+	}
+	return &job, nil
+}
+
+// CreateJob stores a new job
+func CreateJob(job *Job) error {
+	query := `
+		INSERT INTO jobs (id, type, subject, status, callback_url, created_by, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+	`
+	if _, err := DB.Exec(query, job.ID, job.Type, job.Subject, job.Status, job.CallbackURL, job.CreatedBy, job.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob stores a job's status, progress, result, error, callback status
+// and times
+func UpdateJob(job *Job) error {
+	var progress, result []byte
+	if job.Progress != nil {
+		var err error
+		if progress, err = json.Marshal(job.Progress); err != nil {
+			return fmt.Errorf("failed to encode job progress: %w", err)
+		}
+	}
+	if len(job.Result) > 0 {
+		result = job.Result
+	}
+	query := `
+		UPDATE jobs
+		SET status = $2, progress = $3, result = $4, error = NULLIF($5, ''), callback_status = NULLIF($6, ''),
+		    started_at = $7, finished_at = $8
+		WHERE id = $1
+	`
+	res, err := DB.Exec(query, job.ID, job.Status, progress, result, job.Error, job.CallbackStatus, job.StartedAt, job.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// GetJob returns a job by ID
+func GetJob(id string) (*Job, error) {
+	job, err := scanJob(DB.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// DeleteJobsFinishedBefore removes jobs that finished before the cutoff and
+// returns how many were removed
+func DeleteJobsFinishedBefore(cutoff time.Time) (int64, error) {
+	res, err := DB.Exec(`DELETE FROM jobs WHERE finished_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
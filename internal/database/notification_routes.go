@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NotificationRoute sends alerts for a pool and/or team at a severity to one
+// notifier target. Nil PoolID, TeamID and an empty Severity match any.
+type NotificationRoute struct {
+	ID        int       `json:"id"`
+	PoolID    *int      `json:"pool_id,omitempty"`
+	PoolName  string    `json:"pool_name,omitempty"`
+	TeamID    *int      `json:"team_id,omitempty"`
+	TeamName  string    `json:"team_name,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Notifier  string    `json:"notifier"`
+	Target    string    `json:"target"`
+	Enabled   bool      `json:"enabled"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func nullableID(id *int) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*id), Valid: true}
+}
+
+// CreateNotificationRoute stores a new route
+func CreateNotificationRoute(route *NotificationRoute) (*NotificationRoute, error) {
+	var id int
+	err := DB.QueryRow(`
+		INSERT INTO notification_routes (pool_id, team_id, severity, notifier, target, enabled, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)
+		RETURNING id
+	`, nullableID(route.PoolID), nullableID(route.TeamID), route.Severity, route.Notifier, route.Target, route.Enabled, route.CreatedBy).Scan(&id)
+	if err != nil {
+		return nil, ownershipError(err, "create notification route")
+	}
+	return GetNotificationRoute(id)
+}
+
+// UpdateNotificationRoute replaces a route's match and target, returning nil
+// if it does not exist
+func UpdateNotificationRoute(route *NotificationRoute) (*NotificationRoute, error) {
+	result, err := DB.Exec(`
+		UPDATE notification_routes
+		SET pool_id = $2, team_id = $3, severity = NULLIF($4, ''), notifier = $5, target = $6, enabled = $7, updated_at = NOW()
+		WHERE id = $1
+	`, route.ID, nullableID(route.PoolID), nullableID(route.TeamID), route.Severity, route.Notifier, route.Target, route.Enabled)
+	if err != nil {
+		return nil, ownershipError(err, "update notification route")
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, err
+	}
+	return GetNotificationRoute(route.ID)
+}
+
+// DeleteNotificationRoute removes a route
+func DeleteNotificationRoute(id int) (bool, error) {
+	return deleteByID(`DELETE FROM notification_routes WHERE id = $1`, "delete notification route", id)
+}
+
+// GetNotificationRoute returns a route by ID, or nil if it does not exist
+func GetNotificationRoute(id int) (*NotificationRoute, error) {
+	routes, err := listNotificationRoutes(`WHERE r.id = $1`, id)
+	if err != nil || len(routes) == 0 {
+		return nil, err
+	}
+	return &routes[0], nil
+}
+
+// ListNotificationRoutes returns every route
+func ListNotificationRoutes() ([]NotificationRoute, error) {
+	return listNotificationRoutes("")
+}
+
+func listNotificationRoutes(where string, args ...interface{}) ([]NotificationRoute, error) {
+	query := `
+		SELECT r.id, r.pool_id, COALESCE(p.name, ''), r.team_id, COALESCE(t.name, ''),
+		       COALESCE(r.severity, ''), r.notifier, r.target, r.enabled, r.created_by, r.created_at, r.updated_at
+		FROM notification_routes r
+		LEFT JOIN ip_pools p ON p.id = r.pool_id
+		LEFT JOIN teams t ON t.id = r.team_id
+		` + where + `
+		ORDER BY r.id`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []NotificationRoute{}
+	for rows.Next() {
+		var route NotificationRoute
+		var poolID, teamID sql.NullInt64
+		if err := rows.Scan(&route.ID, &poolID, &route.PoolName, &teamID, &route.TeamName,
+			&route.Severity, &route.Notifier, &route.Target, &route.Enabled, &route.CreatedBy, &route.CreatedAt, &route.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification route: %w", err)
+		}
+		if poolID.Valid {
+			id := int(poolID.Int64)
+			route.PoolID = &id
+		}
+		if teamID.Valid {
+			id := int(teamID.Int64)
+			route.TeamID = &id
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, rows.Err()
+}
@@ -0,0 +1,458 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Owner and target types for ownership assignments
+const (
+	OwnerUser  = "user"
+	OwnerTeam  = "team"
+	TargetIP   = "ip"
+	TargetPool = "pool"
+)
+
+// ErrOwnershipConflict is returned when a team, pool, member or assignment
+// already exists
+var ErrOwnershipConflict = errors.New("already exists")
+
+// ErrOwnershipReference is returned when an assignment or membership refers
+// to a user, team or pool that does not exist
+var ErrOwnershipReference = errors.New("referenced user, team or pool does not exist")
+
+// Team groups users
+type Team struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberIDs   []int     `json:"member_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IPPool is a named group of sending IPs
+type IPPool struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IPs         []string  `json:"ips"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OwnershipAssignment ties a user or team to an IP or pool
+type OwnershipAssignment struct {
+	ID         int       `json:"id"`
+	OwnerType  string    `json:"owner_type"`
+	OwnerID    int       `json:"owner_id"`
+	OwnerName  string    `json:"owner_name"`
+	TargetType string    `json:"target_type"`
+	Target     string    `json:"target"` // IP address or pool name
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IPOwner is a user or team owning an IP, directly or through a pool
+type IPOwner struct {
+	OwnerType string `json:"owner_type"`
+	OwnerID   int    `json:"owner_id"`
+	OwnerName string `json:"owner_name"`
+	// Via is the pool name when ownership comes through a pool
+	Via string `json:"via,omitempty"`
+}
+
+// AssignmentFilter narrows ListOwnershipAssignments. Zero values match all.
+type AssignmentFilter struct {
+	OwnerType string
+	OwnerID   int
+	IP        string
+}
+
+func ownershipError(err error, action string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrOwnershipConflict
+		case "23503":
+			return ErrOwnershipReference
+		}
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+func deleteByID(query, action string, args ...interface{}) (bool, error) {
+	result, err := DB.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to %s: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CreateTeam creates an empty team
+func CreateTeam(name, description string) (*Team, error) {
+	team := Team{Name: name, Description: description, MemberIDs: []int{}}
+	err := DB.QueryRow(
+		`INSERT INTO teams (name, description) VALUES ($1, $2) RETURNING id, created_at`,
+		name, description,
+	).Scan(&team.ID, &team.CreatedAt)
+	if err != nil {
+		return nil, ownershipError(err, "create team")
+	}
+	return &team, nil
+}
+
+// ListTeams returns all teams with their member IDs
+func ListTeams() ([]Team, error) {
+	return listTeams("")
+}
+
+// GetTeam returns a team with its member IDs, or nil if it does not exist
+func GetTeam(id int) (*Team, error) {
+	teams, err := listTeams(`WHERE t.id = $1`, id)
+	if err != nil || len(teams) == 0 {
+		return nil, err
+	}
+	return &teams[0], nil
+}
+
+func listTeams(where string, args ...interface{}) ([]Team, error) {
+	rows, err := DB.Query(`
+		SELECT t.id, t.name, COALESCE(t.description, ''), t.created_at,
+		       COALESCE(array_agg(m.user_id ORDER BY m.user_id) FILTER (WHERE m.user_id IS NOT NULL), '{}')
+		FROM teams t
+		LEFT JOIN team_members m ON m.team_id = t.id
+		`+where+`
+		GROUP BY t.id
+		ORDER BY t.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []Team{}
+	for rows.Next() {
+		var team Team
+		var members pq.Int64Array
+		if err := rows.Scan(&team.ID, &team.Name, &team.Description, &team.CreatedAt, &members); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		team.MemberIDs = make([]int, len(members))
+		for i, id := range members {
+			team.MemberIDs[i] = int(id)
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+// RenameTeam changes a team's name. It reports whether the team exists.
+func RenameTeam(id int, name string) (bool, error) {
+	result, err := DB.Exec(`UPDATE teams SET name = $2 WHERE id = $1`, id, name)
+	if err != nil {
+		return false, ownershipError(err, "rename team")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// SetTeamMembers replaces a team's members
+func SetTeamMembers(teamID int, userIDs []int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM team_members WHERE team_id = $1`, teamID); err != nil {
+		return fmt.Errorf("failed to clear team members: %w", err)
+	}
+	for _, userID := range userIDs {
+		_, err := tx.Exec(`
+			INSERT INTO team_members (team_id, user_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, teamID, userID)
+		if err != nil {
+			return ownershipError(err, "set team members")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit team members: %w", err)
+	}
+	return nil
+}
+
+// DeleteTeam removes a team, its memberships and its assignments
+func DeleteTeam(id int) (bool, error) {
+	return deleteByID(`DELETE FROM teams WHERE id = $1`, "delete team", id)
+}
+
+// AddTeamMember adds a user to a team
+func AddTeamMember(teamID, userID int) error {
+	_, err := DB.Exec(`INSERT INTO team_members (team_id, user_id) VALUES ($1, $2)`, teamID, userID)
+	if err != nil {
+		return ownershipError(err, "add team member")
+	}
+	return nil
+}
+
+// RemoveTeamMember removes a user from a team
+func RemoveTeamMember(teamID, userID int) (bool, error) {
+	return deleteByID(`DELETE FROM team_members WHERE team_id = $1 AND user_id = $2`, "remove team member", teamID, userID)
+}
+
+// CreateIPPool creates an empty pool
+func CreateIPPool(name, description string) (*IPPool, error) {
+	pool := IPPool{Name: name, Description: description, IPs: []string{}}
+	err := DB.QueryRow(
+		`INSERT INTO ip_pools (name, description) VALUES ($1, $2) RETURNING id, created_at`,
+		name, description,
+	).Scan(&pool.ID, &pool.CreatedAt)
+	if err != nil {
+		return nil, ownershipError(err, "create IP pool")
+	}
+	return &pool, nil
+}
+
+// ListIPPools returns all pools with their IPs
+func ListIPPools() ([]IPPool, error) {
+	rows, err := DB.Query(`
+		SELECT p.id, p.name, COALESCE(p.description, ''), p.created_at,
+		       COALESCE(array_agg(m.ip_address ORDER BY m.ip_address) FILTER (WHERE m.ip_address IS NOT NULL), '{}')
+		FROM ip_pools p
+		LEFT JOIN ip_pool_members m ON m.pool_id = p.id
+		GROUP BY p.id
+		ORDER BY p.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IP pools: %w", err)
+	}
+	defer rows.Close()
+
+	pools := []IPPool{}
+	for rows.Next() {
+		var pool IPPool
+		var ips pq.StringArray
+		if err := rows.Scan(&pool.ID, &pool.Name, &pool.Description, &pool.CreatedAt, &ips); err != nil {
+			return nil, fmt.Errorf("failed to scan IP pool: %w", err)
+		}
+		pool.IPs = []string(ips)
+		pools = append(pools, pool)
+	}
+
+	return pools, rows.Err()
+}
+
+// DeleteIPPool removes a pool, its members and its assignments
+func DeleteIPPool(id int) (bool, error) {
+	return deleteByID(`DELETE FROM ip_pools WHERE id = $1`, "delete IP pool", id)
+}
+
+// AddIPToPool adds an IP to a pool
+func AddIPToPool(poolID int, ip string) error {
+	_, err := DB.Exec(`INSERT INTO ip_pool_members (pool_id, ip_address) VALUES ($1, $2)`, poolID, ip)
+	if err != nil {
+		return ownershipError(err, "add IP to pool")
+	}
+	return nil
+}
+
+// RemoveIPFromPool removes an IP from a pool
+func RemoveIPFromPool(poolID int, ip string) (bool, error) {
+	return deleteByID(`DELETE FROM ip_pool_members WHERE pool_id = $1 AND ip_address = $2`, "remove IP from pool", poolID, ip)
+}
+
+// CreateOwnershipAssignment ties an owner to an IP or, for TargetPool, to the
+// pool with the given name
+func CreateOwnershipAssignment(ownerType string, ownerID int, targetType, target string) (*OwnershipAssignment, error) {
+	var userID, teamID, poolID sql.NullInt64
+	var ip sql.NullString
+
+	switch ownerType {
+	case OwnerUser:
+		userID = sql.NullInt64{Int64: int64(ownerID), Valid: true}
+	case OwnerTeam:
+		teamID = sql.NullInt64{Int64: int64(ownerID), Valid: true}
+	default:
+		return nil, fmt.Errorf("invalid owner type: %s", ownerType)
+	}
+
+	switch targetType {
+	case TargetIP:
+		ip = sql.NullString{String: target, Valid: true}
+	case TargetPool:
+		var id int64
+		err := DB.QueryRow(`SELECT id FROM ip_pools WHERE name = $1`, target).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, ErrOwnershipReference
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up IP pool: %w", err)
+		}
+		poolID = sql.NullInt64{Int64: id, Valid: true}
+	default:
+		return nil, fmt.Errorf("invalid target type: %s", targetType)
+	}
+
+	var id int
+	err := DB.QueryRow(`
+		INSERT INTO ownership_assignments (user_id, team_id, ip_address, pool_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, userID, teamID, ip, poolID).Scan(&id)
+	if err != nil {
+		return nil, ownershipError(err, "create ownership assignment")
+	}
+
+	assignments, err := listAssignments(`WHERE a.id = $1`, id)
+	if err != nil || len(assignments) == 0 {
+		return nil, err
+	}
+	return &assignments[0], nil
+}
+
+// ListOwnershipAssignments returns assignments matching the filter
+func ListOwnershipAssignments(filter AssignmentFilter) ([]OwnershipAssignment, error) {
+	where := `WHERE ($1 = '' OR ($1 = 'user' AND a.user_id = $2) OR ($1 = 'team' AND a.team_id = $2))
+		AND ($3 = '' OR a.ip_address = $3 OR a.pool_id IN (SELECT pool_id FROM ip_pool_members WHERE ip_address = $3))`
+	return listAssignments(where, filter.OwnerType, filter.OwnerID, filter.IP)
+}
+
+func listAssignments(where string, args ...interface{}) ([]OwnershipAssignment, error) {
+	query := `
+		SELECT a.id,
+		       CASE WHEN a.user_id IS NOT NULL THEN 'user' ELSE 'team' END,
+		       COALESCE(a.user_id, a.team_id),
+		       COALESCE(u.username, t.name),
+		       CASE WHEN a.ip_address IS NOT NULL THEN 'ip' ELSE 'pool' END,
+		       COALESCE(a.ip_address, p.name),
+		       a.created_at
+		FROM ownership_assignments a
+		LEFT JOIN users u ON u.id = a.user_id
+		LEFT JOIN teams t ON t.id = a.team_id
+		LEFT JOIN ip_pools p ON p.id = a.pool_id
+		` + where + `
+		ORDER BY a.id`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ownership assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []OwnershipAssignment{}
+	for rows.Next() {
+		var a OwnershipAssignment
+		if err := rows.Scan(&a.ID, &a.OwnerType, &a.OwnerID, &a.OwnerName, &a.TargetType, &a.Target, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ownership assignment: %w", err)
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, rows.Err()
+}
+
+// DeleteOwnershipAssignment removes an assignment
+func DeleteOwnershipAssignment(id int) (bool, error) {
+	return deleteByID(`DELETE FROM ownership_assignments WHERE id = $1`, "delete ownership assignment", id)
+}
+
+// GetIPOwners returns the users and teams owning an IP directly or through a
+// pool, for alert routing
+func GetIPOwners(ip string) ([]IPOwner, error) {
+	rows, err := DB.Query(`
+		SELECT CASE WHEN a.user_id IS NOT NULL THEN 'user' ELSE 'team' END,
+		       COALESCE(a.user_id, a.team_id),
+		       COALESCE(u.username, t.name),
+		       COALESCE(p.name, '')
+		FROM ownership_assignments a
+		LEFT JOIN users u ON u.id = a.user_id
+		LEFT JOIN teams t ON t.id = a.team_id
+		LEFT JOIN ip_pools p ON p.id = a.pool_id
+		WHERE a.ip_address = $1
+		   OR a.pool_id IN (SELECT pool_id FROM ip_pool_members WHERE ip_address = $1)
+		ORDER BY 1, 3
+	`, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IP owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := []IPOwner{}
+	for rows.Next() {
+		var o IPOwner
+		if err := rows.Scan(&o.OwnerType, &o.OwnerID, &o.OwnerName, &o.Via); err != nil {
+			return nil, fmt.Errorf("failed to scan IP owner: %w", err)
+		}
+		owners = append(owners, o)
+	}
+
+	return owners, rows.Err()
+}
+
+// GetIPPoolIDs returns the pools an IP belongs to
+func GetIPPoolIDs(ip string) ([]int, error) {
+	rows, err := DB.Query(`SELECT pool_id FROM ip_pool_members WHERE ip_address = $1 ORDER BY pool_id`, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IP pools: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan IP pool: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetOwnedIPs returns the IPs an owner is responsible for, directly or
+// through a pool. For a user this includes IPs owned by their teams.
+func GetOwnedIPs(ownerType string, ownerID int) ([]string, error) {
+	rows, err := DB.Query(`
+		WITH owners AS (
+			SELECT a.* FROM ownership_assignments a
+			WHERE ($1 = 'user' AND (a.user_id = $2 OR a.team_id IN (SELECT team_id FROM team_members WHERE user_id = $2)))
+			   OR ($1 = 'team' AND a.team_id = $2)
+		)
+		SELECT ip_address FROM owners WHERE ip_address IS NOT NULL
+		UNION
+		SELECT m.ip_address FROM owners o JOIN ip_pool_members m ON m.pool_id = o.pool_id
+		ORDER BY 1
+	`, ownerType, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owned IPs: %w", err)
+	}
+	defer rows.Close()
+
+	ips := []string{}
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("failed to scan owned IP: %w", err)
+		}
+		ips = append(ips, ip)
+	}
+
+	return ips, rows.Err()
+}
@@ -1,25 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
 // DB is the global database connection
 var DB *sql.DB
 
-// User represents a user in the database
-type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
 // Connect establishes a connection to the PostgreSQL database
 func Connect(dsn string, log *logrus.Logger) error {
 	var err error
@@ -28,7 +22,7 @@ func Connect(dsn string, log *logrus.Logger) error {
 		"action": "database_connect",
 	}).Info("Attempting to connect to PostgreSQL database")
 
-	DB, err = sql.Open("postgres", dsn)
+	DB, err = open(dsn, log)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"action": "database_connect",
@@ -58,65 +52,47 @@ func Connect(dsn string, log *logrus.Logger) error {
 	return nil
 }
 
-// Close closes the database connection
-func Close() error {
-	if DB != nil {
-		return DB.Close()
+// open opens the database, through the fault hook and the slow query log
+// if they are set
+func open(dsn string, log *logrus.Logger) (*sql.DB, error) {
+	if faultHook == nil && slowQueryThreshold <= 0 {
+		return sql.Open("postgres", dsn)
 	}
-	return nil
-}
-
-// GetAllUsers retrieves all users from the database
-func GetAllUsers() ([]User, error) {
-	query := "SELECT id, username, email, created_at FROM users ORDER BY id"
-	rows, err := DB.Query(query)
+	pqConnector, err := pq.NewConnector(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query users: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
-
-	var users []User
-	for rows.Next() {
-		var user User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
-		}
-		users = append(users, user)
+	var connector driver.Connector = pqConnector
+	if faultHook != nil {
+		connector = &faultConnector{connector: connector, hook: faultHook}
 	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating users: %w", err)
+	// Outermost, so injected latency shows as slow
+	if slowQueryThreshold > 0 {
+		connector = &slowQueryConnector{connector: connector, threshold: slowQueryThreshold, log: log}
 	}
-
-	return users, nil
+	return sql.OpenDB(connector), nil
 }
 
-// CreateUser creates a new user in the database
-func CreateUser(username, email string) (*User, error) {
-	query := "INSERT INTO users (username, email) VALUES ($1, $2) RETURNING id, username, email, created_at"
-	
-	var user User
-	err := DB.QueryRow(query, username, email).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+// Close closes the database connection
+func Close() error {
+	if DB != nil {
+		return DB.Close()
 	}
-
-	return &user, nil
+	return nil
 }
 
-// GetUserByID retrieves a user by ID
-func GetUserByID(id int) (*User, error) {
-	query := "SELECT id, username, email, created_at FROM users WHERE id = $1"
-	
-	var user User
-	err := DB.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+// Ping checks that the database answers
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not connected")
 	}
-
-	return &user, nil
+	return DB.PingContext(ctx)
 }
 
+// PoolStats returns the connection pool statistics; false when not connected
+func PoolStats() (sql.DBStats, bool) {
+	if DB == nil {
+		return sql.DBStats{}, false
+	}
+	return DB.Stats(), true
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ErasedMarker replaces recipient local parts and addresses found in reasons
+const ErasedMarker = "[erased]"
+
+// emailPattern matches addresses embedded in free-text SMTP reasons
+const emailPattern = `[^[:space:]<>"'()]+@[^[:space:]<>"'()]+`
+
+// EraseRecipient anonymizes every stored failure for a recipient address.
+// Rows are kept so aggregate counts, domains and codes stay intact; only the
+// address and any copy of it in the reason text are scrubbed.
+func EraseRecipient(email string) (int64, error) {
+	query := `
+		UPDATE smtp_failures
+		SET recipient_email = $2 || '@' || recipient_domain,
+		    reason = regexp_replace(reason, $3, $2, 'gi'),
+		    content_hash = NULL,
+		    anonymized_at = NOW()
+		WHERE LOWER(recipient_email) = LOWER($1)
+	`
+
+	result, err := DB.Exec(query, email, ErasedMarker, regexp.QuoteMeta(email))
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase recipient: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// anonymizeSMTPFailuresQuery finds the failures to scrub in the partial
+// idx_smtp_failures_not_anonymized, which only holds the ones left
+const anonymizeSMTPFailuresQuery = `
+		UPDATE smtp_failures
+		SET recipient_email = $2 || '@' || recipient_domain,
+		    reason = regexp_replace(reason, $3, $2, 'g'),
+		    content_hash = NULL,
+		    anonymized_at = NOW()
+		WHERE timestamp < $1 AND anonymized_at IS NULL
+	`
+
+// AnonymizeSMTPFailuresBefore scrubs recipient PII from failures older than
+// the given time that have not been anonymized yet
+func AnonymizeSMTPFailuresBefore(olderThan time.Time) (int64, error) {
+	result, err := DB.Exec(anonymizeSMTPFailuresQuery, olderThan, ErasedMarker, emailPattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize old SMTP failures: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
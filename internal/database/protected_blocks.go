@@ -0,0 +1,76 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrProtectedBlockNotFound is returned when a block is not protected
+var ErrProtectedBlockNotFound = errors.New("protected block not found")
+
+// ProtectedBlock marks an IONOS IP block that cleanup must never delete
+type ProtectedBlock struct {
+	ID        int       `json:"id"`
+	BlockID   string    `json:"block_id"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProtectBlock marks a block protected, or updates the reason of a block
+// that already is
+func ProtectBlock(block *ProtectedBlock) (*ProtectedBlock, error) {
+	stored := *block
+	err := DB.QueryRow(`
+		INSERT INTO protected_blocks (block_id, reason, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (block_id) DO UPDATE SET reason = EXCLUDED.reason, updated_at = NOW()
+		RETURNING id, created_by, created_at, updated_at
+	`, block.BlockID, block.Reason, block.CreatedBy).Scan(&stored.ID, &stored.CreatedBy, &stored.CreatedAt, &stored.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to protect block: %w", err)
+	}
+	return &stored, nil
+}
+
+// ListProtectedBlocks returns every protected block, oldest first
+func ListProtectedBlocks() ([]ProtectedBlock, error) {
+	rows, err := DB.Query(`
+		SELECT id, block_id, reason, created_by, created_at, updated_at
+		FROM protected_blocks
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []ProtectedBlock{}
+	for rows.Next() {
+		var b ProtectedBlock
+		if err := rows.Scan(&b.ID, &b.BlockID, &b.Reason, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan protected block: %w", err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+// UnprotectBlock removes a block's protection
+func UnprotectBlock(blockID string) error {
+	result, err := DB.Exec(`DELETE FROM protected_blocks WHERE block_id = $1`, blockID)
+	if err != nil {
+		return fmt.Errorf("failed to unprotect block: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrProtectedBlockNotFound
+	}
+	return nil
+}
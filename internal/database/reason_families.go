@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/validation"
+)
+
+// ReasonFamilyCount is how many failures of an IP had reasons of one family
+type ReasonFamilyCount struct {
+	Family string
+	IP     string
+	Count  int
+	// Example is the newest reason of the family, and ExampleRecipient the
+	// recipient of its failure, to redact where the reason quotes it
+	Example          string
+	ExampleRecipient string
+	FirstSeen        time.Time
+	LastSeen         time.Time
+}
+
+// GetReasonFamilyCounts counts the failures of every IP per reason family
+// since a time. Empty ip and domain match all IPs and organizational domains.
+// Failures stored before families existed are counted under the family of
+// their reason, so one family and IP may appear more than once.
+func GetReasonFamilyCounts(since time.Time, ip, domain string) ([]ReasonFamilyCount, error) {
+	rows, err := DB.Query(`
+		SELECT COALESCE(reason_family, ''),
+		       CASE WHEN reason_family IS NULL THEN COALESCE(reason, '') ELSE '' END AS legacy_reason,
+		       sending_ip, COUNT(*),
+		       (array_agg(COALESCE(reason, '') ORDER BY timestamp DESC))[1],
+		       (array_agg(recipient_email ORDER BY timestamp DESC))[1],
+		       MIN(timestamp), MAX(timestamp)
+		FROM smtp_failures
+		WHERE timestamp >= $1
+		  AND ($2 = '' OR sending_ip = $2)
+		  AND ($3 = '' OR COALESCE(organizational_domain, recipient_domain) = $3)
+		GROUP BY 1, 2, sending_ip
+		ORDER BY sending_ip
+	`, since, ip, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reason families: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []ReasonFamilyCount{}
+	for rows.Next() {
+		var c ReasonFamilyCount
+		var legacy string
+		if err := rows.Scan(&c.Family, &legacy, &c.IP, &c.Count, &c.Example, &c.ExampleRecipient, &c.FirstSeen, &c.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan reason family: %w", err)
+		}
+		if c.Family == "" {
+			c.Family = validation.ReasonFamily(legacy)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Recommendation is an entry of the recommendation catalog. Texts overrides
+// the built-in text of the action per language; Link points at the runbook.
+type Recommendation struct {
+	Action    string            `json:"action"`
+	Texts     map[string]string `json:"texts"`
+	Link      string            `json:"link,omitempty"`
+	UpdatedBy string            `json:"updated_by"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// RecommendationMapping recommends an action for a status, and optionally
+// only for one issue type. Position orders the actions of a status.
+type RecommendationMapping struct {
+	ID        int       `json:"id"`
+	Status    string    `json:"status"`
+	IssueType string    `json:"issue_type,omitempty"`
+	Action    string    `json:"action"`
+	Position  int       `json:"position"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveRecommendation creates or replaces a catalog entry
+func SaveRecommendation(rec *Recommendation) (*Recommendation, error) {
+	texts := rec.Texts
+	if texts == nil {
+		texts = map[string]string{}
+	}
+	encoded, err := json.Marshal(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recommendation texts: %w", err)
+	}
+
+	_, err = DB.Exec(`
+		INSERT INTO recommendations (action, texts, link, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (action) DO UPDATE
+		SET texts = EXCLUDED.texts, link = EXCLUDED.link, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, rec.Action, encoded, rec.Link, rec.UpdatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recommendation: %w", err)
+	}
+	return GetRecommendation(rec.Action)
+}
+
+// DeleteRecommendation removes a catalog entry with its mappings
+func DeleteRecommendation(action string) (bool, error) {
+	return deleteByID(`DELETE FROM recommendations WHERE action = $1`, "delete recommendation", action)
+}
+
+// GetRecommendation returns a catalog entry, or nil if it does not exist
+func GetRecommendation(action string) (*Recommendation, error) {
+	recs, err := listRecommendations(`WHERE action = $1`, action)
+	if err != nil || len(recs) == 0 {
+		return nil, err
+	}
+	return &recs[0], nil
+}
+
+// ListRecommendations returns the catalog ordered by action
+func ListRecommendations() ([]Recommendation, error) {
+	return listRecommendations("")
+}
+
+func listRecommendations(where string, args ...interface{}) ([]Recommendation, error) {
+	rows, err := DB.Query(`
+		SELECT action, texts, link, updated_by, created_at, updated_at
+		FROM recommendations
+		`+where+`
+		ORDER BY action`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recommendations: %w", err)
+	}
+	defer rows.Close()
+
+	recs := []Recommendation{}
+	for rows.Next() {
+		var rec Recommendation
+		var texts []byte
+		if err := rows.Scan(&rec.Action, &texts, &rec.Link, &rec.UpdatedBy, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation: %w", err)
+		}
+		if err := json.Unmarshal(texts, &rec.Texts); err != nil {
+			return nil, fmt.Errorf("failed to decode recommendation texts: %w", err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// CreateRecommendationMapping stores a new mapping. A mapping repeating an
+// action for the same status and issue type returns ErrOwnershipConflict, and
+// one naming an action missing from the catalog ErrOwnershipReference.
+func CreateRecommendationMapping(mapping *RecommendationMapping) (*RecommendationMapping, error) {
+	stored := *mapping
+	err := DB.QueryRow(`
+		INSERT INTO recommendation_mappings (status, issue_type, action, position, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, mapping.Status, mapping.IssueType, mapping.Action, mapping.Position, mapping.CreatedBy).Scan(&stored.ID, &stored.CreatedAt)
+	if err != nil {
+		return nil, ownershipError(err, "create recommendation mapping")
+	}
+	return &stored, nil
+}
+
+// DeleteRecommendationMapping removes a mapping
+func DeleteRecommendationMapping(id int) (bool, error) {
+	return deleteByID(`DELETE FROM recommendation_mappings WHERE id = $1`, "delete recommendation mapping", id)
+}
+
+// ListRecommendationMappings returns every mapping ordered by status, issue
+// type and position
+func ListRecommendationMappings() ([]RecommendationMapping, error) {
+	rows, err := DB.Query(`
+		SELECT id, status, issue_type, action, position, created_by, created_at
+		FROM recommendation_mappings
+		ORDER BY status, issue_type, position, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recommendation mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []RecommendationMapping{}
+	for rows.Next() {
+		var m RecommendationMapping
+		if err := rows.Scan(&m.ID, &m.Status, &m.IssueType, &m.Action, &m.Position, &m.CreatedBy, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
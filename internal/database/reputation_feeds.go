@@ -0,0 +1,209 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Verdicts a reputation feed can report on an IP
+const (
+	VerdictMalicious  = "malicious"
+	VerdictSuspicious = "suspicious"
+	VerdictClean      = "clean"
+)
+
+// ErrFeedExists is returned when a reputation feed name is already taken
+var ErrFeedExists = errors.New("reputation feed already exists")
+
+// ReputationFeed is a third-party source of IP verdicts, e.g. internal
+// threat intel or a partner blocklist
+type ReputationFeed struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Weight scales the feed's verdicts in the external score
+	Weight          float64    `json:"weight"`
+	Enabled         bool       `json:"enabled"`
+	SignalsReceived int64      `json:"signals_received"`
+	CreatedBy       string     `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// ExternalSignal is the latest verdict of a feed on an IP
+type ExternalSignal struct {
+	ID         int       `json:"id"`
+	FeedID     int       `json:"feed_id"`
+	FeedName   string    `json:"feed_name,omitempty"`
+	FeedWeight float64   `json:"feed_weight"`
+	IP         string    `json:"ip"`
+	Verdict    string    `json:"verdict"`
+	Confidence float64   `json:"confidence"`
+	Reason     string    `json:"reason,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const reputationFeedColumns = `id, name, COALESCE(description, ''), weight, enabled, signals_received, COALESCE(created_by, ''), created_at, last_seen_at`
+
+func scanReputationFeed(row interface{ Scan(...interface{}) error }) (*ReputationFeed, error) {
+	var f ReputationFeed
+	var lastSeen sql.NullTime
+	err := row.Scan(&f.ID, &f.Name, &f.Description, &f.Weight, &f.Enabled, &f.SignalsReceived, &f.CreatedBy, &f.CreatedAt, &lastSeen)
+	if err != nil {
+		return nil, err
+	}
+	if lastSeen.Valid {
+		f.LastSeenAt = &lastSeen.Time
+	}
+	return &f, nil
+}
+
+// CreateReputationFeed stores a new feed with the hash of its token
+func CreateReputationFeed(name, description string, weight float64, tokenHash, createdBy string) (*ReputationFeed, error) {
+	query := `
+		INSERT INTO reputation_feeds (name, description, weight, token_hash, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''))
+		RETURNING ` + reputationFeedColumns
+
+	feed, err := scanReputationFeed(DB.QueryRow(query, name, description, weight, tokenHash, createdBy))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrFeedExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reputation feed: %w", err)
+	}
+	return feed, nil
+}
+
+// GetReputationFeedByTokenHash looks up an enabled feed by token hash
+func GetReputationFeedByTokenHash(tokenHash string) (*ReputationFeed, error) {
+	query := `SELECT ` + reputationFeedColumns + ` FROM reputation_feeds WHERE token_hash = $1 AND enabled = TRUE`
+
+	feed, err := scanReputationFeed(DB.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reputation feed: %w", err)
+	}
+	return feed, nil
+}
+
+// ListReputationFeeds returns all feeds ordered by name
+func ListReputationFeeds() ([]ReputationFeed, error) {
+	rows, err := DB.Query(`SELECT ` + reputationFeedColumns + ` FROM reputation_feeds ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reputation feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []ReputationFeed{}
+	for rows.Next() {
+		feed, err := scanReputationFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reputation feed: %w", err)
+		}
+		feeds = append(feeds, *feed)
+	}
+	return feeds, rows.Err()
+}
+
+// UpdateReputationFeed changes the fields that are not nil and returns the
+// feed, or nil if it does not exist. A disabled feed's token is refused and
+// its signals stop counting; they are kept.
+func UpdateReputationFeed(id int, description *string, weight *float64, enabled *bool) (*ReputationFeed, error) {
+	query := `
+		UPDATE reputation_feeds
+		SET description = COALESCE($2, description),
+		    weight = COALESCE($3, weight),
+		    enabled = COALESCE($4, enabled)
+		WHERE id = $1
+		RETURNING ` + reputationFeedColumns
+
+	feed, err := scanReputationFeed(DB.QueryRow(query, id, description, weight, enabled))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reputation feed: %w", err)
+	}
+	return feed, nil
+}
+
+// UpsertExternalSignals stores a feed's verdicts, each replacing the feed's
+// previous verdict on the IP unless that one was reported later, and counts
+// them on the feed. It returns how many verdicts were applied.
+func UpsertExternalSignals(feedID int, signals []ExternalSignal) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	applied := 0
+	for _, s := range signals {
+		result, err := tx.Exec(`
+			INSERT INTO external_reputation_signals (feed_id, ip, verdict, confidence, reason, reference, reported_at, expires_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+			ON CONFLICT (feed_id, ip) DO UPDATE
+			SET verdict = EXCLUDED.verdict, confidence = EXCLUDED.confidence, reason = EXCLUDED.reason,
+			    reference = EXCLUDED.reference, reported_at = EXCLUDED.reported_at,
+			    expires_at = EXCLUDED.expires_at, updated_at = NOW()
+			WHERE EXCLUDED.reported_at >= external_reputation_signals.reported_at
+		`, feedID, s.IP, s.Verdict, s.Confidence, s.Reason, s.Reference, s.ReportedAt, s.ExpiresAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store external signal: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			applied += int(n)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE reputation_feeds SET signals_received = signals_received + $2, last_seen_at = NOW() WHERE id = $1
+	`, feedID, len(signals)); err != nil {
+		return 0, fmt.Errorf("failed to update reputation feed counters: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit external signals: %w", err)
+	}
+	return applied, nil
+}
+
+// GetExternalSignalsByIP returns the unexpired verdicts of enabled feeds on
+// an IP, heaviest first
+func GetExternalSignalsByIP(ip string, now time.Time) ([]ExternalSignal, error) {
+	query := `
+		SELECT s.id, s.feed_id, f.name, f.weight, s.ip, s.verdict, s.confidence,
+		       COALESCE(s.reason, ''), COALESCE(s.reference, ''), s.reported_at, s.expires_at, s.updated_at
+		FROM external_reputation_signals s
+		JOIN reputation_feeds f ON f.id = s.feed_id
+		WHERE s.ip = $1 AND s.expires_at > $2 AND f.enabled = TRUE
+		ORDER BY f.weight * s.confidence DESC, f.name
+	`
+
+	rows, err := DB.Query(query, ip, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query external signals: %w", err)
+	}
+	defer rows.Close()
+
+	signals := []ExternalSignal{}
+	for rows.Next() {
+		var s ExternalSignal
+		if err := rows.Scan(&s.ID, &s.FeedID, &s.FeedName, &s.FeedWeight, &s.IP, &s.Verdict, &s.Confidence,
+			&s.Reason, &s.Reference, &s.ReportedAt, &s.ExpiresAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan external signal: %w", err)
+		}
+		signals = append(signals, s)
+	}
+	return signals, rows.Err()
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReputationLookup is the answer of an external sender reputation source,
+// e.g. SenderScore, about an IP
+type ReputationLookup struct {
+	ID       int    `json:"id"`
+	IP       string `json:"ip"`
+	Provider string `json:"provider"`
+	// Score is the provider's own scale, nil when it has no score for the IP
+	Score   *float64          `json:"score,omitempty"`
+	Listed  bool              `json:"listed"`
+	Summary string            `json:"summary,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	// Error is set when the lookup failed; the result is then kept for a
+	// shorter time
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// InsertReputationLookup stores a lookup result
+func InsertReputationLookup(lookup *ReputationLookup) error {
+	details, err := json.Marshal(lookup.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lookup details: %w", err)
+	}
+
+	query := `
+		INSERT INTO reputation_lookups (ip, provider, score, listed, summary, details, error, checked_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8)
+		RETURNING id
+	`
+	var score sql.NullFloat64
+	if lookup.Score != nil {
+		score = sql.NullFloat64{Float64: *lookup.Score, Valid: true}
+	}
+	if err := DB.QueryRow(query, lookup.IP, lookup.Provider, score, lookup.Listed, lookup.Summary, details, lookup.Error, lookup.CheckedAt).Scan(&lookup.ID); err != nil {
+		return fmt.Errorf("failed to insert reputation lookup: %w", err)
+	}
+	return nil
+}
+
+// GetLatestReputationLookups returns the newest lookup of each provider for
+// an IP, by provider
+func GetLatestReputationLookups(ip string) ([]ReputationLookup, error) {
+	query := `
+		SELECT DISTINCT ON (provider) id, ip, provider, score, listed, COALESCE(summary, ''), details, COALESCE(error, ''), checked_at
+		FROM reputation_lookups
+		WHERE ip = $1
+		ORDER BY provider, checked_at DESC
+	`
+
+	rows, err := DB.Query(query, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reputation lookups: %w", err)
+	}
+	defer rows.Close()
+
+	lookups := []ReputationLookup{}
+	for rows.Next() {
+		var l ReputationLookup
+		var score sql.NullFloat64
+		var details []byte
+		if err := rows.Scan(&l.ID, &l.IP, &l.Provider, &score, &l.Listed, &l.Summary, &details, &l.Error, &l.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reputation lookup: %w", err)
+		}
+		if score.Valid {
+			l.Score = &score.Float64
+		}
+		if err := json.Unmarshal(details, &l.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal lookup details: %w", err)
+		}
+		lookups = append(lookups, l)
+	}
+	return lookups, rows.Err()
+}
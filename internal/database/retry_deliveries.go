@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetSMTPFailuresToRecipient returns the failures of an IP to one recipient,
+// compared case-insensitively, from since up to and including until, oldest
+// first
+func GetSMTPFailuresToRecipient(ip, recipient string, since, until time.Time) ([]SMTPFailure, error) {
+	query := `
+		SELECT ` + smtpFailureColumns + `
+		FROM smtp_failures
+		WHERE LOWER(recipient_email) = LOWER($2) AND sending_ip = $1
+		  AND timestamp >= $3 AND timestamp <= $4
+		ORDER BY timestamp
+	`
+
+	rows, err := DB.Query(query, ip, recipient, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SMTP failures to recipient: %w", err)
+	}
+	return scanSMTPFailures(rows)
+}
+
+// MarkRetriesDelivered sets retry_delivered_at on the failures with the given
+// IDs that are not marked yet. With a failure classifier set, each marked
+// failure moves to the counter of the kind it classifies as now, in the same
+// transaction. Returns the failures marked.
+func MarkRetriesDelivered(ids []int, deliveredAt time.Time) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE smtp_failures SET retry_delivered_at = $2
+		WHERE id = ANY($1) AND retry_delivered_at IS NULL
+		RETURNING ` + smtpFailureColumns
+	rows, err := tx.Query(query, pq.Array(ids), deliveredAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark delivered retries: %w", err)
+	}
+	marked, err := scanSMTPFailures(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	if failureClassifier != nil {
+		for _, f := range marked {
+			pending := f
+			pending.RetryDeliveredAt = nil
+			from, to := failureClassifier(pending), failureClassifier(f)
+			if from == to {
+				continue
+			}
+			if err := moveFailureCounter(tx, f, from, to); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit delivered retries: %w", err)
+	}
+	return len(marked), nil
+}
+
+// moveFailureCounter moves a stored failure from the counter of one kind to
+// another. Failures of pruned buckets are no longer counted and stay so.
+func moveFailureCounter(tx *sql.Tx, f SMTPFailure, from, to string) error {
+	bucket := f.Timestamp.UTC().Truncate(FailureCounterBucket)
+	result, err := tx.Exec(`
+		UPDATE failure_counters SET failures = failures - 1
+		WHERE sending_ip = $1 AND bucket_start = $2 AND mta_instance = $3 AND domain = $4
+		  AND enhanced_code = $5 AND kind = $6 AND failures > 0
+	`, f.SendingIP, bucket, f.MTAInstance, f.OrgDomain(), f.EnhancedCode, from)
+	if err != nil {
+		return fmt.Errorf("failed to decrement failure counter: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO failure_counters (sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind, failures)
+		VALUES ($1, $2, $3, $4, $5, $6, 1)
+		ON CONFLICT (sending_ip, bucket_start, mta_instance, domain, enhanced_code, kind)
+		DO UPDATE SET failures = failure_counters.failures + 1
+	`, f.SendingIP, bucket, f.MTAInstance, f.OrgDomain(), f.EnhancedCode, to)
+	if err != nil {
+		return fmt.Errorf("failed to increment failure counter: %w", err)
+	}
+	return nil
+}
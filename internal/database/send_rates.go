@@ -0,0 +1,78 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SendRate is the recommended send rate of an IP to a destination provider
+type SendRate struct {
+	IP string `json:"ip"`
+	// Provider is "*" for every provider without a rate of its own
+	Provider        string `json:"provider"`
+	MessagesPerHour int    `json:"messages_per_hour"`
+	// WarmupStage is the 1-based warm-up stage capping the rate, 0 once the
+	// IP is warmed up
+	WarmupStage int `json:"warmup_stage"`
+	// Reasons explain each reduction from the full rate
+	Reasons   []string  `json:"reasons"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReplaceSendRates replaces the recommended send rates of an IP
+func ReplaceSendRates(ip string, rates []SendRate) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM ip_send_rates WHERE ip = $1`, ip); err != nil {
+		return fmt.Errorf("failed to delete send rates: %w", err)
+	}
+	for _, r := range rates {
+		reasons, err := json.Marshal(r.Reasons)
+		if err != nil {
+			return fmt.Errorf("failed to marshal send rate reasons: %w", err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO ip_send_rates (ip, provider, messages_per_hour, warmup_stage, reasons, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, ip, r.Provider, r.MessagesPerHour, r.WarmupStage, reasons, r.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to insert send rate: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit send rates: %w", err)
+	}
+	return nil
+}
+
+// GetSendRates returns the recommended send rates of an IP, by provider
+func GetSendRates(ip string) ([]SendRate, error) {
+	rows, err := DB.Query(`
+		SELECT ip, provider, messages_per_hour, warmup_stage, reasons, updated_at
+		FROM ip_send_rates
+		WHERE ip = $1
+		ORDER BY provider
+	`, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query send rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []SendRate{}
+	for rows.Next() {
+		var r SendRate
+		var reasons []byte
+		if err := rows.Scan(&r.IP, &r.Provider, &r.MessagesPerHour, &r.WarmupStage, &reasons, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan send rate: %w", err)
+		}
+		if err := json.Unmarshal(reasons, &r.Reasons); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal send rate reasons: %w", err)
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SendingVolume is the number of messages a sending system reports an IP
+// sent over a period
+type SendingVolume struct {
+	SendingIP   string    `json:"sending_ip"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Messages    int       `json:"messages"`
+	Source      string    `json:"source"`
+}
+
+// UpsertSendingVolumes stores reported volumes in one transaction. A source
+// reporting the same period of an IP again replaces its earlier report.
+func UpsertSendingVolumes(volumes []SendingVolume) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, v := range volumes {
+		if _, err := tx.Exec(`
+			INSERT INTO sending_volumes (sending_ip, period_start, period_end, messages, source)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (sending_ip, period_start, period_end, source)
+			DO UPDATE SET messages = EXCLUDED.messages, reported_at = NOW()
+		`, v.SendingIP, v.PeriodStart, v.PeriodEnd, v.Messages, v.Source); err != nil {
+			return fmt.Errorf("failed to store sending volume: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sending volumes: %w", err)
+	}
+	return nil
+}
+
+// SumSendingVolume returns the messages reported for an IP between since
+// and until. Reports only partly in the range count in proportion to their
+// overlap, and the reports of different sources add up. found is false
+// when no report overlaps the range.
+func SumSendingVolume(ip string, since, until time.Time) (messages int, found bool, err error) {
+	query := `
+		SELECT COUNT(*), COALESCE(ROUND(SUM(
+			messages * EXTRACT(EPOCH FROM LEAST(period_end, $3) - GREATEST(period_start, $2))
+			/ GREATEST(EXTRACT(EPOCH FROM period_end - period_start), 1)
+		)), 0)
+		FROM sending_volumes
+		WHERE sending_ip = $1 AND period_end > $2 AND period_start < $3
+	`
+	var reports int
+	if err := DB.QueryRow(query, ip, since, until).Scan(&reports, &messages); err != nil {
+		return 0, false, fmt.Errorf("failed to sum sending volume: %w", err)
+	}
+	return messages, reports > 0, nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SentVolumeBucket is the width of the buckets delivered messages are
+// counted in
+const SentVolumeBucket = time.Minute
+
+// DeliverySuccess is a message an MTA reports it delivered from an IP. The
+// recipient and message ID are not stored; they tell which deferrals the
+// delivery got past.
+type DeliverySuccess struct {
+	SendingIP      string    `json:"sending_ip"`
+	EventID        string    `json:"event_id"`
+	MTAInstance    string    `json:"mta_instance,omitempty"`
+	RecipientEmail string    `json:"recipient_email,omitempty"`
+	MessageID      string    `json:"message_id,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// RecordDeliverySuccess counts a delivered message in the sent_volume bucket
+// of its IP and MTA instance. An event ID counted before is not counted
+// again, and stored is false; events without an ID are always counted.
+func RecordDeliverySuccess(success *DeliverySuccess) (stored bool, err error) {
+	query := `
+		WITH seen AS (
+			INSERT INTO sent_volume_events (event_id)
+			SELECT $4 WHERE $4 <> ''
+			ON CONFLICT DO NOTHING
+			RETURNING event_id
+		)
+		INSERT INTO sent_volume (sending_ip, bucket_start, mta_instance, messages)
+		SELECT $1, $2, $3, 1 WHERE $4 = '' OR EXISTS (SELECT 1 FROM seen)
+		ON CONFLICT (sending_ip, bucket_start, mta_instance)
+		DO UPDATE SET messages = sent_volume.messages + 1
+		RETURNING messages
+	`
+	bucket := success.Timestamp.UTC().Truncate(SentVolumeBucket)
+	var messages int
+	err = DB.QueryRow(query, success.SendingIP, bucket, success.MTAInstance, success.EventID).Scan(&messages)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record delivery success: %w", err)
+	}
+	return true, nil
+}
+
+// SumSentVolume returns the messages delivered from an IP in the buckets
+// from the one holding since up to until, over all MTA instances. found is
+// false when no bucket in the range counted any.
+func SumSentVolume(ip string, since, until time.Time) (messages int, found bool, err error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(messages), 0)
+		FROM sent_volume
+		WHERE sending_ip = $1 AND bucket_start >= $2 AND bucket_start < $3
+	`
+	var buckets int
+	if err := DB.QueryRow(query, ip, since.Truncate(SentVolumeBucket), until).Scan(&buckets, &messages); err != nil {
+		return 0, false, fmt.Errorf("failed to sum sent volume: %w", err)
+	}
+	return messages, buckets > 0, nil
+}
+
+// PruneSentVolume deletes the buckets before olderThan and the event IDs
+// received before it. Returns the buckets deleted.
+func PruneSentVolume(olderThan time.Time) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM sent_volume WHERE bucket_start < $1`, olderThan.Truncate(SentVolumeBucket))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sent volume: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM sent_volume_events WHERE received_at < $1`, olderThan); err != nil {
+		return 0, fmt.Errorf("failed to prune sent volume events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit sent volume pruning: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SLOMeasurement is one check of a fleet objective: the value measured and
+// whether it met the objective's threshold
+type SLOMeasurement struct {
+	Objective  string    `json:"objective"`
+	MeasuredAt time.Time `json:"measured_at"`
+	Value      float64   `json:"value"`
+	Threshold  float64   `json:"threshold"`
+	Met        bool      `json:"met"`
+	// TotalIPs is the size of the fleet measured
+	TotalIPs int `json:"total_ips"`
+}
+
+// CountIPsByStatus counts the IPs with reputation metrics per status,
+// leaving out archived IPs
+func CountIPsByStatus() (map[string]int, error) {
+	rows, err := DB.Query(`
+		SELECT m.status, COUNT(*)
+		FROM ip_reputation_metrics m
+		WHERE NOT EXISTS (SELECT 1 FROM archived_ips a WHERE a.ip = m.ip)
+		GROUP BY m.status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count IPs by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan status count: %w", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// InsertSLOMeasurements stores the measurements of one check
+func InsertSLOMeasurements(measurements []SLOMeasurement) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range measurements {
+		if _, err := tx.Exec(`
+			INSERT INTO slo_measurements (objective, measured_at, value, threshold, met, total_ips)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, m.Objective, m.MeasuredAt, m.Value, m.Threshold, m.Met, m.TotalIPs); err != nil {
+			return fmt.Errorf("failed to insert SLO measurement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit SLO measurements: %w", err)
+	}
+	return nil
+}
+
+// CountSLOMeasurements counts the measurements of an objective since a time,
+// and how many of them met it
+func CountSLOMeasurements(objective string, since time.Time) (total, met int, err error) {
+	err = DB.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE met)
+		FROM slo_measurements
+		WHERE objective = $1 AND measured_at >= $2
+	`, objective, since).Scan(&total, &met)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count SLO measurements: %w", err)
+	}
+	return total, met, nil
+}
+
+// GetLatestSLOMeasurement returns the newest measurement of an objective,
+// nil if it was never measured
+func GetLatestSLOMeasurement(objective string) (*SLOMeasurement, error) {
+	var m SLOMeasurement
+	err := DB.QueryRow(`
+		SELECT objective, measured_at, value, threshold, met, total_ips
+		FROM slo_measurements
+		WHERE objective = $1
+		ORDER BY measured_at DESC
+		LIMIT 1
+	`, objective).Scan(&m.Objective, &m.MeasuredAt, &m.Value, &m.Threshold, &m.Met, &m.TotalIPs)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest SLO measurement: %w", err)
+	}
+	return &m, nil
+}
+
+// GetSLOMeasurements returns the measurements of an objective since a time,
+// newest first
+func GetSLOMeasurements(objective string, since time.Time) ([]SLOMeasurement, error) {
+	rows, err := DB.Query(`
+		SELECT objective, measured_at, value, threshold, met, total_ips
+		FROM slo_measurements
+		WHERE objective = $1 AND measured_at >= $2
+		ORDER BY measured_at DESC
+	`, objective, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SLO measurements: %w", err)
+	}
+	defer rows.Close()
+
+	measurements := []SLOMeasurement{}
+	for rows.Next() {
+		var m SLOMeasurement
+		if err := rows.Scan(&m.Objective, &m.MeasuredAt, &m.Value, &m.Threshold, &m.Met, &m.TotalIPs); err != nil {
+			return nil, fmt.Errorf("failed to scan SLO measurement: %w", err)
+		}
+		measurements = append(measurements, m)
+	}
+	return measurements, rows.Err()
+}
+
+// DeleteSLOMeasurementsBefore deletes the measurements older than cutoff
+func DeleteSLOMeasurementsBefore(cutoff time.Time) (int64, error) {
+	result, err := DB.Exec(`DELETE FROM slo_measurements WHERE measured_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old SLO measurements: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxLoggedQueryLength truncates the statements of slow query logs
+const maxLoggedQueryLength = 1000
+
+var slowQueryThreshold time.Duration
+
+// SetSlowQueryThreshold makes Connect log statements taking at least
+// threshold as slow_query warnings. Queries are timed until their first row.
+// Arguments are not logged, as they carry IPs and addresses. It must be set
+// before Connect; zero logs none.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	slowQueryThreshold = threshold
+}
+
+// slowQueryConnector wraps the connections of a connector in slowQueryConns
+type slowQueryConnector struct {
+	connector driver.Connector
+	threshold time.Duration
+	log       *logrus.Logger
+}
+
+func (c *slowQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryConn{conn: conn, threshold: c.threshold, log: c.log}, nil
+}
+
+func (c *slowQueryConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// slowQueryConn times the statements of the driver's connection
+type slowQueryConn struct {
+	conn      driver.Conn
+	threshold time.Duration
+	log       *logrus.Logger
+}
+
+func (c *slowQueryConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *slowQueryConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *slowQueryConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *slowQueryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c *slowQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.conn.Begin()
+}
+
+func (c *slowQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	c.observe(query, time.Since(start), err)
+	return rows, err
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := e.ExecContext(ctx, query, args)
+	c.observe(query, time.Since(start), err)
+	return result, err
+}
+
+func (c *slowQueryConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// observe logs a statement that took at least the threshold
+func (c *slowQueryConn) observe(query string, took time.Duration, err error) {
+	if took < c.threshold || err == driver.ErrSkip {
+		return
+	}
+	fields := logrus.Fields{
+		"action":       "slow_query",
+		"duration_ms":  took.Milliseconds(),
+		"threshold_ms": c.threshold.Milliseconds(),
+		"query":        compactQuery(query),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	c.log.WithFields(fields).Warn("Slow database query")
+}
+
+// compactQuery collapses the whitespace of a statement onto one line and
+// truncates it for logging
+func compactQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedQueryLength {
+		query = query[:maxLoggedQueryLength] + "..."
+	}
+	return query
+}
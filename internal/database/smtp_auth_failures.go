@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SMTPAuthFailure is a failed outgoing SMTP authentication, e.g. against a
+// smarthost
+type SMTPAuthFailure struct {
+	ID          int       `json:"id"`
+	SendingIP   string    `json:"sending_ip"`
+	RemoteHost  string    `json:"remote_host,omitempty"`
+	Mechanism   string    `json:"mechanism,omitempty"`
+	Username    string    `json:"username,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	EventID     string    `json:"event_id"`
+	SourceID    *int      `json:"source_id,omitempty"`
+	MTAInstance string    `json:"mta_instance,omitempty"`
+}
+
+// InsertSMTPAuthFailure stores an authentication failure. Duplicate webhook
+// events are ignored.
+func InsertSMTPAuthFailure(failure *SMTPAuthFailure) error {
+	query := `
+		INSERT INTO smtp_auth_failures (
+			sending_ip, remote_host, mechanism, username, reason, timestamp,
+			event_id, source_id, mta_instance
+		) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''))
+		ON CONFLICT (event_id) DO NOTHING
+	`
+
+	_, err := DB.Exec(query,
+		failure.SendingIP, failure.RemoteHost, failure.Mechanism, failure.Username, failure.Reason,
+		failure.Timestamp, failure.EventID, failure.SourceID, failure.MTAInstance,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert SMTP auth failure: %w", err)
+	}
+	return nil
+}
+
+// GetSMTPAuthFailuresByIP returns the authentication failures of an IP since
+// a time, newest first. An empty instance matches all instances.
+func GetSMTPAuthFailuresByIP(ip, instance string, since time.Time) ([]SMTPAuthFailure, error) {
+	query := `
+		SELECT id, sending_ip, COALESCE(remote_host, ''), COALESCE(mechanism, ''),
+		       COALESCE(username, ''), COALESCE(reason, ''), timestamp, event_id,
+		       source_id, COALESCE(mta_instance, '')
+		FROM smtp_auth_failures
+		WHERE sending_ip = $1 AND timestamp >= $2
+		  AND ($3 = '' OR mta_instance = $3)
+		ORDER BY timestamp DESC
+	`
+
+	rows, err := DB.Query(query, ip, since, instance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SMTP auth failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []SMTPAuthFailure{}
+	for rows.Next() {
+		var f SMTPAuthFailure
+		if err := rows.Scan(
+			&f.ID, &f.SendingIP, &f.RemoteHost, &f.Mechanism, &f.Username, &f.Reason,
+			&f.Timestamp, &f.EventID, &f.SourceID, &f.MTAInstance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan SMTP auth failure: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+// CountSMTPAuthFailures counts the authentication failures of an IP since a
+// time. An empty instance matches all instances.
+func CountSMTPAuthFailures(ip, instance string, since time.Time) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM smtp_auth_failures
+		WHERE sending_ip = $1 AND timestamp >= $2
+		  AND ($3 = '' OR mta_instance = $3)
+	`, ip, since, instance).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count SMTP auth failures: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Spamtrap types
+const (
+	TrapPristine = "pristine"
+	TrapRecycled = "recycled"
+	TrapTypo     = "typo"
+	TrapInternal = "internal"
+	TrapUnknown  = "unknown"
+)
+
+// SpamtrapHit is mail from a sending IP that reached a spamtrap address
+type SpamtrapHit struct {
+	ID          int       `json:"id"`
+	SendingIP   string    `json:"sending_ip"`
+	TrapAddress string    `json:"trap_address,omitempty"`
+	TrapType    string    `json:"trap_type"`
+	Source      string    `json:"source"`
+	ReportID    string    `json:"report_id,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	HitAt       time.Time `json:"hit_at"`
+	ReportedBy  string    `json:"reported_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InsertSpamtrapHit stores a hit and sets its ID. It returns false when the
+// source already reported a hit with the same report ID.
+func InsertSpamtrapHit(hit *SpamtrapHit) (bool, error) {
+	query := `
+		INSERT INTO spamtrap_hits (
+			sending_ip, trap_address, trap_type, source, report_id, message_id, hit_at, reported_by
+		) VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''))
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := DB.QueryRow(query,
+		hit.SendingIP, hit.TrapAddress, hit.TrapType, hit.Source, hit.ReportID, hit.MessageID,
+		hit.HitAt, hit.ReportedBy,
+	).Scan(&hit.ID, &hit.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert spamtrap hit: %w", err)
+	}
+	return true, nil
+}
+
+// GetSpamtrapHitsByIP returns the spamtrap hits of an IP since a time,
+// newest first
+func GetSpamtrapHitsByIP(ip string, since time.Time) ([]SpamtrapHit, error) {
+	query := `
+		SELECT id, sending_ip, COALESCE(trap_address, ''), trap_type, source,
+		       COALESCE(report_id, ''), COALESCE(message_id, ''), hit_at,
+		       COALESCE(reported_by, ''), created_at
+		FROM spamtrap_hits
+		WHERE sending_ip = $1 AND hit_at >= $2
+		ORDER BY hit_at DESC
+	`
+
+	rows, err := DB.Query(query, ip, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spamtrap hits: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []SpamtrapHit{}
+	for rows.Next() {
+		var h SpamtrapHit
+		if err := rows.Scan(
+			&h.ID, &h.SendingIP, &h.TrapAddress, &h.TrapType, &h.Source,
+			&h.ReportID, &h.MessageID, &h.HitAt, &h.ReportedBy, &h.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan spamtrap hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// CountSpamtrapHits counts the spamtrap hits of an IP since a time
+func CountSpamtrapHits(ip string, since time.Time) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM spamtrap_hits
+		WHERE sending_ip = $1 AND hit_at >= $2
+	`, ip, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count spamtrap hits: %w", err)
+	}
+	return count, nil
+}
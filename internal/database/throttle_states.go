@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ThrottleState is whether a destination provider is rate-limiting an IP
+type ThrottleState struct {
+	IP       string `json:"ip"`
+	Provider string `json:"provider"`
+	// Throttled is set while the provider keeps deferring the IP's mail with
+	// rate-limit responses; Since is when that started
+	Throttled       bool       `json:"throttled"`
+	Since           *time.Time `json:"since,omitempty"`
+	LastThrottledAt *time.Time `json:"last_throttled_at,omitempty"`
+	// ThrottleCount is the rate-limit responses within the throttle window
+	ThrottleCount int `json:"throttle_count"`
+	// BackoffSeconds is how long MTAs should hold mail to the provider after
+	// the last rate-limit response; RetryAfter is when that ends
+	BackoffSeconds int        `json:"backoff_seconds"`
+	RetryAfter     *time.Time `json:"retry_after,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+const throttleStateColumns = `ip, provider, throttled, since, last_throttled_at, throttle_count, backoff_seconds, retry_after, updated_at`
+
+// UpsertThrottleState stores the throttling state of an IP at a provider
+func UpsertThrottleState(s *ThrottleState) error {
+	query := `
+		INSERT INTO ip_throttle_states (` + throttleStateColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (ip, provider) DO UPDATE SET
+			throttled = EXCLUDED.throttled,
+			since = EXCLUDED.since,
+			last_throttled_at = EXCLUDED.last_throttled_at,
+			throttle_count = EXCLUDED.throttle_count,
+			backoff_seconds = EXCLUDED.backoff_seconds,
+			retry_after = EXCLUDED.retry_after,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := DB.Exec(query, s.IP, s.Provider, s.Throttled, s.Since, s.LastThrottledAt, s.ThrottleCount, s.BackoffSeconds, s.RetryAfter, s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert throttle state: %w", err)
+	}
+	return nil
+}
+
+// GetThrottleStates returns the throttling states of an IP, by provider
+func GetThrottleStates(ip string) ([]ThrottleState, error) {
+	query := `SELECT ` + throttleStateColumns + ` FROM ip_throttle_states WHERE ip = $1 ORDER BY provider`
+	return queryThrottleStates(query, ip)
+}
+
+// ListThrottledStates returns every IP and provider currently throttled,
+// longest throttled first
+func ListThrottledStates() ([]ThrottleState, error) {
+	query := `SELECT ` + throttleStateColumns + ` FROM ip_throttle_states WHERE throttled ORDER BY since, ip, provider`
+	return queryThrottleStates(query)
+}
+
+func queryThrottleStates(query string, args ...interface{}) ([]ThrottleState, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query throttle states: %w", err)
+	}
+	defer rows.Close()
+
+	states := []ThrottleState{}
+	for rows.Next() {
+		var s ThrottleState
+		var since, last, retryAfter sql.NullTime
+		if err := rows.Scan(&s.IP, &s.Provider, &s.Throttled, &since, &last, &s.ThrottleCount, &s.BackoffSeconds, &retryAfter, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan throttle state: %w", err)
+		}
+		if since.Valid {
+			s.Since = &since.Time
+		}
+		if last.Valid {
+			s.LastThrottledAt = &last.Time
+		}
+		if retryAfter.Valid {
+			s.RetryAfter = &retryAfter.Time
+		}
+		states = append(states, s)
+	}
+	return states, rows.Err()
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TLSReport is an SMTP TLS report (RFC 8460) on our outbound sessions
+type TLSReport struct {
+	ID           int               `json:"id"`
+	Organization string            `json:"organization"`
+	ReportID     string            `json:"report_id"`
+	ContactInfo  string            `json:"contact_info,omitempty"`
+	StartAt      time.Time         `json:"start_at"`
+	EndAt        time.Time         `json:"end_at"`
+	Policies     []TLSReportPolicy `json:"policies"`
+	Failures     []TLSFailure      `json:"failures"`
+	ReportedBy   string            `json:"reported_by,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// TLSReportPolicy counts a report's sessions to one policy domain
+type TLSReportPolicy struct {
+	PolicyDomain       string `json:"policy_domain"`
+	PolicyType         string `json:"policy_type"`
+	SuccessfulSessions int    `json:"successful_sessions"`
+	FailedSessions     int    `json:"failed_sessions"`
+}
+
+// TLSFailure is one kind of failed TLS negotiation from a sending IP to a
+// policy domain over the report's date range
+type TLSFailure struct {
+	PolicyDomain          string `json:"policy_domain"`
+	PolicyType            string `json:"policy_type"`
+	SendingIP             string `json:"sending_ip,omitempty"`
+	ResultType            string `json:"result_type"`
+	ReceivingMXHostname   string `json:"receiving_mx_hostname,omitempty"`
+	ReceivingIP           string `json:"receiving_ip,omitempty"`
+	FailedSessions        int    `json:"failed_sessions"`
+	FailureReasonCode     string `json:"failure_reason_code,omitempty"`
+	AdditionalInformation string `json:"additional_information,omitempty"`
+}
+
+// TLSFailureSummary totals an IP's failed sessions to a policy domain by
+// result type
+type TLSFailureSummary struct {
+	PolicyDomain   string    `json:"policy_domain"`
+	PolicyType     string    `json:"policy_type"`
+	ResultType     string    `json:"result_type"`
+	FailedSessions int       `json:"failed_sessions"`
+	Reports        int       `json:"reports"`
+	LastSeen       time.Time `json:"last_seen"` // End of the newest report's date range
+}
+
+// TLSDestinationSummary totals the reported sessions to a policy domain
+type TLSDestinationSummary struct {
+	PolicyDomain       string  `json:"policy_domain"`
+	PolicyType         string  `json:"policy_type"`
+	SuccessfulSessions int     `json:"successful_sessions"`
+	FailedSessions     int     `json:"failed_sessions"`
+	FailureRatio       float64 `json:"failure_ratio"`
+}
+
+// InsertTLSReport stores a report with its policies and failures and sets
+// its ID. It returns false when the organization already sent a report with
+// the same report ID.
+func InsertTLSReport(report *TLSReport) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO tls_reports (organization, report_id, contact_info, start_at, end_at, reported_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''))
+		ON CONFLICT (organization, report_id) DO NOTHING
+		RETURNING id, created_at
+	`, report.Organization, report.ReportID, report.ContactInfo, report.StartAt, report.EndAt, report.ReportedBy,
+	).Scan(&report.ID, &report.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert TLS report: %w", err)
+	}
+
+	for _, p := range report.Policies {
+		if _, err := tx.Exec(`
+			INSERT INTO tls_report_policies (report_id, policy_domain, policy_type, successful_sessions, failed_sessions)
+			VALUES ($1, $2, $3, $4, $5)
+		`, report.ID, p.PolicyDomain, p.PolicyType, p.SuccessfulSessions, p.FailedSessions); err != nil {
+			return false, fmt.Errorf("failed to insert TLS report policy: %w", err)
+		}
+	}
+	for _, f := range report.Failures {
+		if _, err := tx.Exec(`
+			INSERT INTO tls_failures (
+				report_id, policy_domain, policy_type, sending_ip, result_type, receiving_mx_hostname,
+				receiving_ip, failed_sessions, failure_reason_code, additional_information, start_at, end_at
+			) VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12)
+		`, report.ID, f.PolicyDomain, f.PolicyType, f.SendingIP, f.ResultType, f.ReceivingMXHostname,
+			f.ReceivingIP, f.FailedSessions, f.FailureReasonCode, f.AdditionalInformation, report.StartAt, report.EndAt,
+		); err != nil {
+			return false, fmt.Errorf("failed to insert TLS failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit TLS report: %w", err)
+	}
+	return true, nil
+}
+
+// GetTLSFailureSummary totals the failed TLS sessions of an IP in reports
+// ending since a time, by policy domain and result type, most failures first
+func GetTLSFailureSummary(ip string, since time.Time) ([]TLSFailureSummary, error) {
+	query := `
+		SELECT policy_domain, policy_type, result_type, SUM(failed_sessions),
+		       COUNT(DISTINCT report_id), MAX(end_at)
+		FROM tls_failures
+		WHERE sending_ip = $1 AND end_at >= $2
+		GROUP BY policy_domain, policy_type, result_type
+		ORDER BY SUM(failed_sessions) DESC, policy_domain
+	`
+
+	rows, err := DB.Query(query, ip, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TLS failures: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []TLSFailureSummary{}
+	for rows.Next() {
+		var s TLSFailureSummary
+		if err := rows.Scan(&s.PolicyDomain, &s.PolicyType, &s.ResultType, &s.FailedSessions, &s.Reports, &s.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan TLS failure summary: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// GetTLSDestinationSummary totals the sessions to each policy domain in
+// reports ending since a time, highest failure ratio first
+func GetTLSDestinationSummary(since time.Time) ([]TLSDestinationSummary, error) {
+	query := `
+		SELECT p.policy_domain, p.policy_type, SUM(p.successful_sessions), SUM(p.failed_sessions)
+		FROM tls_report_policies p
+		JOIN tls_reports r ON r.id = p.report_id
+		WHERE r.end_at >= $1
+		GROUP BY p.policy_domain, p.policy_type
+		ORDER BY SUM(p.failed_sessions)::float / NULLIF(SUM(p.successful_sessions) + SUM(p.failed_sessions), 0) DESC NULLS LAST,
+		         SUM(p.failed_sessions) DESC, p.policy_domain
+	`
+
+	rows, err := DB.Query(query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TLS destinations: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []TLSDestinationSummary{}
+	for rows.Next() {
+		var s TLSDestinationSummary
+		if err := rows.Scan(&s.PolicyDomain, &s.PolicyType, &s.SuccessfulSessions, &s.FailedSessions); err != nil {
+			return nil, fmt.Errorf("failed to scan TLS destination summary: %w", err)
+		}
+		if total := s.SuccessfulSessions + s.FailedSessions; total > 0 {
+			s.FailureRatio = float64(s.FailedSessions) / float64(total)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// CountTLSFailedSessions sums the failed TLS sessions of an IP in reports
+// ending since a time
+func CountTLSFailedSessions(ip string, since time.Time) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COALESCE(SUM(failed_sessions), 0) FROM tls_failures
+		WHERE sending_ip = $1 AND end_at >= $2
+	`, ip, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count TLS failed sessions: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrUserExists is returned when a username or email is already taken
+var ErrUserExists = errors.New("user already exists")
+
+// UserConflictError names the field that clashed with an existing user.
+// It matches ErrUserExists with errors.Is.
+type UserConflictError struct {
+	Field string
+}
+
+func (e *UserConflictError) Error() string {
+	return fmt.Sprintf("user already exists: %s is taken", e.Field)
+}
+
+func (e *UserConflictError) Is(target error) bool {
+	return target == ErrUserExists
+}
+
+// User represents a user in the database
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Active    bool      `json:"active"` // false once deprovisioned; cannot log in
+	Role      string    `json:"role"`   // read-only, operator or admin
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const userColumns = `id, username, email, active, role, created_at, COALESCE(updated_at, created_at)`
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	var user User
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Active, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userConflict maps a unique violation on users to ErrUserExists, naming the
+// field that clashed
+func userConflict(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return nil
+	}
+	switch pqErr.Constraint {
+	case "users_username_key":
+		return &UserConflictError{Field: "username"}
+	case "users_email_key":
+		return &UserConflictError{Field: "email"}
+	default:
+		return ErrUserExists
+	}
+}
+
+// GetAllUsers retrieves all users from the database
+func GetAllUsers() ([]User, error) {
+	users, _, err := ListUsers(0, 0)
+	return users, err
+}
+
+// ListUsers returns a page of users ordered by ID and the total number of
+// users. A limit of 0 returns all users from offset.
+func ListUsers(limit, offset int) ([]User, int, error) {
+	var total int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := "SELECT " + userColumns + " FROM users ORDER BY id OFFSET $1"
+	args := []interface{}{offset}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// CreateUser creates a new user in the database
+func CreateUser(username, email string) (*User, error) {
+	query := "INSERT INTO users (username, email) VALUES ($1, $2) RETURNING " + userColumns
+
+	user, err := scanUser(DB.QueryRow(query, username, email))
+	if err != nil {
+		if conflict := userConflict(err); conflict != nil {
+			return nil, conflict
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func GetUserByID(id int) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+
+	user, err := scanUser(DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by username. It returns nil, nil if the
+// user does not exist.
+func GetUserByUsername(username string) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE username = $1"
+
+	user, err := scanUser(DB.QueryRow(query, username))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserActive activates or deactivates a user. It reports whether the user
+// exists.
+func SetUserActive(id int, active bool) (bool, error) {
+	result, err := DB.Exec(`UPDATE users SET active = $2, updated_at = NOW() WHERE id = $1`, id, active)
+	if err != nil {
+		return false, fmt.Errorf("failed to set user active: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UpdateUser changes a user's username and/or email. Nil fields are left
+// unchanged. It returns nil, nil if the user does not exist.
+func UpdateUser(id int, username, email *string) (*User, error) {
+	query := `
+		UPDATE users
+		SET username = COALESCE($2, username),
+		    email = COALESCE($3, email),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + userColumns
+
+	user, err := scanUser(DB.QueryRow(query, id, username, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		if conflict := userConflict(err); conflict != nil {
+			return nil, conflict
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserRole changes a user's role. It returns nil, nil if the user does
+// not exist.
+func SetUserRole(id int, role string) (*User, error) {
+	query := `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1 RETURNING ` + userColumns
+
+	user, err := scanUser(DB.QueryRow(query, id, role))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser removes a user. It reports whether the user existed.
+func DeleteUser(id int) (bool, error) {
+	result, err := DB.Exec("DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WebhookSource is a Stalwart instance allowed to post webhook events
+type WebhookSource struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Enabled         bool       `json:"enabled"`
+	EventsProcessed int64      `json:"events_processed"`
+	EventsFailed    int64      `json:"events_failed"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// WebhookSourceStats summarizes what a source has ingested
+type WebhookSourceStats struct {
+	Source         WebhookSource  `json:"source"`
+	Since          time.Time      `json:"since"`
+	FailuresStored int            `json:"failures_stored"`
+	UniqueIPs      int            `json:"unique_ips"`
+	UniqueDomains  int            `json:"unique_domains"`
+	LastEventAt    *time.Time     `json:"last_event_at,omitempty"`
+	EnhancedCodes  map[string]int `json:"enhanced_codes"`
+}
+
+const webhookSourceColumns = `id, name, COALESCE(description, ''), enabled, events_processed, events_failed, created_at, last_seen_at`
+
+func scanWebhookSource(row interface{ Scan(...interface{}) error }) (*WebhookSource, error) {
+	var s WebhookSource
+	var lastSeen sql.NullTime
+	err := row.Scan(&s.ID, &s.Name, &s.Description, &s.Enabled, &s.EventsProcessed, &s.EventsFailed, &s.CreatedAt, &lastSeen)
+	if err != nil {
+		return nil, err
+	}
+	if lastSeen.Valid {
+		s.LastSeenAt = &lastSeen.Time
+	}
+	return &s, nil
+}
+
+// CreateWebhookSource stores a new source with the hash of its token
+func CreateWebhookSource(name, description, tokenHash string) (*WebhookSource, error) {
+	query := `
+		INSERT INTO webhook_sources (name, description, token_hash)
+		VALUES ($1, $2, $3)
+		RETURNING ` + webhookSourceColumns
+
+	source, err := scanWebhookSource(DB.QueryRow(query, name, description, tokenHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook source: %w", err)
+	}
+	return source, nil
+}
+
+// GetWebhookSourceByTokenHash looks up an enabled source by token hash
+func GetWebhookSourceByTokenHash(tokenHash string) (*WebhookSource, error) {
+	query := `SELECT ` + webhookSourceColumns + ` FROM webhook_sources WHERE token_hash = $1 AND enabled = TRUE`
+
+	source, err := scanWebhookSource(DB.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook source: %w", err)
+	}
+	return source, nil
+}
+
+// GetWebhookSourceByID retrieves a source by ID
+func GetWebhookSourceByID(id int) (*WebhookSource, error) {
+	query := `SELECT ` + webhookSourceColumns + ` FROM webhook_sources WHERE id = $1`
+
+	source, err := scanWebhookSource(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook source: %w", err)
+	}
+	return source, nil
+}
+
+// ListWebhookSources returns all sources ordered by name
+func ListWebhookSources() ([]WebhookSource, error) {
+	query := `SELECT ` + webhookSourceColumns + ` FROM webhook_sources ORDER BY name`
+
+	rows, err := DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []WebhookSource{}
+	for rows.Next() {
+		source, err := scanWebhookSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook source: %w", err)
+		}
+		sources = append(sources, *source)
+	}
+
+	return sources, rows.Err()
+}
+
+// DisableWebhookSource revokes a source's token. Failures it reported are kept.
+func DisableWebhookSource(id int) (bool, error) {
+	result, err := DB.Exec(`UPDATE webhook_sources SET enabled = FALSE WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable webhook source: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RecordWebhookSourceIngestion adds a delivery's outcome to the source counters
+func RecordWebhookSourceIngestion(id int, processed, failed int) error {
+	query := `
+		UPDATE webhook_sources
+		SET events_processed = events_processed + $2,
+		    events_failed = events_failed + $3,
+		    last_seen_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := DB.Exec(query, id, processed, failed); err != nil {
+		return fmt.Errorf("failed to record webhook source ingestion: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookSourceStats summarizes the failures a source stored since a time
+func GetWebhookSourceStats(id int, since time.Time) (*WebhookSourceStats, error) {
+	source, err := GetWebhookSourceByID(id)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	stats := &WebhookSourceStats{
+		Source:        *source,
+		Since:         since,
+		EnhancedCodes: make(map[string]int),
+	}
+
+	var lastEvent sql.NullTime
+	query := `
+		SELECT COUNT(*), COUNT(DISTINCT sending_ip), COUNT(DISTINCT COALESCE(organizational_domain, recipient_domain)), MAX(timestamp)
+		FROM smtp_failures
+		WHERE source_id = $1 AND timestamp >= $2
+	`
+	if err := DB.QueryRow(query, id, since).Scan(&stats.FailuresStored, &stats.UniqueIPs, &stats.UniqueDomains, &lastEvent); err != nil {
+		return nil, fmt.Errorf("failed to get webhook source stats: %w", err)
+	}
+	if lastEvent.Valid {
+		stats.LastEventAt = &lastEvent.Time
+	}
+
+	rows, err := DB.Query(`
+		SELECT COALESCE(enhanced_code, ''), COUNT(*)
+		FROM smtp_failures
+		WHERE source_id = $1 AND timestamp >= $2
+		GROUP BY enhanced_code
+	`, id, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook source code breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		var count int
+		if err := rows.Scan(&code, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan code breakdown: %w", err)
+		}
+		stats.EnhancedCodes[code] = count
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,290 @@
+package dependencies
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Names of the dependencies the service registers
+const (
+	Postgres            = "postgres"
+	IONOS               = "ionos"
+	DNS                 = "dns"
+	ReservationWebhooks = "reservation_webhooks"
+	Jira                = "jira"
+	ServiceNow          = "servicenow"
+	Prometheus          = "prometheus"
+	Stalwart            = "stalwart"
+	Slack               = "slack"
+	SMTP                = "smtp"
+	AlertWebhooks       = "alert_webhooks"
+	PagerDuty           = "pagerduty"
+)
+
+// Kinds group dependencies on the dashboard
+const (
+	KindDatabase = "database"
+	KindAPI      = "api"
+	KindDNS      = "dns"
+	KindAlert    = "alert"
+)
+
+// Dependency statuses. A dependency is degraded when its probe succeeds but
+// most recent calls failed, and unknown when it has neither a probe nor calls.
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+)
+
+const (
+	bucketWidth       = time.Minute
+	windowBuckets     = 15
+	degradedErrorRate = 0.5
+	probeTimeout      = 3 * time.Second
+	probeCacheTTL     = 30 * time.Second
+)
+
+// Probe actively checks that a dependency is reachable
+type Probe func(ctx context.Context) error
+
+// Status is the reachability and recent error rate of one dependency
+type Status struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Reachable is the result of the last probe; nil without a probe
+	Reachable     *bool      `json:"reachable,omitempty"`
+	LatencyMS     int64      `json:"latency_ms,omitempty"`
+	ProbeError    string     `json:"probe_error,omitempty"`
+	CheckedAt     *time.Time `json:"checked_at,omitempty"`
+	WindowMinutes int        `json:"window_minutes"`
+	Calls         int        `json:"calls"`
+	Errors        int        `json:"errors"`
+	ErrorRate     float64    `json:"error_rate"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+type bucket struct {
+	start  time.Time
+	calls  int
+	errors int
+}
+
+type dependency struct {
+	name    string
+	kind    string
+	probe   Probe
+	buckets [windowBuckets]bucket
+
+	lastError   string
+	lastErrorAt time.Time
+
+	probedAt time.Time
+	probeErr error
+	latency  time.Duration
+}
+
+// Registry tracks the outcome of calls to external dependencies over the
+// last 15 minutes and probes them on demand
+type Registry struct {
+	mu    sync.Mutex
+	order []string
+	deps  map[string]*dependency
+	now   func() time.Time
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		deps: make(map[string]*dependency),
+		now:  time.Now,
+	}
+}
+
+// Default is the registry the service's clients record into
+var Default = NewRegistry()
+
+// Record records a call outcome in the default registry
+func Record(name string, err error) {
+	Default.Record(name, err)
+}
+
+// get returns the named dependency, creating it if needed; callers hold mu
+func (r *Registry) get(name string) *dependency {
+	dep, ok := r.deps[name]
+	if !ok {
+		dep = &dependency{name: name}
+		r.deps[name] = dep
+		r.order = append(r.order, name)
+	}
+	return dep
+}
+
+// Register adds a dependency or replaces its kind and probe, keeping the
+// calls recorded so far. A nil probe reports recorded calls only.
+func (r *Registry) Register(name, kind string, probe Probe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dep := r.get(name)
+	dep.kind = kind
+	dep.probe = probe
+	dep.probedAt = time.Time{}
+}
+
+// Record counts one call to a dependency, failed when err is set
+func (r *Registry) Record(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	dep := r.get(name)
+	start := now.Truncate(bucketWidth)
+	b := &dep.buckets[(start.Unix()/int64(bucketWidth/time.Second))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	b.calls++
+	if err != nil {
+		b.errors++
+		dep.lastError = err.Error()
+		dep.lastErrorAt = now
+	}
+}
+
+// Check probes the dependencies whose last probe is older than 30 seconds
+// and returns the status of every dependency in registration order
+func (r *Registry) Check(ctx context.Context) []Status {
+	r.mu.Lock()
+	now := r.now()
+	due := []*dependency{}
+	probes := []Probe{}
+	for _, name := range r.order {
+		dep := r.deps[name]
+		if dep.probe != nil && now.Sub(dep.probedAt) >= probeCacheTTL {
+			due = append(due, dep)
+			probes = append(probes, dep.probe)
+		}
+	}
+	r.mu.Unlock()
+
+	type outcome struct {
+		err     error
+		latency time.Duration
+	}
+	outcomes := make([]outcome, len(due))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe Probe) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			started := time.Now()
+			err := probe(probeCtx)
+			outcomes[i] = outcome{err: err, latency: time.Since(started)}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, dep := range due {
+		dep.probedAt = now
+		dep.probeErr = outcomes[i].err
+		dep.latency = outcomes[i].latency
+	}
+
+	statuses := make([]Status, 0, len(r.order))
+	for _, name := range r.order {
+		statuses = append(statuses, r.deps[name].status(now))
+	}
+	return statuses
+}
+
+// status summarizes a dependency; callers hold mu
+func (d *dependency) status(now time.Time) Status {
+	s := Status{
+		Name:          d.name,
+		Kind:          d.kind,
+		WindowMinutes: int(windowBuckets * bucketWidth / time.Minute),
+	}
+
+	oldest := now.Truncate(bucketWidth).Add(-(windowBuckets - 1) * bucketWidth)
+	for _, b := range d.buckets {
+		if !b.start.Before(oldest) {
+			s.Calls += b.calls
+			s.Errors += b.errors
+		}
+	}
+	if s.Calls > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Calls)
+	}
+	if !d.lastErrorAt.IsZero() {
+		at := d.lastErrorAt
+		s.LastError = d.lastError
+		s.LastErrorAt = &at
+	}
+
+	switch {
+	case d.probe != nil && !d.probedAt.IsZero():
+		reachable := d.probeErr == nil
+		checkedAt := d.probedAt
+		s.Reachable = &reachable
+		s.CheckedAt = &checkedAt
+		s.LatencyMS = d.latency.Milliseconds()
+		if !reachable {
+			s.ProbeError = d.probeErr.Error()
+			s.Status = StatusDown
+		}
+	case s.Calls == 0:
+		s.Status = StatusUnknown
+	}
+	if s.Status == "" {
+		s.Status = StatusUp
+		if s.Calls > 0 && s.ErrorRate >= degradedErrorRate {
+			s.Status = StatusDegraded
+		}
+	}
+	return s
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Transport records every request made through base as a call to name.
+// Transport errors, 5xx and 429 responses count as errors; other statuses
+// are the caller's concern. A nil base uses http.DefaultTransport.
+func (r *Registry) Transport(name string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		switch {
+		case err != nil:
+			r.Record(name, err)
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			r.Record(name, &statusError{code: resp.StatusCode})
+		default:
+			r.Record(name, nil)
+		}
+		return resp, err
+	})
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.code, http.StatusText(e.code))
+}
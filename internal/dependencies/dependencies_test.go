@@ -0,0 +1,95 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(now *time.Time) *Registry {
+	r := NewRegistry()
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestRecordErrorRateWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)
+	r := newTestRegistry(&now)
+	r.Register(IONOS, KindAPI, nil)
+
+	if s := r.Check(context.Background())[0]; s.Status != StatusUnknown {
+		t.Errorf("Expected unknown without calls or probe, got %+v", s)
+	}
+
+	r.Record(IONOS, errors.New("timeout"))
+	r.Record(IONOS, errors.New("timeout"))
+	r.Record(IONOS, nil)
+	s := r.Check(context.Background())[0]
+	if s.Calls != 3 || s.Errors != 2 || s.Status != StatusDegraded || s.LastError != "timeout" || s.Reachable != nil {
+		t.Errorf("Unexpected status: %+v", s)
+	}
+
+	// Calls age out of the 15 minute window, the last error stays
+	now = now.Add(16 * time.Minute)
+	r.Record(IONOS, nil)
+	s = r.Check(context.Background())[0]
+	if s.Calls != 1 || s.Errors != 0 || s.Status != StatusUp || s.LastErrorAt == nil {
+		t.Errorf("Expected old calls to age out, got %+v", s)
+	}
+}
+
+func TestCheckProbesAndCaches(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestRegistry(&now)
+
+	probes := 0
+	probeErr := errors.New("connection refused")
+	r.Register(Postgres, KindDatabase, func(ctx context.Context) error {
+		probes++
+		return probeErr
+	})
+
+	s := r.Check(context.Background())[0]
+	if s.Status != StatusDown || s.Reachable == nil || *s.Reachable || s.ProbeError != "connection refused" {
+		t.Errorf("Unexpected status for a failing probe: %+v", s)
+	}
+
+	probeErr = nil
+	r.Check(context.Background())
+	if probes != 1 {
+		t.Errorf("Expected the probe result to be cached, probed %d times", probes)
+	}
+
+	now = now.Add(probeCacheTTL)
+	if s := r.Check(context.Background())[0]; s.Status != StatusUp || !*s.Reachable || probes != 2 {
+		t.Errorf("Expected a fresh successful probe, got %+v after %d probes", s, probes)
+	}
+}
+
+func TestTransportRecordsOutcomes(t *testing.T) {
+	codes := []int{http.StatusOK, http.StatusNotFound, http.StatusTooManyRequests, http.StatusBadGateway}
+	i := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(codes[i])
+		i++
+	}))
+	defer server.Close()
+
+	r := NewRegistry()
+	client := &http.Client{Transport: r.Transport(IONOS, nil)}
+	for range codes {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	s := r.Check(context.Background())[0]
+	if s.Calls != 4 || s.Errors != 2 || s.LastError != "HTTP 502 Bad Gateway" {
+		t.Errorf("Unexpected status: %+v", s)
+	}
+}
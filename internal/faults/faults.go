@@ -0,0 +1,328 @@
+// Package faults injects failures into calls to the service's dependencies
+// so that backpressure, retries and circuit breakers can be exercised in
+// staging. It is test-only: nothing is injected unless the service enables
+// it by config and an operator sets a fault.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/dependencies"
+	"golang-backend-service/internal/resolver"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Targets faults can be set on, named like their dependencies
+const (
+	Postgres = dependencies.Postgres
+	IONOS    = dependencies.IONOS
+	DNS      = dependencies.DNS
+)
+
+var targets = []string{Postgres, IONOS, DNS}
+
+const (
+	defaultDuration = 10 * time.Minute
+	maxDuration     = time.Hour
+	maxLatency      = time.Minute
+)
+
+var faultsInjected = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "faults_injected_total",
+		Help: "Total number of dependency calls delayed or failed by failure injection, by target and effect (latency, error)",
+	},
+	[]string{"target", "effect"},
+)
+
+var (
+	// ErrDisabled is returned when setting a fault while injection is off
+	ErrDisabled = errors.New("failure injection is disabled")
+	// ErrUnknownTarget is returned for a target faults cannot be set on
+	ErrUnknownTarget = errors.New("unknown fault target")
+)
+
+// InjectedError is the error of a call failed by an injected fault
+type InjectedError struct {
+	Target string
+}
+
+// Error implements the error interface
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("injected %s failure", e.Target)
+}
+
+// Fault delays calls to a target and fails a share of them until it expires
+type Fault struct {
+	Target string `json:"target"`
+	// LatencyMS is added to every call
+	LatencyMS int `json:"latency_ms"`
+	// ErrorRate is the share of calls failed, between 0 and 1
+	ErrorRate float64 `json:"error_rate"`
+	// StatusCode is the response of failed HTTP calls, 500 by default; DNS
+	// calls time out and database calls return an InjectedError
+	StatusCode int       `json:"status_code,omitempty"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (f Fault) latency() time.Duration {
+	return time.Duration(f.LatencyMS) * time.Millisecond
+}
+
+// Injector holds the active faults. The zero value is not usable; create
+// one with NewInjector.
+type Injector struct {
+	mu      sync.Mutex
+	enabled bool
+	faults  map[string]Fault
+	now     func() time.Time
+	random  func() float64
+}
+
+// NewInjector creates a disabled injector without faults
+func NewInjector() *Injector {
+	return &Injector{
+		faults: make(map[string]Fault),
+		now:    time.Now,
+		random: rand.Float64,
+	}
+}
+
+// Default is the injector the service's dependency hooks consult
+var Default = NewInjector()
+
+// SetEnabled turns failure injection on or off; turning it off clears every
+// fault
+func (i *Injector) SetEnabled(enabled bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.enabled = enabled
+	if !enabled {
+		i.faults = make(map[string]Fault)
+	}
+}
+
+// Enabled reports whether faults can be set
+func (i *Injector) Enabled() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.enabled
+}
+
+// AllowedIn reports whether failure injection may be enabled in an
+// environment: anywhere but production
+func AllowedIn(environment string) bool {
+	switch strings.ToLower(environment) {
+	case "production", "prod":
+		return false
+	}
+	return true
+}
+
+// Targets returns the targets faults can be set on
+func Targets() []string {
+	return append([]string(nil), targets...)
+}
+
+func knownTarget(target string) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Set validates a fault and replaces the target's fault with it. A fault
+// lasts duration, 10 minutes if 0 and at most an hour.
+func (i *Injector) Set(f Fault, duration time.Duration) (Fault, error) {
+	if !i.Enabled() {
+		return Fault{}, ErrDisabled
+	}
+	if !knownTarget(f.Target) {
+		return Fault{}, ErrUnknownTarget
+	}
+	switch {
+	case f.LatencyMS < 0 || f.latency() > maxLatency:
+		return Fault{}, fmt.Errorf("latency_ms must be between 0 and %d", maxLatency.Milliseconds())
+	case f.ErrorRate < 0 || f.ErrorRate > 1:
+		return Fault{}, fmt.Errorf("error_rate must be between 0 and 1")
+	case f.LatencyMS == 0 && f.ErrorRate == 0:
+		return Fault{}, fmt.Errorf("latency_ms or error_rate is required")
+	case f.StatusCode != 0 && (f.StatusCode < 400 || f.StatusCode > 599):
+		return Fault{}, fmt.Errorf("status_code must be a 4xx or 5xx status")
+	case duration < 0 || duration > maxDuration:
+		return Fault{}, fmt.Errorf("duration must be at most %s", maxDuration)
+	}
+	if duration == 0 {
+		duration = defaultDuration
+	}
+	if f.StatusCode == 0 && f.Target == IONOS {
+		f.StatusCode = http.StatusInternalServerError
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !i.enabled {
+		return Fault{}, ErrDisabled
+	}
+	f.CreatedAt = i.now()
+	f.ExpiresAt = f.CreatedAt.Add(duration)
+	i.faults[f.Target] = f
+	return f, nil
+}
+
+// Clear removes a target's fault and reports whether it had one
+func (i *Injector) Clear(target string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, ok := i.active(target)
+	delete(i.faults, target)
+	return ok
+}
+
+// ClearAll removes every fault
+func (i *Injector) ClearAll() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults = make(map[string]Fault)
+}
+
+// List returns the unexpired faults by target
+func (i *Injector) List() []Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	faults := []Fault{}
+	for target := range i.faults {
+		if f, ok := i.active(target); ok {
+			faults = append(faults, f)
+		}
+	}
+	sort.Slice(faults, func(a, b int) bool { return faults[a].Target < faults[b].Target })
+	return faults
+}
+
+// active returns a target's unexpired fault, dropping an expired one;
+// callers hold mu
+func (i *Injector) active(target string) (Fault, bool) {
+	f, ok := i.faults[target]
+	if ok && !i.now().Before(f.ExpiresAt) {
+		delete(i.faults, target)
+		return Fault{}, false
+	}
+	return f, ok
+}
+
+// apply waits out a target's latency and reports whether the call fails,
+// never without a fault
+func (i *Injector) apply(ctx context.Context, target string) (f Fault, fail bool, err error) {
+	i.mu.Lock()
+	f, ok := i.active(target)
+	fail = ok && f.ErrorRate > 0 && i.random() < f.ErrorRate
+	i.mu.Unlock()
+	if !ok {
+		return Fault{}, false, nil
+	}
+
+	if latency := f.latency(); latency > 0 {
+		faultsInjected.WithLabelValues(target, "latency").Inc()
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return f, false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if fail {
+		faultsInjected.WithLabelValues(target, "error").Inc()
+	}
+	return f, fail, nil
+}
+
+// Inject applies a target's fault to a call: it waits out the latency and
+// returns an InjectedError for a failed call, or the context's error if it
+// ends first
+func (i *Injector) Inject(ctx context.Context, target string) error {
+	_, fail, err := i.apply(ctx, target)
+	if err != nil {
+		return err
+	}
+	if fail {
+		return &InjectedError{Target: target}
+	}
+	return nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Transport applies a target's fault to the requests made through base. A
+// failed request gets the fault's status code without reaching base. A nil
+// base uses http.DefaultTransport.
+func (i *Injector) Transport(target string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		f, fail, err := i.apply(req.Context(), target)
+		if err != nil {
+			return nil, err
+		}
+		if !fail {
+			return base.RoundTrip(req)
+		}
+		body := fmt.Sprintf(`{"error":"injected","message":"injected %s failure"}`, target)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+			StatusCode:    f.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	})
+}
+
+// injectingResolver applies a target's fault to DNS lookups
+type injectingResolver struct {
+	injector *Injector
+	target   string
+	resolver resolver.Resolver
+}
+
+// Resolver applies a target's fault to the lookups made through r. A failed
+// lookup times out without reaching r.
+func (i *Injector) Resolver(target string, r resolver.Resolver) resolver.Resolver {
+	return &injectingResolver{injector: i, target: target, resolver: r}
+}
+
+// LookupHost implements resolver.Resolver
+func (r *injectingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	_, fail, err := r.injector.apply(ctx, r.target)
+	if err != nil {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true, IsTemporary: true}
+	}
+	if fail {
+		return nil, &net.DNSError{Err: "i/o timeout (injected)", Name: host, IsTimeout: true, IsTemporary: true}
+	}
+	return r.resolver.LookupHost(ctx, host)
+}
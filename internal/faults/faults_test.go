@@ -0,0 +1,181 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type staticResolver struct{ calls int }
+
+func (r *staticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.calls++
+	return []string{"127.0.0.2"}, nil
+}
+
+func newTestInjector(now time.Time, random float64) *Injector {
+	i := NewInjector()
+	i.now = func() time.Time { return now }
+	i.random = func() float64 { return random }
+	i.SetEnabled(true)
+	return i
+}
+
+func TestSetValidation(t *testing.T) {
+	i := newTestInjector(time.Now(), 0)
+	for _, tc := range []struct {
+		name     string
+		fault    Fault
+		duration time.Duration
+		err      error
+	}{
+		{"unknown target", Fault{Target: "redis", ErrorRate: 1}, 0, ErrUnknownTarget},
+		{"negative latency", Fault{Target: Postgres, LatencyMS: -1}, 0, nil},
+		{"latency over a minute", Fault{Target: Postgres, LatencyMS: 60001}, 0, nil},
+		{"error rate over 1", Fault{Target: DNS, ErrorRate: 1.5}, 0, nil},
+		{"no effect", Fault{Target: DNS}, 0, nil},
+		{"status below 400", Fault{Target: IONOS, ErrorRate: 1, StatusCode: 302}, 0, nil},
+		{"duration over an hour", Fault{Target: IONOS, ErrorRate: 1}, 2 * time.Hour, nil},
+	} {
+		_, err := i.Set(tc.fault, tc.duration)
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		} else if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+		}
+	}
+	if faults := i.List(); len(faults) != 0 {
+		t.Errorf("Expected no faults after invalid sets, got %+v", faults)
+	}
+}
+
+func TestSetDefaultsAndExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	i := newTestInjector(now, 0)
+
+	f, err := i.Set(Fault{Target: IONOS, ErrorRate: 0.5}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.StatusCode != http.StatusInternalServerError || !f.ExpiresAt.Equal(now.Add(defaultDuration)) {
+		t.Errorf("Unexpected defaults: %+v", f)
+	}
+	if _, err := i.Set(Fault{Target: DNS, LatencyMS: 100}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if faults := i.List(); len(faults) != 2 || faults[0].Target != DNS || faults[1].Target != IONOS {
+		t.Fatalf("Expected dns and ionos faults by target, got %+v", faults)
+	}
+
+	now = now.Add(2 * time.Minute)
+	i.now = func() time.Time { return now }
+	if faults := i.List(); len(faults) != 1 || faults[0].Target != IONOS {
+		t.Errorf("Expected the dns fault to expire, got %+v", faults)
+	}
+	if i.Clear(DNS) {
+		t.Error("Expected clearing an expired fault to report none")
+	}
+	if !i.Clear(IONOS) || len(i.List()) != 0 {
+		t.Error("Expected the ionos fault to be cleared")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	i := newTestInjector(time.Now(), 0)
+	if _, err := i.Set(Fault{Target: Postgres, ErrorRate: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	i.SetEnabled(false)
+	if _, err := i.Set(Fault{Target: Postgres, ErrorRate: 1}, 0); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Expected ErrDisabled, got %v", err)
+	}
+	if err := i.Inject(context.Background(), Postgres); err != nil {
+		t.Errorf("Expected disabling to clear the fault, got %v", err)
+	}
+}
+
+func TestInject(t *testing.T) {
+	i := newTestInjector(time.Now(), 0.3)
+	if err := i.Inject(context.Background(), Postgres); err != nil {
+		t.Errorf("Expected no error without a fault, got %v", err)
+	}
+
+	i.Set(Fault{Target: Postgres, ErrorRate: 0.5}, 0)
+	var injected *InjectedError
+	if err := i.Inject(context.Background(), Postgres); !errors.As(err, &injected) || injected.Target != Postgres {
+		t.Errorf("Expected an injected postgres error, got %v", err)
+	}
+	i.Set(Fault{Target: Postgres, ErrorRate: 0.2}, 0)
+	if err := i.Inject(context.Background(), Postgres); err != nil {
+		t.Errorf("Expected a call above the error rate to pass, got %v", err)
+	}
+
+	i.Set(Fault{Target: Postgres, LatencyMS: 60000}, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := i.Inject(ctx, Postgres); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the latency to end with the context, got %v", err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	reached := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	i := newTestInjector(time.Now(), 0)
+	client := &http.Client{Transport: i.Transport(IONOS, nil)}
+	i.Set(Fault{Target: IONOS, ErrorRate: 1, StatusCode: http.StatusServiceUnavailable}, 0)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || reached != 0 {
+		t.Errorf("Expected an injected 503 without reaching the server, got %d after %d requests", resp.StatusCode, reached)
+	}
+
+	i.Clear(IONOS)
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || reached != 1 {
+		t.Errorf("Expected the request to reach the server once cleared, got %d", resp.StatusCode)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	i := newTestInjector(time.Now(), 0)
+	base := &staticResolver{}
+	r := i.Resolver(DNS, base)
+	i.Set(Fault{Target: DNS, ErrorRate: 1}, 0)
+
+	_, err := r.LookupHost(context.Background(), "2.0.0.127.zen.spamhaus.org")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsTimeout || base.calls != 0 {
+		t.Errorf("Expected an injected timeout without a lookup, got %v after %d lookups", err, base.calls)
+	}
+
+	i.Clear(DNS)
+	if addrs, err := r.LookupHost(context.Background(), "2.0.0.127.zen.spamhaus.org"); err != nil || len(addrs) != 1 {
+		t.Errorf("Expected the lookup to pass once cleared, got %v, %v", addrs, err)
+	}
+}
+
+func TestAllowedIn(t *testing.T) {
+	for env, want := range map[string]bool{"development": true, "staging": true, "production": false, "PROD": false} {
+		if got := AllowedIn(env); got != want {
+			t.Errorf("AllowedIn(%q) = %v, want %v", env, got, want)
+		}
+	}
+}
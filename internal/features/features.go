@@ -0,0 +1,286 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Flag names
+const (
+	// AutoRotation lets the service swap a burned IP for a clean reserve IP
+	// without an operator
+	AutoRotation = "auto_rotation"
+	// StrictWebhookAuth rejects webhook deliveries without a source token
+	StrictWebhookAuth = "strict_webhook_auth"
+	// ShadowRules evaluates the shadow reputation config next to the live one
+	ShadowRules = "shadow_rules"
+	// FailureCounters aggregates windows from the failure counters kept at
+	// ingestion instead of raw failures
+	FailureCounters = "failure_counters"
+)
+
+// Flag sources, from lowest to highest precedence
+const (
+	SourceDefault  = "default"
+	SourceConfig   = "config"
+	SourceDatabase = "database"
+)
+
+// ErrUnknownFlag is returned for names that are not defined flags
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+type definition struct {
+	description string
+	enabled     bool
+}
+
+var definitions = map[string]definition{
+	AutoRotation:      {description: "Swap burned IPs for clean reserve IPs automatically", enabled: false},
+	StrictWebhookAuth: {description: "Reject webhook deliveries without a source token", enabled: false},
+	ShadowRules:       {description: "Evaluate the shadow reputation config next to the live one", enabled: true},
+	FailureCounters:   {description: "Aggregate windows from failure counters instead of raw failures", enabled: true},
+}
+
+// Flag is the effective value of a flag and where it came from
+type Flag struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Default     bool       `json:"default"`
+	Source      string     `json:"source"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// Store persists runtime overrides and their audit trail
+type Store interface {
+	ListFeatureFlagOverrides() ([]database.FeatureFlagOverride, error)
+	SetFeatureFlag(change *database.FeatureFlagChange) (*database.FeatureFlagChange, error)
+	ListFeatureFlagChanges(name string, limit int) ([]database.FeatureFlagChange, error)
+}
+
+type dbStore struct{}
+
+func (dbStore) ListFeatureFlagOverrides() ([]database.FeatureFlagOverride, error) {
+	return database.ListFeatureFlagOverrides()
+}
+
+func (dbStore) SetFeatureFlag(change *database.FeatureFlagChange) (*database.FeatureFlagChange, error) {
+	return database.SetFeatureFlag(change)
+}
+
+func (dbStore) ListFeatureFlagChanges(name string, limit int) ([]database.FeatureFlagChange, error) {
+	return database.ListFeatureFlagChanges(name, limit)
+}
+
+// Set holds the flags of the service. Values come from the built-in
+// defaults, then the config, then runtime overrides in the database, which
+// are reloaded periodically so toggles reach every instance.
+type Set struct {
+	store Store
+
+	mu        sync.RWMutex
+	config    map[string]bool
+	overrides map[string]database.FeatureFlagOverride
+
+	runMu    sync.Mutex
+	ticker   *time.Ticker
+	stopChan chan bool
+	running  bool
+}
+
+// New creates a flag set backed by store; a nil store keeps overrides in
+// memory only
+func New(store Store) *Set {
+	return &Set{
+		store:     store,
+		config:    map[string]bool{},
+		overrides: map[string]database.FeatureFlagOverride{},
+		stopChan:  make(chan bool),
+	}
+}
+
+// Default is the flag set the service queries
+var Default = New(dbStore{})
+
+// Enabled reports whether a flag is on in the default set
+func Enabled(name string) bool {
+	return Default.Enabled(name)
+}
+
+// Configure sets config defaults, e.g. {"auto_rotation": true}
+func (s *Set) Configure(config map[string]bool) error {
+	for name := range config {
+		if _, ok := definitions[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = make(map[string]bool, len(config))
+	for name, enabled := range config {
+		s.config[name] = enabled
+	}
+	return nil
+}
+
+// Enabled reports whether a flag is on; unknown flags are off
+func (s *Set) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.flag(name).Enabled
+}
+
+// flag resolves one defined flag; callers hold mu
+func (s *Set) flag(name string) Flag {
+	def := definitions[name]
+	f := Flag{Name: name, Description: def.description, Enabled: def.enabled, Default: def.enabled, Source: SourceDefault}
+	if enabled, ok := s.config[name]; ok {
+		f.Enabled, f.Default, f.Source = enabled, enabled, SourceConfig
+	}
+	if o, ok := s.overrides[name]; ok {
+		updatedAt := o.UpdatedAt
+		f.Enabled, f.Source = o.Enabled, SourceDatabase
+		f.UpdatedBy, f.UpdatedAt = o.UpdatedBy, &updatedAt
+	}
+	return f
+}
+
+// List returns every defined flag by name
+func (s *Set) List() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flags := make([]Flag, 0, len(definitions))
+	for name := range definitions {
+		flags = append(flags, s.flag(name))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// Get returns one flag
+func (s *Set) Get(name string) (Flag, error) {
+	if _, ok := definitions[name]; !ok {
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.flag(name), nil
+}
+
+// Refresh reloads the runtime overrides from the store
+func (s *Set) Refresh() error {
+	if s.store == nil {
+		return nil
+	}
+	overrides, err := s.store.ListFeatureFlagOverrides()
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]database.FeatureFlagOverride, len(overrides))
+	for _, o := range overrides {
+		if _, ok := definitions[o.Name]; ok {
+			loaded[o.Name] = o
+		}
+	}
+	s.mu.Lock()
+	s.overrides = loaded
+	s.mu.Unlock()
+	return nil
+}
+
+// Toggle sets a flag at runtime and records who changed it
+func (s *Set) Toggle(name string, enabled bool, author, comment string) (Flag, *database.FeatureFlagChange, error) {
+	previous, err := s.Get(name)
+	if err != nil {
+		return Flag{}, nil, err
+	}
+
+	change := &database.FeatureFlagChange{
+		Name:      name,
+		Enabled:   enabled,
+		Previous:  previous.Enabled,
+		Author:    author,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	}
+	if s.store != nil {
+		if change, err = s.store.SetFeatureFlag(change); err != nil {
+			return Flag{}, nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.overrides[name] = database.FeatureFlagOverride{Name: name, Enabled: enabled, UpdatedBy: author, UpdatedAt: change.CreatedAt}
+	flag := s.flag(name)
+	s.mu.Unlock()
+	return flag, change, nil
+}
+
+// History returns the newest toggles of a flag first
+func (s *Set) History(name string, limit int) ([]database.FeatureFlagChange, error) {
+	if _, ok := definitions[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if s.store == nil {
+		return []database.FeatureFlagChange{}, nil
+	}
+	return s.store.ListFeatureFlagChanges(name, limit)
+}
+
+// Start loads the overrides and reloads them every interval
+func (s *Set) Start(interval time.Duration, logger *logrus.Logger) error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("feature flag refresh is already running")
+	}
+
+	refresh := func() {
+		if err := s.Refresh(); err != nil {
+			logger.WithFields(logrus.Fields{
+				"action": "feature_flag_refresh_failed",
+				"error":  err.Error(),
+			}).Warn("Failed to reload feature flags, keeping the previous values")
+		}
+	}
+	refresh()
+
+	s.ticker = time.NewTicker(interval)
+	s.running = true
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				refresh()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops reloading the overrides
+func (s *Set) Stop() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.ticker.Stop()
+	s.stopChan <- true
+	s.running = false
+}
@@ -0,0 +1,97 @@
+package features
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+type memoryStore struct {
+	overrides []database.FeatureFlagOverride
+	changes   []database.FeatureFlagChange
+	err       error
+}
+
+func (m *memoryStore) ListFeatureFlagOverrides() ([]database.FeatureFlagOverride, error) {
+	return m.overrides, m.err
+}
+
+func (m *memoryStore) SetFeatureFlag(change *database.FeatureFlagChange) (*database.FeatureFlagChange, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	created := *change
+	created.ID = len(m.changes) + 1
+	m.changes = append([]database.FeatureFlagChange{created}, m.changes...)
+	return &created, nil
+}
+
+func (m *memoryStore) ListFeatureFlagChanges(name string, limit int) ([]database.FeatureFlagChange, error) {
+	return m.changes, m.err
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	store := &memoryStore{overrides: []database.FeatureFlagOverride{
+		{Name: ShadowRules, Enabled: false, UpdatedBy: "user:alice", UpdatedAt: time.Now()},
+		{Name: "retired_flag", Enabled: true},
+	}}
+	flags := New(store)
+
+	if !flags.Enabled(ShadowRules) || flags.Enabled(AutoRotation) {
+		t.Fatal("Expected the built-in defaults before config and overrides")
+	}
+
+	if err := flags.Configure(map[string]bool{AutoRotation: true}); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := flags.Get(AutoRotation); !f.Enabled || f.Source != SourceConfig {
+		t.Errorf("Expected auto_rotation on from config, got %+v", f)
+	}
+
+	if err := flags.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := flags.Get(ShadowRules)
+	if f.Enabled || !f.Default || f.Source != SourceDatabase || f.UpdatedBy != "user:alice" {
+		t.Errorf("Expected the stored override to win, got %+v", f)
+	}
+	if flags.Enabled("retired_flag") || len(flags.List()) != 4 {
+		t.Errorf("Expected overrides of unknown flags to be ignored, got %+v", flags.List())
+	}
+}
+
+func TestConfigureRejectsUnknownFlags(t *testing.T) {
+	if err := New(nil).Configure(map[string]bool{"auto_rotaton": true}); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Expected ErrUnknownFlag, got %v", err)
+	}
+}
+
+func TestToggleRecordsChange(t *testing.T) {
+	store := &memoryStore{}
+	flags := New(store)
+
+	flag, change, err := flags.Toggle(StrictWebhookAuth, true, "user:bob", "all instances have tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !flag.Enabled || flag.Source != SourceDatabase || !flags.Enabled(StrictWebhookAuth) {
+		t.Errorf("Expected the toggle to apply immediately, got %+v", flag)
+	}
+	if change.ID != 1 || change.Previous || !change.Enabled || change.Author != "user:bob" {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+
+	if _, _, err := flags.Toggle("nope", true, "user:bob", ""); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Expected ErrUnknownFlag, got %v", err)
+	}
+
+	store.err = errors.New("connection refused")
+	if _, _, err := flags.Toggle(StrictWebhookAuth, false, "user:bob", ""); err == nil {
+		t.Fatal("Expected the store error")
+	}
+	if !flags.Enabled(StrictWebhookAuth) {
+		t.Error("Expected a failed toggle to keep the previous value")
+	}
+}
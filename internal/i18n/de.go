@@ -0,0 +1,23 @@
+package i18n
+
+var german = catalog{
+	decimalSeparator: ",",
+	messages: map[string]string{
+		"status_summary.blacklisted": "KRITISCH: IP %[1]s steht auf einer BLACKLIST. Ablehnungsquote: %[2]s %%, %[3]d Domains lehnen ab, %[4]d große Anbieter lehnen ab. Sofortiges Handeln erforderlich.",
+		"status_summary.quarantine":  "WARNUNG: IP %[1]s ist in QUARANTÄNE. Ablehnungsquote: %[2]s %%, %[3]d Domains lehnen ab. Hohes Risiko, muss untersucht werden.",
+		"status_summary.warning":     "ACHTUNG: IP %[1]s hat den Status WARNUNG. Ablehnungsquote: %[2]s %%. Genau beobachten.",
+		"status_summary.healthy":     "OK: IP %[1]s ist in ORDNUNG. Ablehnungsquote: %[2]s %%.",
+
+		"recommendation.immediate_quarantine":       "IP sofort unter Quarantäne stellen",
+		"recommendation.swap_to_backup_ip":          "Verkehr auf eine Ersatz-IP verlagern",
+		"recommendation.run_dnsbl_checks":           "DNSBL-Prüfungen ausführen",
+		"recommendation.alert_ops_critical":         "Ops-Team alarmieren (kritisch)",
+		"recommendation.investigate_root_cause":     "Ursache untersuchen",
+		"recommendation.reduce_traffic_50_percent":  "Verkehr der IP halbieren",
+		"recommendation.alert_ops_warning":          "Ops-Team alarmieren (Warnung)",
+		"recommendation.monitor_closely":            "IP genau beobachten",
+		"recommendation.reduce_send_rate":           "Senderate senken",
+		"recommendation.check_email_list_hygiene":   "Empfängerlisten auf ungültige Adressen prüfen",
+		"recommendation.continue_normal_operations": "Normalbetrieb fortsetzen",
+	},
+}
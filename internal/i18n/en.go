@@ -0,0 +1,23 @@
+package i18n
+
+var english = catalog{
+	decimalSeparator: ".",
+	messages: map[string]string{
+		"status_summary.blacklisted": "CRITICAL: IP %[1]s is BLACKLISTED. Rejection ratio: %[2]s%%, %[3]d unique domains rejected, %[4]d major providers rejecting. Immediate action required.",
+		"status_summary.quarantine":  "WARNING: IP %[1]s is QUARANTINED. Rejection ratio: %[2]s%%, %[3]d unique domains rejected. High risk, needs investigation.",
+		"status_summary.warning":     "CAUTION: IP %[1]s has WARNING status. Rejection ratio: %[2]s%%. Monitor closely.",
+		"status_summary.healthy":     "OK: IP %[1]s is HEALTHY. Rejection ratio: %[2]s%%.",
+
+		"recommendation.immediate_quarantine":       "Quarantine the IP immediately",
+		"recommendation.swap_to_backup_ip":          "Move its traffic to a backup IP",
+		"recommendation.run_dnsbl_checks":           "Run the DNSBL checks",
+		"recommendation.alert_ops_critical":         "Alert the ops team (critical)",
+		"recommendation.investigate_root_cause":     "Investigate the root cause",
+		"recommendation.reduce_traffic_50_percent":  "Cut the IP's traffic by half",
+		"recommendation.alert_ops_warning":          "Alert the ops team (warning)",
+		"recommendation.monitor_closely":            "Monitor the IP closely",
+		"recommendation.reduce_send_rate":           "Lower the send rate",
+		"recommendation.check_email_list_hygiene":   "Check the recipient lists for invalid addresses",
+		"recommendation.continue_normal_operations": "Continue normal operations",
+	},
+}
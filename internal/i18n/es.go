@@ -0,0 +1,23 @@
+package i18n
+
+var spanish = catalog{
+	decimalSeparator: ",",
+	messages: map[string]string{
+		"status_summary.blacklisted": "CRÍTICO: la IP %[1]s está en LISTA NEGRA. Tasa de rechazo: %[2]s %%, %[3]d dominios rechazan, %[4]d grandes proveedores rechazan. Se requiere acción inmediata.",
+		"status_summary.quarantine":  "AVISO: la IP %[1]s está en CUARENTENA. Tasa de rechazo: %[2]s %%, %[3]d dominios rechazan. Riesgo alto, requiere investigación.",
+		"status_summary.warning":     "PRECAUCIÓN: la IP %[1]s tiene estado de AVISO. Tasa de rechazo: %[2]s %%. Vigilar de cerca.",
+		"status_summary.healthy":     "OK: la IP %[1]s está SANA. Tasa de rechazo: %[2]s %%.",
+
+		"recommendation.immediate_quarantine":       "Poner la IP en cuarentena de inmediato",
+		"recommendation.swap_to_backup_ip":          "Pasar su tráfico a una IP de respaldo",
+		"recommendation.run_dnsbl_checks":           "Ejecutar las comprobaciones DNSBL",
+		"recommendation.alert_ops_critical":         "Alertar al equipo de operaciones (crítico)",
+		"recommendation.investigate_root_cause":     "Investigar la causa",
+		"recommendation.reduce_traffic_50_percent":  "Reducir a la mitad el tráfico de la IP",
+		"recommendation.alert_ops_warning":          "Alertar al equipo de operaciones (aviso)",
+		"recommendation.monitor_closely":            "Vigilar la IP de cerca",
+		"recommendation.reduce_send_rate":           "Bajar la tasa de envío",
+		"recommendation.check_email_list_hygiene":   "Revisar las listas de destinatarios en busca de direcciones no válidas",
+		"recommendation.continue_normal_operations": "Continuar con las operaciones normales",
+	},
+}
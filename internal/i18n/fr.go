@@ -0,0 +1,23 @@
+package i18n
+
+var french = catalog{
+	decimalSeparator: ",",
+	messages: map[string]string{
+		"status_summary.blacklisted": "CRITIQUE : l'IP %[1]s est sur LISTE NOIRE. Taux de rejet : %[2]s %%, %[3]d domaines rejettent, %[4]d grands fournisseurs rejettent. Action immédiate requise.",
+		"status_summary.quarantine":  "AVERTISSEMENT : l'IP %[1]s est en QUARANTAINE. Taux de rejet : %[2]s %%, %[3]d domaines rejettent. Risque élevé, à analyser.",
+		"status_summary.warning":     "ATTENTION : l'IP %[1]s est en statut AVERTISSEMENT. Taux de rejet : %[2]s %%. À surveiller de près.",
+		"status_summary.healthy":     "OK : l'IP %[1]s est SAINE. Taux de rejet : %[2]s %%.",
+
+		"recommendation.immediate_quarantine":       "Mettre l'IP en quarantaine immédiatement",
+		"recommendation.swap_to_backup_ip":          "Basculer son trafic sur une IP de secours",
+		"recommendation.run_dnsbl_checks":           "Lancer les vérifications DNSBL",
+		"recommendation.alert_ops_critical":         "Alerter l'équipe ops (critique)",
+		"recommendation.investigate_root_cause":     "Rechercher la cause",
+		"recommendation.reduce_traffic_50_percent":  "Réduire de moitié le trafic de l'IP",
+		"recommendation.alert_ops_warning":          "Alerter l'équipe ops (avertissement)",
+		"recommendation.monitor_closely":            "Surveiller l'IP de près",
+		"recommendation.reduce_send_rate":           "Baisser le débit d'envoi",
+		"recommendation.check_email_list_hygiene":   "Vérifier les listes de destinataires pour les adresses invalides",
+		"recommendation.continue_normal_operations": "Poursuivre les opérations normales",
+	},
+}
@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// English is the source language; every message exists in its catalog
+const English = "en"
+
+// catalog holds one language's messages, keyed like "status_summary.healthy".
+// Messages are fmt formats and may reorder their arguments with %[n]s.
+type catalog struct {
+	decimalSeparator string
+	messages         map[string]string
+}
+
+var catalogs = map[string]catalog{
+	English: english,
+	"de":    german,
+	"es":    spanish,
+	"fr":    french,
+}
+
+// Available returns the languages with a catalog, sorted
+func Available() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// IsAvailable reports whether language has a catalog
+func IsAvailable(language string) bool {
+	_, ok := catalogs[language]
+	return ok
+}
+
+// Translate formats the message key in language. Messages missing from the
+// language fall back to English, and unknown keys to the key itself.
+func Translate(language, key string, args ...interface{}) string {
+	format, ok := catalogs[language].messages[key]
+	if !ok {
+		if format, ok = english.messages[key]; !ok {
+			return key
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Exists reports whether key is a known message
+func Exists(key string) bool {
+	_, ok := english.messages[key]
+	return ok
+}
+
+// FormatPercent formats ratio as a percentage with two decimals and the
+// language's decimal separator, without the percent sign
+func FormatPercent(language string, ratio float64) string {
+	s := strconv.FormatFloat(ratio*100, 'f', 2, 64)
+	if sep := catalogs[language].decimalSeparator; sep != "" && sep != "." {
+		s = strings.Replace(s, ".", sep, 1)
+	}
+	return s
+}
+
+// ParseLanguages splits a comma separated list of language tags, lower-cased
+func ParseLanguages(s string) []string {
+	var languages []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			languages = append(languages, tag)
+		}
+	}
+	return languages
+}
+
+// Bundle is the set of languages a deployment serves
+type Bundle struct {
+	languages []string
+	fallback  string
+}
+
+// NewBundle serves languages, or every available catalog if empty, falling
+// back to fallback (English if empty) when a client accepts none of them
+func NewBundle(languages []string, fallback string) (*Bundle, error) {
+	if len(languages) == 0 {
+		languages = Available()
+	}
+	if fallback == "" {
+		fallback = English
+	}
+	fallback = strings.ToLower(fallback)
+
+	b := &Bundle{fallback: fallback}
+	seen := make(map[string]bool)
+	for _, language := range append(languages, fallback) {
+		if !IsAvailable(language) {
+			return nil, fmt.Errorf("no message catalog for language %q (available: %s)", language, strings.Join(Available(), ", "))
+		}
+		if !seen[language] {
+			seen[language] = true
+			b.languages = append(b.languages, language)
+		}
+	}
+	return b, nil
+}
+
+// Default serves every available language with an English fallback
+var Default, _ = NewBundle(nil, English)
+
+// Languages returns the served languages
+func (b *Bundle) Languages() []string {
+	return append([]string(nil), b.languages...)
+}
+
+// Fallback returns the language served when a client accepts none
+func (b *Bundle) Fallback() string {
+	return b.fallback
+}
+
+// Negotiate picks the served language an Accept-Language header prefers,
+// honouring q-values. A range such as de-CH matches de; * and an empty or
+// unmatched header get the fallback.
+func (b *Bundle) Negotiate(header string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if r.tag == "*" {
+			return b.fallback
+		}
+		// Truncate the range until it names a served language (RFC 4647 lookup)
+		for tag := r.tag; tag != ""; {
+			if b.serves(tag) {
+				return tag
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return b.fallback
+}
+
+func (b *Bundle) serves(language string) bool {
+	for _, l := range b.languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithLanguage returns a context carrying the language a response is
+// rendered in
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, contextKey{}, language)
+}
+
+// FromContext returns the context's language, English if none was set
+func FromContext(ctx context.Context) string {
+	if language, ok := ctx.Value(contextKey{}).(string); ok && language != "" {
+		return language
+	}
+	return English
+}
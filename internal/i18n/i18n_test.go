@@ -0,0 +1,94 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	bundle, err := NewBundle([]string{"en", "de", "fr"}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-CH", "de"},
+		{"FR-ca, en;q=0.8", "fr"},
+		{"es, fr;q=0.5", "fr"},
+		{"en;q=0.3, de;q=0.7", "de"},
+		{"de;q=0, fr", "fr"},
+		{"ja, *;q=0.1", "en"},
+		{"es", "en"},
+		{"de;q=abc, fr;q=0.2", "fr"},
+	}
+	for _, tt := range tests {
+		if got := bundle.Negotiate(tt.header); got != tt.expected {
+			t.Errorf("Negotiate(%q) = %q, expected %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestNewBundleValidation(t *testing.T) {
+	if _, err := NewBundle([]string{"en", "xx"}, "en"); err == nil {
+		t.Error("Expected a language without a catalog to be rejected")
+	}
+	if _, err := NewBundle([]string{"de"}, "ja"); err == nil {
+		t.Error("Expected a fallback without a catalog to be rejected")
+	}
+
+	bundle, err := NewBundle(ParseLanguages(" DE, fr ,"), "es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(bundle.Languages(), ","); got != "de,fr,es" {
+		t.Errorf("Expected the fallback to be served too, got %s", got)
+	}
+	if got := strings.Join(Default.Languages(), ","); got != strings.Join(Available(), ",") || Default.Fallback() != English {
+		t.Errorf("Expected the default bundle to serve every catalog, got %s", got)
+	}
+}
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for language, c := range catalogs {
+		for key := range english.messages {
+			if _, ok := c.messages[key]; !ok {
+				t.Errorf("%s: missing %s", language, key)
+			}
+		}
+		for key := range c.messages {
+			if _, ok := english.messages[key]; !ok {
+				t.Errorf("%s: %s is not an English message", language, key)
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("de", "status_summary.warning", "192.0.2.1", FormatPercent("de", 0.1234)); got != "ACHTUNG: IP 192.0.2.1 hat den Status WARNUNG. Ablehnungsquote: 12,34 %. Genau beobachten." {
+		t.Errorf("Unexpected German summary: %q", got)
+	}
+	if got := Translate("ja", "recommendation.monitor_closely"); got != "Monitor the IP closely" {
+		t.Errorf("Expected the English message for a language without a catalog, got %q", got)
+	}
+	if got := Translate("de", "unknown.key"); got != "unknown.key" {
+		t.Errorf("Expected an unknown key to be returned as is, got %q", got)
+	}
+	if got := FormatPercent(English, 0.05); got != "5.00" {
+		t.Errorf("Expected 5.00, got %s", got)
+	}
+}
+
+func TestContextLanguage(t *testing.T) {
+	if got := FromContext(context.Background()); got != English {
+		t.Errorf("Expected English without a language, got %s", got)
+	}
+	if got := FromContext(WithLanguage(context.Background(), "fr")); got != "fr" {
+		t.Errorf("Expected fr, got %s", got)
+	}
+}
@@ -0,0 +1,224 @@
+package ionos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotAttachable is returned for IPs that are released, quarantined or
+// blacklisted
+var ErrNotAttachable = errors.New("IP cannot be attached")
+
+// ErrNICNotFound is returned when the server has no NIC matching the request
+var ErrNICNotFound = errors.New("NIC not found")
+
+// NICProperties holds the properties of a server network interface
+type NICProperties struct {
+	Name string   `json:"name,omitempty"`
+	MAC  string   `json:"mac,omitempty"`
+	IPs  []string `json:"ips"`
+	LAN  int      `json:"lan,omitempty"`
+	DHCP *bool    `json:"dhcp,omitempty"`
+}
+
+// NIC represents an IONOS server network interface
+type NIC struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	Href       string        `json:"href"`
+	Properties NICProperties `json:"properties"`
+}
+
+// NICsResponse is the response when listing the NICs of a server
+type NICsResponse struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Href  string `json:"href"`
+	Items []NIC  `json:"items"`
+}
+
+// ListNICs lists the network interfaces of a server
+func (c *Client) ListNICs(ctx context.Context, datacenterID, serverID string) (*NICsResponse, error) {
+	url := fmt.Sprintf("%s/datacenters/%s/servers/%s/nics?depth=1", c.baseURL, datacenterID, serverID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var nics NICsResponse
+	if err := json.Unmarshal(body, &nics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &nics, nil
+}
+
+// UpdateNICIPs replaces the IPs of a server NIC. IONOS applies the change
+// asynchronously and answers 202.
+func (c *Client) UpdateNICIPs(ctx context.Context, datacenterID, serverID, nicID string, ips []string) (*NIC, error) {
+	bodyBytes, err := json.Marshal(NICProperties{IPs: ips})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/datacenters/%s/servers/%s/nics/%s", c.baseURL, datacenterID, serverID, nicID)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	c.logger.WithFields(logrus.Fields{
+		"action":        "update_nic_ips",
+		"datacenter_id": datacenterID,
+		"server_id":     serverID,
+		"nic_id":        nicID,
+		"ips":           ips,
+	}).Info("Updating NIC IPs")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var nic NIC
+	if err := json.Unmarshal(body, &nic); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &nic, nil
+}
+
+// AttachIPRequest names the server NIC to bind a reserved IP to. Without a
+// NICID the server's NIC in LANID is used, or its only NIC.
+type AttachIPRequest struct {
+	DatacenterID string `json:"datacenter_id"`
+	ServerID     string `json:"server_id"`
+	NICID        string `json:"nic_id,omitempty"`
+	LANID        int    `json:"lan_id,omitempty"`
+}
+
+// AttachIP binds a reserved IP to a server NIC, records the attachment in
+// the IP's metadata and marks it in use. Attaching an IP to a NIC that
+// already has it only updates the record.
+func (s *Service) AttachIP(ctx context.Context, ipID int, req AttachIPRequest) (*database.ReservedIP, error) {
+	ip, err := s.store.GetReservedIPByID(ipID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserved IP: %w", err)
+	}
+	if ip.IsBlacklisted || (ip.Status != "reserved" && ip.Status != "in_use") {
+		return nil, fmt.Errorf("%w: status is %s, blacklisted %t", ErrNotAttachable, ip.Status, ip.IsBlacklisted)
+	}
+
+	nics, err := s.client.ListNICs(ctx, req.DatacenterID, req.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server NICs: %w", err)
+	}
+	nic, err := selectNIC(nics.Items, req)
+	if err != nil {
+		return nil, err
+	}
+
+	attached := false
+	for _, existing := range nic.Properties.IPs {
+		if existing == ip.IPAddress {
+			attached = true
+			break
+		}
+	}
+	if !attached {
+		ips := append(append([]string{}, nic.Properties.IPs...), ip.IPAddress)
+		if _, err := s.client.UpdateNICIPs(ctx, req.DatacenterID, req.ServerID, nic.ID, ips); err != nil {
+			return nil, fmt.Errorf("failed to attach IP to NIC: %w", err)
+		}
+	}
+
+	attachment := map[string]interface{}{
+		"datacenter_id": req.DatacenterID,
+		"server_id":     req.ServerID,
+		"nic_id":        nic.ID,
+		"lan_id":        nic.Properties.LAN,
+		"attached_at":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := s.store.UpdateReservedIPAttachment(ipID, attachment, "server:"+req.ServerID); err != nil {
+		return nil, fmt.Errorf("failed to record attachment: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":    "attach_ip",
+		"ip":        ip.IPAddress,
+		"server_id": req.ServerID,
+		"nic_id":    nic.ID,
+		"lan_id":    nic.Properties.LAN,
+	}).Info("IP attached to server NIC")
+
+	return s.store.GetReservedIPByID(ipID)
+}
+
+// selectNIC picks the NIC an attach request refers to
+func selectNIC(nics []NIC, req AttachIPRequest) (*NIC, error) {
+	var matches []NIC
+	for _, nic := range nics {
+		switch {
+		case req.NICID != "":
+			if nic.ID == req.NICID {
+				matches = append(matches, nic)
+			}
+		case req.LANID != 0:
+			if nic.Properties.LAN == req.LANID {
+				matches = append(matches, nic)
+			}
+		default:
+			matches = append(matches, nic)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return &matches[0], nil
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%w: the server has %d NICs, set nic_id or lan_id", ErrNICNotFound, len(matches))
+	default:
+		return nil, ErrNICNotFound
+	}
+}
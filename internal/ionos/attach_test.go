@@ -0,0 +1,80 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/resolver"
+)
+
+// TestAttachIP tests that a reserved IP is added to the NIC and recorded as in use
+func TestAttachIP(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	mock.nics["dc-1/srv-1"] = []NIC{
+		{ID: "nic-public", Properties: NICProperties{IPs: []string{"203.0.113.50"}, LAN: 1}},
+		{ID: "nic-private", Properties: NICProperties{IPs: []string{"10.0.0.5"}, LAN: 2}},
+	}
+	ip := seedPoolIP(mock, store, "203.0.113.7", "reserved", "marketing")
+
+	attached, err := svc.AttachIP(context.Background(), ip.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-1", LANID: 1})
+	if err != nil {
+		t.Fatalf("AttachIP failed: %v", err)
+	}
+	if nicIPs := mock.nics["dc-1/srv-1"][0].Properties.IPs; len(nicIPs) != 2 || nicIPs[1] != "203.0.113.7" {
+		t.Errorf("Expected the IP added to the LAN 1 NIC, got %v", nicIPs)
+	}
+	if attached.Status != "in_use" || attached.AssignedTo == nil || *attached.AssignedTo != "server:srv-1" {
+		t.Errorf("Expected the IP in use by the server, got %+v", attached)
+	}
+	attachment, _ := attached.Metadata["attachment"].(map[string]interface{})
+	if attachment["nic_id"] != "nic-public" || attachment["datacenter_id"] != "dc-1" || attached.Metadata[PoolSpecTagKey] != "marketing" {
+		t.Errorf("Expected the attachment recorded next to the tag, got %+v", attached.Metadata)
+	}
+
+	// Attaching again does not add the IP twice
+	if _, err := svc.AttachIP(context.Background(), ip.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-1", NICID: "nic-public"}); err != nil {
+		t.Fatalf("AttachIP failed: %v", err)
+	}
+	if mock.nicUpdates != 1 {
+		t.Errorf("Expected one NIC update, got %d", mock.nicUpdates)
+	}
+}
+
+// TestAttachIPErrors tests the cases where an IP cannot be attached
+func TestAttachIPErrors(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	mock.nics["dc-1/srv-1"] = []NIC{{ID: "nic-a", Properties: NICProperties{LAN: 1}}, {ID: "nic-b", Properties: NICProperties{LAN: 2}}}
+	reserved := seedPoolIP(mock, store, "203.0.113.7", "reserved", "")
+	released := seedPoolIP(mock, store, "203.0.113.8", "released", "")
+
+	tests := []struct {
+		name string
+		id   int
+		req  AttachIPRequest
+		want error
+	}{
+		{"released IP", released.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-1", NICID: "nic-a"}, ErrNotAttachable},
+		{"ambiguous NIC", reserved.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-1"}, ErrNICNotFound},
+		{"unknown LAN", reserved.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-1", LANID: 9}, ErrNICNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.AttachIP(context.Background(), tt.id, tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	var apiErr *APIError
+	if _, err := svc.AttachIP(context.Background(), reserved.ID, AttachIPRequest{DatacenterID: "dc-1", ServerID: "srv-2", NICID: "nic-a"}); !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("Expected the IONOS 404 for an unknown server, got %v", err)
+	}
+	if ip, _ := store.GetReservedIPByID(reserved.ID); ip.Status != "reserved" {
+		t.Errorf("Expected a failed attach to leave the IP reserved, got %s", ip.Status)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"golang-backend-service/internal/resolver"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,11 +18,23 @@ type DNSBLChecker struct {
 	blacklists []string
 	ignored    []string
 	timeout    time.Duration
+	resolver   resolver.Resolver
 	logger     *logrus.Logger
 }
 
-// NewDNSBLChecker creates a new DNSBL checker
+// NewDNSBLChecker creates a new DNSBL checker using the system DNS
 func NewDNSBLChecker(logger *logrus.Logger) *DNSBLChecker {
+	return NewDNSBLCheckerWithResolver(logger, nil)
+}
+
+// NewDNSBLCheckerWithResolver creates a DNSBL checker that uses the given
+// resolver for lookups. A nil resolver falls back to the system DNS.
+func NewDNSBLCheckerWithResolver(logger *logrus.Logger, r resolver.Resolver) *DNSBLChecker {
+	timeout := 2 * time.Second
+	if r == nil {
+		r = resolver.New(timeout)
+	}
+
 	// Comprehensive list of DNSBLs (excluding UCEPROTECT and Invaluement per user requirements)
 	blacklists := []string{
 		// Spamhaus
@@ -59,7 +73,8 @@ func NewDNSBLChecker(logger *logrus.Logger) *DNSBLChecker {
 	return &DNSBLChecker{
 		blacklists: blacklists,
 		ignored:    ignored,
-		timeout:    2 * time.Second,
+		timeout:    timeout,
+		resolver:   r,
 		logger:     logger,
 	}
 }
@@ -139,26 +154,26 @@ func (c *DNSBLChecker) CheckIP(ctx context.Context, ip string) (*BlacklistResult
 // checkSingleBlacklist checks a single blacklist
 func (c *DNSBLChecker) checkSingleBlacklist(ctx context.Context, reversedIP, blacklist string) bool {
 	query := fmt.Sprintf("%s.%s", reversedIP, blacklist)
-	
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: c.timeout,
-			}
-			return d.DialContext(ctx, network, address)
-		},
-	}
 
 	// Create a context with timeout
 	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	_, err := resolver.LookupHost(timeoutCtx, query)
-	
-	// If resolution succeeds, the IP is listed
+	addrs, err := c.resolver.LookupHost(timeoutCtx, query)
+
+	// If resolution succeeds with a listing answer, the IP is listed
 	if err == nil {
-		return true
+		if resolver.IsListingResponse(addrs) {
+			return true
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"action":    "check_single_blacklist",
+			"blacklist": blacklist,
+			"query":     query,
+			"answers":   addrs,
+		}).Warn("Ignoring non-listing DNSBL answer")
+		return false
 	}
 
 	// Check if it's a timeout or other error
@@ -0,0 +1,114 @@
+package ionos
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/resolver"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestDNSBLCheckerWithFakeResolver tests listing detection against a fake resolver
+func TestDNSBLCheckerWithFakeResolver(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name               string
+		setup              func(f *resolver.Fake)
+		timeout            time.Duration
+		expectedBlacklists []string
+	}{
+		{
+			name:               "Not listed anywhere",
+			setup:              func(f *resolver.Fake) {},
+			expectedBlacklists: []string{},
+		},
+		{
+			name: "Listed on Spamhaus",
+			setup: func(f *resolver.Fake) {
+				f.Records["4.3.2.1.zen.spamhaus.org"] = []string{"127.0.0.2"}
+			},
+			expectedBlacklists: []string{"zen.spamhaus.org"},
+		},
+		{
+			name: "Spamhaus error code is not a listing",
+			setup: func(f *resolver.Fake) {
+				f.Records["4.3.2.1.zen.spamhaus.org"] = []string{"127.255.255.254"}
+			},
+			expectedBlacklists: []string{},
+		},
+		{
+			name: "Wildcard NXDOMAIN rewrite is not a listing",
+			setup: func(f *resolver.Fake) {
+				f.Wildcard = []string{"198.51.100.7"}
+			},
+			expectedBlacklists: []string{},
+		},
+		{
+			name: "Timeouts are treated as not listed",
+			setup: func(f *resolver.Fake) {
+				f.Delay = time.Second
+			},
+			timeout:            20 * time.Millisecond,
+			expectedBlacklists: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resolver.NewFake()
+			tt.setup(fake)
+
+			checker := NewDNSBLCheckerWithResolver(logger, fake)
+			if tt.timeout > 0 {
+				checker.timeout = tt.timeout
+			}
+
+			result, err := checker.CheckIP(context.Background(), "1.2.3.4")
+			if err != nil {
+				t.Fatalf("CheckIP failed: %v", err)
+			}
+
+			if len(result.Blacklists) != len(tt.expectedBlacklists) {
+				t.Fatalf("Expected blacklists %v, got %v", tt.expectedBlacklists, result.Blacklists)
+			}
+			for i, bl := range tt.expectedBlacklists {
+				if result.Blacklists[i] != bl {
+					t.Errorf("Expected blacklist %s, got %s", bl, result.Blacklists[i])
+				}
+			}
+
+			if result.IsBlacklisted != (len(tt.expectedBlacklists) > 0) {
+				t.Errorf("Unexpected IsBlacklisted=%v", result.IsBlacklisted)
+			}
+
+			if got := len(fake.Queries()); got != len(checker.blacklists) {
+				t.Errorf("Expected %d queries, got %d", len(checker.blacklists), got)
+			}
+		})
+	}
+}
+
+// FuzzReverseIP tests that only four-part addresses reverse and that
+// reversing twice gives them back
+func FuzzReverseIP(f *testing.F) {
+	for _, seed := range []string{"1.2.3.4", "127.0.0.2", "2001:db8::1", "1.2.3", "1.2.3.4.5", "...", "", "a.b.c.d"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, ip string) {
+		reversed, err := reverseIP(ip)
+		if (err == nil) != (strings.Count(ip, ".") == 3) {
+			t.Fatalf("reverseIP(%q) error = %v", ip, err)
+		}
+		if err != nil {
+			return
+		}
+		if back, err := reverseIP(reversed); err != nil || back != ip {
+			t.Errorf("reverseIP(reverseIP(%q)) = %q, %v", ip, back, err)
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBlockSize is the largest IP block a single reservation may request
+const MaxBlockSize = 16
+
+// Metadata keys recorded on every member IP of a multi-IP block
+const (
+	BlockSizeKey  = "block_size"
+	BlockIndexKey = "block_index"
+)
+
+// ErrIPInUse is returned when releasing an IP that is still attached
+var ErrIPInUse = errors.New("IP is in use")
+
+// ReserveCleanBlocks reserves count IONOS blocks of size IPs each. Every
+// member IP is checked against the blacklists; a block with more than
+// maxBlacklisted listed members is deleted and another one is tried, and the
+// listed members of a kept block are stored quarantined. A size of 1 is the
+// same as ReserveCleanIPs.
+func (s *Service) ReserveCleanBlocks(ctx context.Context, count, size int, location string, maxBlacklisted int) (*ReserveIPResponse, error) {
+	if size <= 1 {
+		return s.ReserveCleanIPs(ctx, count, location)
+	}
+	if size > MaxBlockSize {
+		return nil, fmt.Errorf("block size %d exceeds the maximum of %d", size, MaxBlockSize)
+	}
+	if location == "" {
+		location = s.defaultLocation
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":          "reserve_clean_blocks",
+		"count":           count,
+		"block_size":      size,
+		"max_blacklisted": maxBlacklisted,
+		"location":        location,
+	}).Info("Starting IP block reservation process")
+
+	// Quota is counted in blocks, whatever their size
+	quota, err := s.CheckQuota(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check quota: %w", err)
+	}
+	if quota.Remaining < count {
+		return nil, fmt.Errorf("%w: need %d blocks, have %d", ErrInsufficientQuota, count, quota.Remaining)
+	}
+
+	response := &ReserveIPResponse{
+		JobID:       uuid.New().String(),
+		BlockSize:   size,
+		BlockIDs:    []string{},
+		ReservedIPs: []database.ReservedIP{},
+		Attempts:    []database.ReservationAttempt{},
+	}
+	startedAt := time.Now()
+	requested := count * size
+
+	maxAttempts := count * 5
+	for attempt := 0; attempt < maxAttempts && len(response.BlockIDs) < count; attempt++ {
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("context cancelled: %w", ctx.Err())
+			s.publishCompleted(response, requested, location, startedAt, err)
+			return response, err
+		default:
+		}
+
+		result, err := s.reserveBlock(ctx, location, size, maxBlacklisted, nil)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to reserve IP block")
+			response.FailureCount++
+			continue
+		}
+
+		response.Attempts = append(response.Attempts, *result.Attempt)
+		response.BlacklistedCount += result.Listed
+		if result.Kept {
+			response.BlockIDs = append(response.BlockIDs, *result.Attempt.BlockID)
+			response.ReservedIPs = append(response.ReservedIPs, result.Members...)
+			response.SuccessCount += len(result.Members) - result.Listed
+		}
+
+		time.Sleep(s.reservationDelay)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":            "reserve_clean_blocks",
+		"blocks_reserved":   len(response.BlockIDs),
+		"success_count":     response.SuccessCount,
+		"blacklisted_count": response.BlacklistedCount,
+		"failure_count":     response.FailureCount,
+	}).Info("IP block reservation process completed")
+
+	s.publishCompleted(response, requested, location, startedAt, nil)
+	return response, nil
+}
+
+// reserveBlockResult is the outcome of reserving one multi-IP block
+type reserveBlockResult struct {
+	// Kept is false when the block was deleted for too many listed members
+	Kept    bool
+	Members []database.ReservedIP
+	Listed  int
+	Attempt *database.ReservationAttempt
+}
+
+// reserveBlock reserves one block of size IPs, checks each member and keeps
+// the block when at most maxBlacklisted members are listed
+func (s *Service) reserveBlock(ctx context.Context, location string, size, maxBlacklisted int, metadata map[string]interface{}) (*reserveBlockResult, error) {
+	startTime := time.Now()
+	attemptUID := uuid.New().String()[:8]
+	blockName := fmt.Sprintf("IP-Reserver-%s", attemptUID)
+
+	attempt := &database.ReservationAttempt{
+		AttemptUID:  attemptUID,
+		Location:    location,
+		AttemptedAt: startTime,
+		Metadata:    map[string]interface{}{BlockSizeKey: size},
+	}
+
+	block, err := s.client.ReserveIPBlock(ctx, location, size, blockName)
+	if err != nil {
+		s.recordFailedAttempt(attempt, startTime, err.Error())
+		return nil, fmt.Errorf("failed to reserve IP block: %w", err)
+	}
+
+	blockID := block.ID
+	attempt.BlockID = &blockID
+
+	if len(block.Properties.IPs) < size {
+		s.logger.Info("IPs not immediately available, waiting...")
+		time.Sleep(s.assignmentWait)
+		block, err = s.client.GetIPBlock(ctx, blockID)
+		if err != nil {
+			s.recordFailedAttempt(attempt, startTime, fmt.Sprintf("failed to retrieve block: %s", err.Error()))
+			return nil, fmt.Errorf("failed to retrieve IP block: %w", err)
+		}
+	}
+
+	if len(block.Properties.IPs) < size {
+		// Without all members the block cannot be tracked; give it back so it
+		// does not hold quota
+		if err := s.client.DeleteIPBlock(ctx, blockID); err != nil {
+			s.logger.WithError(err).Error("Failed to delete incomplete IP block")
+		}
+		s.recordFailedAttempt(attempt, startTime, fmt.Sprintf("%d of %d IPs assigned to block", len(block.Properties.IPs), size))
+		return nil, fmt.Errorf("only %d of %d IPs assigned to block %s", len(block.Properties.IPs), size, blockID)
+	}
+
+	ips := make([]string, size)
+	for i, ip := range block.Properties.IPs[:size] {
+		if normalized, err := validation.NormalizeIP(ip); err == nil {
+			ip = normalized
+		}
+		ips[i] = ip
+	}
+	attempt.IPAddress = &ips[0]
+
+	s.logger.WithFields(logrus.Fields{
+		"ips":      ips,
+		"block_id": blockID,
+	}).Info("IP block reserved, checking blacklist for each member")
+
+	checks := make([]*BlacklistResult, size)
+	listed := 0
+	found := []string{}
+	for i, ip := range ips {
+		check, err := s.blacklistCheck.CheckIP(ctx, ip)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to check blacklist")
+			check = &BlacklistResult{IP: ip, Blacklists: []string{}}
+		}
+		checks[i] = check
+		if check.IsBlacklisted {
+			listed++
+			found = appendMissing(found, check.Blacklists...)
+		}
+	}
+
+	attempt.WasBlacklisted = listed > 0
+	attempt.BlacklistsFound = found
+	attempt.Metadata["blacklisted_members"] = listed
+	completedAt := time.Now()
+	attempt.CompletedAt = &completedAt
+	duration := int(time.Since(startTime).Milliseconds())
+	attempt.DurationMs = &duration
+
+	if listed > maxBlacklisted {
+		s.logger.WithFields(logrus.Fields{
+			"block_id":        blockID,
+			"listed":          listed,
+			"max_blacklisted": maxBlacklisted,
+		}).Warn("Too many block members blacklisted, deleting block")
+
+		if err := s.client.DeleteIPBlock(ctx, blockID); err != nil {
+			s.logger.WithError(err).Error("Failed to delete blacklisted IP block")
+		}
+
+		attempt.Success = false
+		actionTaken := "deleted"
+		attempt.ActionTaken = &actionTaken
+		if err := s.store.CreateReservationAttempt(attempt); err != nil {
+			s.logger.WithError(err).Error("Failed to record reservation attempt")
+		}
+
+		return &reserveBlockResult{Listed: listed, Attempt: attempt}, nil
+	}
+
+	members := make([]database.ReservedIP, 0, size)
+	for i, ip := range ips {
+		member := &database.ReservedIP{
+			IPAddress:          ip,
+			ReservationBlockID: blockID,
+			UID:                fmt.Sprintf("%s-%d", attemptUID, i),
+			Location:           location,
+			Status:             "reserved",
+			IsBlacklisted:      checks[i].IsBlacklisted,
+			BlacklistDetails:   checks[i].Blacklists,
+			ReservedAt:         startTime,
+			Metadata:           map[string]interface{}{BlockSizeKey: size, BlockIndexKey: i},
+		}
+		if member.IsBlacklisted {
+			member.Status = "quarantined"
+		}
+		for k, v := range metadata {
+			member.Metadata[k] = v
+		}
+
+		if err := s.store.CreateReservedIP(member); err != nil {
+			s.logger.WithError(err).Error("Failed to store block member in database")
+
+			attempt.Success = false
+			failureReason := fmt.Sprintf("database error: %s", err.Error())
+			attempt.FailureReason = &failureReason
+			actionTaken := "kept_but_not_stored"
+			attempt.ActionTaken = &actionTaken
+			if dbErr := s.store.CreateReservationAttempt(attempt); dbErr != nil {
+				s.logger.WithError(dbErr).Error("Failed to record reservation attempt")
+			}
+
+			return nil, fmt.Errorf("failed to store reserved IP: %w", err)
+		}
+
+		entry := &database.BlacklistHistoryEntry{
+			ReservedIPID:    member.ID,
+			IPAddress:       ip,
+			CheckedAt:       time.Now(),
+			WasBlacklisted:  checks[i].IsBlacklisted,
+			BlacklistsFound: checks[i].Blacklists,
+			CheckDurationMs: int(checks[i].CheckDuration.Milliseconds()),
+			Metadata:        make(map[string]interface{}),
+		}
+		if err := s.store.CreateBlacklistHistoryEntry(entry); err != nil {
+			s.logger.WithError(err).Error("Failed to record blacklist history")
+		}
+
+		members = append(members, *member)
+	}
+
+	attempt.Success = true
+	actionTaken := "kept"
+	attempt.ActionTaken = &actionTaken
+	if err := s.store.CreateReservationAttempt(attempt); err != nil {
+		s.logger.WithError(err).Error("Failed to record reservation attempt")
+	}
+
+	return &reserveBlockResult{Kept: true, Members: members, Listed: listed, Attempt: attempt}, nil
+}
+
+// recordFailedAttempt stores an attempt that ended before the blacklist check
+func (s *Service) recordFailedAttempt(attempt *database.ReservationAttempt, startTime time.Time, reason string) {
+	attempt.Success = false
+	attempt.FailureReason = &reason
+	completedAt := time.Now()
+	attempt.CompletedAt = &completedAt
+	duration := int(time.Since(startTime).Milliseconds())
+	attempt.DurationMs = &duration
+
+	if err := s.store.CreateReservationAttempt(attempt); err != nil {
+		s.logger.WithError(err).Error("Failed to record reservation attempt")
+	}
+}
+
+// appendMissing appends the values not already in list
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		present := false
+		for _, existing := range list {
+			if existing == v {
+				present = true
+				break
+			}
+		}
+		if !present {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// ReleaseIP releases one reserved IP. The IONOS block is deleted only once
+// none of its members is reserved, in use or quarantined any more, so
+// releasing one member of a multi-IP block keeps the others; protected
+// blocks are never deleted. It reports whether the block was deleted. An
+// in-use IP must be detached first.
+func (s *Service) ReleaseIP(ctx context.Context, ipID int) (bool, error) {
+	ip, err := s.store.GetReservedIPByID(ipID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get reserved IP: %w", err)
+	}
+	if ip.Status == "in_use" {
+		return false, fmt.Errorf("%w: detach %s first", ErrIPInUse, ip.IPAddress)
+	}
+
+	held := false
+	if ip.ReservationBlockID != "" {
+		members, err := s.store.ListReservedIPsByBlock(ip.ReservationBlockID)
+		if err != nil {
+			return false, fmt.Errorf("failed to list block members: %w", err)
+		}
+		for _, member := range members {
+			if member.ID != ip.ID && member.Status != "released" {
+				held = true
+				break
+			}
+		}
+	}
+
+	if ip.ReservationBlockID != "" && !held {
+		// A protected block stays in IONOS when its last member is released
+		protected, err := s.protectedBlockIDs()
+		if err != nil {
+			return false, err
+		}
+		held = protected[ip.ReservationBlockID]
+	}
+
+	blockDeleted := false
+	if ip.ReservationBlockID != "" && !held && ip.Status != "released" {
+		// A block that is already gone was deleted by an earlier,
+		// interrupted release
+		var apiErr *APIError
+		if err := s.client.DeleteIPBlock(ctx, ip.ReservationBlockID); err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound) {
+			return false, fmt.Errorf("failed to delete IP block %s: %w", ip.ReservationBlockID, err)
+		}
+		blockDeleted = true
+	}
+
+	if ip.Status != "released" {
+		if err := s.store.ReleaseReservedIP(ip.ID); err != nil {
+			return blockDeleted, fmt.Errorf("failed to release reserved IP: %w", err)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":        "release_ip",
+		"ip":            ip.IPAddress,
+		"block_id":      ip.ReservationBlockID,
+		"block_deleted": blockDeleted,
+	}).Info("Reserved IP released")
+
+	return blockDeleted, nil
+}
@@ -0,0 +1,97 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/resolver"
+)
+
+// TestReserveCleanBlocksKeepsListedMembersQuarantined tests that a block within the blacklist allowance is kept whole
+func TestReserveCleanBlocksKeepsListedMembersQuarantined(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2", "198.51.100.3", "198.51.100.4")
+	defer mock.Close()
+	dns := resolver.NewFake()
+	dns.Records["3.100.51.198.zen.spamhaus.org"] = []string{"127.0.0.2"}
+	svc, store := newTestService(mock, dns, 50)
+
+	response, err := svc.ReserveCleanBlocks(context.Background(), 1, 4, "", 1)
+	if err != nil {
+		t.Fatalf("ReserveCleanBlocks failed: %v", err)
+	}
+	if len(response.BlockIDs) != 1 || len(response.ReservedIPs) != 4 || response.SuccessCount != 3 || response.BlacklistedCount != 1 {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+
+	members, _ := store.ListReservedIPsByBlock(response.BlockIDs[0])
+	if len(members) != 4 {
+		t.Fatalf("Expected 4 members sharing the block, got %d", len(members))
+	}
+	for i, member := range members {
+		if member.Metadata[BlockIndexKey] != i || member.Metadata[BlockSizeKey] != 4 {
+			t.Errorf("Expected block metadata on member %d, got %+v", i, member.Metadata)
+		}
+	}
+	if members[2].Status != "quarantined" || !members[2].IsBlacklisted || members[1].Status != "reserved" {
+		t.Errorf("Expected only the listed member quarantined, got %+v", members)
+	}
+	if len(store.history) != 4 || len(store.attempts) != 1 || !store.attempts[0].Success {
+		t.Errorf("Expected one history entry per member and one kept attempt, got %d and %+v", len(store.history), store.attempts)
+	}
+}
+
+// TestReserveCleanBlocksRetriesDirtyBlock tests that a block over the allowance is deleted and another reserved
+func TestReserveCleanBlocksRetriesDirtyBlock(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2", "203.0.113.1", "203.0.113.2")
+	defer mock.Close()
+	dns := resolver.NewFake()
+	dns.Records["2.100.51.198.zen.spamhaus.org"] = []string{"127.0.0.2"}
+	svc, store := newTestService(mock, dns, 50)
+
+	response, err := svc.ReserveCleanBlocks(context.Background(), 1, 2, "", 0)
+	if err != nil {
+		t.Fatalf("ReserveCleanBlocks failed: %v", err)
+	}
+	if len(mock.deleted) != 1 || mock.deleted[0] != "block-1" {
+		t.Errorf("Expected the dirty block deleted, got %v", mock.deleted)
+	}
+	if len(response.BlockIDs) != 1 || response.BlockIDs[0] != "block-2" || response.SuccessCount != 2 || response.BlacklistedCount != 1 {
+		t.Errorf("Expected the second block kept, got %+v", response)
+	}
+	if ips, _ := store.ListReservedIPs(nil, nil, nil); len(ips) != 2 {
+		t.Errorf("Expected only the kept block's members stored, got %d", len(ips))
+	}
+}
+
+// TestReleaseIPKeepsBlockWhileMembersHeld tests that a block is deleted only with its last held member
+func TestReleaseIPKeepsBlockWhileMembersHeld(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2")
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	response, err := svc.ReserveCleanBlocks(context.Background(), 1, 2, "", 0)
+	if err != nil {
+		t.Fatalf("ReserveCleanBlocks failed: %v", err)
+	}
+	first, second := response.ReservedIPs[0], response.ReservedIPs[1]
+	store.UpdateReservedIPAttachment(second.ID, map[string]interface{}{"server_id": "srv-1"}, "server:srv-1")
+
+	if _, err := svc.ReleaseIP(context.Background(), second.ID); !errors.Is(err, ErrIPInUse) {
+		t.Errorf("Expected ErrIPInUse for the attached member, got %v", err)
+	}
+
+	deleted, err := svc.ReleaseIP(context.Background(), first.ID)
+	if err != nil || deleted || len(mock.deleted) != 0 {
+		t.Fatalf("Expected the block kept while a member is in use, got deleted=%t err=%v", deleted, err)
+	}
+	if ip, _ := store.GetReservedIPByID(first.ID); ip.Status != "released" {
+		t.Errorf("Expected the member released, got %s", ip.Status)
+	}
+
+	store.ips[second.ID].Status = "reserved"
+	deleted, err = svc.ReleaseIP(context.Background(), second.ID)
+	if err != nil || !deleted || len(mock.deleted) != 1 || mock.deleted[0] != response.BlockIDs[0] {
+		t.Errorf("Expected the block deleted with its last member, got deleted=%t err=%v %v", deleted, err, mock.deleted)
+	}
+}
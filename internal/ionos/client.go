@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"time"
 
+	"golang-backend-service/internal/dependencies"
+	"golang-backend-service/internal/faults"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,12 +29,41 @@ func NewClient(baseURL, token string, logger *logrus.Logger) *Client {
 		baseURL: baseURL,
 		token:   token,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: dependencies.Default.Transport(dependencies.IONOS, faults.Default.Transport(faults.IONOS, nil)),
 		},
 		logger: logger,
 	}
 }
 
+// APIError is returned when IONOS responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// IsRateLimited reports whether IONOS rejected the request with 429
+func (e *APIError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether IONOS failed with a 5xx status
+func (e *APIError) IsServerError() bool {
+	return e.StatusCode >= 500
+}
+
 // IPBlockProperties holds the properties of an IP block
 type IPBlockProperties struct {
 	Name     string   `json:"name"`
@@ -116,7 +148,7 @@ func (c *Client) ReserveIPBlock(ctx context.Context, location string, size int,
 	}
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var ipBlock IPBlockResponse
@@ -156,7 +188,7 @@ func (c *Client) GetIPBlock(ctx context.Context, blockID string) (*IPBlockRespon
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var ipBlock IPBlockResponse
@@ -190,7 +222,7 @@ func (c *Client) ListIPBlocks(ctx context.Context) (*IPBlocksResponse, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var blocks IPBlocksResponse
@@ -225,7 +257,7 @@ func (c *Client) DeleteIPBlock(ctx context.Context, blockID string) error {
 
 	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return newAPIError(resp.StatusCode, body)
 	}
 
 	c.logger.WithFields(logrus.Fields{
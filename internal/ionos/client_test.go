@@ -0,0 +1,238 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/resolver"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+// TestClientHappyPath tests reserve, get and delete against the mock server
+func TestClientHappyPath(t *testing.T) {
+	mock := newMockIONOS("198.51.100.10")
+	defer mock.Close()
+
+	client := NewClient(mock.URL(), "test-token", newTestLogger())
+	ctx := context.Background()
+
+	block, err := client.ReserveIPBlock(ctx, "us/ewr", 1, "test-block")
+	if err != nil {
+		t.Fatalf("ReserveIPBlock failed: %v", err)
+	}
+	if len(block.Properties.IPs) != 1 || block.Properties.IPs[0] != "198.51.100.10" {
+		t.Errorf("Unexpected IPs: %v", block.Properties.IPs)
+	}
+	if mock.lastAuth != "Bearer test-token" {
+		t.Errorf("Expected bearer token, got %q", mock.lastAuth)
+	}
+
+	fetched, err := client.GetIPBlock(ctx, block.ID)
+	if err != nil {
+		t.Fatalf("GetIPBlock failed: %v", err)
+	}
+	if fetched.ID != block.ID {
+		t.Errorf("Expected block %s, got %s", block.ID, fetched.ID)
+	}
+
+	if err := client.DeleteIPBlock(ctx, block.ID); err != nil {
+		t.Fatalf("DeleteIPBlock failed: %v", err)
+	}
+
+	if _, err := client.GetIPBlock(ctx, block.ID); err == nil {
+		t.Error("Expected error fetching deleted block")
+	}
+}
+
+// TestClientErrorPaths tests status code, malformed body and timeout handling
+func TestClientErrorPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		failStatus  int
+		malformed   bool
+		delay       time.Duration
+		rateLimited bool
+		serverError bool
+	}{
+		{name: "Rate limited", failStatus: http.StatusTooManyRequests, rateLimited: true},
+		{name: "Internal server error", failStatus: http.StatusInternalServerError, serverError: true},
+		{name: "Service unavailable", failStatus: http.StatusServiceUnavailable, serverError: true},
+		{name: "Malformed JSON", malformed: true},
+		{name: "Slow response", delay: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockIONOS("198.51.100.20")
+			defer mock.Close()
+
+			// Seed a block so Get/Delete have something to address
+			client := NewClient(mock.URL(), "test-token", newTestLogger())
+			seeded, err := client.ReserveIPBlock(context.Background(), "us/ewr", 1, "seed")
+			if err != nil {
+				t.Fatalf("Seeding block failed: %v", err)
+			}
+
+			mock.mu.Lock()
+			mock.failStatus = tt.failStatus
+			mock.malformed = tt.malformed
+			mock.delay = tt.delay
+			mock.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			calls := map[string]error{}
+			_, calls["reserve"] = client.ReserveIPBlock(ctx, "us/ewr", 1, "test")
+			_, calls["get"] = client.GetIPBlock(ctx, seeded.ID)
+			calls["delete"] = client.DeleteIPBlock(ctx, seeded.ID)
+
+			for call, err := range calls {
+				if tt.malformed && call == "delete" {
+					// DELETE has no body to parse
+					if err != nil {
+						t.Errorf("%s: unexpected error: %v", call, err)
+					}
+					continue
+				}
+
+				if err == nil {
+					t.Errorf("%s: expected error", call)
+					continue
+				}
+
+				var apiErr *APIError
+				isAPIErr := errors.As(err, &apiErr)
+				if tt.failStatus != 0 {
+					if !isAPIErr {
+						t.Errorf("%s: expected APIError, got %v", call, err)
+						continue
+					}
+					if apiErr.StatusCode != tt.failStatus {
+						t.Errorf("%s: expected status %d, got %d", call, tt.failStatus, apiErr.StatusCode)
+					}
+					if apiErr.IsRateLimited() != tt.rateLimited || apiErr.IsServerError() != tt.serverError {
+						t.Errorf("%s: unexpected classification for %d", call, apiErr.StatusCode)
+					}
+				}
+
+				if tt.delay > 0 && !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("%s: expected deadline exceeded, got %v", call, err)
+				}
+			}
+		})
+	}
+}
+
+// newTestService builds a Service wired to the mock server, a fake resolver and a memory store
+func newTestService(mock *mockIONOS, dns *resolver.Fake, maxQuota int) (*Service, *memoryStore) {
+	logger := newTestLogger()
+	store := newMemoryStore()
+
+	svc := NewService(NewClient(mock.URL(), "test-token", logger), logger, "us/ewr", maxQuota)
+	svc.store = store
+	svc.blacklistCheck = NewDNSBLCheckerWithResolver(logger, dns)
+	svc.ptrResolver = dns
+	svc.reservationDelay = 0
+	svc.assignmentWait = 0
+	svc.cleanupDelay = 0
+
+	return svc, store
+}
+
+// TestReserveCleanIPsEndToEnd tests that blacklisted IPs are released and clean IPs kept
+func TestReserveCleanIPsEndToEnd(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2", "198.51.100.3")
+	defer mock.Close()
+
+	dns := resolver.NewFake()
+	// 198.51.100.1 is listed on Spamhaus
+	dns.Records["1.100.51.198.zen.spamhaus.org"] = []string{"127.0.0.2"}
+
+	svc, store := newTestService(mock, dns, 50)
+
+	response, err := svc.ReserveCleanIPs(context.Background(), 2, "")
+	if err != nil {
+		t.Fatalf("ReserveCleanIPs failed: %v", err)
+	}
+
+	if response.SuccessCount != 2 {
+		t.Errorf("Expected 2 clean IPs, got %d", response.SuccessCount)
+	}
+	if response.BlacklistedCount != 1 {
+		t.Errorf("Expected 1 blacklisted IP, got %d", response.BlacklistedCount)
+	}
+
+	if len(mock.deleted) != 1 || mock.deleted[0] != "block-1" {
+		t.Errorf("Expected the blacklisted block to be deleted, got %v", mock.deleted)
+	}
+
+	if len(store.ips) != 2 {
+		t.Errorf("Expected 2 stored IPs, got %d", len(store.ips))
+	}
+	if len(store.attempts) != 3 {
+		t.Errorf("Expected 3 recorded attempts, got %d", len(store.attempts))
+	}
+	if len(store.snapshots) != 1 {
+		t.Errorf("Expected a quota snapshot, got %d", len(store.snapshots))
+	}
+
+	for _, ip := range response.ReservedIPs {
+		if ip.Location != "us/ewr" || ip.Status != "reserved" {
+			t.Errorf("Unexpected reserved IP: %+v", ip)
+		}
+	}
+}
+
+// TestReserveCleanIPsQuotaExceeded tests that reservation is refused without quota
+func TestReserveCleanIPsQuotaExceeded(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1")
+	defer mock.Close()
+
+	svc, _ := newTestService(mock, resolver.NewFake(), 0)
+
+	if _, err := svc.ReserveCleanIPs(context.Background(), 1, ""); err == nil {
+		t.Error("Expected insufficient quota error")
+	}
+}
+
+// TestReserveCleanIPsUpstreamFailure tests that IONOS errors are counted as failures
+func TestReserveCleanIPsUpstreamFailure(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	// Let the quota check succeed, then fail every reservation
+	quotaSeen := false
+	mock.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && !quotaSeen {
+			quotaSeen = true
+			mock.handle(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	response, err := svc.ReserveCleanIPs(context.Background(), 1, "")
+	if err != nil {
+		t.Fatalf("ReserveCleanIPs failed: %v", err)
+	}
+
+	if response.SuccessCount != 0 || response.FailureCount != 5 {
+		t.Errorf("Expected 0 successes and 5 failures, got %d/%d", response.SuccessCount, response.FailureCount)
+	}
+	if len(store.attempts) != 5 {
+		t.Errorf("Expected 5 failed attempts recorded, got %d", len(store.attempts))
+	}
+}
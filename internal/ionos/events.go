@@ -0,0 +1,221 @@
+package ionos
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/dependencies"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reservation event types
+const (
+	EventReservationCompleted = "reservation.completed"
+	// Sent by the stale reservation policy for one idle IP, with its owners
+	EventReservationStale    = "reservation.stale"
+	EventReservationReleased = "reservation.auto_released"
+)
+
+// Reservation job outcomes
+const (
+	JobSucceeded = "succeeded" // Every requested IP was reserved
+	JobPartial   = "partial"   // Some IPs were reserved before attempts ran out
+	JobFailed    = "failed"    // No IP was reserved
+	JobCancelled = "cancelled" // The request was cancelled mid-job
+)
+
+const (
+	webhookQueueSize = 100
+	webhookTimeout   = 10 * time.Second
+	webhookAttempts  = 3
+)
+
+// ReservationEvent tells provisioning systems that a reservation job ended
+// and which clean IPs it produced. Stale policy events carry the one idle IP
+// and its owners, without job fields.
+type ReservationEvent struct {
+	ID               string                `json:"id"`
+	Type             string                `json:"type"`
+	JobID            string                `json:"job_id"`
+	Status           string                `json:"status"`
+	Location         string                `json:"location"`
+	Requested        int                   `json:"requested"`
+	BlockSize        int                   `json:"block_size,omitempty"`
+	SuccessCount     int                   `json:"success_count"`
+	BlacklistedCount int                   `json:"blacklisted_count"`
+	FailureCount     int                   `json:"failure_count"`
+	IPs              []database.ReservedIP `json:"ips"`
+	Owners           []database.IPOwner    `json:"owners,omitempty"`
+	Error            string                `json:"error,omitempty"`
+	StartedAt        time.Time             `json:"started_at"`
+	CompletedAt      time.Time             `json:"completed_at"`
+}
+
+// Publisher delivers reservation events. Publish must not block the
+// reservation job.
+type Publisher interface {
+	Publish(event ReservationEvent)
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(ReservationEvent) {}
+
+// WebhookPublisher POSTs reservation events as JSON to every configured URL
+// in the background. With a secret, each request carries
+// X-Signature-256: sha256=<hex HMAC-SHA256 of the body>. Failed deliveries
+// are retried with backoff, then logged and dropped; events are dropped when
+// the queue is full.
+type WebhookPublisher struct {
+	urls      []string
+	secret    []byte
+	client    *http.Client
+	logger    *logrus.Logger
+	queue     chan ReservationEvent
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// retryDelay doubles after every failed attempt; zeroed in tests
+	retryDelay time.Duration
+}
+
+// NewWebhookPublisher starts a publisher for urls
+func NewWebhookPublisher(urls []string, secret string, logger *logrus.Logger) *WebhookPublisher {
+	p := &WebhookPublisher{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: dependencies.Default.Transport(dependencies.ReservationWebhooks, nil),
+		},
+		logger:     logger,
+		queue:      make(chan ReservationEvent, webhookQueueSize),
+		retryDelay: time.Second,
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Publish queues event for delivery
+func (p *WebhookPublisher) Publish(event ReservationEvent) {
+	select {
+	case p.queue <- event:
+	default:
+		p.logger.WithFields(logrus.Fields{
+			"action":   "reservation_webhook_dropped",
+			"event_id": event.ID,
+			"job_id":   event.JobID,
+		}).Warn("Reservation webhook queue is full, dropping event")
+	}
+}
+
+// Close delivers the queued events and stops the publisher
+func (p *WebhookPublisher) Close() {
+	p.closeOnce.Do(func() { close(p.queue) })
+	p.wg.Wait()
+}
+
+func (p *WebhookPublisher) run() {
+	defer p.wg.Done()
+	for event := range p.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			p.logger.WithError(err).Error("Failed to encode reservation event")
+			continue
+		}
+		for _, target := range p.urls {
+			if err := p.deliver(target, event, body); err != nil {
+				p.logger.WithFields(logrus.Fields{
+					"action":   "reservation_webhook_failed",
+					"url":      target,
+					"event_id": event.ID,
+					"job_id":   event.JobID,
+					"error":    err.Error(),
+				}).Error("Failed to deliver reservation webhook")
+			}
+		}
+	}
+}
+
+// deliver POSTs body to target, retrying transport errors, 429 and 5xx
+func (p *WebhookPublisher) deliver(target string, event ReservationEvent, body []byte) error {
+	delay := p.retryDelay
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		if retry, err = p.post(target, event, body); err == nil || !retry {
+			return err
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", webhookAttempts, err)
+}
+
+func (p *WebhookPublisher) post(target string, event ReservationEvent, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-backend-service/1.0")
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Event-ID", event.ID)
+	if len(p.secret) > 0 {
+		req.Header.Set("X-Signature-256", "sha256="+Sign(p.secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// ParseWebhookURLs parses a comma-separated list of http(s) URLs
+func ParseWebhookURLs(raw string) ([]string, error) {
+	var urls []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		u, err := url.Parse(part)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q: must be http or https", part)
+		}
+		urls = append(urls, part)
+	}
+	return urls, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as sent in X-Signature-256
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
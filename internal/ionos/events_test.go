@@ -0,0 +1,128 @@
+package ionos
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/resolver"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []ReservationEvent
+}
+
+func (p *recordingPublisher) Publish(event ReservationEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+// TestReserveCleanIPsPublishesCompletion tests that a finished job announces its clean IPs
+func TestReserveCleanIPsPublishesCompletion(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2")
+	defer mock.Close()
+
+	svc, _ := newTestService(mock, resolver.NewFake(), 50)
+	publisher := &recordingPublisher{}
+	svc.SetPublisher(publisher)
+
+	response, err := svc.ReserveCleanIPs(context.Background(), 2, "de/fra")
+	if err != nil {
+		t.Fatalf("ReserveCleanIPs failed: %v", err)
+	}
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("Expected one event, got %d", len(publisher.events))
+	}
+	event := publisher.events[0]
+	if event.Type != EventReservationCompleted || event.Status != JobSucceeded || event.JobID != response.JobID {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Location != "de/fra" || event.Requested != 2 || len(event.IPs) != 2 || event.IPs[1].IPAddress != "198.51.100.2" {
+		t.Errorf("Expected the two reserved IPs, got %+v", event)
+	}
+}
+
+// TestReserveCleanIPsQuotaFailureNotPublished tests that a job that never started is not announced
+func TestReserveCleanIPsQuotaFailureNotPublished(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	mock.failStatus = http.StatusBadGateway
+
+	svc, _ := newTestService(mock, resolver.NewFake(), 50)
+	publisher := &recordingPublisher{}
+	svc.SetPublisher(publisher)
+
+	if _, err := svc.ReserveCleanIPs(context.Background(), 1, ""); err == nil {
+		t.Fatal("Expected the quota check to fail")
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("Expected no event for a job that never started, got %+v", publisher.events)
+	}
+}
+
+// TestWebhookPublisher tests signed delivery and retries
+func TestWebhookPublisher(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	var signatures []string
+	calls := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/flaky":
+			if calls[r.URL.Path] == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/rejected":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Event-Type") != EventReservationCompleted {
+			t.Errorf("Unexpected X-Event-Type %q", r.Header.Get("X-Event-Type"))
+		}
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get("X-Signature-256"))
+	}))
+	defer server.Close()
+
+	publisher := NewWebhookPublisher([]string{server.URL + "/flaky", server.URL + "/rejected"}, "s3cret", newTestLogger())
+	publisher.retryDelay = 0
+	publisher.Publish(ReservationEvent{ID: "evt-1", Type: EventReservationCompleted, JobID: "job-1", Status: JobSucceeded})
+	publisher.Close()
+
+	if calls["/flaky"] != 2 || calls["/rejected"] != 1 {
+		t.Errorf("Expected a retry after 503 and none after 400, got %v", calls)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one delivery, got %d", len(bodies))
+	}
+	if signatures[0] != "sha256="+Sign([]byte("s3cret"), bodies[0]) {
+		t.Errorf("Signature %q does not match the body", signatures[0])
+	}
+	var event ReservationEvent
+	if err := json.Unmarshal(bodies[0], &event); err != nil || event.JobID != "job-1" {
+		t.Errorf("Unexpected body %s: %v", bodies[0], err)
+	}
+}
+
+func TestParseWebhookURLs(t *testing.T) {
+	urls, err := ParseWebhookURLs(" https://ci.example.com/hook , http://10.0.0.5:8080/ips,")
+	if err != nil || len(urls) != 2 || urls[1] != "http://10.0.0.5:8080/ips" {
+		t.Errorf("ParseWebhookURLs = %v, %v", urls, err)
+	}
+	if _, err := ParseWebhookURLs("ftp://example.com"); err == nil {
+		t.Error("Expected an error for a non-http URL")
+	}
+}
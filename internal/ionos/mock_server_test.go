@@ -0,0 +1,490 @@
+package ionos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// mockIONOS is an in-memory stand-in for the IONOS Cloud ipblocks API
+type mockIONOS struct {
+	mu      sync.Mutex
+	server  *httptest.Server
+	blocks  map[string]IPBlock
+	nextIPs []string
+	nextID  int
+
+	// failStatus, if non-zero, is returned for every request
+	failStatus int
+	// malformed makes successful responses return invalid JSON
+	malformed bool
+	// delay is applied before every response
+	delay time.Duration
+	// deleted records the IDs of deleted blocks
+	deleted []string
+	// lastAuth records the last Authorization header seen
+	lastAuth string
+	// nics holds server NICs keyed by "datacenter/server"
+	nics map[string][]NIC
+	// nicUpdates counts NIC IP updates
+	nicUpdates int
+}
+
+// newMockIONOS starts a mock server that hands out the given IPs in order
+func newMockIONOS(ips ...string) *mockIONOS {
+	m := &mockIONOS{
+		blocks:  make(map[string]IPBlock),
+		nics:    make(map[string][]NIC),
+		nextIPs: ips,
+	}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	return m
+}
+
+func (m *mockIONOS) Close() {
+	m.server.Close()
+}
+
+func (m *mockIONOS) URL() string {
+	return m.server.URL
+}
+
+func (m *mockIONOS) handle(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	delay := m.delay
+	failStatus := m.failStatus
+	malformed := m.malformed
+	m.lastAuth = r.Header.Get("Authorization")
+	m.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if failStatus != 0 {
+		w.WriteHeader(failStatus)
+		fmt.Fprintf(w, `{"httpStatus":%d,"messages":[{"message":"mock failure"}]}`, failStatus)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/datacenters/") {
+		m.handleNICs(w, r, malformed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/ipblocks")
+	id := strings.TrimPrefix(path, "/")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && id == "":
+		var req ReserveIPBlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		m.nextID++
+		block := IPBlock{
+			ID:         fmt.Sprintf("block-%d", m.nextID),
+			Type:       "ipblock",
+			Properties: req.Properties,
+		}
+		size := req.Properties.Size
+		if size < 1 {
+			size = 1
+		}
+		if len(m.nextIPs) >= size {
+			block.Properties.IPs = append([]string{}, m.nextIPs[:size]...)
+			m.nextIPs = m.nextIPs[size:]
+		}
+		m.blocks[block.ID] = block
+		m.writeJSON(w, http.StatusAccepted, block, malformed)
+
+	case r.Method == http.MethodGet && id == "":
+		items := make([]IPBlock, 0, len(m.blocks))
+		for _, b := range m.blocks {
+			items = append(items, b)
+		}
+		m.writeJSON(w, http.StatusOK, IPBlocksResponse{ID: "ipblocks", Items: items}, malformed)
+
+	case r.Method == http.MethodGet:
+		block, ok := m.blocks[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		m.writeJSON(w, http.StatusOK, block, malformed)
+
+	case r.Method == http.MethodDelete:
+		if _, ok := m.blocks[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(m.blocks, id)
+		m.deleted = append(m.deleted, id)
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNICs serves /datacenters/{dc}/servers/{server}/nics[/{nic}]
+func (m *mockIONOS) handleNICs(w http.ResponseWriter, r *http.Request, malformed bool) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 5 || parts[2] != "servers" || parts[4] != "nics" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := parts[1] + "/" + parts[3]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nics, ok := m.nics[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 5:
+		m.writeJSON(w, http.StatusOK, NICsResponse{ID: parts[3] + "/nics", Items: nics}, malformed)
+
+	case r.Method == http.MethodPatch && len(parts) == 6:
+		var props NICProperties
+		if err := json.NewDecoder(r.Body).Decode(&props); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for i := range nics {
+			if nics[i].ID == parts[5] {
+				nics[i].Properties.IPs = props.IPs
+				m.nicUpdates++
+				m.writeJSON(w, http.StatusAccepted, nics[i], malformed)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *mockIONOS) writeJSON(w http.ResponseWriter, status int, v interface{}, malformed bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if malformed {
+		w.Write([]byte(`{"id": "broken`))
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+// memoryStore is an in-memory Store used to exercise the service without Postgres
+type memoryStore struct {
+	mu        sync.Mutex
+	nextID    int
+	ips       map[int]*database.ReservedIP
+	attempts  []database.ReservationAttempt
+	history   []database.BlacklistHistoryEntry
+	snapshots []database.QuotaSnapshot
+	protected []database.ProtectedBlock
+	owners    map[string][]database.IPOwner
+	actions   []database.IPAction
+	metrics   []database.IPReputationMetrics
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{ips: make(map[int]*database.ReservedIP), owners: make(map[string][]database.IPOwner)}
+}
+
+func (s *memoryStore) CreateReservedIP(ip *database.ReservedIP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	ip.ID = s.nextID
+	ip.CreatedAt = time.Now()
+	ip.UpdatedAt = ip.CreatedAt
+	copied := *ip
+	s.ips[ip.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) GetReservedIPByID(id int) (*database.ReservedIP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return nil, database.ErrReservedIPNotFound
+	}
+	copied := *ip
+	return &copied, nil
+}
+
+func (s *memoryStore) GetReservedIPByAddress(ipAddress string) (*database.ReservedIP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ip := range s.ips {
+		if ip.IPAddress == ipAddress {
+			copied := *ip
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrReservedIPNotFound
+}
+
+func (s *memoryStore) ListReservedIPs(status *string, isBlacklisted *bool, location *string) ([]database.ReservedIP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ips []database.ReservedIP
+	for _, ip := range s.ips {
+		if status != nil && ip.Status != *status {
+			continue
+		}
+		if isBlacklisted != nil && ip.IsBlacklisted != *isBlacklisted {
+			continue
+		}
+		if location != nil && ip.Location != *location {
+			continue
+		}
+		ips = append(ips, *ip)
+	}
+	return ips, nil
+}
+
+func (s *memoryStore) ListReservedIPsByBlock(blockID string) ([]database.ReservedIP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ips []database.ReservedIP
+	for _, ip := range s.ips {
+		if ip.ReservationBlockID == blockID {
+			ips = append(ips, *ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool { return ips[i].ID < ips[j].ID })
+	return ips, nil
+}
+
+func (s *memoryStore) UpdateReservedIPBlacklistStatus(id int, isBlacklisted bool, blacklists []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return fmt.Errorf("reserved IP not found")
+	}
+	ip.IsBlacklisted = isBlacklisted
+	ip.BlacklistDetails = blacklists
+	return nil
+}
+
+func (s *memoryStore) ReleaseReservedIP(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return fmt.Errorf("reserved IP not found")
+	}
+	now := time.Now()
+	ip.Status = "released"
+	ip.ReleasedAt = &now
+	ip.AssignedTo = nil
+	return nil
+}
+
+func (s *memoryStore) UpdateReservedIPAttachment(id int, attachment map[string]interface{}, assignedTo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return fmt.Errorf("reserved IP not found")
+	}
+	metadata := map[string]interface{}{"attachment": attachment}
+	for k, v := range ip.Metadata {
+		if k != "attachment" {
+			metadata[k] = v
+		}
+	}
+	ip.Metadata = metadata
+	ip.Status = "in_use"
+	ip.AssignedTo = &assignedTo
+	ip.UsageCount++
+	return nil
+}
+
+func (s *memoryStore) CreateReservationAttempt(attempt *database.ReservationAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, *attempt)
+	return nil
+}
+
+func (s *memoryStore) CreateBlacklistHistoryEntry(entry *database.BlacklistHistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, *entry)
+	return nil
+}
+
+func (s *memoryStore) CreateQuotaSnapshot(snapshot *database.QuotaSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, *snapshot)
+	return nil
+}
+
+func (s *memoryStore) ProtectBlock(block *database.ProtectedBlock) (*database.ProtectedBlock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for i := range s.protected {
+		if s.protected[i].BlockID == block.BlockID {
+			s.protected[i].Reason = block.Reason
+			s.protected[i].UpdatedAt = now
+			stored := s.protected[i]
+			return &stored, nil
+		}
+	}
+	stored := *block
+	stored.ID = len(s.protected) + 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.protected = append(s.protected, stored)
+	return &stored, nil
+}
+
+func (s *memoryStore) ListProtectedBlocks() ([]database.ProtectedBlock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]database.ProtectedBlock{}, s.protected...), nil
+}
+
+func (s *memoryStore) UnprotectBlock(blockID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.protected {
+		if s.protected[i].BlockID == blockID {
+			s.protected = append(s.protected[:i], s.protected[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrProtectedBlockNotFound
+}
+
+func (s *memoryStore) FlagReservedIPStale(id int, flaggedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return database.ErrReservedIPNotFound
+	}
+	metadata := map[string]interface{}{"stale_flagged_at": flaggedAt.UTC().Format(time.RFC3339)}
+	for k, v := range ip.Metadata {
+		if k != "stale_flagged_at" {
+			metadata[k] = v
+		}
+	}
+	ip.Metadata = metadata
+	return nil
+}
+
+func (s *memoryStore) GetIPOwners(ip string) ([]database.IPOwner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owners[ip], nil
+}
+
+func (s *memoryStore) InsertIPAction(action *database.IPAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	action.ID = len(s.actions) + 1
+	s.actions = append(s.actions, *action)
+	return nil
+}
+
+func (s *memoryStore) QuarantineReservedIP(id int, previousStatus string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return database.ErrReservedIPNotFound
+	}
+	metadata := map[string]interface{}{"reputation_previous_status": previousStatus}
+	for k, v := range ip.Metadata {
+		if k != "reputation_previous_status" {
+			metadata[k] = v
+		}
+	}
+	ip.Metadata = metadata
+	ip.Status = "quarantined"
+	return nil
+}
+
+func (s *memoryStore) RestoreReservedIPStatus(id int, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[id]
+	if !ok {
+		return database.ErrReservedIPNotFound
+	}
+	metadata := map[string]interface{}{}
+	for k, v := range ip.Metadata {
+		if k != "reputation_previous_status" {
+			metadata[k] = v
+		}
+	}
+	ip.Metadata = metadata
+	ip.Status = status
+	return nil
+}
+
+func (s *memoryStore) SwapReservedIPs(replacedID, replacementID int, assignedTo *string, swappedFor string, actions []*database.IPAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	replacement, ok := s.ips[replacementID]
+	if !ok || replacement.Status != "reserved" || replacement.IsBlacklisted {
+		return database.ErrReservedIPUnavailable
+	}
+	replaced, ok := s.ips[replacedID]
+	if !ok {
+		return database.ErrReservedIPNotFound
+	}
+	replacement.Metadata = withMetadata(replacement.Metadata, "swapped_for", swappedFor)
+	replacement.Status = "in_use"
+	replacement.AssignedTo = assignedTo
+	replacement.UsageCount++
+	replaced.Metadata = withMetadata(replaced.Metadata, "reputation_previous_status", "reserved")
+	replaced.Status = "quarantined"
+	for _, action := range actions {
+		action.ID = len(s.actions) + 1
+		s.actions = append(s.actions, *action)
+	}
+	return nil
+}
+
+func (s *memoryStore) GetAllIPReputationMetrics(status string) ([]database.IPReputationMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var metrics []database.IPReputationMetrics
+	for _, m := range s.metrics {
+		if status == "" || m.Status == status {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
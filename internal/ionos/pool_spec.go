@@ -0,0 +1,148 @@
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PoolSpecTagKey is the reserved IP metadata key holding the pool spec tag
+const PoolSpecTagKey = "tag"
+
+// PoolSpec is the desired state of a tagged pool: Count clean IPs in Location
+type PoolSpec struct {
+	Count    int    `json:"count"`
+	Location string `json:"location"`
+	Tag      string `json:"tag"`
+}
+
+// PoolSpecDiff is what applying a pool spec changed, or would change on a
+// dry run
+type PoolSpecDiff struct {
+	Spec   PoolSpec `json:"spec"`
+	DryRun bool     `json:"dry_run"`
+	// Current is the number of matching clean IPs before the apply
+	Current   int `json:"current"`
+	ToReserve int `json:"to_reserve"`
+	ToRelease int `json:"to_release"`
+	// Reserved and Released list the IPs changed; on a dry run Released
+	// lists the IPs that would be released
+	Reserved []database.ReservedIP `json:"reserved"`
+	Released []database.ReservedIP `json:"released"`
+	// InUseKept counts surplus IPs that were kept because they are in use
+	InUseKept int `json:"in_use_kept,omitempty"`
+	// Result is the number of matching clean IPs after the apply
+	Result    int    `json:"result"`
+	Converged bool   `json:"converged"`
+	JobID     string `json:"job_id,omitempty"`
+}
+
+// ApplyPoolSpec reserves or releases IPs tagged spec.Tag in spec.Location
+// until exactly spec.Count clean ones remain. Applying the same spec again
+// changes nothing. Only IPs with status reserved are released, newest first;
+// in-use IPs are never released. A spec that could not be fully reserved
+// reports Converged false and can be applied again.
+func (s *Service) ApplyPoolSpec(ctx context.Context, spec PoolSpec, dryRun bool) (*PoolSpecDiff, error) {
+	if spec.Location == "" {
+		spec.Location = s.defaultLocation
+	}
+
+	s.specMu.Lock()
+	defer s.specMu.Unlock()
+
+	current, err := s.poolSpecIPs(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PoolSpecDiff{
+		Spec:     spec,
+		DryRun:   dryRun,
+		Current:  len(current),
+		Reserved: []database.ReservedIP{},
+		Released: []database.ReservedIP{},
+	}
+
+	var release []database.ReservedIP
+	if surplus := len(current) - spec.Count; surplus > 0 {
+		// Newest first; never release an IP that is sending mail
+		sort.Slice(current, func(i, j int) bool { return current[i].ID > current[j].ID })
+		for _, ip := range current {
+			if len(release) == surplus {
+				break
+			}
+			if ip.Status == "reserved" {
+				release = append(release, ip)
+			}
+		}
+		diff.InUseKept = surplus - len(release)
+	} else {
+		diff.ToReserve = -surplus
+	}
+	diff.ToRelease = len(release)
+
+	s.logger.WithFields(logrus.Fields{
+		"action":     "apply_pool_spec",
+		"tag":        spec.Tag,
+		"location":   spec.Location,
+		"count":      spec.Count,
+		"current":    diff.Current,
+		"to_reserve": diff.ToReserve,
+		"to_release": diff.ToRelease,
+		"dry_run":    dryRun,
+	}).Info("Reconciling IP pool spec")
+
+	if dryRun {
+		diff.Released = release
+		diff.Result = diff.Current + diff.ToReserve - diff.ToRelease
+		diff.Converged = diff.InUseKept == 0
+		return diff, nil
+	}
+
+	for _, ip := range release {
+		if _, err := s.ReleaseIP(ctx, ip.ID); err != nil {
+			return diff, err
+		}
+		ip.Status = "released"
+		diff.Released = append(diff.Released, ip)
+	}
+
+	if diff.ToReserve > 0 {
+		response, err := s.reserveCleanIPs(ctx, diff.ToReserve, spec.Location, map[string]interface{}{PoolSpecTagKey: spec.Tag})
+		if response != nil {
+			diff.JobID = response.JobID
+			diff.Reserved = response.ReservedIPs
+		}
+		if err != nil {
+			return diff, err
+		}
+	}
+
+	diff.Result = diff.Current + len(diff.Reserved) - len(diff.Released)
+	diff.Converged = diff.Result == spec.Count
+	return diff, nil
+}
+
+// poolSpecIPs returns the clean reserved or in-use IPs belonging to spec
+func (s *Service) poolSpecIPs(spec PoolSpec) ([]database.ReservedIP, error) {
+	clean := false
+	ips, err := s.store.ListReservedIPs(nil, &clean, &spec.Location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved IPs: %w", err)
+	}
+
+	matching := []database.ReservedIP{}
+	for _, ip := range ips {
+		if tag, _ := ip.Metadata[PoolSpecTagKey].(string); tag != spec.Tag {
+			continue
+		}
+		if ip.Status == "reserved" || ip.Status == "in_use" {
+			matching = append(matching, ip)
+		}
+	}
+	return matching, nil
+}
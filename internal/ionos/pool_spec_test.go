@@ -0,0 +1,106 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/resolver"
+)
+
+// seedPoolIP stores a clean IP tagged tag whose block exists in the mock
+func seedPoolIP(mock *mockIONOS, store *memoryStore, ip, status, tag string) *database.ReservedIP {
+	mock.mu.Lock()
+	blockID := "seed-" + ip
+	mock.blocks[blockID] = IPBlock{ID: blockID, Properties: IPBlockProperties{Location: "us/ewr", Size: 1, IPs: []string{ip}}}
+	mock.mu.Unlock()
+
+	reserved := &database.ReservedIP{
+		IPAddress:          ip,
+		ReservationBlockID: blockID,
+		UID:                ip,
+		Location:           "us/ewr",
+		Status:             status,
+		ReservedAt:         time.Now(),
+		Metadata:           map[string]interface{}{PoolSpecTagKey: tag},
+	}
+	store.CreateReservedIP(reserved)
+	return reserved
+}
+
+// TestApplyPoolSpecReservesMissingIPs tests that a spec is reconciled once and then left alone
+func TestApplyPoolSpecReservesMissingIPs(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2")
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	seedPoolIP(mock, store, "203.0.113.1", "in_use", "marketing")
+	seedPoolIP(mock, store, "203.0.113.9", "reserved", "transactional")
+
+	spec := PoolSpec{Count: 3, Tag: "marketing"}
+	diff, err := svc.ApplyPoolSpec(context.Background(), spec, false)
+	if err != nil {
+		t.Fatalf("ApplyPoolSpec failed: %v", err)
+	}
+	if diff.Current != 1 || diff.ToReserve != 2 || len(diff.Reserved) != 2 || diff.Result != 3 || !diff.Converged || diff.JobID == "" {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+	if diff.Spec.Location != "us/ewr" || diff.Reserved[0].Metadata[PoolSpecTagKey] != "marketing" {
+		t.Errorf("Expected the new IPs tagged in the default location, got %+v", diff.Reserved[0])
+	}
+
+	again, err := svc.ApplyPoolSpec(context.Background(), spec, false)
+	if err != nil {
+		t.Fatalf("ApplyPoolSpec failed: %v", err)
+	}
+	if again.ToReserve != 0 || again.ToRelease != 0 || again.Current != 3 || !again.Converged {
+		t.Errorf("Expected applying the same spec to change nothing, got %+v", again)
+	}
+}
+
+// TestApplyPoolSpecReleasesSurplus tests that surplus reserved IPs are released newest first and in-use IPs kept
+func TestApplyPoolSpecReleasesSurplus(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	inUse := seedPoolIP(mock, store, "203.0.113.1", "in_use", "marketing")
+	older := seedPoolIP(mock, store, "203.0.113.2", "reserved", "marketing")
+	newer := seedPoolIP(mock, store, "203.0.113.3", "reserved", "marketing")
+
+	plan, err := svc.ApplyPoolSpec(context.Background(), PoolSpec{Count: 2, Tag: "marketing"}, true)
+	if err != nil {
+		t.Fatalf("ApplyPoolSpec failed: %v", err)
+	}
+	if !plan.DryRun || plan.ToRelease != 1 || len(plan.Released) != 1 || plan.Released[0].ID != newer.ID || len(mock.deleted) != 0 {
+		t.Errorf("Expected a dry run planning to release the newest IP, got %+v", plan)
+	}
+
+	diff, err := svc.ApplyPoolSpec(context.Background(), PoolSpec{Count: 0, Tag: "marketing"}, false)
+	if err != nil {
+		t.Fatalf("ApplyPoolSpec failed: %v", err)
+	}
+	if diff.ToRelease != 2 || diff.InUseKept != 1 || diff.Result != 1 || diff.Converged {
+		t.Errorf("Expected both reserved IPs released and the in-use IP kept, got %+v", diff)
+	}
+	if len(mock.deleted) != 2 || mock.deleted[0] != newer.ReservationBlockID || mock.deleted[1] != older.ReservationBlockID {
+		t.Errorf("Expected the blocks deleted newest first, got %v", mock.deleted)
+	}
+	if ip, _ := store.GetReservedIPByID(older.ID); ip.Status != "released" || ip.ReleasedAt == nil {
+		t.Errorf("Expected the IP marked released, got %+v", ip)
+	}
+	if ip, _ := store.GetReservedIPByID(inUse.ID); ip.Status != "in_use" {
+		t.Errorf("Expected the in-use IP untouched, got %+v", ip)
+	}
+}
+
+// TestApplyPoolSpecQuota tests that a spec beyond the quota fails before reserving
+func TestApplyPoolSpecQuota(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1")
+	defer mock.Close()
+	svc, _ := newTestService(mock, resolver.NewFake(), 0)
+
+	if _, err := svc.ApplyPoolSpec(context.Background(), PoolSpec{Count: 1, Tag: "marketing"}, false); !errors.Is(err, ErrInsufficientQuota) {
+		t.Errorf("Expected ErrInsufficientQuota, got %v", err)
+	}
+}
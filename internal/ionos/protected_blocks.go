@@ -0,0 +1,101 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBlockNotFound is returned when IONOS has no block with the ID
+var ErrBlockNotFound = errors.New("IP block not found")
+
+// ProtectedBlockInfo is a protected block with its current IONOS state;
+// Block is nil when the block no longer exists in IONOS
+type ProtectedBlockInfo struct {
+	database.ProtectedBlock
+	Block *IPBlock `json:"block"`
+}
+
+// ProtectBlock marks an existing IONOS block protected so cleanup never
+// deletes it. Protecting a protected block updates its reason.
+func (s *Service) ProtectBlock(ctx context.Context, blockID, reason, createdBy string) (*database.ProtectedBlock, error) {
+	if _, err := s.client.GetIPBlock(ctx, blockID); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+		}
+		return nil, fmt.Errorf("failed to get IP block: %w", err)
+	}
+
+	protected, err := s.store.ProtectBlock(&database.ProtectedBlock{BlockID: blockID, Reason: reason, CreatedBy: createdBy})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":   "protect_block",
+		"block_id": blockID,
+		"reason":   reason,
+		"by":       createdBy,
+	}).Info("IP block protected")
+	return protected, nil
+}
+
+// UnprotectBlock removes a block's protection; the block itself is kept
+func (s *Service) UnprotectBlock(blockID string) error {
+	if err := s.store.UnprotectBlock(blockID); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":   "unprotect_block",
+		"block_id": blockID,
+	}).Info("IP block protection removed")
+	return nil
+}
+
+// ListProtectedBlocks returns the protected blocks with their IONOS state
+func (s *Service) ListProtectedBlocks(ctx context.Context) ([]ProtectedBlockInfo, error) {
+	protected, err := s.store.ListProtectedBlocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list protected blocks: %w", err)
+	}
+
+	blocks, err := s.client.ListIPBlocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP blocks: %w", err)
+	}
+	byID := make(map[string]IPBlock, len(blocks.Items))
+	for _, block := range blocks.Items {
+		byID[block.ID] = block
+	}
+
+	infos := make([]ProtectedBlockInfo, 0, len(protected))
+	for _, p := range protected {
+		info := ProtectedBlockInfo{ProtectedBlock: p}
+		if block, ok := byID[p.BlockID]; ok {
+			info.Block = &block
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// protectedBlockIDs returns the set of protected block IDs
+func (s *Service) protectedBlockIDs() (map[string]bool, error) {
+	protected, err := s.store.ListProtectedBlocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list protected blocks: %w", err)
+	}
+
+	ids := make(map[string]bool, len(protected))
+	for _, p := range protected {
+		ids[p.BlockID] = true
+	}
+	return ids, nil
+}
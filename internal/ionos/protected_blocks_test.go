@@ -0,0 +1,75 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/resolver"
+)
+
+// TestProtectedBlocksSkippedByQuotaAndCleanup tests that protection is read from the store instead of the block size
+func TestProtectedBlocksSkippedByQuotaAndCleanup(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, _ := newTestService(mock, resolver.NewFake(), 50)
+	mock.blocks["block-mx"] = IPBlock{ID: "block-mx", Properties: IPBlockProperties{Size: 1, IPs: []string{"203.0.113.1"}}}
+	mock.blocks["block-old"] = IPBlock{ID: "block-old", Properties: IPBlockProperties{Size: 1, IPs: []string{"203.0.113.2"}}}
+	mock.blocks["block-eleven"] = IPBlock{ID: "block-eleven", Properties: IPBlockProperties{Size: 11}}
+
+	if _, err := svc.ProtectBlock(context.Background(), "block-mx", "mail servers", "user:alice"); err != nil {
+		t.Fatalf("ProtectBlock failed: %v", err)
+	}
+
+	quota, err := svc.CheckQuota(context.Background())
+	if err != nil {
+		t.Fatalf("CheckQuota failed: %v", err)
+	}
+	if quota.ProtectedBlocks != 1 || quota.SingleIPBlocks != 1 || quota.Remaining != 47 {
+		t.Errorf("Expected only the marked block protected, got %+v", quota)
+	}
+
+	deleted, err := svc.CleanupSingleIPBlocks(context.Background())
+	if err != nil {
+		t.Fatalf("CleanupSingleIPBlocks failed: %v", err)
+	}
+	if deleted != 1 || len(mock.deleted) != 1 || mock.deleted[0] != "block-old" {
+		t.Errorf("Expected only the unprotected single-IP block deleted, got %v", mock.deleted)
+	}
+
+	infos, err := svc.ListProtectedBlocks(context.Background())
+	if err != nil || len(infos) != 1 || infos[0].Block == nil || infos[0].Reason != "mail servers" {
+		t.Errorf("Expected the protected block with its IONOS state, got %+v (%v)", infos, err)
+	}
+}
+
+// TestProtectBlockUnknown tests that only existing IONOS blocks can be protected
+func TestProtectBlockUnknown(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	if _, err := svc.ProtectBlock(context.Background(), "block-missing", "", "user:alice"); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected ErrBlockNotFound, got %v", err)
+	}
+	if len(store.protected) != 0 {
+		t.Errorf("Expected nothing stored, got %+v", store.protected)
+	}
+}
+
+// TestReleaseIPKeepsProtectedBlock tests that releasing the last member of a protected block keeps the block
+func TestReleaseIPKeepsProtectedBlock(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	ip := seedPoolIP(mock, store, "203.0.113.7", "reserved", "")
+	svc.ProtectBlock(context.Background(), ip.ReservationBlockID, "", "user:alice")
+
+	deleted, err := svc.ReleaseIP(context.Background(), ip.ID)
+	if err != nil || deleted || len(mock.deleted) != 0 {
+		t.Errorf("Expected the protected block kept, got deleted=%t err=%v", deleted, err)
+	}
+	if released, _ := store.GetReservedIPByID(ip.ID); released.Status != "released" {
+		t.Errorf("Expected the IP released, got %s", released.Status)
+	}
+}
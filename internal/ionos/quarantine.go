@@ -0,0 +1,338 @@
+package ionos
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReputationPreviousStatusKey is the reserved IP metadata key holding the
+// status an IP had before its reputation quarantined it
+const ReputationPreviousStatusKey = "reputation_previous_status"
+
+// Audit actions recorded in ip_actions when a reputation change is
+// propagated to a reserved IP
+const (
+	ActionReservationQuarantined = "reservation_quarantined"
+	ActionReservationRestored    = "reservation_restored"
+)
+
+// QuarantineReconcileReport is the outcome of one reconciliation of the
+// reserved IP statuses against the reputation statuses
+type QuarantineReconcileReport struct {
+	CheckedAt   time.Time             `json:"checked_at"`
+	Checked     int                   `json:"checked"`
+	Quarantined []database.ReservedIP `json:"quarantined"`
+	Restored    []database.ReservedIP `json:"restored"`
+	Errors      int                   `json:"errors"`
+}
+
+// ReputationHook returns a state machine hook that propagates every applied
+// reputation transition to the IP's reservation record
+func (s *Service) ReputationHook() state.Hook {
+	return func(t state.Transition) {
+		if _, err := s.SyncReputationStatus(t.IP, t.To, "reputation:"+t.TriggeredBy); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "reputation_status_propagation",
+				"ip":     t.IP,
+				"status": t.To.String(),
+				"error":  err.Error(),
+			}).Error("Failed to propagate reputation status to reserved IP")
+		}
+	}
+}
+
+// SyncReputationStatus brings a reserved IP in line with its reputation
+// status. A quarantined or blacklisted IP that is reserved or in use is
+// quarantined; once its reputation recovers it gets its previous status
+// back. IPs quarantined by hand, released IPs and IPs that were never
+// reserved are left alone. It reports whether the record changed.
+func (s *Service) SyncReputationStatus(ip string, status state.Status, triggeredBy string) (bool, error) {
+	reserved, err := s.store.GetReservedIPByAddress(ip)
+	if errors.Is(err, database.ErrReservedIPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, changed, err := s.applyReputationStatus(*reserved, status, triggeredBy)
+	return changed, err
+}
+
+// applyReputationStatus updates ip for the reputation status and returns
+// the updated record. Outside enforce mode the record is left alone; in
+// dry-run mode the change it would have made is audited as simulated, once
+// per outcome.
+func (s *Service) applyReputationStatus(ip database.ReservedIP, status state.Status, triggeredBy string) (database.ReservedIP, bool, error) {
+	previous, propagated := ip.Metadata[ReputationPreviousStatusKey].(string)
+	mode := remediation.Current()
+
+	switch status {
+	case state.Quarantine, state.Blacklisted:
+		if ip.Status != "reserved" && ip.Status != "in_use" {
+			return ip, false, nil
+		}
+		if mode != remediation.Enforce {
+			if mode == remediation.DryRun && s.simulations.Changed(ip.IPAddress, "quarantined") {
+				s.auditReputationStatus(ip, ActionReservationQuarantined, "quarantined", status, triggeredBy, mode)
+			}
+			return ip, false, nil
+		}
+		if err := s.store.QuarantineReservedIP(ip.ID, ip.Status); err != nil {
+			return ip, false, err
+		}
+		s.auditReputationStatus(ip, ActionReservationQuarantined, "quarantined", status, triggeredBy, mode)
+		ip.Metadata = withMetadata(ip.Metadata, ReputationPreviousStatusKey, ip.Status)
+		ip.Status = "quarantined"
+		return ip, true, nil
+
+	case state.Healthy, state.Warning:
+		if ip.Status != "quarantined" || !propagated {
+			s.simulations.Forget(ip.IPAddress)
+			return ip, false, nil
+		}
+		if mode != remediation.Enforce {
+			if mode == remediation.DryRun && s.simulations.Changed(ip.IPAddress, "restored") {
+				s.auditReputationStatus(ip, ActionReservationRestored, previous, status, triggeredBy, mode)
+			}
+			return ip, false, nil
+		}
+		if err := s.store.RestoreReservedIPStatus(ip.ID, previous); err != nil {
+			return ip, false, err
+		}
+		s.auditReputationStatus(ip, ActionReservationRestored, previous, status, triggeredBy, mode)
+		ip.Metadata = withMetadata(ip.Metadata, ReputationPreviousStatusKey, nil)
+		ip.Status = previous
+		return ip, true, nil
+	}
+
+	return ip, false, nil
+}
+
+// withMetadata returns a copy of metadata with key set to value, or
+// removed when value is nil
+func withMetadata(metadata map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	if value == nil {
+		delete(copied, key)
+	} else {
+		copied[key] = value
+	}
+	return copied
+}
+
+// auditReputationStatus records a propagated status change in the IP actions
+func (s *Service) auditReputationStatus(ip database.ReservedIP, action, newStatus string, reputation state.Status, triggeredBy string, mode remediation.Mode) {
+	record := &database.IPAction{
+		IP:             ip.IPAddress,
+		Action:         action,
+		PreviousStatus: ip.Status,
+		NewStatus:      newStatus,
+		Reason:         fmt.Sprintf("reputation status is %s", reputation),
+		TriggeredBy:    triggeredBy,
+		Metadata: map[string]interface{}{
+			"reserved_ip_id":    ip.ID,
+			"block_id":          ip.ReservationBlockID,
+			"reputation_status": reputation.String(),
+		},
+		CreatedAt: time.Now(),
+	}
+	remediation.Tag(record, mode)
+	if err := s.store.InsertIPAction(record); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "reputation_status_audit",
+			"ip":     ip.IPAddress,
+			"error":  err.Error(),
+		}).Error("Failed to record reserved IP status change")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":            action,
+		"ip":                ip.IPAddress,
+		"previous_status":   ip.Status,
+		"new_status":        newStatus,
+		"reputation_status": reputation.String(),
+		"remediation_mode":  string(mode),
+	}).Info("Reserved IP status follows reputation")
+}
+
+// ReconcileQuarantine repairs reserved IPs whose status drifted from their
+// reputation, e.g. because a propagation failed or the IP was reserved
+// after it was quarantined
+func (s *Service) ReconcileQuarantine() (*QuarantineReconcileReport, error) {
+	metrics, err := s.store.GetAllIPReputationMetrics("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP reputation metrics: %w", err)
+	}
+	reputation := make(map[string]state.Status, len(metrics))
+	for _, m := range metrics {
+		reputation[m.IP] = state.Status(m.Status)
+	}
+
+	ips, err := s.store.ListReservedIPs(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved IPs: %w", err)
+	}
+
+	report := &QuarantineReconcileReport{
+		CheckedAt:   time.Now(),
+		Quarantined: []database.ReservedIP{},
+		Restored:    []database.ReservedIP{},
+	}
+	for _, ip := range ips {
+		status, ok := reputation[ip.IPAddress]
+		if !ok || ip.Status == "released" {
+			continue
+		}
+		report.Checked++
+
+		updated, changed, err := s.applyReputationStatus(ip, status, "quarantine_reconciliation")
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "quarantine_reconcile_ip",
+				"ip":     ip.IPAddress,
+				"error":  err.Error(),
+			}).Error("Failed to reconcile reserved IP status")
+			report.Errors++
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if updated.Status == "quarantined" {
+			report.Quarantined = append(report.Quarantined, updated)
+		} else {
+			report.Restored = append(report.Restored, updated)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":      "reconcile_quarantine",
+		"checked":     report.Checked,
+		"quarantined": len(report.Quarantined),
+		"restored":    len(report.Restored),
+		"errors":      report.Errors,
+	}).Info("Reserved IP quarantine reconciled")
+
+	return report, nil
+}
+
+// QuarantineReconciliationService periodically reconciles the reserved IP
+// statuses with the reputation statuses
+type QuarantineReconciliationService struct {
+	service     *Service
+	logger      *logrus.Logger
+	ticker      *time.Ticker
+	stopChan    chan bool
+	running     bool
+	mu          sync.Mutex
+	lastRun     time.Time
+	quarantined int
+	restored    int
+	errors      int
+}
+
+// NewQuarantineReconciliationService creates a reconciliation scheduler
+func NewQuarantineReconciliationService(service *Service, logger *logrus.Logger) *QuarantineReconciliationService {
+	return &QuarantineReconciliationService{
+		service:  service,
+		logger:   logger,
+		stopChan: make(chan bool),
+	}
+}
+
+// Start begins the periodic reconciliation
+func (s *QuarantineReconciliationService) Start(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("quarantine reconciliation service is already running")
+	}
+
+	s.ticker = time.NewTicker(interval)
+	s.running = true
+
+	s.logger.WithFields(logrus.Fields{
+		"action":   "quarantine_reconciliation_service_start",
+		"interval": interval.String(),
+	}).Info("Starting quarantine reconciliation service")
+
+	go s.RunOnce()
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.RunOnce()
+			case <-s.stopChan:
+				s.logger.Info("Quarantine reconciliation service stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the quarantine reconciliation service
+func (s *QuarantineReconciliationService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+
+	s.stopChan <- true
+	s.running = false
+}
+
+// RunOnce reconciles once
+func (s *QuarantineReconciliationService) RunOnce() {
+	report, err := s.service.ReconcileQuarantine()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = time.Now()
+	if err != nil {
+		s.errors++
+		s.logger.WithFields(logrus.Fields{
+			"action": "quarantine_reconcile_failed",
+			"error":  err.Error(),
+		}).Error("Failed to reconcile reserved IP quarantine")
+		return
+	}
+	s.quarantined += len(report.Quarantined)
+	s.restored += len(report.Restored)
+	s.errors += report.Errors
+}
+
+// GetStats returns service statistics
+func (s *QuarantineReconciliationService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"running":     s.running,
+		"last_run":    s.lastRun,
+		"quarantined": s.quarantined,
+		"restored":    s.restored,
+		"errors":      s.errors,
+	}
+}
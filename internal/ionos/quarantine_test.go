@@ -0,0 +1,128 @@
+package ionos
+
+import (
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+	"golang-backend-service/internal/resolver"
+)
+
+// TestReputationHookPropagatesQuarantine tests that a reputation quarantine quarantines the reservation and recovery restores it
+func TestReputationHookPropagatesQuarantine(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	ip := seedPoolIP(mock, store, "203.0.113.1", "in_use", "")
+
+	machine := state.NewMachine()
+	machine.AddHook(svc.ReputationHook())
+
+	machine.Apply(state.Transition{IP: "203.0.113.1", From: state.Healthy, To: state.Quarantine, TriggeredBy: "automated_aggregation"}, nil)
+	quarantined, _ := store.GetReservedIPByID(ip.ID)
+	if quarantined.Status != "quarantined" || quarantined.Metadata[ReputationPreviousStatusKey] != "in_use" {
+		t.Fatalf("Expected the reservation quarantined with its previous status kept, got %s %+v", quarantined.Status, quarantined.Metadata)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != ActionReservationQuarantined || store.actions[0].TriggeredBy != "reputation:automated_aggregation" {
+		t.Errorf("Expected the quarantine audited, got %+v", store.actions)
+	}
+
+	machine.Apply(state.Transition{IP: "203.0.113.1", From: state.Quarantine, To: state.Blacklisted}, nil)
+	if len(store.actions) != 1 {
+		t.Errorf("Expected an already quarantined reservation left alone, got %+v", store.actions)
+	}
+
+	machine.Apply(state.Transition{IP: "203.0.113.1", From: state.Blacklisted, To: state.Quarantine}, nil)
+	machine.Apply(state.Transition{IP: "203.0.113.1", From: state.Quarantine, To: state.Healthy}, nil)
+	restored, _ := store.GetReservedIPByID(ip.ID)
+	if restored.Status != "in_use" {
+		t.Errorf("Expected the previous status restored, got %s", restored.Status)
+	}
+	if _, ok := restored.Metadata[ReputationPreviousStatusKey]; ok {
+		t.Errorf("Expected the previous status dropped from the metadata, got %+v", restored.Metadata)
+	}
+	if last := store.actions[len(store.actions)-1]; last.Action != ActionReservationRestored || last.NewStatus != "in_use" {
+		t.Errorf("Expected the restore audited, got %+v", last)
+	}
+
+	changed, err := svc.SyncReputationStatus("198.51.100.9", state.Quarantine, "manual")
+	if err != nil || changed {
+		t.Errorf("Expected an IP without reservation ignored, got changed=%t err=%v", changed, err)
+	}
+}
+
+// TestReconcileQuarantine tests that drifted reservations are repaired and manual quarantines kept
+func TestReconcileQuarantine(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	drifted := seedPoolIP(mock, store, "203.0.113.1", "reserved", "")
+	recovered := seedPoolIP(mock, store, "203.0.113.2", "quarantined", "")
+	store.ips[recovered.ID].Metadata[ReputationPreviousStatusKey] = "reserved"
+	manual := seedPoolIP(mock, store, "203.0.113.3", "quarantined", "")
+	released := seedPoolIP(mock, store, "203.0.113.4", "released", "")
+	store.metrics = []database.IPReputationMetrics{
+		{IP: "203.0.113.1", Status: "blacklisted"},
+		{IP: "203.0.113.2", Status: "healthy"},
+		{IP: "203.0.113.3", Status: "healthy"},
+		{IP: "203.0.113.4", Status: "quarantine"},
+	}
+
+	report, err := svc.ReconcileQuarantine()
+	if err != nil {
+		t.Fatalf("ReconcileQuarantine failed: %v", err)
+	}
+	if report.Checked != 3 || len(report.Quarantined) != 1 || len(report.Restored) != 1 {
+		t.Fatalf("Expected one reservation quarantined and one restored, got %+v", report)
+	}
+	for id, want := range map[int]string{drifted.ID: "quarantined", recovered.ID: "reserved", manual.ID: "quarantined", released.ID: "released"} {
+		if ip, _ := store.GetReservedIPByID(id); ip.Status != want {
+			t.Errorf("Expected %s to be %s, got %s", ip.IPAddress, want, ip.Status)
+		}
+	}
+
+	again, _ := svc.ReconcileQuarantine()
+	if len(again.Quarantined) != 0 || len(again.Restored) != 0 {
+		t.Errorf("Expected no drift left, got %+v", again)
+	}
+}
+
+// TestReconcileQuarantineHonorsRemediationMode tests that dry-run mode only records the quarantine once as simulated and off mode records nothing
+func TestReconcileQuarantineHonorsRemediationMode(t *testing.T) {
+	defer remediation.SetMode(remediation.Enforce)
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	ip := seedPoolIP(mock, store, "203.0.113.1", "in_use", "")
+	store.metrics = []database.IPReputationMetrics{{IP: "203.0.113.1", Status: "blacklisted"}}
+
+	remediation.SetMode(remediation.DryRun)
+	for i := 0; i < 2; i++ {
+		report, err := svc.ReconcileQuarantine()
+		if err != nil || len(report.Quarantined) != 0 {
+			t.Fatalf("Expected nothing quarantined in dry-run mode, got %+v %v", report, err)
+		}
+	}
+	if reserved, _ := store.GetReservedIPByID(ip.ID); reserved.Status != "in_use" {
+		t.Errorf("Expected the reservation left in use, got %s", reserved.Status)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != ActionReservationQuarantined || !remediation.IsSimulated(store.actions[0]) {
+		t.Fatalf("Expected one simulated quarantine recorded, got %+v", store.actions)
+	}
+
+	remediation.SetMode(remediation.Off)
+	svc.ReconcileQuarantine()
+	if len(store.actions) != 1 {
+		t.Errorf("Expected nothing recorded with remediation off, got %+v", store.actions)
+	}
+
+	remediation.SetMode(remediation.Enforce)
+	if report, _ := svc.ReconcileQuarantine(); len(report.Quarantined) != 1 {
+		t.Errorf("Expected the reservation quarantined once enforced, got %+v", report)
+	}
+	if last := store.actions[len(store.actions)-1]; remediation.IsSimulated(last) {
+		t.Errorf("Expected the enforced quarantine not tagged simulated, got %+v", last)
+	}
+}
@@ -2,21 +2,49 @@ package ionos
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/validation"
 )
 
 // Service handles IP reservation business logic
 type Service struct {
 	client         *Client
 	blacklistCheck *DNSBLChecker
+	store          Store
+	publisher      Publisher
 	logger         *logrus.Logger
 	defaultLocation string
 	maxQuota       int
+
+	// specMu serializes pool spec applies so concurrent applies of one spec
+	// cannot both reserve the missing IPs
+	specMu sync.Mutex
+
+	staleMu     sync.Mutex
+	stalePolicy StalePolicy
+
+	// simulations keeps dry-run remediation from auditing the same
+	// simulated change on every reconciliation
+	simulations remediation.Journal
+
+	// verification selects the checks a new IP must pass to be kept
+	verification VerificationConfig
+	ptrResolver  ptrResolver
+
+	// Delays between IONOS calls; zeroed in tests
+	reservationDelay time.Duration
+	assignmentWait   time.Duration
+	cleanupDelay     time.Duration
 }
 
 // NewService creates a new IP reservation service
@@ -24,10 +52,25 @@ func NewService(client *Client, logger *logrus.Logger, defaultLocation string, m
 	return &Service{
 		client:          client,
 		blacklistCheck:  NewDNSBLChecker(logger),
+		ptrResolver:     net.DefaultResolver,
+		store:           dbStore{},
+		publisher:       nopPublisher{},
 		logger:          logger,
 		defaultLocation: defaultLocation,
 		maxQuota:        maxQuota,
+
+		reservationDelay: 1 * time.Second,
+		assignmentWait:   5 * time.Second,
+		cleanupDelay:     300 * time.Millisecond,
+	}
+}
+
+// SetPublisher sets where reservation events are sent once a job ends
+func (s *Service) SetPublisher(p Publisher) {
+	if p == nil {
+		p = nopPublisher{}
 	}
+	s.publisher = p
 }
 
 // ReserveIPRequest represents a request to reserve IPs
@@ -38,15 +81,31 @@ type ReserveIPRequest struct {
 
 // ReserveIPResponse represents the response from reserving IPs
 type ReserveIPResponse struct {
+	JobID           string                    `json:"job_id"`
 	SuccessCount    int                       `json:"success_count"`
 	FailureCount    int                       `json:"failure_count"`
 	BlacklistedCount int                      `json:"blacklisted_count"`
+	// RejectedCount counts clean IPs deleted for failing another
+	// verification check
+	RejectedCount   int                       `json:"rejected_count,omitempty"`
+	// BlockSize and BlockIDs are set for multi-IP block reservations, whose
+	// ReservedIPs include quarantined members
+	BlockSize       int                       `json:"block_size,omitempty"`
+	BlockIDs        []string                  `json:"block_ids,omitempty"`
 	ReservedIPs     []database.ReservedIP     `json:"reserved_ips"`
 	Attempts        []database.ReservationAttempt `json:"attempts,omitempty"`
 }
 
+// ErrInsufficientQuota is returned when IONOS has fewer free blocks than IPs requested
+var ErrInsufficientQuota = errors.New("insufficient quota")
+
 // ReserveCleanIPs reserves a specified number of clean (non-blacklisted) IPs
 func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location string) (*ReserveIPResponse, error) {
+	return s.reserveCleanIPs(ctx, count, location, nil)
+}
+
+// reserveCleanIPs reserves count clean IPs, storing metadata with each
+func (s *Service) reserveCleanIPs(ctx context.Context, count int, location string, metadata map[string]interface{}) (*ReserveIPResponse, error) {
 	if location == "" {
 		location = s.defaultLocation
 	}
@@ -64,13 +123,15 @@ func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location strin
 	}
 
 	if quota.Remaining < count {
-		return nil, fmt.Errorf("insufficient quota: need %d, have %d", count, quota.Remaining)
+		return nil, fmt.Errorf("%w: need %d, have %d", ErrInsufficientQuota, count, quota.Remaining)
 	}
 
 	response := &ReserveIPResponse{
+		JobID:       uuid.New().String(),
 		ReservedIPs: []database.ReservedIP{},
 		Attempts:    []database.ReservationAttempt{},
 	}
+	startedAt := time.Now()
 
 	successCount := 0
 	maxAttempts := count * 5 // Allow up to 5x attempts to account for blacklisted IPs
@@ -78,7 +139,9 @@ func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location strin
 	for attempt := 0; attempt < maxAttempts && successCount < count; attempt++ {
 		select {
 		case <-ctx.Done():
-			return response, fmt.Errorf("context cancelled: %w", ctx.Err())
+			err := fmt.Errorf("context cancelled: %w", ctx.Err())
+			s.publishCompleted(response, count, location, startedAt, err)
+			return response, err
 		default:
 		}
 
@@ -89,7 +152,7 @@ func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location strin
 		}).Info("Attempting IP reservation")
 
 		// Reserve single IP
-		result, err := s.reserveSingleIP(ctx, location)
+		result, err := s.reserveSingleIP(ctx, location, metadata)
 		if err != nil {
 			s.logger.WithError(err).Error("Failed to reserve IP")
 			response.FailureCount++
@@ -103,12 +166,14 @@ func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location strin
 			response.ReservedIPs = append(response.ReservedIPs, *result.ReservedIP)
 			successCount++
 			response.SuccessCount++
-		} else {
+		} else if result.Blacklisted {
 			response.BlacklistedCount++
+		} else {
+			response.RejectedCount++
 		}
 
 		// Rate limiting
-		time.Sleep(1 * time.Second)
+		time.Sleep(s.reservationDelay)
 	}
 
 	s.logger.WithFields(logrus.Fields{
@@ -118,18 +183,59 @@ func (s *Service) ReserveCleanIPs(ctx context.Context, count int, location strin
 		"failure_count":     response.FailureCount,
 	}).Info("IP reservation process completed")
 
+	s.publishCompleted(response, count, location, startedAt, nil)
 	return response, nil
 }
 
+// publishCompleted announces the end of a reservation job with the clean IPs
+// it kept, so downstream automation can attach them
+func (s *Service) publishCompleted(response *ReserveIPResponse, count int, location string, startedAt time.Time, jobErr error) {
+	clean := make([]database.ReservedIP, 0, len(response.ReservedIPs))
+	for _, ip := range response.ReservedIPs {
+		if !ip.IsBlacklisted {
+			clean = append(clean, ip)
+		}
+	}
+
+	event := ReservationEvent{
+		ID:               uuid.New().String(),
+		Type:             EventReservationCompleted,
+		JobID:            response.JobID,
+		Location:         location,
+		Requested:        count,
+		BlockSize:        response.BlockSize,
+		SuccessCount:     response.SuccessCount,
+		BlacklistedCount: response.BlacklistedCount,
+		FailureCount:     response.FailureCount,
+		IPs:              clean,
+		StartedAt:        startedAt,
+		CompletedAt:      time.Now(),
+	}
+	switch {
+	case jobErr != nil:
+		event.Status = JobCancelled
+		event.Error = jobErr.Error()
+	case response.SuccessCount >= count:
+		event.Status = JobSucceeded
+	case response.SuccessCount > 0:
+		event.Status = JobPartial
+	default:
+		event.Status = JobFailed
+	}
+	s.publisher.Publish(event)
+}
+
 // reserveIPResult contains the result of a single IP reservation
 type reserveIPResult struct {
-	IsClean    bool
-	ReservedIP *database.ReservedIP
-	Attempt    *database.ReservationAttempt
+	IsClean bool
+	// Blacklisted tells a listed IP from one rejected by another check
+	Blacklisted bool
+	ReservedIP  *database.ReservedIP
+	Attempt     *database.ReservationAttempt
 }
 
-// reserveSingleIP reserves a single IP, checks blacklist, and handles accordingly
-func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserveIPResult, error) {
+// reserveSingleIP reserves a single IP, runs the verification pipeline, and keeps or deletes it
+func (s *Service) reserveSingleIP(ctx context.Context, location string, metadata map[string]interface{}) (*reserveIPResult, error) {
 	startTime := time.Now()
 	attemptUID := uuid.New().String()[:8]
 	blockName := fmt.Sprintf("IP-Reserver-%s", attemptUID)
@@ -153,7 +259,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 		attempt.DurationMs = &duration
 
 		// Record failed attempt
-		if dbErr := database.CreateReservationAttempt(attempt); dbErr != nil {
+		if dbErr := s.store.CreateReservationAttempt(attempt); dbErr != nil {
 			s.logger.WithError(dbErr).Error("Failed to record reservation attempt")
 		}
 
@@ -166,7 +272,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 	// Wait for IPs to be assigned if not immediately available
 	if len(block.Properties.IPs) == 0 {
 		s.logger.Info("IPs not immediately available, waiting...")
-		time.Sleep(5 * time.Second)
+		time.Sleep(s.assignmentWait)
 		block, err = s.client.GetIPBlock(ctx, blockID)
 		if err != nil {
 			attempt.Success = false
@@ -178,7 +284,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 			attempt.DurationMs = &duration
 
 			// Record failed attempt
-			if dbErr := database.CreateReservationAttempt(attempt); dbErr != nil {
+			if dbErr := s.store.CreateReservationAttempt(attempt); dbErr != nil {
 				s.logger.WithError(dbErr).Error("Failed to record reservation attempt")
 			}
 
@@ -196,7 +302,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 		attempt.DurationMs = &duration
 
 		// Record failed attempt
-		if dbErr := database.CreateReservationAttempt(attempt); dbErr != nil {
+		if dbErr := s.store.CreateReservationAttempt(attempt); dbErr != nil {
 			s.logger.WithError(dbErr).Error("Failed to record reservation attempt")
 		}
 
@@ -204,56 +310,57 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 	}
 
 	ipAddress := block.Properties.IPs[0]
+	if normalized, err := validation.NormalizeIP(ipAddress); err == nil {
+		ipAddress = normalized
+	}
 	attempt.IPAddress = &ipAddress
 
 	s.logger.WithFields(logrus.Fields{
 		"ip":       ipAddress,
 		"block_id": blockID,
-	}).Info("IP block reserved, checking blacklist")
+	}).Info("IP block reserved, verifying")
 
-	// Check blacklist
-	blacklistResult, err := s.blacklistCheck.CheckIP(ctx, ipAddress)
-	if err != nil {
-		s.logger.WithError(err).Error("Failed to check blacklist")
-		// Don't fail the reservation if blacklist check fails, just mark as unknown
-		blacklistResult = &BlacklistResult{
-			IP:            ipAddress,
-			IsBlacklisted: false,
-			Blacklists:    []string{},
-		}
-	}
+	// Run the verification pipeline; the DNSBL check always runs
+	verification := s.verifyIP(ctx, ipAddress)
+	blacklistResult := verification.blacklist(ipAddress)
 
 	attempt.WasBlacklisted = blacklistResult.IsBlacklisted
 	attempt.BlacklistsFound = blacklistResult.Blacklists
+	attempt.Metadata["verification"] = verification
 	completedAt := time.Now()
 	attempt.CompletedAt = &completedAt
 	duration := int(time.Since(startTime).Milliseconds())
 	attempt.DurationMs = &duration
 
-	if blacklistResult.IsBlacklisted {
+	if !verification.Passed {
 		s.logger.WithFields(logrus.Fields{
 			"ip":         ipAddress,
+			"failed":     verification.Failed,
+			"score":      verification.Score,
 			"blacklists": blacklistResult.Blacklists,
-		}).Warn("IP is blacklisted, deleting")
+		}).Warn("IP failed verification, deleting")
 
-		// Delete the dirty IP
+		// Delete the rejected IP
 		if err := s.client.DeleteIPBlock(ctx, blockID); err != nil {
-			s.logger.WithError(err).Error("Failed to delete blacklisted IP block")
+			s.logger.WithError(err).Error("Failed to delete rejected IP block")
 		}
 
 		attempt.Success = false
+		failureReason := fmt.Sprintf("failed verification: %s", strings.Join(verification.Failed, ", "))
+		attempt.FailureReason = &failureReason
 		actionTaken := "deleted"
 		attempt.ActionTaken = &actionTaken
 
 		// Record attempt
-		if err := database.CreateReservationAttempt(attempt); err != nil {
+		if err := s.store.CreateReservationAttempt(attempt); err != nil {
 			s.logger.WithError(err).Error("Failed to record reservation attempt")
 		}
 
 		return &reserveIPResult{
-			IsClean:    false,
-			ReservedIP: nil,
-			Attempt:    attempt,
+			IsClean:     false,
+			Blacklisted: blacklistResult.IsBlacklisted,
+			ReservedIP:  nil,
+			Attempt:     attempt,
 		}, nil
 	}
 
@@ -272,8 +379,11 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 		UsageCount:         0,
 		Metadata:           make(map[string]interface{}),
 	}
+	for k, v := range metadata {
+		reservedIP.Metadata[k] = v
+	}
 
-	if err := database.CreateReservedIP(reservedIP); err != nil {
+	if err := s.store.CreateReservedIP(reservedIP); err != nil {
 		s.logger.WithError(err).Error("Failed to store reserved IP in database")
 		
 		attempt.Success = false
@@ -283,7 +393,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 		attempt.ActionTaken = &actionTaken
 
 		// Record attempt
-		if dbErr := database.CreateReservationAttempt(attempt); dbErr != nil {
+		if dbErr := s.store.CreateReservationAttempt(attempt); dbErr != nil {
 			s.logger.WithError(dbErr).Error("Failed to record reservation attempt")
 		}
 
@@ -301,7 +411,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 		Metadata:        make(map[string]interface{}),
 	}
 
-	if err := database.CreateBlacklistHistoryEntry(historyEntry); err != nil {
+	if err := s.store.CreateBlacklistHistoryEntry(historyEntry); err != nil {
 		s.logger.WithError(err).Error("Failed to record blacklist history")
 	}
 
@@ -310,7 +420,7 @@ func (s *Service) reserveSingleIP(ctx context.Context, location string) (*reserv
 	attempt.ActionTaken = &actionTaken
 
 	// Record successful attempt
-	if err := database.CreateReservationAttempt(attempt); err != nil {
+	if err := s.store.CreateReservationAttempt(attempt); err != nil {
 		s.logger.WithError(err).Error("Failed to record reservation attempt")
 	}
 
@@ -337,19 +447,20 @@ func (s *Service) CheckQuota(ctx context.Context) (*QuotaInfo, error) {
 		return nil, fmt.Errorf("failed to list IP blocks: %w", err)
 	}
 
+	protected, err := s.protectedBlockIDs()
+	if err != nil {
+		return nil, err
+	}
+
 	quota := &QuotaInfo{
 		TotalBlocks:    len(blocks.Items),
 		EstimatedLimit: s.maxQuota,
 	}
 
-	// Identify protected blocks (11-IP blocks)
 	for _, block := range blocks.Items {
-		size := block.Properties.Size
-		ipCount := len(block.Properties.IPs)
-
-		if size == 11 || ipCount == 11 {
+		if protected[block.ID] {
 			quota.ProtectedBlocks++
-		} else if size == 1 || ipCount == 1 {
+		} else if block.Properties.Size == 1 || len(block.Properties.IPs) == 1 {
 			quota.SingleIPBlocks++
 		}
 	}
@@ -374,14 +485,14 @@ func (s *Service) CheckQuota(ctx context.Context) (*QuotaInfo, error) {
 		Metadata:        make(map[string]interface{}),
 	}
 
-	if err := database.CreateQuotaSnapshot(snapshot); err != nil {
+	if err := s.store.CreateQuotaSnapshot(snapshot); err != nil {
 		s.logger.WithError(err).Error("Failed to record quota snapshot")
 	}
 
 	return quota, nil
 }
 
-// CleanupSingleIPBlocks removes all single-IP blocks (except those in use)
+// CleanupSingleIPBlocks removes all single-IP blocks (except protected and in-use ones)
 func (s *Service) CleanupSingleIPBlocks(ctx context.Context) (int, error) {
 	s.logger.Info("Starting cleanup of single-IP blocks")
 
@@ -391,11 +502,16 @@ func (s *Service) CleanupSingleIPBlocks(ctx context.Context) (int, error) {
 	}
 
 	// Get all reserved IPs from database to avoid deleting in-use blocks
-	reservedIPs, err := database.ListReservedIPs(nil, nil, nil)
+	reservedIPs, err := s.store.ListReservedIPs(nil, nil, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to list reserved IPs: %w", err)
 	}
 
+	protected, err := s.protectedBlockIDs()
+	if err != nil {
+		return 0, err
+	}
+
 	inUseBlocks := make(map[string]bool)
 	for _, ip := range reservedIPs {
 		if ip.Status == "in_use" || ip.Status == "reserved" {
@@ -405,8 +521,8 @@ func (s *Service) CleanupSingleIPBlocks(ctx context.Context) (int, error) {
 
 	deletedCount := 0
 	for _, block := range blocks.Items {
-		// Skip protected blocks (11-IP blocks)
-		if block.Properties.Size == 11 || len(block.Properties.IPs) == 11 {
+		// Skip protected blocks
+		if protected[block.ID] {
 			continue
 		}
 
@@ -428,7 +544,7 @@ func (s *Service) CleanupSingleIPBlocks(ctx context.Context) (int, error) {
 			}
 
 			deletedCount++
-			time.Sleep(300 * time.Millisecond) // Rate limiting
+			time.Sleep(s.cleanupDelay) // Rate limiting
 		}
 	}
 
@@ -438,7 +554,7 @@ func (s *Service) CleanupSingleIPBlocks(ctx context.Context) (int, error) {
 
 // RecheckBlacklist rechecks an IP against blacklists
 func (s *Service) RecheckBlacklist(ctx context.Context, ipID int) error {
-	ip, err := database.GetReservedIPByID(ipID)
+	ip, err := s.store.GetReservedIPByID(ipID)
 	if err != nil {
 		return fmt.Errorf("failed to get reserved IP: %w", err)
 	}
@@ -451,7 +567,7 @@ func (s *Service) RecheckBlacklist(ctx context.Context, ipID int) error {
 	}
 
 	// Update database
-	if err := database.UpdateReservedIPBlacklistStatus(ipID, result.IsBlacklisted, result.Blacklists); err != nil {
+	if err := s.store.UpdateReservedIPBlacklistStatus(ipID, result.IsBlacklisted, result.Blacklists); err != nil {
 		return fmt.Errorf("failed to update blacklist status: %w", err)
 	}
 
@@ -466,7 +582,7 @@ func (s *Service) RecheckBlacklist(ctx context.Context, ipID int) error {
 		Metadata:        make(map[string]interface{}),
 	}
 
-	if err := database.CreateBlacklistHistoryEntry(historyEntry); err != nil {
+	if err := s.store.CreateBlacklistHistoryEntry(historyEntry); err != nil {
 		s.logger.WithError(err).Error("Failed to record blacklist history")
 	}
 
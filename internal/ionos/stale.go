@@ -0,0 +1,350 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// StaleFlaggedAtKey is the reserved IP metadata key holding when the IP was
+// flagged stale
+const StaleFlaggedAtKey = "stale_flagged_at"
+
+// Audit actions recorded in ip_actions by the stale reservation policy
+const (
+	ActionStaleFlagged  = "reservation_stale_flagged"
+	ActionStaleReleased = "reservation_auto_released"
+)
+
+// ErrStalePolicyDisabled is returned when no idle age is configured
+var ErrStalePolicyDisabled = errors.New("stale reservation policy is disabled")
+
+// StalePolicy decides when a reserved IP that was never assigned is stale.
+// An IP idle for MaxIdle is flagged and its owners notified; with
+// AutoRelease it is released once GracePeriod has passed since the flag.
+type StalePolicy struct {
+	MaxIdle     time.Duration `json:"max_idle"`
+	GracePeriod time.Duration `json:"grace_period"`
+	AutoRelease bool          `json:"auto_release"`
+}
+
+// StaleIP is a stale reservation with the owners who are told about it
+type StaleIP struct {
+	database.ReservedIP
+	IdleHours    float64            `json:"idle_hours"`
+	Owners       []database.IPOwner `json:"owners"`
+	FlaggedAt    time.Time          `json:"flagged_at"`
+	ReleaseAfter time.Time          `json:"release_after"`
+}
+
+// StaleReport is the outcome of one stale policy evaluation, or the plan
+// on a dry run
+type StaleReport struct {
+	Policy    StalePolicy `json:"policy"`
+	DryRun    bool        `json:"dry_run"`
+	CheckedAt time.Time   `json:"checked_at"`
+	// Flagged were flagged by this evaluation, Released auto-released;
+	// Pending were flagged earlier and are within their grace period, or
+	// auto-release is off or held back by the remediation mode
+	Flagged  []StaleIP `json:"flagged"`
+	Released []StaleIP `json:"released"`
+	Pending  []StaleIP `json:"pending"`
+	Errors   int       `json:"errors"`
+}
+
+// SetStalePolicy sets the policy EvaluateStaleReservations applies
+func (s *Service) SetStalePolicy(policy StalePolicy) {
+	s.staleMu.Lock()
+	defer s.staleMu.Unlock()
+	s.stalePolicy = policy
+}
+
+// StalePolicy returns the configured stale reservation policy
+func (s *Service) StalePolicy() StalePolicy {
+	s.staleMu.Lock()
+	defer s.staleMu.Unlock()
+	return s.stalePolicy
+}
+
+// EvaluateStaleReservations flags reserved IPs that were never assigned and
+// have been idle longer than the policy allows, and auto-releases flagged
+// IPs past their grace period. Every flag and release is audited in the IP
+// actions and published to the reservation webhooks with the IP's owners.
+func (s *Service) EvaluateStaleReservations(ctx context.Context, dryRun bool) (*StaleReport, error) {
+	policy := s.StalePolicy()
+	if policy.MaxIdle <= 0 {
+		return nil, ErrStalePolicyDisabled
+	}
+
+	status := "reserved"
+	ips, err := s.store.ListReservedIPs(&status, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved IPs: %w", err)
+	}
+
+	now := time.Now()
+	report := &StaleReport{
+		Policy:    policy,
+		DryRun:    dryRun,
+		CheckedAt: now,
+		Flagged:   []StaleIP{},
+		Released:  []StaleIP{},
+		Pending:   []StaleIP{},
+	}
+
+	for _, ip := range ips {
+		if ip.UsageCount > 0 || ip.AssignedTo != nil {
+			continue
+		}
+		idle := now.Sub(ip.ReservedAt)
+		if idle < policy.MaxIdle {
+			continue
+		}
+
+		owners, err := s.store.GetIPOwners(ip.IPAddress)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "stale_reservation_owners",
+				"ip":     ip.IPAddress,
+				"error":  err.Error(),
+			}).Warn("Failed to look up IP owners")
+		}
+		if owners == nil {
+			owners = []database.IPOwner{}
+		}
+		stale := StaleIP{ReservedIP: ip, IdleHours: idle.Hours(), Owners: owners}
+
+		flaggedAt, flagged := staleFlaggedAt(ip)
+		if !flagged {
+			stale.FlaggedAt = now
+			stale.ReleaseAfter = now.Add(policy.GracePeriod)
+			if !dryRun {
+				if err := s.store.FlagReservedIPStale(ip.ID, now); err != nil {
+					s.logStaleError(ip, "flag", err)
+					report.Errors++
+					continue
+				}
+				s.auditStale(stale, ActionStaleFlagged, "reserved", fmt.Sprintf("reserved but unassigned for %s", idle.Round(time.Hour)), remediation.Enforce)
+				s.publishStale(stale, EventReservationStale)
+			}
+			report.Flagged = append(report.Flagged, stale)
+			continue
+		}
+
+		stale.FlaggedAt = flaggedAt
+		stale.ReleaseAfter = flaggedAt.Add(policy.GracePeriod)
+		if !policy.AutoRelease || now.Before(stale.ReleaseAfter) {
+			report.Pending = append(report.Pending, stale)
+			continue
+		}
+
+		if mode := remediation.Current(); !dryRun && mode != remediation.Enforce {
+			// Auto-release is remediation: held back outside enforce mode,
+			// and audited once as simulated in dry-run mode
+			if mode == remediation.DryRun && s.simulations.Changed(fmt.Sprintf("stale:%d", ip.ID), "released") {
+				s.auditStale(stale, ActionStaleReleased, "released", fmt.Sprintf("still unassigned %s after being flagged stale", policy.GracePeriod), mode)
+			}
+			report.Pending = append(report.Pending, stale)
+			continue
+		}
+		if !dryRun {
+			if _, err := s.ReleaseIP(ctx, ip.ID); err != nil {
+				s.logStaleError(ip, "release", err)
+				report.Errors++
+				continue
+			}
+			stale.Status = "released"
+			s.auditStale(stale, ActionStaleReleased, "released", fmt.Sprintf("still unassigned %s after being flagged stale", policy.GracePeriod), remediation.Enforce)
+			s.publishStale(stale, EventReservationReleased)
+		}
+		report.Released = append(report.Released, stale)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":   "evaluate_stale_reservations",
+		"flagged":  len(report.Flagged),
+		"released": len(report.Released),
+		"pending":  len(report.Pending),
+		"errors":   report.Errors,
+		"dry_run":  dryRun,
+	}).Info("Stale reservation policy evaluated")
+
+	return report, nil
+}
+
+// staleFlaggedAt returns when the IP was flagged stale, if it was
+func staleFlaggedAt(ip database.ReservedIP) (time.Time, bool) {
+	value, _ := ip.Metadata[StaleFlaggedAtKey].(string)
+	flaggedAt, err := time.Parse(time.RFC3339, value)
+	return flaggedAt, err == nil
+}
+
+// auditStale records a stale policy decision in the IP actions
+func (s *Service) auditStale(stale StaleIP, action, newStatus, reason string, mode remediation.Mode) {
+	owners := make([]string, 0, len(stale.Owners))
+	for _, o := range stale.Owners {
+		owners = append(owners, o.OwnerType+":"+o.OwnerName)
+	}
+
+	record := &database.IPAction{
+		IP:             stale.IPAddress,
+		Action:         action,
+		PreviousStatus: "reserved",
+		NewStatus:      newStatus,
+		Reason:         reason,
+		TriggeredBy:    "stale_policy",
+		Metadata: map[string]interface{}{
+			"reserved_ip_id": stale.ID,
+			"block_id":       stale.ReservationBlockID,
+			"idle_hours":     stale.IdleHours,
+			"owners":         owners,
+			"release_after":  stale.ReleaseAfter,
+		},
+		CreatedAt: time.Now(),
+	}
+	remediation.Tag(record, mode)
+	if err := s.store.InsertIPAction(record); err != nil {
+		s.logStaleError(stale.ReservedIP, "audit", err)
+	}
+}
+
+// publishStale tells the webhooks about a flagged or released IP
+func (s *Service) publishStale(stale StaleIP, eventType string) {
+	now := time.Now()
+	s.publisher.Publish(ReservationEvent{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		Location:    stale.Location,
+		IPs:         []database.ReservedIP{stale.ReservedIP},
+		Owners:      stale.Owners,
+		StartedAt:   stale.FlaggedAt,
+		CompletedAt: now,
+	})
+}
+
+func (s *Service) logStaleError(ip database.ReservedIP, step string, err error) {
+	s.logger.WithFields(logrus.Fields{
+		"action": "stale_reservation_" + step,
+		"ip":     ip.IPAddress,
+		"error":  err.Error(),
+	}).Error("Stale reservation policy step failed")
+}
+
+// StaleReservationService periodically applies the stale reservation policy
+type StaleReservationService struct {
+	service  *Service
+	logger   *logrus.Logger
+	ticker   *time.Ticker
+	stopChan chan bool
+	running  bool
+	mu       sync.Mutex
+	lastRun  time.Time
+	flagged  int
+	released int
+	errors   int
+}
+
+// NewStaleReservationService creates a scheduler for the service's policy
+func NewStaleReservationService(service *Service, logger *logrus.Logger) *StaleReservationService {
+	return &StaleReservationService{
+		service:  service,
+		logger:   logger,
+		stopChan: make(chan bool),
+	}
+}
+
+// Start begins the periodic policy evaluation
+func (s *StaleReservationService) Start(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("stale reservation service is already running")
+	}
+	if s.service.StalePolicy().MaxIdle <= 0 {
+		return ErrStalePolicyDisabled
+	}
+
+	s.ticker = time.NewTicker(interval)
+	s.running = true
+
+	s.logger.WithFields(logrus.Fields{
+		"action":   "stale_reservation_service_start",
+		"interval": interval.String(),
+	}).Info("Starting stale reservation service")
+
+	go s.RunOnce()
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.RunOnce()
+			case <-s.stopChan:
+				s.logger.Info("Stale reservation service stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the stale reservation service
+func (s *StaleReservationService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+
+	s.stopChan <- true
+	s.running = false
+}
+
+// RunOnce evaluates the policy once
+func (s *StaleReservationService) RunOnce() {
+	report, err := s.service.EvaluateStaleReservations(context.Background(), false)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = time.Now()
+	if err != nil {
+		s.errors++
+		s.logger.WithFields(logrus.Fields{
+			"action": "stale_reservation_evaluate_failed",
+			"error":  err.Error(),
+		}).Error("Failed to evaluate stale reservations")
+		return
+	}
+	s.flagged += len(report.Flagged)
+	s.released += len(report.Released)
+	s.errors += report.Errors
+}
+
+// GetStats returns service statistics
+func (s *StaleReservationService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"running":  s.running,
+		"last_run": s.lastRun,
+		"flagged":  s.flagged,
+		"released": s.released,
+		"errors":   s.errors,
+	}
+}
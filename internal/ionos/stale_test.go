@@ -0,0 +1,129 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/resolver"
+)
+
+// TestEvaluateStaleReservations tests that idle IPs are flagged, kept through the grace period and then released
+func TestEvaluateStaleReservations(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	publisher := &recordingPublisher{}
+	svc.SetPublisher(publisher)
+	svc.SetStalePolicy(StalePolicy{MaxIdle: 24 * time.Hour, GracePeriod: time.Hour, AutoRelease: true})
+
+	idle := seedPoolIP(mock, store, "203.0.113.1", "reserved", "")
+	store.ips[idle.ID].ReservedAt = time.Now().Add(-48 * time.Hour)
+	recent := seedPoolIP(mock, store, "203.0.113.2", "reserved", "")
+	used := seedPoolIP(mock, store, "203.0.113.3", "reserved", "")
+	store.ips[used.ID].ReservedAt = time.Now().Add(-48 * time.Hour)
+	store.ips[used.ID].UsageCount = 1
+	store.owners["203.0.113.1"] = []database.IPOwner{{OwnerType: "team", OwnerID: 1, OwnerName: "marketing"}}
+
+	plan, err := svc.EvaluateStaleReservations(context.Background(), true)
+	if err != nil {
+		t.Fatalf("EvaluateStaleReservations failed: %v", err)
+	}
+	if len(plan.Flagged) != 1 || len(store.actions) != 0 || len(publisher.events) != 0 {
+		t.Errorf("Expected a dry run to only plan the flag, got %+v", plan)
+	}
+
+	report, err := svc.EvaluateStaleReservations(context.Background(), false)
+	if err != nil {
+		t.Fatalf("EvaluateStaleReservations failed: %v", err)
+	}
+	if len(report.Flagged) != 1 || report.Flagged[0].ID != idle.ID || len(report.Released) != 0 {
+		t.Fatalf("Expected only the idle, never used IP flagged, got %+v", report)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != EventReservationStale || publisher.events[0].Owners[0].OwnerName != "marketing" {
+		t.Errorf("Expected the owners notified, got %+v", publisher.events)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != ActionStaleFlagged || store.actions[0].TriggeredBy != "stale_policy" {
+		t.Errorf("Expected the flag audited, got %+v", store.actions)
+	}
+
+	again, _ := svc.EvaluateStaleReservations(context.Background(), false)
+	if len(again.Flagged) != 0 || len(again.Pending) != 1 || len(publisher.events) != 1 {
+		t.Errorf("Expected the flagged IP kept during the grace period, got %+v", again)
+	}
+
+	store.ips[idle.ID].Metadata[StaleFlaggedAtKey] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	released, _ := svc.EvaluateStaleReservations(context.Background(), false)
+	if len(released.Released) != 1 || len(mock.deleted) != 1 || mock.deleted[0] != idle.ReservationBlockID {
+		t.Fatalf("Expected the IP released after the grace period, got %+v", released)
+	}
+	if ip, _ := store.GetReservedIPByID(idle.ID); ip.Status != "released" {
+		t.Errorf("Expected the IP marked released, got %s", ip.Status)
+	}
+	if ip, _ := store.GetReservedIPByID(recent.ID); ip.Status != "reserved" {
+		t.Errorf("Expected the recent IP untouched, got %s", ip.Status)
+	}
+	if last := store.actions[len(store.actions)-1]; last.Action != ActionStaleReleased || last.NewStatus != "released" {
+		t.Errorf("Expected the release audited, got %+v", last)
+	}
+	if last := publisher.events[len(publisher.events)-1]; last.Type != EventReservationReleased {
+		t.Errorf("Expected a release event, got %s", last.Type)
+	}
+}
+
+// TestEvaluateStaleReservationsWithoutAutoRelease tests that flagged IPs stay reserved when auto-release is off
+func TestEvaluateStaleReservationsWithoutAutoRelease(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	if _, err := svc.EvaluateStaleReservations(context.Background(), false); !errors.Is(err, ErrStalePolicyDisabled) {
+		t.Errorf("Expected ErrStalePolicyDisabled, got %v", err)
+	}
+
+	svc.SetStalePolicy(StalePolicy{MaxIdle: time.Hour})
+	ip := seedPoolIP(mock, store, "203.0.113.1", "reserved", "")
+	store.ips[ip.ID].ReservedAt = time.Now().Add(-2 * time.Hour)
+	store.ips[ip.ID].Metadata[StaleFlaggedAtKey] = time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+
+	report, err := svc.EvaluateStaleReservations(context.Background(), false)
+	if err != nil {
+		t.Fatalf("EvaluateStaleReservations failed: %v", err)
+	}
+	if len(report.Pending) != 1 || len(report.Released) != 0 || len(mock.deleted) != 0 {
+		t.Errorf("Expected the IP left pending, got %+v", report)
+	}
+}
+
+// TestEvaluateStaleReservationsInDryRunMode tests that dry-run remediation keeps a stale IP reserved and records its release once as simulated
+func TestEvaluateStaleReservationsInDryRunMode(t *testing.T) {
+	defer remediation.SetMode(remediation.Enforce)
+	remediation.SetMode(remediation.DryRun)
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	svc.SetStalePolicy(StalePolicy{MaxIdle: 24 * time.Hour, GracePeriod: time.Hour, AutoRelease: true})
+
+	idle := seedPoolIP(mock, store, "203.0.113.1", "reserved", "")
+	store.ips[idle.ID].ReservedAt = time.Now().Add(-48 * time.Hour)
+	store.ips[idle.ID].Metadata[StaleFlaggedAtKey] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+
+	for i := 0; i < 2; i++ {
+		report, err := svc.EvaluateStaleReservations(context.Background(), false)
+		if err != nil || len(report.Released) != 0 || len(report.Pending) != 1 {
+			t.Fatalf("Expected the release held back, got %+v %v", report, err)
+		}
+	}
+	if len(mock.deleted) != 0 {
+		t.Errorf("Expected no IONOS block deleted, got %v", mock.deleted)
+	}
+	if ip, _ := store.GetReservedIPByID(idle.ID); ip.Status != "reserved" {
+		t.Errorf("Expected the IP kept reserved, got %s", ip.Status)
+	}
+	if len(store.actions) != 1 || store.actions[0].Action != ActionStaleReleased || !remediation.IsSimulated(store.actions[0]) {
+		t.Errorf("Expected one simulated release recorded, got %+v", store.actions)
+	}
+}
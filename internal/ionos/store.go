@@ -0,0 +1,120 @@
+package ionos
+
+import (
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// Store persists reservation state. The default implementation delegates to
+// the database package; tests substitute an in-memory store.
+type Store interface {
+	CreateReservedIP(ip *database.ReservedIP) error
+	GetReservedIPByID(id int) (*database.ReservedIP, error)
+	GetReservedIPByAddress(ipAddress string) (*database.ReservedIP, error)
+	ListReservedIPs(status *string, isBlacklisted *bool, location *string) ([]database.ReservedIP, error)
+	ListReservedIPsByBlock(blockID string) ([]database.ReservedIP, error)
+	UpdateReservedIPBlacklistStatus(id int, isBlacklisted bool, blacklists []string) error
+	ReleaseReservedIP(id int) error
+	UpdateReservedIPAttachment(id int, attachment map[string]interface{}, assignedTo string) error
+	CreateReservationAttempt(attempt *database.ReservationAttempt) error
+	CreateBlacklistHistoryEntry(entry *database.BlacklistHistoryEntry) error
+	CreateQuotaSnapshot(snapshot *database.QuotaSnapshot) error
+	ProtectBlock(block *database.ProtectedBlock) (*database.ProtectedBlock, error)
+	ListProtectedBlocks() ([]database.ProtectedBlock, error)
+	UnprotectBlock(blockID string) error
+	FlagReservedIPStale(id int, flaggedAt time.Time) error
+	GetIPOwners(ip string) ([]database.IPOwner, error)
+	InsertIPAction(action *database.IPAction) error
+	QuarantineReservedIP(id int, previousStatus string) error
+	RestoreReservedIPStatus(id int, status string) error
+	SwapReservedIPs(replacedID, replacementID int, assignedTo *string, swappedFor string, actions []*database.IPAction) error
+	GetAllIPReputationMetrics(status string) ([]database.IPReputationMetrics, error)
+}
+
+// dbStore is the Store backed by the global database connection
+type dbStore struct{}
+
+func (dbStore) CreateReservedIP(ip *database.ReservedIP) error {
+	return database.CreateReservedIP(ip)
+}
+
+func (dbStore) GetReservedIPByID(id int) (*database.ReservedIP, error) {
+	return database.GetReservedIPByID(id)
+}
+
+func (dbStore) GetReservedIPByAddress(ipAddress string) (*database.ReservedIP, error) {
+	return database.GetReservedIPByAddress(ipAddress)
+}
+
+func (dbStore) ListReservedIPs(status *string, isBlacklisted *bool, location *string) ([]database.ReservedIP, error) {
+	return database.ListReservedIPs(status, isBlacklisted, location)
+}
+
+func (dbStore) ListReservedIPsByBlock(blockID string) ([]database.ReservedIP, error) {
+	return database.ListReservedIPsByBlock(blockID)
+}
+
+func (dbStore) UpdateReservedIPBlacklistStatus(id int, isBlacklisted bool, blacklists []string) error {
+	return database.UpdateReservedIPBlacklistStatus(id, isBlacklisted, blacklists)
+}
+
+func (dbStore) ReleaseReservedIP(id int) error {
+	return database.ReleaseReservedIP(id)
+}
+
+func (dbStore) UpdateReservedIPAttachment(id int, attachment map[string]interface{}, assignedTo string) error {
+	return database.UpdateReservedIPAttachment(id, attachment, assignedTo)
+}
+
+func (dbStore) CreateReservationAttempt(attempt *database.ReservationAttempt) error {
+	return database.CreateReservationAttempt(attempt)
+}
+
+func (dbStore) CreateBlacklistHistoryEntry(entry *database.BlacklistHistoryEntry) error {
+	return database.CreateBlacklistHistoryEntry(entry)
+}
+
+func (dbStore) CreateQuotaSnapshot(snapshot *database.QuotaSnapshot) error {
+	return database.CreateQuotaSnapshot(snapshot)
+}
+
+func (dbStore) ProtectBlock(block *database.ProtectedBlock) (*database.ProtectedBlock, error) {
+	return database.ProtectBlock(block)
+}
+
+func (dbStore) ListProtectedBlocks() ([]database.ProtectedBlock, error) {
+	return database.ListProtectedBlocks()
+}
+
+func (dbStore) UnprotectBlock(blockID string) error {
+	return database.UnprotectBlock(blockID)
+}
+
+func (dbStore) FlagReservedIPStale(id int, flaggedAt time.Time) error {
+	return database.FlagReservedIPStale(id, flaggedAt)
+}
+
+func (dbStore) GetIPOwners(ip string) ([]database.IPOwner, error) {
+	return database.GetIPOwners(ip)
+}
+
+func (dbStore) InsertIPAction(action *database.IPAction) error {
+	return database.InsertIPAction(action)
+}
+
+func (dbStore) QuarantineReservedIP(id int, previousStatus string) error {
+	return database.QuarantineReservedIP(id, previousStatus)
+}
+
+func (dbStore) RestoreReservedIPStatus(id int, status string) error {
+	return database.RestoreReservedIPStatus(id, status)
+}
+
+func (dbStore) SwapReservedIPs(replacedID, replacementID int, assignedTo *string, swappedFor string, actions []*database.IPAction) error {
+	return database.SwapReservedIPs(replacedID, replacementID, assignedTo, swappedFor, actions)
+}
+
+func (dbStore) GetAllIPReputationMetrics(status string) ([]database.IPReputationMetrics, error) {
+	return database.GetAllIPReputationMetrics(status)
+}
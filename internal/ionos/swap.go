@@ -0,0 +1,254 @@
+package ionos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SwappedForKey is the reserved IP metadata key holding the IP a
+// replacement was put in use for
+const SwappedForKey = "swapped_for"
+
+// Audit actions recorded in ip_actions for both sides of a swap
+const (
+	ActionIPSwappedOut = "ip_swapped_out"
+	ActionIPSwappedIn  = "ip_swapped_in"
+)
+
+// Swap errors
+var (
+	ErrNotSwappable   = errors.New("IP is not in use")
+	ErrNoCleanReserve = errors.New("no clean reserved IP available in the location")
+)
+
+// SwapResult describes a swap of an IP for a clean reserved IP
+type SwapResult struct {
+	Replaced    database.ReservedIP `json:"replaced"`
+	Replacement database.ReservedIP `json:"replacement"`
+	DryRun      bool                `json:"dry_run"`
+}
+
+// swappable reports whether ip is in use, or was in use until its
+// reputation quarantined it
+func swappable(ip database.ReservedIP) bool {
+	if ip.Status == "in_use" {
+		return true
+	}
+	previous, _ := ip.Metadata[ReputationPreviousStatusKey].(string)
+	return ip.Status == "quarantined" && previous == "in_use"
+}
+
+// swapCandidates returns the clean reserved IPs of location that can
+// replace an IP, least used and then longest reserved first. IPs whose
+// reputation is quarantine or blacklisted are not clean even when no DNSBL
+// lists them.
+func (s *Service) swapCandidates(location string) ([]database.ReservedIP, error) {
+	status, blacklisted := "reserved", false
+	ips, err := s.store.ListReservedIPs(&status, &blacklisted, &location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reserved IPs: %w", err)
+	}
+	metrics, err := s.store.GetAllIPReputationMetrics("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP reputation metrics: %w", err)
+	}
+	burned := make(map[string]bool)
+	for _, m := range metrics {
+		if reputation := state.Status(m.Status); reputation == state.Quarantine || reputation == state.Blacklisted {
+			burned[m.IP] = true
+		}
+	}
+
+	candidates := make([]database.ReservedIP, 0, len(ips))
+	for _, ip := range ips {
+		if !burned[ip.IPAddress] {
+			candidates = append(candidates, ip)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.UsageCount != b.UsageCount {
+			return a.UsageCount < b.UsageCount
+		}
+		if !a.ReservedAt.Equal(b.ReservedAt) {
+			return a.ReservedAt.Before(b.ReservedAt)
+		}
+		return a.ID < b.ID
+	})
+	return candidates, nil
+}
+
+// SwapIP replaces an IP in use with a clean reserved IP of its location:
+// the replacement is put in use for the same assignment and the IP is
+// quarantined, to go back to the reserve once its reputation recovers.
+// With dryRun the replacement is picked but nothing changes. Binding the
+// replacement to a NIC is left to AttachIP.
+func (s *Service) SwapIP(ip, reason, triggeredBy string, dryRun bool) (*SwapResult, error) {
+	replaced, err := s.store.GetReservedIPByAddress(ip)
+	if err != nil {
+		return nil, err
+	}
+	if !swappable(*replaced) {
+		return nil, ErrNotSwappable
+	}
+
+	candidates, err := s.swapCandidates(replaced.Location)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoCleanReserve
+	}
+	if dryRun {
+		return &SwapResult{Replaced: *replaced, Replacement: candidates[0], DryRun: true}, nil
+	}
+
+	// A concurrent swap may claim a candidate first; move on to the next.
+	// The claim, the quarantine and their audit records are one transaction.
+	var result *SwapResult
+	for i := range candidates {
+		candidate := &SwapResult{Replaced: *replaced, Replacement: candidates[i]}
+		actions := swapActions(*candidate, reason, triggeredBy, remediation.Enforce)
+		err := s.store.SwapReservedIPs(replaced.ID, candidates[i].ID, replaced.AssignedTo, replaced.IPAddress, actions)
+		if errors.Is(err, database.ErrReservedIPUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to swap %s for %s: %w", replaced.IPAddress, candidates[i].IPAddress, err)
+		}
+		result = candidate
+		break
+	}
+	if result == nil {
+		return nil, ErrNoCleanReserve
+	}
+	s.logSwap(*result, triggeredBy, remediation.Enforce)
+
+	result.Replaced.Status = "quarantined"
+	result.Replaced.Metadata = withMetadata(replaced.Metadata, ReputationPreviousStatusKey, "reserved")
+	result.Replacement.Status = "in_use"
+	result.Replacement.AssignedTo = replaced.AssignedTo
+	result.Replacement.UsageCount++
+	result.Replacement.Metadata = withMetadata(result.Replacement.Metadata, SwappedForKey, replaced.IPAddress)
+	return result, nil
+}
+
+// SwapHook returns a state machine hook that swaps an IP turning
+// blacklisted for a clean reserved IP while auto_rotation is enabled. Add
+// it after ReputationHook. Outside enforce mode nothing is swapped; in
+// dry-run mode the swap it would have made is audited as simulated.
+func (s *Service) SwapHook() state.Hook {
+	return func(t state.Transition) {
+		if t.To != state.Blacklisted || !features.Enabled(features.AutoRotation) {
+			return
+		}
+		mode := remediation.Current()
+		if mode == remediation.Off {
+			return
+		}
+
+		reason := fmt.Sprintf("reputation status is %s", t.To)
+		triggeredBy := "auto_rotation:" + t.TriggeredBy
+		result, err := s.SwapIP(t.IP, reason, triggeredBy, mode == remediation.DryRun)
+		if errors.Is(err, database.ErrReservedIPNotFound) || errors.Is(err, ErrNotSwappable) {
+			return
+		}
+		if err != nil {
+			entry := s.logger.WithFields(logrus.Fields{
+				"action": "auto_rotation_failed",
+				"ip":     t.IP,
+				"error":  err.Error(),
+			})
+			if errors.Is(err, ErrNoCleanReserve) {
+				entry.Warn("No clean reserved IP to swap a blacklisted IP for")
+			} else {
+				entry.Error("Failed to swap blacklisted IP")
+			}
+			return
+		}
+		if result.DryRun {
+			s.auditSimulatedSwap(*result, reason, triggeredBy, mode)
+		}
+	}
+}
+
+// swapActions returns the IP actions recording both sides of a swap
+func swapActions(result SwapResult, reason, triggeredBy string, mode remediation.Mode) []*database.IPAction {
+	replaced, replacement := result.Replaced, result.Replacement
+	metadata := func(ip database.ReservedIP, key, other string) map[string]interface{} {
+		m := map[string]interface{}{
+			"reserved_ip_id": ip.ID,
+			"block_id":       ip.ReservationBlockID,
+			"location":       ip.Location,
+			key:              other,
+		}
+		if replaced.AssignedTo != nil {
+			m["assigned_to"] = *replaced.AssignedTo
+		}
+		return m
+	}
+	now := time.Now()
+	records := []*database.IPAction{
+		{
+			IP:     replaced.IPAddress,
+			Action: ActionIPSwappedOut,
+			// The replaced IP was in use, even when ReputationHook
+			// quarantined it before the swap
+			PreviousStatus: "in_use",
+			NewStatus:      "quarantined",
+			Reason:         reason,
+			TriggeredBy:    triggeredBy,
+			Metadata:       metadata(replaced, "replacement_ip", replacement.IPAddress),
+			CreatedAt:      now,
+		},
+		{
+			IP:             replacement.IPAddress,
+			Action:         ActionIPSwappedIn,
+			PreviousStatus: replacement.Status,
+			NewStatus:      "in_use",
+			Reason:         reason,
+			TriggeredBy:    triggeredBy,
+			Metadata:       metadata(replacement, "replaced_ip", replaced.IPAddress),
+			CreatedAt:      now,
+		},
+	}
+	for _, record := range records {
+		remediation.Tag(record, mode)
+	}
+	return records
+}
+
+// auditSimulatedSwap records a swap a dry run would have made
+func (s *Service) auditSimulatedSwap(result SwapResult, reason, triggeredBy string, mode remediation.Mode) {
+	for _, record := range swapActions(result, reason, triggeredBy, mode) {
+		if err := s.store.InsertIPAction(record); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "ip_swap_audit",
+				"ip":     record.IP,
+				"error":  err.Error(),
+			}).Error("Failed to record IP swap")
+		}
+	}
+	s.logSwap(result, triggeredBy, mode)
+}
+
+// logSwap logs a swap made or, in dry-run mode, simulated
+func (s *Service) logSwap(result SwapResult, triggeredBy string, mode remediation.Mode) {
+	s.logger.WithFields(logrus.Fields{
+		"action":           "ip_swapped",
+		"ip":               result.Replaced.IPAddress,
+		"replacement_ip":   result.Replacement.IPAddress,
+		"location":         result.Replaced.Location,
+		"triggered_by":     triggeredBy,
+		"remediation_mode": string(mode),
+	}).Warn("IP swapped for a clean reserved IP")
+}
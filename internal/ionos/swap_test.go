@@ -0,0 +1,156 @@
+package ionos
+
+import (
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+	"golang-backend-service/internal/resolver"
+)
+
+// TestSwapIP tests that the least used clean reserve of the location replaces an IP in use
+func TestSwapIP(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+
+	bad := seedPoolIP(mock, store, "203.0.113.1", "in_use", "")
+	assignedTo := "mta-1"
+	store.ips[bad.ID].AssignedTo = &assignedTo
+	used := seedPoolIP(mock, store, "203.0.113.2", "reserved", "")
+	store.ips[used.ID].UsageCount = 3
+	burned := seedPoolIP(mock, store, "203.0.113.3", "reserved", "")
+	listed := seedPoolIP(mock, store, "203.0.113.4", "reserved", "")
+	store.ips[listed.ID].IsBlacklisted = true
+	elsewhere := seedPoolIP(mock, store, "203.0.113.5", "reserved", "")
+	store.ips[elsewhere.ID].Location = "de/fra"
+	clean := seedPoolIP(mock, store, "203.0.113.6", "reserved", "")
+	store.metrics = []database.IPReputationMetrics{{IP: burned.IPAddress, Status: "quarantine"}}
+
+	preview, err := svc.SwapIP("203.0.113.1", "manual", "alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.DryRun || preview.Replacement.IPAddress != clean.IPAddress || len(store.actions) != 0 {
+		t.Fatalf("Expected %s picked without changes, got %+v %+v", clean.IPAddress, preview, store.actions)
+	}
+	if unchanged, _ := store.GetReservedIPByID(clean.ID); unchanged.Status != "reserved" {
+		t.Errorf("Expected a dry run to leave the replacement reserved, got %s", unchanged.Status)
+	}
+
+	result, err := svc.SwapIP("203.0.113.1", "manual", "alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement, _ := store.GetReservedIPByID(clean.ID)
+	if replacement.Status != "in_use" || replacement.AssignedTo == nil || *replacement.AssignedTo != "mta-1" || replacement.Metadata[SwappedForKey] != "203.0.113.1" {
+		t.Errorf("Expected the replacement in use for mta-1, got %+v", replacement)
+	}
+	replaced, _ := store.GetReservedIPByID(bad.ID)
+	if replaced.Status != "quarantined" || replaced.Metadata[ReputationPreviousStatusKey] != "reserved" {
+		t.Errorf("Expected the IP quarantined to return to the reserve, got %s %+v", replaced.Status, replaced.Metadata)
+	}
+	if result.Replaced.Status != "quarantined" || result.Replacement.Status != "in_use" {
+		t.Errorf("Expected the updated records returned, got %+v", result)
+	}
+	if len(store.actions) != 2 || store.actions[0].Action != ActionIPSwappedOut || store.actions[1].Action != ActionIPSwappedIn {
+		t.Fatalf("Expected both sides of the swap audited, got %+v", store.actions)
+	}
+	if out := store.actions[0]; out.PreviousStatus != "in_use" || out.Metadata["replacement_ip"] != clean.IPAddress || out.TriggeredBy != "alice" || remediation.IsSimulated(out) {
+		t.Errorf("Unexpected swap out action: %+v", out)
+	}
+
+	// Once the reputation recovers, the IP goes back to the reserve
+	svc.SyncReputationStatus("203.0.113.1", state.Healthy, "manual")
+	if recovered, _ := store.GetReservedIPByID(bad.ID); recovered.Status != "reserved" {
+		t.Errorf("Expected the recovered IP reserved, got %s", recovered.Status)
+	}
+}
+
+// TestSwapIPErrors tests that unknown, idle and unreplaceable IPs are refused
+func TestSwapIPErrors(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	seedPoolIP(mock, store, "203.0.113.1", "in_use", "")
+	seedPoolIP(mock, store, "203.0.113.2", "reserved", "")
+	seedPoolIP(mock, store, "203.0.113.3", "in_use", "")
+
+	if _, err := svc.SwapIP("198.51.100.1", "", "alice", false); !errors.Is(err, database.ErrReservedIPNotFound) {
+		t.Errorf("Expected ErrReservedIPNotFound, got %v", err)
+	}
+	if _, err := svc.SwapIP("203.0.113.2", "", "alice", false); !errors.Is(err, ErrNotSwappable) {
+		t.Errorf("Expected a reserved IP not swappable, got %v", err)
+	}
+	if _, err := svc.SwapIP("203.0.113.1", "", "alice", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SwapIP("203.0.113.3", "", "alice", false); !errors.Is(err, ErrNoCleanReserve) {
+		t.Errorf("Expected ErrNoCleanReserve once the reserve is used up, got %v", err)
+	}
+}
+
+// TestSwapHook tests that a blacklisting swaps the IP only with auto_rotation enabled and honors the remediation mode
+func TestSwapHook(t *testing.T) {
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, store := newTestService(mock, resolver.NewFake(), 50)
+	bad := seedPoolIP(mock, store, "203.0.113.1", "in_use", "")
+	clean := seedPoolIP(mock, store, "203.0.113.2", "reserved", "")
+
+	machine := state.NewMachine()
+	machine.AddHook(svc.ReputationHook())
+	machine.AddHook(svc.SwapHook())
+	blacklist := func() {
+		machine.Apply(state.Transition{IP: "203.0.113.1", From: state.Healthy, To: state.Blacklisted, TriggeredBy: "automated_aggregation"}, nil)
+	}
+
+	remediation.SetMode(remediation.Off)
+	defer remediation.SetMode(remediation.Enforce)
+	features.Default.Configure(map[string]bool{features.AutoRotation: true})
+	defer features.Default.Configure(nil)
+	blacklist()
+	if len(store.actions) != 0 {
+		t.Errorf("Expected nothing done with remediation off, got %+v", store.actions)
+	}
+
+	remediation.SetMode(remediation.DryRun)
+	blacklist()
+	var simulated []string
+	for _, action := range store.actions {
+		if remediation.IsSimulated(action) {
+			simulated = append(simulated, action.Action)
+		}
+	}
+	if len(simulated) != 3 || simulated[1] != ActionIPSwappedOut || simulated[2] != ActionIPSwappedIn {
+		t.Errorf("Expected the quarantine and swap simulated, got %v", simulated)
+	}
+	if ip, _ := store.GetReservedIPByID(clean.ID); ip.Status != "reserved" {
+		t.Errorf("Expected a dry run to leave the reserve alone, got %s", ip.Status)
+	}
+
+	remediation.SetMode(remediation.Enforce)
+	features.Default.Configure(nil)
+	store.actions = nil
+	blacklist()
+	if ip, _ := store.GetReservedIPByID(clean.ID); ip.Status != "reserved" {
+		t.Errorf("Expected no swap with auto_rotation disabled, got %s", ip.Status)
+	}
+
+	features.Default.Configure(map[string]bool{features.AutoRotation: true})
+	store.RestoreReservedIPStatus(bad.ID, "in_use")
+	store.actions = nil
+	blacklist()
+	if ip, _ := store.GetReservedIPByID(clean.ID); ip.Status != "in_use" || ip.Metadata[SwappedForKey] != "203.0.113.1" {
+		t.Errorf("Expected the reserve swapped in, got %+v", ip)
+	}
+	if ip, _ := store.GetReservedIPByID(bad.ID); ip.Status != "quarantined" || ip.Metadata[ReputationPreviousStatusKey] != "reserved" {
+		t.Errorf("Expected the blacklisted IP bound for the reserve, got %s %+v", ip.Status, ip.Metadata)
+	}
+	if len(store.actions) != 3 || store.actions[1].PreviousStatus != "in_use" || store.actions[1].TriggeredBy != "auto_rotation:automated_aggregation" {
+		t.Errorf("Expected the quarantine then the swap audited, got %+v", store.actions)
+	}
+}
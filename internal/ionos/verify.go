@@ -0,0 +1,205 @@
+package ionos
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/resolver"
+)
+
+// Verification check names
+const (
+	CheckDNSBL = "dnsbl"
+	CheckPTR   = "ptr"
+	CheckSMTP  = "smtp"
+)
+
+const verifyCheckTimeout = 10 * time.Second
+
+// VerificationConfig selects the checks run on every newly reserved IP on
+// top of the DNSBL check, which always runs
+type VerificationConfig struct {
+	// PTR requires the IP to have a reverse DNS name
+	PTR bool
+	// SMTPTarget is a host:port whose SMTP banner must be reachable; since
+	// the new IP is not attached yet this tests outbound port 25 from the
+	// datacenter the service runs in. Empty disables the check.
+	SMTPTarget string
+	// MinScore is the lowest average check score an IP may have, 0 to 1
+	MinScore float64
+}
+
+// ptrResolver performs the PTR and forward-confirmation lookups
+type ptrResolver interface {
+	resolver.Resolver
+	resolver.AddrResolver
+}
+
+// CheckResult is the outcome of one verification step
+type CheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Score grades the result from 0 to 1, e.g. 0.5 for a PTR name that
+	// does not resolve back to the IP
+	Score      float64  `json:"score"`
+	Detail     string   `json:"detail,omitempty"`
+	Blacklists []string `json:"blacklists,omitempty"`
+	DurationMs int      `json:"duration_ms"`
+}
+
+// Verification is the outcome of the pipeline for one IP, stored on the
+// reservation attempt
+type Verification struct {
+	Checks []CheckResult `json:"checks"`
+	Score  float64       `json:"score"`
+	// Passed is true when every check passed and Score reaches the minimum
+	Passed bool     `json:"passed"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+// blacklist returns the DNSBL step as a BlacklistResult
+func (v *Verification) blacklist(ip string) *BlacklistResult {
+	result := &BlacklistResult{IP: ip, Blacklists: []string{}}
+	for _, check := range v.Checks {
+		if check.Name == CheckDNSBL {
+			result.IsBlacklisted = !check.Passed
+			if check.Blacklists != nil {
+				result.Blacklists = check.Blacklists
+			}
+			result.CheckDuration = time.Duration(check.DurationMs) * time.Millisecond
+		}
+	}
+	return result
+}
+
+// SetVerification configures the checks a new IP must pass to be kept
+func (s *Service) SetVerification(cfg VerificationConfig) {
+	s.verification = cfg
+}
+
+// verifyIP runs the enabled checks on a newly reserved IP
+func (s *Service) verifyIP(ctx context.Context, ip string) *Verification {
+	checks := []func(context.Context, string) CheckResult{s.checkDNSBL}
+	if s.verification.PTR {
+		checks = append(checks, s.checkPTR)
+	}
+	if s.verification.SMTPTarget != "" {
+		checks = append(checks, s.checkSMTP)
+	}
+
+	v := &Verification{Checks: make([]CheckResult, 0, len(checks)), Passed: true}
+	total := 0.0
+	for _, check := range checks {
+		started := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, verifyCheckTimeout)
+		result := check(checkCtx, ip)
+		cancel()
+		result.DurationMs = int(time.Since(started).Milliseconds())
+
+		v.Checks = append(v.Checks, result)
+		total += result.Score
+		if !result.Passed {
+			v.Passed = false
+			v.Failed = append(v.Failed, result.Name)
+		}
+	}
+
+	v.Score = total / float64(len(v.Checks))
+	if v.Score < s.verification.MinScore {
+		v.Passed = false
+		v.Failed = append(v.Failed, "score")
+	}
+	return v
+}
+
+// checkDNSBL fails listed IPs. A failed lookup does not fail the IP.
+func (s *Service) checkDNSBL(ctx context.Context, ip string) CheckResult {
+	result := CheckResult{Name: CheckDNSBL}
+
+	listing, err := s.blacklistCheck.CheckIP(ctx, ip)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check blacklist")
+		result.Passed = true
+		result.Score = 1
+		result.Detail = fmt.Sprintf("lookup failed: %s", err.Error())
+		return result
+	}
+
+	result.Blacklists = listing.Blacklists
+	if listing.IsBlacklisted {
+		result.Detail = fmt.Sprintf("listed on %d blacklists", len(listing.Blacklists))
+		return result
+	}
+	result.Passed = true
+	result.Score = 1
+	return result
+}
+
+// checkPTR requires a reverse DNS name; a name that resolves back to the
+// IP scores full marks
+func (s *Service) checkPTR(ctx context.Context, ip string) CheckResult {
+	result := CheckResult{Name: CheckPTR}
+
+	names, err := s.ptrResolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		result.Detail = "no PTR record"
+		return result
+	}
+
+	name := strings.TrimSuffix(names[0], ".")
+	result.Passed = true
+	result.Score = 0.5
+	result.Detail = fmt.Sprintf("%s does not resolve back to the IP", name)
+
+	addrs, err := s.ptrResolver.LookupHost(ctx, name)
+	if err != nil {
+		return result
+	}
+	for _, addr := range addrs {
+		if addr == ip {
+			result.Score = 1
+			result.Detail = fmt.Sprintf("forward-confirmed %s", name)
+			break
+		}
+	}
+	return result
+}
+
+// checkSMTP requires a 220 banner from the configured SMTP target
+func (s *Service) checkSMTP(ctx context.Context, ip string) CheckResult {
+	result := CheckResult{Name: CheckSMTP}
+	target := s.verification.SMTPTarget
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		result.Detail = fmt.Sprintf("%s unreachable: %s", target, err.Error())
+		return result
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	banner = strings.TrimSpace(banner)
+	if err != nil && banner == "" {
+		result.Score = 0.5
+		result.Detail = fmt.Sprintf("%s sent no banner: %s", target, err.Error())
+		return result
+	}
+	if !strings.HasPrefix(banner, "220") {
+		result.Score = 0.5
+		result.Detail = fmt.Sprintf("%s answered %q", target, banner)
+		return result
+	}
+
+	result.Passed = true
+	result.Score = 1
+	result.Detail = banner
+	return result
+}
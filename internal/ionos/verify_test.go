@@ -0,0 +1,96 @@
+package ionos
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"golang-backend-service/internal/resolver"
+)
+
+// smtpListener accepts connections on a local port and greets them with banner
+func smtpListener(t *testing.T, banner string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(banner + "\r\n"))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// TestReserveCleanIPsRequiresPTR tests that IPs without reverse DNS are deleted and the results stored on the attempt
+func TestReserveCleanIPsRequiresPTR(t *testing.T) {
+	mock := newMockIONOS("198.51.100.1", "198.51.100.2")
+	defer mock.Close()
+	dns := resolver.NewFake()
+	dns.PTR["198.51.100.2"] = []string{"mail2.example.com."}
+	dns.Records["mail2.example.com"] = []string{"198.51.100.2"}
+	svc, store := newTestService(mock, dns, 50)
+	svc.SetVerification(VerificationConfig{PTR: true})
+
+	response, err := svc.ReserveCleanIPs(context.Background(), 1, "")
+	if err != nil {
+		t.Fatalf("ReserveCleanIPs failed: %v", err)
+	}
+	if response.SuccessCount != 1 || response.RejectedCount != 1 || response.BlacklistedCount != 0 {
+		t.Errorf("Expected one IP rejected for its missing PTR, got %+v", response)
+	}
+	if len(mock.deleted) != 1 || response.ReservedIPs[0].IPAddress != "198.51.100.2" {
+		t.Errorf("Expected the first block deleted and the second IP kept, got %v", mock.deleted)
+	}
+
+	rejected := store.attempts[0]
+	if rejected.FailureReason == nil || *rejected.FailureReason != "failed verification: ptr" {
+		t.Errorf("Expected the failed step as the reason, got %v", rejected.FailureReason)
+	}
+	kept := store.attempts[1].Metadata["verification"].(*Verification)
+	if len(kept.Checks) != 2 || kept.Checks[1].Name != CheckPTR || kept.Checks[1].Score != 1 || !kept.Passed {
+		t.Errorf("Expected both steps stored on the attempt, got %+v", kept)
+	}
+}
+
+// TestVerifyIPSMTPAndScore tests the SMTP banner check and the scoring gate
+func TestVerifyIPSMTPAndScore(t *testing.T) {
+	dns := resolver.NewFake()
+	dns.PTR["198.51.100.1"] = []string{"static.example.net."}
+	mock := newMockIONOS()
+	defer mock.Close()
+	svc, _ := newTestService(mock, dns, 50)
+
+	tests := []struct {
+		name       string
+		cfg        VerificationConfig
+		wantPassed bool
+		wantFailed string
+	}{
+		{"banner accepted", VerificationConfig{SMTPTarget: smtpListener(t, "220 mx.example.com ESMTP")}, true, ""},
+		{"banner refused", VerificationConfig{SMTPTarget: smtpListener(t, "554 no service")}, false, CheckSMTP},
+		{"port closed", VerificationConfig{SMTPTarget: "127.0.0.1:1"}, false, CheckSMTP},
+		{"score below minimum", VerificationConfig{PTR: true, MinScore: 0.9}, false, "score"},
+		{"score at minimum", VerificationConfig{PTR: true, MinScore: 0.75}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.SetVerification(tt.cfg)
+			v := svc.verifyIP(context.Background(), "198.51.100.1")
+			if v.Passed != tt.wantPassed {
+				t.Errorf("Expected passed %t, got %+v", tt.wantPassed, v)
+			}
+			if tt.wantFailed != "" && (len(v.Failed) != 1 || v.Failed[0] != tt.wantFailed) {
+				t.Errorf("Expected %s to fail, got %v", tt.wantFailed, v.Failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package itsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// Systems tickets are opened in
+const (
+	SystemJira       = "jira"
+	SystemServiceNow = "servicenow"
+)
+
+// Events that open tickets
+const (
+	EventBlacklisted     = "ip_blacklisted"
+	EventDelistingOpened = "delisting_opened"
+)
+
+const requestTimeout = 15 * time.Second
+
+// ValidSystem reports whether s is a known system
+func ValidSystem(s string) bool {
+	return s == SystemJira || s == SystemServiceNow
+}
+
+// ValidEvent reports whether e is a known event
+func ValidEvent(e string) bool {
+	return e == EventBlacklisted || e == EventDelistingOpened
+}
+
+// Ticket is what a rule asks a system to open
+type Ticket struct {
+	// Project is the Jira project key or ServiceNow assignment group
+	Project     string
+	Summary     string
+	Description string
+	Labels      []string
+}
+
+// Ref identifies an opened ticket
+type Ref struct {
+	Key        string
+	ExternalID string
+	URL        string
+}
+
+// Update is a comment added to a ticket, resolving it when Resolve is set
+type Update struct {
+	Comment string
+	Resolve bool
+}
+
+// StatusReport is a ticket status a system sent to the status webhook
+type StatusReport struct {
+	Key      string
+	Status   string
+	Resolved bool
+}
+
+// Client opens and updates tickets in one system and reads its status
+// webhooks
+type Client interface {
+	CreateTicket(ctx context.Context, ticket Ticket) (Ref, error)
+	// UpdateTicket comments on a ticket and reports whether it was resolved
+	UpdateTicket(ctx context.Context, ref Ref, update Update) (bool, error)
+	ParseWebhook(body []byte) (StatusReport, error)
+}
+
+// APIError is returned when a system responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether a failed call may succeed when repeated:
+// transport errors, 429 and 5xx
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// doJSON sends body as JSON and decodes a 2xx response into out, if not nil
+func doJSON(ctx context.Context, client *http.Client, method, url string, body, out interface{}, authorize func(*http.Request)) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "golang-backend-service/1.0")
+	authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// SelectRules returns the enabled rules for an event on an IP in poolIDs. A
+// rule matches when its pool is unset or one of poolIDs. Rules naming a pool
+// are more specific than catch-all rules and replace them, like notification
+// routes do.
+func SelectRules(rules []database.ITSMRule, event string, poolIDs []int) []database.ITSMRule {
+	var specific, fallback []database.ITSMRule
+	for _, rule := range rules {
+		if !rule.Enabled || rule.Event != event {
+			continue
+		}
+		if rule.PoolID == nil {
+			fallback = append(fallback, rule)
+			continue
+		}
+		for _, id := range poolIDs {
+			if id == *rule.PoolID {
+				specific = append(specific, rule)
+				break
+			}
+		}
+	}
+	if len(specific) > 0 {
+		return specific
+	}
+	return fallback
+}
@@ -0,0 +1,125 @@
+package itsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang-backend-service/internal/dependencies"
+)
+
+// JiraConfig holds the Jira Cloud or Data Center site tickets are opened in
+type JiraConfig struct {
+	BaseURL string
+	// User and Token authenticate with basic auth: an account email and API
+	// token on Jira Cloud, a username and personal access token on-premises
+	User  string
+	Token string
+	// IssueType defaults to Task
+	IssueType string
+	// ResolveTransition is the ID of the workflow transition that resolves
+	// an issue; empty only comments and leaves resolving to people
+	ResolveTransition string
+}
+
+// JiraClient opens issues with the Jira REST API v2
+type JiraClient struct {
+	config JiraConfig
+	client *http.Client
+}
+
+// NewJiraClient creates a client for the site in config
+func NewJiraClient(config JiraConfig) *JiraClient {
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.IssueType == "" {
+		config.IssueType = "Task"
+	}
+	return &JiraClient{
+		config: config,
+		client: &http.Client{Transport: dependencies.Default.Transport(dependencies.Jira, nil)},
+	}
+}
+
+func (c *JiraClient) authorize(req *http.Request) {
+	req.SetBasicAuth(c.config.User, c.config.Token)
+}
+
+// CreateTicket opens an issue in the ticket's project
+func (c *JiraClient) CreateTicket(ctx context.Context, ticket Ticket) (Ref, error) {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": ticket.Project},
+		"issuetype":   map[string]string{"name": c.config.IssueType},
+		"summary":     ticket.Summary,
+		"description": ticket.Description,
+	}
+	if len(ticket.Labels) > 0 {
+		fields["labels"] = ticket.Labels
+	}
+
+	var created struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	if err := doJSON(ctx, c.client, http.MethodPost, c.config.BaseURL+"/rest/api/2/issue",
+		map[string]interface{}{"fields": fields}, &created, c.authorize); err != nil {
+		return Ref{}, fmt.Errorf("failed to create Jira issue: %w", err)
+	}
+	if created.Key == "" {
+		return Ref{}, errors.New("failed to create Jira issue: response has no key")
+	}
+	return Ref{Key: created.Key, ExternalID: created.ID, URL: c.config.BaseURL + "/browse/" + created.Key}, nil
+}
+
+// UpdateTicket comments on an issue and, when asked to resolve it and a
+// resolve transition is configured, moves it through the transition
+func (c *JiraClient) UpdateTicket(ctx context.Context, ref Ref, update Update) (bool, error) {
+	issue := c.config.BaseURL + "/rest/api/2/issue/" + url.PathEscape(ref.Key)
+	if update.Comment != "" {
+		if err := doJSON(ctx, c.client, http.MethodPost, issue+"/comment",
+			map[string]string{"body": update.Comment}, nil, c.authorize); err != nil {
+			return false, fmt.Errorf("failed to comment on Jira issue %s: %w", ref.Key, err)
+		}
+	}
+	if !update.Resolve || c.config.ResolveTransition == "" {
+		return false, nil
+	}
+	if err := doJSON(ctx, c.client, http.MethodPost, issue+"/transitions",
+		map[string]interface{}{"transition": map[string]string{"id": c.config.ResolveTransition}}, nil, c.authorize); err != nil {
+		return false, fmt.Errorf("failed to resolve Jira issue %s: %w", ref.Key, err)
+	}
+	return true, nil
+}
+
+// ParseWebhook reads an issue event sent by a Jira webhook. An issue is
+// resolved once its status is in the done category.
+func (c *JiraClient) ParseWebhook(body []byte) (StatusReport, error) {
+	var event struct {
+		Issue struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Status struct {
+					Name           string `json:"name"`
+					StatusCategory struct {
+						Key string `json:"key"`
+					} `json:"statusCategory"`
+				} `json:"status"`
+			} `json:"fields"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return StatusReport{}, fmt.Errorf("invalid Jira webhook: %w", err)
+	}
+	status := event.Issue.Fields.Status
+	if event.Issue.Key == "" || status.Name == "" {
+		return StatusReport{}, errors.New("invalid Jira webhook: issue key and status are required")
+	}
+	return StatusReport{
+		Key:      event.Issue.Key,
+		Status:   status.Name,
+		Resolved: status.StatusCategory.Key == "done",
+	}, nil
+}
@@ -0,0 +1,116 @@
+package itsm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordedRequest struct {
+	method, path, user, password string
+	body                         map[string]interface{}
+}
+
+// recordingServer answers every request with status and response and keeps
+// what it received
+func recordingServer(t *testing.T, status int, response string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := []recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{r.Method, r.URL.Path, user, password, body})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// TestJiraCreateTicket tests that an issue is created in the rule's project and linked by key
+func TestJiraCreateTicket(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusCreated, `{"id":"10042","key":"OPS-7","self":"x"}`)
+	client := NewJiraClient(JiraConfig{BaseURL: server.URL + "/", User: "bot@example.com", Token: "secret"})
+
+	ref, err := client.CreateTicket(context.Background(), Ticket{Project: "OPS", Summary: "Sending IP 192.0.2.1 is blacklisted", Labels: []string{"deliverability"}})
+	if err != nil {
+		t.Fatalf("CreateTicket failed: %v", err)
+	}
+	if ref.Key != "OPS-7" || ref.ExternalID != "10042" || ref.URL != server.URL+"/browse/OPS-7" {
+		t.Errorf("Unexpected ref: %+v", ref)
+	}
+
+	req := (*requests)[0]
+	if req.method != http.MethodPost || req.path != "/rest/api/2/issue" || req.user != "bot@example.com" || req.password != "secret" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	fields := req.body["fields"].(map[string]interface{})
+	if fields["project"].(map[string]interface{})["key"] != "OPS" || fields["issuetype"].(map[string]interface{})["name"] != "Task" {
+		t.Errorf("Expected a Task in OPS, got %v", fields)
+	}
+}
+
+// TestJiraUpdateTicket tests commenting and resolving through the configured transition
+func TestJiraUpdateTicket(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusNoContent, "")
+
+	client := NewJiraClient(JiraConfig{BaseURL: server.URL})
+	resolved, err := client.UpdateTicket(context.Background(), Ref{Key: "OPS-7"}, Update{Comment: "Healthy again", Resolve: true})
+	if err != nil || resolved {
+		t.Fatalf("Expected only a comment without a resolve transition, got %v, %v", resolved, err)
+	}
+	if len(*requests) != 1 || (*requests)[0].path != "/rest/api/2/issue/OPS-7/comment" || (*requests)[0].body["body"] != "Healthy again" {
+		t.Fatalf("Unexpected requests: %+v", *requests)
+	}
+
+	client = NewJiraClient(JiraConfig{BaseURL: server.URL, ResolveTransition: "31"})
+	resolved, err = client.UpdateTicket(context.Background(), Ref{Key: "OPS-7"}, Update{Comment: "Healthy again", Resolve: true})
+	if err != nil || !resolved {
+		t.Fatalf("Expected the issue to be resolved, got %v, %v", resolved, err)
+	}
+	last := (*requests)[len(*requests)-1]
+	if last.path != "/rest/api/2/issue/OPS-7/transitions" || last.body["transition"].(map[string]interface{})["id"] != "31" {
+		t.Errorf("Expected transition 31, got %+v", last)
+	}
+}
+
+// TestJiraErrorStatus tests that error responses are returned as retryable only for 429 and 5xx
+func TestJiraErrorStatus(t *testing.T) {
+	for status, retry := range map[int]bool{http.StatusBadRequest: false, http.StatusTooManyRequests: true, http.StatusBadGateway: true} {
+		server, _ := recordingServer(t, status, `{"errorMessages":["nope"]}`)
+		_, err := NewJiraClient(JiraConfig{BaseURL: server.URL}).CreateTicket(context.Background(), Ticket{Project: "OPS"})
+		if err == nil || !strings.Contains(err.Error(), "nope") {
+			t.Fatalf("%d: expected the error body, got %v", status, err)
+		}
+		if retryable(err) != retry {
+			t.Errorf("%d: expected retryable %v", status, retry)
+		}
+	}
+}
+
+// TestJiraParseWebhook tests that issues in the done category are resolved
+func TestJiraParseWebhook(t *testing.T) {
+	client := NewJiraClient(JiraConfig{})
+	report, err := client.ParseWebhook([]byte(`{"webhookEvent":"jira:issue_updated","issue":{"key":"OPS-7","fields":{"status":{"name":"Done","statusCategory":{"key":"done"}}}}}`))
+	if err != nil || report != (StatusReport{Key: "OPS-7", Status: "Done", Resolved: true}) {
+		t.Errorf("Unexpected report %+v, %v", report, err)
+	}
+	report, err = client.ParseWebhook([]byte(`{"issue":{"key":"OPS-7","fields":{"status":{"name":"In Progress","statusCategory":{"key":"indeterminate"}}}}}`))
+	if err != nil || report.Resolved {
+		t.Errorf("Expected an unresolved report, got %+v, %v", report, err)
+	}
+	for _, body := range []string{`not json`, `{"issue":{"fields":{"status":{"name":"Done"}}}}`, `{"issue":{"key":"OPS-7"}}`} {
+		if _, err := client.ParseWebhook([]byte(body)); err == nil {
+			t.Errorf("Expected %s to be rejected", body)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package itsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize    = 100
+	callAttempts = 3
+	// createdBy is recorded as the author of tickets the rules open
+	createdBy = "automated_itsm"
+)
+
+// Store reads the rules and what an IP belongs to, and persists tickets
+type Store interface {
+	ListITSMRules() ([]database.ITSMRule, error)
+	GetIPPoolIDs(ip string) ([]int, error)
+	IsIPArchived(ip string) (bool, error)
+	HasOpenITSMTicket(ruleID int, ip, event string, taskID *int) (bool, error)
+	RecordITSMTicket(ticket *database.ITSMTicket, author string) (*database.ITSMTicket, error)
+	ListITSMTickets(ip string) ([]database.ITSMTicket, error)
+	GetITSMTicketByKey(system, key string) (*database.ITSMTicket, error)
+	UpdateITSMTicketStatus(id int, status string, resolved bool, author string) (*database.ITSMTicket, bool, error)
+	UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error)
+}
+
+type dbStore struct{}
+
+func (dbStore) ListITSMRules() ([]database.ITSMRule, error) {
+	return database.ListITSMRules()
+}
+
+func (dbStore) GetIPPoolIDs(ip string) ([]int, error) {
+	return database.GetIPPoolIDs(ip)
+}
+
+func (dbStore) IsIPArchived(ip string) (bool, error) {
+	return database.IsIPArchived(ip)
+}
+
+func (dbStore) HasOpenITSMTicket(ruleID int, ip, event string, taskID *int) (bool, error) {
+	return database.HasOpenITSMTicket(ruleID, ip, event, taskID)
+}
+
+func (dbStore) RecordITSMTicket(ticket *database.ITSMTicket, author string) (*database.ITSMTicket, error) {
+	return database.RecordITSMTicket(ticket, author)
+}
+
+func (dbStore) ListITSMTickets(ip string) ([]database.ITSMTicket, error) {
+	return database.ListITSMTickets(ip)
+}
+
+func (dbStore) GetITSMTicketByKey(system, key string) (*database.ITSMTicket, error) {
+	return database.GetITSMTicketByKey(system, key)
+}
+
+func (dbStore) UpdateITSMTicketStatus(id int, status string, resolved bool, author string) (*database.ITSMTicket, bool, error) {
+	return database.UpdateITSMTicketStatus(id, status, resolved, author)
+}
+
+func (dbStore) UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error) {
+	return database.UpdateDelistingTaskStatus(id, status, comment, author)
+}
+
+// job is one queued piece of work: opening the tickets of an event, or
+// updating the open tickets of an event on an IP
+type job struct {
+	ip     string
+	event  string
+	taskID *int
+	ticket *Ticket
+	update *Update
+}
+
+// Service opens tickets when the rules match an event and keeps them in sync
+// with the IP and its delisting tasks. Calls to the systems run in the
+// background so status changes and API requests never wait on them; work
+// is dropped when the queue is full.
+type Service struct {
+	clients   map[string]Client
+	store     Store
+	logger    *logrus.Logger
+	queue     chan job
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// retryDelay doubles after every failed attempt; zeroed in tests
+	retryDelay time.Duration
+}
+
+// NewService starts a service calling the clients by system; a nil store
+// uses the database
+func NewService(clients map[string]Client, store Store, logger *logrus.Logger) *Service {
+	if store == nil {
+		store = dbStore{}
+	}
+	s := &Service{
+		clients:    clients,
+		store:      store,
+		logger:     logger,
+		queue:      make(chan job, queueSize),
+		retryDelay: time.Second,
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// Close finishes the queued work and stops the service
+func (s *Service) Close() {
+	s.closeOnce.Do(func() { close(s.queue) })
+	s.wg.Wait()
+}
+
+// Systems returns the systems the service has clients for
+func (s *Service) Systems() []string {
+	systems := []string{}
+	for _, system := range []string{SystemJira, SystemServiceNow} {
+		if s.clients[system] != nil {
+			systems = append(systems, system)
+		}
+	}
+	return systems
+}
+
+func (s *Service) enqueue(j job) {
+	select {
+	case s.queue <- j:
+	default:
+		s.logger.WithFields(logrus.Fields{
+			"action": "itsm_job_dropped",
+			"ip":     j.ip,
+			"event":  j.event,
+		}).Warn("ITSM queue is full, dropping ticket update")
+	}
+}
+
+// ReputationHook returns a state machine hook that opens tickets when an IP
+// is blacklisted and comments on them as its status changes, resolving them
+// once it is healthy again
+func (s *Service) ReputationHook() state.Hook {
+	return func(t state.Transition) {
+		if t.To == state.Blacklisted {
+			s.enqueue(job{ip: t.IP, event: EventBlacklisted, ticket: &Ticket{
+				Summary: fmt.Sprintf("Sending IP %s is blacklisted", t.IP),
+				Description: fmt.Sprintf("The reputation status of %s changed from %s to blacklisted (%s). "+
+					"Providers are rejecting its mail; pause it and request delisting with the data from "+
+					"/api/ips/%s/mitigation-data.", t.IP, t.From, t.TriggeredBy, t.IP),
+				Labels: []string{"deliverability", "ip-blacklisted"},
+			}})
+			return
+		}
+		s.enqueue(job{ip: t.IP, event: EventBlacklisted, update: &Update{
+			Comment: fmt.Sprintf("The reputation status of %s changed from %s to %s (%s).", t.IP, t.From, t.To, t.TriggeredBy),
+			Resolve: t.To == state.Healthy,
+		}})
+	}
+}
+
+// DelistingOpened opens the tickets of the rules matching a new delisting
+// task
+func (s *Service) DelistingOpened(task database.DelistingTask) {
+	description := fmt.Sprintf("A delisting request for %s was opened with %s by %s.", task.IP, task.Provider, task.OpenedBy)
+	if len(task.BlockReferences) > 0 {
+		description += " Block references: " + strings.Join(task.BlockReferences, ", ") + "."
+	}
+	if task.Comment != "" {
+		description += "\n\n" + task.Comment
+	}
+	id := task.ID
+	s.enqueue(job{ip: task.IP, event: EventDelistingOpened, taskID: &id, ticket: &Ticket{
+		Summary:     fmt.Sprintf("Delist %s with %s", task.IP, task.Provider),
+		Description: description,
+		Labels:      []string{"deliverability", "delisting"},
+	}})
+}
+
+// DelistingUpdated comments on the tickets of a delisting task that moved
+// to a new status, resolving them with the task
+func (s *Service) DelistingUpdated(task database.DelistingTask, comment string) {
+	text := fmt.Sprintf("The delisting task with %s is %s.", task.Provider, task.Status)
+	if comment != "" {
+		text += "\n\n" + comment
+	}
+	id := task.ID
+	s.enqueue(job{ip: task.IP, event: EventDelistingOpened, taskID: &id, update: &Update{
+		Comment: text,
+		Resolve: task.Status == database.DelistingResolved,
+	}})
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+	for j := range s.queue {
+		var err error
+		if j.ticket != nil {
+			err = s.openTickets(j)
+		} else {
+			err = s.updateTickets(j)
+		}
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "itsm_sync_failed",
+				"ip":     j.ip,
+				"event":  j.event,
+				"error":  err.Error(),
+			}).Error("Failed to sync ITSM tickets")
+		}
+	}
+}
+
+// openTickets opens a ticket for every rule matching the job's event that
+// does not already have an open one. Archived IPs get no tickets.
+func (s *Service) openTickets(j job) error {
+	archived, err := s.store.IsIPArchived(j.ip)
+	if err != nil {
+		return err
+	}
+	if archived {
+		return nil
+	}
+	rules, err := s.store.ListITSMRules()
+	if err != nil {
+		return err
+	}
+	poolIDs, err := s.store.GetIPPoolIDs(j.ip)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range SelectRules(rules, j.event, poolIDs) {
+		client := s.clients[rule.System]
+		if client == nil {
+			s.logger.WithFields(logrus.Fields{
+				"action":  "itsm_system_not_configured",
+				"rule_id": rule.ID,
+				"system":  rule.System,
+			}).Warn("ITSM rule names a system that is not configured")
+			continue
+		}
+		open, err := s.store.HasOpenITSMTicket(rule.ID, j.ip, j.event, j.taskID)
+		if err != nil {
+			return err
+		}
+		if open {
+			continue
+		}
+
+		ticket := *j.ticket
+		ticket.Project = rule.Project
+		var ref Ref
+		err = s.retry(func(ctx context.Context) error {
+			var err error
+			ref, err = client.CreateTicket(ctx, ticket)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", rule.ID, err)
+		}
+
+		ruleID := rule.ID
+		stored, err := s.store.RecordITSMTicket(&database.ITSMTicket{
+			RuleID:          &ruleID,
+			System:          rule.System,
+			Key:             ref.Key,
+			ExternalID:      ref.ExternalID,
+			URL:             ref.URL,
+			IP:              j.ip,
+			Event:           j.event,
+			DelistingTaskID: j.taskID,
+			Status:          "open",
+		}, createdBy)
+		if err != nil {
+			return fmt.Errorf("rule %d opened %s but it could not be stored: %w", rule.ID, ref.Key, err)
+		}
+		s.logger.WithFields(logrus.Fields{
+			"action":     "itsm_ticket_created",
+			"ip":         j.ip,
+			"event":      j.event,
+			"rule_id":    rule.ID,
+			"system":     stored.System,
+			"ticket_key": stored.Key,
+		}).Info("ITSM ticket created")
+	}
+	return nil
+}
+
+// updateTickets sends the job's update to the open tickets of its event, IP
+// and delisting task
+func (s *Service) updateTickets(j job) error {
+	tickets, err := s.store.ListITSMTickets(j.ip)
+	if err != nil {
+		return err
+	}
+
+	for _, ticket := range tickets {
+		if ticket.Resolved || ticket.Event != j.event || !sameTask(ticket.DelistingTaskID, j.taskID) {
+			continue
+		}
+		client := s.clients[ticket.System]
+		if client == nil {
+			continue
+		}
+
+		ref := Ref{Key: ticket.Key, ExternalID: ticket.ExternalID, URL: ticket.URL}
+		var resolved bool
+		err := s.retry(func(ctx context.Context) error {
+			var err error
+			resolved, err = client.UpdateTicket(ctx, ref, *j.update)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if resolved {
+			if _, _, err := s.store.UpdateITSMTicketStatus(ticket.ID, "resolved", true, createdBy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sameTask(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// retry calls fn until it succeeds, fails with an error that repeating
+// cannot fix, or runs out of attempts
+func (s *Service) retry(fn func(ctx context.Context) error) error {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; attempt <= callAttempts; attempt++ {
+		if err = fn(context.Background()); err == nil || !retryable(err) {
+			return err
+		}
+		if attempt < callAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", callAttempts, err)
+}
+
+// ApplyStatusReport stores the status a system's webhook reported for one of
+// its tickets. Resolving the ticket of a delisting task resolves the task;
+// this never calls back into the system. It returns
+// database.ErrITSMTicketNotFound for tickets the rules did not open.
+func (s *Service) ApplyStatusReport(system string, report StatusReport) (*database.ITSMTicket, error) {
+	ticket, err := s.store.GetITSMTicketByKey(system, report.Key)
+	if err != nil {
+		return nil, err
+	}
+	author := "itsm:" + system
+	updated, changed, err := s.store.UpdateITSMTicketStatus(ticket.ID, report.Status, report.Resolved, author)
+	if err != nil {
+		return nil, err
+	}
+	if !changed || !updated.Resolved || ticket.Resolved || updated.DelistingTaskID == nil {
+		return updated, nil
+	}
+
+	comment := fmt.Sprintf("Resolved in %s %s", system, updated.Key)
+	_, err = s.store.UpdateDelistingTaskStatus(*updated.DelistingTaskID, database.DelistingResolved, comment, author)
+	if err != nil && !errors.Is(err, database.ErrDelistingTaskResolved) && !errors.Is(err, database.ErrDelistingTaskNotFound) {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Client returns the client of a system, or nil if it is not configured
+func (s *Service) Client(system string) Client {
+	return s.clients[system]
+}
@@ -0,0 +1,274 @@
+package itsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func intPtr(i int) *int { return &i }
+
+type memoryStore struct {
+	mu       sync.Mutex
+	rules    []database.ITSMRule
+	poolIDs  []int
+	archived bool
+	tickets  []database.ITSMTicket
+	tasks    map[int]string
+}
+
+func (m *memoryStore) ListITSMRules() ([]database.ITSMRule, error) { return m.rules, nil }
+
+func (m *memoryStore) GetIPPoolIDs(ip string) ([]int, error) { return m.poolIDs, nil }
+
+func (m *memoryStore) IsIPArchived(ip string) (bool, error) { return m.archived, nil }
+
+func (m *memoryStore) HasOpenITSMTicket(ruleID int, ip, event string, taskID *int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tickets {
+		if *t.RuleID == ruleID && t.IP == ip && t.Event == event && sameTask(t.DelistingTaskID, taskID) && !t.Resolved {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryStore) RecordITSMTicket(ticket *database.ITSMTicket, author string) (*database.ITSMTicket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *ticket
+	stored.ID = len(m.tickets) + 1
+	m.tickets = append(m.tickets, stored)
+	return &stored, nil
+}
+
+func (m *memoryStore) ListITSMTickets(ip string) ([]database.ITSMTicket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tickets := []database.ITSMTicket{}
+	for _, t := range m.tickets {
+		if t.IP == ip {
+			tickets = append(tickets, t)
+		}
+	}
+	return tickets, nil
+}
+
+func (m *memoryStore) GetITSMTicketByKey(system, key string) (*database.ITSMTicket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tickets {
+		if t.System == system && t.Key == key {
+			return &t, nil
+		}
+	}
+	return nil, database.ErrITSMTicketNotFound
+}
+
+func (m *memoryStore) UpdateITSMTicketStatus(id int, status string, resolved bool, author string) (*database.ITSMTicket, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &m.tickets[id-1]
+	if t.Status == status && t.Resolved == resolved {
+		return t, false, nil
+	}
+	t.Status, t.Resolved = status, resolved
+	updated := *t
+	return &updated, true, nil
+}
+
+func (m *memoryStore) UpdateDelistingTaskStatus(id int, status, comment, author string) (*database.DelistingTask, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tasks[id] == database.DelistingResolved {
+		return nil, database.ErrDelistingTaskResolved
+	}
+	m.tasks[id] = status
+	return &database.DelistingTask{ID: id, Status: status}, nil
+}
+
+type fakeClient struct {
+	mu      sync.Mutex
+	created []Ticket
+	updates map[string][]Update
+}
+
+func (c *fakeClient) CreateTicket(ctx context.Context, ticket Ticket) (Ref, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, ticket)
+	key := fmt.Sprintf("OPS-%d", len(c.created))
+	return Ref{Key: key, ExternalID: key, URL: "https://jira.example.com/browse/" + key}, nil
+}
+
+func (c *fakeClient) UpdateTicket(ctx context.Context, ref Ref, update Update) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.updates == nil {
+		c.updates = map[string][]Update{}
+	}
+	c.updates[ref.Key] = append(c.updates[ref.Key], update)
+	return update.Resolve, nil
+}
+
+func (c *fakeClient) ParseWebhook(body []byte) (StatusReport, error) {
+	return StatusReport{}, errors.New("not used")
+}
+
+func newTestService(store *memoryStore, client *fakeClient) *Service {
+	logger, _ := test.NewNullLogger()
+	s := NewService(map[string]Client{SystemJira: client}, store, logger)
+	s.retryDelay = 0
+	return s
+}
+
+// TestSelectRules tests that pool rules replace catch-all rules for the event
+func TestSelectRules(t *testing.T) {
+	rules := []database.ITSMRule{
+		{ID: 1, Event: EventBlacklisted, System: SystemJira, Enabled: true},
+		{ID: 2, Event: EventBlacklisted, System: SystemServiceNow, PoolID: intPtr(10), Enabled: true},
+		{ID: 3, Event: EventDelistingOpened, System: SystemJira, Enabled: true},
+		{ID: 4, Event: EventBlacklisted, System: SystemJira, PoolID: intPtr(20), Enabled: false},
+	}
+	for _, tc := range []struct {
+		event   string
+		poolIDs []int
+		want    []int
+	}{
+		{EventBlacklisted, nil, []int{1}},
+		{EventBlacklisted, []int{10}, []int{2}},
+		{EventBlacklisted, []int{20}, []int{1}},
+		{EventDelistingOpened, []int{10}, []int{3}},
+	} {
+		got := []int{}
+		for _, rule := range SelectRules(rules, tc.event, tc.poolIDs) {
+			got = append(got, rule.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s %v: expected rules %v, got %v", tc.event, tc.poolIDs, tc.want, got)
+		}
+	}
+}
+
+// TestReputationHookOpensAndResolvesTickets tests the ticket lifecycle of a blacklisting
+func TestReputationHookOpensAndResolvesTickets(t *testing.T) {
+	store := &memoryStore{rules: []database.ITSMRule{{ID: 1, Event: EventBlacklisted, System: SystemJira, Project: "OPS", Enabled: true}}}
+	client := &fakeClient{}
+	s := newTestService(store, client)
+
+	hook := s.ReputationHook()
+	hook(state.Transition{IP: "192.0.2.1", From: state.Quarantine, To: state.Blacklisted, TriggeredBy: "automated_aggregation"})
+	hook(state.Transition{IP: "192.0.2.1", From: state.Blacklisted, To: state.Quarantine, TriggeredBy: "automated_aggregation"})
+	// Flapping back must not open a second ticket while the first is open
+	hook(state.Transition{IP: "192.0.2.1", From: state.Quarantine, To: state.Blacklisted, TriggeredBy: "automated_aggregation"})
+	hook(state.Transition{IP: "192.0.2.1", From: state.Blacklisted, To: state.Quarantine, TriggeredBy: "automated_aggregation"})
+	hook(state.Transition{IP: "192.0.2.1", From: state.Quarantine, To: state.Healthy, TriggeredBy: "automated_aggregation"})
+	s.Close()
+
+	if len(client.created) != 1 || client.created[0].Project != "OPS" || client.created[0].Summary != "Sending IP 192.0.2.1 is blacklisted" {
+		t.Fatalf("Expected one ticket in OPS, got %+v", client.created)
+	}
+	updates := client.updates["OPS-1"]
+	if len(updates) != 3 || updates[0].Resolve || !updates[2].Resolve {
+		t.Errorf("Expected two comments and a resolution, got %+v", updates)
+	}
+	if ticket := store.tickets[0]; !ticket.Resolved || ticket.Key != "OPS-1" || ticket.URL == "" || *ticket.RuleID != 1 {
+		t.Errorf("Expected the stored ticket to be resolved, got %+v", ticket)
+	}
+}
+
+// TestReputationHookSkipsArchivedIPs tests that archived IPs get no tickets
+func TestReputationHookSkipsArchivedIPs(t *testing.T) {
+	store := &memoryStore{archived: true, rules: []database.ITSMRule{{ID: 1, Event: EventBlacklisted, System: SystemJira, Enabled: true}}}
+	client := &fakeClient{}
+	s := newTestService(store, client)
+	s.ReputationHook()(state.Transition{IP: "192.0.2.1", From: state.Quarantine, To: state.Blacklisted})
+	s.Close()
+
+	if len(client.created) != 0 {
+		t.Errorf("Expected no ticket, got %+v", client.created)
+	}
+}
+
+// TestDelistingTicketSync tests tickets opened per delisting task and resolved with it
+func TestDelistingTicketSync(t *testing.T) {
+	store := &memoryStore{rules: []database.ITSMRule{{ID: 2, Event: EventDelistingOpened, System: SystemJira, Project: "DLV", Enabled: true}}}
+	client := &fakeClient{}
+	s := newTestService(store, client)
+
+	task := database.DelistingTask{ID: 5, IP: "192.0.2.1", Provider: "outlook", Status: database.DelistingOpen, BlockReferences: []string{"S3150"}, OpenedBy: "alice"}
+	s.DelistingOpened(task)
+	other := task
+	other.ID, other.Provider = 6, "yahoo"
+	s.DelistingOpened(other)
+	task.Status = database.DelistingResolved
+	s.DelistingUpdated(task, "Delisted by Microsoft")
+	s.Close()
+
+	if len(client.created) != 2 || client.created[0].Summary != "Delist 192.0.2.1 with outlook" {
+		t.Fatalf("Expected a ticket per task, got %+v", client.created)
+	}
+	if want := "A delisting request for 192.0.2.1 was opened with outlook by alice. Block references: S3150."; client.created[0].Description != want {
+		t.Errorf("Expected description %q, got %q", want, client.created[0].Description)
+	}
+	if len(client.updates["OPS-1"]) != 1 || !client.updates["OPS-1"][0].Resolve || len(client.updates["OPS-2"]) != 0 {
+		t.Errorf("Expected only the outlook ticket to be resolved, got %+v", client.updates)
+	}
+}
+
+// TestApplyStatusReport tests that resolving a delisting ticket in the system resolves its task
+func TestApplyStatusReport(t *testing.T) {
+	store := &memoryStore{tasks: map[int]string{5: database.DelistingSubmitted}, tickets: []database.ITSMTicket{
+		{ID: 1, RuleID: intPtr(2), System: SystemJira, Key: "OPS-1", IP: "192.0.2.1", Event: EventDelistingOpened, DelistingTaskID: intPtr(5), Status: "open"},
+	}}
+	s := newTestService(store, &fakeClient{})
+	defer s.Close()
+
+	ticket, err := s.ApplyStatusReport(SystemJira, StatusReport{Key: "OPS-1", Status: "In Progress"})
+	if err != nil || ticket.Status != "In Progress" || store.tasks[5] != database.DelistingSubmitted {
+		t.Fatalf("Expected only the status to change, got %+v, %v, task %s", ticket, err, store.tasks[5])
+	}
+	if _, err := s.ApplyStatusReport(SystemJira, StatusReport{Key: "OPS-1", Status: "Done", Resolved: true}); err != nil {
+		t.Fatal(err)
+	}
+	if store.tasks[5] != database.DelistingResolved {
+		t.Errorf("Expected the task to be resolved, got %s", store.tasks[5])
+	}
+	// A repeated delivery changes nothing
+	if _, err := s.ApplyStatusReport(SystemJira, StatusReport{Key: "OPS-1", Status: "Done", Resolved: true}); err != nil {
+		t.Errorf("Expected a repeated report to succeed, got %v", err)
+	}
+	if _, err := s.ApplyStatusReport(SystemServiceNow, StatusReport{Key: "OPS-1", Status: "6"}); !errors.Is(err, database.ErrITSMTicketNotFound) {
+		t.Errorf("Expected ErrITSMTicketNotFound, got %v", err)
+	}
+}
+
+// TestRetry tests that failures worth repeating are retried and others are not
+func TestRetry(t *testing.T) {
+	s := newTestService(&memoryStore{}, &fakeClient{})
+	defer s.Close()
+
+	calls := 0
+	err := s.retry(func(ctx context.Context) error {
+		calls++
+		return &APIError{StatusCode: 503}
+	})
+	if err == nil || calls != callAttempts {
+		t.Errorf("Expected %d attempts, got %d: %v", callAttempts, calls, err)
+	}
+	calls = 0
+	if err := s.retry(func(ctx context.Context) error {
+		calls++
+		return &APIError{StatusCode: 400}
+	}); err == nil || calls != 1 {
+		t.Errorf("Expected one attempt for a 400, got %d: %v", calls, err)
+	}
+}
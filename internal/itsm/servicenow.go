@@ -0,0 +1,146 @@
+package itsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/dependencies"
+)
+
+// ServiceNowConfig holds the ServiceNow instance tickets are opened in
+type ServiceNowConfig struct {
+	InstanceURL string
+	User        string
+	Password    string
+	// Table defaults to incident
+	Table string
+	// ResolvedState is the state value a resolved ticket is set to,
+	// defaulting to the incident table's 6. Reported states at or above it
+	// count as resolved, like the incident table's Closed and Canceled.
+	ResolvedState string
+	// CloseCode is set with the resolved state, defaulting to
+	// "Solved (Permanently)"
+	CloseCode string
+}
+
+// ServiceNowClient opens records with the ServiceNow Table API
+type ServiceNowClient struct {
+	config ServiceNowConfig
+	client *http.Client
+}
+
+// NewServiceNowClient creates a client for the instance in config
+func NewServiceNowClient(config ServiceNowConfig) *ServiceNowClient {
+	config.InstanceURL = strings.TrimRight(config.InstanceURL, "/")
+	if config.Table == "" {
+		config.Table = "incident"
+	}
+	if config.ResolvedState == "" {
+		config.ResolvedState = "6"
+	}
+	if config.CloseCode == "" {
+		config.CloseCode = "Solved (Permanently)"
+	}
+	return &ServiceNowClient{
+		config: config,
+		client: &http.Client{Transport: dependencies.Default.Transport(dependencies.ServiceNow, nil)},
+	}
+}
+
+func (c *ServiceNowClient) authorize(req *http.Request) {
+	req.SetBasicAuth(c.config.User, c.config.Password)
+}
+
+func (c *ServiceNowClient) tableURL() string {
+	return c.config.InstanceURL + "/api/now/table/" + url.PathEscape(c.config.Table)
+}
+
+// CreateTicket opens a record assigned to the ticket's assignment group
+func (c *ServiceNowClient) CreateTicket(ctx context.Context, ticket Ticket) (Ref, error) {
+	record := map[string]string{
+		"short_description": ticket.Summary,
+		"description":       ticket.Description,
+		"assignment_group":  ticket.Project,
+	}
+	var created struct {
+		Result struct {
+			SysID  string `json:"sys_id"`
+			Number string `json:"number"`
+		} `json:"result"`
+	}
+	if err := doJSON(ctx, c.client, http.MethodPost, c.tableURL(), record, &created, c.authorize); err != nil {
+		return Ref{}, fmt.Errorf("failed to create ServiceNow record: %w", err)
+	}
+	if created.Result.SysID == "" || created.Result.Number == "" {
+		return Ref{}, errors.New("failed to create ServiceNow record: response has no sys_id or number")
+	}
+	return Ref{
+		Key:        created.Result.Number,
+		ExternalID: created.Result.SysID,
+		URL:        c.config.InstanceURL + "/" + url.PathEscape(c.config.Table) + ".do?sys_id=" + url.QueryEscape(created.Result.SysID),
+	}, nil
+}
+
+// UpdateTicket adds a work note to a record and, when asked to, resolves it
+// with the comment as close notes
+func (c *ServiceNowClient) UpdateTicket(ctx context.Context, ref Ref, update Update) (bool, error) {
+	changes := map[string]string{}
+	if update.Comment != "" {
+		changes["work_notes"] = update.Comment
+	}
+	if update.Resolve {
+		changes["state"] = c.config.ResolvedState
+		changes["close_code"] = c.config.CloseCode
+		changes["close_notes"] = update.Comment
+	}
+	if len(changes) == 0 {
+		return false, nil
+	}
+	if err := doJSON(ctx, c.client, http.MethodPatch, c.tableURL()+"/"+url.PathEscape(ref.ExternalID),
+		changes, nil, c.authorize); err != nil {
+		return false, fmt.Errorf("failed to update ServiceNow record %s: %w", ref.Key, err)
+	}
+	return update.Resolve, nil
+}
+
+// ParseWebhook reads the record a ServiceNow business rule or flow posted:
+// {"number": "INC0010001", "state": "6", "state_label": "Resolved"}. The
+// label, when sent, is stored as the status.
+func (c *ServiceNowClient) ParseWebhook(body []byte) (StatusReport, error) {
+	var record struct {
+		Number     string `json:"number"`
+		State      string `json:"state"`
+		StateLabel string `json:"state_label"`
+	}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return StatusReport{}, fmt.Errorf("invalid ServiceNow webhook: %w", err)
+	}
+	if record.Number == "" || record.State == "" {
+		return StatusReport{}, errors.New("invalid ServiceNow webhook: number and state are required")
+	}
+	status := record.StateLabel
+	if status == "" {
+		status = record.State
+	}
+	return StatusReport{Key: record.Number, Status: status, Resolved: c.resolvedState(record.State)}, nil
+}
+
+// resolvedState reports whether a state value is at or past the resolved
+// state
+func (c *ServiceNowClient) resolvedState(state string) bool {
+	if state == c.config.ResolvedState {
+		return true
+	}
+	value, err := strconv.Atoi(state)
+	if err != nil {
+		return false
+	}
+	resolved, err := strconv.Atoi(c.config.ResolvedState)
+	return err == nil && value >= resolved
+}
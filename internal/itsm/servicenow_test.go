@@ -0,0 +1,72 @@
+package itsm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// TestServiceNowCreateTicket tests that an incident is assigned to the rule's group and linked by number
+func TestServiceNowCreateTicket(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusCreated, `{"result":{"sys_id":"a1b2","number":"INC0010001"}}`)
+	client := NewServiceNowClient(ServiceNowConfig{InstanceURL: server.URL, User: "svc", Password: "pw"})
+
+	ref, err := client.CreateTicket(context.Background(), Ticket{Project: "Deliverability", Summary: "Delist 192.0.2.1 with outlook"})
+	if err != nil {
+		t.Fatalf("CreateTicket failed: %v", err)
+	}
+	if ref.Key != "INC0010001" || ref.ExternalID != "a1b2" || ref.URL != server.URL+"/incident.do?sys_id=a1b2" {
+		t.Errorf("Unexpected ref: %+v", ref)
+	}
+	req := (*requests)[0]
+	if req.method != http.MethodPost || req.path != "/api/now/table/incident" || req.user != "svc" || req.password != "pw" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if req.body["assignment_group"] != "Deliverability" || req.body["short_description"] != "Delist 192.0.2.1 with outlook" {
+		t.Errorf("Unexpected record: %v", req.body)
+	}
+}
+
+// TestServiceNowUpdateTicket tests work notes and resolving with close notes
+func TestServiceNowUpdateTicket(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusOK, `{"result":{}}`)
+	client := NewServiceNowClient(ServiceNowConfig{InstanceURL: server.URL})
+
+	resolved, err := client.UpdateTicket(context.Background(), Ref{Key: "INC0010001", ExternalID: "a1b2"}, Update{Comment: "Submitted"})
+	if err != nil || resolved {
+		t.Fatalf("Expected a work note only, got %v, %v", resolved, err)
+	}
+	req := (*requests)[0]
+	if req.method != http.MethodPatch || req.path != "/api/now/table/incident/a1b2" || req.body["work_notes"] != "Submitted" || req.body["state"] != nil {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+
+	resolved, err = client.UpdateTicket(context.Background(), Ref{Key: "INC0010001", ExternalID: "a1b2"}, Update{Comment: "Delisted", Resolve: true})
+	if err != nil || !resolved {
+		t.Fatalf("Expected the incident to be resolved, got %v, %v", resolved, err)
+	}
+	req = (*requests)[1]
+	if req.body["state"] != "6" || req.body["close_code"] != "Solved (Permanently)" || req.body["close_notes"] != "Delisted" {
+		t.Errorf("Unexpected resolution: %v", req.body)
+	}
+}
+
+// TestServiceNowParseWebhook tests that states at or past the resolved state resolve the ticket
+func TestServiceNowParseWebhook(t *testing.T) {
+	client := NewServiceNowClient(ServiceNowConfig{})
+	for body, want := range map[string]StatusReport{
+		`{"number":"INC0010001","state":"2","state_label":"In Progress"}`: {Key: "INC0010001", Status: "In Progress"},
+		`{"number":"INC0010001","state":"6"}`:                             {Key: "INC0010001", Status: "6", Resolved: true},
+		`{"number":"INC0010001","state":"7","state_label":"Closed"}`:      {Key: "INC0010001", Status: "Closed", Resolved: true},
+	} {
+		report, err := client.ParseWebhook([]byte(body))
+		if err != nil || report != want {
+			t.Errorf("%s: expected %+v, got %+v, %v", body, want, report, err)
+		}
+	}
+	for _, body := range []string{`[]`, `{"state":"6"}`, `{"number":"INC0010001"}`} {
+		if _, err := client.ParseWebhook([]byte(body)); err == nil {
+			t.Errorf("Expected %s to be rejected", body)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWorkers   = 4
+	defaultTimeout   = 2 * time.Minute
+	defaultRetention = 24 * time.Hour
+	cleanupInterval  = time.Hour
+	callbackTimeout  = 10 * time.Second
+	callbackAttempts = 3
+	progressInterval = time.Second
+)
+
+var jobsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "jobs_total",
+		Help: "Total number of background jobs finished, by type and status (succeeded, failed)",
+	},
+	[]string{"type", "status"},
+)
+
+// ErrClosed is returned by Submit once the runner is closing
+var ErrClosed = errors.New("job runner is closed")
+
+// ErrPrivateCallback is returned for callback URLs whose host is not a public
+// address, so jobs cannot be used to reach internal services
+var ErrPrivateCallback = errors.New("callback host is not a public address")
+
+// callbackAddrAllowed reports whether callbacks may be sent to an address;
+// tests allow loopback servers
+var callbackAddrAllowed = validation.IsPublicIP
+
+// Store persists jobs. The default implementation delegates to the database
+// package; tests substitute an in-memory store.
+type Store interface {
+	CreateJob(job *database.Job) error
+	UpdateJob(job *database.Job) error
+	GetJob(id string) (*database.Job, error)
+	DeleteJobsFinishedBefore(cutoff time.Time) (int64, error)
+}
+
+// dbStore is the Store backed by the global database connection
+type dbStore struct{}
+
+func (dbStore) CreateJob(job *database.Job) error {
+	return database.CreateJob(job)
+}
+
+func (dbStore) UpdateJob(job *database.Job) error {
+	return database.UpdateJob(job)
+}
+
+func (dbStore) GetJob(id string) (*database.Job, error) {
+	return database.GetJob(id)
+}
+
+func (dbStore) DeleteJobsFinishedBefore(cutoff time.Time) (int64, error) {
+	return database.DeleteJobsFinishedBefore(cutoff)
+}
+
+// Func does a job's work; its result is stored as JSON
+type Func func(ctx context.Context) (interface{}, error)
+
+// Options describe a submitted job
+type Options struct {
+	Type    string
+	Subject string
+	// CallbackURL receives the finished job as a JSON POST; empty disables it
+	CallbackURL string
+	CreatedBy   string
+	// Timeout overrides the runner's timeout for long jobs
+	Timeout time.Duration
+}
+
+// Config tunes the runner; zero values use the defaults
+type Config struct {
+	// Workers is the number of jobs run at once (default 4)
+	Workers int
+	// Timeout bounds each job (default 2m)
+	Timeout time.Duration
+	// Retention is how long finished jobs are kept (default 24h)
+	Retention time.Duration
+	// CallbackSecret signs callbacks with
+	// X-Signature-256: sha256=<hex HMAC-SHA256 of the body>
+	CallbackSecret string
+}
+
+// Runner runs jobs in the background, recording their progress in the
+// store so any instance can report them
+type Runner struct {
+	store     Store
+	client    *http.Client
+	secret    []byte
+	logger    *logrus.Logger
+	slots     chan struct{}
+	timeout   time.Duration
+	retention time.Duration
+
+	// retryDelay doubles after every failed callback attempt; zeroed in tests
+	retryDelay time.Duration
+
+	mu          sync.Mutex
+	closed      bool
+	lastCleanup time.Time
+	wg          sync.WaitGroup
+}
+
+// NewRunner creates a runner backed by store; a nil store uses the database
+func NewRunner(store Store, config Config, logger *logrus.Logger) *Runner {
+	if store == nil {
+		store = dbStore{}
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+	return &Runner{
+		store:      store,
+		client:     newCallbackClient(),
+		secret:     []byte(config.CallbackSecret),
+		logger:     logger,
+		slots:      make(chan struct{}, config.Workers),
+		timeout:    config.Timeout,
+		retention:  config.Retention,
+		retryDelay: time.Second,
+	}
+}
+
+// newCallbackClient returns the client callbacks are posted with. Its dialer
+// checks the address actually connected to, so a host that resolves to a
+// public address at submit time cannot be rebound to an internal one. It
+// does not use a proxy, which would hide that address.
+func newCallbackClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: callbackTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !callbackAddrAllowed(addr) {
+				return fmt.Errorf("%w: %s", ErrPrivateCallback, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: callbackTimeout, Transport: transport}
+}
+
+// ValidateCallbackURL checks that a callback URL is an absolute http(s) URL
+// whose host resolves to public addresses only
+func ValidateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback URL %q: must be http or https", raw)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve callback host %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !callbackAddrAllowed(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateCallback, u.Hostname(), addr)
+		}
+	}
+	return nil
+}
+
+// callbackHost is the host of a callback URL, logged instead of the URL,
+// whose path and query may carry a token
+func callbackHost(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// Submit stores a pending job and runs fn in the background. The job waits
+// for a free worker, so Submit never blocks on other jobs.
+func (r *Runner) Submit(opts Options, fn Func) (*database.Job, error) {
+	if opts.CallbackURL != "" {
+		if err := ValidateCallbackURL(opts.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+
+	job := &database.Job{
+		ID:          uuid.New().String(),
+		Type:        opts.Type,
+		Subject:     opts.Subject,
+		Status:      database.JobPending,
+		CallbackURL: opts.CallbackURL,
+		CreatedBy:   opts.CreatedBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := r.store.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	timeout := r.timeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	r.wg.Add(1)
+	snapshot := *job
+	go r.run(&snapshot, fn, timeout)
+
+	if time.Since(r.lastCleanup) >= cleanupInterval {
+		r.lastCleanup = time.Now()
+		r.wg.Add(1)
+		go r.cleanup()
+	}
+	return job, nil
+}
+
+// Get returns a job by ID
+func (r *Runner) Get(id string) (*database.Job, error) {
+	return r.store.GetJob(id)
+}
+
+// Close stops accepting jobs and waits for the running ones, or returns
+// ctx's error when its deadline passes first
+func (r *Runner) Close(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) run(job *database.Job, fn Func, timeout time.Duration) {
+	defer r.wg.Done()
+	r.slots <- struct{}{}
+	defer func() { <-r.slots }()
+
+	started := time.Now()
+	job.Status = database.JobRunning
+	job.StartedAt = &started
+	r.save(job)
+
+	progress := &progressReporter{runner: r, job: job}
+	result, err := r.call(fn, timeout, progress)
+	progress.finish()
+
+	if err == nil {
+		job.Result, err = json.Marshal(result)
+	}
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = database.JobFailed
+		job.Error = err.Error()
+		job.Result = nil
+	} else {
+		job.Status = database.JobSucceeded
+	}
+	r.save(job)
+	jobsTotal.WithLabelValues(job.Type, job.Status).Inc()
+
+	r.logger.WithFields(logrus.Fields{
+		"action":      "job_finished",
+		"job_id":      job.ID,
+		"type":        job.Type,
+		"subject":     job.Subject,
+		"status":      job.Status,
+		"duration_ms": finished.Sub(started).Milliseconds(),
+	}).Info("Background job finished")
+
+	if job.CallbackURL != "" {
+		job.CallbackStatus = database.CallbackDelivered
+		if err := r.deliver(job); err != nil {
+			job.CallbackStatus = database.CallbackFailed
+			r.logger.WithFields(logrus.Fields{
+				"action": "job_callback_failed",
+				"job_id": job.ID,
+				"host":   callbackHost(job.CallbackURL),
+				"error":  err.Error(),
+			}).Error("Failed to deliver job callback")
+		}
+		r.save(job)
+	}
+}
+
+// call runs fn within the job timeout, turning a panic into the job's error
+// since the handler's recovery middleware no longer covers it
+func (r *Runner) call(fn Func, timeout time.Duration, progress *progressReporter) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return fn(context.WithValue(ctx, progressKey{}, progress))
+}
+
+type progressKey struct{}
+
+// progressReporter stores the progress of a running job, at most once per
+// progressInterval so long jobs do not hammer the store
+type progressReporter struct {
+	runner *Runner
+	job    *database.Job
+
+	mu       sync.Mutex
+	saved    time.Time
+	finished bool
+}
+
+// ReportProgress records that a job has done done of total items. The
+// progress is stored at most once a second, and always when done reaches
+// total. It does nothing outside a job.
+func ReportProgress(ctx context.Context, done, total int) {
+	p, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.job.Progress = &database.JobProgress{Done: done, Total: total}
+	if done < total && time.Since(p.saved) < progressInterval {
+		return
+	}
+	p.saved = time.Now()
+	p.runner.save(p.job)
+}
+
+// ID returns the ID of the job running with ctx, empty outside a job
+func ID(ctx context.Context) string {
+	p, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return ""
+	}
+	return p.job.ID
+}
+
+// finish stops further reports once the job's function returned
+func (p *progressReporter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished = true
+}
+
+// save stores a job's progress; a failure only loses the progress
+func (r *Runner) save(job *database.Job) {
+	if err := r.store.UpdateJob(job); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"action": "update_job_failed",
+			"job_id": job.ID,
+			"error":  err.Error(),
+		}).Error("Failed to update job")
+	}
+}
+
+func (r *Runner) cleanup() {
+	defer r.wg.Done()
+	if _, err := r.store.DeleteJobsFinishedBefore(time.Now().Add(-r.retention)); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"action": "cleanup_jobs_failed",
+			"error":  err.Error(),
+		}).Warn("Failed to delete old jobs")
+	}
+}
+
+// deliver POSTs the job to its callback URL, retrying transport errors, 429
+// and 5xx
+func (r *Runner) deliver(job *database.Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	delay := r.retryDelay
+	for attempt := 1; attempt <= callbackAttempts; attempt++ {
+		var retry bool
+		if retry, err = r.post(job, body); err == nil || !retry {
+			return err
+		}
+		if attempt < callbackAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", callbackAttempts, err)
+}
+
+func (r *Runner) post(job *database.Job, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-backend-service/1.0")
+	req.Header.Set("X-Job-ID", job.ID)
+	if len(r.secret) > 0 {
+		mac := hmac.New(sha256.New, r.secret)
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// The url.Error names the whole callback URL; keep only its cause
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return !errors.Is(err, ErrPrivateCallback), err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("callback returned %d", resp.StatusCode)
+	}
+	return false, nil
+}
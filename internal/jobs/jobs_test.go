@@ -0,0 +1,364 @@
+package jobs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/validation"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]database.Job
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: make(map[string]database.Job)}
+}
+
+func (s *memoryStore) CreateJob(job *database.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryStore) UpdateJob(job *database.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.ID]; !ok {
+		return database.ErrJobNotFound
+	}
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryStore) GetJob(id string) (*database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, database.ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *memoryStore) DeleteJobsFinishedBefore(cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// allowLoopbackCallbacks lets callbacks reach httptest servers
+func allowLoopbackCallbacks(t *testing.T) {
+	t.Helper()
+	callbackAddrAllowed = func(addr netip.Addr) bool { return addr.IsLoopback() || validation.IsPublicIP(addr) }
+	t.Cleanup(func() { callbackAddrAllowed = validation.IsPublicIP })
+}
+
+func newTestRunner(t *testing.T, config Config) (*Runner, *memoryStore) {
+	t.Helper()
+	logger, _ := test.NewNullLogger()
+	store := newMemoryStore()
+	runner := NewRunner(store, config, logger)
+	runner.retryDelay = 0
+	return runner, store
+}
+
+func closeRunner(t *testing.T, runner *Runner) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := runner.Close(ctx); err != nil {
+		t.Fatalf("Jobs did not finish: %v", err)
+	}
+}
+
+func TestSubmitStoresResult(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{})
+	job, err := runner.Submit(Options{Type: "dnsbl_check", Subject: "192.0.2.1", CreatedBy: "alice"}, func(ctx context.Context) (interface{}, error) {
+		return map[string]bool{"listed": true}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != database.JobPending || job.ID == "" {
+		t.Errorf("Expected a pending job with an ID, got %+v", job)
+	}
+	closeRunner(t, runner)
+
+	stored, err := runner.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != database.JobSucceeded || string(stored.Result) != `{"listed":true}` {
+		t.Errorf("Expected the result stored, got %s %s", stored.Status, stored.Result)
+	}
+	if stored.StartedAt == nil || stored.FinishedAt == nil || stored.CreatedBy != "alice" {
+		t.Errorf("Expected start, finish and author recorded, got %+v", stored)
+	}
+}
+
+func TestSubmitRecordsFailures(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{Timeout: time.Millisecond})
+	failed, _ := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("lookup failed")
+	})
+	timedOut, _ := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	panicked, _ := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	closeRunner(t, runner)
+
+	for id, want := range map[string]string{
+		failed.ID:   "lookup failed",
+		timedOut.ID: context.DeadlineExceeded.Error(),
+		panicked.ID: "job panicked: boom",
+	} {
+		job, _ := runner.Get(id)
+		if job.Status != database.JobFailed || job.Error != want || job.Result != nil {
+			t.Errorf("Expected failed job with error %q, got %s %q", want, job.Status, job.Error)
+		}
+	}
+}
+
+func TestSubmitTimeoutOverride(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{Timeout: time.Millisecond})
+	job, _ := runner.Submit(Options{Type: "test", Timeout: time.Minute}, func(ctx context.Context) (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, ctx.Err()
+	})
+	closeRunner(t, runner)
+
+	if stored, _ := runner.Get(job.ID); stored.Status != database.JobSucceeded {
+		t.Errorf("Expected the job's own timeout to apply, got %s %q", stored.Status, stored.Error)
+	}
+}
+
+func TestReportProgress(t *testing.T) {
+	runner, store := newTestRunner(t, Config{})
+	reported := make(chan struct{})
+	release := make(chan struct{})
+	job, _ := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+		ReportProgress(ctx, 1, 3)
+		// Within a second of the last report, so only kept in memory
+		ReportProgress(ctx, 2, 3)
+		close(reported)
+		<-release
+		ReportProgress(ctx, 3, 3)
+		return nil, nil
+	})
+
+	<-reported
+	running, _ := store.GetJob(job.ID)
+	if running.Status != database.JobRunning || running.Progress == nil || *running.Progress != (database.JobProgress{Done: 1, Total: 3}) {
+		t.Errorf("Expected the first progress stored, got %s %+v", running.Status, running.Progress)
+	}
+	close(release)
+	closeRunner(t, runner)
+
+	finished, _ := runner.Get(job.ID)
+	if finished.Progress == nil || *finished.Progress != (database.JobProgress{Done: 3, Total: 3}) {
+		t.Errorf("Expected the final progress stored, got %+v", finished.Progress)
+	}
+
+	// Outside a job there is nothing to report to
+	ReportProgress(context.Background(), 1, 1)
+}
+
+func TestID(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{})
+	var id string
+	job, _ := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+		id = ID(ctx)
+		return nil, nil
+	})
+	closeRunner(t, runner)
+
+	if id != job.ID {
+		t.Errorf("Expected the job's ID %q, got %q", job.ID, id)
+	}
+	if id := ID(context.Background()); id != "" {
+		t.Errorf("Expected no ID outside a job, got %q", id)
+	}
+}
+
+func TestSubmitLimitsWorkers(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{Workers: 2})
+	var running, peak int32
+	release := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			return nil, nil
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	closeRunner(t, runner)
+
+	if peak != 2 {
+		t.Errorf("Expected at most 2 jobs at once, got %d", peak)
+	}
+}
+
+func TestSubmitAfterClose(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{})
+	closeRunner(t, runner)
+	if _, err := runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) { return nil, nil }); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestSubmitRejectsInvalidCallback(t *testing.T) {
+	runner, _ := newTestRunner(t, Config{})
+	for _, raw := range []string{"ftp://example.com/hook", "/relative", "http://"} {
+		if _, err := runner.Submit(Options{Type: "test", CallbackURL: raw}, nil); err == nil {
+			t.Errorf("Expected %q rejected", raw)
+		}
+	}
+	for _, raw := range []string{"http://127.0.0.1:8080/hook", "http://10.0.0.5/hook", "https://[::1]/hook", "http://169.254.169.254/latest/meta-data", "http://0.0.0.0/", "http://[::ffff:192.168.1.1]/"} {
+		if _, err := runner.Submit(Options{Type: "test", CallbackURL: raw}, nil); !errors.Is(err, ErrPrivateCallback) {
+			t.Errorf("Expected %q rejected as private, got %v", raw, err)
+		}
+	}
+	if err := ValidateCallbackURL("https://203.0.113.10/hook"); err != nil {
+		t.Errorf("Expected a public callback accepted, got %v", err)
+	}
+}
+
+func TestCallbackDialerRefusesPrivateAddresses(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+	}))
+	defer server.Close()
+
+	runner, _ := newTestRunner(t, Config{})
+	err := runner.deliver(&database.Job{ID: "rebound", CallbackURL: server.URL + "/hook?token=secret"})
+	if !errors.Is(err, ErrPrivateCallback) || attempts != 0 {
+		t.Fatalf("Expected the loopback callback refused by the dialer without retries, got %d attempts and %v", attempts, err)
+	}
+	if strings.Contains(err.Error(), "token=secret") {
+		t.Errorf("Expected the error not to carry the callback URL, got %v", err)
+	}
+}
+
+func TestCallbackDelivery(t *testing.T) {
+	allowLoopbackCallbacks(t)
+	var attempts int32
+	var body []byte
+	var signature, jobID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature-256")
+		jobID = r.Header.Get("X-Job-ID")
+	}))
+	defer server.Close()
+
+	runner, _ := newTestRunner(t, Config{CallbackSecret: "s3cret"})
+	job, err := runner.Submit(Options{Type: "test", CallbackURL: server.URL}, func(ctx context.Context) (interface{}, error) {
+		return []string{"zen.spamhaus.org"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeRunner(t, runner)
+
+	if attempts != 2 || jobID != job.ID {
+		t.Fatalf("Expected the callback retried once for job %s, got %d attempts for %q", job.ID, attempts, jobID)
+	}
+	var delivered database.Job
+	if err := json.Unmarshal(body, &delivered); err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != database.JobSucceeded || string(delivered.Result) != `["zen.spamhaus.org"]` {
+		t.Errorf("Expected the finished job in the callback, got %s", body)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("Unexpected signature %q", signature)
+	}
+
+	stored, _ := runner.Get(job.ID)
+	if stored.CallbackStatus != database.CallbackDelivered {
+		t.Errorf("Expected callback delivered, got %q", stored.CallbackStatus)
+	}
+}
+
+func TestCallbackFailure(t *testing.T) {
+	allowLoopbackCallbacks(t)
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	runner, _ := newTestRunner(t, Config{})
+	job, _ := runner.Submit(Options{Type: "test", CallbackURL: server.URL}, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	closeRunner(t, runner)
+
+	stored, _ := runner.Get(job.ID)
+	if attempts != 1 || stored.CallbackStatus != database.CallbackFailed {
+		t.Errorf("Expected a 404 to fail the callback without retries, got %d attempts and %q", attempts, stored.CallbackStatus)
+	}
+}
+
+func TestCleanupRemovesOldJobs(t *testing.T) {
+	runner, store := newTestRunner(t, Config{Retention: time.Hour})
+	old := time.Now().Add(-2 * time.Hour)
+	store.jobs["old"] = database.Job{ID: "old", Status: database.JobSucceeded, FinishedAt: &old}
+	store.jobs["pending"] = database.Job{ID: "pending", Status: database.JobPending}
+
+	runner.Submit(Options{Type: "test"}, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	closeRunner(t, runner)
+
+	if _, err := runner.Get("old"); err != database.ErrJobNotFound {
+		t.Errorf("Expected the old job removed, got %v", err)
+	}
+	if _, err := runner.Get("pending"); err != nil {
+		t.Errorf("Expected the unfinished job kept, got %v", err)
+	}
+}
@@ -6,38 +6,17 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Log is the logrus logger used by existing call sites. After Init or Setup
+// it forwards to Slog.
 var Log *logrus.Logger
 
-// Init initializes the logger with specified configuration
+// Init initializes JSON logging to stdout at the specified level
 func Init(level string) error {
-	Log = logrus.New()
-
-	// Set output to stdout
-	Log.SetOutput(os.Stdout)
-
-	// Set log format to JSON for easy parsing by log aggregation tools
-	Log.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: "2006-01-02T15:04:05.000Z",
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "level",
-			logrus.FieldKeyMsg:   "message",
-		},
+	return Setup(Options{
+		Level:  level,
+		Format: "json",
+		Output: os.Stdout,
 	})
-
-	// Parse and set log level
-	logLevel, err := logrus.ParseLevel(level)
-	if err != nil {
-		Log.Warn("Invalid log level provided, defaulting to INFO")
-		logLevel = logrus.InfoLevel
-	}
-	Log.SetLevel(logLevel)
-
-	Log.WithFields(logrus.Fields{
-		"level": logLevel.String(),
-	}).Info("Logger initialized successfully")
-
-	return nil
 }
 
 // WithFields creates a new log entry with the specified fields
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewLogrusAdapter returns a logrus logger that forwards every entry to the
+// given slog logger. Entries below level are dropped by logrus itself.
+func NewLogrusAdapter(l *slog.Logger, level slog.Level) *logrus.Logger {
+	adapter := logrus.New()
+	adapter.SetOutput(io.Discard)
+	adapter.SetLevel(toLogrusLevel(level))
+	adapter.AddHook(&slogHook{handler: l.Handler()})
+	return adapter
+}
+
+// slogHook turns logrus entries into slog records
+type slogHook struct {
+	handler slog.Handler
+}
+
+func (h *slogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *slogHook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	level := fromLogrusLevel(entry.Level)
+	if !h.handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	record := slog.NewRecord(entry.Time, level, entry.Message, 0)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := entry.Data[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		record.AddAttrs(slog.Any(k, v))
+	}
+
+	return h.handler.Handle(ctx, record)
+}
+
+func fromLogrusLevel(level logrus.Level) slog.Level {
+	switch level {
+	case logrus.TraceLevel:
+		return LevelTrace
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+func toLogrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return logrus.TraceLevel
+	case level < slog.LevelInfo:
+		return logrus.DebugLevel
+	case level < slog.LevelWarn:
+		return logrus.InfoLevel
+	case level < slog.LevelError:
+		return logrus.WarnLevel
+	case level < LevelFatal:
+		return logrus.ErrorLevel
+	default:
+		return logrus.FatalLevel
+	}
+}
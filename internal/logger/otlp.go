@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	otlpBatchSize     = 256
+	otlpFlushInterval = 2 * time.Second
+	otlpQueueSize     = 4096
+)
+
+// newOTLPHandler builds a handler that ships records to an OTLP/HTTP collector
+// using the JSON encoding of the logs signal
+func newOTLPHandler(opts Options, _ func([]string, slog.Attr) slog.Attr) (slog.Handler, error) {
+	if opts.OTLPEndpoint == "" {
+		return nil, errors.New("otlp log format requires an OTLP endpoint")
+	}
+
+	exp := &otlpExporter{
+		url:         strings.TrimSuffix(opts.OTLPEndpoint, "/") + "/v1/logs",
+		serviceName: opts.ServiceName,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		queue:       make(chan otlpLogRecord, otlpQueueSize),
+		done:        make(chan struct{}),
+	}
+	exp.wg.Add(1)
+	go exp.run()
+
+	return &otlpHandler{exp: exp}, nil
+}
+
+// otlpHandler converts slog records into OTLP log records
+type otlpHandler struct {
+	exp    *otlpExporter
+	attrs  []otlpKeyValue
+	prefix string
+}
+
+func (h *otlpHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *otlpHandler) Handle(_ context.Context, r slog.Record) error {
+	record := otlpLogRecord{
+		TimeUnixNano:   strconv.FormatInt(r.Time.UnixNano(), 10),
+		SeverityNumber: otlpSeverity(r.Level),
+		SeverityText:   strings.ToUpper(levelName(r.Level)),
+		Body:           otlpAnyValue{StringValue: stringPtr(r.Message)},
+		Attributes:     append([]otlpKeyValue(nil), h.attrs...),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		record.Attributes = appendOTLPAttr(record.Attributes, h.prefix, a)
+		return true
+	})
+
+	h.exp.enqueue(record)
+	return nil
+}
+
+func (h *otlpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &otlpHandler{exp: h.exp, prefix: h.prefix, attrs: append([]otlpKeyValue(nil), h.attrs...)}
+	for _, a := range attrs {
+		next.attrs = appendOTLPAttr(next.attrs, h.prefix, a)
+	}
+	return next
+}
+
+func (h *otlpHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &otlpHandler{exp: h.exp, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// Close flushes pending records and stops the exporter
+func (h *otlpHandler) Close() error {
+	return h.exp.Close()
+}
+
+func appendOTLPAttr(kvs []otlpKeyValue, prefix string, a slog.Attr) []otlpKeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			kvs = appendOTLPAttr(kvs, prefix+a.Key+".", ga)
+		}
+		return kvs
+	}
+	return append(kvs, otlpKeyValue{Key: prefix + a.Key, Value: otlpValue(a.Value)})
+}
+
+func otlpValue(v slog.Value) otlpAnyValue {
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpAnyValue{BoolValue: &b}
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindUint64:
+		s := strconv.FormatUint(v.Uint64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	default:
+		return otlpAnyValue{StringValue: stringPtr(v.String())}
+	}
+}
+
+// otlpSeverity maps slog levels onto the OTLP severity number ranges
+func otlpSeverity(level slog.Level) int {
+	switch {
+	case level < slog.LevelDebug:
+		return 1
+	case level < slog.LevelInfo:
+		return 5
+	case level < slog.LevelWarn:
+		return 9
+	case level < slog.LevelError:
+		return 13
+	case level < LevelFatal:
+		return 17
+	default:
+		return 21
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// otlpExporter batches records and posts them to the collector
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	queue       chan otlpLogRecord
+	done        chan struct{}
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+func (e *otlpExporter) enqueue(r otlpLogRecord) {
+	select {
+	case <-e.done:
+	case e.queue <- r:
+	default:
+		// Drop rather than block callers when the collector falls behind
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpLogRecord, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to export logs to OTLP collector: %v\n", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case r := <-e.queue:
+			batch = append(batch, r)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case r := <-e.queue:
+					batch = append(batch, r)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *otlpExporter) export(records []otlpLogRecord) error {
+	payload := otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				{Key: "service.name", Value: otlpAnyValue{StringValue: stringPtr(e.serviceName)}},
+			}},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "golang-backend-service/internal/logger"},
+				LogRecords: records,
+			}},
+		}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode logs: %w", err)
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close flushes pending records and waits for the exporter to stop
+func (e *otlpExporter) Close() error {
+	e.closeOnce.Do(func() { close(e.done) })
+	e.wg.Wait()
+	return nil
+}
+
+// OTLP/HTTP JSON payload types (opentelemetry-proto logs/v1)
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
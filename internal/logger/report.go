@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"golang-backend-service/internal/reporting"
+)
+
+// reportHandler forwards error records that carry an "error" attribute to an
+// error reporter, tagged with the record's other scalar attributes so
+// failures can be filtered by module, action, ip and so on. Records with a
+// "stack" attribute come from panic recovery, which reports them itself.
+type reportHandler struct {
+	inner    slog.Handler
+	reporter reporting.ErrorReporter
+	attrs    []slog.Attr
+}
+
+func (h *reportHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *reportHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		h.report(r)
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *reportHandler) report(r slog.Record) {
+	var errText string
+	hasError, hasStack := false, false
+	tags := make(map[string]string)
+	collect := func(a slog.Attr) bool {
+		switch a.Key {
+		case "error":
+			errText, hasError = a.Value.String(), true
+		case "stack":
+			hasStack = true
+		default:
+			switch a.Value.Kind() {
+			case slog.KindString, slog.KindInt64, slog.KindUint64, slog.KindBool, slog.KindFloat64:
+				tags[a.Key] = a.Value.String()
+			}
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		collect(a)
+	}
+	r.Attrs(collect)
+	if !hasError || hasStack {
+		return
+	}
+
+	// Group by where the failure was logged, not by its message, which
+	// usually contains IPs or hosts
+	what := tags["action"]
+	if what == "" {
+		what = r.Message
+	}
+	h.reporter.Report(reporting.Event{
+		Message:     r.Message,
+		Err:         errors.New(errText),
+		Tags:        tags,
+		Fingerprint: []string{tags["module"], what},
+		Timestamp:   r.Time,
+	})
+}
+
+func (h *reportHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &reportHandler{inner: h.inner.WithAttrs(attrs), reporter: h.reporter, attrs: merged}
+}
+
+// WithGroup keeps tagging with the ungrouped attributes only
+func (h *reportHandler) WithGroup(name string) slog.Handler {
+	return &reportHandler{inner: h.inner.WithGroup(name), reporter: h.reporter, attrs: h.attrs}
+}
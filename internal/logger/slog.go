@@ -0,0 +1,337 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"golang-backend-service/internal/reporting"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Custom slog levels for the logrus levels slog does not define
+const (
+	LevelTrace = slog.LevelDebug - 4
+	LevelFatal = slog.LevelError + 4
+)
+
+// Slog is the primary structured logger. Log is a logrus adapter on top of it
+// kept for existing call sites.
+var Slog *slog.Logger
+
+// Options configures the slog backend
+type Options struct {
+	// Level is the default level for all modules (trace, debug, info, warn, error)
+	Level string
+	// Format selects the handler: json, text (or console), otlp, or any name
+	// added with RegisterHandler
+	Format string
+	// Modules overrides the level per module, e.g. {"ionos": "debug"}
+	Modules map[string]string
+	// Output is where the json and text handlers write; defaults to stdout
+	Output io.Writer
+	// OTLPEndpoint is the OTLP/HTTP collector base URL for the otlp handler
+	OTLPEndpoint string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// Redact, when set, rewrites every top-level attribute before it reaches
+	// the handler, e.g. to pseudonymize recipient addresses
+	Redact func(slog.Attr) slog.Attr
+	// Reporter, when set, receives error records that carry an "error"
+	// attribute, after redaction
+	Reporter reporting.ErrorReporter
+}
+
+// HandlerFactory builds a slog handler for the given options. The handler
+// should accept every level; per-module filtering happens in front of it.
+type HandlerFactory func(opts Options, replace func([]string, slog.Attr) slog.Attr) (slog.Handler, error)
+
+var (
+	mu           sync.RWMutex
+	handlers     = map[string]HandlerFactory{}
+	base         slog.Handler
+	defaultLevel = slog.LevelInfo
+	moduleLevels = map[string]slog.Level{}
+	closers      []io.Closer
+)
+
+func init() {
+	RegisterHandler("json", func(opts Options, replace func([]string, slog.Attr) slog.Attr) (slog.Handler, error) {
+		return slog.NewJSONHandler(opts.Output, &slog.HandlerOptions{Level: LevelTrace, ReplaceAttr: replace}), nil
+	})
+	text := func(opts Options, replace func([]string, slog.Attr) slog.Attr) (slog.Handler, error) {
+		return slog.NewTextHandler(opts.Output, &slog.HandlerOptions{Level: LevelTrace, ReplaceAttr: replace}), nil
+	}
+	RegisterHandler("text", text)
+	RegisterHandler("console", text)
+	RegisterHandler("otlp", newOTLPHandler)
+}
+
+// RegisterHandler makes a handler available under the given format name
+func RegisterHandler(name string, factory HandlerFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[strings.ToLower(name)] = factory
+}
+
+// Setup configures Slog and the logrus adapter Log from the given options
+func Setup(opts Options) error {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.Format == "" {
+		opts.Format = "json"
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "golang-backend-service"
+	}
+
+	level, levelErr := ParseLevel(opts.Level)
+	if levelErr != nil {
+		level = slog.LevelInfo
+	}
+
+	modules := make(map[string]slog.Level, len(opts.Modules))
+	for module, raw := range opts.Modules {
+		moduleLevel, err := ParseLevel(raw)
+		if err != nil {
+			return fmt.Errorf("invalid log level for module %s: %w", module, err)
+		}
+		modules[module] = moduleLevel
+	}
+
+	mu.RLock()
+	factory, ok := handlers[strings.ToLower(opts.Format)]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown log format: %s", opts.Format)
+	}
+
+	handler, err := factory(opts, replaceAttr)
+	if err != nil {
+		return fmt.Errorf("failed to create %s log handler: %w", opts.Format, err)
+	}
+
+	if opts.Reporter != nil {
+		handler = &reportHandler{inner: handler, reporter: opts.Reporter}
+	}
+	if opts.Redact != nil {
+		handler = &redactHandler{inner: handler, redact: opts.Redact}
+	}
+
+	Close()
+
+	mu.Lock()
+	base = handler
+	defaultLevel = level
+	moduleLevels = modules
+	if c, ok := unwrapCloser(handler); ok {
+		closers = append(closers, c)
+	}
+	mu.Unlock()
+
+	Slog = slog.New(&levelHandler{inner: handler, level: level})
+	slog.SetDefault(Slog)
+	Log = NewLogrusAdapter(Slog, level)
+
+	if levelErr != nil {
+		Log.Warn("Invalid log level provided, defaulting to INFO")
+	}
+
+	Log.WithFields(logrus.Fields{
+		"level":  levelName(level),
+		"format": opts.Format,
+	}).Info("Logger initialized successfully")
+
+	return nil
+}
+
+// Close flushes and releases handlers that buffer output
+func Close() error {
+	mu.Lock()
+	pending := closers
+	closers = nil
+	mu.Unlock()
+
+	var firstErr error
+	for _, c := range pending {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// For returns a slog logger for the named module, honouring its level override
+func For(module string) *slog.Logger {
+	handler, level := moduleHandler(module)
+	return slog.New(&levelHandler{inner: handler, level: level}).With("module", module)
+}
+
+// Module returns a logrus logger for the named module, honouring its level
+// override. Use it for constructors that still take a *logrus.Logger.
+func Module(module string) *logrus.Logger {
+	_, level := moduleHandler(module)
+	return NewLogrusAdapter(For(module), level)
+}
+
+func moduleHandler(module string) (slog.Handler, slog.Level) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	handler := base
+	if handler == nil {
+		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: LevelTrace, ReplaceAttr: replaceAttr})
+	}
+	level, ok := moduleLevels[module]
+	if !ok {
+		level = defaultLevel
+	}
+	return handler, level
+}
+
+// ParseModuleLevels parses per-module overrides written as
+// "ionos=debug,reputation=warn"
+func ParseModuleLevels(s string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		module, level, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(module) == "" {
+			return nil, fmt.Errorf("invalid module level %q, expected module=level", pair)
+		}
+		if _, err := ParseLevel(level); err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(module)] = strings.TrimSpace(level)
+	}
+	return levels, nil
+}
+
+// ParseLevel converts a level name into a slog level
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "fatal", "panic":
+		return LevelFatal, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level: %s", s)
+	}
+}
+
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "trace"
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warning"
+	case level < LevelFatal:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// replaceAttr keeps the field names and level strings the logrus JSON output used
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+		a.Value = slog.StringValue(a.Value.Time().UTC().Format("2006-01-02T15:04:05.000Z"))
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(levelName(level))
+		}
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// levelHandler filters records below a fixed level before passing them on
+type levelHandler struct {
+	inner slog.Handler
+	level slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.inner.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{inner: h.inner.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{inner: h.inner.WithGroup(name), level: h.level}
+}
+
+// redactHandler rewrites attributes before passing records on
+type redactHandler struct {
+	inner  slog.Handler
+	redact func(slog.Attr) slog.Attr
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.inner.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &redactHandler{inner: h.inner.WithAttrs(redacted), redact: h.redact}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{inner: h.inner.WithGroup(name), redact: h.redact}
+}
+
+func unwrapCloser(h slog.Handler) (io.Closer, bool) {
+	if r, ok := h.(*redactHandler); ok {
+		h = r.inner
+	}
+	if r, ok := h.(*reportHandler); ok {
+		h = r.inner
+	}
+	c, ok := h.(io.Closer)
+	return c, ok
+}
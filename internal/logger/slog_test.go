@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang-backend-service/internal/reporting"
+
+	"github.com/sirupsen/logrus"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestSetupKeepsLogrusJSONFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(Options{Level: "info", Format: "json", Output: &buf}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	buf.Reset()
+
+	Log.WithFields(logrus.Fields{
+		"action": "test",
+		"error":  errors.New("boom"),
+	}).Warn("something happened")
+	Log.Debug("should be filtered")
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 log line, got %d: %s", len(lines), buf.String())
+	}
+
+	entry := lines[0]
+	if entry["message"] != "something happened" {
+		t.Errorf("Expected message field, got %v", entry["message"])
+	}
+	if entry["level"] != "warning" {
+		t.Errorf("Expected level warning, got %v", entry["level"])
+	}
+	if entry["action"] != "test" {
+		t.Errorf("Expected action field, got %v", entry["action"])
+	}
+	if entry["error"] != "boom" {
+		t.Errorf("Expected error to be stringified, got %v", entry["error"])
+	}
+	if _, ok := entry["timestamp"]; !ok {
+		t.Error("Expected timestamp field")
+	}
+}
+
+func TestModuleLevelOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	err := Setup(Options{
+		Level:   "warn",
+		Format:  "json",
+		Output:  &buf,
+		Modules: map[string]string{"ionos": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	buf.Reset()
+
+	Module("ionos").Debug("ionos debug")
+	Module("reputation").Debug("reputation debug")
+	Module("reputation").Warn("reputation warn")
+	For("ionos").Debug("ionos slog debug")
+	Slog.Info("root info")
+
+	lines := decodeLines(t, &buf)
+	var messages []string
+	for _, line := range lines {
+		messages = append(messages, line["message"].(string))
+	}
+
+	expected := []string{"ionos debug", "reputation warn", "ionos slog debug"}
+	if strings.Join(messages, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected messages %v, got %v", expected, messages)
+	}
+	if lines[0]["module"] != "ionos" {
+		t.Errorf("Expected module attribute ionos, got %v", lines[0]["module"])
+	}
+}
+
+func TestSetupRejectsBadOptions(t *testing.T) {
+	if err := Setup(Options{Format: "xml", Output: io.Discard}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if err := Setup(Options{Format: "json", Output: io.Discard, Modules: map[string]string{"ionos": "loud"}}); err == nil {
+		t.Error("Expected error for invalid module level")
+	}
+	if err := Setup(Options{Format: "otlp", Output: io.Discard}); err == nil {
+		t.Error("Expected error for otlp without endpoint")
+	}
+}
+
+func TestParseModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels(" ionos=debug, reputation=warn ,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if levels["ionos"] != "debug" || levels["reputation"] != "warn" || len(levels) != 2 {
+		t.Errorf("Unexpected levels: %v", levels)
+	}
+
+	for _, bad := range []string{"ionos", "=debug", "ionos=loud"} {
+		if _, err := ParseModuleLevels(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestOTLPHandlerExportsOnClose(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []otlpLogsRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var payload otlpLogsRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	err := Setup(Options{
+		Level:        "info",
+		Format:       "otlp",
+		OTLPEndpoint: server.URL,
+		ServiceName:  "test-service",
+	})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	Module("ionos").WithFields(logrus.Fields{"count": 3}).Error("export me")
+	if err := Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var found *otlpLogRecord
+	for _, p := range payloads {
+		for _, rl := range p.ResourceLogs {
+			if v := rl.Resource.Attributes[0].Value.StringValue; v == nil || *v != "test-service" {
+				t.Errorf("Expected service.name test-service")
+			}
+			for _, sl := range rl.ScopeLogs {
+				for i := range sl.LogRecords {
+					if *sl.LogRecords[i].Body.StringValue == "export me" {
+						found = &sl.LogRecords[i]
+					}
+				}
+			}
+		}
+	}
+	if found == nil {
+		t.Fatal("Expected exported record")
+	}
+	if found.SeverityNumber != 17 {
+		t.Errorf("Expected severity 17, got %d", found.SeverityNumber)
+	}
+
+	attrs := map[string]otlpAnyValue{}
+	for _, kv := range found.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["module"].StringValue; v == nil || *v != "ionos" {
+		t.Errorf("Expected module attribute ionos")
+	}
+	if v := attrs["count"].IntValue; v == nil || *v != "3" {
+		t.Errorf("Expected count attribute 3")
+	}
+}
+
+func TestRedactAppliesToFieldsAndAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	err := Setup(Options{
+		Format: "json",
+		Output: &buf,
+		Redact: func(a slog.Attr) slog.Attr {
+			if a.Key == "recipient" {
+				return slog.String(a.Key, "[redacted]")
+			}
+			return a
+		},
+	})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	buf.Reset()
+
+	Log.WithField("recipient", "alice@example.com").Info("from logrus")
+	Slog.With("recipient", "bob@example.com").Info("from slog")
+
+	for _, line := range decodeLines(t, &buf) {
+		if line["recipient"] != "[redacted]" {
+			t.Errorf("Expected recipient to be redacted in %q, got %v", line["message"], line["recipient"])
+		}
+	}
+}
+
+type recordingReporter struct {
+	events []reporting.Event
+}
+
+func (r *recordingReporter) Report(event reporting.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestSetupReportsLoggedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := &recordingReporter{}
+	redact := func(a slog.Attr) slog.Attr {
+		if a.Key == "recipient" {
+			return slog.String(a.Key, "***@example.com")
+		}
+		return a
+	}
+	if err := Setup(Options{Level: "info", Format: "json", Output: &buf, Redact: redact, Reporter: reporter}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	log := Module("reputation")
+	log.WithFields(logrus.Fields{
+		"action":    "aggregate_metrics",
+		"ip":        "192.0.2.1",
+		"recipient": "alice@example.com",
+		"error":     errors.New("connection refused"),
+	}).Error("Failed to aggregate metrics for IP")
+	log.WithField("error", "no route").Warn("Warnings are not reported")
+	log.Error("Errors without an error attribute are not reported")
+	log.WithFields(logrus.Fields{"error": "nil map", "stack": "goroutine 1"}).Error("Recovered panic")
+
+	if len(reporter.events) != 1 {
+		t.Fatalf("Expected 1 reported event, got %d: %+v", len(reporter.events), reporter.events)
+	}
+	event := reporter.events[0]
+	if event.Message != "Failed to aggregate metrics for IP" || event.Err.Error() != "connection refused" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Tags["module"] != "reputation" || event.Tags["ip"] != "192.0.2.1" || event.Tags["recipient"] != "***@example.com" {
+		t.Errorf("Expected module, ip and redacted recipient tags, got %v", event.Tags)
+	}
+	if len(event.Fingerprint) != 2 || event.Fingerprint[0] != "reputation" || event.Fingerprint[1] != "aggregate_metrics" {
+		t.Errorf("Unexpected fingerprint: %v", event.Fingerprint)
+	}
+}
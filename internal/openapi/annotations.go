@@ -0,0 +1,404 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mimeTypes expands swag's @Accept and @Produce aliases
+var mimeTypes = map[string]string{
+	"json":                  "application/json",
+	"xml":                   "application/xml",
+	"plain":                 "text/plain",
+	"html":                  "text/html",
+	"mpfd":                  "multipart/form-data",
+	"x-www-form-urlencoded": "application/x-www-form-urlencoded",
+	"octet-stream":          "application/octet-stream",
+}
+
+// readAnnotations parses the swag comments of the handlers in dir into one
+// operation per @Router line, keyed by method and path
+func readAnnotations(dir string, schemas *schemaBuilder) (map[string]*Operation, error) {
+	pkg, err := schemas.load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make(map[string]*Operation)
+	for _, file := range pkg.files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			ann := parseAnnotation(fn.Doc)
+			if len(ann.routes) == 0 {
+				continue
+			}
+			for _, route := range ann.routes {
+				op, err := ann.operation(schemas, pkg, file)
+				if err != nil {
+					pos := schemas.fset.Position(fn.Pos())
+					return nil, fmt.Errorf("%s: %s: %w", filepath.Base(pos.Filename), fn.Name.Name, err)
+				}
+				operations[route] = op
+			}
+		}
+	}
+	return operations, nil
+}
+
+// annotation is the swag comment of one handler
+type annotation struct {
+	summary     string
+	description []string
+	tags        []string
+	accept      []string
+	produce     []string
+	params      []string
+	responses   []string
+	headers     []string
+	security    []string
+	routes      []string
+}
+
+func parseAnnotation(doc *ast.CommentGroup) annotation {
+	var a annotation
+	for _, c := range doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		attribute, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(attribute) {
+		case "@summary":
+			a.summary = value
+		case "@description":
+			a.description = append(a.description, value)
+		case "@tags":
+			a.tags = append(a.tags, splitList(value)...)
+		case "@accept":
+			a.accept = append(a.accept, splitList(value)...)
+		case "@produce":
+			a.produce = append(a.produce, splitList(value)...)
+		case "@param":
+			a.params = append(a.params, value)
+		case "@success", "@failure":
+			a.responses = append(a.responses, value)
+		case "@header":
+			a.headers = append(a.headers, value)
+		case "@security":
+			a.security = append(a.security, value)
+		case "@router":
+			// /api/ips/{ip}/reputation [get]
+			path, method, ok := strings.Cut(value, " ")
+			if ok {
+				a.routes = append(a.routes, routeKey(strings.Trim(strings.TrimSpace(method), "[]"), path))
+			}
+		}
+	}
+	return a
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func mimeType(alias string) string {
+	if mime, ok := mimeTypes[alias]; ok {
+		return mime
+	}
+	return alias
+}
+
+// contentType is the first of the declared types, JSON if none are
+func contentType(declared []string) string {
+	if len(declared) == 0 {
+		return "application/json"
+	}
+	return mimeType(declared[0])
+}
+
+// operation builds the annotation's operation; each route gets its own, as
+// operation IDs and path parameters are adjusted per route
+func (a annotation) operation(schemas *schemaBuilder, pkg *sourcePackage, file *ast.File) (*Operation, error) {
+	op := &Operation{
+		OperationID: identifier([]string{a.summary}, false),
+		Summary:     a.summary,
+		Description: strings.Join(a.description, "\n"),
+		Tags:        a.tags,
+		Responses:   map[string]*Response{},
+	}
+	if a.summary == "" {
+		op.OperationID = ""
+	}
+
+	for _, line := range a.params {
+		fields := tokenize(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("malformed @Param %q", line)
+		}
+		name, in, typ := fields[0], fields[1], fields[2]
+		required, _ := strconv.ParseBool(fields[3])
+		description, options := "", fields[4:]
+		if len(options) > 0 && isQuoted(options[0]) {
+			description, options = unquote(options[0]), options[1:]
+		}
+
+		if in == "body" {
+			schema, err := schemas.annotatedType("object", typ, pkg, file)
+			if err != nil {
+				return nil, err
+			}
+			op.RequestBody = &RequestBody{
+				Description: description,
+				Required:    required,
+				Content:     map[string]MediaType{contentType(a.accept): {Schema: schema}},
+			}
+			continue
+		}
+		if in == "formData" {
+			in = "query"
+		}
+		schema := primitiveSchema(typ)
+		if schema == nil {
+			return nil, fmt.Errorf("@Param %s has unsupported type %q", name, typ)
+		}
+		for _, option := range options {
+			applyParamOption(schema, option)
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        name,
+			In:          in,
+			Description: description,
+			Required:    required || in == "path",
+			Schema:      schema,
+		})
+	}
+
+	for _, line := range a.responses {
+		fields := tokenize(line)
+		if len(fields) == 0 {
+			continue
+		}
+		code := fields[0]
+		resp := &Response{Description: statusText(code)}
+		rest := fields[1:]
+		if len(rest) >= 2 && strings.HasPrefix(rest[0], "{") {
+			kind := strings.Trim(rest[0], "{}")
+			schema, err := schemas.annotatedType(kind, rest[1], pkg, file)
+			if err != nil {
+				return nil, err
+			}
+			mime := contentType(a.produce)
+			if kind == "file" {
+				schema = &Schema{Type: "string", Format: "binary"}
+			}
+			resp.Content = map[string]MediaType{mime: {Schema: schema}}
+			rest = rest[2:]
+		}
+		if len(rest) > 0 && isQuoted(rest[0]) {
+			resp.Description = unquote(rest[0])
+		}
+		op.Responses[code] = resp
+	}
+
+	for _, line := range a.headers {
+		// 202 {string} Location "URL of the job"
+		fields := tokenize(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("malformed @Header %q", line)
+		}
+		resp := op.Responses[fields[0]]
+		if resp == nil {
+			resp = &Response{Description: statusText(fields[0])}
+			op.Responses[fields[0]] = resp
+		}
+		if resp.Headers == nil {
+			resp.Headers = map[string]Header{}
+		}
+		header := Header{Schema: primitiveSchema(strings.Trim(fields[1], "{}"))}
+		if len(fields) > 3 {
+			header.Description = unquote(fields[3])
+		}
+		resp.Headers[fields[2]] = header
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["default"] = &Response{Description: "Undocumented response"}
+	}
+
+	for _, name := range a.security {
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+	return op, nil
+}
+
+// applyParamOption applies swag's default(...) and Enums(...) options
+func applyParamOption(schema *Schema, option string) {
+	name, value, ok := strings.Cut(option, "(")
+	if !ok || !strings.HasSuffix(value, ")") {
+		return
+	}
+	value = strings.TrimSuffix(value, ")")
+	switch strings.ToLower(name) {
+	case "default":
+		schema.Default = typedValue(schema.Type, value)
+	case "enums":
+		for _, v := range strings.Split(value, ",") {
+			schema.Enum = append(schema.Enum, typedValue(schema.Type, strings.TrimSpace(v)))
+		}
+	}
+}
+
+func typedValue(typ, value string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// primitiveSchema maps swag's parameter types
+func primitiveSchema(typ string) *Schema {
+	switch typ {
+	case "string":
+		return &Schema{Type: "string"}
+	case "int", "integer":
+		return &Schema{Type: "integer"}
+	case "int64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "number", "float", "float64":
+		return &Schema{Type: "number"}
+	case "bool", "boolean":
+		return &Schema{Type: "boolean"}
+	case "file":
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	return nil
+}
+
+// tokenize splits an annotation on spaces, keeping quoted strings and
+// parenthesized options whole
+func tokenize(line string) []string {
+	var fields []string
+	var current strings.Builder
+	quoted, depth := false, 0
+	for _, r := range line {
+		switch {
+		case r == '"' && depth == 0:
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+		case r == ')' && !quoted && depth > 0:
+			depth--
+		case (r == ' ' || r == '\t') && !quoted && depth == 0:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)
+}
+
+func unquote(s string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(s, `"`), `"`)
+}
+
+// readGeneralInfo reads the API's title, version, description, contact and
+// security definitions from the comments of file
+func readGeneralInfo(file string, info *Info) (map[string]SecurityScheme, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read general API info: %w", err)
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse general API info: %w", err)
+	}
+
+	schemes := make(map[string]SecurityScheme)
+	var scheme string
+	for _, group := range parsed.Comments {
+		for _, c := range group.List {
+			line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			attribute, value, _ := strings.Cut(line, " ")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(attribute) {
+			case "@title":
+				info.Title = value
+			case "@version":
+				info.Version = value
+			case "@description":
+				if scheme != "" {
+					s := schemes[scheme]
+					s.Description = value
+					schemes[scheme] = s
+				} else {
+					info.Description = value
+				}
+			case "@contact.name":
+				if info.Contact == nil {
+					info.Contact = &Contact{}
+				}
+				info.Contact.Name = value
+			case "@contact.email":
+				if info.Contact == nil {
+					info.Contact = &Contact{}
+				}
+				info.Contact.Email = value
+			case "@securitydefinitions.apikey":
+				scheme = value
+				schemes[scheme] = SecurityScheme{Type: "apiKey"}
+			case "@in":
+				if scheme != "" {
+					s := schemes[scheme]
+					s.In = value
+					schemes[scheme] = s
+				}
+			case "@name":
+				if scheme != "" {
+					s := schemes[scheme]
+					s.Name = value
+					schemes[scheme] = s
+				}
+			}
+		}
+	}
+	if info.Title == "" {
+		return nil, fmt.Errorf("no @title in %s", file)
+	}
+	return schemes, nil
+}
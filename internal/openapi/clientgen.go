@@ -0,0 +1,547 @@
+package openapi
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reservedTypeNames are declared by every generated client
+var reservedTypeNames = map[string]bool{"Client": true, "Error": true, "Option": true}
+
+// GenerateClient renders a Go client package for doc: a type per component
+// schema and a Client method per operation. Schemas are named after their Go
+// type, prefixed with the package where two packages declare the same name.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := &clientGen{doc: doc, imports: map[string]bool{}}
+	g.nameTypes()
+
+	var body strings.Builder
+	g.writeTypes(&body)
+	if err := g.writeOperations(&body); err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	out.WriteString("// Code generated by clientgen from the OpenAPI description. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "// Package %s is a typed client for the %s, version %s.\n", pkg, doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	for _, imp := range []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "reflect", "strings"} {
+		g.imports[imp] = true
+	}
+	out.WriteString("import (\n")
+	for _, imp := range sortedKeys(g.imports) {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	g.writeClient(&out)
+	out.WriteString(body.String())
+
+	src, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated client does not compile: %w", err)
+	}
+	return src, nil
+}
+
+type clientGen struct {
+	doc     *Document
+	imports map[string]bool
+	// names maps component schemas to Go type names
+	names map[string]string
+}
+
+func (g *clientGen) nameTypes() {
+	count := make(map[string]int)
+	for component := range g.doc.Components.Schemas {
+		count[bareName(component)]++
+	}
+	g.names = make(map[string]string)
+	for component := range g.doc.Components.Schemas {
+		name := bareName(component)
+		if count[name] > 1 || reservedTypeNames[name] {
+			name = identifier([]string{strings.ReplaceAll(component, ".", " ")}, true)
+		}
+		g.names[component] = name
+	}
+}
+
+// bareName strips the package from a component name such as database.User
+func bareName(component string) string {
+	if i := strings.LastIndex(component, "."); i >= 0 {
+		return component[i+1:]
+	}
+	return component
+}
+
+func (g *clientGen) writeTypes(b *strings.Builder) {
+	for _, component := range sortedKeys(g.doc.Components.Schemas) {
+		schema := g.doc.Components.Schemas[component]
+		name := g.names[component]
+		description := schema.Description
+		if description == "" {
+			description = fmt.Sprintf("%s is the %s schema", name, component)
+		} else if !strings.HasPrefix(description, bareName(component)+" ") {
+			description = name + ": " + description
+		} else {
+			description = name + description[len(bareName(component)):]
+		}
+		writeComment(b, "", description)
+		fmt.Fprintf(b, "type %s %s\n\n", name, g.goType(schema, true, ""))
+	}
+}
+
+// goType is the Go type of schema. Optional references and times are
+// pointers so omitting them round-trips.
+func (g *clientGen) goType(s *Schema, required bool, indent string) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		name := g.names[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+		if !required {
+			return "*" + name
+		}
+		return name
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			if !required {
+				return "*time.Time"
+			}
+			return "time.Time"
+		case "binary", "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		switch s.Format {
+		case "int64":
+			return "int64"
+		case "int32":
+			return "int32"
+		}
+		return "int"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, true, indent)
+	case "object":
+		if len(s.Properties) > 0 {
+			return g.structType(s, indent)
+		}
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, true, indent)
+		}
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+func (g *clientGen) structType(s *Schema, indent string) string {
+	required := make(map[string]bool, len(s.Required))
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	used := make(map[string]bool)
+	for _, property := range sortedKeys(s.Properties) {
+		schema := s.Properties[property]
+		field := identifier([]string{property}, true)
+		if field == "" || field[0] >= '0' && field[0] <= '9' {
+			field = "X" + field
+		}
+		for used[field] {
+			field += "_"
+		}
+		used[field] = true
+
+		if schema.Description != "" {
+			writeComment(&b, indent+"\t", schema.Description)
+		}
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s\t%s %s `json:%q`\n", indent, field, g.goType(schema, required[property], indent+"\t"), tag)
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// clientOperation is an operation with its method and path
+type clientOperation struct {
+	method, path string
+	op           *Operation
+}
+
+func (g *clientGen) writeOperations(b *strings.Builder) error {
+	var ops []clientOperation
+	for path, item := range g.doc.Paths {
+		for method, op := range item {
+			ops = append(ops, clientOperation{strings.ToUpper(method), path, op})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].op.OperationID < ops[j].op.OperationID })
+
+	for _, o := range ops {
+		if err := g.writeOperation(b, o); err != nil {
+			return fmt.Errorf("%s %s: %w", o.method, o.path, err)
+		}
+	}
+	return nil
+}
+
+func (g *clientGen) writeOperation(b *strings.Builder, o clientOperation) error {
+	name := exportedName(o.op.OperationID)
+
+	args := []string{"ctx context.Context"}
+	pathExpr := strconv.Quote(o.path)
+	var pathParams, otherParams []Parameter
+	for _, p := range o.op.Parameters {
+		if p.In == "path" {
+			pathParams = append(pathParams, p)
+		} else {
+			otherParams = append(otherParams, p)
+		}
+	}
+	for _, p := range pathParams {
+		arg, typ := argName(p.Name), g.goType(p.Schema, true, "")
+		args = append(args, arg+" "+typ)
+		value := arg
+		if typ != "string" {
+			value = "fmt.Sprint(" + arg + ")"
+		}
+		pathExpr = strings.Replace(pathExpr, "{"+p.Name+"}", `" + url.PathEscape(`+value+`) + "`, 1)
+	}
+	pathExpr = strings.TrimSuffix(strings.ReplaceAll(pathExpr, ` + ""`, ""), ` + ""`)
+
+	paramsType := name + "Params"
+	if len(otherParams) > 0 {
+		args = append(args, "params *"+paramsType)
+	}
+
+	var bodyType, bodyContent string
+	if rb := o.op.RequestBody; rb != nil {
+		bodyContent = firstContentType(rb.Content)
+		if bodyContent == "application/json" {
+			bodyType = g.goType(rb.Content[bodyContent].Schema, true, "")
+			if !rb.Required {
+				bodyType = "*" + strings.TrimPrefix(bodyType, "*")
+			}
+		} else {
+			bodyType = "io.Reader"
+		}
+		args = append(args, "body "+bodyType)
+	}
+
+	result, resultContent, documented := g.result(o.op)
+	returns := "error"
+	if result != "" {
+		returns = "(" + result + ", error)"
+	}
+
+	if len(otherParams) > 0 {
+		writeComment(b, "", fmt.Sprintf("%s are the query and header parameters of %s. Zero values are not sent.", paramsType, name))
+		fmt.Fprintf(b, "type %s struct {\n", paramsType)
+		for _, p := range otherParams {
+			if p.Description != "" {
+				writeComment(b, "\t", p.Description)
+			}
+			fmt.Fprintf(b, "\t%s %s\n", identifier([]string{p.Name}, true), g.goType(p.Schema, true, "\t"))
+		}
+		b.WriteString("}\n\n")
+	}
+
+	doc := fmt.Sprintf("%s calls %s %s.", name, o.method, o.path)
+	writeComment(b, "", doc)
+	if text := strings.TrimSpace(strings.TrimSuffix(o.op.Summary, ".") + ". " + o.op.Description); o.op.Summary != "" {
+		b.WriteString("//\n")
+		writeComment(b, "", text)
+	} else if !documented {
+		b.WriteString("//\n// The operation is undocumented; its response is returned as raw JSON.\n")
+	}
+	fmt.Fprintf(b, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+	fmt.Fprintf(b, "\treq := &request{method: %q, path: %s}\n", o.method, pathExpr)
+	if len(otherParams) > 0 {
+		b.WriteString("\tif params != nil {\n")
+		for _, p := range otherParams {
+			fmt.Fprintf(b, "\t\treq.param(%q, %q, params.%s)\n", p.In, p.Name, identifier([]string{p.Name}, true))
+		}
+		b.WriteString("\t}\n")
+	}
+
+	zero := "err"
+	if result != "" {
+		zero = zeroValue(result) + ", err"
+	}
+	switch {
+	case bodyType == "io.Reader":
+		fmt.Fprintf(b, "\treq.body, req.contentType = body, %q\n", bodyContent)
+	case bodyType != "":
+		fmt.Fprintf(b, "\tif err := req.jsonBody(body); err != nil {\n\t\treturn %s\n\t}\n", zero)
+	}
+
+	switch {
+	case result == "":
+		b.WriteString("\treturn c.do(ctx, req, nil)\n")
+	case resultContent != "application/json" && result == "[]byte":
+		b.WriteString("\tvar out []byte\n\terr := c.do(ctx, req, &out)\n\treturn out, err\n")
+	case strings.HasPrefix(result, "*"):
+		fmt.Fprintf(b, "\tout := new(%s)\n\tif err := c.do(ctx, req, out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n", result[1:])
+	default:
+		fmt.Fprintf(b, "\tvar out %s\n\terr := c.do(ctx, req, &out)\n\treturn out, err\n", result)
+	}
+	b.WriteString("}\n\n")
+	return nil
+}
+
+// result is the Go type an operation returns: its first 2xx response with a
+// body, nothing for bodyless success, raw JSON when no success is documented
+func (g *clientGen) result(op *Operation) (string, string, bool) {
+	var successes []string
+	for code := range op.Responses {
+		if len(code) == 3 && code[0] == '2' {
+			successes = append(successes, code)
+		}
+	}
+	if len(successes) == 0 {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", "application/json", false
+	}
+	sort.Strings(successes)
+	for _, code := range successes {
+		content := op.Responses[code].Content
+		if len(content) == 0 {
+			continue
+		}
+		mime := firstContentType(content)
+		if mime != "application/json" {
+			return "[]byte", mime, true
+		}
+		typ := g.goType(content[mime].Schema, true, "")
+		if content[mime].Schema != nil && content[mime].Schema.Ref != "" {
+			typ = "*" + typ
+		}
+		return typ, mime, true
+	}
+	return "", "", true
+}
+
+func firstContentType(content map[string]MediaType) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+	types := sortedKeys(content)
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+func zeroValue(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "*"), strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["), typ == "json.RawMessage", typ == "interface{}":
+		return "nil"
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "float"):
+		return "0"
+	}
+	return typ + "{}"
+}
+
+// exportedName upper-cases an operation ID's first letter, or its leading
+// initialism as in scimServiceProviderConfiguration
+func exportedName(id string) string {
+	for initialism, ok := range initialisms {
+		lower := strings.ToLower(initialism)
+		rest, found := strings.CutPrefix(id, lower)
+		if ok && found && (rest == "" || rest[0] >= 'A' && rest[0] <= 'Z') {
+			return initialism + rest
+		}
+	}
+	return strings.ToUpper(id[:1]) + id[1:]
+}
+
+// shadowedNames are used by generated methods and may not name arguments
+var shadowedNames = map[string]bool{
+	"c": true, "ctx": true, "body": true, "params": true, "req": true, "out": true, "err": true,
+	"new": true, "fmt": true, "url": true, "string": true,
+}
+
+// argName is a parameter's Go argument name
+func argName(name string) string {
+	arg := identifier([]string{name}, false)
+	if token.IsKeyword(arg) || shadowedNames[arg] {
+		arg += "Param"
+	}
+	return arg
+}
+
+// writeComment writes text as a Go comment wrapped at 80 columns
+func writeComment(b *strings.Builder, indent, text string) {
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > 80 && line != indent+"//" {
+			b.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+}
+
+func (g *clientGen) writeClient(b *strings.Builder) {
+	b.WriteString(`// Client calls the API. The zero HTTPClient is http.DefaultClient.
+type Client struct {
+	// BaseURL is the service's root, e.g. https://reputation.example.com
+	BaseURL    string
+	HTTPClient *http.Client
+`)
+	var schemes []string
+	for _, name := range sortedKeys(g.doc.Components.SecuritySchemes) {
+		scheme := g.doc.Components.SecuritySchemes[name]
+		if scheme.Type != "apiKey" || scheme.In != "header" {
+			continue
+		}
+		field := identifier([]string{name}, true)
+		comment := fmt.Sprintf("%s is sent in the %s header when set", field, scheme.Name)
+		if scheme.Description != "" {
+			comment += ". " + scheme.Description
+		}
+		writeComment(b, "\t", comment)
+		fmt.Fprintf(b, "\t%s string\n", field)
+		schemes = append(schemes, fmt.Sprintf("\tif c.%s != \"\" {\n\t\thttpReq.Header.Set(%q, c.%s)\n\t}\n", field, scheme.Name, field))
+	}
+	b.WriteString(`}
+
+// New creates a client for the service at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Error is a response outside 2xx. Code and Message are the service's error
+// code and message when it sent them.
+type Error struct {
+	StatusCode int    ` + "`json:\"-\"`" + `
+	Code       string ` + "`json:\"error\"`" + `
+	Message    string ` + "`json:\"message\"`" + `
+	Body       []byte ` + "`json:\"-\"`" + `
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        io.Reader
+	contentType string
+}
+
+// param sets a query or header parameter unless value is its zero value
+func (r *request) param(in, name string, value interface{}) {
+	if v := reflect.ValueOf(value); !v.IsValid() || v.IsZero() {
+		return
+	}
+	switch in {
+	case "query":
+		if r.query == nil {
+			r.query = url.Values{}
+		}
+		r.query.Set(name, fmt.Sprint(value))
+	case "header":
+		if r.header == nil {
+			r.header = http.Header{}
+		}
+		r.header.Set(name, fmt.Sprint(value))
+	}
+}
+
+func (r *request) jsonBody(body interface{}) error {
+	if v := reflect.ValueOf(body); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	r.body, r.contentType = bytes.NewReader(data), "application/json"
+	return nil
+}
+
+// do sends req and decodes a JSON response into out; a *[]byte out
+// receives the raw body and a nil out discards it
+func (c *Client) do(ctx context.Context, req *request, out interface{}) error {
+	target := strings.TrimSuffix(c.BaseURL, "/") + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, req.body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+`)
+	for _, s := range schemes {
+		b.WriteString(s)
+	}
+	b.WriteString(`
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		apiErr.Body, _ = io.ReadAll(resp.Body)
+		json.Unmarshal(apiErr.Body, apiErr)
+		return apiErr
+	}
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out, err = io.ReadAll(resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+`)
+}
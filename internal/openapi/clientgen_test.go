@@ -0,0 +1,49 @@
+package openapi
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerateClient(t *testing.T) {
+	doc, _, err := Generate(testRouter(), Options{Root: writeTestModule(t), Handlers: "handlers", General: "main.go"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// A second Widget forces the package prefix
+	doc.Components.Schemas["handlers.Widget"] = &Schema{Type: "object", Properties: map[string]*Schema{"id": {Type: "integer"}}}
+
+	src, err := GenerateClient(doc, "widgets")
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", src, 0); err != nil {
+		t.Fatalf("Generated client does not parse: %v", err)
+	}
+
+	// Compare ignoring gofmt's alignment
+	code := strings.Join(strings.Fields(string(src)), " ")
+	expected := []string{
+		"// Code generated by clientgen from the OpenAPI description. DO NOT EDIT.",
+		"package widgets",
+		"ApiKeyAuth string",
+		`httpReq.Header.Set("X-API-Key", c.ApiKeyAuth)`,
+		"type ModelsWidget struct {",
+		"type HandlersWidget struct {",
+		"type ErrorResponse struct {",
+		"CreatedAt time.Time `json:\"created_at\"`",
+		"Parent *ModelsWidget `json:\"parent,omitempty\"`",
+		"func (c *Client) ListWidgets(ctx context.Context, params *ListWidgetsParams) ([]ModelsWidget, error) {",
+		`req.param("query", "limit", params.Limit)`,
+		"func (c *Client) RenameAWidget(ctx context.Context, id int, body ModelsWidget) (*ModelsWidget, error) {",
+		`path: "/widgets/" + url.PathEscape(fmt.Sprint(id))}`,
+		"func (c *Client) PostWidgetsByIDArchive(ctx context.Context, id string) (json.RawMessage, error) {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("Expected the client to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+// Package openapi describes the API as an OpenAPI 3.1 document and generates
+// a typed Go client from it. Paths and methods come from the registered
+// routes, so the description cannot list endpoints that do not exist or miss
+// ones that do; summaries, parameters and response types come from the
+// handlers' swag comments and the Go types they name.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Document is an OpenAPI description
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version"`
+	Contact     *Contact `json:"contact,omitempty"`
+}
+
+// Contact is who to ask about the API
+type Contact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes what an operation accepts
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response describes one status code of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds the named schemas and the security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+}
+
+// Schema is the JSON Schema subset Go types map to. The empty schema
+// accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+// schemaRefPrefix prefixes references to component schemas
+const schemaRefPrefix = "#/components/schemas/"
+
+// Options locate the sources an API description is read from
+type Options struct {
+	// Root is the module root; the module path is read from its go.mod
+	Root string
+	// Handlers is the directory, relative to Root, of the handlers whose
+	// comments document the routes
+	Handlers string
+	// General is the file, relative to Root, with the API's title, version
+	// and security definitions
+	General string
+}
+
+// Generate describes the routes of router. The returned problems list routes
+// without documentation and documentation that does not match a route; the
+// document is complete either way, with undocumented operations described
+// only by their path.
+func Generate(router *mux.Router, opts Options) (*Document, []string, error) {
+	module, err := readModulePath(filepath.Join(opts.Root, "go.mod"))
+	if err != nil {
+		return nil, nil, err
+	}
+	schemas := newSchemaBuilder(opts.Root, module)
+
+	doc := &Document{OpenAPI: Version, Paths: map[string]PathItem{}}
+	security, err := readGeneralInfo(filepath.Join(opts.Root, opts.General), &doc.Info)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.Components.SecuritySchemes = security
+
+	documented, err := readAnnotations(filepath.Join(opts.Root, opts.Handlers), schemas)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problems []string
+	routed := make(map[string]bool)
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Catch-all handlers such as /metrics and /swagger/ are not
+			// part of the JSON API
+			return nil
+		}
+		path := pathVariable.ReplaceAllString(template, "{$1}")
+		for _, method := range methods {
+			key := routeKey(method, path)
+			if routed[key] {
+				continue
+			}
+			routed[key] = true
+
+			op := documented[key]
+			if op == nil {
+				problems = append(problems, fmt.Sprintf("%s %s has no @Router comment", method, path))
+				op = undocumentedOperation(method, path)
+			} else if op.OperationID == "" {
+				op.OperationID = undocumentedOperation(method, path).OperationID
+			}
+			problems = append(problems, addPathParameters(op, method, path)...)
+
+			item := doc.Paths[path]
+			if item == nil {
+				item = PathItem{}
+				doc.Paths[path] = item
+			}
+			item[strings.ToLower(method)] = op
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk routes: %w", err)
+	}
+
+	for key := range documented {
+		if !routed[key] {
+			problems = append(problems, fmt.Sprintf("@Router %s does not match a route", key))
+		}
+	}
+	uniqueOperationIDs(doc)
+	doc.Components.Schemas = schemas.schemas
+	sort.Strings(problems)
+	return doc, problems, nil
+}
+
+// pathVariable matches mux path variables, which may carry a pattern
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// addPathParameters declares the path's variables the documentation left
+// out and reports documented path parameters the path does not have
+func addPathParameters(op *Operation, method, path string) []string {
+	var problems []string
+	variables := make(map[string]bool)
+	for _, m := range pathVariable.FindAllStringSubmatch(path, -1) {
+		variables[m[1]] = true
+	}
+
+	declared := make(map[string]bool)
+	kept := op.Parameters[:0]
+	for _, p := range op.Parameters {
+		if p.In == "path" {
+			if !variables[p.Name] {
+				problems = append(problems, fmt.Sprintf("%s %s documents path parameter %q it does not have", method, path, p.Name))
+				continue
+			}
+			declared[p.Name] = true
+		}
+		kept = append(kept, p)
+	}
+	op.Parameters = kept
+
+	for _, m := range pathVariable.FindAllStringSubmatch(path, -1) {
+		if !declared[m[1]] {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return problems
+}
+
+// undocumentedOperation describes a route with no comments by its path
+func undocumentedOperation(method, path string) *Operation {
+	words := []string{strings.ToLower(method)}
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			words = append(words, "by", strings.TrimSuffix(name, "}"))
+		} else if segment != "" {
+			words = append(words, segment)
+		}
+	}
+	return &Operation{
+		OperationID: identifier(words, false),
+		Responses:   map[string]*Response{"default": {Description: "Undocumented response"}},
+	}
+}
+
+// uniqueOperationIDs numbers repeated operation IDs in path order
+func uniqueOperationIDs(doc *Document) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]int)
+	for _, path := range paths {
+		for _, method := range sortedKeys(doc.Paths[path]) {
+			op := doc.Paths[path][method]
+			seen[op.OperationID]++
+			if n := seen[op.OperationID]; n > 1 {
+				op.OperationID = fmt.Sprintf("%s%d", op.OperationID, n)
+			}
+		}
+	}
+}
+
+// identifier joins words camel-cased, upper-casing the first when exported.
+// Common initialisms such as ID and IP are kept upper-case.
+func identifier(words []string, exported bool) string {
+	var b strings.Builder
+	for _, word := range words {
+		// An IP's → IPs
+		word = strings.ReplaceAll(word, "'", "")
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			if initialisms[strings.ToUpper(part)] {
+				part = strings.ToUpper(part)
+			} else {
+				part = strings.ToUpper(part[:1]) + part[1:]
+			}
+			if b.Len() == 0 && !exported {
+				part = lowerFirst(part)
+			}
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// lowerFirst lower-cases the first letter, or a leading initialism
+func lowerFirst(s string) string {
+	if initialisms[s] {
+		return strings.ToLower(s)
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+var initialisms = map[string]bool{
+	"API": true, "ARF": true, "DNS": true, "DNSBL": true, "HTTP": true, "ID": true,
+	"IP": true, "ITSM": true, "JSON": true, "PTR": true, "SCIM": true,
+	"SMTP": true, "SLO": true, "TLS": true, "TTL": true, "URL": true,
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in %s", path)
+}
+
+// statusText is the default description of a response
+func statusText(code string) string {
+	var n int
+	fmt.Sscanf(code, "%d", &n)
+	if text := http.StatusText(n); text != "" {
+		return text
+	}
+	return "Response"
+}
+
+// MarshalIndent encodes the document as indented JSON, leaving characters
+// such as < in descriptions unescaped
+func (d *Document) MarshalIndent() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+var testSources = map[string]string{
+	"go.mod": "module example.com/svc\n\ngo 1.23\n",
+	"main.go": `package main
+
+// @title Test API
+// @version 2.0
+// @description Test service
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+func main() {}
+`,
+	"models/models.go": `package models
+
+import "time"
+
+// Base holds the common fields
+type Base struct {
+	ID        int       ` + "`json:\"id\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+// Widget is a thing
+type Widget struct {
+	Base
+	Name   string            ` + "`json:\"name\"`" + ` // Display name
+	Labels map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Parent *Widget           ` + "`json:\"parent,omitempty\"`" + `
+	Config []byte            ` + "`json:\"config\" swaggertype:\"object\"`" + `
+	secret string
+	Ignored string ` + "`json:\"-\"`" + `
+}
+`,
+	"handlers/handlers.go": `package handlers
+
+import (
+	"net/http"
+
+	"example.com/svc/models"
+)
+
+// ErrorResponse is an error
+type ErrorResponse struct {
+	Error string ` + "`json:\"error\"`" + `
+}
+
+// @Summary List widgets
+// @Tags widgets
+// @Produce json
+// @Param limit query int false "Maximum widgets" default(50)
+// @Success 200 {array} models.Widget
+// @Failure 500 {object} ErrorResponse
+// @Security ApiKeyAuth
+// @Router /widgets [get]
+func listWidgets(w http.ResponseWriter, r *http.Request) {}
+
+// @Summary Rename a widget
+// @Accept json
+// @Produce json
+// @Param id path int true "Widget ID"
+// @Param widget body models.Widget true "Widget"
+// @Success 200 {object} models.Widget
+// @Router /widgets/{id} [put]
+func renameWidget(w http.ResponseWriter, r *http.Request) {}
+
+// @Summary Removed long ago
+// @Router /gadgets [get]
+func listGadgets(w http.ResponseWriter, r *http.Request) {}
+`,
+}
+
+func writeTestModule(t *testing.T) string {
+	root := t.TempDir()
+	for name, src := range testSources {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func testRouter() *mux.Router {
+	noop := func(http.ResponseWriter, *http.Request) {}
+	router := mux.NewRouter()
+	router.HandleFunc("/widgets", noop).Methods("GET")
+	router.HandleFunc("/widgets/{id:[0-9]+}", noop).Methods("PUT")
+	router.HandleFunc("/widgets/{id}/archive", noop).Methods("POST")
+	router.Handle("/metrics", http.HandlerFunc(noop))
+	return router
+}
+
+func TestGenerate(t *testing.T) {
+	doc, problems, err := Generate(testRouter(), Options{Root: writeTestModule(t), Handlers: "handlers", General: "main.go"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expectedProblems := []string{
+		"@Router GET /gadgets does not match a route",
+		"POST /widgets/{id}/archive has no @Router comment",
+	}
+	if !reflect.DeepEqual(problems, expectedProblems) {
+		t.Errorf("Expected problems %v, got %v", expectedProblems, problems)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Test API" || doc.Info.Version != "2.0" {
+		t.Errorf("Unexpected header: %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if scheme := doc.Components.SecuritySchemes["ApiKeyAuth"]; scheme.In != "header" || scheme.Name != "X-API-Key" {
+		t.Errorf("Unexpected security scheme: %+v", scheme)
+	}
+	if len(doc.Paths) != 3 || doc.Paths["/metrics"] != nil {
+		t.Errorf("Expected the three JSON API paths, got %v", sortedKeys(doc.Paths))
+	}
+
+	list := doc.Paths["/widgets"]["get"]
+	if list.OperationID != "listWidgets" || len(list.Security) != 1 {
+		t.Errorf("Unexpected list operation: %+v", list)
+	}
+	if p := list.Parameters[0]; p.Name != "limit" || p.In != "query" || p.Schema.Type != "integer" || p.Schema.Default != int64(50) {
+		t.Errorf("Unexpected limit parameter: %+v %+v", p, p.Schema)
+	}
+	items := list.Responses["200"].Content["application/json"].Schema
+	if items.Type != "array" || items.Items.Ref != "#/components/schemas/models.Widget" {
+		t.Errorf("Expected an array of widgets, got %+v", items)
+	}
+
+	rename := doc.Paths["/widgets/{id}"]["put"]
+	if rename == nil || len(rename.Parameters) != 1 || rename.Parameters[0].Schema.Type != "integer" || !rename.Parameters[0].Required {
+		t.Fatalf("Expected the patterned route with an integer id, got %+v", rename)
+	}
+	if rename.RequestBody == nil || rename.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/models.Widget" {
+		t.Errorf("Expected a widget body, got %+v", rename.RequestBody)
+	}
+
+	undocumented := doc.Paths["/widgets/{id}/archive"]["post"]
+	if undocumented.OperationID != "postWidgetsByIDArchive" || undocumented.Responses["default"] == nil {
+		t.Errorf("Unexpected undocumented operation: %+v", undocumented)
+	}
+	if len(undocumented.Parameters) != 1 || undocumented.Parameters[0].Name != "id" {
+		t.Errorf("Expected the path parameter declared, got %+v", undocumented.Parameters)
+	}
+
+	widget := doc.Components.Schemas["models.Widget"]
+	if widget == nil {
+		t.Fatal("Expected the models.Widget schema")
+	}
+	if widget.Description != "Widget is a thing" {
+		t.Errorf("Unexpected description %q", widget.Description)
+	}
+	if got := strings.Join(sortedKeys(widget.Properties), ","); got != "config,created_at,id,labels,name,parent" {
+		t.Errorf("Expected embedded fields flattened and hidden ones skipped, got %s", got)
+	}
+	if got := strings.Join(widget.Required, ","); got != "config,created_at,id,name" {
+		t.Errorf("Expected fields without omitempty required, got %s", got)
+	}
+	if widget.Properties["created_at"].Format != "date-time" || widget.Properties["config"].Type != "object" {
+		t.Errorf("Unexpected property schemas: %+v %+v", widget.Properties["created_at"], widget.Properties["config"])
+	}
+	if widget.Properties["name"].Description != "Display name" {
+		t.Errorf("Expected the field comment as description, got %q", widget.Properties["name"].Description)
+	}
+	if widget.Properties["parent"].Ref != "#/components/schemas/models.Widget" {
+		t.Errorf("Expected a recursive reference, got %+v", widget.Properties["parent"])
+	}
+	if _, ok := doc.Components.Schemas["handlers.ErrorResponse"]; !ok {
+		t.Error("Expected the handlers.ErrorResponse schema")
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"List an IP's delisting tasks":        "listAnIPsDelistingTasks",
+		"SCIM service provider configuration": "scimServiceProviderConfiguration",
+		"Check DNSBL status for IP":           "checkDNSBLStatusForIP",
+	}
+	for summary, expected := range cases {
+		if got := identifier([]string{summary}, false); got != expected {
+			t.Errorf("identifier(%q) = %q, want %q", summary, got, expected)
+		}
+	}
+	if got := exportedName("scimServiceProviderConfiguration"); got != "SCIMServiceProviderConfiguration" {
+		t.Errorf("exportedName = %q", got)
+	}
+}
@@ -0,0 +1,436 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sourcePackage is a parsed package of the module
+type sourcePackage struct {
+	name  string
+	files []*ast.File
+	types map[string]typeDecl
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *ast.File
+}
+
+// schemaBuilder turns the Go types named by annotations into schemas. Struct
+// types become component schemas named like database.User; other named
+// types are inlined.
+type schemaBuilder struct {
+	fset     *token.FileSet
+	root     string
+	module   string
+	packages map[string]*sourcePackage
+	schemas  map[string]*Schema
+}
+
+func newSchemaBuilder(root, module string) *schemaBuilder {
+	return &schemaBuilder{
+		fset:     token.NewFileSet(),
+		root:     root,
+		module:   module,
+		packages: make(map[string]*sourcePackage),
+		schemas:  make(map[string]*Schema),
+	}
+}
+
+// load parses the non-test Go files of dir once
+func (b *schemaBuilder) load(dir string) (*sourcePackage, error) {
+	dir = filepath.Clean(dir)
+	if pkg, ok := b.packages[dir]; ok {
+		return pkg, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", dir, err)
+	}
+	pkg := &sourcePackage{types: make(map[string]typeDecl)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(b.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		if strings.HasSuffix(file.Name.Name, "_test") {
+			continue
+		}
+		pkg.name = file.Name.Name
+		pkg.files = append(pkg.files, file)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				pkg.types[ts.Name.Name] = typeDecl{spec: ts, file: file}
+			}
+		}
+	}
+	b.packages[dir] = pkg
+	return pkg, nil
+}
+
+// annotatedType is the schema of an annotation's {kind} type, such as
+// {array} database.User or {object} map[string]interface{}
+func (b *schemaBuilder) annotatedType(kind, typ string, pkg *sourcePackage, file *ast.File) (*Schema, error) {
+	if kind == "file" {
+		return &Schema{Type: "string", Format: "binary"}, nil
+	}
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil, fmt.Errorf("malformed type %q: %w", typ, err)
+	}
+	schema, err := b.expr(expr, pkg, file)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "array" {
+		schema = &Schema{Type: "array", Items: schema}
+	}
+	return schema, nil
+}
+
+// basicTypes maps Go's predeclared types
+var basicTypes = map[string]Schema{
+	"string":  {Type: "string"},
+	"bool":    {Type: "boolean"},
+	"int":     {Type: "integer"},
+	"int8":    {Type: "integer"},
+	"int16":   {Type: "integer"},
+	"int32":   {Type: "integer", Format: "int32"},
+	"int64":   {Type: "integer", Format: "int64"},
+	"uint":    {Type: "integer"},
+	"uint8":   {Type: "integer"},
+	"uint16":  {Type: "integer"},
+	"uint32":  {Type: "integer", Format: "int32"},
+	"uint64":  {Type: "integer", Format: "int64"},
+	"float32": {Type: "number", Format: "float"},
+	"float64": {Type: "number", Format: "double"},
+	"byte":    {Type: "integer"},
+	"rune":    {Type: "integer", Format: "int32"},
+	"integer": {Type: "integer"},
+	"number":  {Type: "number"},
+	"boolean": {Type: "boolean"},
+	"object":  {Type: "object"},
+	"error":   {Type: "string"},
+	"any":     {},
+}
+
+// externalTypes maps the standard library types used in API types
+var externalTypes = map[string]Schema{
+	"time.Time":       {Type: "string", Format: "date-time"},
+	"time.Duration":   {Type: "integer", Format: "int64", Description: "Nanoseconds"},
+	"json.RawMessage": {},
+	"net.IP":          {Type: "string"},
+	"url.URL":         {Type: "string", Format: "uri"},
+	"netip.Addr":      {Type: "string"},
+	"netip.Prefix":    {Type: "string"},
+}
+
+func (b *schemaBuilder) expr(expr ast.Expr, pkg *sourcePackage, file *ast.File) (*Schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if basic, ok := basicTypes[t.Name]; ok {
+			return &basic, nil
+		}
+		return b.named(pkg, t.Name)
+	case *ast.StarExpr:
+		return b.expr(t.X, pkg, file)
+	case *ast.ParenExpr:
+		return b.expr(t.X, pkg, file)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := b.expr(t.Elt, pkg, file)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		values, err := b.expr(t.Value, pkg, file)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case *ast.InterfaceType:
+		return &Schema{}, nil
+	case *ast.StructType:
+		return b.structSchema(t, pkg, file)
+	case *ast.SelectorExpr:
+		qualifier, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", typeString(expr))
+		}
+		name := qualifier.Name + "." + t.Sel.Name
+		if external, ok := externalTypes[name]; ok {
+			return &external, nil
+		}
+		other, err := b.imported(qualifier.Name, file)
+		if err != nil {
+			return nil, err
+		}
+		if other == nil {
+			// Types of other modules are described as any value
+			return &Schema{}, nil
+		}
+		return b.named(other, t.Sel.Name)
+	case *ast.FuncType, *ast.ChanType:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", typeString(expr))
+}
+
+// typeString names a type expression for error messages
+func typeString(expr ast.Expr) string {
+	return fmt.Sprintf("%T", expr)
+}
+
+// imported returns the module package file imports as qualifier, or nil if
+// it is not part of the module
+func (b *schemaBuilder) imported(qualifier string, file *ast.File) (*sourcePackage, error) {
+	if file == nil {
+		return nil, fmt.Errorf("unknown package %s", qualifier)
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		rel, inModule := strings.CutPrefix(path, b.module+"/")
+
+		var pkg *sourcePackage
+		name := filepath.Base(path)
+		if inModule {
+			loaded, err := b.load(filepath.Join(b.root, rel))
+			if err != nil {
+				return nil, err
+			}
+			pkg, name = loaded, loaded.name
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == qualifier {
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown package %s", qualifier)
+}
+
+// named returns a reference to a struct type's component schema, or the
+// inlined schema of any other named type
+func (b *schemaBuilder) named(pkg *sourcePackage, name string) (*Schema, error) {
+	decl, ok := pkg.types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s.%s", pkg.name, name)
+	}
+	st, isStruct := decl.spec.Type.(*ast.StructType)
+	if !isStruct {
+		return b.expr(decl.spec.Type, pkg, decl.file)
+	}
+
+	component := pkg.name + "." + name
+	ref := &Schema{Ref: schemaRefPrefix + component}
+	if _, ok := b.schemas[component]; ok {
+		return ref, nil
+	}
+	// Reserve the name first so recursive types refer to themselves
+	b.schemas[component] = &Schema{}
+	schema, err := b.structSchema(st, pkg, decl.file)
+	if err != nil {
+		delete(b.schemas, component)
+		return nil, fmt.Errorf("%s: %w", component, err)
+	}
+	schema.Description = docText(decl.spec.Doc)
+	if schema.Description == "" {
+		schema.Description = declDoc(decl.file, decl.spec)
+	}
+	b.schemas[component] = schema
+	return ref, nil
+}
+
+// structSchema describes the JSON encoding of a struct. Fields without
+// omitempty are required, as they are always encoded; embedded structs
+// without a JSON name are flattened, as encoding/json does.
+func (b *schemaBuilder) structSchema(st *ast.StructType, pkg *sourcePackage, file *ast.File) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		if tag.Get("swaggerignore") == "true" {
+			continue
+		}
+		jsonName, jsonOptions, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" && jsonOptions == "" {
+			continue
+		}
+		omitempty := strings.Contains(","+jsonOptions+",", ",omitempty,")
+
+		if len(field.Names) == 0 && jsonName == "" {
+			embedded, err := b.embedded(field.Type, pkg, file)
+			if err != nil {
+				return nil, err
+			}
+			if embedded != nil {
+				for name, property := range embedded.Properties {
+					if _, ok := schema.Properties[name]; !ok {
+						schema.Properties[name] = property
+					}
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+			}
+			continue
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{typeName(field.Type)}
+		}
+		for _, ident := range names {
+			if ident == nil || !ident.IsExported() {
+				continue
+			}
+			name := jsonName
+			if name == "" {
+				name = ident.Name
+			}
+
+			var property *Schema
+			switch swaggerType := tag.Get("swaggertype"); {
+			case swaggerType != "":
+				property = primitiveSchema(swaggerType)
+				if property == nil {
+					property = &Schema{Type: swaggerType}
+				}
+			case strings.Contains(","+jsonOptions+",", ",string,"):
+				property = &Schema{Type: "string"}
+			default:
+				var err error
+				if property, err = b.expr(field.Type, pkg, file); err != nil {
+					return nil, fmt.Errorf("field %s: %w", ident.Name, err)
+				}
+				if property == nil {
+					continue
+				}
+			}
+			if description := fieldDoc(field); description != "" {
+				property = withDescription(property, description)
+			}
+			schema.Properties[name] = property
+			if !omitempty {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+	sort.Strings(schema.Required)
+	schema.Required = dedupe(schema.Required)
+	return schema, nil
+}
+
+// embedded resolves the struct an embedded field flattens into its parent
+func (b *schemaBuilder) embedded(expr ast.Expr, pkg *sourcePackage, file *ast.File) (*Schema, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	var decl typeDecl
+	var owner *sourcePackage
+	switch t := expr.(type) {
+	case *ast.Ident:
+		decl, owner = pkg.types[t.Name], pkg
+	case *ast.SelectorExpr:
+		qualifier, _ := t.X.(*ast.Ident)
+		if qualifier == nil {
+			return nil, nil
+		}
+		other, err := b.imported(qualifier.Name, file)
+		if err != nil || other == nil {
+			return nil, err
+		}
+		decl, owner = other.types[t.Sel.Name], other
+	}
+	if decl.spec == nil {
+		return nil, nil
+	}
+	st, ok := decl.spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, nil
+	}
+	return b.structSchema(st, owner, decl.file)
+}
+
+// withDescription describes a property; references cannot have siblings in
+// older tools, so they are wrapped
+func withDescription(s *Schema, description string) *Schema {
+	if s.Ref != "" {
+		return &Schema{Ref: s.Ref, Description: description}
+	}
+	copied := *s
+	copied.Description = description
+	return &copied
+}
+
+func typeName(expr ast.Expr) *ast.Ident {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel
+	}
+	return nil
+}
+
+func fieldDoc(field *ast.Field) string {
+	if text := docText(field.Doc); text != "" {
+		return text
+	}
+	return docText(field.Comment)
+}
+
+// declDoc returns the comment of the declaration holding spec, for types
+// declared one per type keyword
+func declDoc(file *ast.File, spec *ast.TypeSpec) string {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if ok && len(gen.Specs) == 1 && gen.Specs[0] == spec {
+			return docText(gen.Doc)
+		}
+	}
+	return ""
+}
+
+func docText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
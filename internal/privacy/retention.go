@@ -0,0 +1,137 @@
+package privacy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetentionService periodically anonymizes recipient data older than the
+// configured age
+type RetentionService struct {
+	anonymizeAfter time.Duration
+	anonymize      func(olderThan time.Time) (int64, error)
+	logger         *logrus.Logger
+	ticker         *time.Ticker
+	stopChan       chan bool
+	running        bool
+	mu             sync.Mutex
+	lastRun        time.Time
+	rowsAnonymized int64
+	errors         int
+}
+
+// NewRetentionService creates a retention service that anonymizes failures
+// older than anonymizeAfter
+func NewRetentionService(anonymizeAfter time.Duration, logger *logrus.Logger) *RetentionService {
+	return &RetentionService{
+		anonymizeAfter: anonymizeAfter,
+		anonymize:      database.AnonymizeSMTPFailuresBefore,
+		logger:         logger,
+		stopChan:       make(chan bool),
+	}
+}
+
+// Start begins the periodic anonymization pass
+func (s *RetentionService) Start(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("retention service is already running")
+	}
+	if s.anonymizeAfter <= 0 {
+		return fmt.Errorf("retention service requires a positive anonymization age")
+	}
+
+	s.ticker = time.NewTicker(interval)
+	s.running = true
+
+	s.logger.WithFields(logrus.Fields{
+		"action":          "retention_service_start",
+		"interval":        interval.String(),
+		"anonymize_after": s.anonymizeAfter.String(),
+	}).Info("Starting PII retention service")
+
+	go s.RunOnce()
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.RunOnce()
+			case <-s.stopChan:
+				s.logger.Info("Retention service stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the retention service
+func (s *RetentionService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+
+	s.stopChan <- true
+	s.running = false
+}
+
+// RunOnce anonymizes everything past the retention age
+func (s *RetentionService) RunOnce() {
+	cutoff := time.Now().Add(-s.anonymizeAfter)
+
+	rows, err := s.anonymize(cutoff)
+
+	s.mu.Lock()
+	s.lastRun = time.Now()
+	if err != nil {
+		s.errors++
+	} else {
+		s.rowsAnonymized += rows
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "retention_anonymize_failed",
+			"cutoff": cutoff,
+			"error":  err.Error(),
+		}).Error("Failed to anonymize old SMTP failures")
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":          "retention_anonymize_completed",
+		"cutoff":          cutoff,
+		"rows_anonymized": rows,
+	}).Info("Anonymized old SMTP failures")
+}
+
+// GetStats returns service statistics
+func (s *RetentionService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"running":         s.running,
+		"last_run":        s.lastRun,
+		"anonymize_after": s.anonymizeAfter.String(),
+		"rows_anonymized": s.rowsAnonymized,
+		"errors":          s.errors,
+	}
+}
@@ -0,0 +1,58 @@
+package privacy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRunOnceUsesRetentionCutoff(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	service := NewRetentionService(24*time.Hour, log)
+
+	var cutoff time.Time
+	service.anonymize = func(olderThan time.Time) (int64, error) {
+		cutoff = olderThan
+		return 7, nil
+	}
+
+	before := time.Now().Add(-24 * time.Hour)
+	service.RunOnce()
+	after := time.Now().Add(-24 * time.Hour)
+
+	if cutoff.Before(before) || cutoff.After(after) {
+		t.Errorf("Expected cutoff around %v, got %v", before, cutoff)
+	}
+
+	stats := service.GetStats()
+	if stats["rows_anonymized"] != int64(7) {
+		t.Errorf("Expected 7 rows anonymized, got %v", stats["rows_anonymized"])
+	}
+}
+
+func TestRunOnceRecordsErrors(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	service := NewRetentionService(time.Hour, log)
+	service.anonymize = func(time.Time) (int64, error) {
+		return 0, errors.New("database down")
+	}
+
+	service.RunOnce()
+
+	if service.GetStats()["errors"] != 1 {
+		t.Errorf("Expected 1 error, got %v", service.GetStats()["errors"])
+	}
+	if hook.LastEntry() == nil || hook.LastEntry().Level != logrus.ErrorLevel {
+		t.Error("Expected failure to be logged at error level")
+	}
+}
+
+func TestStartRequiresPositiveAge(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	if err := NewRetentionService(0, log).Start(time.Hour); err == nil {
+		t.Error("Expected error for zero anonymization age")
+	}
+}
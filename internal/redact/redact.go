@@ -0,0 +1,172 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"golang-backend-service/internal/database"
+)
+
+// Mode selects how recipient email addresses are redacted
+type Mode string
+
+const (
+	// ModeNone leaves addresses untouched
+	ModeNone Mode = "none"
+	// ModeFull replaces the whole address
+	ModeFull Mode = "full"
+	// ModeDomain keeps only the domain: ***@example.com
+	ModeDomain Mode = "domain"
+	// ModeHash replaces the local part with a keyed hash so the same
+	// recipient maps to the same pseudonym: 3f9a1c2b7d4e5f60@example.com
+	ModeHash Mode = "hash"
+)
+
+// Redacted is the placeholder used by ModeFull
+const Redacted = "[redacted]"
+
+// sensitiveKeys are log field names that carry recipient addresses
+var sensitiveKeys = map[string]bool{
+	"recipient":       true,
+	"recipient_email": true,
+	"email":           true,
+}
+
+// ParseMode converts a config value into a Mode
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case ModeNone:
+		return ModeNone, nil
+	case ModeFull:
+		return ModeFull, nil
+	case ModeDomain:
+		return ModeDomain, nil
+	case ModeHash, "":
+		return ModeHash, nil
+	default:
+		return "", fmt.Errorf("unknown redaction mode: %s", s)
+	}
+}
+
+// Redactor applies one redaction mode to every place recipient data leaves
+// the service: logs, API responses and exports
+type Redactor struct {
+	mode Mode
+	key  []byte
+}
+
+// New creates a redactor. key seeds the pseudonyms in ModeHash; when empty a
+// random key is used, so pseudonyms are only stable for the process lifetime.
+func New(mode Mode, key string) (*Redactor, error) {
+	if _, err := ParseMode(string(mode)); err != nil {
+		return nil, err
+	}
+
+	r := &Redactor{mode: mode, key: []byte(key)}
+	if r.mode == "" {
+		r.mode = ModeHash
+	}
+	if r.mode == ModeHash && len(r.key) == 0 {
+		r.key = make([]byte, 32)
+		if _, err := rand.Read(r.key); err != nil {
+			return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Mode returns the configured redaction mode
+func (r *Redactor) Mode() Mode {
+	return r.mode
+}
+
+// Email redacts a single email address according to the mode
+func (r *Redactor) Email(addr string) string {
+	if r == nil || r.mode == ModeNone || addr == "" {
+		return addr
+	}
+	if r.mode == ModeFull {
+		return Redacted
+	}
+
+	local, domain, ok := cutLast(addr, "@")
+	if !ok {
+		// Not an address; nothing to keep
+		return Redacted
+	}
+
+	if r.mode == ModeDomain {
+		return "***@" + domain
+	}
+
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(strings.ToLower(local)))
+	return hex.EncodeToString(mac.Sum(nil))[:16] + "@" + domain
+}
+
+// SMTPFailures returns a copy of failures with recipient addresses redacted
+func (r *Redactor) SMTPFailures(failures []database.SMTPFailure) []database.SMTPFailure {
+	redacted := make([]database.SMTPFailure, len(failures))
+	for i, f := range failures {
+		redacted[i] = r.SMTPFailure(f)
+	}
+	return redacted
+}
+
+// SMTPFailure returns failure with its recipient address redacted, also
+// where the reason quotes it, as NDR text often does
+func (r *Redactor) SMTPFailure(failure database.SMTPFailure) database.SMTPFailure {
+	if r == nil || r.mode == ModeNone || failure.RecipientEmail == "" {
+		return failure
+	}
+	redacted := r.Email(failure.RecipientEmail)
+	quoted := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(failure.RecipientEmail))
+	failure.Reason = quoted.ReplaceAllLiteralString(failure.Reason, redacted)
+	failure.RecipientEmail = redacted
+	return failure
+}
+
+// ReasonFamilyCounts returns a copy of counts with the recipient address
+// redacted in their examples, as SMTPFailure does in a reason
+func (r *Redactor) ReasonFamilyCounts(counts []database.ReasonFamilyCount) []database.ReasonFamilyCount {
+	redacted := make([]database.ReasonFamilyCount, len(counts))
+	for i, c := range counts {
+		f := r.SMTPFailure(database.SMTPFailure{RecipientEmail: c.ExampleRecipient, Reason: c.Example})
+		c.Example, c.ExampleRecipient = f.Reason, f.RecipientEmail
+		redacted[i] = c
+	}
+	return redacted
+}
+
+// SMTPAuthFailures returns a copy of auth failures with usernames redacted
+// like addresses, which they usually are
+func (r *Redactor) SMTPAuthFailures(failures []database.SMTPAuthFailure) []database.SMTPAuthFailure {
+	redacted := make([]database.SMTPAuthFailure, len(failures))
+	for i, f := range failures {
+		f.Username = r.Email(f.Username)
+		redacted[i] = f
+	}
+	return redacted
+}
+
+// LogAttr redacts string attributes whose key is known to carry an address.
+// It matches logger.Options.Redact so it can sit in front of every handler.
+func (r *Redactor) LogAttr(a slog.Attr) slog.Attr {
+	if !sensitiveKeys[a.Key] || a.Value.Kind() != slog.KindString {
+		return a
+	}
+	return slog.String(a.Key, r.Email(a.Value.String()))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,133 @@
+package redact
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestEmailModes(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		input    string
+		expected string
+	}{
+		{ModeNone, "Alice@Example.com", "Alice@Example.com"},
+		{ModeFull, "alice@example.com", Redacted},
+		{ModeDomain, "alice@example.com", "***@example.com"},
+		{ModeDomain, "not-an-address", Redacted},
+		{ModeFull, "", ""},
+	}
+
+	for _, tt := range tests {
+		r, err := New(tt.mode, "")
+		if err != nil {
+			t.Fatalf("New(%s) failed: %v", tt.mode, err)
+		}
+		if got := r.Email(tt.input); got != tt.expected {
+			t.Errorf("%s: Email(%q) = %q, expected %q", tt.mode, tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestHashModeIsStableForKey(t *testing.T) {
+	a, _ := New(ModeHash, "secret")
+	b, _ := New(ModeHash, "secret")
+	other, _ := New(ModeHash, "other-secret")
+
+	first := a.Email("alice@example.com")
+	if !strings.HasSuffix(first, "@example.com") || strings.HasPrefix(first, "alice") {
+		t.Fatalf("Unexpected pseudonym %q", first)
+	}
+	if got := b.Email("ALICE@example.com"); got != first {
+		t.Errorf("Expected same pseudonym for same key and local part, got %q and %q", first, got)
+	}
+	if got := other.Email("alice@example.com"); got == first {
+		t.Error("Expected different pseudonym for a different key")
+	}
+	if got := a.Email("bob@example.com"); got == first {
+		t.Error("Expected different pseudonyms for different recipients")
+	}
+}
+
+func TestSMTPFailuresDoesNotModifyInput(t *testing.T) {
+	r, _ := New(ModeDomain, "")
+	failures := []database.SMTPFailure{{RecipientEmail: "alice@example.com", RecipientDomain: "example.com"}}
+
+	redacted := r.SMTPFailures(failures)
+	if redacted[0].RecipientEmail != "***@example.com" {
+		t.Errorf("Expected redacted address, got %q", redacted[0].RecipientEmail)
+	}
+	if failures[0].RecipientEmail != "alice@example.com" {
+		t.Error("Expected input to be left untouched")
+	}
+}
+
+func TestSMTPFailureRedactsReason(t *testing.T) {
+	r, _ := New(ModeFull, "")
+	failure := database.SMTPFailure{
+		RecipientEmail: "alice@example.com",
+		Reason:         "550 5.1.1 <Alice@Example.com>: Recipient address rejected; alice@example.com unknown",
+	}
+
+	redacted := r.SMTPFailure(failure)
+	if strings.Contains(strings.ToLower(redacted.Reason), "alice@") {
+		t.Errorf("Expected the address redacted in the reason, got %q", redacted.Reason)
+	}
+	if redacted.Reason != "550 5.1.1 <[redacted]>: Recipient address rejected; [redacted] unknown" {
+		t.Errorf("Unexpected reason %q", redacted.Reason)
+	}
+
+	none, _ := New(ModeNone, "")
+	if kept := none.SMTPFailure(failure); kept.Reason != failure.Reason {
+		t.Errorf("Expected the reason untouched in mode none, got %q", kept.Reason)
+	}
+}
+
+func TestReasonFamilyCountsRedactsExample(t *testing.T) {
+	r, _ := New(ModeDomain, "")
+	counts := []database.ReasonFamilyCount{{Family: "<email> unknown", Example: "bob@example.com unknown", ExampleRecipient: "bob@example.com"}}
+
+	redacted := r.ReasonFamilyCounts(counts)
+	if redacted[0].Example != "***@example.com unknown" || redacted[0].ExampleRecipient != "***@example.com" {
+		t.Errorf("Expected the example redacted, got %+v", redacted[0])
+	}
+	if counts[0].Example != "bob@example.com unknown" {
+		t.Error("Expected input to be left untouched")
+	}
+}
+
+func TestSMTPAuthFailuresRedactsUsername(t *testing.T) {
+	r, _ := New(ModeDomain, "")
+	failures := []database.SMTPAuthFailure{{Username: "relay@example.com", RemoteHost: "smtp.example.com"}}
+
+	redacted := r.SMTPAuthFailures(failures)
+	if redacted[0].Username != "***@example.com" || redacted[0].RemoteHost != "smtp.example.com" {
+		t.Errorf("Unexpected redaction: %+v", redacted[0])
+	}
+	if failures[0].Username != "relay@example.com" {
+		t.Error("Expected input to be left untouched")
+	}
+}
+
+func TestLogAttr(t *testing.T) {
+	r, _ := New(ModeFull, "")
+
+	if got := r.LogAttr(slog.String("recipient", "alice@example.com")); got.Value.String() != Redacted {
+		t.Errorf("Expected recipient to be redacted, got %q", got.Value.String())
+	}
+	if got := r.LogAttr(slog.String("ip", "192.0.2.1")); got.Value.String() != "192.0.2.1" {
+		t.Errorf("Expected other fields untouched, got %q", got.Value.String())
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(""); err != nil || mode != ModeHash {
+		t.Errorf("Expected empty mode to default to hash, got %q, %v", mode, err)
+	}
+	if _, err := ParseMode("scramble"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
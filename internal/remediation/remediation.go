@@ -0,0 +1,259 @@
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mode decides whether automated remediation acts
+type Mode string
+
+// Remediation modes
+const (
+	// Off disables automated actions; detections are still logged
+	Off Mode = "off"
+	// DryRun records what automated actions would have done as simulated IP
+	// actions without doing it
+	DryRun Mode = "dry-run"
+	// Enforce acts
+	Enforce Mode = "enforce"
+)
+
+// Metadata keys of simulated IP actions
+const (
+	SimulatedKey = "simulated"
+	ModeKey      = "remediation_mode"
+)
+
+// ParseMode reads a mode name
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case Off, DryRun, Enforce:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown remediation mode %q (use off, dry-run or enforce)", name)
+}
+
+// Settings is the stored remediation config
+type Settings struct {
+	Mode Mode `json:"mode"`
+}
+
+var (
+	mu         sync.RWMutex
+	current    = Enforce
+	generation int
+)
+
+// Current returns the mode automated actions honor
+func Current() Mode {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// SetMode changes the mode of every automated action
+func SetMode(mode Mode) {
+	mu.Lock()
+	defer mu.Unlock()
+	if mode != current {
+		current = mode
+		generation++
+	}
+}
+
+// Tag records the mode an automated action ran in on its IP action. Actions
+// of dry-run mode are tagged simulated; enforced actions are left as is.
+func Tag(action *database.IPAction, mode Mode) {
+	if mode == Enforce {
+		return
+	}
+	metadata := make(map[string]interface{}, len(action.Metadata)+2)
+	for k, v := range action.Metadata {
+		metadata[k] = v
+	}
+	if mode == DryRun {
+		metadata[SimulatedKey] = true
+	}
+	metadata[ModeKey] = string(mode)
+	action.Metadata = metadata
+}
+
+// IsSimulated reports whether an action was recorded in dry-run mode
+func IsSimulated(action database.IPAction) bool {
+	simulated, _ := action.Metadata[SimulatedKey].(bool)
+	return simulated
+}
+
+// Journal remembers the simulations of periodic evaluations, so an action
+// that would be taken on every run is recorded once until its outcome or
+// the mode changes. Its zero value is ready to use.
+type Journal struct {
+	mu      sync.Mutex
+	entries map[string]journalEntry
+}
+
+type journalEntry struct {
+	outcome    string
+	generation int
+}
+
+// Changed reports whether key has a different outcome than last recorded
+// in this mode, and remembers outcome
+func (j *Journal) Changed(key, outcome string) bool {
+	mu.RLock()
+	gen := generation
+	mu.RUnlock()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.entries == nil {
+		j.entries = make(map[string]journalEntry)
+	}
+	if e, ok := j.entries[key]; ok && e.outcome == outcome && e.generation == gen {
+		return false
+	}
+	j.entries[key] = journalEntry{outcome: outcome, generation: gen}
+	return true
+}
+
+// Forget drops the outcome of key
+func (j *Journal) Forget(key string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.entries, key)
+}
+
+// Store reads the stored remediation revisions
+type Store interface {
+	GetLatestConfigRevision(scope string) (*database.ConfigRevision, error)
+}
+
+type dbStore struct{}
+
+func (dbStore) GetLatestConfigRevision(scope string) (*database.ConfigRevision, error) {
+	return database.GetLatestConfigRevision(scope)
+}
+
+// store is where LoadMode and Refresh read the mode; tests substitute it
+var store Store = dbStore{}
+
+// LoadMode returns the mode of the newest stored revision, or fallback and
+// a nil revision if the mode was never changed at runtime
+func LoadMode(fallback Mode) (Mode, *database.ConfigRevision, error) {
+	rev, err := store.GetLatestConfigRevision(database.ConfigScopeRemediation)
+	if err != nil || rev == nil {
+		return fallback, nil, err
+	}
+	mode, err := DecodeSettings(rev)
+	if err != nil {
+		return fallback, nil, err
+	}
+	return mode, rev, nil
+}
+
+// Refresh applies the mode LoadMode returns, so a change made on another
+// instance reaches this one. When the stored mode cannot be read, enforce
+// falls back to dry-run until it can: a kill switch set elsewhere must not
+// be missed while acting.
+func Refresh(fallback Mode) (Mode, error) {
+	mode, _, err := LoadMode(fallback)
+	if err != nil {
+		mu.Lock()
+		if current == Enforce {
+			current = DryRun
+			generation++
+		}
+		mode = current
+		mu.Unlock()
+		return mode, err
+	}
+	SetMode(mode)
+	return mode, nil
+}
+
+var (
+	runMu    sync.Mutex
+	ticker   *time.Ticker
+	stopChan = make(chan bool)
+	running  bool
+)
+
+// Start applies the stored mode, or fallback, and reapplies it every
+// interval. Every instance must run it for a mode set through the API to
+// reach all of them.
+func Start(interval time.Duration, fallback Mode, logger *logrus.Logger) error {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	if running {
+		return fmt.Errorf("remediation mode refresh is already running")
+	}
+
+	refresh := func() {
+		previous := Current()
+		mode, err := Refresh(fallback)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"action": "remediation_mode_refresh_failed",
+				"mode":   string(mode),
+				"error":  err.Error(),
+			}).Warn("Failed to reload remediation mode, not enforcing until it loads")
+		}
+		if mode != previous {
+			logger.WithFields(logrus.Fields{
+				"action": "remediation_mode_applied",
+				"from":   string(previous),
+				"to":     string(mode),
+			}).Warn("Remediation mode applied")
+		}
+	}
+	SetMode(fallback)
+	refresh()
+
+	ticker = time.NewTicker(interval)
+	running = true
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				refresh()
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops reapplying the stored mode
+func Stop() {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	if !running {
+		return
+	}
+	ticker.Stop()
+	stopChan <- true
+	running = false
+}
+
+// DecodeSettings reads the mode of a remediation revision
+func DecodeSettings(rev *database.ConfigRevision) (Mode, error) {
+	var settings Settings
+	if err := json.Unmarshal(rev.Config, &settings); err != nil {
+		return "", fmt.Errorf("failed to decode remediation revision %d: %w", rev.ID, err)
+	}
+	mode, err := ParseMode(string(settings.Mode))
+	if err != nil {
+		return "", fmt.Errorf("remediation revision %d is invalid: %w", rev.ID, err)
+	}
+	return mode, nil
+}
@@ -0,0 +1,106 @@
+package remediation
+
+import (
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, name := range []string{"off", "dry-run", "enforce"} {
+		if mode, err := ParseMode(name); err != nil || string(mode) != name {
+			t.Errorf("ParseMode(%q) = %q, %v", name, mode, err)
+		}
+	}
+	for _, name := range []string{"", "dryrun", "Enforce"} {
+		if _, err := ParseMode(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	original := map[string]interface{}{"rule": "gmail_burst"}
+	action := &database.IPAction{Action: "burst_quarantine", Metadata: original}
+	Tag(action, DryRun)
+	if !IsSimulated(*action) || action.Metadata[ModeKey] != "dry-run" || action.Metadata["rule"] != "gmail_burst" {
+		t.Errorf("Expected the action tagged simulated, got %+v", action.Metadata)
+	}
+	if _, ok := original[SimulatedKey]; ok {
+		t.Error("Expected the original metadata left unchanged")
+	}
+
+	off := &database.IPAction{}
+	Tag(off, Off)
+	if IsSimulated(*off) || off.Metadata[ModeKey] != "off" {
+		t.Errorf("Expected only the mode recorded with remediation off, got %+v", off.Metadata)
+	}
+
+	enforced := &database.IPAction{}
+	Tag(enforced, Enforce)
+	if enforced.Metadata != nil {
+		t.Errorf("Expected an enforced action left as is, got %+v", enforced.Metadata)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	defer SetMode(Enforce)
+	SetMode(DryRun)
+
+	var journal Journal
+	if !journal.Changed("192.0.2.1", "quarantined") {
+		t.Fatal("Expected the first outcome to be new")
+	}
+	if journal.Changed("192.0.2.1", "quarantined") {
+		t.Error("Expected the same outcome to be recorded once")
+	}
+	if !journal.Changed("192.0.2.1", "restored") {
+		t.Error("Expected a different outcome to be new")
+	}
+
+	journal.Forget("192.0.2.1")
+	if !journal.Changed("192.0.2.1", "restored") {
+		t.Error("Expected a forgotten outcome to be new")
+	}
+
+	SetMode(Off)
+	SetMode(DryRun)
+	if !journal.Changed("192.0.2.1", "restored") {
+		t.Error("Expected outcomes to be new again after a mode change")
+	}
+}
+
+type fakeStore struct {
+	rev *database.ConfigRevision
+	err error
+}
+
+func (f fakeStore) GetLatestConfigRevision(scope string) (*database.ConfigRevision, error) {
+	return f.rev, f.err
+}
+
+func TestRefresh(t *testing.T) {
+	defer func() { store = dbStore{} }()
+	defer SetMode(Enforce)
+
+	store = fakeStore{}
+	if mode, err := Refresh(Enforce); err != nil || mode != Enforce || Current() != Enforce {
+		t.Errorf("Expected the fallback without a stored mode, got %s %v", mode, err)
+	}
+
+	// A kill switch set on another instance
+	store = fakeStore{rev: &database.ConfigRevision{ID: 2, Config: []byte(`{"mode":"off"}`)}}
+	if mode, err := Refresh(Enforce); err != nil || mode != Off || Current() != Off {
+		t.Errorf("Expected the stored mode applied, got %s %v", mode, err)
+	}
+
+	store = fakeStore{err: errors.New("connection refused")}
+	if mode, err := Refresh(Enforce); err == nil || mode != Off {
+		t.Errorf("Expected off kept while the store is unreadable, got %s %v", mode, err)
+	}
+	SetMode(Enforce)
+	if mode, err := Refresh(Enforce); err == nil || mode != DryRun || Current() != DryRun {
+		t.Errorf("Expected enforce to fall back to dry-run while the store is unreadable, got %s %v", mode, err)
+	}
+}
@@ -0,0 +1,37 @@
+package reporting
+
+import (
+	"time"
+)
+
+// Event is one error or recovered panic to report
+type Event struct {
+	// Message summarizes the event, e.g. the recovered panic value
+	Message string
+	// Err is the underlying error, nil for panics with a non-error value
+	Err error
+	// Panic marks events recovered from a panic
+	Panic bool
+	// Stack is the goroutine stack at the time of the event, if captured
+	Stack []byte
+	// Tags group and filter events, e.g. route, method or ip
+	Tags map[string]string
+	// Fingerprint groups recurring events; empty leaves grouping to the
+	// aggregation service
+	Fingerprint []string
+	Timestamp   time.Time
+}
+
+// ErrorReporter ships events to an error aggregation service. Report must
+// not block the caller for long; implementations queue or send in the
+// background.
+type ErrorReporter interface {
+	Report(event Event)
+}
+
+// Nop discards every event
+var Nop ErrorReporter = nopReporter{}
+
+type nopReporter struct{}
+
+func (nopReporter) Report(Event) {}
@@ -0,0 +1,321 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sentryQueueSize   = 100
+	sentryTimeout     = 5 * time.Second
+	sentryClient      = "golang-backend-service/1.0"
+	sentryTagMaxLen   = 200
+	modulePathPrefix  = "golang-backend-service/"
+	defaultRetryAfter = time.Minute
+)
+
+// SentryOptions configures the Sentry reporter
+type SentryOptions struct {
+	// DSN is the project DSN, https://<public key>@<host>/<project id>. Any
+	// service speaking the Sentry envelope API (e.g. GlitchTip) works.
+	DSN         string
+	Environment string
+	Release     string
+	ServerName  string
+}
+
+// Sentry sends events to Sentry in the background. Events are dropped
+// rather than blocking callers when the queue is full or Sentry asked the
+// client to back off.
+type Sentry struct {
+	opts      SentryOptions
+	endpoint  string
+	auth      string
+	client    *http.Client
+	queue     chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	mu         sync.Mutex
+	pausedTill time.Time
+}
+
+// NewSentry parses the DSN and starts the sender
+func NewSentry(opts SentryOptions) (*Sentry, error) {
+	dsn, err := url.Parse(opts.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	key := dsn.User.Username()
+	path := strings.TrimSuffix(dsn.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	if dsn.Scheme == "" || dsn.Host == "" || key == "" || slash < 0 || path[slash+1:] == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: expected https://<key>@<host>/<project id>")
+	}
+	if opts.ServerName == "" {
+		opts.ServerName, _ = os.Hostname()
+	}
+
+	s := &Sentry{
+		opts:     opts,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", dsn.Scheme, dsn.Host, path[:slash], path[slash+1:]),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, key),
+		client:   &http.Client{Timeout: sentryTimeout},
+		queue:    make(chan Event, sentryQueueSize),
+		done:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Report queues an event for sending
+func (s *Sentry) Report(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	select {
+	case <-s.done:
+	case s.queue <- event:
+	default:
+		// Drop rather than block callers when Sentry falls behind
+	}
+}
+
+// Close sends the queued events and stops the sender
+func (s *Sentry) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Sentry) run() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case event := <-s.queue:
+			s.deliver(event)
+		case <-s.done:
+			for {
+				select {
+				case event := <-s.queue:
+					s.deliver(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Sentry) deliver(event Event) {
+	s.mu.Lock()
+	paused := time.Now().Before(s.pausedTill)
+	s.mu.Unlock()
+	if paused {
+		return
+	}
+
+	// Errors go to stderr: logging them would report them again
+	if err := s.send(event); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to send event to Sentry: %v\n", err)
+	}
+}
+
+func (s *Sentry) send(event Event) error {
+	out := s.buildEvent(event)
+	payload, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{
+		"event_id": out.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	body.Write(header)
+	fmt.Fprintf(&body, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		s.mu.Lock()
+		s.pausedTill = time.Now().Add(retryAfter)
+		s.mu.Unlock()
+		return fmt.Errorf("rate limited, pausing for %s", retryAfter)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sentry event payload (https://develop.sentry.dev/sdk/data-model/event-payloads/)
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     *sentryMessage    `json:"message,omitempty"`
+	Exception   *sentryExceptions `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Mechanism  *sentryMechanism  `json:"mechanism,omitempty"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryMechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+func (s *Sentry) buildEvent(event Event) sentryEvent {
+	out := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   event.Timestamp.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Environment: s.opts.Environment,
+		Release:     s.opts.Release,
+		ServerName:  s.opts.ServerName,
+		Fingerprint: event.Fingerprint,
+	}
+	if event.Panic {
+		out.Level = "fatal"
+	}
+	if event.Message != "" {
+		out.Message = &sentryMessage{Formatted: event.Message}
+	}
+	if event.Err != nil {
+		// The exception type is the event's title in Sentry; the logged
+		// message reads better there than an error's Go type
+		exception := sentryException{
+			Type:  event.Message,
+			Value: event.Err.Error(),
+		}
+		if exception.Type == "" {
+			exception.Type = fmt.Sprintf("%T", event.Err)
+		}
+		if event.Panic {
+			exception.Type = "panic"
+			exception.Mechanism = &sentryMechanism{Type: "recovery", Handled: false}
+		}
+		if frames := parseStack(event.Stack); len(frames) > 0 {
+			exception.Stacktrace = &sentryStacktrace{Frames: frames}
+		}
+		out.Exception = &sentryExceptions{Values: []sentryException{exception}}
+	}
+	if len(event.Tags) > 0 {
+		out.Tags = make(map[string]string, len(event.Tags))
+		for k, v := range event.Tags {
+			if len(v) > sentryTagMaxLen {
+				v = v[:sentryTagMaxLen]
+			}
+			out.Tags[k] = v
+		}
+	}
+	return out
+}
+
+// parseStack turns a runtime/debug.Stack trace into Sentry frames, oldest
+// call first as Sentry expects
+func parseStack(stack []byte) []sentryFrame {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	if len(lines) < 3 {
+		return nil
+	}
+
+	var frames []sentryFrame
+	// The first line is the goroutine header; then each frame is a function
+	// line followed by a tab-indented file:line line
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := lines[i]
+		if created, ok := strings.CutPrefix(function, "created by "); ok {
+			function, _, _ = strings.Cut(created, " in goroutine")
+		} else if open := strings.LastIndex(function, "("); open > 0 {
+			// Strip the argument list
+			function = function[:open]
+		}
+
+		location := strings.TrimSpace(lines[i+1])
+		if idx := strings.LastIndex(location, " +0x"); idx > 0 {
+			location = location[:idx]
+		}
+		frame := sentryFrame{Function: function, AbsPath: location, InApp: strings.HasPrefix(function, modulePathPrefix)}
+		if colon := strings.LastIndex(location, ":"); colon > 0 {
+			if lineno, err := strconv.Atoi(location[colon+1:]); err == nil {
+				frame.AbsPath = location[:colon]
+				frame.Lineno = lineno
+			}
+		}
+		frames = append(frames, frame)
+	}
+
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package reporting
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewSentryRejectsInvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://sentry.example.com/42", "https://key@sentry.example.com/", "not a url"} {
+		if _, err := NewSentry(SentryOptions{DSN: dsn}); err == nil {
+			t.Errorf("Expected DSN %q to be rejected", dsn)
+		}
+	}
+}
+
+func TestSentrySendsEnvelopes(t *testing.T) {
+	var mu sync.Mutex
+	var paths, auths []string
+	var events []sentryEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		var event sentryEvent
+		if len(lines) == 3 {
+			json.Unmarshal([]byte(lines[2]), &event)
+		}
+
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("X-Sentry-Auth"))
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/prefix/42"
+	sentry, err := NewSentry(SentryOptions{DSN: dsn, Environment: "production", Release: "1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentry.Report(Event{
+		Message:     "Failed to aggregate metrics for IP",
+		Err:         errors.New("connection refused"),
+		Tags:        map[string]string{"ip": "192.0.2.1", "module": "reputation"},
+		Fingerprint: []string{"reputation", "aggregate_metrics"},
+	})
+	sentry.Report(Event{
+		Message: "nil map write",
+		Err:     errors.New("nil map write"),
+		Panic:   true,
+		Stack: []byte("goroutine 7 [running]:\n" +
+			"runtime/debug.Stack()\n\t/usr/local/go/src/runtime/debug/stack.go:26 +0x5e\n" +
+			"golang-backend-service/internal/api.(*Drainer).Middleware.func1({0x1, 0x2}, 0xc0)\n\t/src/internal/api/drain.go:62 +0x1d\n" +
+			"created by net/http.(*Server).Serve in goroutine 1\n\t/usr/local/go/src/net/http/server.go:3285 +0x4b4\n"),
+	})
+	sentry.Close()
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if paths[0] != "/prefix/api/42/envelope/" || !strings.Contains(auths[0], "sentry_key=public") {
+		t.Errorf("Unexpected endpoint %s or auth %s", paths[0], auths[0])
+	}
+
+	logged := events[0]
+	if logged.Level != "error" || logged.Environment != "production" || logged.Release != "1.2.3" {
+		t.Errorf("Unexpected event metadata: %+v", logged)
+	}
+	if logged.Exception == nil || logged.Exception.Values[0].Type != "Failed to aggregate metrics for IP" || logged.Exception.Values[0].Value != "connection refused" {
+		t.Errorf("Unexpected exception: %+v", logged.Exception)
+	}
+	if logged.Tags["ip"] != "192.0.2.1" || len(logged.Fingerprint) != 2 {
+		t.Errorf("Unexpected tags %v or fingerprint %v", logged.Tags, logged.Fingerprint)
+	}
+
+	panicked := events[1]
+	if panicked.Level != "fatal" || panicked.Exception.Values[0].Type != "panic" {
+		t.Fatalf("Unexpected panic event: %+v", panicked)
+	}
+	frames := panicked.Exception.Values[0].Stacktrace.Frames
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %+v", frames)
+	}
+	if frames[0].Function != "net/http.(*Server).Serve" || frames[0].InApp {
+		t.Errorf("Expected the oldest frame first, got %+v", frames[0])
+	}
+	if frames[1].Function != "golang-backend-service/internal/api.(*Drainer).Middleware.func1" || !frames[1].InApp ||
+		frames[1].AbsPath != "/src/internal/api/drain.go" || frames[1].Lineno != 62 {
+		t.Errorf("Unexpected in-app frame: %+v", frames[1])
+	}
+}
+
+func TestSentryPausesWhenRateLimited(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	sentry, err := NewSentry(SentryOptions{DSN: strings.Replace(server.URL, "http://", "http://public@", 1) + "/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		sentry.Report(Event{Message: "boom", Err: errors.New("boom")})
+	}
+	sentry.Close()
+
+	if requests != 1 {
+		t.Errorf("Expected sending to pause after the 429, got %d requests", requests)
+	}
+}
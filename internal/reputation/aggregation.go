@@ -1,19 +1,44 @@
 package reputation
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"golang-backend-service/internal/alerting"
 	"golang-backend-service/internal/database"
-	"golang-backend-service/internal/logger"
+	"golang-backend-service/internal/features"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
 
 	"github.com/sirupsen/logrus"
 )
 
+// TLSReportWindow is how far back TLS reports count towards the issue type;
+// reports cover a day and arrive the day after
+const TLSReportWindow = 48 * time.Hour
+
 // AggregationService handles periodic IP reputation aggregation
 type AggregationService struct {
 	config        ReputationConfig
+	shadow        *ReputationConfig
+	configMu      sync.RWMutex
+	machine       *state.Machine
+	dnsbl         *DNSBLService
+	lookups       *LookupService
+	// volume estimates total_sent; fallback is the failure heuristic, used
+	// when volume is nil or has no data
+	volumes       []VolumeEstimator
+	fallback      VolumeEstimator
+	burst         *BurstDetector
+	recommender   *RecommendationCatalog
+	// alerts sends status changes to the notification routes; nil only logs
+	alerts        *alerting.Dispatcher
+	logger        *logrus.Logger
 	ticker        *time.Ticker
 	stopChan      chan bool
 	running       bool
@@ -21,15 +46,90 @@ type AggregationService struct {
 	lastRun       time.Time
 	ipsProcessed  int
 	errors        int
+	shadowRun     *ShadowReport
+	shadowReport  *ShadowReport
+	// countingSince caches the start of the failure counters
+	countingSince time.Time
+	countersMu    sync.Mutex
+	// simulations keeps dry-run remediation from recording the same send
+	// rate cut on every run
+	simulations remediation.Journal
 }
 
 // NewAggregationService creates a new aggregation service
-func NewAggregationService(config ReputationConfig) *AggregationService {
-	return &AggregationService{
-		config:   config,
-		stopChan: make(chan bool),
-		running:  false,
+func NewAggregationService(config ReputationConfig, logger *logrus.Logger) *AggregationService {
+	s := &AggregationService{
+		config:      config,
+		machine:     state.Default,
+		dnsbl:       NewDNSBLService(logger),
+		burst:       NewBurstDetector(),
+		recommender: NewRecommendationCatalog(nil),
+		logger:      logger,
+		stopChan:    make(chan bool),
+		running:     false,
+	}
+	s.fallback = failureVolume{count: s.countFailures}
+	return s
+}
+
+// SetLookupService sets the external reputation lookups run when an IP is
+// quarantined; nil disables them. Call it before Start.
+func (s *AggregationService) SetLookupService(lookups *LookupService) {
+	s.lookups = lookups
+}
+
+// SetAlertDispatcher sets where status change alerts are sent; nil only
+// logs them. Call it before Start.
+func (s *AggregationService) SetAlertDispatcher(alerts *alerting.Dispatcher) {
+	s.alerts = alerts
+}
+
+// SetVolumeEstimator sets where total_sent comes from: the first estimator
+// with data for an IP, then the failure heuristic; nil ones are skipped.
+// Call it before Start. The delivered estimator counts failures with the
+// service's counters.
+func (s *AggregationService) SetVolumeEstimator(volumes ...VolumeEstimator) {
+	s.volumes = nil
+	for _, volume := range volumes {
+		if volume == nil {
+			continue
+		}
+		if delivered, ok := volume.(*DeliveredVolume); ok && delivered.countFailures == nil {
+			delivered.countFailures = s.countFailures
+		}
+		s.volumes = append(s.volumes, volume)
+	}
+}
+
+// Config returns the thresholds the service currently applies
+func (s *AggregationService) Config() ReputationConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// SetConfig replaces the thresholds; the next aggregation run uses them
+func (s *AggregationService) SetConfig(config ReputationConfig) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config = config
+}
+
+// LoadActiveConfig returns the defaults overlaid with the newest stored
+// config revision, if any
+func LoadActiveConfig() (ReputationConfig, *database.ConfigRevision, error) {
+	config := DefaultReputationConfig()
+	rev, err := database.GetLatestConfigRevision(database.ConfigScopeReputation)
+	if err != nil || rev == nil {
+		return config, nil, err
+	}
+	if err := json.Unmarshal(rev.Config, &config); err != nil {
+		return DefaultReputationConfig(), nil, fmt.Errorf("failed to decode config revision %d: %w", rev.ID, err)
 	}
+	if err := config.Validate(); err != nil {
+		return DefaultReputationConfig(), nil, fmt.Errorf("config revision %d is invalid: %w", rev.ID, err)
+	}
+	return config, rev, nil
 }
 
 // Start begins the aggregation service
@@ -44,10 +144,10 @@ func (s *AggregationService) Start(intervalMinutes int) error {
 	s.ticker = time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
 	s.running = true
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action":           "aggregation_service_start",
 		"interval_minutes": intervalMinutes,
-		"window_minutes":   s.config.WindowMinutes,
+		"window_minutes":   s.Config().WindowMinutes,
 	}).Info("Starting IP reputation aggregation service")
 
 	// Run immediately on start
@@ -60,7 +160,7 @@ func (s *AggregationService) Start(intervalMinutes int) error {
 			case <-s.ticker.C:
 				s.runAggregation()
 			case <-s.stopChan:
-				logger.Info("Aggregation service stopped")
+				s.logger.Info("Aggregation service stopped")
 				return
 			}
 		}
@@ -85,7 +185,7 @@ func (s *AggregationService) Stop() {
 	s.stopChan <- true
 	s.running = false
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action": "aggregation_service_stop",
 	}).Info("Stopping IP reputation aggregation service")
 }
@@ -114,15 +214,15 @@ func (s *AggregationService) GetStats() map[string]interface{} {
 func (s *AggregationService) runAggregation() {
 	start := time.Now()
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action": "aggregation_run_start",
 	}).Info("Starting IP reputation aggregation run")
 
 	// Get IPs that need aggregation
-	since := time.Now().Add(-time.Duration(s.config.WindowMinutes) * time.Minute)
+	since := time.Now().Add(-time.Duration(s.Config().WindowMinutes) * time.Minute)
 	ips, err := database.GetIPsNeedingAggregation(since)
 	if err != nil {
-		logger.WithFields(logrus.Fields{
+		s.logger.WithFields(logrus.Fields{
 			"action": "aggregation_get_ips_failed",
 			"error":  err.Error(),
 		}).Error("Failed to get IPs needing aggregation")
@@ -132,18 +232,24 @@ func (s *AggregationService) runAggregation() {
 		return
 	}
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action":    "aggregation_ips_found",
 		"ip_count":  len(ips),
 	}).Info("Found IPs needing aggregation")
 
+	if s.ShadowConfig() != nil && features.Enabled(features.ShadowRules) {
+		s.mu.Lock()
+		s.shadowRun = newShadowReport()
+		s.mu.Unlock()
+	}
+
 	// Process each IP
 	successCount := 0
 	errorCount := 0
 
 	for _, ip := range ips {
 		if err := s.aggregateIPMetrics(ip); err != nil {
-			logger.WithFields(logrus.Fields{
+			s.logger.WithFields(logrus.Fields{
 				"action": "aggregation_ip_failed",
 				"ip":     ip,
 				"error":  err.Error(),
@@ -154,11 +260,20 @@ func (s *AggregationService) runAggregation() {
 		}
 	}
 
+	config := s.Config()
+	s.pruneFailureCounters(config)
+	s.pruneSentVolume(config)
+
 	// Update stats
 	s.mu.Lock()
 	s.lastRun = time.Now()
 	s.ipsProcessed += successCount
 	s.errors += errorCount
+	if s.shadowRun != nil {
+		s.shadowRun.RunAt = s.lastRun
+		s.shadowReport = s.shadowRun
+		s.shadowRun = nil
+	}
 	s.mu.Unlock()
 
 	// Record metrics
@@ -169,7 +284,7 @@ func (s *AggregationService) runAggregation() {
 
 	duration := time.Since(start)
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action":       "aggregation_run_complete",
 		"ips_success":  successCount,
 		"ips_failed":   errorCount,
@@ -179,7 +294,8 @@ func (s *AggregationService) runAggregation() {
 
 // aggregateIPMetrics aggregates metrics for a single IP
 func (s *AggregationService) aggregateIPMetrics(ip string) error {
-	windowStart := time.Now().Add(-time.Duration(s.config.WindowMinutes) * time.Minute)
+	config := s.Config()
+	windowStart := time.Now().Add(-time.Duration(config.WindowMinutes) * time.Minute)
 	windowEnd := time.Now()
 
 	// Get previous metrics to determine old status
@@ -188,57 +304,85 @@ func (s *AggregationService) aggregateIPMetrics(ip string) error {
 	if err == nil {
 		oldStatus = oldMetrics.Status
 	} else {
-		oldStatus = "unknown"
+		oldStatus = state.Unknown.String()
 	}
 
-	// For this implementation, we'll use a simple estimate for total_sent
-	// In production, this should come from your sending system
-	totalSent := s.estimateTotalSent(ip, windowStart)
+	totalSent, volumeSource := s.estimateTotalSent(ip, windowStart, windowEnd, config.MinVolumeForAssessment)
 
 	// Calculate health metrics
-	health, err := CalculateIPHealthCheck(ip, s.config.WindowMinutes, totalSent)
+	health, err := s.healthCheck(ip, config.WindowMinutes, totalSent, config.DecayHalfLifeMinutes)
 	if err != nil {
 		return fmt.Errorf("failed to calculate health check: %w", err)
 	}
+	health.setVolumeSource(volumeSource)
+	if err := s.AddReportedSignals(health, config, windowEnd); err != nil {
+		return err
+	}
 
-	// Determine status
-	status := DetermineIPStatus(*health, s.config)
+	// Determine status, constrained to the transitions the state machine allows
+	decided := DetermineIPStatus(*health, config)
+	s.evaluateShadow(*health, decided)
+
+	// Longer windows catch slow-burn degradation the primary window misses
+	windows := s.evaluateWindows(ip, config, windowEnd)
+	decided, decidingWindow := CombineWindowStatus(decided, windows)
+	if decidingWindow != "" {
+		s.logger.WithFields(logrus.Fields{
+			"action":          "status_escalated_by_window",
+			"ip":              ip,
+			"window":          decidingWindow,
+			"decided_status":  decided,
+		}).Info("Longer evaluation window escalated IP status")
+	}
+	status := s.machine.Resolve(state.Status(oldStatus), state.Status(decided)).String()
+	if status != decided {
+		s.logger.WithFields(logrus.Fields{
+			"action":         "status_transition_clamped",
+			"ip":             ip,
+			"old_status":     oldStatus,
+			"decided_status": decided,
+			"applied_status": status,
+		}).Info("Decided status not reachable directly, applying intermediate status")
+	}
 
 	// Record rejection ratio metric
 	RecordRejectionRatio(health.RejectionRatio)
 
 	// Create metrics record
-	metrics := &database.IPReputationMetrics{
-		IP:                       ip,
-		WindowStart:              windowStart,
-		WindowEnd:                windowEnd,
-		TotalSent:                health.TotalSent,
-		TotalRejected:            health.TotalRejected,
-		RejectionRatio:           health.RejectionRatio,
-		UniqueDomainsRejected:    health.UniqueDomainsRejected,
-		DistinctRejectionReasons: health.ReputationCodes,
-		MajorProvidersRejecting:  health.MajorProviders,
-		Status:                   status,
-		LastUpdated:              time.Now(),
-		Metadata: map[string]interface{}{
-			"throttle_count": health.ThrottleCount,
-			"domain_counts":  health.DomainCounts,
-			"issue_type":     GetIssueType(*health),
-		},
+	metrics := healthMetrics(health, status, decidingWindow, windowEnd)
+
+	// Save metrics through the state machine so guards and hooks are honoured
+	transition := state.Transition{
+		IP:          ip,
+		From:        state.Status(oldStatus),
+		To:          state.Status(status),
+		TriggeredBy: "automated_aggregation",
 	}
-
-	// Save metrics
-	if err := database.UpsertIPReputationMetrics(metrics); err != nil {
+	if err := s.machine.Apply(transition, func() error {
+		return database.UpsertIPReputationMetrics(metrics)
+	}); err != nil {
 		return fmt.Errorf("failed to save metrics: %w", err)
 	}
+	if err := database.InsertIPReputationHistory(metrics); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "record_reputation_history_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Warn("Failed to record IP reputation history")
+	}
+
+	// Providers rate-limiting the IP, and the send rates recommended to
+	// each provider, are tracked apart from its status
+	s.updateThrottleStates(ip, config, windowEnd)
+	s.updateSendRates(ip, status, config, windowEnd)
 
 	// If status changed, record action and take appropriate measures
-	if oldStatus != status && oldStatus != "unknown" {
+	if oldStatus != status && oldStatus != state.Unknown.String() {
 		// Record status change metric
 		RecordStatusChange(ip, oldStatus, status)
 		
 		if err := s.handleStatusChange(ip, oldStatus, status, *health); err != nil {
-			logger.WithFields(logrus.Fields{
+			s.logger.WithFields(logrus.Fields{
 				"action": "status_change_handler_failed",
 				"ip":     ip,
 				"error":  err.Error(),
@@ -249,7 +393,7 @@ func (s *AggregationService) aggregateIPMetrics(ip string) error {
 		RecordStatusChange(ip, status, status)
 	}
 
-	logger.WithFields(logrus.Fields{
+	s.logger.WithFields(logrus.Fields{
 		"action":          "ip_metrics_aggregated",
 		"ip":              ip,
 		"status":          status,
@@ -261,8 +405,101 @@ func (s *AggregationService) aggregateIPMetrics(ip string) error {
 	return nil
 }
 
+// healthMetrics is the metrics record of a health check
+func healthMetrics(health *IPHealthCheck, status, decidingWindow string, at time.Time) *database.IPReputationMetrics {
+	return &database.IPReputationMetrics{
+		IP:                       health.IP,
+		WindowStart:              at.Add(-time.Duration(health.WindowMinutes) * time.Minute),
+		WindowEnd:                at,
+		TotalSent:                health.TotalSent,
+		TotalRejected:            health.TotalRejected,
+		RejectionRatio:           health.RejectionRatio,
+		UniqueDomainsRejected:    health.UniqueDomainsRejected,
+		DistinctRejectionReasons: health.ReputationCodes,
+		MajorProvidersRejecting:  health.MajorProviders,
+		Status:                   status,
+		LastUpdated:              at,
+		Metadata: map[string]interface{}{
+			"throttle_count":       health.ThrottleCount,
+			"weighted_rejected":    health.WeightedRejected,
+			"greylisted":           health.Greylisted,
+			"retries_deduplicated": health.RetriesDeduplicated,
+			"auth_failures":        health.AuthFailures,
+			"spamtrap_hits":        health.SpamtrapHits,
+			"complaints":           health.Complaints,
+			"complaint_rate":       health.ComplaintRate,
+			"tls_failed_sessions":  health.TLSFailedSessions,
+			"tls_required":         health.TLSRequired,
+			"external_score":       health.ExternalScore,
+			"greylisted_domains":   health.GreylistedDomains,
+			"tls_required_domains": health.TLSRequiredDomains,
+			"external_feeds":       health.ExternalFeeds,
+			"domain_counts":        health.DomainCounts,
+			"instance_counts":      health.InstanceCounts,
+			"issue_type":           GetIssueType(*health),
+			"deciding_window":      decidingWindow,
+			"volume_source":        health.VolumeSource,
+			"volume_estimated":     health.VolumeEstimated,
+		},
+	}
+}
+
+// AddReportedSignals fills the signals reported out of band, each over its
+// own window: spamtrap hits, complaints, failed TLS sessions and the
+// unexpired verdicts of third-party feeds
+func (s *AggregationService) AddReportedSignals(health *IPHealthCheck, config ReputationConfig, now time.Time) error {
+	var err error
+	if config.SpamtrapQuarantineHits > 0 {
+		since := now.Add(-time.Duration(config.SpamtrapWindowHours) * time.Hour)
+		if health.SpamtrapHits, err = database.CountSpamtrapHits(health.IP, since); err != nil {
+			return err
+		}
+	}
+	if config.QuarantineComplaintRate > 0 || config.WarningComplaintRate > 0 {
+		// Complaints trail delivery by hours, so they are rated over their
+		// own, longer window
+		since := now.Add(-time.Duration(config.ComplaintWindowHours) * time.Hour)
+		if health.Complaints, err = database.CountAbuseComplaints(health.IP, since); err != nil {
+			return err
+		}
+		if sent, _ := s.estimateTotalSent(health.IP, since, now, config.MinVolumeForAssessment); sent > 0 {
+			health.ComplaintRate = float64(health.Complaints) / float64(sent)
+		}
+	}
+	if health.TLSFailedSessions, err = database.CountTLSFailedSessions(health.IP, now.Add(-TLSReportWindow)); err != nil {
+		return err
+	}
+	if config.QuarantineExternalScore > 0 || config.WarningExternalScore > 0 {
+		signals, err := database.GetExternalSignalsByIP(health.IP, now)
+		if err != nil {
+			return err
+		}
+		health.ExternalScore, health.ExternalFeeds = ExternalScore(signals)
+	}
+	return nil
+}
+
 // handleStatusChange handles actions when IP status changes
 func (s *AggregationService) handleStatusChange(ip, oldStatus, newStatus string, health IPHealthCheck) error {
+	// Alerts recommend what the API recommends for the IP
+	recommended, err := s.recommender.RecommendOrDefault(i18n.English, newStatus, GetIssueType(health))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"ip":    ip,
+			"error": err.Error(),
+		}).Warn("Failed to read the recommendation catalog, using the built-in recommendations")
+	}
+
+	// Alerts carry what on-call should know about the IP
+	ipContext, err := database.GetIPContext(ip)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"ip":    ip,
+			"error": err.Error(),
+		}).Warn("Failed to read the IP context, alerting without it")
+	}
+	alert := alertContext(ipContext)
+
 	// Record the action
 	action := &database.IPAction{
 		IP:             ip,
@@ -276,40 +513,93 @@ func (s *AggregationService) handleStatusChange(ip, oldStatus, newStatus string,
 			"unique_domains":        health.UniqueDomainsRejected,
 			"major_providers":       health.MajorProviders,
 			"total_rejected":        health.TotalRejected,
+			"recommendations":       recommended,
 		},
 		CreatedAt: time.Now(),
 	}
+	for k, v := range alert {
+		action.Metadata[k] = v
+	}
 
 	if err := database.InsertIPAction(action); err != nil {
 		return fmt.Errorf("failed to record action: %w", err)
 	}
 
+	notification := alerting.Alert{
+		IP:              ip,
+		PreviousStatus:  oldStatus,
+		Status:          newStatus,
+		Summary:         action.Reason,
+		RejectionRatio:  health.RejectionRatio,
+		MajorProviders:  health.MajorProviders,
+		Recommendations: RecommendedActionCodes(recommended),
+		At:              action.CreatedAt,
+	}
+	if ipContext != nil {
+		notification.Notes = ipContext.Notes
+		notification.RunbookURL = ipContext.RunbookURL
+	}
+	s.dispatchAlert(notification)
+
 	// Take automated actions based on new status
-	switch newStatus {
-	case "blacklisted":
-		s.handleBlacklistedIP(ip, health)
-	case "quarantine":
-		s.handleQuarantinedIP(ip, health)
-	case "warning":
-		s.handleWarningIP(ip, health)
+	switch state.Status(newStatus) {
+	case state.Blacklisted:
+		s.handleBlacklistedIP(ip, health, recommended, alert)
+	case state.Quarantine:
+		s.handleQuarantinedIP(ip, health, recommended, alert)
+	case state.Warning:
+		s.handleWarningIP(ip, health, recommended, alert)
 	}
 
 	return nil
 }
 
+// dispatchAlert sends an alert through the dispatcher, if any, logging
+// failures to route it
+func (s *AggregationService) dispatchAlert(alert alerting.Alert) {
+	if s.alerts == nil {
+		return
+	}
+	if _, err := s.alerts.Dispatch(alert); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "alert_dispatch_failed",
+			"ip":     alert.IP,
+			"status": alert.Status,
+			"error":  err.Error(),
+		}).Error("Failed to dispatch status change alert")
+	}
+}
+
+// alertContext returns the notes and runbook URL of an IP to add to its
+// alerts, empty without a context
+func alertContext(c *database.IPContext) logrus.Fields {
+	fields := logrus.Fields{}
+	if c == nil {
+		return fields
+	}
+	if c.Notes != "" {
+		fields["notes"] = c.Notes
+	}
+	if c.RunbookURL != "" {
+		fields["runbook_url"] = c.RunbookURL
+	}
+	return fields
+}
+
 // handleBlacklistedIP handles critical blacklist status
-func (s *AggregationService) handleBlacklistedIP(ip string, health IPHealthCheck) {
-	logger.WithFields(logrus.Fields{
+func (s *AggregationService) handleBlacklistedIP(ip string, health IPHealthCheck, recommended []Recommendation, alert logrus.Fields) {
+	s.logger.WithFields(alert).WithFields(logrus.Fields{
 		"action":          "ip_blacklisted",
 		"ip":              ip,
 		"rejection_ratio": health.RejectionRatio,
 		"major_providers": health.MajorProviders,
+		"recommendations": RecommendedActionCodes(recommended),
 	}).Error("IP has been BLACKLISTED - immediate action required")
 
 	// Trigger DNSBL check asynchronously
-	CheckDNSBLAsync(ip, 5, func(result *DNSBLResult, err error) {
+	s.dnsbl.CheckDNSBLAsync(ip, 5, func(result *DNSBLResult, err error) {
 		if err != nil {
-			logger.WithFields(logrus.Fields{
+			s.logger.WithFields(logrus.Fields{
 				"action": "dnsbl_check_failed",
 				"ip":     ip,
 				"error":  err.Error(),
@@ -318,7 +608,7 @@ func (s *AggregationService) handleBlacklistedIP(ip string, health IPHealthCheck
 		}
 
 		if result.Listed {
-			logger.WithFields(logrus.Fields{
+			s.logger.WithFields(logrus.Fields{
 				"action":   "dnsbl_listings_found",
 				"ip":       ip,
 				"listings": result.Listings,
@@ -327,24 +617,23 @@ func (s *AggregationService) handleBlacklistedIP(ip string, health IPHealthCheck
 	})
 
 	// In production, you would:
-	// - Send critical alerts to ops team
 	// - Automatically quarantine the IP
-	// - Swap to backup IP
 	// - Trigger incident response
 }
 
 // handleQuarantinedIP handles quarantine status
-func (s *AggregationService) handleQuarantinedIP(ip string, health IPHealthCheck) {
-	logger.WithFields(logrus.Fields{
+func (s *AggregationService) handleQuarantinedIP(ip string, health IPHealthCheck, recommended []Recommendation, alert logrus.Fields) {
+	s.logger.WithFields(alert).WithFields(logrus.Fields{
 		"action":          "ip_quarantined",
 		"ip":              ip,
 		"rejection_ratio": health.RejectionRatio,
+		"recommendations": RecommendedActionCodes(recommended),
 	}).Warn("IP has been QUARANTINED - investigation needed")
 
 	// Trigger DNSBL check
-	CheckDNSBLAsync(ip, 5, func(result *DNSBLResult, err error) {
+	s.dnsbl.CheckDNSBLAsync(ip, 5, func(result *DNSBLResult, err error) {
 		if err == nil && result.Listed {
-			logger.WithFields(logrus.Fields{
+			s.logger.WithFields(logrus.Fields{
 				"action":   "dnsbl_listings_found_quarantine",
 				"ip":       ip,
 				"listings": result.Listings,
@@ -352,57 +641,81 @@ func (s *AggregationService) handleQuarantinedIP(ip string, health IPHealthCheck
 		}
 	})
 
+	// Ask external reputation sources how they rate the IP
+	if s.lookups != nil {
+		s.lookups.LookupAsync(ip)
+	}
+
 	// In production, you would:
-	// - Send warning alerts
 	// - Reduce traffic by 50%
 	// - Increase monitoring
 }
 
 // handleWarningIP handles warning status
-func (s *AggregationService) handleWarningIP(ip string, health IPHealthCheck) {
-	logger.WithFields(logrus.Fields{
+func (s *AggregationService) handleWarningIP(ip string, health IPHealthCheck, recommended []Recommendation, alert logrus.Fields) {
+	s.logger.WithFields(alert).WithFields(logrus.Fields{
 		"action":          "ip_warning",
 		"ip":              ip,
 		"rejection_ratio": health.RejectionRatio,
+		"recommendations": RecommendedActionCodes(recommended),
 	}).Warn("IP has WARNING status - monitor closely")
 
 	// In production, you would:
-	// - Send informational alerts
 	// - Increase monitoring frequency
 	// - Prepare for potential escalation
 }
 
-// estimateTotalSent estimates total emails sent (placeholder implementation)
-// In production, this should query your actual sending metrics
-func (s *AggregationService) estimateTotalSent(ip string, since time.Time) int {
-	// This is a simple estimation based on failures
-	// In production, integrate with your actual sending system metrics
-	
-	failures, err := database.GetSMTPFailuresByIP(ip, since)
+// estimateTotalSent estimates the emails an IP sent between since and until
+// with the configured estimator, falling back to the failure heuristic when
+// it has no data or fails. Returns the volume and the estimator it came from.
+func (s *AggregationService) estimateTotalSent(ip string, since, until time.Time, minVolume int) (int, string) {
+	estimated, source, err := s.sentVolume(ip, since, until)
 	if err != nil {
-		return 100 // Default minimum for assessment
+		return defaultEstimatedSent, source
 	}
 
-	failureCount := len(failures)
-	
-	// For testing/demo: Use a more conservative ratio
-	// Assume 5% failure rate for estimation
-	// So if we have X failures, total sent is approximately X / 0.05
-	estimated := int(float64(failureCount) * 20) // failureCount / 0.05 = failureCount * 20
-	
 	// Ensure minimum volume for assessment
-	if estimated < s.config.MinVolumeForAssessment {
-		return s.config.MinVolumeForAssessment
+	if estimated < minVolume {
+		return minVolume, source
+	}
+
+	return estimated, source
+}
+
+// sentVolume asks the configured estimators in order, then the fallback
+func (s *AggregationService) sentVolume(ip string, since, until time.Time) (int, string, error) {
+	for _, volume := range s.volumes {
+		sent, err := volume.EstimateSent(context.Background(), ip, since, until)
+		if err == nil {
+			return sent, volume.Name(), nil
+		}
+		if !errors.Is(err, ErrNoVolume) {
+			s.logger.WithFields(logrus.Fields{
+				"action":    "volume_estimate_failed",
+				"ip":        ip,
+				"estimator": volume.Name(),
+				"error":     err.Error(),
+			}).Warn("Failed to estimate sent volume, trying the next source")
+		}
 	}
+	sent, err := s.fallback.EstimateSent(context.Background(), ip, since, until)
+	return sent, s.fallback.Name(), err
+}
+
+// sentPerFailure is the messages assumed sent per failure when volume is
+// estimated from failures: a 5% failure rate
+const sentPerFailure = 20
 
-	return estimated
+// EstimateSentFromFailures estimates how much mail was sent from the number
+// of failures. In production, integrate with your actual sending system
+// metrics.
+func EstimateSentFromFailures(failures int) int {
+	return failures * sentPerFailure
 }
 
 // AggregateIPOnDemand manually triggers aggregation for a specific IP
-func AggregateIPOnDemand(ip string, config ReputationConfig) (*database.IPReputationMetrics, error) {
-	service := NewAggregationService(config)
-	
-	if err := service.aggregateIPMetrics(ip); err != nil {
+func (s *AggregationService) AggregateIPOnDemand(ip string) (*database.IPReputationMetrics, error) {
+	if err := s.aggregateIPMetrics(ip); err != nil {
 		return nil, fmt.Errorf("failed to aggregate metrics: %w", err)
 	}
 
@@ -0,0 +1,69 @@
+package reputation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// syntheticFailures builds n failures over the last hour, shaped like real
+// traffic: mostly major providers, a long tail of other domains, every tenth
+// one a retry of the previous message and every twentieth a 4xx deferral
+func syntheticFailures(n int, now time.Time) []database.SMTPFailure {
+	domains := []string{"gmail.com", "outlook.com", "yahoo.com", "hotmail.com", "aol.com"}
+	codes := []string{"5.7.1", "5.1.1", "5.7.606", "5.2.2", "5.7.26"}
+	failures := make([]database.SMTPFailure, n)
+	for i := range failures {
+		domain := domains[i%len(domains)]
+		if i%3 == 0 {
+			domain = fmt.Sprintf("example%d.org", i%500)
+		}
+		message := i
+		if i%10 == 9 {
+			message = i - 1
+		}
+		f := database.SMTPFailure{
+			SendingIP:       "192.0.2.10",
+			RecipientEmail:  fmt.Sprintf("user%d@%s", message, domain),
+			RecipientDomain: domain,
+			SMTPCode:        550,
+			EnhancedCode:    codes[i%len(codes)],
+			Reason:          "Message rejected",
+			Timestamp:       now.Add(-time.Duration(i%3600) * time.Second),
+			MTAInstance:     fmt.Sprintf("mta-%d", i%4),
+			MessageID:       fmt.Sprintf("q-%d", message),
+		}
+		if i%20 == 0 {
+			f.SMTPCode, f.EnhancedCode, f.Reason = 421, "4.7.0", "Try again later"
+		}
+		failures[i] = f
+	}
+	return failures
+}
+
+// BenchmarkCalculateIPHealthCheck measures the in-memory part of
+// CalculateIPHealthCheck, which runs for every IP on every aggregation run;
+// the database reads are not included
+func BenchmarkCalculateIPHealthCheck(b *testing.B) {
+	now := time.Now()
+	for _, n := range []int{1000, 10000, 100000} {
+		failures := syntheticFailures(n, now)
+		b.Run(fmt.Sprintf("failures=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildHealthCheck("192.0.2.10", "", 60, n*10, 30, failures, now)
+			}
+		})
+	}
+}
+
+func BenchmarkDetermineIPStatus(b *testing.B) {
+	health := buildHealthCheck("192.0.2.10", "", 60, 100000, 30, syntheticFailures(10000, time.Now()), time.Now())
+	config := DefaultReputationConfig()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DetermineIPStatus(*health, config)
+	}
+}
@@ -0,0 +1,330 @@
+package reputation
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Burst rule actions
+const (
+	BurstActionQuarantine = "quarantine"
+	BurstActionAlert      = "alert"
+)
+
+// BurstRule detects an extreme burst of matching failures at ingestion
+// time, before the next aggregation run sees it
+type BurstRule struct {
+	Name string `json:"name"`
+	// Domain is the organizational recipient domain that must reject (e.g.
+	// gmail.com); empty matches any domain
+	Domain string `json:"domain"`
+	// EnhancedCode is an exact code (5.7.1) or a class.subject prefix
+	// (5.7); empty matches any code
+	EnhancedCode  string `json:"enhanced_code"`
+	Threshold     int    `json:"threshold"`
+	WindowSeconds int    `json:"window_seconds"`
+	Action        string `json:"action"`
+}
+
+// DefaultBurstRules quarantines an IP on 50 Gmail policy rejections within
+// two minutes and alerts on 200 policy rejections from anywhere within five
+func DefaultBurstRules() []BurstRule {
+	return []BurstRule{
+		{Name: "gmail_policy", Domain: "gmail.com", EnhancedCode: "5.7.1", Threshold: 50, WindowSeconds: 120, Action: BurstActionQuarantine},
+		{Name: "policy_flood", EnhancedCode: "5.7", Threshold: 200, WindowSeconds: 300, Action: BurstActionAlert},
+	}
+}
+
+// window returns the rule's window as a duration
+func (r BurstRule) window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// matches reports whether a failure counts towards the rule
+func (r BurstRule) matches(domain, enhancedCode string) bool {
+	if r.Domain != "" && !strings.EqualFold(r.Domain, domain) {
+		return false
+	}
+	if r.EnhancedCode != "" && enhancedCode != r.EnhancedCode && !strings.HasPrefix(enhancedCode, r.EnhancedCode+".") {
+		return false
+	}
+	return true
+}
+
+// validateBurstRules checks rules have unique names and sane thresholds
+func validateBurstRules(rules []BurstRule) error {
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return fmt.Errorf("burst_rules: name is required")
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("burst_rules: duplicate name %q", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Threshold <= 0 {
+			return fmt.Errorf("burst_rules[%s]: threshold must be positive", r.Name)
+		}
+		if r.WindowSeconds <= 0 {
+			return fmt.Errorf("burst_rules[%s]: window_seconds must be positive", r.Name)
+		}
+		if r.Action != BurstActionQuarantine && r.Action != BurstActionAlert {
+			return fmt.Errorf("burst_rules[%s]: action must be %s or %s", r.Name, BurstActionQuarantine, BurstActionAlert)
+		}
+	}
+	return nil
+}
+
+// Burst is a rule an IP tripped
+type Burst struct {
+	IP      string    `json:"ip"`
+	Rule    BurstRule `json:"rule"`
+	Count   int       `json:"count"`
+	FirstAt time.Time `json:"first_at"`
+	LastAt  time.Time `json:"last_at"`
+}
+
+// burstRing holds the timestamps of the last Threshold matching failures of
+// one IP for one rule
+type burstRing struct {
+	times   []time.Time
+	next    int
+	full    bool
+	firedAt time.Time
+}
+
+func (b *burstRing) add(at time.Time) {
+	b.times[b.next] = at
+	b.next = (b.next + 1) % len(b.times)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// span returns the oldest and newest timestamp held. Events may arrive out
+// of order, so the whole ring is scanned; it is at most Threshold long.
+func (b *burstRing) span() (time.Time, time.Time) {
+	n := b.next
+	if b.full {
+		n = len(b.times)
+	}
+	first, last := b.times[0], b.times[0]
+	for _, t := range b.times[1:n] {
+		if t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return first, last
+}
+
+func (b *burstRing) reset() {
+	b.next = 0
+	b.full = false
+}
+
+type burstKey struct {
+	ip   string
+	rule string
+}
+
+// BurstDetector keeps a ring buffer per IP and rule. A rule fires when its
+// ring is full and spans no more than the rule's window; it then stays
+// quiet for one window so a sustained burst is reported once per window.
+type BurstDetector struct {
+	mu        sync.Mutex
+	rings     map[burstKey]*burstRing
+	lastSweep time.Time
+}
+
+// NewBurstDetector creates an empty detector
+func NewBurstDetector() *BurstDetector {
+	return &BurstDetector{rings: make(map[burstKey]*burstRing)}
+}
+
+// Observe counts a failure against the rules and returns the ones it
+// tripped. Failures older than a rule's window are ignored, so a replayed
+// backlog does not look like a burst.
+func (d *BurstDetector) Observe(rules []BurstRule, ip, domain, enhancedCode string, at, now time.Time) []Burst {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var bursts []Burst
+	var maxWindow time.Duration
+	for _, rule := range rules {
+		window := rule.window()
+		if window > maxWindow {
+			maxWindow = window
+		}
+		if !rule.matches(domain, enhancedCode) || now.Sub(at) > window {
+			continue
+		}
+
+		key := burstKey{ip: ip, rule: rule.Name}
+		ring := d.rings[key]
+		if ring == nil || len(ring.times) != rule.Threshold {
+			ring = &burstRing{times: make([]time.Time, rule.Threshold)}
+			d.rings[key] = ring
+		}
+		if !ring.firedAt.IsZero() && now.Sub(ring.firedAt) < window {
+			continue
+		}
+
+		ring.add(at)
+		if !ring.full {
+			continue
+		}
+		first, last := ring.span()
+		if last.Sub(first) > window {
+			continue
+		}
+
+		ring.firedAt = now
+		ring.reset()
+		bursts = append(bursts, Burst{IP: ip, Rule: rule, Count: rule.Threshold, FirstAt: first, LastAt: last})
+	}
+
+	if now.Sub(d.lastSweep) > maxWindow {
+		d.sweep(now, maxWindow)
+	}
+	return bursts
+}
+
+// sweep drops the rings of IPs that have been quiet for longer than any
+// window, keeping memory proportional to the recently failing IPs
+func (d *BurstDetector) sweep(now time.Time, maxWindow time.Duration) {
+	for key, ring := range d.rings {
+		if ring.next == 0 && !ring.full {
+			if now.Sub(ring.firedAt) > maxWindow {
+				delete(d.rings, key)
+			}
+			continue
+		}
+		if _, last := ring.span(); now.Sub(last) > maxWindow && now.Sub(ring.firedAt) > maxWindow {
+			delete(d.rings, key)
+		}
+	}
+	d.lastSweep = now
+}
+
+// Tracked returns the number of IP and rule pairs currently counted
+func (d *BurstDetector) Tracked() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.rings)
+}
+
+// ObserveFailure feeds an ingested failure to the burst detector. Tripped
+// rules are acted on in the background so ingestion is not held up.
+func (s *AggregationService) ObserveFailure(ip, domain, enhancedCode string, at time.Time) []Burst {
+	bursts := s.burst.Observe(s.Config().BurstRules, ip, domain, enhancedCode, at, time.Now())
+	for _, burst := range bursts {
+		go s.handleBurst(burst)
+	}
+	return bursts
+}
+
+// handleBurst records a burst and, for quarantine rules, quarantines the IP
+// right away
+func (s *AggregationService) handleBurst(burst Burst) {
+	RecordBurstDetected(burst.IP, burst.Rule.Name, burst.Rule.Action)
+	s.logger.WithFields(logrus.Fields{
+		"action":      "burst_detected",
+		"ip":          burst.IP,
+		"rule":        burst.Rule.Name,
+		"rule_action": burst.Rule.Action,
+		"count":       burst.Count,
+		"first_at":    burst.FirstAt,
+		"last_at":     burst.LastAt,
+	}).Error("Failure burst detected at ingestion")
+
+	previous, current := "", ""
+	mode := remediation.Current()
+	if burst.Rule.Action == BurstActionQuarantine {
+		var err error
+		previous, current, err = s.forceQuarantine(burst.IP, "burst_detector", mode)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "burst_quarantine_failed",
+				"ip":     burst.IP,
+				"rule":   burst.Rule.Name,
+				"error":  err.Error(),
+			}).Error("Failed to quarantine IP after burst")
+		}
+	}
+
+	action := &database.IPAction{
+		IP:             burst.IP,
+		Action:         "burst_" + burst.Rule.Action,
+		PreviousStatus: previous,
+		NewStatus:      current,
+		Reason:         fmt.Sprintf("%d matching failures within %s (rule %s)", burst.Count, burst.LastAt.Sub(burst.FirstAt).Round(time.Second), burst.Rule.Name),
+		TriggeredBy:    "burst_detector",
+		Metadata: map[string]interface{}{
+			"rule":           burst.Rule.Name,
+			"domain":         burst.Rule.Domain,
+			"enhanced_code":  burst.Rule.EnhancedCode,
+			"threshold":      burst.Rule.Threshold,
+			"window_seconds": burst.Rule.WindowSeconds,
+			"first_at":       burst.FirstAt,
+			"last_at":        burst.LastAt,
+		},
+		CreatedAt: time.Now(),
+	}
+	if burst.Rule.Action == BurstActionQuarantine {
+		remediation.Tag(action, mode)
+	}
+	if err := database.InsertIPAction(action); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "record_burst_failed",
+			"ip":     burst.IP,
+			"error":  err.Error(),
+		}).Error("Failed to record burst action")
+	}
+}
+
+// forceQuarantine aggregates the IP now and, unless that already put it in
+// quarantine or worse, moves it to quarantine. It returns the status before
+// and after. In dry-run mode the IP is left alone and the status it would
+// have had is returned; with remediation off the status is unchanged.
+func (s *AggregationService) forceQuarantine(ip, triggeredBy string, mode remediation.Mode) (string, string, error) {
+	metrics, err := s.AggregateIPOnDemand(ip)
+	if err != nil {
+		return "", "", err
+	}
+	previous := state.Status(metrics.Status)
+	if previous.Severity() >= state.Quarantine.Severity() {
+		return previous.String(), previous.String(), nil
+	}
+	switch mode {
+	case remediation.Off:
+		return previous.String(), previous.String(), nil
+	case remediation.DryRun:
+		return previous.String(), state.Quarantine.String(), nil
+	}
+
+	transition := state.Transition{
+		IP:          ip,
+		From:        previous,
+		To:          state.Quarantine,
+		TriggeredBy: triggeredBy,
+	}
+	if err := s.machine.Apply(transition, func() error {
+		metrics.Status = state.Quarantine.String()
+		return database.UpsertIPReputationMetrics(metrics)
+	}); err != nil {
+		return previous.String(), previous.String(), err
+	}
+	RecordStatusChange(ip, previous.String(), state.Quarantine.String())
+	return previous.String(), state.Quarantine.String(), nil
+}
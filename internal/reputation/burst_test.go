@@ -0,0 +1,120 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBurstRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule   BurstRule
+		domain string
+		code   string
+		want   bool
+	}{
+		{BurstRule{Domain: "gmail.com", EnhancedCode: "5.7.1"}, "gmail.com", "5.7.1", true},
+		{BurstRule{Domain: "gmail.com", EnhancedCode: "5.7.1"}, "GMAIL.com", "5.7.1", true},
+		{BurstRule{Domain: "gmail.com", EnhancedCode: "5.7.1"}, "yahoo.com", "5.7.1", false},
+		{BurstRule{Domain: "gmail.com", EnhancedCode: "5.7.1"}, "gmail.com", "5.7.10", false},
+		{BurstRule{EnhancedCode: "5.7"}, "yahoo.com", "5.7.26", true},
+		{BurstRule{EnhancedCode: "5.7"}, "yahoo.com", "5.70.1", false},
+		{BurstRule{}, "example.com", "4.2.0", true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.matches(tt.domain, tt.code); got != tt.want {
+			t.Errorf("%+v.matches(%s, %s) = %t, want %t", tt.rule, tt.domain, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestBurstDetectorObserve(t *testing.T) {
+	rules := []BurstRule{{Name: "gmail_policy", Domain: "gmail.com", EnhancedCode: "5.7.1", Threshold: 5, WindowSeconds: 120, Action: BurstActionQuarantine}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	d := NewBurstDetector()
+
+	for i := 0; i < 4; i++ {
+		if bursts := d.Observe(rules, "192.0.2.1", "gmail.com", "5.7.1", now, now); len(bursts) != 0 {
+			t.Fatalf("Expected no burst below the threshold, got %+v", bursts)
+		}
+		d.Observe(rules, "192.0.2.2", "yahoo.com", "5.7.1", now, now)
+	}
+	bursts := d.Observe(rules, "192.0.2.1", "gmail.com", "5.7.1", now.Add(time.Minute), now.Add(time.Minute))
+	if len(bursts) != 1 || bursts[0].IP != "192.0.2.1" || bursts[0].Count != 5 || !bursts[0].FirstAt.Equal(now) {
+		t.Fatalf("Expected one burst at the threshold, got %+v", bursts)
+	}
+
+	// a sustained burst is reported once per window
+	later := now.Add(time.Minute)
+	for i := 0; i < 10; i++ {
+		if bursts := d.Observe(rules, "192.0.2.1", "gmail.com", "5.7.1", later, later); len(bursts) != 0 {
+			t.Fatalf("Expected the rule quiet during its cooldown, got %+v", bursts)
+		}
+	}
+	after := now.Add(4 * time.Minute)
+	for i := 0; i < 5; i++ {
+		bursts = d.Observe(rules, "192.0.2.1", "gmail.com", "5.7.1", after, after)
+	}
+	if len(bursts) != 1 {
+		t.Errorf("Expected the rule to fire again after its cooldown, got %+v", bursts)
+	}
+}
+
+func TestBurstDetectorWindow(t *testing.T) {
+	rules := []BurstRule{{Name: "policy", EnhancedCode: "5.7", Threshold: 3, WindowSeconds: 60, Action: BurstActionAlert}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	spread := NewBurstDetector()
+	for i := 0; i < 6; i++ {
+		at := now.Add(time.Duration(i) * 40 * time.Second)
+		if bursts := spread.Observe(rules, "192.0.2.1", "example.com", "5.7.1", at, at); len(bursts) != 0 {
+			t.Fatalf("Expected failures spread over more than the window not to burst, got %+v", bursts)
+		}
+	}
+
+	replayed := NewBurstDetector()
+	for i := 0; i < 5; i++ {
+		if bursts := replayed.Observe(rules, "192.0.2.1", "example.com", "5.7.1", now.Add(-time.Hour), now); len(bursts) != 0 {
+			t.Fatalf("Expected failures older than the window ignored, got %+v", bursts)
+		}
+	}
+	if replayed.Tracked() != 0 {
+		t.Errorf("Expected nothing tracked for ignored failures, got %d", replayed.Tracked())
+	}
+}
+
+func TestBurstDetectorSweep(t *testing.T) {
+	rules := []BurstRule{{Name: "policy", EnhancedCode: "5.7", Threshold: 3, WindowSeconds: 60, Action: BurstActionAlert}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	d := NewBurstDetector()
+
+	d.Observe(rules, "192.0.2.1", "example.com", "5.7.1", now, now)
+	d.Observe(rules, "192.0.2.2", "example.com", "5.7.1", now, now)
+	if d.Tracked() != 2 {
+		t.Fatalf("Expected two IPs tracked, got %d", d.Tracked())
+	}
+
+	later := now.Add(5 * time.Minute)
+	d.Observe(rules, "192.0.2.3", "example.com", "5.7.1", later, later)
+	if d.Tracked() != 1 {
+		t.Errorf("Expected idle IPs swept, got %d tracked", d.Tracked())
+	}
+}
+
+func TestBurstRulesValidate(t *testing.T) {
+	invalid := []func(*ReputationConfig){
+		func(c *ReputationConfig) {
+			c.BurstRules = []BurstRule{{Threshold: 1, WindowSeconds: 1, Action: BurstActionAlert}}
+		},
+		func(c *ReputationConfig) { c.BurstRules = append(c.BurstRules, c.BurstRules[0]) },
+		func(c *ReputationConfig) { c.BurstRules[0].Threshold = 0 },
+		func(c *ReputationConfig) { c.BurstRules[0].WindowSeconds = -1 },
+		func(c *ReputationConfig) { c.BurstRules[0].Action = "page" },
+	}
+	for i, mutate := range invalid {
+		config := DefaultReputationConfig()
+		mutate(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error for %+v", i, config.BurstRules)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package reputation
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strings"
 	"time"
 
 	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/reputation/state"
 )
 
 // Configuration for reputation thresholds
@@ -19,6 +24,41 @@ type ReputationConfig struct {
 	QuarantineMinDomains           int     `json:"quarantine_min_domains"`
 	WarningRejectionRatio          float64 `json:"warning_rejection_ratio"`
 	WarningReputationCodeThreshold int     `json:"warning_reputation_code_threshold"`
+	// DecayHalfLifeMinutes weights failures in the primary window by age, so
+	// an old burst counts less as it ages; 0 weighs all failures equally
+	DecayHalfLifeMinutes int `json:"decay_half_life_minutes"`
+	// Windows are evaluated next to WindowMinutes and may escalate the status
+	Windows []EvaluationWindow `json:"windows"`
+	// BurstRules are checked as failures are ingested and act without
+	// waiting for the next aggregation run
+	BurstRules []BurstRule `json:"burst_rules"`
+	// SpamtrapQuarantineHits spamtrap hits within SpamtrapWindowHours
+	// quarantine an IP whatever its volume and ratios; 0 disables it
+	SpamtrapQuarantineHits int `json:"spamtrap_quarantine_hits"`
+	SpamtrapWindowHours    int `json:"spamtrap_window_hours"`
+	// Complaints from ARF reports within ComplaintWindowHours over the mail
+	// sent in that window give the complaint rate; a rate above
+	// QuarantineComplaintRate quarantines and one above WarningComplaintRate
+	// warns. A threshold of 0 disables its rule.
+	QuarantineComplaintRate float64 `json:"quarantine_complaint_rate"`
+	WarningComplaintRate    float64 `json:"warning_complaint_rate"`
+	ComplaintWindowHours    int     `json:"complaint_window_hours"`
+	// The external score sums the unexpired verdicts of third-party feeds,
+	// each scaled by the feed's weight and the verdict's confidence. A score
+	// of at least QuarantineExternalScore quarantines and one of at least
+	// WarningExternalScore warns, whatever the volume. 0 disables a rule.
+	QuarantineExternalScore float64 `json:"quarantine_external_score"`
+	WarningExternalScore    float64 `json:"warning_external_score"`
+	// ThrottleMinResponses rate-limit deferrals from one provider within
+	// ThrottleWindowMinutes mark the IP throttled there, so MTAs back off
+	// before the provider blocks it; 0 disables throttle tracking
+	ThrottleMinResponses  int `json:"throttle_min_responses"`
+	ThrottleWindowMinutes int `json:"throttle_window_minutes"`
+	// MaxMessagesPerHour is the send rate recommended to each provider for a
+	// healthy, warmed-up IP, capped by WarmupStages while the IP is young; 0
+	// disables send rate recommendations
+	MaxMessagesPerHour int           `json:"max_messages_per_hour"`
+	WarmupStages       []WarmupStage `json:"warmup_stages"`
 }
 
 // DefaultReputationConfig returns the default configuration
@@ -33,46 +73,218 @@ func DefaultReputationConfig() ReputationConfig {
 		QuarantineMinDomains:           2,
 		WarningRejectionRatio:          0.02, // 2%
 		WarningReputationCodeThreshold: 5,
+		Windows:                        DefaultEvaluationWindows(),
+		BurstRules:                     DefaultBurstRules(),
+		SpamtrapQuarantineHits:         1,
+		SpamtrapWindowHours:            24,
+		QuarantineComplaintRate:        0.003, // 0.3%
+		WarningComplaintRate:           0.001, // 0.1%
+		ComplaintWindowHours:           24,
+		QuarantineExternalScore:        2,
+		WarningExternalScore:           1,
+		ThrottleMinResponses:           3,
+		ThrottleWindowMinutes:          60,
+		MaxMessagesPerHour:             2000,
+		WarmupStages:                   DefaultWarmupStages(),
 	}
 }
 
+// Validate checks that ratios are fractions and counts are not negative
+func (c ReputationConfig) Validate() error {
+	if c.WindowMinutes <= 0 {
+		return fmt.Errorf("window_minutes must be positive")
+	}
+
+	ratios := []struct {
+		name  string
+		value float64
+	}{
+		{"blacklist_rejection_ratio", c.BlacklistRejectionRatio},
+		{"quarantine_rejection_ratio", c.QuarantineRejectionRatio},
+		{"warning_rejection_ratio", c.WarningRejectionRatio},
+		{"quarantine_complaint_rate", c.QuarantineComplaintRate},
+		{"warning_complaint_rate", c.WarningComplaintRate},
+	}
+	for _, r := range ratios {
+		if r.value < 0 || r.value > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", r.name)
+		}
+	}
+
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"min_volume_for_assessment", c.MinVolumeForAssessment},
+		{"blacklist_min_domains", c.BlacklistMinDomains},
+		{"blacklist_min_major_providers", c.BlacklistMinMajorProviders},
+		{"quarantine_min_domains", c.QuarantineMinDomains},
+		{"warning_reputation_code_threshold", c.WarningReputationCodeThreshold},
+		{"decay_half_life_minutes", c.DecayHalfLifeMinutes},
+		{"spamtrap_quarantine_hits", c.SpamtrapQuarantineHits},
+		{"throttle_min_responses", c.ThrottleMinResponses},
+		{"max_messages_per_hour", c.MaxMessagesPerHour},
+	}
+	for _, n := range counts {
+		if n.value < 0 {
+			return fmt.Errorf("%s must not be negative", n.name)
+		}
+	}
+	if c.SpamtrapQuarantineHits > 0 && c.SpamtrapWindowHours <= 0 {
+		return fmt.Errorf("spamtrap_window_hours must be positive")
+	}
+	if (c.QuarantineComplaintRate > 0 || c.WarningComplaintRate > 0) && c.ComplaintWindowHours <= 0 {
+		return fmt.Errorf("complaint_window_hours must be positive")
+	}
+	if c.QuarantineExternalScore < 0 || c.WarningExternalScore < 0 {
+		return fmt.Errorf("quarantine_external_score and warning_external_score must not be negative")
+	}
+	if c.ThrottleMinResponses > 0 && c.ThrottleWindowMinutes <= 0 {
+		return fmt.Errorf("throttle_window_minutes must be positive")
+	}
+	if err := validateWarmupStages(c.WarmupStages); err != nil {
+		return err
+	}
+	if err := validateWindows(c.Windows); err != nil {
+		return err
+	}
+	return validateBurstRules(c.BurstRules)
+}
+
+// ConfigChange is the old and new value of a changed config field
+type ConfigChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DiffConfigs returns the changed fields keyed by their JSON name
+func DiffConfigs(from, to ReputationConfig) map[string]ConfigChange {
+	fields := func(c ReputationConfig) map[string]interface{} {
+		m := make(map[string]interface{})
+		raw, _ := json.Marshal(c)
+		json.Unmarshal(raw, &m)
+		return m
+	}
+
+	before, after := fields(from), fields(to)
+	diff := make(map[string]ConfigChange)
+	for name, old := range before {
+		if now := after[name]; !reflect.DeepEqual(old, now) {
+			diff[name] = ConfigChange{From: old, To: now}
+		}
+	}
+	return diff
+}
+
 // IPHealthCheck contains calculated metrics for IP health assessment
 type IPHealthCheck struct {
 	IP                    string         `json:"ip"`
 	WindowMinutes         int            `json:"window_minutes"`
 	TotalSent             int            `json:"total_sent"`
 	TotalRejected         int            `json:"total_rejected"`
+	WeightedRejected      float64        `json:"weighted_rejected"` // TotalRejected with age decay, used for the ratio
 	RejectionRatio        float64        `json:"rejection_ratio"`
 	UniqueDomainsRejected int            `json:"unique_domains_rejected"`
 	MajorProviders        []string       `json:"major_providers"`
 	ReputationCodes       map[string]int `json:"reputation_codes"`
 	ThrottleCount         int            `json:"throttle_count"`
 	DomainCounts          map[string]int `json:"domain_counts"`
+	InstanceCounts        map[string]int `json:"instance_counts"`
+	Instance              string         `json:"instance,omitempty"`
+	// RetriesDeduplicated counts retry attempts not counted as rejections
+	RetriesDeduplicated int `json:"retries_deduplicated"`
+	// Greylisted counts deferrals recognised as greylisting; they are left
+	// out of every other count and the rejection ratio
+	Greylisted        int            `json:"greylisted"`
+	GreylistedDomains map[string]int `json:"greylisted_domains"`
+	// AuthFailures counts failed outgoing SMTP authentications of the IP
+	AuthFailures int `json:"auth_failures"`
+	// SpamtrapHits counts spamtrap hits of the IP within the config's
+	// SpamtrapWindowHours rather than WindowMinutes
+	SpamtrapHits int `json:"spamtrap_hits"`
+	// Complaints counts ARF complaints about the IP's mail within the
+	// config's ComplaintWindowHours; ComplaintRate divides them by the mail
+	// sent in that window
+	Complaints    int     `json:"complaints"`
+	ComplaintRate float64 `json:"complaint_rate"`
+	// TLSFailedSessions counts the IP's failed TLS negotiations in TLS
+	// reports of the last two days. They are diagnostic only: forced-TLS
+	// failures look like deliverability problems but say nothing about
+	// reputation.
+	TLSFailedSessions int `json:"tls_failed_sessions"`
+	// TLSRequired counts TLS failures to destinations whose MTA-STS or DANE
+	// policy requires TLS; like greylisting they are left out of every
+	// other count and the rejection ratio
+	TLSRequired        int            `json:"tls_required"`
+	TLSRequiredDomains map[string]int `json:"tls_required_domains"`
+	// ExternalScore is the weighted verdicts of third-party reputation
+	// feeds; ExternalFeeds is each feed's share of it
+	ExternalScore float64            `json:"external_score"`
+	ExternalFeeds map[string]float64 `json:"external_feeds,omitempty"`
+	// VolumeSource names the estimator TotalSent came from; VolumeEstimated
+	// is set when it is the failure heuristic rather than a counted volume
+	VolumeSource    string `json:"volume_source,omitempty"`
+	VolumeEstimated bool   `json:"volume_estimated"`
 }
 
+// setVolumeSource records the estimator TotalSent came from
+func (h *IPHealthCheck) setVolumeSource(source string) {
+	h.VolumeSource = source
+	h.VolumeEstimated = source == VolumeFromFailures
+}
+
+// Fixed warning thresholds not covered by ReputationConfig
+const (
+	warningThrottleCount = 10 // 4xx throttles, exceeded
+	repeated571Count     = 5  // 5.7.1 rejections, reached
+	warningAuthFailures  = 10 // outgoing SMTP auth failures, reached
+	minComplaints        = 3  // ARF complaints before the complaint rate counts, reached
+	tlsIssueSessions     = 5  // failed TLS sessions before they explain the failures, exceeded
+	externalIssueScore   = 1  // external score of one full-weight malicious verdict, reached
+)
+
 // DetermineIPStatus applies the decision algorithm to determine IP status
 func DetermineIPStatus(metrics IPHealthCheck, config ReputationConfig) string {
+	status := determineRatioStatus(metrics, config)
+
+	// SPAMTRAP - Critical signal, quarantines whatever the volume and ratios
+	if isSpamtrapped(metrics, config) && state.Status(status).Severity() < state.Quarantine.Severity() {
+		return state.Quarantine.String()
+	}
+
+	// EXTERNAL FEEDS - third-party verdicts, also whatever the volume
+	if reachesExternalScore(metrics, config.QuarantineExternalScore) && state.Status(status).Severity() < state.Quarantine.Severity() {
+		return state.Quarantine.String()
+	}
+	if reachesExternalScore(metrics, config.WarningExternalScore) && state.Status(status).Severity() < state.Warning.Severity() {
+		return state.Warning.String()
+	}
+	return status
+}
+
+// determineRatioStatus decides the status from volume, ratios and codes
+func determineRatioStatus(metrics IPHealthCheck, config ReputationConfig) string {
 	// CRITICAL: Must have minimum volume to assess
 	if metrics.TotalSent < config.MinVolumeForAssessment {
-		return "healthy"
+		return state.Healthy.String()
 	}
 
 	// BLACKLISTED - Immediate action required
 	if isBlacklisted(metrics, config) {
-		return "blacklisted"
+		return state.Blacklisted.String()
 	}
 
 	// QUARANTINE - High risk, needs investigation
 	if isQuarantined(metrics, config) {
-		return "quarantine"
+		return state.Quarantine.String()
 	}
 
 	// WARNING - Monitor closely
 	if isWarning(metrics, config) {
-		return "warning"
+		return state.Warning.String()
 	}
 
-	return "healthy"
+	return state.Healthy.String()
 }
 
 // isBlacklisted checks if IP meets blacklist criteria
@@ -83,6 +295,17 @@ func isBlacklisted(metrics IPHealthCheck, config ReputationConfig) bool {
 		hasReputationRelatedCodes(metrics.ReputationCodes)
 }
 
+// isSpamtrapped checks if IP hit enough spamtraps to be quarantined
+func isSpamtrapped(metrics IPHealthCheck, config ReputationConfig) bool {
+	return config.SpamtrapQuarantineHits > 0 && metrics.SpamtrapHits >= config.SpamtrapQuarantineHits
+}
+
+// reachesExternalScore checks the external score against a threshold; 0
+// disables the check
+func reachesExternalScore(metrics IPHealthCheck, threshold float64) bool {
+	return threshold > 0 && metrics.ExternalScore >= threshold
+}
+
 // isQuarantined checks if IP meets quarantine criteria
 func isQuarantined(metrics IPHealthCheck, config ReputationConfig) bool {
 	// High rejection rate with at least one major provider
@@ -95,6 +318,11 @@ func isQuarantined(metrics IPHealthCheck, config ReputationConfig) bool {
 		return true
 	}
 
+	// Recipients keep reporting the mail as spam
+	if exceedsComplaintRate(metrics, config.QuarantineComplaintRate) {
+		return true
+	}
+
 	return false
 }
 
@@ -106,7 +334,7 @@ func isWarning(metrics IPHealthCheck, config ReputationConfig) bool {
 	}
 
 	// Many 4xx codes plus some 5xx
-	if metrics.ThrottleCount > 10 && metrics.TotalRejected > 0 {
+	if metrics.ThrottleCount > warningThrottleCount && metrics.TotalRejected > 0 {
 		return true
 	}
 
@@ -115,64 +343,69 @@ func isWarning(metrics IPHealthCheck, config ReputationConfig) bool {
 		return true
 	}
 
+	// Outgoing authentication keeps failing
+	if metrics.AuthFailures >= warningAuthFailures {
+		return true
+	}
+
+	// Complaint rate above the warning threshold
+	if exceedsComplaintRate(metrics, config.WarningComplaintRate) {
+		return true
+	}
+
 	return false
 }
 
-// hasReputationRelatedCodes checks if reputation-related codes are present
-func hasReputationRelatedCodes(codes map[string]int) bool {
-	// PRIMARY reputation codes (most severe)
-	primaryCodes := []string{
+// exceedsComplaintRate checks the complaint rate against a threshold; a
+// handful of complaints is noise whatever the rate, and 0 disables the check
+func exceedsComplaintRate(metrics IPHealthCheck, threshold float64) bool {
+	return threshold > 0 && metrics.Complaints >= minComplaints && metrics.ComplaintRate > threshold
+}
+
+// reputationCodeGroup is a family of enhanced status codes and how many of
+// any one of them indicate a reputation problem
+type reputationCodeGroup struct {
+	Category  string
+	Codes     []string
+	Threshold int
+}
+
+// reputationCodeGroups are checked by hasReputationRelatedCodes, most severe
+// first
+var reputationCodeGroups = []reputationCodeGroup{
+	// PRIMARY reputation codes (most critical - lower threshold)
+	{"primary", []string{
 		"5.7.1",   // IP/domain reputation blocked
 		"5.7.606", // Access denied (Microsoft-specific)
 		"5.7.512", // Message content rejected (spam)
-	}
-
+	}, 2},
 	// AUTHENTICATION codes (configuration issues that affect reputation)
-	authCodes := []string{
+	{"authentication", []string{
 		"5.7.23", // SPF validation failed
 		"5.7.26", // Sender authentication required (ARC/DKIM)
-	}
-
+	}, 3},
 	// INFRASTRUCTURE codes (DNS/PTR issues)
-	infraCodes := []string{
+	{"infrastructure", []string{
 		"5.7.25", // PTR record required
 		"5.7.27", // Sender address has null MX
 		"5.7.7",  // Domain name has no MX/A/AAAA record
 		"5.1.8",  // Bad sender's system address
-	}
-
-	// POLICY codes (temporary rejections)
-	policyCodes := []string{
+	}, 3},
+	// POLICY codes (higher threshold as these can be temporary)
+	{"policy", []string{
 		"4.7.0",   // Temporary rate limit/greylisting
 		"4.7.1",   // Temporary policy rejection
 		"5.7.510", // Recipient address rejected (policy)
-	}
-
-	// Check primary codes (most critical - lower threshold)
-	for _, code := range primaryCodes {
-		if count, exists := codes[code]; exists && count >= 2 {
-			return true
-		}
-	}
-
-	// Check authentication codes
-	for _, code := range authCodes {
-		if count, exists := codes[code]; exists && count >= 3 {
-			return true
-		}
-	}
-
-	// Check infrastructure codes
-	for _, code := range infraCodes {
-		if count, exists := codes[code]; exists && count >= 3 {
-			return true
-		}
-	}
+	}, 5},
+}
 
-	// Check policy codes (higher threshold as these can be temporary)
-	for _, code := range policyCodes {
-		if count, exists := codes[code]; exists && count >= 5 {
-			return true
+// hasReputationRelatedCodes checks if reputation-related codes are present
+func hasReputationRelatedCodes(codes map[string]int) bool {
+	for _, group := range reputationCodeGroups {
+		for _, code := range group.Codes {
+			if count, exists := codes[code]; exists && count >= group.Threshold {
+				return true
+			}
 		}
 	}
 
@@ -181,38 +414,80 @@ func hasReputationRelatedCodes(codes map[string]int) bool {
 
 // hasRepeated571Patterns checks for repeated 5.7.1 codes
 func hasRepeated571Patterns(codes map[string]int) bool {
-	if count, exists := codes["5.7.1"]; exists && count >= 5 {
+	if count, exists := codes["5.7.1"]; exists && count >= repeated571Count {
 		return true
 	}
 	return false
 }
 
-// CalculateIPHealthCheck computes health metrics from SMTP failures
-func CalculateIPHealthCheck(ip string, windowMinutes int, totalSent int) (*IPHealthCheck, error) {
-	windowStart := time.Now().Add(-time.Duration(windowMinutes) * time.Minute)
+// CalculateIPHealthCheck computes health metrics from SMTP failures. With a
+// positive halfLifeMinutes a failure's weight in the rejection ratio halves
+// every half-life.
+func CalculateIPHealthCheck(ip string, windowMinutes int, totalSent int, halfLifeMinutes int) (*IPHealthCheck, error) {
+	return CalculateIPHealthCheckForInstance(ip, "", windowMinutes, totalSent, halfLifeMinutes)
+}
+
+// CalculateIPHealthCheckForInstance computes health metrics from the failures
+// one MTA instance reported for an IP. An empty instance covers all instances.
+func CalculateIPHealthCheckForInstance(ip, instance string, windowMinutes int, totalSent int, halfLifeMinutes int) (*IPHealthCheck, error) {
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(windowMinutes) * time.Minute)
 
 	// Get all failures in the window
-	failures, err := database.GetSMTPFailuresByIP(ip, windowStart)
+	failures, err := database.GetSMTPFailuresByIPAndInstance(ip, instance, windowStart)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SMTP failures: %w", err)
 	}
 
-	health := &IPHealthCheck{
+	health := buildHealthCheck(ip, instance, windowMinutes, totalSent, halfLifeMinutes, failures, now)
+
+	health.AuthFailures, err = database.CountSMTPAuthFailures(ip, instance, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count SMTP auth failures: %w", err)
+	}
+	return health, nil
+}
+
+// newHealthCheck returns the health of a window without failures
+func newHealthCheck(ip, instance string, windowMinutes, totalSent int) *IPHealthCheck {
+	return &IPHealthCheck{
 		IP:                    ip,
 		WindowMinutes:         windowMinutes,
 		TotalSent:             totalSent,
-		TotalRejected:         len(failures),
+		GreylistedDomains:     make(map[string]int),
+		TLSRequiredDomains:    make(map[string]int),
 		RejectionRatio:        0.0,
 		UniqueDomainsRejected: 0,
 		MajorProviders:        []string{},
 		ReputationCodes:       make(map[string]int),
 		ThrottleCount:         0,
 		DomainCounts:          make(map[string]int),
+		InstanceCounts:        make(map[string]int),
+		Instance:              instance,
 	}
+}
+
+// buildHealthCheck computes health metrics from the failures of a window
+func buildHealthCheck(ip, instance string, windowMinutes int, totalSent int, halfLifeMinutes int, failures []database.SMTPFailure, now time.Time) *IPHealthCheck {
+	// Greylisting deferrals say nothing about reputation
+	failures, greylisted := splitGreylisted(failures)
+
+	// Neither do refusals to deliver without the TLS a destination requires
+	failures, tlsRequired := splitTLSRequired(failures)
+
+	// Retries of one message are one rejection; every attempt stays stored
+	failures, retries := dedupeRetries(failures)
+
+	health := newHealthCheck(ip, instance, windowMinutes, totalSent)
+	health.TotalRejected = len(failures)
+	health.RetriesDeduplicated = retries
+	health.Greylisted = len(greylisted)
+	health.TLSRequired = len(tlsRequired)
 
-	// Calculate rejection ratio
+	// Calculate rejection ratio, letting old failures fade
+	health.WeightedRejected = weightFailures(failures, now, halfLifeMinutes)
 	if totalSent > 0 {
-		health.RejectionRatio = float64(health.TotalRejected) / float64(totalSent)
+		health.RejectionRatio = health.WeightedRejected / float64(totalSent)
 	}
 
 	// Analyze failures
@@ -220,9 +495,15 @@ func CalculateIPHealthCheck(ip string, windowMinutes int, totalSent int) (*IPHea
 	majorProviderSet := make(map[string]bool)
 
 	for _, failure := range failures {
-		// Count domains
-		domainSet[failure.RecipientDomain] = true
-		health.DomainCounts[failure.RecipientDomain]++
+		// Count domains at the organizational level
+		domain := failure.OrgDomain()
+		domainSet[domain] = true
+		health.DomainCounts[domain]++
+
+		// Count MTA instances
+		if failure.MTAInstance != "" {
+			health.InstanceCounts[failure.MTAInstance]++
+		}
 
 		// Count enhanced codes
 		if failure.EnhancedCode != "" {
@@ -235,8 +516,8 @@ func CalculateIPHealthCheck(ip string, windowMinutes int, totalSent int) (*IPHea
 		}
 
 		// Track major providers
-		if database.IsMajorProvider(failure.RecipientDomain) {
-			majorProviderSet[failure.RecipientDomain] = true
+		if database.IsMajorProvider(domain) {
+			majorProviderSet[domain] = true
 		}
 	}
 
@@ -248,46 +529,70 @@ func CalculateIPHealthCheck(ip string, windowMinutes int, totalSent int) (*IPHea
 		health.MajorProviders = append(health.MajorProviders, provider)
 	}
 
-	return health, nil
+	for _, failure := range greylisted {
+		health.GreylistedDomains[failure.OrgDomain()]++
+	}
+	for _, failure := range tlsRequired {
+		health.TLSRequiredDomains[failure.OrgDomain()]++
+	}
+
+	return health
+}
+
+// weightFailures sums failure weights of 0.5^(age/half-life). A half-life of
+// 0 gives every failure weight 1.
+func weightFailures(failures []database.SMTPFailure, now time.Time, halfLifeMinutes int) float64 {
+	if halfLifeMinutes <= 0 {
+		return float64(len(failures))
+	}
+	total := 0.0
+	for _, failure := range failures {
+		total += decayWeight(now.Sub(failure.Timestamp), halfLifeMinutes)
+	}
+	return total
+}
+
+// decayWeight is the weight 0.5^(age/half-life) of a failure of the given
+// age, or 1 with a half-life of 0
+func decayWeight(age time.Duration, halfLifeMinutes int) float64 {
+	if halfLifeMinutes <= 0 {
+		return 1
+	}
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/(float64(halfLifeMinutes)*float64(time.Minute)))
 }
 
 // GetStatusSummary provides a human-readable summary of the IP status determination
 func GetStatusSummary(status string, health IPHealthCheck) string {
-	switch status {
-	case "blacklisted":
-		return fmt.Sprintf(
-			"CRITICAL: IP %s is BLACKLISTED. Rejection ratio: %.2f%%, %d unique domains rejected, %d major providers rejecting. Immediate action required.",
+	return GetLocalizedStatusSummary(i18n.English, status, health)
+}
+
+// GetLocalizedStatusSummary is GetStatusSummary in language
+func GetLocalizedStatusSummary(language, status string, health IPHealthCheck) string {
+	ratio := i18n.FormatPercent(language, health.RejectionRatio)
+	switch state.Status(status) {
+	case state.Blacklisted:
+		return i18n.Translate(language, "status_summary.blacklisted",
 			health.IP,
-			health.RejectionRatio*100,
+			ratio,
 			health.UniqueDomainsRejected,
 			len(health.MajorProviders),
 		)
-	case "quarantine":
-		return fmt.Sprintf(
-			"WARNING: IP %s is QUARANTINED. Rejection ratio: %.2f%%, %d unique domains rejected. High risk, needs investigation.",
-			health.IP,
-			health.RejectionRatio*100,
-			health.UniqueDomainsRejected,
-		)
-	case "warning":
-		return fmt.Sprintf(
-			"CAUTION: IP %s has WARNING status. Rejection ratio: %.2f%%. Monitor closely.",
-			health.IP,
-			health.RejectionRatio*100,
-		)
+	case state.Quarantine:
+		return i18n.Translate(language, "status_summary.quarantine", health.IP, ratio, health.UniqueDomainsRejected)
+	case state.Warning:
+		return i18n.Translate(language, "status_summary.warning", health.IP, ratio)
 	default:
-		return fmt.Sprintf(
-			"OK: IP %s is HEALTHY. Rejection ratio: %.2f%%.",
-			health.IP,
-			health.RejectionRatio*100,
-		)
+		return i18n.Translate(language, "status_summary.healthy", health.IP, ratio)
 	}
 }
 
 // GetRecommendedActions returns recommended actions based on status
 func GetRecommendedActions(status string) []string {
-	switch status {
-	case "blacklisted":
+	switch state.Status(status) {
+	case state.Blacklisted:
 		return []string{
 			"immediate_quarantine",
 			"swap_to_backup_ip",
@@ -295,14 +600,14 @@ func GetRecommendedActions(status string) []string {
 			"alert_ops_critical",
 			"investigate_root_cause",
 		}
-	case "quarantine":
+	case state.Quarantine:
 		return []string{
 			"reduce_traffic_50_percent",
 			"run_dnsbl_checks",
 			"alert_ops_warning",
 			"monitor_closely",
 		}
-	case "warning":
+	case state.Warning:
 		return []string{
 			"monitor_closely",
 			"reduce_send_rate",
@@ -313,6 +618,25 @@ func GetRecommendedActions(status string) []string {
 	}
 }
 
+// Recommendation is a recommended action with its text in the response
+// language and, from the catalog, its runbook link
+type Recommendation struct {
+	Action string `json:"action"`
+	Text   string `json:"text"`
+	Link   string `json:"link,omitempty"`
+}
+
+// GetLocalizedRecommendations returns GetRecommendedActions with their texts
+// in language
+func GetLocalizedRecommendations(language, status string) []Recommendation {
+	actions := GetRecommendedActions(status)
+	recommendations := make([]Recommendation, len(actions))
+	for i, action := range actions {
+		recommendations[i] = Recommendation{Action: action, Text: i18n.Translate(language, "recommendation."+action)}
+	}
+	return recommendations
+}
+
 // IsReputationIssue determines if the failures are IP reputation related
 func IsReputationIssue(health IPHealthCheck) bool {
 	// Check for reputation-specific error codes
@@ -336,6 +660,32 @@ func IsReputationIssue(health IPHealthCheck) bool {
 
 // GetIssueType categorizes the type of issue based on error codes
 func GetIssueType(health IPHealthCheck) string {
+	// SPAMTRAP hits - list hygiene or a compromised sender
+	if health.SpamtrapHits > 0 {
+		return "spamtrap_hit"
+	}
+
+	// COMPLAINTS - recipients reporting the mail as spam
+	if exceedsComplaintRate(health, 0.001) { // 0.1%
+		return "recipient_complaints"
+	}
+
+	// EXTERNAL FEEDS - threat intel or partner blocklists list the IP
+	if health.ExternalScore >= externalIssueScore {
+		return "external_feed_listing"
+	}
+
+	// TLS NEGOTIATION failures rivalling the rejections - a destination's
+	// forced TLS (MTA-STS, DANE) rather than reputation
+	if health.TLSFailedSessions > tlsIssueSessions && health.TLSFailedSessions*2 >= health.TotalRejected {
+		return "tls_negotiation_failure"
+	}
+
+	// TLS REQUIREMENTS - destinations refusing mail our TLS does not meet
+	if health.TLSRequired > tlsIssueSessions && health.TLSRequired >= health.TotalRejected {
+		return "tls_requirement_unmet"
+	}
+
 	// SPAM DETECTION - Most severe
 	spamCodes := []string{"5.7.512", "5.7.606"}
 	for _, code := range spamCodes {
@@ -361,6 +711,11 @@ func GetIssueType(health IPHealthCheck) string {
 		return "authentication_failure"
 	}
 
+	// OUTBOUND AUTHENTICATION failures (credentials for remote hosts)
+	if health.AuthFailures > 5 {
+		return "outbound_auth_failure"
+	}
+
 	// INFRASTRUCTURE issues (DNS/PTR/MX)
 	infraCodes := []string{"5.7.25", "5.7.27", "5.7.7", "5.1.8"}
 	totalInfraFailures := 0
@@ -0,0 +1,120 @@
+package reputation
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestWeightFailures(t *testing.T) {
+	now := time.Now()
+	failures := []database.SMTPFailure{
+		{Timestamp: now},
+		{Timestamp: now.Add(-10 * time.Minute)},
+		{Timestamp: now.Add(-20 * time.Minute)},
+		{Timestamp: now.Add(time.Minute)}, // clock skew counts as fresh
+	}
+
+	tests := []struct {
+		name     string
+		halfLife int
+		want     float64
+	}{
+		{"disabled", 0, 4},
+		{"negative disables", -5, 4},
+		{"ten minute half-life", 10, 1 + 0.5 + 0.25 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weightFailures(failures, now, tt.halfLife); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("weightFailures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := weightFailures(nil, now, 10); got != 0 {
+		t.Errorf("weightFailures(nil) = %v, want 0", got)
+	}
+}
+
+func TestDecayLetsOldBurstRecover(t *testing.T) {
+	now := time.Now()
+	burst := make([]database.SMTPFailure, 6)
+	for i := range burst {
+		burst[i].Timestamp = now.Add(-14 * time.Minute)
+	}
+
+	config := DefaultReputationConfig()
+	config.DecayHalfLifeMinutes = 5
+	health := IPHealthCheck{TotalSent: 100, TotalRejected: len(burst), MajorProviders: []string{"gmail.com"}}
+
+	health.RejectionRatio = weightFailures(burst, now, 0) / float64(health.TotalSent)
+	if got := DetermineIPStatus(health, config); got != "quarantine" {
+		t.Fatalf("Expected an unweighted burst to quarantine, got %q", got)
+	}
+
+	health.RejectionRatio = weightFailures(burst, now, config.DecayHalfLifeMinutes) / float64(health.TotalSent)
+	if got := DetermineIPStatus(health, config); got != "healthy" {
+		t.Errorf("Expected the aged burst to have decayed below the thresholds, got %q (ratio %.4f)", got, health.RejectionRatio)
+	}
+}
+
+func TestBuildHealthCheckRollsUpOrganizationalDomains(t *testing.T) {
+	now := time.Now()
+	failures := []database.SMTPFailure{
+		{RecipientEmail: "a@mx1.mail.yahoo.co.jp", RecipientDomain: "mx1.mail.yahoo.co.jp", EnhancedCode: "5.7.1", Timestamp: now},
+		{RecipientEmail: "b@yahoo.co.jp", RecipientDomain: "yahoo.co.jp", EnhancedCode: "5.7.1", Timestamp: now},
+		{RecipientEmail: "c@eu.mail.yahoo.com", RecipientDomain: "eu.mail.yahoo.com", EnhancedCode: "5.7.1", Timestamp: now},
+	}
+
+	health := buildHealthCheck("192.0.2.1", "", 15, 100, 0, failures, now)
+	if health.UniqueDomainsRejected != 2 || health.DomainCounts["yahoo.co.jp"] != 2 || health.DomainCounts["yahoo.com"] != 1 {
+		t.Errorf("Expected rejections rolled up to organizational domains, got %v", health.DomainCounts)
+	}
+	if len(health.MajorProviders) != 1 || health.MajorProviders[0] != "yahoo.com" {
+		t.Errorf("Unexpected major providers: %v", health.MajorProviders)
+	}
+}
+
+func TestTLSFailuresAreDiagnosticOnly(t *testing.T) {
+	config := DefaultReputationConfig()
+	health := IPHealthCheck{
+		TotalSent: 1000, TotalRejected: 30, RejectionRatio: 0.03,
+		ReputationCodes: map[string]int{"4.7.5": 30}, TLSFailedSessions: 40,
+	}
+
+	if got := GetIssueType(health); got != "tls_negotiation_failure" {
+		t.Errorf("Expected TLS failures to explain the rejections, got %q", got)
+	}
+	without := health
+	without.TLSFailedSessions = 0
+	if got, want := DetermineIPStatus(health, config), DetermineIPStatus(without, config); got != want {
+		t.Errorf("Expected TLS failures to leave the status alone, got %q want %q", got, want)
+	}
+
+	// A few TLS failures next to many rejections are not the explanation
+	health.TLSFailedSessions, health.TotalRejected = 6, 100
+	if got := GetIssueType(health); got == "tls_negotiation_failure" {
+		t.Errorf("Expected a handful of TLS failures not to decide the issue type")
+	}
+}
+
+func TestLocalizedStatusSummary(t *testing.T) {
+	health := IPHealthCheck{IP: "192.0.2.1", RejectionRatio: 0.4567, UniqueDomainsRejected: 12, MajorProviders: []string{"gmail.com", "outlook.com"}}
+
+	english := "CRITICAL: IP 192.0.2.1 is BLACKLISTED. Rejection ratio: 45.67%, 12 unique domains rejected, 2 major providers rejecting. Immediate action required."
+	if got := GetStatusSummary("blacklisted", health); got != english {
+		t.Errorf("Expected the English summary unchanged, got %q", got)
+	}
+	french := "CRITIQUE : l'IP 192.0.2.1 est sur LISTE NOIRE. Taux de rejet : 45,67 %, 12 domaines rejettent, 2 grands fournisseurs rejettent. Action immédiate requise."
+	if got := GetLocalizedStatusSummary("fr", "blacklisted", health); got != french {
+		t.Errorf("Unexpected French summary: %q", got)
+	}
+
+	recommendations := GetLocalizedRecommendations("de", "warning")
+	if len(recommendations) != 3 || recommendations[0].Action != "monitor_closely" || recommendations[0].Text != "IP genau beobachten" {
+		t.Errorf("Unexpected German recommendations: %+v", recommendations)
+	}
+}
@@ -2,6 +2,7 @@ package reputation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -9,7 +10,8 @@ import (
 	"time"
 
 	"golang-backend-service/internal/database"
-	"golang-backend-service/internal/logger"
+	"golang-backend-service/internal/dependencies"
+	"golang-backend-service/internal/resolver"
 
 	"github.com/sirupsen/logrus"
 )
@@ -26,6 +28,29 @@ var MajorDNSBLs = []string{
 	"dnsbl-1.uceprotect.net", // UCEProtect Level 1
 }
 
+// DNSBLService runs DNSBL checks and records the results
+type DNSBLService struct {
+	resolver resolver.Resolver
+	logger   *logrus.Logger
+}
+
+// NewDNSBLService creates a DNSBL service using the system DNS
+func NewDNSBLService(logger *logrus.Logger) *DNSBLService {
+	return NewDNSBLServiceWithResolver(logger, nil)
+}
+
+// NewDNSBLServiceWithResolver creates a DNSBL service using the given
+// resolver. A nil resolver falls back to the system DNS.
+func NewDNSBLServiceWithResolver(logger *logrus.Logger, r resolver.Resolver) *DNSBLService {
+	if r == nil {
+		r = &net.Resolver{PreferGo: true}
+	}
+	return &DNSBLService{
+		resolver: r,
+		logger:   logger,
+	}
+}
+
 // DNSBLResult represents the result of a DNSBL check
 type DNSBLResult struct {
 	IP              string
@@ -37,10 +62,17 @@ type DNSBLResult struct {
 }
 
 // CheckDNSBL performs a comprehensive DNSBL check for an IP address
-func CheckDNSBL(ip string, timeoutSeconds int) (*DNSBLResult, error) {
+func (d *DNSBLService) CheckDNSBL(ip string, timeoutSeconds int) (*DNSBLResult, error) {
+	return d.CheckDNSBLContext(context.Background(), ip, timeoutSeconds)
+}
+
+// CheckDNSBLContext is CheckDNSBL, stopped when ctx is done. A cancelled
+// check returns ctx's error and stores nothing, since unanswered lookups
+// would read as not listed.
+func (d *DNSBLService) CheckDNSBLContext(ctx context.Context, ip string, timeoutSeconds int) (*DNSBLResult, error) {
 	start := time.Now()
 
-	logger.WithFields(logrus.Fields{
+	d.logger.WithFields(logrus.Fields{
 		"action": "dnsbl_check_start",
 		"ip":     ip,
 	}).Info("Starting DNSBL check")
@@ -55,7 +87,10 @@ func CheckDNSBL(ip string, timeoutSeconds int) (*DNSBLResult, error) {
 	reversedIP := reverseIP(ip)
 
 	// Check all DNSBLs concurrently
-	listings := checkAllDNSBLs(reversedIP, timeoutSeconds)
+	listings := d.checkAllDNSBLs(ctx, reversedIP, timeoutSeconds)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("DNSBL check cancelled: %w", err)
+	}
 
 	duration := time.Since(start)
 	result := &DNSBLResult{
@@ -80,14 +115,14 @@ func CheckDNSBL(ip string, timeoutSeconds int) (*DNSBLResult, error) {
 	}
 
 	if err := database.InsertDNSBLCheck(dbCheck); err != nil {
-		logger.WithFields(logrus.Fields{
+		d.logger.WithFields(logrus.Fields{
 			"action": "dnsbl_check_store_failed",
 			"ip":     ip,
 			"error":  err.Error(),
 		}).Error("Failed to store DNSBL check result")
 	}
 
-	logger.WithFields(logrus.Fields{
+	d.logger.WithFields(logrus.Fields{
 		"action":      "dnsbl_check_complete",
 		"ip":          ip,
 		"listed":      result.Listed,
@@ -99,12 +134,12 @@ func CheckDNSBL(ip string, timeoutSeconds int) (*DNSBLResult, error) {
 }
 
 // checkAllDNSBLs checks an IP against all DNSBLs concurrently
-func checkAllDNSBLs(reversedIP string, timeoutSeconds int) []string {
+func (d *DNSBLService) checkAllDNSBLs(parent context.Context, reversedIP string, timeoutSeconds int) []string {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	listings := []string{}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutSeconds)*time.Second)
 	defer cancel()
 
 	for _, dnsbl := range MajorDNSBLs {
@@ -113,21 +148,20 @@ func checkAllDNSBLs(reversedIP string, timeoutSeconds int) []string {
 			defer wg.Done()
 
 			query := fmt.Sprintf("%s.%s", reversedIP, dnsbl)
-			
-			// Create resolver with timeout
-			resolver := &net.Resolver{
-				PreferGo: true,
+
+			addrs, err := d.resolver.LookupHost(ctx, query)
+			if parent.Err() == nil {
+				// The caller giving up says nothing about the resolver
+				dependencies.Record(dependencies.DNS, resolverFailure(err))
 			}
 
-			_, err := resolver.LookupHost(ctx, query)
-			
-			// If no error, the IP is listed
-			if err == nil {
+			// A 127.0.0.0/8 answer means the IP is listed
+			if err == nil && resolver.IsListingResponse(addrs) {
 				mu.Lock()
 				listings = append(listings, dnsbl)
 				mu.Unlock()
 
-				logger.WithFields(logrus.Fields{
+				d.logger.WithFields(logrus.Fields{
 					"action": "dnsbl_listing_found",
 					"dnsbl":  dnsbl,
 					"query":  query,
@@ -141,6 +175,23 @@ func checkAllDNSBLs(reversedIP string, timeoutSeconds int) []string {
 	return listings
 }
 
+// resolverFailure returns err unless it is an answer from the resolver;
+// NXDOMAIN is the usual answer for IPs that are not listed
+func resolverFailure(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
+
+// Probe checks that the resolver answers DNSBL queries, using the
+// 127.0.0.2 test entry every DNSBL lists
+func (d *DNSBLService) Probe(ctx context.Context) error {
+	_, err := d.resolver.LookupHost(ctx, reverseIP("127.0.0.2")+"."+MajorDNSBLs[0])
+	return resolverFailure(err)
+}
+
 // reverseIP reverses an IP address for DNSBL lookup
 func reverseIP(ip string) string {
 	parts := strings.Split(ip, ".")
@@ -153,9 +204,9 @@ func reverseIP(ip string) string {
 }
 
 // CheckDNSBLAsync performs DNSBL check asynchronously
-func CheckDNSBLAsync(ip string, timeoutSeconds int, callback func(*DNSBLResult, error)) {
+func (d *DNSBLService) CheckDNSBLAsync(ip string, timeoutSeconds int, callback func(*DNSBLResult, error)) {
 	go func() {
-		result, err := CheckDNSBL(ip, timeoutSeconds)
+		result, err := d.CheckDNSBL(ip, timeoutSeconds)
 		callback(result, err)
 	}()
 }
@@ -221,7 +272,7 @@ func FormatDNSBLReport(result *DNSBLResult) string {
 }
 
 // BatchCheckDNSBL checks multiple IPs concurrently
-func BatchCheckDNSBL(ips []string, timeoutSeconds int) map[string]*DNSBLResult {
+func (d *DNSBLService) BatchCheckDNSBL(ips []string, timeoutSeconds int) map[string]*DNSBLResult {
 	results := make(map[string]*DNSBLResult)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -231,9 +282,9 @@ func BatchCheckDNSBL(ips []string, timeoutSeconds int) map[string]*DNSBLResult {
 		go func(ip string) {
 			defer wg.Done()
 			
-			result, err := CheckDNSBL(ip, timeoutSeconds)
+			result, err := d.CheckDNSBL(ip, timeoutSeconds)
 			if err != nil {
-				logger.WithFields(logrus.Fields{
+				d.logger.WithFields(logrus.Fields{
 					"action": "batch_dnsbl_check_error",
 					"ip":     ip,
 					"error":  err.Error(),
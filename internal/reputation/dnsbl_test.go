@@ -0,0 +1,142 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/resolver"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestCheckAllDNSBLs tests DNSBL lookups against a fake resolver
+func TestCheckAllDNSBLs(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name     string
+		setup    func(f *resolver.Fake)
+		timeout  int
+		expected []string
+	}{
+		{
+			name:     "Not listed",
+			setup:    func(f *resolver.Fake) {},
+			timeout:  1,
+			expected: []string{},
+		},
+		{
+			name: "Listed on two DNSBLs",
+			setup: func(f *resolver.Fake) {
+				f.Records["4.3.2.1.zen.spamhaus.org"] = []string{"127.0.0.4"}
+				f.Records["4.3.2.1.bl.spamcop.net"] = []string{"127.0.0.2"}
+			},
+			timeout:  1,
+			expected: []string{"bl.spamcop.net", "zen.spamhaus.org"},
+		},
+		{
+			name: "Wildcard answers are ignored",
+			setup: func(f *resolver.Fake) {
+				f.Wildcard = []string{"203.0.113.1"}
+			},
+			timeout:  1,
+			expected: []string{},
+		},
+		{
+			name: "Timeout is not a listing",
+			setup: func(f *resolver.Fake) {
+				f.Records["4.3.2.1.zen.spamhaus.org"] = []string{"127.0.0.2"}
+				f.Delay = 3 * time.Second
+			},
+			timeout:  1,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resolver.NewFake()
+			tt.setup(fake)
+			service := NewDNSBLServiceWithResolver(log, fake)
+
+			listings := service.checkAllDNSBLs(context.Background(), reverseIP("1.2.3.4"), tt.timeout)
+			sort.Strings(listings)
+
+			if len(listings) != len(tt.expected) {
+				t.Fatalf("Expected listings %v, got %v", tt.expected, listings)
+			}
+			for i := range tt.expected {
+				if listings[i] != tt.expected[i] {
+					t.Errorf("Expected %s, got %s", tt.expected[i], listings[i])
+				}
+			}
+		})
+	}
+}
+
+// TestDNSBLProbe tests that NXDOMAIN counts as a resolver answer
+func TestDNSBLProbe(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	fake := resolver.NewFake()
+	svc := NewDNSBLServiceWithResolver(log, fake)
+	if err := svc.Probe(context.Background()); err != nil {
+		t.Errorf("Expected NXDOMAIN to pass the probe, got %v", err)
+	}
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != "2.0.0.127."+MajorDNSBLs[0] {
+		t.Errorf("Unexpected probe query: %v", queries)
+	}
+
+	fake.Errors["2.0.0.127."+MajorDNSBLs[0]] = &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+	if err := svc.Probe(context.Background()); err == nil {
+		t.Error("Expected a resolver failure to fail the probe")
+	}
+}
+
+// TestCheckDNSBLContextCancelled tests that a check abandoned by its caller
+// returns the context error instead of a clean result
+func TestCheckDNSBLContextCancelled(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	fake := resolver.NewFake()
+	fake.Delay = time.Second
+	svc := NewDNSBLServiceWithResolver(log, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result, err := svc.CheckDNSBLContext(ctx, "1.2.3.4", 5)
+	if !errors.Is(err, context.DeadlineExceeded) || result != nil {
+		t.Errorf("Expected deadline exceeded without a result, got %+v %v", result, err)
+	}
+}
+
+// FuzzReverseIP tests that reversing an address twice gives it back and that
+// IPv4 addresses reverse to IPv4 addresses
+func FuzzReverseIP(f *testing.F) {
+	for _, seed := range []string{"1.2.3.4", "127.0.0.2", "2001:db8::1", "1.2.3", "1.2.3.4.5", "...", "", "a.b.c.d"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, ip string) {
+		reversed := reverseIP(ip)
+		if strings.Count(ip, ".") != 3 {
+			if reversed != ip {
+				t.Errorf("reverseIP(%q) = %q, want it unchanged", ip, reversed)
+			}
+			return
+		}
+		if back := reverseIP(reversed); back != ip {
+			t.Errorf("reverseIP(reverseIP(%q)) = %q", ip, back)
+		}
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil && net.ParseIP(reversed) == nil {
+			t.Errorf("reverseIP(%q) = %q, not an IPv4 address", ip, reversed)
+		}
+	})
+}
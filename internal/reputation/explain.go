@@ -0,0 +1,293 @@
+package reputation
+
+import (
+	"sort"
+	"strings"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+)
+
+// Condition is one comparison inside a rule, with the configured threshold
+// next to the observed value
+type Condition struct {
+	Name      string  `json:"name"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+	Observed  float64 `json:"observed"`
+	Met       bool    `json:"met"`
+}
+
+// RuleResult is the outcome of one decision rule. A rule fires when all of
+// its conditions are met.
+type RuleResult struct {
+	Rule        string      `json:"rule"`
+	Status      string      `json:"status"`
+	Description string      `json:"description"`
+	Fired       bool        `json:"fired"`
+	Conditions  []Condition `json:"conditions"`
+}
+
+// CodeCount is an enhanced code counted by hasReputationRelatedCodes
+type CodeCount struct {
+	Code      string `json:"code"`
+	Category  string `json:"category"`
+	Count     int    `json:"count"`
+	Threshold int    `json:"threshold"`
+	Tripped   bool   `json:"tripped"`
+}
+
+// Explanation shows why DetermineIPStatus picks a status
+type Explanation struct {
+	// Status is the status the rules produce for the observed metrics
+	Status string `json:"status"`
+	// DecidingRule is the first rule that fired, in evaluation order
+	DecidingRule string       `json:"deciding_rule"`
+	Rules        []RuleResult `json:"rules"`
+	// ReputationCodes lists the observed codes that count towards the
+	// blacklist rule
+	ReputationCodes []CodeCount `json:"reputation_codes"`
+	// ProviderCounts are rejections per major provider
+	ProviderCounts map[string]int   `json:"provider_counts"`
+	Observed       IPHealthCheck    `json:"observed"`
+	Config         ReputationConfig `json:"config"`
+}
+
+// WithDerivedFields fills fields a hand-written health check may leave out:
+// the weighted rejections and rejection ratio, domain and provider counts from DomainCounts, and the
+// throttle count from 4.x.x codes. Fields that are set are kept.
+func (m IPHealthCheck) WithDerivedFields() IPHealthCheck {
+	if m.WeightedRejected == 0 {
+		m.WeightedRejected = float64(m.TotalRejected)
+	}
+	if m.RejectionRatio == 0 && m.TotalSent > 0 {
+		m.RejectionRatio = m.WeightedRejected / float64(m.TotalSent)
+	}
+	if m.UniqueDomainsRejected == 0 {
+		m.UniqueDomainsRejected = len(m.DomainCounts)
+	}
+	if len(m.MajorProviders) == 0 {
+		m.MajorProviders = []string{}
+		for domain := range m.DomainCounts {
+			if database.IsMajorProvider(domain) {
+				m.MajorProviders = append(m.MajorProviders, domain)
+			}
+		}
+		sort.Strings(m.MajorProviders)
+	}
+	if m.ThrottleCount == 0 {
+		for code, count := range m.ReputationCodes {
+			if strings.HasPrefix(code, "4") {
+				m.ThrottleCount += count
+			}
+		}
+	}
+	return m
+}
+
+func greater(name string, observed, threshold float64) Condition {
+	return Condition{Name: name, Operator: ">", Threshold: threshold, Observed: observed, Met: observed > threshold}
+}
+
+func atLeast(name string, observed, threshold float64) Condition {
+	return Condition{Name: name, Operator: ">=", Threshold: threshold, Observed: observed, Met: observed >= threshold}
+}
+
+// explainCodes lists the observed codes of reputationCodeGroups
+func explainCodes(codes map[string]int) []CodeCount {
+	counts := []CodeCount{}
+	for _, group := range reputationCodeGroups {
+		for _, code := range group.Codes {
+			if count, exists := codes[code]; exists {
+				counts = append(counts, CodeCount{
+					Code:      code,
+					Category:  group.Category,
+					Count:     count,
+					Threshold: group.Threshold,
+					Tripped:   count >= group.Threshold,
+				})
+			}
+		}
+	}
+	return counts
+}
+
+// raisingRules is the number of rules at the end that only raise the status
+// the others decide
+const raisingRules = 3
+
+// ExplainIPStatus evaluates every decision rule, in the order
+// DetermineIPStatus applies them, and reports thresholds against observed
+// values
+func ExplainIPStatus(metrics IPHealthCheck, config ReputationConfig) Explanation {
+	codes := explainCodes(metrics.ReputationCodes)
+	tripped := 0
+	for _, c := range codes {
+		if c.Tripped {
+			tripped++
+		}
+	}
+
+	ratio := metrics.RejectionRatio
+	domains := float64(metrics.UniqueDomainsRejected)
+	providers := float64(len(metrics.MajorProviders))
+
+	rules := []RuleResult{
+		{
+			Rule:        "min_volume",
+			Status:      state.Healthy.String(),
+			Description: "Too little volume to assess; the IP stays healthy",
+			Conditions: []Condition{
+				{Name: "total_sent", Operator: "<", Threshold: float64(config.MinVolumeForAssessment), Observed: float64(metrics.TotalSent),
+					Met: metrics.TotalSent < config.MinVolumeForAssessment},
+			},
+		},
+		{
+			Rule:        "blacklist",
+			Status:      state.Blacklisted.String(),
+			Description: "High rejection ratio across several domains and major providers with reputation codes",
+			Conditions: []Condition{
+				greater("rejection_ratio", ratio, config.BlacklistRejectionRatio),
+				atLeast("unique_domains_rejected", domains, float64(config.BlacklistMinDomains)),
+				atLeast("major_providers_rejecting", providers, float64(config.BlacklistMinMajorProviders)),
+				atLeast("reputation_codes_tripped", float64(tripped), 1),
+			},
+		},
+		{
+			Rule:        "quarantine_major_provider",
+			Status:      state.Quarantine.String(),
+			Description: "Rejection ratio above the quarantine threshold with a major provider rejecting",
+			Conditions: []Condition{
+				greater("rejection_ratio", ratio, config.QuarantineRejectionRatio),
+				atLeast("major_providers_rejecting", providers, 1),
+			},
+		},
+		{
+			Rule:        "quarantine_domain_spread",
+			Status:      state.Quarantine.String(),
+			Description: "Rejection ratio above the blacklist threshold across several domains",
+			Conditions: []Condition{
+				greater("rejection_ratio", ratio, config.BlacklistRejectionRatio),
+				atLeast("unique_domains_rejected", domains, float64(config.QuarantineMinDomains)),
+			},
+		},
+		{
+			Rule:        "quarantine_complaint_rate",
+			Status:      state.Quarantine.String(),
+			Description: "Recipients report the mail as spam above the quarantine complaint rate",
+			Conditions: []Condition{
+				greater("complaint_rate", metrics.ComplaintRate, config.QuarantineComplaintRate),
+				atLeast("complaints", float64(metrics.Complaints), minComplaints),
+				greater("quarantine_complaint_rate", config.QuarantineComplaintRate, 0),
+			},
+		},
+		{
+			Rule:        "warning_rejection_ratio",
+			Status:      state.Warning.String(),
+			Description: "Sustained rejection ratio at the warning threshold",
+			Conditions: []Condition{
+				atLeast("rejection_ratio", ratio, config.WarningRejectionRatio),
+			},
+		},
+		{
+			Rule:        "warning_throttling",
+			Status:      state.Warning.String(),
+			Description: "Many 4xx throttles alongside rejections",
+			Conditions: []Condition{
+				greater("throttle_count", float64(metrics.ThrottleCount), warningThrottleCount),
+				greater("total_rejected", float64(metrics.TotalRejected), 0),
+			},
+		},
+		{
+			Rule:        "warning_repeated_5_7_1",
+			Status:      state.Warning.String(),
+			Description: "Repeated 5.7.1 reputation blocks",
+			Conditions: []Condition{
+				atLeast("code_5.7.1", float64(metrics.ReputationCodes["5.7.1"]), repeated571Count),
+			},
+		},
+		{
+			Rule:        "warning_outbound_auth",
+			Status:      state.Warning.String(),
+			Description: "Repeated outgoing SMTP authentication failures",
+			Conditions: []Condition{
+				atLeast("auth_failures", float64(metrics.AuthFailures), warningAuthFailures),
+			},
+		},
+		{
+			Rule:        "warning_complaint_rate",
+			Status:      state.Warning.String(),
+			Description: "Recipients report the mail as spam above the warning complaint rate",
+			Conditions: []Condition{
+				greater("complaint_rate", metrics.ComplaintRate, config.WarningComplaintRate),
+				atLeast("complaints", float64(metrics.Complaints), minComplaints),
+				greater("warning_complaint_rate", config.WarningComplaintRate, 0),
+			},
+		},
+		{
+			Rule:        "quarantine_spamtrap",
+			Status:      state.Quarantine.String(),
+			Description: "Spamtrap hits quarantine the IP whatever its volume and ratios; a blacklist decision still wins",
+			Conditions: []Condition{
+				atLeast("spamtrap_hits", float64(metrics.SpamtrapHits), float64(config.SpamtrapQuarantineHits)),
+				greater("spamtrap_quarantine_hits", float64(config.SpamtrapQuarantineHits), 0),
+			},
+		},
+		{
+			Rule:        "quarantine_external_score",
+			Status:      state.Quarantine.String(),
+			Description: "Third-party feeds' weighted verdicts quarantine the IP whatever its volume and ratios",
+			Conditions: []Condition{
+				atLeast("external_score", metrics.ExternalScore, config.QuarantineExternalScore),
+				greater("quarantine_external_score", config.QuarantineExternalScore, 0),
+			},
+		},
+		{
+			Rule:        "warning_external_score",
+			Status:      state.Warning.String(),
+			Description: "Third-party feeds' weighted verdicts warn whatever the volume and ratios",
+			Conditions: []Condition{
+				atLeast("external_score", metrics.ExternalScore, config.WarningExternalScore),
+				greater("warning_external_score", config.WarningExternalScore, 0),
+			},
+		},
+	}
+
+	explanation := Explanation{
+		Status:          state.Healthy.String(),
+		DecidingRule:    "none",
+		ReputationCodes: codes,
+		ProviderCounts:  make(map[string]int),
+		Observed:        metrics,
+		Config:          config,
+	}
+	for i := range rules {
+		rules[i].Fired = true
+		for _, c := range rules[i].Conditions {
+			rules[i].Fired = rules[i].Fired && c.Met
+		}
+		if rules[i].Fired && explanation.DecidingRule == "none" {
+			explanation.Status = rules[i].Status
+			explanation.DecidingRule = rules[i].Rule
+		}
+	}
+	// The spamtrap and external feed rules are applied after the others, in
+	// order, and only raise the status
+	for _, raising := range rules[len(rules)-raisingRules:] {
+		if raising.Fired && state.Status(explanation.Status).Severity() < state.Status(raising.Status).Severity() {
+			explanation.Status = raising.Status
+			explanation.DecidingRule = raising.Rule
+		}
+	}
+	explanation.Rules = rules
+
+	for domain, count := range metrics.DomainCounts {
+		if database.IsMajorProvider(domain) {
+			explanation.ProviderCounts[domain] = count
+		}
+	}
+	explanation.Observed.MajorProviders = append([]string{}, metrics.MajorProviders...)
+	sort.Strings(explanation.Observed.MajorProviders)
+
+	return explanation
+}
@@ -0,0 +1,257 @@
+package reputation
+
+import (
+	"testing"
+
+	"golang-backend-service/internal/reputation/state"
+)
+
+func TestExplainIPStatusAgreesWithDecision(t *testing.T) {
+	config := DefaultReputationConfig()
+
+	tests := []struct {
+		name     string
+		metrics  IPHealthCheck
+		wantRule string
+	}{
+		{
+			name:     "low volume",
+			metrics:  IPHealthCheck{TotalSent: 10, TotalRejected: 5, RejectionRatio: 0.5},
+			wantRule: "min_volume",
+		},
+		{
+			name: "blacklisted",
+			metrics: IPHealthCheck{
+				TotalSent: 1000, TotalRejected: 80, RejectionRatio: 0.08,
+				UniqueDomainsRejected: 4, MajorProviders: []string{"yahoo.com", "gmail.com"},
+				ReputationCodes: map[string]int{"5.7.1": 3},
+			},
+			wantRule: "blacklist",
+		},
+		{
+			name: "blacklist without reputation codes falls to quarantine",
+			metrics: IPHealthCheck{
+				TotalSent: 1000, TotalRejected: 80, RejectionRatio: 0.08,
+				UniqueDomainsRejected: 4, MajorProviders: []string{"gmail.com", "yahoo.com"},
+				ReputationCodes: map[string]int{"5.7.1": 1, "5.1.1": 40},
+			},
+			wantRule: "quarantine_major_provider",
+		},
+		{
+			name: "quarantine by domain spread",
+			metrics: IPHealthCheck{
+				TotalSent: 1000, TotalRejected: 60, RejectionRatio: 0.06,
+				UniqueDomainsRejected: 2, MajorProviders: []string{},
+			},
+			wantRule: "quarantine_domain_spread",
+		},
+		{
+			name:     "warning by ratio",
+			metrics:  IPHealthCheck{TotalSent: 1000, TotalRejected: 25, RejectionRatio: 0.025},
+			wantRule: "warning_rejection_ratio",
+		},
+		{
+			name:     "warning by throttling",
+			metrics:  IPHealthCheck{TotalSent: 1000, TotalRejected: 12, RejectionRatio: 0.012, ThrottleCount: 11},
+			wantRule: "warning_throttling",
+		},
+		{
+			name:     "warning by outbound auth failures",
+			metrics:  IPHealthCheck{TotalSent: 1000, AuthFailures: 10},
+			wantRule: "warning_outbound_auth",
+		},
+		{
+			name:     "quarantine by complaint rate",
+			metrics:  IPHealthCheck{TotalSent: 1000, Complaints: 4, ComplaintRate: 0.004},
+			wantRule: "quarantine_complaint_rate",
+		},
+		{
+			name:     "warning by complaint rate",
+			metrics:  IPHealthCheck{TotalSent: 1000, Complaints: 3, ComplaintRate: 0.002},
+			wantRule: "warning_complaint_rate",
+		},
+		{
+			name:     "too few complaints to count",
+			metrics:  IPHealthCheck{TotalSent: 100, Complaints: 2, ComplaintRate: 0.02},
+			wantRule: "none",
+		},
+		{
+			name:     "spamtrap hit at low volume",
+			metrics:  IPHealthCheck{TotalSent: 10, SpamtrapHits: 1},
+			wantRule: "quarantine_spamtrap",
+		},
+		{
+			name:     "spamtrap hit raises a warning",
+			metrics:  IPHealthCheck{TotalSent: 1000, TotalRejected: 25, RejectionRatio: 0.025, SpamtrapHits: 2},
+			wantRule: "quarantine_spamtrap",
+		},
+		{
+			name:     "quarantine by external score",
+			metrics:  IPHealthCheck{TotalSent: 10, ExternalScore: 2},
+			wantRule: "quarantine_external_score",
+		},
+		{
+			name:     "warning by external score",
+			metrics:  IPHealthCheck{TotalSent: 1000, TotalRejected: 5, RejectionRatio: 0.005, ExternalScore: 1},
+			wantRule: "warning_external_score",
+		},
+		{
+			name: "blacklist wins over a spamtrap hit",
+			metrics: IPHealthCheck{
+				TotalSent: 1000, TotalRejected: 80, RejectionRatio: 0.08,
+				UniqueDomainsRejected: 4, MajorProviders: []string{"yahoo.com", "gmail.com"},
+				ReputationCodes: map[string]int{"5.7.1": 3}, SpamtrapHits: 1,
+			},
+			wantRule: "blacklist",
+		},
+		{
+			name:     "healthy",
+			metrics:  IPHealthCheck{TotalSent: 1000, TotalRejected: 5, RejectionRatio: 0.005},
+			wantRule: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation := ExplainIPStatus(tt.metrics, config)
+			if want := DetermineIPStatus(tt.metrics, config); explanation.Status != want {
+				t.Errorf("Status = %s, DetermineIPStatus = %s", explanation.Status, want)
+			}
+			if explanation.DecidingRule != tt.wantRule {
+				t.Errorf("DecidingRule = %s, want %s", explanation.DecidingRule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestExplainIPStatusReportsCodesAndProviders(t *testing.T) {
+	metrics := IPHealthCheck{
+		TotalSent: 1000, TotalRejected: 9, RejectionRatio: 0.009,
+		MajorProviders:  []string{"yahoo.com", "gmail.com"},
+		ReputationCodes: map[string]int{"5.7.1": 2, "4.7.0": 4, "5.1.1": 3},
+		DomainCounts:    map[string]int{"gmail.com": 5, "yahoo.com": 2, "example.org": 2},
+	}
+	explanation := ExplainIPStatus(metrics, DefaultReputationConfig())
+
+	if len(explanation.ReputationCodes) != 2 {
+		t.Fatalf("Expected 5.7.1 and 4.7.0 to be reported, got %+v", explanation.ReputationCodes)
+	}
+	if c := explanation.ReputationCodes[0]; c.Code != "5.7.1" || c.Category != "primary" || !c.Tripped {
+		t.Errorf("Unexpected 5.7.1 entry: %+v", c)
+	}
+	if c := explanation.ReputationCodes[1]; c.Code != "4.7.0" || c.Threshold != 5 || c.Tripped {
+		t.Errorf("Unexpected 4.7.0 entry: %+v", c)
+	}
+
+	if len(explanation.ProviderCounts) != 2 || explanation.ProviderCounts["gmail.com"] != 5 {
+		t.Errorf("Unexpected provider counts: %v", explanation.ProviderCounts)
+	}
+	if explanation.Observed.MajorProviders[0] != "gmail.com" || metrics.MajorProviders[0] != "yahoo.com" {
+		t.Error("Expected sorted providers in the explanation without reordering the input")
+	}
+	if explanation.Status != state.Healthy.String() || len(explanation.Rules) != 13 {
+		t.Errorf("Unexpected explanation: status=%s rules=%d", explanation.Status, len(explanation.Rules))
+	}
+}
+
+func TestWithDerivedFields(t *testing.T) {
+	health := IPHealthCheck{
+		TotalSent:       200,
+		TotalRejected:   10,
+		DomainCounts:    map[string]int{"gmail.com": 6, "outlook.com": 2, "example.org": 2},
+		ReputationCodes: map[string]int{"4.7.0": 3, "4.2.1": 1, "5.7.1": 6},
+	}.WithDerivedFields()
+
+	if health.RejectionRatio != 0.05 || health.UniqueDomainsRejected != 3 || health.ThrottleCount != 4 {
+		t.Errorf("Unexpected derived fields: %+v", health)
+	}
+	if len(health.MajorProviders) != 2 || health.MajorProviders[0] != "gmail.com" {
+		t.Errorf("Unexpected major providers: %v", health.MajorProviders)
+	}
+
+	// Explicit values win
+	kept := IPHealthCheck{TotalSent: 200, TotalRejected: 10, RejectionRatio: 0.5, ThrottleCount: 1,
+		ReputationCodes: map[string]int{"4.7.0": 3}}.WithDerivedFields()
+	if kept.RejectionRatio != 0.5 || kept.ThrottleCount != 1 {
+		t.Errorf("Expected explicit fields to be kept, got %+v", kept)
+	}
+}
+
+func TestReputationConfigValidate(t *testing.T) {
+	if err := DefaultReputationConfig().Validate(); err != nil {
+		t.Errorf("Default config invalid: %v", err)
+	}
+
+	invalid := []func(*ReputationConfig){
+		func(c *ReputationConfig) { c.WindowMinutes = 0 },
+		func(c *ReputationConfig) { c.BlacklistRejectionRatio = 1.5 },
+		func(c *ReputationConfig) { c.WarningRejectionRatio = -0.1 },
+		func(c *ReputationConfig) { c.QuarantineMinDomains = -1 },
+		func(c *ReputationConfig) { c.SpamtrapQuarantineHits = -1 },
+		func(c *ReputationConfig) { c.SpamtrapWindowHours = 0 },
+		func(c *ReputationConfig) { c.QuarantineComplaintRate = 1.5 },
+		func(c *ReputationConfig) { c.ComplaintWindowHours = 0 },
+		func(c *ReputationConfig) { c.ThrottleMinResponses = -1 },
+		func(c *ReputationConfig) { c.ThrottleWindowMinutes = 0 },
+		func(c *ReputationConfig) { c.MaxMessagesPerHour = -1 },
+		func(c *ReputationConfig) {
+			c.WarmupStages = []WarmupStage{{Days: 2, MessagesPerHour: 50}, {Days: 2, MessagesPerHour: 100}}
+		},
+		func(c *ReputationConfig) { c.WarmupStages = []WarmupStage{{Days: 1, MessagesPerHour: 0}} },
+	}
+	for i, mutate := range invalid {
+		config := DefaultReputationConfig()
+		mutate(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error for %+v", i, config)
+		}
+	}
+}
+
+func TestSpamtrapThresholdDisabled(t *testing.T) {
+	config := DefaultReputationConfig()
+	config.SpamtrapQuarantineHits = 0
+	config.SpamtrapWindowHours = 0
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected a disabled spamtrap rule to need no window: %v", err)
+	}
+	metrics := IPHealthCheck{TotalSent: 10, SpamtrapHits: 3}
+	if status := DetermineIPStatus(metrics, config); status != state.Healthy.String() {
+		t.Errorf("Expected spamtrap hits ignored when disabled, got %s", status)
+	}
+	if explanation := ExplainIPStatus(metrics, config); explanation.DecidingRule != "min_volume" {
+		t.Errorf("Expected min_volume to decide, got %s", explanation.DecidingRule)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	from := DefaultReputationConfig()
+	if diff := DiffConfigs(from, from); len(diff) != 0 {
+		t.Errorf("Expected no diff for identical configs, got %v", diff)
+	}
+
+	to := from
+	to.WarningRejectionRatio = 0.1
+	to.QuarantineMinDomains = 7
+	diff := DiffConfigs(from, to)
+	if len(diff) != 2 {
+		t.Fatalf("Expected 2 changed fields, got %v", diff)
+	}
+	if change := diff["warning_rejection_ratio"]; change.From != from.WarningRejectionRatio || change.To != 0.1 {
+		t.Errorf("Unexpected warning ratio change: %+v", change)
+	}
+	if change := diff["quarantine_min_domains"]; change.To != float64(7) {
+		t.Errorf("Unexpected quarantine domains change: %+v", change)
+	}
+}
+
+func TestDiffConfigsWindows(t *testing.T) {
+	from := DefaultReputationConfig()
+	to := DefaultReputationConfig()
+	to.Windows[1].WarningRejectionRatio = 0.015
+
+	diff := DiffConfigs(from, to)
+	if _, changed := diff["windows"]; !changed || len(diff) != 1 {
+		t.Errorf("Expected only windows to change, got %v", diff)
+	}
+}
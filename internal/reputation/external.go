@@ -0,0 +1,27 @@
+package reputation
+
+import (
+	"golang-backend-service/internal/database"
+)
+
+// verdictScores are what a verdict adds to the external score before the
+// feed's weight and the verdict's confidence. A clean verdict replaces the
+// feed's earlier one without offsetting other feeds.
+var verdictScores = map[string]float64{
+	database.VerdictMalicious:  1,
+	database.VerdictSuspicious: 0.5,
+	database.VerdictClean:      0,
+}
+
+// ExternalScore sums the weighted verdicts of third-party feeds and returns
+// each feed's share of the score
+func ExternalScore(signals []database.ExternalSignal) (float64, map[string]float64) {
+	score := 0.0
+	feeds := make(map[string]float64)
+	for _, s := range signals {
+		contribution := verdictScores[s.Verdict] * s.FeedWeight * s.Confidence
+		score += contribution
+		feeds[s.FeedName] += contribution
+	}
+	return score, feeds
+}
@@ -0,0 +1,31 @@
+package reputation
+
+import (
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestExternalScore(t *testing.T) {
+	signals := []database.ExternalSignal{
+		{FeedName: "threat-intel", FeedWeight: 2, Verdict: database.VerdictMalicious, Confidence: 0.75},
+		{FeedName: "partner", FeedWeight: 1, Verdict: database.VerdictSuspicious, Confidence: 1},
+		{FeedName: "partner-b", FeedWeight: 1, Verdict: database.VerdictClean, Confidence: 1},
+	}
+
+	score, feeds := ExternalScore(signals)
+	if score != 2 {
+		t.Errorf("ExternalScore() = %v, want 2", score)
+	}
+	if feeds["threat-intel"] != 1.5 || feeds["partner"] != 0.5 || feeds["partner-b"] != 0 {
+		t.Errorf("Unexpected feed shares %v", feeds)
+	}
+
+	health := IPHealthCheck{TotalSent: 1000, ExternalScore: score, ExternalFeeds: feeds}
+	if got := DetermineIPStatus(health, DefaultReputationConfig()); got != "quarantine" {
+		t.Errorf("DetermineIPStatus() = %s, want quarantine", got)
+	}
+	if got := GetIssueType(health); got != "external_feed_listing" {
+		t.Errorf("GetIssueType() = %q, want external_feed_listing", got)
+	}
+}
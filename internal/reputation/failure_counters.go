@@ -0,0 +1,210 @@
+package reputation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of failure counters
+const (
+	counterRejection = "rejection"
+	// counterRetry counts attempts after the first
+	counterRetry = "retry"
+	// counterDeferral counts greylist-style deferrals no delivery followed
+	// yet, as rejections. ResolveGreylisting moves them to counterGreylisted.
+	counterDeferral    = "deferral"
+	counterGreylisted  = "greylisted"
+	counterTLSRequired = "tls_required"
+)
+
+// FailureKind is the database.FailureClassifier counting failures the way
+// buildHealthCheck splits them. It judges every failure alone, where
+// buildHealthCheck looks at the failures of a recipient together: retries
+// are told by attempt number rather than message ID, and a deferral chain
+// with a permanent failure in it still counts its delivered deferrals as
+// greylisting.
+func FailureKind(f database.SMTPFailure) string {
+	switch {
+	case isGreylistDeferral(f) && f.RetryDeliveredAt != nil:
+		return counterGreylisted
+	case isGreylistDeferral(f):
+		return counterDeferral
+	case isTLSRequirement(f):
+		return counterTLSRequired
+	case f.AttemptNumber > 1:
+		return counterRetry
+	default:
+		return counterRejection
+	}
+}
+
+// buildHealthCheckFromCounters computes health metrics from the failure
+// counters of a window, as buildHealthCheck does from its failures. Counted
+// failures are weighted by the age of their bucket.
+func buildHealthCheckFromCounters(ip, instance string, windowMinutes, totalSent, halfLifeMinutes int, counters []database.FailureCounter, now time.Time) *IPHealthCheck {
+	health := newHealthCheck(ip, instance, windowMinutes, totalSent)
+
+	domainSet := make(map[string]bool)
+	majorProviderSet := make(map[string]bool)
+
+	for _, c := range counters {
+		switch c.Kind {
+		case counterGreylisted:
+			health.Greylisted += c.Failures
+			health.GreylistedDomains[c.Domain] += c.Failures
+		case counterTLSRequired:
+			health.TLSRequired += c.Failures
+			health.TLSRequiredDomains[c.Domain] += c.Failures
+		case counterRetry:
+			health.RetriesDeduplicated += c.Failures
+		default:
+			health.TotalRejected += c.Failures
+			health.WeightedRejected += decayWeight(now.Sub(c.BucketStart), halfLifeMinutes) * float64(c.Failures)
+
+			domainSet[c.Domain] = true
+			health.DomainCounts[c.Domain] += c.Failures
+			if c.MTAInstance != "" {
+				health.InstanceCounts[c.MTAInstance] += c.Failures
+			}
+			if c.EnhancedCode != "" {
+				health.ReputationCodes[c.EnhancedCode] += c.Failures
+				if strings.HasPrefix(c.EnhancedCode, "4") {
+					health.ThrottleCount += c.Failures
+				}
+			}
+			if database.IsMajorProvider(c.Domain) {
+				majorProviderSet[c.Domain] = true
+			}
+		}
+	}
+
+	if totalSent > 0 {
+		health.RejectionRatio = health.WeightedRejected / float64(totalSent)
+	}
+	health.UniqueDomainsRejected = len(domainSet)
+	for provider := range majorProviderSet {
+		health.MajorProviders = append(health.MajorProviders, provider)
+	}
+	return health
+}
+
+// countersCover reports whether the failure counters hold every failure
+// since the given time, so windows starting then can be read from them
+func (s *AggregationService) countersCover(since time.Time) bool {
+	if !features.Enabled(features.FailureCounters) {
+		return false
+	}
+
+	s.countersMu.Lock()
+	defer s.countersMu.Unlock()
+	if s.countingSince.IsZero() {
+		countingSince, err := database.GetFailureCountingSince()
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "failure_counting_start_failed",
+				"error":  err.Error(),
+			}).Warn("Failed to get failure counting start, reading raw failures")
+			return false
+		}
+		s.countingSince = countingSince
+	}
+	return !since.Truncate(database.FailureCounterBucket).Before(s.countingSince)
+}
+
+// healthCheck computes the health of an IP's last windowMinutes, from the
+// failure counters when they cover the window and raw failures otherwise
+func (s *AggregationService) healthCheck(ip string, windowMinutes, totalSent, halfLifeMinutes int) (*IPHealthCheck, error) {
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(windowMinutes) * time.Minute)
+	if !s.countersCover(windowStart) {
+		return CalculateIPHealthCheck(ip, windowMinutes, totalSent, halfLifeMinutes)
+	}
+
+	counters, err := database.GetFailureCounters(ip, "", windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure counters: %w", err)
+	}
+	health := buildHealthCheckFromCounters(ip, "", windowMinutes, totalSent, halfLifeMinutes, counters, now)
+
+	health.AuthFailures, err = database.CountSMTPAuthFailures(ip, "", windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count SMTP auth failures: %w", err)
+	}
+	return health, nil
+}
+
+// countFailures counts the failures of an IP since the given time
+func (s *AggregationService) countFailures(ip string, since time.Time) (int, error) {
+	if s.countersCover(since) {
+		return database.SumFailureCounters(ip, since)
+	}
+	failures, err := database.GetSMTPFailuresByIP(ip, since)
+	return len(failures), err
+}
+
+// counterLookback is how far back the longest window reads
+func counterLookback(config ReputationConfig) time.Duration {
+	lookback := time.Duration(config.WindowMinutes) * time.Minute
+	for _, window := range config.Windows {
+		lookback = max(lookback, time.Duration(window.Minutes)*time.Minute)
+	}
+	return max(lookback, time.Duration(config.ComplaintWindowHours)*time.Hour)
+}
+
+// pruneFailureCounters drops the counters older than any window reads
+func (s *AggregationService) pruneFailureCounters(config ReputationConfig) {
+	lookback := counterLookback(config)
+	if lookback <= 0 {
+		return
+	}
+
+	pruned, countingSince, err := database.PruneFailureCounters(time.Now().Add(-lookback))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "failure_counters_prune_failed",
+			"error":  err.Error(),
+		}).Warn("Failed to prune failure counters")
+		return
+	}
+
+	s.countersMu.Lock()
+	s.countingSince = countingSince
+	s.countersMu.Unlock()
+
+	if pruned > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"action": "failure_counters_pruned",
+			"rows":   pruned,
+		}).Debug("Pruned old failure counters")
+	}
+}
+
+// pruneSentVolume drops the delivered message counts older than any window
+// reads
+func (s *AggregationService) pruneSentVolume(config ReputationConfig) {
+	lookback := counterLookback(config)
+	if lookback <= 0 {
+		return
+	}
+
+	pruned, err := database.PruneSentVolume(time.Now().Add(-lookback))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "sent_volume_prune_failed",
+			"error":  err.Error(),
+		}).Warn("Failed to prune sent volume")
+		return
+	}
+	if pruned > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"action": "sent_volume_pruned",
+			"rows":   pruned,
+		}).Debug("Pruned old sent volume")
+	}
+}
@@ -0,0 +1,117 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/tlspolicy"
+)
+
+// testCounters buckets failures the way StoreSMTPFailure counts them
+func testCounters(failures []database.SMTPFailure) []database.FailureCounter {
+	index := make(map[database.FailureCounter]int)
+	var counters []database.FailureCounter
+	for _, f := range failures {
+		key := database.FailureCounter{
+			BucketStart:  f.Timestamp.Truncate(database.FailureCounterBucket),
+			MTAInstance:  f.MTAInstance,
+			Domain:       f.OrgDomain(),
+			EnhancedCode: f.EnhancedCode,
+			Kind:         FailureKind(f),
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(counters)
+			index[key] = i
+			counters = append(counters, key)
+		}
+		counters[i].Failures++
+	}
+	return counters
+}
+
+func TestFailureKind(t *testing.T) {
+	now := time.Now()
+	tlsFailure := testFailure("a@secure.example", 530, "5.7.0", "Must issue a STARTTLS command first", time.Minute, now)
+	tlsFailure.TLSPolicy = tlspolicy.RequirementDANE
+	retry := testFailure("a@gmail.com", 550, "5.7.1", "Blocked", time.Minute, now)
+	retry.AttemptNumber = 2
+
+	tests := []struct {
+		failure database.SMTPFailure
+		kind    string
+	}{
+		{testFailure("a@gmail.com", 550, "5.7.1", "Blocked", time.Minute, now), counterRejection},
+		{retry, counterRetry},
+		{testFailure("a@new-provider.example", 451, "4.7.0", "Try again later", time.Minute, now), counterDeferral},
+		{testFailure("a@new-provider.example", 450, "4.2.0", "Greylisted, please retry", time.Minute, now), counterDeferral},
+		{delivered(testFailure("a@new-provider.example", 450, "4.2.0", "Greylisted, please retry", time.Minute, now)), counterGreylisted},
+		{testFailure("a@gmail.com", 421, "4.7.28", "Rate limited", time.Minute, now), counterRejection},
+		{tlsFailure, counterTLSRequired},
+	}
+	for _, tt := range tests {
+		if kind := FailureKind(tt.failure); kind != tt.kind {
+			t.Errorf("Expected %s %s to be counted as %s, got %s", tt.failure.EnhancedCode, tt.failure.Reason, tt.kind, kind)
+		}
+	}
+}
+
+func TestHealthCheckFromCountersMatchesFailures(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)
+	tlsFailure := testFailure("t@secure.example", 530, "5.7.0", "Must issue a STARTTLS command first", 3*time.Minute, now)
+	tlsFailure.TLSPolicy = tlspolicy.RequirementSTSEnforce
+	first := testFailure("r@yahoo.com", 550, "5.7.1", "Blocked", 20*time.Minute, now)
+	retry := testFailure("r@yahoo.com", 550, "5.7.1", "Blocked", 10*time.Minute, now)
+	retry.AttemptNumber = 2
+	failures := []database.SMTPFailure{
+		testFailure("a@gmail.com", 550, "5.7.1", "Blocked", time.Minute, now),
+		testFailure("b@gmail.com", 550, "5.7.1", "Blocked", 2*time.Minute, now),
+		testFailure("c@outlook.com", 421, "4.7.28", "Rate limited", 5*time.Minute, now),
+		testFailure("d@mail.example.co.uk", 550, "5.1.1", "No such user", 7*time.Minute, now),
+		testFailure("g@new-provider.example", 451, "4.7.0", "Try again later", 45*time.Minute, now),
+		delivered(testFailure("h@new-provider.example", 450, "4.2.0", "Greylisted", 2*time.Minute, now)),
+		tlsFailure,
+		retry,
+		first,
+	}
+	for i := range failures {
+		failures[i].MTAInstance = "mta-1"
+	}
+
+	exact := buildHealthCheck("192.0.2.1", "", 60, 400, 0, failures, now)
+	counted := buildHealthCheckFromCounters("192.0.2.1", "", 60, 400, 0, testCounters(failures), now)
+
+	if counted.TotalRejected != exact.TotalRejected || counted.RejectionRatio != exact.RejectionRatio {
+		t.Errorf("Expected %d rejected at %.4f, got %d at %.4f", exact.TotalRejected, exact.RejectionRatio, counted.TotalRejected, counted.RejectionRatio)
+	}
+	if counted.RetriesDeduplicated != exact.RetriesDeduplicated || counted.Greylisted != exact.Greylisted || counted.TLSRequired != exact.TLSRequired {
+		t.Errorf("Expected %d retries, %d greylisted and %d TLS required, got %d, %d and %d",
+			exact.RetriesDeduplicated, exact.Greylisted, exact.TLSRequired, counted.RetriesDeduplicated, counted.Greylisted, counted.TLSRequired)
+	}
+	if counted.UniqueDomainsRejected != exact.UniqueDomainsRejected || counted.ThrottleCount != exact.ThrottleCount || len(counted.MajorProviders) != len(exact.MajorProviders) {
+		t.Errorf("Expected %+v, got %+v", exact, counted)
+	}
+	if counted.DomainCounts["gmail.com"] != 2 || counted.InstanceCounts["mta-1"] != exact.InstanceCounts["mta-1"] || counted.ReputationCodes["5.7.1"] != exact.ReputationCodes["5.7.1"] {
+		t.Errorf("Expected the breakdowns %v, %v and %v, got %v, %v and %v", exact.DomainCounts, exact.InstanceCounts, exact.ReputationCodes, counted.DomainCounts, counted.InstanceCounts, counted.ReputationCodes)
+	}
+}
+
+func TestHealthCheckFromCountersDecay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	counters := []database.FailureCounter{
+		{BucketStart: now, Domain: "gmail.com", EnhancedCode: "5.7.1", Kind: counterRejection, Failures: 2},
+		{BucketStart: now.Add(-30 * time.Minute), Domain: "gmail.com", EnhancedCode: "5.7.1", Kind: counterRejection, Failures: 4},
+		// A deferral no delivery followed counts as a rejection
+		{BucketStart: now.Add(-5 * time.Minute), Domain: "new-provider.example", EnhancedCode: "4.7.0", Kind: counterDeferral, Failures: 1},
+	}
+
+	health := buildHealthCheckFromCounters("192.0.2.1", "", 60, 100, 30, counters, now)
+	if health.TotalRejected != 7 || health.Greylisted != 0 {
+		t.Fatalf("Expected 7 rejected and none greylisted, got %+v", health)
+	}
+	expected := 2 + 4*0.5 + decayWeight(5*time.Minute, 30)
+	if diff := health.WeightedRejected - expected; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected weighted rejections of %.4f, got %.4f", expected, health.WeightedRejected)
+	}
+}
@@ -0,0 +1,57 @@
+package reputation
+
+import "math"
+
+// TrendPoint is one observation of a daily series; Day counts from the start
+// of the series, so days without data are simply left out
+type TrendPoint struct {
+	Day   int
+	Value float64
+}
+
+// LinearTrend fits value = intercept + slope*day by least squares. A single
+// point, or points all on the same day, give a flat trend.
+func LinearTrend(points []TrendPoint) (slope, intercept float64) {
+	if len(points) == 0 {
+		return 0, 0
+	}
+
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := float64(p.Day)
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, sumY / n
+	}
+	slope = (n*sumXY - sumX*sumY) / denominator
+	intercept = (sumY - slope*sumX) / n
+	return slope, intercept
+}
+
+// ForecastLinear projects the trend of points for the horizon days after
+// lastDay, clamped to [0, ceiling]
+func ForecastLinear(points []TrendPoint, lastDay, horizon int, ceiling float64) []float64 {
+	slope, intercept := LinearTrend(points)
+	forecast := make([]float64, horizon)
+	for i := range forecast {
+		value := intercept + slope*float64(lastDay+i+1)
+		forecast[i] = math.Max(0, math.Min(ceiling, value))
+	}
+	return forecast
+}
+
+// DaysUntilExhausted is how long available units last at burnPerDay. It
+// returns false when nothing is being consumed.
+func DaysUntilExhausted(available int, burnPerDay float64) (float64, bool) {
+	if burnPerDay <= 0 {
+		return 0, false
+	}
+	return float64(available) / burnPerDay, true
+}
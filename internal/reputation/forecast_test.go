@@ -0,0 +1,39 @@
+package reputation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLinearTrend(t *testing.T) {
+	slope, intercept := LinearTrend([]TrendPoint{{0, 10}, {1, 9}, {3, 7}})
+	if math.Abs(slope+1) > 1e-9 || math.Abs(intercept-10) > 1e-9 {
+		t.Errorf("LinearTrend() = %v, %v; want -1, 10", slope, intercept)
+	}
+
+	if slope, intercept := LinearTrend([]TrendPoint{{2, 4}}); slope != 0 || intercept != 4 {
+		t.Errorf("Expected a flat trend for one point, got %v, %v", slope, intercept)
+	}
+}
+
+func TestForecastLinearClamps(t *testing.T) {
+	points := []TrendPoint{{0, 3}, {1, 2}, {2, 1}}
+	forecast := ForecastLinear(points, 2, 3, 10)
+	if forecast[0] != 0 || forecast[2] != 0 {
+		t.Errorf("Expected forecast clamped at zero, got %v", forecast)
+	}
+
+	rising := ForecastLinear([]TrendPoint{{0, 8}, {1, 9}}, 1, 2, 10)
+	if rising[0] != 10 || rising[1] != 10 {
+		t.Errorf("Expected forecast clamped at the ceiling, got %v", rising)
+	}
+}
+
+func TestDaysUntilExhausted(t *testing.T) {
+	if days, ok := DaysUntilExhausted(10, 2.5); !ok || days != 4 {
+		t.Errorf("DaysUntilExhausted() = %v, %v", days, ok)
+	}
+	if _, ok := DaysUntilExhausted(10, 0); ok {
+		t.Error("Expected no exhaustion without burn")
+	}
+}
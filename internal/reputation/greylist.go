@@ -0,0 +1,103 @@
+package reputation
+
+import (
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// greylistRetryWindow is how long before a delivery a deferral to the same
+// recipient can be the greylisting its retry got past
+const greylistRetryWindow = 24 * time.Hour
+
+// greylistCodes are the enhanced codes greylisting servers defer with
+var greylistCodes = map[string]bool{
+	"4.2.0": true,
+	"4.7.0": true,
+}
+
+var greylistReasons = []string{"greylist", "graylist", "grey-list", "gray-list", "grey list", "gray list"}
+
+func isTemporaryFailure(f database.SMTPFailure) bool {
+	return f.SMTPCode/100 == 4 || strings.HasPrefix(f.EnhancedCode, "4.")
+}
+
+// isGreylistDeferral reports whether f is a temporary failure with a code or
+// reason greylisting servers defer with
+func isGreylistDeferral(f database.SMTPFailure) bool {
+	return isTemporaryFailure(f) && (greylistCodes[f.EnhancedCode] || mentionsGreylisting(f.Reason))
+}
+
+func mentionsGreylisting(reason string) bool {
+	reason = strings.ToLower(reason)
+	for _, hint := range greylistReasons {
+		if strings.Contains(reason, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveGreylisting marks the greylist-style deferrals an IP had for the
+// recipient of a delivery success, up to the delivery, as delivered on
+// retry: only then are they greylisting rather than rejections. When the
+// success and a deferral both carry a message ID they must match. Returns
+// the deferrals marked.
+func ResolveGreylisting(success database.DeliverySuccess) (int, error) {
+	if success.RecipientEmail == "" {
+		return 0, nil
+	}
+	failures, err := database.GetSMTPFailuresToRecipient(success.SendingIP, success.RecipientEmail, success.Timestamp.Add(-greylistRetryWindow), success.Timestamp)
+	if err != nil {
+		return 0, err
+	}
+	return database.MarkRetriesDelivered(deliveredDeferrals(failures, success), success.Timestamp)
+}
+
+// deliveredDeferrals returns the IDs of the failures a delivery success
+// shows were greylisting deferrals, delivered on retry
+func deliveredDeferrals(failures []database.SMTPFailure, success database.DeliverySuccess) []int {
+	var ids []int
+	for _, f := range failures {
+		if f.RetryDeliveredAt != nil || !isGreylistDeferral(f) || f.Timestamp.After(success.Timestamp) {
+			continue
+		}
+		if !strings.EqualFold(f.RecipientEmail, success.RecipientEmail) {
+			continue
+		}
+		if f.MessageID != "" && success.MessageID != "" && f.MessageID != success.MessageID {
+			continue
+		}
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+// splitGreylisted separates greylisting deferrals from the failures that
+// count towards reputation. A failure is greylisting when it is a temporary
+// greylist-style deferral that a delivery to the recipient followed
+// (ResolveGreylisting) and no other failure of the recipient is a permanent
+// or other kind of failure. A chain that was never delivered counts as
+// rejections, however long it has gone quiet. Order is preserved.
+func splitGreylisted(failures []database.SMTPFailure) (kept, greylisted []database.SMTPFailure) {
+	greylist := make(map[string]bool)
+	for _, f := range failures {
+		key := strings.ToLower(f.RecipientEmail)
+		if _, ok := greylist[key]; !ok {
+			greylist[key] = true
+		}
+		if !isGreylistDeferral(f) {
+			greylist[key] = false
+		}
+	}
+
+	for _, f := range failures {
+		if greylist[strings.ToLower(f.RecipientEmail)] && f.RetryDeliveredAt != nil {
+			greylisted = append(greylisted, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, greylisted
+}
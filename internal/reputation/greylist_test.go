@@ -0,0 +1,125 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func testFailure(recipient string, smtpCode int, enhanced, reason string, age time.Duration, now time.Time) database.SMTPFailure {
+	return database.SMTPFailure{
+		RecipientEmail:  recipient,
+		RecipientDomain: database.ExtractDomain(recipient),
+		SMTPCode:        smtpCode,
+		EnhancedCode:    enhanced,
+		Reason:          reason,
+		Timestamp:       now.Add(-age),
+	}
+}
+
+// delivered marks f as followed by a delivery to its recipient
+func delivered(f database.SMTPFailure) database.SMTPFailure {
+	at := f.Timestamp.Add(10 * time.Minute)
+	f.RetryDeliveredAt = &at
+	return f
+}
+
+func TestSplitGreylisted(t *testing.T) {
+	now := time.Now()
+	failures := []database.SMTPFailure{
+		// Deferred twice, then delivered on retry
+		delivered(testFailure("a@new-provider.example", 451, "4.7.0", "Try again later", 40*time.Minute, now)),
+		delivered(testFailure("a@new-provider.example", 451, "4.7.0", "Try again later", 35*time.Minute, now)),
+		// Deferred and silent for hours, but never delivered
+		testFailure("b@new-provider.example", 451, "4.7.0", "Try again later", 3*time.Hour, now),
+		testFailure("b@new-provider.example", 451, "4.7.0", "Try again later", 2*time.Hour, now),
+		// The reason says so, but no delivery followed yet
+		testFailure("c@other.example", 450, "4.2.0", "Recipient address rejected: Greylisted", time.Minute, now),
+		// Deferred and delivered, then permanently rejected
+		delivered(testFailure("d@new-provider.example", 451, "4.7.0", "Try again later", 50*time.Minute, now)),
+		testFailure("D@new-provider.example", 550, "5.7.1", "Blocked", 45*time.Minute, now),
+		// Temporary, but not a greylisting code
+		testFailure("e@gmail.com", 421, "4.7.28", "Rate limited", 40*time.Minute, now),
+	}
+
+	kept, greylisted := splitGreylisted(failures)
+	if len(greylisted) != 2 || len(kept) != 6 {
+		t.Fatalf("Expected 2 greylisted and 6 kept, got %d and %d", len(greylisted), len(kept))
+	}
+	for _, f := range greylisted {
+		if f.RecipientEmail != "a@new-provider.example" {
+			t.Errorf("Unexpected greylisted failure for %s", f.RecipientEmail)
+		}
+	}
+}
+
+func TestHealthCheckCountsUndeliveredDeferrals(t *testing.T) {
+	now := time.Now()
+	// Yahoo defers IPs it distrusts with 4.7.0; without a later delivery the
+	// chain is a rejection however long ago it stopped
+	failures := []database.SMTPFailure{
+		testFailure("a@yahoo.com", 421, "4.7.0", "[TSS04] Messages from 192.0.2.1 temporarily deferred", 55*time.Minute, now),
+		testFailure("a@yahoo.com", 421, "4.7.0", "[TSS04] Messages from 192.0.2.1 temporarily deferred", 50*time.Minute, now),
+		testFailure("b@yahoo.com", 421, "4.7.0", "[TSS04] Messages from 192.0.2.1 temporarily deferred", 45*time.Minute, now),
+	}
+
+	health := buildHealthCheck("192.0.2.1", "", 60, 100, 0, failures, now)
+	if health.TotalRejected != 3 || health.Greylisted != 0 || health.DomainCounts["yahoo.com"] != 3 {
+		t.Errorf("Expected the undelivered deferrals counted as rejections, got %+v", health)
+	}
+
+	counted := buildHealthCheckFromCounters("192.0.2.1", "", 60, 100, 0, testCounters(failures), now)
+	if counted.TotalRejected != 3 || counted.Greylisted != 0 {
+		t.Errorf("Expected the counters to count the undelivered deferrals as rejections, got %+v", counted)
+	}
+}
+
+func TestDeliveredDeferrals(t *testing.T) {
+	now := time.Now()
+	failure := func(id int, recipient string, smtpCode int, enhanced, messageID string, age time.Duration) database.SMTPFailure {
+		f := testFailure(recipient, smtpCode, enhanced, "Try again later", age, now)
+		f.ID, f.MessageID = id, messageID
+		return f
+	}
+	resolved := delivered(failure(7, "a@new-provider.example", 451, "4.7.0", "", 30*time.Minute))
+	failures := []database.SMTPFailure{
+		failure(1, "A@new-provider.example", 451, "4.7.0", "q-1", 20*time.Minute),
+		failure(2, "a@new-provider.example", 450, "4.2.0", "", 10*time.Minute),
+		// Another message to the recipient
+		failure(3, "a@new-provider.example", 451, "4.7.0", "q-2", 10*time.Minute),
+		// Not a greylisting deferral
+		failure(4, "a@new-provider.example", 550, "5.7.1", "q-1", 5*time.Minute),
+		// Reported after the delivery
+		failure(5, "a@new-provider.example", 451, "4.7.0", "q-1", -time.Minute),
+		// Another recipient
+		failure(6, "b@new-provider.example", 451, "4.7.0", "q-1", 10*time.Minute),
+		resolved,
+	}
+	success := database.DeliverySuccess{RecipientEmail: "a@new-provider.example", MessageID: "q-1", Timestamp: now}
+
+	ids := deliveredDeferrals(failures, success)
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Expected deferrals 1 and 2 delivered, got %v", ids)
+	}
+}
+
+func TestHealthCheckExcludesGreylisting(t *testing.T) {
+	now := time.Now()
+	failures := []database.SMTPFailure{
+		delivered(testFailure("a@new-provider.example", 451, "4.7.0", "Greylisted, please retry", time.Minute, now)),
+		delivered(testFailure("b@new-provider.example", 451, "4.7.0", "Greylisted, please retry", time.Minute, now)),
+		testFailure("c@gmail.com", 550, "5.7.1", "Blocked", time.Minute, now),
+	}
+
+	health := buildHealthCheck("192.0.2.1", "", 15, 100, 0, failures, now)
+	if health.TotalRejected != 1 || health.RejectionRatio != 0.01 || health.ThrottleCount != 0 {
+		t.Errorf("Expected greylisting to be left out, got %+v", health)
+	}
+	if health.Greylisted != 2 || health.GreylistedDomains["new-provider.example"] != 2 {
+		t.Errorf("Expected 2 greylisted deferrals, got %d %v", health.Greylisted, health.GreylistedDomains)
+	}
+	if _, counted := health.DomainCounts["new-provider.example"]; counted {
+		t.Error("Greylisted domain counted as rejecting")
+	}
+}
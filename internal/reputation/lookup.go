@@ -0,0 +1,212 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/resolver"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLookupUnsupported is returned by a provider that cannot look up an IP,
+// e.g. an IPv6 address; nothing is stored for it
+var ErrLookupUnsupported = errors.New("lookup not supported for this IP")
+
+// LookupProvider queries an external sender reputation source about an IP.
+// The result's IP, Provider and CheckedAt are filled in by the service.
+type LookupProvider interface {
+	Name() string
+	Lookup(ctx context.Context, ip string) (*database.ReputationLookup, error)
+}
+
+// NewLookupProvider returns the provider with the given name
+func NewLookupProvider(name string, r resolver.Resolver) (LookupProvider, error) {
+	switch name {
+	case SenderScoreProvider:
+		return NewSenderScore(r), nil
+	default:
+		return nil, fmt.Errorf("unknown reputation lookup provider %q", name)
+	}
+}
+
+// NewLookupProviders returns the providers of a comma-separated list of names
+func NewLookupProviders(names string, r resolver.Resolver) ([]LookupProvider, error) {
+	providers := []LookupProvider{}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		provider, err := NewLookupProvider(name, r)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// lookupErrorTTL is how long a failed lookup is cached before it is retried
+const lookupErrorTTL = 15 * time.Minute
+
+// lookupStore persists lookup results; the latest per provider is the cache
+type lookupStore interface {
+	InsertReputationLookup(lookup *database.ReputationLookup) error
+	GetLatestReputationLookups(ip string) ([]database.ReputationLookup, error)
+}
+
+type dbLookupStore struct{}
+
+func (dbLookupStore) InsertReputationLookup(lookup *database.ReputationLookup) error {
+	return database.InsertReputationLookup(lookup)
+}
+
+func (dbLookupStore) GetLatestReputationLookups(ip string) ([]database.ReputationLookup, error) {
+	return database.GetLatestReputationLookups(ip)
+}
+
+// LookupService runs external reputation lookups and caches their results
+// for ttl
+type LookupService struct {
+	providers []LookupProvider
+	store     lookupStore
+	ttl       time.Duration
+	timeout   time.Duration
+	logger    *logrus.Logger
+	// running holds the IPs being looked up in the background
+	running   map[string]bool
+	runningMu sync.Mutex
+}
+
+// NewLookupService creates a lookup service. Each provider's lookup is
+// bounded by timeout, 5s if not positive.
+func NewLookupService(providers []LookupProvider, ttl, timeout time.Duration, logger *logrus.Logger) *LookupService {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &LookupService{
+		providers: providers,
+		store:     dbLookupStore{},
+		ttl:       ttl,
+		timeout:   timeout,
+		logger:    logger,
+		running:   make(map[string]bool),
+	}
+}
+
+// fresh reports whether a cached result can still be used
+func (l *LookupService) fresh(lookup database.ReputationLookup, now time.Time) bool {
+	ttl := l.ttl
+	if lookup.Error != "" && lookupErrorTTL < ttl {
+		ttl = lookupErrorTTL
+	}
+	return now.Sub(lookup.CheckedAt) < ttl
+}
+
+// Lookup returns every provider's result for an IP, by provider, querying the
+// providers whose cached result is missing or stale. A failed lookup is
+// returned and cached with its error.
+func (l *LookupService) Lookup(ctx context.Context, ip string) ([]database.ReputationLookup, error) {
+	cached, err := l.store.GetLatestReputationLookups(ip)
+	if err != nil {
+		return nil, err
+	}
+	byProvider := make(map[string]database.ReputationLookup, len(cached))
+	for _, lookup := range cached {
+		byProvider[lookup.Provider] = lookup
+	}
+
+	now := time.Now()
+	results := []database.ReputationLookup{}
+	for _, provider := range l.providers {
+		if lookup, ok := byProvider[provider.Name()]; ok && l.fresh(lookup, now) {
+			results = append(results, lookup)
+			continue
+		}
+
+		lookup, ok := l.query(ctx, provider, ip)
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			// A cancelled lookup says nothing about the IP
+			return nil, err
+		}
+		if err := l.store.InsertReputationLookup(lookup); err != nil {
+			return nil, err
+		}
+		results = append(results, *lookup)
+	}
+	return results, nil
+}
+
+// query runs one provider; ok is false when the provider does not support
+// the IP
+func (l *LookupService) query(ctx context.Context, provider LookupProvider, ip string) (*database.ReputationLookup, bool) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	lookup, err := provider.Lookup(ctx, ip)
+	if errors.Is(err, ErrLookupUnsupported) {
+		return nil, false
+	}
+	if err != nil {
+		l.logger.WithFields(logrus.Fields{
+			"action":   "reputation_lookup_failed",
+			"ip":       ip,
+			"provider": provider.Name(),
+			"error":    err.Error(),
+		}).Warn("External reputation lookup failed")
+		lookup = &database.ReputationLookup{Error: err.Error()}
+	}
+	lookup.IP = ip
+	lookup.Provider = provider.Name()
+	lookup.CheckedAt = time.Now()
+	RecordReputationLookup(lookup.Provider, lookup.Error == "", lookup.Listed)
+	return lookup, true
+}
+
+// LookupAsync runs Lookup in the background, once per IP at a time, and
+// logs the listings it finds
+func (l *LookupService) LookupAsync(ip string) {
+	l.runningMu.Lock()
+	if l.running[ip] {
+		l.runningMu.Unlock()
+		return
+	}
+	l.running[ip] = true
+	l.runningMu.Unlock()
+
+	go func() {
+		defer func() {
+			l.runningMu.Lock()
+			delete(l.running, ip)
+			l.runningMu.Unlock()
+		}()
+
+		lookups, err := l.Lookup(context.Background(), ip)
+		if err != nil {
+			l.logger.WithFields(logrus.Fields{
+				"action": "reputation_lookups_failed",
+				"ip":     ip,
+				"error":  err.Error(),
+			}).Error("Failed to run external reputation lookups")
+			return
+		}
+		for _, lookup := range lookups {
+			if lookup.Listed {
+				l.logger.WithFields(logrus.Fields{
+					"action":   "reputation_lookup_listed",
+					"ip":       ip,
+					"provider": lookup.Provider,
+					"summary":  lookup.Summary,
+				}).Warn("External reputation source rates the IP poorly")
+			}
+		}
+	}()
+}
@@ -0,0 +1,135 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/resolver"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryLookupStore is an in-memory lookupStore
+type memoryLookupStore struct {
+	lookups []database.ReputationLookup
+}
+
+func (s *memoryLookupStore) InsertReputationLookup(lookup *database.ReputationLookup) error {
+	lookup.ID = len(s.lookups) + 1
+	s.lookups = append(s.lookups, *lookup)
+	return nil
+}
+
+func (s *memoryLookupStore) GetLatestReputationLookups(ip string) ([]database.ReputationLookup, error) {
+	latest := map[string]database.ReputationLookup{}
+	for _, l := range s.lookups {
+		if l.IP == ip && l.CheckedAt.After(latest[l.Provider].CheckedAt) {
+			latest[l.Provider] = l
+		}
+	}
+	lookups := []database.ReputationLookup{}
+	for _, l := range latest {
+		lookups = append(lookups, l)
+	}
+	return lookups, nil
+}
+
+// countingProvider answers every lookup with err, or a score, and counts them
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Lookup(ctx context.Context, ip string) (*database.ReputationLookup, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	score := 50.0
+	return &database.ReputationLookup{Score: &score, Listed: true}, nil
+}
+
+func TestSenderScoreLookup(t *testing.T) {
+	dns := resolver.NewFake()
+	dns.Records["1.2.0.192.score.senderscore.com"] = []string{"127.0.4.62"}
+	dns.Records["2.2.0.192.score.senderscore.com"] = []string{"127.0.4.95"}
+	dns.Errors["3.2.0.192.score.senderscore.com"] = &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+	provider := NewSenderScore(dns)
+
+	lookup, err := provider.Lookup(context.Background(), "192.0.2.1")
+	if err != nil || lookup.Score == nil || *lookup.Score != 62 || !lookup.Listed {
+		t.Errorf("Expected a poor score of 62, got %+v %v", lookup, err)
+	}
+	lookup, err = provider.Lookup(context.Background(), "192.0.2.2")
+	if err != nil || lookup.Score == nil || *lookup.Score != 95 || lookup.Listed {
+		t.Errorf("Expected a good score of 95, got %+v %v", lookup, err)
+	}
+	// NXDOMAIN: SenderScore has seen too little mail from the IP
+	lookup, err = provider.Lookup(context.Background(), "192.0.2.4")
+	if err != nil || lookup.Score != nil || lookup.Listed {
+		t.Errorf("Expected no score, got %+v %v", lookup, err)
+	}
+	if _, err := provider.Lookup(context.Background(), "192.0.2.3"); err == nil {
+		t.Error("Expected an error from a failing resolver")
+	}
+	if _, err := provider.Lookup(context.Background(), "2001:db8::1"); !errors.Is(err, ErrLookupUnsupported) {
+		t.Errorf("Expected IPv6 to be unsupported, got %v", err)
+	}
+}
+
+func TestLookupServiceCaches(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	provider := &countingProvider{}
+	service := NewLookupService([]LookupProvider{provider}, time.Hour, time.Second, logger)
+	store := &memoryLookupStore{}
+	service.store = store
+
+	for i := 0; i < 2; i++ {
+		lookups, err := service.Lookup(context.Background(), "192.0.2.1")
+		if err != nil || len(lookups) != 1 || lookups[0].Provider != "counting" || !lookups[0].Listed {
+			t.Fatalf("Unexpected lookups %+v %v", lookups, err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("Expected the second lookup served from the cache, got %d calls", provider.calls)
+	}
+
+	// A stale result is looked up again
+	store.lookups[0].CheckedAt = time.Now().Add(-2 * time.Hour)
+	if _, err := service.Lookup(context.Background(), "192.0.2.1"); err != nil || provider.calls != 2 {
+		t.Errorf("Expected a stale result refreshed, got %d calls %v", provider.calls, err)
+	}
+}
+
+func TestLookupServiceCachesErrorsBriefly(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	provider := &countingProvider{err: errors.New("timeout")}
+	service := NewLookupService([]LookupProvider{provider}, 24*time.Hour, time.Second, logger)
+	store := &memoryLookupStore{}
+	service.store = store
+
+	lookups, err := service.Lookup(context.Background(), "192.0.2.1")
+	if err != nil || len(lookups) != 1 || lookups[0].Error != "timeout" {
+		t.Fatalf("Expected the failed lookup returned with its error, got %+v %v", lookups, err)
+	}
+	store.lookups[0].CheckedAt = time.Now().Add(-lookupErrorTTL - time.Minute)
+	if _, err := service.Lookup(context.Background(), "192.0.2.1"); err != nil || provider.calls != 2 {
+		t.Errorf("Expected a failed lookup retried after %s, got %d calls %v", lookupErrorTTL, provider.calls, err)
+	}
+}
+
+func TestNewLookupProviders(t *testing.T) {
+	providers, err := NewLookupProviders(" senderscore, ", nil)
+	if err != nil || len(providers) != 1 || providers[0].Name() != SenderScoreProvider {
+		t.Errorf("Unexpected providers %v %v", providers, err)
+	}
+	if _, err := NewLookupProviders("senderscore,talos", nil); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
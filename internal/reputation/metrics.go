@@ -1,6 +1,8 @@
 package reputation
 
 import (
+	"golang-backend-service/internal/reputation/state"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -16,6 +18,51 @@ var (
 		[]string{"ip", "enhanced_code", "domain"},
 	)
 
+	// Counter for SMTP failures per MTA instance
+	SMTPFailuresByInstanceTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_failures_by_instance_total",
+			Help: "Total number of SMTP failures processed per sending IP and MTA instance",
+		},
+		[]string{"ip", "instance"},
+	)
+
+	// Counter for SMTP failures skipped as duplicates
+	SMTPFailureDuplicatesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_failure_duplicates_total",
+			Help: "Total number of SMTP failures skipped as duplicates (duplicate_event or duplicate_content)",
+		},
+		[]string{"kind"},
+	)
+
+	// Counter for webhook payloads refused by the backpressure policy
+	WebhookBackpressureTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_backpressure_total",
+			Help: "Total number of webhook payloads refused with 429/503 by the backpressure policy",
+		},
+		[]string{"reason"},
+	)
+
+	// Counter for webhook payloads refused for their HMAC signature
+	WebhookSignatureRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_signature_rejections_total",
+			Help: "Total number of webhook payloads rejected for a missing, invalid or stale HMAC signature",
+		},
+		[]string{"reason", "source"},
+	)
+
+	// Counter for ingestion-time bursts
+	BurstsDetectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reputation_bursts_detected_total",
+			Help: "Total number of failure bursts detected at ingestion time",
+		},
+		[]string{"ip", "rule", "action"},
+	)
+
 	// Counter for IP status changes
 	IPStatusChangesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -70,6 +117,15 @@ var (
 		[]string{"status"},
 	)
 
+	// Counter for shadow config decisions, next to the live decision
+	ShadowDecisionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ip_shadow_decisions_total",
+			Help: "Total number of IP classifications by the shadow reputation config",
+		},
+		[]string{"live_status", "shadow_status"},
+	)
+
 	// Gauge for IPs processed in last aggregation
 	IPsProcessedGauge = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -86,22 +142,101 @@ var (
 		},
 		[]string{"event_type", "status"},
 	)
+
+	// Counter for webhook events per payload schema version
+	WebhookSchemaVersionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_events_by_schema_version_total",
+			Help: "Total number of webhook events received per payload schema version",
+		},
+		[]string{"version"},
+	)
+
+	// Counter for failed outgoing SMTP authentications
+	SMTPAuthFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_auth_failures_total",
+			Help: "Total number of outgoing SMTP authentication failures processed",
+		},
+		[]string{"ip", "mechanism"},
+	)
+
+	// Counter for webhook fields no supported schema version knows
+	WebhookUnknownFieldsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_unknown_fields_total",
+			Help: "Total number of unknown fields seen in webhook events",
+		},
+		[]string{"version", "field"},
+	)
+
+	// Counter for spamtrap hits reported by trap networks or internal traps
+	SpamtrapHitsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "spamtrap_hits_total",
+			Help: "Total number of spamtrap hits recorded",
+		},
+		[]string{"ip", "source", "trap_type"},
+	)
+
+	// Counter for abuse complaints parsed from ARF reports
+	AbuseComplaintsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "abuse_complaints_total",
+			Help: "Total number of abuse complaints recorded from ARF reports",
+		},
+		[]string{"ip", "reporter", "feedback_type"},
+	)
+
+	// Counter for failed TLS sessions reported in SMTP TLS reports
+	TLSFailedSessionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tls_failed_sessions_total",
+			Help: "Total number of failed TLS sessions reported in SMTP TLS reports",
+		},
+		[]string{"ip", "policy_type", "result_type"},
+	)
+
+	// Counter for webhook events per source
+	WebhookSourceEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_source_events_total",
+			Help: "Total number of webhook events received per webhook source",
+		},
+		[]string{"source", "status"},
+	)
+
+	// Counter for verdicts posted by third-party reputation feeds
+	ExternalSignalsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "external_reputation_signals_total",
+			Help: "Total number of IP verdicts received from third-party reputation feeds",
+		},
+		[]string{"feed", "verdict"},
+	)
+
+	// Counter for external sender reputation lookups
+	ReputationLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reputation_lookups_total",
+			Help: "Total number of external sender reputation lookups by provider and result",
+		},
+		[]string{"provider", "result"},
+	)
+
+	// Counter for IPs starting and stopping being throttled by a provider
+	ThrottleStateChangesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "throttle_state_changes_total",
+			Help: "Total number of times a destination provider started or stopped throttling an IP",
+		},
+		[]string{"provider", "state"},
+	)
 )
 
 // GetStatusValue converts status string to numeric value for metrics
 func GetStatusValue(status string) float64 {
-	switch status {
-	case "healthy":
-		return 1
-	case "warning":
-		return 2
-	case "quarantine":
-		return 3
-	case "blacklisted":
-		return 4
-	default:
-		return 0
-	}
+	return float64(state.Status(status).Severity())
 }
 
 // RecordSMTPFailure records an SMTP failure metric
@@ -136,8 +271,102 @@ func RecordAggregationRun(status string, ipsProcessed int) {
 	IPsProcessedGauge.Set(float64(ipsProcessed))
 }
 
+// RecordShadowDecision records a shadow config classification
+func RecordShadowDecision(liveStatus, shadowStatus string) {
+	ShadowDecisionsTotal.WithLabelValues(liveStatus, shadowStatus).Inc()
+}
+
 // RecordWebhookEvent records a webhook event metric
 func RecordWebhookEvent(eventType, status string) {
 	WebhookEventsTotal.WithLabelValues(eventType, status).Inc()
 }
 
+// RecordWebhookSchemaVersion records the schema version of a webhook event
+func RecordWebhookSchemaVersion(version string) {
+	WebhookSchemaVersionsTotal.WithLabelValues(version).Inc()
+}
+
+// RecordSMTPAuthFailure records a failed outgoing SMTP authentication
+func RecordSMTPAuthFailure(ip, mechanism string) {
+	SMTPAuthFailuresTotal.WithLabelValues(ip, mechanism).Inc()
+}
+
+// RecordWebhookUnknownField records a webhook field that was not decoded
+func RecordWebhookUnknownField(version, field string) {
+	WebhookUnknownFieldsTotal.WithLabelValues(version, field).Inc()
+}
+
+// RecordSMTPFailureInstance records which MTA instance reported a failure
+func RecordSMTPFailureInstance(ip, instance string) {
+	SMTPFailuresByInstanceTotal.WithLabelValues(ip, instance).Inc()
+}
+
+// RecordWebhookSourceEvent records a webhook event metric for a source
+func RecordWebhookSourceEvent(source, status string) {
+	WebhookSourceEventsTotal.WithLabelValues(source, status).Inc()
+}
+
+// RecordSMTPFailureDuplicate records a failure skipped as a duplicate
+func RecordSMTPFailureDuplicate(kind string) {
+	SMTPFailureDuplicatesTotal.WithLabelValues(kind).Inc()
+}
+
+// RecordWebhookBackpressure records a webhook payload refused by the
+// backpressure policy
+func RecordWebhookBackpressure(reason string) {
+	WebhookBackpressureTotal.WithLabelValues(reason).Inc()
+}
+
+// RecordWebhookSignatureRejection records a webhook payload rejected by
+// signature verification
+func RecordWebhookSignatureRejection(reason, source string) {
+	WebhookSignatureRejectionsTotal.WithLabelValues(reason, source).Inc()
+}
+
+// RecordBurstDetected records a burst detected at ingestion time
+func RecordBurstDetected(ip, rule, action string) {
+	BurstsDetectedTotal.WithLabelValues(ip, rule, action).Inc()
+}
+
+// RecordSpamtrapHit records a stored spamtrap hit
+func RecordSpamtrapHit(ip, source, trapType string) {
+	SpamtrapHitsTotal.WithLabelValues(ip, source, trapType).Inc()
+}
+
+// RecordAbuseComplaint records a stored ARF complaint
+func RecordAbuseComplaint(ip, reporter, feedbackType string) {
+	AbuseComplaintsTotal.WithLabelValues(ip, reporter, feedbackType).Inc()
+}
+
+// RecordTLSFailedSessions records failed TLS sessions from a stored TLS
+// report; an empty ip is a failure the report did not attribute
+func RecordTLSFailedSessions(ip, policyType, resultType string, sessions int) {
+	TLSFailedSessionsTotal.WithLabelValues(ip, policyType, resultType).Add(float64(sessions))
+}
+
+// RecordExternalSignal records a verdict posted by a reputation feed
+func RecordExternalSignal(feed, verdict string) {
+	ExternalSignalsTotal.WithLabelValues(feed, verdict).Inc()
+}
+
+// RecordReputationLookup records an external reputation lookup
+func RecordReputationLookup(provider string, succeeded, listed bool) {
+	result := "not_listed"
+	switch {
+	case !succeeded:
+		result = "error"
+	case listed:
+		result = "listed"
+	}
+	ReputationLookupsTotal.WithLabelValues(provider, result).Inc()
+}
+
+// RecordThrottleStateChange records a provider starting or stopping
+// throttling an IP
+func RecordThrottleStateChange(provider string, throttled bool) {
+	state := "cleared"
+	if throttled {
+		state = "throttled"
+	}
+	ThrottleStateChangesTotal.WithLabelValues(provider, state).Inc()
+}
@@ -0,0 +1,115 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/dependencies"
+)
+
+// PrometheusVolume reads the sent volume from the sending systems' metrics
+// in Prometheus, with an instant query like DefaultPrometheusVolumeQuery
+type PrometheusVolume struct {
+	baseURL string
+	query   string
+	client  *http.Client
+}
+
+// NewPrometheusVolume creates an estimator querying the Prometheus server at
+// baseURL
+func NewPrometheusVolume(baseURL, query string, timeout time.Duration) *PrometheusVolume {
+	return &PrometheusVolume{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		query:   query,
+		client: &http.Client{
+			Transport: dependencies.Default.Transport(dependencies.Prometheus, nil),
+			Timeout:   timeout,
+		},
+	}
+}
+
+// Name implements VolumeEstimator
+func (p *PrometheusVolume) Name() string {
+	return VolumeFromPrometheus
+}
+
+// prometheusResponse is the envelope of the Prometheus query API
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// EstimateSent implements VolumeEstimator, evaluating the query at until.
+// The values of every series returned are summed; none is ErrNoVolume.
+func (p *PrometheusVolume) EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error) {
+	window := fmt.Sprintf("%ds", int64(until.Sub(since).Seconds()))
+	query := strings.NewReplacer("{{ip}}", ip, "{{window}}", window).Replace(p.query)
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(until.Unix(), 10))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("prometheus query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode prometheus response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed (HTTP %d): %s", resp.StatusCode, body.Error)
+	}
+
+	var values [][2]interface{}
+	switch body.Data.ResultType {
+	case "vector":
+		var series []struct {
+			Value [2]interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(body.Data.Result, &series); err != nil {
+			return 0, fmt.Errorf("failed to decode prometheus vector: %w", err)
+		}
+		for _, s := range series {
+			values = append(values, s.Value)
+		}
+	case "scalar":
+		var value [2]interface{}
+		if err := json.Unmarshal(body.Data.Result, &value); err != nil {
+			return 0, fmt.Errorf("failed to decode prometheus scalar: %w", err)
+		}
+		values = append(values, value)
+	default:
+		return 0, fmt.Errorf("prometheus query returned a %s, want a vector or scalar", body.Data.ResultType)
+	}
+	if len(values) == 0 {
+		return 0, ErrNoVolume
+	}
+
+	var sent float64
+	for _, v := range values {
+		s, _ := v[1].(string)
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("invalid prometheus sample %v", v[1])
+		}
+		sent += f
+	}
+	return int(sent + 0.5), nil
+}
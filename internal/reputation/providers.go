@@ -0,0 +1,65 @@
+package reputation
+
+import (
+	"strings"
+
+	"golang-backend-service/internal/database"
+)
+
+// Mailbox providers that failures are grouped under when they are theirs
+const (
+	ProviderOutlook = "outlook"
+	ProviderGmail   = "gmail"
+	ProviderYahoo   = "yahoo"
+)
+
+// mailboxProvider recognises the failures of a large mailbox provider
+type mailboxProvider struct {
+	name string
+	// domains are the first labels of the provider's registrable domains,
+	// so hotmail.co.uk counts like hotmail.com
+	domains []string
+	// mxSuffixes match the MX hosts of domains the provider hosts, e.g. on
+	// Microsoft 365 or Google Workspace
+	mxSuffixes []string
+}
+
+var mailboxProviders = []mailboxProvider{
+	{
+		name:       ProviderOutlook,
+		domains:    []string{"outlook", "hotmail", "live", "msn"},
+		mxSuffixes: []string{".protection.outlook.com", ".hotmail.com"},
+	},
+	{
+		name:       ProviderGmail,
+		domains:    []string{"gmail", "googlemail"},
+		mxSuffixes: []string{".google.com", ".googlemail.com"},
+	},
+	{
+		name:       ProviderYahoo,
+		domains:    []string{"yahoo", "ymail", "rocketmail", "aol"},
+		mxSuffixes: []string{".yahoodns.net"},
+	},
+}
+
+// ProviderOf returns the mailbox provider a failure came from: outlook,
+// gmail or yahoo when the recipient domain or its MX is theirs, otherwise
+// the recipient's organizational domain
+func ProviderOf(f database.SMTPFailure) string {
+	org := f.OrgDomain()
+	first, _, _ := strings.Cut(org, ".")
+	mx := strings.TrimSuffix(strings.ToLower(f.MXServer), ".")
+	for _, p := range mailboxProviders {
+		for _, d := range p.domains {
+			if first == d {
+				return p.name
+			}
+		}
+		for _, suffix := range p.mxSuffixes {
+			if strings.HasSuffix(mx, suffix) {
+				return p.name
+			}
+		}
+	}
+	return org
+}
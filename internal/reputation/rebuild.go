@@ -0,0 +1,344 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Rebuild limits and defaults
+const (
+	// MaxRebuildRange bounds the time range of one rebuild
+	MaxRebuildRange = 90 * 24 * time.Hour
+	// MinRebuildStep is the closest rebuilt history records can be
+	MinRebuildStep = time.Minute
+	// MaxRebuildRecords bounds the history records rebuilt per IP
+	MaxRebuildRecords = 10000
+	// DefaultRebuildStep spaces rebuilt history records an hour apart
+	DefaultRebuildStep = time.Hour
+	// DefaultRebuildRange is the last day
+	DefaultRebuildRange = 24 * time.Hour
+)
+
+// rebuildTrigger marks the metrics transitions and actions of a rebuild
+const rebuildTrigger = "reputation_rebuild"
+
+// RebuildOptions selects what Rebuild recomputes
+type RebuildOptions struct {
+	// IPs to rebuild; empty rebuilds every IP with failures in the range
+	IPs []string
+	// From defaults to DefaultRebuildRange before To
+	From time.Time
+	// To defaults to now. Only a range ending now updates the IPs' current
+	// metrics; history is rebuilt either way.
+	To time.Time
+	// Step is the spacing of the rebuilt history records
+	Step time.Duration
+	// DryRun computes without storing anything
+	DryRun bool
+
+	untilNow bool
+}
+
+// Validate checks the options and fills in the defaults
+func (o *RebuildOptions) Validate(now time.Time) error {
+	for _, ip := range o.IPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid IP %q", ip)
+		}
+	}
+	if o.To.IsZero() {
+		o.To = now
+		o.untilNow = true
+	}
+	if o.To.After(now) {
+		return fmt.Errorf("to must not be in the future")
+	}
+	if o.From.IsZero() {
+		o.From = o.To.Add(-DefaultRebuildRange)
+	}
+	if !o.From.Before(o.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	if o.To.Sub(o.From) > MaxRebuildRange {
+		return fmt.Errorf("the range must not exceed %d days", int(MaxRebuildRange.Hours()/24))
+	}
+	if o.Step == 0 {
+		o.Step = DefaultRebuildStep
+	}
+	if o.Step < MinRebuildStep {
+		return fmt.Errorf("step must be at least %s", MinRebuildStep)
+	}
+	if records := int(o.To.Sub(o.From)/o.Step) + 1; records > MaxRebuildRecords {
+		return fmt.Errorf("the range and step give %d history records per IP, at most %d are allowed", records, MaxRebuildRecords)
+	}
+	return nil
+}
+
+// RebuildResult reports what a rebuild recomputed
+type RebuildResult struct {
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Step           string    `json:"step"`
+	DryRun         bool      `json:"dry_run"`
+	IPs            int       `json:"ips"`
+	HistoryRecords int       `json:"history_records"`
+	// MetricsUpdated counts the IPs whose current metrics were replaced
+	MetricsUpdated int `json:"metrics_updated"`
+	// Changes are the IPs whose current status the rebuild changed, or
+	// would change on a dry run
+	Changes  []RebuildChange  `json:"changes"`
+	Failures []RebuildFailure `json:"failures"`
+}
+
+// RebuildChange is a status changed by a rebuild
+type RebuildChange struct {
+	IP             string `json:"ip"`
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+}
+
+// RebuildFailure is an IP a rebuild could not recompute
+type RebuildFailure struct {
+	IP    string `json:"ip"`
+	Error string `json:"error"`
+}
+
+// Rebuild recomputes the reputation history of IPs, and their current
+// metrics when the range ends now, from the raw SMTP failures under the
+// current config. History records are replayed every Step from From to To
+// through the state machine, starting from the last status recorded before
+// From. Other signals (auth failures, spamtrap hits, complaints, TLS reports
+// and feeds) are only counted in the current metrics, as they are not kept
+// per point in time. No alerts or automated actions are triggered.
+//
+// progress is called after each IP. An IP that fails is reported in the
+// result and the rebuild carries on; ctx ending stops it.
+func (s *AggregationService) Rebuild(ctx context.Context, opts RebuildOptions, progress func(done, total int)) (*RebuildResult, error) {
+	if err := opts.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+	config := s.Config()
+
+	ips := opts.IPs
+	if len(ips) == 0 {
+		var err error
+		if ips, err = database.GetIPsWithFailuresBetween(opts.From.Add(-rebuildLookback(config)), opts.To); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RebuildResult{
+		From:     opts.From,
+		To:       opts.To,
+		Step:     opts.Step.String(),
+		DryRun:   opts.DryRun,
+		IPs:      len(ips),
+		Changes:  []RebuildChange{},
+		Failures: []RebuildFailure{},
+	}
+	for i, ip := range ips {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := s.rebuildIP(ctx, ip, opts, config, result); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			s.logger.WithFields(logrus.Fields{
+				"action": "reputation_rebuild_ip_failed",
+				"ip":     ip,
+				"error":  err.Error(),
+			}).Error("Failed to rebuild IP reputation")
+			result.Failures = append(result.Failures, RebuildFailure{IP: ip, Error: err.Error()})
+		}
+		if progress != nil {
+			progress(i+1, len(ips))
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"action":          "reputation_rebuilt",
+		"ips":             result.IPs,
+		"history_records": result.HistoryRecords,
+		"metrics_updated": result.MetricsUpdated,
+		"changes":         len(result.Changes),
+		"failures":        len(result.Failures),
+		"dry_run":         opts.DryRun,
+	}).Info("Reputation rebuilt from SMTP failures")
+	return result, nil
+}
+
+// rebuildLookback is how far before a point failures count, the longest of
+// the configured windows
+func rebuildLookback(config ReputationConfig) time.Duration {
+	minutes := config.WindowMinutes
+	for _, w := range config.Windows {
+		if w.Minutes > minutes {
+			minutes = w.Minutes
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (s *AggregationService) rebuildIP(ctx context.Context, ip string, opts RebuildOptions, config ReputationConfig, result *RebuildResult) error {
+	failures, err := database.GetSMTPFailuresByIPBetween(ctx, ip, opts.From.Add(-rebuildLookback(config)), opts.To)
+	if err != nil {
+		return err
+	}
+	initial, ok, err := database.GetIPStatusBefore(ip, opts.From)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		initial = state.Unknown.String()
+	}
+
+	history := replayHistory(s.machine, ip, initial, failures, config, opts.From, opts.To, opts.Step)
+
+	if opts.untilNow {
+		last := history[len(history)-1]
+		if err := s.rebuildMetrics(ip, failures, config, last, opts.DryRun, result); err != nil {
+			return err
+		}
+	}
+	if !opts.DryRun {
+		if err := database.ReplaceIPReputationHistory(ip, opts.From, opts.To, history); err != nil {
+			return err
+		}
+	}
+	result.HistoryRecords += len(history)
+	return nil
+}
+
+// replayHistory computes the metrics of ip every step from from to to, and
+// at to, from its failures (newest first, reaching back the longest window
+// before from). Each status is resolved through the state machine from the
+// one before, starting at initial.
+func replayHistory(machine *state.Machine, ip, initial string, failures []database.SMTPFailure, config ReputationConfig, from, to time.Time, step time.Duration) []*database.IPReputationMetrics {
+	var history []*database.IPReputationMetrics
+	status := initial
+	for at := from; ; at = at.Add(step) {
+		if at.After(to) {
+			at = to
+		}
+		metrics := metricsAt(ip, failures, config, at)
+		status = machine.Resolve(state.Status(status), state.Status(metrics.Status)).String()
+		metrics.Status = status
+		history = append(history, metrics)
+		if !at.Before(to) {
+			return history
+		}
+	}
+}
+
+// metricsAt computes the metrics of ip at a point in time from its failures,
+// as aggregation would have then; Status is the decided status, before the
+// state machine
+func metricsAt(ip string, failures []database.SMTPFailure, config ReputationConfig, at time.Time) *database.IPReputationMetrics {
+	health := healthAt(ip, failures, config.WindowMinutes, config.MinVolumeForAssessment, config.DecayHalfLifeMinutes, at)
+	decided, decidingWindow := decideAt(health, failures, config, at)
+	return healthMetrics(health, decided, decidingWindow, at)
+}
+
+// decideAt is the status the health check and the evaluation windows at a
+// point in time support, and the window that decided it if any
+func decideAt(health *IPHealthCheck, failures []database.SMTPFailure, config ReputationConfig, at time.Time) (string, string) {
+	decided := DetermineIPStatus(*health, config)
+	windows := make([]WindowHealth, 0, len(config.Windows))
+	for _, window := range config.Windows {
+		wh := healthAt(health.IP, failures, window.Minutes, window.MinVolumeForAssessment, 0, at)
+		windows = append(windows, WindowHealth{Window: window, Health: *wh, Status: EvaluateWindow(*wh, window)})
+	}
+	return CombineWindowStatus(decided, windows)
+}
+
+// healthAt is CalculateIPHealthCheck at a point in time from failures
+// already loaded, with the sent volume estimated from them
+func healthAt(ip string, failures []database.SMTPFailure, windowMinutes, minVolume, halfLifeMinutes int, at time.Time) *IPHealthCheck {
+	since := at.Add(-time.Duration(windowMinutes) * time.Minute)
+	var window []database.SMTPFailure
+	for _, f := range failures {
+		if !f.Timestamp.Before(since) && !f.Timestamp.After(at) {
+			window = append(window, f)
+		}
+	}
+	totalSent := EstimateSentFromFailures(len(window))
+	if totalSent < minVolume {
+		totalSent = minVolume
+	}
+	health := buildHealthCheck(ip, "", windowMinutes, totalSent, halfLifeMinutes, window, at)
+	health.setVolumeSource(VolumeFromFailures)
+	return health
+}
+
+// rebuildMetrics replaces the current metrics of ip, adding the signals
+// reported out of band to the failures, and corrects the last history
+// record to the status applied
+func (s *AggregationService) rebuildMetrics(ip string, failures []database.SMTPFailure, config ReputationConfig, last *database.IPReputationMetrics, dryRun bool, result *RebuildResult) error {
+	at := last.LastUpdated
+	health := healthAt(ip, failures, config.WindowMinutes, config.MinVolumeForAssessment, config.DecayHalfLifeMinutes, at)
+	var err error
+	if health.AuthFailures, err = database.CountSMTPAuthFailures(ip, "", at.Add(-time.Duration(config.WindowMinutes)*time.Minute)); err != nil {
+		return err
+	}
+	if err := s.AddReportedSignals(health, config, at); err != nil {
+		return err
+	}
+
+	decided, decidingWindow := decideAt(health, failures, config, at)
+
+	oldStatus := state.Unknown.String()
+	if old, err := database.GetIPReputationMetrics(ip); err == nil {
+		oldStatus = old.Status
+	}
+	status := s.machine.Resolve(state.Status(oldStatus), state.Status(decided)).String()
+	metrics := healthMetrics(health, status, decidingWindow, at)
+	last.Status = status
+
+	if status != oldStatus && oldStatus != state.Unknown.String() {
+		result.Changes = append(result.Changes, RebuildChange{IP: ip, PreviousStatus: oldStatus, Status: status})
+	}
+	if dryRun {
+		return nil
+	}
+
+	transition := state.Transition{
+		IP:          ip,
+		From:        state.Status(oldStatus),
+		To:          state.Status(status),
+		TriggeredBy: rebuildTrigger,
+	}
+	if err := s.machine.Apply(transition, func() error {
+		return database.UpsertIPReputationMetrics(metrics)
+	}); err != nil {
+		return fmt.Errorf("failed to save metrics: %w", err)
+	}
+	result.MetricsUpdated++
+
+	if status != oldStatus && oldStatus != state.Unknown.String() {
+		RecordStatusChange(ip, oldStatus, status)
+		if err := database.InsertIPAction(&database.IPAction{
+			IP:             ip,
+			Action:         "status_change",
+			PreviousStatus: oldStatus,
+			NewStatus:      status,
+			Reason:         GetStatusSummary(status, *health),
+			TriggeredBy:    rebuildTrigger,
+			CreatedAt:      time.Now(),
+		}); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "record_rebuild_action_failed",
+				"ip":     ip,
+				"error":  err.Error(),
+			}).Warn("Failed to record status change of rebuild")
+		}
+	}
+	return nil
+}
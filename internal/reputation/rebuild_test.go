@@ -0,0 +1,100 @@
+package reputation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+)
+
+func TestRebuildOptionsValidate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	opts := RebuildOptions{}
+	if err := opts.Validate(now); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if !opts.To.Equal(now) || !opts.untilNow {
+		t.Errorf("Expected To to default to now, got %v", opts.To)
+	}
+	if !opts.From.Equal(now.Add(-DefaultRebuildRange)) || opts.Step != DefaultRebuildStep {
+		t.Errorf("Expected the default range and step, got %v every %s", opts.From, opts.Step)
+	}
+
+	past := RebuildOptions{From: now.Add(-48 * time.Hour), To: now.Add(-24 * time.Hour)}
+	if err := past.Validate(now); err != nil || past.untilNow {
+		t.Errorf("Expected a past range to leave current metrics alone, err = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts RebuildOptions
+		want string
+	}{
+		{"invalid IP", RebuildOptions{IPs: []string{"192.0.2.1", "mail.example.com"}}, "invalid IP"},
+		{"future", RebuildOptions{To: now.Add(time.Hour)}, "future"},
+		{"reversed", RebuildOptions{From: now, To: now.Add(-time.Hour)}, "before"},
+		{"range too long", RebuildOptions{From: now.Add(-91 * 24 * time.Hour), Step: 24 * time.Hour}, "90 days"},
+		{"step too short", RebuildOptions{Step: time.Second}, "at least"},
+		{"too many records", RebuildOptions{From: now.Add(-30 * 24 * time.Hour), Step: time.Minute}, "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := opts.Validate(now)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayHistory(t *testing.T) {
+	config := DefaultReputationConfig()
+	config.Windows = nil
+	from := time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)
+	to := from.Add(3*time.Hour + 30*time.Minute)
+
+	// A burst of blocks just before noon, newest first like the database
+	domains := []string{"gmail.com", "outlook.com", "yahoo.com", "example.org", "example.net"}
+	var failures []database.SMTPFailure
+	for i := 0; i < 60; i++ {
+		domain := domains[i%len(domains)]
+		failures = append(failures, database.SMTPFailure{
+			SendingIP:       "192.0.2.1",
+			RecipientEmail:  fmt.Sprintf("user%d@%s", i, domain),
+			RecipientDomain: domain,
+			SMTPCode:        550,
+			EnhancedCode:    "5.7.1",
+			Reason:          "Message rejected due to local policy",
+			Timestamp:       from.Add(2*time.Hour - time.Duration(i+1)*5*time.Second),
+			MessageID:       fmt.Sprintf("q-%d", i),
+		})
+	}
+
+	history := replayHistory(state.NewMachine(), "192.0.2.1", "blacklisted", failures, config, from, to, time.Hour)
+
+	wantAt := []time.Time{from, from.Add(time.Hour), from.Add(2 * time.Hour), from.Add(3 * time.Hour), to}
+	if len(history) != len(wantAt) {
+		t.Fatalf("Expected %d history records, got %d", len(wantAt), len(history))
+	}
+	for i, metrics := range history {
+		if !metrics.LastUpdated.Equal(wantAt[i]) || !metrics.WindowEnd.Equal(wantAt[i]) {
+			t.Errorf("Record %d at %v, want %v", i, metrics.LastUpdated, wantAt[i])
+		}
+	}
+
+	// A blacklisted IP recovers through quarantine
+	if history[0].Status != "quarantine" || history[1].Status != "healthy" {
+		t.Errorf("Expected quarantine then healthy, got %s then %s", history[0].Status, history[1].Status)
+	}
+	if history[2].Status == "healthy" || history[2].TotalRejected != 60 {
+		t.Errorf("Expected the burst to degrade the IP, got %s with %d rejected", history[2].Status, history[2].TotalRejected)
+	}
+	if history[3].Status != "healthy" || history[3].TotalRejected != 0 {
+		t.Errorf("Expected the IP to recover once the burst left the window, got %s", history[3].Status)
+	}
+}
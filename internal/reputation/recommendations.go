@@ -0,0 +1,168 @@
+package reputation
+
+import (
+	"fmt"
+	"sort"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/i18n"
+	"golang-backend-service/internal/reputation/state"
+)
+
+// IssueTypes are the issue types GetIssueType reports, which recommendation
+// mappings can be limited to
+var IssueTypes = []string{
+	"spamtrap_hit",
+	"recipient_complaints",
+	"external_feed_listing",
+	"tls_negotiation_failure",
+	"tls_requirement_unmet",
+	"content_spam_detected",
+	"ip_reputation_damage",
+	"authentication_failure",
+	"outbound_auth_failure",
+	"infrastructure_misconfiguration",
+	"policy_violation",
+	"list_hygiene_issue",
+	"rate_limiting",
+	"mixed_issues",
+}
+
+// ValidIssueType reports whether issueType is one of IssueTypes
+func ValidIssueType(issueType string) bool {
+	for _, t := range IssueTypes {
+		if t == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+// RecommendationStore reads the recommendation catalog
+type RecommendationStore interface {
+	ListRecommendations() ([]database.Recommendation, error)
+	ListRecommendationMappings() ([]database.RecommendationMapping, error)
+}
+
+type dbRecommendationStore struct{}
+
+func (dbRecommendationStore) ListRecommendations() ([]database.Recommendation, error) {
+	return database.ListRecommendations()
+}
+
+func (dbRecommendationStore) ListRecommendationMappings() ([]database.RecommendationMapping, error) {
+	return database.ListRecommendationMappings()
+}
+
+// RecommendationCatalog picks the recommended actions of an IP from the
+// operator-maintained catalog, so API responses and alerts recommend the
+// same actions with the same texts and runbook links
+type RecommendationCatalog struct {
+	store RecommendationStore
+}
+
+// NewRecommendationCatalog creates a catalog backed by store; a nil store
+// uses the database
+func NewRecommendationCatalog(store RecommendationStore) *RecommendationCatalog {
+	if store == nil {
+		store = dbRecommendationStore{}
+	}
+	return &RecommendationCatalog{store: store}
+}
+
+// Recommend returns the recommendations for an IP with status and
+// issueType, with their texts in language
+func (c *RecommendationCatalog) Recommend(language, status, issueType string) ([]Recommendation, error) {
+	recs, err := c.store.ListRecommendations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recommendations: %w", err)
+	}
+	mappings, err := c.store.ListRecommendationMappings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recommendation mappings: %w", err)
+	}
+	return SelectRecommendations(recs, mappings, language, status, issueType), nil
+}
+
+// RecommendOrDefault is Recommend falling back to the built-in
+// recommendations when the catalog cannot be read
+func (c *RecommendationCatalog) RecommendOrDefault(language, status, issueType string) ([]Recommendation, error) {
+	recommendations, err := c.Recommend(language, status, issueType)
+	if err != nil {
+		return GetLocalizedRecommendations(language, status), err
+	}
+	return recommendations, nil
+}
+
+// SelectRecommendations returns the actions mapped to status, in position
+// order. Mappings for issueType replace the status' catch-all mappings, so
+// a spamtrap hit can recommend list cleaning instead of the generic steps.
+// Statuses other than warning, quarantine and blacklisted use the healthy
+// mappings.
+func SelectRecommendations(recs []database.Recommendation, mappings []database.RecommendationMapping, language, status, issueType string) []Recommendation {
+	switch state.Status(status) {
+	case state.Warning, state.Quarantine, state.Blacklisted:
+	default:
+		status = string(state.Healthy)
+	}
+
+	var specific, fallback []database.RecommendationMapping
+	for _, m := range mappings {
+		if m.Status != status {
+			continue
+		}
+		switch m.IssueType {
+		case "":
+			fallback = append(fallback, m)
+		case issueType:
+			specific = append(specific, m)
+		}
+	}
+	selected := fallback
+	if len(specific) > 0 {
+		selected = specific
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Position < selected[j].Position })
+
+	catalog := make(map[string]database.Recommendation, len(recs))
+	for _, rec := range recs {
+		catalog[rec.Action] = rec
+	}
+	recommendations := []Recommendation{}
+	for _, m := range selected {
+		rec := catalog[m.Action]
+		rec.Action = m.Action
+		recommendations = append(recommendations, Recommendation{
+			Action: m.Action,
+			Text:   recommendationText(rec, language),
+			Link:   rec.Link,
+		})
+	}
+	return recommendations
+}
+
+// recommendationText prefers the operator's text in language, then the
+// built-in translation, then the operator's English text and finally the
+// action itself
+func recommendationText(rec database.Recommendation, language string) string {
+	if text := rec.Texts[language]; text != "" {
+		return text
+	}
+	key := "recommendation." + rec.Action
+	if i18n.Exists(key) {
+		return i18n.Translate(language, key)
+	}
+	if text := rec.Texts[i18n.English]; text != "" {
+		return text
+	}
+	return rec.Action
+}
+
+// RecommendedActionCodes returns the actions of recommendations
+func RecommendedActionCodes(recommendations []Recommendation) []string {
+	codes := make([]string, len(recommendations))
+	for i, r := range recommendations {
+		codes[i] = r.Action
+	}
+	return codes
+}
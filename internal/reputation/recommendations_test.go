@@ -0,0 +1,68 @@
+package reputation
+
+import (
+	"errors"
+	"testing"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestSelectRecommendations(t *testing.T) {
+	recs := []database.Recommendation{
+		{Action: "run_dnsbl_checks", Link: "https://wiki.example.com/runbooks/dnsbl"},
+		{Action: "monitor_closely", Texts: map[string]string{"de": "Im Dashboard beobachten"}},
+		{Action: "clean_trap_addresses", Texts: map[string]string{"en": "Remove the trap addresses from the list"}},
+		{Action: "continue_normal_operations"},
+	}
+	mappings := []database.RecommendationMapping{
+		{ID: 1, Status: "quarantine", Action: "monitor_closely", Position: 2},
+		{ID: 2, Status: "quarantine", Action: "run_dnsbl_checks", Position: 1},
+		{ID: 3, Status: "quarantine", IssueType: "spamtrap_hit", Action: "clean_trap_addresses", Position: 1},
+		{ID: 4, Status: "healthy", Action: "continue_normal_operations", Position: 1},
+	}
+
+	got := SelectRecommendations(recs, mappings, "de", "quarantine", "ip_reputation_damage")
+	if len(got) != 2 || got[0].Action != "run_dnsbl_checks" || got[1].Action != "monitor_closely" {
+		t.Fatalf("Expected the catch-all mappings in position order, got %+v", got)
+	}
+	if got[0].Text != "DNSBL-Prüfungen ausführen" || got[0].Link != "https://wiki.example.com/runbooks/dnsbl" {
+		t.Errorf("Expected the built-in German text with the runbook link, got %+v", got[0])
+	}
+	if got[1].Text != "Im Dashboard beobachten" {
+		t.Errorf("Expected the operator's German text, got %q", got[1].Text)
+	}
+
+	got = SelectRecommendations(recs, mappings, "de", "quarantine", "spamtrap_hit")
+	if len(got) != 1 || got[0].Action != "clean_trap_addresses" || got[0].Text != "Remove the trap addresses from the list" {
+		t.Errorf("Expected the spamtrap mapping to replace the others with its English text, got %+v", got)
+	}
+
+	got = SelectRecommendations(recs, mappings, "en", "unknown", "")
+	if len(got) != 1 || got[0].Action != "continue_normal_operations" || got[0].Text != "Continue normal operations" {
+		t.Errorf("Expected an unknown status to get the healthy recommendations, got %+v", got)
+	}
+	if got := SelectRecommendations(recs, mappings, "en", "blacklisted", ""); len(got) != 0 {
+		t.Errorf("Expected no recommendations without mappings, got %+v", got)
+	}
+}
+
+type failingRecommendationStore struct{}
+
+func (failingRecommendationStore) ListRecommendations() ([]database.Recommendation, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingRecommendationStore) ListRecommendationMappings() ([]database.RecommendationMapping, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRecommendOrDefault(t *testing.T) {
+	catalog := NewRecommendationCatalog(failingRecommendationStore{})
+	got, err := catalog.RecommendOrDefault("en", "warning", "")
+	if err == nil {
+		t.Error("Expected the store error to be returned")
+	}
+	if len(got) != 3 || got[0].Action != "monitor_closely" {
+		t.Errorf("Expected the built-in recommendations, got %+v", got)
+	}
+}
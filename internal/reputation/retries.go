@@ -0,0 +1,55 @@
+package reputation
+
+import (
+	"strconv"
+	"strings"
+
+	"golang-backend-service/internal/database"
+)
+
+// reasonClass groups failures by enhanced status class and subject, e.g.
+// "5.7" for 5.7.1 and 5.7.26, falling back to the SMTP reply class
+func reasonClass(f database.SMTPFailure) string {
+	if parts := strings.SplitN(f.EnhancedCode, ".", 3); len(parts) >= 2 && parts[0] != "" && parts[1] != "" {
+		return parts[0] + "." + parts[1]
+	}
+	return strconv.Itoa(f.SMTPCode / 100)
+}
+
+// dedupeRetries collapses retries of one message so that they count as a
+// single rejection. Failures sharing a message ID, recipient and reason
+// class are one rejection, represented by the first in input order (the
+// latest attempt, as failures are read newest first). Without a message ID,
+// attempts after the first fold into another failure of the same recipient
+// and class, so first attempts are what remain.
+func dedupeRetries(failures []database.SMTPFailure) (kept []database.SMTPFailure, collapsed int) {
+	seen := make(map[string]bool)
+	chains := make(map[string]int)
+	for _, f := range failures {
+		if f.MessageID == "" {
+			chains[strings.ToLower(f.RecipientEmail)+"|"+reasonClass(f)]++
+		}
+	}
+
+	for _, f := range failures {
+		recipient := strings.ToLower(f.RecipientEmail)
+		class := reasonClass(f)
+		if f.MessageID != "" {
+			key := f.MessageID + "|" + recipient + "|" + class
+			if seen[key] {
+				collapsed++
+				continue
+			}
+			seen[key] = true
+		} else if f.AttemptNumber > 1 {
+			chain := recipient + "|" + class
+			if chains[chain] > 1 {
+				chains[chain]--
+				collapsed++
+				continue
+			}
+		}
+		kept = append(kept, f)
+	}
+	return kept, collapsed
+}
@@ -0,0 +1,98 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func TestReasonClass(t *testing.T) {
+	tests := []struct {
+		smtpCode int
+		enhanced string
+		want     string
+	}{
+		{550, "5.7.1", "5.7"},
+		{550, "5.7.26", "5.7"},
+		{421, "4.7.0", "4.7"},
+		{550, "", "5"},
+		{451, "4", "4"},
+	}
+	for _, tt := range tests {
+		if got := reasonClass(database.SMTPFailure{SMTPCode: tt.smtpCode, EnhancedCode: tt.enhanced}); got != tt.want {
+			t.Errorf("reasonClass(%d, %q) = %q, want %q", tt.smtpCode, tt.enhanced, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeRetries(t *testing.T) {
+	now := time.Now()
+	attempt := func(messageID, recipient, enhanced string, number int) database.SMTPFailure {
+		return database.SMTPFailure{
+			MessageID:      messageID,
+			RecipientEmail: recipient,
+			SMTPCode:       550,
+			EnhancedCode:   enhanced,
+			AttemptNumber:  number,
+			Timestamp:      now.Add(-time.Duration(number) * time.Minute),
+		}
+	}
+
+	t.Run("message id", func(t *testing.T) {
+		kept, collapsed := dedupeRetries([]database.SMTPFailure{
+			attempt("q1", "a@example.com", "5.7.26", 3),
+			attempt("q1", "A@example.com", "5.7.1", 2),
+			attempt("q1", "a@example.com", "4.7.0", 1),
+			attempt("q1", "b@example.com", "5.7.1", 1),
+			attempt("q2", "a@example.com", "5.7.1", 1),
+		})
+		if collapsed != 1 || len(kept) != 4 {
+			t.Fatalf("Expected 1 collapsed and 4 kept, got %d and %d", collapsed, len(kept))
+		}
+		if kept[0].AttemptNumber != 3 {
+			t.Errorf("Expected the latest attempt to represent the message, got attempt %d", kept[0].AttemptNumber)
+		}
+	})
+
+	t.Run("attempt numbers", func(t *testing.T) {
+		kept, collapsed := dedupeRetries([]database.SMTPFailure{
+			attempt("", "a@example.com", "5.7.1", 2),
+			attempt("", "b@example.com", "5.7.1", 2),
+			attempt("", "a@example.com", "5.7.1", 1),
+			attempt("", "b@example.com", "5.7.1", 1),
+			attempt("", "a@example.com", "5.7.1", 1),
+			// Earlier attempts fell outside the window
+			attempt("", "c@example.com", "5.7.1", 4),
+		})
+		if collapsed != 2 || len(kept) != 4 {
+			t.Fatalf("Expected 2 collapsed and 4 kept, got %d and %d", collapsed, len(kept))
+		}
+		for _, f := range kept[:3] {
+			if f.AttemptNumber != 1 {
+				t.Errorf("Expected first attempts to remain, got %+v", f)
+			}
+		}
+	})
+}
+
+func TestHealthCheckCountsMessagesNotAttempts(t *testing.T) {
+	now := time.Now()
+	failures := make([]database.SMTPFailure, 0, 5)
+	for i := 5; i >= 1; i-- {
+		failures = append(failures, database.SMTPFailure{
+			MessageID:       "q1",
+			RecipientEmail:  "a@gmail.com",
+			RecipientDomain: "gmail.com",
+			SMTPCode:        550,
+			EnhancedCode:    "5.7.1",
+			AttemptNumber:   i,
+			Timestamp:       now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	health := buildHealthCheck("192.0.2.1", "", 15, 100, 0, failures, now)
+	if health.TotalRejected != 1 || health.RetriesDeduplicated != 4 || health.ReputationCodes["5.7.1"] != 1 {
+		t.Errorf("Expected one rejection for five attempts, got %+v", health)
+	}
+}
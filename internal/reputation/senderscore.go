@@ -0,0 +1,76 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/resolver"
+)
+
+// SenderScoreProvider is the name of Validity's SenderScore lookup
+const SenderScoreProvider = "senderscore"
+
+// senderScoreZone answers d.c.b.a.score.senderscore.com with 127.0.4.N,
+// N being the IP's score from 0 to 100; NXDOMAIN means it has no score
+const senderScoreZone = "score.senderscore.com"
+
+// senderScorePoor is the score below which SenderScore considers an IP's
+// reputation poor and it counts as listed
+const senderScorePoor = 70
+
+// SenderScore looks up IPs in Validity's SenderScore DNS zone
+type SenderScore struct {
+	resolver resolver.Resolver
+}
+
+// NewSenderScore creates a SenderScore provider. A nil resolver falls back to
+// the system DNS.
+func NewSenderScore(r resolver.Resolver) *SenderScore {
+	if r == nil {
+		r = &net.Resolver{PreferGo: true}
+	}
+	return &SenderScore{resolver: r}
+}
+
+// Name implements LookupProvider
+func (s *SenderScore) Name() string {
+	return SenderScoreProvider
+}
+
+// Lookup implements LookupProvider. Only IPv4 addresses are scored.
+func (s *SenderScore) Lookup(ctx context.Context, ip string) (*database.ReputationLookup, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Unmap().Is4() {
+		return nil, ErrLookupUnsupported
+	}
+
+	addrs, err := s.resolver.LookupHost(ctx, reverseIP(addr.Unmap().String())+"."+senderScoreZone)
+	if err != nil {
+		if resolverFailure(err) == nil {
+			return &database.ReputationLookup{Summary: "No SenderScore; too little mail seen from the IP"}, nil
+		}
+		return nil, fmt.Errorf("senderscore lookup failed: %w", err)
+	}
+
+	for _, a := range addrs {
+		answer, err := netip.ParseAddr(a)
+		if err != nil || !answer.Is4() {
+			continue
+		}
+		octets := answer.As4()
+		if octets[0] != 127 || octets[1] != 0 || octets[2] != 4 || octets[3] > 100 {
+			continue
+		}
+		score := float64(octets[3])
+		return &database.ReputationLookup{
+			Score:   &score,
+			Listed:  octets[3] < senderScorePoor,
+			Summary: fmt.Sprintf("SenderScore %d/100", octets[3]),
+			Details: map[string]string{"answer": a},
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected senderscore answer %v", addrs)
+}
@@ -0,0 +1,282 @@
+package reputation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSendRateProvider is the provider of the rate recommended to every
+// provider without a rate of its own
+const DefaultSendRateProvider = "*"
+
+// sendRateTrendWindow is the span rejections are compared over: the last one
+// against the one before
+const sendRateTrendWindow = time.Hour
+
+// minRisingRejections is how many rejections a provider needs in the last
+// trend window before a rise counts
+const minRisingRejections = 3
+
+// Factors applied to the full send rate
+const (
+	warningRateFactor   = 0.5
+	throttledRateFactor = 0.25
+	deferringRateFactor = 0.75 // rate-limit responses, not yet throttled
+	risingRateFactor    = 0.5
+)
+
+// WarmupStage caps the send rate of an IP younger than Days days, counted
+// from its first aggregation run
+type WarmupStage struct {
+	Days            int `json:"days"`
+	MessagesPerHour int `json:"messages_per_hour"`
+}
+
+// DefaultWarmupStages returns a two-week warm-up, doubling every few days
+func DefaultWarmupStages() []WarmupStage {
+	return []WarmupStage{
+		{Days: 1, MessagesPerHour: 50},
+		{Days: 2, MessagesPerHour: 100},
+		{Days: 4, MessagesPerHour: 200},
+		{Days: 7, MessagesPerHour: 500},
+		{Days: 14, MessagesPerHour: 1000},
+	}
+}
+
+// validateWarmupStages checks stages end in increasing order with positive
+// rates
+func validateWarmupStages(stages []WarmupStage) error {
+	last := 0
+	for i, s := range stages {
+		if s.Days <= last {
+			return fmt.Errorf("warmup_stages[%d]: days must be positive and increasing", i)
+		}
+		if s.MessagesPerHour <= 0 {
+			return fmt.Errorf("warmup_stages[%d]: messages_per_hour must be positive", i)
+		}
+		last = s.Days
+	}
+	return nil
+}
+
+// SendRateInput is what a send rate recommendation is made of
+type SendRateInput struct {
+	IP     string
+	Status string
+	// Age is the time since the IP's first aggregation run
+	Age time.Duration
+	// Failures cover at least the last two trend windows
+	Failures  []database.SMTPFailure
+	Throttles []database.ThrottleState
+}
+
+// RecommendSendRates recommends a messages/hour rate for an IP to each
+// provider that throttled or rejected it lately, and to all others under
+// DefaultSendRateProvider. The config's MaxMessagesPerHour is capped by the
+// warm-up stage of the IP's age, then halved for a warning status, cut to a
+// quarter while the provider throttles and by a quarter while it defers
+// without throttling yet, and halved when its permanent rejections more than
+// doubled over the last trend window. Quarantined and blacklisted IPs get 0.
+func RecommendSendRates(in SendRateInput, config ReputationConfig, now time.Time) []database.SendRate {
+	base := config.MaxMessagesPerHour
+	stage := 0
+	reasons := []string{}
+	days := int(in.Age / (24 * time.Hour))
+	for i, s := range config.WarmupStages {
+		if days < s.Days {
+			stage = i + 1
+			if s.MessagesPerHour < base {
+				base = s.MessagesPerHour
+			}
+			reasons = append(reasons, fmt.Sprintf("warm-up stage %d of %d (day %d)", stage, len(config.WarmupStages), days+1))
+			break
+		}
+	}
+
+	factor := 1.0
+	switch state.Status(in.Status) {
+	case state.Warning:
+		factor = warningRateFactor
+		reasons = append(reasons, "IP status is warning")
+	case state.Quarantine, state.Blacklisted:
+		factor = 0
+		reasons = append(reasons, fmt.Sprintf("IP status is %s", in.Status))
+	}
+
+	recommend := func(provider string, factor float64, reasons []string) database.SendRate {
+		rate := int(float64(base) * factor)
+		if rate == 0 && factor > 0 && base > 0 {
+			rate = 1
+		}
+		return database.SendRate{IP: in.IP, Provider: provider, MessagesPerHour: rate, WarmupStage: stage, Reasons: reasons, UpdatedAt: now}
+	}
+	rates := []database.SendRate{recommend(DefaultSendRateProvider, factor, reasons)}
+
+	// Permanent rejections by provider over the last trend window and the
+	// one before
+	type trend struct{ current, previous int }
+	trends := make(map[string]*trend)
+	for _, f := range in.Failures {
+		if isTemporaryFailure(f) || f.Timestamp.After(now) {
+			continue
+		}
+		age := now.Sub(f.Timestamp)
+		if age >= 2*sendRateTrendWindow {
+			continue
+		}
+		provider := ProviderOf(f)
+		t, ok := trends[provider]
+		if !ok {
+			t = &trend{}
+			trends[provider] = t
+		}
+		if age < sendRateTrendWindow {
+			t.current++
+		} else {
+			t.previous++
+		}
+	}
+	throttles := make(map[string]database.ThrottleState)
+	for _, t := range in.Throttles {
+		if t.Throttled || t.ThrottleCount > 0 {
+			throttles[t.Provider] = t
+		}
+	}
+
+	providers := make([]string, 0, len(trends)+len(throttles))
+	for provider := range trends {
+		providers = append(providers, provider)
+	}
+	for provider := range throttles {
+		if _, ok := trends[provider]; !ok {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		f := factor
+		r := append([]string{}, reasons...)
+		if t, ok := throttles[provider]; ok {
+			if t.Throttled {
+				f *= throttledRateFactor
+				r = append(r, fmt.Sprintf("throttled, %d rate-limit responses", t.ThrottleCount))
+			} else {
+				f *= deferringRateFactor
+				r = append(r, fmt.Sprintf("%d rate-limit responses", t.ThrottleCount))
+			}
+		}
+		if t, ok := trends[provider]; ok && t.current >= minRisingRejections && t.current > 2*t.previous {
+			f *= risingRateFactor
+			r = append(r, fmt.Sprintf("rejections rising: %d in the last hour, %d the hour before", t.current, t.previous))
+		}
+		if len(r) == len(reasons) {
+			// Stray rejections, nothing to slow down for
+			continue
+		}
+		rates = append(rates, recommend(provider, f, r))
+	}
+	return rates
+}
+
+// updateSendRates recomputes and stores the send rates recommended for an IP
+func (s *AggregationService) updateSendRates(ip, status string, config ReputationConfig, now time.Time) {
+	if config.MaxMessagesPerHour <= 0 {
+		return
+	}
+	logFailure := func(err error) {
+		s.logger.WithFields(logrus.Fields{
+			"action": "send_rates_update_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Warn("Failed to update send rates")
+	}
+
+	in := SendRateInput{IP: ip, Status: status}
+	firstSeen, ok, err := database.GetIPFirstSeen(ip)
+	if err != nil {
+		logFailure(err)
+		return
+	}
+	if ok {
+		in.Age = now.Sub(firstSeen)
+	}
+	if in.Failures, err = database.GetSMTPFailuresByIP(ip, now.Add(-2*sendRateTrendWindow)); err != nil {
+		logFailure(err)
+		return
+	}
+	if config.ThrottleMinResponses > 0 {
+		if in.Throttles, err = database.GetThrottleStates(ip); err != nil {
+			logFailure(err)
+			return
+		}
+	}
+
+	rates := RecommendSendRates(in, config, now)
+	if mode := remediation.Current(); mode != remediation.Enforce {
+		// Outside enforce mode only the warm-up caps are published; the cuts
+		// for the IP's status, throttling and rising rejections are held
+		// back, and recorded as simulated in dry-run mode
+		planned := RecommendSendRates(SendRateInput{IP: ip, Age: in.Age}, config, now)
+		if mode == remediation.DryRun {
+			s.simulateSendRateCut(ip, status, rates, planned)
+		}
+		rates = planned
+	}
+	if err := database.ReplaceSendRates(ip, rates); err != nil {
+		logFailure(err)
+	}
+}
+
+// simulateSendRateCut records the send rates that would have been
+// recommended in place of planned, once until they change
+func (s *AggregationService) simulateSendRateCut(ip, status string, rates, planned []database.SendRate) {
+	cut := len(rates) > len(planned) || rates[0].MessagesPerHour != planned[0].MessagesPerHour
+	if !cut {
+		s.simulations.Forget("send_rate:" + ip)
+		return
+	}
+	fingerprint := make([]string, 0, len(rates))
+	recommended := make([]map[string]interface{}, 0, len(rates))
+	for _, r := range rates {
+		fingerprint = append(fingerprint, fmt.Sprintf("%s=%d", r.Provider, r.MessagesPerHour))
+		recommended = append(recommended, map[string]interface{}{
+			"provider":          r.Provider,
+			"messages_per_hour": r.MessagesPerHour,
+			"reasons":           r.Reasons,
+		})
+	}
+	if !s.simulations.Changed("send_rate:"+ip, strings.Join(fingerprint, ",")) {
+		return
+	}
+
+	action := &database.IPAction{
+		IP:             ip,
+		Action:         "send_rate_reduced",
+		PreviousStatus: status,
+		NewStatus:      status,
+		Reason:         fmt.Sprintf("send rate cut to %d messages/hour from %d", rates[0].MessagesPerHour, planned[0].MessagesPerHour),
+		TriggeredBy:    "send_rate_policy",
+		Metadata: map[string]interface{}{
+			"send_rates":        recommended,
+			"messages_per_hour": planned[0].MessagesPerHour,
+		},
+		CreatedAt: time.Now(),
+	}
+	remediation.Tag(action, remediation.DryRun)
+	if err := database.InsertIPAction(action); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "record_send_rate_cut_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Error("Failed to record simulated send rate cut")
+	}
+}
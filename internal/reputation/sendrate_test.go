@@ -0,0 +1,96 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+)
+
+func rejection(domain string, ts time.Time) database.SMTPFailure {
+	return database.SMTPFailure{
+		SendingIP:       "192.0.2.1",
+		RecipientEmail:  "user@" + domain,
+		RecipientDomain: domain,
+		SMTPCode:        550,
+		EnhancedCode:    "5.7.1",
+		Reason:          "Message rejected due to poor reputation",
+		Timestamp:       ts,
+	}
+}
+
+func ratesByProvider(rates []database.SendRate) map[string]database.SendRate {
+	byProvider := make(map[string]database.SendRate, len(rates))
+	for _, r := range rates {
+		byProvider[r.Provider] = r
+	}
+	return byProvider
+}
+
+func TestRecommendSendRates(t *testing.T) {
+	config := DefaultReputationConfig()
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	failures := []database.SMTPFailure{
+		// Rising at yahoo: 3 in the last hour, 1 the hour before
+		rejection("yahoo.com", now.Add(-10*time.Minute)),
+		rejection("yahoo.com", now.Add(-20*time.Minute)),
+		rejection("aol.com", now.Add(-30*time.Minute)),
+		rejection("yahoo.com", now.Add(-90*time.Minute)),
+		// A stray rejection is no reason to slow down
+		rejection("example.com", now.Add(-5*time.Minute)),
+	}
+	throttles := []database.ThrottleState{
+		{Provider: ProviderGmail, Throttled: true, ThrottleCount: 4},
+		{Provider: ProviderOutlook, ThrottleCount: 1},
+		{Provider: "cleared.example"},
+	}
+
+	in := SendRateInput{IP: "192.0.2.1", Status: state.Healthy.String(), Age: 30 * 24 * time.Hour, Failures: failures, Throttles: throttles}
+	rates := ratesByProvider(RecommendSendRates(in, config, now))
+	if len(rates) != 4 {
+		t.Fatalf("Expected the default, gmail, outlook and yahoo rates, got %+v", rates)
+	}
+	want := map[string]int{DefaultSendRateProvider: 2000, ProviderGmail: 500, ProviderOutlook: 1500, ProviderYahoo: 1000}
+	for provider, rate := range want {
+		if got := rates[provider]; got.MessagesPerHour != rate || got.WarmupStage != 0 {
+			t.Errorf("%s: expected %d/h warmed up, got %+v", provider, rate, got)
+		}
+	}
+	if len(rates[ProviderYahoo].Reasons) != 1 {
+		t.Errorf("Expected the rising rejections as the reason, got %v", rates[ProviderYahoo].Reasons)
+	}
+
+	// A 3-day-old IP in warning: stage 3 caps the rate at 200, halved
+	in.Age = 3*24*time.Hour + time.Hour
+	in.Status = state.Warning.String()
+	rates = ratesByProvider(RecommendSendRates(in, config, now))
+	if d := rates[DefaultSendRateProvider]; d.MessagesPerHour != 100 || d.WarmupStage != 3 || len(d.Reasons) != 2 {
+		t.Errorf("Expected 100/h at warm-up stage 3, got %+v", d)
+	}
+	if g := rates[ProviderGmail]; g.MessagesPerHour != 25 || len(g.Reasons) != 3 {
+		t.Errorf("Expected 25/h to the throttling provider, got %+v", g)
+	}
+
+	// Nothing is sent from a quarantined IP
+	in.Status = state.Quarantine.String()
+	for _, r := range RecommendSendRates(in, config, now) {
+		if r.MessagesPerHour != 0 {
+			t.Errorf("Expected no sending from a quarantined IP, got %+v", r)
+		}
+	}
+}
+
+func TestRecommendSendRatesNewIP(t *testing.T) {
+	config := DefaultReputationConfig()
+	rates := RecommendSendRates(SendRateInput{IP: "192.0.2.1", Status: state.Unknown.String()}, config, time.Now())
+	if len(rates) != 1 || rates[0].MessagesPerHour != 50 || rates[0].WarmupStage != 1 {
+		t.Errorf("Expected the first warm-up stage for an IP never aggregated, got %+v", rates)
+	}
+
+	config.WarmupStages = nil
+	rates = RecommendSendRates(SendRateInput{IP: "192.0.2.1", Status: state.Unknown.String()}, config, time.Now())
+	if rates[0].MessagesPerHour != config.MaxMessagesPerHour || len(rates[0].Reasons) != 0 {
+		t.Errorf("Expected the full rate without warm-up stages, got %+v", rates[0])
+	}
+}
@@ -0,0 +1,153 @@
+package reputation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/features"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxShadowDivergences caps the IPs a ShadowReport lists individually
+const maxShadowDivergences = 100
+
+// ShadowDivergence is an IP the shadow config classifies differently from
+// the live one
+type ShadowDivergence struct {
+	IP           string `json:"ip"`
+	LiveStatus   string `json:"live_status"`
+	ShadowStatus string `json:"shadow_status"`
+	ShadowRule   string `json:"shadow_rule"`
+}
+
+// ShadowReport compares the live and shadow classifications of one
+// aggregation run. Statuses are compared before the state machine clamps
+// transitions, so they show what each rule set decided.
+type ShadowReport struct {
+	RunAt     time.Time `json:"run_at"`
+	Evaluated int       `json:"evaluated"`
+	Agreed    int       `json:"agreed"`
+	Diverged  int       `json:"diverged"`
+	// Transitions counts diverging IPs by "live->shadow" status pair
+	Transitions map[string]int     `json:"transitions"`
+	Divergences []ShadowDivergence `json:"divergences"`
+	// Truncated is set when more IPs diverged than Divergences lists
+	Truncated bool `json:"truncated"`
+}
+
+func newShadowReport() *ShadowReport {
+	return &ShadowReport{Transitions: make(map[string]int), Divergences: []ShadowDivergence{}}
+}
+
+func (r *ShadowReport) record(ip, liveStatus string, shadow Explanation) {
+	r.Evaluated++
+	if shadow.Status == liveStatus {
+		r.Agreed++
+		return
+	}
+	r.Diverged++
+	r.Transitions[liveStatus+"->"+shadow.Status]++
+	if len(r.Divergences) >= maxShadowDivergences {
+		r.Truncated = true
+		return
+	}
+	r.Divergences = append(r.Divergences, ShadowDivergence{
+		IP:           ip,
+		LiveStatus:   liveStatus,
+		ShadowStatus: shadow.Status,
+		ShadowRule:   shadow.DecidingRule,
+	})
+}
+
+// ShadowConfig returns the shadow thresholds, or nil if none are registered
+func (s *AggregationService) ShadowConfig() *ReputationConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	if s.shadow == nil {
+		return nil
+	}
+	shadow := *s.shadow
+	return &shadow
+}
+
+// SetShadowConfig registers thresholds that are evaluated on every
+// aggregation run but never act. nil removes the shadow config.
+func (s *AggregationService) SetShadowConfig(config *ReputationConfig) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	if config == nil {
+		s.shadow = nil
+		return
+	}
+	shadow := *config
+	s.shadow = &shadow
+}
+
+// ShadowReport returns the comparison from the last aggregation run that had
+// a shadow config, or nil
+func (s *AggregationService) ShadowReport() *ShadowReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadowReport
+}
+
+// evaluateShadow classifies health with the shadow config, if any, and logs
+// and meters the outcome next to the live decision
+func (s *AggregationService) evaluateShadow(health IPHealthCheck, liveStatus string) {
+	shadow := s.ShadowConfig()
+	if shadow == nil || !features.Enabled(features.ShadowRules) {
+		return
+	}
+
+	explanation := ExplainIPStatus(health, *shadow)
+	RecordShadowDecision(liveStatus, explanation.Status)
+
+	s.mu.Lock()
+	if s.shadowRun != nil {
+		s.shadowRun.record(health.IP, liveStatus, explanation)
+	}
+	s.mu.Unlock()
+
+	if explanation.Status != liveStatus {
+		s.logger.WithFields(logrus.Fields{
+			"action":        "shadow_decision_diverged",
+			"ip":            health.IP,
+			"live_status":   liveStatus,
+			"shadow_status": explanation.Status,
+			"shadow_rule":   explanation.DecidingRule,
+		}).Info("Shadow config classifies IP differently")
+	}
+}
+
+// LoadShadowConfig returns the newest stored shadow config and its revision.
+// The config is nil when no shadow is registered or it was removed.
+func LoadShadowConfig() (*ReputationConfig, *database.ConfigRevision, error) {
+	rev, err := database.GetLatestConfigRevision(database.ConfigScopeReputationShadow)
+	if err != nil || rev == nil {
+		return nil, nil, err
+	}
+	config, err := DecodeShadowConfig(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, rev, nil
+}
+
+// DecodeShadowConfig reads a shadow revision. A JSON null config records
+// that the shadow was removed and decodes to nil.
+func DecodeShadowConfig(rev *database.ConfigRevision) (*ReputationConfig, error) {
+	if string(rev.Config) == "null" {
+		return nil, nil
+	}
+	config := DefaultReputationConfig()
+	if err := json.Unmarshal(rev.Config, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode shadow config revision %d: %w", rev.ID, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("shadow config revision %d is invalid: %w", rev.ID, err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,62 @@
+package reputation
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestShadowReportRecord(t *testing.T) {
+	report := newShadowReport()
+	report.record("192.0.2.1", "healthy", Explanation{Status: "healthy"})
+	report.record("192.0.2.2", "healthy", Explanation{Status: "warning", DecidingRule: "warning_rejection_ratio"})
+	for i := 0; i < maxShadowDivergences; i++ {
+		report.record(fmt.Sprintf("198.51.100.%d", i), "warning", Explanation{Status: "quarantine"})
+	}
+
+	if report.Evaluated != maxShadowDivergences+2 || report.Agreed != 1 || report.Diverged != maxShadowDivergences+1 {
+		t.Errorf("Unexpected counts: %+v", report)
+	}
+	if report.Transitions["healthy->warning"] != 1 || report.Transitions["warning->quarantine"] != maxShadowDivergences {
+		t.Errorf("Unexpected transitions: %v", report.Transitions)
+	}
+	if len(report.Divergences) != maxShadowDivergences || !report.Truncated {
+		t.Errorf("Expected %d divergences and truncation, got %d, %v", maxShadowDivergences, len(report.Divergences), report.Truncated)
+	}
+	if first := report.Divergences[0]; first.IP != "192.0.2.2" || first.ShadowRule != "warning_rejection_ratio" {
+		t.Errorf("Unexpected first divergence: %+v", first)
+	}
+}
+
+func TestEvaluateShadowNeverChangesLiveConfig(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	s := NewAggregationService(DefaultReputationConfig(), logger)
+
+	strict := DefaultReputationConfig()
+	strict.WarningRejectionRatio = 0.01
+	s.SetShadowConfig(&strict)
+	strict.WarningRejectionRatio = 0.5
+	if got := s.ShadowConfig().WarningRejectionRatio; got != 0.01 {
+		t.Errorf("SetShadowConfig kept a reference to the caller's config: %v", got)
+	}
+
+	health := IPHealthCheck{IP: "192.0.2.1", TotalSent: 1000, TotalRejected: 15, RejectionRatio: 0.015}
+	live := DetermineIPStatus(health, s.Config())
+
+	s.shadowRun = newShadowReport()
+	s.evaluateShadow(health, live)
+	if s.shadowRun.Diverged != 1 || s.shadowRun.Divergences[0].ShadowStatus != "warning" {
+		t.Errorf("Expected the shadow config to classify the IP as warning, got %+v", s.shadowRun)
+	}
+	if !reflect.DeepEqual(s.Config(), DefaultReputationConfig()) {
+		t.Error("Shadow evaluation changed the live config")
+	}
+
+	s.SetShadowConfig(nil)
+	s.evaluateShadow(health, live)
+	if s.shadowRun.Evaluated != 1 {
+		t.Error("Expected no shadow evaluation after removing the shadow config")
+	}
+}
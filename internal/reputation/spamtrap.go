@@ -0,0 +1,95 @@
+package reputation
+
+import (
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/remediation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SpamtrapOutcome is what recording a spamtrap hit did to its IP
+type SpamtrapOutcome struct {
+	// Hits counts the IP's hits within the config's SpamtrapWindowHours,
+	// including this one
+	Hits           int    `json:"hits"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Quarantined    bool   `json:"quarantined"`
+	// Simulated is set when the quarantine was only recorded, in dry-run
+	// remediation mode
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// HandleSpamtrapHit acts on a stored spamtrap hit. Once the IP has
+// SpamtrapQuarantineHits hits within the window it is quarantined right
+// away, whatever its ratios. Every hit is recorded in the IP's action
+// history, as spamtrap_quarantine when it quarantined the IP.
+func (s *AggregationService) HandleSpamtrapHit(hit *database.SpamtrapHit) (*SpamtrapOutcome, error) {
+	config := s.Config()
+	RecordSpamtrapHit(hit.SendingIP, hit.Source, hit.TrapType)
+
+	outcome := &SpamtrapOutcome{}
+	mode := remediation.Current()
+	if config.SpamtrapQuarantineHits > 0 {
+		since := time.Now().Add(-time.Duration(config.SpamtrapWindowHours) * time.Hour)
+		hits, err := database.CountSpamtrapHits(hit.SendingIP, since)
+		if err != nil {
+			return nil, err
+		}
+		outcome.Hits = hits
+
+		if hits >= config.SpamtrapQuarantineHits {
+			previous, current, err := s.forceQuarantine(hit.SendingIP, "spamtrap", mode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to quarantine IP: %w", err)
+			}
+			outcome.PreviousStatus, outcome.Status = previous, current
+			outcome.Quarantined = previous != current
+			outcome.Simulated = outcome.Quarantined && mode == remediation.DryRun
+		}
+	}
+
+	action := "spamtrap_hit"
+	if outcome.Quarantined {
+		action = "spamtrap_quarantine"
+	}
+	s.logger.WithFields(logrus.Fields{
+		"action":    action,
+		"ip":        hit.SendingIP,
+		"source":    hit.Source,
+		"trap_type": hit.TrapType,
+		"hits":      outcome.Hits,
+		"status":    outcome.Status,
+	}).Warn("Spamtrap hit recorded")
+
+	record := &database.IPAction{
+		IP:             hit.SendingIP,
+		Action:         action,
+		PreviousStatus: outcome.PreviousStatus,
+		NewStatus:      outcome.Status,
+		Reason:         fmt.Sprintf("%s spamtrap hit reported by %s (%d within %dh)", hit.TrapType, hit.Source, outcome.Hits, config.SpamtrapWindowHours),
+		TriggeredBy:    "spamtrap",
+		Metadata: map[string]interface{}{
+			"spamtrap_hit_id": hit.ID,
+			"source":          hit.Source,
+			"trap_type":       hit.TrapType,
+			"report_id":       hit.ReportID,
+			"hit_at":          hit.HitAt,
+		},
+		CreatedAt: time.Now(),
+	}
+	if outcome.Simulated {
+		remediation.Tag(record, mode)
+	}
+	if err := database.InsertIPAction(record); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "record_spamtrap_hit_failed",
+			"ip":     hit.SendingIP,
+			"error":  err.Error(),
+		}).Error("Failed to record spamtrap action")
+	}
+	return outcome, nil
+}
@@ -0,0 +1,228 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Status is the lifecycle state of a sending IP
+type Status string
+
+// Lifecycle states in increasing order of severity
+const (
+	Unknown     Status = "unknown"
+	Healthy     Status = "healthy"
+	Warning     Status = "warning"
+	Quarantine  Status = "quarantine"
+	Blacklisted Status = "blacklisted"
+)
+
+// ErrInvalidTransition is returned when a transition is not in the allowed table
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// String returns the string form stored in the database
+func (s Status) String() string {
+	return string(s)
+}
+
+// IsValid reports whether s is a known lifecycle state
+func (s Status) IsValid() bool {
+	switch s {
+	case Unknown, Healthy, Warning, Quarantine, Blacklisted:
+		return true
+	}
+	return false
+}
+
+// Severity returns the numeric severity of a state (0=unknown, 4=blacklisted)
+func (s Status) Severity() int {
+	switch s {
+	case Healthy:
+		return 1
+	case Warning:
+		return 2
+	case Quarantine:
+		return 3
+	case Blacklisted:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Parse converts a stored status string into a Status
+func Parse(s string) (Status, error) {
+	status := Status(s)
+	if !status.IsValid() {
+		return Unknown, fmt.Errorf("unknown status: %s", s)
+	}
+	return status, nil
+}
+
+// All returns the assessable states (excluding Unknown) in severity order
+func All() []Status {
+	return []Status{Healthy, Warning, Quarantine, Blacklisted}
+}
+
+// Transition describes a single status change for an IP
+type Transition struct {
+	IP          string `json:"ip"`
+	From        Status `json:"from"`
+	To          Status `json:"to"`
+	TriggeredBy string `json:"triggered_by"`
+}
+
+// Guard can veto a transition by returning an error
+type Guard func(t Transition) error
+
+// Hook is invoked after a transition has been applied
+type Hook func(t Transition)
+
+// Machine enforces the allowed IP status transitions
+type Machine struct {
+	mu      sync.RWMutex
+	allowed map[Status]map[Status]bool
+	guards  []Guard
+	hooks   []Hook
+}
+
+// defaultTransitions is the allowed transition table.
+// A blacklisted IP must pass through quarantine before it can recover.
+var defaultTransitions = map[Status][]Status{
+	Unknown:     {Healthy, Warning, Quarantine, Blacklisted},
+	Healthy:     {Warning, Quarantine, Blacklisted},
+	Warning:     {Healthy, Quarantine, Blacklisted},
+	Quarantine:  {Healthy, Warning, Blacklisted},
+	Blacklisted: {Quarantine},
+}
+
+// Default is the shared machine used by aggregation and the API handlers
+var Default = NewMachine()
+
+// NewMachine creates a state machine with the default transition table
+func NewMachine() *Machine {
+	m := &Machine{
+		allowed: make(map[Status]map[Status]bool),
+	}
+
+	for from, targets := range defaultTransitions {
+		m.allowed[from] = make(map[Status]bool)
+		for _, to := range targets {
+			m.allowed[from][to] = true
+		}
+	}
+
+	return m
+}
+
+// AddGuard registers a guard that is evaluated before every transition
+func (m *Machine) AddGuard(g Guard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guards = append(m.guards, g)
+}
+
+// AddHook registers a hook that runs after every applied transition
+func (m *Machine) AddHook(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// CanTransition reports whether from -> to is in the allowed table.
+// Staying in the same state is always allowed.
+func (m *Machine) CanTransition(from, to Status) bool {
+	if from == to {
+		return true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.allowed[from][to]
+}
+
+// Resolve returns the state an IP should move to when the decision engine
+// asks for target. If the direct transition is not allowed, the allowed
+// state closest in severity to target is returned instead.
+func (m *Machine) Resolve(from, target Status) Status {
+	if m.CanTransition(from, target) {
+		return target
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	best := from
+	bestDistance := abs(from.Severity() - target.Severity())
+	for _, candidate := range All() {
+		if !m.allowed[from][candidate] {
+			continue
+		}
+		distance := abs(candidate.Severity() - target.Severity())
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// Validate checks the transition table and all guards without applying it
+func (m *Machine) Validate(t Transition) error {
+	if !t.To.IsValid() || t.To == Unknown {
+		return fmt.Errorf("%w: invalid target status %q", ErrInvalidTransition, t.To)
+	}
+
+	if !m.CanTransition(t.From, t.To) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, t.From, t.To)
+	}
+
+	m.mu.RLock()
+	guards := append([]Guard(nil), m.guards...)
+	m.mu.RUnlock()
+
+	for _, guard := range guards {
+		if err := guard(t); err != nil {
+			return fmt.Errorf("transition %s -> %s rejected: %w", t.From, t.To, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply validates the transition, persists it with the supplied function
+// and then runs the registered hooks. Hooks do not run if persist fails.
+func (m *Machine) Apply(t Transition, persist func() error) error {
+	if err := m.Validate(t); err != nil {
+		return err
+	}
+
+	if persist != nil {
+		if err := persist(); err != nil {
+			return err
+		}
+	}
+
+	if t.From == t.To {
+		return nil
+	}
+
+	m.mu.RLock()
+	hooks := append([]Hook(nil), m.hooks...)
+	m.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(t)
+	}
+
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestCanTransition tests the default transition table
+func TestCanTransition(t *testing.T) {
+	m := NewMachine()
+
+	tests := []struct {
+		name    string
+		from    Status
+		to      Status
+		allowed bool
+	}{
+		{name: "healthy to warning", from: Healthy, to: Warning, allowed: true},
+		{name: "healthy to blacklisted", from: Healthy, to: Blacklisted, allowed: true},
+		{name: "quarantine to healthy", from: Quarantine, to: Healthy, allowed: true},
+		{name: "blacklisted to quarantine", from: Blacklisted, to: Quarantine, allowed: true},
+		{name: "blacklisted to healthy", from: Blacklisted, to: Healthy, allowed: false},
+		{name: "blacklisted to warning", from: Blacklisted, to: Warning, allowed: false},
+		{name: "unknown to blacklisted", from: Unknown, to: Blacklisted, allowed: true},
+		{name: "same state", from: Blacklisted, to: Blacklisted, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.CanTransition(tt.from, tt.to); got != tt.allowed {
+				t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.allowed)
+			}
+		})
+	}
+}
+
+// TestResolve tests that disallowed targets are clamped to the nearest allowed state
+func TestResolve(t *testing.T) {
+	m := NewMachine()
+
+	if got := m.Resolve(Blacklisted, Healthy); got != Quarantine {
+		t.Errorf("Expected blacklisted -> healthy to resolve to quarantine, got %s", got)
+	}
+
+	if got := m.Resolve(Warning, Healthy); got != Healthy {
+		t.Errorf("Expected warning -> healthy to resolve to healthy, got %s", got)
+	}
+}
+
+// TestApplyGuardsAndHooks tests that guards veto transitions and hooks run after persisting
+func TestApplyGuardsAndHooks(t *testing.T) {
+	m := NewMachine()
+
+	var hooked []Transition
+	m.AddHook(func(tr Transition) {
+		hooked = append(hooked, tr)
+	})
+	m.AddGuard(func(tr Transition) error {
+		if tr.TriggeredBy == "blocked" {
+			return errors.New("blocked by guard")
+		}
+		return nil
+	})
+
+	persisted := false
+	err := m.Apply(Transition{IP: "192.0.2.1", From: Healthy, To: Warning}, func() error {
+		persisted = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !persisted || len(hooked) != 1 {
+		t.Errorf("Expected persist and one hook call, got persisted=%v hooks=%d", persisted, len(hooked))
+	}
+
+	err = m.Apply(Transition{IP: "192.0.2.1", From: Blacklisted, To: Healthy}, nil)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+
+	err = m.Apply(Transition{IP: "192.0.2.1", From: Healthy, To: Warning, TriggeredBy: "blocked"}, nil)
+	if err == nil {
+		t.Error("Expected guard to reject transition")
+	}
+
+	persistErr := errors.New("db down")
+	err = m.Apply(Transition{IP: "192.0.2.1", From: Healthy, To: Quarantine}, func() error {
+		return persistErr
+	})
+	if !errors.Is(err, persistErr) {
+		t.Errorf("Expected persist error, got %v", err)
+	}
+	if len(hooked) != 1 {
+		t.Errorf("Hooks should not run when persist fails, got %d calls", len(hooked))
+	}
+}
@@ -0,0 +1,197 @@
+package reputation
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backoff recommended to MTAs while a provider throttles an IP: it starts at
+// throttleBaseBackoff and doubles for every hour the throttling lasts, up to
+// throttleMaxBackoff
+const (
+	throttleBaseBackoff = 5 * time.Minute
+	throttleMaxBackoff  = 4 * time.Hour
+)
+
+// throttleCodes are the enhanced codes providers rate-limit with: Gmail's
+// 4.7.28 unusual rate and Microsoft's 4.7.650-4.7.652 rate limits. Other
+// codes, 4.7.0 mostly, only count when the reason says so.
+var throttleCodes = map[string]bool{
+	"4.7.28":  true,
+	"4.7.650": true,
+	"4.7.651": true,
+	"4.7.652": true,
+}
+
+var throttleReasons = []string{"rate limit", "rate-limit", "ratelimit", "unusual rate", "too many", "throttl", "slow down", "unexpected volume", "temporarily deferred", "[ts0", "[tss0"}
+
+func mentionsThrottling(reason string) bool {
+	reason = strings.ToLower(reason)
+	for _, hint := range throttleReasons {
+		if strings.Contains(reason, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isThrottle reports whether a failure is a provider rate-limiting the IP.
+// Greylisting also defers with 4.7.0 but is not a rate limit.
+func isThrottle(f database.SMTPFailure) bool {
+	return isTemporaryFailure(f) && !mentionsGreylisting(f.Reason) &&
+		(throttleCodes[f.EnhancedCode] || mentionsThrottling(f.Reason))
+}
+
+// throttleBackoff is the backoff recommended for a throttling that started
+// at since
+func throttleBackoff(since, now time.Time) time.Duration {
+	backoff := throttleBaseBackoff
+	for elapsed := now.Sub(since); elapsed >= time.Hour && backoff < throttleMaxBackoff; elapsed -= time.Hour {
+		backoff *= 2
+	}
+	if backoff > throttleMaxBackoff {
+		backoff = throttleMaxBackoff
+	}
+	return backoff
+}
+
+// EvaluateThrottleStates updates the throttling states of an IP from its
+// failures within the config's ThrottleWindowMinutes. A provider throttles
+// the IP once it sent ThrottleMinResponses rate-limit responses in the
+// window, and keeps throttling it, from the same Since, until the window
+// holds none. Only the states with responses in the window or that need
+// clearing are returned, by provider.
+func EvaluateThrottleStates(ip string, failures []database.SMTPFailure, previous []database.ThrottleState, config ReputationConfig, now time.Time) []database.ThrottleState {
+	windowStart := now.Add(-time.Duration(config.ThrottleWindowMinutes) * time.Minute)
+
+	type responses struct {
+		count       int
+		first, last time.Time
+	}
+	byProvider := make(map[string]*responses)
+	for _, f := range failures {
+		if f.Timestamp.Before(windowStart) || f.Timestamp.After(now) || !isThrottle(f) {
+			continue
+		}
+		provider := ProviderOf(f)
+		r, ok := byProvider[provider]
+		if !ok {
+			r = &responses{first: f.Timestamp, last: f.Timestamp}
+			byProvider[provider] = r
+		}
+		r.count++
+		if f.Timestamp.Before(r.first) {
+			r.first = f.Timestamp
+		}
+		if f.Timestamp.After(r.last) {
+			r.last = f.Timestamp
+		}
+	}
+
+	prev := make(map[string]database.ThrottleState, len(previous))
+	for _, p := range previous {
+		prev[p.Provider] = p
+	}
+	providers := make([]string, 0, len(byProvider)+len(prev))
+	for provider := range byProvider {
+		providers = append(providers, provider)
+	}
+	for provider := range prev {
+		if _, ok := byProvider[provider]; !ok {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+
+	states := make([]database.ThrottleState, 0, len(providers))
+	for _, provider := range providers {
+		p, existed := prev[provider]
+		s := database.ThrottleState{IP: ip, Provider: provider, LastThrottledAt: p.LastThrottledAt, UpdatedAt: now}
+		r, ok := byProvider[provider]
+		if ok {
+			last := r.last
+			s.LastThrottledAt = &last
+			s.ThrottleCount = r.count
+			s.Throttled = r.count >= config.ThrottleMinResponses || p.Throttled
+		}
+		if !s.Throttled {
+			if ok || (existed && (p.Throttled || p.ThrottleCount > 0)) {
+				states = append(states, s)
+			}
+			continue
+		}
+
+		since := r.first
+		if p.Throttled && p.Since != nil {
+			since = *p.Since
+		}
+		backoff := throttleBackoff(since, now)
+		retryAfter := r.last.Add(backoff)
+		s.Since = &since
+		s.BackoffSeconds = int(backoff.Seconds())
+		s.RetryAfter = &retryAfter
+		states = append(states, s)
+	}
+	return states
+}
+
+// updateThrottleStates recomputes and stores an IP's throttling state at
+// each provider, logging providers that start or stop throttling it
+func (s *AggregationService) updateThrottleStates(ip string, config ReputationConfig, now time.Time) {
+	if config.ThrottleMinResponses <= 0 {
+		return
+	}
+	logFailure := func(err error) {
+		s.logger.WithFields(logrus.Fields{
+			"action": "throttle_states_update_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Warn("Failed to update throttle states")
+	}
+
+	failures, err := database.GetSMTPFailuresByIP(ip, now.Add(-time.Duration(config.ThrottleWindowMinutes)*time.Minute))
+	if err != nil {
+		logFailure(err)
+		return
+	}
+	previous, err := database.GetThrottleStates(ip)
+	if err != nil {
+		logFailure(err)
+		return
+	}
+	wasThrottled := make(map[string]bool, len(previous))
+	for _, p := range previous {
+		wasThrottled[p.Provider] = p.Throttled
+	}
+
+	for _, state := range EvaluateThrottleStates(ip, failures, previous, config, now) {
+		if err := database.UpsertThrottleState(&state); err != nil {
+			logFailure(err)
+			return
+		}
+		if state.Throttled == wasThrottled[state.Provider] {
+			continue
+		}
+		RecordThrottleStateChange(state.Provider, state.Throttled)
+		if state.Throttled {
+			s.logger.WithFields(logrus.Fields{
+				"action":          "provider_throttling_started",
+				"ip":              ip,
+				"provider":        state.Provider,
+				"throttle_count":  state.ThrottleCount,
+				"backoff_seconds": state.BackoffSeconds,
+			}).Warn("Destination provider is throttling the IP")
+		} else {
+			s.logger.WithFields(logrus.Fields{
+				"action":   "provider_throttling_cleared",
+				"ip":       ip,
+				"provider": state.Provider,
+			}).Info("Destination provider stopped throttling the IP")
+		}
+	}
+}
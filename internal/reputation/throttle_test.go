@@ -0,0 +1,118 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+func throttleFailure(domain, mx, enhanced, reason string, ts time.Time) database.SMTPFailure {
+	return database.SMTPFailure{
+		SendingIP:       "192.0.2.1",
+		RecipientEmail:  "user@" + domain,
+		RecipientDomain: domain,
+		MXServer:        mx,
+		SMTPCode:        421,
+		EnhancedCode:    enhanced,
+		Reason:          reason,
+		Timestamp:       ts,
+	}
+}
+
+func TestIsThrottle(t *testing.T) {
+	now := time.Now()
+	permanent := throttleFailure("gmail.com", "", "5.7.28", "Unusual rate of unsolicited mail", now)
+	permanent.SMTPCode = 550
+	cases := []struct {
+		failure database.SMTPFailure
+		want    bool
+	}{
+		{throttleFailure("gmail.com", "", "4.7.28", "Our system has detected an unusual rate of unsolicited mail", now), true},
+		{throttleFailure("outlook.com", "", "4.7.650", "The mail server has been temporarily rate limited due to IP reputation", now), true},
+		{throttleFailure("yahoo.com", "", "4.7.0", "[TSS04] Messages from 192.0.2.1 temporarily deferred due to unexpected volume or user complaints", now), true},
+		{throttleFailure("example.com", "", "4.7.1", "Too many connections from your IP", now), true},
+		{throttleFailure("example.com", "", "4.7.0", "Greylisted, please try again later", now), false},
+		{throttleFailure("example.com", "", "4.2.2", "Mailbox full", now), false},
+		{permanent, false},
+	}
+
+	for _, c := range cases {
+		if got := isThrottle(c.failure); got != c.want {
+			t.Errorf("isThrottle(%s %q) = %v, want %v", c.failure.EnhancedCode, c.failure.Reason, got, c.want)
+		}
+	}
+}
+
+func TestProviderOf(t *testing.T) {
+	cases := map[string]database.SMTPFailure{
+		ProviderOutlook: {RecipientDomain: "hotmail.co.uk"},
+		ProviderGmail:   {RecipientDomain: "company.example", MXServer: "aspmx.l.google.com."},
+		ProviderYahoo:   {RecipientDomain: "aol.com"},
+		"example.org":   {RecipientDomain: "mail.example.org", MXServer: "mx.example.org"},
+	}
+	for want, f := range cases {
+		if got := ProviderOf(f); got != want {
+			t.Errorf("ProviderOf(%s, %s) = %q, want %q", f.RecipientDomain, f.MXServer, got, want)
+		}
+	}
+}
+
+func TestEvaluateThrottleStates(t *testing.T) {
+	config := DefaultReputationConfig()
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	gmail := func(ago time.Duration) database.SMTPFailure {
+		return throttleFailure("gmail.com", "gmail-smtp-in.l.google.com", "4.7.28", "Unusual rate of unsolicited mail", now.Add(-ago))
+	}
+	failures := []database.SMTPFailure{
+		gmail(50 * time.Minute),
+		gmail(30 * time.Minute),
+		gmail(10 * time.Minute),
+		// Too old for the window
+		gmail(2 * time.Hour),
+		// Below the threshold
+		throttleFailure("outlook.com", "", "4.7.650", "Temporarily rate limited", now.Add(-5*time.Minute)),
+		// Not a throttle
+		throttleFailure("yahoo.com", "", "4.2.2", "Mailbox full", now.Add(-5*time.Minute)),
+	}
+
+	states := EvaluateThrottleStates("192.0.2.1", failures, nil, config, now)
+	if len(states) != 2 || states[0].Provider != ProviderGmail || states[1].Provider != ProviderOutlook {
+		t.Fatalf("Unexpected states: %+v", states)
+	}
+	g := states[0]
+	if !g.Throttled || g.ThrottleCount != 3 || !g.Since.Equal(now.Add(-50*time.Minute)) {
+		t.Errorf("Expected gmail throttling since the first response, got %+v", g)
+	}
+	if g.BackoffSeconds != 300 || !g.RetryAfter.Equal(now.Add(-5*time.Minute)) {
+		t.Errorf("Expected a 5m backoff after the last response, got %ds until %s", g.BackoffSeconds, g.RetryAfter)
+	}
+	if o := states[1]; o.Throttled || o.ThrottleCount != 1 || o.Since != nil || o.RetryAfter != nil {
+		t.Errorf("Expected outlook not throttled, got %+v", o)
+	}
+
+	// Throttled for three hours: Since is kept and the backoff doubled thrice,
+	// and a single response keeps the throttling going
+	since := now.Add(-3 * time.Hour)
+	previous := []database.ThrottleState{{IP: "192.0.2.1", Provider: ProviderGmail, Throttled: true, Since: &since, ThrottleCount: 3}}
+	states = EvaluateThrottleStates("192.0.2.1", []database.SMTPFailure{gmail(10 * time.Minute)}, previous, config, now)
+	if len(states) != 1 || !states[0].Throttled || !states[0].Since.Equal(since) || states[0].BackoffSeconds != 40*60 {
+		t.Errorf("Expected the throttling continued with a 40m backoff, got %+v", states)
+	}
+
+	// No responses left in the window clears it, once
+	states = EvaluateThrottleStates("192.0.2.1", nil, previous, config, now)
+	if len(states) != 1 || states[0].Throttled || states[0].ThrottleCount != 0 || states[0].Since != nil || states[0].BackoffSeconds != 0 {
+		t.Errorf("Expected the throttling cleared, got %+v", states)
+	}
+	if states = EvaluateThrottleStates("192.0.2.1", nil, states, config, now); len(states) != 0 {
+		t.Errorf("Expected a cleared state left alone, got %+v", states)
+	}
+}
+
+func TestThrottleBackoffCapped(t *testing.T) {
+	now := time.Now()
+	if got := throttleBackoff(now.Add(-48*time.Hour), now); got != throttleMaxBackoff {
+		t.Errorf("throttleBackoff after 48h = %s, want %s", got, throttleMaxBackoff)
+	}
+}
@@ -0,0 +1,51 @@
+package reputation
+
+import (
+	"strings"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/tlspolicy"
+)
+
+// tlsCodes are the enhanced codes servers refuse mail without (valid) TLS
+// with: 4.7.5/5.7.5 cryptographic failure, 5.7.10 encryption needed
+var tlsCodes = map[string]bool{
+	"4.7.5":  true,
+	"5.7.5":  true,
+	"4.7.10": true,
+	"5.7.10": true,
+}
+
+var tlsReasons = []string{"starttls", "tls required", "tls is required", "encryption required", "encryption needed", "certificate", "tlsa", "dane", "mta-sts"}
+
+func mentionsTLS(reason string) bool {
+	reason = strings.ToLower(reason)
+	for _, hint := range tlsReasons {
+		if strings.Contains(reason, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTLSRequirement reports whether f was caused by its destination's TLS
+// requirement
+func isTLSRequirement(f database.SMTPFailure) bool {
+	return tlspolicy.RequiresTLS(f.TLSPolicy) && (tlsCodes[f.EnhancedCode] || f.SMTPCode == 530 || mentionsTLS(f.Reason))
+}
+
+// splitTLSRequired separates failures caused by a destination's TLS
+// requirement from the failures that count towards reputation. A failure is
+// a TLS requirement when the destination had an MTA-STS enforce or DANE
+// policy at ingestion and its code or reason is about TLS. Order is
+// preserved.
+func splitTLSRequired(failures []database.SMTPFailure) (kept, tlsRequired []database.SMTPFailure) {
+	for _, f := range failures {
+		if isTLSRequirement(f) {
+			tlsRequired = append(tlsRequired, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, tlsRequired
+}
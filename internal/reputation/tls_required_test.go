@@ -0,0 +1,58 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/tlspolicy"
+)
+
+func tlsFailure(recipient string, smtpCode int, enhanced, reason, policy string, now time.Time) database.SMTPFailure {
+	f := testFailure(recipient, smtpCode, enhanced, reason, time.Minute, now)
+	f.TLSPolicy = policy
+	return f
+}
+
+func TestSplitTLSRequired(t *testing.T) {
+	now := time.Now()
+	failures := []database.SMTPFailure{
+		tlsFailure("a@gmail.com", 550, "5.7.10", "Encryption needed", tlspolicy.RequirementSTSEnforce, now),
+		tlsFailure("b@posteo.de", 451, "4.7.5", "TLSA record mismatch", tlspolicy.RequirementDANE, now),
+		tlsFailure("c@gmail.com", 530, "5.7.0", "Must issue a STARTTLS command first", tlspolicy.RequirementSTSEnforce, now),
+		// A reputation block at a TLS-requiring destination still counts
+		tlsFailure("d@gmail.com", 550, "5.7.1", "Blocked", tlspolicy.RequirementSTSEnforce, now),
+		// Testing mode, no policy or an unknown one do not refuse mail over TLS
+		tlsFailure("e@example.com", 550, "5.7.10", "Encryption needed", tlspolicy.RequirementSTSTesting, now),
+		tlsFailure("f@example.com", 550, "5.7.10", "Encryption needed", tlspolicy.RequirementNone, now),
+		tlsFailure("g@example.com", 550, "5.7.10", "Encryption needed", "", now),
+	}
+
+	kept, tlsRequired := splitTLSRequired(failures)
+	if len(tlsRequired) != 3 || len(kept) != 4 {
+		t.Fatalf("Expected 3 TLS requirement failures and 4 kept, got %d and %d", len(tlsRequired), len(kept))
+	}
+	if kept[0].RecipientEmail != "d@gmail.com" {
+		t.Errorf("Expected the 5.7.1 block kept first, got %s", kept[0].RecipientEmail)
+	}
+}
+
+func TestHealthCheckExcludesTLSRequirements(t *testing.T) {
+	now := time.Now()
+	var failures []database.SMTPFailure
+	for i := 0; i < 6; i++ {
+		failures = append(failures, tlsFailure("a@gmail.com", 550, "5.7.10", "TLS required", tlspolicy.RequirementSTSEnforce, now))
+	}
+	failures = append(failures, testFailure("b@yahoo.com", 550, "5.7.1", "Blocked", time.Minute, now))
+
+	health := buildHealthCheck("192.0.2.1", "", 15, 100, 0, failures, now)
+	if health.TotalRejected != 1 || health.TLSRequired != 6 || health.TLSRequiredDomains["gmail.com"] != 6 {
+		t.Errorf("Expected TLS requirement failures left out, got %+v", health)
+	}
+	if _, counted := health.DomainCounts["gmail.com"]; counted {
+		t.Error("TLS-requiring domain counted as rejecting")
+	}
+	if got := GetIssueType(*health); got != "tls_requirement_unmet" {
+		t.Errorf("GetIssueType() = %q, want tls_requirement_unmet", got)
+	}
+}
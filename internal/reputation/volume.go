@@ -0,0 +1,180 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-backend-service/internal/database"
+)
+
+// Names of the volume estimators, recorded as volume_source in the metrics
+// metadata
+const (
+	VolumeFromFailures   = "failures"
+	VolumeFromPrometheus = "prometheus"
+	VolumeFromReported   = "reported"
+	VolumeFromDelivered  = "delivered"
+)
+
+// DefaultPrometheusVolumeQuery is the query of the prometheus estimator when
+// none is configured
+const DefaultPrometheusVolumeQuery = `sum(increase(mta_messages_sent_total{ip="{{ip}}"}[{{window}}]))`
+
+// defaultEstimatedSent is the volume assumed when failures cannot be counted
+const defaultEstimatedSent = 100
+
+// ErrNoVolume is returned by an estimator without volume data for an IP;
+// the volume is then estimated from failures
+var ErrNoVolume = errors.New("no volume data for this IP")
+
+// VolumeEstimator tells how many messages an IP sent between since and
+// until, the total_sent rejection ratios are divided by
+type VolumeEstimator interface {
+	Name() string
+	EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error)
+}
+
+// VolumeOptions configures the volume estimators that read an external
+// source
+type VolumeOptions struct {
+	// PrometheusURL is the Prometheus server queried, PrometheusQuery the
+	// instant query whose {{ip}} and {{window}} are replaced with the IP and
+	// the window as a Prometheus duration, DefaultPrometheusVolumeQuery when
+	// empty
+	PrometheusURL   string
+	PrometheusQuery string
+	Timeout         time.Duration
+}
+
+// DefaultVolumeEstimators are asked when none is configured: the
+// smtp.delivery.success counts, then, for IPs without a delivery feed, the
+// volumes sending systems report
+const DefaultVolumeEstimators = VolumeFromDelivered + "," + VolumeFromReported
+
+// NewVolumeEstimators returns the estimators of a comma separated list, to
+// ask in that order. The failure heuristic is the last resort whatever the
+// list, so failures adds none. An empty list is DefaultVolumeEstimators.
+func NewVolumeEstimators(names string, options VolumeOptions) ([]VolumeEstimator, error) {
+	if strings.TrimSpace(names) == "" {
+		names = DefaultVolumeEstimators
+	}
+	var estimators []VolumeEstimator
+	for _, name := range strings.Split(names, ",") {
+		estimator, err := NewVolumeEstimator(strings.TrimSpace(name), options)
+		if err != nil {
+			return nil, err
+		}
+		if estimator != nil {
+			estimators = append(estimators, estimator)
+		}
+	}
+	return estimators, nil
+}
+
+// NewVolumeEstimator returns the estimator with the given name. The failure
+// heuristic is the aggregation service's own and is returned as nil.
+func NewVolumeEstimator(name string, options VolumeOptions) (VolumeEstimator, error) {
+	switch name {
+	case "", VolumeFromFailures:
+		return nil, nil
+	case VolumeFromPrometheus:
+		if options.PrometheusURL == "" {
+			return nil, fmt.Errorf("the prometheus volume estimator needs a URL")
+		}
+		if options.PrometheusQuery == "" {
+			options.PrometheusQuery = DefaultPrometheusVolumeQuery
+		}
+		return NewPrometheusVolume(options.PrometheusURL, options.PrometheusQuery, options.Timeout), nil
+	case VolumeFromReported:
+		return ReportedVolume{}, nil
+	case VolumeFromDelivered:
+		return &DeliveredVolume{}, nil
+	default:
+		return nil, fmt.Errorf("unknown volume estimator %q", name)
+	}
+}
+
+// failureVolume is the heuristic estimating the volume from the number of
+// failures, for deployments without real volume data
+type failureVolume struct {
+	count func(ip string, since time.Time) (int, error)
+}
+
+// Name implements VolumeEstimator
+func (v failureVolume) Name() string {
+	return VolumeFromFailures
+}
+
+// EstimateSent implements VolumeEstimator. Failures are only stored up to
+// now, so until is not needed.
+func (v failureVolume) EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error) {
+	failures, err := v.count(ip, since)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateSentFromFailures(failures), nil
+}
+
+// ReportedVolume reads the volumes the sending systems report to
+// /api/volumes
+type ReportedVolume struct{}
+
+// Name implements VolumeEstimator
+func (ReportedVolume) Name() string {
+	return VolumeFromReported
+}
+
+// EstimateSent implements VolumeEstimator. Reports only partly in the
+// window count in proportion to their overlap.
+func (ReportedVolume) EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error) {
+	messages, found, err := database.SumSendingVolume(ip, since, until)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrNoVolume
+	}
+	return messages, nil
+}
+
+// DeliveredVolume counts the messages delivered according to the
+// smtp.delivery.success events ingested into sent_volume, plus the failures
+// of the window, since failed messages were sent as well
+type DeliveredVolume struct {
+	// countFailures counts the failures of an IP since a time; the
+	// aggregation service sets its own, raw failures are counted otherwise
+	countFailures func(ip string, since time.Time) (int, error)
+}
+
+// Name implements VolumeEstimator
+func (*DeliveredVolume) Name() string {
+	return VolumeFromDelivered
+}
+
+// EstimateSent implements VolumeEstimator. Without deliveries counted in the
+// window there is no volume, even when the IP failed.
+func (v *DeliveredVolume) EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error) {
+	delivered, found, err := database.SumSentVolume(ip, since, until)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrNoVolume
+	}
+
+	var failures int
+	if v.countFailures != nil {
+		failures, err = v.countFailures(ip, since)
+	} else {
+		var stored []database.SMTPFailure
+		stored, err = database.GetSMTPFailuresByIP(ip, since)
+		failures = len(stored)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return delivered + failures, nil
+}
@@ -0,0 +1,161 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// fixedVolume answers every estimate with sent, or err
+type fixedVolume struct {
+	name string
+	sent int
+	err  error
+}
+
+func (v fixedVolume) Name() string { return v.name }
+
+func (v fixedVolume) EstimateSent(ctx context.Context, ip string, since, until time.Time) (int, error) {
+	return v.sent, v.err
+}
+
+func TestNewVolumeEstimator(t *testing.T) {
+	for _, name := range []string{"", VolumeFromFailures} {
+		if estimator, err := NewVolumeEstimator(name, VolumeOptions{}); err != nil || estimator != nil {
+			t.Errorf("Expected %q to keep the failure heuristic, got %v %v", name, estimator, err)
+		}
+	}
+	if estimator, err := NewVolumeEstimator(VolumeFromReported, VolumeOptions{}); err != nil || estimator.Name() != VolumeFromReported {
+		t.Errorf("Expected the reported estimator, got %v %v", estimator, err)
+	}
+	estimator, err := NewVolumeEstimator(VolumeFromDelivered, VolumeOptions{})
+	if err != nil || estimator.Name() != VolumeFromDelivered {
+		t.Errorf("Expected the delivered estimator, got %v %v", estimator, err)
+	}
+	logger, _ := test.NewNullLogger()
+	NewAggregationService(DefaultReputationConfig(), logger).SetVolumeEstimator(estimator)
+	if estimator.(*DeliveredVolume).countFailures == nil {
+		t.Error("Expected the delivered estimator to count failures with the service's counters")
+	}
+	if _, err := NewVolumeEstimator(VolumeFromPrometheus, VolumeOptions{}); err == nil {
+		t.Error("Expected the prometheus estimator to need a URL")
+	}
+	estimator, err = NewVolumeEstimator(VolumeFromPrometheus, VolumeOptions{PrometheusURL: "http://prometheus:9090/"})
+	if err != nil || estimator.(*PrometheusVolume).query != DefaultPrometheusVolumeQuery {
+		t.Errorf("Expected the prometheus estimator with the default query, got %v %v", estimator, err)
+	}
+	if _, err := NewVolumeEstimator("graphite", VolumeOptions{}); err == nil {
+		t.Error("Expected an unknown estimator to be refused")
+	}
+}
+
+func TestNewVolumeEstimators(t *testing.T) {
+	names := func(estimators []VolumeEstimator) []string {
+		var names []string
+		for _, estimator := range estimators {
+			names = append(names, estimator.Name())
+		}
+		return names
+	}
+	tests := []struct {
+		names string
+		want  []string
+	}{
+		{"", []string{VolumeFromDelivered, VolumeFromReported}},
+		{VolumeFromFailures, nil},
+		{"reported, failures", []string{VolumeFromReported}},
+	}
+	for _, tt := range tests {
+		estimators, err := NewVolumeEstimators(tt.names, VolumeOptions{})
+		if err != nil || !reflect.DeepEqual(names(estimators), tt.want) {
+			t.Errorf("Expected %v for %q, got %v %v", tt.want, tt.names, names(estimators), err)
+		}
+	}
+	if _, err := NewVolumeEstimators("delivered,graphite", VolumeOptions{}); err == nil {
+		t.Error("Expected an unknown estimator in the list to be refused")
+	}
+}
+
+func TestPrometheusVolume(t *testing.T) {
+	until := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var query, at string
+	body := `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"instance":"mta-1"},"value":[1714564800,"1200.4"]},
+		{"metric":{"instance":"mta-2"},"value":[1714564800,"300"]}
+	]}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		query, at = r.URL.Query().Get("query"), r.URL.Query().Get("time")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	prometheus := NewPrometheusVolume(server.URL+"/", `sum(increase(sent_total{ip="{{ip}}"}[{{window}}]))`, time.Second)
+	sent, err := prometheus.EstimateSent(context.Background(), "192.0.2.1", until.Add(-time.Hour), until)
+	if err != nil || sent != 1500 {
+		t.Fatalf("Expected 1500 sent, got %d %v", sent, err)
+	}
+	if query != `sum(increase(sent_total{ip="192.0.2.1"}[3600s]))` || at != "1714564800" {
+		t.Errorf("Unexpected query %q at %s", query, at)
+	}
+
+	body = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	if _, err := prometheus.EstimateSent(context.Background(), "192.0.2.1", until.Add(-time.Hour), until); !errors.Is(err, ErrNoVolume) {
+		t.Errorf("Expected no volume for an empty vector, got %v", err)
+	}
+	body = `{"status":"error","errorType":"bad_data","error":"parse error"}`
+	if _, err := prometheus.EstimateSent(context.Background(), "192.0.2.1", until.Add(-time.Hour), until); err == nil || errors.Is(err, ErrNoVolume) {
+		t.Errorf("Expected the query error, got %v", err)
+	}
+}
+
+func TestEstimateTotalSentFallsBack(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	s := NewAggregationService(DefaultReputationConfig(), logger)
+	s.fallback = fixedVolume{name: VolumeFromFailures, sent: 400}
+	now := time.Now()
+
+	tests := []struct {
+		volume VolumeEstimator
+		sent   int
+		source string
+	}{
+		{nil, 400, VolumeFromFailures},
+		{fixedVolume{name: VolumeFromReported, sent: 5000}, 5000, VolumeFromReported},
+		{fixedVolume{name: VolumeFromReported, sent: 10}, 100, VolumeFromReported},
+		{fixedVolume{name: VolumeFromReported, err: ErrNoVolume}, 400, VolumeFromFailures},
+		{fixedVolume{name: VolumeFromPrometheus, err: errors.New("connection refused")}, 400, VolumeFromFailures},
+	}
+	for _, tt := range tests {
+		s.SetVolumeEstimator(tt.volume)
+		sent, source := s.estimateTotalSent("192.0.2.1", now.Add(-time.Hour), now, 100)
+		if sent != tt.sent || source != tt.source {
+			t.Errorf("Expected %d from %s with %v, got %d from %s", tt.sent, tt.source, tt.volume, sent, source)
+		}
+	}
+
+	s.SetVolumeEstimator(fixedVolume{name: VolumeFromDelivered, err: ErrNoVolume}, fixedVolume{name: VolumeFromReported, sent: 3000})
+	if sent, source := s.estimateTotalSent("192.0.2.1", now.Add(-time.Hour), now, 100); sent != 3000 || source != VolumeFromReported {
+		t.Errorf("Expected the reported volume without a delivery feed, got %d from %s", sent, source)
+	}
+}
+
+func TestSetVolumeSourceFlagsEstimates(t *testing.T) {
+	var health IPHealthCheck
+	health.setVolumeSource(VolumeFromDelivered)
+	if health.VolumeEstimated {
+		t.Error("Expected a delivered volume not to be flagged as estimated")
+	}
+	health.setVolumeSource(VolumeFromFailures)
+	if !health.VolumeEstimated {
+		t.Error("Expected the failure heuristic to be flagged as estimated")
+	}
+}
@@ -0,0 +1,151 @@
+package reputation
+
+import (
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/reputation/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EvaluationWindow is a longer window evaluated next to WindowMinutes so that
+// slow-burn degradation is caught even when the primary window is clean. A
+// window can escalate an IP to warning or quarantine, never to blacklisted.
+type EvaluationWindow struct {
+	Name                   string `json:"name"`
+	Minutes                int    `json:"minutes"`
+	MinVolumeForAssessment int    `json:"min_volume_for_assessment"`
+	// WarningRejectionRatio is reached for warning; 0 disables it
+	WarningRejectionRatio float64 `json:"warning_rejection_ratio"`
+	// QuarantineRejectionRatio is exceeded, with a major provider rejecting,
+	// for quarantine; 0 disables it
+	QuarantineRejectionRatio float64 `json:"quarantine_rejection_ratio"`
+}
+
+// DefaultEvaluationWindows returns the 1h and 24h windows
+func DefaultEvaluationWindows() []EvaluationWindow {
+	return []EvaluationWindow{
+		{Name: "1h", Minutes: 60, MinVolumeForAssessment: 200, WarningRejectionRatio: 0.02},
+		{Name: "24h", Minutes: 1440, MinVolumeForAssessment: 1000, WarningRejectionRatio: 0.02, QuarantineRejectionRatio: 0.03},
+	}
+}
+
+// validateWindows checks windows have unique names and sane thresholds
+func validateWindows(windows []EvaluationWindow) error {
+	seen := make(map[string]bool)
+	for _, w := range windows {
+		if w.Name == "" {
+			return fmt.Errorf("windows: name is required")
+		}
+		if seen[w.Name] {
+			return fmt.Errorf("windows: duplicate name %q", w.Name)
+		}
+		seen[w.Name] = true
+		if w.Minutes <= 0 {
+			return fmt.Errorf("windows[%s]: minutes must be positive", w.Name)
+		}
+		if w.MinVolumeForAssessment < 0 {
+			return fmt.Errorf("windows[%s]: min_volume_for_assessment must not be negative", w.Name)
+		}
+		if w.WarningRejectionRatio < 0 || w.WarningRejectionRatio > 1 ||
+			w.QuarantineRejectionRatio < 0 || w.QuarantineRejectionRatio > 1 {
+			return fmt.Errorf("windows[%s]: ratios must be between 0 and 1", w.Name)
+		}
+	}
+	return nil
+}
+
+// WindowHealth is the health of an IP over one EvaluationWindow and the
+// status that window supports on its own
+type WindowHealth struct {
+	Window EvaluationWindow `json:"window"`
+	Health IPHealthCheck    `json:"health"`
+	Status string           `json:"status"`
+}
+
+// EvaluateWindow returns the status the metrics of one window support
+func EvaluateWindow(health IPHealthCheck, window EvaluationWindow) string {
+	if health.TotalSent < window.MinVolumeForAssessment {
+		return state.Healthy.String()
+	}
+	if window.QuarantineRejectionRatio > 0 && health.RejectionRatio > window.QuarantineRejectionRatio &&
+		len(health.MajorProviders) > 0 {
+		return state.Quarantine.String()
+	}
+	if window.WarningRejectionRatio > 0 && health.RejectionRatio >= window.WarningRejectionRatio {
+		return state.Warning.String()
+	}
+	return state.Healthy.String()
+}
+
+// CombineWindowStatus returns the most severe of the primary status and the
+// window statuses, and the name of the window that decided it. The name is
+// empty when the primary window decides, which it does on ties.
+func CombineWindowStatus(primary string, windows []WindowHealth) (string, string) {
+	status, decidedBy := primary, ""
+	for _, w := range windows {
+		if state.Status(w.Status).Severity() > state.Status(status).Severity() {
+			status, decidedBy = w.Status, w.Window.Name
+		}
+	}
+	return status, decidedBy
+}
+
+// evaluateWindows computes and stores the health of ip over each configured
+// window. Windows that fail to compute are logged and left out.
+func (s *AggregationService) evaluateWindows(ip string, config ReputationConfig, now time.Time) []WindowHealth {
+	windows := make([]WindowHealth, 0, len(config.Windows))
+	names := make([]string, 0, len(config.Windows))
+	for _, window := range config.Windows {
+		names = append(names, window.Name)
+		start := now.Add(-time.Duration(window.Minutes) * time.Minute)
+		totalSent, volumeSource := s.estimateTotalSent(ip, start, now, window.MinVolumeForAssessment)
+
+		health, err := s.healthCheck(ip, window.Minutes, totalSent, 0)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "window_health_failed",
+				"ip":     ip,
+				"window": window.Name,
+				"error":  err.Error(),
+			}).Warn("Failed to calculate window health")
+			continue
+		}
+		health.setVolumeSource(volumeSource)
+
+		wh := WindowHealth{Window: window, Health: *health, Status: EvaluateWindow(*health, window)}
+		windows = append(windows, wh)
+
+		if err := database.UpsertIPWindowMetrics(&database.IPWindowMetrics{
+			IP:                    ip,
+			WindowName:            window.Name,
+			WindowMinutes:         window.Minutes,
+			WindowStart:           start,
+			WindowEnd:             now,
+			TotalSent:             health.TotalSent,
+			TotalRejected:         health.TotalRejected,
+			RejectionRatio:        health.RejectionRatio,
+			UniqueDomainsRejected: health.UniqueDomainsRejected,
+			Status:                wh.Status,
+			LastUpdated:           now,
+		}); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"action": "window_metrics_save_failed",
+				"ip":     ip,
+				"window": window.Name,
+				"error":  err.Error(),
+			}).Warn("Failed to save window metrics")
+		}
+	}
+
+	if err := database.DeleteStaleIPWindowMetrics(ip, names); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action": "window_metrics_cleanup_failed",
+			"ip":     ip,
+			"error":  err.Error(),
+		}).Warn("Failed to remove metrics of unconfigured windows")
+	}
+	return windows
+}
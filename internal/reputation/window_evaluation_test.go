@@ -0,0 +1,73 @@
+package reputation
+
+import (
+	"testing"
+)
+
+func TestEvaluateWindow(t *testing.T) {
+	day := EvaluationWindow{Name: "24h", Minutes: 1440, MinVolumeForAssessment: 1000, WarningRejectionRatio: 0.02, QuarantineRejectionRatio: 0.03}
+
+	tests := []struct {
+		name   string
+		health IPHealthCheck
+		want   string
+	}{
+		{"below volume", IPHealthCheck{TotalSent: 999, RejectionRatio: 0.5, MajorProviders: []string{"gmail.com"}}, "healthy"},
+		{"clean", IPHealthCheck{TotalSent: 5000, RejectionRatio: 0.01}, "healthy"},
+		{"slow burn", IPHealthCheck{TotalSent: 5000, RejectionRatio: 0.02}, "warning"},
+		{"high ratio without major provider", IPHealthCheck{TotalSent: 5000, RejectionRatio: 0.04}, "warning"},
+		{"high ratio at major provider", IPHealthCheck{TotalSent: 5000, RejectionRatio: 0.04, MajorProviders: []string{"gmail.com"}}, "quarantine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvaluateWindow(tt.health, day); got != tt.want {
+				t.Errorf("EvaluateWindow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	disabled := EvaluationWindow{Name: "1h", Minutes: 60}
+	if got := EvaluateWindow(IPHealthCheck{TotalSent: 5000, RejectionRatio: 0.9}, disabled); got != "healthy" {
+		t.Errorf("Expected zero thresholds to be disabled, got %q", got)
+	}
+}
+
+func TestCombineWindowStatus(t *testing.T) {
+	windows := []WindowHealth{
+		{Window: EvaluationWindow{Name: "1h"}, Status: "warning"},
+		{Window: EvaluationWindow{Name: "24h"}, Status: "quarantine"},
+	}
+
+	if status, window := CombineWindowStatus("healthy", windows); status != "quarantine" || window != "24h" {
+		t.Errorf("CombineWindowStatus() = %q, %q, want quarantine, 24h", status, window)
+	}
+	if status, window := CombineWindowStatus("quarantine", windows); status != "quarantine" || window != "" {
+		t.Errorf("Expected the primary window to win ties, got %q, %q", status, window)
+	}
+	if status, window := CombineWindowStatus("blacklisted", windows); status != "blacklisted" || window != "" {
+		t.Errorf("Expected windows not to lower the status, got %q, %q", status, window)
+	}
+	if status, _ := CombineWindowStatus("healthy", nil); status != "healthy" {
+		t.Errorf("Expected the primary status without windows, got %q", status)
+	}
+}
+
+func TestValidateWindows(t *testing.T) {
+	if err := validateWindows(DefaultEvaluationWindows()); err != nil {
+		t.Errorf("Default windows invalid: %v", err)
+	}
+
+	invalid := [][]EvaluationWindow{
+		{{Name: "", Minutes: 60}},
+		{{Name: "1h", Minutes: 60}, {Name: "1h", Minutes: 120}},
+		{{Name: "1h", Minutes: 0}},
+		{{Name: "1h", Minutes: 60, MinVolumeForAssessment: -1}},
+		{{Name: "1h", Minutes: 60, WarningRejectionRatio: 2}},
+	}
+	for i, windows := range invalid {
+		if err := validateWindows(windows); err == nil {
+			t.Errorf("case %d: expected validation error for %+v", i, windows)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Resolver performs the host lookups needed for DNSBL queries.
+// *net.Resolver satisfies this interface.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// AddrResolver performs the reverse (PTR) lookups needed to verify rDNS.
+// *net.Resolver satisfies this interface.
+type AddrResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// New returns a pure-Go resolver whose dials are bounded by dialTimeout
+func New(dialTimeout time.Duration) Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{
+				Timeout: dialTimeout,
+			}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// listingRange is the loopback range DNSBLs use for listing answers
+var listingRange = netip.MustParsePrefix("127.0.0.0/8")
+
+// errorRange holds the 127.255.255.x answers Spamhaus and others return
+// for refused, rate-limited or misconfigured queries rather than listings
+var errorRange = netip.MustParsePrefix("127.255.255.0/24")
+
+// IsListingResponse reports whether a DNSBL answer indicates a real listing.
+// Answers outside 127.0.0.0/8 (e.g. from resolvers that rewrite NXDOMAIN to a
+// wildcard address) and DNSBL error codes are not treated as listings.
+func IsListingResponse(addrs []string) bool {
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		if listingRange.Contains(addr) && !errorRange.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fake is an in-memory Resolver for deterministic tests
+type Fake struct {
+	mu sync.Mutex
+
+	// Records maps fully-qualified query names to the addresses returned
+	Records map[string][]string
+	// Errors maps query names to the error returned for them
+	Errors map[string]error
+	// Wildcard, if set, is returned for any name not present in Records
+	Wildcard []string
+	// PTR maps addresses to the names LookupAddr returns for them
+	PTR map[string][]string
+	// Delay is applied to every lookup; the context deadline still applies
+	Delay time.Duration
+
+	queries []string
+}
+
+// NewFake creates an empty fake resolver where every name is NXDOMAIN
+func NewFake() *Fake {
+	return &Fake{
+		Records: make(map[string][]string),
+		Errors:  make(map[string]error),
+		PTR:     make(map[string][]string),
+	}
+}
+
+// LookupHost implements Resolver
+func (f *Fake) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, host)
+	delay := f.Delay
+	addrs, found := f.Records[host]
+	err := f.Errors[host]
+	wildcard := f.Wildcard
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		case <-timer.C:
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return addrs, nil
+	}
+	if len(wildcard) > 0 {
+		return wildcard, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// LookupAddr implements AddrResolver
+func (f *Fake) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, addr)
+	names, found := f.PTR[addr]
+	f.mu.Unlock()
+
+	if found {
+		return names, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+// Queries returns the names looked up so far
+func (f *Fake) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
@@ -0,0 +1,98 @@
+package simulate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// LoadRecording reads recorded webhook traffic: a payload file, a JSON
+// lines file of payloads or events as logged by Stalwart, or several
+// concatenated
+func LoadRecording(path string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	events, err := decodeRecording(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+	return events, nil
+}
+
+func decodeRecording(data []byte) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var events []map[string]interface{}
+	for {
+		var value map[string]interface{}
+		if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		raw, ok := value["events"]
+		if !ok {
+			events = append(events, value)
+			continue
+		}
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("events must be an array")
+		}
+		for i, item := range list {
+			event, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("events[%d] is not an object", i)
+			}
+			// Events are sent without their payload, so they carry its version
+			if version, ok := value["version"]; ok {
+				if _, set := event["version"]; !set {
+					event["version"] = version
+				}
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Replay schedules recorded events. A positive rate sends them evenly at
+// that many events per second; otherwise the gaps between their recorded
+// createdAt times are kept.
+func Replay(recorded []map[string]interface{}, rate float64) []Event {
+	events := make([]Event, len(recorded))
+	var first time.Time
+	for i, body := range recorded {
+		events[i].Body = body
+		if rate > 0 {
+			events[i].Offset = time.Duration(float64(i) / rate * float64(time.Second))
+			continue
+		}
+		created, _ := body["createdAt"].(string)
+		at, err := time.Parse(time.RFC3339, created)
+		if err != nil {
+			// Untimed events go out with the one before them
+			if i > 0 {
+				events[i].Offset = events[i-1].Offset
+			}
+			continue
+		}
+		if first.IsZero() {
+			first = at
+		}
+		if offset := at.Sub(first); i == 0 || offset >= events[i-1].Offset {
+			events[i].Offset = offset
+		} else {
+			events[i].Offset = events[i-1].Offset
+		}
+	}
+	return events
+}
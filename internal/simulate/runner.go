@@ -0,0 +1,249 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventsPath is the webhook the HTTP sink posts to
+const EventsPath = "/api/webhooks/stalwart/events"
+
+// maxRetryAfter caps how long the HTTP sink honours a Retry-After header
+const maxRetryAfter = 30 * time.Second
+
+// Event is one webhook event and when to send it, relative to the start of
+// the run
+type Event struct {
+	Offset time.Duration
+	Body   map[string]interface{}
+}
+
+// Result counts the events of a payload the service stored or rejected
+type Result struct {
+	Processed int
+	Failed    int
+}
+
+// Sink delivers webhook payloads to the service
+type Sink interface {
+	Send(ctx context.Context, payload []byte) (Result, error)
+}
+
+// HTTPSink posts payloads to a running instance, retrying like Stalwart
+// when it answers 429 or 503
+type HTTPSink struct {
+	BaseURL string
+	// Token is the webhook source token; empty sends unauthenticated
+	Token   string
+	Client  *http.Client
+	Retries int
+}
+
+// Send posts a payload to the events webhook
+func (s *HTTPSink) Send(ctx context.Context, payload []byte) (Result, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := strings.TrimSuffix(s.BaseURL, "/") + EventsPath
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return Result{}, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if s.Token != "" {
+			req.Header.Set("X-Webhook-Token", s.Token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return Result{}, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var counts struct {
+			Processed int `json:"processed"`
+			Failed    int `json:"failed"`
+		}
+		json.Unmarshal(body, &counts)
+		result := Result{Processed: counts.Processed, Failed: counts.Failed}
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusMultiStatus:
+			return result, nil
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			if attempt < s.Retries {
+				if err := sleep(ctx, retryAfter(resp.Header.Get("Retry-After"))); err != nil {
+					return result, err
+				}
+				continue
+			}
+		}
+		return result, fmt.Errorf("webhook responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+// retryAfter parses a Retry-After header in seconds, defaulting to a second
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds < 1 {
+		return time.Second
+	}
+	if wait := time.Duration(seconds) * time.Second; wait < maxRetryAfter {
+		return wait
+	}
+	return maxRetryAfter
+}
+
+// IngestSink hands payloads straight to the ingestion layer, e.g. the
+// ReputationHandler's IngestMessage, without a running server
+type IngestSink func(payload []byte) error
+
+// Send ingests a payload. Ingestion only reports whether the payload needs
+// a retry, so the events are counted as processed or failed as a whole.
+func (f IngestSink) Send(_ context.Context, payload []byte) (Result, error) {
+	var envelope struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	json.Unmarshal(payload, &envelope)
+	if err := f(payload); err != nil {
+		return Result{Failed: len(envelope.Events)}, err
+	}
+	return Result{Processed: len(envelope.Events)}, nil
+}
+
+// Stats summarizes a run
+type Stats struct {
+	Events    int           `json:"events"`
+	Payloads  int           `json:"payloads"`
+	Processed int           `json:"processed"`
+	Failed    int           `json:"failed"`
+	Errors    int           `json:"errors"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// Runner sends events to a sink on their schedule
+type Runner struct {
+	Sink Sink
+	// BatchSize caps the events per payload; events due at the same time
+	// are sent together. Default 1.
+	BatchSize int
+	// Speed compresses the schedule: 10 plays ten minutes in one. Zero or
+	// less sends as fast as the sink accepts.
+	Speed float64
+	// RunID prefixes event IDs so a rerun is not dropped as a duplicate
+	RunID string
+	// Verbatim keeps the IDs and timestamps of replayed events; otherwise
+	// events are stamped with the time they are sent
+	Verbatim bool
+	Logger   *logrus.Logger
+
+	now func() time.Time
+}
+
+// Run sends the events, which must be ordered by offset, until they are
+// all sent or ctx is done. Payloads the sink fails are counted and logged;
+// the run carries on.
+func (r *Runner) Run(ctx context.Context, events []Event) (Stats, error) {
+	now := r.now
+	if now == nil {
+		now = time.Now
+	}
+	batchSize := r.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	var stats Stats
+	start := now()
+	for i := 0; i < len(events); {
+		if r.Speed > 0 {
+			due := start.Add(time.Duration(float64(events[i].Offset) / r.Speed))
+			if err := sleep(ctx, due.Sub(now())); err != nil {
+				stats.Elapsed = now().Sub(start)
+				return stats, err
+			}
+		}
+
+		// Everything already due goes out in the same payload
+		elapsed := time.Duration(float64(now().Sub(start)) * r.Speed)
+		batch := []interface{}{}
+		for ; i < len(events) && len(batch) < batchSize; i++ {
+			if len(batch) > 0 && r.Speed > 0 && events[i].Offset > elapsed {
+				break
+			}
+			batch = append(batch, r.stamp(events[i].Body, now()))
+		}
+
+		payload, err := json.Marshal(map[string]interface{}{"events": batch})
+		if err != nil {
+			return stats, err
+		}
+		result, err := r.Sink.Send(ctx, payload)
+		stats.Events += len(batch)
+		stats.Payloads++
+		stats.Processed += result.Processed
+		stats.Failed += result.Failed
+		if err != nil {
+			if ctx.Err() != nil {
+				stats.Elapsed = now().Sub(start)
+				return stats, ctx.Err()
+			}
+			stats.Errors++
+			if r.Logger != nil {
+				r.Logger.WithFields(logrus.Fields{
+					"action": "simulate_send_failed",
+					"events": len(batch),
+					"error":  err.Error(),
+				}).Warn("Failed to deliver simulated payload")
+			}
+		}
+	}
+	stats.Elapsed = now().Sub(start)
+	return stats, nil
+}
+
+// stamp copies an event, giving it a run-scoped ID and the send time
+func (r *Runner) stamp(body map[string]interface{}, at time.Time) map[string]interface{} {
+	event := make(map[string]interface{}, len(body)+2)
+	for k, v := range body {
+		event[k] = v
+	}
+	id, _ := event["id"].(string)
+	if !r.Verbatim || id == "" {
+		if r.RunID != "" {
+			id = r.RunID + "-" + id
+		}
+		event["id"] = id
+	}
+	if _, ok := event["createdAt"]; !r.Verbatim || !ok {
+		event["createdAt"] = at.UTC().Format(time.RFC3339)
+	}
+	return event
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
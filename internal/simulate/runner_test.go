@@ -0,0 +1,181 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	payloads [][]map[string]interface{}
+	err      error
+}
+
+func (s *recordingSink) Send(_ context.Context, payload []byte) (Result, error) {
+	var envelope struct {
+		Events []map[string]interface{} `json:"events"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return Result{}, err
+	}
+	s.payloads = append(s.payloads, envelope.Events)
+	if s.err != nil {
+		return Result{Failed: len(envelope.Events)}, s.err
+	}
+	return Result{Processed: len(envelope.Events)}, nil
+}
+
+func TestReplay(t *testing.T) {
+	recording := `{"version": "1", "events": [
+		{"id": "a", "createdAt": "2026-03-02T10:00:00Z", "type": "smtp.bounce", "data": {"ip": "192.0.2.1"}},
+		{"id": "b", "createdAt": "2026-03-02T10:00:30Z", "type": "smtp.bounce", "data": {"ip": "192.0.2.1"}}
+	]}
+	{"id": "c", "version": "2", "createdAt": "2026-03-02T10:01:00Z", "type": "smtp.bounce", "data": {"remoteIp": "192.0.2.1"}}
+	{"id": "d", "type": "smtp.bounce", "data": {"remoteIp": "192.0.2.1"}}
+	`
+	recorded, err := decodeRecording([]byte(recording))
+	if err != nil {
+		t.Fatalf("decodeRecording failed: %v", err)
+	}
+	if len(recorded) != 4 || recorded[0]["version"] != "1" || recorded[2]["version"] != "2" {
+		t.Fatalf("Expected four events carrying their versions, got %v", recorded)
+	}
+
+	paced := Replay(recorded, 0)
+	expected := []time.Duration{0, 30 * time.Second, time.Minute, time.Minute}
+	for i, e := range paced {
+		if e.Offset != expected[i] {
+			t.Errorf("Event %d: expected the recorded offset %s, got %s", i, expected[i], e.Offset)
+		}
+	}
+	if even := Replay(recorded, 2); even[3].Offset != 1500*time.Millisecond {
+		t.Errorf("Expected events every 500ms at rate 2, got %s", even[3].Offset)
+	}
+
+	if _, err := decodeRecording([]byte(`{"events": {}}`)); err == nil {
+		t.Error("Expected a non-array events field to be rejected")
+	}
+}
+
+func TestRunner(t *testing.T) {
+	events := []Event{
+		{Body: map[string]interface{}{"id": "a", "createdAt": "2026-03-02T10:00:00Z"}},
+		{Body: map[string]interface{}{"id": "b"}},
+		{Body: map[string]interface{}{"id": "c"}},
+	}
+	sink := &recordingSink{}
+	runner := &Runner{Sink: sink, BatchSize: 2, RunID: "run1"}
+
+	stats, err := runner.Run(context.Background(), events)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stats.Events != 3 || stats.Payloads != 2 || stats.Processed != 3 || stats.Errors != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	first := sink.payloads[0][0]
+	if first["id"] != "run1-a" || first["createdAt"] == "2026-03-02T10:00:00Z" {
+		t.Errorf("Expected a fresh ID and timestamp, got %v", first)
+	}
+	if events[0].Body["id"] != "a" {
+		t.Error("Expected the scheduled events left unchanged")
+	}
+
+	sink = &recordingSink{}
+	runner = &Runner{Sink: sink, RunID: "run2", Verbatim: true}
+	if _, err := runner.Run(context.Background(), events); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := sink.payloads[0][0]; got["id"] != "a" || got["createdAt"] != "2026-03-02T10:00:00Z" {
+		t.Errorf("Expected the recorded ID and timestamp kept, got %v", got)
+	}
+	if got := sink.payloads[1][0]; got["createdAt"] == nil {
+		t.Errorf("Expected untimed events stamped, got %v", got)
+	}
+
+	sink = &recordingSink{err: errors.New("connection refused")}
+	runner = &Runner{Sink: sink}
+	stats, err = runner.Run(context.Background(), events)
+	if err != nil || stats.Errors != 3 || stats.Failed != 3 {
+		t.Errorf("Expected failed payloads counted and the run completed, got %+v %v", stats, err)
+	}
+}
+
+func TestRunnerSchedule(t *testing.T) {
+	events := []Event{
+		{Offset: 0, Body: map[string]interface{}{"id": "a"}},
+		{Offset: 0, Body: map[string]interface{}{"id": "b"}},
+		{Offset: 10 * time.Second, Body: map[string]interface{}{"id": "c"}},
+	}
+	sink := &recordingSink{}
+	runner := &Runner{Sink: sink, BatchSize: 10, Speed: 200}
+
+	stats, err := runner.Run(context.Background(), events)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(sink.payloads) != 2 || len(sink.payloads[0]) != 2 {
+		t.Errorf("Expected the due events batched and the later one sent alone, got %v", sink.payloads)
+	}
+	if stats.Elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the schedule compressed to 50ms, took %s", stats.Elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner = &Runner{Sink: &recordingSink{}, Speed: 1}
+	if _, err := runner.Run(ctx, events[2:]); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the run stopped, got %v", err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != EventsPath || r.Header.Get("X-Webhook-Token") != "secret" || len(body) == 0 {
+			t.Errorf("Unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "retry", "processed": 0, "failed": 1}`))
+			return
+		}
+		w.Write([]byte(`{"status": "success", "processed": 1, "failed": 0}`))
+	}))
+	defer server.Close()
+
+	sink := &HTTPSink{BaseURL: server.URL + "/", Token: "secret", Retries: 1}
+	result, err := sink.Send(context.Background(), []byte(`{"events": [{}]}`))
+	if err != nil || result.Processed != 1 || calls != 2 {
+		t.Errorf("Expected a retried delivery, got %+v %v after %d calls", result, err, calls)
+	}
+
+	calls = 0
+	sink.Retries = 0
+	if result, err := sink.Send(context.Background(), []byte(`{"events": [{}]}`)); err == nil || result.Failed != 1 {
+		t.Errorf("Expected the 503 reported, got %+v %v", result, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":     time.Second,
+		"0":    time.Second,
+		"5":    5 * time.Second,
+		"3600": maxRetryAfter,
+		"soon": time.Second,
+	}
+	for header, expected := range cases {
+		if got := retryAfter(header); got != expected {
+			t.Errorf("retryAfter(%q) = %s, want %s", header, got, expected)
+		}
+	}
+}
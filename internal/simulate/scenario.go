@@ -0,0 +1,281 @@
+// Package simulate produces Stalwart webhook traffic for local development:
+// synthetic events from scenario files describing how IPs degrade, or
+// recorded payloads replayed at a chosen rate.
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Curve shapes
+const (
+	ShapeConstant    = "constant"
+	ShapeLinear      = "linear"
+	ShapeStep        = "step"
+	ShapeExponential = "exponential"
+)
+
+// generatedSchemaVersion is the webhook schema of generated events
+const generatedSchemaVersion = "2"
+
+// generationStep is the resolution at which curves are integrated
+const generationStep = 100 * time.Millisecond
+
+// Scenario describes the failures seen from a set of IPs over time
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Duration is how long the scenario runs, e.g. "10m"
+	Duration string `json:"duration"`
+	// Seed makes recipients and failure picks reproducible
+	Seed int64 `json:"seed"`
+	// Instance is reported as the MTA instance of every event
+	Instance string       `json:"instance"`
+	IPs      []IPScenario `json:"ips"`
+
+	duration time.Duration
+}
+
+// IPScenario is the traffic of one sending IP
+type IPScenario struct {
+	IP string `json:"ip"`
+	// Domains are the recipient domains, picked at random; default gmail.com
+	Domains []string `json:"domains"`
+	Curve   Curve    `json:"curve"`
+	// Failures are the events sent, picked by weight; default a 5.7.1 block
+	Failures []Failure `json:"failures"`
+}
+
+// Curve is the rate of failures, in events per second, over the scenario.
+// The rate is From until Start and To from End; linear and exponential
+// curves ramp between the two, step curves jump at Start.
+type Curve struct {
+	Shape string  `json:"shape"`
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	// Start and End default to the beginning and end of the scenario
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	start, end time.Duration
+}
+
+// Failure is one kind of event an IP produces
+type Failure struct {
+	// Type is a Stalwart event type; default smtp.delivery.failure
+	Type         string `json:"type"`
+	SMTPCode     int    `json:"smtp_code"`
+	EnhancedCode string `json:"enhanced_code"`
+	Reason       string `json:"reason"`
+	// Weight is the failure's share of the IP's events; default 1
+	Weight float64 `json:"weight"`
+}
+
+var defaultFailures = []Failure{{
+	Type:         "smtp.delivery.failure",
+	SMTPCode:     550,
+	EnhancedCode: "5.7.1",
+	Reason:       "Message rejected due to local policy",
+	Weight:       1,
+}}
+
+// LoadScenario reads and validates a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks the scenario and fills in defaults
+func (s *Scenario) Validate() error {
+	duration, err := time.ParseDuration(s.Duration)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("duration must be a positive duration, got %q", s.Duration)
+	}
+	s.duration = duration
+	if len(s.IPs) == 0 {
+		return fmt.Errorf("at least one IP is required")
+	}
+
+	for i := range s.IPs {
+		ip := &s.IPs[i]
+		if net.ParseIP(ip.IP) == nil {
+			return fmt.Errorf("ips[%d]: invalid IP %q", i, ip.IP)
+		}
+		if len(ip.Domains) == 0 {
+			ip.Domains = []string{"gmail.com"}
+		}
+		if len(ip.Failures) == 0 {
+			ip.Failures = defaultFailures
+		}
+		for j := range ip.Failures {
+			f := &ip.Failures[j]
+			if f.Type == "" {
+				f.Type = "smtp.delivery.failure"
+			}
+			if f.Weight == 0 {
+				f.Weight = 1
+			}
+			if f.Weight < 0 {
+				return fmt.Errorf("ips[%d].failures[%d]: weight must not be negative", i, j)
+			}
+		}
+		if err := ip.Curve.validate(duration); err != nil {
+			return fmt.Errorf("ips[%d].curve: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (c *Curve) validate(duration time.Duration) error {
+	if c.Shape == "" {
+		c.Shape = ShapeConstant
+	}
+	switch c.Shape {
+	case ShapeConstant, ShapeLinear, ShapeStep:
+	case ShapeExponential:
+		if c.From <= 0 || c.To <= 0 {
+			return fmt.Errorf("exponential curves need positive rates")
+		}
+	default:
+		return fmt.Errorf("unknown shape %q", c.Shape)
+	}
+	if c.From < 0 || c.To < 0 {
+		return fmt.Errorf("rates must not be negative")
+	}
+
+	c.start, c.end = 0, duration
+	for _, bound := range []struct {
+		raw    string
+		target *time.Duration
+	}{{c.Start, &c.start}, {c.End, &c.end}} {
+		if bound.raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(bound.raw)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid offset %q", bound.raw)
+		}
+		*bound.target = d
+	}
+	if c.end < c.start {
+		return fmt.Errorf("end is before start")
+	}
+	return nil
+}
+
+// Rate is the curve's rate at offset t into the scenario
+func (c Curve) Rate(t time.Duration) float64 {
+	if c.Shape == ShapeConstant {
+		return c.From
+	}
+	if t < c.start {
+		return c.From
+	}
+	if t >= c.end || c.Shape == ShapeStep {
+		return c.To
+	}
+	p := float64(t-c.start) / float64(c.end-c.start)
+	if c.Shape == ShapeExponential {
+		return c.From * math.Pow(c.To/c.From, p)
+	}
+	return c.From + (c.To-c.From)*p
+}
+
+// Generate produces the scenario's events. Each IP's curve is integrated so
+// that an event is emitted each time its expected count reaches the next
+// whole event; the same scenario always produces the same events.
+func (s *Scenario) Generate() []Event {
+	rng := rand.New(rand.NewSource(s.Seed))
+	var events []Event
+	for _, ip := range s.IPs {
+		expected := 0.0
+		emitted := 0
+		for t := time.Duration(0); t < s.duration; t += generationStep {
+			// Trapezoid over the step
+			expected += (ip.Curve.Rate(t) + ip.Curve.Rate(t+generationStep)) / 2 * generationStep.Seconds()
+			for float64(emitted+1) <= expected {
+				emitted++
+				events = append(events, s.event(ip, emitted, t+generationStep, rng))
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Offset < events[j].Offset })
+	return events
+}
+
+func (s *Scenario) event(ip IPScenario, n int, offset time.Duration, rng *rand.Rand) Event {
+	failure := pickFailure(ip.Failures, rng)
+	domain := ip.Domains[rng.Intn(len(ip.Domains))]
+	id := fmt.Sprintf("%s-%d", strings.NewReplacer(".", "-", ":", "-").Replace(ip.IP), n)
+
+	data := map[string]interface{}{
+		"remoteIp": ip.IP,
+		"reason":   failure.Reason,
+	}
+	if s.Instance != "" {
+		data["instance"] = s.Instance
+	}
+	if failure.Type == "smtp.auth.failure" {
+		data["remoteHost"] = "smtp." + domain
+		data["mechanism"] = "plain"
+		data["username"] = fmt.Sprintf("sender%d@%s", rng.Intn(10)+1, domain)
+	} else {
+		data["domain"] = domain
+		data["recipient"] = fmt.Sprintf("user%d@%s", rng.Intn(1000)+1, domain)
+		data["mx"] = "mx." + domain
+		data["queueId"] = "sim-" + id
+		data["attemptNumber"] = 1
+		if failure.SMTPCode != 0 {
+			data["smtpCode"] = failure.SMTPCode
+		}
+		if failure.EnhancedCode != "" {
+			data["enhancedCode"] = failure.EnhancedCode
+		}
+		if failure.Type == "smtp.dsn" {
+			data["action"] = "failed"
+		}
+	}
+
+	return Event{
+		Offset: offset,
+		Body: map[string]interface{}{
+			"id":      id,
+			"version": generatedSchemaVersion,
+			"type":    failure.Type,
+			"data":    data,
+		},
+	}
+}
+
+func pickFailure(failures []Failure, rng *rand.Rand) Failure {
+	total := 0.0
+	for _, f := range failures {
+		total += f.Weight
+	}
+	pick := rng.Float64() * total
+	for _, f := range failures {
+		if pick < f.Weight {
+			return f
+		}
+		pick -= f.Weight
+	}
+	return failures[len(failures)-1]
+}
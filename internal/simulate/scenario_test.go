@@ -0,0 +1,139 @@
+package simulate
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCurveRate(t *testing.T) {
+	cases := []struct {
+		curve    Curve
+		at       time.Duration
+		expected float64
+	}{
+		{Curve{Shape: ShapeConstant, From: 2}, 5 * time.Minute, 2},
+		{Curve{Shape: ShapeLinear, From: 0, To: 10}, 5 * time.Minute, 5},
+		{Curve{Shape: ShapeLinear, From: 1, To: 3, Start: "2m", End: "4m"}, time.Minute, 1},
+		{Curve{Shape: ShapeLinear, From: 1, To: 3, Start: "2m", End: "4m"}, 3 * time.Minute, 2},
+		{Curve{Shape: ShapeLinear, From: 1, To: 3, Start: "2m", End: "4m"}, 9 * time.Minute, 3},
+		{Curve{Shape: ShapeStep, From: 1, To: 4, Start: "5m"}, 4 * time.Minute, 1},
+		{Curve{Shape: ShapeStep, From: 1, To: 4, Start: "5m"}, 5 * time.Minute, 4},
+		{Curve{Shape: ShapeExponential, From: 1, To: 100}, 5 * time.Minute, 10},
+	}
+	for _, c := range cases {
+		curve := c.curve
+		if err := curve.validate(10 * time.Minute); err != nil {
+			t.Fatalf("validate %+v: %v", c.curve, err)
+		}
+		if got := curve.Rate(c.at); math.Abs(got-c.expected) > 1e-9 {
+			t.Errorf("%s curve at %s = %v, want %v", curve.Shape, c.at, got, c.expected)
+		}
+	}
+}
+
+func TestScenarioValidate(t *testing.T) {
+	cases := map[string]Scenario{
+		"duration must be a positive duration":   {Duration: "soon", IPs: []IPScenario{{IP: "192.0.2.1"}}},
+		"at least one IP is required":            {Duration: "1m"},
+		"invalid IP":                             {Duration: "1m", IPs: []IPScenario{{IP: "mta-1"}}},
+		"unknown shape":                          {Duration: "1m", IPs: []IPScenario{{IP: "192.0.2.1", Curve: Curve{Shape: "sine"}}}},
+		"exponential curves need positive rates": {Duration: "1m", IPs: []IPScenario{{IP: "192.0.2.1", Curve: Curve{Shape: ShapeExponential, To: 1}}}},
+		"end is before start":                    {Duration: "1m", IPs: []IPScenario{{IP: "192.0.2.1", Curve: Curve{Shape: ShapeLinear, Start: "50s", End: "10s"}}}},
+	}
+	for expected, scenario := range cases {
+		if err := scenario.Validate(); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q, got %v", expected, err)
+		}
+	}
+
+	s := Scenario{Duration: "1m", IPs: []IPScenario{{IP: "192.0.2.1"}}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if ip := s.IPs[0]; ip.Curve.Shape != ShapeConstant || len(ip.Domains) != 1 || !reflect.DeepEqual(ip.Failures, defaultFailures) {
+		t.Errorf("Expected defaults filled in, got %+v", ip)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := Scenario{
+		Duration: "10m",
+		Seed:     7,
+		Instance: "mta-sim",
+		IPs: []IPScenario{
+			{IP: "192.0.2.10", Curve: Curve{Shape: ShapeLinear, From: 0, To: 1}},
+			{IP: "192.0.2.11", Curve: Curve{From: 0.5}, Failures: []Failure{{Type: "smtp.auth.failure", Reason: "535 5.7.8"}}},
+		},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	events := s.Generate()
+	perIP := map[string]int{}
+	for i, e := range events {
+		if i > 0 && e.Offset < events[i-1].Offset {
+			t.Fatalf("Events out of order at %d", i)
+		}
+		data := e.Body["data"].(map[string]interface{})
+		perIP[data["remoteIp"].(string)]++
+	}
+	// The integral of each curve over ten minutes
+	if perIP["192.0.2.10"] != 300 || perIP["192.0.2.11"] != 300 {
+		t.Errorf("Expected 300 events per IP, got %v", perIP)
+	}
+
+	// A ramp sends most of its events late
+	var late int
+	for _, e := range events {
+		if e.Body["data"].(map[string]interface{})["remoteIp"] == "192.0.2.10" && e.Offset > 5*time.Minute {
+			late++
+		}
+	}
+	if late < 224 || late > 226 {
+		t.Errorf("Expected three quarters of the ramp's events in the second half, got %d", late)
+	}
+
+	first := events[0].Body
+	if first["version"] != "2" || first["id"] == "" {
+		t.Errorf("Unexpected event envelope: %v", first)
+	}
+	for _, e := range events {
+		data := e.Body["data"].(map[string]interface{})
+		if data["remoteIp"] == "192.0.2.11" && (e.Body["type"] != "smtp.auth.failure" || data["mechanism"] != "plain" || data["instance"] != "mta-sim") {
+			t.Fatalf("Unexpected auth failure: %v", e.Body)
+		}
+	}
+
+	if again := s.Generate(); !reflect.DeepEqual(again, events) {
+		t.Error("Expected the same seed to produce the same events")
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	paths, err := filepath.Glob("../../cmd/simulate/scenarios/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("No example scenarios: %v", err)
+	}
+	for _, path := range paths {
+		s, err := LoadScenario(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if len(s.Generate()) == 0 {
+			t.Errorf("%s generates no events", path)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(bad, []byte(`{"duration": "1m", "ips": [{"ip": "192.0.2.1", "curve": {"shape": "sine"}}]}`), 0o644)
+	if _, err := LoadScenario(bad); err == nil || !strings.Contains(err.Error(), "ips[0].curve: unknown shape") {
+		t.Errorf("Expected the invalid curve reported, got %v", err)
+	}
+}
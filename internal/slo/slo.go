@@ -0,0 +1,217 @@
+package slo
+
+import (
+	"fmt"
+	"time"
+
+	"golang-backend-service/internal/reputation/state"
+)
+
+// Fleet metrics an objective can bound. Ratios are shares of the IPs with
+// reputation metrics, archived IPs left out; the others are IP counts.
+const (
+	MetricHealthyRatio     = "healthy_ratio"
+	MetricBlacklistedRatio = "blacklisted_ratio"
+	MetricWarning          = "warning"
+	MetricQuarantined      = "quarantined"
+	MetricBlacklisted      = "blacklisted"
+)
+
+// Objective bounds a fleet metric from below with Min, above with Max, or
+// both. The fleet is compliant at a measurement that is within the bounds.
+type Objective struct {
+	Name   string   `json:"name"`
+	Metric string   `json:"metric"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+// Validate checks the objective names a known metric and has a bound
+func (o Objective) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("objective name is required")
+	}
+	switch o.Metric {
+	case MetricHealthyRatio, MetricBlacklistedRatio, MetricWarning, MetricQuarantined, MetricBlacklisted:
+	default:
+		return fmt.Errorf("objective %s: unknown metric %q", o.Name, o.Metric)
+	}
+	if o.Min == nil && o.Max == nil {
+		return fmt.Errorf("objective %s: min or max is required", o.Name)
+	}
+	if o.Min != nil && o.Max != nil && *o.Min > *o.Max {
+		return fmt.Errorf("objective %s: min must not exceed max", o.Name)
+	}
+	return nil
+}
+
+// Met reports whether a measured value is within the objective's bounds
+func (o Objective) Met(value float64) bool {
+	if o.Min != nil && value < *o.Min {
+		return false
+	}
+	if o.Max != nil && value > *o.Max {
+		return false
+	}
+	return true
+}
+
+// Threshold is the bound recorded with a measurement: Min when set, Max
+// otherwise
+func (o Objective) Threshold() float64 {
+	if o.Min != nil {
+		return *o.Min
+	}
+	return *o.Max
+}
+
+// Config holds the objectives and the error budget they are held to
+type Config struct {
+	Objectives []Objective
+	// Target is the share of measurements within Window that must meet an
+	// objective; the rest is its error budget
+	Target float64
+	Window time.Duration
+	// BurnWindow is the recent stretch the burn rate is measured over
+	BurnWindow time.Duration
+	// Retention is how long measurements are kept for reporting
+	Retention time.Duration
+}
+
+// DefaultConfig holds at least 95% of the fleet healthy and no IP
+// blacklisted for 99% of the last 30 days
+func DefaultConfig() Config {
+	healthy, blacklisted := 0.95, 0.0
+	return Config{
+		Objectives: []Objective{
+			{Name: "fleet_healthy", Metric: MetricHealthyRatio, Min: &healthy},
+			{Name: "no_blacklisted", Metric: MetricBlacklisted, Max: &blacklisted},
+		},
+		Target:     0.99,
+		Window:     30 * 24 * time.Hour,
+		BurnWindow: time.Hour,
+		Retention:  90 * 24 * time.Hour,
+	}
+}
+
+// Validate checks the target leaves an error budget and the objectives are
+// valid and uniquely named
+func (c Config) Validate() error {
+	if c.Target <= 0 || c.Target >= 1 {
+		return fmt.Errorf("target must be between 0 and 1")
+	}
+	if c.Window <= 0 || c.BurnWindow <= 0 || c.BurnWindow > c.Window {
+		return fmt.Errorf("window and burn_window must be positive, burn_window at most window")
+	}
+	if c.Retention < c.Window {
+		return fmt.Errorf("retention must be at least window")
+	}
+	names := make(map[string]bool, len(c.Objectives))
+	for _, o := range c.Objectives {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		if names[o.Name] {
+			return fmt.Errorf("objective %s is defined twice", o.Name)
+		}
+		names[o.Name] = true
+	}
+	return nil
+}
+
+// Fleet is the number of IPs in each status at one point in time
+type Fleet struct {
+	Total       int `json:"total"`
+	Healthy     int `json:"healthy"`
+	Warning     int `json:"warning"`
+	Quarantined int `json:"quarantined"`
+	Blacklisted int `json:"blacklisted"`
+}
+
+// NewFleet counts the fleet from IP counts per status. IPs of an unknown
+// status count towards the total only.
+func NewFleet(counts map[string]int) Fleet {
+	fleet := Fleet{
+		Healthy:     counts[state.Healthy.String()],
+		Warning:     counts[state.Warning.String()],
+		Quarantined: counts[state.Quarantine.String()],
+		Blacklisted: counts[state.Blacklisted.String()],
+	}
+	for _, n := range counts {
+		fleet.Total += n
+	}
+	return fleet
+}
+
+// Value is the fleet's value of a metric. An empty fleet is all healthy.
+func (f Fleet) Value(metric string) float64 {
+	share := func(n int) float64 {
+		if f.Total == 0 {
+			return 0
+		}
+		return float64(n) / float64(f.Total)
+	}
+	switch metric {
+	case MetricHealthyRatio:
+		if f.Total == 0 {
+			return 1
+		}
+		return share(f.Healthy)
+	case MetricBlacklistedRatio:
+		return share(f.Blacklisted)
+	case MetricWarning:
+		return float64(f.Warning)
+	case MetricQuarantined:
+		return float64(f.Quarantined)
+	case MetricBlacklisted:
+		return float64(f.Blacklisted)
+	}
+	return 0
+}
+
+// Status is an objective's compliance and error budget over the window
+type Status struct {
+	Objective
+	// Current is the latest measurement; nil before the first
+	Current    *float64   `json:"current,omitempty"`
+	MeasuredAt *time.Time `json:"measured_at,omitempty"`
+	// Compliant is whether the latest measurement met the objective
+	Compliant    bool    `json:"compliant"`
+	Target       float64 `json:"target"`
+	WindowHours  float64 `json:"window_hours"`
+	Measurements int     `json:"measurements"`
+	// Compliance is the share of measurements in the window that met the
+	// objective; 1 without measurements
+	Compliance float64 `json:"compliance"`
+	// ErrorBudgetRemaining is the share of the error budget, 1 - Target,
+	// not yet spent; negative once the objective is breached
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// BurnRate is how fast the budget is spent over the burn window: 1
+	// spends it exactly over the window, above 1 exhausts it early
+	BurnRate float64 `json:"burn_rate"`
+	// Breached is set when the error budget is spent
+	Breached bool `json:"breached"`
+}
+
+// newStatus computes an objective's status from the measurements in the
+// window and in the burn window, each a total and the number that met it
+func newStatus(o Objective, config Config, total, met, burnTotal, burnMet int) Status {
+	budget := 1 - config.Target
+	status := Status{
+		Objective:            o,
+		Target:               config.Target,
+		WindowHours:          config.Window.Hours(),
+		Measurements:         total,
+		Compliance:           1,
+		ErrorBudgetRemaining: 1,
+	}
+	if total > 0 {
+		status.Compliance = float64(met) / float64(total)
+		status.ErrorBudgetRemaining = 1 - (1-status.Compliance)/budget
+	}
+	if burnTotal > 0 {
+		status.BurnRate = float64(burnTotal-burnMet) / float64(burnTotal) / budget
+	}
+	status.Breached = status.ErrorBudgetRemaining < 0
+	return status
+}
@@ -0,0 +1,188 @@
+package slo
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	counts       map[string]int
+	measurements []database.SLOMeasurement
+}
+
+func (s *memoryStore) CountIPsByStatus() (map[string]int, error) {
+	return s.counts, nil
+}
+
+func (s *memoryStore) InsertSLOMeasurements(measurements []database.SLOMeasurement) error {
+	s.measurements = append(s.measurements, measurements...)
+	return nil
+}
+
+func (s *memoryStore) CountSLOMeasurements(objective string, since time.Time) (int, int, error) {
+	total, met := 0, 0
+	for _, m := range s.measurements {
+		if m.Objective == objective && !m.MeasuredAt.Before(since) {
+			total++
+			if m.Met {
+				met++
+			}
+		}
+	}
+	return total, met, nil
+}
+
+func (s *memoryStore) GetLatestSLOMeasurement(objective string) (*database.SLOMeasurement, error) {
+	measurements, _ := s.GetSLOMeasurements(objective, time.Time{})
+	if len(measurements) == 0 {
+		return nil, nil
+	}
+	return &measurements[0], nil
+}
+
+func (s *memoryStore) GetSLOMeasurements(objective string, since time.Time) ([]database.SLOMeasurement, error) {
+	measurements := []database.SLOMeasurement{}
+	for _, m := range s.measurements {
+		if m.Objective == objective && !m.MeasuredAt.Before(since) {
+			measurements = append(measurements, m)
+		}
+	}
+	sort.Slice(measurements, func(i, j int) bool { return measurements[i].MeasuredAt.After(measurements[j].MeasuredAt) })
+	return measurements, nil
+}
+
+func (s *memoryStore) DeleteSLOMeasurementsBefore(cutoff time.Time) (int64, error) {
+	kept := s.measurements[:0]
+	for _, m := range s.measurements {
+		if !m.MeasuredAt.Before(cutoff) {
+			kept = append(kept, m)
+		}
+	}
+	deleted := int64(len(s.measurements) - len(kept))
+	s.measurements = kept
+	return deleted, nil
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Expected the default config to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"target of 1", func(c *Config) { c.Target = 1 }},
+		{"no window", func(c *Config) { c.Window = 0 }},
+		{"burn window past window", func(c *Config) { c.BurnWindow = c.Window + time.Hour }},
+		{"retention below window", func(c *Config) { c.Retention = c.Window - time.Hour }},
+		{"unknown metric", func(c *Config) { c.Objectives[0].Metric = "uptime" }},
+		{"no bound", func(c *Config) { c.Objectives[0].Min = nil }},
+		{"min above max", func(c *Config) { c.Objectives[0].Max = bound(0.5) }},
+		{"duplicate name", func(c *Config) { c.Objectives[1].Name = c.Objectives[0].Name }},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.modify(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", tt.name)
+		}
+	}
+}
+
+func TestObjectiveMet(t *testing.T) {
+	between := Objective{Name: "band", Metric: MetricWarning, Min: bound(1), Max: bound(3)}
+	for value, want := range map[float64]bool{0: false, 1: true, 3: true, 4: false} {
+		if got := between.Met(value); got != want {
+			t.Errorf("Met(%v) = %v, want %v", value, got, want)
+		}
+	}
+	if threshold := between.Threshold(); threshold != 1 {
+		t.Errorf("Expected min as threshold, got %v", threshold)
+	}
+}
+
+func TestFleetValue(t *testing.T) {
+	fleet := NewFleet(map[string]int{"healthy": 6, "warning": 2, "blacklisted": 1, "unknown": 1})
+	if fleet.Total != 10 {
+		t.Fatalf("Expected 10 IPs, got %+v", fleet)
+	}
+	for metric, want := range map[string]float64{MetricHealthyRatio: 0.6, MetricBlacklistedRatio: 0.1, MetricWarning: 2, MetricQuarantined: 0, MetricBlacklisted: 1} {
+		if got := fleet.Value(metric); got != want {
+			t.Errorf("Value(%s) = %v, want %v", metric, got, want)
+		}
+	}
+	if ratio := NewFleet(nil).Value(MetricHealthyRatio); ratio != 1 {
+		t.Errorf("Expected an empty fleet to be healthy, got %v", ratio)
+	}
+}
+
+func TestNewStatus(t *testing.T) {
+	config := DefaultConfig()
+	objective := config.Objectives[0]
+
+	// 2 of 400 missed spends half of the 1% budget; 1 of 10 missed in the
+	// burn window spends it 10 times too fast
+	status := newStatus(objective, config, 400, 398, 10, 9)
+	if math.Abs(status.Compliance-0.995) > 1e-9 || math.Abs(status.ErrorBudgetRemaining-0.5) > 1e-9 || math.Abs(status.BurnRate-10) > 1e-9 || status.Breached {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	status = newStatus(objective, config, 100, 97, 0, 0)
+	if !status.Breached || status.ErrorBudgetRemaining >= 0 || status.BurnRate != 0 {
+		t.Errorf("Expected the budget breached, got %+v", status)
+	}
+
+	status = newStatus(objective, config, 0, 0, 0, 0)
+	if status.Compliance != 1 || status.ErrorBudgetRemaining != 1 || status.Breached {
+		t.Errorf("Expected a full budget without measurements, got %+v", status)
+	}
+}
+
+func TestTrackerMeasure(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	store := &memoryStore{counts: map[string]int{"healthy": 19, "blacklisted": 1}}
+	tracker := NewTrackerWithStore(DefaultConfig(), store, logger)
+
+	now := time.Now()
+	store.measurements = append(store.measurements, database.SLOMeasurement{Objective: "fleet_healthy", MeasuredAt: now.Add(-100 * 24 * time.Hour), Met: true})
+	if err := tracker.Measure(now); err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	if len(store.measurements) != 2 {
+		t.Fatalf("Expected one measurement per objective and the old one pruned, got %+v", store.measurements)
+	}
+
+	statuses, err := tracker.Status(now)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Unexpected statuses: %+v %v", statuses, err)
+	}
+	healthy, blacklisted := statuses[0], statuses[1]
+	if healthy.Current == nil || *healthy.Current != 0.95 || !healthy.Compliant || healthy.Breached {
+		t.Errorf("Expected 95%% healthy to meet the objective, got %+v", healthy)
+	}
+	if blacklisted.Current == nil || *blacklisted.Current != 1 || blacklisted.Compliant || !blacklisted.Breached || math.Abs(blacklisted.BurnRate-100) > 1e-6 {
+		t.Errorf("Expected a blacklisted IP to breach the objective, got %+v", blacklisted)
+	}
+
+	warned := false
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["action"] == "slo_not_met" && entry.Data["objective"] == "no_blacklisted" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("Expected a warning for the objective not met")
+	}
+}
@@ -0,0 +1,276 @@
+package slo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Prometheus gauges of every objective, updated at each measurement
+var (
+	sloValue = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_value",
+			Help: "Latest measured value of the fleet metric an SLO bounds",
+		},
+		[]string{"objective"},
+	)
+	sloCompliance = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_compliance_ratio",
+			Help: "Share of SLO measurements within the SLO window that met the objective",
+		},
+		[]string{"objective"},
+	)
+	sloErrorBudgetRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_error_budget_remaining_ratio",
+			Help: "Share of the SLO error budget not yet spent within the SLO window; negative once breached",
+		},
+		[]string{"objective"},
+	)
+	sloBurnRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_burn_rate",
+			Help: "Rate the SLO error budget is spent at over the burn window; 1 spends it exactly over the SLO window",
+		},
+		[]string{"objective"},
+	)
+)
+
+// Store persists measurements. The default implementation delegates to the
+// database package; tests substitute an in-memory store.
+type Store interface {
+	CountIPsByStatus() (map[string]int, error)
+	InsertSLOMeasurements(measurements []database.SLOMeasurement) error
+	CountSLOMeasurements(objective string, since time.Time) (total, met int, err error)
+	GetLatestSLOMeasurement(objective string) (*database.SLOMeasurement, error)
+	GetSLOMeasurements(objective string, since time.Time) ([]database.SLOMeasurement, error)
+	DeleteSLOMeasurementsBefore(cutoff time.Time) (int64, error)
+}
+
+// dbStore is the Store backed by the global database connection
+type dbStore struct{}
+
+func (dbStore) CountIPsByStatus() (map[string]int, error) {
+	return database.CountIPsByStatus()
+}
+
+func (dbStore) InsertSLOMeasurements(measurements []database.SLOMeasurement) error {
+	return database.InsertSLOMeasurements(measurements)
+}
+
+func (dbStore) CountSLOMeasurements(objective string, since time.Time) (int, int, error) {
+	return database.CountSLOMeasurements(objective, since)
+}
+
+func (dbStore) GetLatestSLOMeasurement(objective string) (*database.SLOMeasurement, error) {
+	return database.GetLatestSLOMeasurement(objective)
+}
+
+func (dbStore) GetSLOMeasurements(objective string, since time.Time) ([]database.SLOMeasurement, error) {
+	return database.GetSLOMeasurements(objective, since)
+}
+
+func (dbStore) DeleteSLOMeasurementsBefore(cutoff time.Time) (int64, error) {
+	return database.DeleteSLOMeasurementsBefore(cutoff)
+}
+
+// Tracker periodically measures the fleet against the objectives and
+// reports their compliance and error budgets
+type Tracker struct {
+	config   Config
+	store    Store
+	logger   *logrus.Logger
+	ticker   *time.Ticker
+	stopChan chan bool
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewTracker creates a tracker storing measurements in the database
+func NewTracker(config Config, logger *logrus.Logger) *Tracker {
+	return NewTrackerWithStore(config, dbStore{}, logger)
+}
+
+// NewTrackerWithStore creates a tracker storing measurements in store
+func NewTrackerWithStore(config Config, store Store, logger *logrus.Logger) *Tracker {
+	return &Tracker{config: config, store: store, logger: logger, stopChan: make(chan bool)}
+}
+
+// Config returns the objectives and error budget the tracker applies
+func (t *Tracker) Config() Config {
+	return t.config
+}
+
+// Objective returns the objective with the given name
+func (t *Tracker) Objective(name string) (Objective, bool) {
+	for _, o := range t.config.Objectives {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return Objective{}, false
+}
+
+// Start measures now and then every interval
+func (t *Tracker) Start(interval time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running {
+		return fmt.Errorf("SLO tracker is already running")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("SLO tracker requires a positive interval")
+	}
+
+	t.ticker = time.NewTicker(interval)
+	t.running = true
+
+	t.logger.WithFields(logrus.Fields{
+		"action":     "slo_tracker_start",
+		"interval":   interval.String(),
+		"objectives": len(t.config.Objectives),
+	}).Info("Starting SLO tracker")
+
+	go t.RunOnce()
+
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				t.RunOnce()
+			case <-t.stopChan:
+				t.logger.Info("SLO tracker stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the tracker
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.running {
+		return
+	}
+
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+
+	t.stopChan <- true
+	t.running = false
+}
+
+// RunOnce measures the fleet, logging failures
+func (t *Tracker) RunOnce() {
+	if err := t.Measure(time.Now()); err != nil {
+		t.logger.WithFields(logrus.Fields{
+			"action": "slo_measure_failed",
+			"error":  err.Error(),
+		}).Error("Failed to measure SLOs")
+	}
+}
+
+// Measure records a measurement of every objective at now, updates the
+// gauges and drops measurements past the retention
+func (t *Tracker) Measure(now time.Time) error {
+	counts, err := t.store.CountIPsByStatus()
+	if err != nil {
+		return err
+	}
+	fleet := NewFleet(counts)
+
+	measurements := make([]database.SLOMeasurement, 0, len(t.config.Objectives))
+	for _, o := range t.config.Objectives {
+		value := fleet.Value(o.Metric)
+		measurements = append(measurements, database.SLOMeasurement{
+			Objective:  o.Name,
+			MeasuredAt: now,
+			Value:      value,
+			Threshold:  o.Threshold(),
+			Met:        o.Met(value),
+			TotalIPs:   fleet.Total,
+		})
+	}
+	if err := t.store.InsertSLOMeasurements(measurements); err != nil {
+		return err
+	}
+
+	statuses, err := t.Status(now)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if s.Current != nil {
+			sloValue.WithLabelValues(s.Name).Set(*s.Current)
+		}
+		sloCompliance.WithLabelValues(s.Name).Set(s.Compliance)
+		sloErrorBudgetRemaining.WithLabelValues(s.Name).Set(s.ErrorBudgetRemaining)
+		sloBurnRate.WithLabelValues(s.Name).Set(s.BurnRate)
+		if s.Current != nil && !s.Compliant {
+			t.logger.WithFields(logrus.Fields{
+				"action":                 "slo_not_met",
+				"objective":              s.Name,
+				"value":                  *s.Current,
+				"error_budget_remaining": s.ErrorBudgetRemaining,
+				"burn_rate":              s.BurnRate,
+			}).Warn("Fleet does not meet SLO")
+		}
+	}
+
+	if _, err := t.store.DeleteSLOMeasurementsBefore(now.Add(-t.config.Retention)); err != nil {
+		t.logger.WithFields(logrus.Fields{
+			"action": "slo_prune_failed",
+			"error":  err.Error(),
+		}).Warn("Failed to delete old SLO measurements")
+	}
+	return nil
+}
+
+// Status returns the compliance, error budget and burn rate of every
+// objective at now
+func (t *Tracker) Status(now time.Time) ([]Status, error) {
+	statuses := make([]Status, 0, len(t.config.Objectives))
+	for _, o := range t.config.Objectives {
+		total, met, err := t.store.CountSLOMeasurements(o.Name, now.Add(-t.config.Window))
+		if err != nil {
+			return nil, err
+		}
+		burnTotal, burnMet, err := t.store.CountSLOMeasurements(o.Name, now.Add(-t.config.BurnWindow))
+		if err != nil {
+			return nil, err
+		}
+		status := newStatus(o, t.config, total, met, burnTotal, burnMet)
+
+		latest, err := t.store.GetLatestSLOMeasurement(o.Name)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil {
+			status.Current = &latest.Value
+			status.MeasuredAt = &latest.MeasuredAt
+			status.Compliant = latest.Met
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Measurements returns the measurements of an objective since a time,
+// newest first
+func (t *Tracker) Measurements(objective string, since time.Time) ([]database.SLOMeasurement, error) {
+	return t.store.GetSLOMeasurements(objective, since)
+}
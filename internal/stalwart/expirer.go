@@ -0,0 +1,177 @@
+package stalwart
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pushTimeout bounds the pushes of one sweep
+const pushTimeout = 30 * time.Second
+
+// ActionRateLimitExpired is the IP action recorded when a cap lapses
+const ActionRateLimitExpired = "rate_limit_expired"
+
+// Store deletes the expired rate caps and records their expiry. The
+// default implementation delegates to the database package; tests
+// substitute an in-memory store.
+type Store interface {
+	DeleteExpiredIPRateLimits(now time.Time) ([]database.IPRateLimit, error)
+	InsertIPAction(action *database.IPAction) error
+}
+
+// dbStore is the Store backed by the global database connection
+type dbStore struct{}
+
+func (dbStore) DeleteExpiredIPRateLimits(now time.Time) ([]database.IPRateLimit, error) {
+	return database.DeleteExpiredIPRateLimits(now)
+}
+
+func (dbStore) InsertIPAction(action *database.IPAction) error {
+	return database.InsertIPAction(action)
+}
+
+// Expirer periodically deletes the rate caps that expired and pushes their
+// lifting to Stalwart
+type Expirer struct {
+	store    Store
+	pusher   Pusher
+	logger   *logrus.Logger
+	ticker   *time.Ticker
+	stopChan chan bool
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewExpirer creates an expirer of the caps stored in the database. A nil
+// pusher only deletes them.
+func NewExpirer(pusher Pusher, logger *logrus.Logger) *Expirer {
+	return NewExpirerWithStore(dbStore{}, pusher, logger)
+}
+
+// NewExpirerWithStore creates an expirer backed by store
+func NewExpirerWithStore(store Store, pusher Pusher, logger *logrus.Logger) *Expirer {
+	return &Expirer{store: store, pusher: pusher, logger: logger, stopChan: make(chan bool)}
+}
+
+// Start sweeps every interval
+func (e *Expirer) Start(interval time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running {
+		return fmt.Errorf("rate limit expirer is already running")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("rate limit expirer requires a positive interval")
+	}
+
+	e.ticker = time.NewTicker(interval)
+	e.running = true
+
+	e.logger.WithFields(logrus.Fields{
+		"action":   "rate_limit_expirer_start",
+		"interval": interval.String(),
+	}).Info("Starting rate limit expirer")
+
+	go func() {
+		for {
+			select {
+			case <-e.ticker.C:
+				e.RunOnce()
+			case <-e.stopChan:
+				e.logger.Info("Rate limit expirer stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the expirer
+func (e *Expirer) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.running {
+		return
+	}
+
+	if e.ticker != nil {
+		e.ticker.Stop()
+	}
+
+	e.stopChan <- true
+	e.running = false
+}
+
+// RunOnce sweeps now, logging failures
+func (e *Expirer) RunOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	if _, err := e.Run(ctx, time.Now()); err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"action": "rate_limit_expiry_failed",
+			"error":  err.Error(),
+		}).Error("Failed to expire rate limits")
+	}
+}
+
+// Run deletes the caps expired at now, records their expiry and pushes
+// their lifting. A failed push is logged and not retried; the hook got the
+// cap's expiry with it. Returns the caps expired.
+func (e *Expirer) Run(ctx context.Context, now time.Time) (int, error) {
+	expired, err := e.store.DeleteExpiredIPRateLimits(now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, limit := range expired {
+		action := &database.IPAction{
+			IP:          limit.IP,
+			Action:      ActionRateLimitExpired,
+			Reason:      fmt.Sprintf("Rate limit of %d messages/minute expired", limit.MessagesPerMinute),
+			TriggeredBy: "automated",
+			Metadata: map[string]interface{}{
+				"messages_per_minute": limit.MessagesPerMinute,
+				"set_by":              limit.SetBy,
+				"expires_at":          limit.ExpiresAt,
+			},
+			CreatedAt: now,
+		}
+		if err := e.store.InsertIPAction(action); err != nil {
+			e.logger.WithFields(logrus.Fields{
+				"action": "record_rate_limit_expiry_failed",
+				"ip":     limit.IP,
+				"error":  err.Error(),
+			}).Warn("Failed to record rate limit expiry")
+		}
+
+		pushed := false
+		if e.pusher != nil {
+			if err := e.pusher.PushRateLimit(ctx, Lifted(limit.IP)); err != nil {
+				e.logger.WithFields(logrus.Fields{
+					"action": "rate_limit_push_failed",
+					"ip":     limit.IP,
+					"error":  err.Error(),
+				}).Warn("Failed to push the lifted rate limit to Stalwart")
+			} else {
+				pushed = true
+			}
+		}
+
+		e.logger.WithFields(logrus.Fields{
+			"action":              "rate_limit_expired",
+			"ip":                  limit.IP,
+			"messages_per_minute": limit.MessagesPerMinute,
+			"pushed":              pushed,
+		}).Info("Rate limit expired")
+	}
+	return len(expired), nil
+}
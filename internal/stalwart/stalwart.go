@@ -0,0 +1,92 @@
+// Package stalwart pushes the outbound rate caps set on IPs to Stalwart's
+// throttling hook and lifts them when they expire
+package stalwart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-backend-service/internal/database"
+	"golang-backend-service/internal/dependencies"
+)
+
+// RateLimit is what the throttling hook receives for an IP. A
+// MessagesPerMinute of 0 lifts the cap; the IP goes back to its
+// recommended send rates.
+type RateLimit struct {
+	IP                string `json:"ip"`
+	MessagesPerMinute int    `json:"messages_per_minute"`
+	Reason            string `json:"reason,omitempty"`
+	// ExpiresAt lets the hook lift the cap itself should the push lifting
+	// it never arrive
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Capped returns the push setting limit
+func Capped(limit database.IPRateLimit) RateLimit {
+	expiresAt := limit.ExpiresAt
+	return RateLimit{IP: limit.IP, MessagesPerMinute: limit.MessagesPerMinute, Reason: limit.Reason, ExpiresAt: &expiresAt}
+}
+
+// Lifted returns the push lifting the cap of ip
+func Lifted(ip string) RateLimit {
+	return RateLimit{IP: ip}
+}
+
+// Pusher sends rate limits to Stalwart
+type Pusher interface {
+	PushRateLimit(ctx context.Context, limit RateLimit) error
+}
+
+// Client posts rate limits as JSON to the throttling hook
+type Client struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewClient creates a client posting to url, with token as a bearer token
+// unless empty
+func NewClient(url, token string, timeout time.Duration) *Client {
+	return &Client{
+		url:   url,
+		token: token,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: dependencies.Default.Transport(dependencies.Stalwart, nil),
+		},
+	}
+}
+
+// PushRateLimit implements Pusher. Any status but 2xx is an error.
+func (c *Client) PushRateLimit(ctx context.Context, limit RateLimit) error {
+	body, err := json.Marshal(limit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-backend-service/1.0")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push rate limit: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("throttling hook responded %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
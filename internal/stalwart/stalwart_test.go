@@ -0,0 +1,113 @@
+package stalwart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-backend-service/internal/database"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	limits  []database.IPRateLimit
+	actions []database.IPAction
+}
+
+func (s *memoryStore) DeleteExpiredIPRateLimits(now time.Time) ([]database.IPRateLimit, error) {
+	var expired, kept []database.IPRateLimit
+	for _, limit := range s.limits {
+		if limit.ExpiresAt.After(now) {
+			kept = append(kept, limit)
+		} else {
+			expired = append(expired, limit)
+		}
+	}
+	s.limits = kept
+	return expired, nil
+}
+
+func (s *memoryStore) InsertIPAction(action *database.IPAction) error {
+	s.actions = append(s.actions, *action)
+	return nil
+}
+
+// recordingPusher records the rate limits pushed, failing with err
+type recordingPusher struct {
+	pushed []RateLimit
+	err    error
+}
+
+func (p *recordingPusher) PushRateLimit(ctx context.Context, limit RateLimit) error {
+	p.pushed = append(p.pushed, limit)
+	return p.err
+}
+
+func TestClientPushRateLimit(t *testing.T) {
+	var got RateLimit
+	var authorization string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode push: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	expiresAt := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	client := NewClient(server.URL, "s3cret", time.Second)
+	limit := Capped(database.IPRateLimit{IP: "192.0.2.1", MessagesPerMinute: 30, Reason: "deferrals", ExpiresAt: expiresAt})
+	if err := client.PushRateLimit(context.Background(), limit); err != nil {
+		t.Fatalf("PushRateLimit failed: %v", err)
+	}
+	if got.IP != "192.0.2.1" || got.MessagesPerMinute != 30 || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) || authorization != "Bearer s3cret" {
+		t.Errorf("Unexpected push %+v with %q", got, authorization)
+	}
+
+	status, got = http.StatusBadGateway, RateLimit{}
+	if err := client.PushRateLimit(context.Background(), Lifted("192.0.2.1")); err == nil {
+		t.Error("Expected an error for a 502")
+	}
+	if got.MessagesPerMinute != 0 || got.ExpiresAt != nil {
+		t.Errorf("Expected the lifting pushed without an expiry, got %+v", got)
+	}
+}
+
+func TestExpirerRun(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	now := time.Now()
+	store := &memoryStore{limits: []database.IPRateLimit{
+		{IP: "192.0.2.1", MessagesPerMinute: 30, SetBy: "alice", ExpiresAt: now.Add(-time.Minute)},
+		{IP: "192.0.2.2", MessagesPerMinute: 10, SetBy: "alice", ExpiresAt: now.Add(time.Hour)},
+		{IP: "192.0.2.3", MessagesPerMinute: 5, SetBy: "bob", ExpiresAt: now},
+	}}
+	pusher := &recordingPusher{err: errors.New("connection refused")}
+
+	expired, err := NewExpirerWithStore(store, pusher, logger).Run(context.Background(), now)
+	if err != nil || expired != 2 {
+		t.Fatalf("Expected 2 caps expired, got %d %v", expired, err)
+	}
+	if len(store.limits) != 1 || store.limits[0].IP != "192.0.2.2" {
+		t.Errorf("Expected the unexpired cap kept, got %+v", store.limits)
+	}
+	if len(pusher.pushed) != 2 || pusher.pushed[0] != Lifted("192.0.2.1") || pusher.pushed[1] != Lifted("192.0.2.3") {
+		t.Errorf("Expected the liftings pushed despite the failures, got %+v", pusher.pushed)
+	}
+	if len(store.actions) != 2 || store.actions[0].Action != ActionRateLimitExpired || store.actions[1].Metadata["set_by"] != "bob" {
+		t.Errorf("Expected the expiries recorded, got %+v", store.actions)
+	}
+
+	// Without a pusher caps still expire
+	store.limits[0].ExpiresAt = now
+	if expired, err := NewExpirerWithStore(store, nil, logger).Run(context.Background(), now); err != nil || expired != 1 {
+		t.Errorf("Expected the cap expired without a pusher, got %d %v", expired, err)
+	}
+}
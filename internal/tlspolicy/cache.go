@@ -0,0 +1,165 @@
+package tlspolicy
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// errorTTL caps how long a failed lookup is cached
+	errorTTL = 5 * time.Minute
+	// maxEntries caps the cached domains
+	maxEntries = 10000
+	// maxRefreshes caps the background lookups in flight
+	maxRefreshes = 16
+)
+
+// Cache keeps resolved policies so annotating a failure never waits on DNS
+// or HTTPS. Concurrent lookups of one domain share one resolution.
+type Cache struct {
+	resolver *Resolver
+	ttl      time.Duration
+	timeout  time.Duration
+	logger   *logrus.Logger
+
+	mu       sync.Mutex
+	entries  map[string]Policy
+	inflight map[string]chan struct{}
+}
+
+// NewCache creates a policy cache keeping policies for ttl, or the MTA-STS
+// max_age when shorter, each resolution bounded by timeout
+func NewCache(resolver *Resolver, ttl, timeout time.Duration, logger *logrus.Logger) *Cache {
+	return &Cache{
+		resolver: resolver,
+		ttl:      ttl,
+		timeout:  timeout,
+		logger:   logger,
+		entries:  make(map[string]Policy),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Requirement returns the cached TLS requirement of a domain without
+// blocking. A missing or expired policy is refreshed in the background; an
+// expired one is still answered meanwhile, a missing one is "".
+func (c *Cache) Requirement(domain string) string {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	policy, ok := c.entries[domain]
+	if !ok || c.expired(policy, time.Now()) {
+		if len(c.inflight) < maxRefreshes {
+			c.refreshLocked(domain)
+		}
+	}
+	if !ok {
+		return ""
+	}
+	return policy.Requirement()
+}
+
+// Lookup returns the policy of a domain, resolving it unless a fresh one is
+// cached. It returns ctx's error when ctx ends first.
+func (c *Cache) Lookup(ctx context.Context, domain string) (Policy, error) {
+	domain = normalizeDomain(domain)
+	c.mu.Lock()
+	policy, ok := c.entries[domain]
+	if ok && !c.expired(policy, time.Now()) {
+		c.mu.Unlock()
+		return policy, nil
+	}
+	done := c.refreshLocked(domain)
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return Policy{}, ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[domain], nil
+}
+
+// Policies returns the cached policies by domain, expired ones included
+func (c *Cache) Policies() []Policy {
+	c.mu.Lock()
+	policies := make([]Policy, 0, len(c.entries))
+	for _, policy := range c.entries {
+		policies = append(policies, policy)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Domain < policies[j].Domain })
+	return policies
+}
+
+func (c *Cache) expired(policy Policy, now time.Time) bool {
+	ttl := c.ttl
+	if policy.Error != "" && ttl > errorTTL {
+		ttl = errorTTL
+	}
+	if maxAge := time.Duration(policy.STSMaxAge) * time.Second; policy.STSMode != "" && maxAge < ttl {
+		ttl = maxAge
+	}
+	return now.Sub(policy.FetchedAt) >= ttl
+}
+
+// refreshLocked starts resolving a domain unless it already is, and returns
+// a channel closed once it is cached. c.mu must be held.
+func (c *Cache) refreshLocked(domain string) chan struct{} {
+	if done, ok := c.inflight[domain]; ok {
+		return done
+	}
+	done := make(chan struct{})
+	c.inflight[domain] = done
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		policy := c.resolver.Resolve(ctx, domain)
+		if policy.Error != "" {
+			c.logger.WithFields(logrus.Fields{
+				"action": "tls_policy_lookup_failed",
+				"domain": domain,
+				"error":  policy.Error,
+			}).Debug("Failed to resolve destination TLS policy")
+		}
+
+		c.mu.Lock()
+		c.storeLocked(policy)
+		delete(c.inflight, domain)
+		c.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// storeLocked caches a policy, dropping expired entries, or any entry when
+// none has expired, once the cache is full
+func (c *Cache) storeLocked(policy Policy) {
+	if _, ok := c.entries[policy.Domain]; !ok && len(c.entries) >= maxEntries {
+		now := time.Now()
+		for domain, cached := range c.entries {
+			if c.expired(cached, now) {
+				delete(c.entries, domain)
+			}
+		}
+		for domain := range c.entries {
+			if len(c.entries) < maxEntries {
+				break
+			}
+			delete(c.entries, domain)
+		}
+	}
+	c.entries[policy.Domain] = policy
+}
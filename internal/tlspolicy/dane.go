@@ -0,0 +1,162 @@
+package tlspolicy
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// typeTLSA is the TLSA resource record type (RFC 6698)
+const typeTLSA = dnsmessage.Type(52)
+
+// ednsPayloadSize is the UDP payload size advertised with EDNS0
+const ednsPayloadSize = 1232
+
+// DNSSECTLSA looks up TLSA records through a validating resolver. Records
+// only count when the resolver marks the answer authenticated (AD bit),
+// since unsigned TLSA records must be ignored (RFC 7672 section 2.2).
+type DNSSECTLSA struct {
+	server  string
+	timeout time.Duration
+}
+
+// NewDNSSECTLSA creates a TLSA checker asking the validating resolver at
+// server (host:port), each query bounded by timeout
+func NewDNSSECTLSA(server string, timeout time.Duration) *DNSSECTLSA {
+	return &DNSSECTLSA{server: server, timeout: timeout}
+}
+
+// HasTLSA implements TLSAChecker for _25._tcp.<host>
+func (c *DNSSECTLSA) HasTLSA(ctx context.Context, host string) (bool, error) {
+	name, err := dnsmessage.NewName("_25._tcp." + strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return false, err
+	}
+	id := uint16(rand.Uint32())
+	query, err := tlsaQuery(id, name)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.exchange(ctx, "udp", query)
+	if err != nil {
+		return false, err
+	}
+	var parser dnsmessage.Parser
+	header, err := parser.Start(resp)
+	if err == nil && header.Truncated {
+		if resp, err = c.exchange(ctx, "tcp", query); err != nil {
+			return false, err
+		}
+		header, err = parser.Start(resp)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to parse DNS answer: %w", err)
+	}
+	return tlsaAnswer(id, header, &parser)
+}
+
+func tlsaQuery(id uint16, name dnsmessage.Name) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true, AuthenticData: true})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: name, Type: typeTLSA, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	// DO asks the resolver to validate and report it
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(ednsPayloadSize, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, err
+	}
+	if err := builder.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// tlsaAnswer reports whether a parsed answer holds authenticated TLSA
+// records. NXDOMAIN is no records rather than an error.
+func tlsaAnswer(id uint16, header dnsmessage.Header, parser *dnsmessage.Parser) (bool, error) {
+	switch {
+	case header.ID != id || !header.Response:
+		return false, fmt.Errorf("unexpected DNS answer")
+	case header.RCode == dnsmessage.RCodeNameError:
+		return false, nil
+	case header.RCode != dnsmessage.RCodeSuccess:
+		return false, fmt.Errorf("DNS answer %s", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return false, err
+	}
+	found := false
+	for {
+		answer, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		if answer.Type == typeTLSA {
+			found = true
+		}
+		if err := parser.SkipAnswer(); err != nil {
+			return false, err
+		}
+	}
+	return found && header.AuthenticData, nil
+}
+
+// exchange sends a query and reads the answer, with the two-byte length
+// prefix over TCP
+func (c *DNSSECTLSA) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, c.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,294 @@
+package tlspolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of an MTA-STS policy (RFC 8461)
+const (
+	ModeEnforce = "enforce"
+	ModeTesting = "testing"
+	ModeNone    = "none"
+)
+
+// Requirements a destination's policies place on our TLS, as stored on
+// failures. An empty requirement means the policies were not known.
+const (
+	RequirementDANE       = "dane"
+	RequirementSTSEnforce = "mta-sts-enforce"
+	RequirementSTSTesting = "mta-sts-testing"
+	RequirementNone       = "none"
+)
+
+// maxPolicySize caps an MTA-STS policy file (RFC 8461 section 3.2 suggests 64KB)
+const maxPolicySize = 64 << 10
+
+// maxMXHosts caps the MX hosts checked for TLSA records
+const maxMXHosts = 5
+
+// RequiresTLS reports whether a destination with the requirement refuses
+// mail it cannot deliver over authenticated TLS
+func RequiresTLS(requirement string) bool {
+	return requirement == RequirementDANE || requirement == RequirementSTSEnforce
+}
+
+// Policy is what a destination domain publishes about inbound TLS
+type Policy struct {
+	Domain string `json:"domain"`
+	// STSMode is the mode of the domain's MTA-STS policy, empty without one
+	STSMode   string   `json:"sts_mode,omitempty"`
+	STSID     string   `json:"sts_id,omitempty"`
+	STSMX     []string `json:"sts_mx,omitempty"`
+	STSMaxAge int      `json:"sts_max_age,omitempty"`
+	// DANEHosts are the MX hosts with DNSSEC-validated TLSA records
+	DANEHosts []string  `json:"dane_hosts,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Error is the first lookup that failed; the policy may be partial
+	Error string `json:"error,omitempty"`
+}
+
+// Requirement returns the TLS requirement of the policy. DANE wins over
+// MTA-STS, as senders supporting both apply it (RFC 8461 section 2). A
+// failed lookup that found nothing is unknown rather than none.
+func (p Policy) Requirement() string {
+	switch {
+	case len(p.DANEHosts) > 0:
+		return RequirementDANE
+	case p.STSMode == ModeEnforce:
+		return RequirementSTSEnforce
+	case p.STSMode == ModeTesting:
+		return RequirementSTSTesting
+	case p.Error != "":
+		return ""
+	}
+	return RequirementNone
+}
+
+// DNS performs the lookups policy discovery needs. *net.Resolver satisfies
+// this interface.
+type DNS interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// TLSAChecker reports whether a host publishes DNSSEC-validated TLSA
+// records for SMTP
+type TLSAChecker interface {
+	HasTLSA(ctx context.Context, host string) (bool, error)
+}
+
+// Fetcher retrieves the MTA-STS policy file of a domain
+type Fetcher interface {
+	FetchPolicy(ctx context.Context, domain string) (string, error)
+}
+
+// Resolver discovers the MTA-STS and DANE policies of destination domains
+type Resolver struct {
+	dns     DNS
+	tlsa    TLSAChecker
+	fetcher Fetcher
+}
+
+// NewResolver creates a policy resolver. A nil DNS uses the pure-Go system
+// resolver, a nil fetcher HTTPS with a 10s timeout, and a nil TLSA checker
+// skips DANE.
+func NewResolver(dns DNS, tlsa TLSAChecker, fetcher Fetcher) *Resolver {
+	if dns == nil {
+		dns = &net.Resolver{PreferGo: true}
+	}
+	if fetcher == nil {
+		fetcher = NewHTTPFetcher(10 * time.Second)
+	}
+	return &Resolver{dns: dns, tlsa: tlsa, fetcher: fetcher}
+}
+
+// Resolve looks up the policies of a domain. Lookup errors are kept on the
+// policy rather than returned, so failed lookups can be cached too.
+func (r *Resolver) Resolve(ctx context.Context, domain string) Policy {
+	policy := Policy{Domain: normalizeDomain(domain)}
+	fail := func(err error) {
+		if policy.Error == "" {
+			policy.Error = err.Error()
+		}
+	}
+
+	if err := r.resolveSTS(ctx, &policy); err != nil {
+		fail(err)
+	}
+	if r.tlsa != nil {
+		if err := r.resolveDANE(ctx, &policy); err != nil {
+			fail(err)
+		}
+	}
+	policy.FetchedAt = time.Now()
+	return policy
+}
+
+func (r *Resolver) resolveSTS(ctx context.Context, policy *Policy) error {
+	records, err := r.dns.LookupTXT(ctx, "_mta-sts."+policy.Domain)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up MTA-STS record: %w", err)
+	}
+	id, ok := parseSTSRecord(records)
+	if !ok {
+		return nil
+	}
+
+	text, err := r.fetcher.FetchPolicy(ctx, policy.Domain)
+	if err != nil {
+		return fmt.Errorf("failed to fetch MTA-STS policy: %w", err)
+	}
+	mode, mx, maxAge, err := ParseSTSPolicy(text)
+	if err != nil {
+		return err
+	}
+	policy.STSID = id
+	policy.STSMode = mode
+	policy.STSMX = mx
+	policy.STSMaxAge = maxAge
+	return nil
+}
+
+func (r *Resolver) resolveDANE(ctx context.Context, policy *Policy) error {
+	mxs, err := r.dns.LookupMX(ctx, policy.Domain)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up MX records: %w", err)
+	}
+	sort.Slice(mxs, func(i, j int) bool { return mxs[i].Pref < mxs[j].Pref })
+
+	var firstErr error
+	for i, mx := range mxs {
+		if i == maxMXHosts {
+			break
+		}
+		host := normalizeDomain(mx.Host)
+		if host == "" {
+			continue
+		}
+		found, err := r.tlsa.HasTLSA(ctx, host)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to look up TLSA records of %s: %w", host, err)
+			}
+			continue
+		}
+		if found {
+			policy.DANEHosts = append(policy.DANEHosts, host)
+		}
+	}
+	return firstErr
+}
+
+// parseSTSRecord returns the policy ID of the single v=STSv1 TXT record.
+// Several records, or none, mean no policy (RFC 8461 section 3.1).
+func parseSTSRecord(records []string) (string, bool) {
+	id, found := "", 0
+	for _, record := range records {
+		fields := strings.Split(record, ";")
+		if strings.TrimSpace(fields[0]) != "v=STSv1" {
+			continue
+		}
+		found++
+		for _, field := range fields[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok && key == "id" {
+				id = value
+			}
+		}
+	}
+	return id, found == 1 && id != ""
+}
+
+// ParseSTSPolicy parses an MTA-STS policy file
+func ParseSTSPolicy(text string) (mode string, mx []string, maxAge int, err error) {
+	version := ""
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			mode = value
+		case "mx":
+			mx = append(mx, strings.ToLower(value))
+		case "max_age":
+			if maxAge, err = strconv.Atoi(value); err != nil || maxAge < 0 {
+				return "", nil, 0, fmt.Errorf("invalid MTA-STS max_age %q", value)
+			}
+		}
+	}
+
+	switch {
+	case version != "STSv1":
+		return "", nil, 0, fmt.Errorf("unsupported MTA-STS version %q", version)
+	case mode != ModeEnforce && mode != ModeTesting && mode != ModeNone:
+		return "", nil, 0, fmt.Errorf("invalid MTA-STS mode %q", mode)
+	case mode != ModeNone && len(mx) == 0:
+		return "", nil, 0, fmt.Errorf("MTA-STS policy in %s mode has no mx", mode)
+	}
+	return mode, mx, maxAge, nil
+}
+
+// HTTPFetcher fetches MTA-STS policies from https://mta-sts.<domain>
+type HTTPFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPFetcher creates a policy fetcher whose requests are bounded by
+// timeout. Redirects are not followed (RFC 8461 section 3.3).
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	return &HTTPFetcher{client: &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// FetchPolicy implements Fetcher
+func (f *HTTPFetcher) FetchPolicy(ctx context.Context, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://mta-sts."+domain+"/.well-known/mta-sts.txt", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("policy host answered %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
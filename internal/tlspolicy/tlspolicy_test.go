@@ -0,0 +1,226 @@
+package tlspolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNS answers TXT and MX lookups from maps; other names are NXDOMAIN
+type fakeDNS struct {
+	txt map[string][]string
+	mx  map[string][]*net.MX
+	err map[string]error
+}
+
+func (f *fakeDNS) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if err := f.err[name]; err != nil {
+		return nil, err
+	}
+	if records, ok := f.txt[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeDNS) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if mxs, ok := f.mx[name]; ok {
+		return mxs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+type fakeFetcher struct {
+	mu       sync.Mutex
+	policies map[string]string
+	fetches  int
+}
+
+func (f *fakeFetcher) FetchPolicy(ctx context.Context, domain string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+	if policy, ok := f.policies[domain]; ok {
+		return policy, nil
+	}
+	return "", errors.New("policy host answered 404")
+}
+
+type fakeTLSA map[string]bool
+
+func (f fakeTLSA) HasTLSA(ctx context.Context, host string) (bool, error) {
+	return f[host], nil
+}
+
+func newFakeResolver() (*Resolver, *fakeFetcher) {
+	dns := &fakeDNS{
+		txt: map[string][]string{
+			"_mta-sts.gmail.com":    {"v=STSv1; id=20190429T010101;"},
+			"_mta-sts.testing.test": {"v=STSv1; id=1"},
+			"_mta-sts.broken.test":  {"v=STSv1; id=1"},
+			"_mta-sts.twice.test":   {"v=STSv1; id=1", "v=STSv1; id=2"},
+		},
+		mx: map[string][]*net.MX{
+			"posteo.de": {{Host: "mx02.posteo.de.", Pref: 20}, {Host: "mx01.posteo.de.", Pref: 10}},
+			"gmail.com": {{Host: "gmail-smtp-in.l.google.com.", Pref: 5}},
+		},
+		err: map[string]error{
+			"_mta-sts.timeout.test": &net.DNSError{Err: "i/o timeout", Name: "_mta-sts.timeout.test", IsTimeout: true},
+		},
+	}
+	fetcher := &fakeFetcher{policies: map[string]string{
+		"gmail.com":    "version: STSv1\nmode: enforce\nmx: gmail-smtp-in.l.google.com\nmx: *.gmail-smtp-in.l.google.com\nmax_age: 86400\n",
+		"testing.test": "version: STSv1\r\nmode: testing\r\nmx: mx.testing.test\r\nmax_age: 600\r\n",
+		"twice.test":   "version: STSv1\nmode: enforce\nmx: mx.twice.test\nmax_age: 600\n",
+	}}
+	return NewResolver(dns, fakeTLSA{"mx01.posteo.de": true}, fetcher), fetcher
+}
+
+func TestResolve(t *testing.T) {
+	resolver, _ := newFakeResolver()
+	ctx := context.Background()
+
+	tests := []struct {
+		domain      string
+		requirement string
+		errored     bool
+	}{
+		{"Gmail.com.", RequirementSTSEnforce, false},
+		{"testing.test", RequirementSTSTesting, false},
+		{"posteo.de", RequirementDANE, false},
+		{"example.com", RequirementNone, false},
+		// Several records are no policy
+		{"twice.test", RequirementNone, false},
+		// A record without a fetchable policy, or a failed lookup, is unknown
+		{"broken.test", "", true},
+		{"timeout.test", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			policy := resolver.Resolve(ctx, tt.domain)
+			if got := policy.Requirement(); got != tt.requirement {
+				t.Errorf("Requirement() = %q, want %q (%+v)", got, tt.requirement, policy)
+			}
+			if (policy.Error != "") != tt.errored {
+				t.Errorf("Unexpected error %q", policy.Error)
+			}
+		})
+	}
+
+	policy := resolver.Resolve(ctx, "gmail.com")
+	if policy.STSID != "20190429T010101" || policy.STSMaxAge != 86400 || len(policy.STSMX) != 2 {
+		t.Errorf("Unexpected MTA-STS policy: %+v", policy)
+	}
+	if policy := resolver.Resolve(ctx, "posteo.de"); len(policy.DANEHosts) != 1 || policy.DANEHosts[0] != "mx01.posteo.de" {
+		t.Errorf("Unexpected DANE hosts: %+v", policy)
+	}
+}
+
+func TestParseSTSPolicy(t *testing.T) {
+	for _, text := range []string{
+		"mode: enforce\nmx: mx.example.com\nmax_age: 600",
+		"version: STSv1\nmode: strict\nmx: mx.example.com\nmax_age: 600",
+		"version: STSv1\nmode: enforce\nmax_age: 600",
+		"version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: week",
+	} {
+		if _, _, _, err := ParseSTSPolicy(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+	if mode, mx, _, err := ParseSTSPolicy("version: STSv1\nmode: none\nmax_age: 600"); err != nil || mode != ModeNone || len(mx) != 0 {
+		t.Errorf("Expected a none policy without mx, got %q %v %v", mode, mx, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	resolver, fetcher := newFakeResolver()
+	logger, _ := test.NewNullLogger()
+	cache := NewCache(resolver, time.Hour, time.Second, logger)
+
+	// Unknown until the background lookup lands
+	if got := cache.Requirement("gmail.com"); got != "" {
+		t.Errorf("Expected no requirement before the lookup, got %q", got)
+	}
+	policy, err := cache.Lookup(context.Background(), "gmail.com")
+	if err != nil || policy.STSMode != ModeEnforce {
+		t.Fatalf("Lookup = %+v, %v", policy, err)
+	}
+	if got := cache.Requirement("GMAIL.com"); got != RequirementSTSEnforce {
+		t.Errorf("Requirement() = %q, want %q", got, RequirementSTSEnforce)
+	}
+	if fetcher.fetches != 1 {
+		t.Errorf("Expected one fetch, got %d", fetcher.fetches)
+	}
+
+	// A max_age below the TTL expires the policy sooner
+	cache.entries["testing.test"] = Policy{Domain: "testing.test", STSMode: ModeTesting, STSMaxAge: 600, FetchedAt: time.Now().Add(-11 * time.Minute)}
+	if !cache.expired(cache.entries["testing.test"], time.Now()) {
+		t.Error("Expected the policy to expire after its max_age")
+	}
+	if got := cache.Requirement("testing.test"); got != RequirementSTSTesting {
+		t.Errorf("Expected the expired policy answered while refreshing, got %q", got)
+	}
+
+	if policies := cache.Policies(); len(policies) != 2 || policies[0].Domain != "gmail.com" {
+		t.Errorf("Unexpected cached policies: %+v", policies)
+	}
+}
+
+// serveTLSA answers every query on a local UDP socket with a TLSA record,
+// setting the AD bit when authenticated
+func serveTLSA(t *testing.T, authenticated bool) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+			builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, AuthenticData: authenticated})
+			builder.StartQuestions()
+			builder.Question(question)
+			builder.StartAnswers()
+			builder.UnknownResource(
+				dnsmessage.ResourceHeader{Name: question.Name, Type: typeTLSA, Class: dnsmessage.ClassINET, TTL: 300},
+				dnsmessage.UnknownResource{Type: typeTLSA, Data: []byte{3, 1, 1, 0xab, 0xcd}},
+			)
+			resp, _ := builder.Finish()
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSSECTLSA(t *testing.T) {
+	checker := NewDNSSECTLSA(serveTLSA(t, true), time.Second)
+	if found, err := checker.HasTLSA(context.Background(), "mx01.posteo.de."); err != nil || !found {
+		t.Errorf("HasTLSA = %v, %v; want true", found, err)
+	}
+
+	// Without the AD bit the records are not trusted
+	checker = NewDNSSECTLSA(serveTLSA(t, false), time.Second)
+	if found, err := checker.HasTLSA(context.Background(), "mx01.posteo.de"); err != nil || found {
+		t.Errorf("HasTLSA = %v, %v; want false", found, err)
+	}
+}
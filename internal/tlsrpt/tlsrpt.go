@@ -0,0 +1,122 @@
+package tlsrpt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Policy types of RFC 8460
+const (
+	PolicySTS      = "sts"
+	PolicyTLSA     = "tlsa"
+	PolicyNotFound = "no-policy-found"
+)
+
+// maxReportSize caps a decompressed report
+const maxReportSize = 10 << 20
+
+// Report is an SMTP TLS report (RFC 8460): the TLS sessions one sending
+// organization attempted to each policy domain over a date range
+type Report struct {
+	OrganizationName string    `json:"organization-name"`
+	DateRange        DateRange `json:"date-range"`
+	ContactInfo      string    `json:"contact-info"`
+	ReportID         string    `json:"report-id"`
+	Policies         []Policy  `json:"policies"`
+}
+
+// DateRange is the period a report covers
+type DateRange struct {
+	StartDatetime time.Time `json:"start-datetime"`
+	EndDatetime   time.Time `json:"end-datetime"`
+}
+
+// Policy is the sessions to one policy domain under one policy
+type Policy struct {
+	Policy         PolicyDetails `json:"policy"`
+	Summary        Summary       `json:"summary"`
+	FailureDetails []Failure     `json:"failure-details"`
+}
+
+// PolicyDetails names the policy that was applied
+type PolicyDetails struct {
+	PolicyType   string   `json:"policy-type"`
+	PolicyString []string `json:"policy-string,omitempty"`
+	PolicyDomain string   `json:"policy-domain"`
+	MXHost       []string `json:"mx-host,omitempty"`
+}
+
+// Summary counts the sessions under a policy
+type Summary struct {
+	TotalSuccessfulSessionCount int `json:"total-successful-session-count"`
+	TotalFailureSessionCount    int `json:"total-failure-session-count"`
+}
+
+// Failure is one kind of failed TLS negotiation
+type Failure struct {
+	ResultType            string `json:"result-type"`
+	SendingMTAIP          string `json:"sending-mta-ip,omitempty"`
+	ReceivingMXHostname   string `json:"receiving-mx-hostname,omitempty"`
+	ReceivingMXHelo       string `json:"receiving-mx-helo,omitempty"`
+	ReceivingIP           string `json:"receiving-ip,omitempty"`
+	FailedSessionCount    int    `json:"failed-session-count"`
+	AdditionalInformation string `json:"additional-information,omitempty"`
+	FailureReasonCode     string `json:"failure-reason-code,omitempty"`
+}
+
+// Parse reads a JSON TLS report, gzipped (application/tlsrpt+gzip) or not,
+// and checks the fields the report is keyed and grouped by
+func Parse(r io.Reader) (*Report, error) {
+	buffered := bufio.NewReader(r)
+	var body io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress report: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var report Report
+	if err := json.NewDecoder(io.LimitReader(body, maxReportSize)).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+
+	report.OrganizationName = strings.TrimSpace(report.OrganizationName)
+	report.ReportID = strings.TrimSpace(report.ReportID)
+	switch {
+	case report.OrganizationName == "":
+		return nil, fmt.Errorf("organization-name is required")
+	case report.ReportID == "":
+		return nil, fmt.Errorf("report-id is required")
+	case report.DateRange.StartDatetime.IsZero() || report.DateRange.EndDatetime.Before(report.DateRange.StartDatetime):
+		return nil, fmt.Errorf("date-range must have a start-datetime before its end-datetime")
+	}
+
+	for i := range report.Policies {
+		policy := &report.Policies[i].Policy
+		policy.PolicyType = strings.ToLower(strings.TrimSpace(policy.PolicyType))
+		policy.PolicyDomain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(policy.PolicyDomain)), ".")
+		if policy.PolicyDomain == "" {
+			return nil, fmt.Errorf("policies[%d]: policy-domain is required", i)
+		}
+		for j := range report.Policies[i].FailureDetails {
+			failure := &report.Policies[i].FailureDetails[j]
+			failure.ResultType = strings.ToLower(strings.TrimSpace(failure.ResultType))
+			if failure.ResultType == "" {
+				return nil, fmt.Errorf("policies[%d].failure-details[%d]: result-type is required", i, j)
+			}
+			if failure.FailedSessionCount < 0 {
+				return nil, fmt.Errorf("policies[%d].failure-details[%d]: failed-session-count must not be negative", i, j)
+			}
+		}
+	}
+	return &report, nil
+}
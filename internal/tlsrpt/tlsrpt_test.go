@@ -0,0 +1,106 @@
+package tlsrpt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+// sampleReport follows the example of RFC 8460
+const sampleReport = `{
+  "organization-name": "Company-X",
+  "date-range": {
+    "start-datetime": "2016-04-01T00:00:00Z",
+    "end-datetime": "2016-04-01T23:59:59Z"
+  },
+  "contact-info": "sts-reporting@company-x.example",
+  "report-id": "5065427c-23d3-47ca-b6e0-946ea0e8c4be",
+  "policies": [{
+    "policy": {
+      "policy-type": "STS",
+      "policy-string": ["version: STSv1", "mode: testing", "mx: *.mail.company-y.example", "max_age: 86400"],
+      "policy-domain": "Company-Y.example.",
+      "mx-host": ["*.mail.company-y.example"]
+    },
+    "summary": {
+      "total-successful-session-count": 5326,
+      "total-failure-session-count": 303
+    },
+    "failure-details": [{
+      "result-type": "certificate-expired",
+      "sending-mta-ip": "2001:db8:abcd:0012::1",
+      "receiving-mx-hostname": "mx1.mail.company-y.example",
+      "failed-session-count": 100
+    }, {
+      "result-type": "starttls-not-supported",
+      "sending-mta-ip": "2001:db8:abcd:0013::1",
+      "receiving-mx-hostname": "mx2.mail.company-y.example",
+      "receiving-ip": "203.0.113.56",
+      "failed-session-count": 200,
+      "additional-information": "https://reports.company-x.example/report_info?id=5065427c-23d3#StarttlsNotSupported"
+    }]
+  }]
+}`
+
+func TestParse(t *testing.T) {
+	report, err := Parse(strings.NewReader(sampleReport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if report.OrganizationName != "Company-X" || report.ReportID != "5065427c-23d3-47ca-b6e0-946ea0e8c4be" {
+		t.Errorf("Unexpected report identity: %+v", report)
+	}
+	if report.DateRange.StartDatetime.Day() != 1 || report.DateRange.EndDatetime.Hour() != 23 {
+		t.Errorf("Unexpected date range: %+v", report.DateRange)
+	}
+	if len(report.Policies) != 1 {
+		t.Fatalf("Expected 1 policy, got %d", len(report.Policies))
+	}
+	policy := report.Policies[0]
+	if policy.Policy.PolicyType != PolicySTS || policy.Policy.PolicyDomain != "company-y.example" {
+		t.Errorf("Expected the policy normalized, got %+v", policy.Policy)
+	}
+	if policy.Summary.TotalSuccessfulSessionCount != 5326 || policy.Summary.TotalFailureSessionCount != 303 {
+		t.Errorf("Unexpected summary: %+v", policy.Summary)
+	}
+	if len(policy.FailureDetails) != 2 || policy.FailureDetails[1].ResultType != "starttls-not-supported" || policy.FailureDetails[1].FailedSessionCount != 200 {
+		t.Errorf("Unexpected failure details: %+v", policy.FailureDetails)
+	}
+}
+
+func TestParseGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(sampleReport))
+	gz.Close()
+
+	report, err := Parse(&compressed)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if report.ReportID != "5065427c-23d3-47ca-b6e0-946ea0e8c4be" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+	}{
+		{"not json", `report`},
+		{"missing organization", `{"report-id":"r","date-range":{"start-datetime":"2016-04-01T00:00:00Z","end-datetime":"2016-04-02T00:00:00Z"}}`},
+		{"missing report id", `{"organization-name":"X","date-range":{"start-datetime":"2016-04-01T00:00:00Z","end-datetime":"2016-04-02T00:00:00Z"}}`},
+		{"inverted date range", `{"organization-name":"X","report-id":"r","date-range":{"start-datetime":"2016-04-02T00:00:00Z","end-datetime":"2016-04-01T00:00:00Z"}}`},
+		{"missing policy domain", `{"organization-name":"X","report-id":"r","date-range":{"start-datetime":"2016-04-01T00:00:00Z","end-datetime":"2016-04-02T00:00:00Z"},"policies":[{"policy":{"policy-type":"sts"}}]}`},
+		{"missing result type", `{"organization-name":"X","report-id":"r","date-range":{"start-datetime":"2016-04-01T00:00:00Z","end-datetime":"2016-04-02T00:00:00Z"},"policies":[{"policy":{"policy-type":"sts","policy-domain":"example.com"},"failure-details":[{"failed-session-count":1}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.report)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// NormalizeDomain returns the canonical ASCII form of a domain name: trimmed,
+// without a trailing dot, IDNs converted to punycode (xn--) and lower-cased,
+// so Gmail.com, gmail.com. and GMAIL.COM are one domain.
+func NormalizeDomain(raw string) (string, error) {
+	s := strings.TrimSuffix(strings.TrimSpace(raw), ".")
+	if s == "" {
+		return "", fmt.Errorf("domain is required")
+	}
+
+	ascii, err := idna.Lookup.ToASCII(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %w", raw, err)
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// RegistrableDomain folds a normalized domain to the part a registrant
+// controls according to the public suffix list, e.g. mx1.mail.yahoo.co.jp to
+// yahoo.co.jp. Domains that are themselves a public suffix are returned as is.
+func RegistrableDomain(domain string) string {
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+	return registrable
+}
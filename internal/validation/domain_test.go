@@ -0,0 +1,56 @@
+package validation
+
+import "testing"
+
+// TestNormalizeDomain tests case folding and IDN conversion
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		domain   string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Lowercase", domain: "gmail.com", expected: "gmail.com"},
+		{name: "Mixed case", domain: "Gmail.COM", expected: "gmail.com"},
+		{name: "Trailing dot", domain: " gmail.com. ", expected: "gmail.com"},
+		{name: "IDN", domain: "Bücher.example", expected: "xn--bcher-kva.example"},
+		{name: "Punycode", domain: "XN--BCHER-KVA.example", expected: "xn--bcher-kva.example"},
+		{name: "Empty", domain: "", wantErr: true},
+		{name: "Invalid label", domain: "exa mple.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NormalizeDomain(tt.domain)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q but got %q", tt.domain, result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"mx1.mail.yahoo.co.jp": "yahoo.co.jp",
+		"yahoo.co.jp":          "yahoo.co.jp",
+		"eu.mail.gmail.com":    "gmail.com",
+		"co.uk":                "co.uk",
+		"localhost":            "localhost",
+	}
+	for domain, want := range tests {
+		if got := RegistrableDomain(domain); got != want {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// NormalizeIP parses an IP address and returns its canonical string form.
+// Zero-padded IPv4 octets (e.g. 010.001.002.003) are read as decimal,
+// IPv4-mapped IPv6 addresses are unmapped and IPv6 is lower-cased and
+// compressed. Zones and non-unicast style inputs such as CIDRs are rejected.
+func NormalizeIP(raw string) (string, error) {
+	addr, err := ParseIP(raw)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// ParseIP parses and normalizes an IP address into a netip.Addr
+func ParseIP(raw string) (netip.Addr, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return netip.Addr{}, fmt.Errorf("IP address is required")
+	}
+
+	if strings.ContainsAny(s, "/%") {
+		return netip.Addr{}, fmt.Errorf("invalid IP address: %s", raw)
+	}
+
+	if !strings.Contains(s, ":") {
+		s = stripIPv4LeadingZeros(s)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid IP address: %s", raw)
+	}
+
+	return addr.Unmap(), nil
+}
+
+// IsValidIP reports whether raw can be normalized into an IP address
+func IsValidIP(raw string) bool {
+	_, err := ParseIP(raw)
+	return err == nil
+}
+
+// IsPublicIP reports whether addr can be a host on the internet rather than
+// a private, loopback, link-local or unspecified address
+func IsPublicIP(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsUnspecified()
+}
+
+// stripIPv4LeadingZeros removes leading zeros from each dotted-decimal octet
+func stripIPv4LeadingZeros(s string) string {
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return s
+	}
+
+	for i, part := range parts {
+		trimmed := strings.TrimLeft(part, "0")
+		if trimmed == "" && part != "" {
+			trimmed = "0"
+		}
+		parts[i] = trimmed
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// TestNormalizeIP tests IP parsing and canonicalization
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Plain IPv4", ip: "192.0.2.1", expected: "192.0.2.1"},
+		{name: "Zero-padded IPv4", ip: "192.000.002.010", expected: "192.0.2.10"},
+		{name: "Whitespace", ip: " 192.0.2.1 ", expected: "192.0.2.1"},
+		{name: "IPv4-mapped IPv6", ip: "::ffff:192.0.2.1", expected: "192.0.2.1"},
+		{name: "Uppercase expanded IPv6", ip: "2001:0DB8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1"},
+		{name: "Empty", ip: "", wantErr: true},
+		{name: "Hostname", ip: "mail.example.com", wantErr: true},
+		{name: "CIDR", ip: "192.0.2.0/24", wantErr: true},
+		{name: "Zone", ip: "fe80::1%eth0", wantErr: true},
+		{name: "Octet out of range", ip: "192.0.2.256", wantErr: true},
+		{name: "SQL injection", ip: "1.2.3.4' OR '1'='1", wantErr: true},
+		{name: "Too few octets", ip: "1.2.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NormalizeIP(tt.ip)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q but got %q", tt.ip, result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestIsPublicIP tests that internal addresses are told from public ones
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"203.0.113.10":     true,
+		"2001:db8::1":      true,
+		"10.1.2.3":         false,
+		"192.168.0.1":      false,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"::ffff:127.0.0.1": false,
+		"fd00::1":          false,
+	}
+	for raw, want := range tests {
+		if got := IsPublicIP(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
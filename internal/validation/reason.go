@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Placeholders standing in for the variable tokens of a reason family
+const (
+	ReasonTokenURL    = "<url>"
+	ReasonTokenEmail  = "<email>"
+	ReasonTokenIP     = "<ip>"
+	ReasonTokenNumber = "<n>"
+	ReasonTokenID     = "<id>"
+)
+
+// blockReferencePattern matches the block list identifiers providers quote
+// in rejections and ask for in mitigation requests: Outlook's S3150, AS3140
+// and AS(7230), Yahoo's TSS04, TS01, IPTS04 and PH01, and Comcast's
+// BL000010, RL000001 and CM000001
+var blockReferencePattern = regexp.MustCompile(`\b(?:(?:A?S\d{3,5}|(?:TSS|TS|IPTS|PH)\d{2}|(?:BL|RL|CM)\d{6})\b|AS\(\d{1,10}\))`)
+
+// reasonPunctuation is stripped from both ends of a token before it is
+// classified and put back after
+const reasonPunctuation = `[](){}<>"',;:.!?`
+
+// ReasonFamily reduces a free-text SMTP reason to its template, so reasons
+// that differ only in the IP, recipient, block or queue ID, timestamp or
+// lookup URL they quote fall into one family. Words are lower-cased, URLs,
+// email addresses, IPs, numbers and tokens mixing letters and digits become
+// placeholders, runs of one placeholder collapse, and SMTP reply and enhanced
+// status codes are kept as they carry the meaning. For example
+//
+//	550 5.7.1 Client host [192.0.2.1] blocked using zen.spamhaus.org; https://www.spamhaus.org/query/ip/192.0.2.1
+//
+// becomes
+//
+//	550 5.7.1 client host [<ip>] blocked using zen.spamhaus.org; <url>
+func ReasonFamily(reason string) string {
+	var tokens []string
+	for _, field := range strings.Fields(reason) {
+		core := strings.TrimLeft(field, reasonPunctuation)
+		prefix := field[:len(field)-len(core)]
+		trimmed := strings.TrimRight(core, reasonPunctuation)
+		suffix := core[len(trimmed):]
+		if trimmed == "" {
+			tokens = append(tokens, field)
+			continue
+		}
+
+		word, variable := reasonToken(trimmed)
+		// A placeholder continuing a run of itself only adds its closing
+		// punctuation, so "[<id> <id>]" becomes "[<id>]"
+		if n := len(tokens); n > 0 && variable && prefix == "" && strings.HasSuffix(tokens[n-1], word) {
+			tokens[n-1] += suffix
+			continue
+		}
+		tokens = append(tokens, prefix+word+suffix)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// reasonToken returns the placeholder of a variable token and true, or the
+// token lower-cased and false
+func reasonToken(token string) (string, bool) {
+	lower := strings.ToLower(token)
+	switch {
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		return ReasonTokenURL, true
+	case strings.Contains(token, "@") && strings.Contains(token[strings.LastIndex(token, "@"):], "."):
+		return ReasonTokenEmail, true
+	case net.ParseIP(token) != nil:
+		return ReasonTokenIP, true
+	case isSMTPReplyCode(token):
+		return token, false
+	}
+	if _, err := ParseEnhancedCode(token); err == nil {
+		return token, false
+	}
+
+	digits, letters := false, false
+	for _, r := range token {
+		switch {
+		case unicode.IsDigit(r):
+			digits = true
+		case unicode.IsLetter(r):
+			letters = true
+		}
+	}
+	switch {
+	case digits && !letters:
+		return ReasonTokenNumber, true
+	case digits:
+		return ReasonTokenID, true
+	}
+	return lower, false
+}
+
+// isSMTPReplyCode reports whether token is a three-digit SMTP reply code
+func isSMTPReplyCode(token string) bool {
+	return len(token) == 3 && strings.Trim(token, "0123456789") == "" && token[0] >= '2' && token[0] <= '5'
+}
+
+// BlockReferences returns the provider block list identifiers quoted in a
+// reason, in order of appearance and without duplicates, or nil when there
+// are none
+func BlockReferences(reason string) []string {
+	var refs []string
+	seen := map[string]bool{}
+	for _, ref := range blockReferencePattern.FindAllString(reason, -1) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,101 @@
+package validation
+
+import "testing"
+
+// TestReasonFamily tests that reasons differing in their variable tokens
+// share a family
+func TestReasonFamily(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		want   string
+	}{
+		{
+			"spamhaus",
+			"550 5.7.1 Client host [192.0.2.1] blocked using zen.spamhaus.org; https://www.spamhaus.org/query/ip/192.0.2.1",
+			"550 5.7.1 client host [<ip>] blocked using zen.spamhaus.org; <url>",
+		},
+		{
+			"outlook block list",
+			"550 5.7.1 Unfortunately, messages from [2001:db8::1] weren't sent. (S3150) [BN8NAM11FT066.eop-nam11.prod.protection.outlook.com 2024-03-01T10:00:00.000Z 08DC3A1B]",
+			"550 5.7.1 unfortunately, messages from [<ip>] weren't sent. (<id>) [<id>]",
+		},
+		{
+			"gmail rate limit",
+			"421 4.7.28 Our system has detected an unusual rate of unsolicited mail. d2e1a72fcca58-6f4b1c2c1basi1234567b3a.123 - gsmtp",
+			"421 4.7.28 our system has detected an unusual rate of unsolicited mail. <id> - gsmtp",
+		},
+		{
+			"recipient and counts",
+			"552 Mailbox <User.Name@Example.com> is over quota: 10240 of 10240 KB",
+			"552 mailbox <<email>> is over quota: <n> of <n> kb",
+		},
+		{"whitespace", "  Recipient   address\trejected  ", "recipient address rejected"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ReasonFamily(tt.reason); got != tt.want {
+			t.Errorf("%s: ReasonFamily() =\n%q, want\n%q", tt.name, got, tt.want)
+		}
+	}
+
+	a := ReasonFamily("550 5.7.1 Message rejected, block ID 8f14e45fce; see https://postmaster.example/8f14e45fce")
+	b := ReasonFamily("550 5.7.1 Message rejected, block ID c9f0f895fb2; see https://postmaster.example/c9f0f895fb2")
+	if a != b {
+		t.Errorf("Expected one family for different block IDs, got %q and %q", a, b)
+	}
+}
+
+// FuzzReasonFamily tests that the family of any reason is its own family, so
+// stored families regroup stably
+func FuzzReasonFamily(f *testing.F) {
+	for _, seed := range []string{
+		"550 5.7.1 Client host [192.0.2.1] blocked using zen.spamhaus.org; https://www.spamhaus.org/query/ip/192.0.2.1",
+		"552 Mailbox <a@b.example> is over quota: 10 of 10 KB",
+		"(S3150) [AB1 2024-03-01T10:00:00Z 08DC]",
+		"<n> 5 <id>",
+		"[[", "", " \t ",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, reason string) {
+		family := ReasonFamily(reason)
+		if again := ReasonFamily(family); again != family {
+			t.Errorf("ReasonFamily(%q) = %q, whose family is %q", reason, family, again)
+		}
+	})
+}
+
+// TestBlockReferences tests that provider block list identifiers are found
+// in reasons, once each
+func TestBlockReferences(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   []string
+	}{
+		{"550 5.7.1 Unfortunately, messages from [192.0.2.1] weren't sent. Please contact your Internet service provider since part of their network is on our block list (S3150). You can also refer your provider to http://mail.live.com/mail/troubleshooting.aspx#errors. [AM2PEPF0001C70C.eurprd05.prod.outlook.com]", []string{"S3150"}},
+		{"550 5.7.1 Service unavailable, Client host [192.0.2.1] blocked using Spamhaus. To request removal from this list see https://www.spamhaus.org/query/ip/192.0.2.1 AS(1430) [BN1NAM02FT019.eop-nam02.prod.protection.outlook.com]", []string{"AS(1430)"}},
+		{"550 5.7.606 Access denied, banned sending IP [192.0.2.1]. To request removal from this list please visit https://sender.office.com/ (AS3140 S3140)", []string{"AS3140", "S3140"}},
+		{"421 4.7.0 [TSS04] Messages from 192.0.2.1 temporarily deferred due to unexpected volume or user complaints - 4.16.55.1; see https://postmaster.yahooinc.com/error-codes", []string{"TSS04"}},
+		{"554 5.7.9 Message not accepted for policy reasons. [PH01] See https://postmaster.yahooinc.com/error-codes", []string{"PH01"}},
+		{"554 192.0.2.1 found on one or more DNSBLs, see http://postmaster.comcast.net/smtp-error-codes.php#BL000010 BL000010", []string{"BL000010"}},
+		{"550 5.7.1 (S3150) retry later (S3150)", []string{"S3150"}},
+		{"550-5.7.1 Our system has detected that this message is likely unsolicited mail. gsmtp", nil},
+		{"452 4.2.2 The email account that you tried to reach is over quota. S3 STS1 TS1 BL12345", nil},
+	}
+
+	for _, tt := range tests {
+		got := BlockReferences(tt.reason)
+		if len(got) != len(tt.want) {
+			t.Errorf("BlockReferences(%q) = %v, want %v", tt.reason, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("BlockReferences(%q) = %v, want %v", tt.reason, got, tt.want)
+				break
+			}
+		}
+	}
+}
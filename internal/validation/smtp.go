@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EnhancedCode is a parsed RFC 3463 enhanced status code
+// (class.subject.detail)
+type EnhancedCode struct {
+	Class   int
+	Subject int
+	Detail  int
+}
+
+// String returns the canonical form without zero padding, e.g. 5.7.1
+func (c EnhancedCode) String() string {
+	return fmt.Sprintf("%d.%d.%d", c.Class, c.Subject, c.Detail)
+}
+
+// ParseEnhancedCode parses an enhanced status code. The class must be 2, 4
+// or 5 and subject and detail have one to three digits, so zero-padded codes
+// such as 5.7.001 are accepted and read as 5.7.1.
+func ParseEnhancedCode(raw string) (EnhancedCode, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return EnhancedCode{}, fmt.Errorf("enhanced status code is required")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return EnhancedCode{}, fmt.Errorf("invalid enhanced status code: %q", raw)
+	}
+
+	var values [3]int
+	for i, part := range parts {
+		if len(part) == 0 || len(part) > 3 || strings.Trim(part, "0123456789") != "" {
+			return EnhancedCode{}, fmt.Errorf("invalid enhanced status code: %q", raw)
+		}
+		values[i], _ = strconv.Atoi(part)
+	}
+
+	code := EnhancedCode{Class: values[0], Subject: values[1], Detail: values[2]}
+	if len(parts[0]) != 1 || (code.Class != 2 && code.Class != 4 && code.Class != 5) {
+		return EnhancedCode{}, fmt.Errorf("invalid enhanced status code class: %q", raw)
+	}
+	return code, nil
+}
+
+// NormalizeEnhancedCode returns the canonical form of an enhanced status code
+func NormalizeEnhancedCode(raw string) (string, error) {
+	code, err := ParseEnhancedCode(raw)
+	if err != nil {
+		return "", err
+	}
+	return code.String(), nil
+}
@@ -0,0 +1,78 @@
+package validation
+
+import "testing"
+
+// TestNormalizeEnhancedCode tests enhanced status code parsing and canonicalization
+func TestNormalizeEnhancedCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Plain", code: "5.7.1", expected: "5.7.1"},
+		{name: "Zero-padded", code: "5.7.001", expected: "5.7.1"},
+		{name: "Three-digit subject", code: "4.100.26", expected: "4.100.26"},
+		{name: "Whitespace", code: " 2.0.0 ", expected: "2.0.0"},
+		{name: "Empty", code: "", wantErr: true},
+		{name: "Two parts", code: "5.7", wantErr: true},
+		{name: "Four parts", code: "5.7.1.1", wantErr: true},
+		{name: "Bad class", code: "3.1.1", wantErr: true},
+		{name: "Padded class", code: "05.7.1", wantErr: true},
+		{name: "Letters", code: "5.x.1", wantErr: true},
+		{name: "Sign", code: "5.+7.1", wantErr: true},
+		{name: "Too long", code: "5.7.1234", wantErr: true},
+		{name: "Garbage", code: "550 denied", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NormalizeEnhancedCode(tt.code)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q but got %q", tt.code, result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseEnhancedCode(t *testing.T) {
+	code, err := ParseEnhancedCode("4.7.026")
+	if err != nil || code.Class != 4 || code.Subject != 7 || code.Detail != 26 {
+		t.Errorf("ParseEnhancedCode() = %+v, %v", code, err)
+	}
+}
+
+// FuzzNormalizeEnhancedCode tests that any MTA output either fails to parse or
+// normalizes to a canonical code that normalizes to itself
+func FuzzNormalizeEnhancedCode(f *testing.F) {
+	for _, seed := range []string{"5.7.1", " 4.7.001 ", "2.0.0", "5.7", "5.7.1.1", "x.y.z", "05.7.1", "5..1", "5.7.1\n", "５.7.1", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		code, err := NormalizeEnhancedCode(raw)
+		if err != nil {
+			return
+		}
+		parsed, err := ParseEnhancedCode(code)
+		if err != nil {
+			t.Fatalf("NormalizeEnhancedCode(%q) = %q, which does not parse: %v", raw, code, err)
+		}
+		if parsed.String() != code {
+			t.Errorf("NormalizeEnhancedCode(%q) = %q, not canonical (%q)", raw, code, parsed.String())
+		}
+		if parsed.Subject > 999 || parsed.Detail > 999 {
+			t.Errorf("NormalizeEnhancedCode(%q) = %q, out of range", raw, code)
+		}
+	})
+}
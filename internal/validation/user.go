@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{2,49}$`)
+
+// NormalizeEmail checks that raw is a bare address (no display name) and
+// returns it with the domain lower-cased. The local part is kept as given
+// since it may be case sensitive.
+func NormalizeEmail(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", fmt.Errorf("email is required")
+	}
+
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return "", fmt.Errorf("invalid email address: %s", raw)
+	}
+
+	local, domain, _ := strings.Cut(s, "@")
+	if !strings.Contains(domain, ".") || len(s) > 255 {
+		return "", fmt.Errorf("invalid email address: %s", raw)
+	}
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+// ValidateUsername checks a username is 3-50 characters of letters, digits,
+// dots, dashes and underscores, starting with a letter or digit
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username must be 3-50 characters of letters, digits, '.', '-' or '_'")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package validation
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"john.doe@example.com", "john.doe@example.com", false},
+		{" John.Doe@Example.COM ", "John.Doe@example.com", false},
+		{"", "", true},
+		{"not-an-email", "", true},
+		{"John <john@example.com>", "", true},
+		{"john@localhost", "", true},
+		{"john@@example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeEmail(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeEmail(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("NormalizeEmail(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, valid := range []string{"john_doe", "jane.smith", "ops-team", "abc"} {
+		if err := ValidateUsername(valid); err != nil {
+			t.Errorf("Expected %q to be valid: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "ab", "_john", "john doe", "john@doe"} {
+		if err := ValidateUsername(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+#!/bin/bash
+
+# Run the hot path benchmarks and check them against the performance budget
+# Usage: ./scripts/bench-check.sh [baseline.txt]
+#
+# Every benchmark in scripts/bench-budget.txt must run and stay within its
+# ns/op and allocs/op limits. With a baseline (the saved output of an earlier
+# run, e.g. on main) each benchmark is also compared with it and the check
+# fails when one got more than MAX_REGRESSION percent slower.
+
+set -e
+
+cd "$(dirname "$0")/.."
+
+BUDGET="scripts/bench-budget.txt"
+BASELINE="$1"
+COUNT="${COUNT:-3}"
+MAX_REGRESSION="${MAX_REGRESSION:-20}"
+OUT="${OUT:-bench_output.txt}"
+PACKAGES="./internal/reputation ./internal/api"
+
+echo "========================================="
+echo "Benchmark Budget Check"
+echo "========================================="
+echo ""
+
+go test -run '^$' -bench . -benchmem -count "$COUNT" $PACKAGES | tee "$OUT"
+echo ""
+
+# Average ns/op and allocs/op per benchmark, without the -GOMAXPROCS suffix
+summarize() {
+    awk '/^Benchmark/ {
+        name = $1; sub(/-[0-9]+$/, "", name)
+        for (i = 2; i < NF; i++) {
+            if ($(i+1) == "ns/op") ns[name] += $i
+            if ($(i+1) == "allocs/op") allocs[name] += $i
+        }
+        runs[name]++
+    }
+    END { for (name in runs) printf "%s %.0f %.0f\n", name, ns[name] / runs[name], allocs[name] / runs[name] }' "$1"
+}
+
+failed=0
+results=$(summarize "$OUT")
+
+echo "Budget:"
+while read -r name max_ns max_allocs; do
+    case "$name" in ""|\#*) continue ;; esac
+    line=$(echo "$results" | awk -v n="$name" '$1 == n')
+    if [ -z "$line" ]; then
+        echo "  ✗ $name did not run"
+        failed=1
+        continue
+    fi
+    read -r _ ns allocs <<< "$line"
+    if [ "$ns" -gt "$max_ns" ] || [ "$allocs" -gt "$max_allocs" ]; then
+        echo "  ✗ $name: $ns ns/op, $allocs allocs/op (budget $max_ns ns/op, $max_allocs allocs/op)"
+        failed=1
+    else
+        echo "  ✓ $name: $ns ns/op, $allocs allocs/op"
+    fi
+done < "$BUDGET"
+
+if [ -n "$BASELINE" ]; then
+    echo ""
+    echo "Against $BASELINE (max +${MAX_REGRESSION}%):"
+    baseline=$(summarize "$BASELINE")
+    while read -r name ns _; do
+        old=$(echo "$baseline" | awk -v n="$name" '$1 == n { print $2 }')
+        if [ -z "$old" ] || [ "$old" -eq 0 ]; then
+            echo "  - $name: new"
+            continue
+        fi
+        delta=$(awk -v old="$old" -v new="$ns" 'BEGIN { printf "%+.1f", (new - old) * 100 / old }')
+        if awk -v d="$delta" -v max="$MAX_REGRESSION" 'BEGIN { exit !(d > max) }'; then
+            echo "  ✗ $name: $old -> $ns ns/op (${delta}%)"
+            failed=1
+        else
+            echo "  ✓ $name: $old -> $ns ns/op (${delta}%)"
+        fi
+    done <<< "$(echo "$results" | sort)"
+fi
+
+echo ""
+if [ "$failed" -ne 0 ]; then
+    echo "✗ Performance budget exceeded"
+    exit 1
+fi
+echo "✓ Within the performance budget"